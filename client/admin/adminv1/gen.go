@@ -0,0 +1,5 @@
+// Package adminv1 provides the protobuf messages and the gRPC client of the
+// admin service.
+package adminv1
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative admin.proto
@@ -11,6 +11,7 @@ import (
 
 var autoconfirm, isAdmin bool
 var audience string
+var reEncryptBatchSize int
 
 func getAudience(c *conf.GlobalConfiguration) string {
 	if audience == "" {
@@ -25,12 +26,14 @@ func adminCmd() *cobra.Command {
 		Use: "admin",
 	}
 
-	adminCmd.AddCommand(&adminCreateUserCmd, &adminDeleteUserCmd)
+	adminCmd.AddCommand(&adminCreateUserCmd, &adminDeleteUserCmd, &adminRotateEncryptionKeyCmd)
 	adminCmd.PersistentFlags().StringVarP(&audience, "aud", "a", "", "Set the new user's audience")
 
 	adminCreateUserCmd.Flags().BoolVar(&autoconfirm, "confirm", false, "Automatically confirm user without sending an email")
 	adminCreateUserCmd.Flags().BoolVar(&isAdmin, "admin", false, "Create user with admin privileges")
 
+	adminRotateEncryptionKeyCmd.Flags().IntVar(&reEncryptBatchSize, "batch-size", 1000, "Number of rows to re-encrypt per batch")
+
 	return adminCmd
 }
 
@@ -58,6 +61,14 @@ var adminDeleteUserCmd = cobra.Command{
 	},
 }
 
+var adminRotateEncryptionKeyCmd = cobra.Command{
+	Use:  "rotate-encryption-key",
+	Long: "Re-encrypt the values encrypted with older database encryption keys with the current encryption key. The older keys must stay in the decryption keys until it has finished.",
+	Run: func(cmd *cobra.Command, args []string) {
+		execWithConfigAndArgs(cmd, adminRotateEncryptionKey, args)
+	},
+}
+
 func adminCreateUser(config *conf.GlobalConfiguration, args []string) {
 	db, err := storage.Dial(config)
 	if err != nil {
@@ -129,3 +140,29 @@ func adminDeleteUser(config *conf.GlobalConfiguration, args []string) {
 
 	logrus.Infof("Removed user: %s", args[0])
 }
+
+func adminRotateEncryptionKey(config *conf.GlobalConfiguration, args []string) {
+	if !config.Security.DBEncryption.Encrypt {
+		logrus.Fatal("Database encryption is not enabled, set GOTRUE_SECURITY_DB_ENCRYPTION_ENCRYPT")
+	}
+	if reEncryptBatchSize < 1 {
+		logrus.Fatal("The batch size must be at least 1")
+	}
+
+	db, err := storage.Dial(config)
+	if err != nil {
+		logrus.Fatalf("Error opening database: %+v", err)
+	}
+	defer db.Close()
+
+	for _, column := range models.EncryptedColumns() {
+		n, err := models.ReEncryptColumn(db, column, config.Security.DBEncryption, reEncryptBatchSize)
+		if err != nil {
+			logrus.Fatalf("Error re-encrypting %s.%s after re-encrypting %d values: %+v", column.Table, column.Column, n, err)
+		}
+
+		logrus.Infof("Re-encrypted %d values of %s.%s", n, column.Table, column.Column)
+	}
+
+	logrus.Infof("Re-encrypted all values with key %q", config.Security.DBEncryption.EncryptionKeyID)
+}
@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/supabase/auth/internal/conf"
+	"github.com/supabase/auth/internal/reaper"
+	"github.com/supabase/auth/internal/storage"
+)
+
+var cleanupCmd = cobra.Command{
+	Use:  "cleanup",
+	Long: "Prune the expired rows with the retention of the reaper configuration, for running from cron instead of the background reaper.",
+	Run: func(cmd *cobra.Command, args []string) {
+		execWithConfigAndArgs(cmd, cleanup, args)
+	},
+}
+
+func cleanup(config *conf.GlobalConfiguration, args []string) {
+	// the retention is validated as when the background reaper is enabled
+	pruneConfig := *config
+	pruneConfig.Reaper.Enabled = true
+	if err := pruneConfig.Reaper.Validate(); err != nil {
+		logrus.Fatalf("Invalid reaper configuration: %+v", err)
+	}
+
+	db, err := storage.Dial(config)
+	if err != nil {
+		logrus.Fatalf("Error opening database: %+v", err)
+	}
+	defer db.Close()
+
+	le := logrus.WithField("component", "reaper")
+	removed, err := reaper.Prune(db.Context(), db, &pruneConfig, time.Now(), le)
+	if err != nil {
+		logrus.Fatalf("Error pruning expired rows: %+v", err)
+	}
+
+	total := 0
+	for _, n := range removed {
+		total += n
+	}
+	le.WithField("removed", total).Info("Pruned expired rows")
+}
@@ -2,25 +2,69 @@ package cmd
 
 import (
 	"embed"
+	"fmt"
 	"net/url"
 	"os"
+	"path"
+	"slices"
+	"sort"
+	"strings"
 
 	"github.com/gobuffalo/pop/v6"
 	"github.com/gobuffalo/pop/v6/logging"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
+	"github.com/supabase/auth/internal/storage"
 )
 
 var EmbeddedMigrations embed.FS
 
+var (
+	migrateDryRun bool
+	migrateTo     string
+	migrateSteps  int
+)
+
 var migrateCmd = cobra.Command{
 	Use:  "migrate",
 	Long: "Migrate database strucutures. This will create new tables and add missing columns and indexes.",
 	Run:  migrate,
 }
 
-func migrate(cmd *cobra.Command, args []string) {
+var migrateStatusCmd = cobra.Command{
+	Use:   "status",
+	Short: "Show the applied and pending migrations",
+	Long:  "List the migrations and whether they are applied or pending.",
+	Run:   migrateStatus,
+}
+
+var migrateUpCmd = cobra.Command{
+	Use:   "up",
+	Short: "Apply pending migrations",
+	Long:  "Apply the pending migrations, or only those up to and including the version given with --to.",
+	Run:   migrate,
+}
+
+var migrateDownCmd = cobra.Command{
+	Use:   "down",
+	Short: "Roll back applied migrations",
+	Long:  "Roll back the latest applied migrations with their down migrations. Migrations without a down migration can not be rolled back.",
+	Run:   migrateDown,
+}
+
+func migrateCommand() *cobra.Command {
+	migrateCmd.AddCommand(&migrateStatusCmd, &migrateUpCmd, &migrateDownCmd)
+	migrateCmd.PersistentFlags().BoolVar(&migrateDryRun, "dry-run", false, "Print the SQL of the migrations instead of running it")
+	migrateCmd.Flags().StringVar(&migrateTo, "to", "", "Apply the migrations up to and including this version")
+	migrateUpCmd.Flags().StringVar(&migrateTo, "to", "", "Apply the migrations up to and including this version")
+	migrateDownCmd.Flags().IntVar(&migrateSteps, "steps", 1, "Number of migrations to roll back")
+	return &migrateCmd
+}
+
+// openMigrations connects to the database for migrations and loads the
+// migrations embedded in the executable.
+func openMigrations(cmd *cobra.Command) (*pop.Connection, pop.MigrationBox) {
 	globalConfig := loadGlobalConfig(cmd.Context())
 	u, err := url.Parse(globalConfig.DB.URL)
 	if err != nil {
@@ -31,6 +75,13 @@ func migrate(cmd *cobra.Command, args []string) {
 		globalConfig.DB.Driver = u.Scheme
 	}
 
+	dialect := globalConfig.DB.Driver
+	if backend, err := storage.LookupBackend(globalConfig.DB.Driver); err != nil {
+		logrus.WithError(err).Warn("DEPRECATION NOTICE: only PostgreSQL compatible backends are supported by Supabase's GoTrue, will be removed soon")
+	} else {
+		dialect = backend.Dialect()
+	}
+
 	log := logrus.StandardLogger()
 
 	pop.Debug = false
@@ -55,9 +106,13 @@ func migrate(cmd *cobra.Command, args []string) {
 	q := u.Query()
 	q.Add("application_name", "auth_migrations")
 	u.RawQuery = q.Encode()
+	migrationsURL, err := storage.ConnectionURL(&globalConfig.DB, u.String())
+	if err != nil {
+		logrus.Fatalf("%+v", err)
+	}
 	deets := &pop.ConnectionDetails{
-		Dialect: globalConfig.DB.Driver,
-		URL:     u.String(),
+		Dialect: dialect,
+		URL:     migrationsURL,
 	}
 	deets.Options = map[string]string{
 		"migration_table_name": "schema_migrations",
@@ -68,7 +123,6 @@ func migrate(cmd *cobra.Command, args []string) {
 	if err != nil {
 		log.Fatalf("%+v", errors.Wrap(err, "opening db connection"))
 	}
-	defer db.Close()
 
 	if err := db.Open(); err != nil {
 		log.Fatalf("%+v", errors.Wrap(err, "checking database connection"))
@@ -80,21 +134,45 @@ func migrate(cmd *cobra.Command, args []string) {
 		log.Fatalf("%+v", errors.Wrap(err, "creating db migrator"))
 	}
 
+	// turn off schema dump
+	box.SchemaPath = ""
+
+	return db, box
+}
+
+func migrate(cmd *cobra.Command, args []string) {
+	log := logrus.StandardLogger()
+
+	db, box := openMigrations(cmd)
+	defer db.Close()
+
 	mig := box.Migrator
 
 	log.Debugf("before status")
 
 	if log.Level == logrus.DebugLevel {
-		err = mig.Status(os.Stdout)
+		err := mig.Status(os.Stdout)
 		if err != nil {
 			log.Fatalf("%+v", errors.Wrap(err, "migration status"))
 		}
 	}
 
-	// turn off schema dump
-	mig.SchemaPath = ""
+	pending, err := pendingMigrations(db, mig, migrateTo, migrateDryRun)
+	if err != nil {
+		log.Fatalf("%+v", err)
+	}
 
-	count, err := mig.UpTo(0)
+	if migrateDryRun {
+		for _, mf := range pending {
+			printMigration(box, db, mf, fmt.Sprintf("insert into %s (version) values ('%s');", db.MigrationTableName(), mf.Version))
+		}
+		return
+	}
+
+	count := 0
+	if len(pending) > 0 {
+		count, err = mig.UpTo(len(pending))
+	}
 	if err != nil {
 		log.Fatalf("%v", errors.Wrap(err, "running db migrations"))
 	} else {
@@ -110,3 +188,142 @@ func migrate(cmd *cobra.Command, args []string) {
 		}
 	}
 }
+
+func migrateStatus(cmd *cobra.Command, args []string) {
+	db, box := openMigrations(cmd)
+	defer db.Close()
+
+	if err := box.Status(os.Stdout); err != nil {
+		logrus.Fatalf("%+v", errors.Wrap(err, "migration status"))
+	}
+
+	pending, err := pendingMigrations(db, box.Migrator, "", false)
+	if err != nil {
+		logrus.Fatalf("%+v", err)
+	}
+	total := len(compatibleMigrations(db, box.UpMigrations.Migrations))
+	fmt.Printf("\n%d applied, %d pending\n", total-len(pending), len(pending))
+}
+
+type migrationVersion struct {
+	Version string `db:"version"`
+}
+
+func migrateDown(cmd *cobra.Command, args []string) {
+	log := logrus.StandardLogger()
+
+	db, box := openMigrations(cmd)
+	defer db.Close()
+
+	if migrateSteps < 1 {
+		log.Fatal("--steps must be at least 1")
+	}
+
+	if err := box.CreateSchemaMigrations(); err != nil {
+		log.Fatalf("%+v", err)
+	}
+
+	var applied []migrationVersion
+	mtn := db.MigrationTableName()
+	if err := db.RawQuery(fmt.Sprintf("select version from %s order by version desc limit ?", mtn), migrateSteps).All(&applied); err != nil {
+		log.Fatalf("%+v", errors.Wrap(err, "listing applied migrations"))
+	}
+
+	downs := compatibleMigrations(db, box.DownMigrations.Migrations)
+	rollback := make([]pop.Migration, 0, len(applied))
+	for _, v := range applied {
+		i := slices.IndexFunc(downs, func(mf pop.Migration) bool { return mf.Version == v.Version })
+		if i < 0 {
+			log.Fatalf("migration %s has no down migration and can not be rolled back", v.Version)
+		}
+		rollback = append(rollback, downs[i])
+	}
+
+	for _, mf := range rollback {
+		deleteVersion := fmt.Sprintf("delete from %s where version = '%s';", mtn, mf.Version)
+		if migrateDryRun {
+			printMigration(box, db, mf, deleteVersion)
+			continue
+		}
+
+		err := db.Transaction(func(tx *pop.Connection) error {
+			if err := mf.Run(tx); err != nil {
+				return err
+			}
+			return tx.RawQuery(deleteVersion).Exec()
+		})
+		if err != nil {
+			log.Fatalf("%v", errors.Wrap(err, "rolling back db migration"))
+		}
+		log.WithField("version", mf.Version).Infof("GoTrue migration %s rolled back", mf.Name)
+	}
+}
+
+// compatibleMigrations returns the migrations for the dialect of the
+// connection, sorted by version.
+func compatibleMigrations(db *pop.Connection, migrations pop.Migrations) pop.Migrations {
+	mfs := make(pop.Migrations, 0, len(migrations))
+	for _, mf := range migrations {
+		if mf.DBType == "all" || mf.DBType == db.Dialect.Name() {
+			mfs = append(mfs, mf)
+		}
+	}
+	sort.Slice(mfs, func(i, j int) bool { return mfs[i].Version < mfs[j].Version })
+	return mfs
+}
+
+// pendingMigrations returns the up migrations which are not applied yet, up
+// to and including the version to when it is not empty. The migrations table
+// is created when missing, unless for a dry run.
+func pendingMigrations(db *pop.Connection, mig pop.Migrator, to string, dryRun bool) (pop.Migrations, error) {
+	mfs := compatibleMigrations(db, mig.UpMigrations.Migrations)
+	if to != "" && !slices.ContainsFunc(mfs, func(mf pop.Migration) bool { return mf.Version == to }) {
+		return nil, fmt.Errorf("unknown migration version %s", to)
+	}
+
+	mtn := db.MigrationTableName()
+	_, err := db.Store.Exec(fmt.Sprintf("select version from %s limit 1", mtn))
+	hasTable := err == nil
+	if !hasTable && !dryRun {
+		if err := mig.CreateSchemaMigrations(); err != nil {
+			return nil, err
+		}
+		hasTable = true
+	}
+
+	var pending pop.Migrations
+	for _, mf := range mfs {
+		if to != "" && mf.Version > to {
+			break
+		}
+		if !hasTable {
+			pending = append(pending, mf)
+			continue
+		}
+		exists, err := db.Where("version = ?", mf.Version).Exists(mtn)
+		if err != nil {
+			return nil, errors.Wrapf(err, "checking for migration version %s", mf.Version)
+		}
+		if !exists {
+			pending = append(pending, mf)
+		}
+	}
+	return pending, nil
+}
+
+// printMigration prints the SQL of the migration, followed by the statement
+// recording it in the migrations table.
+func printMigration(box pop.MigrationBox, db *pop.Connection, mf pop.Migration, record string) {
+	f, err := box.FS.Open(mf.Path)
+	if err != nil {
+		logrus.Fatalf("%+v", errors.Wrapf(err, "opening migration %s", mf.Path))
+	}
+	defer f.Close()
+
+	content, err := pop.MigrationContent(mf, db, f, true)
+	if err != nil {
+		logrus.Fatalf("%+v", err)
+	}
+
+	fmt.Printf("-- %s\n%s\n%s\n\n", path.Base(mf.Path), strings.TrimSpace(content), record)
+}
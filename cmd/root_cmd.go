@@ -5,8 +5,13 @@ import (
 
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
+	"github.com/supabase/auth/internal/cache"
 	"github.com/supabase/auth/internal/conf"
+	"github.com/supabase/auth/internal/crypto"
 	"github.com/supabase/auth/internal/observability"
+
+	// Resolve secret references in the configuration.
+	_ "github.com/supabase/auth/internal/secrets"
 )
 
 var (
@@ -24,7 +29,7 @@ var rootCmd = cobra.Command{
 
 // RootCommand will setup and return the root command
 func RootCommand() *cobra.Command {
-	rootCmd.AddCommand(&serveCmd, &migrateCmd, &versionCmd, adminCmd())
+	rootCmd.AddCommand(&serveCmd, migrateCommand(), &versionCmd, adminCmd(), &cleanupCmd)
 	rootCmd.PersistentFlags().StringVarP(&configFile, "config", "c", "", "base configuration file to load")
 	rootCmd.PersistentFlags().StringVarP(&watchDir, "config-dir", "d", "", "directory containing a sorted list of config files to watch for changes")
 	return &rootCmd
@@ -55,6 +60,14 @@ func loadGlobalConfig(ctx context.Context) *conf.GlobalConfiguration {
 	if err := observability.ConfigureProfiler(ctx, &config.Profiler); err != nil {
 		logrus.WithError(err).Error("unable to configure profiler")
 	}
+
+	crypto.ConfigurePasswordHashing(&config.Password.Hashing)
+
+	// commands changing users or sessions invalidate them in the cache
+	if err := cache.Configure(&config.Cache); err != nil {
+		logrus.WithError(err).Fatal("unable to configure cache")
+	}
+
 	return config
 }
 
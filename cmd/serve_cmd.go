@@ -4,6 +4,7 @@ import (
 	"context"
 	"net"
 	"net/http"
+	"os/signal"
 	"sync"
 	"syscall"
 	"time"
@@ -15,8 +16,14 @@ import (
 	"github.com/spf13/cobra"
 	"github.com/supabase/auth/internal/api"
 	"github.com/supabase/auth/internal/api/apiworker"
+	"github.com/supabase/auth/internal/auditsink"
+	"github.com/supabase/auth/internal/cache"
 	"github.com/supabase/auth/internal/conf"
+	"github.com/supabase/auth/internal/crypto"
+	"github.com/supabase/auth/internal/mailer/queueclient"
 	"github.com/supabase/auth/internal/mailer/templatemailer"
+	"github.com/supabase/auth/internal/models"
+	"github.com/supabase/auth/internal/observability"
 	"github.com/supabase/auth/internal/reloader"
 	"github.com/supabase/auth/internal/storage"
 	"github.com/supabase/auth/internal/utilities"
@@ -44,20 +51,49 @@ func serve(ctx context.Context) {
 		logrus.WithError(err).Fatal("unable to load config")
 	}
 
+	crypto.ConfigurePasswordHashing(&config.Password.Hashing)
+
+	sinkLog := logrus.WithField("component", "auditsink")
+	if err := auditsink.Configure(&config.AuditLog.Sinks, sinkLog); err != nil {
+		logrus.WithError(err).Fatal("unable to configure audit log sinks")
+	}
+
+	if err := cache.Configure(&config.Cache); err != nil {
+		logrus.WithError(err).Fatal("unable to configure cache")
+	}
+
 	// Include serve ctx which carries cancelation signals so DialContext does
 	// not hang indefinitely at startup.
 	db, err := storage.DialContext(ctx, config)
 	if err != nil {
 		logrus.Fatalf("error opening database: %+v", err)
 	}
-	defer db.Close()
+	defer func() {
+		if err := db.Close(); err != nil {
+			logrus.WithError(err).Error("error closing database connection")
+		}
+	}()
+
+	if config.Metrics.Enabled {
+		if err := observability.ObtainCachedGauge(
+			"gotrue_active_sessions",
+			"Number of sessions that have not ended",
+			config.Metrics.ActiveSessionsInterval,
+			func(ctx context.Context) (int64, error) {
+				count, err := models.CountActiveSessions(db.WithContext(ctx), time.Now())
+				return int64(count), err
+			},
+		); err != nil {
+			logrus.WithError(err).Error("unable to get gotrue.gotrue_active_sessions gauge metric")
+		}
+	}
 
 	baseCtx, baseCancel := context.WithCancel(context.Background())
 	defer baseCancel()
 
 	// Add the base context to the db, this is so during the shutdown sequence
 	// the DB will be available while connections drain.
-	db = db.WithContext(ctx)
+	db = db.WithContext(baseCtx)
 
 	var wg sync.WaitGroup
 	defer wg.Wait() // Do not return to caller until this goroutine is done.
@@ -67,13 +103,80 @@ func serve(ctx context.Context) {
 	initialAPI := api.NewAPIWithVersion(
 		config, db, utilities.Version,
 		limiterOpts,
-		api.WithMailer(templatemailer.FromConfig(config, mrCache)),
+		api.WithMailer(newMailer(config, db, mrCache)),
 	)
 
 	addr := net.JoinHostPort(config.API.Host, config.API.Port)
 	logrus.WithField("version", initialAPI.Version()).Infof("GoTrue API started on: %s", addr)
 
-	ah := reloader.NewAtomicHandler(initialAPI)
+	var tenantRouter *api.TenantRouter
+	var handler http.Handler = initialAPI
+	if config.Tenancy.Enabled {
+		tenantRouter = api.NewTenantRouter(initialAPI, func(tenant *models.Tenant, tenantConfig *conf.GlobalConfiguration, tenantDB *storage.Connection) *api.API {
+			// Tenants get their own mailer and rate limiters. Their mail
+			// is not queued as the worker only sends the deployment's.
+			return api.NewAPIWithVersion(
+				tenantConfig, tenantDB, utilities.Version,
+				api.WithTenantID(tenant.ID),
+				api.WithMailer(
+					templatemailer.FromConfig(tenantConfig, mrCache).WithDatabase(tenantDB, &tenant.ID),
+				),
+			)
+		})
+		defer tenantRouter.Close()
+
+		handler = tenantRouter
+	}
+
+	var adminSvc *api.AdminService
+	if config.GRPC.Enabled {
+		adminSvc = api.NewAdminService(initialAPI)
+		grpcSrv, err := api.NewGRPCServer(&config.GRPC, adminSvc)
+		if err != nil {
+			logrus.WithError(err).Fatal("unable to create gRPC server")
+		}
+
+		grpcAddr := net.JoinHostPort(config.GRPC.Host, config.GRPC.Port)
+		grpcListener, err := net.Listen("tcp", grpcAddr)
+		if err != nil {
+			logrus.WithError(err).Fatal("gRPC server listen failed")
+		}
+		logrus.Infof("GoTrue gRPC admin API started on: %s", grpcAddr)
+
+		grpcLog := logrus.WithField("component", "grpc")
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			if err := grpcSrv.Serve(grpcListener); err != nil {
+				grpcLog.WithError(err).Error("gRPC server serve failed")
+			}
+		}()
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			<-ctx.Done()
+
+			// In-flight calls are drained like http requests, and
+			// canceled once the shutdown timeout passed.
+			stopped := make(chan struct{})
+			go func() {
+				defer close(stopped)
+				grpcSrv.GracefulStop()
+			}()
+			select {
+			case <-stopped:
+			case <-time.After(config.API.ShutdownDelay + config.API.ShutdownTimeout):
+				grpcSrv.Stop()
+				<-stopped
+			}
+			grpcLog.Info("gRPC server closed")
+		}()
+	}
+
+	ah := reloader.NewAtomicHandler(handler)
 	httpSrv := &http.Server{
 		Addr:              addr,
 		Handler:           ah,
@@ -105,6 +208,12 @@ func serve(ctx context.Context) {
 	}()
 
 	if watchDir != "" {
+		// SIGHUP stops the server, unless it is the signal reloading the
+		// configuration.
+		if config.Reloading.SignalEnabled && config.Reloading.SignalNumber == int(syscall.SIGHUP) {
+			signal.Reset(syscall.SIGHUP)
+		}
+
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
@@ -130,28 +239,48 @@ func serve(ctx context.Context) {
 				exitFn("config reloader is exiting")
 			}()
 
+			currentCfg := config
 			fn := func(latestCfg *conf.GlobalConfiguration) {
 				le.Info("reloading api with new configuration")
 
+				if settings := restartSettings(currentCfg, latestCfg); len(settings) > 0 {
+					le.WithField("settings", settings).Warn("reloaded configuration changes settings which only apply on restart")
+				}
+				limiterOpts = limiterOpts.Reload(currentCfg, latestCfg)
+				currentCfg = latestCfg
+
 				// When config is updated we notify the apiworker.
 				wrk.ReloadConfig(latestCfg)
 
+				crypto.ConfigurePasswordHashing(&latestCfg.Password.Hashing)
+
+				if err := auditsink.Configure(&latestCfg.AuditLog.Sinks, sinkLog); err != nil {
+					le.WithError(err).Error("unable to configure audit log sinks, keeping the previous sinks")
+				}
+
+				if err := cache.Configure(&latestCfg.Cache); err != nil {
+					le.WithError(err).Error("unable to configure cache, keeping the previous cache")
+				}
+
 				// Create a new API version with the updated config.
 				latestAPI := api.NewAPIWithVersion(
 					latestCfg, db, utilities.Version,
 
 					// Create a new mailer with existing template cache.
 					api.WithMailer(
-						templatemailer.FromConfig(latestCfg, mrCache),
+						newMailer(latestCfg, db, mrCache),
 					),
 
-					// Persist existing rate limiters.
-					//
-					// TODO(cstockton): we should consider updating these, if we
-					// rely on hot config reloads 100% then rate limiter changes
-					// won't be picked up.
+					// Persist the rate limiters whose rate did not change.
 					limiterOpts,
 				)
+				if adminSvc != nil {
+					adminSvc.Reload(latestAPI)
+				}
+				if tenantRouter != nil {
+					tenantRouter.Reload(latestAPI)
+					return
+				}
 				ah.Store(latestAPI)
 			}
 
@@ -173,12 +302,29 @@ func serve(ctx context.Context) {
 		// is canceled.
 		defer baseCancel()
 
-		shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), time.Minute)
+		// Fail readiness checks while still accepting requests, so load
+		// balancers stop routing requests here before the listener closes.
+		api.SetShuttingDown()
+		if delay := config.API.ShutdownDelay; delay > 0 {
+			log.WithField("shutdown_delay", delay.String()).Info("waiting before draining in-flight requests")
+			time.Sleep(delay)
+		}
+
+		shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), config.API.ShutdownTimeout)
 		defer shutdownCancel()
 
+		log.WithField("shutdown_timeout", config.API.ShutdownTimeout.String()).Info("draining in-flight requests")
 		if err := httpSrv.Shutdown(shutdownCtx); err != nil && !errors.Is(err, context.Canceled) {
 			log.WithError(err).Error("shutdown failed")
 		}
+
+		// Send the webhook events queued by the drained requests.
+		wrk.FlushWebhooks(shutdownCtx)
+
+		// Send the audit log entries of the drained requests.
+		if err := auditsink.Close(shutdownCtx); err != nil {
+			sinkLog.WithError(err).Error("failed to send buffered audit log entries")
+		}
 	}()
 
 	lc := net.ListenConfig{
@@ -203,3 +349,35 @@ func serve(ctx context.Context) {
 		log.WithError(err).Fatal("http server serve failed")
 	}
 }
+
+// restartSettings returns the settings changed by a reload of the
+// configuration which only apply when the server restarts.
+func restartSettings(prev, next *conf.GlobalConfiguration) []string {
+	var settings []string
+	if prev.DB.Driver != next.DB.Driver || prev.DB.URL != next.DB.URL || prev.DB.ReplicaURL != next.DB.ReplicaURL {
+		settings = append(settings, "db")
+	}
+	if prev.API.Host != next.API.Host || prev.API.Port != next.API.Port {
+		settings = append(settings, "api")
+	}
+	if prev.GRPC.Enabled != next.GRPC.Enabled || prev.GRPC.Host != next.GRPC.Host || prev.GRPC.Port != next.GRPC.Port {
+		settings = append(settings, "grpc")
+	}
+	if prev.Tenancy.Enabled != next.Tenancy.Enabled {
+		settings = append(settings, "tenancy")
+	}
+	store, nextStore := prev.RateLimitStore, next.RateLimitStore
+	if store.Type != nextStore.Type || store.RedisURL != nextStore.RedisURL || store.RedisKeyPrefix != nextStore.RedisKeyPrefix {
+		settings = append(settings, "rate_limit_store")
+	}
+	return settings
+}
+
+// newMailer returns the mailer of the deployment, which adds mail to the
+// queue in db for the apiworker to send when the mailer queue is enabled.
+func newMailer(config *conf.GlobalConfiguration, db *storage.Connection, tc *templatemailer.Cache) *templatemailer.Mailer {
+	if config.Mailer.Queue.Enabled {
+		return templatemailer.FromQueue(config, queueclient.New(db), tc).WithDatabase(db, nil)
+	}
+	return templatemailer.FromConfig(config, tc).WithDatabase(db, nil)
+}
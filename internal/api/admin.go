@@ -2,7 +2,11 @@ package api
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 	"net/http"
+	"net/url"
+	"strconv"
 	"time"
 
 	"github.com/fatih/structs"
@@ -12,10 +16,12 @@ import (
 	"github.com/sethvargo/go-password/password"
 	"github.com/supabase/auth/internal/api/apierrors"
 	"github.com/supabase/auth/internal/api/provider"
+	"github.com/supabase/auth/internal/conf"
 	"github.com/supabase/auth/internal/models"
 	"github.com/supabase/auth/internal/observability"
 	"github.com/supabase/auth/internal/storage"
 	"github.com/supabase/auth/internal/utilities"
+	"github.com/supabase/auth/internal/webhooks"
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -36,6 +42,11 @@ type AdminUserParams struct {
 
 type adminUserDeleteParams struct {
 	ShouldSoftDelete bool `json:"should_soft_delete"`
+
+	// ShouldAnonymize erases the personal data of a soft deleted user right
+	// away, which is the default. Otherwise the data is kept until the user
+	// is purged, and the user can be restored until then.
+	ShouldAnonymize *bool `json:"should_anonymize"`
 }
 
 type adminUserUpdateFactorParams struct {
@@ -49,10 +60,20 @@ type AdminListUsersResponse struct {
 }
 
 func (a *API) loadUser(w http.ResponseWriter, r *http.Request) (context.Context, error) {
-	ctx := r.Context()
-	db := a.db.WithContext(ctx)
+	u, err := a.findAdminUser(r, chi.URLParam(r, "user_id"))
+	if err != nil {
+		return nil, err
+	}
+
+	return withUser(r.Context(), u), nil
+}
 
-	userID, err := uuid.FromString(chi.URLParam(r, "user_id"))
+// findAdminUser finds the user of an admin request by its ID. It is shared by
+// the REST and gRPC admin APIs.
+func (a *API) findAdminUser(r *http.Request, id string) (*models.User, error) {
+	db := a.db.WithContext(r.Context()).Reader()
+
+	userID, err := uuid.FromString(id)
 	if err != nil {
 		return nil, apierrors.NewNotFoundError(apierrors.ErrorCodeValidationFailed, "user_id must be an UUID")
 	}
@@ -67,7 +88,7 @@ func (a *API) loadUser(w http.ResponseWriter, r *http.Request) (context.Context,
 		return nil, apierrors.NewInternalServerError("Database error loading user").WithInternalError(err)
 	}
 
-	return withUser(ctx, u), nil
+	return u, nil
 }
 
 // Use only after requireAuthentication, so that there is a valid user
@@ -101,34 +122,162 @@ func (a *API) getAdminParams(r *http.Request) (*AdminUserParams, error) {
 	return params, nil
 }
 
-// adminUsers responds with a list of all users in a given audience
-func (a *API) adminUsers(w http.ResponseWriter, r *http.Request) error {
+// parseAdminUserFilter parses the filter, email, phone, provider,
+// confirmed, banned, created_after, created_before, last_sign_in_after,
+// last_sign_in_before, user_metadata and app_metadata query parameters of a
+// user search.
+func parseAdminUserFilter(query url.Values) (*models.UserFilter, error) {
+	filter := &models.UserFilter{
+		Query:    query.Get("filter"),
+		Email:    query.Get("email"),
+		Phone:    query.Get("phone"),
+		Provider: query.Get("provider"),
+	}
+
+	for name, dst := range map[string]**bool{
+		"confirmed": &filter.Confirmed,
+		"banned":    &filter.Banned,
+	} {
+		if value := query.Get(name); value != "" {
+			b, err := strconv.ParseBool(value)
+			if err != nil {
+				return nil, apierrors.NewBadRequestError(apierrors.ErrorCodeValidationFailed, "%s must be true or false", name)
+			}
+			*dst = &b
+		}
+	}
+
+	for name, dst := range map[string]**time.Time{
+		"created_after":       &filter.CreatedAfter,
+		"created_before":      &filter.CreatedBefore,
+		"last_sign_in_after":  &filter.LastSignInAfter,
+		"last_sign_in_before": &filter.LastSignInBefore,
+	} {
+		if value := query.Get(name); value != "" {
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				return nil, apierrors.NewBadRequestError(apierrors.ErrorCodeValidationFailed, "%s must be an RFC 3339 timestamp", name)
+			}
+			*dst = &t
+		}
+	}
+
+	for name, dst := range map[string]*map[string]interface{}{
+		"user_metadata": &filter.UserMetadata,
+		"app_metadata":  &filter.AppMetadata,
+	} {
+		if value := query.Get(name); value != "" {
+			if err := json.Unmarshal([]byte(value), dst); err != nil || *dst == nil {
+				return nil, apierrors.NewBadRequestError(apierrors.ErrorCodeValidationFailed, "%s must be a JSON object", name)
+			}
+		}
+	}
+
+	return filter, nil
+}
+
+// adminUsersPage is a page of a user search.
+type adminUsersPage struct {
+	AdminListUsersResponse
+
+	// Pagination is set with offset pagination.
+	Pagination *models.Pagination
+
+	// Next is the ID of the last user with keyset pagination when the page
+	// is full, the next page is listed after it.
+	Next *uuid.UUID
+}
+
+// findAdminUsers lists the users of the audience of the request with its
+// pagination, sort and filter query parameters. It is shared by the REST and
+// gRPC admin APIs.
+func (a *API) findAdminUsers(r *http.Request) (*adminUsersPage, error) {
 	ctx := r.Context()
-	db := a.db.WithContext(ctx)
+	db := a.db.WithContext(ctx).Reader()
 	aud := a.requestAud(ctx, r)
+	query := r.URL.Query()
 
 	pageParams, err := paginate(r)
 	if err != nil {
-		return apierrors.NewBadRequestError(apierrors.ErrorCodeValidationFailed, "Bad Pagination Parameters: %v", err).WithInternalError(err)
+		return nil, apierrors.NewBadRequestError(apierrors.ErrorCodeValidationFailed, "Bad Pagination Parameters: %v", err).WithInternalError(err)
+	}
+
+	sortParams, err := sort(r, models.UserSortFields(), []models.SortField{{Name: models.CreatedAt, Dir: models.Descending}})
+	if err != nil {
+		return nil, apierrors.NewBadRequestError(apierrors.ErrorCodeValidationFailed, "Bad Sort Parameters: %v", err)
 	}
 
-	sortParams, err := sort(r, map[string]bool{models.CreatedAt: true}, []models.SortField{{Name: models.CreatedAt, Dir: models.Descending}})
+	filter, err := parseAdminUserFilter(query)
 	if err != nil {
-		return apierrors.NewBadRequestError(apierrors.ErrorCodeValidationFailed, "Bad Sort Parameters: %v", err)
+		return nil, err
+	}
+
+	page := &adminUsersPage{
+		AdminListUsersResponse: AdminListUsersResponse{Aud: aud},
+	}
+
+	if !query.Has("after") {
+		page.Users, err = models.SearchUsersInAudience(db, aud, filter, pageParams, sortParams)
+		if err != nil {
+			return nil, apierrors.NewInternalServerError("Database error finding users").WithInternalError(err)
+		}
+		page.Pagination = pageParams
+		return page, nil
+	}
+
+	// With keyset pagination, the first page is requested with an empty
+	// after query parameter, the next page by passing the ID of the last
+	// user received. The total is not counted.
+	if pageParams.PerPage == 0 {
+		return nil, apierrors.NewBadRequestError(apierrors.ErrorCodeValidationFailed, "Bad Pagination Parameters: per_page must be positive")
 	}
 
-	filter := r.URL.Query().Get("filter")
+	var afterID *uuid.UUID
+	if after := query.Get("after"); after != "" {
+		id, err := uuid.FromString(after)
+		if err != nil {
+			return nil, apierrors.NewBadRequestError(apierrors.ErrorCodeValidationFailed, "after must be an UUID")
+		}
+		if _, err := models.FindUserByID(db, id); err != nil {
+			if models.IsNotFoundError(err) {
+				return nil, apierrors.NewBadRequestError(apierrors.ErrorCodeValidationFailed, "User in after not found")
+			}
+			return nil, apierrors.NewInternalServerError("Database error finding users").WithInternalError(err)
+		}
+		afterID = &id
+	}
 
-	users, err := models.FindUsersInAudience(db, aud, pageParams, sortParams, filter)
+	page.Users, err = models.SearchUsersInAudienceAfter(db, aud, filter, sortParams, afterID, int(pageParams.PerPage)) // #nosec G115
 	if err != nil {
-		return apierrors.NewInternalServerError("Database error finding users").WithInternalError(err)
+		return nil, apierrors.NewInternalServerError("Database error finding users").WithInternalError(err)
+	}
+	if uint64(len(page.Users)) == pageParams.PerPage {
+		page.Next = &page.Users[len(page.Users)-1].ID
 	}
-	addPaginationHeaders(w, r, pageParams)
 
-	return sendJSON(w, http.StatusOK, AdminListUsersResponse{
-		Users: users,
-		Aud:   aud,
-	})
+	return page, nil
+}
+
+// adminUsers responds with a list of all users in a given audience. With
+// keyset pagination, when the after query parameter is present, the next
+// page is in the Link header of the response.
+func (a *API) adminUsers(w http.ResponseWriter, r *http.Request) error {
+	page, err := a.findAdminUsers(r)
+	if err != nil {
+		return err
+	}
+
+	if page.Pagination != nil {
+		addPaginationHeaders(w, r, page.Pagination)
+	} else if page.Next != nil {
+		next, _ := url.ParseRequestURI(r.URL.String())
+		nextQuery := next.Query()
+		nextQuery.Set("after", page.Next.String())
+		next.RawQuery = nextQuery.Encode()
+		w.Header().Add("Link", fmt.Sprintf("<%s>; rel=\"next\"", next.String()))
+	}
+
+	return sendJSON(w, http.StatusOK, page.AdminListUsersResponse)
 }
 
 // adminUserGet returns information about a single user
@@ -140,16 +289,28 @@ func (a *API) adminUserGet(w http.ResponseWriter, r *http.Request) error {
 
 // adminUserUpdate updates a single user object
 func (a *API) adminUserUpdate(w http.ResponseWriter, r *http.Request) error {
-	ctx := r.Context()
-	db := a.db.WithContext(ctx)
-	config := a.config
-	user := getUser(ctx)
-	adminUser := getAdminUser(ctx)
+	user := getUser(r.Context())
 	params, err := a.getAdminParams(r)
 	if err != nil {
 		return err
 	}
 
+	if err := a.updateAdminUser(r, user, params); err != nil {
+		return err
+	}
+
+	return sendJSON(w, http.StatusOK, user)
+}
+
+// updateAdminUser updates the user with the params of an admin request. It
+// is shared by the REST and gRPC admin APIs.
+func (a *API) updateAdminUser(r *http.Request, user *models.User, params *AdminUserParams) error {
+	ctx := r.Context()
+	db := a.db.WithContext(ctx)
+	config := a.config
+	adminUser := getAdminUser(ctx)
+
+	var err error
 	if params.Email != "" {
 		params.Email, err = a.validateEmail(params.Email)
 		if err != nil {
@@ -179,7 +340,7 @@ func (a *API) adminUserUpdate(w http.ResponseWriter, r *http.Request) error {
 	if params.Password != nil {
 		password := *params.Password
 
-		if err := a.checkPasswordStrength(ctx, password); err != nil {
+		if err := a.checkPasswordStrength(ctx, password, user.GetEmail(), params.Email); err != nil {
 			return err
 		}
 
@@ -318,40 +479,52 @@ func (a *API) adminUserUpdate(w http.ResponseWriter, r *http.Request) error {
 		return apierrors.NewInternalServerError("Error updating user").WithInternalError(err)
 	}
 
-	return sendJSON(w, http.StatusOK, user)
+	return nil
 }
 
 // adminUserCreate creates a new user based on the provided data
 func (a *API) adminUserCreate(w http.ResponseWriter, r *http.Request) error {
-	ctx := r.Context()
-	db := a.db.WithContext(ctx)
-	config := a.config
-
-	adminUser := getAdminUser(ctx)
 	params, err := a.getAdminParams(r)
 	if err != nil {
 		return err
 	}
 
+	user, err := a.createAdminUser(r, params)
+	if err != nil {
+		return err
+	}
+
+	return sendJSON(w, http.StatusOK, user)
+}
+
+// createAdminUser creates a user with the params of an admin request. It is
+// shared by the REST and gRPC admin APIs.
+func (a *API) createAdminUser(r *http.Request, params *AdminUserParams) (*models.User, error) {
+	ctx := r.Context()
+	db := a.db.WithContext(ctx)
+	config := a.config
+	adminUser := getAdminUser(ctx)
+
 	aud := a.requestAud(ctx, r)
 	if params.Aud != "" {
 		aud = params.Aud
 	}
 
 	if params.Email == "" && params.Phone == "" {
-		return apierrors.NewBadRequestError(apierrors.ErrorCodeValidationFailed, "Cannot create a user without either an email or phone")
+		return nil, apierrors.NewBadRequestError(apierrors.ErrorCodeValidationFailed, "Cannot create a user without either an email or phone")
 	}
 
+	var err error
 	var providers []string
 	if params.Email != "" {
 		params.Email, err = a.validateEmail(params.Email)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		if user, err := models.IsDuplicatedEmail(db, params.Email, aud, nil, config.Experimental.ProvidersWithOwnLinkingDomain); err != nil {
-			return apierrors.NewInternalServerError("Database error checking email").WithInternalError(err)
+			return nil, apierrors.NewInternalServerError("Database error checking email").WithInternalError(err)
 		} else if user != nil {
-			return apierrors.NewUnprocessableEntityError(apierrors.ErrorCodeEmailExists, DuplicateEmailMsg)
+			return nil, apierrors.NewUnprocessableEntityError(apierrors.ErrorCodeEmailExists, DuplicateEmailMsg)
 		}
 		providers = append(providers, "email")
 	}
@@ -359,24 +532,24 @@ func (a *API) adminUserCreate(w http.ResponseWriter, r *http.Request) error {
 	if params.Phone != "" {
 		params.Phone, err = validatePhone(params.Phone)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		if exists, err := models.IsDuplicatedPhone(db, params.Phone, aud); err != nil {
-			return apierrors.NewInternalServerError("Database error checking phone").WithInternalError(err)
+			return nil, apierrors.NewInternalServerError("Database error checking phone").WithInternalError(err)
 		} else if exists {
-			return apierrors.NewUnprocessableEntityError(apierrors.ErrorCodePhoneExists, "Phone number already registered by another user")
+			return nil, apierrors.NewUnprocessableEntityError(apierrors.ErrorCodePhoneExists, "Phone number already registered by another user")
 		}
 		providers = append(providers, "phone")
 	}
 
 	if params.Password != nil && params.PasswordHash != "" {
-		return apierrors.NewBadRequestError(apierrors.ErrorCodeValidationFailed, "Only a password or a password hash should be provided")
+		return nil, apierrors.NewBadRequestError(apierrors.ErrorCodeValidationFailed, "Only a password or a password hash should be provided")
 	}
 
 	if (params.Password == nil || *params.Password == "") && params.PasswordHash == "" {
 		password, err := password.Generate(64, 10, 0, false, true)
 		if err != nil {
-			return apierrors.NewInternalServerError("Error generating password").WithInternalError(err)
+			return nil, apierrors.NewInternalServerError("Error generating password").WithInternalError(err)
 		}
 		params.Password = &password
 	}
@@ -390,18 +563,18 @@ func (a *API) adminUserCreate(w http.ResponseWriter, r *http.Request) error {
 
 	if err != nil {
 		if errors.Is(err, bcrypt.ErrPasswordTooLong) {
-			return apierrors.NewBadRequestError(apierrors.ErrorCodeValidationFailed, "%s", err.Error())
+			return nil, apierrors.NewBadRequestError(apierrors.ErrorCodeValidationFailed, "%s", err.Error())
 		}
-		return apierrors.NewInternalServerError("Error creating user").WithInternalError(err)
+		return nil, apierrors.NewInternalServerError("Error creating user").WithInternalError(err)
 	}
 
 	if params.Id != "" {
 		customId, err := uuid.FromString(params.Id)
 		if err != nil {
-			return apierrors.NewBadRequestError(apierrors.ErrorCodeValidationFailed, "ID must conform to the uuid v4 format")
+			return nil, apierrors.NewBadRequestError(apierrors.ErrorCodeValidationFailed, "ID must conform to the uuid v4 format")
 		}
 		if customId == uuid.Nil {
-			return apierrors.NewBadRequestError(apierrors.ErrorCodeValidationFailed, "ID cannot be a nil uuid")
+			return nil, apierrors.NewBadRequestError(apierrors.ErrorCodeValidationFailed, "ID cannot be a nil uuid")
 		}
 		user.ID = customId
 	}
@@ -419,7 +592,7 @@ func (a *API) adminUserCreate(w http.ResponseWriter, r *http.Request) error {
 		if params.BanDuration != "none" {
 			duration, err = time.ParseDuration(params.BanDuration)
 			if err != nil {
-				return apierrors.NewBadRequestError(apierrors.ErrorCodeValidationFailed, "invalid format for ban duration: %v", err)
+				return nil, apierrors.NewBadRequestError(apierrors.ErrorCodeValidationFailed, "invalid format for ban duration: %v", err)
 			}
 		}
 		banDuration = &duration
@@ -498,23 +671,23 @@ func (a *API) adminUserCreate(w http.ResponseWriter, r *http.Request) error {
 			}
 		}
 
+		if terr := webhooks.Emit(tx, &config.Webhooks, conf.WebhookEventUserCreated, user, nil); terr != nil {
+			return terr
+		}
+
 		return nil
 	})
 
 	if err != nil {
-		return apierrors.NewInternalServerError("Database error creating new user").WithInternalError(err)
+		return nil, apierrors.NewInternalServerError("Database error creating new user").WithInternalError(err)
 	}
 
-	return sendJSON(w, http.StatusOK, user)
+	return user, nil
 }
 
 // adminUserDelete deletes a user
 func (a *API) adminUserDelete(w http.ResponseWriter, r *http.Request) error {
-	ctx := r.Context()
-	user := getUser(ctx)
-	config := a.config
-	adminUser := getAdminUser(ctx)
-	db := a.db.WithContext(ctx)
+	user := getUser(r.Context())
 
 	// ShouldSoftDelete defaults to false
 	params := &adminUserDeleteParams{}
@@ -526,8 +699,31 @@ func (a *API) adminUserDelete(w http.ResponseWriter, r *http.Request) error {
 		}
 	}
 
+	shouldAnonymize := params.ShouldAnonymize == nil || *params.ShouldAnonymize
+	if err := a.deleteAdminUser(r, user, params.ShouldSoftDelete, shouldAnonymize); err != nil {
+		return err
+	}
+
+	return sendJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+// deleteAdminUser deletes the user, or soft deletes it, erasing its data
+// when shouldAnonymize is set. It is shared by the REST and gRPC admin APIs.
+func (a *API) deleteAdminUser(r *http.Request, user *models.User, shouldSoftDelete, shouldAnonymize bool) error {
+	return a.deleteUser(r, getAdminUser(r.Context()), user, shouldSoftDelete, shouldAnonymize)
+}
+
+// deleteUser deletes the user on behalf of actor, or soft deletes it,
+// erasing its data when shouldAnonymize is set. Soft deleted users are
+// purged after the grace period when purging is enabled.
+func (a *API) deleteUser(r *http.Request, actor, user *models.User, shouldSoftDelete, shouldAnonymize bool) error {
+	ctx := r.Context()
+	config := a.config
+	db := a.db.WithContext(ctx)
+
+	deleted := false
 	err := db.Transaction(func(tx *storage.Connection) error {
-		if terr := models.NewAuditLogEntry(config.AuditLog, r, tx, adminUser, models.UserDeletedAction, "", map[string]interface{}{
+		if terr := models.NewAuditLogEntry(config.AuditLog, r, tx, actor, models.UserDeletedAction, "", map[string]interface{}{
 			"user_id":    user.ID,
 			"user_email": user.Email,
 			"user_phone": user.Phone,
@@ -535,40 +731,570 @@ func (a *API) adminUserDelete(w http.ResponseWriter, r *http.Request) error {
 			return apierrors.NewInternalServerError("Error recording audit log entry").WithInternalError(terr)
 		}
 
-		if params.ShouldSoftDelete {
-			if user.DeletedAt != nil {
-				// user has been soft deleted already
+		if shouldSoftDelete {
+			softDeleted := user.DeletedAt != nil
+			if softDeleted && (!shouldAnonymize || user.IsAnonymized) {
+				// user has been soft deleted already, and its data erased
+				// when requested
 				return nil
 			}
-			if terr := user.SoftDeleteUser(tx); terr != nil {
-				return apierrors.NewInternalServerError("Error soft deleting user").WithInternalError(terr)
-			}
+			if shouldAnonymize {
+				if terr := user.SoftDeleteUser(tx); terr != nil {
+					return apierrors.NewInternalServerError("Error soft deleting user").WithInternalError(terr)
+				}
 
-			if terr := user.SoftDeleteUserIdentities(tx); terr != nil {
-				return apierrors.NewInternalServerError("Error soft deleting user identities").WithInternalError(terr)
-			}
+				if terr := user.SoftDeleteUserIdentities(tx); terr != nil {
+					return apierrors.NewInternalServerError("Error soft deleting user identities").WithInternalError(terr)
+				}
 
-			// hard delete all associated factors
-			if terr := models.DeleteFactorsByUserId(tx, user.ID); terr != nil {
-				return apierrors.NewInternalServerError("Error deleting user's factors").WithInternalError(terr)
+				// hard delete all associated factors
+				if terr := models.DeleteFactorsByUserId(tx, user.ID); terr != nil {
+					return apierrors.NewInternalServerError("Error deleting user's factors").WithInternalError(terr)
+				}
 			}
 			// hard delete all associated sessions
 			if terr := models.Logout(tx, user.ID); terr != nil {
 				return apierrors.NewInternalServerError("Error deleting user's sessions").WithInternalError(terr)
 			}
+
+			// erasing the data of a soft deleted user keeps its scheduled
+			// purge
+			if !softDeleted {
+				var purgeAfter *time.Time
+				if config.UserDeletion.PurgeEnabled {
+					t := time.Now().Add(config.UserDeletion.GracePeriod)
+					purgeAfter = &t
+				}
+				if terr := user.ScheduleDeletion(tx, purgeAfter); terr != nil {
+					return apierrors.NewInternalServerError("Error scheduling user purge").WithInternalError(terr)
+				}
+			}
 		} else {
 			if terr := tx.Destroy(user); terr != nil {
 				return apierrors.NewInternalServerError("Database error deleting user").WithInternalError(terr)
 			}
 		}
 
+		if terr := webhooks.Emit(tx, &config.Webhooks, conf.WebhookEventUserDeleted, user, map[string]interface{}{
+			"soft_deleted": shouldSoftDelete,
+		}); terr != nil {
+			return apierrors.NewInternalServerError("Error queueing webhook event").WithInternalError(terr)
+		}
+
+		deleted = true
+		return nil
+	})
+	if err != nil || !deleted {
+		return err
+	}
+
+	// the hook is only triggered once the deletion is committed, as hooks can
+	// not be triggered during a transaction
+	return a.triggerAfterUserDeleted(r, db, user, shouldSoftDelete)
+}
+
+// adminUserPurge permanently deletes a soft deleted user right away,
+// without waiting for the grace period to end.
+func (a *API) adminUserPurge(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	user := getUser(ctx)
+	config := a.config
+	adminUser := getAdminUser(ctx)
+	db := a.db.WithContext(ctx)
+
+	if user.DeletedAt == nil {
+		return apierrors.NewUnprocessableEntityError(apierrors.ErrorCodeUserNotDeleted, "Only soft deleted users can be purged")
+	}
+
+	err := db.Transaction(func(tx *storage.Connection) error {
+		if terr := models.NewAuditLogEntry(config.AuditLog, r, tx, adminUser, models.UserPurgedAction, "", map[string]interface{}{
+			"user_id":     user.ID,
+			"deleted_at":  user.DeletedAt,
+			"purge_after": user.PurgeAfter,
+		}); terr != nil {
+			return apierrors.NewInternalServerError("Error recording audit log entry").WithInternalError(terr)
+		}
+
+		if terr := tx.Destroy(user); terr != nil {
+			return apierrors.NewInternalServerError("Database error deleting user").WithInternalError(terr)
+		}
 		return nil
 	})
 	if err != nil {
 		return err
 	}
 
-	return sendJSON(w, http.StatusOK, map[string]interface{}{})
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+// adminUserCancelPurge cancels the purge of a soft deleted user. Users whose
+// data was kept are restored and can sign in again, anonymized users stay
+// soft deleted.
+func (a *API) adminUserCancelPurge(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	user := getUser(ctx)
+	config := a.config
+	adminUser := getAdminUser(ctx)
+	db := a.db.WithContext(ctx)
+
+	if user.DeletedAt == nil {
+		return apierrors.NewUnprocessableEntityError(apierrors.ErrorCodeUserNotDeleted, "User has not been soft deleted")
+	}
+
+	err := db.Transaction(func(tx *storage.Connection) error {
+		if terr := models.NewAuditLogEntry(config.AuditLog, r, tx, adminUser, models.UserDeletionCancelledAction, "", map[string]interface{}{
+			"user_id":       user.ID,
+			"is_anonymized": user.IsAnonymized,
+		}); terr != nil {
+			return apierrors.NewInternalServerError("Error recording audit log entry").WithInternalError(terr)
+		}
+
+		if terr := user.CancelDeletion(tx); terr != nil {
+			return apierrors.NewInternalServerError("Database error cancelling user purge").WithInternalError(terr)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return sendJSON(w, http.StatusOK, user)
+}
+
+// adminUserUnlock clears the lockout and the failed sign-in attempts of a user
+func (a *API) adminUserUnlock(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	user := getUser(ctx)
+	config := a.config
+	adminUser := getAdminUser(ctx)
+	db := a.db.WithContext(ctx)
+
+	err := db.Transaction(func(tx *storage.Connection) error {
+		if terr := models.NewAuditLogEntry(config.AuditLog, r, tx, adminUser, models.UserUnlockedAction, "", map[string]interface{}{
+			"user_id":         user.ID,
+			"user_email":      user.Email,
+			"user_phone":      user.Phone,
+			"failed_attempts": user.FailedSignInAttempts,
+		}); terr != nil {
+			return apierrors.NewInternalServerError("Error recording audit log entry").WithInternalError(terr)
+		}
+
+		if terr := user.ResetFailedSignIns(tx); terr != nil {
+			return apierrors.NewInternalServerError("Database error unlocking user").WithInternalError(terr)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return sendJSON(w, http.StatusOK, user)
+}
+
+// indefiniteBanDuration is how long users banned without a duration are
+// banned for.
+const indefiniteBanDuration = 100 * 365 * 24 * time.Hour
+
+// maxBanReasonLength bounds the reason of a ban recorded in the audit log.
+const maxBanReasonLength = 1024
+
+// AdminUserBanParams are the parameters of a ban.
+type AdminUserBanParams struct {
+	// Duration is how long the user is banned for, in the format of
+	// time.ParseDuration. The user is banned indefinitely without it.
+	Duration string `json:"duration"`
+	Reason   string `json:"reason"`
+}
+
+// adminUserBan bans a user for a duration or indefinitely, and signs out
+// all of their sessions.
+func (a *API) adminUserBan(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	user := getUser(ctx)
+	config := a.config
+	adminUser := getAdminUser(ctx)
+	db := a.db.WithContext(ctx)
+
+	params := &AdminUserBanParams{}
+	if r.ContentLength != 0 {
+		if err := retrieveRequestParams(r, params); err != nil {
+			return err
+		}
+	}
+
+	duration := indefiniteBanDuration
+	if params.Duration != "" {
+		var err error
+		duration, err = time.ParseDuration(params.Duration)
+		if err != nil {
+			return apierrors.NewBadRequestError(apierrors.ErrorCodeValidationFailed, "invalid format for duration: %v", err)
+		}
+		if duration <= 0 {
+			return apierrors.NewBadRequestError(apierrors.ErrorCodeValidationFailed, "duration must be positive")
+		}
+	}
+
+	if len(params.Reason) > maxBanReasonLength {
+		return apierrors.NewBadRequestError(apierrors.ErrorCodeValidationFailed, "reason must be at most %d characters", maxBanReasonLength)
+	}
+
+	err := db.Transaction(func(tx *storage.Connection) error {
+		if terr := user.Ban(tx, duration); terr != nil {
+			return apierrors.NewInternalServerError("Database error banning user").WithInternalError(terr)
+		}
+
+		traits := map[string]interface{}{
+			"user_id":      user.ID,
+			"user_email":   user.Email,
+			"user_phone":   user.Phone,
+			"banned_until": user.BannedUntil,
+		}
+		if params.Duration == "" {
+			traits["indefinite"] = true
+		}
+		if params.Reason != "" {
+			traits["reason"] = params.Reason
+		}
+		if terr := models.NewAuditLogEntry(config.AuditLog, r, tx, adminUser, models.UserBannedAction, "", traits); terr != nil {
+			return apierrors.NewInternalServerError("Error recording audit log entry").WithInternalError(terr)
+		}
+
+		if terr := models.Logout(tx, user.ID); terr != nil {
+			return apierrors.NewInternalServerError("Error revoking sessions").WithInternalError(terr)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return sendJSON(w, http.StatusOK, user)
+}
+
+// adminUserUnban lifts the ban of a user.
+func (a *API) adminUserUnban(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	user := getUser(ctx)
+	config := a.config
+	adminUser := getAdminUser(ctx)
+	db := a.db.WithContext(ctx)
+
+	err := db.Transaction(func(tx *storage.Connection) error {
+		if terr := models.NewAuditLogEntry(config.AuditLog, r, tx, adminUser, models.UserUnbannedAction, "", map[string]interface{}{
+			"user_id":      user.ID,
+			"user_email":   user.Email,
+			"user_phone":   user.Phone,
+			"banned_until": user.BannedUntil,
+		}); terr != nil {
+			return apierrors.NewInternalServerError("Error recording audit log entry").WithInternalError(terr)
+		}
+
+		if terr := user.Ban(tx, 0); terr != nil {
+			return apierrors.NewInternalServerError("Database error unbanning user").WithInternalError(terr)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return sendJSON(w, http.StatusOK, user)
+}
+
+// bulkPasswordResetMaxSize is the number of users whose password can be
+// expired at once.
+const bulkPasswordResetMaxSize = 1000
+
+// AdminPasswordResetParams are the parameters of the bulk password reset
+// endpoint.
+type AdminPasswordResetParams struct {
+	UserIDs []uuid.UUID `json:"user_ids"`
+}
+
+// AdminPasswordResetResult is the outcome of expiring the password of one
+// of the users of a bulk password reset.
+type AdminPasswordResetResult struct {
+	UserID uuid.UUID    `json:"user_id"`
+	User   *models.User `json:"user,omitempty"`
+	Error  *HTTPError   `json:"error,omitempty"`
+}
+
+// AdminPasswordResetResponse is the response of the bulk password reset
+// endpoint, with the results in the order of the user IDs.
+type AdminPasswordResetResponse struct {
+	Users []AdminPasswordResetResult `json:"users"`
+}
+
+// adminUserPasswordReset expires the password of a user.
+func (a *API) adminUserPasswordReset(w http.ResponseWriter, r *http.Request) error {
+	user := getUser(r.Context())
+
+	if err := a.requirePasswordReset(r, user); err != nil {
+		return err
+	}
+
+	return sendJSON(w, http.StatusOK, user)
+}
+
+// adminUsersPasswordReset expires the passwords of a list of users. Users
+// that fail don't stop the others.
+func (a *API) adminUsersPasswordReset(w http.ResponseWriter, r *http.Request) error {
+	params := &AdminPasswordResetParams{}
+	if err := retrieveRequestParams(r, params); err != nil {
+		return err
+	}
+
+	if len(params.UserIDs) == 0 {
+		return apierrors.NewBadRequestError(apierrors.ErrorCodeValidationFailed, "At least one user ID is required")
+	}
+	if len(params.UserIDs) > bulkPasswordResetMaxSize {
+		return apierrors.NewBadRequestError(apierrors.ErrorCodeValidationFailed, "At most %d passwords can be reset at once", bulkPasswordResetMaxSize)
+	}
+
+	results := make([]AdminPasswordResetResult, 0, len(params.UserIDs))
+	for _, userID := range params.UserIDs {
+		result := AdminPasswordResetResult{UserID: userID}
+
+		user, err := a.findAdminUser(r, userID.String())
+		if err == nil {
+			err = a.requirePasswordReset(r, user)
+		}
+		if err != nil {
+			herr, ok := err.(*HTTPError)
+			if !ok {
+				herr = apierrors.NewInternalServerError("Error resetting password").WithInternalError(err)
+			}
+			if herr.HTTPStatus >= http.StatusInternalServerError {
+				observability.GetLogEntry(r).Entry.WithError(herr.Cause()).WithField("user_id", userID).Error("bulk password reset failed")
+			}
+			result.Error = herr
+		} else {
+			result.User = user
+		}
+
+		results = append(results, result)
+	}
+
+	return sendJSON(w, http.StatusOK, AdminPasswordResetResponse{
+		Users: results,
+	})
+}
+
+// requirePasswordReset expires the password of the user, signs out all of
+// their sessions and sends them a recovery email when they have an email.
+func (a *API) requirePasswordReset(r *http.Request, user *models.User) error {
+	ctx := r.Context()
+	config := a.config
+	adminUser := getAdminUser(ctx)
+	db := a.db.WithContext(ctx)
+
+	return db.Transaction(func(tx *storage.Connection) error {
+		if terr := models.NewAuditLogEntry(config.AuditLog, r, tx, adminUser, models.UserPasswordResetRequiredAction, "", map[string]interface{}{
+			"user_id":    user.ID,
+			"user_email": user.Email,
+			"user_phone": user.Phone,
+		}); terr != nil {
+			return apierrors.NewInternalServerError("Error recording audit log entry").WithInternalError(terr)
+		}
+
+		if terr := user.RequirePasswordReset(tx); terr != nil {
+			return apierrors.NewInternalServerError("Database error expiring password").WithInternalError(terr)
+		}
+
+		if terr := models.Logout(tx, user.ID); terr != nil {
+			return apierrors.NewInternalServerError("Error revoking sessions").WithInternalError(terr)
+		}
+
+		if user.GetEmail() != "" {
+			if terr := a.sendPasswordRecovery(r, tx, user, models.ImplicitFlow); terr != nil {
+				return terr
+			}
+		}
+
+		return nil
+	})
+}
+
+// ImpersonationResponse is the response of the impersonate endpoint, an
+// access token of the impersonated session without a refresh token.
+type ImpersonationResponse struct {
+	Token     string       `json:"access_token"`
+	TokenType string       `json:"token_type"`
+	ExpiresIn int64        `json:"expires_in"`
+	ExpiresAt int64        `json:"expires_at"`
+	SessionID uuid.UUID    `json:"session_id"`
+	User      *models.User `json:"user"`
+}
+
+// adminUserImpersonate creates a session of the user for the admin, which
+// ends after GOTRUE_IMPERSONATION_DURATION. Its access token identifies the
+// admin as the act claim, and the session is revoked like any other session
+// of the user.
+func (a *API) adminUserImpersonate(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	user := getUser(ctx)
+	config := a.config
+	adminUser := getAdminUser(ctx)
+	db := a.db.WithContext(ctx)
+
+	session, err := models.NewSession(user.ID, nil)
+	if err != nil {
+		return apierrors.NewInternalServerError("Failed to create new session").WithInternalError(err)
+	}
+
+	grantParams := models.GrantParams{}
+	grantParams.FillGrantParams(r)
+	notAfter := time.Now().Add(config.Impersonation.Duration)
+	grantParams.SessionNotAfter = &notAfter
+	session.ApplyGrantParams(&grantParams)
+
+	impersonatorRole := adminUser.Role
+	session.ImpersonatorRole = &impersonatorRole
+	if claims := getClaims(ctx); claims != nil && claims.Subject != "" {
+		if impersonatorID, err := uuid.FromString(claims.Subject); err == nil {
+			session.ImpersonatorID = &impersonatorID
+		}
+	}
+
+	var token string
+	var expiresAt int64
+	err = db.Transaction(func(tx *storage.Connection) error {
+		if terr := tx.Create(session); terr != nil {
+			return apierrors.NewInternalServerError("Database error creating new session").WithInternalError(terr)
+		}
+
+		if terr := models.AddClaimToSession(tx, session.ID, models.Impersonation); terr != nil {
+			return terr
+		}
+
+		if terr := models.NewAuditLogEntry(config.AuditLog, r, tx, adminUser, models.UserImpersonatedAction, "", map[string]interface{}{
+			"user_id":         user.ID,
+			"user_email":      user.Email,
+			"session_id":      session.ID,
+			"impersonator_id": session.ImpersonatorID,
+			"not_after":       notAfter,
+		}); terr != nil {
+			return apierrors.NewInternalServerError("Error recording audit log entry").WithInternalError(terr)
+		}
+
+		var terr error
+		token, expiresAt, terr = a.generateAccessToken(r, tx, user, &session.ID, models.Impersonation)
+		return terr
+	})
+	if err != nil {
+		return err
+	}
+
+	return sendJSON(w, http.StatusOK, &ImpersonationResponse{
+		Token:     token,
+		TokenType: "bearer",
+		ExpiresIn: expiresAt - time.Now().Unix(),
+		ExpiresAt: expiresAt,
+		SessionID: session.ID,
+		User:      user,
+	})
+}
+
+// adminUserSessions lists the active sessions of a user
+func (a *API) adminUserSessions(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	db := a.db.WithContext(ctx)
+
+	resp, err := a.findActiveSessions(db, getUser(ctx), nil)
+	if err != nil {
+		return err
+	}
+
+	return sendJSON(w, http.StatusOK, map[string]interface{}{
+		"sessions": resp,
+	})
+}
+
+// adminUserGetProviderToken returns the access token of the provider a user
+// signed in with
+func (a *API) adminUserGetProviderToken(w http.ResponseWriter, r *http.Request) error {
+	return a.sendProviderToken(w, r, getUser(r.Context()))
+}
+
+// adminUserRevokeSessions signs out all the sessions of a user
+func (a *API) adminUserRevokeSessions(w http.ResponseWriter, r *http.Request) error {
+	if err := a.revokeAdminUserSessions(r, getUser(r.Context()), nil); err != nil {
+		return err
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+
+	return nil
+}
+
+// adminUserRevokeSession signs out one of the sessions of a user
+func (a *API) adminUserRevokeSession(w http.ResponseWriter, r *http.Request) error {
+	sessionID, err := uuid.FromString(chi.URLParam(r, "session_id"))
+	if err != nil {
+		return apierrors.NewNotFoundError(apierrors.ErrorCodeValidationFailed, "session_id must be an UUID")
+	}
+
+	if err := a.revokeAdminUserSessions(r, getUser(r.Context()), &sessionID); err != nil {
+		return err
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+
+	return nil
+}
+
+// revokeAdminUserSessions signs out one of the sessions of the user, or all
+// of them when sessionID is nil. It is shared by the REST and gRPC admin
+// APIs.
+func (a *API) revokeAdminUserSessions(r *http.Request, user *models.User, sessionID *uuid.UUID) error {
+	ctx := r.Context()
+	config := a.config
+	adminUser := getAdminUser(ctx)
+	db := a.db.WithContext(ctx)
+
+	traits := map[string]interface{}{
+		"user_id": user.ID,
+		"scope":   LogoutGlobal,
+	}
+
+	if sessionID != nil {
+		session, err := models.FindSessionByID(db, *sessionID, false)
+		if err != nil {
+			if models.IsNotFoundError(err) {
+				return apierrors.NewNotFoundError(apierrors.ErrorCodeSessionNotFound, "Session not found")
+			}
+			return apierrors.NewInternalServerError("Database error finding session").WithInternalError(err)
+		}
+
+		if session.UserID != user.ID {
+			return apierrors.NewNotFoundError(apierrors.ErrorCodeSessionNotFound, "Session not found")
+		}
+
+		traits = map[string]interface{}{
+			"user_id":    user.ID,
+			"session_id": session.ID,
+		}
+	}
+
+	err := db.Transaction(func(tx *storage.Connection) error {
+		if terr := models.NewAuditLogEntry(config.AuditLog, r, tx, adminUser, models.SessionRevokedAction, "", traits); terr != nil {
+			return terr
+		}
+
+		if sessionID != nil {
+			return models.LogoutSession(tx, *sessionID)
+		}
+		return models.Logout(tx, user.ID)
+	})
+	if err != nil {
+		return apierrors.NewInternalServerError("Error revoking sessions").WithInternalError(err)
+	}
+
+	return nil
 }
 
 func (a *API) adminUserDeleteFactor(w http.ResponseWriter, r *http.Request) error {
@@ -578,16 +1304,22 @@ func (a *API) adminUserDeleteFactor(w http.ResponseWriter, r *http.Request) erro
 	factor := getFactor(ctx)
 	db := a.db.WithContext(ctx)
 
+	adminUser := getAdminUser(ctx)
+
 	err := db.Transaction(func(tx *storage.Connection) error {
-		if terr := models.NewAuditLogEntry(config.AuditLog, r, tx, user, models.DeleteFactorAction, utilities.GetIPAddress(r), map[string]interface{}{
-			"user_id":   user.ID,
-			"factor_id": factor.ID,
+		if terr := models.NewAuditLogEntry(config.AuditLog, r, tx, adminUser, models.DeleteFactorAction, utilities.GetIPAddress(r), map[string]interface{}{
+			"user_id":     user.ID,
+			"factor_id":   factor.ID,
+			"factor_type": factor.FactorType,
 		}); terr != nil {
 			return terr
 		}
 		if terr := tx.Destroy(factor); terr != nil {
 			return apierrors.NewInternalServerError("Database error deleting factor").WithInternalError(terr)
 		}
+		if terr := factor.DowngradeSessionsToAAL1(tx); terr != nil {
+			return apierrors.NewInternalServerError("Database error downgrading sessions").WithInternalError(terr)
+		}
 		return nil
 	})
 	if err != nil {
@@ -648,3 +1380,100 @@ func (a *API) adminUserUpdateFactor(w http.ResponseWriter, r *http.Request) erro
 
 	return sendJSON(w, http.StatusOK, factor)
 }
+
+// adminUserUnverifyFactor marks a verified factor of a user as unverified,
+// for users who lost their authenticator. The factor no longer counts
+// towards AAL2, and the sessions verified with it are downgraded to AAL1.
+func (a *API) adminUserUnverifyFactor(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	config := a.config
+	factor := getFactor(ctx)
+	user := getUser(ctx)
+	adminUser := getAdminUser(ctx)
+	db := a.db.WithContext(ctx)
+
+	if !factor.IsVerified() {
+		return apierrors.NewUnprocessableEntityError(apierrors.ErrorCodeValidationFailed, "Factor is not verified")
+	}
+
+	err := db.Transaction(func(tx *storage.Connection) error {
+		if terr := models.NewAuditLogEntry(config.AuditLog, r, tx, adminUser, models.UnverifyFactorAction, "", map[string]interface{}{
+			"user_id":     user.ID,
+			"factor_id":   factor.ID,
+			"factor_type": factor.FactorType,
+		}); terr != nil {
+			return terr
+		}
+		if terr := factor.UpdateStatus(tx, models.FactorStateUnverified); terr != nil {
+			return apierrors.NewInternalServerError("Database error unverifying factor").WithInternalError(terr)
+		}
+		if terr := factor.DowngradeSessionsToAAL1(tx); terr != nil {
+			return apierrors.NewInternalServerError("Database error downgrading sessions").WithInternalError(terr)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return sendJSON(w, http.StatusOK, factor)
+}
+
+// adminUserGetRecoveryCodes returns how many of the recovery codes of a user
+// are left.
+func (a *API) adminUserGetRecoveryCodes(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	user := getUser(ctx)
+	db := a.db.WithContext(ctx)
+
+	if !a.config.MFA.RecoveryCodes.Enabled {
+		return apierrors.NewUnprocessableEntityError(apierrors.ErrorCodeMFARecoveryCodesDisabled, "MFA recovery codes are disabled")
+	}
+
+	total, remaining, err := models.CountRecoveryCodes(db, user.ID)
+	if err != nil {
+		return apierrors.NewInternalServerError("Database error counting recovery codes").WithInternalError(err)
+	}
+
+	return sendJSON(w, http.StatusOK, &RecoveryCodesResponse{
+		Total:     total,
+		Remaining: remaining,
+	})
+}
+
+// adminUserRegenerateRecoveryCodes replaces the recovery codes of a user with
+// new ones, which are returned for the admin to hand over to the user.
+func (a *API) adminUserRegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	config := a.config
+	user := getUser(ctx)
+	adminUser := getAdminUser(ctx)
+	db := a.db.WithContext(ctx)
+
+	if !config.MFA.RecoveryCodes.Enabled {
+		return apierrors.NewUnprocessableEntityError(apierrors.ErrorCodeMFARecoveryCodesDisabled, "MFA recovery codes are disabled")
+	}
+
+	var codes []string
+	err := db.Transaction(func(tx *storage.Connection) error {
+		var terr error
+		codes, terr = models.GenerateRecoveryCodes(tx, user, config.MFA.RecoveryCodes.Count)
+		if terr != nil {
+			return apierrors.NewInternalServerError("Database error generating recovery codes").WithInternalError(terr)
+		}
+		return models.NewAuditLogEntry(config.AuditLog, r, tx, adminUser, models.GenerateRecoveryCodesAction, "", map[string]interface{}{
+			"user_id": user.ID,
+			"count":   len(codes),
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	return sendJSON(w, http.StatusOK, &RecoveryCodesResponse{
+		Total:         len(codes),
+		Remaining:     len(codes),
+		RecoveryCodes: codes,
+	})
+}
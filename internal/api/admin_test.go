@@ -7,17 +7,21 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/gofrs/uuid"
 	jwt "github.com/golang-jwt/jwt/v5"
+	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
 	"github.com/supabase/auth/internal/api/apierrors"
 	"github.com/supabase/auth/internal/conf"
 	"github.com/supabase/auth/internal/models"
+	"github.com/supabase/auth/internal/userpurge"
 )
 
 type AdminTestSuite struct {
@@ -227,6 +231,105 @@ func (ts *AdminTestSuite) TestAdminUsers_FilterName() {
 	assert.Equal(ts.T(), "test1@example.com", data.Users[0].GetEmail())
 }
 
+func (ts *AdminTestSuite) TestAdminUsers_Search() {
+	u1, err := models.NewUser("", "search1@example.com", "test", ts.Config.JWT.Aud, map[string]interface{}{"plan": "pro", "team": "a"})
+	require.NoError(ts.T(), err, "Error making new user")
+	require.NoError(ts.T(), ts.API.db.Create(u1), "Error creating user")
+
+	u2, err := models.NewUser("123456789", "search2@example.com", "test", ts.Config.JWT.Aud, map[string]interface{}{"plan": "free"})
+	require.NoError(ts.T(), err, "Error making new user")
+	bannedUntil := time.Now().Add(time.Hour)
+	u2.BannedUntil = &bannedUntil
+	require.NoError(ts.T(), ts.API.db.Create(u2), "Error creating user")
+
+	cases := []struct {
+		desc     string
+		query    string
+		expected []string
+	}{
+		{desc: "Email", query: "email=SEARCH2", expected: []string{"search2@example.com"}},
+		{desc: "Phone", query: "phone=%2B12345", expected: []string{"search2@example.com"}},
+		{desc: "Banned", query: "banned=true", expected: []string{"search2@example.com"}},
+		{desc: "Not banned", query: "banned=false&email=search", expected: []string{"search1@example.com"}},
+		{desc: "User metadata", query: "user_metadata=" + url.QueryEscape(`{"plan": "pro"}`), expected: []string{"search1@example.com"}},
+		{desc: "Created range", query: "created_after=" + url.QueryEscape(time.Now().Add(-time.Hour).Format(time.RFC3339)) + "&email=search&sort=email%20asc", expected: []string{"search1@example.com", "search2@example.com"}},
+		{desc: "Never signed in", query: "last_sign_in_after=" + url.QueryEscape(time.Now().Add(-time.Hour).Format(time.RFC3339)), expected: []string{}},
+	}
+
+	for _, c := range cases {
+		ts.Run(c.desc, func() {
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/admin/users?"+c.query, nil)
+			req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", ts.token))
+
+			ts.API.handler.ServeHTTP(w, req)
+			require.Equal(ts.T(), http.StatusOK, w.Code)
+
+			data := AdminListUsersResponse{}
+			require.NoError(ts.T(), json.NewDecoder(w.Body).Decode(&data))
+
+			emails := []string{}
+			for _, user := range data.Users {
+				emails = append(emails, user.GetEmail())
+			}
+			require.Equal(ts.T(), c.expected, emails)
+		})
+	}
+}
+
+func (ts *AdminTestSuite) TestAdminUsers_SearchInvalid() {
+	for _, query := range []string{
+		"banned=maybe",
+		"created_after=yesterday",
+		"user_metadata=plan",
+		"sort=banned_until",
+		"after=abc",
+		"after=" + uuid.Must(uuid.NewV4()).String(),
+	} {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/admin/users?"+query, nil)
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", ts.token))
+
+		ts.API.handler.ServeHTTP(w, req)
+		require.Equal(ts.T(), http.StatusBadRequest, w.Code, query)
+	}
+}
+
+func (ts *AdminTestSuite) TestAdminUsers_KeysetPagination() {
+	for _, email := range []string{"c@example.com", "a@example.com", "b@example.com"} {
+		u, err := models.NewUser("", email, "test", ts.Config.JWT.Aud, nil)
+		require.NoError(ts.T(), err, "Error making new user")
+		require.NoError(ts.T(), ts.API.db.Create(u), "Error creating user")
+	}
+
+	var emails []string
+	target := "/admin/users?sort=email%20asc&per_page=2&after="
+	for target != "" {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", ts.token))
+
+		ts.API.handler.ServeHTTP(w, req)
+		require.Equal(ts.T(), http.StatusOK, w.Code)
+		require.Empty(ts.T(), w.Header().Get("X-Total-Count"))
+
+		data := AdminListUsersResponse{}
+		require.NoError(ts.T(), json.NewDecoder(w.Body).Decode(&data))
+		for _, user := range data.Users {
+			emails = append(emails, user.GetEmail())
+		}
+
+		target = ""
+		if link := w.Header().Get("Link"); link != "" {
+			next, err := url.Parse(strings.TrimSuffix(strings.TrimPrefix(link, "<"), ">; rel=\"next\""))
+			require.NoError(ts.T(), err)
+			target = next.RequestURI()
+		}
+	}
+
+	require.Equal(ts.T(), []string{"a@example.com", "b@example.com", "c@example.com"}, emails)
+}
+
 // TestAdminUserCreate tests API /admin/user route (POST)
 func (ts *AdminTestSuite) TestAdminUserCreate() {
 	cases := []struct {
@@ -692,6 +795,142 @@ func (ts *AdminTestSuite) TestAdminUserSoftDeletion() {
 	}
 }
 
+func (ts *AdminTestSuite) TestAdminUserPurge() {
+	ts.Config.UserDeletion.PurgeEnabled = true
+	ts.Config.UserDeletion.GracePeriod = time.Hour
+	defer func() {
+		ts.Config.UserDeletion.PurgeEnabled = false
+	}()
+
+	u, err := models.NewUser("", "test@example.com", "secret", ts.Config.JWT.Aud, map[string]interface{}{"name": "test"})
+	require.NoError(ts.T(), err)
+	require.NoError(ts.T(), ts.API.db.Create(u))
+
+	request := func(method, path string, body map[string]interface{}) *httptest.ResponseRecorder {
+		var buffer bytes.Buffer
+		if body != nil {
+			require.NoError(ts.T(), json.NewEncoder(&buffer).Encode(body))
+		}
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, &buffer)
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", ts.token))
+		ts.API.handler.ServeHTTP(w, req)
+		return w
+	}
+
+	path := fmt.Sprintf("/admin/users/%s", u.ID)
+
+	// only soft deleted users can be purged
+	w := request(http.MethodPost, path+"/purge", nil)
+	require.Equal(ts.T(), http.StatusUnprocessableEntity, w.Code)
+
+	w = request(http.MethodDelete, path, map[string]interface{}{
+		"should_soft_delete": true,
+		"should_anonymize":   false,
+	})
+	require.Equal(ts.T(), http.StatusOK, w.Code)
+
+	deletedUser, err := models.FindUserByID(ts.API.db, u.ID)
+	require.NoError(ts.T(), err)
+	require.NotNil(ts.T(), deletedUser.DeletedAt)
+	require.NotNil(ts.T(), deletedUser.PurgeAfter)
+	require.False(ts.T(), deletedUser.IsAnonymized)
+	require.Equal(ts.T(), "test", deletedUser.UserMetaData["name"])
+
+	// the purge is not due yet
+	purged, err := userpurge.Purge(context.Background(), ts.API.db, &ts.Config.UserDeletion, time.Now(), logrus.NewEntry(logrus.New()))
+	require.NoError(ts.T(), err)
+	require.Equal(ts.T(), 0, purged)
+
+	// cancelling the purge restores the user
+	w = request(http.MethodDelete, path+"/purge", nil)
+	require.Equal(ts.T(), http.StatusOK, w.Code, w.Body.String())
+	restoredUser, err := models.FindUserByID(ts.API.db, u.ID)
+	require.NoError(ts.T(), err)
+	require.Nil(ts.T(), restoredUser.DeletedAt)
+	require.Nil(ts.T(), restoredUser.PurgeAfter)
+
+	w = request(http.MethodDelete, path, map[string]interface{}{
+		"should_soft_delete": true,
+	})
+	require.Equal(ts.T(), http.StatusOK, w.Code)
+
+	purged, err = userpurge.Purge(context.Background(), ts.API.db, &ts.Config.UserDeletion, time.Now().Add(2*time.Hour), logrus.NewEntry(logrus.New()))
+	require.NoError(ts.T(), err)
+	require.Equal(ts.T(), 1, purged)
+	_, err = models.FindUserByID(ts.API.db, u.ID)
+	require.True(ts.T(), models.IsNotFoundError(err))
+}
+
+func (ts *AdminTestSuite) TestAdminUserDeleteSoftDeleted() {
+	u, err := models.NewUser("", "test@example.com", "secret", ts.Config.JWT.Aud, map[string]interface{}{"name": "test"})
+	require.NoError(ts.T(), err)
+	require.NoError(ts.T(), ts.API.db.Create(u))
+
+	request := func(body map[string]interface{}) *httptest.ResponseRecorder {
+		var buffer bytes.Buffer
+		require.NoError(ts.T(), json.NewEncoder(&buffer).Encode(body))
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodDelete, fmt.Sprintf("/admin/users/%s", u.ID), &buffer)
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", ts.token))
+		ts.API.handler.ServeHTTP(w, req)
+		return w
+	}
+
+	w := request(map[string]interface{}{
+		"should_soft_delete": true,
+		"should_anonymize":   false,
+	})
+	require.Equal(ts.T(), http.StatusOK, w.Code)
+
+	softDeletedUser, err := models.FindUserByID(ts.API.db, u.ID)
+	require.NoError(ts.T(), err)
+	require.NotNil(ts.T(), softDeletedUser.DeletedAt)
+	require.False(ts.T(), softDeletedUser.IsAnonymized)
+
+	// the data of a soft deleted user can be erased afterwards, keeping its
+	// scheduled purge
+	w = request(map[string]interface{}{
+		"should_soft_delete": true,
+	})
+	require.Equal(ts.T(), http.StatusOK, w.Code)
+
+	anonymizedUser, err := models.FindUserByID(ts.API.db, u.ID)
+	require.NoError(ts.T(), err)
+	require.True(ts.T(), anonymizedUser.IsAnonymized)
+	require.NotEqual(ts.T(), "test@example.com", anonymizedUser.GetEmail())
+	require.NotContains(ts.T(), anonymizedUser.UserMetaData, "name")
+	if softDeletedUser.PurgeAfter != nil {
+		require.NotNil(ts.T(), anonymizedUser.PurgeAfter)
+		require.WithinDuration(ts.T(), *softDeletedUser.PurgeAfter, *anonymizedUser.PurgeAfter, time.Second)
+	}
+
+	// and it can be deleted right away
+	w = request(map[string]interface{}{
+		"should_soft_delete": false,
+	})
+	require.Equal(ts.T(), http.StatusOK, w.Code)
+
+	_, err = models.FindUserByID(ts.API.db, u.ID)
+	require.True(ts.T(), models.IsNotFoundError(err))
+}
+
+func (ts *AdminTestSuite) TestAdminUserForcePurge() {
+	u, err := models.NewUser("", "test@example.com", "secret", ts.Config.JWT.Aud, nil)
+	require.NoError(ts.T(), err)
+	require.NoError(ts.T(), ts.API.db.Create(u))
+	require.NoError(ts.T(), u.SoftDeleteUser(ts.API.db))
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/admin/users/%s/purge", u.ID), nil)
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", ts.token))
+	ts.API.handler.ServeHTTP(w, req)
+	require.Equal(ts.T(), http.StatusNoContent, w.Code, w.Body.String())
+
+	_, err = models.FindUserByID(ts.API.db, u.ID)
+	require.True(ts.T(), models.IsNotFoundError(err))
+}
+
 func (ts *AdminTestSuite) TestAdminUserCreateWithDisabledLogin() {
 	var cases = []struct {
 		desc         string
@@ -789,6 +1028,387 @@ func (ts *AdminTestSuite) TestAdminUserDeleteFactor() {
 
 }
 
+func (ts *AdminTestSuite) TestAdminUserUnverifyFactor() {
+	u, err := models.NewUser("", "test-unverify@example.com", "test", ts.Config.JWT.Aud, nil)
+	require.NoError(ts.T(), err, "Error making new user")
+	require.NoError(ts.T(), ts.API.db.Create(u), "Error creating user")
+
+	f := models.NewTOTPFactor(u, "testSimpleName")
+	f.Status = models.FactorStateVerified.String()
+	require.NoError(ts.T(), f.SetSecret("secretkey", ts.Config.Security.DBEncryption.Encrypt, ts.Config.Security.DBEncryption.EncryptionKeyID, ts.Config.Security.DBEncryption.EncryptionKey))
+	require.NoError(ts.T(), ts.API.db.Create(f), "Error saving new test factor")
+
+	unverify := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/admin/users/%s/factors/%s/unverify", u.ID, f.ID), nil)
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", ts.token))
+		ts.API.handler.ServeHTTP(w, req)
+		return w
+	}
+
+	require.Equal(ts.T(), http.StatusOK, unverify().Code)
+
+	f, err = models.FindFactorByFactorID(ts.API.db, f.ID)
+	require.NoError(ts.T(), err)
+	require.True(ts.T(), f.IsUnverified())
+
+	entries, err := models.FindAuditLogEntries(ts.API.db, nil, "", &models.AuditLogFilter{Action: string(models.UnverifyFactorAction)}, nil)
+	require.NoError(ts.T(), err)
+	require.Len(ts.T(), entries, 1)
+
+	// only verified factors can be unverified
+	require.Equal(ts.T(), http.StatusUnprocessableEntity, unverify().Code)
+}
+
+func (ts *AdminTestSuite) TestAdminUserRecoveryCodes() {
+	u, err := models.NewUser("", "test-recovery-codes@example.com", "test", ts.Config.JWT.Aud, nil)
+	require.NoError(ts.T(), err, "Error making new user")
+	require.NoError(ts.T(), ts.API.db.Create(u), "Error creating user")
+
+	recoveryCodes := func(method string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, fmt.Sprintf("/admin/users/%s/recovery_codes", u.ID), nil)
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", ts.token))
+		ts.API.handler.ServeHTTP(w, req)
+		return w
+	}
+
+	ts.Config.MFA.RecoveryCodes.Enabled = false
+	require.Equal(ts.T(), http.StatusUnprocessableEntity, recoveryCodes(http.MethodPost).Code)
+
+	ts.Config.MFA.RecoveryCodes.Enabled = true
+	ts.Config.MFA.RecoveryCodes.Count = 5
+	defer func() {
+		ts.Config.MFA.RecoveryCodes.Enabled = false
+	}()
+
+	w := recoveryCodes(http.MethodPost)
+	require.Equal(ts.T(), http.StatusOK, w.Code)
+	require.Equal(ts.T(), "no-store", w.Header().Get("Cache-Control"))
+
+	data := RecoveryCodesResponse{}
+	require.NoError(ts.T(), json.NewDecoder(w.Body).Decode(&data))
+	require.Len(ts.T(), data.RecoveryCodes, 5)
+
+	w = recoveryCodes(http.MethodGet)
+	require.Equal(ts.T(), http.StatusOK, w.Code)
+
+	data = RecoveryCodesResponse{}
+	require.NoError(ts.T(), json.NewDecoder(w.Body).Decode(&data))
+	require.Equal(ts.T(), 5, data.Total)
+	require.Equal(ts.T(), 5, data.Remaining)
+	require.Empty(ts.T(), data.RecoveryCodes)
+}
+
+// TestAdminUserUnlock tests API /admin/users/<user_id>/unlock
+func (ts *AdminTestSuite) TestAdminUserUnlock() {
+	u, err := models.NewUser("", "test-unlock@example.com", "test", ts.Config.JWT.Aud, nil)
+	require.NoError(ts.T(), err, "Error making new user")
+	require.NoError(ts.T(), ts.API.db.Create(u), "Error creating user")
+
+	for i := 0; i < 3; i++ {
+		_, err := u.RecordFailedSignIn(ts.API.db, 3, time.Minute, time.Hour)
+		require.NoError(ts.T(), err)
+	}
+	require.True(ts.T(), u.IsLocked())
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/admin/users/%s/unlock", u.ID), nil)
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", ts.token))
+
+	ts.API.handler.ServeHTTP(w, req)
+	require.Equal(ts.T(), http.StatusOK, w.Code)
+
+	u, err = models.FindUserByID(ts.API.db, u.ID)
+	require.NoError(ts.T(), err)
+	require.False(ts.T(), u.IsLocked())
+	require.Equal(ts.T(), 0, u.FailedSignInAttempts)
+}
+
+func (ts *AdminTestSuite) TestAdminUserBan() {
+	u, err := models.NewUser("", "test-ban@example.com", "test", ts.Config.JWT.Aud, nil)
+	require.NoError(ts.T(), err, "Error making new user")
+	require.NoError(ts.T(), ts.API.db.Create(u), "Error creating user")
+
+	s, err := models.NewSession(u.ID, nil)
+	require.NoError(ts.T(), err)
+	require.NoError(ts.T(), ts.API.db.Create(s))
+
+	ban := func(method string, params map[string]interface{}) *httptest.ResponseRecorder {
+		var buffer bytes.Buffer
+		if params != nil {
+			require.NoError(ts.T(), json.NewEncoder(&buffer).Encode(params))
+		}
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, fmt.Sprintf("/admin/users/%s/ban", u.ID), &buffer)
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", ts.token))
+		req.Header.Set("Content-Type", "application/json")
+		ts.API.handler.ServeHTTP(w, req)
+		return w
+	}
+
+	require.Equal(ts.T(), http.StatusBadRequest, ban(http.MethodPost, map[string]interface{}{"duration": "forever"}).Code)
+	require.Equal(ts.T(), http.StatusBadRequest, ban(http.MethodPost, map[string]interface{}{"duration": "-1h"}).Code)
+
+	w := ban(http.MethodPost, map[string]interface{}{"duration": "24h", "reason": "spam"})
+	require.Equal(ts.T(), http.StatusOK, w.Code)
+
+	data := models.User{}
+	require.NoError(ts.T(), json.NewDecoder(w.Body).Decode(&data))
+	require.NotNil(ts.T(), data.BannedUntil)
+	require.WithinDuration(ts.T(), time.Now().Add(24*time.Hour), *data.BannedUntil, time.Minute)
+
+	u, err = models.FindUserByID(ts.API.db, u.ID)
+	require.NoError(ts.T(), err)
+	require.True(ts.T(), u.IsBanned())
+
+	// the sessions of the user are revoked
+	sessions, err := models.FindAllSessionsForUser(ts.API.db, u.ID, false)
+	require.NoError(ts.T(), err)
+	require.Empty(ts.T(), sessions)
+
+	// the reason is recorded in the audit log
+	entries, err := models.FindAuditLogEntries(ts.API.db, nil, "", &models.AuditLogFilter{Action: string(models.UserBannedAction)}, nil)
+	require.NoError(ts.T(), err)
+	require.Len(ts.T(), entries, 1)
+	traits := entries[0].Payload["traits"].(map[string]interface{})
+	require.Equal(ts.T(), "spam", traits["reason"])
+
+	// without a duration the user is banned indefinitely
+	w = ban(http.MethodPost, nil)
+	require.Equal(ts.T(), http.StatusOK, w.Code)
+	u, err = models.FindUserByID(ts.API.db, u.ID)
+	require.NoError(ts.T(), err)
+	require.True(ts.T(), u.BannedUntil.After(time.Now().Add(50*365*24*time.Hour)))
+
+	w = ban(http.MethodDelete, nil)
+	require.Equal(ts.T(), http.StatusOK, w.Code)
+	u, err = models.FindUserByID(ts.API.db, u.ID)
+	require.NoError(ts.T(), err)
+	require.False(ts.T(), u.IsBanned())
+	require.Nil(ts.T(), u.BannedUntil)
+}
+
+func (ts *AdminTestSuite) TestAdminUserPasswordReset() {
+	ts.Config.SMTP.MaxFrequency = 0
+
+	u, err := models.NewUser("", "test-reset@example.com", "password", ts.Config.JWT.Aud, nil)
+	require.NoError(ts.T(), err, "Error making new user")
+	require.NoError(ts.T(), ts.API.db.Create(u), "Error creating user")
+	require.NoError(ts.T(), u.Confirm(ts.API.db))
+
+	s, err := models.NewSession(u.ID, nil)
+	require.NoError(ts.T(), err)
+	require.NoError(ts.T(), ts.API.db.Create(s))
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/admin/users/%s/password_reset", u.ID), nil)
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", ts.token))
+	ts.API.handler.ServeHTTP(w, req)
+	require.Equal(ts.T(), http.StatusOK, w.Code)
+
+	u, err = models.FindUserByID(ts.API.db, u.ID)
+	require.NoError(ts.T(), err)
+	require.True(ts.T(), u.PasswordResetRequired)
+	require.NotNil(ts.T(), u.RecoverySentAt)
+
+	sessions, err := models.FindAllSessionsForUser(ts.API.db, u.ID, false)
+	require.NoError(ts.T(), err)
+	require.Empty(ts.T(), sessions)
+
+	// the expired password can't be used to sign in
+	var buffer bytes.Buffer
+	require.NoError(ts.T(), json.NewEncoder(&buffer).Encode(map[string]interface{}{
+		"email":    "test-reset@example.com",
+		"password": "password",
+	}))
+	w = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodPost, "/token?grant_type=password", &buffer)
+	req.Header.Set("Content-Type", "application/json")
+	ts.API.handler.ServeHTTP(w, req)
+	require.Equal(ts.T(), http.StatusBadRequest, w.Code)
+
+	data := HTTPError{}
+	require.NoError(ts.T(), json.NewDecoder(w.Body).Decode(&data))
+	require.Equal(ts.T(), apierrors.ErrorCodePasswordResetRequired, data.ErrorCode)
+
+	// changing the password lifts the expiry
+	require.NoError(ts.T(), u.SetPassword(context.Background(), "new-password", false, "", ""))
+	require.NoError(ts.T(), u.UpdatePassword(ts.API.db, nil))
+	u, err = models.FindUserByID(ts.API.db, u.ID)
+	require.NoError(ts.T(), err)
+	require.False(ts.T(), u.PasswordResetRequired)
+}
+
+func (ts *AdminTestSuite) TestAdminUsersPasswordReset() {
+	ts.Config.SMTP.MaxFrequency = 0
+
+	u, err := models.NewUser("", "test-bulk-reset@example.com", "password", ts.Config.JWT.Aud, nil)
+	require.NoError(ts.T(), err, "Error making new user")
+	require.NoError(ts.T(), ts.API.db.Create(u), "Error creating user")
+
+	missing := uuid.Must(uuid.NewV4())
+
+	var buffer bytes.Buffer
+	require.NoError(ts.T(), json.NewEncoder(&buffer).Encode(map[string]interface{}{
+		"user_ids": []uuid.UUID{u.ID, missing},
+	}))
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/admin/users/password_reset", &buffer)
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", ts.token))
+	req.Header.Set("Content-Type", "application/json")
+	ts.API.handler.ServeHTTP(w, req)
+	require.Equal(ts.T(), http.StatusOK, w.Code)
+
+	data := AdminPasswordResetResponse{}
+	require.NoError(ts.T(), json.NewDecoder(w.Body).Decode(&data))
+	require.Len(ts.T(), data.Users, 2)
+	require.Equal(ts.T(), u.ID, data.Users[0].UserID)
+	require.Nil(ts.T(), data.Users[0].Error)
+	require.True(ts.T(), data.Users[0].User.PasswordResetRequired)
+	require.Equal(ts.T(), missing, data.Users[1].UserID)
+	require.Equal(ts.T(), apierrors.ErrorCodeUserNotFound, data.Users[1].Error.ErrorCode)
+
+	buffer.Reset()
+	require.NoError(ts.T(), json.NewEncoder(&buffer).Encode(map[string]interface{}{
+		"user_ids": []uuid.UUID{},
+	}))
+	w = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodPost, "/admin/users/password_reset", &buffer)
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", ts.token))
+	req.Header.Set("Content-Type", "application/json")
+	ts.API.handler.ServeHTTP(w, req)
+	require.Equal(ts.T(), http.StatusBadRequest, w.Code)
+}
+
+func (ts *AdminTestSuite) TestAdminUserImpersonate() {
+	u, err := models.NewUser("", "test-impersonate@example.com", "test", ts.Config.JWT.Aud, nil)
+	require.NoError(ts.T(), err, "Error making new user")
+	require.NoError(ts.T(), ts.API.db.Create(u), "Error creating user")
+
+	impersonate := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/admin/users/%s/impersonate", u.ID), nil)
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", ts.token))
+		ts.API.handler.ServeHTTP(w, req)
+		return w
+	}
+
+	ts.Config.Impersonation.Enabled = false
+	require.Equal(ts.T(), http.StatusNotFound, impersonate().Code)
+
+	ts.Config.Impersonation.Enabled = true
+	ts.Config.Impersonation.Duration = 10 * time.Minute
+	defer func() {
+		ts.Config.Impersonation.Enabled = false
+	}()
+
+	w := impersonate()
+	require.Equal(ts.T(), http.StatusOK, w.Code, w.Body.String())
+	var resp ImpersonationResponse
+	require.NoError(ts.T(), json.NewDecoder(w.Body).Decode(&resp))
+	require.LessOrEqual(ts.T(), resp.ExpiresIn, int64(600))
+
+	claims := &AccessTokenClaims{}
+	_, err = jwt.ParseWithClaims(resp.Token, claims, func(token *jwt.Token) (interface{}, error) {
+		return []byte(ts.Config.JWT.Secret), nil
+	})
+	require.NoError(ts.T(), err)
+	require.Equal(ts.T(), u.ID.String(), claims.Subject)
+	require.Equal(ts.T(), resp.SessionID.String(), claims.SessionId)
+	require.Equal(ts.T(), &models.SessionActor{Role: "supabase_admin"}, claims.Actor)
+	require.Equal(ts.T(), "impersonation", claims.AuthenticationMethodReference[0].Method)
+
+	// the impersonated user can be used until the session is revoked
+	w = httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/user", nil)
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", resp.Token))
+	ts.API.handler.ServeHTTP(w, req)
+	require.Equal(ts.T(), http.StatusOK, w.Code, w.Body.String())
+
+	w = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodGet, fmt.Sprintf("/admin/users/%s/sessions", u.ID), nil)
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", ts.token))
+	ts.API.handler.ServeHTTP(w, req)
+	require.Equal(ts.T(), http.StatusOK, w.Code)
+	var data struct {
+		Sessions []*SessionResponse `json:"sessions"`
+	}
+	require.NoError(ts.T(), json.NewDecoder(w.Body).Decode(&data))
+	require.Len(ts.T(), data.Sessions, 1)
+	require.Equal(ts.T(), "supabase_admin", data.Sessions[0].Impersonator.Role)
+
+	w = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodDelete, fmt.Sprintf("/admin/users/%s/sessions/%s", u.ID, resp.SessionID), nil)
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", ts.token))
+	ts.API.handler.ServeHTTP(w, req)
+	require.Equal(ts.T(), http.StatusNoContent, w.Code, w.Body.String())
+
+	w = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodGet, "/user", nil)
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", resp.Token))
+	ts.API.handler.ServeHTTP(w, req)
+	require.Equal(ts.T(), http.StatusForbidden, w.Code)
+}
+
+func (ts *AdminTestSuite) TestAdminUserSessions() {
+	u, err := models.NewUser("", "test-sessions@example.com", "test", ts.Config.JWT.Aud, nil)
+	require.NoError(ts.T(), err, "Error making new user")
+	require.NoError(ts.T(), ts.API.db.Create(u), "Error creating user")
+
+	var sessions []*models.Session
+	for i := 0; i < 2; i++ {
+		s, err := models.NewSession(u.ID, nil)
+		require.NoError(ts.T(), err)
+		require.NoError(ts.T(), ts.API.db.Create(s))
+		sessions = append(sessions, s)
+	}
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/admin/users/%s/sessions", u.ID), nil)
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", ts.token))
+	ts.API.handler.ServeHTTP(w, req)
+	require.Equal(ts.T(), http.StatusOK, w.Code)
+
+	var data struct {
+		Sessions []*SessionResponse `json:"sessions"`
+	}
+	require.NoError(ts.T(), json.NewDecoder(w.Body).Decode(&data))
+	require.Len(ts.T(), data.Sessions, 2)
+
+	// a session of another user is not found
+	other, err := models.NewUser("", "test-sessions-other@example.com", "test", ts.Config.JWT.Aud, nil)
+	require.NoError(ts.T(), err, "Error making new user")
+	require.NoError(ts.T(), ts.API.db.Create(other), "Error creating user")
+
+	w = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodDelete, fmt.Sprintf("/admin/users/%s/sessions/%s", other.ID, sessions[0].ID), nil)
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", ts.token))
+	ts.API.handler.ServeHTTP(w, req)
+	require.Equal(ts.T(), http.StatusNotFound, w.Code)
+
+	w = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodDelete, fmt.Sprintf("/admin/users/%s/sessions/%s", u.ID, sessions[0].ID), nil)
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", ts.token))
+	ts.API.handler.ServeHTTP(w, req)
+	require.Equal(ts.T(), http.StatusNoContent, w.Code)
+
+	_, err = models.FindSessionByID(ts.API.db, sessions[0].ID, false)
+	require.True(ts.T(), models.IsNotFoundError(err))
+	_, err = models.FindSessionByID(ts.API.db, sessions[1].ID, false)
+	require.NoError(ts.T(), err)
+
+	w = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodDelete, fmt.Sprintf("/admin/users/%s/sessions", u.ID), nil)
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", ts.token))
+	ts.API.handler.ServeHTTP(w, req)
+	require.Equal(ts.T(), http.StatusNoContent, w.Code)
+
+	remaining, err := models.FindAllSessionsForUser(ts.API.db, u.ID, false)
+	require.NoError(ts.T(), err)
+	require.Empty(ts.T(), remaining)
+}
+
 // TestAdminUserGetFactor tests API /admin/user/<user_id>/factors/
 func (ts *AdminTestSuite) TestAdminUserGetFactors() {
 	u, err := models.NewUser("123456789", "test-delete@example.com", "test", ts.Config.JWT.Aud, nil)
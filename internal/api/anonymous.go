@@ -44,6 +44,11 @@ func (a *API) SignupAnonymously(w http.ResponseWriter, r *http.Request) error {
 		if terr != nil {
 			return terr
 		}
+		if terr = models.NewAuditLogEntry(config.AuditLog, r, tx, newUser, models.UserSignedUpAction, "", map[string]interface{}{
+			"provider": params.Provider,
+		}); terr != nil {
+			return terr
+		}
 		token, terr = a.issueRefreshToken(r, w.Header(), tx, newUser, models.Anonymous, grantParams)
 		if terr != nil {
 			return terr
@@ -58,5 +63,5 @@ func (a *API) SignupAnonymously(w http.ResponseWriter, r *http.Request) error {
 	}
 
 	metering.RecordLogin(metering.LoginTypeAnonymous, newUser.ID, nil)
-	return sendJSON(w, http.StatusOK, token)
+	return a.sendTokenResponse(w, r, token)
 }
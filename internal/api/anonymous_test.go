@@ -82,6 +82,11 @@ func (ts *AnonymousTestSuite) TestAnonymousLogins() {
 	assert.Empty(ts.T(), data.User.GetPhone())
 	assert.True(ts.T(), data.User.IsAnonymous)
 	assert.Equal(ts.T(), models.JSONMap(models.JSONMap{"field": "foo"}), data.User.UserMetaData)
+
+	logs, err := models.FindAuditLogEntries(ts.API.db, []string{"action"}, string(models.UserSignedUpAction), nil, nil)
+	require.NoError(ts.T(), err)
+	require.Len(ts.T(), logs, 1)
+	assert.Equal(ts.T(), data.User.ID.String(), logs[0].Payload["actor_id"])
 }
 
 func (ts *AnonymousTestSuite) TestConvertAnonymousUserToPermanent() {
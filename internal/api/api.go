@@ -5,6 +5,7 @@ import (
 	"regexp"
 	"time"
 
+	"github.com/gofrs/uuid"
 	"github.com/rs/cors"
 	"github.com/sebest/xff"
 	"github.com/sirupsen/logrus"
@@ -12,10 +13,12 @@ import (
 	"github.com/supabase/auth/internal/api/apitask"
 	"github.com/supabase/auth/internal/api/oauthserver"
 	"github.com/supabase/auth/internal/conf"
+	"github.com/supabase/auth/internal/geoip"
 	"github.com/supabase/auth/internal/hooks/hookshttp"
 	"github.com/supabase/auth/internal/hooks/hookspgfunc"
 	"github.com/supabase/auth/internal/hooks/v0hooks"
 	"github.com/supabase/auth/internal/mailer"
+	"github.com/supabase/auth/internal/mailer/queueclient"
 	"github.com/supabase/auth/internal/mailer/templatemailer"
 	"github.com/supabase/auth/internal/models"
 	"github.com/supabase/auth/internal/observability"
@@ -46,10 +49,16 @@ type API struct {
 	tokenService *tokens.Service
 	mailer       mailer.Mailer
 
+	// tenantID is the tenant served by the API, nil for the deployment's API.
+	tenantID *uuid.UUID
+
 	// overrideTime can be used to override the clock used by handlers. Should only be used in tests!
 	overrideTime func() time.Time
 
 	limiterOpts *LimiterOptions
+
+	// jwks memoizes the JWKS response when caching is enabled.
+	jwks jwksCache
 }
 
 func (a *API) GetConfig() *conf.GlobalConfiguration { return a.config }
@@ -114,22 +123,34 @@ func NewAPIWithVersion(globalConfig *conf.GlobalConfiguration, db *storage.Conne
 	}
 	if api.mailer == nil {
 		tc := templatemailer.NewCache()
-		api.mailer = templatemailer.FromConfig(globalConfig, tc)
+		if globalConfig.Mailer.Queue.Enabled {
+			api.mailer = templatemailer.FromQueue(globalConfig, queueclient.New(db), tc).WithDatabase(db, api.tenantID)
+		} else {
+			api.mailer = templatemailer.FromConfig(globalConfig, tc).WithDatabase(db, api.tenantID)
+		}
 	}
 
 	// Connect token service to API's time function (supports test overrides)
 	api.tokenService.SetTimeFunc(api.Now)
 
+	// Record where sessions are signed in from and notify users of
+	// sign-ins from new locations
+	if locator := geoip.New(&globalConfig.GeoIP); locator != nil {
+		api.tokenService.SetLocator(locator)
+	}
+	api.tokenService.SetSignInFunc(api.notifyNewSignIn)
+	api.tokenService.SetRiskFunc(api.assessSignInRisk)
+
 	// Initialize OAuth server (only if enabled)
 	if globalConfig.OAuthServer.Enabled {
-		api.oauthServer = oauthserver.NewServer(globalConfig, db, api.tokenService)
+		api.oauthServer = oauthserver.NewServer(globalConfig, db, api.tokenService, api.hooksMgr)
 	}
 
 	if api.config.Password.HIBP.Enabled {
 		httpClient := &http.Client{
 			// all HIBP API requests should finish quickly to avoid
 			// unnecessary slowdowns
-			Timeout: 5 * time.Second,
+			Timeout: api.config.Password.HIBP.Timeout,
 		}
 
 		api.hibpClient = &hibp.PwnedClient{
@@ -163,6 +184,7 @@ func NewAPIWithVersion(globalConfig *conf.GlobalConfiguration, db *storage.Conne
 		),
 	)
 	r.UseBypass(logger)
+	r.UseBypass(api.enforceIPAccess)
 	r.UseBypass(xffmw.Handler)
 
 	if globalConfig.API.MaxRequestDuration > 0 {
@@ -174,6 +196,10 @@ func NewAPIWithVersion(globalConfig *conf.GlobalConfiguration, db *storage.Conne
 		r.UseBypass(observability.RequestTracing())
 	}
 
+	if globalConfig.DB.ReplicaURL != "" {
+		r.UseBypass(replicaReads)
+	}
+
 	if globalConfig.DB.CleanupEnabled {
 		cleanup := models.NewCleanup(globalConfig)
 		r.UseBypass(api.databaseCleanup(cleanup))
@@ -184,7 +210,10 @@ func NewAPIWithVersion(globalConfig *conf.GlobalConfiguration, db *storage.Conne
 	}
 
 	r.Get("/health", api.HealthCheck)
+	r.Get("/health/live", api.HealthCheck)
+	r.Get("/health/ready", api.HealthReady)
 	r.Get("/.well-known/jwks.json", api.WellKnownJwks)
+	r.Get("/token-exchange/jwks", api.TokenExchangeJwks)
 
 	// Both OIDC Discovery and OAuth Authorization Server Metadata use the same unified handler
 	// OIDC Discovery is an extension of RFC 8414, so one response satisfies both specs
@@ -193,6 +222,10 @@ func NewAPIWithVersion(globalConfig *conf.GlobalConfiguration, db *storage.Conne
 		r.Get("/.well-known/oauth-authorization-server", api.WellKnownOpenID)
 	}
 
+	if globalConfig.Metrics.Enabled && globalConfig.Metrics.Exporter == conf.Prometheus && globalConfig.Metrics.EndpointEnabled {
+		r.Get("/metrics", api.Metrics)
+	}
+
 	r.Route("/callback", func(r *router) {
 		r.Use(api.isValidExternalHost)
 		r.Use(api.loadFlowState)
@@ -204,6 +237,7 @@ func NewAPIWithVersion(globalConfig *conf.GlobalConfiguration, db *storage.Conne
 	r.Route("/", func(r *router) {
 
 		r.Use(api.isValidExternalHost)
+		r.Use(api.limitByRules)
 
 		r.Get("/settings", api.Settings)
 
@@ -253,6 +287,8 @@ func NewAPIWithVersion(globalConfig *conf.GlobalConfiguration, db *storage.Conne
 		// rate limiting applied in handler
 		r.With(api.verifyCaptcha).Post("/token", api.Token)
 
+		r.With(api.limitHandler(api.limiterOpts.Web3)).Post("/web3/nonce", api.Web3Nonce)
+
 		r.With(api.limitHandler(api.limiterOpts.Verify)).Route("/verify", func(r *router) {
 			r.Get("/", api.Verify)
 			r.Post("/", api.Verify)
@@ -260,20 +296,41 @@ func NewAPIWithVersion(globalConfig *conf.GlobalConfiguration, db *storage.Conne
 
 		r.With(api.requireAuthentication).Post("/logout", api.Logout)
 
+		r.With(api.limitHandler(api.limiterOpts.Token)).
+			With(api.optionalOAuthClientAuth).Post("/revoke", api.Revoke)
+
 		r.With(api.requireAuthentication).Route("/reauthenticate", func(r *router) {
 			r.Get("/", api.Reauthenticate)
 		})
 
+		// export downloads are authenticated by the signature of their link
+		r.With(api.requireUserExportEnabled).Get("/user/export/{export_id}/download", api.UserExportDownload)
+
+		// so are the links of new sign-in notifications
+		r.Get("/user/sessions/{session_id}/report", api.ReportSessionConfirm)
+		r.Post("/user/sessions/{session_id}/report", api.ReportSession)
+
 		r.With(api.requireAuthentication).Route("/user", func(r *router) {
 			r.Get("/", api.UserGet)
 			r.With(api.limitHandler(api.limiterOpts.User)).Put("/", api.UserUpdate)
+			r.With(api.requireUserDeletionEnabled).With(api.limitHandler(api.limiterOpts.User)).Delete("/", api.UserDelete)
+			r.With(api.requireUserExportEnabled).Get("/export", api.UserExport)
 
 			r.Route("/identities", func(r *router) {
 				r.Use(api.requireManualLinkingEnabled)
+				r.Get("/", api.ListIdentities)
 				r.Get("/authorize", api.LinkIdentity)
 				r.Delete("/{identity_id}", api.DeleteIdentity)
 			})
 
+			r.Route("/sessions", func(r *router) {
+				r.Get("/", api.ListSessions)
+				r.Delete("/", api.RevokeOtherSessions)
+				r.Delete("/{session_id}", api.RevokeSession)
+			})
+
+			r.Get("/provider_tokens/{provider}", api.GetProviderToken)
+
 			// OAuth grant management endpoints (only if OAuth server is enabled)
 			if globalConfig.OAuthServer.Enabled {
 				r.Route("/oauth/grants", func(r *router) {
@@ -283,9 +340,42 @@ func NewAPIWithVersion(globalConfig *conf.GlobalConfiguration, db *storage.Conne
 			}
 		})
 
+		r.With(api.requireAuthentication).Route("/organizations", func(r *router) {
+			r.Use(api.requireOrganizationsEnabled)
+			r.Use(api.requireNotAnonymous)
+			r.Get("/", api.UserOrganizations)
+			r.Post("/", api.CreateOrganization)
+
+			r.Route("/{organization_id}", func(r *router) {
+				r.Use(api.loadOrganizationMembership)
+				r.Get("/", api.organizationGet)
+				r.Put("/", api.organizationUpdate)
+				r.Delete("/", api.organizationDelete)
+
+				r.Route("/members", func(r *router) {
+					r.Get("/", api.organizationMembers)
+					r.Post("/", api.organizationMemberAdd)
+					r.Put("/{user_id}", api.organizationMemberUpdate)
+					r.Delete("/{user_id}", api.organizationMemberRemove)
+				})
+			})
+		})
+
 		r.With(api.requireAuthentication).Route("/factors", func(r *router) {
 			r.Use(api.requireNotAnonymous)
 			r.Post("/", api.EnrollFactor)
+			r.With(api.limitHandler(api.limiterOpts.FactorChallenge)).
+				Post("/step_up", api.StepUp)
+			r.Route("/recovery_codes", func(r *router) {
+				r.Get("/", api.GetRecoveryCodes)
+				r.Post("/", api.RegenerateRecoveryCodes)
+			})
+			r.Route("/trusted_devices", func(r *router) {
+				r.Get("/", api.ListTrustedDevices)
+				r.With(api.limitHandler(api.limiterOpts.FactorVerify)).
+					Post("/verify", api.VerifyTrustedDevice)
+				r.Delete("/{device_id}", api.DeleteTrustedDevice)
+			})
 			r.Route("/{factor_id}", func(r *router) {
 				r.Use(api.loadFactor)
 
@@ -308,6 +398,9 @@ func NewAPIWithVersion(globalConfig *conf.GlobalConfiguration, db *storage.Conne
 
 				r.With(api.limitHandler(api.limiterOpts.SAMLAssertion)).
 					Post("/acs", api.SamlAcs)
+
+				r.With(api.limitHandler(api.limiterOpts.SAMLAssertion)).
+					Post("/slo", api.SAMLSingleLogout)
 			})
 		})
 
@@ -322,6 +415,15 @@ func NewAPIWithVersion(globalConfig *conf.GlobalConfiguration, db *storage.Conne
 				r.Get("/", api.adminUsers)
 				r.Post("/", api.adminUserCreate)
 
+				r.Get("/export", api.adminUsersExport)
+
+				r.Post("/password_reset", api.adminUsersPasswordReset)
+
+				r.Route("/import", func(r *router) {
+					r.Post("/", api.adminUsersImport)
+					r.Get("/{import_id}", api.adminUsersImportGet)
+				})
+
 				r.Route("/{user_id}", func(r *router) {
 					r.Use(api.loadUser)
 					r.Route("/factors", func(r *router) {
@@ -330,17 +432,131 @@ func NewAPIWithVersion(globalConfig *conf.GlobalConfiguration, db *storage.Conne
 							r.Use(api.loadFactor)
 							r.Delete("/", api.adminUserDeleteFactor)
 							r.Put("/", api.adminUserUpdateFactor)
+							r.Post("/unverify", api.adminUserUnverifyFactor)
 						})
 					})
 
+					r.Route("/recovery_codes", func(r *router) {
+						r.Get("/", api.adminUserGetRecoveryCodes)
+						r.Post("/", api.adminUserRegenerateRecoveryCodes)
+					})
+
 					r.Get("/", api.adminUserGet)
 					r.Put("/", api.adminUserUpdate)
 					r.Delete("/", api.adminUserDelete)
+
+					r.Post("/unlock", api.adminUserUnlock)
+
+					r.Post("/password_reset", api.adminUserPasswordReset)
+
+					r.Route("/ban", func(r *router) {
+						r.Post("/", api.adminUserBan)
+						r.Delete("/", api.adminUserUnban)
+					})
+
+					r.Route("/purge", func(r *router) {
+						r.Post("/", api.adminUserPurge)
+						r.Delete("/", api.adminUserCancelPurge)
+					})
+
+					r.With(api.requireImpersonationEnabled).Post("/impersonate", api.adminUserImpersonate)
+
+					r.Route("/sessions", func(r *router) {
+						r.Get("/", api.adminUserSessions)
+						r.Delete("/", api.adminUserRevokeSessions)
+						r.Delete("/{session_id}", api.adminUserRevokeSession)
+					})
+
+					r.Get("/provider_tokens/{provider}", api.adminUserGetProviderToken)
+
+					r.Route("/roles", func(r *router) {
+						r.Use(api.requireRBACEnabled)
+						r.Get("/", api.adminUserRoles)
+						r.Post("/", api.adminUserRoleAssign)
+						r.Delete("/{role_id}", api.adminUserRoleRemove)
+					})
 				})
 			})
 
 			r.Post("/generate_link", api.adminGenerateLink)
 
+			r.Get("/password_hashes", api.adminPasswordHashes)
+
+			r.Route("/mail/jobs", func(r *router) {
+				r.Get("/", api.adminMailJobs)
+				r.Post("/redrive", api.adminMailJobsRedrive)
+			})
+
+			r.Route("/webhooks/deliveries", func(r *router) {
+				r.Get("/", api.adminWebhookDeliveries)
+				r.Post("/redrive", api.adminWebhookDeliveriesRedrive)
+				r.Get("/{delivery_id}", api.adminWebhookDelivery)
+			})
+
+			r.Route("/email_templates", func(r *router) {
+				r.Get("/", api.adminEmailTemplates)
+
+				r.Route("/{template_type}", func(r *router) {
+					r.Get("/", api.adminEmailTemplateGet)
+					r.Put("/", api.adminEmailTemplateUpdate)
+					r.Delete("/", api.adminEmailTemplateDelete)
+					r.Post("/versions/{version}/activate", api.adminEmailTemplateActivate)
+				})
+			})
+
+			r.Route("/invites", func(r *router) {
+				r.Get("/", api.adminInvites)
+				r.Post("/", api.adminInvitesBulk)
+
+				r.Route("/{user_id}", func(r *router) {
+					r.Use(api.loadUser)
+					r.Post("/resend", api.adminInviteResend)
+				})
+			})
+
+			r.Route("/organizations", func(r *router) {
+				r.Use(api.requireOrganizationsEnabled)
+				r.Get("/", api.adminOrganizations)
+				r.Post("/", api.adminOrganizationCreate)
+
+				r.Route("/{organization_id}", func(r *router) {
+					r.Use(api.loadOrganization)
+					r.Get("/", api.organizationGet)
+					r.Put("/", api.organizationUpdate)
+					r.Delete("/", api.organizationDelete)
+
+					r.Route("/members", func(r *router) {
+						r.Get("/", api.organizationMembers)
+						r.Post("/", api.organizationMemberAdd)
+						r.Put("/{user_id}", api.organizationMemberUpdate)
+						r.Delete("/{user_id}", api.organizationMemberRemove)
+					})
+				})
+			})
+
+			r.Route("/roles", func(r *router) {
+				r.Use(api.requireRBACEnabled)
+				r.Get("/", api.adminRoles)
+				r.Post("/", api.adminRoleCreate)
+
+				r.Route("/{role_id}", func(r *router) {
+					r.Use(api.loadRole)
+					r.Get("/", api.adminRoleGet)
+					r.Put("/", api.adminRoleUpdate)
+					r.Delete("/", api.adminRoleDelete)
+				})
+			})
+
+			r.Route("/provider_domains", func(r *router) {
+				r.Get("/", api.adminProviderDomainRules)
+
+				r.Route("/{provider}", func(r *router) {
+					r.Get("/", api.adminProviderDomainRuleGet)
+					r.Put("/", api.adminProviderDomainRuleUpdate)
+					r.Delete("/", api.adminProviderDomainRuleDelete)
+				})
+			})
+
 			r.Route("/sso", func(r *router) {
 				r.Route("/providers", func(r *router) {
 					r.Get("/", api.adminSSOProvidersList)
@@ -377,8 +593,67 @@ func NewAPIWithVersion(globalConfig *conf.GlobalConfiguration, db *storage.Conne
 			}
 		})
 
+		// Tenant management endpoints, authenticated with the operator token
+		if globalConfig.Tenancy.Enabled {
+			r.Route("/tenants", func(r *router) {
+				r.Use(api.requireOperatorCredentials)
+
+				r.Get("/", api.adminTenantsList)
+				r.Post("/", api.adminTenantsCreate)
+
+				r.Route("/{tenant_id}", func(r *router) {
+					r.Use(api.loadTenant)
+
+					r.Get("/", api.adminTenantsGet)
+					r.Put("/", api.adminTenantsUpdate)
+					r.Delete("/", api.adminTenantsDelete)
+				})
+			})
+		}
+
+		// SCIM 2.0 provisioning endpoints, authenticated with the SCIM bearer tokens
+		if globalConfig.SCIM.Enabled {
+			r.Route("/scim/v2", func(r *router) {
+				r.Use(scimMiddleware(api.requireSCIMCredentials))
+
+				r.Get("/ServiceProviderConfig", scimHandler(api.scimServiceProviderConfig))
+
+				r.Route("/Users", func(r *router) {
+					r.Get("/", scimHandler(api.scimUsersList))
+					r.Post("/", scimHandler(api.scimUsersCreate))
+
+					r.Route("/{user_id}", func(r *router) {
+						r.Use(scimMiddleware(api.loadUser))
+
+						r.Get("/", scimHandler(api.scimUserGet))
+						r.Put("/", scimHandler(api.scimUserReplace))
+						r.Patch("/", scimHandler(api.scimUserPatch))
+						r.Delete("/", scimHandler(api.scimUserDelete))
+					})
+				})
+
+				r.Route("/Groups", func(r *router) {
+					r.Get("/", scimHandler(api.scimGroupsList))
+					r.Post("/", scimHandler(api.scimGroupsCreate))
+
+					r.Route("/{group_id}", func(r *router) {
+						r.Use(scimMiddleware(api.loadSCIMGroup))
+
+						r.Get("/", scimHandler(api.scimGroupGet))
+						r.Put("/", scimHandler(api.scimGroupReplace))
+						r.Patch("/", scimHandler(api.scimGroupPatch))
+						r.Delete("/", scimHandler(api.scimGroupDelete))
+					})
+				})
+			})
+		}
+
 		// OAuth Dynamic Client Registration endpoint (public, rate limited)
 		if globalConfig.OAuthServer.Enabled {
+			// Token introspection (RFC 7662) for resource servers, with
+			// confidential client authentication
+			r.With(api.requireOAuthClientAuth).Post("/introspect", api.Introspect)
+
 			r.Route("/oauth", func(r *router) {
 				r.With(api.limitHandler(api.limiterOpts.OAuthClientRegister)).
 					Post("/clients/register", api.oauthServer.OAuthServerClientDynamicRegister)
@@ -399,9 +674,10 @@ func NewAPIWithVersion(globalConfig *conf.GlobalConfiguration, db *storage.Conne
 	})
 
 	corsHandler := cors.New(cors.Options{
+		AllowedOrigins:   globalConfig.CORS.AllowedOrigins,
 		AllowedMethods:   []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodDelete},
-		AllowedHeaders:   globalConfig.CORS.AllAllowedHeaders([]string{"Accept", "Authorization", "Content-Type", "X-Client-IP", "X-Client-Info", audHeaderName, useCookieHeader, APIVersionHeaderName}),
-		ExposedHeaders:   []string{"X-Total-Count", "Link", APIVersionHeaderName},
+		AllowedHeaders:   globalConfig.CORS.AllAllowedHeaders([]string{"Accept", "Authorization", "Content-Type", "X-Client-IP", "X-Client-Info", audHeaderName, useCookieHeader, csrfHeaderName, APIVersionHeaderName}),
+		ExposedHeaders:   []string{"X-Total-Count", "Link", APIVersionHeaderName, csrfHeaderName},
 		AllowCredentials: true,
 	})
 
@@ -415,7 +691,9 @@ type HealthCheckResponse struct {
 	Description string `json:"description"`
 }
 
-// HealthCheck endpoint indicates if the gotrue api service is available
+// HealthCheck endpoint indicates if the gotrue api service is available. It
+// is served as /health and as the liveness endpoint /health/live, and does not
+// check the dependencies of the service.
 func (a *API) HealthCheck(w http.ResponseWriter, r *http.Request) error {
 	return sendJSON(w, http.StatusOK, HealthCheckResponse{
 		Version:     a.version,
@@ -1,6 +1,8 @@
 package api
 
 import (
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -92,3 +94,30 @@ func TestOAuthServerCanBeEnabled(t *testing.T) {
 	// OAuth server instance should be initialized when enabled
 	require.NotNil(t, api.oauthServer)
 }
+
+func TestCORSAllowedOrigins(t *testing.T) {
+	api, _, err := setupAPIForTestWithCallback(func(config *conf.GlobalConfiguration, conn *storage.Connection) {
+		if config != nil {
+			config.CORS.AllowedOrigins = []string{"https://app.example.com"}
+		}
+	})
+	require.NoError(t, err)
+
+	preflight := func(origin string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodOptions, "http://localhost/token", nil)
+		req.Header.Set("Origin", origin)
+		req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+		w := httptest.NewRecorder()
+		api.handler.ServeHTTP(w, req)
+		return w
+	}
+
+	// the origin is returned rather than *, which browsers reject for
+	// requests with credentials
+	w := preflight("https://app.example.com")
+	require.Equal(t, "https://app.example.com", w.Header().Get("Access-Control-Allow-Origin"))
+	require.Equal(t, "true", w.Header().Get("Access-Control-Allow-Credentials"))
+
+	w = preflight("https://evil.example.com")
+	require.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+}
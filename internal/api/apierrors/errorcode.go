@@ -28,6 +28,7 @@ const (
 	ErrorCodeOAuthInvalidState                 ErrorCode = "oauth_invalid_state"
 	ErrorCodeSignupDisabled                    ErrorCode = "signup_disabled"
 	ErrorCodeUserBanned                        ErrorCode = "user_banned"
+	ErrorCodePasswordResetRequired             ErrorCode = "password_reset_required"
 	ErrorCodeProviderEmailNeedsVerification    ErrorCode = "provider_email_needs_verification"
 	ErrorCodeInviteNotFound                    ErrorCode = "invite_not_found"
 	ErrorCodeBadOAuthState                     ErrorCode = "bad_oauth_state"
@@ -46,6 +47,7 @@ const (
 	ErrorCodeMFAChallengeExpired               ErrorCode = "mfa_challenge_expired"
 	ErrorCodeMFAVerificationFailed             ErrorCode = "mfa_verification_failed"
 	ErrorCodeMFAVerificationRejected           ErrorCode = "mfa_verification_rejected"
+	ErrorCodeMFAAdditionalVerification         ErrorCode = "mfa_additional_verification_required"
 	ErrorCodeInsufficientAAL                   ErrorCode = "insufficient_aal"
 	ErrorCodeCaptchaFailed                     ErrorCode = "captcha_failed"
 	ErrorCodeSAMLProviderDisabled              ErrorCode = "saml_provider_disabled"
@@ -92,16 +94,70 @@ const (
 	ErrorCodeMFAWebAuthnEnrollDisabled         ErrorCode = "mfa_webauthn_enroll_not_enabled"
 	ErrorCodeMFAWebAuthnVerifyDisabled         ErrorCode = "mfa_webauthn_verify_not_enabled"
 	ErrorCodeMFAVerifiedFactorExists           ErrorCode = "mfa_verified_factor_exists"
+	ErrorCodeMFARecoveryCodesDisabled          ErrorCode = "mfa_recovery_codes_not_enabled"
+	ErrorCodeMFATrustedDevicesDisabled         ErrorCode = "mfa_trusted_devices_not_enabled"
+	ErrorCodeMFATrustedDeviceNotFound          ErrorCode = "mfa_trusted_device_not_found"
 	//#nosec G101 -- Not a secret value.
 	ErrorCodeInvalidCredentials                     ErrorCode = "invalid_credentials"
 	ErrorCodeEmailAddressNotAuthorized              ErrorCode = "email_address_not_authorized"
+	ErrorCodeEmailDomainNotAllowed                  ErrorCode = "email_domain_not_allowed"
 	ErrorCodeEmailAddressInvalid                    ErrorCode = "email_address_invalid"
 	ErrorCodeWeb3ProviderDisabled                   ErrorCode = "web3_provider_disabled"
 	ErrorCodeWeb3UnsupportedChain                   ErrorCode = "web3_unsupported_chain"
+	ErrorCodeTelegramProviderDisabled               ErrorCode = "telegram_provider_disabled"
 	ErrorCodeOAuthDynamicClientRegistrationDisabled ErrorCode = "oauth_dynamic_client_registration_disabled"
 	ErrorCodeEmailAddressNotProvided                ErrorCode = "email_address_not_provided"
+	ErrorCodeEmailLinkDisabled                      ErrorCode = "email_link_disabled"
 
 	ErrorCodeOAuthClientNotFound        ErrorCode = "oauth_client_not_found"
 	ErrorCodeOAuthAuthorizationNotFound ErrorCode = "oauth_authorization_not_found"
 	ErrorCodeOAuthConsentNotFound       ErrorCode = "oauth_consent_not_found"
+
+	ErrorCodeTenantNotFound        ErrorCode = "tenant_not_found"
+	ErrorCodeUserImportNotFound    ErrorCode = "user_import_not_found"
+	ErrorCodeEmailTemplateNotFound ErrorCode = "email_template_not_found"
+
+	ErrorCodeProviderDomainRuleNotFound ErrorCode = "provider_domain_rule_not_found"
+
+	ErrorCodeOrganizationsDisabled        ErrorCode = "organizations_disabled"
+	ErrorCodeOrganizationNotFound         ErrorCode = "organization_not_found"
+	ErrorCodeOrganizationSlugExists       ErrorCode = "organization_slug_exists"
+	ErrorCodeOrganizationMemberNotFound   ErrorCode = "organization_member_not_found"
+	ErrorCodeOrganizationMemberExists     ErrorCode = "organization_member_exists"
+	ErrorCodeOrganizationOwnerRequired    ErrorCode = "organization_owner_required"
+	ErrorCodeOrganizationPermissionDenied ErrorCode = "organization_permission_denied"
+
+	ErrorCodeRBACDisabled     ErrorCode = "rbac_disabled"
+	ErrorCodeRoleNotFound     ErrorCode = "role_not_found"
+	ErrorCodeRoleExists       ErrorCode = "role_exists"
+	ErrorCodeUserRoleNotFound ErrorCode = "user_role_not_found"
+
+	ErrorCodeImpersonationDisabled ErrorCode = "impersonation_disabled"
+
+	ErrorCodeUserDeleted    ErrorCode = "user_deleted"
+	ErrorCodeUserNotDeleted ErrorCode = "user_not_deleted"
+
+	ErrorCodeUserDeletionDisabled ErrorCode = "user_deletion_disabled"
+
+	ErrorCodeUserExportDisabled ErrorCode = "user_export_disabled"
+	ErrorCodeUserExportNotFound ErrorCode = "user_export_not_found"
+
+	ErrorCodeWebhookDeliveryNotFound ErrorCode = "webhook_delivery_not_found"
+
+	ErrorCodeSignupRejected ErrorCode = "signup_rejected"
+	ErrorCodeSignInRejected ErrorCode = "sign_in_rejected"
+
+	ErrorCodeSignInBlocked             ErrorCode = "sign_in_blocked"
+	ErrorCodeEmailVerificationRequired ErrorCode = "email_verification_required"
+
+	ErrorCodeProviderTokenNotFound    ErrorCode = "provider_token_not_found"
+	ErrorCodeProviderTokenExpired     ErrorCode = "provider_token_expired"
+	ErrorCodeProviderTenantNotAllowed ErrorCode = "provider_tenant_not_allowed"
+
+	ErrorCodeBadCSRFToken ErrorCode = "bad_csrf_token"
+
+	ErrorCodeTokenExchangeDisabled ErrorCode = "token_exchange_disabled"
+	ErrorCodeInvalidTarget         ErrorCode = "invalid_target"
+
+	ErrorCodeIPAddressNotAllowed ErrorCode = "ip_address_not_allowed"
 )
@@ -56,7 +56,8 @@ func Func(typ string, fn func(context.Context) error) Task {
 // immediately if the current context supports it. Otherwise it makes an
 // immediate blocking call to task.Run(ctx).
 //
-// It is invalid to call Run within a tasks Run method.
+// Calls to Run within a tasks Run method are blocking, as the context given
+// to tasks does not support background tasks.
 func Run(ctx context.Context, task Task) error {
 	wrk, ok := from(ctx)
 	if !ok {
@@ -121,6 +122,9 @@ func (o *requestWorker) run(ctx context.Context, task Task) error {
 	go func() {
 		defer o.wg.Done()
 
+		// tasks may outlive the call to Wait, so the tasks they run are
+		// not added to this worker
+		ctx := context.WithValue(ctx, ctxKey, (*requestWorker)(nil))
 		if err := task.Run(ctx); err != nil {
 			typ := task.Type()
 			err = fmt.Errorf("apitask: error running %q: %w", typ, err)
@@ -61,6 +61,32 @@ func TestRun(t *testing.T) {
 			require.Equal(t, expCalls, gotCalls)
 		}
 	})
+	t.Run("Nested", func(t *testing.T) {
+		withCtx := With(ctx)
+
+		nested := make(chan error, 1)
+		release := make(chan struct{})
+		task := Func("test.run", func(ctx context.Context) error {
+			<-release
+
+			// runs inline although Wait was already called
+			calls := 0
+			nested <- Run(ctx, Func("test.nested", func(ctx context.Context) error {
+				calls++
+				return nil
+			}))
+			require.Equal(t, 1, calls)
+			return nil
+		})
+		require.NoError(t, Run(withCtx, task))
+
+		go func() {
+			time.Sleep(time.Millisecond * 10)
+			close(release)
+		}()
+		Wait(withCtx)
+		require.NoError(t, <-nested)
+	})
 	t.Run("Failure", func(t *testing.T) {
 
 		// exp no errors when async
@@ -7,10 +7,17 @@ import (
 	"time"
 
 	"github.com/sirupsen/logrus"
+	"github.com/supabase/auth/internal/auditretention"
 	"github.com/supabase/auth/internal/conf"
 	"github.com/supabase/auth/internal/indexworker"
+	"github.com/supabase/auth/internal/mailer"
+	"github.com/supabase/auth/internal/mailer/queueclient"
 	"github.com/supabase/auth/internal/mailer/templatemailer"
+	"github.com/supabase/auth/internal/providertokens"
+	"github.com/supabase/auth/internal/reaper"
 	"github.com/supabase/auth/internal/storage"
+	"github.com/supabase/auth/internal/userpurge"
+	"github.com/supabase/auth/internal/webhooks"
 	"golang.org/x/sync/errgroup"
 )
 
@@ -81,9 +88,15 @@ func (o *Worker) Work(ctx context.Context) error {
 		notifyTpl = make(chan struct{}, 1)
 		notifyDb  = make(chan struct{}, 1)
 		notifyIdx = make(chan struct{}, 1)
+		notifyMq  = make(chan struct{}, 1)
+		notifyAr  = make(chan struct{}, 1)
+		notifyPt  = make(chan struct{}, 1)
+		notifyUp  = make(chan struct{}, 1)
+		notifyWh  = make(chan struct{}, 1)
+		notifyRp  = make(chan struct{}, 1)
 	)
 	eg.Go(func() error {
-		return o.configNotifier(ctx, notifyTpl, notifyDb, notifyIdx)
+		return o.configNotifier(ctx, notifyTpl, notifyDb, notifyIdx, notifyMq, notifyAr, notifyPt, notifyUp, notifyWh, notifyRp)
 	})
 	eg.Go(func() error {
 		return o.templateWorker(ctx, notifyTpl)
@@ -94,6 +107,24 @@ func (o *Worker) Work(ctx context.Context) error {
 	eg.Go(func() error {
 		return o.indexWorker(ctx, notifyIdx)
 	})
+	eg.Go(func() error {
+		return o.mailQueueWorker(ctx, notifyMq)
+	})
+	eg.Go(func() error {
+		return o.auditRetentionWorker(ctx, notifyAr)
+	})
+	eg.Go(func() error {
+		return o.providerTokenRefreshWorker(ctx, notifyPt)
+	})
+	eg.Go(func() error {
+		return o.userPurgeWorker(ctx, notifyUp)
+	})
+	eg.Go(func() error {
+		return o.webhookWorker(ctx, notifyWh)
+	})
+	eg.Go(func() error {
+		return o.reaperWorker(ctx, notifyRp)
+	})
 	return eg.Wait()
 }
 
@@ -222,3 +253,365 @@ func (o *Worker) maybeCreateIndexes(
 		}
 	}
 }
+
+// mailQueueWorker sends the mail queued by requests when the mailer queue is
+// enabled.
+func (o *Worker) mailQueueWorker(ctx context.Context, cfgCh <-chan struct{}) error {
+	le := o.le.WithFields(logrus.Fields{
+		"worker_type": "apiworker_mail_queue_worker",
+	})
+	le.Info("apiworker: mail queue worker started")
+	defer le.Info("apiworker: mail queue worker exited")
+
+	// mc sends the mail with the current config, it is created on first use
+	cfg := o.getConfig()
+	var mc mailer.Client
+
+	ival := func() time.Duration {
+		return max(time.Millisecond, cfg.Mailer.Queue.PollInterval)
+	}
+
+	tr := time.NewTicker(ival())
+	defer tr.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-cfgCh:
+			cfg = o.getConfig()
+			mc = nil
+			tr.Reset(ival())
+			continue
+		case <-tr.C:
+		}
+
+		if cfg.Mailer.Queue.Enabled {
+			if mc == nil {
+				mc = templatemailer.NewClient(cfg)
+			}
+			o.sendQueuedMail(ctx, cfg, mc, le)
+		}
+	}
+}
+
+// sendQueuedMail sends queued mail until none is due.
+func (o *Worker) sendQueuedMail(
+	ctx context.Context,
+	cfg *conf.GlobalConfiguration,
+	mc mailer.Client,
+	le *logrus.Entry,
+) {
+	for ctx.Err() == nil {
+		n, err := queueclient.Process(ctx, o.db, mc, &cfg.Mailer.Queue, le)
+		if err != nil {
+			le.WithError(err).Error("Failed to send queued mail")
+			return
+		}
+		if n < cfg.Mailer.Queue.BatchSize {
+			return
+		}
+	}
+}
+
+// auditRetentionWorker prunes the audit log entries older than the retention
+// when audit log retention is enabled.
+func (o *Worker) auditRetentionWorker(ctx context.Context, cfgCh <-chan struct{}) error {
+	le := o.le.WithFields(logrus.Fields{
+		"worker_type": "apiworker_audit_retention_worker",
+	})
+	le.Info("apiworker: audit retention worker started")
+	defer le.Info("apiworker: audit retention worker exited")
+
+	cfg := o.getConfig()
+
+	ival := func() time.Duration {
+		return max(time.Second, cfg.AuditLog.Retention.Interval)
+	}
+
+	tr := time.NewTicker(ival())
+	defer tr.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-cfgCh:
+			cfg = o.getConfig()
+			tr.Reset(ival())
+			continue
+		case <-tr.C:
+		}
+
+		if cfg.AuditLog.Retention.Enabled {
+			o.pruneAuditLog(ctx, cfg, le)
+		}
+	}
+}
+
+// pruneAuditLog prunes batches of expired audit log entries until none is
+// left.
+func (o *Worker) pruneAuditLog(
+	ctx context.Context,
+	cfg *conf.GlobalConfiguration,
+	le *logrus.Entry,
+) {
+	for ctx.Err() == nil {
+		n, err := auditretention.Prune(ctx, o.db, &cfg.AuditLog.Retention, time.Now(), le)
+		if err != nil {
+			le.WithError(err).Error("Failed to prune the audit log")
+			return
+		}
+		if n < cfg.AuditLog.Retention.BatchSize {
+			return
+		}
+	}
+}
+
+// providerTokenRefreshWorker refreshes the stored OAuth provider tokens
+// before they expire when provider token storage is enabled.
+func (o *Worker) providerTokenRefreshWorker(ctx context.Context, cfgCh <-chan struct{}) error {
+	le := o.le.WithFields(logrus.Fields{
+		"worker_type": "apiworker_provider_token_refresh_worker",
+	})
+	le.Info("apiworker: provider token refresh worker started")
+	defer le.Info("apiworker: provider token refresh worker exited")
+
+	cfg := o.getConfig()
+
+	ival := func() time.Duration {
+		return max(time.Second, cfg.External.ProviderTokens.RefreshInterval)
+	}
+
+	tr := time.NewTicker(ival())
+	defer tr.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-cfgCh:
+			cfg = o.getConfig()
+			tr.Reset(ival())
+			continue
+		case <-tr.C:
+		}
+
+		if cfg.External.ProviderTokens.Enabled {
+			o.refreshProviderTokens(ctx, cfg, le)
+		}
+	}
+}
+
+// refreshProviderTokens refreshes batches of expiring provider tokens until
+// none is left. Tokens refreshed during the run are not refreshed again.
+func (o *Worker) refreshProviderTokens(
+	ctx context.Context,
+	cfg *conf.GlobalConfiguration,
+	le *logrus.Entry,
+) {
+	now := time.Now()
+	for ctx.Err() == nil {
+		n, err := providertokens.RefreshExpiring(ctx, o.db, cfg, now, le)
+		if err != nil {
+			le.WithError(err).Error("Failed to refresh provider tokens")
+			return
+		}
+		if n < cfg.External.ProviderTokens.BatchSize {
+			return
+		}
+	}
+}
+
+// userPurgeWorker permanently deletes the soft deleted users whose grace
+// period has ended when purging users is enabled.
+func (o *Worker) userPurgeWorker(ctx context.Context, cfgCh <-chan struct{}) error {
+	le := o.le.WithFields(logrus.Fields{
+		"worker_type": "apiworker_user_purge_worker",
+	})
+	le.Info("apiworker: user purge worker started")
+	defer le.Info("apiworker: user purge worker exited")
+
+	cfg := o.getConfig()
+
+	ival := func() time.Duration {
+		return max(time.Second, cfg.UserDeletion.PurgeInterval)
+	}
+
+	tr := time.NewTicker(ival())
+	defer tr.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-cfgCh:
+			cfg = o.getConfig()
+			tr.Reset(ival())
+			continue
+		case <-tr.C:
+		}
+
+		if cfg.UserDeletion.PurgeEnabled {
+			o.purgeUsers(ctx, cfg, le)
+		}
+	}
+}
+
+// purgeUsers purges batches of soft deleted users until none is due.
+func (o *Worker) purgeUsers(
+	ctx context.Context,
+	cfg *conf.GlobalConfiguration,
+	le *logrus.Entry,
+) {
+	for ctx.Err() == nil {
+		n, err := userpurge.Purge(ctx, o.db, &cfg.UserDeletion, time.Now(), le)
+		if err != nil {
+			le.WithError(err).Error("Failed to purge soft deleted users")
+			return
+		}
+		if n < cfg.UserDeletion.BatchSize {
+			return
+		}
+	}
+}
+
+// reaperWorker prunes the expired rows when the reaper is enabled.
+func (o *Worker) reaperWorker(ctx context.Context, cfgCh <-chan struct{}) error {
+	le := o.le.WithFields(logrus.Fields{
+		"worker_type": "apiworker_reaper_worker",
+	})
+	le.Info("apiworker: reaper worker started")
+	defer le.Info("apiworker: reaper worker exited")
+
+	cfg := o.getConfig()
+
+	ival := func() time.Duration {
+		return max(time.Second, cfg.Reaper.Interval)
+	}
+
+	tr := time.NewTicker(ival())
+	defer tr.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-cfgCh:
+			cfg = o.getConfig()
+			tr.Reset(ival())
+			continue
+		case <-tr.C:
+		}
+
+		if cfg.Reaper.Enabled {
+			if _, err := reaper.Prune(ctx, o.db, cfg, time.Now(), le); err != nil && ctx.Err() == nil {
+				le.WithError(err).Error("Failed to prune expired rows")
+			}
+		}
+	}
+}
+
+// webhookWorker sends the webhook events queued by requests and prunes the
+// delivery log when webhooks are enabled.
+func (o *Worker) webhookWorker(ctx context.Context, cfgCh <-chan struct{}) error {
+	le := o.le.WithFields(logrus.Fields{
+		"worker_type": "apiworker_webhook_worker",
+	})
+	le.Info("apiworker: webhook worker started")
+	defer le.Info("apiworker: webhook worker exited")
+
+	// publishers send the events with the current config, they are created
+	// on first use
+	cfg := o.getConfig()
+	var publishers webhooks.Publishers
+	var prunedAt time.Time
+
+	ival := func() time.Duration {
+		return max(time.Millisecond, cfg.Webhooks.PollInterval)
+	}
+
+	tr := time.NewTicker(ival())
+	defer tr.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-cfgCh:
+			cfg = o.getConfig()
+			publishers = nil
+			tr.Reset(ival())
+			continue
+		case <-tr.C:
+		}
+
+		if !cfg.Webhooks.Enabled {
+			continue
+		}
+		if publishers == nil {
+			publishers = webhooks.NewPublishers(&cfg.Webhooks, o.db)
+		}
+		o.sendWebhookEvents(ctx, cfg, publishers, le)
+
+		// the delivery log is pruned hourly
+		if now := time.Now(); now.Sub(prunedAt) >= time.Hour {
+			o.pruneWebhookDeliveries(ctx, cfg, now, le)
+			prunedAt = now
+		}
+	}
+}
+
+// sendWebhookEvents sends queued webhook events until none is due.
+func (o *Worker) sendWebhookEvents(
+	ctx context.Context,
+	cfg *conf.GlobalConfiguration,
+	publishers webhooks.Publishers,
+	le *logrus.Entry,
+) {
+	for ctx.Err() == nil {
+		n, err := webhooks.Process(ctx, o.db, publishers, &cfg.Webhooks, le)
+		if err != nil {
+			le.WithError(err).Error("Failed to send webhook events")
+			return
+		}
+		if n < cfg.Webhooks.BatchSize {
+			return
+		}
+	}
+}
+
+// FlushWebhooks sends the webhook events which are due, such as the ones
+// queued by the requests drained on shutdown, until ctx is done. It is meant
+// to be called once Work returned.
+func (o *Worker) FlushWebhooks(ctx context.Context) {
+	cfg := o.getConfig()
+	if !cfg.Webhooks.Enabled {
+		return
+	}
+
+	le := o.le.WithFields(logrus.Fields{
+		"worker_type": "apiworker_webhook_worker",
+	})
+	o.sendWebhookEvents(ctx, cfg, webhooks.NewPublishers(&cfg.Webhooks, o.db), le)
+}
+
+// pruneWebhookDeliveries prunes batches of delivered webhook events older
+// than the retention until none is left.
+func (o *Worker) pruneWebhookDeliveries(
+	ctx context.Context,
+	cfg *conf.GlobalConfiguration,
+	now time.Time,
+	le *logrus.Entry,
+) {
+	for ctx.Err() == nil {
+		n, err := webhooks.Prune(ctx, o.db, &cfg.Webhooks, now)
+		if err != nil {
+			le.WithError(err).Error("Failed to prune the webhook delivery log")
+			return
+		}
+		if n < webhooks.PruneBatchSize {
+			return
+		}
+	}
+}
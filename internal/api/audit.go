@@ -1,9 +1,13 @@
 package api
 
 import (
+	"fmt"
 	"net/http"
+	"net/url"
 	"strings"
+	"time"
 
+	"github.com/gofrs/uuid"
 	"github.com/supabase/auth/internal/api/apierrors"
 	"github.com/supabase/auth/internal/models"
 )
@@ -14,35 +18,159 @@ var filterColumnMap = map[string][]string{
 	"type":   {"log_type"},
 }
 
-func (a *API) adminAuditLog(w http.ResponseWriter, r *http.Request) error {
-	ctx := r.Context()
-	db := a.db.WithContext(ctx)
+// parseAuditLogFilter parses the actor_id, action, user_id, ip_address, from
+// and to query parameters of an audit log search.
+func parseAuditLogFilter(query url.Values) (*models.AuditLogFilter, error) {
+	filter := &models.AuditLogFilter{
+		Action:    query.Get("action"),
+		IPAddress: query.Get("ip_address"),
+	}
 
-	// aud := a.requestAud(ctx, r)
-	pageParams, err := paginate(r)
-	if err != nil {
-		return apierrors.NewBadRequestError(apierrors.ErrorCodeValidationFailed, "Bad Pagination Parameters: %v", err)
+	for name, dst := range map[string]**uuid.UUID{
+		"actor_id": &filter.ActorID,
+		"user_id":  &filter.TargetUserID,
+	} {
+		if value := query.Get(name); value != "" {
+			id, err := uuid.FromString(value)
+			if err != nil {
+				return nil, apierrors.NewBadRequestError(apierrors.ErrorCodeValidationFailed, "%s must be an UUID", name)
+			}
+			*dst = &id
+		}
+	}
+
+	for name, dst := range map[string]**time.Time{
+		"from": &filter.From,
+		"to":   &filter.To,
+	} {
+		if value := query.Get(name); value != "" {
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				return nil, apierrors.NewBadRequestError(apierrors.ErrorCodeValidationFailed, "%s must be an RFC 3339 timestamp", name)
+			}
+			*dst = &t
+		}
 	}
 
+	return filter, nil
+}
+
+// auditLogPage is a page of an audit log search.
+type auditLogPage struct {
+	Entries []*models.AuditLogEntry
+
+	// Pagination is set with offset pagination.
+	Pagination *models.Pagination
+
+	// Next is the ID of the last entry with keyset pagination when the page
+	// is full, the next page is listed after it.
+	Next *uuid.UUID
+}
+
+// findAuditLog searches the audit log with the query parameters of the
+// request. It is shared by the REST and gRPC admin APIs.
+func (a *API) findAuditLog(r *http.Request) (*auditLogPage, error) {
+	ctx := r.Context()
+	db := a.db.WithContext(ctx)
+	query := r.URL.Query()
+
 	var col []string
 	var qval string
-	q := r.URL.Query().Get("query")
+	q := query.Get("query")
 	if q != "" {
 		var exists bool
 		qparts := strings.SplitN(q, ":", 2)
 		col, exists = filterColumnMap[qparts[0]]
 		if !exists || len(qparts) < 2 {
-			return apierrors.NewBadRequestError(apierrors.ErrorCodeValidationFailed, "Invalid query scope: %s", q)
+			return nil, apierrors.NewBadRequestError(apierrors.ErrorCodeValidationFailed, "Invalid query scope: %s", q)
 		}
 		qval = qparts[1]
 	}
 
-	logs, err := models.FindAuditLogEntries(db, col, qval, pageParams)
+	filter, err := parseAuditLogFilter(query)
+	if err != nil {
+		return nil, err
+	}
+
+	// aud := a.requestAud(ctx, r)
+	pageParams, err := paginate(r)
+	if err != nil {
+		return nil, apierrors.NewBadRequestError(apierrors.ErrorCodeValidationFailed, "Bad Pagination Parameters: %v", err)
+	}
+
+	if query.Has("after") {
+		return a.findAuditLogAfter(r, col, qval, filter, pageParams.PerPage)
+	}
+
+	logs, err := models.FindAuditLogEntries(db, col, qval, filter, pageParams)
 	if err != nil {
-		return apierrors.NewInternalServerError("Error searching for audit logs").WithInternalError(err)
+		return nil, apierrors.NewInternalServerError("Error searching for audit logs").WithInternalError(err)
+	}
+
+	return &auditLogPage{Entries: logs, Pagination: pageParams}, nil
+}
+
+// findAuditLogAfter lists the audit log with keyset pagination, newest
+// first. The first page is requested with an empty after query parameter,
+// the next page by passing the ID of the last entry received. The total is
+// not counted.
+func (a *API) findAuditLogAfter(r *http.Request, col []string, qval string, filter *models.AuditLogFilter, perPage uint64) (*auditLogPage, error) {
+	ctx := r.Context()
+	db := a.db.WithContext(ctx)
+	query := r.URL.Query()
+
+	if perPage == 0 {
+		return nil, apierrors.NewBadRequestError(apierrors.ErrorCodeValidationFailed, "Bad Pagination Parameters: per_page must be positive")
 	}
 
-	addPaginationHeaders(w, r, pageParams)
+	var cursor *models.AuditLogCursor
+	if after := query.Get("after"); after != "" {
+		afterID, err := uuid.FromString(after)
+		if err != nil {
+			return nil, apierrors.NewBadRequestError(apierrors.ErrorCodeValidationFailed, "after must be an UUID")
+		}
+
+		entry, err := models.FindAuditLogEntryByID(db, afterID)
+		if err != nil {
+			if models.IsNotFoundError(err) {
+				return nil, apierrors.NewBadRequestError(apierrors.ErrorCodeValidationFailed, "Audit log entry in after not found")
+			}
+			return nil, apierrors.NewInternalServerError("Error searching for audit logs").WithInternalError(err)
+		}
+		cursor = &models.AuditLogCursor{CreatedAt: entry.CreatedAt, ID: entry.ID}
+	}
+
+	logs, err := models.FindAuditLogEntriesAfter(db, col, qval, filter, cursor, int(perPage)) // #nosec G115
+	if err != nil {
+		return nil, apierrors.NewInternalServerError("Error searching for audit logs").WithInternalError(err)
+	}
+
+	page := &auditLogPage{Entries: logs}
+	if uint64(len(logs)) == perPage {
+		page.Next = &logs[len(logs)-1].ID
+	}
+
+	return page, nil
+}
+
+// adminAuditLog searches the audit log. With keyset pagination, when the
+// after query parameter is present, the next page is in the Link header of
+// the response.
+func (a *API) adminAuditLog(w http.ResponseWriter, r *http.Request) error {
+	page, err := a.findAuditLog(r)
+	if err != nil {
+		return err
+	}
+
+	if page.Pagination != nil {
+		addPaginationHeaders(w, r, page.Pagination)
+	} else if page.Next != nil {
+		next, _ := url.ParseRequestURI(r.URL.String())
+		nextQuery := next.Query()
+		nextQuery.Set("after", page.Next.String())
+		next.RawQuery = nextQuery.Encode()
+		w.Header().Add("Link", fmt.Sprintf("<%s>; rel=\"next\"", next.String()))
+	}
 
-	return sendJSON(w, http.StatusOK, logs)
+	return sendJSON(w, http.StatusOK, page.Entries)
 }
@@ -5,8 +5,11 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/gofrs/uuid"
 	jwt "github.com/golang-jwt/jwt/v5"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -87,6 +90,7 @@ func (ts *AuditTestSuite) TestAuditGet() {
 	require.Len(ts.T(), logs, 1)
 	require.Contains(ts.T(), logs[0].Payload, "actor_username")
 	assert.Equal(ts.T(), "supabase_admin", logs[0].Payload["actor_username"])
+	assert.NotEmpty(ts.T(), logs[0].Payload["request_id"])
 	traits, ok := logs[0].Payload["traits"].(map[string]interface{})
 	require.True(ts.T(), ok)
 	require.Contains(ts.T(), traits, "user_email")
@@ -123,7 +127,97 @@ func (ts *AuditTestSuite) TestAuditFilters() {
 	}
 }
 
-func (ts *AuditTestSuite) prepareDeleteEvent() {
+func (ts *AuditTestSuite) TestAuditFilterParams() {
+	u := ts.prepareDeleteEvent()
+
+	var logs []models.AuditLogEntry
+	require.NoError(ts.T(), ts.API.db.All(&logs))
+	require.Len(ts.T(), logs, 1)
+	actorID := logs[0].Payload["actor_id"].(string)
+
+	from := logs[0].CreatedAt.Add(-time.Minute).UTC().Format(time.RFC3339)
+	to := logs[0].CreatedAt.Add(time.Minute).UTC().Format(time.RFC3339)
+
+	cases := []struct {
+		query string
+		count int
+	}{
+		{"action=user_deleted", 1},
+		{"action=user_deleted&query=type:team", 1},
+		{"action=user", 0},
+		{"actor_id=" + actorID, 1},
+		{"actor_id=" + uuid.Must(uuid.NewV4()).String(), 0},
+		{"user_id=" + u.ID.String(), 1},
+		{"user_id=" + uuid.Must(uuid.NewV4()).String(), 0},
+		{"ip_address=127.0.0.1", 0},
+		{"from=" + from + "&to=" + to, 1},
+		{"to=" + from, 0},
+	}
+
+	for _, tc := range cases {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/admin/audit?"+tc.query, nil)
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", ts.token))
+
+		ts.API.handler.ServeHTTP(w, req)
+		require.Equal(ts.T(), http.StatusOK, w.Code, tc.query)
+
+		logs := []models.AuditLogEntry{}
+		require.NoError(ts.T(), json.NewDecoder(w.Body).Decode(&logs))
+		require.Len(ts.T(), logs, tc.count, tc.query)
+	}
+
+	for _, query := range []string{"actor_id=admin", "user_id=1", "from=yesterday", "after=1"} {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/admin/audit?"+query, nil)
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", ts.token))
+
+		ts.API.handler.ServeHTTP(w, req)
+		require.Equal(ts.T(), http.StatusBadRequest, w.Code, query)
+	}
+}
+
+func (ts *AuditTestSuite) TestAuditKeysetPagination() {
+	for i := 0; i < 5; i++ {
+		ts.prepareDeleteEvent()
+	}
+
+	var ids []string
+	next := "/admin/audit?after=&per_page=2&action=user_deleted"
+	for next != "" {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, next, nil)
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", ts.token))
+
+		ts.API.handler.ServeHTTP(w, req)
+		require.Equal(ts.T(), http.StatusOK, w.Code)
+		require.Empty(ts.T(), w.Header().Get("X-Total-Count"))
+
+		logs := []models.AuditLogEntry{}
+		require.NoError(ts.T(), json.NewDecoder(w.Body).Decode(&logs))
+		for _, l := range logs {
+			ids = append(ids, l.ID.String())
+		}
+
+		next = ""
+		if link := w.Header().Get("Link"); link != "" {
+			require.True(ts.T(), strings.HasSuffix(link, `>; rel="next"`), link)
+			next = strings.TrimSuffix(strings.TrimPrefix(link, "<"), `>; rel="next"`)
+			require.Contains(ts.T(), next, "action=user_deleted")
+		}
+	}
+
+	// every entry is listed once, newest first
+	require.Len(ts.T(), ids, 5)
+
+	expected := []*models.AuditLogEntry{}
+	require.NoError(ts.T(), ts.API.db.Q().Order("created_at desc, id desc").All(&expected))
+	for i, l := range expected {
+		require.Equal(ts.T(), l.ID.String(), ids[i])
+	}
+}
+
+func (ts *AuditTestSuite) prepareDeleteEvent() *models.User {
 	// DELETE USER
 	u, err := models.NewUser("12345678", "test-delete@example.com", "test", ts.Config.JWT.Aud, nil)
 	require.NoError(ts.T(), err, "Error making new user")
@@ -136,4 +230,6 @@ func (ts *AuditTestSuite) prepareDeleteEvent() {
 
 	ts.API.handler.ServeHTTP(w, req)
 	require.Equal(ts.T(), http.StatusOK, w.Code)
+
+	return u
 }
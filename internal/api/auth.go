@@ -11,6 +11,7 @@ import (
 	jwt "github.com/golang-jwt/jwt/v5"
 	"github.com/supabase/auth/internal/api/apierrors"
 	"github.com/supabase/auth/internal/api/shared"
+	"github.com/supabase/auth/internal/cache"
 	"github.com/supabase/auth/internal/conf"
 	"github.com/supabase/auth/internal/models"
 	"github.com/supabase/auth/internal/storage"
@@ -28,6 +29,10 @@ func (a *API) requireAuthentication(w http.ResponseWriter, r *http.Request) (con
 		return ctx, err
 	}
 
+	if allowsMFARequiredSession(r) {
+		ctx = withMFARequiredSessionAllowed(ctx)
+	}
+
 	ctx, err = a.maybeLoadUserOrSession(ctx)
 	if err != nil {
 		return ctx, err
@@ -35,6 +40,27 @@ func (a *API) requireAuthentication(w http.ResponseWriter, r *http.Request) (con
 	return ctx, err
 }
 
+// allowsMFARequiredSession reports whether the request can be made with a
+// session which has to be verified with MFA first, which is the case for
+// challenging and verifying factors and for signing out.
+func allowsMFARequiredSession(r *http.Request) bool {
+	if r.Method != http.MethodPost {
+		return false
+	}
+
+	segments := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	n := len(segments)
+	switch {
+	case segments[n-1] == "logout":
+		return true
+	case n >= 2 && segments[n-2] == "factors" && segments[n-1] == "step_up":
+		return true
+	case n >= 3 && segments[n-3] == "factors" && (segments[n-1] == "challenge" || segments[n-1] == "verify"):
+		return true
+	}
+	return false
+}
+
 func (a *API) requireNotAnonymous(w http.ResponseWriter, r *http.Request) (context.Context, error) {
 	ctx := r.Context()
 	claims := getClaims(ctx)
@@ -109,6 +135,25 @@ func (a *API) parseJWTClaims(bearer string, r *http.Request) (context.Context, e
 	return withToken(ctx, token), nil
 }
 
+// isAccessTokenRevoked reports whether the access token with the claims has
+// been added to the denylist through the token revocation endpoint.
+func (a *API) isAccessTokenRevoked(db *storage.Connection, claims *AccessTokenClaims) (bool, error) {
+	if !a.config.JWT.DenylistEnabled || claims.ID == "" {
+		return false, nil
+	}
+
+	jti, err := uuid.FromString(claims.ID)
+	if err != nil {
+		return false, nil
+	}
+
+	revoked, err := models.IsAccessTokenRevoked(db, jti)
+	if err != nil {
+		return false, apierrors.NewInternalServerError("Database error checking access token").WithInternalError(err)
+	}
+	return revoked, nil
+}
+
 func (a *API) maybeLoadUserOrSession(ctx context.Context) (context.Context, error) {
 	db := a.db.WithContext(ctx)
 	claims := getClaims(ctx)
@@ -121,13 +166,29 @@ func (a *API) maybeLoadUserOrSession(ctx context.Context) (context.Context, erro
 		return nil, apierrors.NewForbiddenError(apierrors.ErrorCodeBadJWT, "invalid claim: missing sub claim")
 	}
 
+	revoked, err := a.isAccessTokenRevoked(db, claims)
+	if err != nil {
+		return ctx, err
+	}
+	if revoked {
+		return ctx, apierrors.NewForbiddenError(apierrors.ErrorCodeBadJWT, "invalid JWT: token has been revoked")
+	}
+
+	// the user and the session are read from the replica, but revoked
+	// access tokens are checked on the primary. Cached users and sessions
+	// are read from the primary, as the replica could still have the rows
+	// as they were before a change which invalidated them.
+	if !cache.Enabled() {
+		db = db.Reader()
+	}
+
 	var user *models.User
 	if claims.Subject != "" {
 		userId, err := uuid.FromString(claims.Subject)
 		if err != nil {
 			return ctx, apierrors.NewBadRequestError(apierrors.ErrorCodeBadJWT, "invalid claim: sub claim must be a UUID").WithInternalError(err)
 		}
-		user, err = models.FindUserByID(db, userId)
+		user, err = models.FindUserByIDCached(db, userId)
 		if err != nil {
 			if models.IsNotFoundError(err) {
 				return ctx, apierrors.NewForbiddenError(apierrors.ErrorCodeUserNotFound, "User from sub claim in JWT does not exist")
@@ -143,13 +204,18 @@ func (a *API) maybeLoadUserOrSession(ctx context.Context) (context.Context, erro
 		if err != nil {
 			return ctx, apierrors.NewForbiddenError(apierrors.ErrorCodeBadJWT, "invalid claim: session_id claim must be a UUID").WithInternalError(err)
 		}
-		session, err = models.FindSessionByID(db, sessionId, false)
+		session, err = models.FindSessionByIDCached(db, sessionId)
 		if err != nil {
 			if models.IsNotFoundError(err) {
 				return ctx, apierrors.NewForbiddenError(apierrors.ErrorCodeSessionNotFound, "Session from session_id claim in JWT does not exist").WithInternalError(err).WithInternalMessage("session id (%s) doesn't exist", sessionId)
 			}
 			return ctx, err
 		}
+		// sessions of risky sign-ins can only be used to verify MFA until
+		// they are elevated to AAL2
+		if session.MFARequired && !session.IsAAL2() && !isMFARequiredSessionAllowed(ctx) {
+			return ctx, apierrors.NewHTTPError(http.StatusUnauthorized, apierrors.ErrorCodeInsufficientAAL, "AAL2 session is required as the sign in has to be verified with MFA")
+		}
 		ctx = withSession(ctx, session)
 		// Also store in shared context for cross-package access (e.g., oauthserver package)
 		ctx = shared.WithSession(ctx, session)
@@ -20,22 +20,28 @@ const (
 	externalProviderTypeKey          = contextKey("external_provider_type")
 	externalProviderEmailOptionalKey = contextKey("external_provider_allow_no_email")
 
-	tokenKey            = contextKey("jwt")
-	inviteTokenKey      = contextKey("invite_token")
-	signatureKey        = contextKey("signature")
-	targetUserKey       = contextKey("target_user")
-	factorKey           = contextKey("factor")
-	sessionKey          = contextKey("session")
-	externalReferrerKey = contextKey("external_referrer")
-	functionHooksKey    = contextKey("function_hooks")
-	adminUserKey        = contextKey("admin_user")
-	oauthTokenKey       = contextKey("oauth_token") // for OAuth1.0, also known as request token
-	oauthVerifierKey    = contextKey("oauth_verifier")
-	ssoProviderKey      = contextKey("sso_provider")
-	externalHostKey     = contextKey("external_host")
-	flowStateKey        = contextKey("flow_state_id")
-	oauthClientStateKey = contextKey("oauth_client_state_id")
-	flowStateContextKey = contextKey("flow_state")
+	tokenKey              = contextKey("jwt")
+	inviteTokenKey        = contextKey("invite_token")
+	signatureKey          = contextKey("signature")
+	targetUserKey         = contextKey("target_user")
+	factorKey             = contextKey("factor")
+	sessionKey            = contextKey("session")
+	externalReferrerKey   = contextKey("external_referrer")
+	functionHooksKey      = contextKey("function_hooks")
+	adminUserKey          = contextKey("admin_user")
+	oauthTokenKey         = contextKey("oauth_token") // for OAuth1.0, also known as request token
+	oauthVerifierKey      = contextKey("oauth_verifier")
+	ssoProviderKey        = contextKey("sso_provider")
+	externalHostKey       = contextKey("external_host")
+	flowStateKey          = contextKey("flow_state_id")
+	oauthClientStateKey   = contextKey("oauth_client_state_id")
+	flowStateContextKey   = contextKey("flow_state")
+	scimGroupKey          = contextKey("scim_group")
+	tenantKey             = contextKey("tenant")
+	organizationKey       = contextKey("organization")
+	organizationMemberKey = contextKey("organization_member")
+	roleKey               = contextKey("role")
+	mfaRequiredAllowedKey = contextKey("mfa_required_allowed")
 )
 
 // withToken adds the JWT token to the context.
@@ -263,6 +269,30 @@ func getSSOProvider(ctx context.Context) *models.SSOProvider {
 	return obj.(*models.SSOProvider)
 }
 
+func withSCIMGroup(ctx context.Context, group *models.SCIMGroup) context.Context {
+	return context.WithValue(ctx, scimGroupKey, group)
+}
+
+func getSCIMGroup(ctx context.Context) *models.SCIMGroup {
+	obj := ctx.Value(scimGroupKey)
+	if obj == nil {
+		return nil
+	}
+	return obj.(*models.SCIMGroup)
+}
+
+func withTenant(ctx context.Context, tenant *models.Tenant) context.Context {
+	return context.WithValue(ctx, tenantKey, tenant)
+}
+
+func getTenant(ctx context.Context) *models.Tenant {
+	obj := ctx.Value(tenantKey)
+	if obj == nil {
+		return nil
+	}
+	return obj.(*models.Tenant)
+}
+
 func withExternalHost(ctx context.Context, u *url.URL) context.Context {
 	return context.WithValue(ctx, externalHostKey, u)
 }
@@ -274,3 +304,52 @@ func getExternalHost(ctx context.Context) *url.URL {
 	}
 	return obj.(*url.URL)
 }
+
+func withOrganization(ctx context.Context, organization *models.Organization) context.Context {
+	return context.WithValue(ctx, organizationKey, organization)
+}
+
+func getOrganization(ctx context.Context) *models.Organization {
+	obj := ctx.Value(organizationKey)
+	if obj == nil {
+		return nil
+	}
+	return obj.(*models.Organization)
+}
+
+// withOrganizationMember adds the membership of the user managing the
+// organization to the context, which admin requests don't have.
+func withOrganizationMember(ctx context.Context, member *models.OrganizationMember) context.Context {
+	return context.WithValue(ctx, organizationMemberKey, member)
+}
+
+func getOrganizationMember(ctx context.Context) *models.OrganizationMember {
+	obj := ctx.Value(organizationMemberKey)
+	if obj == nil {
+		return nil
+	}
+	return obj.(*models.OrganizationMember)
+}
+
+func withRole(ctx context.Context, role *models.Role) context.Context {
+	return context.WithValue(ctx, roleKey, role)
+}
+
+func getRole(ctx context.Context) *models.Role {
+	obj := ctx.Value(roleKey)
+	if obj == nil {
+		return nil
+	}
+	return obj.(*models.Role)
+}
+
+// withMFARequiredSessionAllowed marks the request as one which can be made
+// with a session that still has to be verified with MFA.
+func withMFARequiredSessionAllowed(ctx context.Context) context.Context {
+	return context.WithValue(ctx, mfaRequiredAllowedKey, true)
+}
+
+func isMFARequiredSessionAllowed(ctx context.Context) bool {
+	allowed, _ := ctx.Value(mfaRequiredAllowedKey).(bool)
+	return allowed
+}
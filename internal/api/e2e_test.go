@@ -181,6 +181,7 @@ func signupAndConfirmEmail(
 		expUser.GetEmail(), hookReq.EmailData.Token)
 	require.NotEmpty(t, hookReq.EmailData.Token)
 	require.Equal(t, otpHash, hookReq.EmailData.TokenHash)
+	require.Contains(t, hookReq.EmailData.ConfirmationURL, "token="+hookReq.EmailData.TokenHash)
 
 	// hook user matches the signup user
 	require.Equal(t, expUser.ID, hookReq.User.ID)
@@ -926,6 +927,11 @@ func TestE2EHooks(t *testing.T) {
 					require.NoError(t, err)
 					require.Equal(t, currentUser.ID, hookReq.UserID)
 					require.Equal(t, currentUser.ID.String(), hookReq.Claims.Subject)
+					require.NotNil(t, hookReq.User)
+					require.Equal(t, currentUser.ID, hookReq.User.ID)
+					require.NotNil(t, hookReq.Session)
+					require.Equal(t, currentUser.ID, hookReq.Session.UserID)
+					require.Equal(t, hookReq.Claims.SessionId, hookReq.Session.ID.String())
 				}
 
 				// check if we expected an error
@@ -1137,6 +1143,8 @@ func TestE2EHooks(t *testing.T) {
 				// email change flag enabled
 				require.NotEmpty(t, hookReq.EmailData.Token)
 				require.NotEmpty(t, hookReq.EmailData.TokenNew)
+				require.Contains(t, hookReq.EmailData.ConfirmationURL, "type=email_change")
+				require.Contains(t, hookReq.EmailData.ConfirmationURLCurrent, "type=email_change")
 
 				// verify new and prev emails
 				require.Equal(t, curEmail, hookReq.User.Email.String())
@@ -0,0 +1,253 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/supabase/auth/internal/api/apierrors"
+	"github.com/supabase/auth/internal/mailer/templatemailer"
+	"github.com/supabase/auth/internal/models"
+	"github.com/supabase/auth/internal/storage"
+)
+
+// EmailTemplateParams are the parameters for creating a new version of an
+// email template. Body is an HTML template, or an MJML document when the
+// format is mjml.
+type EmailTemplateParams struct {
+	Subject string                     `json:"subject"`
+	Body    string                     `json:"body"`
+	Format  models.EmailTemplateFormat `json:"format"`
+}
+
+// AdminListEmailTemplatesResponse is the response of the admin email
+// templates endpoint.
+type AdminListEmailTemplatesResponse struct {
+	Templates []*models.EmailTemplate `json:"templates"`
+}
+
+// EmailTemplateResponse describes an email template type in a locale with
+// its active version, if any, and all of its versions, latest first.
+type EmailTemplateResponse struct {
+	Type     string                  `json:"type"`
+	Locale   string                  `json:"locale,omitempty"`
+	Active   *models.EmailTemplate   `json:"active"`
+	Versions []*models.EmailTemplate `json:"versions"`
+}
+
+// emailTemplateType returns the template type of the request, or an error if
+// it is not a known type.
+func emailTemplateType(r *http.Request) (string, error) {
+	typ := chi.URLParam(r, "template_type")
+	if !templatemailer.IsTemplateType(typ) {
+		return "", apierrors.NewNotFoundError(apierrors.ErrorCodeEmailTemplateNotFound, "Email template type %q is not supported", typ)
+	}
+	return typ, nil
+}
+
+// emailTemplateLocale returns the locale given in the locale query parameter
+// of the request, "" for the default locale.
+func emailTemplateLocale(r *http.Request) (string, error) {
+	value := r.URL.Query().Get("locale")
+	if value == "" {
+		return "", nil
+	}
+
+	locale, err := templatemailer.NormalizeLocale(value)
+	if err != nil {
+		return "", apierrors.NewBadRequestError(apierrors.ErrorCodeValidationFailed, "Locale %q is not a valid language tag", value)
+	}
+	return locale, nil
+}
+
+// emailTemplateTypeAndLocale returns the template type and locale of the
+// request.
+func emailTemplateTypeAndLocale(r *http.Request) (string, string, error) {
+	typ, err := emailTemplateType(r)
+	if err != nil {
+		return "", "", err
+	}
+
+	locale, err := emailTemplateLocale(r)
+	if err != nil {
+		return "", "", err
+	}
+	return typ, locale, nil
+}
+
+// validate checks the template and returns the HTML body the mail is
+// rendered with.
+func (p *EmailTemplateParams) validate(typ string) (string, error) {
+	if p.Format == "" {
+		p.Format = models.EmailTemplateHTML
+	}
+
+	body := p.Body
+	switch p.Format {
+	case models.EmailTemplateHTML:
+	case models.EmailTemplateMJML:
+		compiled, err := templatemailer.CompileMJML(p.Body)
+		if err != nil {
+			return "", apierrors.NewBadRequestError(apierrors.ErrorCodeValidationFailed, "Email template is not valid: %s", err.Error())
+		}
+		body = compiled
+	default:
+		return "", apierrors.NewBadRequestError(apierrors.ErrorCodeValidationFailed, "Email template format must be html or mjml")
+	}
+
+	if err := templatemailer.ValidateTemplate(typ, p.Subject, body); err != nil {
+		return "", apierrors.NewBadRequestError(apierrors.ErrorCodeValidationFailed, "Email template is not valid: %s", err.Error())
+	}
+
+	return body, nil
+}
+
+// emailTemplateResponse loads the versions of a template type and locale.
+func (a *API) emailTemplateResponse(db *storage.Connection, typ, locale string) (*EmailTemplateResponse, error) {
+	versions, err := models.FindEmailTemplateVersions(db, a.tenantID, typ, locale)
+	if err != nil {
+		return nil, apierrors.NewInternalServerError("Database error finding email templates").WithInternalError(err)
+	}
+
+	resp := &EmailTemplateResponse{
+		Type:     typ,
+		Locale:   locale,
+		Versions: versions,
+	}
+	for _, version := range versions {
+		if version.Active {
+			resp.Active = version
+			break
+		}
+	}
+
+	return resp, nil
+}
+
+// adminEmailTemplates lists the active email templates.
+func (a *API) adminEmailTemplates(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	db := a.db.WithContext(ctx)
+
+	templates, err := models.FindActiveEmailTemplates(db, a.tenantID)
+	if err != nil {
+		return apierrors.NewInternalServerError("Database error finding email templates").WithInternalError(err)
+	}
+
+	return sendJSON(w, http.StatusOK, AdminListEmailTemplatesResponse{
+		Templates: templates,
+	})
+}
+
+// adminEmailTemplateGet returns an email template type with its versions.
+func (a *API) adminEmailTemplateGet(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	db := a.db.WithContext(ctx)
+
+	typ, locale, err := emailTemplateTypeAndLocale(r)
+	if err != nil {
+		return err
+	}
+
+	resp, err := a.emailTemplateResponse(db, typ, locale)
+	if err != nil {
+		return err
+	}
+
+	return sendJSON(w, http.StatusOK, resp)
+}
+
+// adminEmailTemplateUpdate creates a new version of an email template and
+// makes it the active version.
+func (a *API) adminEmailTemplateUpdate(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	db := a.db.WithContext(ctx)
+
+	typ, locale, err := emailTemplateTypeAndLocale(r)
+	if err != nil {
+		return err
+	}
+
+	params := &EmailTemplateParams{}
+	if err := retrieveRequestParams(r, params); err != nil {
+		return err
+	}
+
+	body, err := params.validate(typ)
+	if err != nil {
+		return err
+	}
+
+	template := models.NewEmailTemplate(a.tenantID, typ, locale, params.Format, params.Subject, params.Body, body)
+	err = db.Transaction(func(tx *storage.Connection) error {
+		return models.CreateEmailTemplateVersion(tx, template)
+	})
+	if err != nil {
+		return apierrors.NewInternalServerError("Database error creating email template").WithInternalError(err)
+	}
+
+	return sendJSON(w, http.StatusOK, template)
+}
+
+// adminEmailTemplateActivate makes a previous version of an email template
+// the active version again.
+func (a *API) adminEmailTemplateActivate(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	db := a.db.WithContext(ctx)
+
+	typ, locale, err := emailTemplateTypeAndLocale(r)
+	if err != nil {
+		return err
+	}
+
+	version, err := strconv.Atoi(chi.URLParam(r, "version"))
+	if err != nil {
+		return apierrors.NewNotFoundError(apierrors.ErrorCodeEmailTemplateNotFound, "Email template version not found")
+	}
+
+	template, err := models.FindEmailTemplateVersion(db, a.tenantID, typ, locale, version)
+	if err != nil {
+		if models.IsNotFoundError(err) {
+			return apierrors.NewNotFoundError(apierrors.ErrorCodeEmailTemplateNotFound, "Email template version not found")
+		}
+		return apierrors.NewInternalServerError("Database error finding email template").WithInternalError(err)
+	}
+
+	err = db.Transaction(func(tx *storage.Connection) error {
+		return template.Activate(tx)
+	})
+	if err != nil {
+		return apierrors.NewInternalServerError("Database error activating email template").WithInternalError(err)
+	}
+
+	return sendJSON(w, http.StatusOK, template)
+}
+
+// adminEmailTemplateDelete deactivates an email template in a locale, mail
+// of its type is sent with the template of the next locale in the fallback
+// chain, or the configured template, again. Its versions are kept so that
+// one can be activated later.
+func (a *API) adminEmailTemplateDelete(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	db := a.db.WithContext(ctx)
+
+	typ, locale, err := emailTemplateTypeAndLocale(r)
+	if err != nil {
+		return err
+	}
+
+	deactivated, err := models.DeactivateEmailTemplates(db, a.tenantID, typ, locale)
+	if err != nil {
+		return apierrors.NewInternalServerError("Database error deactivating email template").WithInternalError(err)
+	}
+	if !deactivated {
+		return apierrors.NewNotFoundError(apierrors.ErrorCodeEmailTemplateNotFound, "Email template has no active version")
+	}
+
+	resp, err := a.emailTemplateResponse(db, typ, locale)
+	if err != nil {
+		return err
+	}
+
+	return sendJSON(w, http.StatusOK, resp)
+}
@@ -0,0 +1,328 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/gofrs/uuid"
+	jwt "github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"github.com/supabase/auth/internal/conf"
+	"github.com/supabase/auth/internal/mailer/templatemailer"
+	"github.com/supabase/auth/internal/models"
+)
+
+type EmailTemplatesTestSuite struct {
+	suite.Suite
+	API    *API
+	Config *conf.GlobalConfiguration
+
+	token string
+}
+
+func TestEmailTemplates(t *testing.T) {
+	api, config, err := setupAPIForTest()
+	require.NoError(t, err)
+
+	ts := &EmailTemplatesTestSuite{
+		API:    api,
+		Config: config,
+	}
+	defer api.db.Close()
+
+	suite.Run(t, ts)
+}
+
+func (ts *EmailTemplatesTestSuite) SetupTest() {
+	models.TruncateAll(ts.API.db)
+
+	claims := &AccessTokenClaims{
+		Role: "supabase_admin",
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(ts.Config.JWT.Secret))
+	require.NoError(ts.T(), err, "Error generating admin jwt")
+	ts.token = token
+}
+
+func (ts *EmailTemplatesTestSuite) request(api *API, method, path string, body interface{}) *httptest.ResponseRecorder {
+	var buf bytes.Buffer
+	if body != nil {
+		require.NoError(ts.T(), json.NewEncoder(&buf).Encode(body))
+	}
+
+	req := httptest.NewRequest(method, path, &buf)
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", ts.token))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	api.handler.ServeHTTP(w, req)
+	return w
+}
+
+func (ts *EmailTemplatesTestSuite) update(api *API, typ string, params EmailTemplateParams) *models.EmailTemplate {
+	w := ts.request(api, http.MethodPut, "/admin/email_templates/"+typ, params)
+	require.Equal(ts.T(), http.StatusOK, w.Code, w.Body.String())
+
+	var template models.EmailTemplate
+	require.NoError(ts.T(), json.NewDecoder(w.Body).Decode(&template))
+	return &template
+}
+
+func (ts *EmailTemplatesTestSuite) get(api *API, typ string) *EmailTemplateResponse {
+	w := ts.request(api, http.MethodGet, "/admin/email_templates/"+typ, nil)
+	require.Equal(ts.T(), http.StatusOK, w.Code, w.Body.String())
+
+	var res EmailTemplateResponse
+	require.NoError(ts.T(), json.NewDecoder(w.Body).Decode(&res))
+	return &res
+}
+
+func (ts *EmailTemplatesTestSuite) TestVersions() {
+	first := ts.update(ts.API, "recovery", EmailTemplateParams{
+		Subject: "Reset your password",
+		Body:    `<p><a href="{{ .ConfirmationURL }}">Reset</a></p>`,
+	})
+	require.Equal(ts.T(), 1, first.Version)
+	require.Equal(ts.T(), models.EmailTemplateHTML, first.Format)
+	require.True(ts.T(), first.Active)
+
+	second := ts.update(ts.API, "recovery", EmailTemplateParams{
+		Subject: "Reset your password on {{ .SiteURL }}",
+		Body:    `<p>Your code is {{ .Token }}</p>`,
+	})
+	require.Equal(ts.T(), 2, second.Version)
+
+	res := ts.get(ts.API, "recovery")
+	require.Len(ts.T(), res.Versions, 2)
+	require.Equal(ts.T(), 2, res.Versions[0].Version)
+	require.NotNil(ts.T(), res.Active)
+	require.Equal(ts.T(), second.ID, res.Active.ID)
+
+	// activating a previous version rolls back
+	w := ts.request(ts.API, http.MethodPost, "/admin/email_templates/recovery/versions/1/activate", nil)
+	require.Equal(ts.T(), http.StatusOK, w.Code, w.Body.String())
+	require.Equal(ts.T(), first.ID, ts.get(ts.API, "recovery").Active.ID)
+
+	w = ts.request(ts.API, http.MethodPost, "/admin/email_templates/recovery/versions/3/activate", nil)
+	require.Equal(ts.T(), http.StatusNotFound, w.Code, w.Body.String())
+
+	w = ts.request(ts.API, http.MethodGet, "/admin/email_templates", nil)
+	require.Equal(ts.T(), http.StatusOK, w.Code, w.Body.String())
+
+	var list AdminListEmailTemplatesResponse
+	require.NoError(ts.T(), json.NewDecoder(w.Body).Decode(&list))
+	require.Len(ts.T(), list.Templates, 1)
+	require.Equal(ts.T(), first.ID, list.Templates[0].ID)
+
+	// deleting deactivates the template and keeps its versions
+	w = ts.request(ts.API, http.MethodDelete, "/admin/email_templates/recovery", nil)
+	require.Equal(ts.T(), http.StatusOK, w.Code, w.Body.String())
+
+	res = ts.get(ts.API, "recovery")
+	require.Nil(ts.T(), res.Active)
+	require.Len(ts.T(), res.Versions, 2)
+
+	w = ts.request(ts.API, http.MethodDelete, "/admin/email_templates/recovery", nil)
+	require.Equal(ts.T(), http.StatusNotFound, w.Code, w.Body.String())
+}
+
+func (ts *EmailTemplatesTestSuite) TestMJML() {
+	template := ts.update(ts.API, "magic_link", EmailTemplateParams{
+		Subject: "Your Magic Link",
+		Format:  models.EmailTemplateMJML,
+		Body: `<mjml>
+  <mj-body>
+    <mj-section>
+      <mj-column>
+        <mj-text>Follow this link to log in:</mj-text>
+        <mj-button href="{{ .ConfirmationURL }}">Log In</mj-button>
+      </mj-column>
+    </mj-section>
+  </mj-body>
+</mjml>`,
+	})
+	require.Equal(ts.T(), models.EmailTemplateMJML, template.Format)
+	require.Contains(ts.T(), template.Source, "<mj-button")
+	require.Contains(ts.T(), template.Body, `<a href="{{ .ConfirmationURL }}"`)
+}
+
+func (ts *EmailTemplatesTestSuite) TestValidation() {
+	cases := []struct {
+		desc   string
+		typ    string
+		params EmailTemplateParams
+		code   int
+	}{
+		{
+			desc:   "unknown type",
+			typ:    "welcome",
+			params: EmailTemplateParams{Subject: "Welcome", Body: "<p>{{ .Token }}</p>"},
+			code:   http.StatusNotFound,
+		},
+		{
+			desc:   "unknown variable",
+			typ:    "confirmation",
+			params: EmailTemplateParams{Subject: "Confirm", Body: "<p>{{ .Token }} {{ .Password }}</p>"},
+			code:   http.StatusBadRequest,
+		},
+		{
+			desc:   "missing link and code",
+			typ:    "invite",
+			params: EmailTemplateParams{Subject: "Invite", Body: "<p>Welcome {{ .Email }}</p>"},
+			code:   http.StatusBadRequest,
+		},
+		{
+			desc:   "parse error",
+			typ:    "confirmation",
+			params: EmailTemplateParams{Subject: "Confirm", Body: "<p>{{ .Token </p>"},
+			code:   http.StatusBadRequest,
+		},
+		{
+			desc:   "empty subject",
+			typ:    "confirmation",
+			params: EmailTemplateParams{Body: "<p>{{ .Token }}</p>"},
+			code:   http.StatusBadRequest,
+		},
+		{
+			desc:   "invalid MJML",
+			typ:    "confirmation",
+			params: EmailTemplateParams{Subject: "Confirm", Format: models.EmailTemplateMJML, Body: "<mjml><mj-body><mj-carousel /></mj-body></mjml>"},
+			code:   http.StatusBadRequest,
+		},
+		{
+			desc:   "unknown format",
+			typ:    "confirmation",
+			params: EmailTemplateParams{Subject: "Confirm", Format: "markdown", Body: "{{ .Token }}"},
+			code:   http.StatusBadRequest,
+		},
+	}
+
+	for _, c := range cases {
+		w := ts.request(ts.API, http.MethodPut, "/admin/email_templates/"+c.typ, c.params)
+		require.Equal(ts.T(), c.code, w.Code, "%s: %s", c.desc, w.Body.String())
+	}
+}
+
+type emailTemplatesTestClient struct {
+	subject string
+	body    string
+}
+
+func (c *emailTemplatesTestClient) Mail(ctx context.Context, to, subject, body string, headers map[string][]string, typ string) error {
+	c.subject = subject
+	c.body = body
+	return nil
+}
+
+func (ts *EmailTemplatesTestSuite) TestTenantOverride() {
+	tenantID := uuid.Must(uuid.NewV4())
+	tenantAPI := NewAPIWithVersion(ts.Config, ts.API.db, apiTestVersion, WithTenantID(tenantID))
+
+	ts.update(ts.API, "confirmation", EmailTemplateParams{
+		Subject: "Deployment",
+		Body:    "<p>Deployment {{ .Token }}</p>",
+	})
+
+	mail := func(tenantID *uuid.UUID) *emailTemplatesTestClient {
+		client := &emailTemplatesTestClient{}
+		m := templatemailer.New(ts.Config, client, templatemailer.NewCache()).WithDatabase(ts.API.db, tenantID)
+
+		user, err := models.NewUser("", "test@example.com", "", ts.Config.JWT.Aud, nil)
+		require.NoError(ts.T(), err)
+
+		req := httptest.NewRequest(http.MethodPost, "/signup", nil)
+		require.NoError(ts.T(), m.ConfirmationMail(req, user, "123456", "", &url.URL{Scheme: "http", Host: "localhost"}))
+		return client
+	}
+
+	// the tenant uses the deployment's template until it overrides it
+	require.Equal(ts.T(), "Deployment", mail(&tenantID).subject)
+	require.Empty(ts.T(), ts.get(tenantAPI, "confirmation").Versions)
+
+	ts.update(tenantAPI, "confirmation", EmailTemplateParams{
+		Subject: "Tenant",
+		Body:    "<p>Tenant {{ .Token }}</p>",
+	})
+
+	client := mail(&tenantID)
+	require.Equal(ts.T(), "Tenant", client.subject)
+	require.Equal(ts.T(), "<p>Tenant 123456</p>", client.body)
+	require.Equal(ts.T(), "Deployment", mail(nil).subject)
+
+	// without templates the configured template is used
+	w := ts.request(ts.API, http.MethodDelete, "/admin/email_templates/confirmation", nil)
+	require.Equal(ts.T(), http.StatusOK, w.Code, w.Body.String())
+	require.Equal(ts.T(), "Confirm Your Email", mail(nil).subject)
+}
+
+func (ts *EmailTemplatesTestSuite) TestLocales() {
+	ts.Config.Mailer.LocalizationEnabled = true
+	defer func() {
+		ts.Config.Mailer.LocalizationEnabled = false
+	}()
+
+	ts.update(ts.API, "recovery", EmailTemplateParams{
+		Subject: "Reset your password",
+		Body:    "<p>{{ .Token }}</p>",
+	})
+	fr := ts.update(ts.API, "recovery?locale=fr", EmailTemplateParams{
+		Subject: "Réinitialisez votre mot de passe",
+		Body:    "<p>{{ .Token }}</p>",
+	})
+	require.Equal(ts.T(), "fr", fr.Locale)
+	require.Equal(ts.T(), 1, fr.Version)
+
+	frCA := ts.update(ts.API, "recovery?locale=fr_ca", EmailTemplateParams{
+		Subject: "Réinitialisez votre mot de passe, eh",
+		Body:    "<p>{{ .Token }}</p>",
+	})
+	require.Equal(ts.T(), "fr-CA", frCA.Locale)
+
+	res := ts.get(ts.API, "recovery?locale=fr-CA")
+	require.Equal(ts.T(), "fr-CA", res.Locale)
+	require.Len(ts.T(), res.Versions, 1)
+	require.Equal(ts.T(), frCA.ID, res.Active.ID)
+
+	w := ts.request(ts.API, http.MethodGet, "/admin/email_templates/recovery?locale=not-a-locale!", nil)
+	require.Equal(ts.T(), http.StatusBadRequest, w.Code, w.Body.String())
+
+	mail := func(locale, acceptLanguage string) string {
+		client := &emailTemplatesTestClient{}
+		m := templatemailer.New(ts.Config, client, templatemailer.NewCache()).WithDatabase(ts.API.db, nil)
+
+		user, err := models.NewUser("", "test@example.com", "", ts.Config.JWT.Aud, nil)
+		require.NoError(ts.T(), err)
+		if locale != "" {
+			user.UserMetaData["locale"] = locale
+		}
+
+		req := httptest.NewRequest(http.MethodPost, "/recover", nil)
+		req.Header.Set("Accept-Language", acceptLanguage)
+		require.NoError(ts.T(), m.RecoveryMail(req, user, "123456", "", &url.URL{Scheme: "http", Host: "localhost"}))
+		return client.subject
+	}
+
+	require.Equal(ts.T(), frCA.Subject, mail("fr-CA", ""))
+	require.Equal(ts.T(), fr.Subject, mail("fr-BE", "fr-CA"))
+	require.Equal(ts.T(), frCA.Subject, mail("", "de, fr-CA;q=0.5"))
+	require.Equal(ts.T(), "Reset your password", mail("de", "es"))
+
+	// deactivating a locale falls back to the next in the chain
+	w = ts.request(ts.API, http.MethodDelete, "/admin/email_templates/recovery?locale=fr-CA", nil)
+	require.Equal(ts.T(), http.StatusOK, w.Code, w.Body.String())
+	require.Equal(ts.T(), fr.Subject, mail("fr-CA", ""))
+
+	w = ts.request(ts.API, http.MethodGet, "/admin/email_templates", nil)
+	require.Equal(ts.T(), http.StatusOK, w.Code, w.Body.String())
+
+	var list AdminListEmailTemplatesResponse
+	require.NoError(ts.T(), json.NewDecoder(w.Body).Decode(&list))
+	require.Len(ts.T(), list.Templates, 2)
+}
@@ -47,6 +47,11 @@ func recoverer(next http.Handler) http.Handler {
 	fn := func(w http.ResponseWriter, r *http.Request) {
 		defer func() {
 			if rvr := recover(); rvr != nil {
+				if rvr == http.ErrAbortHandler {
+					// aborts the response without logging a stack trace
+					panic(rvr)
+				}
+
 				logEntry := observability.GetLogEntry(r)
 				if logEntry != nil {
 					logEntry.Panic(rvr, debug.Stack())
@@ -75,8 +80,14 @@ type ErrorCause interface {
 type HTTPErrorResponse20240101 struct {
 	Code    apierrors.ErrorCode `json:"code"`
 	Message string              `json:"message"`
+	ErrorID string              `json:"error_id,omitempty"`
 }
 
+// errorIDHeaderName is the response header with the ID of the request which
+// failed, which is also returned as the error_id of every error and logged
+// with it, to correlate the error a client received with the logs.
+const errorIDHeaderName = "x-sb-error-id"
+
 func HandleResponseError(err error, w http.ResponseWriter, r *http.Request) {
 	log := observability.GetLogEntry(r).Entry
 	errorID := utilities.GetRequestID(r.Context())
@@ -89,19 +100,28 @@ func HandleResponseError(err error, w http.ResponseWriter, r *http.Request) {
 		w.Header().Set(APIVersionHeaderName, FormatAPIVersion(apiVersion))
 	}
 
+	if errorID != "" {
+		w.Header().Set(errorIDHeaderName, errorID)
+	}
+
 	switch e := err.(type) {
 	case *WeakPasswordError:
+		w.Header().Set("x-sb-error-code", apierrors.ErrorCodeWeakPassword)
+
 		if apiVersion.Compare(APIVersion20240101) >= 0 {
 			var output struct {
 				HTTPErrorResponse20240101
 				Payload struct {
-					Reasons []string `json:"reasons,omitempty"`
+					Reasons      []string              `json:"reasons,omitempty"`
+					Requirements *PasswordRequirements `json:"requirements,omitempty"`
 				} `json:"weak_password,omitempty"`
 			}
 
 			output.Code = apierrors.ErrorCodeWeakPassword
 			output.Message = e.Message
+			output.ErrorID = errorID
 			output.Payload.Reasons = e.Reasons
+			output.Payload.Requirements = e.Requirements
 
 			if jsonErr := sendJSON(w, http.StatusUnprocessableEntity, output); jsonErr != nil && jsonErr != context.DeadlineExceeded {
 				log.WithError(jsonErr).Warn("Failed to send JSON on ResponseWriter")
@@ -111,16 +131,17 @@ func HandleResponseError(err error, w http.ResponseWriter, r *http.Request) {
 			var output struct {
 				HTTPError
 				Payload struct {
-					Reasons []string `json:"reasons,omitempty"`
+					Reasons      []string              `json:"reasons,omitempty"`
+					Requirements *PasswordRequirements `json:"requirements,omitempty"`
 				} `json:"weak_password,omitempty"`
 			}
 
 			output.HTTPStatus = http.StatusUnprocessableEntity
 			output.ErrorCode = apierrors.ErrorCodeWeakPassword
 			output.Message = e.Message
+			output.ErrorID = errorID
 			output.Payload.Reasons = e.Reasons
-
-			w.Header().Set("x-sb-error-code", output.ErrorCode)
+			output.Payload.Requirements = e.Requirements
 
 			if jsonErr := sendJSON(w, output.HTTPStatus, output); jsonErr != nil && jsonErr != context.DeadlineExceeded {
 				log.WithError(jsonErr).Warn("Failed to send JSON on ResponseWriter")
@@ -128,9 +149,9 @@ func HandleResponseError(err error, w http.ResponseWriter, r *http.Request) {
 		}
 
 	case *HTTPError:
+		e.ErrorID = errorID
 		switch {
 		case e.HTTPStatus >= http.StatusInternalServerError:
-			e.ErrorID = errorID
 			// this will get us the stack trace too
 			log.WithError(e.Cause()).Error(e.Error())
 		case e.HTTPStatus == http.StatusTooManyRequests:
@@ -147,6 +168,7 @@ func HandleResponseError(err error, w http.ResponseWriter, r *http.Request) {
 			resp := HTTPErrorResponse20240101{
 				Code:    e.ErrorCode,
 				Message: e.Message,
+				ErrorID: errorID,
 			}
 
 			if resp.Code == "" {
@@ -182,6 +204,17 @@ func HandleResponseError(err error, w http.ResponseWriter, r *http.Request) {
 			}
 		}
 
+	case *SCIMError:
+		if e.HTTPStatus >= http.StatusInternalServerError {
+			log.WithError(e.Cause()).Error(e.Error())
+		} else {
+			log.WithError(e.Cause()).Info(e.Error())
+		}
+
+		if jsonErr := sendSCIM(w, e.HTTPStatus, e); jsonErr != nil && jsonErr != context.DeadlineExceeded {
+			log.WithError(jsonErr).Warn("Failed to send JSON on ResponseWriter")
+		}
+
 	case *OAuthError:
 		log.WithError(e.Cause()).Info(e.Error())
 		if jsonErr := sendJSON(w, http.StatusBadRequest, e); jsonErr != nil && jsonErr != context.DeadlineExceeded {
@@ -193,11 +226,13 @@ func HandleResponseError(err error, w http.ResponseWriter, r *http.Request) {
 
 	default:
 		log.WithError(e).Errorf("Unhandled server error: %s", e.Error())
+		w.Header().Set("x-sb-error-code", apierrors.ErrorCodeUnexpectedFailure)
 
 		if apiVersion.Compare(APIVersion20240101) >= 0 {
 			resp := HTTPErrorResponse20240101{
 				Code:    apierrors.ErrorCodeUnexpectedFailure,
 				Message: "Unexpected failure, please check server logs for more information",
+				ErrorID: errorID,
 			}
 
 			if jsonErr := sendJSON(w, http.StatusInternalServerError, resp); jsonErr != nil && jsonErr != context.DeadlineExceeded {
@@ -208,6 +243,7 @@ func HandleResponseError(err error, w http.ResponseWriter, r *http.Request) {
 				HTTPStatus: http.StatusInternalServerError,
 				ErrorCode:  apierrors.ErrorCodeUnexpectedFailure,
 				Message:    "Unexpected failure, please check server logs for more information",
+				ErrorID:    errorID,
 			}
 
 			if jsonErr := sendJSON(w, http.StatusInternalServerError, httpError); jsonErr != nil && jsonErr != context.DeadlineExceeded {
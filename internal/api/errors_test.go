@@ -3,6 +3,7 @@ package api
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -12,6 +13,7 @@ import (
 	"github.com/supabase/auth/internal/api/apierrors"
 	"github.com/supabase/auth/internal/conf"
 	"github.com/supabase/auth/internal/observability"
+	"github.com/supabase/auth/internal/utilities"
 )
 
 func TestHandleResponseErrorWithHTTPError(t *testing.T) {
@@ -69,6 +71,63 @@ func TestHandleResponseErrorWithHTTPError(t *testing.T) {
 	}
 }
 
+func TestHandleResponseErrorWithErrorID(t *testing.T) {
+	examples := []struct {
+		Error        error
+		APIVersion   string
+		ExpectedBody string
+	}{
+		{
+			Error:        apierrors.NewBadRequestError(apierrors.ErrorCodeBadJSON, "Unable to parse JSON"),
+			ExpectedBody: `{"code":400,"error_code":"bad_json","msg":"Unable to parse JSON","error_id":"request-id"}`,
+		},
+		{
+			Error:        apierrors.NewBadRequestError(apierrors.ErrorCodeBadJSON, "Unable to parse JSON"),
+			APIVersion:   "2024-01-01",
+			ExpectedBody: `{"code":"bad_json","message":"Unable to parse JSON","error_id":"request-id"}`,
+		},
+		{
+			Error: &WeakPasswordError{
+				Message:      "Password should be at least 8 characters.",
+				Reasons:      []string{"length"},
+				Requirements: &PasswordRequirements{MinLength: 8},
+			},
+			ExpectedBody: `{"code":422,"error_code":"weak_password","msg":"Password should be at least 8 characters.","error_id":"request-id","weak_password":{"reasons":["length"],"requirements":{"min_length":8}}}`,
+		},
+		{
+			Error: &WeakPasswordError{
+				Message:      "Password should be at least 8 characters.",
+				Reasons:      []string{"length"},
+				Requirements: &PasswordRequirements{MinLength: 8},
+			},
+			APIVersion:   "2024-01-01",
+			ExpectedBody: `{"code":"weak_password","message":"Password should be at least 8 characters.","error_id":"request-id","weak_password":{"reasons":["length"],"requirements":{"min_length":8}}}`,
+		},
+		{
+			Error:        errors.New("unhandled"),
+			APIVersion:   "2024-01-01",
+			ExpectedBody: `{"code":"unexpected_failure","message":"Unexpected failure, please check server logs for more information","error_id":"request-id"}`,
+		},
+	}
+
+	for _, example := range examples {
+		rec := httptest.NewRecorder()
+		req, err := http.NewRequest(http.MethodPost, "http://example.com", nil)
+		require.NoError(t, err)
+		req = req.WithContext(utilities.WithRequestID(req.Context(), "request-id"))
+
+		if example.APIVersion != "" {
+			req.Header.Set(APIVersionHeaderName, example.APIVersion)
+		}
+
+		HandleResponseError(example.Error, rec, req)
+
+		require.JSONEq(t, example.ExpectedBody, rec.Body.String())
+		require.Equal(t, "request-id", rec.Header().Get("x-sb-error-id"))
+		require.NotEmpty(t, rec.Header().Get("x-sb-error-code"))
+	}
+}
+
 func TestRecoverer(t *testing.T) {
 	var logBuffer bytes.Buffer
 	config, err := conf.LoadGlobal(apiTestConfig)
@@ -15,6 +15,7 @@ import (
 	"github.com/supabase/auth/internal/api/apierrors"
 	"github.com/supabase/auth/internal/api/provider"
 	"github.com/supabase/auth/internal/conf"
+	"github.com/supabase/auth/internal/hooks/v0hooks"
 	"github.com/supabase/auth/internal/metering"
 	"github.com/supabase/auth/internal/models"
 	"github.com/supabase/auth/internal/observability"
@@ -136,6 +137,10 @@ func (a *API) GetExternalProviderRedirectURL(w http.ResponseWriter, r *http.Requ
 
 	// Use the flow state ID as the state parameter (UUID format)
 	authURL := p.AuthCodeURL(flowState.ID.String(), authUrlParams...)
+	if authURL == "" {
+		// providers that request the URL from a server return an empty one on errors
+		return "", apierrors.NewInternalServerError("Error getting authorization URL from external provider")
+	}
 
 	return authURL, nil
 }
@@ -161,6 +166,8 @@ func (a *API) handleOAuthCallback(r *http.Request) (*OAuthProviderData, error) {
 	case "twitter":
 		// future OAuth1.0 providers will use this method
 		oAuthResponseData, err = a.oAuth1Callback(ctx, providerType)
+	case "steam":
+		oAuthResponseData, err = a.openIDCallback(ctx, r, providerType)
 	default:
 		oAuthResponseData, err = a.oAuthCallback(ctx, r, providerType)
 	}
@@ -246,10 +253,16 @@ func (a *API) internalExternalProviderCallback(w http.ResponseWriter, r *http.Re
 				return terr
 			}
 		}
+		if a.config.External.ProviderTokens.Enabled && data.token != "" {
+			if terr = a.saveProviderToken(tx, userData, providerType, data); terr != nil {
+				return terr
+			}
+		}
 		if flowState != nil && flowState.IsPKCE() {
 			// PKCE flow: update flow state with user ID and tokens
-			flowState.ProviderAccessToken = providerAccessToken
-			flowState.ProviderRefreshToken = providerRefreshToken
+			if terr = flowState.SetProviderTokens(providerAccessToken, providerRefreshToken, a.config.Security.DBEncryption); terr != nil {
+				return terr
+			}
 			flowState.UserID = &(user.ID)
 			issueTime := time.Now()
 			flowState.AuthCodeIssuedAt = &issueTime
@@ -346,6 +359,10 @@ func (a *API) createAccountFromExternalIdentity(tx *storage.Connection, r *http.
 			return 0, nil, apierrors.NewUnprocessableEntityError(apierrors.ErrorCodeSignupDisabled, "Signups not allowed for this instance")
 		}
 
+		if terr = a.checkSignupEmailDomain(tx, r, providerType, decision.CandidateEmail.Email); terr != nil {
+			return 0, nil, terr
+		}
+
 		params := &SignupParams{
 			Provider: providerType,
 			Email:    decision.CandidateEmail.Email,
@@ -373,6 +390,12 @@ func (a *API) createAccountFromExternalIdentity(tx *storage.Connection, r *http.
 			return 0, nil, terr
 		}
 
+		changes := &v0hooks.MetaDataChanges{
+			UserMetaData: userData.UserMetaData,
+			AppMetaData:  userData.AppMetaData,
+		}
+		changes.Apply(user)
+
 		if user, terr = a.signupNewUser(tx, user); terr != nil {
 			return 0, nil, terr
 		}
@@ -386,6 +409,18 @@ func (a *API) createAccountFromExternalIdentity(tx *storage.Connection, r *http.
 		user = decision.User
 		identity = decision.Identities[0]
 
+		if providerType == "apple" {
+			// Apple only sends the name of the user on the first
+			// sign-in, so keep the one captured back then
+			for _, key := range []string{"name", "full_name", "given_name", "family_name"} {
+				if _, ok := identityData[key]; !ok {
+					if value, ok := identity.IdentityData[key]; ok {
+						identityData[key] = value
+					}
+				}
+			}
+		}
+
 		identity.IdentityData = identityData
 		if terr = tx.UpdateOnly(identity, "identity_data", "last_sign_in_at"); terr != nil {
 			return 0, nil, terr
@@ -404,6 +439,29 @@ func (a *API) createAccountFromExternalIdentity(tx *storage.Connection, r *http.
 		return 0, nil, apierrors.NewInternalServerError("Unknown automatic linking decision: %v", decision.Decision)
 	}
 
+	if providerType == "azure" && config.External.Azure.GroupsInAppMetadata {
+		// app_metadata can't be changed by the user, so the groups can
+		// be relied on for role mapping; they are left as they were
+		// when the ID token only references Microsoft Graph
+		if groups, ok := provider.AzureGroups(userData); ok {
+			if terr = user.UpdateAppMetaData(tx, map[string]interface{}{
+				"azure_groups": groups,
+			}); terr != nil {
+				return 0, nil, terr
+			}
+		}
+	}
+
+	if providerType == "keycloak" && config.External.Keycloak.RolesInAppMetadata {
+		if roles, ok := provider.KeycloakRoles(userData); ok {
+			if terr = user.UpdateAppMetaData(tx, map[string]interface{}{
+				"roles": roles,
+			}); terr != nil {
+				return 0, nil, terr
+			}
+		}
+	}
+
 	if user.IsBanned() {
 		return 0, nil, apierrors.NewForbiddenError(apierrors.ErrorCodeUserBanned, "User is banned")
 	}
@@ -476,6 +534,10 @@ func (a *API) processInvite(r *http.Request, tx *storage.Connection, userData *p
 		return nil, apierrors.NewInternalServerError("Database error finding user").WithInternalError(err)
 	}
 
+	if user.ConfirmationSentAt == nil || isOtpExpired(user.ConfirmationSentAt, config.Mailer.InviteExp) {
+		return nil, apierrors.NewForbiddenError(apierrors.ErrorCodeOTPExpired, "Invite has expired")
+	}
+
 	var emailData *provider.Email
 	var emails []string
 	for i, e := range userData.Emails {
@@ -669,103 +731,7 @@ func (a *API) loadExternalStateFromJWT(ctx context.Context, db *storage.Connecti
 
 // Provider returns a Provider interface for the given name.
 func (a *API) Provider(ctx context.Context, name string, scopes string) (provider.Provider, conf.OAuthProviderConfiguration, error) {
-	config := a.config
-	name = strings.ToLower(name)
-
-	var err error
-	var p provider.Provider
-	var pConfig conf.OAuthProviderConfiguration
-
-	switch name {
-	case "apple":
-		pConfig = config.External.Apple
-		p, err = provider.NewAppleProvider(ctx, pConfig)
-	case "azure":
-		pConfig = config.External.Azure
-		p, err = provider.NewAzureProvider(pConfig, scopes)
-	case "bitbucket":
-		pConfig = config.External.Bitbucket
-		p, err = provider.NewBitbucketProvider(pConfig)
-	case "discord":
-		pConfig = config.External.Discord
-		p, err = provider.NewDiscordProvider(pConfig, scopes)
-	case "facebook":
-		pConfig = config.External.Facebook
-		p, err = provider.NewFacebookProvider(pConfig, scopes)
-	case "figma":
-		pConfig = config.External.Figma
-		p, err = provider.NewFigmaProvider(pConfig, scopes)
-	case "fly":
-		pConfig = config.External.Fly
-		p, err = provider.NewFlyProvider(pConfig, scopes)
-	case "generic_oidc_1":
-		pConfig = *config.External.GenericOIDC1.OAuthProviderConfiguration
-		p, err = provider.NewGenericProvider(config.External.GenericOIDC1, scopes)
-	case "generic_oidc_2":
-		pConfig = *config.External.GenericOIDC2.OAuthProviderConfiguration
-		p, err = provider.NewGenericProvider(config.External.GenericOIDC2, scopes)
-	case "generic_oidc_3":
-		pConfig = *config.External.GenericOIDC3.OAuthProviderConfiguration
-		p, err = provider.NewGenericProvider(config.External.GenericOIDC3, scopes)
-	case "github":
-		pConfig = config.External.Github
-		p, err = provider.NewGithubProvider(pConfig, scopes)
-	case "gitlab":
-		pConfig = config.External.Gitlab
-		p, err = provider.NewGitlabProvider(pConfig, scopes)
-	case "google":
-		pConfig = config.External.Google
-		p, err = provider.NewGoogleProvider(ctx, pConfig, scopes)
-	case "kakao":
-		pConfig = config.External.Kakao
-		p, err = provider.NewKakaoProvider(pConfig, scopes)
-	case "keycloak":
-		pConfig = config.External.Keycloak
-		p, err = provider.NewKeycloakProvider(pConfig, scopes)
-	case "linkedin":
-		pConfig = config.External.Linkedin
-		p, err = provider.NewLinkedinProvider(pConfig, scopes)
-	case "linkedin_oidc":
-		pConfig = config.External.LinkedinOIDC
-		p, err = provider.NewLinkedinOIDCProvider(ctx, pConfig, scopes)
-	case "notion":
-		pConfig = config.External.Notion
-		p, err = provider.NewNotionProvider(pConfig)
-	case "snapchat":
-		pConfig = config.External.Snapchat
-		p, err = provider.NewSnapchatProvider(pConfig, scopes)
-	case "spotify":
-		pConfig = config.External.Spotify
-		p, err = provider.NewSpotifyProvider(pConfig, scopes)
-	case "slack":
-		pConfig = config.External.Slack
-		p, err = provider.NewSlackProvider(pConfig, scopes)
-	case "slack_oidc":
-		pConfig = config.External.SlackOIDC
-		p, err = provider.NewSlackOIDCProvider(pConfig, scopes)
-	case "twitch":
-		pConfig = config.External.Twitch
-		p, err = provider.NewTwitchProvider(pConfig, scopes)
-	case "twitter":
-		pConfig = config.External.Twitter
-		p, err = provider.NewTwitterProvider(pConfig, scopes)
-	case "x":
-		pConfig = config.External.X
-		p, err = provider.NewXProvider(pConfig, scopes)
-	case "vercel_marketplace":
-		pConfig = config.External.VercelMarketplace
-		p, err = provider.NewVercelMarketplaceProvider(ctx, pConfig, scopes)
-	case "workos":
-		pConfig = config.External.WorkOS
-		p, err = provider.NewWorkOSProvider(pConfig)
-	case "zoom":
-		pConfig = config.External.Zoom
-		p, err = provider.NewZoomProvider(pConfig)
-	default:
-		return nil, pConfig, fmt.Errorf("Provider %s could not be found", name)
-	}
-
-	return p, pConfig, err
+	return provider.New(ctx, a.config, name, scopes)
 }
 
 func redirectErrors(handler apiHandler, w http.ResponseWriter, r *http.Request, u *url.URL) {
@@ -16,11 +16,13 @@ import (
 
 	"github.com/coreos/go-oidc/v3/oidc"
 	"github.com/supabase/auth/internal/api/provider"
+	"github.com/supabase/auth/internal/models"
 )
 
 const (
-	azureUser        string = `{"name":"Azure Test","email":"azure@example.com","sub":"azuretestid"}`
-	azureUserNoEmail string = `{"name":"Azure Test","sub":"azuretestid"}`
+	azureUser           string = `{"name":"Azure Test","email":"azure@example.com","sub":"azuretestid"}`
+	azureUserNoEmail    string = `{"name":"Azure Test","sub":"azuretestid"}`
+	azureUserWithGroups string = `{"name":"Azure Test","email":"azure@example.com","sub":"azuretestid","tid":"72f988bf-86f1-41af-91ab-2d7cd011db47","groups":["admins","developers"]}`
 )
 
 func idTokenPrivateKey() *rsa.PrivateKey {
@@ -67,6 +69,9 @@ func mintIDToken(user string) string {
 		Name    string `json:"name,omitempty"`
 		Email   string `json:"email,omitempty"`
 		XmsEdov any    `json:"xms_edov,omitempty"`
+
+		Tid    string   `json:"tid,omitempty"`
+		Groups []string `json:"groups,omitempty"`
 	}
 
 	if err := json.Unmarshal([]byte(user), &idToken); err != nil {
@@ -258,3 +263,64 @@ func (ts *ExternalTestSuite) TestInviteTokenExternalAzureErrorWhenEmailDoesntMat
 
 	assertAuthorizationFailure(ts, u, "Invited email does not match emails from external provider", "invalid_request", "")
 }
+
+func (ts *ExternalTestSuite) TestSignupExternalAzureAllowedTenants() {
+	setupAzureOverrideVerifiers()
+
+	ts.Config.DisableSignup = false
+	ts.Config.External.Azure.AllowedTenants = []string{"72F988BF-86F1-41AF-91AB-2D7CD011DB47"}
+	defer func() {
+		ts.Config.External.Azure.AllowedTenants = nil
+	}()
+
+	tokenCount := 0
+	code := "authcode"
+	server := AzureTestSignupSetup(ts, &tokenCount, code, azureUserWithGroups)
+	defer server.Close()
+
+	u := performAuthorization(ts, "azure", code, "")
+
+	assertAuthorizationSuccess(ts, u, tokenCount, -1, "azure@example.com", "Azure Test", "azuretestid", "")
+}
+
+func (ts *ExternalTestSuite) TestSignupExternalAzureTenantNotAllowed() {
+	setupAzureOverrideVerifiers()
+
+	ts.Config.DisableSignup = false
+	ts.Config.External.Azure.AllowedTenants = []string{"9188040d-6c67-4c5b-b112-36a304b66dad"}
+	defer func() {
+		ts.Config.External.Azure.AllowedTenants = nil
+	}()
+
+	tokenCount := 0
+	code := "authcode"
+	server := AzureTestSignupSetup(ts, &tokenCount, code, azureUserWithGroups)
+	defer server.Close()
+
+	u := performAuthorization(ts, "azure", code, "")
+
+	assertAuthorizationFailure(ts, u, "Azure tenant is not allowed", "access_denied", "azure@example.com")
+}
+
+func (ts *ExternalTestSuite) TestSignupExternalAzureGroupsInAppMetadata() {
+	setupAzureOverrideVerifiers()
+
+	ts.Config.DisableSignup = false
+	ts.Config.External.Azure.GroupsInAppMetadata = true
+	defer func() {
+		ts.Config.External.Azure.GroupsInAppMetadata = false
+	}()
+
+	tokenCount := 0
+	code := "authcode"
+	server := AzureTestSignupSetup(ts, &tokenCount, code, azureUserWithGroups)
+	defer server.Close()
+
+	u := performAuthorization(ts, "azure", code, "")
+
+	assertAuthorizationSuccess(ts, u, tokenCount, -1, "azure@example.com", "Azure Test", "azuretestid", "")
+
+	user, err := models.FindUserByEmailAndAudience(ts.API.db, "azure@example.com", ts.Config.JWT.Aud)
+	ts.Require().NoError(err)
+	ts.Equal([]interface{}{"admins", "developers"}, user.AppMetaData["azure_groups"])
+}
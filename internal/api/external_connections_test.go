@@ -0,0 +1,68 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+
+	"github.com/supabase/auth/internal/conf"
+	"github.com/supabase/auth/internal/models"
+)
+
+func (ts *ExternalTestSuite) setupGitlabConnection(slug string, serverURL string) {
+	ts.Config.External.Connections = conf.ExternalConnections{{
+		Slug: slug,
+		Type: "gitlab",
+		GenericOAuthProviderConfiguration: conf.GenericOAuthProviderConfiguration{
+			OAuthProviderConfiguration: &conf.OAuthProviderConfiguration{
+				Enabled:     true,
+				ClientID:    []string{"connectionclientid"},
+				Secret:      "connectionsecret",
+				RedirectURI: ts.Config.External.Gitlab.RedirectURI,
+				URL:         serverURL,
+			},
+		},
+	}}
+}
+
+func (ts *ExternalTestSuite) TestSignupExternalConnection() {
+	ts.setupGitlabConnection("gitlab-internal", "https://gitlab.example.com")
+	defer func() { ts.Config.External.Connections = nil }()
+
+	req := httptest.NewRequest(http.MethodGet, "http://localhost/authorize?provider=gitlab-internal", nil)
+	w := httptest.NewRecorder()
+	ts.API.handler.ServeHTTP(w, req)
+	ts.Require().Equal(http.StatusFound, w.Code)
+	u, err := url.Parse(w.Header().Get("Location"))
+	ts.Require().NoError(err, "redirect url parse failed")
+	ts.Equal("https://gitlab.example.com/oauth/authorize", u.Scheme+"://"+u.Host+u.Path)
+	q := u.Query()
+	ts.Equal("connectionclientid", q.Get("client_id"))
+
+	assertValidOAuthState(ts, q.Get("state"), "gitlab-internal")
+}
+
+func (ts *ExternalTestSuite) TestSignupExternalConnection_AuthorizationCode() {
+	// additional emails from GitLab don't return confirm status
+	ts.Config.Mailer.Autoconfirm = true
+
+	tokenCount, userCount := 0, 0
+	code := "authcode"
+	emails := `[{"id":1,"email":"gitlab@example.com"}]`
+	server := GitlabTestSignupSetup(ts, &tokenCount, &userCount, code, gitlabUser, emails)
+	defer server.Close()
+
+	ts.setupGitlabConnection("gitlab-internal", server.URL)
+	defer func() { ts.Config.External.Connections = nil }()
+
+	u := performAuthorization(ts, "gitlab-internal", code, "")
+
+	assertAuthorizationSuccess(ts, u, tokenCount, userCount, "gitlab@example.com", "GitLab Test", "123", "http://example.com/avatar")
+
+	identity, err := models.FindIdentityByIdAndProvider(ts.API.db, "123", "gitlab-internal")
+	ts.Require().NoError(err)
+	ts.Equal("gitlab-internal", identity.Provider)
+
+	_, err = models.FindIdentityByIdAndProvider(ts.API.db, "123", "gitlab")
+	ts.True(models.IsNotFoundError(err))
+}
@@ -0,0 +1,138 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+
+	standardwebhooks "github.com/standard-webhooks/standard-webhooks/libraries/go"
+)
+
+const (
+	customUser        string = `{"sub":"customTestId","email":"custom@example.com","email_verified":true,"name":"Custom Test","picture":"http://example.com/avatar","custom_claims":{"department":"engineering"}}`
+	customUserNoEmail string = `{"sub":"customTestId","name":"Custom Test","picture":"http://example.com/avatar"}`
+)
+
+func CustomTestSignupSetup(ts *ExternalTestSuite, tokenCount *int, userCount *int, code string, user string) *httptest.Server {
+	wh, err := standardwebhooks.NewWebhook(strings.TrimPrefix(ts.Config.External.Custom.Secrets[0], "v1,"))
+	ts.Require().NoError(err)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ts.Equal(http.MethodPost, r.Method)
+		body, err := io.ReadAll(r.Body)
+		ts.Require().NoError(err)
+		ts.Require().NoError(wh.Verify(body, r.Header))
+
+		var req map[string]interface{}
+		ts.Require().NoError(json.Unmarshal(body, &req))
+
+		w.Header().Add("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/authorize":
+			ts.Equal(ts.Config.External.Custom.RedirectURI, req["redirect_uri"])
+			fmt.Fprintf(w, `{"url":"https://idp.example.com/login?state=%s"}`, req["state"])
+		case "/token":
+			*tokenCount++
+			ts.Equal(code, req["code"])
+			ts.Equal(ts.Config.External.Custom.RedirectURI, req["redirect_uri"])
+			fmt.Fprint(w, `{"access_token":"custom_token","token_type":"bearer","expires_in":3600}`)
+		case "/user":
+			*userCount++
+			ts.Equal("custom_token", req["access_token"])
+			fmt.Fprint(w, user)
+		default:
+			w.WriteHeader(500)
+			ts.Fail("unknown custom provider call %s", r.URL.Path)
+		}
+	}))
+
+	ts.Config.External.Custom.URL = server.URL
+
+	return server
+}
+
+func (ts *ExternalTestSuite) TestSignupExternalCustom() {
+	tokenCount, userCount := 0, 0
+	server := CustomTestSignupSetup(ts, &tokenCount, &userCount, "", customUser)
+	defer server.Close()
+
+	req := httptest.NewRequest(http.MethodGet, "http://localhost/authorize?provider=custom", nil)
+	w := httptest.NewRecorder()
+	ts.API.handler.ServeHTTP(w, req)
+	ts.Require().Equal(http.StatusFound, w.Code)
+	u, err := url.Parse(w.Header().Get("Location"))
+	ts.Require().NoError(err, "redirect url parse failed")
+	ts.Equal("https://idp.example.com/login", u.Scheme+"://"+u.Host+u.Path)
+
+	assertValidOAuthState(ts, u.Query().Get("state"), "custom")
+}
+
+func (ts *ExternalTestSuite) TestSignupExternalCustomEndpointError() {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+	ts.Config.External.Custom.URL = server.URL
+
+	req := httptest.NewRequest(http.MethodGet, "http://localhost/authorize?provider=custom", nil)
+	w := httptest.NewRecorder()
+	ts.API.handler.ServeHTTP(w, req)
+	ts.Equal(http.StatusInternalServerError, w.Code)
+}
+
+func (ts *ExternalTestSuite) TestSignupExternalCustom_AuthorizationCode() {
+	ts.Config.DisableSignup = false
+	tokenCount, userCount := 0, 0
+	code := "authcode"
+	server := CustomTestSignupSetup(ts, &tokenCount, &userCount, code, customUser)
+	defer server.Close()
+
+	u := performAuthorization(ts, "custom", code, "")
+
+	assertAuthorizationSuccess(ts, u, tokenCount, userCount, "custom@example.com", "Custom Test", "customTestId", "http://example.com/avatar")
+}
+
+func (ts *ExternalTestSuite) TestSignupExternalCustomDisableSignupErrorWhenNoUser() {
+	ts.Config.DisableSignup = true
+
+	tokenCount, userCount := 0, 0
+	code := "authcode"
+	server := CustomTestSignupSetup(ts, &tokenCount, &userCount, code, customUser)
+	defer server.Close()
+
+	u := performAuthorization(ts, "custom", code, "")
+
+	assertAuthorizationFailure(ts, u, "Signups not allowed for this instance", "access_denied", "custom@example.com")
+}
+
+func (ts *ExternalTestSuite) TestSignupExternalCustomErrorWhenEmptyEmail() {
+	ts.Config.DisableSignup = false
+
+	tokenCount, userCount := 0, 0
+	code := "authcode"
+	server := CustomTestSignupSetup(ts, &tokenCount, &userCount, code, customUserNoEmail)
+	defer server.Close()
+
+	u := performAuthorization(ts, "custom", code, "")
+
+	assertAuthorizationFailure(ts, u, "Error getting user email from external provider", "server_error", "")
+}
+
+func (ts *ExternalTestSuite) TestSignupExternalCustomDisableSignupSuccessWithPrimaryEmail() {
+	ts.Config.DisableSignup = true
+
+	ts.createUser("customTestId", "custom@example.com", "Custom Test", "http://example.com/avatar", "")
+
+	tokenCount, userCount := 0, 0
+	code := "authcode"
+	server := CustomTestSignupSetup(ts, &tokenCount, &userCount, code, customUser)
+	defer server.Close()
+
+	u := performAuthorization(ts, "custom", code, "")
+
+	assertAuthorizationSuccess(ts, u, tokenCount, userCount, "custom@example.com", "Custom Test", "customTestId", "http://example.com/avatar")
+}
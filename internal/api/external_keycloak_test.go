@@ -1,10 +1,18 @@
 package api
 
 import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/supabase/auth/internal/models"
 )
 
 const (
@@ -170,3 +178,72 @@ func (ts *ExternalTestSuite) TestInviteTokenExternalKeycloakErrorWhenEmailDoesnt
 
 	assertAuthorizationFailure(ts, u, "Invited email does not match emails from external provider", "invalid_request", "")
 }
+
+func (ts *ExternalTestSuite) TestSignupExternalKeycloakRolesInAppMetadata() {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	ts.Require().NoError(err)
+
+	publicJWK, err := jwk.FromRaw(privateKey.Public())
+	ts.Require().NoError(err)
+	ts.Require().NoError(publicJWK.Set(jwk.KeyIDKey, "test-key"))
+	ts.Require().NoError(publicJWK.Set(jwk.AlgorithmKey, "RS256"))
+
+	ts.Config.DisableSignup = false
+	ts.Config.External.Keycloak.RolesInAppMetadata = true
+	defer func() {
+		ts.Config.External.Keycloak.RolesInAppMetadata = false
+	}()
+
+	clientID := ts.Config.External.Keycloak.ClientID[0]
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/protocol/openid-connect/token":
+			now := time.Now()
+			token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+				"iss": server.URL,
+				"azp": clientID,
+				"sub": "keycloaktestid",
+				"iat": now.Unix(),
+				"exp": now.Add(time.Hour).Unix(),
+				"realm_access": map[string]interface{}{
+					"roles": []string{"admin"},
+				},
+				"resource_access": map[string]interface{}{
+					clientID: map[string]interface{}{
+						"roles": []string{"editor"},
+					},
+				},
+			})
+			token.Header["kid"] = "test-key"
+			accessToken, err := token.SignedString(privateKey)
+			ts.Require().NoError(err)
+
+			w.Header().Add("Content-Type", "application/json")
+			fmt.Fprintf(w, `{"access_token":%q,"expires_in":100000}`, accessToken)
+		case "/protocol/openid-connect/certs":
+			w.Header().Add("Content-Type", "application/json")
+			ts.Require().NoError(json.NewEncoder(w).Encode(map[string]interface{}{
+				"keys": []jwk.Key{publicJWK},
+			}))
+		case "/protocol/openid-connect/userinfo":
+			w.Header().Add("Content-Type", "application/json")
+			fmt.Fprint(w, keycloakUser)
+		default:
+			w.WriteHeader(500)
+			ts.Fail("unknown keycloak oauth call %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+	ts.Config.External.Keycloak.URL = server.URL
+
+	u := performAuthorization(ts, "keycloak", "authcode", "")
+
+	v, err := url.ParseQuery(u.Fragment)
+	ts.Require().NoError(err)
+	ts.NotEmpty(v.Get("access_token"))
+
+	user, err := models.FindUserByEmailAndAudience(ts.API.db, "keycloak@example.com", ts.Config.JWT.Aud)
+	ts.Require().NoError(err)
+	ts.Equal([]interface{}{"admin", "editor"}, user.AppMetaData["roles"])
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"time"
 
 	"github.com/gofrs/uuid"
 	"github.com/mrjones/oauth"
@@ -15,6 +16,7 @@ import (
 	"github.com/supabase/auth/internal/models"
 	"github.com/supabase/auth/internal/observability"
 	"github.com/supabase/auth/internal/utilities"
+	"go.opentelemetry.io/otel/attribute"
 	"golang.org/x/oauth2"
 )
 
@@ -23,6 +25,8 @@ type OAuthProviderData struct {
 	userData     *provider.UserProvidedData
 	token        string
 	refreshToken string
+	expiresAt    *time.Time
+	scopes       string
 	code         string
 }
 
@@ -116,16 +120,26 @@ func (a *API) oAuthCallback(ctx context.Context, r *http.Request, providerType s
 	if oauthClientState != nil {
 		tokenOpts = append(tokenOpts, oauth2.VerifierOption(*oauthClientState.CodeVerifier))
 	}
-	token, err := oauthProvider.GetOAuthToken(ctx, oauthCode, tokenOpts...)
+	spanCtx, span := observability.StartSpan(ctx, "oauth.exchange_code", attribute.String("gotrue.provider", providerType))
+	token, err := oauthProvider.GetOAuthToken(spanCtx, oauthCode, tokenOpts...)
+	observability.EndSpan(span, err)
 	if err != nil {
 		return nil, apierrors.NewInternalServerError("Unable to exchange external code: %s", oauthCode).WithInternalError(err)
 	}
 
-	userData, err := oauthProvider.GetUserData(ctx, token)
+	spanCtx, span = observability.StartSpan(ctx, "oauth.get_user_data", attribute.String("gotrue.provider", providerType))
+	userData, err := oauthProvider.GetUserData(spanCtx, token)
+	observability.EndSpan(span, err)
 	if err != nil {
 		return nil, apierrors.NewInternalServerError("Error getting user profile from external provider").WithInternalError(err)
 	}
 
+	if providerType == "azure" {
+		if err := provider.ValidateAzureTenant(userData, a.config.External.Azure.AllowedTenants); err != nil {
+			return nil, apierrors.NewForbiddenError(apierrors.ErrorCodeProviderTenantNotAllowed, "Azure tenant is not allowed").WithInternalError(err)
+		}
+	}
+
 	switch externalProvider := oauthProvider.(type) {
 	case *provider.AppleProvider:
 		// apple only returns user info the first time
@@ -138,12 +152,20 @@ func (a *API) oAuthCallback(ctx context.Context, r *http.Request, providerType s
 		}
 	}
 
-	return &OAuthProviderData{
+	data := &OAuthProviderData{
 		userData:     userData,
 		token:        token.AccessToken,
 		refreshToken: token.RefreshToken,
 		code:         oauthCode,
-	}, nil
+	}
+	if !token.Expiry.IsZero() {
+		data.expiresAt = &token.Expiry
+	}
+	if scopes, ok := token.Extra("scope").(string); ok {
+		data.scopes = scopes
+	}
+
+	return data, nil
 }
 
 func (a *API) oAuth1Callback(ctx context.Context, providerType string) (*OAuthProviderData, error) {
@@ -176,6 +198,42 @@ func (a *API) oAuth1Callback(ctx context.Context, providerType string) (*OAuthPr
 
 }
 
+// openIDCallback verifies the OpenID 2.0 assertion of providers like Steam,
+// which identify users without issuing tokens.
+func (a *API) openIDCallback(ctx context.Context, r *http.Request, providerType string) (*OAuthProviderData, error) {
+	oAuthProvider, _, err := a.OAuthProvider(ctx, providerType)
+	if err != nil {
+		return nil, apierrors.NewBadRequestError(apierrors.ErrorCodeOAuthProviderNotSupported, "Unsupported provider: %+v", err).WithInternalError(err)
+	}
+	steamProvider, ok := oAuthProvider.(*provider.SteamProvider)
+	if !ok {
+		return nil, apierrors.NewBadRequestError(apierrors.ErrorCodeOAuthProviderNotSupported, "Provider %v cannot be used for OpenID", providerType)
+	}
+
+	rq := r.URL.Query()
+	if rq.Get("openid.mode") == "cancel" {
+		return nil, apierrors.NewOAuthError("access_denied", "The user denied the OpenID authentication request")
+	}
+
+	spanCtx, span := observability.StartSpan(ctx, "openid.verify_assertion", attribute.String("gotrue.provider", providerType))
+	steamID, err := steamProvider.VerifyAssertion(spanCtx, rq)
+	observability.EndSpan(span, err)
+	if err != nil {
+		return nil, apierrors.NewBadRequestError(apierrors.ErrorCodeBadOAuthCallback, "OpenID callback with invalid assertion").WithInternalError(err)
+	}
+
+	spanCtx, span = observability.StartSpan(ctx, "oauth.get_user_data", attribute.String("gotrue.provider", providerType))
+	userData, err := steamProvider.FetchUserData(spanCtx, steamID)
+	observability.EndSpan(span, err)
+	if err != nil {
+		return nil, apierrors.NewInternalServerError("Error getting user profile from external provider").WithInternalError(err)
+	}
+
+	return &OAuthProviderData{
+		userData: userData,
+	}, nil
+}
+
 // OAuthProvider returns the corresponding oauth provider as an OAuthProvider interface
 func (a *API) OAuthProvider(ctx context.Context, name string) (provider.OAuthProvider, conf.OAuthProviderConfiguration, error) {
 	providerCandidate, pConfig, err := a.Provider(ctx, name, "")
@@ -0,0 +1,89 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+)
+
+const (
+	qqUser string = `{"ret":0,"msg":"","nickname":"QQ Test","gender":"男","figureurl_qq_1":"https://thirdqq.qlogo.cn/test/40","figureurl_qq_2":"https://thirdqq.qlogo.cn/test/100"}`
+)
+
+func (ts *ExternalTestSuite) TestSignupExternalQQ() {
+	req := httptest.NewRequest(http.MethodGet, "http://localhost/authorize?provider=qq", nil)
+	w := httptest.NewRecorder()
+	ts.API.handler.ServeHTTP(w, req)
+	ts.Require().Equal(http.StatusFound, w.Code)
+	u, err := url.Parse(w.Header().Get("Location"))
+	ts.Require().NoError(err, "redirect url parse failed")
+	q := u.Query()
+	ts.Equal(ts.Config.External.QQ.RedirectURI, q.Get("redirect_uri"))
+	ts.Equal(ts.Config.External.QQ.ClientID, []string{q.Get("client_id")})
+	ts.Equal("code", q.Get("response_type"))
+	ts.Equal("get_user_info", q.Get("scope"))
+
+	assertValidOAuthState(ts, q.Get("state"), "qq")
+}
+
+func QQTestSignupSetup(ts *ExternalTestSuite, tokenCount *int, userCount *int, code string, me string) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/oauth2.0/token":
+			*tokenCount++
+			ts.Equal(code, r.URL.Query().Get("code"))
+			ts.Equal("authorization_code", r.URL.Query().Get("grant_type"))
+			ts.Equal(ts.Config.External.QQ.RedirectURI, r.URL.Query().Get("redirect_uri"))
+
+			w.Header().Add("Content-Type", "application/json")
+			fmt.Fprint(w, `{"access_token":"qq_token","expires_in":"7776000","refresh_token":"qq_refresh_token"}`)
+		case "/oauth2.0/me":
+			ts.Equal("qq_token", r.URL.Query().Get("access_token"))
+			ts.Equal("1", r.URL.Query().Get("unionid"))
+
+			w.Header().Add("Content-Type", "application/json")
+			fmt.Fprint(w, me)
+		case "/user/get_user_info":
+			*userCount++
+			ts.Equal(ts.Config.External.QQ.ClientID[0], r.URL.Query().Get("oauth_consumer_key"))
+			ts.Equal("qqOpenId", r.URL.Query().Get("openid"))
+
+			w.Header().Add("Content-Type", "application/json")
+			fmt.Fprint(w, qqUser)
+		default:
+			w.WriteHeader(500)
+			ts.Fail("unknown qq oauth call %s", r.URL.Path)
+		}
+	}))
+
+	ts.Config.External.QQ.URL = server.URL
+
+	return server
+}
+
+func (ts *ExternalTestSuite) TestSignupExternalQQ_AuthorizationCode() {
+	ts.Config.DisableSignup = false
+	ts.Config.External.QQ.EmailOptional = true
+	tokenCount, userCount := 0, 0
+	code := "authcode"
+	server := QQTestSignupSetup(ts, &tokenCount, &userCount, code, `{"client_id":"testclientid","openid":"qqOpenId","unionid":"qqUnionId"}`)
+	defer server.Close()
+
+	u := performAuthorization(ts, "qq", code, "")
+
+	assertAuthorizationSuccess(ts, u, tokenCount, userCount, "", "QQ Test", "qqUnionId", "https://thirdqq.qlogo.cn/test/100")
+}
+
+func (ts *ExternalTestSuite) TestSignupExternalQQ_AuthorizationCode_Error() {
+	ts.Config.DisableSignup = false
+	ts.Config.External.QQ.EmailOptional = true
+	tokenCount, userCount := 0, 0
+	code := "authcode"
+	server := QQTestSignupSetup(ts, &tokenCount, &userCount, code, `{"error":100016,"error_description":"access token check failed"}`)
+	defer server.Close()
+
+	u := performAuthorization(ts, "qq", code, "")
+
+	assertAuthorizationFailure(ts, u, "Error getting user profile from external provider", "server_error", "")
+}
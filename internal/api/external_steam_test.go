@@ -0,0 +1,154 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+)
+
+const (
+	steamID   string = "76561197960287930"
+	steamUser string = `{"response":{"players":[{"steamid":"76561197960287930","personaname":"Steam Test","profileurl":"https://steamcommunity.com/id/steamtest/","avatarfull":"https://avatars.steamstatic.com/steamtest_full.jpg","loccountrycode":"US"}]}}`
+)
+
+func (ts *ExternalTestSuite) TestSignupExternalSteam() {
+	req := httptest.NewRequest(http.MethodGet, "http://localhost/authorize?provider=steam", nil)
+	w := httptest.NewRecorder()
+	ts.API.handler.ServeHTTP(w, req)
+	ts.Require().Equal(http.StatusFound, w.Code)
+	u, err := url.Parse(w.Header().Get("Location"))
+	ts.Require().NoError(err, "redirect url parse failed")
+	ts.Equal("https://steamcommunity.com/openid/login", u.Scheme+"://"+u.Host+u.Path)
+
+	q := u.Query()
+	ts.Equal("http://specs.openid.net/auth/2.0", q.Get("openid.ns"))
+	ts.Equal("checkid_setup", q.Get("openid.mode"))
+	ts.Equal("https://identity.services.netlify.com/", q.Get("openid.realm"))
+	ts.Equal("http://specs.openid.net/auth/2.0/identifier_select", q.Get("openid.claimed_id"))
+	ts.Equal("http://specs.openid.net/auth/2.0/identifier_select", q.Get("openid.identity"))
+
+	returnTo, err := url.Parse(q.Get("openid.return_to"))
+	ts.Require().NoError(err)
+	ts.Equal(ts.Config.External.Steam.RedirectURI, returnTo.Scheme+"://"+returnTo.Host+returnTo.Path)
+
+	assertValidOAuthState(ts, returnTo.Query().Get("state"), "steam")
+}
+
+func SteamTestSignupSetup(ts *ExternalTestSuite, verifyCount *int, userCount *int, isValid string) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/openid/login":
+			*verifyCount++
+			ts.Require().NoError(r.ParseForm())
+			ts.Equal(http.MethodPost, r.Method)
+			ts.Equal("check_authentication", r.PostForm.Get("openid.mode"))
+			ts.Equal("steam_signature", r.PostForm.Get("openid.sig"))
+
+			w.Header().Add("Content-Type", "text/plain")
+			fmt.Fprintf(w, "ns:http://specs.openid.net/auth/2.0\nis_valid:%s\n", isValid)
+		case "/ISteamUser/GetPlayerSummaries/v0002/":
+			*userCount++
+			ts.Equal(ts.Config.External.Steam.Secret, r.URL.Query().Get("key"))
+			ts.Equal(steamID, r.URL.Query().Get("steamids"))
+
+			w.Header().Add("Content-Type", "application/json")
+			fmt.Fprint(w, steamUser)
+		default:
+			w.WriteHeader(500)
+			ts.Fail("unknown steam call %s", r.URL.Path)
+		}
+	}))
+
+	ts.Config.External.Steam.URL = server.URL
+	ts.Config.External.Steam.ApiURL = server.URL
+
+	return server
+}
+
+// performSteamAuthorization follows the redirect to Steam and returns to the
+// callback with a positive assertion for the claimed ID.
+func performSteamAuthorization(ts *ExternalTestSuite, claimedID string) *url.URL {
+	w := performAuthorizationRequest(ts, "steam", "")
+	ts.Require().Equal(http.StatusFound, w.Code)
+	u, err := url.Parse(w.Header().Get("Location"))
+	ts.Require().NoError(err, "redirect url parse failed")
+	returnTo := u.Query().Get("openid.return_to")
+	returnToURL, err := url.Parse(returnTo)
+	ts.Require().NoError(err)
+
+	// auth server callback
+	testURL, err := url.Parse("http://localhost/callback")
+	ts.Require().NoError(err)
+	v := returnToURL.Query()
+	v.Set("openid.ns", "http://specs.openid.net/auth/2.0")
+	v.Set("openid.mode", "id_res")
+	v.Set("openid.op_endpoint", u.Scheme+"://"+u.Host+u.Path)
+	v.Set("openid.claimed_id", claimedID)
+	v.Set("openid.identity", claimedID)
+	v.Set("openid.return_to", returnTo)
+	v.Set("openid.response_nonce", "2025-01-01T00:00:00Zsteamnonce")
+	v.Set("openid.assoc_handle", "1234567890")
+	v.Set("openid.signed", "signed,op_endpoint,claimed_id,identity,return_to,response_nonce,assoc_handle")
+	v.Set("openid.sig", "steam_signature")
+	testURL.RawQuery = v.Encode()
+	req := httptest.NewRequest(http.MethodGet, testURL.String(), nil)
+	w = httptest.NewRecorder()
+	ts.API.handler.ServeHTTP(w, req)
+	ts.Require().Equal(http.StatusFound, w.Code)
+	u, err = url.Parse(w.Header().Get("Location"))
+	ts.Require().NoError(err, "redirect url parse failed")
+	ts.Require().Equal("/admin", u.Path)
+
+	return u
+}
+
+func (ts *ExternalTestSuite) TestSignupExternalSteam_Assertion() {
+	ts.Config.External.Steam.EmailOptional = true
+	verifyCount, userCount := 0, 0
+	server := SteamTestSignupSetup(ts, &verifyCount, &userCount, "true")
+	defer server.Close()
+
+	u := performSteamAuthorization(ts, server.URL+"/openid/id/"+steamID)
+
+	assertAuthorizationSuccess(ts, u, verifyCount, userCount, "", "Steam Test", steamID, "https://avatars.steamstatic.com/steamtest_full.jpg")
+}
+
+func (ts *ExternalTestSuite) TestSignupExternalSteam_EmailRequired() {
+	ts.Config.External.Steam.EmailOptional = false
+	defer func() {
+		ts.Config.External.Steam.EmailOptional = true
+	}()
+	verifyCount, userCount := 0, 0
+	server := SteamTestSignupSetup(ts, &verifyCount, &userCount, "true")
+	defer server.Close()
+
+	u := performSteamAuthorization(ts, server.URL+"/openid/id/"+steamID)
+
+	assertAuthorizationFailure(ts, u, "Error getting user email from external provider", "server_error", "")
+}
+
+func (ts *ExternalTestSuite) TestSignupExternalSteam_InvalidAssertion() {
+	ts.Config.External.Steam.EmailOptional = true
+	verifyCount, userCount := 0, 0
+	server := SteamTestSignupSetup(ts, &verifyCount, &userCount, "false")
+	defer server.Close()
+
+	u := performSteamAuthorization(ts, server.URL+"/openid/id/"+steamID)
+
+	assertAuthorizationFailure(ts, u, "OpenID callback with invalid assertion", "invalid_request", "")
+	ts.Equal(1, verifyCount)
+	ts.Equal(0, userCount)
+}
+
+func (ts *ExternalTestSuite) TestSignupExternalSteam_ForeignClaimedID() {
+	ts.Config.External.Steam.EmailOptional = true
+	verifyCount, userCount := 0, 0
+	server := SteamTestSignupSetup(ts, &verifyCount, &userCount, "true")
+	defer server.Close()
+
+	u := performSteamAuthorization(ts, "https://openid.example.com/id/"+steamID)
+
+	assertAuthorizationFailure(ts, u, "OpenID callback with invalid assertion", "invalid_request", "")
+	ts.Equal(0, verifyCount)
+}
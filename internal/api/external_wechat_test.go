@@ -0,0 +1,130 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+)
+
+const (
+	wechatUser          string = `{"openid":"wechatOpenId","unionid":"wechatUnionId","nickname":"WeChat Test","headimgurl":"https://thirdwx.qlogo.cn/mmopen/test/132","country":"CN","province":"Guangdong","city":"Shenzhen"}`
+	wechatUserNoUnionID string = `{"openid":"wechatOpenId","nickname":"WeChat Test","headimgurl":"https://thirdwx.qlogo.cn/mmopen/test/132"}`
+)
+
+func (ts *ExternalTestSuite) TestSignupExternalWeChat() {
+	req := httptest.NewRequest(http.MethodGet, "http://localhost/authorize?provider=wechat", nil)
+	w := httptest.NewRecorder()
+	ts.API.handler.ServeHTTP(w, req)
+	ts.Require().Equal(http.StatusFound, w.Code)
+	u, err := url.Parse(w.Header().Get("Location"))
+	ts.Require().NoError(err, "redirect url parse failed")
+	ts.Equal("/connect/qrconnect", u.Path)
+	ts.Equal("wechat_redirect", u.Fragment)
+	q := u.Query()
+	ts.Equal(ts.Config.External.WeChat.RedirectURI, q.Get("redirect_uri"))
+	ts.Equal(ts.Config.External.WeChat.ClientID, []string{q.Get("appid")})
+	ts.Empty(q.Get("client_id"))
+	ts.Equal("code", q.Get("response_type"))
+	ts.Equal("snsapi_login", q.Get("scope"))
+
+	assertValidOAuthState(ts, q.Get("state"), "wechat")
+}
+
+func (ts *ExternalTestSuite) TestSignupExternalWeChatOfficialAccount() {
+	req := httptest.NewRequest(http.MethodGet, "http://localhost/authorize?provider=wechat_official_account", nil)
+	w := httptest.NewRecorder()
+	ts.API.handler.ServeHTTP(w, req)
+	ts.Require().Equal(http.StatusFound, w.Code)
+	u, err := url.Parse(w.Header().Get("Location"))
+	ts.Require().NoError(err, "redirect url parse failed")
+	ts.Equal("/connect/oauth2/authorize", u.Path)
+	ts.Equal("wechat_redirect", u.Fragment)
+	q := u.Query()
+	ts.Equal(ts.Config.External.WeChatOfficialAccount.ClientID, []string{q.Get("appid")})
+	ts.Equal("snsapi_userinfo", q.Get("scope"))
+
+	assertValidOAuthState(ts, q.Get("state"), "wechat_official_account")
+}
+
+func WeChatTestSignupSetup(ts *ExternalTestSuite, tokenCount *int, userCount *int, code string, user string) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/sns/oauth2/access_token":
+			*tokenCount++
+			ts.Equal(code, r.URL.Query().Get("code"))
+			ts.Equal("authorization_code", r.URL.Query().Get("grant_type"))
+			ts.Equal(ts.Config.External.WeChat.ClientID[0], r.URL.Query().Get("appid"))
+			ts.Equal(ts.Config.External.WeChat.Secret, r.URL.Query().Get("secret"))
+
+			w.Header().Add("Content-Type", "application/json")
+			fmt.Fprint(w, `{"access_token":"wechat_token","expires_in":7200,"refresh_token":"wechat_refresh_token","openid":"wechatOpenId","scope":"snsapi_login"}`)
+		case "/sns/userinfo":
+			*userCount++
+			ts.Equal("wechat_token", r.URL.Query().Get("access_token"))
+			ts.Equal("wechatOpenId", r.URL.Query().Get("openid"))
+
+			w.Header().Add("Content-Type", "application/json")
+			fmt.Fprint(w, user)
+		default:
+			w.WriteHeader(500)
+			ts.Fail("unknown wechat oauth call %s", r.URL.Path)
+		}
+	}))
+
+	ts.Config.External.WeChat.URL = server.URL
+
+	return server
+}
+
+func (ts *ExternalTestSuite) TestSignupExternalWeChat_AuthorizationCode() {
+	ts.Config.DisableSignup = false
+	ts.Config.External.WeChat.EmailOptional = true
+	tokenCount, userCount := 0, 0
+	code := "authcode"
+	server := WeChatTestSignupSetup(ts, &tokenCount, &userCount, code, wechatUser)
+	defer server.Close()
+
+	u := performAuthorization(ts, "wechat", code, "")
+
+	assertAuthorizationSuccess(ts, u, tokenCount, userCount, "", "WeChat Test", "wechatUnionId", "https://thirdwx.qlogo.cn/mmopen/test/132")
+}
+
+func (ts *ExternalTestSuite) TestSignupExternalWeChat_AuthorizationCode_NoUnionID() {
+	ts.Config.DisableSignup = false
+	ts.Config.External.WeChat.EmailOptional = true
+	tokenCount, userCount := 0, 0
+	code := "authcode"
+	server := WeChatTestSignupSetup(ts, &tokenCount, &userCount, code, wechatUserNoUnionID)
+	defer server.Close()
+
+	u := performAuthorization(ts, "wechat", code, "")
+
+	assertAuthorizationSuccess(ts, u, tokenCount, userCount, "", "WeChat Test", "wechatOpenId", "https://thirdwx.qlogo.cn/mmopen/test/132")
+}
+
+func (ts *ExternalTestSuite) TestSignupExternalWeChat_AuthorizationCode_NoEmailWithoutEmailOptional() {
+	ts.Config.DisableSignup = false
+	ts.Config.External.WeChat.EmailOptional = false
+	tokenCount, userCount := 0, 0
+	code := "authcode"
+	server := WeChatTestSignupSetup(ts, &tokenCount, &userCount, code, wechatUser)
+	defer server.Close()
+
+	u := performAuthorization(ts, "wechat", code, "")
+
+	assertAuthorizationFailure(ts, u, "Error getting user email from external provider", "server_error", "")
+}
+
+func (ts *ExternalTestSuite) TestSignupExternalWeChat_AuthorizationCode_Error() {
+	ts.Config.DisableSignup = false
+	ts.Config.External.WeChat.EmailOptional = true
+	tokenCount, userCount := 0, 0
+	code := "authcode"
+	server := WeChatTestSignupSetup(ts, &tokenCount, &userCount, code, `{"errcode":40003,"errmsg":"invalid openid"}`)
+	defer server.Close()
+
+	u := performAuthorization(ts, "wechat", code, "")
+
+	assertAuthorizationFailure(ts, u, "Error getting user profile from external provider", "server_error", "")
+}
@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"strings"
 )
 
 const (
@@ -95,6 +96,70 @@ func (ts *ExternalTestSuite) TestSignupExternalX_AuthorizationCode_NoEmailWithou
 	assertAuthorizationFailure(ts, u, "Error getting user email from external provider", "server_error", "")
 }
 
+func (ts *ExternalTestSuite) TestSignupExternalX_AuthorizationCode_EmailScopeNotGranted() {
+	// The confirmed email isn't requested when users.email wasn't granted
+	ts.Config.DisableSignup = false
+	ts.Config.External.X.EmailOptional = true
+	tokenCount, userCount := 0, 0
+	code := "authcode"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/2/oauth2/token":
+			tokenCount++
+			w.Header().Add("Content-Type", "application/json")
+			fmt.Fprint(w, `{"access_token":"x_token","expires_in":100000,"scope":"tweet.read users.read offline.access"}`)
+		case "/2/users/me":
+			userCount++
+			ts.NotContains(r.URL.Query().Get("user.fields"), "confirmed_email")
+			w.Header().Add("Content-Type", "application/json")
+			fmt.Fprint(w, xUserNoEmail)
+		default:
+			w.WriteHeader(500)
+			ts.Fail("unknown X oauth call %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+	ts.Config.External.X.URL = server.URL
+
+	u := performAuthorization(ts, "x", code, "")
+
+	assertAuthorizationSuccess(ts, u, tokenCount, userCount, "", "X Test", "xTestId", "https://pbs.twimg.com/profile_images/test.jpg")
+}
+
+func (ts *ExternalTestSuite) TestSignupExternalX_AuthorizationCode_EmailForbidden() {
+	// Without access to the confirmed email the user is fetched without it
+	ts.Config.DisableSignup = false
+	ts.Config.External.X.EmailOptional = true
+	tokenCount, userCount := 0, 0
+	code := "authcode"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/2/oauth2/token":
+			tokenCount++
+			w.Header().Add("Content-Type", "application/json")
+			fmt.Fprint(w, `{"access_token":"x_token","expires_in":100000}`)
+		case "/2/users/me":
+			w.Header().Add("Content-Type", "application/json")
+			if strings.Contains(r.URL.Query().Get("user.fields"), "confirmed_email") {
+				w.WriteHeader(http.StatusForbidden)
+				fmt.Fprint(w, `{"title":"Forbidden","status":403}`)
+				return
+			}
+			userCount++
+			fmt.Fprint(w, xUserNoEmail)
+		default:
+			w.WriteHeader(500)
+			ts.Fail("unknown X oauth call %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+	ts.Config.External.X.URL = server.URL
+
+	u := performAuthorization(ts, "x", code, "")
+
+	assertAuthorizationSuccess(ts, u, tokenCount, userCount, "", "X Test", "xTestId", "https://pbs.twimg.com/profile_images/test.jpg")
+}
+
 func (ts *ExternalTestSuite) TestSignupExternalXDisableSignupErrorWhenNoUser() {
 	ts.Config.DisableSignup = true
 
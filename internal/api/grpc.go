@@ -0,0 +1,244 @@
+package api
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sync/atomic"
+
+	"github.com/sirupsen/logrus"
+	"github.com/supabase/auth/client/admin/adminv1"
+	"github.com/supabase/auth/internal/api/apierrors"
+	"github.com/supabase/auth/internal/conf"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// grpcErrorDomain is the domain of the ErrorInfo details of gRPC errors.
+const grpcErrorDomain = "auth"
+
+// grpcHeaders are the metadata keys of gRPC calls passed on as the headers
+// of the request the shared admin handler logic is run with.
+var grpcHeaders = []string{"authorization", audHeaderName, "user-agent"}
+
+// AdminService serves the gRPC admin API. It runs the same handler logic as
+// the /admin endpoints of the REST API with the current API.
+type AdminService struct {
+	adminv1.UnimplementedAdminServiceServer
+
+	api atomic.Pointer[API]
+}
+
+// NewAdminService returns the gRPC admin service of the API.
+func NewAdminService(a *API) *AdminService {
+	s := &AdminService{}
+	s.api.Store(a)
+	return s
+}
+
+// Reload serves the following calls with the API, which has reloaded its
+// configuration.
+func (s *AdminService) Reload(a *API) {
+	s.api.Store(a)
+}
+
+// NewGRPCServer returns a gRPC server of the admin service. It serves TLS
+// when a certificate is configured and requires client certificates signed
+// by the client CA when one is configured.
+func NewGRPCServer(config *conf.GRPCConfiguration, svc *AdminService) (*grpc.Server, error) {
+	opts := []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(grpcErrorInterceptor, svc.enforceIPAccess),
+	}
+
+	if config.TLSCertFile != "" {
+		cert, err := tls.LoadX509KeyPair(config.TLSCertFile, config.TLSKeyFile)
+		if err != nil {
+			return nil, err
+		}
+
+		tlsConfig := &tls.Config{
+			Certificates: []tls.Certificate{cert},
+			MinVersion:   tls.VersionTLS12,
+		}
+
+		if config.TLSClientCAFile != "" {
+			pem, err := os.ReadFile(config.TLSClientCAFile)
+			if err != nil {
+				return nil, err
+			}
+
+			pool := x509.NewCertPool()
+			if !pool.AppendCertsFromPEM(pem) {
+				return nil, errors.New("no certificates found in the gRPC client CA file")
+			}
+
+			tlsConfig.ClientCAs = pool
+			tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+		}
+
+		opts = append(opts, grpc.Creds(credentials.NewTLS(tlsConfig)))
+	}
+
+	srv := grpc.NewServer(opts...)
+	adminv1.RegisterAdminServiceServer(srv, svc)
+
+	return srv, nil
+}
+
+// request authenticates the call with the admin credentials in its metadata
+// and returns the API and the request to run the shared handler logic with.
+// The query holds the query parameters of the matching REST endpoint.
+func (s *AdminService) request(ctx context.Context, query url.Values) (*API, *http.Request, error) {
+	a := s.api.Load()
+
+	u := &url.URL{Path: "/admin", RawQuery: query.Encode()}
+	r, err := http.NewRequestWithContext(ctx, http.MethodPost, u.String(), nil)
+	if err != nil {
+		return nil, nil, apierrors.NewInternalServerError("Error creating request").WithInternalError(err)
+	}
+
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		for _, key := range grpcHeaders {
+			if values := md.Get(key); len(values) > 0 {
+				r.Header.Set(key, values[0])
+			}
+		}
+	}
+
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		r.RemoteAddr = p.Addr.String()
+	}
+
+	ctx, err = a.requireAdminCredentials(nil, r)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return a, r.WithContext(ctx), nil
+}
+
+// enforceIPAccess rejects calls from IP addresses the access rules don't
+// allow, as the enforceIPAccess middleware does for HTTP requests. Every call
+// is an admin call, so the admin rules apply in addition to the rules of all
+// endpoints. The address is the peer address of the connection, as gRPC
+// calls are not forwarded by the trusted proxies of the HTTP API.
+func (s *AdminService) enforceIPAccess(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	a := s.api.Load()
+	config := &a.config.IPAccess
+
+	if !config.Enabled() {
+		return handler(ctx, req)
+	}
+
+	r, err := http.NewRequestWithContext(ctx, http.MethodPost, info.FullMethod, nil)
+	if err != nil {
+		return nil, apierrors.NewInternalServerError("Error creating request").WithInternalError(err)
+	}
+
+	ip := ""
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		r.RemoteAddr = p.Addr.String()
+		if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+			ip = host
+		}
+	}
+
+	scope := ""
+	if !isIPAllowed(ip, config.Allow, config.Deny) {
+		scope = "all"
+	} else if !isIPAllowed(ip, config.AdminAllow, config.AdminDeny) {
+		scope = "admin"
+	}
+	if scope != "" {
+		return nil, a.denyIPAccess(r, ip, scope)
+	}
+
+	return handler(ctx, req)
+}
+
+// grpcErrorInterceptor converts the errors of the shared handler logic to
+// gRPC status errors.
+func grpcErrorInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	resp, err := handler(ctx, req)
+	if err == nil {
+		return resp, nil
+	}
+
+	st := grpcError(err)
+	if status.Code(st) == codes.Internal {
+		logrus.WithField("component", "grpc").WithField("method", info.FullMethod).WithError(err).Error("gRPC call failed")
+	}
+	return resp, st
+}
+
+// grpcError converts an API error to a gRPC status error with the error code
+// in its ErrorInfo details.
+func grpcError(err error) error {
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+
+	var weakPasswordErr *WeakPasswordError
+	if errors.As(err, &weakPasswordErr) {
+		return grpcStatus(codes.InvalidArgument, apierrors.ErrorCodeWeakPassword, weakPasswordErr.Message)
+	}
+
+	var httpErr *apierrors.HTTPError
+	if !errors.As(err, &httpErr) {
+		return grpcStatus(codes.Internal, apierrors.ErrorCodeUnexpectedFailure, "Unexpected failure")
+	}
+
+	var code codes.Code
+	switch httpErr.HTTPStatus {
+	case http.StatusBadRequest:
+		code = codes.InvalidArgument
+	case http.StatusUnauthorized:
+		code = codes.Unauthenticated
+	case http.StatusForbidden:
+		code = codes.PermissionDenied
+	case http.StatusNotFound:
+		code = codes.NotFound
+	case http.StatusUnprocessableEntity:
+		switch httpErr.ErrorCode {
+		case apierrors.ErrorCodeEmailExists, apierrors.ErrorCodePhoneExists, apierrors.ErrorCodeUserAlreadyExists:
+			code = codes.AlreadyExists
+		default:
+			code = codes.InvalidArgument
+		}
+	case http.StatusTooManyRequests:
+		code = codes.ResourceExhausted
+	case http.StatusInternalServerError:
+		code = codes.Internal
+	default:
+		code = codes.Unknown
+	}
+
+	errorCode := httpErr.ErrorCode
+	if errorCode == "" {
+		if code == codes.Internal {
+			errorCode = apierrors.ErrorCodeUnexpectedFailure
+		} else {
+			errorCode = apierrors.ErrorCodeUnknown
+		}
+	}
+
+	return grpcStatus(code, errorCode, httpErr.Message)
+}
+
+func grpcStatus(code codes.Code, errorCode, msg string) error {
+	st := status.New(code, msg)
+	if detailed, err := st.WithDetails(&errdetails.ErrorInfo{Reason: errorCode, Domain: grpcErrorDomain}); err == nil {
+		st = detailed
+	}
+	return st.Err()
+}
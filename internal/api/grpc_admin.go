@@ -0,0 +1,365 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/supabase/auth/client/admin/adminv1"
+	"github.com/supabase/auth/internal/api/apierrors"
+	"github.com/supabase/auth/internal/models"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// ListUsers lists the users of the audience like GET /admin/users.
+func (s *AdminService) ListUsers(ctx context.Context, req *adminv1.ListUsersRequest) (*adminv1.ListUsersResponse, error) {
+	query := url.Values{}
+	if req.Page != 0 {
+		query.Set("page", strconv.FormatUint(req.Page, 10))
+	}
+	if req.PerPage != 0 {
+		query.Set("per_page", strconv.FormatUint(req.PerPage, 10))
+	}
+	if req.Sort != "" {
+		query.Set("sort", req.Sort)
+	}
+	if req.Filter != "" {
+		query.Set("filter", req.Filter)
+	}
+
+	a, r, err := s.request(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	page, err := a.findAdminUsers(r)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &adminv1.ListUsersResponse{
+		Aud:   page.Aud,
+		Total: page.Pagination.Count,
+	}
+	for _, user := range page.Users {
+		pbUser, err := toProtoUser(user)
+		if err != nil {
+			return nil, err
+		}
+		resp.Users = append(resp.Users, pbUser)
+	}
+
+	return resp, nil
+}
+
+// GetUser returns a user like GET /admin/users/{user_id}.
+func (s *AdminService) GetUser(ctx context.Context, req *adminv1.GetUserRequest) (*adminv1.User, error) {
+	a, r, err := s.request(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := a.findAdminUser(r, req.UserId)
+	if err != nil {
+		return nil, err
+	}
+
+	return toProtoUser(user)
+}
+
+// CreateUser creates a user like POST /admin/users.
+func (s *AdminService) CreateUser(ctx context.Context, req *adminv1.CreateUserRequest) (*adminv1.User, error) {
+	a, r, err := s.request(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	params := &AdminUserParams{
+		Id:           req.Id,
+		Aud:          req.Aud,
+		Role:         req.Role,
+		Email:        req.Email,
+		Phone:        req.Phone,
+		Password:     req.Password,
+		PasswordHash: req.PasswordHash,
+		EmailConfirm: req.EmailConfirm,
+		PhoneConfirm: req.PhoneConfirm,
+		BanDuration:  req.BanDuration,
+	}
+	if req.UserMetadata != nil {
+		params.UserMetaData = req.UserMetadata.AsMap()
+	}
+	if req.AppMetadata != nil {
+		params.AppMetaData = req.AppMetadata.AsMap()
+	}
+
+	user, err := a.createAdminUser(r, params)
+	if err != nil {
+		return nil, err
+	}
+
+	return toProtoUser(user)
+}
+
+// UpdateUser updates a user like PUT /admin/users/{user_id}.
+func (s *AdminService) UpdateUser(ctx context.Context, req *adminv1.UpdateUserRequest) (*adminv1.User, error) {
+	a, r, err := s.request(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := a.findAdminUser(r, req.UserId)
+	if err != nil {
+		return nil, err
+	}
+
+	params := &AdminUserParams{
+		Role:         req.Role,
+		Email:        req.Email,
+		Phone:        req.Phone,
+		Password:     req.Password,
+		EmailConfirm: req.EmailConfirm,
+		PhoneConfirm: req.PhoneConfirm,
+		BanDuration:  req.BanDuration,
+	}
+	if req.UserMetadata != nil {
+		params.UserMetaData = req.UserMetadata.AsMap()
+	}
+	if req.AppMetadata != nil {
+		params.AppMetaData = req.AppMetadata.AsMap()
+	}
+
+	if err := a.updateAdminUser(r, user, params); err != nil {
+		return nil, err
+	}
+
+	return toProtoUser(user)
+}
+
+// DeleteUser deletes a user like DELETE /admin/users/{user_id}.
+func (s *AdminService) DeleteUser(ctx context.Context, req *adminv1.DeleteUserRequest) (*adminv1.DeleteUserResponse, error) {
+	a, r, err := s.request(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := a.findAdminUser(r, req.UserId)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := a.deleteAdminUser(r, user, req.ShouldSoftDelete, true); err != nil {
+		return nil, err
+	}
+
+	return &adminv1.DeleteUserResponse{}, nil
+}
+
+// ListUserSessions lists the active sessions of a user like GET
+// /admin/users/{user_id}/sessions.
+func (s *AdminService) ListUserSessions(ctx context.Context, req *adminv1.ListUserSessionsRequest) (*adminv1.ListUserSessionsResponse, error) {
+	a, r, err := s.request(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := a.findAdminUser(r, req.UserId)
+	if err != nil {
+		return nil, err
+	}
+
+	sessions, err := a.findActiveSessions(a.db.WithContext(r.Context()), user, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &adminv1.ListUserSessionsResponse{}
+	for _, session := range sessions {
+		resp.Sessions = append(resp.Sessions, toProtoSession(session))
+	}
+
+	return resp, nil
+}
+
+// RevokeUserSessions signs out one or all of the sessions of a user like
+// DELETE /admin/users/{user_id}/sessions.
+func (s *AdminService) RevokeUserSessions(ctx context.Context, req *adminv1.RevokeUserSessionsRequest) (*adminv1.RevokeUserSessionsResponse, error) {
+	a, r, err := s.request(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := a.findAdminUser(r, req.UserId)
+	if err != nil {
+		return nil, err
+	}
+
+	var sessionID *uuid.UUID
+	if req.SessionId != "" {
+		id, err := uuid.FromString(req.SessionId)
+		if err != nil {
+			return nil, apierrors.NewNotFoundError(apierrors.ErrorCodeValidationFailed, "session_id must be an UUID")
+		}
+		sessionID = &id
+	}
+
+	if err := a.revokeAdminUserSessions(r, user, sessionID); err != nil {
+		return nil, err
+	}
+
+	return &adminv1.RevokeUserSessionsResponse{}, nil
+}
+
+// ListAuditLogEntries searches the audit log like GET /admin/audit with
+// keyset pagination.
+func (s *AdminService) ListAuditLogEntries(ctx context.Context, req *adminv1.ListAuditLogEntriesRequest) (*adminv1.ListAuditLogEntriesResponse, error) {
+	query := url.Values{}
+	query.Set("after", req.After)
+	if req.PerPage != 0 {
+		query.Set("per_page", strconv.FormatUint(req.PerPage, 10))
+	}
+	for name, value := range map[string]string{
+		"query":      req.Query,
+		"actor_id":   req.ActorId,
+		"action":     req.Action,
+		"user_id":    req.UserId,
+		"ip_address": req.IpAddress,
+	} {
+		if value != "" {
+			query.Set(name, value)
+		}
+	}
+	if req.From != nil {
+		query.Set("from", req.From.AsTime().Format(time.RFC3339Nano))
+	}
+	if req.To != nil {
+		query.Set("to", req.To.AsTime().Format(time.RFC3339Nano))
+	}
+
+	a, r, err := s.request(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	page, err := a.findAuditLog(r)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &adminv1.ListAuditLogEntriesResponse{}
+	for _, entry := range page.Entries {
+		payload, err := toProtoStruct(entry.Payload)
+		if err != nil {
+			return nil, err
+		}
+		resp.Entries = append(resp.Entries, &adminv1.AuditLogEntry{
+			Id:        entry.ID.String(),
+			Payload:   payload,
+			IpAddress: entry.IPAddress,
+			CreatedAt: timestamppb.New(entry.CreatedAt),
+		})
+	}
+	if page.Next != nil {
+		resp.NextAfter = page.Next.String()
+	}
+
+	return resp, nil
+}
+
+func toProtoUser(user *models.User) (*adminv1.User, error) {
+	appMetadata, err := toProtoStruct(user.AppMetaData)
+	if err != nil {
+		return nil, err
+	}
+
+	userMetadata, err := toProtoStruct(user.UserMetaData)
+	if err != nil {
+		return nil, err
+	}
+
+	pbUser := &adminv1.User{
+		Id:               user.ID.String(),
+		Aud:              user.Aud,
+		Role:             user.Role,
+		Email:            user.GetEmail(),
+		Phone:            user.GetPhone(),
+		EmailConfirmedAt: toProtoTimestamp(user.EmailConfirmedAt),
+		PhoneConfirmedAt: toProtoTimestamp(user.PhoneConfirmedAt),
+		LastSignInAt:     toProtoTimestamp(user.LastSignInAt),
+		AppMetadata:      appMetadata,
+		UserMetadata:     userMetadata,
+		IsAnonymous:      user.IsAnonymous,
+		BannedUntil:      toProtoTimestamp(user.BannedUntil),
+		CreatedAt:        timestamppb.New(user.CreatedAt),
+		UpdatedAt:        timestamppb.New(user.UpdatedAt),
+		DeletedAt:        toProtoTimestamp(user.DeletedAt),
+	}
+
+	for i := range user.Identities {
+		identity := &user.Identities[i]
+
+		identityData, err := toProtoStruct(identity.IdentityData)
+		if err != nil {
+			return nil, err
+		}
+
+		pbUser.Identities = append(pbUser.Identities, &adminv1.Identity{
+			IdentityId:   identity.ID.String(),
+			Id:           identity.ProviderID,
+			UserId:       identity.UserID.String(),
+			Provider:     identity.Provider,
+			Email:        identity.GetEmail(),
+			IdentityData: identityData,
+			LastSignInAt: toProtoTimestamp(identity.LastSignInAt),
+			CreatedAt:    timestamppb.New(identity.CreatedAt),
+			UpdatedAt:    timestamppb.New(identity.UpdatedAt),
+		})
+	}
+
+	return pbUser, nil
+}
+
+func toProtoSession(session *SessionResponse) *adminv1.Session {
+	return &adminv1.Session{
+		Id:          session.ID.String(),
+		CreatedAt:   timestamppb.New(session.CreatedAt),
+		UpdatedAt:   timestamppb.New(session.UpdatedAt),
+		RefreshedAt: timestamppb.New(session.RefreshedAt),
+		NotAfter:    toProtoTimestamp(session.NotAfter),
+		UserAgent:   session.UserAgent,
+		Ip:          session.IP,
+		Aal:         session.AAL,
+	}
+}
+
+// toProtoStruct converts JSON metadata to a Struct as it is encoded in the
+// responses of the REST API.
+func toProtoStruct(m map[string]interface{}) (*structpb.Struct, error) {
+	if m == nil {
+		return nil, nil
+	}
+
+	data, err := json.Marshal(m)
+	if err != nil {
+		return nil, apierrors.NewInternalServerError("Error encoding metadata").WithInternalError(err)
+	}
+
+	s := &structpb.Struct{}
+	if err := protojson.Unmarshal(data, s); err != nil {
+		return nil, apierrors.NewInternalServerError("Error encoding metadata").WithInternalError(err)
+	}
+
+	return s, nil
+}
+
+func toProtoTimestamp(t *time.Time) *timestamppb.Timestamp {
+	if t == nil {
+		return nil
+	}
+	return timestamppb.New(*t)
+}
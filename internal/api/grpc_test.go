@@ -0,0 +1,301 @@
+package api
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"testing"
+
+	jwt "github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"github.com/supabase/auth/client/admin/adminv1"
+	"github.com/supabase/auth/internal/api/apierrors"
+	"github.com/supabase/auth/internal/conf"
+	"github.com/supabase/auth/internal/models"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+type GRPCTestSuite struct {
+	suite.Suite
+	API    *API
+	Config *conf.GlobalConfiguration
+
+	client adminv1.AdminServiceClient
+	token  string
+}
+
+func TestGRPC(t *testing.T) {
+	api, config, err := setupAPIForTest()
+	require.NoError(t, err)
+
+	srv, err := NewGRPCServer(&config.GRPC, NewAdminService(api))
+	require.NoError(t, err)
+
+	lis := bufconn.Listen(1024 * 1024)
+	go srv.Serve(lis) // nolint:errcheck
+	defer srv.Stop()
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	ts := &GRPCTestSuite{
+		API:    api,
+		Config: config,
+		client: adminv1.NewAdminServiceClient(conn),
+	}
+	defer api.db.Close()
+
+	suite.Run(t, ts)
+}
+
+func (ts *GRPCTestSuite) SetupTest() {
+	models.TruncateAll(ts.API.db)
+	ts.Config.External.Email.Enabled = true
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, &AccessTokenClaims{
+		Role: "supabase_admin",
+	}).SignedString([]byte(ts.Config.JWT.Secret))
+	require.NoError(ts.T(), err, "Error generating admin jwt")
+	ts.token = token
+}
+
+func (ts *GRPCTestSuite) adminContext() context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+ts.token)
+}
+
+func (ts *GRPCTestSuite) TestUnauthenticated() {
+	_, err := ts.client.ListUsers(context.Background(), &adminv1.ListUsersRequest{})
+	require.Equal(ts.T(), codes.Unauthenticated, status.Code(err))
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, &AccessTokenClaims{
+		Role: "authenticated",
+	}).SignedString([]byte(ts.Config.JWT.Secret))
+	require.NoError(ts.T(), err)
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+token)
+	_, err = ts.client.ListUsers(ctx, &adminv1.ListUsersRequest{})
+	require.Equal(ts.T(), codes.PermissionDenied, status.Code(err))
+}
+
+func (ts *GRPCTestSuite) TestUsers() {
+	ctx := ts.adminContext()
+
+	userMetadata, err := structpb.NewStruct(map[string]interface{}{"name": "Test"})
+	require.NoError(ts.T(), err)
+
+	created, err := ts.client.CreateUser(ctx, &adminv1.CreateUserRequest{
+		Email:        "test@example.com",
+		EmailConfirm: true,
+		UserMetadata: userMetadata,
+	})
+	require.NoError(ts.T(), err)
+	require.Equal(ts.T(), "test@example.com", created.Email)
+	require.Equal(ts.T(), "Test", created.UserMetadata.AsMap()["name"])
+	require.NotNil(ts.T(), created.EmailConfirmedAt)
+
+	_, err = ts.client.CreateUser(ctx, &adminv1.CreateUserRequest{Email: "test@example.com"})
+	require.Equal(ts.T(), codes.AlreadyExists, status.Code(err))
+
+	got, err := ts.client.GetUser(ctx, &adminv1.GetUserRequest{UserId: created.Id})
+	require.NoError(ts.T(), err)
+	require.Equal(ts.T(), created.Id, got.Id)
+
+	list, err := ts.client.ListUsers(ctx, &adminv1.ListUsersRequest{Filter: "test@"})
+	require.NoError(ts.T(), err)
+	require.Len(ts.T(), list.Users, 1)
+	require.Equal(ts.T(), uint64(1), list.Total)
+	require.Equal(ts.T(), ts.Config.JWT.Aud, list.Aud)
+
+	updated, err := ts.client.UpdateUser(ctx, &adminv1.UpdateUserRequest{
+		UserId:      created.Id,
+		Role:        "test-role",
+		BanDuration: "24h",
+	})
+	require.NoError(ts.T(), err)
+	require.Equal(ts.T(), "test-role", updated.Role)
+	require.NotNil(ts.T(), updated.BannedUntil)
+	require.Equal(ts.T(), "Test", updated.UserMetadata.AsMap()["name"])
+
+	_, err = ts.client.UpdateUser(ctx, &adminv1.UpdateUserRequest{
+		UserId:      created.Id,
+		BanDuration: "forever",
+	})
+	require.Equal(ts.T(), codes.InvalidArgument, status.Code(err))
+
+	_, err = ts.client.DeleteUser(ctx, &adminv1.DeleteUserRequest{UserId: created.Id})
+	require.NoError(ts.T(), err)
+
+	_, err = ts.client.GetUser(ctx, &adminv1.GetUserRequest{UserId: created.Id})
+	require.Equal(ts.T(), codes.NotFound, status.Code(err))
+}
+
+func (ts *GRPCTestSuite) TestIPAccess() {
+	require.NoError(ts.T(), ts.Config.IPAccess.AdminAllow.Decode("10.0.0.0/8"))
+	defer func() {
+		ts.Config.IPAccess = conf.IPAccessConfiguration{}
+	}()
+
+	// the in-memory connection of the test is not from an allowed network
+	_, err := ts.client.ListUsers(ts.adminContext(), &adminv1.ListUsersRequest{})
+	st := status.Convert(err)
+	require.Equal(ts.T(), codes.PermissionDenied, st.Code())
+	require.Len(ts.T(), st.Details(), 1)
+	info, ok := st.Details()[0].(*errdetails.ErrorInfo)
+	require.True(ts.T(), ok)
+	require.Equal(ts.T(), apierrors.ErrorCodeIPAddressNotAllowed, info.Reason)
+
+	ts.Config.IPAccess.AdminAllow = nil
+	require.NoError(ts.T(), ts.Config.IPAccess.AdminDeny.Decode("10.0.0.0/8"))
+	_, err = ts.client.ListUsers(ts.adminContext(), &adminv1.ListUsersRequest{})
+	require.NoError(ts.T(), err)
+}
+
+func (ts *GRPCTestSuite) TestUserSessions() {
+	ctx := ts.adminContext()
+
+	u, err := models.NewUser("", "test@example.com", "test", ts.Config.JWT.Aud, nil)
+	require.NoError(ts.T(), err)
+	require.NoError(ts.T(), ts.API.db.Create(u))
+
+	var sessions []*models.Session
+	for i := 0; i < 2; i++ {
+		s, err := models.NewSession(u.ID, nil)
+		require.NoError(ts.T(), err)
+		require.NoError(ts.T(), ts.API.db.Create(s))
+		sessions = append(sessions, s)
+	}
+
+	list, err := ts.client.ListUserSessions(ctx, &adminv1.ListUserSessionsRequest{UserId: u.ID.String()})
+	require.NoError(ts.T(), err)
+	require.Len(ts.T(), list.Sessions, 2)
+
+	_, err = ts.client.RevokeUserSessions(ctx, &adminv1.RevokeUserSessionsRequest{
+		UserId:    u.ID.String(),
+		SessionId: sessions[0].ID.String(),
+	})
+	require.NoError(ts.T(), err)
+
+	list, err = ts.client.ListUserSessions(ctx, &adminv1.ListUserSessionsRequest{UserId: u.ID.String()})
+	require.NoError(ts.T(), err)
+	require.Len(ts.T(), list.Sessions, 1)
+	require.Equal(ts.T(), sessions[1].ID.String(), list.Sessions[0].Id)
+
+	_, err = ts.client.RevokeUserSessions(ctx, &adminv1.RevokeUserSessionsRequest{UserId: u.ID.String()})
+	require.NoError(ts.T(), err)
+
+	list, err = ts.client.ListUserSessions(ctx, &adminv1.ListUserSessionsRequest{UserId: u.ID.String()})
+	require.NoError(ts.T(), err)
+	require.Empty(ts.T(), list.Sessions)
+
+	// the revocations are in the audit log
+	audit, err := ts.client.ListAuditLogEntries(ctx, &adminv1.ListAuditLogEntriesRequest{
+		Action: string(models.SessionRevokedAction),
+		UserId: u.ID.String(),
+	})
+	require.NoError(ts.T(), err)
+	require.Len(ts.T(), audit.Entries, 2)
+	require.Empty(ts.T(), audit.NextAfter)
+
+	audit, err = ts.client.ListAuditLogEntries(ctx, &adminv1.ListAuditLogEntriesRequest{
+		PerPage: 1,
+		Action:  string(models.SessionRevokedAction),
+	})
+	require.NoError(ts.T(), err)
+	require.Len(ts.T(), audit.Entries, 1)
+	require.Equal(ts.T(), audit.Entries[0].Id, audit.NextAfter)
+
+	audit, err = ts.client.ListAuditLogEntries(ctx, &adminv1.ListAuditLogEntriesRequest{
+		PerPage: 1,
+		After:   audit.NextAfter,
+		Action:  string(models.SessionRevokedAction),
+	})
+	require.NoError(ts.T(), err)
+	require.Len(ts.T(), audit.Entries, 1)
+}
+
+func TestGRPCError(t *testing.T) {
+	cases := []struct {
+		err       error
+		code      codes.Code
+		errorCode string
+	}{
+		{
+			err:       apierrors.NewBadRequestError(apierrors.ErrorCodeValidationFailed, "invalid"),
+			code:      codes.InvalidArgument,
+			errorCode: apierrors.ErrorCodeValidationFailed,
+		},
+		{
+			err:       apierrors.NewHTTPError(http.StatusUnauthorized, apierrors.ErrorCodeNoAuthorization, "no token"),
+			code:      codes.Unauthenticated,
+			errorCode: apierrors.ErrorCodeNoAuthorization,
+		},
+		{
+			err:       apierrors.NewForbiddenError(apierrors.ErrorCodeNotAdmin, "not admin"),
+			code:      codes.PermissionDenied,
+			errorCode: apierrors.ErrorCodeNotAdmin,
+		},
+		{
+			err:       apierrors.NewNotFoundError(apierrors.ErrorCodeUserNotFound, "User not found"),
+			code:      codes.NotFound,
+			errorCode: apierrors.ErrorCodeUserNotFound,
+		},
+		{
+			err:       apierrors.NewUnprocessableEntityError(apierrors.ErrorCodeEmailExists, "exists"),
+			code:      codes.AlreadyExists,
+			errorCode: apierrors.ErrorCodeEmailExists,
+		},
+		{
+			err:       apierrors.NewUnprocessableEntityError(apierrors.ErrorCodeValidationFailed, "invalid"),
+			code:      codes.InvalidArgument,
+			errorCode: apierrors.ErrorCodeValidationFailed,
+		},
+		{
+			err:       &WeakPasswordError{Message: "weak"},
+			code:      codes.InvalidArgument,
+			errorCode: apierrors.ErrorCodeWeakPassword,
+		},
+		{
+			err:       apierrors.NewInternalServerError("Database error"),
+			code:      codes.Internal,
+			errorCode: apierrors.ErrorCodeUnexpectedFailure,
+		},
+		{
+			err:       context.Canceled,
+			code:      codes.Internal,
+			errorCode: apierrors.ErrorCodeUnexpectedFailure,
+		},
+	}
+
+	for _, tc := range cases {
+		st := status.Convert(grpcError(tc.err))
+		require.Equal(t, tc.code, st.Code(), tc.err.Error())
+
+		require.Len(t, st.Details(), 1)
+		info, ok := st.Details()[0].(*errdetails.ErrorInfo)
+		require.True(t, ok)
+		require.Equal(t, tc.errorCode, info.Reason)
+		require.Equal(t, grpcErrorDomain, info.Domain)
+	}
+}
+
+func TestNewGRPCServerTLSErrors(t *testing.T) {
+	_, err := NewGRPCServer(&conf.GRPCConfiguration{
+		TLSCertFile: "does-not-exist.crt",
+		TLSKeyFile:  "does-not-exist.key",
+	}, NewAdminService(nil))
+	require.Error(t, err)
+}
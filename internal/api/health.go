@@ -0,0 +1,165 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/supabase/auth/internal/api/sms_provider"
+	"github.com/supabase/auth/internal/conf"
+)
+
+// Statuses of the readiness endpoint and its checks.
+const (
+	readinessStatusOK           = "ok"
+	readinessStatusUnavailable  = "unavailable"
+	readinessStatusShuttingDown = "shutting_down"
+)
+
+// shuttingDown fails the readiness endpoint while the server drains its
+// in-flight requests.
+var shuttingDown atomic.Bool
+
+// SetShuttingDown makes the readiness endpoint respond with 503 without
+// checking the dependencies, so load balancers stop routing requests to the
+// server before it stops accepting them.
+func SetShuttingDown() {
+	shuttingDown.Store(true)
+}
+
+// ReadinessCheck is the result of checking one dependency.
+type ReadinessCheck struct {
+	Status     string `json:"status"`
+	DurationMs int64  `json:"duration_ms"`
+	Error      string `json:"error,omitempty"`
+}
+
+type ReadinessResponse struct {
+	Status string                    `json:"status"`
+	Checks map[string]ReadinessCheck `json:"checks"`
+}
+
+// HealthReady endpoint indicates if the dependencies of the gotrue api
+// service are available, so it can serve requests. It responds with 503 when
+// any of the checks fails, or once the service is shutting down.
+func (a *API) HealthReady(w http.ResponseWriter, r *http.Request) error {
+	config := a.config
+
+	w.Header().Set("Cache-Control", "no-store")
+	if shuttingDown.Load() {
+		return sendJSON(w, http.StatusServiceUnavailable, ReadinessResponse{
+			Status: readinessStatusShuttingDown,
+			Checks: map[string]ReadinessCheck{},
+		})
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), config.Health.Timeout)
+	defer cancel()
+
+	checks := a.readinessChecks(config)
+	resp := ReadinessResponse{
+		Status: readinessStatusOK,
+		Checks: make(map[string]ReadinessCheck, len(checks)),
+	}
+
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+	for name, check := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			start := time.Now()
+			err := check(ctx)
+			result := ReadinessCheck{
+				Status:     readinessStatusOK,
+				DurationMs: time.Since(start).Milliseconds(),
+			}
+			if err != nil {
+				result.Status = readinessStatusUnavailable
+				result.Error = err.Error()
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			resp.Checks[name] = result
+			if err != nil {
+				resp.Status = readinessStatusUnavailable
+			}
+		}()
+	}
+	wg.Wait()
+
+	status := http.StatusOK
+	if resp.Status != readinessStatusOK {
+		status = http.StatusServiceUnavailable
+	}
+	return sendJSON(w, status, resp)
+}
+
+// readinessChecks returns the checks of the dependencies in use. Mail and
+// SMS sent with a hook are not checked.
+func (a *API) readinessChecks(config *conf.GlobalConfiguration) map[string]func(context.Context) error {
+	checks := map[string]func(context.Context) error{
+		"database": func(ctx context.Context) error {
+			return a.db.WithContext(ctx).RawQuery("select 1").Exec()
+		},
+		"signing_keys": func(context.Context) error {
+			k, err := conf.GetSigningJwk(&config.JWT)
+			if err != nil {
+				return err
+			}
+			_, err = conf.GetSigningKey(k)
+			return err
+		},
+	}
+
+	smtpTransport := config.Mailer.Transport == "" || config.Mailer.Transport == conf.MailerTransportSMTP
+	if config.Health.CheckSMTP && smtpTransport && !config.Hook.SendEmail.Enabled && config.SMTP.Host != "" {
+		addr := net.JoinHostPort(config.SMTP.Host, strconv.Itoa(config.SMTP.Port))
+		checks["smtp"] = func(ctx context.Context) error {
+			return dialDependency(ctx, addr)
+		}
+	}
+
+	if config.Health.CheckSMS && config.External.Phone.Enabled && !config.Hook.SendSMS.Enabled {
+		checks["sms"] = func(ctx context.Context) error {
+			return dialSmsProviders(ctx, config)
+		}
+	}
+
+	return checks
+}
+
+// dialSmsProviders succeeds when the SMS provider or one of its fallback
+// providers can be reached.
+func dialSmsProviders(ctx context.Context, config *conf.GlobalConfiguration) error {
+	var errs []error
+	for _, name := range append([]string{config.Sms.Provider}, config.Sms.FallbackProviders...) {
+		addr, err := sms_provider.APIHost(name, config.Sms)
+		if err == nil {
+			err = dialDependency(ctx, addr)
+		}
+		if err == nil {
+			return nil
+		}
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+func dialDependency(ctx context.Context, addr string) error {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
@@ -0,0 +1,123 @@
+package api
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHealthLive(t *testing.T) {
+	api, _, err := setupAPIForTest()
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodGet, "http://localhost/health/live", nil)
+	w := httptest.NewRecorder()
+	api.handler.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var resp HealthCheckResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	require.Equal(t, "GoTrue", resp.Name)
+}
+
+func TestHealthReady(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	reachable := listener.Addr().(*net.TCPAddr).Port
+
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	unreachable := closed.Addr().(*net.TCPAddr).Port
+	require.NoError(t, closed.Close())
+
+	cases := []struct {
+		desc           string
+		smtpPort       int
+		expectedStatus int
+		expectedChecks map[string]string
+	}{
+		{
+			desc:           "Dependencies available",
+			expectedStatus: http.StatusOK,
+			expectedChecks: map[string]string{
+				"database":     readinessStatusOK,
+				"signing_keys": readinessStatusOK,
+			},
+		},
+		{
+			desc:           "SMTP server reachable",
+			smtpPort:       reachable,
+			expectedStatus: http.StatusOK,
+			expectedChecks: map[string]string{
+				"database":     readinessStatusOK,
+				"signing_keys": readinessStatusOK,
+				"smtp":         readinessStatusOK,
+			},
+		},
+		{
+			desc:           "SMTP server unreachable",
+			smtpPort:       unreachable,
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedChecks: map[string]string{
+				"database":     readinessStatusOK,
+				"signing_keys": readinessStatusOK,
+				"smtp":         readinessStatusUnavailable,
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.desc, func(t *testing.T) {
+			api, config, err := setupAPIForTest()
+			require.NoError(t, err)
+			if c.smtpPort != 0 {
+				config.Health.CheckSMTP = true
+				config.Mailer.Transport = ""
+				config.Hook.SendEmail.Enabled = false
+				config.SMTP.Host = "127.0.0.1"
+				config.SMTP.Port = c.smtpPort
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "http://localhost/health/ready", nil)
+			w := httptest.NewRecorder()
+			api.handler.ServeHTTP(w, req)
+			require.Equal(t, c.expectedStatus, w.Code, w.Body.String())
+
+			var resp ReadinessResponse
+			require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+			require.Len(t, resp.Checks, len(c.expectedChecks))
+			for name, status := range c.expectedChecks {
+				require.Equal(t, status, resp.Checks[name].Status, name)
+			}
+		})
+	}
+}
+
+func TestHealthReadyShuttingDown(t *testing.T) {
+	api, _, err := setupAPIForTest()
+	require.NoError(t, err)
+
+	SetShuttingDown()
+	defer shuttingDown.Store(false)
+
+	req := httptest.NewRequest(http.MethodGet, "http://localhost/health/ready", nil)
+	w := httptest.NewRecorder()
+	api.handler.ServeHTTP(w, req)
+	require.Equal(t, http.StatusServiceUnavailable, w.Code)
+
+	var resp ReadinessResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	require.Equal(t, readinessStatusShuttingDown, resp.Status)
+	require.Empty(t, resp.Checks)
+
+	// liveness is not affected while in-flight requests drain
+	req = httptest.NewRequest(http.MethodGet, "http://localhost/health/live", nil)
+	w = httptest.NewRecorder()
+	api.handler.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+}
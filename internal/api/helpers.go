@@ -49,25 +49,46 @@ func (a *API) requestAud(ctx context.Context, r *http.Request) string {
 type RequestParams interface {
 	AdminUserParams |
 		CreateSSOProviderParams |
+		EmailTemplateParams |
+		ProviderDomainRuleParams |
 		EnrollFactorParams |
 		GenerateLinkParams |
 		IdTokenGrantParams |
 		InviteParams |
+		BulkInviteParams |
+		OrganizationParams |
+		OrganizationMemberParams |
+		RoleParams |
+		UserRoleParams |
+		MailJobRedriveParams |
+		WebhookDeliveryRedriveParams |
 		OtpParams |
 		PKCEGrantParams |
 		PasswordGrantParams |
 		RecoverParams |
 		RefreshTokenGrantParams |
 		ResendConfirmationParams |
+		SCIMGroupParams |
+		SCIMPatchParams |
+		SCIMUserParams |
 		SignupParams |
 		SingleSignOnParams |
+		TokenExchangeGrantParams |
+		TenantParams |
+		TrustedDeviceParams |
 		SmsParams |
+		StepUpParams |
 		Web3GrantParams |
+		Web3NonceParams |
+		TelegramGrantParams |
 		UserUpdateParams |
+		UserDeleteParams |
 		VerifyFactorParams |
 		VerifyParams |
 		adminUserUpdateFactorParams |
 		adminUserDeleteParams |
+		AdminUserBanParams |
+		AdminPasswordResetParams |
 		security.GotrueRequest |
 		ChallengeFactorParams |
 
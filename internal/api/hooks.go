@@ -7,9 +7,11 @@ import (
 	"github.com/fatih/structs"
 	"github.com/supabase/auth/internal/api/apierrors"
 	"github.com/supabase/auth/internal/api/provider"
+	"github.com/supabase/auth/internal/conf"
 	"github.com/supabase/auth/internal/hooks/v0hooks"
 	"github.com/supabase/auth/internal/models"
 	"github.com/supabase/auth/internal/storage"
+	"github.com/supabase/auth/internal/webhooks"
 )
 
 func (a *API) triggerAfterUserCreated(
@@ -17,6 +19,12 @@ func (a *API) triggerAfterUserCreated(
 	conn *storage.Connection,
 	user *models.User,
 ) error {
+	// Every way of signing up triggers the hook, so the webhook event is
+	// queued along with it.
+	if err := webhooks.Emit(conn, &a.config.Webhooks, conf.WebhookEventUserCreated, user, nil); err != nil {
+		return apierrors.NewInternalServerError("Error queueing webhook event").WithInternalError(err)
+	}
+
 	if !a.hooksMgr.Enabled(v0hooks.AfterUserCreated) {
 		return nil
 	}
@@ -32,21 +40,65 @@ func (a *API) triggerAfterUserCreated(
 	return a.hooksMgr.InvokeHook(conn, r, req, res)
 }
 
+func (a *API) triggerAfterUserDeleted(
+	r *http.Request,
+	conn *storage.Connection,
+	user *models.User,
+	softDeleted bool,
+) error {
+	if !a.hooksMgr.Enabled(v0hooks.AfterUserDeleted) {
+		return nil
+	}
+	if err := checkTX(conn); err != nil {
+		return err
+	}
+
+	req := v0hooks.NewAfterUserDeletedInput(r, user, softDeleted)
+	res := new(v0hooks.AfterUserDeletedOutput)
+	return a.hooksMgr.InvokeHook(conn, r, req, res)
+}
+
 func (a *API) triggerBeforeUserCreated(
 	r *http.Request,
 	db *storage.Connection,
 	user *models.User,
 ) error {
+	changes, err := a.invokeBeforeUserCreated(r, db, user)
+	if err != nil || changes == nil {
+		return err
+	}
+	changes.Apply(user)
+	return nil
+}
+
+// invokeBeforeUserCreated invokes the before user created hook, returning
+// an error when it rejects the user, and otherwise the changes it makes to
+// the metadata of the user.
+func (a *API) invokeBeforeUserCreated(
+	r *http.Request,
+	db *storage.Connection,
+	user *models.User,
+) (*v0hooks.MetaDataChanges, error) {
 	if !a.hooksMgr.Enabled(v0hooks.BeforeUserCreated) {
-		return nil
+		return nil, nil
 	}
 	if err := checkTX(db); err != nil {
-		return err
+		return nil, err
 	}
 
 	req := v0hooks.NewBeforeUserCreatedInput(r, user)
 	res := new(v0hooks.BeforeUserCreatedOutput)
-	return a.hooksMgr.InvokeHook(db, r, req, res)
+	if err := a.hooksMgr.InvokeHook(db, r, req, res); err != nil {
+		return nil, err
+	}
+
+	if res.Decision == v0hooks.HookRejection {
+		if res.Message == "" {
+			res.Message = v0hooks.DefaultSignupHookRejectionMessage
+		}
+		return nil, apierrors.NewForbiddenError(apierrors.ErrorCodeSignupRejected, "%s", res.Message)
+	}
+	return &res.MetaDataChanges, nil
 }
 
 func (a *API) triggerBeforeUserCreatedExternal(
@@ -113,7 +165,17 @@ func (a *API) triggerBeforeUserCreatedExternal(
 	if err != nil {
 		return err
 	}
-	return a.triggerBeforeUserCreated(r, db, user)
+
+	changes, err := a.invokeBeforeUserCreated(r, db, user)
+	if err != nil || changes == nil {
+		return err
+	}
+
+	// the user is created later from the provided data, which carries the
+	// changes of the hook until then
+	userData.UserMetaData = changes.UserMetaData
+	userData.AppMetaData = changes.AppMetaData
+	return nil
 }
 
 func checkTX(conn *storage.Connection) error {
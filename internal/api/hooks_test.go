@@ -1,6 +1,7 @@
 package api
 
 import (
+	"bytes"
 	"encoding/json"
 	"net/http"
 	"net/url"
@@ -12,6 +13,7 @@ import (
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
+	"github.com/supabase/auth/internal/api/apierrors"
 	"github.com/supabase/auth/internal/conf"
 	"github.com/supabase/auth/internal/hooks/hookserrors"
 	"github.com/supabase/auth/internal/hooks/v0hooks"
@@ -298,6 +300,214 @@ func (ts *HooksTestSuite) TestInvokeHookIntegration() {
 	require.True(ts.T(), gock.IsDone(), "Expected all mocks to have been called including retry")
 }
 
+func (ts *HooksTestSuite) TestBeforeUserCreatedHook() {
+	require.NoError(ts.T(), ts.API.db.RawQuery(`
+		create or replace function before_user_created_test(input jsonb)
+		returns json as $$
+		begin
+			if input->'user'->>'email' like '%@mailinator.com' then
+				return '{"decision": "reject", "message": "Disposable email addresses are not allowed"}'::jsonb;
+			end if;
+			return '{"user_metadata": {"plan": "free"}, "app_metadata": {"provider": "github"}}'::jsonb;
+		end; $$ language plpgsql;`).Exec())
+
+	ts.Config.Hook.BeforeUserCreated = conf.ExtensibilityPointConfiguration{
+		Enabled: true,
+		URI:     "pg-functions://postgres/auth/before_user_created_test",
+	}
+	require.NoError(ts.T(), ts.Config.Hook.BeforeUserCreated.PopulateExtensibilityPoint())
+	defer func() {
+		ts.Config.Hook.BeforeUserCreated = conf.ExtensibilityPointConfiguration{}
+	}()
+
+	signup := func(email string) *httptest.ResponseRecorder {
+		var body bytes.Buffer
+		require.NoError(ts.T(), json.NewEncoder(&body).Encode(map[string]interface{}{
+			"email":    email,
+			"password": "test123",
+			"data":     map[string]interface{}{"name": "Test"},
+		}))
+
+		req := httptest.NewRequest(http.MethodPost, "/signup", &body)
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		ts.API.handler.ServeHTTP(w, req)
+		return w
+	}
+
+	w := signup("test@mailinator.com")
+	require.Equal(ts.T(), http.StatusForbidden, w.Code)
+	data := map[string]interface{}{}
+	require.NoError(ts.T(), json.NewDecoder(w.Body).Decode(&data))
+	require.Equal(ts.T(), apierrors.ErrorCodeSignupRejected, data["error_code"])
+	require.Equal(ts.T(), "Disposable email addresses are not allowed", data["msg"])
+
+	_, err := models.FindUserByEmailAndAudience(ts.API.db, "test@mailinator.com", ts.Config.JWT.Aud)
+	require.True(ts.T(), models.IsNotFoundError(err))
+
+	w = signup("test@example.com")
+	require.Equal(ts.T(), http.StatusOK, w.Code, w.Body.String())
+
+	user, err := models.FindUserByEmailAndAudience(ts.API.db, "test@example.com", ts.Config.JWT.Aud)
+	require.NoError(ts.T(), err)
+	require.Equal(ts.T(), "free", user.UserMetaData["plan"])
+	require.Equal(ts.T(), "Test", user.UserMetaData["name"])
+	// the provider is maintained by Auth
+	require.Equal(ts.T(), "email", user.AppMetaData["provider"])
+
+	identities, err := models.FindIdentitiesByUserID(ts.API.db, user.ID)
+	require.NoError(ts.T(), err)
+	require.Len(ts.T(), identities, 1)
+	require.NotContains(ts.T(), identities[0].IdentityData, "plan")
+}
+
+func (ts *HooksTestSuite) TestBeforeSignInHook() {
+	require.NoError(ts.T(), ts.API.db.RawQuery(`
+		create or replace function before_sign_in_test(input jsonb)
+		returns json as $$
+		begin
+			if input->'user'->'user_metadata'->>'blocked' = 'true' then
+				return '{"decision": "reject"}'::jsonb;
+			end if;
+			return jsonb_build_object('app_metadata', jsonb_build_object('last_sign_in_method', input->>'authentication_method'));
+		end; $$ language plpgsql;`).Exec())
+
+	ts.Config.Hook.BeforeSignIn = conf.ExtensibilityPointConfiguration{
+		Enabled: true,
+		URI:     "pg-functions://postgres/auth/before_sign_in_test",
+	}
+	require.NoError(ts.T(), ts.Config.Hook.BeforeSignIn.PopulateExtensibilityPoint())
+	defer func() {
+		ts.Config.Hook.BeforeSignIn = conf.ExtensibilityPointConfiguration{}
+	}()
+
+	require.NoError(ts.T(), ts.TestUser.Confirm(ts.API.db))
+
+	signIn := func() *httptest.ResponseRecorder {
+		var body bytes.Buffer
+		require.NoError(ts.T(), json.NewEncoder(&body).Encode(map[string]interface{}{
+			"email":    ts.TestUser.GetEmail(),
+			"password": "securetestpassword",
+		}))
+
+		req := httptest.NewRequest(http.MethodPost, "/token?grant_type=password", &body)
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		ts.API.handler.ServeHTTP(w, req)
+		return w
+	}
+
+	w := signIn()
+	require.Equal(ts.T(), http.StatusOK, w.Code, w.Body.String())
+
+	var token AccessTokenResponse
+	require.NoError(ts.T(), json.NewDecoder(w.Body).Decode(&token))
+	require.Equal(ts.T(), "password", token.User.AppMetaData["last_sign_in_method"])
+
+	user, err := models.FindUserByID(ts.API.db, ts.TestUser.ID)
+	require.NoError(ts.T(), err)
+	require.Equal(ts.T(), "password", user.AppMetaData["last_sign_in_method"])
+
+	require.NoError(ts.T(), user.UpdateUserMetaData(ts.API.db, map[string]interface{}{"blocked": true}))
+
+	w = signIn()
+	require.Equal(ts.T(), http.StatusForbidden, w.Code)
+	data := map[string]interface{}{}
+	require.NoError(ts.T(), json.NewDecoder(w.Body).Decode(&data))
+	require.Equal(ts.T(), apierrors.ErrorCodeSignInRejected, data["error_code"])
+	require.Equal(ts.T(), v0hooks.DefaultSignInHookRejectionMessage, data["msg"])
+
+	sessions, err := models.FindAllSessionsForUser(ts.API.db, user.ID, false)
+	require.NoError(ts.T(), err)
+	require.Len(ts.T(), sessions, 1, "the rejected sign in does not create a session")
+}
+
+func (ts *HooksTestSuite) TestAfterUserDeletedHook() {
+	require.NoError(ts.T(), ts.API.db.RawQuery(`
+		create table if not exists auth.after_user_deleted_test_calls (user_id uuid, soft_deleted boolean)`).Exec())
+	defer func() {
+		require.NoError(ts.T(), ts.API.db.RawQuery(`drop table if exists auth.after_user_deleted_test_calls`).Exec())
+	}()
+
+	// the hook fails when the deletion has not been committed yet
+	require.NoError(ts.T(), ts.API.db.RawQuery(`
+		create or replace function after_user_deleted_test(input jsonb)
+		returns json as $$
+		begin
+			if exists (select 1 from auth.users where id = (input->'user'->>'id')::uuid and deleted_at is null) then
+				raise exception 'user has not been deleted';
+			end if;
+			insert into auth.after_user_deleted_test_calls (user_id, soft_deleted)
+				values ((input->'user'->>'id')::uuid, (input->>'soft_deleted')::boolean);
+			return '{}'::jsonb;
+		end; $$ language plpgsql;`).Exec())
+
+	ts.Config.Hook.AfterUserDeleted = conf.ExtensibilityPointConfiguration{
+		Enabled: true,
+		URI:     "pg-functions://postgres/auth/after_user_deleted_test",
+	}
+	require.NoError(ts.T(), ts.Config.Hook.AfterUserDeleted.PopulateExtensibilityPoint())
+	ts.Config.UserDeletion.SelfServiceEnabled = true
+	defer func() {
+		ts.Config.Hook.AfterUserDeleted = conf.ExtensibilityPointConfiguration{}
+		ts.Config.UserDeletion.SelfServiceEnabled = false
+	}()
+
+	require.NoError(ts.T(), ts.TestUser.Confirm(ts.API.db))
+
+	var body bytes.Buffer
+	require.NoError(ts.T(), json.NewEncoder(&body).Encode(map[string]interface{}{
+		"email":    ts.TestUser.GetEmail(),
+		"password": "securetestpassword",
+	}))
+	req := httptest.NewRequest(http.MethodPost, "/token?grant_type=password", &body)
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	ts.API.handler.ServeHTTP(w, req)
+	require.Equal(ts.T(), http.StatusOK, w.Code, w.Body.String())
+
+	var token AccessTokenResponse
+	require.NoError(ts.T(), json.NewDecoder(w.Body).Decode(&token))
+
+	body.Reset()
+	require.NoError(ts.T(), json.NewEncoder(&body).Encode(map[string]interface{}{
+		"password": "securetestpassword",
+	}))
+	req = httptest.NewRequest(http.MethodDelete, "/user", &body)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token.Token)
+	w = httptest.NewRecorder()
+	ts.API.handler.ServeHTTP(w, req)
+	require.Equal(ts.T(), http.StatusOK, w.Code, w.Body.String())
+
+	u, err := models.NewUser("", "soft-deleted@example.com", "securetestpassword", ts.Config.JWT.Aud, nil)
+	require.NoError(ts.T(), err)
+	require.NoError(ts.T(), ts.API.db.Create(u))
+
+	req = httptest.NewRequest(http.MethodDelete, "/admin/users/"+u.ID.String(), nil)
+	require.NoError(ts.T(), ts.API.deleteUser(req, u, u, true, false))
+
+	type call struct {
+		UserID      string `db:"user_id"`
+		SoftDeleted bool   `db:"soft_deleted"`
+	}
+	var calls []call
+	require.NoError(ts.T(), ts.API.db.RawQuery(`select user_id::text, soft_deleted from auth.after_user_deleted_test_calls order by soft_deleted`).All(&calls))
+	require.Equal(ts.T(), []call{
+		{UserID: ts.TestUser.ID.String(), SoftDeleted: false},
+		{UserID: u.ID.String(), SoftDeleted: true},
+	}, calls)
+
+	// users which have already been soft deleted do not trigger the hook
+	u, err = models.FindUserByID(ts.API.db, u.ID)
+	require.NoError(ts.T(), err)
+	require.NoError(ts.T(), ts.API.deleteUser(req, u, u, true, false))
+
+	calls = nil
+	require.NoError(ts.T(), ts.API.db.RawQuery(`select user_id::text, soft_deleted from auth.after_user_deleted_test_calls`).All(&calls))
+	require.Len(ts.T(), calls, 2)
+}
+
 func (ts *HooksTestSuite) TestAccountChangesNotificationsHookPayload() {
 	// Setup hook config for send_email hook
 	defer gock.OffAll()
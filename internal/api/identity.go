@@ -14,6 +14,35 @@ import (
 	"github.com/supabase/auth/internal/storage"
 )
 
+// ListIdentities returns the identities linked to the authenticated user.
+func (a *API) ListIdentities(w http.ResponseWriter, r *http.Request) error {
+	user := getUser(r.Context())
+
+	identities := user.Identities
+	if identities == nil {
+		identities = []models.Identity{}
+	}
+
+	return sendJSON(w, http.StatusOK, map[string]interface{}{
+		"identities": identities,
+	})
+}
+
+// canSignInWithIdentity reports whether the user can still sign in with the
+// identity. Email and phone identities are only usable once the user's email
+// or phone has been confirmed, all other identities are backed by an external
+// provider.
+func canSignInWithIdentity(user *models.User, identity *models.Identity) bool {
+	switch identity.Provider {
+	case "email":
+		return user.IsConfirmed()
+	case "phone":
+		return user.IsPhoneConfirmed()
+	default:
+		return true
+	}
+}
+
 func (a *API) DeleteIdentity(w http.ResponseWriter, r *http.Request) error {
 	ctx := r.Context()
 	db := a.db.WithContext(ctx)
@@ -51,6 +80,18 @@ func (a *API) DeleteIdentity(w http.ResponseWriter, r *http.Request) error {
 		return apierrors.NewUnprocessableEntityError(apierrors.ErrorCodeIdentityNotFound, "Identity doesn't exist")
 	}
 
+	hasOtherLoginMethod := false
+	for i := range user.Identities {
+		identity := &user.Identities[i]
+		if identity.ID != identityToBeDeleted.ID && canSignInWithIdentity(user, identity) {
+			hasOtherLoginMethod = true
+			break
+		}
+	}
+	if !hasOtherLoginMethod {
+		return apierrors.NewUnprocessableEntityError(apierrors.ErrorCodeSingleIdentityNotDeletable, "User must have at least 1 verified identity after unlinking")
+	}
+
 	provider := identityToBeDeleted.Provider
 	err = db.Transaction(func(tx *storage.Connection) error {
 		if terr := models.NewAuditLogEntry(config.AuditLog, r, tx, user, models.IdentityUnlinkAction, "", map[string]interface{}{
@@ -118,6 +118,25 @@ func (ts *IdentityTestSuite) TestUnlinkIdentityError() {
 
 	userWithTwoIdentities, err := models.FindUserByEmailAndAudience(ts.API.db, "two@example.com", ts.Config.JWT.Aud)
 	require.NoError(ts.T(), err)
+
+	// user with an unconfirmed email identity and a google identity
+	userWithUnverifiedIdentity, err := models.NewUser("", "three@example.com", "password", ts.Config.JWT.Aud, nil)
+	require.NoError(ts.T(), err)
+	require.NoError(ts.T(), ts.API.db.Create(userWithUnverifiedIdentity))
+	emailIdentity, err := models.NewIdentity(userWithUnverifiedIdentity, "email", map[string]interface{}{
+		"sub":   userWithUnverifiedIdentity.ID.String(),
+		"email": userWithUnverifiedIdentity.GetEmail(),
+	})
+	require.NoError(ts.T(), err)
+	require.NoError(ts.T(), ts.API.db.Create(emailIdentity))
+	googleIdentity, err := models.NewIdentity(userWithUnverifiedIdentity, "google", map[string]interface{}{
+		"sub":   "google-sub",
+		"email": userWithUnverifiedIdentity.GetEmail(),
+	})
+	require.NoError(ts.T(), err)
+	require.NoError(ts.T(), ts.API.db.Create(googleIdentity))
+	userWithUnverifiedIdentity.Identities = []models.Identity{*emailIdentity, *googleIdentity}
+
 	cases := []struct {
 		desc          string
 		user          *models.User
@@ -130,6 +149,12 @@ func (ts *IdentityTestSuite) TestUnlinkIdentityError() {
 			identityId:    userWithOneIdentity.Identities[0].ID,
 			expectedError: apierrors.NewUnprocessableEntityError(apierrors.ErrorCodeSingleIdentityNotDeletable, "User must have at least 1 identity after unlinking"),
 		},
+		{
+			desc:          "User must have at least 1 verified identity after unlinking",
+			user:          userWithUnverifiedIdentity,
+			identityId:    userWithUnverifiedIdentity.Identities[1].ID,
+			expectedError: apierrors.NewUnprocessableEntityError(apierrors.ErrorCodeSingleIdentityNotDeletable, "User must have at least 1 verified identity after unlinking"),
+		},
 		{
 			desc:          "Identity doesn't exist",
 			user:          userWithTwoIdentities,
@@ -156,6 +181,27 @@ func (ts *IdentityTestSuite) TestUnlinkIdentityError() {
 	}
 }
 
+func (ts *IdentityTestSuite) TestListIdentities() {
+	ts.Config.Security.ManualLinkingEnabled = true
+
+	u, err := models.FindUserByEmailAndAudience(ts.API.db, "two@example.com", ts.Config.JWT.Aud)
+	require.NoError(ts.T(), err)
+
+	token := ts.generateAccessTokenAndSession(u)
+	req, err := http.NewRequest(http.MethodGet, "/user/identities", nil)
+	require.NoError(ts.T(), err)
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	w := httptest.NewRecorder()
+	ts.API.handler.ServeHTTP(w, req)
+	require.Equal(ts.T(), http.StatusOK, w.Code)
+
+	var data struct {
+		Identities []models.Identity `json:"identities"`
+	}
+	require.NoError(ts.T(), json.NewDecoder(w.Body).Decode(&data))
+	require.Len(ts.T(), data.Identities, 2)
+}
+
 func (ts *IdentityTestSuite) TestUnlinkIdentity() {
 	ts.Config.Security.ManualLinkingEnabled = true
 
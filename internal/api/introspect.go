@@ -0,0 +1,272 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/supabase/auth/internal/api/apierrors"
+	"github.com/supabase/auth/internal/api/shared"
+	"github.com/supabase/auth/internal/crypto"
+	"github.com/supabase/auth/internal/models"
+	"github.com/supabase/auth/internal/storage"
+)
+
+const tokenTypeHintRefreshToken = "refresh_token"
+
+// IntrospectParams are the parameters of a token introspection request.
+type IntrospectParams struct {
+	Token         string `json:"token"`
+	TokenTypeHint string `json:"token_type_hint"`
+
+	// Scope lists the scopes the resource server requires, separated by
+	// spaces. Tokens without all of them are reported as inactive.
+	Scope string `json:"scope"`
+}
+
+// IntrospectResponse is a token introspection response as defined in RFC
+// 7662, with the session and the authenticator assurance level of the token.
+type IntrospectResponse struct {
+	Active    bool   `json:"active"`
+	Scope     string `json:"scope,omitempty"`
+	ClientID  string `json:"client_id,omitempty"`
+	Username  string `json:"username,omitempty"`
+	TokenType string `json:"token_type,omitempty"`
+	Exp       int64  `json:"exp,omitempty"`
+	Iat       int64  `json:"iat,omitempty"`
+	Sub       string `json:"sub,omitempty"`
+	Aud       string `json:"aud,omitempty"`
+	Iss       string `json:"iss,omitempty"`
+
+	Role        string            `json:"role,omitempty"`
+	SessionID   string            `json:"session_id,omitempty"`
+	AAL         string            `json:"aal,omitempty"`
+	AMR         []models.AMREntry `json:"amr,omitempty"`
+	IsAnonymous bool              `json:"is_anonymous,omitempty"`
+}
+
+// Introspect reports whether an access or refresh token is active, for
+// resource servers that cannot tell from the JWT alone whether its session
+// was revoked. The caller authenticates as a confidential OAuth client.
+// Tokens that are invalid, expired or revoked are reported as inactive.
+func (a *API) Introspect(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	db := a.db.WithContext(ctx)
+
+	client := shared.GetOAuthServerClient(ctx)
+	if client == nil || client.IsPublic() {
+		return apierrors.NewOAuthError("invalid_client", "Client authentication required")
+	}
+
+	params := &IntrospectParams{}
+	if strings.Contains(r.Header.Get("Content-Type"), "application/json") {
+		if err := json.NewDecoder(r.Body).Decode(params); err != nil {
+			return apierrors.NewOAuthError("invalid_request", "Invalid JSON body")
+		}
+	} else {
+		if err := r.ParseForm(); err != nil {
+			return apierrors.NewOAuthError("invalid_request", "Failed to parse form data")
+		}
+		params.Token = r.FormValue("token")
+		params.TokenTypeHint = r.FormValue("token_type_hint")
+		params.Scope = r.FormValue("scope")
+	}
+
+	if params.Token == "" {
+		return apierrors.NewOAuthError("invalid_request", "token is required")
+	}
+
+	// The hint only decides which kind of token is looked up first, as
+	// required by RFC 7662.
+	introspectors := []func(*http.Request, *storage.Connection, string) (*IntrospectResponse, error){
+		a.introspectAccessToken,
+		a.introspectRefreshToken,
+	}
+	if params.TokenTypeHint == tokenTypeHintRefreshToken {
+		introspectors[0], introspectors[1] = introspectors[1], introspectors[0]
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+
+	for _, introspect := range introspectors {
+		resp, err := introspect(r, db, params.Token)
+		if err != nil {
+			return err
+		}
+		if resp != nil {
+			// tokens lacking a scope the resource server requires are
+			// not active for it
+			if !models.HasAllScopes(models.ParseScopeString(resp.Scope), models.ParseScopeString(params.Scope)) {
+				break
+			}
+			return sendJSON(w, http.StatusOK, resp)
+		}
+	}
+
+	return sendJSON(w, http.StatusOK, &IntrospectResponse{Active: false})
+}
+
+// introspectAccessToken returns the introspection of an active access
+// token, or nil when the token is not one.
+func (a *API) introspectAccessToken(r *http.Request, db *storage.Connection, token string) (*IntrospectResponse, error) {
+	ctx, err := a.parseJWTClaims(token, r)
+	if err != nil {
+		return nil, nil
+	}
+
+	claims := getClaims(ctx)
+	if claims == nil {
+		return nil, nil
+	}
+
+	userID, err := uuid.FromString(claims.Subject)
+	if err != nil {
+		return nil, nil
+	}
+
+	if revoked, err := a.isAccessTokenRevoked(db, claims); err != nil {
+		return nil, err
+	} else if revoked {
+		return nil, nil
+	}
+
+	user, err := models.FindUserByID(db, userID)
+	if err != nil {
+		if models.IsNotFoundError(err) {
+			return nil, nil
+		}
+		return nil, apierrors.NewInternalServerError("Database error finding user").WithInternalError(err)
+	}
+
+	resp := &IntrospectResponse{
+		Active:      true,
+		Scope:       claims.Scope,
+		ClientID:    claims.ClientID,
+		Username:    introspectUsername(user),
+		TokenType:   "Bearer",
+		Sub:         claims.Subject,
+		Iss:         claims.Issuer,
+		Role:        claims.Role,
+		AAL:         claims.AuthenticatorAssuranceLevel,
+		AMR:         claims.AuthenticationMethodReference,
+		IsAnonymous: claims.IsAnonymous,
+	}
+	if claims.ExpiresAt != nil {
+		resp.Exp = claims.ExpiresAt.Unix()
+	}
+	if claims.IssuedAt != nil {
+		resp.Iat = claims.IssuedAt.Unix()
+	}
+	if len(claims.Audience) > 0 {
+		resp.Aud = claims.Audience[0]
+	}
+
+	if claims.SessionId != "" && claims.SessionId != uuid.Nil.String() {
+		sessionID, err := uuid.FromString(claims.SessionId)
+		if err != nil {
+			return nil, nil
+		}
+
+		session, err := models.FindSessionByID(db, sessionID, false)
+		if err != nil {
+			if models.IsNotFoundError(err) {
+				return nil, nil
+			}
+			return nil, apierrors.NewInternalServerError("Database error finding session").WithInternalError(err)
+		}
+
+		if !a.isIntrospectedSessionActive(user, session, nil) {
+			return nil, nil
+		}
+
+		resp.SessionID = session.ID.String()
+	} else if user.IsBanned() || user.DeletedAt != nil {
+		return nil, nil
+	}
+
+	return resp, nil
+}
+
+// introspectRefreshToken returns the introspection of an active refresh
+// token, or nil when the token is not one. Refresh tokens that have been
+// used are not active.
+func (a *API) introspectRefreshToken(r *http.Request, db *storage.Connection, token string) (*IntrospectResponse, error) {
+	config := a.config
+
+	user, anyToken, session, err := models.FindUserWithRefreshToken(db, config.Security.DBEncryption, token, false)
+	if err != nil {
+		if models.IsNotFoundError(err) {
+			return nil, nil
+		}
+		return nil, apierrors.NewInternalServerError("Database error finding refresh token").WithInternalError(err)
+	}
+
+	if session == nil {
+		return nil, nil
+	}
+
+	var refreshTokenTime *time.Time
+	switch t := anyToken.(type) {
+	case *models.RefreshToken:
+		if t.Revoked {
+			return nil, nil
+		}
+		refreshTokenTime = &t.UpdatedAt
+	case *crypto.RefreshToken:
+		if session.RefreshTokenCounter == nil || t.Counter != *session.RefreshTokenCounter {
+			return nil, nil
+		}
+	}
+
+	if !a.isIntrospectedSessionActive(user, session, refreshTokenTime) {
+		return nil, nil
+	}
+
+	resp := &IntrospectResponse{
+		Active:      true,
+		Username:    introspectUsername(user),
+		TokenType:   tokenTypeHintRefreshToken,
+		Iat:         session.LastRefreshedAt(refreshTokenTime).Unix(),
+		Sub:         user.ID.String(),
+		Aud:         user.Aud,
+		Iss:         config.JWT.Issuer,
+		Role:        user.Role,
+		SessionID:   session.ID.String(),
+		AAL:         session.GetAAL(),
+		IsAnonymous: user.IsAnonymous,
+	}
+	if session.NotAfter != nil {
+		resp.Exp = session.NotAfter.Unix()
+	}
+	if session.OAuthClientID != nil && *session.OAuthClientID != uuid.Nil {
+		resp.ClientID = session.OAuthClientID.String()
+	}
+	if session.Scopes != nil {
+		resp.Scope = *session.Scopes
+	}
+
+	return resp, nil
+}
+
+// isIntrospectedSessionActive reports whether the user of the session can
+// still use it, as the refresh token grant checks.
+func (a *API) isIntrospectedSessionActive(user *models.User, session *models.Session, refreshTokenTime *time.Time) bool {
+	config := a.config
+
+	if session.UserID != user.ID || user.IsBanned() || user.DeletedAt != nil {
+		return false
+	}
+
+	validityConfig := models.NewSessionValidityConfig(&config.Sessions, user.Role)
+
+	return session.CheckValidity(validityConfig, time.Now(), refreshTokenTime, user.HighestPossibleAAL()) == models.SessionValid
+}
+
+func introspectUsername(user *models.User) string {
+	if email := user.GetEmail(); email != "" {
+		return email
+	}
+	return user.GetPhone()
+}
@@ -0,0 +1,225 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gofrs/uuid"
+	jwt "github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"github.com/supabase/auth/internal/conf"
+	"github.com/supabase/auth/internal/models"
+	"github.com/supabase/auth/internal/storage"
+)
+
+type IntrospectTestSuite struct {
+	suite.Suite
+	API    *API
+	Config *conf.GlobalConfiguration
+
+	clientID     string
+	clientSecret string
+}
+
+func TestIntrospect(t *testing.T) {
+	api, config, err := setupAPIForTestWithCallback(func(config *conf.GlobalConfiguration, conn *storage.Connection) {
+		if config != nil {
+			config.OAuthServer.Enabled = true
+		}
+	})
+	require.NoError(t, err)
+
+	ts := &IntrospectTestSuite{
+		API:    api,
+		Config: config,
+	}
+	defer api.db.Close()
+
+	suite.Run(t, ts)
+}
+
+func (ts *IntrospectTestSuite) SetupTest() {
+	models.TruncateAll(ts.API.db)
+
+	u, err := models.NewUser("", "test@example.com", "password", ts.Config.JWT.Aud, nil)
+	require.NoError(ts.T(), err)
+	require.NoError(ts.T(), ts.API.db.Create(u))
+	require.NoError(ts.T(), u.Confirm(ts.API.db))
+
+	ts.clientID, ts.clientSecret = ts.registerClient("client_secret_basic")
+}
+
+func (ts *IntrospectTestSuite) registerClient(authMethod string) (string, string) {
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, &AccessTokenClaims{
+		Role: "supabase_admin",
+	}).SignedString([]byte(ts.Config.JWT.Secret))
+	require.NoError(ts.T(), err)
+
+	var body bytes.Buffer
+	require.NoError(ts.T(), json.NewEncoder(&body).Encode(map[string]interface{}{
+		"client_name":                "Gateway",
+		"redirect_uris":              []string{"https://example.com/callback"},
+		"token_endpoint_auth_method": authMethod,
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/admin/oauth/clients", &body)
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	ts.API.handler.ServeHTTP(w, req)
+	require.Equal(ts.T(), http.StatusCreated, w.Code, w.Body.String())
+
+	var client struct {
+		ClientID     string `json:"client_id"`
+		ClientSecret string `json:"client_secret"`
+	}
+	require.NoError(ts.T(), json.NewDecoder(w.Body).Decode(&client))
+	return client.ClientID, client.ClientSecret
+}
+
+func (ts *IntrospectTestSuite) signIn() *AccessTokenResponse {
+	var body bytes.Buffer
+	require.NoError(ts.T(), json.NewEncoder(&body).Encode(map[string]interface{}{
+		"email":    "test@example.com",
+		"password": "password",
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/token?grant_type=password", &body)
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	ts.API.handler.ServeHTTP(w, req)
+	require.Equal(ts.T(), http.StatusOK, w.Code, w.Body.String())
+
+	resp := &AccessTokenResponse{}
+	require.NoError(ts.T(), json.NewDecoder(w.Body).Decode(resp))
+	return resp
+}
+
+func (ts *IntrospectTestSuite) introspect(form url.Values) (int, map[string]interface{}) {
+	req := httptest.NewRequest(http.MethodPost, "/introspect", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(ts.clientID, ts.clientSecret)
+	w := httptest.NewRecorder()
+	ts.API.handler.ServeHTTP(w, req)
+
+	var data map[string]interface{}
+	require.NoError(ts.T(), json.NewDecoder(w.Body).Decode(&data))
+	return w.Code, data
+}
+
+func (ts *IntrospectTestSuite) TestAccessToken() {
+	tokens := ts.signIn()
+
+	code, data := ts.introspect(url.Values{"token": {tokens.Token}})
+	require.Equal(ts.T(), http.StatusOK, code)
+	require.Equal(ts.T(), true, data["active"])
+	require.Equal(ts.T(), "Bearer", data["token_type"])
+	require.Equal(ts.T(), tokens.User.ID.String(), data["sub"])
+	require.Equal(ts.T(), "test@example.com", data["username"])
+	require.Equal(ts.T(), "aal1", data["aal"])
+	require.NotEmpty(ts.T(), data["session_id"])
+	require.NotEmpty(ts.T(), data["exp"])
+
+	// the access token is no longer active once its session is revoked
+	require.NoError(ts.T(), models.Logout(ts.API.db, tokens.User.ID))
+
+	code, data = ts.introspect(url.Values{"token": {tokens.Token}})
+	require.Equal(ts.T(), http.StatusOK, code)
+	require.Equal(ts.T(), map[string]interface{}{"active": false}, data)
+}
+
+func (ts *IntrospectTestSuite) TestRefreshToken() {
+	tokens := ts.signIn()
+
+	code, data := ts.introspect(url.Values{
+		"token":           {tokens.RefreshToken},
+		"token_type_hint": {"refresh_token"},
+	})
+	require.Equal(ts.T(), http.StatusOK, code)
+	require.Equal(ts.T(), true, data["active"])
+	require.Equal(ts.T(), "refresh_token", data["token_type"])
+	require.Equal(ts.T(), tokens.User.ID.String(), data["sub"])
+
+	// the hint is only a hint
+	code, data = ts.introspect(url.Values{
+		"token":           {tokens.RefreshToken},
+		"token_type_hint": {"access_token"},
+	})
+	require.Equal(ts.T(), http.StatusOK, code)
+	require.Equal(ts.T(), true, data["active"])
+
+	require.NoError(ts.T(), models.Logout(ts.API.db, tokens.User.ID))
+
+	code, data = ts.introspect(url.Values{"token": {tokens.RefreshToken}})
+	require.Equal(ts.T(), http.StatusOK, code)
+	require.Equal(ts.T(), false, data["active"])
+}
+
+func (ts *IntrospectTestSuite) TestRequiredScope() {
+	tokens := ts.signIn()
+
+	code, data := ts.introspect(url.Values{"token": {tokens.RefreshToken}})
+	require.Equal(ts.T(), http.StatusOK, code)
+	sessionID, err := uuid.FromString(data["session_id"].(string))
+	require.NoError(ts.T(), err)
+
+	session, err := models.FindSessionByID(ts.API.db, sessionID, false)
+	require.NoError(ts.T(), err)
+	scopes := "openid email"
+	session.Scopes = &scopes
+	require.NoError(ts.T(), ts.API.db.UpdateOnly(session, "scopes"))
+
+	code, data = ts.introspect(url.Values{"token": {tokens.RefreshToken}, "scope": {"email"}})
+	require.Equal(ts.T(), http.StatusOK, code)
+	require.Equal(ts.T(), true, data["active"])
+	require.Equal(ts.T(), "openid email", data["scope"])
+
+	// a token without a required scope is not active for the resource server
+	code, data = ts.introspect(url.Values{"token": {tokens.RefreshToken}, "scope": {"email phone"}})
+	require.Equal(ts.T(), http.StatusOK, code)
+	require.Equal(ts.T(), map[string]interface{}{"active": false}, data)
+}
+
+func (ts *IntrospectTestSuite) TestInvalidToken() {
+	code, data := ts.introspect(url.Values{"token": {"not-a-token"}})
+	require.Equal(ts.T(), http.StatusOK, code)
+	require.Equal(ts.T(), false, data["active"])
+
+	code, _ = ts.introspect(url.Values{})
+	require.Equal(ts.T(), http.StatusBadRequest, code)
+}
+
+func (ts *IntrospectTestSuite) TestClientAuthentication() {
+	tokens := ts.signIn()
+	form := url.Values{"token": {tokens.Token}}
+
+	// without client credentials
+	req := httptest.NewRequest(http.MethodPost, "/introspect", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	ts.API.handler.ServeHTTP(w, req)
+	require.Equal(ts.T(), http.StatusBadRequest, w.Code)
+
+	// with a wrong secret
+	req = httptest.NewRequest(http.MethodPost, "/introspect", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(ts.clientID, "wrong")
+	w = httptest.NewRecorder()
+	ts.API.handler.ServeHTTP(w, req)
+	require.Equal(ts.T(), http.StatusBadRequest, w.Code)
+
+	// public clients cannot introspect tokens
+	publicClientID, _ := ts.registerClient("none")
+	form.Set("client_id", publicClientID)
+	req = httptest.NewRequest(http.MethodPost, "/introspect", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w = httptest.NewRecorder()
+	ts.API.handler.ServeHTTP(w, req)
+	require.Equal(ts.T(), http.StatusBadRequest, w.Code, w.Body.String())
+}
@@ -2,41 +2,93 @@ package api
 
 import (
 	"net/http"
+	"time"
 
 	"github.com/fatih/structs"
 	"github.com/supabase/auth/internal/api/apierrors"
 	"github.com/supabase/auth/internal/api/provider"
 	"github.com/supabase/auth/internal/models"
+	"github.com/supabase/auth/internal/observability"
 	"github.com/supabase/auth/internal/storage"
 )
 
-// InviteParams are the parameters the Signup endpoint accepts
+// bulkInviteMaxSize is the largest number of users invited with one request
+// to the bulk invite endpoint.
+const bulkInviteMaxSize = 100
+
+// InviteParams are the parameters the Invite endpoint accepts
 type InviteParams struct {
-	Email string                 `json:"email"`
-	Data  map[string]interface{} `json:"data"`
+	Email       string                 `json:"email"`
+	Data        map[string]interface{} `json:"data"`
+	Role        string                 `json:"role"`
+	AppMetaData map[string]interface{} `json:"app_metadata"`
+}
+
+// BulkInviteParams are the parameters the bulk invite endpoint accepts
+type BulkInviteParams struct {
+	Invites []InviteParams `json:"invites"`
+}
+
+// BulkInviteResult is the outcome of inviting one of the users of a bulk
+// invite.
+type BulkInviteResult struct {
+	Email string       `json:"email"`
+	User  *models.User `json:"user,omitempty"`
+	Error *HTTPError   `json:"error,omitempty"`
+}
+
+// BulkInviteResponse is the response of the bulk invite endpoint, with the
+// results in the order of the invites.
+type BulkInviteResponse struct {
+	Invites []BulkInviteResult `json:"invites"`
+}
+
+// PendingInvite is an invited user who has not accepted the invite yet.
+type PendingInvite struct {
+	User      *models.User `json:"user"`
+	ExpiresAt *time.Time   `json:"expires_at,omitempty"`
+	Expired   bool         `json:"expired"`
+}
+
+// AdminListInvitesResponse is the response of the pending invites endpoint.
+type AdminListInvitesResponse struct {
+	Invites []PendingInvite `json:"invites"`
+	Aud     string          `json:"aud"`
 }
 
 // Invite is the endpoint for inviting a new user
 func (a *API) Invite(w http.ResponseWriter, r *http.Request) error {
-	ctx := r.Context()
-	db := a.db.WithContext(ctx)
-	config := a.config
-	adminUser := getAdminUser(ctx)
 	params := &InviteParams{}
 	if err := retrieveRequestParams(r, params); err != nil {
 		return err
 	}
 
+	user, err := a.inviteUser(r, params)
+	if err != nil {
+		return err
+	}
+
+	return sendJSON(w, http.StatusOK, user)
+}
+
+// inviteUser creates the invited user, or finds the user who was invited
+// before, and sends the invite.
+func (a *API) inviteUser(r *http.Request, params *InviteParams) (*models.User, error) {
+	ctx := r.Context()
+	db := a.db.WithContext(ctx)
+	config := a.config
+	adminUser := getAdminUser(ctx)
+
 	var err error
 	params.Email, err = a.validateEmail(params.Email)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	aud := a.requestAud(ctx, r)
 	user, err := models.FindUserByEmailAndAudience(db, params.Email, aud)
 	if err != nil && !models.IsNotFoundError(err) {
-		return apierrors.NewInternalServerError("Database error finding user").WithInternalError(err)
+		return nil, apierrors.NewInternalServerError("Database error finding user").WithInternalError(err)
 	}
 
 	isCreate := user == nil
@@ -55,11 +107,11 @@ func (a *API) Invite(w http.ResponseWriter, r *http.Request) error {
 		// a database transaction
 		user, err = signupParams.ToUserModel(false /* <- isSSOUser */)
 		if err != nil {
-			return err
+			return nil, err
 		}
 
 		if err := a.triggerBeforeUserCreated(r, db, user); err != nil {
-			return err
+			return nil, err
 		}
 	}
 
@@ -83,6 +135,18 @@ func (a *API) Invite(w http.ResponseWriter, r *http.Request) error {
 			user.Identities = []models.Identity{*identity}
 		}
 
+		if params.Role != "" {
+			if terr := user.SetRole(tx, params.Role); terr != nil {
+				return terr
+			}
+		}
+
+		if params.AppMetaData != nil {
+			if terr := user.UpdateAppMetaData(tx, params.AppMetaData); terr != nil {
+				return terr
+			}
+		}
+
 		if terr := models.NewAuditLogEntry(config.AuditLog, r, tx, adminUser, models.UserInvitedAction, "", map[string]interface{}{
 			"user_id":    user.ID,
 			"user_email": user.Email,
@@ -96,11 +160,134 @@ func (a *API) Invite(w http.ResponseWriter, r *http.Request) error {
 		return nil
 	})
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	if err := a.triggerAfterUserCreated(r, db, user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// adminInvitesBulk invites several users at once. A failed invite does not
+// stop the others, its error is returned in its result instead.
+func (a *API) adminInvitesBulk(w http.ResponseWriter, r *http.Request) error {
+	params := &BulkInviteParams{}
+	if err := retrieveRequestParams(r, params); err != nil {
 		return err
 	}
-	return sendJSON(w, http.StatusOK, user)
+
+	if len(params.Invites) == 0 {
+		return apierrors.NewBadRequestError(apierrors.ErrorCodeValidationFailed, "At least one invite is required")
+	}
+	if len(params.Invites) > bulkInviteMaxSize {
+		return apierrors.NewBadRequestError(apierrors.ErrorCodeValidationFailed, "At most %d users can be invited at once", bulkInviteMaxSize)
+	}
+
+	results := make([]BulkInviteResult, 0, len(params.Invites))
+	for i := range params.Invites {
+		invite := &params.Invites[i]
+		result := BulkInviteResult{Email: invite.Email}
+
+		user, err := a.inviteUser(r, invite)
+		if err != nil {
+			herr, ok := err.(*HTTPError)
+			if !ok {
+				herr = apierrors.NewInternalServerError("Error inviting user").WithInternalError(err)
+			}
+			if herr.HTTPStatus >= http.StatusInternalServerError {
+				observability.GetLogEntry(r).Entry.WithError(herr.Cause()).WithField("user_email", invite.Email).Error("bulk invite failed")
+			}
+			result.Error = herr
+		} else {
+			result.User = user
+		}
+
+		results = append(results, result)
+	}
+
+	return sendJSON(w, http.StatusOK, BulkInviteResponse{
+		Invites: results,
+	})
+}
+
+// adminInvites lists the invited users in the audience who have not
+// accepted their invite yet, including those whose invite has expired.
+func (a *API) adminInvites(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	db := a.db.WithContext(ctx)
+	aud := a.requestAud(ctx, r)
+
+	pageParams, err := paginate(r)
+	if err != nil {
+		return apierrors.NewBadRequestError(apierrors.ErrorCodeValidationFailed, "Bad Pagination Parameters: %v", err).WithInternalError(err)
+	}
+
+	users, err := models.FindPendingInvitesInAudience(db, aud, pageParams)
+	if err != nil {
+		return apierrors.NewInternalServerError("Database error finding invites").WithInternalError(err)
+	}
+
+	invites := make([]PendingInvite, 0, len(users))
+	for _, user := range users {
+		invites = append(invites, a.pendingInvite(user))
+	}
+	addPaginationHeaders(w, r, pageParams)
+
+	return sendJSON(w, http.StatusOK, AdminListInvitesResponse{
+		Invites: invites,
+		Aud:     aud,
+	})
+}
+
+// pendingInvite returns the invite of the user, which expires
+// MAILER_INVITE_EXP seconds after it was last sent.
+func (a *API) pendingInvite(user *models.User) PendingInvite {
+	invite := PendingInvite{
+		User:    user,
+		Expired: true,
+	}
+
+	if user.ConfirmationSentAt != nil {
+		expiresAt := user.ConfirmationSentAt.Add(time.Second * time.Duration(a.config.Mailer.InviteExp)) // #nosec G115
+		invite.ExpiresAt = &expiresAt
+		invite.Expired = user.ConfirmationToken == "" || time.Now().After(expiresAt)
+	}
+
+	return invite
+}
+
+// adminInviteResend sends the invite of a user who has not accepted it
+// again, replacing the link of the previous invite.
+func (a *API) adminInviteResend(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	db := a.db.WithContext(ctx)
+	config := a.config
+	adminUser := getAdminUser(ctx)
+	user := getUser(ctx)
+
+	if !user.HasBeenInvited() || user.IsConfirmed() {
+		return apierrors.NewNotFoundError(apierrors.ErrorCodeInviteNotFound, "User has no pending invite")
+	}
+
+	if err := validateSentWithinFrequencyLimit(user.ConfirmationSentAt, config.SMTP.MaxFrequency); err != nil {
+		return err
+	}
+
+	err := db.Transaction(func(tx *storage.Connection) error {
+		if terr := models.NewAuditLogEntry(config.AuditLog, r, tx, adminUser, models.UserInvitedAction, "", map[string]interface{}{
+			"user_id":    user.ID,
+			"user_email": user.Email,
+			"resent":     true,
+		}); terr != nil {
+			return terr
+		}
+
+		return a.sendInvite(r, tx, user)
+	})
+	if err != nil {
+		return err
+	}
+
+	return sendJSON(w, http.StatusOK, a.pendingInvite(user))
 }
@@ -454,3 +454,139 @@ func (ts *InviteTestSuite) TestInviteExternalGitlab_MismatchedEmails() {
 	ts.Require().NotEmpty(v.Get("error_description"))
 	ts.Require().Equal("invalid_request", v.Get("error"))
 }
+
+func (ts *InviteTestSuite) invite(path string, body interface{}) *httptest.ResponseRecorder {
+	var buffer bytes.Buffer
+	require.NoError(ts.T(), json.NewEncoder(&buffer).Encode(body))
+
+	req := httptest.NewRequest(http.MethodPost, "http://localhost"+path, &buffer)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", ts.token))
+
+	w := httptest.NewRecorder()
+	ts.API.handler.ServeHTTP(w, req)
+	return w
+}
+
+func (ts *InviteTestSuite) TestInviteWithRoleAndAppMetadata() {
+	w := ts.invite("/invite", InviteParams{
+		Email:       "role@example.com",
+		Role:        "editor",
+		AppMetaData: map[string]interface{}{"team": "docs"},
+	})
+	require.Equal(ts.T(), http.StatusOK, w.Code, w.Body.String())
+
+	user, err := models.FindUserByEmailAndAudience(ts.API.db, "role@example.com", ts.Config.JWT.Aud)
+	require.NoError(ts.T(), err)
+	require.Equal(ts.T(), "editor", user.Role)
+	require.Equal(ts.T(), "docs", user.AppMetaData["team"])
+	require.Equal(ts.T(), "email", user.AppMetaData["provider"])
+	require.NotNil(ts.T(), user.InvitedAt)
+}
+
+func (ts *InviteTestSuite) TestBulkInvite() {
+	w := ts.invite("/admin/invites", BulkInviteParams{
+		Invites: []InviteParams{
+			{Email: "bulk1@example.com", Role: "editor"},
+			{Email: "not-an-email"},
+			{Email: "bulk2@example.com", AppMetaData: map[string]interface{}{"team": "docs"}},
+		},
+	})
+	require.Equal(ts.T(), http.StatusOK, w.Code, w.Body.String())
+
+	var resp BulkInviteResponse
+	require.NoError(ts.T(), json.NewDecoder(w.Body).Decode(&resp))
+	require.Len(ts.T(), resp.Invites, 3)
+
+	require.Nil(ts.T(), resp.Invites[0].Error)
+	require.Equal(ts.T(), "bulk1@example.com", resp.Invites[0].User.GetEmail())
+	require.Equal(ts.T(), "editor", resp.Invites[0].User.Role)
+
+	require.Nil(ts.T(), resp.Invites[1].User)
+	require.NotNil(ts.T(), resp.Invites[1].Error)
+	require.Equal(ts.T(), "not-an-email", resp.Invites[1].Email)
+	require.Equal(ts.T(), http.StatusBadRequest, resp.Invites[1].Error.HTTPStatus)
+
+	require.Nil(ts.T(), resp.Invites[2].Error)
+	require.Equal(ts.T(), "docs", resp.Invites[2].User.AppMetaData["team"])
+}
+
+func (ts *InviteTestSuite) TestBulkInviteLimits() {
+	w := ts.invite("/admin/invites", BulkInviteParams{})
+	require.Equal(ts.T(), http.StatusBadRequest, w.Code)
+
+	invites := make([]InviteParams, bulkInviteMaxSize+1)
+	for i := range invites {
+		invites[i].Email = fmt.Sprintf("bulk%d@example.com", i)
+	}
+	w = ts.invite("/admin/invites", BulkInviteParams{Invites: invites})
+	require.Equal(ts.T(), http.StatusBadRequest, w.Code)
+}
+
+func (ts *InviteTestSuite) TestAdminInvitesPendingAndResend() {
+	ts.Config.SMTP.MaxFrequency = 0
+
+	w := ts.invite("/invite", InviteParams{Email: "pending@example.com"})
+	require.Equal(ts.T(), http.StatusOK, w.Code, w.Body.String())
+
+	confirmed, err := models.NewUser("", "confirmed@example.com", "", ts.Config.JWT.Aud, nil)
+	require.NoError(ts.T(), err)
+	now := time.Now()
+	confirmed.InvitedAt = &now
+	confirmed.EmailConfirmedAt = &now
+	require.NoError(ts.T(), ts.API.db.Create(confirmed))
+
+	req := httptest.NewRequest(http.MethodGet, "http://localhost/admin/invites", nil)
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", ts.token))
+	w = httptest.NewRecorder()
+	ts.API.handler.ServeHTTP(w, req)
+	require.Equal(ts.T(), http.StatusOK, w.Code, w.Body.String())
+
+	var resp AdminListInvitesResponse
+	require.NoError(ts.T(), json.NewDecoder(w.Body).Decode(&resp))
+	require.Len(ts.T(), resp.Invites, 1)
+	invite := resp.Invites[0]
+	require.Equal(ts.T(), "pending@example.com", invite.User.GetEmail())
+	require.False(ts.T(), invite.Expired)
+	require.NotNil(ts.T(), invite.ExpiresAt)
+
+	user, err := models.FindUserByEmailAndAudience(ts.API.db, "pending@example.com", ts.Config.JWT.Aud)
+	require.NoError(ts.T(), err)
+	oldToken := user.ConfirmationToken
+
+	w = ts.invite("/admin/invites/"+user.ID.String()+"/resend", nil)
+	require.Equal(ts.T(), http.StatusOK, w.Code, w.Body.String())
+
+	user, err = models.FindUserByEmailAndAudience(ts.API.db, "pending@example.com", ts.Config.JWT.Aud)
+	require.NoError(ts.T(), err)
+	require.NotEqual(ts.T(), oldToken, user.ConfirmationToken)
+
+	w = ts.invite("/admin/invites/"+confirmed.ID.String()+"/resend", nil)
+	require.Equal(ts.T(), http.StatusNotFound, w.Code)
+}
+
+func (ts *InviteTestSuite) TestVerifyInviteExpired() {
+	ts.Config.Mailer.InviteExp = 60
+
+	user, err := models.NewUser("", "expired@example.com", "", ts.Config.JWT.Aud, nil)
+	require.NoError(ts.T(), err)
+	sentAt := time.Now().Add(-2 * time.Minute)
+	user.InvitedAt = &sentAt
+	user.ConfirmationSentAt = &sentAt
+	user.ConfirmationToken = crypto.GenerateTokenHash(user.GetEmail(), "123456")
+	require.NoError(ts.T(), ts.API.db.Create(user))
+	require.NoError(ts.T(), models.CreateOneTimeToken(ts.API.db, user.ID, user.GetEmail(), user.ConfirmationToken, models.ConfirmationToken))
+
+	var buffer bytes.Buffer
+	require.NoError(ts.T(), json.NewEncoder(&buffer).Encode(map[string]interface{}{
+		"email": "expired@example.com",
+		"type":  "invite",
+		"token": "123456",
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "http://localhost/verify", &buffer)
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	ts.API.handler.ServeHTTP(w, req)
+	require.Equal(ts.T(), http.StatusForbidden, w.Code, w.Body.String())
+}
@@ -0,0 +1,97 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/stretchr/testify/require"
+	"github.com/supabase/auth/internal/api/apierrors"
+	"github.com/supabase/auth/internal/conf"
+	"github.com/supabase/auth/internal/models"
+)
+
+func (ts *MiddlewareTestSuite) TestEnforceIPAccess() {
+	defer func() {
+		ts.Config.IPAccess = conf.IPAccessConfiguration{}
+	}()
+	require.NoError(ts.T(), ts.Config.IPAccess.Deny.Decode("203.0.113.0/24"))
+	require.NoError(ts.T(), ts.Config.IPAccess.AdminAllow.Decode("192.0.2.0/24"))
+	require.NoError(ts.T(), ts.Config.IPAccess.TrustedProxies.Decode("10.0.0.0/8"))
+
+	countDenied := func() int {
+		count, err := ts.API.db.Q().Where("payload->>'action' = ?", models.IPAccessDeniedAction).Count(&models.AuditLogEntry{})
+		require.NoError(ts.T(), err)
+		return count
+	}
+	denied := countDenied()
+
+	testCases := []struct {
+		desc       string
+		path       string
+		remoteAddr string
+		xff        string
+		expected   int
+	}{
+		{
+			desc:       "allowed admin request",
+			path:       "/admin/users",
+			remoteAddr: "192.0.2.1:1234",
+			expected:   http.StatusUnauthorized,
+		},
+		{
+			desc:       "admin request outside of the allowed networks",
+			path:       "/admin/users",
+			remoteAddr: "198.51.100.1:1234",
+			expected:   http.StatusForbidden,
+		},
+		{
+			desc:       "admin request through a trusted proxy",
+			path:       "/admin/users",
+			remoteAddr: "10.0.0.1:1234",
+			xff:        "192.0.2.1",
+			expected:   http.StatusUnauthorized,
+		},
+		{
+			desc:       "admin request with a spoofed X-Forwarded-For",
+			path:       "/admin/users",
+			remoteAddr: "198.51.100.1:1234",
+			xff:        "192.0.2.1",
+			expected:   http.StatusForbidden,
+		},
+		{
+			desc:       "request outside of the admin endpoints",
+			path:       "/settings",
+			remoteAddr: "198.51.100.1:1234",
+			expected:   http.StatusOK,
+		},
+		{
+			desc:       "request from a denied network",
+			path:       "/settings",
+			remoteAddr: "203.0.113.1:1234",
+			expected:   http.StatusForbidden,
+		},
+	}
+
+	for _, c := range testCases {
+		ts.Run(c.desc, func() {
+			req := httptest.NewRequest(http.MethodGet, "http://localhost"+c.path, nil)
+			req.RemoteAddr = c.remoteAddr
+			if c.xff != "" {
+				req.Header.Set("X-Forwarded-For", c.xff)
+			}
+			w := httptest.NewRecorder()
+			ts.API.handler.ServeHTTP(w, req)
+			require.Equal(ts.T(), c.expected, w.Code, w.Body.String())
+
+			if c.expected == http.StatusForbidden {
+				data := make(map[string]interface{})
+				require.NoError(ts.T(), json.NewDecoder(w.Body).Decode(&data))
+				require.Equal(ts.T(), string(apierrors.ErrorCodeIPAddressNotAllowed), data["error_code"])
+
+				denied++
+				require.Equal(ts.T(), denied, countDenied())
+			}
+		})
+	}
+}
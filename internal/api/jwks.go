@@ -2,9 +2,12 @@ package api
 
 import (
 	"net/http"
+	"sync"
+	"time"
 
 	"github.com/lestrrat-go/jwx/v2/jwa"
 	jwk "github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/supabase/auth/internal/conf"
 	"github.com/supabase/auth/internal/models"
 )
 
@@ -12,22 +15,68 @@ type JwksResponse struct {
 	Keys []jwk.Key `json:"keys"`
 }
 
+// jwksCache holds a JWKS response until the cache TTL ends or one of its
+// keys expires, whichever comes first.
+type jwksCache struct {
+	mu        sync.Mutex
+	resp      *JwksResponse
+	expiresAt time.Time
+}
+
 func (a *API) WellKnownJwks(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Cache-Control", "public, max-age=600")
+	return sendJSON(w, http.StatusOK, a.jwksResponse(a.Now()))
+}
+
+// jwksResponse returns the public keys which are valid at now. The response
+// is memoized when caching is enabled, as the keys only change with the
+// configuration.
+func (a *API) jwksResponse(now time.Time) *JwksResponse {
 	config := a.config
-	resp := JwksResponse{
+	if !config.Cache.Enabled {
+		resp, _ := buildJwksResponse(config, now)
+		return resp
+	}
+
+	a.jwks.mu.Lock()
+	defer a.jwks.mu.Unlock()
+
+	if a.jwks.resp == nil || !now.Before(a.jwks.expiresAt) {
+		var expiresAt time.Time
+		a.jwks.resp, expiresAt = buildJwksResponse(config, now)
+
+		a.jwks.expiresAt = now.Add(config.Cache.TTL)
+		if !expiresAt.IsZero() && expiresAt.Before(a.jwks.expiresAt) {
+			a.jwks.expiresAt = expiresAt
+		}
+	}
+	return a.jwks.resp
+}
+
+// buildJwksResponse returns the public keys which are valid at now, and the
+// time the first of them expires or the zero time if none expires.
+func buildJwksResponse(config *conf.GlobalConfiguration, now time.Time) (*JwksResponse, time.Time) {
+	resp := &JwksResponse{
 		Keys: []jwk.Key{},
 	}
 
+	var expiresAt time.Time
 	for _, key := range config.JWT.Keys {
 		// don't expose hmac jwk in endpoint
 		if key.PublicKey == nil || key.PublicKey.KeyType() == jwa.OctetSeq {
 			continue
 		}
+		// keys past their rotation grace period are no longer valid
+		if key.IsExpired(now) {
+			continue
+		}
+		if key.ExpiresAt != nil && (expiresAt.IsZero() || key.ExpiresAt.Before(expiresAt)) {
+			expiresAt = *key.ExpiresAt
+		}
 		resp.Keys = append(resp.Keys, key.PublicKey)
 	}
 
-	w.Header().Set("Cache-Control", "public, max-age=600")
-	return sendJSON(w, http.StatusOK, resp)
+	return resp, expiresAt
 }
 
 // OpenIDConfigurationResponse represents both OIDC Discovery and OAuth 2.0 Authorization Server Metadata
@@ -44,6 +93,8 @@ type OpenIDConfigurationResponse struct {
 	JWKSURL               string `json:"jwks_uri"`
 	UserInfoEndpoint      string `json:"userinfo_endpoint,omitempty"` // OIDC-specific
 	RegistrationEndpoint  string `json:"registration_endpoint,omitempty"`
+	IntrospectionEndpoint string `json:"introspection_endpoint,omitempty"`
+	RevocationEndpoint    string `json:"revocation_endpoint"`
 
 	// Supported Parameters
 	ScopesSupported                   []string `json:"scopes_supported,omitempty"`
@@ -55,6 +106,9 @@ type OpenIDConfigurationResponse struct {
 	TokenEndpointAuthMethodsSupported []string `json:"token_endpoint_auth_methods_supported"`
 	ClaimsSupported                   []string `json:"claims_supported,omitempty"`       // OIDC-specific
 	CodeChallengeMethodsSupported     []string `json:"code_challenge_methods_supported"` // OAuth 2.1/PKCE
+
+	IntrospectionEndpointAuthMethodsSupported []string `json:"introspection_endpoint_auth_methods_supported,omitempty"`
+	RevocationEndpointAuthMethodsSupported    []string `json:"revocation_endpoint_auth_methods_supported"`
 }
 
 // WellKnownOpenID handles both OIDC Discovery and OAuth 2.0 Authorization Server Metadata endpoints
@@ -78,17 +132,20 @@ func (a *API) WellKnownOpenID(w http.ResponseWriter, r *http.Request) error {
 		TokenEndpoint:         issuer + "/oauth/token",
 		JWKSURL:               issuer + "/.well-known/jwks.json",
 		UserInfoEndpoint:      issuer + "/oauth/userinfo",
+		RevocationEndpoint:    issuer + "/revoke",
 
 		// OAuth 2.1 / OIDC Supported Features
 		ResponseTypesSupported:            []string{"code"},
 		ResponseModesSupported:            []string{"query"},
-		GrantTypesSupported:               []string{"authorization_code", "refresh_token"},
+		GrantTypesSupported:               []string{"authorization_code", "refresh_token", "client_credentials"},
 		SubjectTypesSupported:             []string{"public"},
 		IDTokenSigningAlgValuesSupported:  []string{"RS256", "HS256", "ES256"}, // TODO :: should create this based on signing key config?
 		TokenEndpointAuthMethodsSupported: []string{"client_secret_basic", "client_secret_post", "none"},
 		CodeChallengeMethodsSupported:     []string{"S256", "plain"},
 		ScopesSupported:                   models.SupportedOAuthScopes,
 
+		RevocationEndpointAuthMethodsSupported: []string{"client_secret_basic", "client_secret_post", "none"},
+
 		// OIDC Standard Claims
 		ClaimsSupported: []string{
 			"sub",
@@ -109,6 +166,12 @@ func (a *API) WellKnownOpenID(w http.ResponseWriter, r *http.Request) error {
 		},
 	}
 
+	// Include the introspection endpoint, it is served with the OAuth server
+	if config.OAuthServer.Enabled {
+		response.IntrospectionEndpoint = issuer + "/introspect"
+		response.IntrospectionEndpointAuthMethodsSupported = []string{"client_secret_basic", "client_secret_post"}
+	}
+
 	// Include registration endpoint if dynamic registration is enabled
 	if config.OAuthServer.Enabled && config.OAuthServer.AllowDynamicRegistration {
 		response.RegistrationEndpoint = issuer + "/oauth/clients/register"
@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/lestrrat-go/jwx/v2/jwk"
 	"github.com/stretchr/testify/require"
@@ -77,3 +78,46 @@ func TestJwks(t *testing.T) {
 		})
 	}
 }
+
+func TestJwksCache(t *testing.T) {
+	rsaPrivateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	rsaJwkPrivate, err := jwk.FromRaw(rsaPrivateKey)
+	require.NoError(t, err)
+	rsaJwkPublic, err := rsaJwkPrivate.PublicKey()
+	require.NoError(t, err)
+
+	now := time.Now()
+	expiresAt := now.Add(30 * time.Second)
+
+	mockAPI, _, err := setupAPIForTest()
+	require.NoError(t, err)
+	mockAPI.config.Cache = conf.CacheConfiguration{
+		Enabled: true,
+		TTL:     time.Minute,
+	}
+	mockAPI.config.JWT.Keys = conf.JwtKeysDecoder{
+		rsaJwkPublic.KeyID(): conf.JwkInfo{
+			PublicKey:  rsaJwkPublic,
+			PrivateKey: rsaJwkPrivate,
+			ExpiresAt:  &expiresAt,
+		},
+	}
+
+	require.Len(t, mockAPI.jwksResponse(now).Keys, 1)
+
+	// the response is memoized until the key expires
+	mockAPI.config.JWT.Keys = conf.JwtKeysDecoder{}
+	require.Len(t, mockAPI.jwksResponse(now.Add(10*time.Second)).Keys, 1)
+	require.Empty(t, mockAPI.jwksResponse(expiresAt).Keys)
+
+	// the response is not memoized when caching is disabled
+	mockAPI.config.Cache.Enabled = false
+	mockAPI.config.JWT.Keys = conf.JwtKeysDecoder{
+		rsaJwkPublic.KeyID(): conf.JwkInfo{
+			PublicKey:  rsaJwkPublic,
+			PrivateKey: rsaJwkPrivate,
+		},
+	}
+	require.Len(t, mockAPI.jwksResponse(expiresAt).Keys, 1)
+}
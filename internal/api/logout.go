@@ -67,6 +67,11 @@ func (a *API) Logout(w http.ResponseWriter, r *http.Request) error {
 		return apierrors.NewInternalServerError("Error logging out user").WithInternalError(err)
 	}
 
+	// the other sessions are logged out in other browsers
+	if scope != LogoutOthers && a.useSessionCookie(r) {
+		a.clearSessionCookies(w)
+	}
+
 	w.WriteHeader(http.StatusNoContent)
 
 	return nil
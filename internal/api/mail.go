@@ -20,6 +20,7 @@ import (
 	"github.com/supabase/auth/internal/api/apierrors"
 	"github.com/supabase/auth/internal/api/provider"
 	"github.com/supabase/auth/internal/crypto"
+	"github.com/supabase/auth/internal/metering"
 	"github.com/supabase/auth/internal/models"
 	"github.com/supabase/auth/internal/storage"
 	"github.com/supabase/auth/internal/utilities"
@@ -705,6 +706,40 @@ func (a *API) sendMFAFactorUnenrolledNotification(r *http.Request, tx *storage.C
 	return nil
 }
 
+func (a *API) sendDataExportNotification(r *http.Request, tx *storage.Connection, u *models.User, downloadURL string) error {
+	err := a.sendEmail(r, tx, u, sendEmailParams{
+		emailActionType: mail.DataExportNotification,
+		downloadURL:     downloadURL,
+	})
+	if err != nil {
+		if errors.Is(err, EmailRateLimitExceeded) {
+			return apierrors.NewTooManyRequestsError(apierrors.ErrorCodeOverEmailSendRateLimit, "%s", EmailRateLimitExceeded.Error())
+		} else if herr, ok := err.(*HTTPError); ok {
+			return herr
+		}
+		return apierrors.NewInternalServerError("Error sending data export notification email").WithInternalError(err)
+	}
+
+	return nil
+}
+
+func (a *API) sendNewSignInNotification(r *http.Request, tx *storage.Connection, u *models.User, signIn *mail.SignIn) error {
+	err := a.sendEmail(r, tx, u, sendEmailParams{
+		emailActionType: mail.NewSignInNotification,
+		signIn:          signIn,
+	})
+	if err != nil {
+		if errors.Is(err, EmailRateLimitExceeded) {
+			return apierrors.NewTooManyRequestsError(apierrors.ErrorCodeOverEmailSendRateLimit, "%s", EmailRateLimitExceeded.Error())
+		} else if herr, ok := err.(*HTTPError); ok {
+			return herr
+		}
+		return apierrors.NewInternalServerError("Error sending new sign-in notification email").WithInternalError(err)
+	}
+
+	return nil
+}
+
 func (a *API) validateEmail(email string) (string, error) {
 	if email == "" {
 		return "", apierrors.NewBadRequestError(apierrors.ErrorCodeValidationFailed, "An email address is required")
@@ -754,6 +789,8 @@ type sendEmailParams struct {
 	oldPhone            string
 	provider            string
 	factorType          string
+	downloadURL         string
+	signIn              *mail.SignIn
 }
 
 func (a *API) sendEmail(r *http.Request, tx *storage.Connection, u *models.User, params sendEmailParams) error {
@@ -848,6 +885,22 @@ func (a *API) sendEmail(r *http.Request, tx *storage.Connection, u *models.User,
 			}
 		}
 
+		// The links of the built-in emails are passed along, so the hook
+		// does not have to build them
+		var linkErr error
+		switch params.emailActionType {
+		case mail.SignupVerification, mail.MagicLinkVerification, mail.RecoveryVerification, mail.InviteVerification:
+			emailData.ConfirmationURL, linkErr = a.Mailer().GetEmailActionLink(u, params.emailActionType, referrerURL, externalURL)
+		case mail.EmailChangeVerification:
+			emailData.ConfirmationURL, linkErr = a.Mailer().GetEmailActionLink(u, mail.EmailChangeNewVerification, referrerURL, externalURL)
+			if linkErr == nil && config.Mailer.SecureEmailChangeEnabled && u.GetEmail() != "" {
+				emailData.ConfirmationURLCurrent, linkErr = a.Mailer().GetEmailActionLink(u, mail.EmailChangeCurrentVerification, referrerURL, externalURL)
+			}
+		}
+		if linkErr != nil {
+			return apierrors.NewInternalServerError("Error creating confirmation link").WithInternalError(linkErr)
+		}
+
 		// Augment the email data for the email send hook with notification-specific fields
 		switch params.emailActionType {
 		case mail.EmailChangedNotification:
@@ -858,6 +911,10 @@ func (a *API) sendEmail(r *http.Request, tx *storage.Connection, u *models.User,
 			emailData.Provider = params.provider
 		case mail.MFAFactorEnrolledNotification, mail.MFAFactorUnenrolledNotification:
 			emailData.FactorType = params.factorType
+		case mail.DataExportNotification:
+			emailData.DownloadURL = params.downloadURL
+		case mail.NewSignInNotification:
+			emailData.SignIn = params.signIn
 		}
 
 		input := v0hooks.SendEmailInput{
@@ -865,7 +922,12 @@ func (a *API) sendEmail(r *http.Request, tx *storage.Connection, u *models.User,
 			EmailData: emailData,
 		}
 		output := v0hooks.SendEmailOutput{}
-		return a.hooksMgr.InvokeHook(tx, r, &input, &output)
+		if err := a.hooksMgr.InvokeHook(tx, r, &input, &output); err != nil {
+			return err
+		}
+
+		recordEmailOTPSent(params.emailActionType)
+		return nil
 	}
 
 	// Increment email send operations here, since this metric is meant to count number of mail
@@ -901,6 +963,10 @@ func (a *API) sendEmail(r *http.Request, tx *storage.Connection, u *models.User,
 		err = mr.MFAFactorEnrolledNotificationMail(r, u, params.factorType)
 	case mail.MFAFactorUnenrolledNotification:
 		err = mr.MFAFactorUnenrolledNotificationMail(r, u, params.factorType)
+	case mail.DataExportNotification:
+		err = mr.DataExportNotificationMail(r, u, params.downloadURL)
+	case mail.NewSignInNotification:
+		err = mr.NewSignInNotificationMail(r, u, params.signIn)
 	default:
 		err = errors.New("invalid email action type")
 	}
@@ -919,6 +985,21 @@ func (a *API) sendEmail(r *http.Request, tx *storage.Connection, u *models.User,
 		emailErrorsCounter.Add(ctx, 1, metric.WithAttributes(attribute.String("type", params.emailActionType)))
 		return err
 	default:
-		return err
+		recordEmailOTPSent(params.emailActionType)
+		return nil
+	}
+}
+
+// recordEmailOTPSent counts the mail as an OTP send when it carries an OTP,
+// notifications do not.
+func recordEmailOTPSent(emailActionType string) {
+	switch emailActionType {
+	case mail.SignupVerification,
+		mail.MagicLinkVerification,
+		mail.ReauthenticationVerification,
+		mail.RecoveryVerification,
+		mail.InviteVerification,
+		mail.EmailChangeVerification:
+		metering.RecordOTPSent(metering.OTPChannelEmail, emailActionType)
 	}
 }
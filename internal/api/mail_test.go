@@ -249,6 +249,59 @@ func (ts *MailTestSuite) TestGenerateLink() {
 	ts.API.config.Mailer.ExternalHosts = originalHosts
 }
 
+func (ts *MailTestSuite) TestGenerateLinkUnknownEmail() {
+	claims := &AccessTokenClaims{
+		Role: "supabase_admin",
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(ts.Config.JWT.Secret))
+	require.NoError(ts.T(), err, "Error generating admin jwt")
+
+	cases := []struct {
+		Desc         string
+		Type         string
+		ExpectedCode int
+		ExpectedType string
+	}{
+		{
+			Desc:         "Magic link signs the user up",
+			Type:         "magiclink",
+			ExpectedCode: http.StatusOK,
+			ExpectedType: "signup",
+		},
+		{
+			Desc:         "Recovery link requires a user",
+			Type:         "recovery",
+			ExpectedCode: http.StatusNotFound,
+		},
+	}
+
+	for _, c := range cases {
+		ts.Run(c.Desc, func() {
+			var buffer bytes.Buffer
+			require.NoError(ts.T(), json.NewEncoder(&buffer).Encode(GenerateLinkParams{
+				Email: "unknown@example.com",
+				Type:  c.Type,
+			}))
+			req := httptest.NewRequest(http.MethodPost, "/admin/generate_link", &buffer)
+			req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+			w := httptest.NewRecorder()
+
+			ts.API.handler.ServeHTTP(w, req)
+			require.Equal(ts.T(), c.ExpectedCode, w.Code)
+
+			data := make(map[string]interface{})
+			require.NoError(ts.T(), json.NewDecoder(w.Body).Decode(&data))
+			if c.ExpectedCode != http.StatusOK {
+				require.Equal(ts.T(), string(apierrors.ErrorCodeUserNotFound), data["error_code"])
+				return
+			}
+
+			require.Equal(ts.T(), c.ExpectedType, data["verification_type"])
+			require.Equal(ts.T(), "unknown@example.com", data["email"])
+		})
+	}
+}
+
 func (ts *MailTestSuite) setURIAllowListMap(uris ...string) {
 	for _, uri := range uris {
 		g := glob.MustCompile(uri, '.', '/')
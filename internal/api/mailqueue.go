@@ -0,0 +1,79 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gofrs/uuid"
+	"github.com/supabase/auth/internal/api/apierrors"
+	"github.com/supabase/auth/internal/models"
+)
+
+// AdminListMailJobsResponse is the response of the admin mail queue
+// endpoint.
+type AdminListMailJobsResponse struct {
+	Jobs []*models.MailJob `json:"jobs"`
+}
+
+// MailJobRedriveParams are the parameters of a mail queue re-drive.
+type MailJobRedriveParams struct {
+	IDs []uuid.UUID `json:"ids"`
+}
+
+// MailJobRedriveResponse is the response of a mail queue re-drive.
+type MailJobRedriveResponse struct {
+	Redriven int `json:"redriven"`
+}
+
+// adminMailJobs lists the queued mail with the status given in the status
+// query parameter, dead-lettered mail by default.
+func (a *API) adminMailJobs(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	db := a.db.WithContext(ctx)
+
+	pageParams, err := paginate(r)
+	if err != nil {
+		return apierrors.NewBadRequestError(apierrors.ErrorCodeValidationFailed, "Bad Pagination Parameters: %v", err).WithInternalError(err)
+	}
+
+	status := models.MailJobDead
+	switch value := models.MailJobStatus(r.URL.Query().Get("status")); value {
+	case "":
+	case models.MailJobPending, models.MailJobDead:
+		status = value
+	default:
+		return apierrors.NewBadRequestError(apierrors.ErrorCodeValidationFailed, "status must be pending or dead")
+	}
+
+	jobs, err := models.FindMailJobs(db, status, pageParams)
+	if err != nil {
+		return apierrors.NewInternalServerError("Database error finding mail jobs").WithInternalError(err)
+	}
+	addPaginationHeaders(w, r, pageParams)
+
+	return sendJSON(w, http.StatusOK, AdminListMailJobsResponse{
+		Jobs: jobs,
+	})
+}
+
+// adminMailJobsRedrive queues dead-lettered mail for sending again, either
+// the mail with the given IDs or all of it.
+func (a *API) adminMailJobsRedrive(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	db := a.db.WithContext(ctx)
+
+	params := &MailJobRedriveParams{}
+	if r.ContentLength != 0 {
+		if err := retrieveRequestParams(r, params); err != nil {
+			return err
+		}
+	}
+
+	redriven, err := models.RedriveMailJobs(db, params.IDs)
+	if err != nil {
+		return apierrors.NewInternalServerError("Database error re-driving mail jobs").WithInternalError(err)
+	}
+
+	return sendJSON(w, http.StatusOK, MailJobRedriveResponse{
+		Redriven: redriven,
+	})
+}
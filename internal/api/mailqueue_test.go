@@ -0,0 +1,128 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofrs/uuid"
+	jwt "github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"github.com/supabase/auth/internal/conf"
+	"github.com/supabase/auth/internal/models"
+)
+
+type MailQueueTestSuite struct {
+	suite.Suite
+	API    *API
+	Config *conf.GlobalConfiguration
+
+	token string
+}
+
+func TestMailQueue(t *testing.T) {
+	api, config, err := setupAPIForTest()
+	require.NoError(t, err)
+
+	ts := &MailQueueTestSuite{
+		API:    api,
+		Config: config,
+	}
+	defer api.db.Close()
+
+	suite.Run(t, ts)
+}
+
+func (ts *MailQueueTestSuite) SetupTest() {
+	models.TruncateAll(ts.API.db)
+
+	claims := &AccessTokenClaims{
+		Role: "supabase_admin",
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(ts.Config.JWT.Secret))
+	require.NoError(ts.T(), err, "Error generating admin jwt")
+	ts.token = token
+}
+
+func (ts *MailQueueTestSuite) createJob(dead bool) *models.MailJob {
+	job := models.NewMailJob("test@example.com", "Confirm Your Signup", "body", nil, "signup")
+	require.NoError(ts.T(), ts.API.db.Create(job))
+	if dead {
+		require.NoError(ts.T(), job.Failed(ts.API.db, errors.New("connection refused"), nil))
+	}
+	return job
+}
+
+func (ts *MailQueueTestSuite) request(method, path string, body io.Reader) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, body)
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", ts.token))
+
+	w := httptest.NewRecorder()
+	ts.API.handler.ServeHTTP(w, req)
+	return w
+}
+
+func (ts *MailQueueTestSuite) listJobs(query string) []*models.MailJob {
+	w := ts.request(http.MethodGet, "/admin/mail/jobs"+query, nil)
+	require.Equal(ts.T(), http.StatusOK, w.Code, w.Body.String())
+
+	var res AdminListMailJobsResponse
+	require.NoError(ts.T(), json.NewDecoder(w.Body).Decode(&res))
+	return res.Jobs
+}
+
+func (ts *MailQueueTestSuite) TestList() {
+	dead := ts.createJob(true)
+	pending := ts.createJob(false)
+
+	jobs := ts.listJobs("")
+	require.Len(ts.T(), jobs, 1)
+	require.Equal(ts.T(), dead.ID, jobs[0].ID)
+	require.Equal(ts.T(), models.MailJobDead, jobs[0].Status)
+	require.Equal(ts.T(), "connection refused", *jobs[0].LastError)
+	require.Empty(ts.T(), jobs[0].Body, "the body is not exposed")
+
+	jobs = ts.listJobs("?status=pending")
+	require.Len(ts.T(), jobs, 1)
+	require.Equal(ts.T(), pending.ID, jobs[0].ID)
+
+	w := ts.request(http.MethodGet, "/admin/mail/jobs?status=sent", nil)
+	require.Equal(ts.T(), http.StatusBadRequest, w.Code)
+}
+
+func (ts *MailQueueTestSuite) TestRedrive() {
+	first := ts.createJob(true)
+	ts.createJob(true)
+
+	var body bytes.Buffer
+	require.NoError(ts.T(), json.NewEncoder(&body).Encode(MailJobRedriveParams{
+		IDs: []uuid.UUID{first.ID},
+	}))
+
+	w := ts.request(http.MethodPost, "/admin/mail/jobs/redrive", &body)
+	require.Equal(ts.T(), http.StatusOK, w.Code, w.Body.String())
+
+	var res MailJobRedriveResponse
+	require.NoError(ts.T(), json.NewDecoder(w.Body).Decode(&res))
+	require.Equal(ts.T(), 1, res.Redriven)
+
+	jobs := ts.listJobs("?status=pending")
+	require.Len(ts.T(), jobs, 1)
+	require.Equal(ts.T(), first.ID, jobs[0].ID)
+	require.Equal(ts.T(), 0, jobs[0].Attempts)
+
+	// without IDs every dead job is re-driven
+	w = ts.request(http.MethodPost, "/admin/mail/jobs/redrive", nil)
+	require.Equal(ts.T(), http.StatusOK, w.Code, w.Body.String())
+	require.NoError(ts.T(), json.NewDecoder(w.Body).Decode(&res))
+	require.Equal(ts.T(), 1, res.Redriven)
+
+	require.Empty(ts.T(), ts.listJobs(""))
+	require.Len(ts.T(), ts.listJobs("?status=pending"), 2)
+}
@@ -0,0 +1,31 @@
+package api
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"github.com/supabase/auth/internal/api/apierrors"
+	"github.com/supabase/auth/internal/observability"
+)
+
+// Metrics serves the Prometheus metrics. When a username and password are
+// configured the request must authenticate with them using basic auth.
+func (a *API) Metrics(w http.ResponseWriter, r *http.Request) error {
+	config := a.config.Metrics
+
+	if config.EndpointUsername != "" {
+		username, password, ok := r.BasicAuth()
+
+		// compare both to not leak which one is wrong through timing
+		validUsername := subtle.ConstantTimeCompare([]byte(username), []byte(config.EndpointUsername)) == 1
+		validPassword := subtle.ConstantTimeCompare([]byte(password), []byte(config.EndpointPassword)) == 1
+
+		if !ok || !validUsername || !validPassword {
+			w.Header().Set("WWW-Authenticate", `Basic realm="metrics", charset="UTF-8"`)
+			return apierrors.NewHTTPError(http.StatusUnauthorized, apierrors.ErrorCodeNoAuthorization, "Invalid metrics credentials")
+		}
+	}
+
+	observability.PrometheusHandler().ServeHTTP(w, r)
+	return nil
+}
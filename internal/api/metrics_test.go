@@ -0,0 +1,72 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"github.com/supabase/auth/internal/conf"
+	"github.com/supabase/auth/internal/storage"
+)
+
+type MetricsTestSuite struct {
+	suite.Suite
+	API    *API
+	Config *conf.GlobalConfiguration
+}
+
+func TestMetrics(t *testing.T) {
+	api, config, err := setupAPIForTestWithCallback(func(config *conf.GlobalConfiguration, conn *storage.Connection) {
+		if config != nil {
+			config.Metrics.Enabled = true
+			config.Metrics.Exporter = conf.Prometheus
+			config.Metrics.EndpointEnabled = true
+			config.Metrics.EndpointUsername = "metrics"
+			config.Metrics.EndpointPassword = "secret"
+		}
+	})
+	require.NoError(t, err)
+
+	ts := &MetricsTestSuite{
+		API:    api,
+		Config: config,
+	}
+	defer api.db.Close()
+
+	suite.Run(t, ts)
+}
+
+func (ts *MetricsTestSuite) TestMetricsEndpoint() {
+	cases := []struct {
+		desc     string
+		username string
+		password string
+		expected int
+	}{
+		{"no credentials", "", "", http.StatusUnauthorized},
+		{"wrong password", "metrics", "wrong", http.StatusUnauthorized},
+		{"wrong username", "admin", "secret", http.StatusUnauthorized},
+		{"valid credentials", "metrics", "secret", http.StatusOK},
+	}
+
+	for _, c := range cases {
+		ts.Run(c.desc, func() {
+			req := httptest.NewRequest(http.MethodGet, "http://localhost/metrics", nil)
+			if c.username != "" {
+				req.SetBasicAuth(c.username, c.password)
+			}
+
+			w := httptest.NewRecorder()
+			ts.API.handler.ServeHTTP(w, req)
+			ts.Require().Equal(c.expected, w.Code)
+
+			if c.expected == http.StatusUnauthorized {
+				ts.Require().Contains(w.Header().Get("WWW-Authenticate"), "Basic")
+			} else {
+				ts.Require().Contains(w.Header().Get("Content-Type"), "text/plain")
+			}
+		})
+	}
+}
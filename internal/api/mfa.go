@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"slices"
 	"strings"
 	"time"
 
@@ -26,8 +27,10 @@ import (
 	"github.com/supabase/auth/internal/hooks/v0hooks"
 	"github.com/supabase/auth/internal/metering"
 	"github.com/supabase/auth/internal/models"
+	"github.com/supabase/auth/internal/observability"
 	"github.com/supabase/auth/internal/storage"
 	"github.com/supabase/auth/internal/utilities"
+	"github.com/supabase/auth/internal/webhooks"
 )
 
 const DefaultQRSize = 3
@@ -58,14 +61,29 @@ type ChallengeFactorParams struct {
 	WebAuthn *WebAuthnParams `json:"webauthn,omitempty"`
 }
 
+// StepUpParams are the parameters of a step-up request. The factor is the
+// most recently verified factor of the user when FactorID is not set.
+type StepUpParams struct {
+	FactorID *uuid.UUID `json:"factor_id"`
+	ChallengeFactorParams
+}
+
 type VerifyFactorParams struct {
 	ChallengeID uuid.UUID       `json:"challenge_id"`
 	Code        string          `json:"code"`
 	WebAuthn    *WebAuthnParams `json:"webauthn,omitempty"`
+
+	// RecoveryCode verifies a verified factor without its authenticator.
+	RecoveryCode string `json:"recovery_code,omitempty"`
+
+	// RememberDevice issues a device token skipping MFA challenges on the
+	// device, when trusted devices are enabled.
+	RememberDevice bool `json:"remember_device,omitempty"`
 }
 
 type ChallengeFactorResponse struct {
 	ID        uuid.UUID              `json:"id"`
+	FactorID  uuid.UUID              `json:"factor_id"`
 	Type      string                 `json:"type"`
 	ExpiresAt int64                  `json:"expires_at,omitempty"`
 	WebAuthn  *WebAuthnChallengeData `json:"webauthn,omitempty"`
@@ -87,19 +105,48 @@ type UnenrollFactorResponse struct {
 	ID uuid.UUID `json:"id"`
 }
 
-func (w *WebAuthnParams) ToConfig() (*webauthn.WebAuthn, error) {
-	if w.RPID == "" {
+// ToConfig builds the relying party configuration for a WebAuthn ceremony.
+// Server configured RP values take precedence over the client supplied ones,
+// which are then only accepted if they match the configuration.
+func (w *WebAuthnParams) ToConfig(config *conf.WebAuthnFactorTypeConfiguration) (*webauthn.WebAuthn, error) {
+	rpID := w.RPID
+	rpOrigins := w.RPOrigins
+	rpDisplayName := w.RPID
+
+	if config.RPID != "" {
+		if rpID != "" && rpID != config.RPID {
+			return nil, fmt.Errorf("webAuthn RP ID %q is not allowed", rpID)
+		}
+
+		for _, origin := range rpOrigins {
+			if !slices.Contains(config.RPOrigins, origin) {
+				return nil, fmt.Errorf("webAuthn RP origin %q is not allowed", origin)
+			}
+		}
+
+		rpID = config.RPID
+		rpDisplayName = config.RPID
+		if len(rpOrigins) == 0 {
+			rpOrigins = config.RPOrigins
+		}
+	}
+
+	if config.RPDisplayName != "" {
+		rpDisplayName = config.RPDisplayName
+	}
+
+	if rpID == "" {
 		return nil, fmt.Errorf("webAuthn RP ID cannot be empty")
 	}
 
-	if len(w.RPOrigins) == 0 {
+	if len(rpOrigins) == 0 {
 		return nil, fmt.Errorf("webAuthn RP Origins cannot be empty")
 	}
 
 	var validOrigins []string
 	var invalidOrigins []string
 
-	for _, origin := range w.RPOrigins {
+	for _, origin := range rpOrigins {
 		parsedURL, err := url.Parse(origin)
 		if err != nil || (parsedURL.Scheme != "https" && !(parsedURL.Scheme == "http" && parsedURL.Hostname() == "localhost")) || parsedURL.Host == "" {
 			invalidOrigins = append(invalidOrigins, origin)
@@ -114,9 +161,13 @@ func (w *WebAuthnParams) ToConfig() (*webauthn.WebAuthn, error) {
 
 	wconfig := &webauthn.Config{
 		// DisplayName is optional in spec but required to be non-empty in libary, we use the RPID as a placeholder.
-		RPDisplayName: w.RPID,
-		RPID:          w.RPID,
-		RPOrigins:     validOrigins,
+		RPDisplayName:         rpDisplayName,
+		RPID:                  rpID,
+		RPOrigins:             validOrigins,
+		AttestationPreference: wbnprotocol.ConveyancePreference(config.AttestationPreference),
+		AuthenticatorSelection: wbnprotocol.AuthenticatorSelection{
+			UserVerification: wbnprotocol.UserVerificationRequirement(config.UserVerification),
+		},
 	}
 
 	return webauthn.New(wconfig)
@@ -170,7 +221,11 @@ func (a *API) enrollPhoneFactor(w http.ResponseWriter, r *http.Request, params *
 	session := getSession(ctx)
 	db := a.db.WithContext(ctx)
 	if params.Phone == "" {
-		return apierrors.NewBadRequestError(apierrors.ErrorCodeValidationFailed, "Phone number required to enroll Phone factor")
+		// the verified phone number of the user is enrolled by default
+		if !user.IsPhoneConfirmed() || user.GetPhone() == "" {
+			return apierrors.NewBadRequestError(apierrors.ErrorCodeValidationFailed, "Phone number required to enroll Phone factor")
+		}
+		params.Phone = user.GetPhone()
 	}
 
 	phone, err := validatePhone(params.Phone)
@@ -211,6 +266,12 @@ func (a *API) enrollPhoneFactor(w http.ResponseWriter, r *http.Request, params *
 		}); terr != nil {
 			return terr
 		}
+		if terr := webhooks.Emit(tx, &config.Webhooks, conf.WebhookEventMFAEnrolled, user, map[string]interface{}{
+			"factor_id":   factor.ID,
+			"factor_type": factor.FactorType,
+		}); terr != nil {
+			return apierrors.NewInternalServerError("Error queueing webhook event").WithInternalError(terr)
+		}
 		return nil
 	})
 	if err != nil {
@@ -246,6 +307,12 @@ func (a *API) enrollWebAuthnFactor(w http.ResponseWriter, r *http.Request, param
 		}); terr != nil {
 			return terr
 		}
+		if terr := webhooks.Emit(tx, &config.Webhooks, conf.WebhookEventMFAEnrolled, user, map[string]interface{}{
+			"factor_id":   factor.ID,
+			"factor_type": factor.FactorType,
+		}); terr != nil {
+			return apierrors.NewInternalServerError("Error queueing webhook event").WithInternalError(terr)
+		}
 		return nil
 	})
 	if err != nil {
@@ -314,6 +381,12 @@ func (a *API) enrollTOTPFactor(w http.ResponseWriter, r *http.Request, params *E
 		}); terr != nil {
 			return terr
 		}
+		if terr := webhooks.Emit(tx, &config.Webhooks, conf.WebhookEventMFAEnrolled, user, map[string]interface{}{
+			"factor_id":   factor.ID,
+			"factor_type": factor.FactorType,
+		}); terr != nil {
+			return apierrors.NewInternalServerError("Error queueing webhook event").WithInternalError(terr)
+		}
 		return nil
 	})
 	if err != nil {
@@ -393,6 +466,16 @@ func (a *API) challengePhoneFactor(w http.ResponseWriter, r *http.Request) error
 		}
 	}
 
+	if config.MFA.Phone.MaxChallengesPerHour > 0 {
+		count, err := models.CountChallengesSince(db, factor.ID, time.Now().Add(-time.Hour))
+		if err != nil {
+			return apierrors.NewInternalServerError("Database error counting challenges").WithInternalError(err)
+		}
+		if count >= config.MFA.Phone.MaxChallengesPerHour {
+			return apierrors.NewTooManyRequestsError(apierrors.ErrorCodeOverSMSSendRateLimit, "Too many codes were sent to this phone factor, try again later")
+		}
+	}
+
 	otp := crypto.GenerateOtp(config.MFA.Phone.OtpLength)
 
 	challenge, err := factor.CreatePhoneChallenge(ipAddress, otp, config.Security.DBEncryption.Encrypt, config.Security.DBEncryption.EncryptionKeyID, config.Security.DBEncryption.EncryptionKey)
@@ -414,6 +497,8 @@ func (a *API) challengePhoneFactor(w http.ResponseWriter, r *http.Request) error
 				OTP:     otp,
 				SMSType: "mfa",
 				Phone:   phone,
+				Channel: channel,
+				Message: message,
 			},
 		}
 		output := v0hooks.SendSMSOutput{}
@@ -421,16 +506,20 @@ func (a *API) challengePhoneFactor(w http.ResponseWriter, r *http.Request) error
 		if err != nil {
 			return apierrors.NewInternalServerError("error invoking hook")
 		}
+		if err := output.Err(); err != nil {
+			return apierrors.NewInternalServerError("error sending message").WithInternalError(err)
+		}
 	} else {
 		smsProvider, err := sms_provider.GetSmsProvider(*config)
 		if err != nil {
 			return apierrors.NewInternalServerError("Failed to get SMS provider").WithInternalError(err)
 		}
 		// We omit messageID for now, can consider reinstating if there are requests.
-		if _, err = smsProvider.SendMessage(phone, message, channel, otp); err != nil {
+		if _, err = sendSMSMessage(ctx, config, smsProvider, phone, message, channel, otp); err != nil {
 			return apierrors.NewInternalServerError("error sending message").WithInternalError(err)
 		}
 	}
+	metering.RecordOTPSent(channel, "mfa")
 	if err := db.Transaction(func(tx *storage.Connection) error {
 		if terr := factor.WriteChallengeToDatabase(tx, challenge); terr != nil {
 			return terr
@@ -448,6 +537,7 @@ func (a *API) challengePhoneFactor(w http.ResponseWriter, r *http.Request) error
 	}
 	return sendJSON(w, http.StatusOK, &ChallengeFactorResponse{
 		ID:        challenge.ID,
+		FactorID:  factor.ID,
 		Type:      factor.FactorType,
 		ExpiresAt: challenge.GetExpiryTime(config.MFA.ChallengeExpiryDuration).Unix(),
 	})
@@ -481,6 +571,7 @@ func (a *API) challengeTOTPFactor(w http.ResponseWriter, r *http.Request) error
 
 	return sendJSON(w, http.StatusOK, &ChallengeFactorResponse{
 		ID:        challenge.ID,
+		FactorID:  factor.ID,
 		Type:      factor.FactorType,
 		ExpiresAt: challenge.GetExpiryTime(config.MFA.ChallengeExpiryDuration).Unix(),
 	})
@@ -502,9 +593,9 @@ func (a *API) challengeWebAuthnFactor(w http.ResponseWriter, r *http.Request) er
 	if params.WebAuthn == nil {
 		return apierrors.NewBadRequestError(apierrors.ErrorCodeValidationFailed, "web_authn config required")
 	}
-	webAuthn, err := params.WebAuthn.ToConfig()
+	webAuthn, err := params.WebAuthn.ToConfig(&config.MFA.WebAuthn)
 	if err != nil {
-		return err
+		return apierrors.NewBadRequestError(apierrors.ErrorCodeValidationFailed, "%s", err.Error())
 	}
 	var response *ChallengeFactorResponse
 	var ws *models.WebAuthnSessionData
@@ -517,7 +608,7 @@ func (a *API) challengeWebAuthnFactor(w http.ResponseWriter, r *http.Request) er
 			excludeList = append(excludeList, wbnprotocol.CredentialDescriptor{
 				Type:         wbnprotocol.PublicKeyCredentialType,
 				CredentialID: cred.ID,
-				Transport:    []wbnprotocol.AuthenticatorTransport{"usb", "nfc"},
+				Transport:    credentialTransports(cred),
 			})
 		}
 
@@ -531,8 +622,9 @@ func (a *API) challengeWebAuthnFactor(w http.ResponseWriter, r *http.Request) er
 		challenge = ws.ToChallenge(factor.ID, ipAddress)
 
 		response = &ChallengeFactorResponse{
-			Type: factor.FactorType,
-			ID:   challenge.ID,
+			Type:     factor.FactorType,
+			ID:       challenge.ID,
+			FactorID: factor.ID,
 			WebAuthn: &WebAuthnChallengeData{
 				Type:              "create",
 				CredentialOptions: options,
@@ -549,8 +641,9 @@ func (a *API) challengeWebAuthnFactor(w http.ResponseWriter, r *http.Request) er
 		}
 		challenge = ws.ToChallenge(factor.ID, ipAddress)
 		response = &ChallengeFactorResponse{
-			Type: factor.FactorType,
-			ID:   challenge.ID,
+			Type:     factor.FactorType,
+			ID:       challenge.ID,
+			FactorID: factor.ID,
 			WebAuthn: &WebAuthnChallengeData{
 				Type:              "request",
 				CredentialOptions: options,
@@ -568,6 +661,16 @@ func (a *API) challengeWebAuthnFactor(w http.ResponseWriter, r *http.Request) er
 
 }
 
+// credentialTransports returns the transports reported by the authenticator
+// when the credential was registered, falling back to USB and NFC for
+// credentials stored before transports were recorded.
+func credentialTransports(cred webauthn.Credential) []wbnprotocol.AuthenticatorTransport {
+	if len(cred.Transport) > 0 {
+		return cred.Transport
+	}
+	return []wbnprotocol.AuthenticatorTransport{"usb", "nfc"}
+}
+
 func (a *API) validateChallenge(r *http.Request, db *storage.Connection, factor *models.Factor, challengeID uuid.UUID) (*models.Challenge, error) {
 	config := a.config
 	currentIP := utilities.GetIPAddress(r)
@@ -622,6 +725,112 @@ func (a *API) ChallengeFactor(w http.ResponseWriter, r *http.Request) error {
 
 }
 
+// StepUp challenges a verified factor of the authenticated user so that the
+// session can be elevated to AAL2 mid-session, or MFA be verified again
+// before sensitive operations. Verifying the challenge with
+// /factors/{factor_id}/verify issues tokens with the updated aal and amr
+// claims.
+func (a *API) StepUp(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	config := a.config
+	user := getUser(ctx)
+
+	params := &StepUpParams{}
+	if body, err := utilities.GetBodyBytes(r); err != nil {
+		return apierrors.NewInternalServerError("Could not read body into byte slice").WithInternalError(err)
+	} else if len(body) > 0 {
+		if err := retrieveRequestParams(r, params); err != nil {
+			return err
+		}
+	}
+
+	var factor *models.Factor
+	for i := range user.Factors {
+		f := &user.Factors[i]
+		if !f.IsVerified() || !isFactorVerifyEnabled(config, f) {
+			continue
+		}
+
+		if params.FactorID != nil {
+			if f.ID == *params.FactorID {
+				factor = f
+				break
+			}
+			continue
+		}
+
+		if factor == nil || f.UpdatedAt.After(factor.UpdatedAt) {
+			factor = f
+		}
+	}
+
+	if factor == nil {
+		return apierrors.NewUnprocessableEntityError(apierrors.ErrorCodeMFAFactorNotFound, "No verified MFA factor to step up with")
+	}
+
+	observability.LogEntrySetField(r, "factor_id", factor.ID)
+
+	return a.ChallengeFactor(w, r.WithContext(withFactor(ctx, factor)))
+}
+
+// isFactorVerifyEnabled reports whether factors of the type can be verified.
+// invokeMFAVerificationAttempt invokes the MFA verification attempt hook
+// with the outcome of verifying the factor, returning an error when the hook
+// rejects the attempt or requires the user to verify another factor.
+func (a *API) invokeMFAVerificationAttempt(r *http.Request, db *storage.Connection, user *models.User, factor *models.Factor, valid bool) error {
+	if !a.config.Hook.MFAVerificationAttempt.Enabled {
+		return nil
+	}
+
+	input := v0hooks.MFAVerificationAttemptInput{
+		UserID:     user.ID,
+		FactorID:   factor.ID,
+		FactorType: factor.FactorType,
+		Valid:      valid,
+		IPAddress:  utilities.GetIPAddress(r),
+	}
+
+	output := v0hooks.MFAVerificationAttemptOutput{}
+	if err := a.hooksMgr.InvokeHook(nil, r, &input, &output); err != nil {
+		return err
+	}
+
+	switch output.Decision {
+	case v0hooks.HookRejection:
+		if err := models.Logout(db, user.ID); err != nil {
+			return err
+		}
+
+		if output.Message == "" {
+			output.Message = v0hooks.DefaultMFAHookRejectionMessage
+		}
+
+		return apierrors.NewForbiddenError(apierrors.ErrorCodeMFAVerificationRejected, "%s", output.Message)
+
+	case v0hooks.HookRequireAdditionalVerification:
+		if output.Message == "" {
+			output.Message = v0hooks.DefaultMFAHookAdditionalMessage
+		}
+
+		return apierrors.NewForbiddenError(apierrors.ErrorCodeMFAAdditionalVerification, "%s", output.Message)
+	}
+
+	return nil
+}
+
+func isFactorVerifyEnabled(config *conf.GlobalConfiguration, factor *models.Factor) bool {
+	switch factor.FactorType {
+	case models.TOTP:
+		return config.MFA.TOTP.VerifyEnabled
+	case models.Phone:
+		return config.MFA.Phone.VerifyEnabled
+	case models.WebAuthn:
+		return config.MFA.WebAuthn.VerifyEnabled
+	default:
+		return false
+	}
+}
+
 func (a *API) verifyTOTPFactor(w http.ResponseWriter, r *http.Request, params *VerifyFactorParams) error {
 	var err error
 	ctx := r.Context()
@@ -647,31 +856,8 @@ func (a *API) verifyTOTPFactor(w http.ResponseWriter, r *http.Request, params *V
 		Algorithm: otp.AlgorithmSHA1,
 	})
 
-	if config.Hook.MFAVerificationAttempt.Enabled {
-		input := v0hooks.MFAVerificationAttemptInput{
-			UserID:     user.ID,
-			FactorID:   factor.ID,
-			FactorType: factor.FactorType,
-			Valid:      valid,
-		}
-
-		output := v0hooks.MFAVerificationAttemptOutput{}
-		err := a.hooksMgr.InvokeHook(nil, r, &input, &output)
-		if err != nil {
-			return err
-		}
-
-		if output.Decision == v0hooks.HookRejection {
-			if err := models.Logout(db, user.ID); err != nil {
-				return err
-			}
-
-			if output.Message == "" {
-				output.Message = v0hooks.DefaultMFAHookRejectionMessage
-			}
-
-			return apierrors.NewForbiddenError(apierrors.ErrorCodeMFAVerificationRejected, "%s", output.Message)
-		}
+	if err := a.invokeMFAVerificationAttempt(r, db, user, factor, valid); err != nil {
+		return err
 	}
 	if !valid {
 		if shouldReEncrypt && config.Security.DBEncryption.Encrypt {
@@ -683,11 +869,13 @@ func (a *API) verifyTOTPFactor(w http.ResponseWriter, r *http.Request, params *V
 				return err
 			}
 		}
+		metering.RecordLoginFailure(metering.LoginTypeMFA, metering.ProviderMFATOTP)
 		return apierrors.NewUnprocessableEntityError(apierrors.ErrorCodeMFAVerificationFailed, "Invalid TOTP code entered").WithInternalError(verr)
 	}
 
 	var token *AccessTokenResponse
 	verified := false
+	var recoveryCodes []string
 	err = db.Transaction(func(tx *storage.Connection) error {
 		var terr error
 		if terr = models.NewAuditLogEntry(config.AuditLog, r, tx, user, models.VerifyFactorAction, utilities.GetIPAddress(r), map[string]interface{}{
@@ -705,6 +893,9 @@ func (a *API) verifyTOTPFactor(w http.ResponseWriter, r *http.Request, params *V
 				return terr
 			}
 			verified = true
+			if recoveryCodes, terr = a.issueRecoveryCodes(r, tx, user); terr != nil {
+				return terr
+			}
 		}
 		if shouldReEncrypt && config.Security.DBEncryption.Encrypt {
 			es, terr := crypto.NewEncryptedString(factor.ID.String(), []byte(secret), config.Security.DBEncryption.EncryptionKeyID, config.Security.DBEncryption.EncryptionKey)
@@ -728,6 +919,9 @@ func (a *API) verifyTOTPFactor(w http.ResponseWriter, r *http.Request, params *V
 		if terr != nil {
 			return terr
 		}
+		if terr = a.rememberDevice(r, tx, user, factor, params, token); terr != nil {
+			return terr
+		}
 		if terr = models.InvalidateSessionsWithAALLessThan(tx, user.ID, models.AAL2.String()); terr != nil {
 			return apierrors.NewInternalServerError("Failed to update sessions. %s", terr)
 		}
@@ -752,7 +946,8 @@ func (a *API) verifyTOTPFactor(w http.ResponseWriter, r *http.Request, params *V
 		Provider: metering.ProviderMFATOTP,
 	})
 
-	return sendJSON(w, http.StatusOK, token)
+	token.RecoveryCodes = recoveryCodes
+	return a.sendTokenResponse(w, r, token)
 
 }
 
@@ -787,7 +982,7 @@ func (a *API) verifyPhoneFactor(w http.ResponseWriter, r *http.Request, params *
 		if err != nil {
 			return apierrors.NewInternalServerError("Failed to get SMS provider").WithInternalError(err)
 		}
-		if err := smsProvider.VerifyOTP(factor.Phone.String(), params.Code); err != nil {
+		if err := verifySMSOTP(ctx, config, smsProvider, factor.Phone.String(), params.Code); err != nil {
 			return apierrors.NewForbiddenError(apierrors.ErrorCodeOTPExpired, "Token has expired or is invalid").WithInternalError(err)
 		}
 		valid = true
@@ -798,31 +993,8 @@ func (a *API) verifyPhoneFactor(w http.ResponseWriter, r *http.Request, params *
 		}
 		valid = subtle.ConstantTimeCompare([]byte(otpCode), []byte(params.Code)) == 1
 	}
-	if config.Hook.MFAVerificationAttempt.Enabled {
-		input := v0hooks.MFAVerificationAttemptInput{
-			UserID:     user.ID,
-			FactorID:   factor.ID,
-			FactorType: factor.FactorType,
-			Valid:      valid,
-		}
-
-		output := v0hooks.MFAVerificationAttemptOutput{}
-		err := a.hooksMgr.InvokeHook(nil, r, &input, &output)
-		if err != nil {
-			return err
-		}
-
-		if output.Decision == v0hooks.HookRejection {
-			if err := models.Logout(db, user.ID); err != nil {
-				return err
-			}
-
-			if output.Message == "" {
-				output.Message = v0hooks.DefaultMFAHookRejectionMessage
-			}
-
-			return apierrors.NewForbiddenError(apierrors.ErrorCodeMFAVerificationRejected, "%s", output.Message)
-		}
+	if err := a.invokeMFAVerificationAttempt(r, db, user, factor, valid); err != nil {
+		return err
 	}
 	if !valid {
 		if shouldReEncrypt && config.Security.DBEncryption.Encrypt {
@@ -834,11 +1006,13 @@ func (a *API) verifyPhoneFactor(w http.ResponseWriter, r *http.Request, params *
 				return err
 			}
 		}
+		metering.RecordLoginFailure(metering.LoginTypeMFA, metering.ProviderMFAPhone)
 		return apierrors.NewUnprocessableEntityError(apierrors.ErrorCodeMFAVerificationFailed, "Invalid MFA Phone code entered")
 	}
 
 	var token *AccessTokenResponse
 	verified := false
+	var recoveryCodes []string
 	err = db.Transaction(func(tx *storage.Connection) error {
 		var terr error
 		if terr = models.NewAuditLogEntry(config.AuditLog, r, tx, user, models.VerifyFactorAction, utilities.GetIPAddress(r), map[string]interface{}{
@@ -856,6 +1030,9 @@ func (a *API) verifyPhoneFactor(w http.ResponseWriter, r *http.Request, params *
 				return terr
 			}
 			verified = true
+			if recoveryCodes, terr = a.issueRecoveryCodes(r, tx, user); terr != nil {
+				return terr
+			}
 		}
 		user, terr = models.FindUserByID(tx, user.ID)
 		if terr != nil {
@@ -868,6 +1045,9 @@ func (a *API) verifyPhoneFactor(w http.ResponseWriter, r *http.Request, params *
 		if terr != nil {
 			return terr
 		}
+		if terr = a.rememberDevice(r, tx, user, factor, params, token); terr != nil {
+			return terr
+		}
 		if terr = models.InvalidateSessionsWithAALLessThan(tx, user.ID, models.AAL2.String()); terr != nil {
 			return apierrors.NewInternalServerError("Failed to update sessions. %s", terr)
 		}
@@ -892,7 +1072,8 @@ func (a *API) verifyPhoneFactor(w http.ResponseWriter, r *http.Request, params *
 		Provider: metering.ProviderMFAPhone,
 	})
 
-	return sendJSON(w, http.StatusOK, token)
+	token.RecoveryCodes = recoveryCodes
+	return a.sendTokenResponse(w, r, token)
 }
 
 func (a *API) verifyWebAuthnFactor(w http.ResponseWriter, r *http.Request, params *VerifyFactorParams) error {
@@ -914,9 +1095,9 @@ func (a *API) verifyWebAuthnFactor(w http.ResponseWriter, r *http.Request, param
 	case params.WebAuthn.CredentialResponse == nil:
 		return apierrors.NewBadRequestError(apierrors.ErrorCodeValidationFailed, "credential_response required")
 	default:
-		webAuthn, err = params.WebAuthn.ToConfig()
+		webAuthn, err = params.WebAuthn.ToConfig(&config.MFA.WebAuthn)
 		if err != nil {
-			return err
+			return apierrors.NewBadRequestError(apierrors.ErrorCodeValidationFailed, "%s", err.Error())
 		}
 	}
 
@@ -944,7 +1125,11 @@ func (a *API) verifyWebAuthnFactor(w http.ResponseWriter, r *http.Request, param
 			return apierrors.NewBadRequestError(apierrors.ErrorCodeValidationFailed, "Invalid credential_response")
 		}
 		credential, err = webAuthn.ValidateLogin(user, webAuthnSession, parsedResponse.(*wbnprotocol.ParsedCredentialAssertionData))
+		if herr := a.invokeMFAVerificationAttempt(r, db, user, factor, err == nil); herr != nil {
+			return herr
+		}
 		if err != nil {
+			metering.RecordLoginFailure(metering.LoginTypeMFA, metering.ProviderMFAWebAuthn)
 			return apierrors.NewInternalServerError("Failed to validate WebAuthn MFA response").WithInternalError(err)
 		}
 	}
@@ -955,6 +1140,7 @@ func (a *API) verifyWebAuthnFactor(w http.ResponseWriter, r *http.Request, param
 	}
 	var token *AccessTokenResponse
 	verified := false
+	var recoveryCodes []string
 	err = db.Transaction(func(tx *storage.Connection) error {
 		var terr error
 		if terr = models.NewAuditLogEntry(config.AuditLog, r, tx, user, models.VerifyFactorAction, utilities.GetIPAddress(r), map[string]interface{}{
@@ -973,6 +1159,9 @@ func (a *API) verifyWebAuthnFactor(w http.ResponseWriter, r *http.Request, param
 				return terr
 			}
 			verified = true
+			if recoveryCodes, terr = a.issueRecoveryCodes(r, tx, user); terr != nil {
+				return terr
+			}
 		}
 
 		if terr = factor.UpdateLastWebAuthnChallenge(tx, challenge, params.WebAuthn.Type, parsedResponse); terr != nil {
@@ -988,6 +1177,9 @@ func (a *API) verifyWebAuthnFactor(w http.ResponseWriter, r *http.Request, param
 		if terr != nil {
 			return terr
 		}
+		if terr = a.rememberDevice(r, tx, user, factor, params, token); terr != nil {
+			return terr
+		}
 		if terr = models.InvalidateSessionsWithAALLessThan(tx, user.ID, models.AAL2.String()); terr != nil {
 			return apierrors.NewInternalServerError("Failed to update session").WithInternalError(terr)
 		}
@@ -1012,7 +1204,8 @@ func (a *API) verifyWebAuthnFactor(w http.ResponseWriter, r *http.Request, param
 		Provider: metering.ProviderMFAWebAuthn,
 	})
 
-	return sendJSON(w, http.StatusOK, token)
+	token.RecoveryCodes = recoveryCodes
+	return a.sendTokenResponse(w, r, token)
 }
 
 func (a *API) VerifyFactor(w http.ResponseWriter, r *http.Request) error {
@@ -1024,6 +1217,9 @@ func (a *API) VerifyFactor(w http.ResponseWriter, r *http.Request) error {
 	if err := retrieveRequestParams(r, params); err != nil {
 		return err
 	}
+	if params.RecoveryCode != "" {
+		return a.verifyRecoveryCode(w, r, params)
+	}
 	if params.Code == "" && factor.FactorType != models.WebAuthn {
 		return apierrors.NewBadRequestError(apierrors.ErrorCodeValidationFailed, "Code needs to be non-empty")
 	}
@@ -0,0 +1,200 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/supabase/auth/internal/api/apierrors"
+	"github.com/supabase/auth/internal/metering"
+	"github.com/supabase/auth/internal/models"
+	"github.com/supabase/auth/internal/storage"
+	"github.com/supabase/auth/internal/utilities"
+)
+
+// RecoveryCodesResponse is the number of recovery codes of a user. The
+// codes themselves are only included right after they are generated.
+type RecoveryCodesResponse struct {
+	Total         int      `json:"total"`
+	Remaining     int      `json:"remaining"`
+	RecoveryCodes []string `json:"recovery_codes,omitempty"`
+}
+
+// issueRecoveryCodes generates the recovery codes of a user verifying a
+// factor who has none yet. It returns no codes when the user already has
+// some, as they are only shown once.
+func (a *API) issueRecoveryCodes(r *http.Request, tx *storage.Connection, user *models.User) ([]string, error) {
+	config := a.config
+	if !config.MFA.RecoveryCodes.Enabled {
+		return nil, nil
+	}
+
+	total, _, err := models.CountRecoveryCodes(tx, user.ID)
+	if err != nil {
+		return nil, apierrors.NewInternalServerError("Database error counting recovery codes").WithInternalError(err)
+	}
+	if total > 0 {
+		return nil, nil
+	}
+
+	return a.generateRecoveryCodes(r, tx, user)
+}
+
+func (a *API) generateRecoveryCodes(r *http.Request, tx *storage.Connection, user *models.User) ([]string, error) {
+	config := a.config
+
+	codes, err := models.GenerateRecoveryCodes(tx, user, config.MFA.RecoveryCodes.Count)
+	if err != nil {
+		return nil, apierrors.NewInternalServerError("Database error generating recovery codes").WithInternalError(err)
+	}
+	if err := models.NewAuditLogEntry(config.AuditLog, r, tx, user, models.GenerateRecoveryCodesAction, utilities.GetIPAddress(r), map[string]interface{}{
+		"count": len(codes),
+	}); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// GetRecoveryCodes returns how many of the recovery codes of the user are
+// left.
+func (a *API) GetRecoveryCodes(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	user := getUser(ctx)
+	db := a.db.WithContext(ctx)
+
+	if !a.config.MFA.RecoveryCodes.Enabled {
+		return apierrors.NewUnprocessableEntityError(apierrors.ErrorCodeMFARecoveryCodesDisabled, "MFA recovery codes are disabled")
+	}
+
+	total, remaining, err := models.CountRecoveryCodes(db, user.ID)
+	if err != nil {
+		return apierrors.NewInternalServerError("Database error counting recovery codes").WithInternalError(err)
+	}
+
+	return sendJSON(w, http.StatusOK, &RecoveryCodesResponse{
+		Total:     total,
+		Remaining: remaining,
+	})
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes of the user with new
+// ones. Users with verified factors need an AAL2 session, so that a stolen
+// password is not enough to get codes bypassing MFA.
+func (a *API) RegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	user := getUser(ctx)
+	session := getSession(ctx)
+	db := a.db.WithContext(ctx)
+
+	if !a.config.MFA.RecoveryCodes.Enabled {
+		return apierrors.NewUnprocessableEntityError(apierrors.ErrorCodeMFARecoveryCodesDisabled, "MFA recovery codes are disabled")
+	}
+
+	if session == nil {
+		return apierrors.NewInternalServerError("A valid session is required to generate recovery codes")
+	}
+
+	for _, factor := range user.Factors {
+		if factor.IsVerified() && !session.IsAAL2() {
+			return apierrors.NewUnprocessableEntityError(apierrors.ErrorCodeInsufficientAAL, "AAL2 required to generate recovery codes")
+		}
+	}
+
+	var codes []string
+	err := db.Transaction(func(tx *storage.Connection) error {
+		var terr error
+		codes, terr = a.generateRecoveryCodes(r, tx, user)
+		return terr
+	})
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	return sendJSON(w, http.StatusOK, &RecoveryCodesResponse{
+		Total:         len(codes),
+		Remaining:     len(codes),
+		RecoveryCodes: codes,
+	})
+}
+
+// verifyRecoveryCode verifies a verified factor with a recovery code in
+// place of its authenticator. The code can't be used again.
+func (a *API) verifyRecoveryCode(w http.ResponseWriter, r *http.Request, params *VerifyFactorParams) error {
+	ctx := r.Context()
+	config := a.config
+	user := getUser(ctx)
+	factor := getFactor(ctx)
+	db := a.db.WithContext(ctx)
+
+	if !config.MFA.RecoveryCodes.Enabled {
+		return apierrors.NewUnprocessableEntityError(apierrors.ErrorCodeMFARecoveryCodesDisabled, "MFA recovery codes are disabled")
+	}
+
+	if !factor.IsVerified() {
+		return apierrors.NewBadRequestError(apierrors.ErrorCodeValidationFailed, "Recovery codes can only be used to verify a verified factor")
+	}
+
+	valid, err := models.IsUnusedRecoveryCode(db, user.ID, params.RecoveryCode)
+	if err != nil {
+		return apierrors.NewInternalServerError("Database error finding recovery code").WithInternalError(err)
+	}
+	if err := a.invokeMFAVerificationAttempt(r, db, user, factor, valid); err != nil {
+		return err
+	}
+	if !valid {
+		metering.RecordLoginFailure(metering.LoginTypeMFA, metering.ProviderMFARecoveryCode)
+		return apierrors.NewUnprocessableEntityError(apierrors.ErrorCodeMFAVerificationFailed, "Invalid recovery code entered")
+	}
+
+	var token *AccessTokenResponse
+	invalidCode := false
+	err = db.Transaction(func(tx *storage.Connection) error {
+		// the code may have been used concurrently since it was found
+		if terr := models.UseRecoveryCode(tx, user.ID, params.RecoveryCode); terr != nil {
+			if models.IsNotFoundError(terr) {
+				invalidCode = true
+				return apierrors.NewUnprocessableEntityError(apierrors.ErrorCodeMFAVerificationFailed, "Invalid recovery code entered")
+			}
+			return apierrors.NewInternalServerError("Database error using recovery code").WithInternalError(terr)
+		}
+
+		if terr := models.NewAuditLogEntry(config.AuditLog, r, tx, user, models.VerifyFactorAction, utilities.GetIPAddress(r), map[string]interface{}{
+			"factor_id":     factor.ID,
+			"factor_type":   factor.FactorType,
+			"recovery_code": true,
+		}); terr != nil {
+			return terr
+		}
+
+		var terr error
+		user, terr = models.FindUserByID(tx, user.ID)
+		if terr != nil {
+			return terr
+		}
+
+		token, terr = a.updateMFASessionAndClaims(r, tx, user, models.MFARecoveryCode, models.GrantParams{
+			FactorID: &factor.ID,
+		})
+		if terr != nil {
+			return terr
+		}
+		if terr = a.rememberDevice(r, tx, user, factor, params, token); terr != nil {
+			return terr
+		}
+		if terr = models.InvalidateSessionsWithAALLessThan(tx, user.ID, models.AAL2.String()); terr != nil {
+			return apierrors.NewInternalServerError("Failed to update sessions. %s", terr)
+		}
+		return nil
+	})
+	if err != nil {
+		if invalidCode {
+			metering.RecordLoginFailure(metering.LoginTypeMFA, metering.ProviderMFARecoveryCode)
+		}
+		return err
+	}
+
+	metering.RecordLogin(metering.LoginTypeMFA, user.ID, &metering.LoginData{
+		Provider: metering.ProviderMFARecoveryCode,
+	})
+
+	return a.sendTokenResponse(w, r, token)
+}
@@ -410,6 +410,214 @@ func (ts *MFATestSuite) TestChallengeTOTPFactor() {
 
 }
 
+func (ts *MFATestSuite) TestStepUp() {
+	token := ts.generateAAL1Token(ts.TestUser, &ts.TestSession.ID)
+
+	// unverified factors cannot be used to step up
+	var buffer bytes.Buffer
+	w := ServeAuthenticatedRequest(ts, http.MethodPost, "http://localhost/factors/step_up", token, buffer)
+	require.Equal(ts.T(), http.StatusUnprocessableEntity, w.Code)
+
+	f := ts.TestUser.Factors[0]
+	require.NoError(ts.T(), f.UpdateStatus(ts.API.db, models.FactorStateVerified))
+
+	w = ServeAuthenticatedRequest(ts, http.MethodPost, "http://localhost/factors/step_up", token, buffer)
+	require.Equal(ts.T(), http.StatusOK, w.Code, w.Body.String())
+
+	challengeResp := ChallengeFactorResponse{}
+	require.NoError(ts.T(), json.NewDecoder(w.Body).Decode(&challengeResp))
+	require.Equal(ts.T(), f.ID, challengeResp.FactorID)
+	require.Equal(ts.T(), models.TOTP, challengeResp.Type)
+
+	performVerifyFlow(ts, challengeResp.ID, challengeResp.FactorID, token, true)
+
+	session, err := models.FindSessionByID(ts.API.db, ts.TestSession.ID, false)
+	require.NoError(ts.T(), err)
+	require.True(ts.T(), session.IsAAL2())
+
+	// a factor can be chosen, it has to be one of the verified factors
+	require.NoError(ts.T(), json.NewEncoder(&buffer).Encode(map[string]interface{}{
+		"factor_id": uuid.Must(uuid.NewV4()),
+	}))
+	w = ServeAuthenticatedRequest(ts, http.MethodPost, "http://localhost/factors/step_up", token, buffer)
+	require.Equal(ts.T(), http.StatusUnprocessableEntity, w.Code)
+}
+
+func (ts *MFATestSuite) TestRecoveryCodes() {
+	ts.Config.MFA.RecoveryCodes.Enabled = true
+	ts.Config.MFA.RecoveryCodes.Count = 3
+	defer func() {
+		ts.Config.MFA.RecoveryCodes.Enabled = false
+		ts.Config.Hook.MFAVerificationAttempt.Enabled = false
+	}()
+
+	token := ts.generateAAL1Token(ts.TestUser, &ts.TestSession.ID)
+
+	enroll := func(token, friendlyName string) uuid.UUID {
+		w := performEnrollFlow(ts, token, friendlyName, models.TOTP, ts.TestDomain, "", http.StatusOK)
+		require.NotContains(ts.T(), w.Body.String(), "recovery_codes")
+		enrollResp := EnrollFactorResponse{}
+		require.NoError(ts.T(), json.NewDecoder(w.Body).Decode(&enrollResp))
+		return enrollResp.ID
+	}
+
+	verify := func(token string, factorID uuid.UUID) *AccessTokenResponse {
+		w := performChallengeFlow(ts, factorID, token)
+		challengeResp := ChallengeFactorResponse{}
+		require.NoError(ts.T(), json.NewDecoder(w.Body).Decode(&challengeResp))
+
+		w = performVerifyFlow(ts, challengeResp.ID, factorID, token, true)
+		tokenResp := &AccessTokenResponse{}
+		require.NoError(ts.T(), json.NewDecoder(w.Body).Decode(tokenResp))
+		return tokenResp
+	}
+
+	verifyWithRecoveryCode := func(token string, factorID uuid.UUID, code string) *httptest.ResponseRecorder {
+		var buffer bytes.Buffer
+		require.NoError(ts.T(), json.NewEncoder(&buffer).Encode(VerifyFactorParams{RecoveryCode: code}))
+		return ServeAuthenticatedRequest(ts, http.MethodPost, "http://localhost/factors/"+factorID.String()+"/verify", token, buffer)
+	}
+
+	// codes are not issued when a factor is enrolled
+	factorID := enroll(token, "first")
+
+	// recovery codes only verify verified factors
+	w := verifyWithRecoveryCode(token, factorID, "abcde-fghij")
+	require.Equal(ts.T(), http.StatusBadRequest, w.Code)
+
+	// codes are issued when the first factor is verified only
+	tokenResp := verify(token, factorID)
+	require.Len(ts.T(), tokenResp.RecoveryCodes, 3)
+	codes := tokenResp.RecoveryCodes
+
+	tokenResp = verify(tokenResp.Token, enroll(tokenResp.Token, "second"))
+	require.Empty(ts.T(), tokenResp.RecoveryCodes)
+
+	// regenerating codes requires AAL2 once a factor is verified
+	session, err := models.NewSession(ts.TestUser.ID, nil)
+	require.NoError(ts.T(), err)
+	require.NoError(ts.T(), ts.API.db.Create(session))
+	token = ts.generateAAL1Token(ts.TestUser, &session.ID)
+
+	var buffer bytes.Buffer
+	w = ServeAuthenticatedRequest(ts, http.MethodPost, "http://localhost/factors/recovery_codes", token, buffer)
+	require.Equal(ts.T(), http.StatusUnprocessableEntity, w.Code)
+
+	// recovery codes go through the MFA verification attempt hook, and a
+	// rejected code is not used up
+	require.NoError(ts.T(), ts.API.db.RawQuery(`
+        create or replace function recovery_code_verification_hook(input jsonb)
+        returns json as $$
+        begin
+            if (input->>'valid')::boolean then
+                return json_build_object('decision', 'require_additional_verification');
+            end if;
+            return json_build_object('decision', 'continue');
+        end; $$ language plpgsql;`).Exec())
+	defer cleanupHook(ts, "recovery_code_verification_hook(input jsonb)")
+	ts.Config.Hook.MFAVerificationAttempt.Enabled = true
+	ts.Config.Hook.MFAVerificationAttempt.URI = "pg-functions://postgres/auth/recovery_code_verification_hook"
+	require.NoError(ts.T(), ts.Config.Hook.MFAVerificationAttempt.PopulateExtensibilityPoint())
+
+	w = verifyWithRecoveryCode(token, factorID, codes[0])
+	require.Equal(ts.T(), http.StatusForbidden, w.Code, w.Body.String())
+
+	ts.Config.Hook.MFAVerificationAttempt.Enabled = false
+
+	w = verifyWithRecoveryCode(token, factorID, strings.ToUpper(codes[0]))
+	require.Equal(ts.T(), http.StatusOK, w.Code, w.Body.String())
+
+	session, err = models.FindSessionByID(ts.API.db, session.ID, false)
+	require.NoError(ts.T(), err)
+	require.True(ts.T(), session.IsAAL2())
+
+	// a code can only be used once
+	w = verifyWithRecoveryCode(token, factorID, codes[0])
+	require.Equal(ts.T(), http.StatusUnprocessableEntity, w.Code)
+
+	w = ServeAuthenticatedRequest(ts, http.MethodGet, "http://localhost/factors/recovery_codes", token, buffer)
+	require.Equal(ts.T(), http.StatusOK, w.Code)
+	countResp := RecoveryCodesResponse{}
+	require.NoError(ts.T(), json.NewDecoder(w.Body).Decode(&countResp))
+	require.Equal(ts.T(), 3, countResp.Total)
+	require.Equal(ts.T(), 2, countResp.Remaining)
+	require.Empty(ts.T(), countResp.RecoveryCodes)
+
+	w = ServeAuthenticatedRequest(ts, http.MethodPost, "http://localhost/factors/recovery_codes", token, buffer)
+	require.Equal(ts.T(), http.StatusOK, w.Code, w.Body.String())
+	countResp = RecoveryCodesResponse{}
+	require.NoError(ts.T(), json.NewDecoder(w.Body).Decode(&countResp))
+	require.Len(ts.T(), countResp.RecoveryCodes, 3)
+	require.Equal(ts.T(), 3, countResp.Remaining)
+	require.NotContains(ts.T(), countResp.RecoveryCodes, codes[1])
+}
+
+func (ts *MFATestSuite) TestTrustedDevices() {
+	ts.Config.MFA.TrustedDevices.Enabled = true
+	defer func() {
+		ts.Config.MFA.TrustedDevices.Enabled = false
+	}()
+
+	f := ts.TestUser.Factors[0]
+	require.NoError(ts.T(), f.UpdateStatus(ts.API.db, models.FactorStateVerified))
+	token := ts.generateAAL1Token(ts.TestUser, &ts.TestSession.ID)
+
+	w := performChallengeFlow(ts, f.ID, token)
+	challengeResp := ChallengeFactorResponse{}
+	require.NoError(ts.T(), json.NewDecoder(w.Body).Decode(&challengeResp))
+
+	code, err := totp.GenerateCode("secretkey", time.Now().UTC())
+	require.NoError(ts.T(), err)
+	var buffer bytes.Buffer
+	require.NoError(ts.T(), json.NewEncoder(&buffer).Encode(VerifyFactorParams{
+		ChallengeID:    challengeResp.ID,
+		Code:           code,
+		RememberDevice: true,
+	}))
+	w = ServeAuthenticatedRequest(ts, http.MethodPost, fmt.Sprintf("/factors/%s/verify", f.ID), token, buffer)
+	require.Equal(ts.T(), http.StatusOK, w.Code, w.Body.String())
+	verifyResp := AccessTokenResponse{}
+	require.NoError(ts.T(), json.NewDecoder(w.Body).Decode(&verifyResp))
+	require.NotEmpty(ts.T(), verifyResp.DeviceToken)
+
+	// a new session on the device skips the challenge
+	session, err := models.NewSession(ts.TestUser.ID, nil)
+	require.NoError(ts.T(), err)
+	require.NoError(ts.T(), ts.API.db.Create(session))
+	token = ts.generateAAL1Token(ts.TestUser, &session.ID)
+
+	verifyDevice := func(deviceToken string) *httptest.ResponseRecorder {
+		var buffer bytes.Buffer
+		require.NoError(ts.T(), json.NewEncoder(&buffer).Encode(TrustedDeviceParams{DeviceToken: deviceToken}))
+		return ServeAuthenticatedRequest(ts, http.MethodPost, "/factors/trusted_devices/verify", token, buffer)
+	}
+
+	w = verifyDevice("invalid")
+	require.Equal(ts.T(), http.StatusUnprocessableEntity, w.Code)
+
+	w = verifyDevice(verifyResp.DeviceToken)
+	require.Equal(ts.T(), http.StatusOK, w.Code, w.Body.String())
+
+	session, err = models.FindSessionByID(ts.API.db, session.ID, false)
+	require.NoError(ts.T(), err)
+	require.True(ts.T(), session.IsAAL2())
+
+	w = ServeAuthenticatedRequest(ts, http.MethodGet, "/factors/trusted_devices", token, buffer)
+	require.Equal(ts.T(), http.StatusOK, w.Code)
+	devicesResp := TrustedDevicesResponse{}
+	require.NoError(ts.T(), json.NewDecoder(w.Body).Decode(&devicesResp))
+	require.Len(ts.T(), devicesResp.Devices, 1)
+	require.Equal(ts.T(), f.ID, devicesResp.Devices[0].FactorID)
+	require.NotNil(ts.T(), devicesResp.Devices[0].LastUsedAt)
+
+	// revoked devices need to verify MFA again
+	w = ServeAuthenticatedRequest(ts, http.MethodDelete, "/factors/trusted_devices/"+devicesResp.Devices[0].ID.String(), token, buffer)
+	require.Equal(ts.T(), http.StatusNoContent, w.Code)
+
+	w = verifyDevice(verifyResp.DeviceToken)
+	require.Equal(ts.T(), http.StatusUnprocessableEntity, w.Code)
+}
+
 func (ts *MFATestSuite) TestChallengeSMSFactor() {
 	// Challenge should still work with phone provider disabled
 	ts.Config.External.Phone.Enabled = false
@@ -461,6 +669,46 @@ func (ts *MFATestSuite) TestChallengeSMSFactor() {
 	}
 }
 
+func (ts *MFATestSuite) TestPhoneFactorChallengeLimit() {
+	ts.Config.MFA.Phone.MaxFrequency = 0 * time.Second
+	ts.Config.MFA.Phone.MaxChallengesPerHour = 2
+	defer func() {
+		ts.Config.MFA.Phone.MaxChallengesPerHour = 10
+	}()
+
+	f := models.NewPhoneFactor(ts.TestUser, "+1234567", "testphonefactorlimit")
+	require.NoError(ts.T(), ts.API.db.Create(f), "Error creating new SMS factor")
+	for i := 0; i < 2; i++ {
+		require.NoError(ts.T(), f.WriteChallengeToDatabase(ts.API.db, f.CreateChallenge("127.0.0.1")))
+	}
+	token := ts.generateAAL1Token(ts.TestUser, &ts.TestSession.ID)
+
+	var buffer bytes.Buffer
+	require.NoError(ts.T(), json.NewEncoder(&buffer).Encode(ChallengeFactorParams{Channel: sms_provider.SMSProvider}))
+	w := ServeAuthenticatedRequest(ts, http.MethodPost, fmt.Sprintf("http://localhost/factors/%s/challenge", f.ID), token, buffer)
+	require.Equal(ts.T(), http.StatusTooManyRequests, w.Code, w.Body.String())
+}
+
+func (ts *MFATestSuite) TestEnrollVerifiedPhoneByDefault() {
+	token := ts.generateAAL1Token(ts.TestUser, &ts.TestSession.ID)
+
+	// a phone number is needed when the user has no verified phone
+	performEnrollFlow(ts, token, "", models.Phone, "", "", http.StatusBadRequest)
+
+	ts.TestUser.Phone = "123456789"
+	require.NoError(ts.T(), ts.API.db.UpdateOnly(ts.TestUser, "phone"))
+	require.NoError(ts.T(), ts.TestUser.ConfirmPhone(ts.API.db))
+
+	w := performEnrollFlow(ts, token, "", models.Phone, "", "", http.StatusOK)
+	enrollResp := EnrollFactorResponse{}
+	require.NoError(ts.T(), json.NewDecoder(w.Body).Decode(&enrollResp))
+	require.Equal(ts.T(), "123456789", enrollResp.Phone)
+
+	factor, err := models.FindFactorByFactorID(ts.API.db, enrollResp.ID)
+	require.NoError(ts.T(), err)
+	require.Equal(ts.T(), "123456789", factor.Phone.String())
+}
+
 func (ts *MFATestSuite) TestMFAVerifyFactor() {
 	cases := []struct {
 		desc             string
@@ -963,6 +1211,26 @@ func (ts *MFATestSuite) TestVerificationHooks() {
 			expectedCode:        http.StatusOK,
 			cleanupHookFunction: "verification_hook_reject(input jsonb)",
 		},
+		{
+			desc:    "Require Additional Verification",
+			enabled: true,
+			uri:     "pg-functions://postgres/auth/verification_hook_additional",
+			hookFunctionSQL: `
+        create or replace function verification_hook_additional(input jsonb)
+        returns json as $$
+        begin
+            if input->>'ip_address' is null then
+                return json_build_object('decision', 'continue');
+            end if;
+            return json_build_object(
+                'decision', 'require_additional_verification'
+            );
+        end; $$ language plpgsql;`,
+			emailSuffix:         "additional",
+			expectToken:         false,
+			expectedCode:        http.StatusForbidden,
+			cleanupHookFunction: "verification_hook_additional(input jsonb)",
+		},
 		{
 			desc:    "Timeout",
 			enabled: true,
@@ -1109,3 +1377,70 @@ func (ts *MFATestSuite) TestMFAFactorUnenrolledNotificationDisabled() {
 	// Assert that MFA factor unenrolled notification email was sent or not based on the config
 	require.Len(ts.T(), mockMailer.MFAFactorUnenrolledMailCalls, 0, "Expected 0 MFA factor unenrolled notification email(s) to be sent")
 }
+
+func TestWebAuthnParamsToConfig(t *testing.T) {
+	cases := []struct {
+		desc      string
+		params    WebAuthnParams
+		config    conf.WebAuthnFactorTypeConfiguration
+		rpID      string
+		rpOrigins []string
+		err       string
+	}{
+		{
+			desc:      "client values without server configuration",
+			params:    WebAuthnParams{RPID: "localhost", RPOrigins: []string{"http://localhost:3000"}},
+			rpID:      "localhost",
+			rpOrigins: []string{"http://localhost:3000"},
+		},
+		{
+			desc:   "missing client values without server configuration",
+			params: WebAuthnParams{},
+			err:    "webAuthn RP ID cannot be empty",
+		},
+		{
+			desc:   "insecure origin",
+			params: WebAuthnParams{RPID: "example.com", RPOrigins: []string{"http://example.com"}},
+			err:    "invalid RP origins: http://example.com",
+		},
+		{
+			desc:      "server configuration used when client omits values",
+			params:    WebAuthnParams{},
+			config:    conf.WebAuthnFactorTypeConfiguration{RPID: "example.com", RPOrigins: []string{"https://example.com"}},
+			rpID:      "example.com",
+			rpOrigins: []string{"https://example.com"},
+		},
+		{
+			desc:      "client values matching server configuration",
+			params:    WebAuthnParams{RPID: "example.com", RPOrigins: []string{"https://app.example.com"}},
+			config:    conf.WebAuthnFactorTypeConfiguration{RPID: "example.com", RPOrigins: []string{"https://example.com", "https://app.example.com"}},
+			rpID:      "example.com",
+			rpOrigins: []string{"https://app.example.com"},
+		},
+		{
+			desc:   "client RP ID not matching server configuration",
+			params: WebAuthnParams{RPID: "attacker.com"},
+			config: conf.WebAuthnFactorTypeConfiguration{RPID: "example.com", RPOrigins: []string{"https://example.com"}},
+			err:    `webAuthn RP ID "attacker.com" is not allowed`,
+		},
+		{
+			desc:   "client RP origin not matching server configuration",
+			params: WebAuthnParams{RPOrigins: []string{"https://attacker.com"}},
+			config: conf.WebAuthnFactorTypeConfiguration{RPID: "example.com", RPOrigins: []string{"https://example.com"}},
+			err:    `webAuthn RP origin "https://attacker.com" is not allowed`,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.desc, func(t *testing.T) {
+			webAuthn, err := c.params.ToConfig(&c.config)
+			if c.err != "" {
+				require.EqualError(t, err, c.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, c.rpID, webAuthn.Config.RPID)
+			require.Equal(t, c.rpOrigins, webAuthn.Config.RPOrigins)
+		})
+	}
+}
@@ -0,0 +1,174 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/gofrs/uuid"
+	"github.com/supabase/auth/internal/api/apierrors"
+	"github.com/supabase/auth/internal/models"
+	"github.com/supabase/auth/internal/storage"
+	"github.com/supabase/auth/internal/utilities"
+)
+
+// TrustedDeviceParams is the device token a device remembered after MFA
+// verification presents to skip MFA challenges.
+type TrustedDeviceParams struct {
+	DeviceToken string `json:"device_token"`
+}
+
+// TrustedDevicesResponse lists the devices the user trusts.
+type TrustedDevicesResponse struct {
+	Devices []*models.TrustedDevice `json:"devices"`
+}
+
+// rememberDevice trusts the device the factor was verified on when the
+// user asked for it, adding its device token to the token response.
+func (a *API) rememberDevice(r *http.Request, tx *storage.Connection, user *models.User, factor *models.Factor, params *VerifyFactorParams, token *AccessTokenResponse) error {
+	config := a.config
+	if !params.RememberDevice || !config.MFA.TrustedDevices.Enabled {
+		return nil
+	}
+
+	ipAddress := utilities.GetIPAddress(r)
+	device, deviceToken, err := models.NewTrustedDevice(tx, user.ID, factor.ID, r.UserAgent(), ipAddress, config.MFA.TrustedDevices.Duration)
+	if err != nil {
+		return apierrors.NewInternalServerError("Database error creating trusted device").WithInternalError(err)
+	}
+	if err := models.NewAuditLogEntry(config.AuditLog, r, tx, user, models.TrustedDeviceAddedAction, ipAddress, map[string]interface{}{
+		"factor_id": factor.ID,
+		"device_id": device.ID,
+	}); err != nil {
+		return err
+	}
+
+	token.DeviceToken = deviceToken
+	return nil
+}
+
+// VerifyTrustedDevice elevates the session to AAL2 with the device token of
+// a trusted device in place of an MFA challenge.
+func (a *API) VerifyTrustedDevice(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	config := a.config
+	user := getUser(ctx)
+	db := a.db.WithContext(ctx)
+
+	if !config.MFA.TrustedDevices.Enabled {
+		return apierrors.NewUnprocessableEntityError(apierrors.ErrorCodeMFATrustedDevicesDisabled, "MFA trusted devices are disabled")
+	}
+
+	params := &TrustedDeviceParams{}
+	if err := retrieveRequestParams(r, params); err != nil {
+		return err
+	}
+	if params.DeviceToken == "" {
+		return apierrors.NewBadRequestError(apierrors.ErrorCodeValidationFailed, "device_token needs to be non-empty")
+	}
+
+	var token *AccessTokenResponse
+	err := db.Transaction(func(tx *storage.Connection) error {
+		device, terr := models.FindTrustedDeviceByToken(tx, user.ID, params.DeviceToken)
+		if terr != nil {
+			if models.IsNotFoundError(terr) {
+				return apierrors.NewUnprocessableEntityError(apierrors.ErrorCodeMFAVerificationFailed, "Invalid device token")
+			}
+			return apierrors.NewInternalServerError("Database error finding trusted device").WithInternalError(terr)
+		}
+		if device.IsExpired(time.Now()) {
+			return apierrors.NewUnprocessableEntityError(apierrors.ErrorCodeMFAVerificationFailed, "Device token has expired")
+		}
+
+		factor, terr := user.FindOwnedFactorByID(tx, device.FactorID)
+		if terr != nil {
+			return apierrors.NewInternalServerError("Database error finding factor").WithInternalError(terr)
+		}
+		if !factor.IsVerified() || !isFactorVerifyEnabled(config, factor) {
+			return apierrors.NewUnprocessableEntityError(apierrors.ErrorCodeMFAVerificationFailed, "The factor the device was trusted with can no longer be verified")
+		}
+
+		if terr := device.UpdateLastUsedAt(tx); terr != nil {
+			return apierrors.NewInternalServerError("Database error updating trusted device").WithInternalError(terr)
+		}
+		if terr := models.NewAuditLogEntry(config.AuditLog, r, tx, user, models.VerifyFactorAction, utilities.GetIPAddress(r), map[string]interface{}{
+			"factor_id":   factor.ID,
+			"factor_type": factor.FactorType,
+			"device_id":   device.ID,
+		}); terr != nil {
+			return terr
+		}
+
+		token, terr = a.updateMFASessionAndClaims(r, tx, user, models.MFATrustedDevice, models.GrantParams{
+			FactorID: &factor.ID,
+		})
+		if terr != nil {
+			return terr
+		}
+		if terr = models.InvalidateSessionsWithAALLessThan(tx, user.ID, models.AAL2.String()); terr != nil {
+			return apierrors.NewInternalServerError("Failed to update sessions. %s", terr)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return a.sendTokenResponse(w, r, token)
+}
+
+// ListTrustedDevices lists the devices the user trusts.
+func (a *API) ListTrustedDevices(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	user := getUser(ctx)
+	db := a.db.WithContext(ctx)
+
+	if !a.config.MFA.TrustedDevices.Enabled {
+		return apierrors.NewUnprocessableEntityError(apierrors.ErrorCodeMFATrustedDevicesDisabled, "MFA trusted devices are disabled")
+	}
+
+	devices, err := models.FindTrustedDevicesByUserID(db, user.ID)
+	if err != nil {
+		return apierrors.NewInternalServerError("Database error finding trusted devices").WithInternalError(err)
+	}
+
+	return sendJSON(w, http.StatusOK, &TrustedDevicesResponse{
+		Devices: devices,
+	})
+}
+
+// DeleteTrustedDevice revokes a device the user trusts, which has to verify
+// MFA again.
+func (a *API) DeleteTrustedDevice(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	config := a.config
+	user := getUser(ctx)
+	db := a.db.WithContext(ctx)
+
+	deviceID, err := uuid.FromString(chi.URLParam(r, "device_id"))
+	if err != nil {
+		return apierrors.NewNotFoundError(apierrors.ErrorCodeValidationFailed, "device_id must be an UUID")
+	}
+
+	err = db.Transaction(func(tx *storage.Connection) error {
+		device, terr := models.FindTrustedDeviceByID(tx, user.ID, deviceID)
+		if terr != nil {
+			if models.IsNotFoundError(terr) {
+				return apierrors.NewNotFoundError(apierrors.ErrorCodeMFATrustedDeviceNotFound, "Trusted device not found")
+			}
+			return apierrors.NewInternalServerError("Database error finding trusted device").WithInternalError(terr)
+		}
+		if terr := tx.Destroy(device); terr != nil {
+			return apierrors.NewInternalServerError("Database error deleting trusted device").WithInternalError(terr)
+		}
+		return models.NewAuditLogEntry(config.AuditLog, r, tx, user, models.TrustedDeviceRevokedAction, utilities.GetIPAddress(r), map[string]interface{}{
+			"device_id": device.ID,
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
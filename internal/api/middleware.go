@@ -3,6 +3,7 @@ package api
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -18,10 +19,12 @@ import (
 	"github.com/supabase/auth/internal/api/apierrors"
 	"github.com/supabase/auth/internal/api/oauthserver"
 	"github.com/supabase/auth/internal/api/shared"
+	"github.com/supabase/auth/internal/conf"
 	"github.com/supabase/auth/internal/models"
 	"github.com/supabase/auth/internal/observability"
 	"github.com/supabase/auth/internal/sbff"
 	"github.com/supabase/auth/internal/security"
+	"github.com/supabase/auth/internal/storage"
 	"github.com/supabase/auth/internal/utilities"
 
 	"github.com/didip/tollbooth/v5"
@@ -131,6 +134,110 @@ func (a *API) limitHandler(lmt *limiter.Limiter) middlewareHandler {
 	}
 }
 
+// limitByRules enforces the sliding window rate limit rules configured for
+// the endpoint of the request. Rules keyed by email use the email in the JSON
+// request body and rules keyed by user use the subject of a valid access
+// token, the rule is skipped when the request does not contain either.
+func (a *API) limitByRules(w http.ResponseWriter, req *http.Request) (context.Context, error) {
+	ctx := req.Context()
+	config := a.config
+
+	if a.limiterOpts.Store == nil {
+		return ctx, nil
+	}
+
+	endpoint := rateLimitEndpoint(req)
+	rules := config.RateLimitStore.RulesFor(endpoint)
+	if len(rules) == 0 {
+		return ctx, nil
+	}
+
+	now := time.Now()
+	for _, rule := range rules {
+		value := a.rateLimitKey(req, rule.Key)
+		if value == "" {
+			continue
+		}
+
+		// keys are hashed so that emails are not stored in plain text
+		key := endpoint + ":" + rule.Key + ":" + fmt.Sprintf("%x", sha256.Sum256([]byte(value)))
+
+		allowed, err := a.limiterOpts.Store.AllowAt(ctx, key, rule.Rate, now)
+		if err != nil {
+			// fail open, an unavailable store should not take down
+			// the endpoints it protects
+			log := observability.GetLogEntry(req).Entry
+			log.WithError(err).WithField("endpoint", endpoint).Warn("rate limit store error, rate limit rule is not applied")
+			continue
+		}
+
+		if !allowed {
+			return ctx, apierrors.NewTooManyRequestsError(apierrors.ErrorCodeOverRequestRateLimit, "Request rate limit reached")
+		}
+	}
+
+	return ctx, nil
+}
+
+// rateLimitEndpoint returns the name of the endpoint used in rate limit rules,
+// which is the first segment of the request path.
+func rateLimitEndpoint(req *http.Request) string {
+	endpoint, _, _ := strings.Cut(strings.Trim(req.URL.Path, "/"), "/")
+	return endpoint
+}
+
+func (a *API) rateLimitKey(req *http.Request, key string) string {
+	switch key {
+	case conf.RateLimitKeyIP:
+		if sbffAddr, ok := sbff.GetIPAddress(req); ok {
+			return sbffAddr
+		}
+
+		if a.config.RateLimitHeader != "" {
+			values := strings.SplitN(req.Header.Get(a.config.RateLimitHeader), ",", 2)
+			return strings.TrimSpace(values[0])
+		}
+
+		return utilities.GetIPAddress(req)
+
+	case conf.RateLimitKeyEmail:
+		if !strings.HasPrefix(req.Header.Get("Content-Type"), "application/json") {
+			return ""
+		}
+
+		body, err := utilities.GetBodyBytes(req)
+		if err != nil || len(body) == 0 {
+			return ""
+		}
+
+		var params struct {
+			Email string `json:"email"`
+		}
+		if err := json.Unmarshal(body, &params); err != nil {
+			return ""
+		}
+
+		return strings.ToLower(strings.TrimSpace(params.Email))
+
+	case conf.RateLimitKeyUser:
+		bearer, err := a.extractBearerToken(req)
+		if err != nil {
+			return ""
+		}
+
+		ctx, err := a.parseJWTClaims(bearer, req)
+		if err != nil {
+			return ""
+		}
+
+		if claims := getClaims(ctx); claims != nil {
+			return claims.Subject
+		}
+	}
+
+	return ""
+}
+
 // requireOAuthClientAuth authenticates an OAuth client as middleware
 // Requires client_id to be present and validates client credentials
 func (a *API) requireOAuthClientAuth(w http.ResponseWriter, r *http.Request) (context.Context, error) {
@@ -177,6 +284,21 @@ func (a *API) requireOAuthClientAuth(w http.ResponseWriter, r *http.Request) (co
 	return ctx, nil
 }
 
+// optionalOAuthClientAuth authenticates the OAuth client of the request
+// when it sends client credentials, and lets the request through without a
+// client otherwise.
+func (a *API) optionalOAuthClientAuth(w http.ResponseWriter, r *http.Request) (context.Context, error) {
+	if !a.config.OAuthServer.Enabled {
+		return r.Context(), nil
+	}
+
+	if _, err := oauthserver.ExtractClientCredentials(r); err != nil && !strings.HasPrefix(r.Header.Get("Authorization"), "Basic ") {
+		return r.Context(), nil
+	}
+
+	return a.requireOAuthClientAuth(w, r)
+}
+
 func (a *API) requireAdminCredentials(w http.ResponseWriter, req *http.Request) (context.Context, error) {
 	t, err := a.extractBearerToken(req)
 	if err != nil || t == "" {
@@ -206,7 +328,7 @@ func (a *API) verifyCaptcha(w http.ResponseWriter, req *http.Request) (context.C
 	ctx := req.Context()
 	config := a.config
 
-	if !config.Security.Captcha.Enabled {
+	if !config.Security.Captcha.IsEnabledFor(captchaEndpoint(req)) {
 		return ctx, nil
 	}
 	if _, err := a.requireAdminCredentials(w, req); err == nil {
@@ -217,21 +339,61 @@ func (a *API) verifyCaptcha(w http.ResponseWriter, req *http.Request) (context.C
 		return ctx, nil
 	}
 
+	if err := a.checkCaptcha(req); err != nil {
+		return nil, err
+	}
+
+	return ctx, nil
+}
+
+// checkCaptcha verifies the captcha token in the body of the request with
+// the configured captcha provider.
+func (a *API) checkCaptcha(req *http.Request) error {
+	config := a.config
+
 	body := &security.GotrueRequest{}
 	if err := retrieveRequestParams(req, body); err != nil {
-		return nil, err
+		return err
+	}
+
+	captchaProvider, err := security.NewCaptchaProvider(config.Security.Captcha.Provider, strings.TrimSpace(config.Security.Captcha.Secret), config.Security.Captcha.MinScore)
+	if err != nil {
+		return apierrors.NewInternalServerError("captcha verification process failed").WithInternalError(err)
 	}
 
-	verificationResult, err := security.VerifyRequest(body, utilities.GetIPAddress(req), strings.TrimSpace(config.Security.Captcha.Secret), config.Security.Captcha.Provider)
+	verificationResult, err := security.VerifyRequest(body, utilities.GetIPAddress(req), captchaProvider)
 	if err != nil {
-		return nil, apierrors.NewInternalServerError("captcha verification process failed").WithInternalError(err)
+		return apierrors.NewInternalServerError("captcha verification process failed").WithInternalError(err)
 	}
 
 	if !verificationResult.Success {
-		return nil, apierrors.NewBadRequestError(apierrors.ErrorCodeCaptchaFailed, "captcha protection: request disallowed (%s)", strings.Join(verificationResult.ErrorCodes, ", "))
+		return apierrors.NewBadRequestError(apierrors.ErrorCodeCaptchaFailed, "captcha protection: request disallowed (%s)", strings.Join(verificationResult.ErrorCodes, ", "))
 	}
 
-	return ctx, nil
+	return nil
+}
+
+// captchaEndpoint returns the name of the captcha protected endpoint the
+// request is made to, as used in GOTRUE_SECURITY_CAPTCHA_ENDPOINTS.
+func captchaEndpoint(req *http.Request) string {
+	switch path := strings.TrimSuffix(req.URL.Path, "/"); path {
+	case "/signup":
+		return conf.CaptchaEndpointSignup
+	case "/recover":
+		return conf.CaptchaEndpointRecover
+	case "/resend":
+		return conf.CaptchaEndpointResend
+	case "/magiclink":
+		return conf.CaptchaEndpointMagicLink
+	case "/otp":
+		return conf.CaptchaEndpointOTP
+	case "/token":
+		return conf.CaptchaEndpointToken
+	case "/sso":
+		return conf.CaptchaEndpointSSO
+	default:
+		return ""
+	}
 }
 
 func isIgnoreCaptchaRoute(req *http.Request) bool {
@@ -347,6 +509,46 @@ func (a *API) requireSAMLEnabled(w http.ResponseWriter, req *http.Request) (cont
 	return ctx, nil
 }
 
+func (a *API) requireOrganizationsEnabled(w http.ResponseWriter, req *http.Request) (context.Context, error) {
+	ctx := req.Context()
+	if !a.config.Organizations.Enabled {
+		return nil, apierrors.NewNotFoundError(apierrors.ErrorCodeOrganizationsDisabled, "Organizations are disabled")
+	}
+	return ctx, nil
+}
+
+func (a *API) requireRBACEnabled(w http.ResponseWriter, req *http.Request) (context.Context, error) {
+	ctx := req.Context()
+	if !a.config.RBAC.Enabled {
+		return nil, apierrors.NewNotFoundError(apierrors.ErrorCodeRBACDisabled, "Role based access control is disabled")
+	}
+	return ctx, nil
+}
+
+func (a *API) requireImpersonationEnabled(w http.ResponseWriter, req *http.Request) (context.Context, error) {
+	ctx := req.Context()
+	if !a.config.Impersonation.Enabled {
+		return nil, apierrors.NewNotFoundError(apierrors.ErrorCodeImpersonationDisabled, "Impersonation is disabled")
+	}
+	return ctx, nil
+}
+
+func (a *API) requireUserDeletionEnabled(w http.ResponseWriter, req *http.Request) (context.Context, error) {
+	ctx := req.Context()
+	if !a.config.UserDeletion.SelfServiceEnabled {
+		return nil, apierrors.NewNotFoundError(apierrors.ErrorCodeUserDeletionDisabled, "Deleting your own account is disabled")
+	}
+	return ctx, nil
+}
+
+func (a *API) requireUserExportEnabled(w http.ResponseWriter, req *http.Request) (context.Context, error) {
+	ctx := req.Context()
+	if !a.config.UserExport.Enabled {
+		return nil, apierrors.NewNotFoundError(apierrors.ErrorCodeUserExportDisabled, "Exporting your data is disabled")
+	}
+	return ctx, nil
+}
+
 func (a *API) requireManualLinkingEnabled(w http.ResponseWriter, req *http.Request) (context.Context, error) {
 	ctx := req.Context()
 	if !a.config.Security.ManualLinkingEnabled {
@@ -454,9 +656,31 @@ func (t *timeoutResponseWriter) finallyWrite(w http.ResponseWriter) {
 	}
 }
 
+// streamingPaths are the paths of responses that are streamed to the client,
+// they are neither buffered nor bounded by the request timeout.
+var streamingPaths = map[string]bool{
+	"/admin/users/export": true,
+}
+
+// replicaReads lets GET and HEAD requests read from the replica of the
+// database, until they write to the primary.
+func replicaReads(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet || r.Method == http.MethodHead {
+			r = r.WithContext(storage.WithReplicaReads(r.Context()))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
 func timeoutMiddleware(timeout time.Duration) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if streamingPaths[strings.TrimSuffix(r.URL.Path, "/")] {
+				next.ServeHTTP(w, r)
+				return
+			}
+
 			ctx, cancel := context.WithTimeout(r.Context(), timeout)
 			defer cancel()
 
@@ -19,6 +19,7 @@ import (
 	"github.com/stretchr/testify/suite"
 	"github.com/supabase/auth/internal/api/apierrors"
 	"github.com/supabase/auth/internal/conf"
+	"github.com/supabase/auth/internal/ratelimit"
 	"github.com/supabase/auth/internal/sbff"
 	"github.com/supabase/auth/internal/storage"
 )
@@ -188,6 +189,118 @@ func (ts *MiddlewareTestSuite) TestVerifyCaptchaInvalid() {
 	}
 }
 
+func (ts *MiddlewareTestSuite) TestVerifyCaptchaPerEndpoint() {
+	ts.Config.Security.Captcha = conf.CaptchaConfiguration{
+		Enabled:   true,
+		Provider:  "hcaptcha",
+		Secret:    "test",
+		Endpoints: []string{conf.CaptchaEndpointSignup},
+	}
+
+	for _, path := range []string{"/recover", "/otp", "/token"} {
+		req := httptest.NewRequest(http.MethodPost, "http://localhost"+path, nil)
+		_, err := ts.API.verifyCaptcha(httptest.NewRecorder(), req)
+		require.NoError(ts.T(), err, path)
+	}
+
+	var buffer bytes.Buffer
+	require.NoError(ts.T(), json.NewEncoder(&buffer).Encode(map[string]interface{}{
+		"gotrue_meta_security": map[string]interface{}{
+			"captcha_token": CaptchaResponse,
+		},
+	}))
+	req := httptest.NewRequest(http.MethodPost, "http://localhost/signup", &buffer)
+	req.Header.Set("Content-Type", "application/json")
+
+	_, err := ts.API.verifyCaptcha(httptest.NewRecorder(), req)
+	require.Error(ts.T(), err)
+}
+
+func TestCaptchaEndpoint(t *testing.T) {
+	cases := map[string]string{
+		"/signup":    conf.CaptchaEndpointSignup,
+		"/recover":   conf.CaptchaEndpointRecover,
+		"/resend":    conf.CaptchaEndpointResend,
+		"/magiclink": conf.CaptchaEndpointMagicLink,
+		"/otp":       conf.CaptchaEndpointOTP,
+		"/token":     conf.CaptchaEndpointToken,
+		"/sso/":      conf.CaptchaEndpointSSO,
+		"/verify":    "",
+	}
+
+	for path, endpoint := range cases {
+		req := httptest.NewRequest(http.MethodPost, "http://localhost"+path, nil)
+		require.Equal(t, endpoint, captchaEndpoint(req), path)
+	}
+}
+
+func TestLimitByRules(t *testing.T) {
+	a := &API{
+		config: &conf.GlobalConfiguration{
+			RateLimitStore: conf.RateLimitStoreConfiguration{
+				Rules: []conf.RateLimitRule{
+					{Endpoint: "otp", Key: conf.RateLimitKeyEmail, Rate: conf.Rate{Events: 2, OverTime: time.Hour}},
+					{Endpoint: "otp", Key: conf.RateLimitKeyIP, Rate: conf.Rate{Events: 3, OverTime: time.Hour}},
+				},
+			},
+		},
+		limiterOpts: &LimiterOptions{
+			Store: ratelimit.NewMemoryStore(),
+		},
+	}
+
+	do := func(path, email, ip string) error {
+		body, err := json.Marshal(map[string]string{"email": email})
+		require.NoError(t, err)
+
+		req := httptest.NewRequest(http.MethodPost, "http://localhost"+path, bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.RemoteAddr = ip + ":1234"
+
+		_, err = a.limitByRules(httptest.NewRecorder(), req)
+		if err == nil {
+			// the body must still be readable by the handler
+			var params struct {
+				Email string `json:"email"`
+			}
+			require.NoError(t, retrieveRequestParams(req, &params))
+			require.Equal(t, email, params.Email)
+		}
+		return err
+	}
+
+	require.NoError(t, do("/otp", "a@example.com", "10.0.0.1"))
+	require.NoError(t, do("/otp", "A@example.com", "10.0.0.2"))
+
+	// email budget is exhausted, emails are compared case insensitively
+	err := do("/otp", "a@example.com", "10.0.0.3")
+	require.Error(t, err)
+	require.Equal(t, apierrors.ErrorCodeOverRequestRateLimit, err.(*apierrors.HTTPError).ErrorCode)
+
+	// ip budget is exhausted
+	require.NoError(t, do("/otp", "b@example.com", "10.0.0.1"))
+	require.NoError(t, do("/otp", "c@example.com", "10.0.0.1"))
+	require.Error(t, do("/otp", "d@example.com", "10.0.0.1"))
+
+	// other endpoints are not affected
+	require.NoError(t, do("/signup", "a@example.com", "10.0.0.1"))
+}
+
+func TestRateLimitEndpoint(t *testing.T) {
+	cases := map[string]string{
+		"/token":                "token",
+		"/signup/":              "signup",
+		"/factors/abc/verify":   "factors",
+		"/admin/users/abc/mfa/": "admin",
+		"/":                     "",
+	}
+
+	for path, endpoint := range cases {
+		req := httptest.NewRequest(http.MethodPost, "http://localhost"+path, nil)
+		require.Equal(t, endpoint, rateLimitEndpoint(req), path)
+	}
+}
+
 func (ts *MiddlewareTestSuite) TestIsValidExternalHost() {
 	cases := []struct {
 		desc          string
@@ -416,6 +529,33 @@ func TestTimeoutResponseWriter(t *testing.T) {
 	require.Equal(t, w1.Result(), w2.Result())
 }
 
+func TestTimeoutMiddlewareStreaming(t *testing.T) {
+	timeoutHandler := timeoutMiddleware(time.Millisecond)
+
+	streamingHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		if flusher, ok := w.(http.Flusher); ok {
+			flusher.Flush()
+		}
+
+		time.Sleep(10 * time.Millisecond)
+		_, _ = w.Write([]byte("done"))
+	})
+
+	// streamed responses are written directly and are not timed out
+	req := httptest.NewRequest(http.MethodGet, "http://localhost/admin/users/export", nil)
+	w := httptest.NewRecorder()
+	timeoutHandler(streamingHandler).ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.True(t, w.Flushed)
+	require.Equal(t, "done", w.Body.String())
+
+	req = httptest.NewRequest(http.MethodGet, "http://localhost/admin/users", nil)
+	w = httptest.NewRecorder()
+	timeoutHandler(streamingHandler).ServeHTTP(w, req)
+	require.Equal(t, http.StatusGatewayTimeout, w.Code)
+}
+
 func (ts *MiddlewareTestSuite) TestPerformRateLimitingWithSBFF() {
 	origRateLimitHeader := ts.Config.RateLimitHeader
 	origSBFFEnabled := ts.Config.Security.SbForwardedForEnabled
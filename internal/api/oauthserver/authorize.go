@@ -12,6 +12,7 @@ import (
 	"github.com/gofrs/uuid"
 	"github.com/supabase/auth/internal/api/apierrors"
 	"github.com/supabase/auth/internal/api/shared"
+	"github.com/supabase/auth/internal/hooks/v0hooks"
 	"github.com/supabase/auth/internal/models"
 	"github.com/supabase/auth/internal/observability"
 	"github.com/supabase/auth/internal/storage"
@@ -229,6 +230,21 @@ func (s *Server) OAuthServerGetAuthorization(w http.ResponseWriter, r *http.Requ
 		if shouldAutoApprove {
 			return s.autoApproveAndRedirect(w, r, authorization)
 		}
+
+		// Otherwise let the consent hook decide before showing the consent screen
+		if s.config.Hook.OAuthConsent.Enabled {
+			decision, err := s.invokeConsentHook(r, authorization, user)
+			if err != nil {
+				return err
+			}
+
+			switch decision {
+			case v0hooks.OAuthConsentApprove:
+				return s.autoApproveAndRedirect(w, r, authorization)
+			case v0hooks.OAuthConsentDeny:
+				return s.denyAndRedirect(w, r, authorization)
+			}
+		}
 	} else {
 		// Authorization already has user_id set, validate ownership
 		if err := s.validateAuthorizationOwnership(r, authorization, user); err != nil {
@@ -596,6 +612,55 @@ func (s *Server) autoApproveAndRedirect(w http.ResponseWriter, r *http.Request,
 	return shared.SendJSON(w, http.StatusOK, response)
 }
 
+// invokeConsentHook asks the OAuth consent hook whether the authorization
+// should be approved or denied without showing the consent screen.
+func (s *Server) invokeConsentHook(r *http.Request, authorization *models.OAuthServerAuthorization, user *models.User) (string, error) {
+	input := v0hooks.OAuthConsentInput{
+		Metadata: v0hooks.NewMetadata(r, v0hooks.OAuthConsent),
+		User:     user,
+		Client: v0hooks.OAuthConsentClient{
+			ID:   authorization.Client.ID.String(),
+			Name: utilities.StringValue(authorization.Client.ClientName),
+			URI:  utilities.StringValue(authorization.Client.ClientURI),
+		},
+		Scopes:      authorization.GetScopeList(),
+		RedirectURI: authorization.RedirectURI,
+	}
+	output := v0hooks.OAuthConsentOutput{}
+
+	if err := s.hooksMgr.InvokeHook(nil, r, &input, &output); err != nil {
+		return "", err
+	}
+
+	observability.LogEntrySetField(r, "oauth_consent_hook_decision", output.Decision)
+
+	return output.Decision, nil
+}
+
+func (s *Server) denyAndRedirect(w http.ResponseWriter, r *http.Request, authorization *models.OAuthServerAuthorization) error {
+	ctx := r.Context()
+	db := s.db.WithContext(ctx)
+
+	err := db.Transaction(func(tx *storage.Connection) error {
+		return authorization.Deny(tx)
+	})
+
+	if err != nil {
+		return apierrors.NewInternalServerError("Error denying authorization").WithInternalError(err)
+	}
+
+	var state string
+	if authorization.State != nil {
+		state = *authorization.State
+	}
+
+	response := ConsentResponse{
+		RedirectURL: s.buildErrorRedirectURL(authorization.RedirectURI, oAuth2ErrorAccessDenied, "Authorization denied", state),
+	}
+
+	return shared.SendJSON(w, http.StatusOK, response)
+}
+
 func (s *Server) buildSuccessRedirectURL(authorization *models.OAuthServerAuthorization) string {
 	u, _ := url.Parse(authorization.RedirectURI)
 	q := u.Query()
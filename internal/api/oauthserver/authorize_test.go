@@ -39,7 +39,7 @@ func TestValidateRequestOrigin(t *testing.T) {
 
 	hooksMgr := &v0hooks.Manager{}
 	tokenService := tokens.NewService(globalConfig, hooksMgr)
-	server := NewServer(globalConfig, conn, tokenService)
+	server := NewServer(globalConfig, conn, tokenService, hooksMgr)
 
 	tests := []struct {
 		name         string
@@ -134,7 +134,7 @@ func TestValidateRequestOriginEdgeCases(t *testing.T) {
 
 	hooksMgr := &v0hooks.Manager{}
 	tokenService := tokens.NewService(globalConfig, hooksMgr)
-	server := NewServer(globalConfig, conn, tokenService)
+	server := NewServer(globalConfig, conn, tokenService, hooksMgr)
 
 	t.Run("Origin with different port should be allowed (hostname matching)", func(t *testing.T) {
 		req := httptest.NewRequest(http.MethodGet, "/test", nil)
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"net/http"
+	"slices"
 	"strings"
 	"time"
 
@@ -22,6 +23,7 @@ import (
 const (
 	GrantTypeAuthorizationCode = "authorization_code"
 	GrantTypeRefreshToken      = "refresh_token"
+	GrantTypeClientCredentials = models.OAuthServerGrantTypeClientCredentials
 )
 
 // OAuthServerClientResponse represents the response format for OAuth client operations
@@ -34,6 +36,8 @@ type OAuthServerClientResponse struct {
 	TokenEndpointAuthMethod string   `json:"token_endpoint_auth_method,omitempty"`
 	GrantTypes              []string `json:"grant_types,omitempty"`
 	ResponseTypes           []string `json:"response_types,omitempty"`
+	AllowedScopes           []string `json:"allowed_scopes,omitempty"`
+	AllowedAudiences        []string `json:"allowed_audiences,omitempty"`
 	ClientName              string   `json:"client_name,omitempty"`
 	ClientURI               string   `json:"client_uri,omitempty"`
 	LogoURI                 string   `json:"logo_uri,omitempty"`
@@ -60,6 +64,8 @@ func oauthServerClientToResponse(client *models.OAuthServerClient) *OAuthServerC
 		TokenEndpointAuthMethod: client.GetTokenEndpointAuthMethod(),
 		GrantTypes:              client.GetGrantTypes(),
 		ResponseTypes:           []string{"code"}, // Always "code" in OAuth 2.1
+		AllowedScopes:           client.GetAllowedScopes(),
+		AllowedAudiences:        client.GetAllowedAudiences(),
 		ClientName:              utilities.StringValue(client.ClientName),
 		ClientURI:               utilities.StringValue(client.ClientURI),
 		LogoURI:                 utilities.StringValue(client.LogoURI),
@@ -259,6 +265,8 @@ type OAuthTokenParams struct {
 	ClientSecret string `json:"client_secret" form:"client_secret"`
 	CodeVerifier string `json:"code_verifier" form:"code_verifier"`
 	Resource     string `json:"resource" form:"resource"`
+	Scope        string `json:"scope" form:"scope"`
+	Audience     string `json:"audience" form:"audience"`
 }
 
 // OAuthToken handles POST /oauth/token
@@ -286,6 +294,8 @@ func (s *Server) OAuthToken(w http.ResponseWriter, r *http.Request) error {
 		params.ClientID = r.FormValue("client_id")
 		params.ClientSecret = r.FormValue("client_secret")
 		params.CodeVerifier = r.FormValue("code_verifier")
+		params.Scope = r.FormValue("scope")
+		params.Audience = r.FormValue("audience")
 	}
 
 	// Validate grant_type
@@ -308,6 +318,8 @@ func (s *Server) OAuthToken(w http.ResponseWriter, r *http.Request) error {
 		return s.handleAuthorizationCodeGrant(ctx, w, r, &params)
 	case GrantTypeRefreshToken:
 		return s.handleRefreshTokenGrant(ctx, w, r, &params)
+	case GrantTypeClientCredentials:
+		return s.handleClientCredentialsGrant(ctx, w, r, &params)
 	default:
 		return apierrors.NewOAuthError("unsupported_grant_type", "Unsupported grant type: "+params.GrantType)
 	}
@@ -455,6 +467,7 @@ func (s *Server) handleAuthorizationCodeGrant(ctx context.Context, w http.Respon
 		"token_type":    tokenResponse.TokenType,
 		"expires_in":    tokenResponse.ExpiresIn,
 		"refresh_token": tokenResponse.RefreshToken,
+		"scope":         authorization.Scope,
 	}
 
 	// Include ID token if generated (OIDC)
@@ -487,6 +500,7 @@ func (s *Server) handleRefreshTokenGrant(ctx context.Context, w http.ResponseWri
 	tokenResponse, err := tokenService.RefreshTokenGrant(ctx, db, r, w.Header(), tokens.RefreshTokenGrantParams{
 		RefreshToken: params.RefreshToken,
 		ClientID:     clientID,
+		Scopes:       models.ParseScopeString(params.Scope),
 	})
 	if err != nil {
 		return err
@@ -499,6 +513,69 @@ func (s *Server) handleRefreshTokenGrant(ctx context.Context, w http.ResponseWri
 		"expires_in":    tokenResponse.ExpiresIn,
 		"refresh_token": tokenResponse.RefreshToken,
 	}
+	if tokenResponse.Scope != "" {
+		oauthResponse["scope"] = tokenResponse.Scope
+	}
+
+	return shared.SendJSON(w, http.StatusOK, oauthResponse)
+}
+
+// handleClientCredentialsGrant handles the client_credentials grant type,
+// issuing a service token of the client itself for machine-to-machine calls
+func (s *Server) handleClientCredentialsGrant(ctx context.Context, w http.ResponseWriter, r *http.Request, params *OAuthTokenParams) error {
+	client := shared.GetOAuthServerClient(ctx)
+	if client == nil || !client.IsConfidential() {
+		return apierrors.NewOAuthError("unauthorized_client", "Only confidential clients can use the client_credentials grant")
+	}
+
+	tokenService := s.getTokenService()
+	if tokenService == nil {
+		return apierrors.NewInternalServerError("Token service not available")
+	}
+
+	// Without a scope, the token carries all the scopes the client is allowed
+	allowedScopes := client.GetAllowedScopes()
+	scopes := allowedScopes
+	if params.Scope != "" {
+		scopes = strings.Fields(params.Scope)
+		for _, scope := range scopes {
+			if !slices.Contains(allowedScopes, scope) {
+				return apierrors.NewOAuthError("invalid_scope", "Client is not allowed to request scope: "+scope)
+			}
+		}
+	}
+
+	// Without an audience, the token is for the first allowed audience or
+	// the default audience of the project
+	audience := params.Audience
+	if audience == "" {
+		audience = params.Resource
+	}
+	allowedAudiences := client.GetAllowedAudiences()
+	if audience == "" {
+		if len(allowedAudiences) > 0 {
+			audience = allowedAudiences[0]
+		} else {
+			audience = s.config.JWT.Aud
+		}
+	} else if !slices.Contains(allowedAudiences, audience) {
+		return apierrors.NewOAuthError("invalid_target", "Client is not allowed to request audience: "+audience)
+	}
+
+	token, _, err := tokenService.GenerateServiceToken(client, audience, scopes)
+	if err != nil {
+		return apierrors.NewInternalServerError("Error generating service token").WithInternalError(err)
+	}
+
+	// No refresh token is issued, clients request a new token instead (RFC 6749 section 4.4.3)
+	oauthResponse := map[string]interface{}{
+		"access_token": token,
+		"token_type":   "bearer",
+		"expires_in":   s.config.JWT.Exp,
+	}
+	if len(scopes) > 0 {
+		oauthResponse["scope"] = strings.Join(scopes, " ")
+	}
 
 	return shared.SendJSON(w, http.StatusOK, oauthResponse)
 }
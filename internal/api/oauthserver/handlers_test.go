@@ -6,9 +6,12 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"strings"
 	"testing"
 
 	"github.com/gofrs/uuid"
+	jwt "github.com/golang-jwt/jwt/v5"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
@@ -45,7 +48,7 @@ func TestOAuthClientHandler(t *testing.T) {
 	hooksMgr := &v0hooks.Manager{} // minimal mock for testing
 	tokenService := tokens.NewService(globalConfig, hooksMgr)
 
-	server := NewServer(globalConfig, conn, tokenService)
+	server := NewServer(globalConfig, conn, tokenService, hooksMgr)
 
 	ts := &OAuthClientTestSuite{
 		Server: server,
@@ -874,3 +877,83 @@ func (ts *OAuthClientTestSuite) TestUserInfoNoOAuthClient() {
 	assert.Nil(ts.T(), response["name"])
 	assert.Nil(ts.T(), response["phone"])
 }
+
+func (ts *OAuthClientTestSuite) TestClientCredentialsGrant() {
+	client, _, err := ts.Server.registerOAuthServerClient(context.Background(), &OAuthServerClientRegisterParams{
+		ClientName:       "Billing Service",
+		GrantTypes:       []string{"client_credentials"},
+		AllowedScopes:    []string{"invoices:read", "invoices:write"},
+		AllowedAudiences: []string{"https://billing.example.com"},
+		RegistrationType: "manual",
+	})
+	require.NoError(ts.T(), err)
+	assert.Empty(ts.T(), client.GetRedirectURIs())
+
+	token := func(values url.Values) (*httptest.ResponseRecorder, error) {
+		req := httptest.NewRequest(http.MethodPost, "/oauth/token", strings.NewReader(values.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req = req.WithContext(shared.WithOAuthServerClient(req.Context(), client))
+
+		w := httptest.NewRecorder()
+		return w, ts.Server.OAuthToken(w, req)
+	}
+
+	_, err = token(url.Values{"grant_type": {"client_credentials"}, "scope": {"invoices:read admin"}})
+	require.Error(ts.T(), err)
+	assert.Contains(ts.T(), err.Error(), "invalid_scope")
+
+	_, err = token(url.Values{"grant_type": {"client_credentials"}, "audience": {"https://other.example.com"}})
+	require.Error(ts.T(), err)
+	assert.Contains(ts.T(), err.Error(), "invalid_target")
+
+	w, err := token(url.Values{"grant_type": {"client_credentials"}, "scope": {"invoices:read"}})
+	require.NoError(ts.T(), err)
+	assert.Equal(ts.T(), http.StatusOK, w.Code)
+
+	var response map[string]interface{}
+	require.NoError(ts.T(), json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(ts.T(), "invoices:read", response["scope"])
+	assert.Nil(ts.T(), response["refresh_token"])
+
+	claims := &tokens.ServiceTokenClaims{}
+	_, _, err = jwt.NewParser().ParseUnverified(response["access_token"].(string), claims)
+	require.NoError(ts.T(), err)
+	assert.Equal(ts.T(), client.ID.String(), claims.Subject)
+	assert.Equal(ts.T(), client.ID.String(), claims.ClientID)
+	assert.Equal(ts.T(), jwt.ClaimStrings{"https://billing.example.com"}, claims.Audience)
+	assert.Equal(ts.T(), ts.Config.OAuthServer.ClientCredentialsRole, claims.Role)
+	assert.Equal(ts.T(), "invoices:read", claims.Scope)
+
+	// clients not allowed the grant type cannot use it
+	other, _ := ts.createTestOAuthClient()
+	req := httptest.NewRequest(http.MethodPost, "/oauth/token", strings.NewReader("grant_type=client_credentials"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req = req.WithContext(shared.WithOAuthServerClient(req.Context(), other))
+	err = ts.Server.OAuthToken(httptest.NewRecorder(), req)
+	require.Error(ts.T(), err)
+	assert.Contains(ts.T(), err.Error(), "unsupported_grant_type")
+}
+
+func (ts *OAuthClientTestSuite) TestClientCredentialsClientRegistration() {
+	// service clients cannot be registered dynamically
+	_, _, err := ts.Server.registerOAuthServerClient(context.Background(), &OAuthServerClientRegisterParams{
+		GrantTypes:       []string{"client_credentials"},
+		RegistrationType: "dynamic",
+	})
+	require.Error(ts.T(), err)
+
+	// nor be public clients
+	_, _, err = ts.Server.registerOAuthServerClient(context.Background(), &OAuthServerClientRegisterParams{
+		GrantTypes:              []string{"client_credentials"},
+		TokenEndpointAuthMethod: "none",
+		RegistrationType:        "manual",
+	})
+	require.Error(ts.T(), err)
+
+	_, _, err = ts.Server.registerOAuthServerClient(context.Background(), &OAuthServerClientRegisterParams{
+		GrantTypes:       []string{"client_credentials"},
+		AllowedScopes:    []string{"invoices read"},
+		RegistrationType: "manual",
+	})
+	require.Error(ts.T(), err)
+}
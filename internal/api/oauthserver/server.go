@@ -2,6 +2,7 @@ package oauthserver
 
 import (
 	"github.com/supabase/auth/internal/conf"
+	"github.com/supabase/auth/internal/hooks/v0hooks"
 	"github.com/supabase/auth/internal/storage"
 	"github.com/supabase/auth/internal/tokens"
 )
@@ -11,13 +12,15 @@ type Server struct {
 	config       *conf.GlobalConfiguration
 	db           *storage.Connection
 	tokenService *tokens.Service
+	hooksMgr     *v0hooks.Manager
 }
 
 // NewServer creates a new OAuth server instance
-func NewServer(config *conf.GlobalConfiguration, db *storage.Connection, tokenService *tokens.Service) *Server {
+func NewServer(config *conf.GlobalConfiguration, db *storage.Connection, tokenService *tokens.Service, hooksMgr *v0hooks.Manager) *Server {
 	return &Server{
 		config:       config,
 		db:           db,
 		tokenService: tokenService,
+		hooksMgr:     hooksMgr,
 	}
 }
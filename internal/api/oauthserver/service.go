@@ -49,14 +49,42 @@ func validateGrantTypeList(grantTypes []string) error {
 	}
 
 	for _, grantType := range grantTypes {
-		if grantType != "authorization_code" && grantType != "refresh_token" {
-			return apierrors.NewBadRequestError(apierrors.ErrorCodeValidationFailed, "grant_types must only contain 'authorization_code' and/or 'refresh_token'")
+		if grantType != "authorization_code" && grantType != "refresh_token" && grantType != "client_credentials" {
+			return apierrors.NewBadRequestError(apierrors.ErrorCodeValidationFailed, "grant_types must only contain 'authorization_code', 'refresh_token' and/or 'client_credentials'")
 		}
 	}
 
 	return nil
 }
 
+// isClientCredentialsOnly returns true if the grant types only contain
+// client_credentials, in which case the client needs no redirect URIs
+func isClientCredentialsOnly(grantTypes []string) bool {
+	return len(grantTypes) == 1 && grantTypes[0] == models.OAuthServerGrantTypeClientCredentials
+}
+
+// validateAllowedScopes validates the scopes a client may request with the
+// client_credentials grant
+func validateAllowedScopes(scopes []string) error {
+	for _, scope := range scopes {
+		if scope == "" || strings.ContainsAny(scope, ", \t\n") {
+			return apierrors.NewBadRequestError(apierrors.ErrorCodeValidationFailed, "invalid allowed scope '%s'", scope)
+		}
+	}
+	return nil
+}
+
+// validateAllowedAudiences validates the audiences a client may request
+// service tokens for
+func validateAllowedAudiences(audiences []string) error {
+	for _, audience := range audiences {
+		if audience == "" || strings.Contains(audience, ",") {
+			return apierrors.NewBadRequestError(apierrors.ErrorCodeValidationFailed, "invalid allowed audience '%s'", audience)
+		}
+	}
+	return nil
+}
+
 // validateClientName validates a client name
 func validateClientName(clientName string) error {
 	if len(clientName) > 1024 {
@@ -113,15 +141,21 @@ type OAuthServerClientRegisterParams struct {
 	ClientURI  string   `json:"client_uri,omitempty"`
 	LogoURI    string   `json:"logo_uri,omitempty"`
 
+	// Scopes and audiences the client may request with the client_credentials grant
+	AllowedScopes    []string `json:"allowed_scopes,omitempty"`
+	AllowedAudiences []string `json:"allowed_audiences,omitempty"`
+
 	// Internal field
 	RegistrationType string `json:"-"`
 }
 
 // validate validates the OAuth client registration parameters
 func (p *OAuthServerClientRegisterParams) validate() error {
-	// Validate redirect URIs (required for registration)
-	if err := validateRedirectURIList(p.RedirectURIs, true); err != nil {
-		return err
+	// Validate redirect URIs (required for registration, unless the client only uses client_credentials)
+	if !isClientCredentialsOnly(p.GrantTypes) || len(p.RedirectURIs) > 0 {
+		if err := validateRedirectURIList(p.RedirectURIs, true); err != nil {
+			return err
+		}
 	}
 
 	// Validate grant types if provided
@@ -131,6 +165,19 @@ func (p *OAuthServerClientRegisterParams) validate() error {
 		}
 	}
 
+	// Service clients are only registered by admins
+	if p.RegistrationType == "dynamic" && slices.Contains(p.GrantTypes, models.OAuthServerGrantTypeClientCredentials) {
+		return apierrors.NewBadRequestError(apierrors.ErrorCodeValidationFailed, "client_credentials clients cannot be registered dynamically")
+	}
+
+	if err := validateAllowedScopes(p.AllowedScopes); err != nil {
+		return err
+	}
+
+	if err := validateAllowedAudiences(p.AllowedAudiences); err != nil {
+		return err
+	}
+
 	// Validate client name
 	if err := validateClientName(p.ClientName); err != nil {
 		return err
@@ -290,6 +337,8 @@ func (s *Server) registerOAuthServerClient(ctx context.Context, params *OAuthSer
 
 	client.SetRedirectURIs(params.RedirectURIs)
 	client.SetGrantTypes(grantTypes)
+	client.SetAllowedScopes(params.AllowedScopes)
+	client.SetAllowedAudiences(params.AllowedAudiences)
 
 	var plaintextSecret string
 	// Only generate client secret for confidential clients
@@ -374,17 +423,21 @@ func (s *Server) regenerateOAuthServerClientSecret(ctx context.Context, clientID
 
 // OAuthServerClientUpdateParams contains parameters for updating an OAuth client
 type OAuthServerClientUpdateParams struct {
-	RedirectURIs *[]string `json:"redirect_uris,omitempty"`
-	GrantTypes   *[]string `json:"grant_types,omitempty"`
-	ClientName   *string   `json:"client_name,omitempty"`
-	ClientURI    *string   `json:"client_uri,omitempty"`
-	LogoURI      *string   `json:"logo_uri,omitempty"`
+	RedirectURIs     *[]string `json:"redirect_uris,omitempty"`
+	GrantTypes       *[]string `json:"grant_types,omitempty"`
+	AllowedScopes    *[]string `json:"allowed_scopes,omitempty"`
+	AllowedAudiences *[]string `json:"allowed_audiences,omitempty"`
+	ClientName       *string   `json:"client_name,omitempty"`
+	ClientURI        *string   `json:"client_uri,omitempty"`
+	LogoURI          *string   `json:"logo_uri,omitempty"`
 }
 
 // isEmpty returns true if no fields are set for update
 func (p *OAuthServerClientUpdateParams) isEmpty() bool {
 	return p.RedirectURIs == nil &&
 		p.GrantTypes == nil &&
+		p.AllowedScopes == nil &&
+		p.AllowedAudiences == nil &&
 		p.ClientName == nil &&
 		p.ClientURI == nil &&
 		p.LogoURI == nil
@@ -406,6 +459,19 @@ func (p *OAuthServerClientUpdateParams) validate() error {
 		}
 	}
 
+	// Validate allowed scopes and audiences if provided
+	if p.AllowedScopes != nil {
+		if err := validateAllowedScopes(*p.AllowedScopes); err != nil {
+			return err
+		}
+	}
+
+	if p.AllowedAudiences != nil {
+		if err := validateAllowedAudiences(*p.AllowedAudiences); err != nil {
+			return err
+		}
+	}
+
 	// Validate client name if provided
 	if p.ClientName != nil {
 		if err := validateClientName(*p.ClientName); err != nil {
@@ -450,9 +516,20 @@ func (s *Server) updateOAuthServerClient(ctx context.Context, clientID uuid.UUID
 	}
 
 	if params.GrantTypes != nil {
+		if slices.Contains(*params.GrantTypes, models.OAuthServerGrantTypeClientCredentials) && !client.IsConfidential() {
+			return nil, apierrors.NewBadRequestError(apierrors.ErrorCodeValidationFailed, "the client_credentials grant type is only allowed for confidential clients")
+		}
 		client.SetGrantTypes(*params.GrantTypes)
 	}
 
+	if params.AllowedScopes != nil {
+		client.SetAllowedScopes(*params.AllowedScopes)
+	}
+
+	if params.AllowedAudiences != nil {
+		client.SetAllowedAudiences(*params.AllowedAudiences)
+	}
+
 	if params.ClientName != nil {
 		client.ClientName = utilities.StringPtr(*params.ClientName)
 	}
@@ -40,7 +40,7 @@ func TestOAuthService(t *testing.T) {
 	hooksMgr := &v0hooks.Manager{} // minimal mock for testing
 	tokenService := tokens.NewService(globalConfig, hooksMgr)
 
-	server := NewServer(globalConfig, conn, tokenService)
+	server := NewServer(globalConfig, conn, tokenService, hooksMgr)
 
 	ts := &OAuthServiceTestSuite{
 		Server: server,
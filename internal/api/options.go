@@ -5,6 +5,8 @@ import (
 
 	"github.com/didip/tollbooth/v5"
 	"github.com/didip/tollbooth/v5/limiter"
+	"github.com/gofrs/uuid"
+	"github.com/sirupsen/logrus"
 	"github.com/supabase/auth/internal/conf"
 	"github.com/supabase/auth/internal/mailer"
 	"github.com/supabase/auth/internal/ratelimit"
@@ -25,6 +27,13 @@ func WithMailer(m mailer.Mailer) Option {
 	})
 }
 
+// WithTenantID sets the tenant served by the API.
+func WithTenantID(id uuid.UUID) Option {
+	return optionFunc(func(a *API) {
+		a.tenantID = &id
+	})
+}
+
 func WithTokenService(service *tokens.Service) Option {
 	return optionFunc(func(a *API) {
 		a.tokenService = service
@@ -35,6 +44,10 @@ type LimiterOptions struct {
 	Email ratelimit.Limiter
 	Phone ratelimit.Limiter
 
+	// Store keeps the counters of the rate limit rules configured with
+	// GOTRUE_RATE_LIMIT_STORE_RULES.
+	Store ratelimit.Store
+
 	Signups             *limiter.Limiter
 	AnonymousSignIns    *limiter.Limiter
 	Recover             *limiter.Limiter
@@ -60,45 +73,23 @@ func NewLimiterOptions(gc *conf.GlobalConfiguration) *LimiterOptions {
 	o.Email = ratelimit.New(gc.RateLimitEmailSent)
 	o.Phone = ratelimit.New(gc.RateLimitSmsSent)
 
-	o.AnonymousSignIns = tollbooth.NewLimiter(gc.RateLimitAnonymousUsers/(60*60),
-		&limiter.ExpirableOptions{
-			DefaultExpirationTTL: time.Hour,
-		}).SetBurst(int(gc.RateLimitAnonymousUsers)).SetMethods([]string{"POST"})
-
-	o.Token = tollbooth.NewLimiter(gc.RateLimitTokenRefresh/(60*5),
-		&limiter.ExpirableOptions{
-			DefaultExpirationTTL: time.Hour,
-		}).SetBurst(30)
-
-	o.Verify = tollbooth.NewLimiter(gc.RateLimitVerify/(60*5),
-		&limiter.ExpirableOptions{
-			DefaultExpirationTTL: time.Hour,
-		}).SetBurst(30)
-
-	o.FactorVerify = tollbooth.NewLimiter(gc.MFA.RateLimitChallengeAndVerify/60,
-		&limiter.ExpirableOptions{
-			DefaultExpirationTTL: time.Minute,
-		}).SetBurst(30)
-
-	o.FactorChallenge = tollbooth.NewLimiter(gc.MFA.RateLimitChallengeAndVerify/60,
-		&limiter.ExpirableOptions{
-			DefaultExpirationTTL: time.Minute,
-		}).SetBurst(30)
-
-	o.SSO = tollbooth.NewLimiter(gc.RateLimitSso/(60*5),
-		&limiter.ExpirableOptions{
-			DefaultExpirationTTL: time.Hour,
-		}).SetBurst(30)
-
-	o.SAMLAssertion = tollbooth.NewLimiter(gc.SAML.RateLimitAssertion/(60*5),
-		&limiter.ExpirableOptions{
-			DefaultExpirationTTL: time.Hour,
-		}).SetBurst(30)
-
-	o.Web3 = tollbooth.NewLimiter(gc.RateLimitWeb3/(60*5),
-		&limiter.ExpirableOptions{
-			DefaultExpirationTTL: time.Hour,
-		}).SetBurst(30)
+	store, err := ratelimit.NewStore(gc.RateLimitStore)
+	if err != nil {
+		// the store configuration is validated when loading the
+		// configuration so this is not expected to happen
+		logrus.WithError(err).Error("unable to create rate limit store, falling back to the in-memory store")
+		store = ratelimit.NewMemoryStore()
+	}
+	o.Store = store
+
+	o.AnonymousSignIns = newAnonymousSignInsLimiter(gc.RateLimitAnonymousUsers)
+	o.Token = newLimiterPer5mOver1h(gc.RateLimitTokenRefresh)
+	o.Verify = newLimiterPer5mOver1h(gc.RateLimitVerify)
+	o.FactorVerify = newLimiterPerMinute(gc.MFA.RateLimitChallengeAndVerify)
+	o.FactorChallenge = newLimiterPerMinute(gc.MFA.RateLimitChallengeAndVerify)
+	o.SSO = newLimiterPer5mOver1h(gc.RateLimitSso)
+	o.SAMLAssertion = newLimiterPer5mOver1h(gc.SAML.RateLimitAssertion)
+	o.Web3 = newLimiterPer5mOver1h(gc.RateLimitWeb3)
 
 	// These all use the OTP limit per 5 min with 1hour ttl and burst of 30.
 	o.Recover = newLimiterPer5mOver1h(gc.RateLimitOtp)
@@ -112,6 +103,70 @@ func NewLimiterOptions(gc *conf.GlobalConfiguration) *LimiterOptions {
 	return o
 }
 
+// Reload returns the limiters for gc, a reload of the configuration prev.
+// The limiters whose rate did not change are kept with their counters, the
+// others start over with the new rate. The store is kept, changes to its
+// configuration only apply on restart.
+func (lo *LimiterOptions) Reload(prev, gc *conf.GlobalConfiguration) *LimiterOptions {
+	o := *lo
+
+	if gc.RateLimitEmailSent != prev.RateLimitEmailSent {
+		o.Email = ratelimit.New(gc.RateLimitEmailSent)
+	}
+	if gc.RateLimitSmsSent != prev.RateLimitSmsSent {
+		o.Phone = ratelimit.New(gc.RateLimitSmsSent)
+	}
+	if gc.RateLimitAnonymousUsers != prev.RateLimitAnonymousUsers {
+		o.AnonymousSignIns = newAnonymousSignInsLimiter(gc.RateLimitAnonymousUsers)
+	}
+	if gc.RateLimitTokenRefresh != prev.RateLimitTokenRefresh {
+		o.Token = newLimiterPer5mOver1h(gc.RateLimitTokenRefresh)
+	}
+	if gc.RateLimitVerify != prev.RateLimitVerify {
+		o.Verify = newLimiterPer5mOver1h(gc.RateLimitVerify)
+	}
+	if gc.MFA.RateLimitChallengeAndVerify != prev.MFA.RateLimitChallengeAndVerify {
+		o.FactorVerify = newLimiterPerMinute(gc.MFA.RateLimitChallengeAndVerify)
+		o.FactorChallenge = newLimiterPerMinute(gc.MFA.RateLimitChallengeAndVerify)
+	}
+	if gc.RateLimitSso != prev.RateLimitSso {
+		o.SSO = newLimiterPer5mOver1h(gc.RateLimitSso)
+	}
+	if gc.SAML.RateLimitAssertion != prev.SAML.RateLimitAssertion {
+		o.SAMLAssertion = newLimiterPer5mOver1h(gc.SAML.RateLimitAssertion)
+	}
+	if gc.RateLimitWeb3 != prev.RateLimitWeb3 {
+		o.Web3 = newLimiterPer5mOver1h(gc.RateLimitWeb3)
+	}
+	if gc.RateLimitOtp != prev.RateLimitOtp {
+		o.Recover = newLimiterPer5mOver1h(gc.RateLimitOtp)
+		o.Resend = newLimiterPer5mOver1h(gc.RateLimitOtp)
+		o.MagicLink = newLimiterPer5mOver1h(gc.RateLimitOtp)
+		o.Otp = newLimiterPer5mOver1h(gc.RateLimitOtp)
+		o.User = newLimiterPer5mOver1h(gc.RateLimitOtp)
+		o.Signups = newLimiterPer5mOver1h(gc.RateLimitOtp)
+	}
+	if gc.RateLimitOAuthDynamicClientRegister != prev.RateLimitOAuthDynamicClientRegister {
+		o.OAuthClientRegister = newLimiterPer5mOver1h(gc.RateLimitOAuthDynamicClientRegister)
+	}
+
+	return &o
+}
+
+func newAnonymousSignInsLimiter(rate float64) *limiter.Limiter {
+	return tollbooth.NewLimiter(rate/(60*60),
+		&limiter.ExpirableOptions{
+			DefaultExpirationTTL: time.Hour,
+		}).SetBurst(int(rate)).SetMethods([]string{"POST"})
+}
+
+func newLimiterPerMinute(rate float64) *limiter.Limiter {
+	return tollbooth.NewLimiter(rate/60,
+		&limiter.ExpirableOptions{
+			DefaultExpirationTTL: time.Minute,
+		}).SetBurst(30)
+}
+
 func newLimiterPer5mOver1h(rate float64) *limiter.Limiter {
 	freq := rate / (60 * 5)
 	lim := tollbooth.NewLimiter(freq, &limiter.ExpirableOptions{
@@ -28,3 +28,22 @@ func TestNewLimiterOptions(t *testing.T) {
 	assert.NotNil(t, rl.SSO)
 	assert.NotNil(t, rl.SAMLAssertion)
 }
+
+func TestLimiterOptionsReload(t *testing.T) {
+	prev := &conf.GlobalConfiguration{}
+	prev.ApplyDefaults()
+	prev.RateLimitOtp = 30
+	prev.RateLimitTokenRefresh = 150
+
+	rl := NewLimiterOptions(prev)
+
+	next := *prev
+	next.RateLimitOtp = 60
+
+	reloaded := rl.Reload(prev, &next)
+	assert.Same(t, rl.Token, reloaded.Token)
+	assert.Same(t, rl.Store, reloaded.Store)
+	assert.NotSame(t, rl.Otp, reloaded.Otp)
+	assert.NotSame(t, rl.Signups, reloaded.Signups)
+	assert.Equal(t, float64(60)/(60*5), reloaded.Otp.GetMax())
+}
@@ -0,0 +1,605 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/gofrs/uuid"
+	"github.com/supabase/auth/internal/api/apierrors"
+	"github.com/supabase/auth/internal/models"
+	"github.com/supabase/auth/internal/observability"
+	"github.com/supabase/auth/internal/storage"
+)
+
+const organizationNameMaxLength = 255
+
+var (
+	organizationSlugPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,62}$`)
+	organizationSlugInvalid = regexp.MustCompile(`[^a-z0-9]+`)
+)
+
+// OrganizationParams are the parameters for creating and updating an
+// organization.
+type OrganizationParams struct {
+	Name     string                 `json:"name"`
+	Slug     string                 `json:"slug"`
+	Metadata map[string]interface{} `json:"metadata"`
+
+	// OwnerID is the user the admin API makes the owner of a new
+	// organization. Users creating an organization become its owner.
+	OwnerID *uuid.UUID `json:"owner_id"`
+}
+
+// OrganizationMemberParams are the parameters for adding a member to an
+// organization, who is found by user ID or email, and updating the role of
+// a member.
+type OrganizationMemberParams struct {
+	UserID *uuid.UUID              `json:"user_id"`
+	Email  string                  `json:"email"`
+	Role   models.OrganizationRole `json:"role"`
+}
+
+// AdminListOrganizationsResponse is the response of the admin organizations
+// endpoint.
+type AdminListOrganizationsResponse struct {
+	Organizations []*models.Organization `json:"organizations"`
+}
+
+// UserOrganizationsResponse is the response of the organizations endpoint,
+// with the user's role in each organization.
+type UserOrganizationsResponse struct {
+	Organizations []*models.UserOrganization `json:"organizations"`
+}
+
+// OrganizationMembersResponse is the response of the organization members
+// endpoints.
+type OrganizationMembersResponse struct {
+	Members []*models.OrganizationMember `json:"members"`
+}
+
+// validate checks the name and the slug of the organization, deriving the
+// slug from the name when it is left out of a new organization.
+func (p *OrganizationParams) validate(create bool) error {
+	p.Name = strings.TrimSpace(p.Name)
+	p.Slug = strings.ToLower(strings.TrimSpace(p.Slug))
+
+	if create && p.Name == "" {
+		return apierrors.NewBadRequestError(apierrors.ErrorCodeValidationFailed, "Organization name is required")
+	}
+	if len(p.Name) > organizationNameMaxLength {
+		return apierrors.NewBadRequestError(apierrors.ErrorCodeValidationFailed, "Organization name must be at most %d characters long", organizationNameMaxLength)
+	}
+
+	if create && p.Slug == "" {
+		p.Slug = strings.Trim(organizationSlugInvalid.ReplaceAllString(strings.ToLower(p.Name), "-"), "-")
+		if len(p.Slug) > 63 {
+			p.Slug = strings.TrimRight(p.Slug[:63], "-")
+		}
+	}
+	if p.Slug != "" && !organizationSlugPattern.MatchString(p.Slug) {
+		return apierrors.NewBadRequestError(apierrors.ErrorCodeValidationFailed, "Organization slug must be at most 63 lowercase letters, digits and -")
+	}
+	if create && p.Slug == "" {
+		return apierrors.NewBadRequestError(apierrors.ErrorCodeValidationFailed, "Organization slug is required")
+	}
+
+	return nil
+}
+
+// organizationActor returns the user managing the organization: the member
+// for the organizations endpoints, or the admin for the admin API.
+func organizationActor(ctx context.Context) *models.User {
+	if getOrganizationMember(ctx) != nil {
+		return getUser(ctx)
+	}
+	return getAdminUser(ctx)
+}
+
+// authorizeOrganizationMemberChange checks that the member managing the
+// organization may change a membership from a role to another, where an
+// empty role is no membership. Admin requests have no member and may make
+// any change.
+func authorizeOrganizationMemberChange(actor *models.OrganizationMember, from, to models.OrganizationRole) error {
+	if actor == nil {
+		return nil
+	}
+
+	if !actor.Role.CanManageMembers() {
+		return apierrors.NewForbiddenError(apierrors.ErrorCodeOrganizationPermissionDenied, "Only owners and admins can manage the members of the organization")
+	}
+
+	if actor.Role != models.OrganizationRoleOwner && (from == models.OrganizationRoleOwner || to == models.OrganizationRoleOwner) {
+		return apierrors.NewForbiddenError(apierrors.ErrorCodeOrganizationPermissionDenied, "Only owners can manage the owners of the organization")
+	}
+
+	return nil
+}
+
+// ensureOrganizationKeepsOwner rejects changing the role of the last owner
+// of the organization, or removing the last owner when role is empty.
+func ensureOrganizationKeepsOwner(tx *storage.Connection, organization *models.Organization, member *models.OrganizationMember, role models.OrganizationRole) error {
+	if member.Role != models.OrganizationRoleOwner || role == models.OrganizationRoleOwner {
+		return nil
+	}
+
+	owners, err := organization.CountOwners(tx)
+	if err != nil {
+		return apierrors.NewInternalServerError("Database error counting organization owners").WithInternalError(err)
+	}
+	if owners <= 1 {
+		return apierrors.NewUnprocessableEntityError(apierrors.ErrorCodeOrganizationOwnerRequired, "An organization must have at least one owner")
+	}
+
+	return nil
+}
+
+// loadOrganization loads the organization of an admin request.
+func (a *API) loadOrganization(w http.ResponseWriter, r *http.Request) (context.Context, error) {
+	ctx := r.Context()
+	db := a.db.WithContext(ctx)
+
+	organizationID, err := uuid.FromString(chi.URLParam(r, "organization_id"))
+	if err != nil {
+		return nil, apierrors.NewNotFoundError(apierrors.ErrorCodeValidationFailed, "organization_id must be an UUID")
+	}
+
+	observability.LogEntrySetField(r, "organization_id", organizationID)
+
+	organization, err := models.FindOrganizationByID(db, organizationID)
+	if err != nil {
+		if models.IsNotFoundError(err) {
+			return nil, apierrors.NewNotFoundError(apierrors.ErrorCodeOrganizationNotFound, "Organization not found")
+		}
+		return nil, apierrors.NewInternalServerError("Database error loading organization").WithInternalError(err)
+	}
+
+	return withOrganization(ctx, organization), nil
+}
+
+// loadOrganizationMembership loads the organization and the membership of
+// the user in it. Organizations the user is not a member of are not found.
+func (a *API) loadOrganizationMembership(w http.ResponseWriter, r *http.Request) (context.Context, error) {
+	ctx, err := a.loadOrganization(w, r)
+	if err != nil {
+		return nil, err
+	}
+	db := a.db.WithContext(ctx)
+
+	member, err := getOrganization(ctx).FindMember(db, getUser(ctx).ID)
+	if err != nil {
+		if models.IsNotFoundError(err) {
+			return nil, apierrors.NewNotFoundError(apierrors.ErrorCodeOrganizationNotFound, "Organization not found")
+		}
+		return nil, apierrors.NewInternalServerError("Database error loading organization member").WithInternalError(err)
+	}
+
+	return withOrganizationMember(ctx, member), nil
+}
+
+// createOrganization creates the organization, with the owner as its first
+// member when there is one.
+func (a *API) createOrganization(r *http.Request, params *OrganizationParams, owner *models.User) (*models.Organization, error) {
+	ctx := r.Context()
+	db := a.db.WithContext(ctx)
+	config := a.config
+
+	if err := params.validate(true); err != nil {
+		return nil, err
+	}
+
+	organization := models.NewOrganization(params.Name, params.Slug, params.Metadata)
+	err := db.Transaction(func(tx *storage.Connection) error {
+		if _, terr := models.FindOrganizationBySlug(tx, organization.Slug); terr == nil {
+			return apierrors.NewUnprocessableEntityError(apierrors.ErrorCodeOrganizationSlugExists, "An organization with this slug already exists")
+		} else if !models.IsNotFoundError(terr) {
+			return apierrors.NewInternalServerError("Database error finding organization").WithInternalError(terr)
+		}
+
+		if terr := tx.Create(organization); terr != nil {
+			return apierrors.NewInternalServerError("Database error creating organization").WithInternalError(terr)
+		}
+
+		if owner != nil {
+			if _, terr := organization.AddMember(tx, owner.ID, models.OrganizationRoleOwner); terr != nil {
+				return apierrors.NewInternalServerError("Database error adding organization owner").WithInternalError(terr)
+			}
+		}
+
+		return models.NewAuditLogEntry(config.AuditLog, r, tx, organizationActor(ctx), models.OrganizationCreatedAction, "", map[string]interface{}{
+			"organization_id":   organization.ID,
+			"organization_slug": organization.Slug,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return organization, nil
+}
+
+// adminOrganizations lists the organizations, optionally only those whose
+// name or slug contains the filter query parameter.
+func (a *API) adminOrganizations(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	db := a.db.WithContext(ctx)
+
+	pageParams, err := paginate(r)
+	if err != nil {
+		return apierrors.NewBadRequestError(apierrors.ErrorCodeValidationFailed, "Bad Pagination Parameters: %v", err).WithInternalError(err)
+	}
+
+	organizations, err := models.FindOrganizations(db, pageParams, r.URL.Query().Get("filter"))
+	if err != nil {
+		return apierrors.NewInternalServerError("Database error finding organizations").WithInternalError(err)
+	}
+	addPaginationHeaders(w, r, pageParams)
+
+	return sendJSON(w, http.StatusOK, AdminListOrganizationsResponse{
+		Organizations: organizations,
+	})
+}
+
+// adminOrganizationCreate creates an organization, owned by the user of
+// owner_id when it is set.
+func (a *API) adminOrganizationCreate(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	db := a.db.WithContext(ctx)
+
+	params := &OrganizationParams{}
+	if err := retrieveRequestParams(r, params); err != nil {
+		return err
+	}
+
+	var owner *models.User
+	if params.OwnerID != nil {
+		var err error
+		owner, err = models.FindUserByID(db, *params.OwnerID)
+		if err != nil {
+			if models.IsNotFoundError(err) {
+				return apierrors.NewNotFoundError(apierrors.ErrorCodeUserNotFound, "User not found")
+			}
+			return apierrors.NewInternalServerError("Database error finding user").WithInternalError(err)
+		}
+	}
+
+	organization, err := a.createOrganization(r, params, owner)
+	if err != nil {
+		return err
+	}
+
+	return sendJSON(w, http.StatusCreated, organization)
+}
+
+// UserOrganizations lists the organizations the user is a member of.
+func (a *API) UserOrganizations(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	db := a.db.WithContext(ctx)
+
+	organizations, err := models.FindUserOrganizations(db, getUser(ctx).ID)
+	if err != nil {
+		return apierrors.NewInternalServerError("Database error finding organizations").WithInternalError(err)
+	}
+
+	return sendJSON(w, http.StatusOK, UserOrganizationsResponse{
+		Organizations: organizations,
+	})
+}
+
+// CreateOrganization creates an organization owned by the user.
+func (a *API) CreateOrganization(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	user := getUser(ctx)
+
+	if !a.config.Organizations.SelfServiceCreate {
+		return apierrors.NewForbiddenError(apierrors.ErrorCodeOrganizationPermissionDenied, "Organizations can only be created by an admin")
+	}
+
+	params := &OrganizationParams{}
+	if err := retrieveRequestParams(r, params); err != nil {
+		return err
+	}
+	params.OwnerID = nil
+
+	organization, err := a.createOrganization(r, params, user)
+	if err != nil {
+		return err
+	}
+
+	return sendJSON(w, http.StatusCreated, &models.UserOrganization{
+		Organization: *organization,
+		Role:         models.OrganizationRoleOwner,
+	})
+}
+
+// organizationGet returns the organization, with the role of the user for
+// the organizations endpoints.
+func (a *API) organizationGet(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	organization := getOrganization(ctx)
+
+	if member := getOrganizationMember(ctx); member != nil {
+		return sendJSON(w, http.StatusOK, &models.UserOrganization{
+			Organization: *organization,
+			Role:         member.Role,
+		})
+	}
+
+	return sendJSON(w, http.StatusOK, organization)
+}
+
+// organizationUpdate updates the name, slug or metadata of the organization.
+// Empty values keep the current ones.
+func (a *API) organizationUpdate(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	db := a.db.WithContext(ctx)
+	config := a.config
+	organization := getOrganization(ctx)
+
+	if member := getOrganizationMember(ctx); member != nil && !member.Role.CanManageMembers() {
+		return apierrors.NewForbiddenError(apierrors.ErrorCodeOrganizationPermissionDenied, "Only owners and admins can update the organization")
+	}
+
+	params := &OrganizationParams{}
+	if err := retrieveRequestParams(r, params); err != nil {
+		return err
+	}
+	if err := params.validate(false); err != nil {
+		return err
+	}
+
+	err := db.Transaction(func(tx *storage.Connection) error {
+		if params.Slug != "" && params.Slug != organization.Slug {
+			if _, terr := models.FindOrganizationBySlug(tx, params.Slug); terr == nil {
+				return apierrors.NewUnprocessableEntityError(apierrors.ErrorCodeOrganizationSlugExists, "An organization with this slug already exists")
+			} else if !models.IsNotFoundError(terr) {
+				return apierrors.NewInternalServerError("Database error finding organization").WithInternalError(terr)
+			}
+			organization.Slug = params.Slug
+		}
+		if params.Name != "" {
+			organization.Name = params.Name
+		}
+		if params.Metadata != nil {
+			organization.Metadata = params.Metadata
+		}
+
+		if terr := tx.UpdateOnly(organization, "name", "slug", "metadata", "updated_at"); terr != nil {
+			return apierrors.NewInternalServerError("Database error updating organization").WithInternalError(terr)
+		}
+
+		return models.NewAuditLogEntry(config.AuditLog, r, tx, organizationActor(ctx), models.OrganizationUpdatedAction, "", map[string]interface{}{
+			"organization_id":   organization.ID,
+			"organization_slug": organization.Slug,
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	return a.organizationGet(w, r)
+}
+
+// organizationDelete deletes the organization and its memberships.
+func (a *API) organizationDelete(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	db := a.db.WithContext(ctx)
+	config := a.config
+	organization := getOrganization(ctx)
+
+	if member := getOrganizationMember(ctx); member != nil && member.Role != models.OrganizationRoleOwner {
+		return apierrors.NewForbiddenError(apierrors.ErrorCodeOrganizationPermissionDenied, "Only owners can delete the organization")
+	}
+
+	err := db.Transaction(func(tx *storage.Connection) error {
+		if terr := models.NewAuditLogEntry(config.AuditLog, r, tx, organizationActor(ctx), models.OrganizationDeletedAction, "", map[string]interface{}{
+			"organization_id":   organization.ID,
+			"organization_slug": organization.Slug,
+		}); terr != nil {
+			return terr
+		}
+
+		if terr := tx.Destroy(organization); terr != nil {
+			return apierrors.NewInternalServerError("Database error deleting organization").WithInternalError(terr)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+// organizationMembers lists the members of the organization.
+func (a *API) organizationMembers(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	db := a.db.WithContext(ctx)
+
+	members, err := getOrganization(ctx).Members(db)
+	if err != nil {
+		return apierrors.NewInternalServerError("Database error finding organization members").WithInternalError(err)
+	}
+
+	return sendJSON(w, http.StatusOK, OrganizationMembersResponse{
+		Members: members,
+	})
+}
+
+// organizationMemberAdd adds a user to the organization, as a member unless
+// another role is set.
+func (a *API) organizationMemberAdd(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	db := a.db.WithContext(ctx)
+	config := a.config
+	organization := getOrganization(ctx)
+
+	params := &OrganizationMemberParams{}
+	if err := retrieveRequestParams(r, params); err != nil {
+		return err
+	}
+
+	if params.Role == "" {
+		params.Role = models.OrganizationRoleMember
+	}
+	if !params.Role.IsValid() {
+		return apierrors.NewBadRequestError(apierrors.ErrorCodeValidationFailed, "Role must be owner, admin or member")
+	}
+
+	if err := authorizeOrganizationMemberChange(getOrganizationMember(ctx), "", params.Role); err != nil {
+		return err
+	}
+
+	var user *models.User
+	var err error
+	switch {
+	case params.UserID != nil:
+		user, err = models.FindUserByID(db, *params.UserID)
+	case params.Email != "":
+		user, err = models.FindUserByEmailAndAudience(db, params.Email, a.requestAud(ctx, r))
+	default:
+		return apierrors.NewBadRequestError(apierrors.ErrorCodeValidationFailed, "A user_id or email is required")
+	}
+	if err != nil {
+		if models.IsNotFoundError(err) {
+			return apierrors.NewNotFoundError(apierrors.ErrorCodeUserNotFound, "User not found")
+		}
+		return apierrors.NewInternalServerError("Database error finding user").WithInternalError(err)
+	}
+
+	var member *models.OrganizationMember
+	err = db.Transaction(func(tx *storage.Connection) error {
+		if _, terr := organization.FindMember(tx, user.ID); terr == nil {
+			return apierrors.NewUnprocessableEntityError(apierrors.ErrorCodeOrganizationMemberExists, "User is already a member of the organization")
+		} else if !models.IsNotFoundError(terr) {
+			return apierrors.NewInternalServerError("Database error finding organization member").WithInternalError(terr)
+		}
+
+		var terr error
+		if member, terr = organization.AddMember(tx, user.ID, params.Role); terr != nil {
+			return apierrors.NewInternalServerError("Database error adding organization member").WithInternalError(terr)
+		}
+
+		return models.NewAuditLogEntry(config.AuditLog, r, tx, organizationActor(ctx), models.OrganizationMemberAddedAction, "", map[string]interface{}{
+			"organization_id": organization.ID,
+			"user_id":         user.ID,
+			"role":            member.Role,
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	return sendJSON(w, http.StatusCreated, member)
+}
+
+// findOrganizationMember finds the member of the request's user_id.
+func (a *API) findOrganizationMember(r *http.Request) (*models.OrganizationMember, error) {
+	ctx := r.Context()
+	db := a.db.WithContext(ctx)
+
+	userID, err := uuid.FromString(chi.URLParam(r, "user_id"))
+	if err != nil {
+		return nil, apierrors.NewNotFoundError(apierrors.ErrorCodeValidationFailed, "user_id must be an UUID")
+	}
+
+	member, err := getOrganization(ctx).FindMember(db, userID)
+	if err != nil {
+		if models.IsNotFoundError(err) {
+			return nil, apierrors.NewNotFoundError(apierrors.ErrorCodeOrganizationMemberNotFound, "Organization member not found")
+		}
+		return nil, apierrors.NewInternalServerError("Database error finding organization member").WithInternalError(err)
+	}
+
+	return member, nil
+}
+
+// organizationMemberUpdate changes the role of a member.
+func (a *API) organizationMemberUpdate(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	db := a.db.WithContext(ctx)
+	config := a.config
+	organization := getOrganization(ctx)
+
+	member, err := a.findOrganizationMember(r)
+	if err != nil {
+		return err
+	}
+
+	params := &OrganizationMemberParams{}
+	if err := retrieveRequestParams(r, params); err != nil {
+		return err
+	}
+	if !params.Role.IsValid() {
+		return apierrors.NewBadRequestError(apierrors.ErrorCodeValidationFailed, "Role must be owner, admin or member")
+	}
+
+	if err := authorizeOrganizationMemberChange(getOrganizationMember(ctx), member.Role, params.Role); err != nil {
+		return err
+	}
+
+	err = db.Transaction(func(tx *storage.Connection) error {
+		if terr := ensureOrganizationKeepsOwner(tx, organization, member, params.Role); terr != nil {
+			return terr
+		}
+
+		if terr := member.UpdateRole(tx, params.Role); terr != nil {
+			return apierrors.NewInternalServerError("Database error updating organization member").WithInternalError(terr)
+		}
+
+		return models.NewAuditLogEntry(config.AuditLog, r, tx, organizationActor(ctx), models.OrganizationMemberUpdatedAction, "", map[string]interface{}{
+			"organization_id": organization.ID,
+			"user_id":         member.UserID,
+			"role":            member.Role,
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	return sendJSON(w, http.StatusOK, member)
+}
+
+// organizationMemberRemove removes a member from the organization. Members
+// can always remove themselves to leave the organization.
+func (a *API) organizationMemberRemove(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	db := a.db.WithContext(ctx)
+	config := a.config
+	organization := getOrganization(ctx)
+
+	member, err := a.findOrganizationMember(r)
+	if err != nil {
+		return err
+	}
+
+	if actor := getOrganizationMember(ctx); actor == nil || actor.UserID != member.UserID {
+		if err := authorizeOrganizationMemberChange(actor, member.Role, ""); err != nil {
+			return err
+		}
+	}
+
+	err = db.Transaction(func(tx *storage.Connection) error {
+		if terr := ensureOrganizationKeepsOwner(tx, organization, member, ""); terr != nil {
+			return terr
+		}
+
+		if terr := member.Delete(tx); terr != nil {
+			return apierrors.NewInternalServerError("Database error removing organization member").WithInternalError(terr)
+		}
+
+		return models.NewAuditLogEntry(config.AuditLog, r, tx, organizationActor(ctx), models.OrganizationMemberRemovedAction, "", map[string]interface{}{
+			"organization_id": organization.ID,
+			"user_id":         member.UserID,
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
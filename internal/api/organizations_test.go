@@ -0,0 +1,244 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	jwt "github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"github.com/supabase/auth/internal/conf"
+	"github.com/supabase/auth/internal/models"
+)
+
+type OrganizationsTestSuite struct {
+	suite.Suite
+	API    *API
+	Config *conf.GlobalConfiguration
+
+	adminToken string
+}
+
+func TestOrganizations(t *testing.T) {
+	api, config, err := setupAPIForTest()
+	require.NoError(t, err)
+
+	ts := &OrganizationsTestSuite{
+		API:    api,
+		Config: config,
+	}
+	defer api.db.Close()
+
+	suite.Run(t, ts)
+}
+
+func (ts *OrganizationsTestSuite) SetupTest() {
+	models.TruncateAll(ts.API.db)
+	ts.Config.Organizations.Enabled = true
+	ts.Config.Organizations.SelfServiceCreate = true
+
+	claims := &AccessTokenClaims{
+		Role: "supabase_admin",
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(ts.Config.JWT.Secret))
+	require.NoError(ts.T(), err, "Error generating admin jwt")
+	ts.adminToken = token
+}
+
+func (ts *OrganizationsTestSuite) createUser(email string) (*models.User, string) {
+	user, err := models.NewUser("", email, "password", ts.Config.JWT.Aud, nil)
+	require.NoError(ts.T(), err)
+	require.NoError(ts.T(), ts.API.db.Create(user))
+
+	return user, ts.userToken(user)
+}
+
+func (ts *OrganizationsTestSuite) userToken(user *models.User) string {
+	session, err := models.NewSession(user.ID, nil)
+	require.NoError(ts.T(), err)
+	require.NoError(ts.T(), ts.API.db.Create(session))
+
+	req := httptest.NewRequest(http.MethodPost, "/token?grant_type=password", nil)
+	token, _, err := ts.API.generateAccessToken(req, ts.API.db, user, &session.ID, models.PasswordGrant)
+	require.NoError(ts.T(), err)
+
+	return token
+}
+
+func (ts *OrganizationsTestSuite) request(token, method, path string, body interface{}) *httptest.ResponseRecorder {
+	var buf bytes.Buffer
+	if body != nil {
+		require.NoError(ts.T(), json.NewEncoder(&buf).Encode(body))
+	}
+
+	req := httptest.NewRequest(method, "http://localhost"+path, &buf)
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	ts.API.handler.ServeHTTP(w, req)
+	return w
+}
+
+func (ts *OrganizationsTestSuite) TestSelfService() {
+	alice, aliceToken := ts.createUser("alice@example.com")
+	bob, bobToken := ts.createUser("bob@example.com")
+
+	w := ts.request(aliceToken, http.MethodPost, "/organizations", &OrganizationParams{Name: "Acme Corp"})
+	require.Equal(ts.T(), http.StatusCreated, w.Code, w.Body.String())
+	var organization models.UserOrganization
+	require.NoError(ts.T(), json.NewDecoder(w.Body).Decode(&organization))
+	require.Equal(ts.T(), "acme-corp", organization.Slug)
+	require.Equal(ts.T(), models.OrganizationRoleOwner, organization.Role)
+	path := "/organizations/" + organization.ID.String()
+
+	// users that are not members can't see the organization
+	w = ts.request(bobToken, http.MethodGet, path, nil)
+	require.Equal(ts.T(), http.StatusNotFound, w.Code)
+
+	w = ts.request(aliceToken, http.MethodPost, path+"/members", &OrganizationMemberParams{Email: "bob@example.com"})
+	require.Equal(ts.T(), http.StatusCreated, w.Code, w.Body.String())
+
+	w = ts.request(aliceToken, http.MethodPost, path+"/members", &OrganizationMemberParams{Email: "bob@example.com"})
+	require.Equal(ts.T(), http.StatusUnprocessableEntity, w.Code)
+
+	w = ts.request(bobToken, http.MethodGet, path+"/members", nil)
+	require.Equal(ts.T(), http.StatusOK, w.Code, w.Body.String())
+	var members OrganizationMembersResponse
+	require.NoError(ts.T(), json.NewDecoder(w.Body).Decode(&members))
+	require.Len(ts.T(), members.Members, 2)
+
+	w = ts.request(bobToken, http.MethodGet, "/organizations", nil)
+	require.Equal(ts.T(), http.StatusOK, w.Code, w.Body.String())
+	var organizations UserOrganizationsResponse
+	require.NoError(ts.T(), json.NewDecoder(w.Body).Decode(&organizations))
+	require.Len(ts.T(), organizations.Organizations, 1)
+	require.Equal(ts.T(), models.OrganizationRoleMember, organizations.Organizations[0].Role)
+
+	// members don't manage the organization
+	w = ts.request(bobToken, http.MethodPut, path, &OrganizationParams{Name: "Bob Corp"})
+	require.Equal(ts.T(), http.StatusForbidden, w.Code)
+	w = ts.request(bobToken, http.MethodPut, path+"/members/"+bob.ID.String(), &OrganizationMemberParams{Role: models.OrganizationRoleOwner})
+	require.Equal(ts.T(), http.StatusForbidden, w.Code)
+
+	// the last owner can't leave
+	aliceMember := path + "/members/" + alice.ID.String()
+	w = ts.request(aliceToken, http.MethodPut, aliceMember, &OrganizationMemberParams{Role: models.OrganizationRoleAdmin})
+	require.Equal(ts.T(), http.StatusUnprocessableEntity, w.Code)
+	w = ts.request(aliceToken, http.MethodDelete, aliceMember, nil)
+	require.Equal(ts.T(), http.StatusUnprocessableEntity, w.Code)
+
+	// members can leave
+	w = ts.request(bobToken, http.MethodDelete, path+"/members/"+bob.ID.String(), nil)
+	require.Equal(ts.T(), http.StatusNoContent, w.Code, w.Body.String())
+
+	w = ts.request(aliceToken, http.MethodDelete, path, nil)
+	require.Equal(ts.T(), http.StatusNoContent, w.Code, w.Body.String())
+	w = ts.request(aliceToken, http.MethodGet, path, nil)
+	require.Equal(ts.T(), http.StatusNotFound, w.Code)
+}
+
+func (ts *OrganizationsTestSuite) TestSelfServiceCreateDisabled() {
+	ts.Config.Organizations.SelfServiceCreate = false
+	_, token := ts.createUser("alice@example.com")
+
+	w := ts.request(token, http.MethodPost, "/organizations", &OrganizationParams{Name: "Acme"})
+	require.Equal(ts.T(), http.StatusForbidden, w.Code)
+}
+
+func (ts *OrganizationsTestSuite) TestDisabled() {
+	ts.Config.Organizations.Enabled = false
+	_, token := ts.createUser("alice@example.com")
+
+	w := ts.request(token, http.MethodGet, "/organizations", nil)
+	require.Equal(ts.T(), http.StatusNotFound, w.Code)
+	w = ts.request(ts.adminToken, http.MethodGet, "/admin/organizations", nil)
+	require.Equal(ts.T(), http.StatusNotFound, w.Code)
+}
+
+func (ts *OrganizationsTestSuite) TestAdmin() {
+	owner, _ := ts.createUser("owner@example.com")
+	member, memberToken := ts.createUser("member@example.com")
+
+	w := ts.request(ts.adminToken, http.MethodPost, "/admin/organizations", &OrganizationParams{
+		Name:     "Acme",
+		Slug:     "acme",
+		Metadata: map[string]interface{}{"plan": "pro"},
+		OwnerID:  &owner.ID,
+	})
+	require.Equal(ts.T(), http.StatusCreated, w.Code, w.Body.String())
+	var organization models.Organization
+	require.NoError(ts.T(), json.NewDecoder(w.Body).Decode(&organization))
+	require.Equal(ts.T(), "pro", organization.Metadata["plan"])
+	path := "/admin/organizations/" + organization.ID.String()
+
+	w = ts.request(ts.adminToken, http.MethodPost, "/admin/organizations", &OrganizationParams{Name: "Other", Slug: "acme"})
+	require.Equal(ts.T(), http.StatusUnprocessableEntity, w.Code)
+
+	w = ts.request(ts.adminToken, http.MethodGet, "/admin/organizations?filter=acm", nil)
+	require.Equal(ts.T(), http.StatusOK, w.Code, w.Body.String())
+	var organizations AdminListOrganizationsResponse
+	require.NoError(ts.T(), json.NewDecoder(w.Body).Decode(&organizations))
+	require.Len(ts.T(), organizations.Organizations, 1)
+
+	w = ts.request(ts.adminToken, http.MethodPost, path+"/members", &OrganizationMemberParams{UserID: &member.ID, Role: models.OrganizationRoleAdmin})
+	require.Equal(ts.T(), http.StatusCreated, w.Code, w.Body.String())
+
+	w = ts.request(ts.adminToken, http.MethodPut, path+"/members/"+member.ID.String(), &OrganizationMemberParams{Role: "superuser"})
+	require.Equal(ts.T(), http.StatusBadRequest, w.Code)
+
+	// admins can't make themselves owners
+	w = ts.request(memberToken, http.MethodPut, "/organizations/"+organization.ID.String()+"/members/"+member.ID.String(), &OrganizationMemberParams{Role: models.OrganizationRoleOwner})
+	require.Equal(ts.T(), http.StatusForbidden, w.Code)
+
+	w = ts.request(ts.adminToken, http.MethodPut, path+"/members/"+member.ID.String(), &OrganizationMemberParams{Role: models.OrganizationRoleOwner})
+	require.Equal(ts.T(), http.StatusOK, w.Code, w.Body.String())
+
+	w = ts.request(ts.adminToken, http.MethodDelete, path+"/members/"+owner.ID.String(), nil)
+	require.Equal(ts.T(), http.StatusNoContent, w.Code, w.Body.String())
+
+	w = ts.request(ts.adminToken, http.MethodDelete, path, nil)
+	require.Equal(ts.T(), http.StatusNoContent, w.Code, w.Body.String())
+}
+
+func (ts *OrganizationsTestSuite) TestAccessTokenClaims() {
+	user, _ := ts.createUser("alice@example.com")
+
+	organization := models.NewOrganization("Acme", "acme", nil)
+	require.NoError(ts.T(), ts.API.db.Create(organization))
+	_, err := organization.AddMember(ts.API.db, user.ID, models.OrganizationRoleAdmin)
+	require.NoError(ts.T(), err)
+
+	token := ts.userToken(user)
+	claims := &AccessTokenClaims{}
+	_, err = jwt.ParseWithClaims(token, claims, func(token *jwt.Token) (interface{}, error) {
+		return []byte(ts.Config.JWT.Secret), nil
+	})
+	require.NoError(ts.T(), err)
+	require.Equal(ts.T(), map[string]string{organization.ID.String(): "admin"}, claims.Organizations)
+
+	ts.Config.Organizations.Enabled = false
+	token = ts.userToken(user)
+	claims = &AccessTokenClaims{}
+	_, err = jwt.ParseWithClaims(token, claims, func(token *jwt.Token) (interface{}, error) {
+		return []byte(ts.Config.JWT.Secret), nil
+	})
+	require.NoError(ts.T(), err)
+	require.Empty(ts.T(), claims.Organizations)
+}
+
+func TestOrganizationParamsValidate(t *testing.T) {
+	params := &OrganizationParams{Name: "  Acme Corp. (EU)  "}
+	require.NoError(t, params.validate(true))
+	require.Equal(t, "Acme Corp. (EU)", params.Name)
+	require.Equal(t, "acme-corp-eu", params.Slug)
+
+	require.Error(t, (&OrganizationParams{}).validate(true))
+	require.Error(t, (&OrganizationParams{Name: "!!!"}).validate(true))
+	require.Error(t, (&OrganizationParams{Name: "Acme", Slug: "acme corp"}).validate(true))
+	require.NoError(t, (&OrganizationParams{}).validate(false))
+}
@@ -229,6 +229,11 @@ func (ts *OtpTestSuite) TestOtp() {
 			data := make(map[string]interface{})
 			require.NoError(ts.T(), json.NewDecoder(w.Body).Decode(&data))
 
+			// every error is returned with the ID of the request
+			require.NotEmpty(ts.T(), data["error_id"])
+			require.Equal(ts.T(), w.Header().Get("x-sb-error-id"), data["error_id"])
+			delete(data, "error_id")
+
 			// response should be empty
 			assert.Equal(ts.T(), data, c.expected.response)
 		})
@@ -253,6 +258,8 @@ func (ts *OtpTestSuite) TestNoSignupsForOtp() {
 
 	data := make(map[string]interface{})
 	require.NoError(ts.T(), json.NewDecoder(w.Body).Decode(&data))
+	require.NotEmpty(ts.T(), data["error_id"])
+	delete(data, "error_id")
 
 	// response should be empty
 	assert.Equal(ts.T(), data, map[string]interface{}{
@@ -3,7 +3,9 @@ package api
 import (
 	"context"
 	"fmt"
+	"math"
 	"strings"
+	"unicode"
 
 	"github.com/sirupsen/logrus"
 	"github.com/supabase/auth/internal/api/apierrors"
@@ -17,15 +19,29 @@ const MaxPasswordLength = 72
 // a HTTPError with a special weak_password field that encodes the Reasons
 // slice.
 type WeakPasswordError struct {
-	Message string   `json:"message,omitempty"`
-	Reasons []string `json:"reasons,omitempty"`
+	Message      string                `json:"message,omitempty"`
+	Reasons      []string              `json:"reasons,omitempty"`
+	Requirements *PasswordRequirements `json:"requirements,omitempty"`
+}
+
+// PasswordRequirements are the settings of the rules of the password policy
+// a password failed, so clients can explain them without parsing the
+// message.
+type PasswordRequirements struct {
+	MinLength          int      `json:"min_length,omitempty"`
+	RequiredCharacters []string `json:"required_characters,omitempty"`
+	MinScore           int      `json:"min_score,omitempty"`
 }
 
 func (e *WeakPasswordError) Error() string {
 	return e.Message
 }
 
-func (a *API) checkPasswordStrength(ctx context.Context, password string) error {
+// checkPasswordStrength validates password against the configured password
+// policy. The userInputs, typically the user's email addresses, are treated
+// as easily guessable when scoring the password and are rejected outright
+// when GOTRUE_PASSWORD_REJECT_EMAIL is set.
+func (a *API) checkPasswordStrength(ctx context.Context, password string, userInputs ...string) error {
 	config := a.config
 
 	if len(password) > MaxPasswordLength {
@@ -37,15 +53,18 @@ func (a *API) checkPasswordStrength(ctx context.Context, password string) error
 	}
 
 	var messages, reasons []string
+	var requirements PasswordRequirements
 
 	if len(password) < config.Password.MinLength {
 		reasons = append(reasons, "length")
 		messages = append(messages, fmt.Sprintf("Password should be at least %d characters.", config.Password.MinLength))
+		requirements.MinLength = config.Password.MinLength
 	}
 
 	for _, characterSet := range config.Password.RequiredCharacters {
 		if characterSet != "" && !strings.ContainsAny(password, characterSet) {
 			reasons = append(reasons, "characters")
+			requirements.RequiredCharacters = config.Password.RequiredCharacters
 
 			messages = append(messages, fmt.Sprintf("Password should contain at least one character of each: %s.", strings.Join(config.Password.RequiredCharacters, ", ")))
 
@@ -53,14 +72,21 @@ func (a *API) checkPasswordStrength(ctx context.Context, password string) error
 		}
 	}
 
-	if config.Password.HIBP.Enabled {
-		pwned, err := a.hibpClient.Check(ctx, password)
+	if config.Password.RejectEmail && passwordContainsEmail(password, userInputs) {
+		reasons = append(reasons, "email")
+		messages = append(messages, "Password should not contain your email address.")
+	}
+
+	if config.Password.MinScore > 0 && passwordScore(password, userInputs...) < config.Password.MinScore {
+		reasons = append(reasons, "score")
+		messages = append(messages, "Password is too easy to guess, please choose a stronger one.")
+		requirements.MinScore = config.Password.MinScore
+	}
+
+	if config.Password.HIBP.Enabled && !config.Password.HIBP.AuditOnly {
+		pwned, err := a.isPasswordPwned(ctx, password)
 		if err != nil {
-			if config.Password.HIBP.FailClosed {
-				return apierrors.NewInternalServerError("Unable to perform password strength check with HaveIBeenPwned.org.").WithInternalError(err)
-			} else {
-				logrus.WithError(err).Warn("Unable to perform password strength check with HaveIBeenPwned.org, pwned passwords are being allowed")
-			}
+			return err
 		} else if pwned {
 			reasons = append(reasons, "pwned")
 			messages = append(messages, "Password is known to be weak and easy to guess, please choose a different one.")
@@ -68,11 +94,250 @@ func (a *API) checkPasswordStrength(ctx context.Context, password string) error
 	}
 
 	if len(reasons) > 0 {
-		return &WeakPasswordError{
+		e := &WeakPasswordError{
 			Message: strings.Join(messages, " "),
 			Reasons: reasons,
 		}
+		if requirements.MinLength != 0 || requirements.RequiredCharacters != nil || requirements.MinScore != 0 {
+			e.Requirements = &requirements
+		}
+		return e
 	}
 
 	return nil
 }
+
+// isPasswordPwned checks the password against the HaveIBeenPwned.org Pwned
+// Passwords API. Unless GOTRUE_PASSWORD_HIBP_FAIL_CLOSED is set, failures to
+// reach the API are logged and the password is treated as not pwned.
+func (a *API) isPasswordPwned(ctx context.Context, password string) (bool, error) {
+	pwned, err := a.hibpClient.Check(ctx, password)
+	if err != nil {
+		if a.config.Password.HIBP.FailClosed {
+			return false, apierrors.NewInternalServerError("Unable to perform password strength check with HaveIBeenPwned.org.").WithInternalError(err)
+		}
+
+		logrus.WithError(err).Warn("Unable to perform password strength check with HaveIBeenPwned.org, pwned passwords are being allowed")
+		return false, nil
+	}
+
+	return pwned, nil
+}
+
+// shouldAuditPwnedPassword reports whether a new password should be recorded
+// in the audit log as pwned. It only checks the password when
+// GOTRUE_PASSWORD_HIBP_AUDIT_ONLY is set, as otherwise pwned passwords are
+// rejected by checkPasswordStrength.
+func (a *API) shouldAuditPwnedPassword(ctx context.Context, password string) (bool, error) {
+	config := a.config
+
+	if !config.Password.HIBP.Enabled || !config.Password.HIBP.AuditOnly || password == "" {
+		return false, nil
+	}
+
+	return a.isPasswordPwned(ctx, password)
+}
+
+// passwordContainsEmail reports whether password contains any of the email
+// addresses, or their local parts, in a case-insensitive manner.
+func passwordContainsEmail(password string, emails []string) bool {
+	password = strings.ToLower(password)
+
+	for _, email := range emails {
+		email = strings.ToLower(strings.TrimSpace(email))
+		if email == "" {
+			continue
+		}
+
+		if strings.Contains(password, email) {
+			return true
+		}
+
+		if at := strings.LastIndexByte(email, '@'); at >= 3 && strings.Contains(password, email[:at]) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// commonPasswords is a short list of the most frequently used passwords and
+// password fragments, ordered by popularity. The position in the list is used
+// as the number of guesses an attacker needs to find the word.
+var commonPasswords = []string{
+	"password", "123456", "qwerty", "letmein", "welcome", "admin", "monkey",
+	"dragon", "football", "baseball", "master", "login", "princess", "sunshine",
+	"iloveyou", "shadow", "superman", "michael", "trustno1", "starwars",
+	"whatever", "freedom", "hello", "charlie", "secret", "access", "flower",
+	"hunter", "ninja", "mustang", "soccer", "hockey", "killer", "batman",
+	"pepper", "jordan", "harley", "ranger", "buster", "thomas", "tigger",
+	"robert", "jennifer", "summer", "winter", "spring", "autumn", "love",
+	"lovely", "angel", "cookie", "cheese", "chocolate", "computer", "internet",
+	"google", "apple", "orange", "banana", "purple", "silver", "golden",
+	"diamond", "matrix", "passw0rd", "changeme", "default", "guest", "root",
+	"test", "user", "pass", "god", "money", "lucky", "happy", "family",
+	"friend", "daniel", "andrew", "joshua", "maggie", "ginger", "hannah",
+	"jessica", "ashley", "nicole", "amanda", "samsung", "mickey", "mercedes",
+	"ferrari", "liverpool", "chelsea", "arsenal", "yankees", "dallas", "tennis",
+	"guitar", "music", "qazwsx", "zaq1", "abc", "asdf", "zxcv", "pokemon",
+	"minecraft", "naruto", "blink", "snoopy", "garfield", "spider",
+	"rainbow", "butterfly", "beautiful", "sweet", "honey", "baby", "super",
+	"magic", "power", "dream", "heaven", "hell", "devil", "jesus", "christ",
+	"peace", "smile", "forever", "always", "never", "nothing", "something",
+	"company", "business", "office", "server", "system", "database", "network",
+	"supabase", "gotrue", "auth",
+}
+
+var commonPasswordRanks = func() map[string]int {
+	ranks := make(map[string]int, len(commonPasswords))
+	for i, word := range commonPasswords {
+		ranks[word] = i + 1
+	}
+	return ranks
+}()
+
+var passwordKeyboardRows = []string{
+	"`1234567890-=",
+	"qwertyuiop[]\\",
+	"asdfghjkl;'",
+	"zxcvbnm,./",
+	"~!@#$%^&*()_+",
+}
+
+var passwordLeetSubstitutions = strings.NewReplacer(
+	"0", "o",
+	"1", "l",
+	"3", "e",
+	"4", "a",
+	"5", "s",
+	"7", "t",
+	"@", "a",
+	"$", "s",
+	"!", "i",
+)
+
+// passwordScore estimates how hard password is to guess on the zxcvbn scale
+// of 0 (too guessable) to 4 (very unguessable).
+func passwordScore(password string, userInputs ...string) int {
+	guesses := passwordGuessesLog10(password, userInputs)
+
+	switch {
+	case guesses < 3:
+		return 0
+	case guesses < 6:
+		return 1
+	case guesses < 8:
+		return 2
+	case guesses < 10:
+		return 3
+	}
+
+	return 4
+}
+
+// passwordGuessesLog10 follows the approach of zxcvbn: the password is split
+// into the sequence of recognizable patterns (common passwords, user inputs,
+// repeats, sequences and keyboard runs) that is cheapest to guess, and any
+// characters not covered by a pattern are assumed to be brute forced. The
+// estimate is returned as a base 10 logarithm to avoid overflows.
+func passwordGuessesLog10(password string, userInputs []string) float64 {
+	runes := []rune(password)
+	if len(runes) == 0 {
+		return 0
+	}
+
+	inputs := make(map[string]bool)
+	for _, input := range userInputs {
+		input = strings.ToLower(strings.TrimSpace(input))
+		for _, part := range strings.FieldsFunc(input, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		}) {
+			if len(part) >= 3 {
+				inputs[part] = true
+			}
+		}
+	}
+
+	// best[i] holds the cheapest estimate for the first i characters
+	best := make([]float64, len(runes)+1)
+	for i := 1; i <= len(runes); i++ {
+		best[i] = best[i-1] + math.Log10(passwordCharsetSize(runes[i-1]))
+
+		for j := 0; j <= i-3; j++ {
+			if guesses := passwordPatternGuesses(runes[j:i], inputs); guesses > 0 {
+				best[i] = math.Min(best[i], best[j]+math.Log10(guesses))
+			}
+		}
+	}
+
+	return best[len(runes)]
+}
+
+// passwordPatternGuesses returns the number of guesses needed to find token
+// when it matches a known pattern, or 0 if it does not.
+func passwordPatternGuesses(token []rune, inputs map[string]bool) float64 {
+	raw := string(token)
+	lower := strings.ToLower(raw)
+
+	variations := 1.0
+	if lower != raw {
+		variations *= 2
+	}
+
+	if inputs[lower] {
+		return variations
+	}
+
+	if rank, ok := commonPasswordRanks[lower]; ok {
+		return float64(rank) * variations
+	}
+
+	if unleet := passwordLeetSubstitutions.Replace(lower); unleet != lower {
+		if rank, ok := commonPasswordRanks[unleet]; ok {
+			return float64(rank) * variations * 2
+		}
+		if inputs[unleet] {
+			return variations * 2
+		}
+	}
+
+	n := float64(len(token))
+
+	if strings.Count(lower, lower[:1]) == len(lower) {
+		return passwordCharsetSize(token[0]) * n
+	}
+
+	if delta := token[1] - token[0]; delta == 1 || delta == -1 {
+		sequence := true
+		for i := 2; i < len(token) && sequence; i++ {
+			sequence = token[i]-token[i-1] == delta
+		}
+		if sequence {
+			if delta < 0 {
+				return passwordCharsetSize(token[0]) * n * 2
+			}
+			return passwordCharsetSize(token[0]) * n
+		}
+	}
+
+	for _, row := range passwordKeyboardRows {
+		if strings.Contains(row, lower) {
+			return float64(len(row)) * n * variations
+		}
+	}
+
+	return 0
+}
+
+func passwordCharsetSize(r rune) float64 {
+	switch {
+	case r >= '0' && r <= '9':
+		return 10
+	case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z':
+		return 26
+	case r < unicode.MaxASCII:
+		return 33
+	}
+
+	return 100
+}
@@ -2,11 +2,18 @@ package api
 
 import (
 	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/require"
 	"github.com/supabase/auth/internal/api/apierrors"
 	"github.com/supabase/auth/internal/conf"
+	"github.com/supabase/hibp"
 )
 
 func TestPasswordStrengthChecks(t *testing.T) {
@@ -109,6 +116,10 @@ func TestPasswordStrengthChecks(t *testing.T) {
 		switch e := err.(type) {
 		case *WeakPasswordError:
 			require.Equal(t, e.Reasons, example.Reasons, "Example %d failed with wrong reasons", i)
+			require.NotNil(t, e.Requirements, "Example %d failed without requirements", i)
+			if len(example.Password) < example.MinLength {
+				require.Equal(t, example.MinLength, e.Requirements.MinLength, "Example %d failed with wrong requirements", i)
+			}
 		case *HTTPError:
 			require.Equal(t, e.ErrorCode, apierrors.ErrorCodeValidationFailed, "Example %d failed with wrong error code", i)
 		default:
@@ -116,3 +127,156 @@ func TestPasswordStrengthChecks(t *testing.T) {
 		}
 	}
 }
+
+func TestPasswordPolicyChecks(t *testing.T) {
+	examples := []struct {
+		MinScore    int
+		RejectEmail bool
+
+		Password string
+		Email    string
+		Reasons  []string
+	}{
+		{
+			MinScore: 3,
+			Password: "password123",
+			Reasons:  []string{"score"},
+		},
+		{
+			MinScore: 3,
+			Password: "qwertyuiop",
+			Reasons:  []string{"score"},
+		},
+		{
+			MinScore: 3,
+			Password: "P@ssw0rd!",
+			Reasons:  []string{"score"},
+		},
+		{
+			MinScore: 3,
+			Password: "correct-Horse-battery-8",
+			Reasons:  nil,
+		},
+		{
+			MinScore: 3,
+			Password: "JaneDoe2024",
+			Email:    "jane.doe@example.com",
+			Reasons:  []string{"score"},
+		},
+		{
+			RejectEmail: true,
+			Password:    "my-jane.doe@example.com",
+			Email:       "Jane.Doe@example.com",
+			Reasons:     []string{"email"},
+		},
+		{
+			RejectEmail: true,
+			Password:    "xjane.doe1",
+			Email:       "jane.doe@example.com",
+			Reasons:     []string{"email"},
+		},
+		{
+			RejectEmail: true,
+			Password:    "unrelated-secret",
+			Email:       "jane.doe@example.com",
+			Reasons:     nil,
+		},
+	}
+
+	for i, example := range examples {
+		api := &API{
+			config: &conf.GlobalConfiguration{
+				Password: conf.PasswordConfiguration{
+					MinScore:    example.MinScore,
+					RejectEmail: example.RejectEmail,
+				},
+			},
+		}
+
+		err := api.checkPasswordStrength(context.Background(), example.Password, example.Email)
+		if example.Reasons == nil {
+			require.NoError(t, err, "Example %d failed with error", i)
+			continue
+		}
+
+		require.IsType(t, &WeakPasswordError{}, err, "Example %d failed with wrong error", i)
+		require.Equal(t, example.Reasons, err.(*WeakPasswordError).Reasons, "Example %d failed with wrong reasons", i)
+	}
+}
+
+func TestPasswordScore(t *testing.T) {
+	require.Equal(t, 0, passwordScore("password"))
+	require.Equal(t, 0, passwordScore("aaaaaaaaaa"))
+	require.Equal(t, 0, passwordScore("abcdefgh"))
+	require.Equal(t, 0, passwordScore("jdoe", "jdoe@example.com"))
+	require.Equal(t, 4, passwordScore("zZgXb5gzyCNrV36qwbOSbKVQ"))
+}
+
+type hibpTestClient struct {
+	pwned []string
+	err   error
+}
+
+func (c *hibpTestClient) Do(req *http.Request) (*http.Response, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+
+	var body strings.Builder
+	for _, password := range c.pwned {
+		hash := sha1.Sum([]byte(password))
+		body.WriteString(strings.ToUpper(hex.EncodeToString(hash[:]))[5:] + ":10\n")
+	}
+
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Body:       io.NopCloser(strings.NewReader(body.String())),
+		Request:    req,
+	}, nil
+}
+
+func TestPasswordHIBPChecks(t *testing.T) {
+	newAPI := func(hibpConfig conf.HIBPConfiguration, client *hibpTestClient) *API {
+		hibpConfig.Enabled = true
+
+		return &API{
+			config: &conf.GlobalConfiguration{
+				Password: conf.PasswordConfiguration{
+					HIBP: hibpConfig,
+				},
+			},
+			hibpClient: &hibp.PwnedClient{
+				HTTP: client,
+			},
+		}
+	}
+
+	ctx := context.Background()
+
+	api := newAPI(conf.HIBPConfiguration{}, &hibpTestClient{pwned: []string{"pwned-password"}})
+	err := api.checkPasswordStrength(ctx, "pwned-password")
+	require.IsType(t, &WeakPasswordError{}, err)
+	require.Equal(t, []string{"pwned"}, err.(*WeakPasswordError).Reasons)
+	require.NoError(t, api.checkPasswordStrength(ctx, "unique-password"))
+
+	audit, err := api.shouldAuditPwnedPassword(ctx, "pwned-password")
+	require.NoError(t, err)
+	require.False(t, audit)
+
+	api = newAPI(conf.HIBPConfiguration{AuditOnly: true}, &hibpTestClient{pwned: []string{"pwned-password"}})
+	require.NoError(t, api.checkPasswordStrength(ctx, "pwned-password"))
+
+	audit, err = api.shouldAuditPwnedPassword(ctx, "pwned-password")
+	require.NoError(t, err)
+	require.True(t, audit)
+
+	audit, err = api.shouldAuditPwnedPassword(ctx, "unique-password")
+	require.NoError(t, err)
+	require.False(t, audit)
+
+	api = newAPI(conf.HIBPConfiguration{}, &hibpTestClient{err: errors.New("timeout")})
+	require.NoError(t, api.checkPasswordStrength(ctx, "pwned-password"))
+
+	api = newAPI(conf.HIBPConfiguration{FailClosed: true}, &hibpTestClient{err: errors.New("timeout")})
+	require.Error(t, api.checkPasswordStrength(ctx, "pwned-password"))
+}
@@ -0,0 +1,57 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/supabase/auth/internal/api/apierrors"
+	"github.com/supabase/auth/internal/crypto"
+	"github.com/supabase/auth/internal/models"
+)
+
+// PasswordHashesResponse reports how far password hashes have been migrated
+// to the configured algorithm.
+type PasswordHashesResponse struct {
+	// Algorithm is the configured password hashing algorithm.
+	Algorithm string `json:"algorithm"`
+
+	// Users is the number of users with a password.
+	Users int `json:"users"`
+
+	// Algorithms is the number of password hashes by algorithm.
+	Algorithms map[string]int `json:"algorithms"`
+
+	// Current is the number of password hashes generated with the
+	// configured algorithm and parameters.
+	Current int `json:"current"`
+
+	// Migrated is true when every password hash is current.
+	Migrated bool `json:"migrated"`
+}
+
+// adminPasswordHashes reports the distribution of password hash algorithms.
+// Hashes are only upgraded when users sign in with their password, so this
+// tells when the remaining hashes of an old algorithm can be dealt with.
+func (a *API) adminPasswordHashes(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	db := a.db.WithContext(ctx)
+
+	algorithm, prefix := crypto.CurrentPasswordHash()
+
+	counts, err := models.CountPasswordHashes(db, prefix)
+	if err != nil {
+		return apierrors.NewInternalServerError("Database error counting password hashes").WithInternalError(err)
+	}
+
+	users := 0
+	for _, count := range counts.Algorithms {
+		users += count
+	}
+
+	return sendJSON(w, http.StatusOK, &PasswordHashesResponse{
+		Algorithm:  algorithm,
+		Users:      users,
+		Algorithms: counts.Algorithms,
+		Current:    counts.Current,
+		Migrated:   counts.Current == users,
+	})
+}
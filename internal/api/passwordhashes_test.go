@@ -0,0 +1,109 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	jwt "github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"github.com/supabase/auth/internal/conf"
+	"github.com/supabase/auth/internal/crypto"
+	"github.com/supabase/auth/internal/models"
+)
+
+type PasswordHashesTestSuite struct {
+	suite.Suite
+	API    *API
+	Config *conf.GlobalConfiguration
+
+	token string
+}
+
+func TestPasswordHashes(t *testing.T) {
+	api, config, err := setupAPIForTest()
+	require.NoError(t, err)
+
+	ts := &PasswordHashesTestSuite{
+		API:    api,
+		Config: config,
+	}
+	defer api.db.Close()
+
+	suite.Run(t, ts)
+}
+
+func (ts *PasswordHashesTestSuite) SetupTest() {
+	models.TruncateAll(ts.API.db)
+
+	claims := &AccessTokenClaims{
+		Role: "supabase_admin",
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(ts.Config.JWT.Secret))
+	require.NoError(ts.T(), err, "Error generating admin jwt")
+	ts.token = token
+
+	ts.T().Cleanup(func() {
+		crypto.ConfigurePasswordHashing(&conf.PasswordHashingConfiguration{Algorithm: conf.PasswordHashAlgorithmBcrypt})
+	})
+}
+
+func (ts *PasswordHashesTestSuite) createUser(email, password string) *models.User {
+	u, err := models.NewUser("", email, password, ts.Config.JWT.Aud, nil)
+	require.NoError(ts.T(), err)
+	require.NoError(ts.T(), ts.API.db.Create(u))
+	return u
+}
+
+func (ts *PasswordHashesTestSuite) passwordHashes() *PasswordHashesResponse {
+	req := httptest.NewRequest(http.MethodGet, "/admin/password_hashes", nil)
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", ts.token))
+
+	w := httptest.NewRecorder()
+	ts.API.handler.ServeHTTP(w, req)
+	require.Equal(ts.T(), http.StatusOK, w.Code, w.Body.String())
+
+	var res PasswordHashesResponse
+	require.NoError(ts.T(), json.NewDecoder(w.Body).Decode(&res))
+	return &res
+}
+
+func (ts *PasswordHashesTestSuite) TestPasswordHashes() {
+	bcryptUser := ts.createUser("bcrypt@example.com", "test")
+	ts.createUser("nopassword@example.com", "")
+
+	res := ts.passwordHashes()
+	require.Equal(ts.T(), conf.PasswordHashAlgorithmBcrypt, res.Algorithm)
+	require.Equal(ts.T(), 1, res.Users)
+	require.Equal(ts.T(), map[string]int{"bcrypt": 1}, res.Algorithms)
+	require.True(ts.T(), res.Migrated)
+
+	crypto.ConfigurePasswordHashing(&conf.PasswordHashingConfiguration{
+		Algorithm:         conf.PasswordHashAlgorithmArgon2id,
+		Argon2Memory:      64,
+		Argon2Iterations:  1,
+		Argon2Parallelism: 1,
+	})
+	ts.createUser("argon2id@example.com", "test")
+
+	res = ts.passwordHashes()
+	require.Equal(ts.T(), conf.PasswordHashAlgorithmArgon2id, res.Algorithm)
+	require.Equal(ts.T(), 2, res.Users)
+	require.Equal(ts.T(), map[string]int{"bcrypt": 1, "argon2id": 1}, res.Algorithms)
+	require.Equal(ts.T(), 1, res.Current)
+	require.False(ts.T(), res.Migrated)
+
+	// the bcrypt hash is upgraded on sign in
+	isAuthenticated, _, err := bcryptUser.Authenticate(context.Background(), ts.API.db, "test", nil, false, "")
+	require.NoError(ts.T(), err)
+	require.True(ts.T(), isAuthenticated)
+
+	res = ts.passwordHashes()
+	require.Equal(ts.T(), map[string]int{"argon2id": 2}, res.Algorithms)
+	require.Equal(ts.T(), 2, res.Current)
+	require.True(ts.T(), res.Migrated)
+}
@@ -2,6 +2,7 @@ package api
 
 import (
 	"bytes"
+	"context"
 	"net/http"
 	"regexp"
 	"strings"
@@ -11,10 +12,14 @@ import (
 	"github.com/pkg/errors"
 	"github.com/supabase/auth/internal/api/apierrors"
 	"github.com/supabase/auth/internal/api/sms_provider"
+	"github.com/supabase/auth/internal/conf"
 	"github.com/supabase/auth/internal/crypto"
 	"github.com/supabase/auth/internal/hooks/v0hooks"
+	"github.com/supabase/auth/internal/metering"
 	"github.com/supabase/auth/internal/models"
+	"github.com/supabase/auth/internal/observability"
 	"github.com/supabase/auth/internal/storage"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 var e164Format = regexp.MustCompile("^[1-9][0-9]{1,14}$")
@@ -94,12 +99,19 @@ func (a *API) sendPhoneConfirmation(r *http.Request, tx *storage.Connection, use
 		}
 		otp = crypto.GenerateOtp(config.Sms.OtpLength)
 
+		message, err := generateSMSFromTemplate(config.Sms.GetTemplate(channel), otp)
+		if err != nil {
+			return "", apierrors.NewInternalServerError("error generating sms template").WithInternalError(err)
+		}
+
 		if config.Hook.SendSMS.Enabled {
 			input := v0hooks.SendSMSInput{
 				User: user,
 				SMS: v0hooks.SMS{
-					OTP:   otp,
-					Phone: phone,
+					OTP:     otp,
+					Phone:   phone,
+					Channel: channel,
+					Message: message,
 				},
 			}
 			output := v0hooks.SendSMSOutput{}
@@ -107,20 +119,22 @@ func (a *API) sendPhoneConfirmation(r *http.Request, tx *storage.Connection, use
 			if err != nil {
 				return "", err
 			}
+			if err := output.Err(); err != nil {
+				return output.MessageID, apierrors.NewUnprocessableEntityError(apierrors.ErrorCodeSMSSendFailed, "Error sending %s OTP to provider: %v", otpType, err)
+			}
+			messageID = output.MessageID
 		} else {
 			smsProvider, err := sms_provider.GetSmsProvider(*config)
 			if err != nil {
 				return "", apierrors.NewInternalServerError("Unable to get SMS provider").WithInternalError(err)
 			}
-			message, err := generateSMSFromTemplate(config.Sms.SMSTemplate, otp)
-			if err != nil {
-				return "", apierrors.NewInternalServerError("error generating sms template").WithInternalError(err)
-			}
-			messageID, err := smsProvider.SendMessage(phone, message, channel, otp)
+			messageID, err := sendSMSMessage(r.Context(), config, smsProvider, phone, message, channel, otp)
 			if err != nil {
 				return messageID, apierrors.NewUnprocessableEntityError(apierrors.ErrorCodeSMSSendFailed, "Error sending %s OTP to provider: %v", otpType, err)
 			}
 		}
+
+		metering.RecordOTPSent(channel, otpType)
 	}
 
 	*token = crypto.GenerateTokenHash(phone, otp)
@@ -168,3 +182,26 @@ func generateSMSFromTemplate(SMSTemplate *template.Template, otp string) (string
 	}
 	return message.String(), nil
 }
+
+// sendSMSMessage sends the message with the SMS provider in a span of the
+// request's trace.
+func sendSMSMessage(ctx context.Context, config *conf.GlobalConfiguration, smsProvider sms_provider.SmsProvider, phone, message, channel, otp string) (messageID string, err error) {
+	_, span := observability.StartSpan(ctx, "sms.send",
+		attribute.String("gotrue.sms.provider", config.Sms.Provider),
+		attribute.String("gotrue.sms.channel", channel),
+	)
+	defer func() { observability.EndSpan(span, err) }()
+
+	return smsProvider.SendMessage(phone, message, channel, otp)
+}
+
+// verifySMSOTP verifies the OTP with the SMS provider, when the provider
+// sends and verifies the OTP itself, in a span of the request's trace.
+func verifySMSOTP(ctx context.Context, config *conf.GlobalConfiguration, smsProvider sms_provider.SmsProvider, phone, otp string) (err error) {
+	_, span := observability.StartSpan(ctx, "sms.verify_otp",
+		attribute.String("gotrue.sms.provider", config.Sms.Provider),
+	)
+	defer func() { observability.EndSpan(span, err) }()
+
+	return smsProvider.VerifyOTP(phone, otp)
+}
@@ -301,6 +301,7 @@ func (ts *PhoneTestSuite) TestSendSMSHook() {
 		body                   map[string]string
 		hookFunctionSQL        string
 		expectedCode           int
+		expectedMessageID      string
 		expectToken            bool
 		hookFunctionIdentifier string
 	}
@@ -404,6 +405,48 @@ func (ts *PhoneTestSuite) TestSendSMSHook() {
 			expectedCode:           http.StatusInternalServerError,
 			hookFunctionIdentifier: "send_sms_otp_failure(input jsonb)",
 		},
+		{
+			desc:     "SMS OTP Hook (Delivered)",
+			endpoint: "/otp",
+			method:   http.MethodPost,
+			uri:      "pg-functions://postgres/auth/send_sms_otp_delivered",
+			hookFunctionSQL: `
+                create or replace function send_sms_otp_delivered(input jsonb)
+                returns json as $$
+                begin
+                    if input->'sms'->>'message' not like '%' || (input->'sms'->>'otp') || '%' then
+                        raise exception 'message without the OTP';
+                    end if;
+                    return '{"status": "sent", "message_id": "carrier-123"}'::jsonb;
+                end; $$ language plpgsql;`,
+			header: "",
+			body: map[string]string{
+				"phone": "123456789",
+			},
+			expectToken:            false,
+			expectedCode:           http.StatusOK,
+			expectedMessageID:      "carrier-123",
+			hookFunctionIdentifier: "send_sms_otp_delivered(input jsonb)",
+		},
+		{
+			desc:     "SMS OTP Hook (Failed status)",
+			endpoint: "/otp",
+			method:   http.MethodPost,
+			uri:      "pg-functions://postgres/auth/send_sms_otp_failed",
+			hookFunctionSQL: `
+                create or replace function send_sms_otp_failed(input jsonb)
+                returns json as $$
+                begin
+                    return '{"status": "failed", "message": "carrier unreachable"}'::jsonb;
+                end; $$ language plpgsql;`,
+			header: "",
+			body: map[string]string{
+				"phone": "123456789",
+			},
+			expectToken:            false,
+			expectedCode:           http.StatusUnprocessableEntity,
+			hookFunctionIdentifier: "send_sms_otp_failed(input jsonb)",
+		},
 	}
 
 	for _, c := range cases {
@@ -428,6 +471,11 @@ func (ts *PhoneTestSuite) TestSendSMSHook() {
 			ts.API.handler.ServeHTTP(w, req)
 
 			require.Equal(t, c.expectedCode, w.Code, "Unexpected HTTP status code")
+			if c.expectedMessageID != "" {
+				var res SmsOtpResponse
+				require.NoError(t, json.NewDecoder(w.Body).Decode(&res))
+				require.Equal(t, c.expectedMessageID, res.MessageID)
+			}
 
 			// Delete the function and reset env
 			cleanupHookSQL := fmt.Sprintf("drop function if exists %s", ts.Config.Hook.SendSMS.HookName)
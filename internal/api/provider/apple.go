@@ -77,16 +77,50 @@ func (b *IsPrivateEmail) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
-type appleName struct {
-	FirstName string `json:"firstName"`
-	LastName  string `json:"lastName"`
+// AppleName is the name of the user in the Apple user payload. The web flow
+// uses firstName/lastName while the native SDKs use givenName/familyName.
+type AppleName struct {
+	FirstName  string `json:"firstName"`
+	LastName   string `json:"lastName"`
+	GivenName  string `json:"givenName"`
+	FamilyName string `json:"familyName"`
 }
 
-type appleUser struct {
-	Name  appleName `json:"name"`
+// AppleUser is the user payload Apple hands to the client alongside the
+// authorization. Apple only sends it on the first sign-in of a user and it is
+// not part of the ID token, so it has to be passed on by the client.
+type AppleUser struct {
+	Name  AppleName `json:"name"`
 	Email string    `json:"email"`
 }
 
+// Apply copies the name in the user payload onto the user data.
+func (u *AppleUser) Apply(userData *UserProvidedData) {
+	givenName := strings.TrimSpace(u.Name.FirstName)
+	if givenName == "" {
+		givenName = strings.TrimSpace(u.Name.GivenName)
+	}
+
+	familyName := strings.TrimSpace(u.Name.LastName)
+	if familyName == "" {
+		familyName = strings.TrimSpace(u.Name.FamilyName)
+	}
+
+	fullName := strings.TrimSpace(givenName + " " + familyName)
+	if fullName == "" {
+		return
+	}
+
+	if userData.Metadata == nil {
+		userData.Metadata = &Claims{}
+	}
+
+	userData.Metadata.Name = fullName
+	userData.Metadata.FullName = fullName
+	userData.Metadata.GivenName = givenName
+	userData.Metadata.FamilyName = familyName
+}
+
 // NewAppleProvider creates a Apple account provider.
 func NewAppleProvider(ctx context.Context, ext conf.OAuthProviderConfiguration) (OAuthProvider, error) {
 	if err := ext.ValidateOAuth(); err != nil {
@@ -167,13 +201,12 @@ func (p AppleProvider) GetUserData(ctx context.Context, tok *oauth2.Token) (*Use
 
 // ParseUser parses the apple user's info
 func (p AppleProvider) ParseUser(data string, userData *UserProvidedData) error {
-	u := &appleUser{}
+	u := &AppleUser{}
 	err := json.Unmarshal([]byte(data), u)
 	if err != nil {
 		return err
 	}
 
-	userData.Metadata.Name = strings.TrimSpace(u.Name.FirstName + " " + u.Name.LastName)
-	userData.Metadata.FullName = strings.TrimSpace(u.Name.FirstName + " " + u.Name.LastName)
+	u.Apply(userData)
 	return nil
 }
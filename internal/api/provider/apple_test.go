@@ -0,0 +1,62 @@
+package provider
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAppleParseUser(t *testing.T) {
+	cases := []struct {
+		desc     string
+		data     string
+		expected Claims
+	}{
+		{
+			desc: "web flow payload",
+			data: `{"name":{"firstName":"Jane","lastName":"Doe"},"email":"jane@privaterelay.appleid.com"}`,
+			expected: Claims{
+				Name:       "Jane Doe",
+				FullName:   "Jane Doe",
+				GivenName:  "Jane",
+				FamilyName: "Doe",
+			},
+		},
+		{
+			desc: "native SDK payload",
+			data: `{"name":{"givenName":"Jane","familyName":"Doe"}}`,
+			expected: Claims{
+				Name:       "Jane Doe",
+				FullName:   "Jane Doe",
+				GivenName:  "Jane",
+				FamilyName: "Doe",
+			},
+		},
+		{
+			desc: "given name only",
+			data: `{"name":{"firstName":" Jane "}}`,
+			expected: Claims{
+				Name:      "Jane",
+				FullName:  "Jane",
+				GivenName: "Jane",
+			},
+		},
+		{
+			desc: "no name keeps existing data",
+			data: `{"email":"jane@example.com"}`,
+			expected: Claims{
+				Name: "existing",
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.desc, func(t *testing.T) {
+			userData := &UserProvidedData{Metadata: &Claims{Name: "existing"}}
+			require.NoError(t, AppleProvider{}.ParseUser(c.data, userData))
+			require.Equal(t, c.expected, *userData.Metadata)
+		})
+	}
+
+	require.Error(t, AppleProvider{}.ParseUser("{", &UserProvidedData{Metadata: &Claims{}}))
+}
@@ -15,6 +15,7 @@ import (
 
 const IssuerAzureCommon = "https://login.microsoftonline.com/common/v2.0"
 const IssuerAzureOrganizations = "https://login.microsoftonline.com/organizations/v2.0"
+const IssuerAzureConsumers = "https://login.microsoftonline.com/consumers/v2.0"
 
 // IssuerAzureMicrosoft is the OIDC issuer for microsoft.com accounts:
 // https://learn.microsoft.com/en-us/azure/active-directory/develop/id-token-claims-reference#payload-claims
@@ -50,7 +51,7 @@ func IsAzureCIAMIssuer(issuer string) bool {
 }
 
 // NewAzureProvider creates a Azure account provider.
-func NewAzureProvider(ext conf.OAuthProviderConfiguration, scopes string) (OAuthProvider, error) {
+func NewAzureProvider(ext conf.AzureProviderConfiguration, scopes string) (OAuthProvider, error) {
 	if err := ext.ValidateOAuth(); err != nil {
 		return nil, err
 	}
@@ -67,7 +68,14 @@ func NewAzureProvider(ext conf.OAuthProviderConfiguration, scopes string) (OAuth
 	if ext.URL != "" {
 		expectedIssuer = authHost + "/v2.0"
 
-		if !IsAzureIssuer(expectedIssuer) || !IsAzureCIAMIssuer(expectedIssuer) || expectedIssuer == IssuerAzureCommon || expectedIssuer == IssuerAzureOrganizations {
+		switch {
+		case expectedIssuer == IssuerAzureConsumers:
+			// the consumers endpoint only signs in personal
+			// Microsoft accounts, whose ID tokens are issued by the
+			// microsoft.com tenant
+			expectedIssuer = IssuerAzureMicrosoft
+
+		case !IsAzureIssuer(expectedIssuer) || !IsAzureCIAMIssuer(expectedIssuer) || expectedIssuer == IssuerAzureCommon || expectedIssuer == IssuerAzureOrganizations:
 			// in tests, the URL is a local server which should not
 			// be the expected issuer
 			// also, IssuerAzure (common) never actually issues any
@@ -166,3 +174,55 @@ func (g azureProvider) GetUserData(ctx context.Context, tok *oauth2.Token) (*Use
 
 	return nil, fmt.Errorf("azure: no OIDC ID token present in response")
 }
+
+// ValidateAzureTenant checks that the tid claim of the Azure ID token the
+// user data was parsed from is one of the allowed tenants. Any tenant is
+// allowed when allowedTenants is empty.
+func ValidateAzureTenant(data *UserProvidedData, allowedTenants []string) error {
+	if len(allowedTenants) == 0 {
+		return nil
+	}
+
+	tenantID := ""
+	if data.Metadata != nil {
+		tenantID, _ = data.Metadata.CustomClaims["tid"].(string)
+	}
+	if tenantID == "" {
+		return fmt.Errorf("azure: ID token has no tid claim")
+	}
+
+	for _, allowedTenant := range allowedTenants {
+		if strings.EqualFold(strings.TrimSpace(allowedTenant), tenantID) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("azure: tenant %q is not allowed", tenantID)
+}
+
+// AzureGroups returns the group memberships in the groups claim of the
+// Azure ID token the user data was parsed from. It reports false when the
+// groups are unknown, because the user is a member of more groups than
+// fit in the ID token and only a reference to Microsoft Graph was sent.
+func AzureGroups(data *UserProvidedData) ([]string, bool) {
+	if data.Metadata == nil {
+		return nil, false
+	}
+
+	if claimNames, ok := data.Metadata.CustomClaims["_claim_names"].(map[string]any); ok {
+		if _, overage := claimNames["groups"]; overage {
+			return nil, false
+		}
+	}
+
+	groups := []string{}
+	if claim, ok := data.Metadata.CustomClaims["groups"].([]any); ok {
+		for _, group := range claim {
+			if group, ok := group.(string); ok {
+				groups = append(groups, group)
+			}
+		}
+	}
+
+	return groups, true
+}
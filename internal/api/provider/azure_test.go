@@ -1,6 +1,10 @@
 package provider
 
-import "testing"
+import (
+	"testing"
+
+	"github.com/supabase/auth/internal/conf"
+)
 
 func TestIsAzureIssuer(t *testing.T) {
 	positiveExamples := []string{
@@ -27,3 +31,86 @@ func TestIsAzureIssuer(t *testing.T) {
 		}
 	}
 }
+
+func TestNewAzureProviderExpectedIssuer(t *testing.T) {
+	examples := map[string]string{
+		"https://login.microsoftonline.com/common":        "",
+		"https://login.microsoftonline.com/organizations": "",
+		"https://login.microsoftonline.com/consumers":     IssuerAzureMicrosoft,
+	}
+
+	for url, expectedIssuer := range examples {
+		ext := conf.AzureProviderConfiguration{}
+		ext.ClientID = []string{"testclientid"}
+		ext.Secret = "testsecret"
+		ext.RedirectURI = "https://example.com/callback"
+		ext.URL = url
+		ext.Enabled = true
+
+		p, err := NewAzureProvider(ext, "")
+		if err != nil {
+			t.Fatalf("Example %q should create a provider: %v", url, err)
+		}
+
+		if issuer := p.(*azureProvider).ExpectedIssuer; issuer != expectedIssuer {
+			t.Errorf("Example %q should expect issuer %q, got %q", url, expectedIssuer, issuer)
+		}
+	}
+}
+
+func TestValidateAzureTenant(t *testing.T) {
+	data := &UserProvidedData{
+		Metadata: &Claims{
+			CustomClaims: map[string]any{
+				"tid": "72f988bf-86f1-41af-91ab-2d7cd011db47",
+			},
+		},
+	}
+
+	if err := ValidateAzureTenant(data, nil); err != nil {
+		t.Errorf("Any tenant should be allowed without allowed tenants: %v", err)
+	}
+
+	if err := ValidateAzureTenant(data, []string{"9188040d-6c67-4c5b-b112-36a304b66dad", "72F988BF-86F1-41AF-91AB-2D7CD011DB47"}); err != nil {
+		t.Errorf("Tenant should be allowed regardless of case: %v", err)
+	}
+
+	if err := ValidateAzureTenant(data, []string{"9188040d-6c67-4c5b-b112-36a304b66dad"}); err == nil {
+		t.Errorf("Tenant not in the allowed tenants should not be allowed")
+	}
+
+	if err := ValidateAzureTenant(&UserProvidedData{Metadata: &Claims{}}, []string{"9188040d-6c67-4c5b-b112-36a304b66dad"}); err == nil {
+		t.Errorf("ID token without a tid claim should not be allowed")
+	}
+}
+
+func TestAzureGroups(t *testing.T) {
+	groups, ok := AzureGroups(&UserProvidedData{
+		Metadata: &Claims{
+			CustomClaims: map[string]any{
+				"groups": []any{"admins", "developers"},
+			},
+		},
+	})
+	if !ok || len(groups) != 2 || groups[0] != "admins" || groups[1] != "developers" {
+		t.Errorf("Groups should be read from the groups claim, got %v", groups)
+	}
+
+	groups, ok = AzureGroups(&UserProvidedData{Metadata: &Claims{}})
+	if !ok || len(groups) != 0 {
+		t.Errorf("No groups claim should mean no groups, got %v", groups)
+	}
+
+	_, ok = AzureGroups(&UserProvidedData{
+		Metadata: &Claims{
+			CustomClaims: map[string]any{
+				"_claim_names": map[string]any{
+					"groups": "src1",
+				},
+			},
+		},
+	})
+	if ok {
+		t.Errorf("Groups referencing Microsoft Graph should be unknown")
+	}
+}
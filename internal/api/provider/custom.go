@@ -0,0 +1,270 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid"
+	standardwebhooks "github.com/standard-webhooks/standard-webhooks/libraries/go"
+	"github.com/supabase/auth/internal/conf"
+	"github.com/supabase/auth/internal/utilities"
+	"golang.org/x/oauth2"
+)
+
+// Custom
+// The custom provider delegates every step of the flow to the operator's
+// endpoint, which speaks to the actual identity provider. Auth posts JSON to
+// the endpoint, signed as Standard Webhooks with the configured secrets:
+//
+//	POST {url}/authorize {"state", "redirect_uri", "scopes", "params"} -> {"url"}
+//	POST {url}/token     {"code", "redirect_uri"} -> {"access_token", "refresh_token", "token_type", "expires_in"}
+//	POST {url}/user      {"access_token"} -> {"sub", "email", "email_verified", "name", ...}
+//
+// The user object uses the names of the OpenID Connect standard claims, and
+// the endpoint responds with a non-2xx status to reject a request.
+const (
+	customAuthorizePath = "/authorize"
+	customTokenPath     = "/token" //#nosec G101 -- Not a secret value.
+	customUserPath      = "/user"
+)
+
+type customProvider struct {
+	URL         string
+	RedirectURI string
+	Scopes      []string
+
+	webhooks []*standardwebhooks.Webhook
+}
+
+type customAuthorizeRequest struct {
+	State       string            `json:"state"`
+	RedirectURI string            `json:"redirect_uri"`
+	Scopes      []string          `json:"scopes"`
+	Params      map[string]string `json:"params,omitempty"`
+}
+
+type customAuthorizeResponse struct {
+	URL string `json:"url"`
+}
+
+type customTokenRequest struct {
+	Code        string `json:"code"`
+	RedirectURI string `json:"redirect_uri"`
+}
+
+type customTokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+}
+
+type customUserRequest struct {
+	AccessToken string `json:"access_token"`
+}
+
+// NewCustomProvider creates a provider delegating to the operator's endpoint.
+func NewCustomProvider(ext conf.CustomProviderConfiguration, scopes string) (OAuthProvider, error) {
+	if !ext.Enabled {
+		return nil, errors.New("provider is not enabled")
+	}
+	if ext.URL == "" {
+		return nil, errors.New("missing custom provider URL")
+	}
+	if ext.RedirectURI == "" {
+		return nil, errors.New("missing redirect URI")
+	}
+	if len(ext.Secrets) == 0 {
+		return nil, errors.New("missing custom provider secret")
+	}
+
+	p := &customProvider{
+		URL:         strings.TrimSuffix(ext.URL, "/"),
+		RedirectURI: ext.RedirectURI,
+		Scopes:      []string{},
+	}
+
+	if scopes != "" {
+		p.Scopes = strings.Split(scopes, ",")
+	}
+
+	for _, secret := range ext.Secrets {
+		wh, err := standardwebhooks.NewWebhook(strings.TrimPrefix(secret, "v1,"))
+		if err != nil {
+			return nil, fmt.Errorf("custom: invalid secret: %w", err)
+		}
+		p.webhooks = append(p.webhooks, wh)
+	}
+
+	return p, nil
+}
+
+// AuthCodeURL asks the endpoint for the URL the user is sent to, passing on
+// the parameters of the options. It returns an empty URL when the endpoint
+// fails, like other providers that can't build the URL locally.
+func (p customProvider) AuthCodeURL(state string, args ...oauth2.AuthCodeOption) string {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	var res customAuthorizeResponse
+	if err := p.post(ctx, customAuthorizePath, &customAuthorizeRequest{
+		State:       state,
+		RedirectURI: p.RedirectURI,
+		Scopes:      p.Scopes,
+		Params:      authCodeOptionParams(args),
+	}, &res); err != nil {
+		return ""
+	}
+
+	return res.URL
+}
+
+// GetOAuthToken has the endpoint exchange the code for the tokens of the
+// identity provider.
+func (p customProvider) GetOAuthToken(ctx context.Context, code string, _ ...oauth2.AuthCodeOption) (*oauth2.Token, error) {
+	var res customTokenResponse
+	if err := p.post(ctx, customTokenPath, &customTokenRequest{
+		Code:        code,
+		RedirectURI: p.RedirectURI,
+	}, &res); err != nil {
+		return nil, err
+	}
+	if res.AccessToken == "" {
+		return nil, errors.New("custom: token response without an access token")
+	}
+
+	token := &oauth2.Token{
+		AccessToken:  res.AccessToken,
+		RefreshToken: res.RefreshToken,
+		TokenType:    res.TokenType,
+	}
+	if res.ExpiresIn > 0 {
+		token.Expiry = time.Now().Add(time.Duration(res.ExpiresIn) * time.Second)
+	}
+
+	return token, nil
+}
+
+// GetUserData has the endpoint return the user the access token belongs to.
+func (p customProvider) GetUserData(ctx context.Context, tok *oauth2.Token) (*UserProvidedData, error) {
+	var claims Claims
+	if err := p.post(ctx, customUserPath, &customUserRequest{
+		AccessToken: tok.AccessToken,
+	}, &claims); err != nil {
+		return nil, err
+	}
+	if claims.Subject == "" {
+		return nil, errors.New("custom: user without a sub")
+	}
+
+	data := &UserProvidedData{}
+	if claims.Email != "" {
+		data.Emails = []Email{{
+			Email:    claims.Email,
+			Verified: claims.EmailVerified,
+			Primary:  true,
+		}}
+	}
+
+	// To be deprecated
+	claims.ProviderId = claims.Subject
+	if claims.FullName == "" {
+		claims.FullName = claims.Name
+	}
+	if claims.AvatarURL == "" {
+		claims.AvatarURL = claims.Picture
+	}
+	if claims.UserNameKey == "" {
+		claims.UserNameKey = claims.PreferredUsername
+	}
+
+	data.Metadata = &claims
+
+	return data, nil
+}
+
+func (p customProvider) RequiresPKCE() bool {
+	return false
+}
+
+// post sends the signed request to the path of the endpoint and decodes the
+// response into dst.
+func (p customProvider) post(ctx context.Context, path string, body interface{}, dst interface{}) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	msgID := uuid.Must(uuid.NewV4())
+	now := time.Now()
+
+	signatures := make([]string, 0, len(p.webhooks))
+	for _, wh := range p.webhooks {
+		signature, err := wh.Sign(msgID.String(), now, payload)
+		if err != nil {
+			return err
+		}
+		signatures = append(signatures, signature)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.URL+path, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("webhook-id", msgID.String())
+	req.Header.Set("webhook-timestamp", fmt.Sprintf("%d", now.Unix()))
+	req.Header.Set("webhook-signature", strings.Join(signatures, ", "))
+
+	client := &http.Client{Timeout: defaultTimeout}
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer utilities.SafeClose(res.Body)
+
+	bodyBytes, err := io.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+
+	if res.StatusCode < http.StatusOK || res.StatusCode >= http.StatusMultipleChoices {
+		return httpError(res.StatusCode, "%s", string(bodyBytes))
+	}
+
+	return json.Unmarshal(bodyBytes, dst)
+}
+
+// authCodeOptionParams returns the parameters the options add to an
+// authorization URL, which the options keep to themselves otherwise.
+func authCodeOptionParams(args []oauth2.AuthCodeOption) map[string]string {
+	if len(args) == 0 {
+		return nil
+	}
+
+	config := &oauth2.Config{Endpoint: oauth2.Endpoint{AuthURL: "https://example.invalid"}}
+	u, err := url.Parse(config.AuthCodeURL("", args...))
+	if err != nil {
+		return nil
+	}
+
+	params := make(map[string]string)
+	for key, values := range u.Query() {
+		switch key {
+		case "response_type", "client_id", "state":
+			continue
+		}
+		params[key] = values[0]
+	}
+
+	return params
+}
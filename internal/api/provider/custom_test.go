@@ -0,0 +1,66 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/supabase/auth/internal/conf"
+	"golang.org/x/oauth2"
+)
+
+func TestCustomProvider(t *testing.T) {
+	var authorize customAuthorizeRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NotEmpty(t, r.Header.Get("webhook-signature"))
+
+		switch r.URL.Path {
+		case "/authorize":
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&authorize))
+			require.NoError(t, json.NewEncoder(w).Encode(map[string]interface{}{
+				"url": "https://idp.example.com/login",
+			}))
+		case "/user":
+			require.NoError(t, json.NewEncoder(w).Encode(map[string]interface{}{
+				"sub":                "user-1",
+				"email":              "user@example.com",
+				"preferred_username": "user1",
+			}))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+
+	ext := conf.CustomProviderConfiguration{
+		OAuthProviderConfiguration: conf.OAuthProviderConfiguration{
+			Enabled:     true,
+			RedirectURI: "http://localhost/callback",
+			URL:         server.URL + "/",
+		},
+		Secrets: conf.HTTPHookSecrets{"v1,whsec_dGVzdHNlY3JldHRlc3RzZWNyZXR0ZXN0c2VjcmV0"},
+	}
+
+	p, err := NewCustomProvider(ext, "openid,profile")
+	require.NoError(t, err)
+
+	authURL := p.AuthCodeURL("state-1", oauth2.SetAuthURLParam("prompt", "login"))
+	require.Equal(t, "https://idp.example.com/login", authURL)
+	require.Equal(t, "state-1", authorize.State)
+	require.Equal(t, "http://localhost/callback", authorize.RedirectURI)
+	require.Equal(t, []string{"openid", "profile"}, authorize.Scopes)
+	require.Equal(t, map[string]string{"prompt": "login"}, authorize.Params)
+
+	_, err = p.GetOAuthToken(context.Background(), "code")
+	require.Error(t, err)
+
+	data, err := p.GetUserData(context.Background(), &oauth2.Token{AccessToken: "token"})
+	require.NoError(t, err)
+	require.Equal(t, "user-1", data.Metadata.Subject)
+	require.Equal(t, "user-1", data.Metadata.ProviderId)
+	require.Equal(t, "user1", data.Metadata.UserNameKey)
+	require.Equal(t, []Email{{Email: "user@example.com", Primary: true}}, data.Emails)
+}
@@ -10,12 +10,16 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 	"unicode"
 
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/jmespath/go-jmespath"
 	"github.com/supabase/auth/internal/conf"
 	"github.com/supabase/auth/internal/utilities"
 	"golang.org/x/oauth2"
+	"golang.org/x/sync/singleflight"
 )
 
 type genericProvider struct {
@@ -24,6 +28,14 @@ type genericProvider struct {
 	issuer          string
 	profileURL      string
 	userDataMapping map[string]string
+
+	// userDataExpressions are JMESPath expressions keyed by claim name, taking
+	// precedence over userDataMapping.
+	userDataExpressions map[string]string
+
+	// verifier is set when the provider publishes a JWKS through its
+	// discovery document and is used to validate returned ID tokens.
+	verifier *oidc.IDTokenVerifier
 }
 
 func (p genericProvider) GetOAuthToken(ctx context.Context, code string, opts ...oauth2.AuthCodeOption) (*oauth2.Token, error) {
@@ -34,11 +46,56 @@ func (p genericProvider) RequiresPKCE() bool {
 	return p.requiresPKCE
 }
 
-func (p genericProvider) GetUserData(_ctx context.Context, tok *oauth2.Token) (*UserProvidedData, error) {
+func (p genericProvider) GetUserData(ctx context.Context, tok *oauth2.Token) (*UserProvidedData, error) {
+	u := make(map[string]interface{})
+	issuerFallback := p.issuer
+	subjectFallback := ""
+
+	if rawIDToken, ok := tok.Extra("id_token").(string); ok && rawIDToken != "" && p.verifier != nil {
+		idToken, err := p.verifier.Verify(ctx, rawIDToken)
+		if err != nil {
+			return nil, fmt.Errorf("generic: unable to verify id_token: %w", err)
+		}
+
+		if err := idToken.Claims(&u); err != nil {
+			return nil, err
+		}
+
+		issuerFallback = idToken.Issuer
+		subjectFallback = idToken.Subject
+	}
+
+	if p.profileURL != "" {
+		profile, err := p.fetchProfile(ctx, tok)
+		if err != nil {
+			return nil, err
+		}
+
+		// The UserInfo response must be about the same subject as the ID
+		// token, see OpenID Connect Core 1.0 section 5.3.2.
+		if sub, ok := profile["sub"].(string); ok && subjectFallback != "" && sub != subjectFallback {
+			return nil, errors.New("generic: userinfo subject does not match id_token subject")
+		}
+
+		for k, v := range profile {
+			u[k] = v
+		}
+	} else if len(u) == 0 {
+		return nil, errors.New("generic: missing profile_url and no verifiable id_token was returned")
+	}
+
+	if sub, ok := u["sub"].(string); ok && subjectFallback == "" {
+		subjectFallback = sub
+	}
+
+	return p.mapUserData(u, issuerFallback, subjectFallback)
+}
+
+func (p genericProvider) fetchProfile(ctx context.Context, tok *oauth2.Token) (map[string]interface{}, error) {
 	var u map[string]interface{}
 
 	// Perform http request manually, because we need to vary it based on the provider config
-	req, err := http.NewRequest("GET", p.profileURL, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", p.profileURL, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -63,115 +120,116 @@ func (p genericProvider) GetUserData(_ctx context.Context, tok *oauth2.Token) (*
 		return nil, err
 	}
 
-	err = json.Unmarshal(body, &u)
-	if err != nil {
+	if err := json.Unmarshal(body, &u); err != nil {
 		return nil, err
 	}
 
-	// Read user data as specified in the JSON mapping
-	mapping := p.userDataMapping
+	return u, nil
+}
 
-	email, err := getStringFieldByPath(u, getMappingField(mapping, "Email"), "")
+func (p genericProvider) mapUserData(u map[string]interface{}, issuerFallback, subjectFallback string) (*UserProvidedData, error) {
+	// Read user data as specified by the configured expressions and mapping
+	email, err := p.getStringField(u, "Email", "")
 	if err != nil {
 		return nil, err
 	}
 
-	emailVerified, err := getBooleanFieldByPath(u, getMappingField(mapping, "EmailVerified"), email != "")
+	emailVerified, err := p.getBooleanField(u, "EmailVerified", email != "")
 	if err != nil {
 		return nil, err
 	}
 
-	emailPrimary, err := getBooleanFieldByPath(u, getMappingField(mapping, "EmailPrimary"), email != "")
+	emailPrimary, err := p.getBooleanField(u, "EmailPrimary", email != "")
 	if err != nil {
 		return nil, err
 	}
 
-	issuer, err := getStringFieldByPath(u, getMappingField(mapping, "Issuer"), p.issuer)
+	issuer, err := p.getStringField(u, "Issuer", issuerFallback)
 	if err != nil {
 		return nil, err
 	}
 
-	subject, err := getStringFieldByPath(u, getMappingField(mapping, "Subject"), "")
+	subject, err := p.getStringField(u, "Subject", subjectFallback)
 	if err != nil {
 		return nil, err
 	}
 
-	name, err := getStringFieldByPath(u, getMappingField(mapping, "Name"), "")
+	name, err := p.getStringField(u, "Name", "")
 	if err != nil {
 		return nil, err
 	}
 
-	familyName, err := getStringFieldByPath(u, getMappingField(mapping, "FamilyName"), "")
+	familyName, err := p.getStringField(u, "FamilyName", "")
 	if err != nil {
 		return nil, err
 	}
 
-	givenName, err := getStringFieldByPath(u, getMappingField(mapping, "GivenName"), "")
+	givenName, err := p.getStringField(u, "GivenName", "")
 	if err != nil {
 		return nil, err
 	}
 
-	middleName, err := getStringFieldByPath(u, getMappingField(mapping, "MiddleName"), "")
+	middleName, err := p.getStringField(u, "MiddleName", "")
 	if err != nil {
 		return nil, err
 	}
 
-	nickName, err := getStringFieldByPath(u, getMappingField(mapping, "NickName"), "")
+	nickName, err := p.getStringField(u, "NickName", "")
 	if err != nil {
 		return nil, err
 	}
 
-	preferredUsername, err := getStringFieldByPath(u, getMappingField(mapping, "PreferredUsername"), "")
+	preferredUsername, err := p.getStringField(u, "PreferredUsername", "")
 	if err != nil {
 		return nil, err
 	}
 
-	profile, err := getStringFieldByPath(u, getMappingField(mapping, "Profile"), "")
+	profile, err := p.getStringField(u, "Profile", "")
 	if err != nil {
 		return nil, err
 	}
 
-	picture, err := getStringFieldByPath(u, getMappingField(mapping, "Picture"), "")
+	picture, err := p.getStringField(u, "Picture", "")
 	if err != nil {
 		return nil, err
 	}
 
-	website, err := getStringFieldByPath(u, getMappingField(mapping, "Website"), "")
+	website, err := p.getStringField(u, "Website", "")
 	if err != nil {
 		return nil, err
 	}
 
-	gender, err := getStringFieldByPath(u, getMappingField(mapping, "Gender"), "")
+	gender, err := p.getStringField(u, "Gender", "")
 	if err != nil {
 		return nil, err
 	}
 
-	birthdate, err := getStringFieldByPath(u, getMappingField(mapping, "Birthdate"), "")
+	birthdate, err := p.getStringField(u, "Birthdate", "")
 	if err != nil {
 		return nil, err
 	}
 
-	zoneInfo, err := getStringFieldByPath(u, getMappingField(mapping, "ZoneInfo"), "")
+	zoneInfo, err := p.getStringField(u, "ZoneInfo", "")
 	if err != nil {
 		return nil, err
 	}
 
-	locale, err := getStringFieldByPath(u, getMappingField(mapping, "Locale"), "")
+	locale, err := p.getStringField(u, "Locale", "")
 	if err != nil {
 		return nil, err
 	}
 
-	updatedAt, err := getStringFieldByPath(u, getMappingField(mapping, "UpdatedAt"), "")
+	updatedAt, err := p.getStringField(u, "UpdatedAt", "")
 	if err != nil {
 		return nil, err
 	}
 
-	phone, err := getStringFieldByPath(u, getMappingField(mapping, "Phone"), "")
+	phone, err := p.getStringField(u, "Phone", "")
 	if err != nil {
 		return nil, err
 	}
 
-	phoneVerified, err := getBooleanFieldByPath(u, getMappingField(mapping, "PhoneVerified"), phone != "")
+	phoneVerified, err := p.getBooleanField(u, "PhoneVerified", phone != "")
 	if err != nil {
 		return nil, err
 	}
@@ -215,6 +273,42 @@ func (p genericProvider) GetUserData(_ctx context.Context, tok *oauth2.Token) (*
 	return data, nil
 }
 
+// getStringField reads the claim named field from the user data, using the
+// configured JMESPath expression if there is one or the mapped path otherwise.
+func (p genericProvider) getStringField(obj map[string]interface{}, field string, fallback string) (string, error) {
+	if expression, ok := p.userDataExpressions[field]; ok {
+		value, err := jmespath.Search(expression, obj)
+		if err != nil {
+			return "", fmt.Errorf("unable to evaluate user data expression for %q: %w", field, err)
+		}
+		if value == nil {
+			return fallback, nil
+		}
+		return toStringField(field, value)
+	}
+
+	return getStringFieldByPath(obj, getMappingField(p.userDataMapping, field), fallback)
+}
+
+// getBooleanField is the boolean counterpart of getStringField.
+func (p genericProvider) getBooleanField(obj map[string]interface{}, field string, fallback bool) (bool, error) {
+	if expression, ok := p.userDataExpressions[field]; ok {
+		value, err := jmespath.Search(expression, obj)
+		if err != nil {
+			return false, fmt.Errorf("unable to evaluate user data expression for %q: %w", field, err)
+		}
+		if value == nil {
+			return fallback, nil
+		}
+		if result, ok := value.(bool); ok {
+			return result, nil
+		}
+		return false, fmt.Errorf("unable to read field as boolean: %q", field)
+	}
+
+	return getBooleanFieldByPath(obj, getMappingField(p.userDataMapping, field), fallback)
+}
+
 func getFieldByPath(obj map[string]interface{}, path string, fallback interface{}) (interface{}, error) {
 	value := obj
 
@@ -240,6 +334,10 @@ func getStringFieldByPath(obj map[string]interface{}, path string, fallback stri
 	if err != nil {
 		return "", err
 	}
+	return toStringField(path, value)
+}
+
+func toStringField(path string, value interface{}) (string, error) {
 	if result, ok := value.(string); ok {
 		return result, nil
 	} else if intValue, ok := value.(int); ok {
@@ -307,8 +405,9 @@ type OIDCDiscovery struct {
 }
 
 // NewGenericProvider creates an OAuth provider according to the config specified by the user.
-// If DiscoveryURL is set, it will fetch the OIDC Discovery document to obtain the
-// authorization_endpoint, token_endpoint, and userinfo_endpoint.
+// If DiscoveryURL is set, or only Issuer is set, it will fetch the OIDC Discovery document
+// to obtain the authorization_endpoint, token_endpoint, userinfo_endpoint and jwks_uri.
+// Discovery documents are cached for oidcDiscoveryCacheTTL.
 func NewGenericProvider(ext conf.GenericOAuthProviderConfiguration, scopes string) (OAuthProvider, error) {
 	if err := ext.ValidateOAuth(); err != nil {
 		return nil, err
@@ -316,13 +415,20 @@ func NewGenericProvider(ext conf.GenericOAuthProviderConfiguration, scopes strin
 
 	// Determine auth URL, token URL, and profile URL
 	var authURL, tokenURL, profileURL, issuer string
+	var verifier *oidc.IDTokenVerifier
+
+	discoveryURL := ext.DiscoveryURL
+	if discoveryURL == "" && ext.AuthURL == "" && ext.Issuer != "" {
+		discoveryURL = strings.TrimSuffix(ext.Issuer, "/") + "/.well-known/openid-configuration"
+	}
 
-	if ext.DiscoveryURL != "" {
+	if discoveryURL != "" {
 		// Fetch OIDC Discovery document
-		discovery, err := fetchOIDCDiscovery(ext.DiscoveryURL)
+		entry, err := getOIDCDiscovery(discoveryURL)
 		if err != nil {
 			return nil, fmt.Errorf("failed to fetch OIDC discovery document: %w", err)
 		}
+		discovery := entry.discovery
 
 		// Use discovered values
 		authURL = discovery.AuthorizationEndpoint
@@ -337,13 +443,27 @@ func NewGenericProvider(ext conf.GenericOAuthProviderConfiguration, scopes strin
 		if tokenURL == "" {
 			return nil, errors.New("discovery document missing token_endpoint")
 		}
+		if ext.Issuer != "" && strings.TrimSuffix(ext.Issuer, "/") != strings.TrimSuffix(issuer, "/") {
+			return nil, fmt.Errorf("discovery document issuer %q does not match configured issuer %q", issuer, ext.Issuer)
+		}
+
+		if entry.keySet != nil {
+			config := &oidc.Config{
+				ClientID:             ext.ClientID[0],
+				SupportedSigningAlgs: discovery.IDTokenSigningAlgValuesSupported,
+			}
+			if OverrideClock != nil {
+				config.Now = OverrideClock
+			}
+			verifier = oidc.NewVerifier(issuer, entry.keySet, config)
+		}
 	} else {
 		// Use explicitly configured URLs
 		if ext.AuthURL == "" {
-			return nil, errors.New("missing auth_url (or set discovery_url for OIDC discovery)")
+			return nil, errors.New("missing auth_url (or set discovery_url or issuer for OIDC discovery)")
 		}
 		if ext.TokenURL == "" {
-			return nil, errors.New("missing token_url (or set discovery_url for OIDC discovery)")
+			return nil, errors.New("missing token_url (or set discovery_url or issuer for OIDC discovery)")
 		}
 		authURL = ext.AuthURL
 		tokenURL = ext.TokenURL
@@ -364,13 +484,71 @@ func NewGenericProvider(ext conf.GenericOAuthProviderConfiguration, scopes strin
 			RedirectURL: ext.RedirectURI,
 			Scopes:      oauthScopes,
 		},
-		requiresPKCE:    ext.RequiresPKCE,
-		issuer:          issuer,
-		profileURL:      profileURL,
-		userDataMapping: ext.UserDataMapping,
+		requiresPKCE:        ext.RequiresPKCE,
+		issuer:              issuer,
+		profileURL:          profileURL,
+		userDataMapping:     ext.UserDataMapping,
+		userDataExpressions: ext.GetUserDataExpressions(),
+		verifier:            verifier,
 	}, nil
 }
 
+// oidcDiscoveryCacheTTL is how long a fetched discovery document, and the key
+// set built from its jwks_uri, is reused before being fetched again.
+const oidcDiscoveryCacheTTL = time.Hour
+
+type oidcDiscoveryCacheEntry struct {
+	discovery *OIDCDiscovery
+	keySet    *oidc.RemoteKeySet
+	expiresAt time.Time
+}
+
+var (
+	oidcDiscoveryCacheMu sync.Mutex
+	oidcDiscoveryCache   = make(map[string]*oidcDiscoveryCacheEntry) // Guarded by oidcDiscoveryCacheMu.
+
+	// oidcDiscoveryFetches fetches a discovery document once for concurrent
+	// requests of the same URL, without blocking the requests of other URLs.
+	oidcDiscoveryFetches singleflight.Group
+)
+
+// getOIDCDiscovery returns the discovery document for the given URL, fetching
+// it only when it is not cached or the cached copy has expired. The remote key
+// set is kept alongside so its own key cache survives between requests.
+func getOIDCDiscovery(discoveryURL string) (*oidcDiscoveryCacheEntry, error) {
+	oidcDiscoveryCacheMu.Lock()
+	entry, ok := oidcDiscoveryCache[discoveryURL]
+	oidcDiscoveryCacheMu.Unlock()
+	if ok && time.Now().Before(entry.expiresAt) {
+		return entry, nil
+	}
+
+	v, err, _ := oidcDiscoveryFetches.Do(discoveryURL, func() (interface{}, error) {
+		discovery, err := fetchOIDCDiscovery(discoveryURL)
+		if err != nil {
+			return nil, err
+		}
+
+		entry := &oidcDiscoveryCacheEntry{
+			discovery: discovery,
+			expiresAt: time.Now().Add(oidcDiscoveryCacheTTL),
+		}
+		if discovery.JWKSURI != "" {
+			entry.keySet = oidc.NewRemoteKeySet(context.Background(), discovery.JWKSURI)
+		}
+
+		oidcDiscoveryCacheMu.Lock()
+		oidcDiscoveryCache[discoveryURL] = entry
+		oidcDiscoveryCacheMu.Unlock()
+
+		return entry, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.(*oidcDiscoveryCacheEntry), nil
+}
+
 // fetchOIDCDiscovery fetches the OIDC Discovery document from the given URL
 func fetchOIDCDiscovery(discoveryURL string) (*OIDCDiscovery, error) {
 	client := &http.Client{Timeout: 10 * time.Second}
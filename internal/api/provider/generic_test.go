@@ -1,10 +1,22 @@
 package provider
 
 import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/lestrrat-go/jwx/v2/jwk"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/supabase/auth/internal/conf"
+	"golang.org/x/oauth2"
 )
 
 func TestToSnakeCase(t *testing.T) {
@@ -224,3 +236,213 @@ func TestGetStringFieldByPath(t *testing.T) {
 		assert.Equal(t, "", result)
 	})
 }
+
+func TestGenericProviderOIDCDiscovery(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	publicJWK, err := jwk.FromRaw(privateKey.Public())
+	require.NoError(t, err)
+	require.NoError(t, publicJWK.Set(jwk.KeyIDKey, "test-key"))
+	require.NoError(t, publicJWK.Set(jwk.AlgorithmKey, "RS256"))
+
+	discoveryRequests := 0
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/.well-known/openid-configuration":
+			discoveryRequests++
+			require.NoError(t, json.NewEncoder(w).Encode(OIDCDiscovery{
+				Issuer:                           server.URL,
+				AuthorizationEndpoint:            server.URL + "/authorize",
+				TokenEndpoint:                    server.URL + "/token",
+				UserinfoEndpoint:                 server.URL + "/userinfo",
+				JWKSURI:                          server.URL + "/jwks",
+				IDTokenSigningAlgValuesSupported: []string{"RS256"},
+			}))
+		case "/jwks":
+			require.NoError(t, json.NewEncoder(w).Encode(map[string]interface{}{
+				"keys": []jwk.Key{publicJWK},
+			}))
+		case "/userinfo":
+			require.NoError(t, json.NewEncoder(w).Encode(map[string]interface{}{
+				"sub":  "user-1",
+				"name": "Jane Doe",
+			}))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	ext := conf.GenericOAuthProviderConfiguration{
+		OAuthProviderConfiguration: &conf.OAuthProviderConfiguration{
+			Enabled:     true,
+			ClientID:    []string{"client-id"},
+			Secret:      "secret",
+			RedirectURI: "http://localhost/callback",
+		},
+		Issuer: server.URL,
+	}
+
+	p, err := NewGenericProvider(ext, "openid")
+	require.NoError(t, err)
+	_, err = NewGenericProvider(ext, "openid")
+	require.NoError(t, err)
+	require.Equal(t, 1, discoveryRequests, "discovery document should be cached")
+
+	gp := p.(*genericProvider)
+	require.Equal(t, server.URL+"/authorize", gp.Endpoint.AuthURL)
+	require.Equal(t, server.URL+"/token", gp.Endpoint.TokenURL)
+	require.NotNil(t, gp.verifier)
+
+	signIDToken := func(claims jwt.MapClaims) string {
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+		token.Header["kid"] = "test-key"
+		signed, err := token.SignedString(privateKey)
+		require.NoError(t, err)
+		return signed
+	}
+
+	now := time.Now()
+	idToken := signIDToken(jwt.MapClaims{
+		"iss":            server.URL,
+		"aud":            "client-id",
+		"sub":            "user-1",
+		"email":          "jane@example.com",
+		"email_verified": true,
+		"iat":            now.Unix(),
+		"exp":            now.Add(time.Hour).Unix(),
+	})
+
+	tok := (&oauth2.Token{AccessToken: "access-token"}).WithExtra(map[string]interface{}{
+		"id_token": idToken,
+	})
+	data, err := gp.GetUserData(context.Background(), tok)
+	require.NoError(t, err)
+	require.Equal(t, "user-1", data.Metadata.Subject)
+	require.Equal(t, server.URL, data.Metadata.Issuer)
+	require.Equal(t, "Jane Doe", data.Metadata.Name)
+	require.Equal(t, "jane@example.com", data.Emails[0].Email)
+	require.True(t, data.Emails[0].Verified)
+
+	wrongAudience := signIDToken(jwt.MapClaims{
+		"iss": server.URL,
+		"aud": "other-client",
+		"sub": "user-1",
+		"iat": now.Unix(),
+		"exp": now.Add(time.Hour).Unix(),
+	})
+	tok = (&oauth2.Token{AccessToken: "access-token"}).WithExtra(map[string]interface{}{
+		"id_token": wrongAudience,
+	})
+	_, err = gp.GetUserData(context.Background(), tok)
+	require.ErrorContains(t, err, "unable to verify id_token")
+
+	mismatched := signIDToken(jwt.MapClaims{
+		"iss": server.URL,
+		"aud": "client-id",
+		"sub": "user-2",
+		"iat": now.Unix(),
+		"exp": now.Add(time.Hour).Unix(),
+	})
+	tok = (&oauth2.Token{AccessToken: "access-token"}).WithExtra(map[string]interface{}{
+		"id_token": mismatched,
+	})
+	_, err = gp.GetUserData(context.Background(), tok)
+	require.ErrorContains(t, err, "userinfo subject does not match id_token subject")
+
+	ext.Issuer = "https://other.example.com"
+	ext.DiscoveryURL = server.URL + "/.well-known/openid-configuration"
+	_, err = NewGenericProvider(ext, "openid")
+	require.ErrorContains(t, err, "does not match configured issuer")
+}
+
+func TestGetOIDCDiscoveryDoesNotBlockOtherIssuers(t *testing.T) {
+	discoveryHandler := func(wait <-chan struct{}) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if wait != nil {
+				<-wait
+			}
+			issuer := "http://" + r.Host
+			require.NoError(t, json.NewEncoder(w).Encode(map[string]string{
+				"issuer":                 issuer,
+				"authorization_endpoint": issuer + "/authorize",
+				"token_endpoint":         issuer + "/token",
+			}))
+		}
+	}
+
+	wait := make(chan struct{})
+	release := sync.OnceFunc(func() { close(wait) })
+
+	slow := httptest.NewServer(discoveryHandler(wait))
+	defer slow.Close()
+	defer release()
+
+	fast := httptest.NewServer(discoveryHandler(nil))
+	defer fast.Close()
+
+	slowDone := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			_, err := getOIDCDiscovery(slow.URL + "/.well-known/openid-configuration")
+			slowDone <- err
+		}()
+	}
+
+	// a discovery endpoint that is slow to respond does not hold up the
+	// discovery of other issuers
+	entry, err := getOIDCDiscovery(fast.URL + "/.well-known/openid-configuration")
+	require.NoError(t, err)
+	require.Equal(t, fast.URL, entry.discovery.Issuer)
+
+	release()
+	for i := 0; i < 2; i++ {
+		require.NoError(t, <-slowDone)
+	}
+
+	entry, err = getOIDCDiscovery(slow.URL + "/.well-known/openid-configuration")
+	require.NoError(t, err)
+	require.Equal(t, slow.URL, entry.discovery.Issuer)
+}
+
+func TestGenericProviderUserDataExpressions(t *testing.T) {
+	p := genericProvider{
+		userDataMapping: map[string]string{
+			"Picture": "avatar",
+		},
+		userDataExpressions: map[string]string{
+			"Name":          "join(' ', [given_name, family_name])",
+			"Email":         "emails[?primary].value | [0]",
+			"EmailVerified": "emails[?primary].verified | [0]",
+			"Subject":       "to_string(id)",
+		},
+	}
+
+	data, err := p.mapUserData(map[string]interface{}{
+		"id":          float64(42),
+		"given_name":  "Jane",
+		"family_name": "Doe",
+		"avatar":      "https://example.com/jane.png",
+		"emails": []interface{}{
+			map[string]interface{}{"value": "old@example.com", "primary": false, "verified": false},
+			map[string]interface{}{"value": "jane@example.com", "primary": true, "verified": true},
+		},
+	}, "https://example.com", "")
+	require.NoError(t, err)
+	assert.Equal(t, "Jane Doe", data.Metadata.Name)
+	assert.Equal(t, "42", data.Metadata.Subject)
+	assert.Equal(t, "https://example.com/jane.png", data.Metadata.Picture)
+	assert.Equal(t, "jane@example.com", data.Emails[0].Email)
+	assert.True(t, data.Emails[0].Verified)
+
+	p.userDataExpressions["Name"] = "length(given_name)"
+	data, err = p.mapUserData(map[string]interface{}{"given_name": "Jane"}, "", "")
+	require.NoError(t, err)
+	assert.Equal(t, "4", data.Metadata.Name)
+
+	p.userDataExpressions["EmailVerified"] = "'yes'"
+	_, err = p.mapUserData(map[string]interface{}{}, "", "")
+	require.ErrorContains(t, err, `unable to read field as boolean: "EmailVerified"`)
+}
@@ -4,8 +4,13 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"slices"
 	"strings"
+	"sync"
 
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/jmespath/go-jmespath"
 	"github.com/supabase/auth/internal/conf"
 	"golang.org/x/oauth2"
 )
@@ -14,6 +19,28 @@ import (
 type keycloakProvider struct {
 	*oauth2.Config
 	Host string
+
+	mapRoles        bool
+	rolesExpression string
+}
+
+var (
+	keycloakKeySetsMu sync.Mutex
+	keycloakKeySets   = make(map[string]*oidc.RemoteKeySet)
+)
+
+// keycloakKeySet returns the key set of the realm, reused between requests
+// so its keys are only fetched again when a token is signed with a new key.
+func keycloakKeySet(realmURL string) *oidc.RemoteKeySet {
+	keycloakKeySetsMu.Lock()
+	defer keycloakKeySetsMu.Unlock()
+
+	keySet, ok := keycloakKeySets[realmURL]
+	if !ok {
+		keySet = oidc.NewRemoteKeySet(context.Background(), realmURL+"/protocol/openid-connect/certs")
+		keycloakKeySets[realmURL] = keySet
+	}
+	return keySet
 }
 
 type keycloakUser struct {
@@ -47,7 +74,7 @@ func (u *keycloakUser) UnmarshalJSON(data []byte) error {
 }
 
 // NewKeycloakProvider creates a Keycloak account provider.
-func NewKeycloakProvider(ext conf.OAuthProviderConfiguration, scopes string) (OAuthProvider, error) {
+func NewKeycloakProvider(ext conf.KeycloakProviderConfiguration, scopes string) (OAuthProvider, error) {
 	if err := ext.ValidateOAuth(); err != nil {
 		return nil, err
 	}
@@ -65,9 +92,17 @@ func NewKeycloakProvider(ext conf.OAuthProviderConfiguration, scopes string) (OA
 		return nil, errors.New("unable to find URL for the Keycloak provider")
 	}
 
-	extURLlen := len(ext.URL)
-	if ext.URL[extURLlen-1] == '/' {
-		ext.URL = ext.URL[:extURLlen-1]
+	realmURL := ext.RealmURL()
+
+	rolesExpression := ext.RolesExpression
+	if rolesExpression == "" {
+		// the realm roles and the roles of the client, quoting the
+		// client ID as a JMESPath identifier
+		clientID, err := json.Marshal(ext.ClientID[0])
+		if err != nil {
+			return nil, err
+		}
+		rolesExpression = fmt.Sprintf("[realm_access.roles, resource_access.%s.roles][]", clientID)
 	}
 
 	return &keycloakProvider{
@@ -75,13 +110,15 @@ func NewKeycloakProvider(ext conf.OAuthProviderConfiguration, scopes string) (OA
 			ClientID:     ext.ClientID[0],
 			ClientSecret: ext.Secret,
 			Endpoint: oauth2.Endpoint{
-				AuthURL:  ext.URL + "/protocol/openid-connect/auth",
-				TokenURL: ext.URL + "/protocol/openid-connect/token",
+				AuthURL:  realmURL + "/protocol/openid-connect/auth",
+				TokenURL: realmURL + "/protocol/openid-connect/token",
 			},
 			RedirectURL: ext.RedirectURI,
 			Scopes:      oauthScopes,
 		},
-		Host: ext.URL,
+		Host:            realmURL,
+		mapRoles:        ext.RolesInAppMetadata,
+		rolesExpression: rolesExpression,
 	}, nil
 }
 
@@ -133,6 +170,67 @@ func (g keycloakProvider) GetUserData(ctx context.Context, tok *oauth2.Token) (*
 		ProviderId: u.Sub,
 	}
 
+	if g.mapRoles {
+		roles, err := g.getRoles(ctx, tok.AccessToken)
+		if err != nil {
+			return nil, err
+		}
+		customClaims["roles"] = roles
+	}
+
 	return data, nil
+}
+
+// getRoles verifies the access token against the keys of the realm and
+// evaluates the roles expression against its claims.
+func (g keycloakProvider) getRoles(ctx context.Context, accessToken string) ([]string, error) {
+	config := &oidc.Config{
+		// access tokens are issued to the resource servers, and
+		// name the client in azp instead
+		SkipClientIDCheck: true,
+	}
+	if OverrideClock != nil {
+		config.Now = OverrideClock
+	}
+
+	token, err := oidc.NewVerifier(g.Host, keycloakKeySet(g.Host), config).Verify(ctx, accessToken)
+	if err != nil {
+		return nil, fmt.Errorf("keycloak: invalid access token: %w", err)
+	}
+
+	var claims map[string]any
+	if err := token.Claims(&claims); err != nil {
+		return nil, err
+	}
+
+	if azp, _ := claims["azp"].(string); azp != g.ClientID {
+		return nil, fmt.Errorf("keycloak: access token issued to %q instead of %q", azp, g.ClientID)
+	}
+
+	value, err := jmespath.Search(g.rolesExpression, claims)
+	if err != nil {
+		return nil, fmt.Errorf("keycloak: unable to evaluate roles expression: %w", err)
+	}
+
+	roles := []string{}
+	values, _ := value.([]any)
+	for _, value := range values {
+		if role, ok := value.(string); ok && !slices.Contains(roles, role) {
+			roles = append(roles, role)
+		}
+	}
+
+	return roles, nil
+}
+
+// KeycloakRoles returns the roles of the user read from the Keycloak access
+// token. It reports false when the roles weren't mapped, such as for users
+// signing in with an ID token.
+func KeycloakRoles(data *UserProvidedData) ([]string, bool) {
+	if data.Metadata == nil {
+		return nil, false
+	}
 
+	roles, ok := data.Metadata.CustomClaims["roles"].([]string)
+	return roles, ok
 }
@@ -0,0 +1,153 @@
+package provider
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/stretchr/testify/require"
+	"github.com/supabase/auth/internal/conf"
+	"golang.org/x/oauth2"
+)
+
+func TestKeycloakProviderRealmURL(t *testing.T) {
+	ext := conf.KeycloakProviderConfiguration{
+		OAuthProviderConfiguration: conf.OAuthProviderConfiguration{
+			Enabled:     true,
+			ClientID:    []string{"client-id"},
+			Secret:      "secret",
+			RedirectURI: "http://localhost/callback",
+			URL:         "https://keycloak.example.com/",
+		},
+		Realm: "my realm",
+	}
+
+	p, err := NewKeycloakProvider(ext, "")
+	require.NoError(t, err)
+
+	kp := p.(*keycloakProvider)
+	require.Equal(t, "https://keycloak.example.com/realms/my%20realm", kp.Host)
+	require.Equal(t, "https://keycloak.example.com/realms/my%20realm/protocol/openid-connect/auth", kp.Endpoint.AuthURL)
+	require.Equal(t, `[realm_access.roles, resource_access."client-id".roles][]`, kp.rolesExpression)
+}
+
+func TestKeycloakProviderRoles(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	publicJWK, err := jwk.FromRaw(privateKey.Public())
+	require.NoError(t, err)
+	require.NoError(t, publicJWK.Set(jwk.KeyIDKey, "test-key"))
+	require.NoError(t, publicJWK.Set(jwk.AlgorithmKey, "RS256"))
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/realms/test/protocol/openid-connect/certs":
+			require.NoError(t, json.NewEncoder(w).Encode(map[string]interface{}{
+				"keys": []jwk.Key{publicJWK},
+			}))
+		case "/realms/test/protocol/openid-connect/userinfo":
+			require.NoError(t, json.NewEncoder(w).Encode(map[string]interface{}{
+				"sub":   "user-1",
+				"name":  "Jane Doe",
+				"email": "jane@example.com",
+			}))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	ext := conf.KeycloakProviderConfiguration{
+		OAuthProviderConfiguration: conf.OAuthProviderConfiguration{
+			Enabled:     true,
+			ClientID:    []string{"client-id"},
+			Secret:      "secret",
+			RedirectURI: "http://localhost/callback",
+			URL:         server.URL,
+		},
+		Realm:              "test",
+		RolesInAppMetadata: true,
+	}
+
+	p, err := NewKeycloakProvider(ext, "")
+	require.NoError(t, err)
+
+	signAccessToken := func(claims jwt.MapClaims) string {
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+		token.Header["kid"] = "test-key"
+		signed, err := token.SignedString(privateKey)
+		require.NoError(t, err)
+		return signed
+	}
+
+	now := time.Now()
+	accessToken := signAccessToken(jwt.MapClaims{
+		"iss": server.URL + "/realms/test",
+		"aud": "account",
+		"azp": "client-id",
+		"sub": "user-1",
+		"iat": now.Unix(),
+		"exp": now.Add(time.Hour).Unix(),
+		"realm_access": map[string]interface{}{
+			"roles": []string{"admin", "user"},
+		},
+		"resource_access": map[string]interface{}{
+			"client-id": map[string]interface{}{
+				"roles": []string{"editor", "user"},
+			},
+			"other-client": map[string]interface{}{
+				"roles": []string{"other"},
+			},
+		},
+	})
+
+	data, err := p.GetUserData(context.Background(), &oauth2.Token{AccessToken: accessToken})
+	require.NoError(t, err)
+	require.Equal(t, "user-1", data.Metadata.Subject)
+
+	roles, ok := KeycloakRoles(data)
+	require.True(t, ok)
+	require.Equal(t, []string{"admin", "user", "editor"}, roles)
+
+	otherClient := signAccessToken(jwt.MapClaims{
+		"iss": server.URL + "/realms/test",
+		"azp": "other-client",
+		"sub": "user-1",
+		"iat": now.Unix(),
+		"exp": now.Add(time.Hour).Unix(),
+	})
+	_, err = p.GetUserData(context.Background(), &oauth2.Token{AccessToken: otherClient})
+	require.ErrorContains(t, err, "access token issued to")
+
+	otherRealm := signAccessToken(jwt.MapClaims{
+		"iss": server.URL + "/realms/other",
+		"azp": "client-id",
+		"sub": "user-1",
+		"iat": now.Unix(),
+		"exp": now.Add(time.Hour).Unix(),
+	})
+	_, err = p.GetUserData(context.Background(), &oauth2.Token{AccessToken: otherRealm})
+	require.ErrorContains(t, err, "invalid access token")
+
+	_, err = p.GetUserData(context.Background(), &oauth2.Token{AccessToken: "opaque-token"})
+	require.ErrorContains(t, err, "invalid access token")
+
+	ext.RolesExpression = "realm_access.roles"
+	p, err = NewKeycloakProvider(ext, "")
+	require.NoError(t, err)
+
+	data, err = p.GetUserData(context.Background(), &oauth2.Token{AccessToken: accessToken})
+	require.NoError(t, err)
+
+	roles, ok = KeycloakRoles(data)
+	require.True(t, ok)
+	require.Equal(t, []string{"admin", "user"}, roles)
+}
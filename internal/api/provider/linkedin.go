@@ -66,6 +66,9 @@ type linkedinElements struct {
 }
 
 // NewLinkedinProvider creates a Linkedin account provider.
+//
+// Deprecated: LinkedIn no longer grants the r_liteprofile and
+// r_emailaddress scopes to new applications, use NewLinkedinOIDCProvider.
 func NewLinkedinProvider(ext conf.OAuthProviderConfiguration, scopes string) (OAuthProvider, error) {
 	if err := ext.ValidateOAuth(); err != nil {
 		return nil, err
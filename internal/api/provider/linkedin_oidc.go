@@ -68,11 +68,21 @@ func (g linkedinOIDCProvider) RequiresPKCE() bool {
 }
 
 func (g linkedinOIDCProvider) GetUserData(ctx context.Context, tok *oauth2.Token) (*UserProvidedData, error) {
-	idToken := tok.Extra("id_token")
-	if tok.AccessToken == "" || idToken == nil {
+	if tok.AccessToken == "" {
 		return &UserProvidedData{}, nil
 	}
 
+	idToken := tok.Extra("id_token")
+	if idToken == nil {
+		// the userinfo endpoint holds the same claims when LinkedIn
+		// leaves the ID token out
+		var u LinkedinIDTokenClaims
+		if err := makeRequest(ctx, tok, g.Config, g.APIPath+"/v2/userinfo", &u); err != nil {
+			return nil, err
+		}
+		return linkedinUserData(IssuerLinkedin, u.Subject, &u), nil
+	}
+
 	_, data, err := ParseIDToken(ctx, g.oidc, &oidc.Config{
 		ClientID: g.ClientID,
 	}, idToken.(string), ParseIDTokenOptions{
@@ -81,5 +91,17 @@ func (g linkedinOIDCProvider) GetUserData(ctx context.Context, tok *oauth2.Token
 	if err != nil {
 		return nil, err
 	}
+
+	if data.Metadata.Picture == "" {
+		// LinkedIn leaves the picture out of some ID tokens, the avatar is
+		// best effort so that sign-in works when the userinfo endpoint
+		// does not
+		var u LinkedinIDTokenClaims
+		if err := makeRequest(ctx, tok, g.Config, g.APIPath+"/v2/userinfo", &u); err == nil && u.Subject == data.Metadata.Subject {
+			data.Metadata.Picture = u.Picture
+			data.Metadata.AvatarURL = u.Picture
+		}
+	}
+
 	return data, nil
 }
@@ -0,0 +1,146 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/supabase/auth/internal/conf"
+)
+
+// New returns the provider with the name, configured with the external
+// provider configuration of config.
+func New(ctx context.Context, config *conf.GlobalConfiguration, name string, scopes string) (Provider, conf.OAuthProviderConfiguration, error) {
+	name = strings.ToLower(name)
+
+	var err error
+	var p Provider
+	var pConfig conf.OAuthProviderConfiguration
+
+	switch name {
+	case "apple":
+		pConfig = config.External.Apple
+		p, err = NewAppleProvider(ctx, pConfig)
+	case "azure":
+		pConfig = config.External.Azure.OAuthProviderConfiguration
+		p, err = NewAzureProvider(config.External.Azure, scopes)
+	case "bitbucket":
+		pConfig = config.External.Bitbucket
+		p, err = NewBitbucketProvider(pConfig)
+	case "custom":
+		pConfig = config.External.Custom.OAuthProviderConfiguration
+		p, err = NewCustomProvider(config.External.Custom, scopes)
+	case "discord":
+		pConfig = config.External.Discord
+		p, err = NewDiscordProvider(pConfig, scopes)
+	case "facebook":
+		pConfig = config.External.Facebook
+		p, err = NewFacebookProvider(pConfig, scopes)
+	case "figma":
+		pConfig = config.External.Figma
+		p, err = NewFigmaProvider(pConfig, scopes)
+	case "fly":
+		pConfig = config.External.Fly
+		p, err = NewFlyProvider(pConfig, scopes)
+	case "generic_oidc_1":
+		pConfig = *config.External.GenericOIDC1.OAuthProviderConfiguration
+		p, err = NewGenericProvider(config.External.GenericOIDC1, scopes)
+	case "generic_oidc_2":
+		pConfig = *config.External.GenericOIDC2.OAuthProviderConfiguration
+		p, err = NewGenericProvider(config.External.GenericOIDC2, scopes)
+	case "generic_oidc_3":
+		pConfig = *config.External.GenericOIDC3.OAuthProviderConfiguration
+		p, err = NewGenericProvider(config.External.GenericOIDC3, scopes)
+	case "github":
+		pConfig = config.External.Github
+		p, err = NewGithubProvider(pConfig, scopes)
+	case "gitlab":
+		pConfig = config.External.Gitlab
+		p, err = NewGitlabProvider(pConfig, scopes)
+	case "google":
+		pConfig = config.External.Google
+		p, err = NewGoogleProvider(ctx, pConfig, scopes)
+	case "kakao":
+		pConfig = config.External.Kakao
+		p, err = NewKakaoProvider(pConfig, scopes)
+	case "keycloak":
+		pConfig = config.External.Keycloak.OAuthProviderConfiguration
+		p, err = NewKeycloakProvider(config.External.Keycloak, scopes)
+	case "linkedin":
+		pConfig = config.External.Linkedin
+		p, err = NewLinkedinProvider(pConfig, scopes)
+	case "linkedin_oidc":
+		pConfig = config.External.LinkedinOIDC
+		p, err = NewLinkedinOIDCProvider(ctx, pConfig, scopes)
+	case "notion":
+		pConfig = config.External.Notion
+		p, err = NewNotionProvider(pConfig)
+	case "qq":
+		pConfig = config.External.QQ
+		p, err = NewQQProvider(pConfig, scopes)
+	case "snapchat":
+		pConfig = config.External.Snapchat
+		p, err = NewSnapchatProvider(pConfig, scopes)
+	case "spotify":
+		pConfig = config.External.Spotify
+		p, err = NewSpotifyProvider(pConfig, scopes)
+	case "slack":
+		pConfig = config.External.Slack
+		p, err = NewSlackProvider(pConfig, scopes)
+	case "slack_oidc":
+		pConfig = config.External.SlackOIDC
+		p, err = NewSlackOIDCProvider(pConfig, scopes)
+	case "steam":
+		pConfig = config.External.Steam
+		p, err = NewSteamProvider(pConfig)
+	case "twitch":
+		pConfig = config.External.Twitch
+		p, err = NewTwitchProvider(pConfig, scopes)
+	case "twitter":
+		pConfig = config.External.Twitter
+		p, err = NewTwitterProvider(pConfig, scopes)
+	case "x":
+		pConfig = config.External.X
+		p, err = NewXProvider(pConfig, scopes)
+	case "vercel_marketplace":
+		pConfig = config.External.VercelMarketplace
+		p, err = NewVercelMarketplaceProvider(ctx, pConfig, scopes)
+	case "wechat":
+		pConfig = config.External.WeChat
+		p, err = NewWeChatProvider(pConfig, scopes)
+	case "wechat_official_account":
+		pConfig = config.External.WeChatOfficialAccount
+		p, err = NewWeChatOfficialAccountProvider(pConfig, scopes)
+	case "workos":
+		pConfig = config.External.WorkOS
+		p, err = NewWorkOSProvider(pConfig)
+	case "zoom":
+		pConfig = config.External.Zoom
+		p, err = NewZoomProvider(pConfig)
+	default:
+		if conn := config.External.Connections.Find(name); conn != nil {
+			return newConnection(ctx, conn, scopes)
+		}
+		return nil, pConfig, fmt.Errorf("Provider %s could not be found", name)
+	}
+
+	return p, pConfig, err
+}
+
+// newConnection returns the provider of the connection's type, configured
+// with the connection.
+func newConnection(ctx context.Context, conn *conf.ExternalConnectionConfiguration, scopes string) (Provider, conf.OAuthProviderConfiguration, error) {
+	pConfig := *conn.OAuthProviderConfiguration
+
+	if conn.Type == conf.GenericOIDCConnectionType {
+		p, err := NewGenericProvider(conn.GenericOAuthProviderConfiguration, scopes)
+		return p, pConfig, err
+	}
+
+	providers, err := conn.ProviderConfiguration()
+	if err != nil {
+		return nil, pConfig, err
+	}
+
+	return New(ctx, &conf.GlobalConfiguration{External: providers}, conn.Type, scopes)
+}
@@ -2,6 +2,7 @@ package provider
 
 import (
 	"context"
+	"encoding/json"
 	"strconv"
 	"strings"
 	"time"
@@ -217,12 +218,52 @@ func parseAppleIDToken(token *oidc.IDToken) (*oidc.IDToken, *UserProvidedData, e
 type LinkedinIDTokenClaims struct {
 	jwt.RegisteredClaims
 
-	Email         string `json:"email"`
-	EmailVerified string `json:"email_verified"`
-	FamilyName    string `json:"family_name"`
-	GivenName     string `json:"given_name"`
-	Locale        string `json:"locale"`
-	Picture       string `json:"picture"`
+	Email         string         `json:"email"`
+	EmailVerified any            `json:"email_verified"`
+	FamilyName    string         `json:"family_name"`
+	GivenName     string         `json:"given_name"`
+	Locale        LinkedinLocale `json:"locale"`
+	Picture       string         `json:"picture"`
+}
+
+// IsEmailVerified reports whether LinkedIn verified the email, which it
+// encodes either as a boolean or as a string.
+func (c *LinkedinIDTokenClaims) IsEmailVerified() bool {
+	switch v := c.EmailVerified.(type) {
+	case bool:
+		return v
+	case string:
+		verified, _ := strconv.ParseBool(v)
+		return verified
+	}
+	return false
+}
+
+// LinkedinLocale is the locale of a LinkedIn member, which is either a
+// string or an object with the language and country.
+type LinkedinLocale string
+
+func (l *LinkedinLocale) UnmarshalJSON(data []byte) error {
+	var str string
+	if err := json.Unmarshal(data, &str); err == nil {
+		*l = LinkedinLocale(str)
+		return nil
+	}
+
+	var obj struct {
+		Country  string `json:"country"`
+		Language string `json:"language"`
+	}
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return err
+	}
+
+	locale := obj.Language
+	if obj.Country != "" {
+		locale += "-" + obj.Country
+	}
+	*l = LinkedinLocale(locale)
+	return nil
 }
 
 func parseLinkedinIDToken(token *oidc.IDToken) (*oidc.IDToken, *UserProvidedData, error) {
@@ -231,32 +272,35 @@ func parseLinkedinIDToken(token *oidc.IDToken) (*oidc.IDToken, *UserProvidedData
 		return nil, nil, err
 	}
 
+	return token, linkedinUserData(token.Issuer, token.Subject, &claims), nil
+}
+
+func linkedinUserData(issuer, subject string, claims *LinkedinIDTokenClaims) *UserProvidedData {
 	var data UserProvidedData
-	emailVerified, err := strconv.ParseBool(claims.EmailVerified)
-	if err != nil {
-		return nil, nil, err
-	}
 
 	if claims.Email != "" {
 		data.Emails = append(data.Emails, Email{
 			Email:    claims.Email,
-			Verified: emailVerified,
+			Verified: claims.IsEmailVerified(),
 			Primary:  true,
 		})
 	}
 
 	data.Metadata = &Claims{
-		Issuer:     token.Issuer,
-		Subject:    token.Subject,
-		Name:       strings.TrimSpace(claims.GivenName + " " + claims.FamilyName),
-		GivenName:  claims.GivenName,
-		FamilyName: claims.FamilyName,
-		Locale:     claims.Locale,
-		Picture:    claims.Picture,
-		ProviderId: token.Subject,
+		Issuer:        issuer,
+		Subject:       subject,
+		Name:          strings.TrimSpace(claims.GivenName + " " + claims.FamilyName),
+		GivenName:     claims.GivenName,
+		FamilyName:    claims.FamilyName,
+		Locale:        string(claims.Locale),
+		Picture:       claims.Picture,
+		AvatarURL:     claims.Picture,
+		Email:         claims.Email,
+		EmailVerified: claims.IsEmailVerified(),
+		ProviderId:    subject,
 	}
 
-	return token, &data, nil
+	return &data
 }
 
 type AzureIDTokenClaims struct {
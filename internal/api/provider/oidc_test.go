@@ -313,3 +313,54 @@ func TestClaimsAudienceUnmarshal(t *testing.T) {
 		})
 	}
 }
+
+func TestLinkedinIDTokenClaims(t *testing.T) {
+	examples := []struct {
+		claims   string
+		verified bool
+		locale   string
+	}{
+		{
+			claims:   `{"email":"linkedin@example.com","email_verified":true,"locale":"en_US"}`,
+			verified: true,
+			locale:   "en_US",
+		},
+		{
+			claims:   `{"email":"linkedin@example.com","email_verified":"true","locale":{"country":"US","language":"en"}}`,
+			verified: true,
+			locale:   "en-US",
+		},
+		{
+			claims:   `{"email":"linkedin@example.com","email_verified":"false"}`,
+			verified: false,
+		},
+		{
+			claims:   `{"email":"linkedin@example.com"}`,
+			verified: false,
+		},
+	}
+
+	for _, example := range examples {
+		var claims LinkedinIDTokenClaims
+		require.NoError(t, json.Unmarshal([]byte(example.claims), &claims))
+		require.Equal(t, example.verified, claims.IsEmailVerified(), example.claims)
+		require.Equal(t, example.locale, string(claims.Locale), example.claims)
+	}
+}
+
+func TestLinkedinUserData(t *testing.T) {
+	claims := LinkedinIDTokenClaims{
+		Email:         "linkedin@example.com",
+		EmailVerified: true,
+		GivenName:     "Linkedin",
+		FamilyName:    "Test",
+		Picture:       "http://example.com/avatar",
+	}
+
+	data := linkedinUserData(IssuerLinkedin, "linkedinTestId", &claims)
+	require.Equal(t, []Email{{Email: "linkedin@example.com", Verified: true, Primary: true}}, data.Emails)
+	require.Equal(t, "Linkedin Test", data.Metadata.Name)
+	require.Equal(t, "http://example.com/avatar", data.Metadata.Picture)
+	require.Equal(t, "http://example.com/avatar", data.Metadata.AvatarURL)
+	require.Equal(t, "linkedinTestId", data.Metadata.ProviderId)
+}
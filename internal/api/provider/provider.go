@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"io"
 	"log"
 	"net/http"
@@ -93,6 +94,11 @@ type Email struct {
 type UserProvidedData struct {
 	Emails   []Email
 	Metadata *Claims
+
+	// UserMetaData and AppMetaData are the changes the before user created
+	// hook makes to the metadata of the user created from the data.
+	UserMetaData map[string]interface{}
+	AppMetaData  map[string]interface{}
 }
 
 // Provider is an interface for interacting with external account providers
@@ -108,6 +114,38 @@ type OAuthProvider interface {
 	RequiresPKCE() bool
 }
 
+// tokenSourceProvider is implemented by the OAuth providers that embed their
+// oauth2.Config.
+type tokenSourceProvider interface {
+	TokenSource(context.Context, *oauth2.Token) oauth2.TokenSource
+}
+
+// RefreshOAuthToken exchanges the refresh token for a new token with the
+// provider. The returned token keeps the refresh token when the provider
+// does not rotate it.
+func RefreshOAuthToken(ctx context.Context, p Provider, refreshToken string) (*oauth2.Token, error) {
+	tsp, ok := p.(tokenSourceProvider)
+	if !ok {
+		return nil, errors.New("provider does not support refreshing tokens")
+	}
+
+	ctx = context.WithValue(ctx, oauth2.HTTPClient, &http.Client{Timeout: defaultTimeout})
+
+	// The expired token makes the token source refresh it right away.
+	token, err := tsp.TokenSource(ctx, &oauth2.Token{
+		RefreshToken: refreshToken,
+		Expiry:       time.Unix(1, 0),
+	}).Token()
+	if err != nil {
+		return nil, err
+	}
+
+	if token.RefreshToken == "" {
+		token.RefreshToken = refreshToken
+	}
+	return token, nil
+}
+
 func chooseHost(base, defaultHost string) string {
 	if base == "" {
 		return "https://" + defaultHost
@@ -143,3 +181,30 @@ func makeRequest(ctx context.Context, tok *oauth2.Token, g *oauth2.Config, url s
 
 	return nil
 }
+
+// getJSON is makeRequest for providers that take the credentials as query
+// parameters instead of an Authorization header.
+func getJSON(ctx context.Context, url string, dst interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+
+	client := &http.Client{Timeout: defaultTimeout}
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer utilities.SafeClose(res.Body)
+
+	bodyBytes, err := io.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+
+	if res.StatusCode < http.StatusOK || res.StatusCode >= http.StatusMultipleChoices {
+		return httpError(res.StatusCode, "%s", string(bodyBytes))
+	}
+
+	return json.Unmarshal(bodyBytes, dst)
+}
@@ -0,0 +1,62 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/supabase/auth/internal/conf"
+)
+
+func TestRefreshOAuthToken(t *testing.T) {
+	var refreshTokens []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/login/oauth/access_token", r.URL.Path)
+		require.NoError(t, r.ParseForm())
+		require.Equal(t, "refresh_token", r.PostForm.Get("grant_type"))
+		refreshTokens = append(refreshTokens, r.PostForm.Get("refresh_token"))
+
+		resp := map[string]interface{}{
+			"access_token": "new-access-token",
+			"token_type":   "bearer",
+			"expires_in":   3600,
+		}
+		if len(refreshTokens) > 1 {
+			resp["refresh_token"] = "rotated-refresh-token"
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		require.NoError(t, json.NewEncoder(w).Encode(resp))
+	}))
+	defer server.Close()
+
+	p, err := NewGithubProvider(conf.OAuthProviderConfiguration{
+		Enabled:     true,
+		URL:         server.URL,
+		ClientID:    []string{"client-id"},
+		Secret:      "secret",
+		RedirectURI: "https://example.com/callback",
+	}, "")
+	require.NoError(t, err)
+
+	token, err := RefreshOAuthToken(context.Background(), p, "refresh-token")
+	require.NoError(t, err)
+	require.Equal(t, "new-access-token", token.AccessToken)
+	require.False(t, token.Expiry.IsZero())
+	// the refresh token is kept when it is not rotated
+	require.Equal(t, "refresh-token", token.RefreshToken)
+
+	token, err = RefreshOAuthToken(context.Background(), p, "refresh-token")
+	require.NoError(t, err)
+	require.Equal(t, "rotated-refresh-token", token.RefreshToken)
+
+	require.Equal(t, []string{"refresh-token", "refresh-token"}, refreshTokens)
+}
+
+func TestNewUnknownProvider(t *testing.T) {
+	_, _, err := New(context.Background(), &conf.GlobalConfiguration{}, "unknown", "")
+	require.Error(t, err)
+}
@@ -0,0 +1,199 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/supabase/auth/internal/conf"
+	"golang.org/x/oauth2"
+)
+
+// QQ Connect
+// See: https://wiki.connect.qq.com/%E4%BD%BF%E7%94%A8authorization_code%E8%8E%B7%E5%8F%96access_token
+const (
+	defaultQQAPIBase = "graph.qq.com"
+)
+
+// qqProvider signs in with QQ Connect, which exchanges codes with GET
+// requests and identifies users with an openid per app and a unionid shared
+// by the apps of the same developer account. It holds its oauth2.Config in
+// a field, as QQ tokens can't be refreshed the standard way.
+type qqProvider struct {
+	config  *oauth2.Config
+	APIHost string
+}
+
+type qqError struct {
+	Error            any    `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+func (e qqError) err() error {
+	if e.Error == nil {
+		return nil
+	}
+	return fmt.Errorf("qq: error %v: %s", e.Error, e.ErrorDescription)
+}
+
+type qqToken struct {
+	qqError
+	AccessToken  string      `json:"access_token"`
+	ExpiresIn    json.Number `json:"expires_in"`
+	RefreshToken string      `json:"refresh_token"`
+}
+
+type qqOpenID struct {
+	qqError
+	ClientID string `json:"client_id"`
+	OpenID   string `json:"openid"`
+	UnionID  string `json:"unionid"`
+}
+
+type qqUser struct {
+	Ret          int    `json:"ret"`
+	Msg          string `json:"msg"`
+	Nickname     string `json:"nickname"`
+	Gender       string `json:"gender"`
+	FigureURLQQ1 string `json:"figureurl_qq_1"`
+	FigureURLQQ2 string `json:"figureurl_qq_2"`
+}
+
+// NewQQProvider creates a QQ Connect provider.
+func NewQQProvider(ext conf.OAuthProviderConfiguration, scopes string) (OAuthProvider, error) {
+	if err := ext.ValidateOAuth(); err != nil {
+		return nil, err
+	}
+
+	apiHost := chooseHost(ext.URL, defaultQQAPIBase)
+
+	oauthScopes := []string{"get_user_info"}
+	if scopes != "" {
+		oauthScopes = append(oauthScopes, strings.Split(scopes, ",")...)
+	}
+
+	return &qqProvider{
+		config: &oauth2.Config{
+			ClientID:     ext.ClientID[0],
+			ClientSecret: ext.Secret,
+			Endpoint: oauth2.Endpoint{
+				AuthURL:  apiHost + "/oauth2.0/authorize",
+				TokenURL: apiHost + "/oauth2.0/token",
+			},
+			RedirectURL: ext.RedirectURI,
+			Scopes:      oauthScopes,
+		},
+		APIHost: apiHost,
+	}, nil
+}
+
+func (p qqProvider) AuthCodeURL(state string, opts ...oauth2.AuthCodeOption) string {
+	return p.config.AuthCodeURL(state, opts...)
+}
+
+func (p qqProvider) GetOAuthToken(ctx context.Context, code string, opts ...oauth2.AuthCodeOption) (*oauth2.Token, error) {
+	q := url.Values{}
+	q.Set("grant_type", "authorization_code")
+	q.Set("client_id", p.config.ClientID)
+	q.Set("client_secret", p.config.ClientSecret)
+	q.Set("code", code)
+	q.Set("redirect_uri", p.config.RedirectURL)
+	q.Set("fmt", "json")
+
+	var t qqToken
+	if err := getJSON(ctx, p.config.Endpoint.TokenURL+"?"+q.Encode(), &t); err != nil {
+		return nil, err
+	}
+	if err := t.err(); err != nil {
+		return nil, err
+	}
+
+	tok := &oauth2.Token{
+		AccessToken:  t.AccessToken,
+		TokenType:    "Bearer",
+		RefreshToken: t.RefreshToken,
+	}
+
+	// QQ sends expires_in as a string
+	if expiresIn, err := t.ExpiresIn.Int64(); err == nil && expiresIn > 0 {
+		tok.Expiry = time.Now().Add(time.Duration(expiresIn) * time.Second)
+	}
+
+	return tok, nil
+}
+
+func (p qqProvider) RequiresPKCE() bool {
+	return false
+}
+
+func (p qqProvider) GetUserData(ctx context.Context, tok *oauth2.Token) (*UserProvidedData, error) {
+	q := url.Values{}
+	q.Set("access_token", tok.AccessToken)
+	q.Set("unionid", "1")
+	q.Set("fmt", "json")
+
+	var me qqOpenID
+	if err := getJSON(ctx, p.APIHost+"/oauth2.0/me?"+q.Encode(), &me); err != nil {
+		return nil, err
+	}
+	if err := me.err(); err != nil {
+		return nil, err
+	}
+	if me.OpenID == "" {
+		return nil, fmt.Errorf("qq: no openid for access token")
+	}
+
+	q = url.Values{}
+	q.Set("access_token", tok.AccessToken)
+	q.Set("oauth_consumer_key", p.config.ClientID)
+	q.Set("openid", me.OpenID)
+
+	var u qqUser
+	if err := getJSON(ctx, p.APIHost+"/user/get_user_info?"+q.Encode(), &u); err != nil {
+		return nil, err
+	}
+	if u.Ret != 0 {
+		return nil, fmt.Errorf("qq: error %d: %s", u.Ret, u.Msg)
+	}
+
+	// The unionid identifies the user across the apps of a developer
+	// account, while the openid differs per app.
+	subject := me.UnionID
+	if subject == "" {
+		subject = me.OpenID
+	}
+
+	customClaims := map[string]interface{}{
+		"openid": me.OpenID,
+	}
+	if me.UnionID != "" {
+		customClaims["unionid"] = me.UnionID
+	}
+
+	// figureurl_qq_2 is 100x100 and not set for every user, unlike
+	// the 40x40 figureurl_qq_1
+	picture := u.FigureURLQQ2
+	if picture == "" {
+		picture = u.FigureURLQQ1
+	}
+
+	return &UserProvidedData{
+		Metadata: &Claims{
+			Issuer:       p.APIHost,
+			Subject:      subject,
+			Name:         u.Nickname,
+			NickName:     u.Nickname,
+			Picture:      picture,
+			Gender:       u.Gender,
+			CustomClaims: customClaims,
+
+			// To be deprecated
+			AvatarURL:  picture,
+			FullName:   u.Nickname,
+			ProviderId: subject,
+		},
+	}, nil
+}
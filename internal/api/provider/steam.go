@@ -0,0 +1,279 @@
+package provider
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/supabase/auth/internal/conf"
+	"github.com/supabase/auth/internal/utilities"
+	"golang.org/x/oauth2"
+)
+
+// Steam
+// See: https://partner.steamgames.com/doc/features/auth#website
+// and: https://openid.net/specs/openid-authentication-2_0.html
+const (
+	defaultSteamAuthBase = "steamcommunity.com"
+	defaultSteamAPIBase  = "api.steampowered.com"
+
+	steamOpenIDNamespace        = "http://specs.openid.net/auth/2.0"
+	steamOpenIDIdentifierSelect = "http://specs.openid.net/auth/2.0/identifier_select"
+)
+
+var steamIDPattern = regexp.MustCompile(`^[0-9]{17}$`)
+
+// SteamProvider signs in with Steam, which only offers OpenID 2.0. Users
+// are identified by the SteamID64 in the claimed ID Steam asserts, which is
+// verified with Steam, and their profile is fetched from the Steam Web API
+// with the secret as the Web API key. Steam doesn't share the user's email.
+type SteamProvider struct {
+	APIKey          string
+	RedirectURI     string
+	Realm           string
+	LoginURL        string
+	ClaimedIDPrefix string
+	APIHost         string
+}
+
+type steamPlayer struct {
+	SteamID     string `json:"steamid"`
+	PersonaName string `json:"personaname"`
+	RealName    string `json:"realname"`
+	ProfileURL  string `json:"profileurl"`
+	Avatar      string `json:"avatarfull"`
+	CountryCode string `json:"loccountrycode"`
+}
+
+type steamPlayerSummaries struct {
+	Response struct {
+		Players []steamPlayer `json:"players"`
+	} `json:"response"`
+}
+
+// NewSteamProvider creates a Steam account provider.
+func NewSteamProvider(ext conf.OAuthProviderConfiguration) (OAuthProvider, error) {
+	if !ext.Enabled {
+		return nil, errors.New("provider is not enabled")
+	}
+	if ext.Secret == "" {
+		return nil, errors.New("missing Steam Web API key")
+	}
+	if ext.RedirectURI == "" {
+		return nil, errors.New("missing redirect URI")
+	}
+
+	redirectURI, err := url.Parse(ext.RedirectURI)
+	if err != nil || redirectURI.Scheme == "" || redirectURI.Host == "" {
+		return nil, fmt.Errorf("invalid redirect URI %q", ext.RedirectURI)
+	}
+
+	authHost := chooseHost(ext.URL, defaultSteamAuthBase)
+	apiHost := chooseHost(ext.ApiURL, defaultSteamAPIBase)
+
+	return &SteamProvider{
+		APIKey:          ext.Secret,
+		RedirectURI:     ext.RedirectURI,
+		Realm:           redirectURI.Scheme + "://" + redirectURI.Host + "/",
+		LoginURL:        authHost + "/openid/login",
+		ClaimedIDPrefix: authHost + "/openid/id/",
+		APIHost:         apiHost,
+	}, nil
+}
+
+// AuthCodeURL returns the URL of the OpenID authentication request, which
+// returns to the redirect URI with the state.
+func (p SteamProvider) AuthCodeURL(state string, _ ...oauth2.AuthCodeOption) string {
+	returnTo, err := url.Parse(p.RedirectURI)
+	if err != nil {
+		return ""
+	}
+	q := returnTo.Query()
+	q.Set("state", state)
+	returnTo.RawQuery = q.Encode()
+
+	params := url.Values{
+		"openid.ns":         {steamOpenIDNamespace},
+		"openid.mode":       {"checkid_setup"},
+		"openid.return_to":  {returnTo.String()},
+		"openid.realm":      {p.Realm},
+		"openid.identity":   {steamOpenIDIdentifierSelect},
+		"openid.claimed_id": {steamOpenIDIdentifierSelect},
+	}
+
+	return p.LoginURL + "?" + params.Encode()
+}
+
+// GetOAuthToken is a stub method for OAuthProvider interface, unused in OpenID 2.0
+func (p SteamProvider) GetOAuthToken(_ context.Context, _ string, _ ...oauth2.AuthCodeOption) (*oauth2.Token, error) {
+	return &oauth2.Token{}, nil
+}
+
+// GetUserData is a stub method for OAuthProvider interface, unused in OpenID 2.0
+func (p SteamProvider) GetUserData(_ context.Context, _ *oauth2.Token) (*UserProvidedData, error) {
+	return &UserProvidedData{}, nil
+}
+
+func (p SteamProvider) RequiresPKCE() bool {
+	return false
+}
+
+// VerifyAssertion verifies the positive assertion Steam redirected the user
+// back with in the query, and returns the SteamID64 of the user.
+func (p SteamProvider) VerifyAssertion(ctx context.Context, query url.Values) (string, error) {
+	if mode := query.Get("openid.mode"); mode != "id_res" {
+		return "", fmt.Errorf("steam: unexpected OpenID mode %q", mode)
+	}
+	if ns := query.Get("openid.ns"); ns != steamOpenIDNamespace {
+		return "", fmt.Errorf("steam: unexpected OpenID namespace %q", ns)
+	}
+	if endpoint := query.Get("openid.op_endpoint"); endpoint != p.LoginURL {
+		return "", fmt.Errorf("steam: unexpected OpenID provider endpoint %q", endpoint)
+	}
+	if err := p.verifyReturnTo(query); err != nil {
+		return "", err
+	}
+
+	claimedID := query.Get("openid.claimed_id")
+	if query.Get("openid.identity") != claimedID {
+		return "", errors.New("steam: OpenID identity does not match the claimed ID")
+	}
+	steamID := strings.TrimPrefix(claimedID, p.ClaimedIDPrefix)
+	if steamID == claimedID || !steamIDPattern.MatchString(steamID) {
+		return "", fmt.Errorf("steam: unexpected claimed ID %q", claimedID)
+	}
+
+	if err := p.checkAuthentication(ctx, query); err != nil {
+		return "", err
+	}
+
+	return steamID, nil
+}
+
+// verifyReturnTo checks that the assertion was made for the redirect URI
+// with the query it was requested with.
+func (p SteamProvider) verifyReturnTo(query url.Values) error {
+	returnTo, err := url.Parse(query.Get("openid.return_to"))
+	if err != nil {
+		return fmt.Errorf("steam: invalid OpenID return URL: %w", err)
+	}
+	redirectURI, err := url.Parse(p.RedirectURI)
+	if err != nil {
+		return err
+	}
+
+	if returnTo.Scheme != redirectURI.Scheme || returnTo.Host != redirectURI.Host || returnTo.Path != redirectURI.Path {
+		return fmt.Errorf("steam: OpenID return URL %q does not match the redirect URI", returnTo.String())
+	}
+	for key, values := range returnTo.Query() {
+		if query.Get(key) != values[0] {
+			return fmt.Errorf("steam: OpenID return URL parameter %q does not match", key)
+		}
+	}
+
+	return nil
+}
+
+// checkAuthentication asks Steam to verify the signature of the assertion,
+// which Steam does only once per assertion.
+func (p SteamProvider) checkAuthentication(ctx context.Context, query url.Values) error {
+	params := url.Values{}
+	for key, values := range query {
+		if strings.HasPrefix(key, "openid.") {
+			params[key] = values
+		}
+	}
+	params.Set("openid.mode", "check_authentication")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.LoginURL, strings.NewReader(params.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	client := &http.Client{Timeout: defaultTimeout}
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer utilities.SafeClose(res.Body)
+
+	if res.StatusCode < http.StatusOK || res.StatusCode >= http.StatusMultipleChoices {
+		return httpError(res.StatusCode, "steam: OpenID verification failed")
+	}
+
+	// the response is in key-value form, one key:value pair per line
+	scanner := bufio.NewScanner(res.Body)
+	for scanner.Scan() {
+		if key, value, ok := strings.Cut(scanner.Text(), ":"); ok && key == "is_valid" {
+			if value == "true" {
+				return nil
+			}
+			break
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	return errors.New("steam: OpenID assertion is not valid")
+}
+
+// FetchUserData retrieves the profile of the user with the SteamID64 from
+// the Steam Web API.
+func (p SteamProvider) FetchUserData(ctx context.Context, steamID string) (*UserProvidedData, error) {
+	params := url.Values{
+		"key":      {p.APIKey},
+		"steamids": {steamID},
+	}
+
+	var summaries steamPlayerSummaries
+	if err := getJSON(ctx, p.APIHost+"/ISteamUser/GetPlayerSummaries/v0002/?"+params.Encode(), &summaries); err != nil {
+		return nil, err
+	}
+
+	var u *steamPlayer
+	for i := range summaries.Response.Players {
+		if summaries.Response.Players[i].SteamID == steamID {
+			u = &summaries.Response.Players[i]
+			break
+		}
+	}
+	if u == nil {
+		return nil, fmt.Errorf("steam: no player summary for %s", steamID)
+	}
+
+	name := u.RealName
+	if name == "" {
+		name = u.PersonaName
+	}
+
+	return &UserProvidedData{
+		Emails: []Email{},
+		Metadata: &Claims{
+			Issuer:            p.ClaimedIDPrefix,
+			Subject:           steamID,
+			Name:              name,
+			NickName:          u.PersonaName,
+			PreferredUsername: u.PersonaName,
+			Profile:           u.ProfileURL,
+			Picture:           u.Avatar,
+			CustomClaims: map[string]interface{}{
+				"steamid":        steamID,
+				"persona_name":   u.PersonaName,
+				"loccountrycode": u.CountryCode,
+			},
+
+			// To be deprecated
+			AvatarURL:   u.Avatar,
+			FullName:    name,
+			ProviderId:  steamID,
+			UserNameKey: u.PersonaName,
+		},
+	}, nil
+}
@@ -0,0 +1,83 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/supabase/auth/internal/conf"
+)
+
+func TestSteamProviderVerifyAssertion(t *testing.T) {
+	isValid := "true"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		require.Equal(t, "/openid/login", r.URL.Path)
+		require.Equal(t, "check_authentication", r.PostForm.Get("openid.mode"))
+		require.Equal(t, "signature", r.PostForm.Get("openid.sig"))
+		require.Empty(t, r.PostForm.Get("state"))
+		fmt.Fprintf(w, "ns:http://specs.openid.net/auth/2.0\nis_valid:%s\n", isValid)
+	}))
+	defer server.Close()
+
+	p, err := NewSteamProvider(conf.OAuthProviderConfiguration{
+		Enabled:     true,
+		Secret:      "apikey",
+		RedirectURI: "https://auth.example.com/callback",
+		URL:         server.URL,
+	})
+	require.NoError(t, err)
+	sp := p.(*SteamProvider)
+
+	authURL, err := url.Parse(sp.AuthCodeURL("flow-state"))
+	require.NoError(t, err)
+	returnTo := authURL.Query().Get("openid.return_to")
+	require.Equal(t, "https://auth.example.com/callback?state=flow-state", returnTo)
+	require.Equal(t, "https://auth.example.com/", authURL.Query().Get("openid.realm"))
+
+	assertion := func() url.Values {
+		return url.Values{
+			"state":              {"flow-state"},
+			"openid.ns":          {"http://specs.openid.net/auth/2.0"},
+			"openid.mode":        {"id_res"},
+			"openid.op_endpoint": {server.URL + "/openid/login"},
+			"openid.claimed_id":  {server.URL + "/openid/id/76561197960287930"},
+			"openid.identity":    {server.URL + "/openid/id/76561197960287930"},
+			"openid.return_to":   {returnTo},
+			"openid.sig":         {"signature"},
+		}
+	}
+
+	steamID, err := sp.VerifyAssertion(context.Background(), assertion())
+	require.NoError(t, err)
+	require.Equal(t, "76561197960287930", steamID)
+
+	otherState := assertion()
+	otherState.Set("state", "other-flow-state")
+	_, err = sp.VerifyAssertion(context.Background(), otherState)
+	require.ErrorContains(t, err, "return URL parameter")
+
+	otherReturnTo := assertion()
+	otherReturnTo.Set("openid.return_to", "https://evil.example.com/callback?state=flow-state")
+	_, err = sp.VerifyAssertion(context.Background(), otherReturnTo)
+	require.ErrorContains(t, err, "does not match the redirect URI")
+
+	otherEndpoint := assertion()
+	otherEndpoint.Set("openid.op_endpoint", "https://openid.example.com/login")
+	_, err = sp.VerifyAssertion(context.Background(), otherEndpoint)
+	require.ErrorContains(t, err, "unexpected OpenID provider endpoint")
+
+	otherClaimedID := assertion()
+	otherClaimedID.Set("openid.claimed_id", "https://openid.example.com/id/76561197960287930")
+	otherClaimedID.Set("openid.identity", "https://openid.example.com/id/76561197960287930")
+	_, err = sp.VerifyAssertion(context.Background(), otherClaimedID)
+	require.ErrorContains(t, err, "unexpected claimed ID")
+
+	isValid = "false"
+	_, err = sp.VerifyAssertion(context.Background(), assertion())
+	require.ErrorContains(t, err, "assertion is not valid")
+}
@@ -0,0 +1,100 @@
+package provider
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TelegramAuthData is the user data the Telegram Login Widget passes to the
+// app once the user authorized the bot, signed with the bot token.
+// See: https://core.telegram.org/widgets/login#receiving-authorization-data
+type TelegramAuthData struct {
+	ID        int64  `json:"id"`
+	FirstName string `json:"first_name,omitempty"`
+	LastName  string `json:"last_name,omitempty"`
+	Username  string `json:"username,omitempty"`
+	PhotoURL  string `json:"photo_url,omitempty"`
+	AuthDate  int64  `json:"auth_date"`
+	Hash      string `json:"hash"`
+}
+
+// dataCheckString returns the fields of the data sorted by name, one
+// name=value line per field, which is what the hash is computed over.
+func (d *TelegramAuthData) dataCheckString() string {
+	fields := map[string]string{
+		"id":        strconv.FormatInt(d.ID, 10),
+		"auth_date": strconv.FormatInt(d.AuthDate, 10),
+	}
+	if d.FirstName != "" {
+		fields["first_name"] = d.FirstName
+	}
+	if d.LastName != "" {
+		fields["last_name"] = d.LastName
+	}
+	if d.Username != "" {
+		fields["username"] = d.Username
+	}
+	if d.PhotoURL != "" {
+		fields["photo_url"] = d.PhotoURL
+	}
+
+	lines := make([]string, 0, len(fields))
+	for name, value := range fields {
+		lines = append(lines, name+"="+value)
+	}
+	sort.Strings(lines)
+
+	return strings.Join(lines, "\n")
+}
+
+// Verify reports whether the hash is the HMAC-SHA-256 of the data, keyed
+// with the SHA-256 of the bot token.
+// See: https://core.telegram.org/widgets/login#checking-authorization
+func (d *TelegramAuthData) Verify(botToken string) bool {
+	hash, err := hex.DecodeString(d.Hash)
+	if err != nil {
+		return false
+	}
+
+	secretKey := sha256.Sum256([]byte(botToken))
+	mac := hmac.New(sha256.New, secretKey[:])
+	mac.Write([]byte(d.dataCheckString()))
+
+	return hmac.Equal(mac.Sum(nil), hash)
+}
+
+// AuthTime returns when the user authorized the bot.
+func (d *TelegramAuthData) AuthTime() time.Time {
+	return time.Unix(d.AuthDate, 0)
+}
+
+// UserData returns the user data keyed on the Telegram user ID. Telegram
+// doesn't share the user's email or phone number with the widget.
+func (d *TelegramAuthData) UserData() *UserProvidedData {
+	subject := strconv.FormatInt(d.ID, 10)
+	name := strings.TrimSpace(d.FirstName + " " + d.LastName)
+
+	return &UserProvidedData{
+		Emails: []Email{},
+		Metadata: &Claims{
+			Issuer:            "https://telegram.org",
+			Subject:           subject,
+			Name:              name,
+			GivenName:         d.FirstName,
+			FamilyName:        d.LastName,
+			PreferredUsername: d.Username,
+			Picture:           d.PhotoURL,
+
+			// To be deprecated
+			AvatarURL:   d.PhotoURL,
+			FullName:    name,
+			ProviderId:  subject,
+			UserNameKey: d.Username,
+		},
+	}
+}
@@ -0,0 +1,62 @@
+package provider
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTelegramAuthDataVerify(t *testing.T) {
+	const botToken = "123456:testbottoken"
+
+	// the hash as documented at https://core.telegram.org/widgets/login#checking-authorization
+	secretKey := sha256.Sum256([]byte(botToken))
+	mac := hmac.New(sha256.New, secretKey[:])
+	mac.Write([]byte("auth_date=1760000000\nfirst_name=Jane\nid=123456789\nphoto_url=https://t.me/i/userpic/320/janedoe.jpg\nusername=janedoe"))
+
+	data := TelegramAuthData{
+		ID:        123456789,
+		FirstName: "Jane",
+		Username:  "janedoe",
+		PhotoURL:  "https://t.me/i/userpic/320/janedoe.jpg",
+		AuthDate:  1760000000,
+		Hash:      hex.EncodeToString(mac.Sum(nil)),
+	}
+
+	require.True(t, data.Verify(botToken))
+	require.False(t, data.Verify("654321:otherbottoken"))
+
+	tampered := data
+	tampered.Username = "someoneelse"
+	require.False(t, tampered.Verify(botToken))
+
+	tampered = data
+	tampered.LastName = "Doe"
+	require.False(t, tampered.Verify(botToken))
+
+	tampered = data
+	tampered.Hash = "not-hex"
+	require.False(t, tampered.Verify(botToken))
+}
+
+func TestTelegramAuthDataUserData(t *testing.T) {
+	data := TelegramAuthData{
+		ID:        123456789,
+		FirstName: "Jane",
+		LastName:  "Doe",
+		Username:  "janedoe",
+		PhotoURL:  "https://t.me/i/userpic/320/janedoe.jpg",
+		AuthDate:  1760000000,
+	}
+
+	userData := data.UserData()
+	require.Empty(t, userData.Emails)
+	require.Equal(t, "123456789", userData.Metadata.Subject)
+	require.Equal(t, "Jane Doe", userData.Metadata.Name)
+	require.Equal(t, "janedoe", userData.Metadata.PreferredUsername)
+	require.Equal(t, "https://t.me/i/userpic/320/janedoe.jpg", userData.Metadata.Picture)
+	require.Equal(t, "https://t.me/i/userpic/320/janedoe.jpg", userData.Metadata.AvatarURL)
+}
@@ -44,6 +44,9 @@ type twitterUser struct {
 }
 
 // NewTwitterProvider creates a Twitter account provider.
+//
+// Deprecated: Twitter's OAuth 1.0a flow is superseded by the OAuth 2.0
+// "Sign in with X" flow of the x provider.
 func NewTwitterProvider(ext conf.OAuthProviderConfiguration, scopes string) (OAuthProvider, error) {
 	if err := ext.ValidateOAuth(); err != nil {
 		return nil, err
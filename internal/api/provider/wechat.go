@@ -0,0 +1,221 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/supabase/auth/internal/conf"
+	"golang.org/x/oauth2"
+)
+
+// WeChat
+// See: https://developers.weixin.qq.com/doc/oplatform/Website_App/WeChat_Login/Wechat_Login.html
+// and: https://developers.weixin.qq.com/doc/offiaccount/OA_Web_Apps/Wechat_webpage_authorization.html
+const (
+	defaultWeChatAuthBase = "open.weixin.qq.com"
+	defaultWeChatAPIBase  = "api.weixin.qq.com"
+)
+
+// wechatProvider signs in with WeChat, which names the client ID appid and
+// the client secret secret, and identifies users with an openid per app and
+// a unionid shared by the apps of the same Open Platform account. It holds
+// its oauth2.Config in a field, as WeChat tokens can't be refreshed the
+// standard way.
+type wechatProvider struct {
+	config  *oauth2.Config
+	APIHost string
+}
+
+type wechatError struct {
+	ErrCode int    `json:"errcode"`
+	ErrMsg  string `json:"errmsg"`
+}
+
+func (e wechatError) err() error {
+	if e.ErrCode == 0 {
+		return nil
+	}
+	return fmt.Errorf("wechat: error %d: %s", e.ErrCode, e.ErrMsg)
+}
+
+type wechatToken struct {
+	wechatError
+	AccessToken  string `json:"access_token"`
+	ExpiresIn    int    `json:"expires_in"`
+	RefreshToken string `json:"refresh_token"`
+	OpenID       string `json:"openid"`
+	UnionID      string `json:"unionid"`
+	Scope        string `json:"scope"`
+}
+
+type wechatUser struct {
+	wechatError
+	OpenID     string `json:"openid"`
+	UnionID    string `json:"unionid"`
+	Nickname   string `json:"nickname"`
+	HeadImgURL string `json:"headimgurl"`
+	Country    string `json:"country"`
+	Province   string `json:"province"`
+	City       string `json:"city"`
+}
+
+// NewWeChatProvider creates a WeChat provider signing in on websites by
+// scanning a QR code with the WeChat app, with an Open Platform website
+// app.
+func NewWeChatProvider(ext conf.OAuthProviderConfiguration, scopes string) (OAuthProvider, error) {
+	return newWeChatProvider(ext, scopes, "/connect/qrconnect", "snsapi_login")
+}
+
+// NewWeChatOfficialAccountProvider creates a WeChat provider signing in on
+// web pages opened inside the WeChat app, with an Official Account.
+func NewWeChatOfficialAccountProvider(ext conf.OAuthProviderConfiguration, scopes string) (OAuthProvider, error) {
+	return newWeChatProvider(ext, scopes, "/connect/oauth2/authorize", "snsapi_userinfo")
+}
+
+func newWeChatProvider(ext conf.OAuthProviderConfiguration, scopes, authPath, defaultScope string) (OAuthProvider, error) {
+	if err := ext.ValidateOAuth(); err != nil {
+		return nil, err
+	}
+
+	authHost := chooseHost(ext.URL, defaultWeChatAuthBase)
+	apiHost := chooseHost(ext.URL, defaultWeChatAPIBase)
+
+	oauthScopes := []string{defaultScope}
+	if scopes != "" {
+		oauthScopes = strings.Split(scopes, ",")
+	}
+
+	return &wechatProvider{
+		config: &oauth2.Config{
+			ClientID:     ext.ClientID[0],
+			ClientSecret: ext.Secret,
+			Endpoint: oauth2.Endpoint{
+				AuthURL:  authHost + authPath,
+				TokenURL: apiHost + "/sns/oauth2/access_token",
+			},
+			RedirectURL: ext.RedirectURI,
+			Scopes:      oauthScopes,
+		},
+		APIHost: apiHost,
+	}, nil
+}
+
+func (p wechatProvider) AuthCodeURL(state string, opts ...oauth2.AuthCodeOption) string {
+	u, err := url.Parse(p.config.AuthCodeURL(state, opts...))
+	if err != nil {
+		return ""
+	}
+
+	// WeChat expects the parameters in the order appid,
+	// redirect_uri, response_type, scope and state, which is the
+	// order they are encoded in, and only opens the page in WeChat
+	// with the wechat_redirect fragment
+	q := u.Query()
+	q.Set("appid", q.Get("client_id"))
+	q.Del("client_id")
+	u.RawQuery = q.Encode()
+	u.Fragment = "wechat_redirect"
+
+	return u.String()
+}
+
+func (p wechatProvider) GetOAuthToken(ctx context.Context, code string, opts ...oauth2.AuthCodeOption) (*oauth2.Token, error) {
+	q := url.Values{}
+	q.Set("appid", p.config.ClientID)
+	q.Set("secret", p.config.ClientSecret)
+	q.Set("code", code)
+	q.Set("grant_type", "authorization_code")
+
+	var t wechatToken
+	if err := getJSON(ctx, p.config.Endpoint.TokenURL+"?"+q.Encode(), &t); err != nil {
+		return nil, err
+	}
+	if err := t.err(); err != nil {
+		return nil, err
+	}
+
+	tok := &oauth2.Token{
+		AccessToken:  t.AccessToken,
+		TokenType:    "Bearer",
+		RefreshToken: t.RefreshToken,
+		Expiry:       time.Now().Add(time.Duration(t.ExpiresIn) * time.Second),
+	}
+
+	return tok.WithExtra(map[string]interface{}{
+		"openid":  t.OpenID,
+		"unionid": t.UnionID,
+		"scope":   t.Scope,
+	}), nil
+}
+
+func (p wechatProvider) RequiresPKCE() bool {
+	return false
+}
+
+func (p wechatProvider) GetUserData(ctx context.Context, tok *oauth2.Token) (*UserProvidedData, error) {
+	openID, _ := tok.Extra("openid").(string)
+	if openID == "" {
+		return nil, fmt.Errorf("wechat: no openid in token response")
+	}
+
+	q := url.Values{}
+	q.Set("access_token", tok.AccessToken)
+	q.Set("openid", openID)
+	q.Set("lang", "en")
+
+	var u wechatUser
+	if err := getJSON(ctx, p.APIHost+"/sns/userinfo?"+q.Encode(), &u); err != nil {
+		return nil, err
+	}
+	if err := u.err(); err != nil {
+		return nil, err
+	}
+
+	unionID := u.UnionID
+	if unionID == "" {
+		unionID, _ = tok.Extra("unionid").(string)
+	}
+
+	// The unionid identifies the user across the apps of an Open
+	// Platform account, such as the website and the Official Account,
+	// while the openid differs per app.
+	subject := unionID
+	if subject == "" {
+		subject = u.OpenID
+	}
+
+	customClaims := map[string]interface{}{
+		"openid": u.OpenID,
+	}
+	if unionID != "" {
+		customClaims["unionid"] = unionID
+	}
+	if u.Country != "" {
+		customClaims["country"] = u.Country
+	}
+	if u.Province != "" {
+		customClaims["province"] = u.Province
+	}
+	if u.City != "" {
+		customClaims["city"] = u.City
+	}
+
+	return &UserProvidedData{
+		Metadata: &Claims{
+			Issuer:       p.APIHost,
+			Subject:      subject,
+			Name:         u.Nickname,
+			NickName:     u.Nickname,
+			Picture:      u.HeadImgURL,
+			CustomClaims: customClaims,
+
+			// To be deprecated
+			AvatarURL:  u.HeadImgURL,
+			FullName:   u.Nickname,
+			ProviderId: subject,
+		},
+	}, nil
+}
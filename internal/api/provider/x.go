@@ -2,6 +2,9 @@ package provider
 
 import (
 	"context"
+	"errors"
+	"net/http"
+	"slices"
 	"strings"
 
 	"github.com/supabase/auth/internal/conf"
@@ -89,13 +92,31 @@ func (x xProvider) RequiresPKCE() bool {
 	return true
 }
 
+// xUserFields are the user fields requested from X, without confirmed_email.
+const xUserFields = "id,name,username,profile_image_url,url,created_at"
+
 func (x xProvider) GetUserData(ctx context.Context, tok *oauth2.Token) (*UserProvidedData, error) {
 	var resp xUserResponse
 
 	// See: https://developer.x.com/en/docs/twitter-api/users/lookup/api-reference/get-users-me
-	userInfoURL := x.APIHost + "/2/users/me?user.fields=id,name,username,confirmed_email,profile_image_url,url,created_at"
-
-	if err := makeRequest(ctx, tok, x.Config, userInfoURL, &resp); err != nil {
+	userInfoURL := x.APIHost + "/2/users/me?user.fields=" + xUserFields
+
+	// The confirmed email is only returned when the user granted the
+	// users.email scope, which X only offers to apps with elevated
+	// access that request email from users.
+	if x.hasGrantedScope(tok, "users.email") {
+		err := makeRequest(ctx, tok, x.Config, userInfoURL+",confirmed_email", &resp)
+
+		var httpErr *HTTPError
+		if errors.As(err, &httpErr) && httpErr.Code == http.StatusForbidden {
+			// the app lost access to the email, so continue
+			// without it and let email_optional decide
+			err = makeRequest(ctx, tok, x.Config, userInfoURL, &resp)
+		}
+		if err != nil {
+			return nil, err
+		}
+	} else if err := makeRequest(ctx, tok, x.Config, userInfoURL, &resp); err != nil {
 		return nil, err
 	}
 
@@ -135,3 +156,14 @@ func (x xProvider) GetUserData(ctx context.Context, tok *oauth2.Token) (*UserPro
 
 	return data, nil
 }
+
+// hasGrantedScope reports whether the user granted the scope. X lists the
+// granted scopes in the token response; when it doesn't, the requested
+// scopes are assumed to have been granted.
+func (x xProvider) hasGrantedScope(tok *oauth2.Token, scope string) bool {
+	granted, _ := tok.Extra("scope").(string)
+	if granted == "" {
+		return slices.Contains(x.Scopes, scope)
+	}
+	return slices.Contains(strings.Fields(granted), scope)
+}
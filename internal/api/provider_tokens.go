@@ -0,0 +1,107 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/supabase/auth/internal/api/apierrors"
+	"github.com/supabase/auth/internal/api/provider"
+	"github.com/supabase/auth/internal/models"
+	"github.com/supabase/auth/internal/providertokens"
+	"github.com/supabase/auth/internal/storage"
+)
+
+// providerTokenLeeway is how long before it expires a provider access token
+// is refreshed when it is requested.
+const providerTokenLeeway = 30 * time.Second
+
+// ProviderTokenResponse is the access token of the OAuth provider a user
+// signed in with.
+type ProviderTokenResponse struct {
+	Provider    string `json:"provider"`
+	AccessToken string `json:"access_token"`
+	ExpiresAt   int64  `json:"expires_at,omitempty"`
+	Scopes      string `json:"scopes,omitempty"`
+}
+
+// saveProviderToken stores the tokens the provider issued when the user
+// signed in with it.
+func (a *API) saveProviderToken(tx *storage.Connection, userData *provider.UserProvidedData, providerType string, data *OAuthProviderData) error {
+	identity, err := models.FindIdentityByIdAndProvider(tx, userData.Metadata.Subject, providerType)
+	if err != nil {
+		return apierrors.NewInternalServerError("Error finding identity").WithInternalError(err)
+	}
+
+	if _, err := models.SaveProviderToken(tx, identity, data.token, data.refreshToken, data.expiresAt, data.scopes, a.config.Security.DBEncryption); err != nil {
+		return apierrors.NewInternalServerError("Error saving provider token").WithInternalError(err)
+	}
+
+	return nil
+}
+
+// GetProviderToken returns the access token of the provider the
+// authenticated user signed in with.
+func (a *API) GetProviderToken(w http.ResponseWriter, r *http.Request) error {
+	return a.sendProviderToken(w, r, getUser(r.Context()))
+}
+
+// sendProviderToken sends the access token of the provider in the URL the
+// user last signed in with, refreshing it first when it is about to expire.
+func (a *API) sendProviderToken(w http.ResponseWriter, r *http.Request, user *models.User) error {
+	ctx := r.Context()
+	db := a.db.WithContext(ctx)
+	config := a.config
+
+	if !config.External.ProviderTokens.Enabled {
+		return apierrors.NewNotFoundError(apierrors.ErrorCodeProviderTokenNotFound, "Provider token not found")
+	}
+
+	providerType := chi.URLParam(r, "provider")
+
+	var resp *ProviderTokenResponse
+	err := db.Transaction(func(tx *storage.Connection) error {
+		token, terr := models.FindProviderTokenByUserAndProvider(tx, user.ID, providerType, true)
+		if terr != nil {
+			if models.IsNotFoundError(terr) {
+				return apierrors.NewNotFoundError(apierrors.ErrorCodeProviderTokenNotFound, "Provider token not found")
+			}
+			return apierrors.NewInternalServerError("Database error finding provider token").WithInternalError(terr)
+		}
+
+		if token.IsExpired(time.Now(), providerTokenLeeway) {
+			if terr := providertokens.Refresh(ctx, tx, config, token); terr != nil {
+				if !errors.Is(terr, providertokens.ErrRefreshFailed) {
+					return apierrors.NewInternalServerError("Error refreshing provider token").WithInternalError(terr)
+				}
+				if token.IsExpired(time.Now(), 0) {
+					return apierrors.NewUnprocessableEntityError(apierrors.ErrorCodeProviderTokenExpired, "Provider token has expired, the user needs to sign in with the provider again")
+				}
+			}
+		}
+
+		accessToken, terr := token.GetAccessToken(config.Security.DBEncryption)
+		if terr != nil {
+			return apierrors.NewInternalServerError("Error decrypting provider token").WithInternalError(terr)
+		}
+
+		resp = &ProviderTokenResponse{
+			Provider:    token.Provider,
+			AccessToken: accessToken,
+		}
+		if token.ExpiresAt != nil {
+			resp.ExpiresAt = token.ExpiresAt.Unix()
+		}
+		if token.Scopes != nil {
+			resp.Scopes = *token.Scopes
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	return sendJSON(w, http.StatusOK, resp)
+}
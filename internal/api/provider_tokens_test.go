@@ -0,0 +1,167 @@
+package api
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"github.com/supabase/auth/internal/conf"
+	"github.com/supabase/auth/internal/models"
+)
+
+type ProviderTokensTestSuite struct {
+	suite.Suite
+	API    *API
+	Config *conf.GlobalConfiguration
+
+	user     *models.User
+	identity *models.Identity
+}
+
+func TestProviderTokens(t *testing.T) {
+	api, config, err := setupAPIForTest()
+	require.NoError(t, err)
+
+	ts := &ProviderTokensTestSuite{
+		API:    api,
+		Config: config,
+	}
+	defer api.db.Close()
+
+	suite.Run(t, ts)
+}
+
+func (ts *ProviderTokensTestSuite) SetupTest() {
+	models.TruncateAll(ts.API.db)
+
+	key := base64.RawURLEncoding.EncodeToString(make([]byte, 32))
+	ts.Config.Security.DBEncryption = conf.DatabaseEncryptionConfiguration{
+		Encrypt:         true,
+		EncryptionKeyID: "test",
+		EncryptionKey:   key,
+		DecryptionKeys:  map[string]string{"test": key},
+	}
+	ts.Config.External.ProviderTokens.Enabled = true
+
+	u, err := models.NewUser("", "test@example.com", "password", ts.Config.JWT.Aud, nil)
+	require.NoError(ts.T(), err)
+	require.NoError(ts.T(), ts.API.db.Create(u))
+	ts.user = u
+
+	i, err := models.NewIdentity(u, "github", map[string]interface{}{
+		"sub":   "123",
+		"email": "test@example.com",
+	})
+	require.NoError(ts.T(), err)
+	require.NoError(ts.T(), ts.API.db.Create(i))
+	ts.identity = i
+}
+
+func (ts *ProviderTokensTestSuite) getProviderToken() *httptest.ResponseRecorder {
+	session, err := models.NewSession(ts.user.ID, nil)
+	require.NoError(ts.T(), err)
+	require.NoError(ts.T(), ts.API.db.Create(session))
+
+	req := httptest.NewRequest(http.MethodPost, "/token?grant_type=password", nil)
+	token, _, err := ts.API.generateAccessToken(req, ts.API.db, ts.user, &session.ID, models.PasswordGrant)
+	require.NoError(ts.T(), err)
+
+	req = httptest.NewRequest(http.MethodGet, "/user/provider_tokens/github", nil)
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	w := httptest.NewRecorder()
+	ts.API.handler.ServeHTTP(w, req)
+	return w
+}
+
+func (ts *ProviderTokensTestSuite) TestGetProviderToken() {
+	expiresAt := time.Now().Add(time.Hour)
+	_, err := models.SaveProviderToken(ts.API.db, ts.identity, "access-token", "refresh-token", &expiresAt, "repo", ts.Config.Security.DBEncryption)
+	require.NoError(ts.T(), err)
+
+	w := ts.getProviderToken()
+	require.Equal(ts.T(), http.StatusOK, w.Code, w.Body.String())
+
+	resp := &ProviderTokenResponse{}
+	require.NoError(ts.T(), json.NewDecoder(w.Body).Decode(resp))
+	require.Equal(ts.T(), "github", resp.Provider)
+	require.Equal(ts.T(), "access-token", resp.AccessToken)
+	require.Equal(ts.T(), expiresAt.Unix(), resp.ExpiresAt)
+	require.Equal(ts.T(), "repo", resp.Scopes)
+}
+
+func (ts *ProviderTokensTestSuite) TestGetExpiredProviderToken() {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(ts.T(), r.ParseForm())
+		require.Equal(ts.T(), "refresh-token", r.PostForm.Get("refresh_token"))
+
+		w.Header().Set("Content-Type", "application/json")
+		require.NoError(ts.T(), json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token":  "refreshed-access-token",
+			"refresh_token": "rotated-refresh-token",
+			"token_type":    "bearer",
+			"expires_in":    3600,
+		}))
+	}))
+	defer server.Close()
+	ts.Config.External.Github.URL = server.URL
+
+	expiresAt := time.Now().Add(-time.Minute)
+	_, err := models.SaveProviderToken(ts.API.db, ts.identity, "access-token", "refresh-token", &expiresAt, "", ts.Config.Security.DBEncryption)
+	require.NoError(ts.T(), err)
+
+	w := ts.getProviderToken()
+	require.Equal(ts.T(), http.StatusOK, w.Code, w.Body.String())
+
+	resp := &ProviderTokenResponse{}
+	require.NoError(ts.T(), json.NewDecoder(w.Body).Decode(resp))
+	require.Equal(ts.T(), "refreshed-access-token", resp.AccessToken)
+	require.Greater(ts.T(), resp.ExpiresAt, time.Now().Unix())
+
+	token, err := models.FindProviderTokenByIdentityID(ts.API.db, ts.identity.ID)
+	require.NoError(ts.T(), err)
+	refreshToken, err := token.GetRefreshToken(ts.Config.Security.DBEncryption)
+	require.NoError(ts.T(), err)
+	require.Equal(ts.T(), "rotated-refresh-token", refreshToken)
+}
+
+func (ts *ProviderTokensTestSuite) TestGetRevokedProviderToken() {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		require.NoError(ts.T(), json.NewEncoder(w).Encode(map[string]interface{}{
+			"error": "invalid_grant",
+		}))
+	}))
+	defer server.Close()
+	ts.Config.External.Github.URL = server.URL
+
+	expiresAt := time.Now().Add(-time.Minute)
+	_, err := models.SaveProviderToken(ts.API.db, ts.identity, "access-token", "refresh-token", &expiresAt, "", ts.Config.Security.DBEncryption)
+	require.NoError(ts.T(), err)
+
+	w := ts.getProviderToken()
+	require.Equal(ts.T(), http.StatusUnprocessableEntity, w.Code, w.Body.String())
+
+	token, err := models.FindProviderTokenByIdentityID(ts.API.db, ts.identity.ID)
+	require.NoError(ts.T(), err)
+	require.NotNil(ts.T(), token.RefreshError)
+}
+
+func (ts *ProviderTokensTestSuite) TestProviderTokenNotFound() {
+	w := ts.getProviderToken()
+	require.Equal(ts.T(), http.StatusNotFound, w.Code)
+
+	expiresAt := time.Now().Add(time.Hour)
+	_, err := models.SaveProviderToken(ts.API.db, ts.identity, "access-token", "", &expiresAt, "", ts.Config.Security.DBEncryption)
+	require.NoError(ts.T(), err)
+
+	ts.Config.External.ProviderTokens.Enabled = false
+	w = ts.getProviderToken()
+	require.Equal(ts.T(), http.StatusNotFound, w.Code)
+}
@@ -0,0 +1,203 @@
+package api
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/gofrs/uuid"
+	"github.com/supabase/auth/internal/api/apierrors"
+	"github.com/supabase/auth/internal/models"
+	"github.com/supabase/auth/internal/storage"
+)
+
+var emailDomainPattern = regexp.MustCompile(`^(\*\.)?([a-z0-9]([a-z0-9-]*[a-z0-9])?\.)+[a-z]{2,}$`)
+
+// ProviderDomainRuleParams are the parameters for setting the email domains
+// users may sign up with through a provider.
+type ProviderDomainRuleParams struct {
+	AllowedDomains []string `json:"allowed_domains"`
+	DeniedDomains  []string `json:"denied_domains"`
+}
+
+// AdminListProviderDomainRulesResponse is the response of the admin
+// provider domains endpoint.
+type AdminListProviderDomainRulesResponse struct {
+	Rules []*models.ProviderDomainRule `json:"rules"`
+}
+
+// normalizeEmailDomains lowercases the domains and checks that they are
+// domains, optionally with a *. prefix.
+func normalizeEmailDomains(domains []string) ([]string, error) {
+	normalized := make([]string, 0, len(domains))
+	for _, domain := range domains {
+		domain = strings.ToLower(strings.TrimSpace(domain))
+		if !emailDomainPattern.MatchString(domain) {
+			return nil, apierrors.NewBadRequestError(apierrors.ErrorCodeValidationFailed, "%q is not a valid email domain", domain)
+		}
+		normalized = append(normalized, domain)
+	}
+	return normalized, nil
+}
+
+// providerDomainRuleProvider returns the provider of the request, or an
+// error if it is not a configured provider.
+func (a *API) providerDomainRuleProvider(r *http.Request) (string, error) {
+	name := chi.URLParam(r, "provider")
+	if _, ok := a.config.External.OAuthProvider(name); !ok {
+		return "", apierrors.NewNotFoundError(apierrors.ErrorCodeProviderDomainRuleNotFound, "Provider %q is not supported", name)
+	}
+	return name, nil
+}
+
+// providerDomainRule returns the rule the signups through the provider are
+// checked against: the rule managed with the admin API, the deployment's
+// rule for a tenant without one, or one with the configured domains.
+func (a *API) providerDomainRule(tx *storage.Connection, providerType string) (*models.ProviderDomainRule, error) {
+	tenantIDs := []*uuid.UUID{a.tenantID}
+	if a.tenantID != nil {
+		tenantIDs = append(tenantIDs, nil)
+	}
+
+	for _, tenantID := range tenantIDs {
+		rule, err := models.FindProviderDomainRule(tx, tenantID, providerType)
+		if err == nil {
+			return rule, nil
+		}
+		if !models.IsNotFoundError(err) {
+			return nil, err
+		}
+	}
+
+	pConfig, _ := a.config.External.OAuthProvider(providerType)
+	return models.NewProviderDomainRule(a.tenantID, providerType, pConfig.AllowedEmailDomains, pConfig.DeniedEmailDomains), nil
+}
+
+// checkSignupEmailDomain rejects signing up through the provider with an
+// email whose domain is not allowed, and records the rejection in the audit
+// log. The rejection is returned as a storage.CommitWithError, so that the
+// audit log entry is kept.
+func (a *API) checkSignupEmailDomain(tx *storage.Connection, r *http.Request, providerType, email string) error {
+	rule, err := a.providerDomainRule(tx, providerType)
+	if err != nil {
+		return apierrors.NewInternalServerError("Database error finding provider domain rule").WithInternalError(err)
+	}
+
+	if rule.Allows(email) {
+		return nil
+	}
+
+	domain := ""
+	if at := strings.LastIndex(email, "@"); at >= 0 {
+		domain = strings.ToLower(email[at+1:])
+	}
+
+	actor := &models.User{Email: storage.NullString(email)}
+	if err := models.NewAuditLogEntry(a.config.AuditLog, r, tx, actor, models.UserSignupDeniedAction, "", map[string]interface{}{
+		"provider":     providerType,
+		"email_domain": domain,
+	}); err != nil {
+		return err
+	}
+
+	if domain == "" {
+		return storage.NewCommitWithError(apierrors.NewForbiddenError(apierrors.ErrorCodeEmailDomainNotAllowed, "Signups through %v require an email address", providerType))
+	}
+	return storage.NewCommitWithError(apierrors.NewForbiddenError(apierrors.ErrorCodeEmailDomainNotAllowed, "Signups through %v are not allowed with email domain %v", providerType, domain))
+}
+
+// adminProviderDomainRules lists the provider domain rules managed with the
+// admin API.
+func (a *API) adminProviderDomainRules(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	db := a.db.WithContext(ctx)
+
+	rules, err := models.FindProviderDomainRules(db, a.tenantID)
+	if err != nil {
+		return apierrors.NewInternalServerError("Database error finding provider domain rules").WithInternalError(err)
+	}
+
+	return sendJSON(w, http.StatusOK, AdminListProviderDomainRulesResponse{
+		Rules: rules,
+	})
+}
+
+// adminProviderDomainRuleGet returns the rule signups through a provider are
+// checked against, which is built from the configuration when no rule is
+// managed with the admin API.
+func (a *API) adminProviderDomainRuleGet(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	db := a.db.WithContext(ctx)
+
+	providerType, err := a.providerDomainRuleProvider(r)
+	if err != nil {
+		return err
+	}
+
+	rule, err := a.providerDomainRule(db, providerType)
+	if err != nil {
+		return apierrors.NewInternalServerError("Database error finding provider domain rule").WithInternalError(err)
+	}
+
+	return sendJSON(w, http.StatusOK, rule)
+}
+
+// adminProviderDomainRuleUpdate sets the email domains of a provider,
+// replacing the configured domains.
+func (a *API) adminProviderDomainRuleUpdate(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	db := a.db.WithContext(ctx)
+
+	providerType, err := a.providerDomainRuleProvider(r)
+	if err != nil {
+		return err
+	}
+
+	params := &ProviderDomainRuleParams{}
+	if err := retrieveRequestParams(r, params); err != nil {
+		return err
+	}
+
+	allowed, err := normalizeEmailDomains(params.AllowedDomains)
+	if err != nil {
+		return err
+	}
+	denied, err := normalizeEmailDomains(params.DeniedDomains)
+	if err != nil {
+		return err
+	}
+
+	rule := models.NewProviderDomainRule(a.tenantID, providerType, allowed, denied)
+	err = db.Transaction(func(tx *storage.Connection) error {
+		return models.SaveProviderDomainRule(tx, rule)
+	})
+	if err != nil {
+		return apierrors.NewInternalServerError("Database error saving provider domain rule").WithInternalError(err)
+	}
+
+	return sendJSON(w, http.StatusOK, rule)
+}
+
+// adminProviderDomainRuleDelete deletes the rule of a provider, signups
+// through it are checked against the configured domains again.
+func (a *API) adminProviderDomainRuleDelete(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	db := a.db.WithContext(ctx)
+
+	providerType, err := a.providerDomainRuleProvider(r)
+	if err != nil {
+		return err
+	}
+
+	deleted, err := models.DeleteProviderDomainRule(db, a.tenantID, providerType)
+	if err != nil {
+		return apierrors.NewInternalServerError("Database error deleting provider domain rule").WithInternalError(err)
+	}
+	if !deleted {
+		return apierrors.NewNotFoundError(apierrors.ErrorCodeProviderDomainRuleNotFound, "Provider has no domain rule")
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
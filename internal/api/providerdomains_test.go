@@ -0,0 +1,166 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	jwt "github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"github.com/supabase/auth/internal/conf"
+	"github.com/supabase/auth/internal/models"
+)
+
+type ProviderDomainsTestSuite struct {
+	suite.Suite
+	API    *API
+	Config *conf.GlobalConfiguration
+
+	token string
+}
+
+func TestProviderDomains(t *testing.T) {
+	api, config, err := setupAPIForTest()
+	require.NoError(t, err)
+
+	ts := &ProviderDomainsTestSuite{
+		API:    api,
+		Config: config,
+	}
+	defer api.db.Close()
+
+	suite.Run(t, ts)
+}
+
+func (ts *ProviderDomainsTestSuite) SetupTest() {
+	models.TruncateAll(ts.API.db)
+
+	claims := &AccessTokenClaims{
+		Role: "supabase_admin",
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(ts.Config.JWT.Secret))
+	require.NoError(ts.T(), err, "Error generating admin jwt")
+	ts.token = token
+}
+
+func (ts *ProviderDomainsTestSuite) request(method, path string, body interface{}) *httptest.ResponseRecorder {
+	var buf bytes.Buffer
+	if body != nil {
+		require.NoError(ts.T(), json.NewEncoder(&buf).Encode(body))
+	}
+
+	req := httptest.NewRequest(method, path, &buf)
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", ts.token))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	ts.API.handler.ServeHTTP(w, req)
+	return w
+}
+
+func (ts *ProviderDomainsTestSuite) TestManageRules() {
+	ts.Config.External.Google.AllowedEmailDomains = []string{"configured.com"}
+	defer func() { ts.Config.External.Google.AllowedEmailDomains = nil }()
+
+	// the configured domains are returned without a rule
+	w := ts.request(http.MethodGet, "/admin/provider_domains/google", nil)
+	require.Equal(ts.T(), http.StatusOK, w.Code, w.Body.String())
+	var rule models.ProviderDomainRule
+	require.NoError(ts.T(), json.NewDecoder(w.Body).Decode(&rule))
+	require.Equal(ts.T(), models.EmailDomains{"configured.com"}, rule.AllowedDomains)
+
+	w = ts.request(http.MethodPut, "/admin/provider_domains/google", &ProviderDomainRuleParams{
+		AllowedDomains: []string{"OurCompany.com", "*.ourcompany.com"},
+	})
+	require.Equal(ts.T(), http.StatusOK, w.Code, w.Body.String())
+
+	w = ts.request(http.MethodPut, "/admin/provider_domains/github", &ProviderDomainRuleParams{
+		DeniedDomains: []string{"spam.example"},
+	})
+	require.Equal(ts.T(), http.StatusOK, w.Code, w.Body.String())
+
+	w = ts.request(http.MethodGet, "/admin/provider_domains/google", nil)
+	require.Equal(ts.T(), http.StatusOK, w.Code, w.Body.String())
+	require.NoError(ts.T(), json.NewDecoder(w.Body).Decode(&rule))
+	require.Equal(ts.T(), models.EmailDomains{"ourcompany.com", "*.ourcompany.com"}, rule.AllowedDomains)
+	require.Empty(ts.T(), rule.DeniedDomains)
+
+	w = ts.request(http.MethodGet, "/admin/provider_domains", nil)
+	require.Equal(ts.T(), http.StatusOK, w.Code, w.Body.String())
+	var list AdminListProviderDomainRulesResponse
+	require.NoError(ts.T(), json.NewDecoder(w.Body).Decode(&list))
+	require.Len(ts.T(), list.Rules, 2)
+	require.Equal(ts.T(), "github", list.Rules[0].Provider)
+	require.Equal(ts.T(), "google", list.Rules[1].Provider)
+
+	w = ts.request(http.MethodDelete, "/admin/provider_domains/google", nil)
+	require.Equal(ts.T(), http.StatusNoContent, w.Code, w.Body.String())
+
+	w = ts.request(http.MethodDelete, "/admin/provider_domains/google", nil)
+	require.Equal(ts.T(), http.StatusNotFound, w.Code, w.Body.String())
+
+	w = ts.request(http.MethodGet, "/admin/provider_domains/google", nil)
+	require.Equal(ts.T(), http.StatusOK, w.Code, w.Body.String())
+	require.NoError(ts.T(), json.NewDecoder(w.Body).Decode(&rule))
+	require.Equal(ts.T(), models.EmailDomains{"configured.com"}, rule.AllowedDomains)
+}
+
+func (ts *ProviderDomainsTestSuite) TestInvalidRules() {
+	w := ts.request(http.MethodPut, "/admin/provider_domains/unknown", &ProviderDomainRuleParams{})
+	require.Equal(ts.T(), http.StatusNotFound, w.Code, w.Body.String())
+
+	for _, domain := range []string{"", "not a domain", "user@example.com", "*example.com", "example"} {
+		w := ts.request(http.MethodPut, "/admin/provider_domains/google", &ProviderDomainRuleParams{
+			AllowedDomains: []string{domain},
+		})
+		require.Equal(ts.T(), http.StatusBadRequest, w.Code, domain)
+	}
+}
+
+func (ts *ExternalTestSuite) TestSignupExternalGitlabEmailDomainNotAllowed() {
+	ts.Config.Mailer.Autoconfirm = true
+	ts.Config.External.Gitlab.AllowedEmailDomains = []string{"ourcompany.com"}
+	defer func() { ts.Config.External.Gitlab.AllowedEmailDomains = nil }()
+
+	tokenCount, userCount := 0, 0
+	code := "authcode"
+	emails := `[{"id":1,"email":"gitlab@example.com"}]`
+	server := GitlabTestSignupSetup(ts, &tokenCount, &userCount, code, gitlabUser, emails)
+	defer server.Close()
+
+	u := performAuthorization(ts, "gitlab", code, "")
+
+	assertAuthorizationFailure(ts, u, "Signups through gitlab are not allowed with email domain example.com", "access_denied", "gitlab@example.com")
+	ts.Equal("email_domain_not_allowed", u.Query().Get("error_code"))
+
+	entries, err := models.FindAuditLogEntries(ts.API.db, nil, "", &models.AuditLogFilter{Action: string(models.UserSignupDeniedAction)}, nil)
+	ts.Require().NoError(err)
+	ts.Require().Len(entries, 1)
+	traits := entries[0].Payload["traits"].(map[string]interface{})
+	ts.Equal("gitlab", traits["provider"])
+	ts.Equal("example.com", traits["email_domain"])
+}
+
+func (ts *ExternalTestSuite) TestSignupExternalGitlabEmailDomainRule() {
+	ts.Config.Mailer.Autoconfirm = true
+	ts.Config.External.Gitlab.AllowedEmailDomains = []string{"ourcompany.com"}
+	defer func() { ts.Config.External.Gitlab.AllowedEmailDomains = nil }()
+
+	// the rule managed with the admin API replaces the configured domains
+	rule := models.NewProviderDomainRule(nil, "gitlab", nil, []string{"spam.example"})
+	ts.Require().NoError(models.SaveProviderDomainRule(ts.API.db, rule))
+
+	tokenCount, userCount := 0, 0
+	code := "authcode"
+	emails := `[{"id":1,"email":"gitlab@example.com"}]`
+	server := GitlabTestSignupSetup(ts, &tokenCount, &userCount, code, gitlabUser, emails)
+	defer server.Close()
+
+	u := performAuthorization(ts, "gitlab", code, "")
+
+	assertAuthorizationSuccess(ts, u, tokenCount, userCount, "gitlab@example.com", "GitLab Test", "123", "http://example.com/avatar")
+}
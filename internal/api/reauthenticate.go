@@ -78,7 +78,7 @@ func (a *API) verifyReauthentication(nonce string, tx *storage.Connection, confi
 	} else if user.GetPhone() != "" {
 		if config.Sms.IsTwilioVerifyProvider() {
 			smsProvider, _ := sms_provider.GetSmsProvider(*config)
-			if err := smsProvider.(*sms_provider.TwilioVerifyProvider).VerifyOTP(string(user.Phone), nonce); err != nil {
+			if err := verifySMSOTP(tx.Context(), config, smsProvider, string(user.Phone), nonce); err != nil {
 				return apierrors.NewForbiddenError(apierrors.ErrorCodeOTPExpired, "Token has expired or is invalid").WithInternalError(err)
 			}
 			return nil
@@ -15,6 +15,11 @@ type ResendConfirmationParams struct {
 	Type  string `json:"type"`
 	Email string `json:"email"`
 	Phone string `json:"phone"`
+
+	// The PKCE parameters are only used with the email verification types,
+	// the link then redirects with an auth code instead of the tokens.
+	CodeChallengeMethod string `json:"code_challenge_method"`
+	CodeChallenge       string `json:"code_challenge"`
 }
 
 func (p *ResendConfirmationParams) Validate(a *API) error {
@@ -58,6 +63,9 @@ func (p *ResendConfirmationParams) Validate(a *API) error {
 		// both email and phone are empty
 		return apierrors.NewBadRequestError(apierrors.ErrorCodeValidationFailed, "Missing email address or phone number")
 	}
+	if err := validatePKCEParams(p.CodeChallengeMethod, p.CodeChallenge); err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -114,6 +122,8 @@ func (a *API) Resend(w http.ResponseWriter, r *http.Request) error {
 		}
 	}
 
+	flowType := getFlowFromChallenge(params.CodeChallenge)
+
 	messageID := ""
 	err = db.Transaction(func(tx *storage.Connection) error {
 		switch params.Type {
@@ -121,8 +131,12 @@ func (a *API) Resend(w http.ResponseWriter, r *http.Request) error {
 			if terr := models.NewAuditLogEntry(config.AuditLog, r, tx, user, models.UserConfirmationRequestedAction, "", nil); terr != nil {
 				return terr
 			}
-			// PKCE not implemented yet
-			return a.sendConfirmation(r, tx, user, models.ImplicitFlow)
+			if isPKCEFlow(flowType) {
+				if _, terr := generateFlowState(tx, models.EmailSignup.String(), models.EmailSignup, params.CodeChallengeMethod, params.CodeChallenge, &user.ID); terr != nil {
+					return terr
+				}
+			}
+			return a.sendConfirmation(r, tx, user, flowType)
 		case smsVerification:
 			if terr := models.NewAuditLogEntry(config.AuditLog, r, tx, user, models.UserRecoveryRequestedAction, "", nil); terr != nil {
 				return terr
@@ -133,7 +147,12 @@ func (a *API) Resend(w http.ResponseWriter, r *http.Request) error {
 			}
 			messageID = mID
 		case mail.EmailChangeVerification:
-			return a.sendEmailChange(r, tx, user, user.EmailChange, models.ImplicitFlow)
+			if isPKCEFlow(flowType) {
+				if _, terr := generateFlowState(tx, models.EmailChange.String(), models.EmailChange, params.CodeChallengeMethod, params.CodeChallenge, &user.ID); terr != nil {
+					return terr
+				}
+			}
+			return a.sendEmailChange(r, tx, user, user.EmailChange, flowType)
 		case phoneChangeVerification:
 			mID, terr := a.sendPhoneConfirmation(r, tx, user, user.PhoneChange, phoneChangeVerification, sms_provider.SMSProvider)
 			if terr != nil {
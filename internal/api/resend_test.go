@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
@@ -89,6 +90,18 @@ func (ts *ResendTestSuite) TestResendValidation() {
 				"message": "Only an email address or phone number should be provided.",
 			},
 		},
+		{
+			desc: "Code challenge without method",
+			params: map[string]interface{}{
+				"type":           "signup",
+				"email":          "foo@example.com",
+				"code_challenge": "iwnmFrR3SPeJ0FXtyEJTrUG5PXeXNgHOhCSjOGaiSOs",
+			},
+			expected: map[string]interface{}{
+				"code":    http.StatusBadRequest,
+				"message": InvalidPKCEParamsErrorMessage,
+			},
+		},
 	}
 	for _, c := range cases {
 		ts.Run(c.desc, func() {
@@ -215,3 +228,64 @@ func (ts *ResendTestSuite) TestResendSuccess() {
 		})
 	}
 }
+
+func (ts *ResendTestSuite) TestResendPKCE() {
+	u, err := models.NewUser("", "foo@example.com", "password", ts.Config.JWT.Aud, nil)
+	require.NoError(ts.T(), err, "Error creating test user model")
+
+	// Avoid max freq limit error
+	now := time.Now().Add(-1 * time.Minute)
+	u.ConfirmationSentAt = &now
+	u.EmailChange = "bar@example.com"
+	u.EmailChangeSentAt = &now
+	require.NoError(ts.T(), ts.API.db.Create(u), "Error saving new test user")
+
+	cases := []struct {
+		desc       string
+		verifyType string
+		authMethod models.AuthenticationMethod
+	}{
+		{
+			desc:       "Resend signup confirmation",
+			verifyType: mail.SignupVerification,
+			authMethod: models.EmailSignup,
+		},
+		{
+			desc:       "Resend email change",
+			verifyType: mail.EmailChangeVerification,
+			authMethod: models.EmailChange,
+		},
+	}
+
+	for _, c := range cases {
+		ts.Run(c.desc, func() {
+			var buffer bytes.Buffer
+			require.NoError(ts.T(), json.NewEncoder(&buffer).Encode(map[string]interface{}{
+				"type":                  c.verifyType,
+				"email":                 u.GetEmail(),
+				"code_challenge":        "iwnmFrR3SPeJ0FXtyEJTrUG5PXeXNgHOhCSjOGaiSOs",
+				"code_challenge_method": "s256",
+			}))
+			req := httptest.NewRequest(http.MethodPost, "http://localhost/resend", &buffer)
+			req.Header.Set("Content-Type", "application/json")
+
+			w := httptest.NewRecorder()
+			ts.API.handler.ServeHTTP(w, req)
+			require.Equal(ts.T(), http.StatusOK, w.Code, w.Body.String())
+
+			dbUser, err := models.FindUserByID(ts.API.db, u.ID)
+			require.NoError(ts.T(), err)
+
+			// the link redirects with an auth code for the flow state
+			token := dbUser.ConfirmationToken
+			if c.verifyType == mail.EmailChangeVerification {
+				token = dbUser.EmailChangeTokenNew
+			}
+			require.True(ts.T(), strings.HasPrefix(token, PKCEPrefix))
+
+			flowState, err := models.FindFlowStateByUserID(ts.API.db, u.ID.String(), c.authMethod)
+			require.NoError(ts.T(), err)
+			require.True(ts.T(), flowState.IsPKCE())
+		})
+	}
+}
@@ -0,0 +1,175 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/gofrs/uuid"
+	"github.com/supabase/auth/internal/api/apierrors"
+	"github.com/supabase/auth/internal/api/shared"
+	"github.com/supabase/auth/internal/models"
+	"github.com/supabase/auth/internal/storage"
+)
+
+const tokenTypeHintAccessToken = "access_token"
+
+// RevokeParams are the parameters of a token revocation request.
+type RevokeParams struct {
+	Token         string `json:"token"`
+	TokenTypeHint string `json:"token_type_hint"`
+}
+
+// Revoke invalidates a refresh token, or an access token when the access
+// token denylist is enabled, as defined in RFC 7009. It lets native apps
+// sign out without calling /logout with a valid access token. Revoking a
+// refresh token signs out its session. Tokens issued to an OAuth client can
+// only be revoked by that client. Unknown and invalid tokens are not an
+// error.
+func (a *API) Revoke(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	db := a.db.WithContext(ctx)
+
+	params := &RevokeParams{}
+	if strings.Contains(r.Header.Get("Content-Type"), "application/json") {
+		if err := json.NewDecoder(r.Body).Decode(params); err != nil {
+			return apierrors.NewOAuthError("invalid_request", "Invalid JSON body")
+		}
+	} else {
+		if err := r.ParseForm(); err != nil {
+			return apierrors.NewOAuthError("invalid_request", "Failed to parse form data")
+		}
+		params.Token = r.FormValue("token")
+		params.TokenTypeHint = r.FormValue("token_type_hint")
+	}
+
+	if params.Token == "" {
+		return apierrors.NewOAuthError("invalid_request", "token is required")
+	}
+
+	// As with introspection, the hint only decides which kind of token is
+	// looked up first.
+	revokers := []func(*http.Request, *storage.Connection, string) (bool, error){
+		a.revokeRefreshToken,
+		a.revokeAccessToken,
+	}
+	if params.TokenTypeHint == tokenTypeHintAccessToken {
+		revokers[0], revokers[1] = revokers[1], revokers[0]
+	}
+
+	for _, revoke := range revokers {
+		ok, err := revoke(r, db, params.Token)
+		if err != nil {
+			return err
+		}
+		if ok {
+			break
+		}
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	return nil
+}
+
+// revokeRefreshToken signs out the session of a refresh token. It reports
+// whether the token is a refresh token.
+func (a *API) revokeRefreshToken(r *http.Request, db *storage.Connection, token string) (bool, error) {
+	config := a.config
+
+	user, _, session, err := models.FindUserWithRefreshToken(db, config.Security.DBEncryption, token, false)
+	if err != nil {
+		if models.IsNotFoundError(err) {
+			return false, nil
+		}
+		return false, apierrors.NewInternalServerError("Database error finding refresh token").WithInternalError(err)
+	}
+
+	if session == nil {
+		return true, nil
+	}
+
+	if err := checkRevokingClient(r, session.OAuthClientID); err != nil {
+		return false, err
+	}
+
+	err = db.Transaction(func(tx *storage.Connection) error {
+		if terr := models.NewAuditLogEntry(config.AuditLog, r, tx, user, models.TokenRevokedAction, "", map[string]interface{}{
+			"session_id": session.ID.String(),
+			"token_type": tokenTypeHintRefreshToken,
+		}); terr != nil {
+			return terr
+		}
+
+		return models.LogoutSession(tx, session.ID)
+	})
+	if err != nil {
+		return false, apierrors.NewInternalServerError("Error revoking refresh token").WithInternalError(err)
+	}
+
+	return true, nil
+}
+
+// revokeAccessToken adds the jti of an access token to the denylist until
+// it expires. It reports whether the token is an access token.
+func (a *API) revokeAccessToken(r *http.Request, db *storage.Connection, token string) (bool, error) {
+	config := a.config
+
+	ctx, err := a.parseJWTClaims(token, r)
+	if err != nil {
+		return false, nil
+	}
+
+	claims := getClaims(ctx)
+	if claims == nil || claims.ExpiresAt == nil {
+		return false, nil
+	}
+
+	if !config.JWT.DenylistEnabled || claims.ID == "" {
+		return false, apierrors.NewOAuthError("unsupported_token_type", "Access tokens cannot be revoked")
+	}
+
+	jti, err := uuid.FromString(claims.ID)
+	if err != nil {
+		return false, nil
+	}
+
+	var clientID *uuid.UUID
+	if claims.ClientID != "" {
+		id, err := uuid.FromString(claims.ClientID)
+		if err != nil {
+			return false, nil
+		}
+		clientID = &id
+	}
+
+	if err := checkRevokingClient(r, clientID); err != nil {
+		return false, err
+	}
+
+	if err := models.RevokeAccessToken(db, jti, claims.ExpiresAt.Time); err != nil {
+		return false, apierrors.NewInternalServerError("Error revoking access token").WithInternalError(err)
+	}
+
+	return true, nil
+}
+
+// checkRevokingClient checks that the client authenticated with the request,
+// if any, is the OAuth client the token was issued to.
+func checkRevokingClient(r *http.Request, clientID *uuid.UUID) error {
+	if clientID != nil && *clientID == uuid.Nil {
+		clientID = nil
+	}
+
+	client := shared.GetOAuthServerClient(r.Context())
+	switch {
+	case clientID == nil && client == nil:
+		return nil
+	case clientID != nil && client != nil && client.ID == *clientID:
+		return nil
+	case clientID != nil && client == nil:
+		return apierrors.NewOAuthError("invalid_client", "Client authentication required")
+	default:
+		return apierrors.NewOAuthError("unauthorized_client", "The token was not issued to the client")
+	}
+}
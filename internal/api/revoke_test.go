@@ -0,0 +1,145 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"github.com/supabase/auth/internal/conf"
+	"github.com/supabase/auth/internal/models"
+	"github.com/supabase/auth/internal/storage"
+)
+
+type RevokeTestSuite struct {
+	suite.Suite
+	API    *API
+	Config *conf.GlobalConfiguration
+}
+
+func TestRevoke(t *testing.T) {
+	api, config, err := setupAPIForTestWithCallback(func(config *conf.GlobalConfiguration, conn *storage.Connection) {
+		if config != nil {
+			config.JWT.DenylistEnabled = true
+		}
+	})
+	require.NoError(t, err)
+
+	ts := &RevokeTestSuite{
+		API:    api,
+		Config: config,
+	}
+	defer api.db.Close()
+
+	suite.Run(t, ts)
+}
+
+func (ts *RevokeTestSuite) SetupTest() {
+	models.TruncateAll(ts.API.db)
+	ts.Config.JWT.DenylistEnabled = true
+
+	u, err := models.NewUser("", "test@example.com", "password", ts.Config.JWT.Aud, nil)
+	require.NoError(ts.T(), err)
+	require.NoError(ts.T(), ts.API.db.Create(u))
+	require.NoError(ts.T(), u.Confirm(ts.API.db))
+}
+
+func (ts *RevokeTestSuite) signIn() *AccessTokenResponse {
+	var body bytes.Buffer
+	require.NoError(ts.T(), json.NewEncoder(&body).Encode(map[string]interface{}{
+		"email":    "test@example.com",
+		"password": "password",
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/token?grant_type=password", &body)
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	ts.API.handler.ServeHTTP(w, req)
+	require.Equal(ts.T(), http.StatusOK, w.Code, w.Body.String())
+
+	resp := &AccessTokenResponse{}
+	require.NoError(ts.T(), json.NewDecoder(w.Body).Decode(resp))
+	return resp
+}
+
+func (ts *RevokeTestSuite) revoke(form url.Values) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/revoke", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	ts.API.handler.ServeHTTP(w, req)
+	return w
+}
+
+func (ts *RevokeTestSuite) getUser(token string) int {
+	req := httptest.NewRequest(http.MethodGet, "/user", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	ts.API.handler.ServeHTTP(w, req)
+	return w.Code
+}
+
+func (ts *RevokeTestSuite) TestRefreshToken() {
+	tokens := ts.signIn()
+
+	w := ts.revoke(url.Values{"token": {tokens.RefreshToken}})
+	require.Equal(ts.T(), http.StatusOK, w.Code, w.Body.String())
+
+	// the session of the refresh token is signed out
+	var body bytes.Buffer
+	require.NoError(ts.T(), json.NewEncoder(&body).Encode(map[string]interface{}{
+		"refresh_token": tokens.RefreshToken,
+	}))
+	req := httptest.NewRequest(http.MethodPost, "/token?grant_type=refresh_token", &body)
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	ts.API.handler.ServeHTTP(w, req)
+	require.NotEqual(ts.T(), http.StatusOK, w.Code)
+
+	require.Equal(ts.T(), http.StatusForbidden, ts.getUser(tokens.Token))
+
+	// revoking a token twice is not an error
+	w = ts.revoke(url.Values{"token": {tokens.RefreshToken}})
+	require.Equal(ts.T(), http.StatusOK, w.Code)
+}
+
+func (ts *RevokeTestSuite) TestAccessToken() {
+	tokens := ts.signIn()
+	require.Equal(ts.T(), http.StatusOK, ts.getUser(tokens.Token))
+
+	w := ts.revoke(url.Values{
+		"token":           {tokens.Token},
+		"token_type_hint": {"access_token"},
+	})
+	require.Equal(ts.T(), http.StatusOK, w.Code, w.Body.String())
+
+	require.Equal(ts.T(), http.StatusForbidden, ts.getUser(tokens.Token))
+
+	// the session itself is still valid
+	other := ts.signIn()
+	require.Equal(ts.T(), http.StatusOK, ts.getUser(other.Token))
+}
+
+func (ts *RevokeTestSuite) TestAccessTokenWithoutDenylist() {
+	ts.Config.JWT.DenylistEnabled = false
+	tokens := ts.signIn()
+
+	w := ts.revoke(url.Values{"token": {tokens.Token}})
+	require.Equal(ts.T(), http.StatusBadRequest, w.Code)
+
+	var data map[string]interface{}
+	require.NoError(ts.T(), json.NewDecoder(w.Body).Decode(&data))
+	require.Equal(ts.T(), "unsupported_token_type", data["error"])
+}
+
+func (ts *RevokeTestSuite) TestInvalidToken() {
+	w := ts.revoke(url.Values{"token": {"not-a-token"}})
+	require.Equal(ts.T(), http.StatusOK, w.Code)
+
+	w = ts.revoke(url.Values{})
+	require.Equal(ts.T(), http.StatusBadRequest, w.Code)
+}
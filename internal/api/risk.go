@@ -0,0 +1,139 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/supabase/auth/internal/api/apierrors"
+	"github.com/supabase/auth/internal/models"
+	"github.com/supabase/auth/internal/observability"
+	"github.com/supabase/auth/internal/risk"
+	"github.com/supabase/auth/internal/storage"
+	"github.com/supabase/auth/internal/utilities"
+)
+
+// assessSignInRisk scores the risk of a sign-in and takes the action of its
+// score. It is called by the token service before sessions are issued.
+//
+// Sessions of sign-ins that require MFA can only be used to challenge and
+// verify factors, and can't be refreshed, until they are verified with MFA.
+// Users without verified factors are asked to verify their email instead, by
+// signing in with an email link or code, and sign-ins of users without an
+// email address are blocked.
+func (a *API) assessSignInRisk(r *http.Request, conn *storage.Connection, user *models.User, authenticationMethod models.AuthenticationMethod, grantParams *models.GrantParams) error {
+	config := a.config
+
+	if !config.Risk.Enabled || authenticationMethod == models.Impersonation {
+		return nil
+	}
+
+	signals, err := a.signInRiskSignals(r, conn, user, grantParams)
+	if err != nil {
+		return apierrors.NewInternalServerError("Database error assessing sign-in risk").WithInternalError(err)
+	}
+
+	assessment := risk.Assess(&config.Risk, signals)
+	grantParams.RiskScore = &assessment.Score
+
+	action := assessment.Action
+	if action == risk.ActionRequireMFA && !user.HasMFAEnabled() {
+		action = risk.ActionRequireEmailVerification
+	}
+	if action == risk.ActionRequireEmailVerification {
+		switch {
+		case isEmailVerifiedSignIn(authenticationMethod):
+			action = risk.ActionAllow
+		case user.GetEmail() == "":
+			action = risk.ActionBlock
+		}
+	}
+
+	if len(signals) > 0 {
+		observability.GetLogEntry(r).Entry.WithFields(logrus.Fields{
+			"user_id":      user.ID,
+			"risk_score":   assessment.Score,
+			"risk_signals": signals,
+			"risk_action":  action,
+		}).Info("Assessed sign-in risk")
+	}
+
+	switch action {
+	case risk.ActionBlock:
+		return apierrors.NewForbiddenError(apierrors.ErrorCodeSignInBlocked, "Sign in blocked as it looks suspicious")
+	case risk.ActionRequireEmailVerification:
+		return apierrors.NewForbiddenError(apierrors.ErrorCodeEmailVerificationRequired, "Sign in with a link or code sent to your email address to verify it is you")
+	case risk.ActionRequireMFA:
+		grantParams.MFARequired = true
+	}
+
+	return nil
+}
+
+// signInRiskSignals returns the signals a sign-in shows, from the
+// locations and devices the user signed in from before.
+func (a *API) signInRiskSignals(r *http.Request, conn *storage.Connection, user *models.User, grantParams *models.GrantParams) ([]risk.Signal, error) {
+	config := &a.config.Risk
+	now := time.Now()
+
+	ip := grantParams.IP
+	if ip == "" {
+		ip = utilities.GetIPAddress(r)
+	}
+	userAgent := grantParams.UserAgent
+	if userAgent == "" {
+		userAgent = r.Header.Get("User-Agent")
+	}
+
+	var signals []risk.Signal
+
+	locations, err := models.FindSignInLocationsByUserID(conn, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if len(locations) > 0 {
+		last := locations[0]
+		if grantParams.Country != "" && last.Country != "" && last.Country != grantParams.Country && now.Sub(last.LastSeenAt) < config.ImpossibleTravelWindow {
+			signals = append(signals, risk.SignalImpossibleTravel)
+		}
+
+		knownDevice := false
+		for _, location := range locations {
+			if location.UserAgent == userAgent {
+				knownDevice = true
+				break
+			}
+		}
+		if !knownDevice {
+			signals = append(signals, risk.SignalNewDevice)
+		}
+	}
+
+	if config.TorIPList.Contains(ip) {
+		signals = append(signals, risk.SignalTor)
+	}
+	if config.DataCenterIPList.Contains(ip) {
+		signals = append(signals, risk.SignalDataCenter)
+	}
+
+	count, err := models.CountSessionsSince(conn, user.ID, now.Add(-config.VelocityWindow))
+	if err != nil {
+		return nil, err
+	}
+	if count >= config.VelocityMaxSignIns {
+		signals = append(signals, risk.SignalVelocity)
+	}
+
+	return signals, nil
+}
+
+// isEmailVerifiedSignIn reports whether the sign-in was authenticated with
+// a link or code sent to the user.
+func isEmailVerifiedSignIn(authenticationMethod models.AuthenticationMethod) bool {
+	switch authenticationMethod {
+	case models.MagicLink, models.OTP, models.EmailSignup, models.Recovery, models.Invite, models.EmailChange:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,112 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/supabase/auth/internal/api/apierrors"
+	"github.com/supabase/auth/internal/conf"
+	"github.com/supabase/auth/internal/models"
+)
+
+func (ts *TokenTestSuite) TestSignInRisk() {
+	torList := filepath.Join(ts.T().TempDir(), "tor.txt")
+	require.NoError(ts.T(), os.WriteFile(torList, []byte("192.0.2.1\n"), 0600))
+
+	ts.Config.Risk = conf.RiskConfiguration{
+		Enabled:                       true,
+		TorScore:                      50,
+		NewDeviceScore:                20,
+		VelocityMaxSignIns:            100,
+		VelocityWindow:                time.Hour,
+		RequireMFAScore:               40,
+		RequireEmailVerificationScore: 60,
+		BlockScore:                    90,
+	}
+	require.NoError(ts.T(), ts.Config.Risk.TorIPList.Decode(torList))
+	defer func() {
+		ts.Config.Risk = conf.RiskConfiguration{}
+	}()
+
+	signIn := func() *httptest.ResponseRecorder {
+		var buffer bytes.Buffer
+		require.NoError(ts.T(), json.NewEncoder(&buffer).Encode(map[string]interface{}{
+			"email":    "test@example.com",
+			"password": "password",
+		}))
+		req := httptest.NewRequest(http.MethodPost, "http://localhost/token?grant_type=password", &buffer)
+		req.Header.Set("Content-Type", "application/json")
+		req.RemoteAddr = "192.0.2.1:1234"
+		w := httptest.NewRecorder()
+		ts.API.handler.ServeHTTP(w, req)
+		return w
+	}
+	errorCode := func(w *httptest.ResponseRecorder) string {
+		data := make(map[string]interface{})
+		require.NoError(ts.T(), json.NewDecoder(w.Body).Decode(&data))
+		code, _ := data["error_code"].(string)
+		return code
+	}
+
+	// users without MFA are asked to verify their email
+	w := signIn()
+	require.Equal(ts.T(), http.StatusForbidden, w.Code, w.Body.String())
+	require.Equal(ts.T(), string(apierrors.ErrorCodeEmailVerificationRequired), errorCode(w))
+
+	// which sign-ins with an email link satisfy
+	req := httptest.NewRequest(http.MethodPost, "http://localhost/verify", nil)
+	req.RemoteAddr = "192.0.2.1:1234"
+	grantParams := &models.GrantParams{}
+	require.NoError(ts.T(), ts.API.assessSignInRisk(req, ts.API.db, ts.User, models.MagicLink, grantParams))
+	require.Equal(ts.T(), 50, *grantParams.RiskScore)
+	require.False(ts.T(), grantParams.MFARequired)
+
+	// users with MFA sign in, but have to verify MFA to refresh the session
+	factor := models.NewTOTPFactor(ts.User, "totp")
+	require.NoError(ts.T(), ts.API.db.Create(factor))
+	require.NoError(ts.T(), factor.UpdateStatus(ts.API.db, models.FactorStateVerified))
+
+	w = signIn()
+	require.Equal(ts.T(), http.StatusOK, w.Code, w.Body.String())
+
+	var token AccessTokenResponse
+	require.NoError(ts.T(), json.NewDecoder(w.Body).Decode(&token))
+
+	// the session can only be used to verify MFA
+	req = httptest.NewRequest(http.MethodGet, "http://localhost/user", nil)
+	req.Header.Set("Authorization", "Bearer "+token.Token)
+	w = httptest.NewRecorder()
+	ts.API.handler.ServeHTTP(w, req)
+	require.Equal(ts.T(), http.StatusUnauthorized, w.Code, w.Body.String())
+	require.Equal(ts.T(), string(apierrors.ErrorCodeInsufficientAAL), errorCode(w))
+
+	req = httptest.NewRequest(http.MethodPost, "http://localhost/factors/"+factor.ID.String()+"/challenge", bytes.NewBufferString("{}"))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token.Token)
+	w = httptest.NewRecorder()
+	ts.API.handler.ServeHTTP(w, req)
+	require.Equal(ts.T(), http.StatusOK, w.Code, w.Body.String())
+
+	var buffer bytes.Buffer
+	require.NoError(ts.T(), json.NewEncoder(&buffer).Encode(map[string]interface{}{
+		"refresh_token": token.RefreshToken,
+	}))
+	req = httptest.NewRequest(http.MethodPost, "http://localhost/token?grant_type=refresh_token", &buffer)
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	ts.API.handler.ServeHTTP(w, req)
+	require.Equal(ts.T(), http.StatusBadRequest, w.Code, w.Body.String())
+	require.Equal(ts.T(), string(apierrors.ErrorCodeSessionExpired), errorCode(w))
+
+	// high scores are blocked
+	ts.Config.Risk.TorScore = 100
+	w = signIn()
+	require.Equal(ts.T(), http.StatusForbidden, w.Code, w.Body.String())
+	require.Equal(ts.T(), string(apierrors.ErrorCodeSignInBlocked), errorCode(w))
+}
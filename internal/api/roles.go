@@ -0,0 +1,369 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/gofrs/uuid"
+	"github.com/supabase/auth/internal/api/apierrors"
+	"github.com/supabase/auth/internal/models"
+	"github.com/supabase/auth/internal/observability"
+	"github.com/supabase/auth/internal/storage"
+)
+
+const (
+	roleDescriptionMaxLength = 1024
+	rolePermissionsMaxCount  = 100
+)
+
+var (
+	roleNamePattern       = regexp.MustCompile(`^[a-zA-Z0-9_.:-]{1,63}$`)
+	rolePermissionPattern = regexp.MustCompile(`^[a-zA-Z0-9_.:*/-]{1,255}$`)
+)
+
+// RoleParams are the parameters for creating and updating a role.
+type RoleParams struct {
+	Name        string    `json:"name"`
+	Description *string   `json:"description"`
+	Permissions *[]string `json:"permissions"`
+}
+
+// UserRoleParams are the parameters for assigning a role to a user, which
+// is found by ID or name.
+type UserRoleParams struct {
+	RoleID *uuid.UUID `json:"role_id"`
+	Name   string     `json:"name"`
+}
+
+// AdminListRolesResponse is the response of the admin roles endpoint.
+type AdminListRolesResponse struct {
+	Roles []*models.Role `json:"roles"`
+}
+
+// AdminUserRolesResponse is the response of the admin user roles endpoints,
+// with the permissions the roles grant to the user.
+type AdminUserRolesResponse struct {
+	Roles       []*models.Role `json:"roles"`
+	Permissions []string       `json:"permissions"`
+}
+
+// validate checks the name, the description and the permissions of the
+// role. The name is required for a new role.
+func (p *RoleParams) validate(create bool) error {
+	p.Name = strings.TrimSpace(p.Name)
+
+	if create && p.Name == "" {
+		return apierrors.NewBadRequestError(apierrors.ErrorCodeValidationFailed, "Role name is required")
+	}
+	if p.Name != "" && !roleNamePattern.MatchString(p.Name) {
+		return apierrors.NewBadRequestError(apierrors.ErrorCodeValidationFailed, "Role name must be at most 63 letters, digits and _.:-")
+	}
+
+	if p.Description != nil && len(*p.Description) > roleDescriptionMaxLength {
+		return apierrors.NewBadRequestError(apierrors.ErrorCodeValidationFailed, "Role description must be at most %d characters long", roleDescriptionMaxLength)
+	}
+
+	if p.Permissions != nil {
+		if len(*p.Permissions) > rolePermissionsMaxCount {
+			return apierrors.NewBadRequestError(apierrors.ErrorCodeValidationFailed, "A role can grant at most %d permissions", rolePermissionsMaxCount)
+		}
+
+		permissions := make([]string, 0, len(*p.Permissions))
+		seen := make(map[string]bool)
+		for _, permission := range *p.Permissions {
+			permission = strings.TrimSpace(permission)
+			if !rolePermissionPattern.MatchString(permission) {
+				return apierrors.NewBadRequestError(apierrors.ErrorCodeValidationFailed, "%q is not a valid permission", permission)
+			}
+			if !seen[permission] {
+				seen[permission] = true
+				permissions = append(permissions, permission)
+			}
+		}
+		p.Permissions = &permissions
+	}
+
+	return nil
+}
+
+// loadRole loads the role of the request's role_id.
+func (a *API) loadRole(w http.ResponseWriter, r *http.Request) (context.Context, error) {
+	ctx := r.Context()
+	db := a.db.WithContext(ctx)
+
+	roleID, err := uuid.FromString(chi.URLParam(r, "role_id"))
+	if err != nil {
+		return nil, apierrors.NewNotFoundError(apierrors.ErrorCodeValidationFailed, "role_id must be an UUID")
+	}
+
+	observability.LogEntrySetField(r, "role_id", roleID)
+
+	role, err := models.FindRoleByID(db, roleID)
+	if err != nil {
+		if models.IsNotFoundError(err) {
+			return nil, apierrors.NewNotFoundError(apierrors.ErrorCodeRoleNotFound, "Role not found")
+		}
+		return nil, apierrors.NewInternalServerError("Database error loading role").WithInternalError(err)
+	}
+
+	return withRole(ctx, role), nil
+}
+
+// ensureRoleNameAvailable rejects a name another role already has.
+func ensureRoleNameAvailable(tx *storage.Connection, name string) error {
+	if _, err := models.FindRoleByName(tx, name); err == nil {
+		return apierrors.NewUnprocessableEntityError(apierrors.ErrorCodeRoleExists, "A role with this name already exists")
+	} else if !models.IsNotFoundError(err) {
+		return apierrors.NewInternalServerError("Database error finding role").WithInternalError(err)
+	}
+	return nil
+}
+
+// adminRoles lists the roles.
+func (a *API) adminRoles(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	db := a.db.WithContext(ctx)
+
+	roles, err := models.FindRoles(db)
+	if err != nil {
+		return apierrors.NewInternalServerError("Database error finding roles").WithInternalError(err)
+	}
+
+	return sendJSON(w, http.StatusOK, AdminListRolesResponse{
+		Roles: roles,
+	})
+}
+
+// adminRoleCreate creates a role.
+func (a *API) adminRoleCreate(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	db := a.db.WithContext(ctx)
+	config := a.config
+
+	params := &RoleParams{}
+	if err := retrieveRequestParams(r, params); err != nil {
+		return err
+	}
+	if err := params.validate(true); err != nil {
+		return err
+	}
+
+	var description string
+	if params.Description != nil {
+		description = *params.Description
+	}
+	var permissions []string
+	if params.Permissions != nil {
+		permissions = *params.Permissions
+	}
+
+	role := models.NewRole(params.Name, description, permissions)
+	err := db.Transaction(func(tx *storage.Connection) error {
+		if terr := ensureRoleNameAvailable(tx, role.Name); terr != nil {
+			return terr
+		}
+
+		if terr := tx.Create(role); terr != nil {
+			return apierrors.NewInternalServerError("Database error creating role").WithInternalError(terr)
+		}
+
+		return models.NewAuditLogEntry(config.AuditLog, r, tx, getAdminUser(ctx), models.RoleCreatedAction, "", map[string]interface{}{
+			"role_id":     role.ID,
+			"role_name":   role.Name,
+			"permissions": role.Permissions,
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	return sendJSON(w, http.StatusCreated, role)
+}
+
+// adminRoleGet returns the role.
+func (a *API) adminRoleGet(w http.ResponseWriter, r *http.Request) error {
+	return sendJSON(w, http.StatusOK, getRole(r.Context()))
+}
+
+// adminRoleUpdate updates the name, description or permissions of the role.
+// Values left out keep the current ones. The users with the role get the
+// new permissions in the access tokens issued after the update.
+func (a *API) adminRoleUpdate(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	db := a.db.WithContext(ctx)
+	config := a.config
+	role := getRole(ctx)
+
+	params := &RoleParams{}
+	if err := retrieveRequestParams(r, params); err != nil {
+		return err
+	}
+	if err := params.validate(false); err != nil {
+		return err
+	}
+
+	err := db.Transaction(func(tx *storage.Connection) error {
+		if params.Name != "" && params.Name != role.Name {
+			if terr := ensureRoleNameAvailable(tx, params.Name); terr != nil {
+				return terr
+			}
+			role.Name = params.Name
+		}
+		if params.Description != nil {
+			role.Description = *params.Description
+		}
+		if params.Permissions != nil {
+			role.Permissions = *params.Permissions
+		}
+
+		if terr := tx.UpdateOnly(role, "name", "description", "permissions", "updated_at"); terr != nil {
+			return apierrors.NewInternalServerError("Database error updating role").WithInternalError(terr)
+		}
+
+		return models.NewAuditLogEntry(config.AuditLog, r, tx, getAdminUser(ctx), models.RoleUpdatedAction, "", map[string]interface{}{
+			"role_id":     role.ID,
+			"role_name":   role.Name,
+			"permissions": role.Permissions,
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	return sendJSON(w, http.StatusOK, role)
+}
+
+// adminRoleDelete deletes the role, which is removed from the users it is
+// assigned to.
+func (a *API) adminRoleDelete(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	db := a.db.WithContext(ctx)
+	config := a.config
+	role := getRole(ctx)
+
+	err := db.Transaction(func(tx *storage.Connection) error {
+		if terr := models.NewAuditLogEntry(config.AuditLog, r, tx, getAdminUser(ctx), models.RoleDeletedAction, "", map[string]interface{}{
+			"role_id":   role.ID,
+			"role_name": role.Name,
+		}); terr != nil {
+			return terr
+		}
+
+		if terr := tx.Destroy(role); terr != nil {
+			return apierrors.NewInternalServerError("Database error deleting role").WithInternalError(terr)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+// sendUserRoles responds with the roles of the user and the permissions
+// they grant.
+func (a *API) sendUserRoles(w http.ResponseWriter, r *http.Request, status int) error {
+	ctx := r.Context()
+	db := a.db.WithContext(ctx)
+
+	roles, err := models.FindUserRoles(db, getUser(ctx).ID)
+	if err != nil {
+		return apierrors.NewInternalServerError("Database error finding roles").WithInternalError(err)
+	}
+
+	return sendJSON(w, status, AdminUserRolesResponse{
+		Roles:       roles,
+		Permissions: models.RolePermissions(roles),
+	})
+}
+
+// adminUserRoles lists the roles assigned to the user.
+func (a *API) adminUserRoles(w http.ResponseWriter, r *http.Request) error {
+	return a.sendUserRoles(w, r, http.StatusOK)
+}
+
+// adminUserRoleAssign assigns a role to the user. Assigning a role the user
+// already has is not an error.
+func (a *API) adminUserRoleAssign(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	db := a.db.WithContext(ctx)
+	config := a.config
+	user := getUser(ctx)
+
+	params := &UserRoleParams{}
+	if err := retrieveRequestParams(r, params); err != nil {
+		return err
+	}
+
+	var role *models.Role
+	var err error
+	switch {
+	case params.RoleID != nil:
+		role, err = models.FindRoleByID(db, *params.RoleID)
+	case params.Name != "":
+		role, err = models.FindRoleByName(db, strings.TrimSpace(params.Name))
+	default:
+		return apierrors.NewBadRequestError(apierrors.ErrorCodeValidationFailed, "A role_id or name is required")
+	}
+	if err != nil {
+		if models.IsNotFoundError(err) {
+			return apierrors.NewNotFoundError(apierrors.ErrorCodeRoleNotFound, "Role not found")
+		}
+		return apierrors.NewInternalServerError("Database error finding role").WithInternalError(err)
+	}
+
+	err = db.Transaction(func(tx *storage.Connection) error {
+		if terr := models.AssignRole(tx, user.ID, role.ID); terr != nil {
+			return apierrors.NewInternalServerError("Database error assigning role").WithInternalError(terr)
+		}
+
+		return models.NewAuditLogEntry(config.AuditLog, r, tx, getAdminUser(ctx), models.UserRoleAssignedAction, "", map[string]interface{}{
+			"user_id":   user.ID,
+			"role_id":   role.ID,
+			"role_name": role.Name,
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	return a.sendUserRoles(w, r, http.StatusOK)
+}
+
+// adminUserRoleRemove removes a role from the user.
+func (a *API) adminUserRoleRemove(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	db := a.db.WithContext(ctx)
+	config := a.config
+	user := getUser(ctx)
+
+	roleID, err := uuid.FromString(chi.URLParam(r, "role_id"))
+	if err != nil {
+		return apierrors.NewNotFoundError(apierrors.ErrorCodeValidationFailed, "role_id must be an UUID")
+	}
+
+	err = db.Transaction(func(tx *storage.Connection) error {
+		removed, terr := models.UnassignRole(tx, user.ID, roleID)
+		if terr != nil {
+			return apierrors.NewInternalServerError("Database error removing role").WithInternalError(terr)
+		}
+		if !removed {
+			return apierrors.NewNotFoundError(apierrors.ErrorCodeUserRoleNotFound, "User does not have this role")
+		}
+
+		return models.NewAuditLogEntry(config.AuditLog, r, tx, getAdminUser(ctx), models.UserRoleRemovedAction, "", map[string]interface{}{
+			"user_id": user.ID,
+			"role_id": roleID,
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	return a.sendUserRoles(w, r, http.StatusOK)
+}
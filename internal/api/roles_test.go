@@ -0,0 +1,180 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	jwt "github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"github.com/supabase/auth/internal/conf"
+	"github.com/supabase/auth/internal/models"
+)
+
+type RolesTestSuite struct {
+	suite.Suite
+	API    *API
+	Config *conf.GlobalConfiguration
+
+	adminToken string
+}
+
+func TestRoles(t *testing.T) {
+	api, config, err := setupAPIForTest()
+	require.NoError(t, err)
+
+	ts := &RolesTestSuite{
+		API:    api,
+		Config: config,
+	}
+	defer api.db.Close()
+
+	suite.Run(t, ts)
+}
+
+func (ts *RolesTestSuite) SetupTest() {
+	models.TruncateAll(ts.API.db)
+	ts.Config.RBAC.Enabled = true
+
+	claims := &AccessTokenClaims{
+		Role: "supabase_admin",
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(ts.Config.JWT.Secret))
+	require.NoError(ts.T(), err, "Error generating admin jwt")
+	ts.adminToken = token
+}
+
+func (ts *RolesTestSuite) request(method, path string, body interface{}) *httptest.ResponseRecorder {
+	var buf bytes.Buffer
+	if body != nil {
+		require.NoError(ts.T(), json.NewEncoder(&buf).Encode(body))
+	}
+
+	req := httptest.NewRequest(method, "http://localhost"+path, &buf)
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", ts.adminToken))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	ts.API.handler.ServeHTTP(w, req)
+	return w
+}
+
+func (ts *RolesTestSuite) createRole(name string, permissions ...string) *models.Role {
+	w := ts.request(http.MethodPost, "/admin/roles", map[string]interface{}{
+		"name":        name,
+		"permissions": permissions,
+	})
+	require.Equal(ts.T(), http.StatusCreated, w.Code, w.Body.String())
+
+	var role models.Role
+	require.NoError(ts.T(), json.NewDecoder(w.Body).Decode(&role))
+	return &role
+}
+
+func (ts *RolesTestSuite) TestRoles() {
+	editor := ts.createRole("editor", "documents:read", "documents:write", "documents:read")
+	require.Equal(ts.T(), models.Permissions{"documents:read", "documents:write"}, editor.Permissions)
+
+	w := ts.request(http.MethodPost, "/admin/roles", map[string]interface{}{"name": "editor"})
+	require.Equal(ts.T(), http.StatusUnprocessableEntity, w.Code)
+
+	path := "/admin/roles/" + editor.ID.String()
+	w = ts.request(http.MethodPut, path, map[string]interface{}{"description": "Edits documents"})
+	require.Equal(ts.T(), http.StatusOK, w.Code, w.Body.String())
+	var role models.Role
+	require.NoError(ts.T(), json.NewDecoder(w.Body).Decode(&role))
+	require.Equal(ts.T(), "Edits documents", role.Description)
+	require.Equal(ts.T(), editor.Permissions, role.Permissions)
+
+	w = ts.request(http.MethodGet, "/admin/roles", nil)
+	require.Equal(ts.T(), http.StatusOK, w.Code, w.Body.String())
+	var roles AdminListRolesResponse
+	require.NoError(ts.T(), json.NewDecoder(w.Body).Decode(&roles))
+	require.Len(ts.T(), roles.Roles, 1)
+
+	w = ts.request(http.MethodDelete, path, nil)
+	require.Equal(ts.T(), http.StatusNoContent, w.Code, w.Body.String())
+	w = ts.request(http.MethodGet, path, nil)
+	require.Equal(ts.T(), http.StatusNotFound, w.Code)
+}
+
+func (ts *RolesTestSuite) TestUserRoles() {
+	user, err := models.NewUser("", "alice@example.com", "password", ts.Config.JWT.Aud, nil)
+	require.NoError(ts.T(), err)
+	require.NoError(ts.T(), ts.API.db.Create(user))
+
+	editor := ts.createRole("editor", "documents:read", "documents:write")
+	viewer := ts.createRole("viewer", "documents:read")
+	path := "/admin/users/" + user.ID.String() + "/roles"
+
+	w := ts.request(http.MethodPost, path, &UserRoleParams{RoleID: &editor.ID})
+	require.Equal(ts.T(), http.StatusOK, w.Code, w.Body.String())
+	w = ts.request(http.MethodPost, path, &UserRoleParams{Name: "viewer"})
+	require.Equal(ts.T(), http.StatusOK, w.Code, w.Body.String())
+	w = ts.request(http.MethodPost, path, &UserRoleParams{Name: "unknown"})
+	require.Equal(ts.T(), http.StatusNotFound, w.Code)
+
+	w = ts.request(http.MethodGet, path, nil)
+	require.Equal(ts.T(), http.StatusOK, w.Code, w.Body.String())
+	var userRoles AdminUserRolesResponse
+	require.NoError(ts.T(), json.NewDecoder(w.Body).Decode(&userRoles))
+	require.Len(ts.T(), userRoles.Roles, 2)
+	require.Equal(ts.T(), []string{"documents:read", "documents:write"}, userRoles.Permissions)
+
+	session, err := models.NewSession(user.ID, nil)
+	require.NoError(ts.T(), err)
+	require.NoError(ts.T(), ts.API.db.Create(session))
+	req := httptest.NewRequest(http.MethodPost, "/token?grant_type=password", nil)
+	token, _, err := ts.API.generateAccessToken(req, ts.API.db, user, &session.ID, models.PasswordGrant)
+	require.NoError(ts.T(), err)
+
+	claims := &AccessTokenClaims{}
+	_, err = jwt.ParseWithClaims(token, claims, func(token *jwt.Token) (interface{}, error) {
+		return []byte(ts.Config.JWT.Secret), nil
+	})
+	require.NoError(ts.T(), err)
+	require.Equal(ts.T(), []string{"editor", "viewer"}, claims.Roles)
+	require.Equal(ts.T(), []string{"documents:read", "documents:write"}, claims.Permissions)
+
+	w = ts.request(http.MethodDelete, path+"/"+editor.ID.String(), nil)
+	require.Equal(ts.T(), http.StatusOK, w.Code, w.Body.String())
+	userRoles = AdminUserRolesResponse{}
+	require.NoError(ts.T(), json.NewDecoder(w.Body).Decode(&userRoles))
+	require.Equal(ts.T(), []string{"documents:read"}, userRoles.Permissions)
+
+	w = ts.request(http.MethodDelete, path+"/"+editor.ID.String(), nil)
+	require.Equal(ts.T(), http.StatusNotFound, w.Code)
+
+	// deleting a role removes it from its users
+	w = ts.request(http.MethodDelete, "/admin/roles/"+viewer.ID.String(), nil)
+	require.Equal(ts.T(), http.StatusNoContent, w.Code)
+	w = ts.request(http.MethodGet, path, nil)
+	userRoles = AdminUserRolesResponse{}
+	require.NoError(ts.T(), json.NewDecoder(w.Body).Decode(&userRoles))
+	require.Empty(ts.T(), userRoles.Roles)
+}
+
+func (ts *RolesTestSuite) TestDisabled() {
+	ts.Config.RBAC.Enabled = false
+
+	w := ts.request(http.MethodGet, "/admin/roles", nil)
+	require.Equal(ts.T(), http.StatusNotFound, w.Code)
+}
+
+func TestRoleParamsValidate(t *testing.T) {
+	permissions := []string{" documents:read ", "documents:*", "documents:read"}
+	params := &RoleParams{Name: " editor ", Permissions: &permissions}
+	require.NoError(t, params.validate(true))
+	require.Equal(t, "editor", params.Name)
+	require.Equal(t, []string{"documents:read", "documents:*"}, *params.Permissions)
+
+	require.Error(t, (&RoleParams{}).validate(true))
+	require.Error(t, (&RoleParams{Name: "an editor"}).validate(true))
+	invalid := []string{"read documents"}
+	require.Error(t, (&RoleParams{Name: "editor", Permissions: &invalid}).validate(true))
+	require.NoError(t, (&RoleParams{}).validate(false))
+}
@@ -4,11 +4,20 @@ import (
 	tst "testing"
 	"time"
 
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
 	"encoding/xml"
+	"math/big"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 
+	"github.com/beevik/etree"
 	"github.com/crewjam/saml"
+	dsig "github.com/russellhaering/goxmldsig"
 	"github.com/stretchr/testify/require"
 	"github.com/supabase/auth/internal/conf"
 )
@@ -57,3 +66,92 @@ func TestSAMLMetadataWithAPI(t *tst.T) {
 	require.Equal(t, metadata.SPSSODescriptors[0].NameIDFormats[0], saml.EmailAddressNameIDFormat)
 	require.Equal(t, metadata.SPSSODescriptors[0].NameIDFormats[1], saml.PersistentNameIDFormat)
 }
+
+func TestSAMLValidateLogoutRequest(t *tst.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	now := time.Now()
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "idp.example.com"},
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	idpMetadata := &saml.EntityDescriptor{
+		EntityID: "https://idp.example.com/metadata",
+		IDPSSODescriptors: []saml.IDPSSODescriptor{
+			{
+				SSODescriptor: saml.SSODescriptor{
+					RoleDescriptor: saml.RoleDescriptor{
+						KeyDescriptors: []saml.KeyDescriptor{
+							{
+								Use: "signing",
+								KeyInfo: saml.KeyInfo{
+									X509Data: saml.X509Data{
+										X509Certificates: []saml.X509Certificate{
+											{Data: base64.StdEncoding.EncodeToString(der)},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	sloURL := "https://projectref.supabase.co/auth/v1/sso/saml/slo"
+
+	idp := saml.ServiceProvider{
+		EntityID:        idpMetadata.EntityID,
+		Key:             key,
+		Certificate:     cert,
+		SignatureMethod: dsig.RSASHA256SignatureMethod,
+		IDPMetadata:     &saml.EntityDescriptor{EntityID: "https://projectref.supabase.co/auth/v1/sso/saml/metadata"},
+	}
+
+	logoutRequest, err := idp.MakeLogoutRequest(sloURL, "user@example.com")
+	require.NoError(t, err)
+
+	doc := etree.NewDocument()
+	doc.SetRoot(logoutRequest.Element())
+	raw, err := doc.WriteToBytes()
+	require.NoError(t, err)
+
+	parsed, el, err := parseSAMLLogoutRequest(base64.StdEncoding.EncodeToString(raw))
+	require.NoError(t, err)
+	require.Equal(t, "user@example.com", parsed.NameID.Value)
+	require.Equal(t, idpMetadata.EntityID, parsed.Issuer.Value)
+
+	require.NoError(t, validateSAMLLogoutRequest(parsed, el, idpMetadata, sloURL, now))
+
+	require.Error(t, validateSAMLLogoutRequest(parsed, el, idpMetadata, "https://other.example.com/sso/saml/slo", now))
+	require.Error(t, validateSAMLLogoutRequest(parsed, el, idpMetadata, sloURL, now.Add(saml.MaxIssueDelay+time.Minute)))
+
+	// changing the NameID invalidates the signature
+	tampered, tamperedEl, err := parseSAMLLogoutRequest(base64.StdEncoding.EncodeToString(
+		[]byte(strings.Replace(string(raw), "user@example.com", "admin@example.com", 1)),
+	))
+	require.NoError(t, err)
+	require.Error(t, validateSAMLLogoutRequest(tampered, tamperedEl, idpMetadata, sloURL, now))
+
+	// unsigned requests are rejected
+	idp.SignatureMethod = ""
+	unsigned, err := idp.MakeLogoutRequest(sloURL, "user@example.com")
+	require.NoError(t, err)
+	doc = etree.NewDocument()
+	doc.SetRoot(unsigned.Element())
+	raw, err = doc.WriteToBytes()
+	require.NoError(t, err)
+	parsed, el, err = parseSAMLLogoutRequest(base64.StdEncoding.EncodeToString(raw))
+	require.NoError(t, err)
+	require.Error(t, validateSAMLLogoutRequest(parsed, el, idpMetadata, sloURL, now))
+}
@@ -0,0 +1,228 @@
+package api
+
+import (
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/beevik/etree"
+	"github.com/crewjam/saml"
+	dsig "github.com/russellhaering/goxmldsig"
+	"github.com/supabase/auth/internal/api/apierrors"
+	"github.com/supabase/auth/internal/models"
+	"github.com/supabase/auth/internal/storage"
+)
+
+var samlCertificateWhitespace = regexp.MustCompile(`\s+`)
+
+// SAMLSingleLogout implements the Single Logout Service endpoint advertised in
+// the SAML metadata. Identity Providers use it with the HTTP-POST binding to
+// sign a user out of all of their sessions.
+func (a *API) SAMLSingleLogout(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	db := a.db.WithContext(ctx)
+	config := a.config
+
+	if r.FormValue("SAMLResponse") != "" {
+		return apierrors.NewBadRequestError(apierrors.ErrorCodeValidationFailed, "SAML LogoutResponse is not supported, only Identity Provider initiated logout is")
+	}
+
+	samlRequest := r.FormValue("SAMLRequest")
+	if samlRequest == "" {
+		return apierrors.NewBadRequestError(apierrors.ErrorCodeValidationFailed, "SAMLRequest is missing")
+	}
+
+	logoutRequest, el, err := parseSAMLLogoutRequest(samlRequest)
+	if err != nil {
+		return apierrors.NewBadRequestError(apierrors.ErrorCodeValidationFailed, "SAML LogoutRequest is not valid").WithInternalError(err)
+	}
+
+	if logoutRequest.Issuer == nil || logoutRequest.Issuer.Value == "" {
+		return apierrors.NewBadRequestError(apierrors.ErrorCodeValidationFailed, "SAML LogoutRequest does not contain an Issuer")
+	}
+
+	ssoProvider, err := models.FindSAMLProviderByEntityID(db, logoutRequest.Issuer.Value)
+	if err != nil {
+		if models.IsNotFoundError(err) {
+			return apierrors.NewNotFoundError(apierrors.ErrorCodeSSOProviderNotFound, "A SAML connection with this Entity ID does not exist")
+		}
+		return apierrors.NewInternalServerError("Error finding SAML connection").WithInternalError(err)
+	}
+
+	if !ssoProvider.IsEnabled() {
+		return apierrors.NewNotFoundError(apierrors.ErrorCodeSSOProviderDisabled, "SSO Provider is currently disabled")
+	}
+
+	idpMetadata, err := ssoProvider.SAMLProvider.EntityDescriptor()
+	if err != nil {
+		return apierrors.NewInternalServerError("SAML metadata for SSO provider is not valid").WithInternalError(err)
+	}
+
+	serviceProvider := a.getSAMLServiceProvider(idpMetadata, true)
+
+	if err := validateSAMLLogoutRequest(logoutRequest, el, idpMetadata, serviceProvider.SloURL.String(), time.Now()); err != nil {
+		return apierrors.NewBadRequestError(apierrors.ErrorCodeValidationFailed, "SAML LogoutRequest is not valid").WithInternalError(err)
+	}
+
+	providerType := "sso:" + ssoProvider.ID.String()
+
+	identity, err := models.FindIdentityByIdAndProvider(db, logoutRequest.NameID.Value, providerType)
+	if err != nil && !models.IsNotFoundError(err) {
+		return apierrors.NewInternalServerError("Error finding identity").WithInternalError(err)
+	}
+
+	// users that never signed in or were deleted have no sessions to end,
+	// which is still a successful logout from the IdP's point of view
+	if identity != nil {
+		user, err := models.FindUserByID(db, identity.UserID)
+		if err != nil {
+			return apierrors.NewInternalServerError("Error finding user").WithInternalError(err)
+		}
+
+		if err := db.Transaction(func(tx *storage.Connection) error {
+			if terr := models.NewAuditLogEntry(config.AuditLog, r, tx, user, models.LogoutAction, "", map[string]interface{}{
+				"provider":     providerType,
+				"initiated_by": "idp",
+			}); terr != nil {
+				return terr
+			}
+
+			return models.Logout(tx, user.ID)
+		}); err != nil {
+			return apierrors.NewInternalServerError("Error logging out user").WithInternalError(err)
+		}
+	}
+
+	relayState := r.FormValue("RelayState")
+
+	if serviceProvider.GetSLOBindingLocation(saml.HTTPPostBinding) != "" {
+		form, err := serviceProvider.MakePostLogoutResponse(logoutRequest.ID, relayState)
+		if err != nil {
+			return apierrors.NewInternalServerError("Error creating SAML LogoutResponse").WithInternalError(err)
+		}
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		_, err = w.Write(form)
+		return err
+	}
+
+	if serviceProvider.GetSLOBindingLocation(saml.HTTPRedirectBinding) != "" {
+		redirectURL, err := serviceProvider.MakeRedirectLogoutResponse(logoutRequest.ID, relayState)
+		if err != nil {
+			return apierrors.NewInternalServerError("Error creating SAML LogoutResponse").WithInternalError(err)
+		}
+
+		http.Redirect(w, r, redirectURL.String(), http.StatusSeeOther)
+		return nil
+	}
+
+	// the IdP does not accept logout responses
+	http.Redirect(w, r, config.SiteURL, http.StatusSeeOther)
+	return nil
+}
+
+// parseSAMLLogoutRequest decodes a base64 encoded LogoutRequest as sent with
+// the HTTP-POST binding.
+func parseSAMLLogoutRequest(data string) (*saml.LogoutRequest, *etree.Element, error) {
+	raw, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	doc := etree.NewDocument()
+	if err := doc.ReadFromBytes(raw); err != nil {
+		return nil, nil, err
+	}
+
+	if doc.Root() == nil {
+		return nil, nil, errors.New("empty LogoutRequest")
+	}
+
+	var logoutRequest saml.LogoutRequest
+	if err := xml.Unmarshal(raw, &logoutRequest); err != nil {
+		return nil, nil, err
+	}
+
+	return &logoutRequest, doc.Root(), nil
+}
+
+// validateSAMLLogoutRequest checks that the LogoutRequest is signed by the
+// Identity Provider, addressed to this Service Provider and recent.
+func validateSAMLLogoutRequest(logoutRequest *saml.LogoutRequest, el *etree.Element, idpMetadata *saml.EntityDescriptor, sloURL string, now time.Time) error {
+	if logoutRequest.Destination != "" && logoutRequest.Destination != sloURL {
+		return fmt.Errorf("destination %q does not match %q", logoutRequest.Destination, sloURL)
+	}
+
+	if logoutRequest.IssueInstant.Add(saml.MaxIssueDelay).Before(now) {
+		return errors.New("request is too old")
+	}
+
+	if logoutRequest.NotOnOrAfter != nil && !now.Before(*logoutRequest.NotOnOrAfter) {
+		return errors.New("request has expired")
+	}
+
+	if logoutRequest.NameID == nil || logoutRequest.NameID.Value == "" {
+		return errors.New("request does not contain a NameID")
+	}
+
+	certs, err := samlIDPSigningCertificates(idpMetadata)
+	if err != nil {
+		return err
+	}
+
+	if el.FindElement("./Signature") == nil {
+		return errors.New("request is not signed")
+	}
+
+	validationContext := dsig.NewDefaultValidationContext(&dsig.MemoryX509CertificateStore{
+		Roots: certs,
+	})
+	validationContext.IdAttribute = "ID"
+	validationContext.Clock = dsig.NewFakeClockAt(now)
+
+	if _, err := validationContext.Validate(el); err != nil {
+		return fmt.Errorf("invalid signature: %w", err)
+	}
+
+	return nil
+}
+
+// samlIDPSigningCertificates returns the certificates the Identity Provider
+// signs messages with.
+func samlIDPSigningCertificates(idpMetadata *saml.EntityDescriptor) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+
+	for _, idpSSODescriptor := range idpMetadata.IDPSSODescriptors {
+		for _, keyDescriptor := range idpSSODescriptor.KeyDescriptors {
+			if keyDescriptor.Use != "" && keyDescriptor.Use != "signing" {
+				continue
+			}
+
+			for _, certificate := range keyDescriptor.KeyInfo.X509Data.X509Certificates {
+				der, err := base64.StdEncoding.DecodeString(samlCertificateWhitespace.ReplaceAllString(certificate.Data, ""))
+				if err != nil {
+					return nil, fmt.Errorf("cannot parse certificate: %w", err)
+				}
+
+				cert, err := x509.ParseCertificate(der)
+				if err != nil {
+					return nil, err
+				}
+
+				certs = append(certs, cert)
+			}
+		}
+	}
+
+	if len(certs) == 0 {
+		return nil, errors.New("cannot find any signing certificate in the IdP metadata")
+	}
+
+	return certs, nil
+}
@@ -0,0 +1,1415 @@
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fatih/structs"
+	"github.com/go-chi/chi/v5"
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+	"github.com/sethvargo/go-password/password"
+	"github.com/supabase/auth/internal/api/apierrors"
+	"github.com/supabase/auth/internal/api/provider"
+	"github.com/supabase/auth/internal/conf"
+	"github.com/supabase/auth/internal/models"
+	"github.com/supabase/auth/internal/observability"
+	"github.com/supabase/auth/internal/storage"
+	"github.com/supabase/auth/internal/webhooks"
+)
+
+const (
+	scimSchemaUser                  = "urn:ietf:params:scim:schemas:core:2.0:User"
+	scimSchemaGroup                 = "urn:ietf:params:scim:schemas:core:2.0:Group"
+	scimSchemaServiceProviderConfig = "urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"
+	scimSchemaListResponse          = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	scimSchemaError                 = "urn:ietf:params:scim:api:messages:2.0:Error"
+
+	// scimMaxResults is the maximum number of resources returned by a list
+	// request.
+	scimMaxResults = 100
+
+	// scimDeactivatedBanDuration is how long a user deactivated through SCIM
+	// (active=false) is banned for. It is lifted when the user is activated
+	// again.
+	scimDeactivatedBanDuration = 100 * 365 * 24 * time.Hour
+
+	// app_metadata keys holding SCIM attributes that have no column of their
+	// own on the user.
+	scimExternalIDKey = "scim_external_id"
+	scimUserNameKey   = "scim_user_name"
+)
+
+// SCIM error types, see RFC 7644 section 3.12.
+const (
+	scimTypeInvalidFilter = "invalidFilter"
+	scimTypeInvalidSyntax = "invalidSyntax"
+	scimTypeInvalidPath   = "invalidPath"
+	scimTypeInvalidValue  = "invalidValue"
+	scimTypeUniqueness    = "uniqueness"
+	scimTypeNoTarget      = "noTarget"
+)
+
+// SCIMError is an error response in the format mandated by RFC 7644.
+type SCIMError struct {
+	Schemas  []string `json:"schemas"`
+	Status   string   `json:"status"`
+	ScimType string   `json:"scimType,omitempty"`
+	Detail   string   `json:"detail,omitempty"`
+
+	HTTPStatus    int   `json:"-"`
+	InternalError error `json:"-"`
+}
+
+func newSCIMError(httpStatus int, scimType string, fmtString string, args ...interface{}) *SCIMError {
+	return &SCIMError{
+		Schemas:    []string{scimSchemaError},
+		Status:     strconv.Itoa(httpStatus),
+		ScimType:   scimType,
+		Detail:     fmt.Sprintf(fmtString, args...),
+		HTTPStatus: httpStatus,
+	}
+}
+
+func (e *SCIMError) Error() string {
+	if e.InternalError != nil {
+		return fmt.Sprintf("%d: %s: %s", e.HTTPStatus, e.Detail, e.InternalError.Error())
+	}
+	return fmt.Sprintf("%d: %s", e.HTTPStatus, e.Detail)
+}
+
+// Cause returns the root cause error
+func (e *SCIMError) Cause() error {
+	if e.InternalError != nil {
+		return e.InternalError
+	}
+	return e
+}
+
+// WithInternalError adds internal error information to the error
+func (e *SCIMError) WithInternalError(err error) *SCIMError {
+	e.InternalError = err
+	return e
+}
+
+// toSCIMError converts errors returned by the shared helpers of this package
+// into SCIM errors, so that SCIM clients always receive RFC 7644 responses.
+func toSCIMError(err error) error {
+	var scimErr *SCIMError
+	if errors.As(err, &scimErr) {
+		return scimErr
+	}
+
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		status := httpErr.HTTPStatus
+		scimType := ""
+
+		switch httpErr.ErrorCode {
+		case apierrors.ErrorCodeEmailExists, apierrors.ErrorCodePhoneExists:
+			status = http.StatusConflict
+			scimType = scimTypeUniqueness
+		case apierrors.ErrorCodeBadJSON:
+			scimType = scimTypeInvalidSyntax
+		default:
+			if status == http.StatusBadRequest || status == http.StatusUnprocessableEntity {
+				status = http.StatusBadRequest
+				scimType = scimTypeInvalidValue
+			}
+		}
+
+		return newSCIMError(status, scimType, "%s", httpErr.Message).WithInternalError(httpErr)
+	}
+
+	return newSCIMError(http.StatusInternalServerError, "", "Unexpected failure").WithInternalError(err)
+}
+
+// scimHandler wraps a SCIM endpoint so that any error it returns is sent as
+// a SCIM error response.
+func scimHandler(fn apiHandler) apiHandler {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		if err := fn(w, r); err != nil {
+			return toSCIMError(err)
+		}
+		return nil
+	}
+}
+
+// scimMiddleware is the scimHandler equivalent for middlewares.
+func scimMiddleware(fn middlewareHandler) middlewareHandler {
+	return func(w http.ResponseWriter, r *http.Request) (context.Context, error) {
+		ctx, err := fn(w, r)
+		if err != nil {
+			return nil, toSCIMError(err)
+		}
+		return ctx, nil
+	}
+}
+
+func sendSCIM(w http.ResponseWriter, status int, obj interface{}) error {
+	b, err := json.Marshal(obj)
+	if err != nil {
+		return errors.Wrap(err, fmt.Sprintf("Error encoding json response: %v", obj))
+	}
+	w.Header().Set("Content-Type", "application/scim+json")
+	w.WriteHeader(status)
+	_, err = w.Write(b)
+	return err
+}
+
+// requireSCIMCredentials authenticates SCIM clients with one of the
+// configured SCIM bearer tokens.
+func (a *API) requireSCIMCredentials(w http.ResponseWriter, r *http.Request) (context.Context, error) {
+	ctx := r.Context()
+	config := a.config
+
+	token, err := a.extractBearerToken(r)
+	if err != nil {
+		return nil, newSCIMError(http.StatusUnauthorized, "", "This endpoint requires a valid SCIM bearer token")
+	}
+
+	authorized := false
+	for _, t := range config.SCIM.Tokens {
+		if subtle.ConstantTimeCompare([]byte(t), []byte(token)) == 1 {
+			authorized = true
+		}
+	}
+
+	if !authorized {
+		return nil, newSCIMError(http.StatusUnauthorized, "", "Invalid SCIM bearer token")
+	}
+
+	// SCIM clients act on behalf of the identity provider, which is
+	// recorded as the actor in the audit log.
+	return withAdminUser(ctx, &models.User{Role: "scim", Email: storage.NullString("scim")}), nil
+}
+
+func (a *API) loadSCIMGroup(w http.ResponseWriter, r *http.Request) (context.Context, error) {
+	ctx := r.Context()
+	db := a.db.WithContext(ctx)
+
+	groupID, err := uuid.FromString(chi.URLParam(r, "group_id"))
+	if err != nil {
+		return nil, newSCIMError(http.StatusNotFound, "", "Group not found")
+	}
+
+	observability.LogEntrySetField(r, "scim_group_id", groupID)
+
+	group, err := models.FindSCIMGroupByID(db, groupID)
+	if err != nil {
+		if models.IsNotFoundError(err) {
+			return nil, newSCIMError(http.StatusNotFound, "", "Group not found")
+		}
+		return nil, apierrors.NewInternalServerError("Database error loading SCIM group").WithInternalError(err)
+	}
+
+	return withSCIMGroup(ctx, group), nil
+}
+
+// SCIMName is the SCIM name complex attribute.
+type SCIMName struct {
+	Formatted  string `json:"formatted,omitempty"`
+	GivenName  string `json:"givenName,omitempty"`
+	FamilyName string `json:"familyName,omitempty"`
+}
+
+// SCIMMultiValued is an entry of a SCIM multi-valued attribute, such as
+// emails or group members.
+type SCIMMultiValued struct {
+	Value   string `json:"value"`
+	Display string `json:"display,omitempty"`
+	Type    string `json:"type,omitempty"`
+	Primary bool   `json:"primary,omitempty"`
+	Ref     string `json:"$ref,omitempty"`
+}
+
+// SCIMMeta holds the SCIM resource metadata.
+type SCIMMeta struct {
+	ResourceType string    `json:"resourceType"`
+	Created      time.Time `json:"created"`
+	LastModified time.Time `json:"lastModified"`
+	Location     string    `json:"location"`
+}
+
+// SCIMUser is the SCIM representation of a user.
+type SCIMUser struct {
+	Schemas      []string          `json:"schemas"`
+	ID           string            `json:"id"`
+	ExternalID   string            `json:"externalId,omitempty"`
+	UserName     string            `json:"userName"`
+	Name         *SCIMName         `json:"name,omitempty"`
+	DisplayName  string            `json:"displayName,omitempty"`
+	Emails       []SCIMMultiValued `json:"emails,omitempty"`
+	PhoneNumbers []SCIMMultiValued `json:"phoneNumbers,omitempty"`
+	Active       bool              `json:"active"`
+	Groups       []SCIMMultiValued `json:"groups,omitempty"`
+	Meta         SCIMMeta          `json:"meta"`
+}
+
+// SCIMGroup is the SCIM representation of a group.
+type SCIMGroup struct {
+	Schemas     []string          `json:"schemas"`
+	ID          string            `json:"id"`
+	ExternalID  string            `json:"externalId,omitempty"`
+	DisplayName string            `json:"displayName"`
+	Members     []SCIMMultiValued `json:"members"`
+	Meta        SCIMMeta          `json:"meta"`
+}
+
+// SCIMListResponse is the response of SCIM list requests.
+type SCIMListResponse struct {
+	Schemas      []string      `json:"schemas"`
+	TotalResults int           `json:"totalResults"`
+	StartIndex   int           `json:"startIndex"`
+	ItemsPerPage int           `json:"itemsPerPage"`
+	Resources    []interface{} `json:"Resources"`
+}
+
+// SCIMUserParams are the attributes of a user accepted from SCIM clients.
+type SCIMUserParams struct {
+	Schemas      []string          `json:"schemas"`
+	ExternalID   string            `json:"externalId"`
+	UserName     string            `json:"userName"`
+	Name         *SCIMName         `json:"name"`
+	DisplayName  string            `json:"displayName"`
+	Emails       []SCIMMultiValued `json:"emails"`
+	PhoneNumbers []SCIMMultiValued `json:"phoneNumbers"`
+	Active       *bool             `json:"active"`
+	Password     string            `json:"password"`
+}
+
+// SCIMGroupParams are the attributes of a group accepted from SCIM clients.
+type SCIMGroupParams struct {
+	Schemas     []string          `json:"schemas"`
+	ExternalID  string            `json:"externalId"`
+	DisplayName string            `json:"displayName"`
+	Members     []SCIMMultiValued `json:"members"`
+}
+
+// SCIMPatchParams is a SCIM PATCH request, see RFC 7644 section 3.5.2.
+type SCIMPatchParams struct {
+	Schemas    []string             `json:"schemas"`
+	Operations []SCIMPatchOperation `json:"Operations"`
+}
+
+// SCIMPatchOperation is a single operation of a SCIM PATCH request.
+type SCIMPatchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	Value json.RawMessage `json:"value"`
+}
+
+// primaryValue returns the value of the primary entry of a multi-valued
+// attribute, or of its first entry when none is marked as primary.
+func primaryValue(values []SCIMMultiValued) string {
+	for _, v := range values {
+		if v.Primary {
+			return v.Value
+		}
+	}
+	if len(values) > 0 {
+		return values[0].Value
+	}
+	return ""
+}
+
+func (p *SCIMUserParams) email() string {
+	if email := primaryValue(p.Emails); email != "" {
+		return email
+	}
+	if strings.Contains(p.UserName, "@") {
+		return p.UserName
+	}
+	return ""
+}
+
+func (p *SCIMUserParams) userMetaData() map[string]interface{} {
+	data := map[string]interface{}{
+		"given_name":  nil,
+		"family_name": nil,
+		"full_name":   nil,
+	}
+
+	fullName := p.DisplayName
+	if p.Name != nil {
+		if p.Name.GivenName != "" {
+			data["given_name"] = p.Name.GivenName
+		}
+		if p.Name.FamilyName != "" {
+			data["family_name"] = p.Name.FamilyName
+		}
+		if p.Name.Formatted != "" {
+			fullName = p.Name.Formatted
+		} else if fullName == "" {
+			fullName = strings.TrimSpace(p.Name.GivenName + " " + p.Name.FamilyName)
+		}
+	}
+	if fullName != "" {
+		data["full_name"] = fullName
+	}
+
+	return data
+}
+
+func (p *SCIMUserParams) appMetaData(email string) map[string]interface{} {
+	data := map[string]interface{}{
+		scimExternalIDKey: nil,
+		scimUserNameKey:   nil,
+	}
+	if p.ExternalID != "" {
+		data[scimExternalIDKey] = p.ExternalID
+	}
+	if !strings.EqualFold(p.UserName, email) {
+		data[scimUserNameKey] = p.UserName
+	}
+	return data
+}
+
+var scimFilterRegexp = regexp.MustCompile(`^\s*([A-Za-z][\w.]*)\s+(?i:eq)\s+"((?:[^"\\]|\\.)*)"\s*$`)
+
+// parseSCIMFilter parses the subset of the SCIM filter syntax supported by
+// this server: a single attribute compared for equality with a string, e.g.
+// userName eq "jane@example.com".
+func parseSCIMFilter(filter string) (string, string, error) {
+	matches := scimFilterRegexp.FindStringSubmatch(filter)
+	if matches == nil {
+		return "", "", newSCIMError(http.StatusBadRequest, scimTypeInvalidFilter, "Only filters of the form 'attribute eq \"value\"' are supported")
+	}
+
+	value, err := strconv.Unquote(`"` + matches[2] + `"`)
+	if err != nil {
+		return "", "", newSCIMError(http.StatusBadRequest, scimTypeInvalidFilter, "Invalid filter value")
+	}
+
+	return matches[1], value, nil
+}
+
+// scimListParams parses the startIndex and count query parameters of a SCIM
+// list request into an offset and limit.
+func scimListParams(r *http.Request) (int, int, error) {
+	query := r.URL.Query()
+
+	startIndex := 1
+	if v := query.Get("startIndex"); v != "" {
+		i, err := strconv.Atoi(v)
+		if err != nil {
+			return 0, 0, newSCIMError(http.StatusBadRequest, scimTypeInvalidValue, "startIndex must be an integer")
+		}
+		// non-positive values are interpreted as 1, see RFC 7644 section 3.4.2.4
+		if i > 1 {
+			startIndex = i
+		}
+	}
+
+	count := scimMaxResults
+	if v := query.Get("count"); v != "" {
+		i, err := strconv.Atoi(v)
+		if err != nil {
+			return 0, 0, newSCIMError(http.StatusBadRequest, scimTypeInvalidValue, "count must be an integer")
+		}
+		count = min(max(i, 0), scimMaxResults)
+	}
+
+	return startIndex - 1, count, nil
+}
+
+// applySCIMPatch applies PATCH operations to the JSON representation of a
+// resource. Attribute names are matched case insensitively and filtered paths
+// such as emails[type eq "work"].value replace the whole multi-valued
+// attribute, as only a single value of each is stored.
+func applySCIMPatch(resource map[string]interface{}, operations []SCIMPatchOperation) error {
+	for _, operation := range operations {
+		var value interface{}
+		if len(operation.Value) > 0 {
+			if err := json.Unmarshal(operation.Value, &value); err != nil {
+				return newSCIMError(http.StatusBadRequest, scimTypeInvalidSyntax, "Invalid PATCH operation value")
+			}
+		}
+
+		switch strings.ToLower(operation.Op) {
+		case "add", "replace":
+			if operation.Path == "" {
+				attributes, ok := value.(map[string]interface{})
+				if !ok {
+					return newSCIMError(http.StatusBadRequest, scimTypeInvalidValue, "PATCH operations without a path require an object value")
+				}
+				for path, v := range attributes {
+					if err := setSCIMAttribute(resource, path, v); err != nil {
+						return err
+					}
+				}
+			} else if err := setSCIMAttribute(resource, operation.Path, value); err != nil {
+				return err
+			}
+
+		case "remove":
+			if operation.Path == "" {
+				return newSCIMError(http.StatusBadRequest, scimTypeNoTarget, "PATCH remove operations require a path")
+			}
+			if err := setSCIMAttribute(resource, operation.Path, nil); err != nil {
+				return err
+			}
+
+		default:
+			return newSCIMError(http.StatusBadRequest, scimTypeInvalidSyntax, "Unsupported PATCH operation %q", operation.Op)
+		}
+	}
+
+	return nil
+}
+
+// setSCIMAttribute sets the attribute at path to value, removing it when
+// value is nil.
+func setSCIMAttribute(resource map[string]interface{}, path string, value interface{}) error {
+	if i := strings.Index(path, "["); i >= 0 {
+		if !strings.Contains(path[i:], "]") {
+			return newSCIMError(http.StatusBadRequest, scimTypeInvalidPath, "Invalid path %q", path)
+		}
+		attribute := path[:i]
+		if value != nil {
+			value = []interface{}{map[string]interface{}{"value": value, "primary": true}}
+		}
+		return setSCIMAttribute(resource, attribute, value)
+	}
+
+	attribute, subAttribute, nested := strings.Cut(path, ".")
+	key := scimAttributeKey(resource, attribute)
+
+	if strings.EqualFold(attribute, "active") {
+		if s, ok := value.(string); ok {
+			active, err := strconv.ParseBool(s)
+			if err != nil {
+				return newSCIMError(http.StatusBadRequest, scimTypeInvalidValue, "active must be a boolean")
+			}
+			value = active
+		}
+	}
+
+	if !nested {
+		if value == nil {
+			delete(resource, key)
+		} else {
+			resource[key] = value
+		}
+		return nil
+	}
+
+	parent, ok := resource[key].(map[string]interface{})
+	if !ok {
+		if value == nil {
+			return nil
+		}
+		parent = make(map[string]interface{})
+		resource[key] = parent
+	}
+
+	return setSCIMAttribute(parent, subAttribute, value)
+}
+
+func scimAttributeKey(resource map[string]interface{}, attribute string) string {
+	for key := range resource {
+		if strings.EqualFold(key, attribute) {
+			return key
+		}
+	}
+	return attribute
+}
+
+func (a *API) scimLocation(resourceType string, id uuid.UUID) string {
+	return strings.TrimSuffix(a.config.API.ExternalURL, "/") + "/scim/v2/" + resourceType + "/" + id.String()
+}
+
+func (a *API) scimUserResource(user *models.User, groups []*models.SCIMGroup) *SCIMUser {
+	resource := &SCIMUser{
+		Schemas:  []string{scimSchemaUser},
+		ID:       user.ID.String(),
+		UserName: user.GetEmail(),
+		Active:   !user.IsBanned(),
+		Meta: SCIMMeta{
+			ResourceType: "User",
+			Created:      user.CreatedAt,
+			LastModified: user.UpdatedAt,
+			Location:     a.scimLocation("Users", user.ID),
+		},
+	}
+
+	if userName, ok := user.AppMetaData[scimUserNameKey].(string); ok && userName != "" {
+		resource.UserName = userName
+	}
+
+	if externalID, ok := user.AppMetaData[scimExternalIDKey].(string); ok {
+		resource.ExternalID = externalID
+	}
+
+	name := &SCIMName{}
+	if givenName, ok := user.UserMetaData["given_name"].(string); ok {
+		name.GivenName = givenName
+	}
+	if familyName, ok := user.UserMetaData["family_name"].(string); ok {
+		name.FamilyName = familyName
+	}
+	if fullName, ok := user.UserMetaData["full_name"].(string); ok {
+		name.Formatted = fullName
+		resource.DisplayName = fullName
+	}
+	if *name != (SCIMName{}) {
+		resource.Name = name
+	}
+
+	if email := user.GetEmail(); email != "" {
+		resource.Emails = []SCIMMultiValued{{Value: email, Type: "work", Primary: true}}
+	}
+
+	if phone := user.GetPhone(); phone != "" {
+		resource.PhoneNumbers = []SCIMMultiValued{{Value: phone, Type: "work", Primary: true}}
+	}
+
+	for _, group := range groups {
+		resource.Groups = append(resource.Groups, SCIMMultiValued{
+			Value:   group.ID.String(),
+			Display: group.DisplayName,
+			Ref:     a.scimLocation("Groups", group.ID),
+		})
+	}
+
+	return resource
+}
+
+func (a *API) scimGroupResource(group *models.SCIMGroup, memberIDs []uuid.UUID) *SCIMGroup {
+	resource := &SCIMGroup{
+		Schemas:     []string{scimSchemaGroup},
+		ID:          group.ID.String(),
+		DisplayName: group.DisplayName,
+		Members:     []SCIMMultiValued{},
+		Meta: SCIMMeta{
+			ResourceType: "Group",
+			Created:      group.CreatedAt,
+			LastModified: group.UpdatedAt,
+			Location:     a.scimLocation("Groups", group.ID),
+		},
+	}
+
+	if group.ExternalID != nil {
+		resource.ExternalID = *group.ExternalID
+	}
+
+	for _, id := range memberIDs {
+		resource.Members = append(resource.Members, SCIMMultiValued{
+			Value: id.String(),
+			Ref:   a.scimLocation("Users", id),
+		})
+	}
+
+	return resource
+}
+
+func (a *API) loadSCIMUserResource(tx *storage.Connection, user *models.User) (*SCIMUser, error) {
+	groups, err := models.FindSCIMGroupsByUserID(tx, user.ID)
+	if err != nil {
+		return nil, apierrors.NewInternalServerError("Database error finding SCIM groups").WithInternalError(err)
+	}
+	return a.scimUserResource(user, groups), nil
+}
+
+func (a *API) loadSCIMGroupResource(tx *storage.Connection, group *models.SCIMGroup) (*SCIMGroup, error) {
+	memberIDs, err := group.MemberIDs(tx)
+	if err != nil {
+		return nil, apierrors.NewInternalServerError("Database error finding SCIM group members").WithInternalError(err)
+	}
+	return a.scimGroupResource(group, memberIDs), nil
+}
+
+// scimServiceProviderConfig describes the SCIM features supported by this
+// server.
+func (a *API) scimServiceProviderConfig(w http.ResponseWriter, r *http.Request) error {
+	return sendSCIM(w, http.StatusOK, map[string]interface{}{
+		"schemas":        []string{scimSchemaServiceProviderConfig},
+		"patch":          map[string]bool{"supported": true},
+		"bulk":           map[string]interface{}{"supported": false, "maxOperations": 0, "maxPayloadSize": 0},
+		"filter":         map[string]interface{}{"supported": true, "maxResults": scimMaxResults},
+		"changePassword": map[string]bool{"supported": true},
+		"sort":           map[string]bool{"supported": false},
+		"etag":           map[string]bool{"supported": false},
+		"authenticationSchemes": []map[string]interface{}{{
+			"type":        "oauthbearertoken",
+			"name":        "OAuth Bearer Token",
+			"description": "Authentication with one of the configured SCIM bearer tokens",
+			"primary":     true,
+		}},
+	})
+}
+
+func (a *API) scimUsersList(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	db := a.db.WithContext(ctx)
+	aud := a.requestAud(ctx, r)
+
+	offset, limit, err := scimListParams(r)
+	if err != nil {
+		return err
+	}
+
+	var filter models.SCIMUserFilter
+	if f := r.URL.Query().Get("filter"); f != "" {
+		attribute, value, err := parseSCIMFilter(f)
+		if err != nil {
+			return err
+		}
+
+		switch strings.ToLower(attribute) {
+		case "username":
+			filter.UserName = value
+		case "emails", "emails.value":
+			filter.Email = value
+		case "externalid":
+			filter.ExternalID = value
+		default:
+			return newSCIMError(http.StatusBadRequest, scimTypeInvalidFilter, "Filtering users by %q is not supported", attribute)
+		}
+	}
+
+	users, total, err := models.FindSCIMUsers(db, aud, filter, offset, limit)
+	if err != nil {
+		return apierrors.NewInternalServerError("Database error finding users").WithInternalError(err)
+	}
+
+	resources := make([]interface{}, 0, len(users))
+	for _, user := range users {
+		resource, err := a.loadSCIMUserResource(db, user)
+		if err != nil {
+			return err
+		}
+		resources = append(resources, resource)
+	}
+
+	return sendSCIM(w, http.StatusOK, SCIMListResponse{
+		Schemas:      []string{scimSchemaListResponse},
+		TotalResults: total,
+		StartIndex:   offset + 1,
+		ItemsPerPage: len(resources),
+		Resources:    resources,
+	})
+}
+
+func (a *API) scimUsersCreate(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	db := a.db.WithContext(ctx)
+	config := a.config
+	adminUser := getAdminUser(ctx)
+	aud := a.requestAud(ctx, r)
+
+	params := &SCIMUserParams{}
+	if err := retrieveRequestParams(r, params); err != nil {
+		return err
+	}
+
+	if params.UserName == "" {
+		return newSCIMError(http.StatusBadRequest, scimTypeInvalidValue, "userName is required")
+	}
+
+	email, err := a.validateEmail(params.email())
+	if err != nil {
+		return err
+	}
+
+	if user, err := models.IsDuplicatedEmail(db, email, aud, nil, config.Experimental.ProvidersWithOwnLinkingDomain); err != nil {
+		return apierrors.NewInternalServerError("Database error checking email").WithInternalError(err)
+	} else if user != nil {
+		return newSCIMError(http.StatusConflict, scimTypeUniqueness, "A user with this userName or email already exists")
+	}
+
+	phone := primaryValue(params.PhoneNumbers)
+	if phone != "" {
+		phone, err = validatePhone(phone)
+		if err != nil {
+			return err
+		}
+		if exists, err := models.IsDuplicatedPhone(db, phone, aud); err != nil {
+			return apierrors.NewInternalServerError("Database error checking phone").WithInternalError(err)
+		} else if exists {
+			return newSCIMError(http.StatusConflict, scimTypeUniqueness, "A user with this phone number already exists")
+		}
+	}
+
+	pw := params.Password
+	if pw != "" {
+		if err := a.checkPasswordStrength(ctx, pw, email); err != nil {
+			return err
+		}
+	} else {
+		pw, err = password.Generate(64, 10, 0, false, true)
+		if err != nil {
+			return apierrors.NewInternalServerError("Error generating password").WithInternalError(err)
+		}
+	}
+
+	user, err := models.NewUser(phone, email, pw, aud, params.userMetaData())
+	if err != nil {
+		return apierrors.NewInternalServerError("Error creating user").WithInternalError(err)
+	}
+
+	user.AppMetaData = map[string]interface{}{
+		"provider":  "email",
+		"providers": []string{"email"},
+	}
+
+	var resource *SCIMUser
+	err = db.Transaction(func(tx *storage.Connection) error {
+		if terr := tx.Create(user); terr != nil {
+			return terr
+		}
+
+		identity, terr := a.createNewIdentity(tx, user, "email", structs.Map(provider.Claims{
+			Subject:       user.ID.String(),
+			Email:         user.GetEmail(),
+			EmailVerified: true,
+		}))
+		if terr != nil {
+			return terr
+		}
+		user.Identities = []models.Identity{*identity}
+
+		if user.GetPhone() != "" {
+			identity, terr := a.createNewIdentity(tx, user, "phone", structs.Map(provider.Claims{
+				Subject: user.ID.String(),
+				Phone:   user.GetPhone(),
+			}))
+			if terr != nil {
+				return terr
+			}
+			user.Identities = append(user.Identities, *identity)
+		}
+
+		if terr := models.NewAuditLogEntry(config.AuditLog, r, tx, adminUser, models.UserSignedUpAction, "", map[string]interface{}{
+			"user_id":    user.ID,
+			"user_email": user.Email,
+			"user_phone": user.Phone,
+			"provider":   "scim",
+		}); terr != nil {
+			return terr
+		}
+
+		if terr := user.SetRole(tx, config.JWT.DefaultGroupName); terr != nil {
+			return terr
+		}
+
+		if terr := user.UpdateAppMetaData(tx, params.appMetaData(email)); terr != nil {
+			return terr
+		}
+
+		// users provisioned by the identity provider are trusted to own
+		// their email address
+		if terr := user.Confirm(tx); terr != nil {
+			return terr
+		}
+
+		if params.Active != nil && !*params.Active {
+			if terr := user.Ban(tx, scimDeactivatedBanDuration); terr != nil {
+				return terr
+			}
+		}
+
+		if terr := webhooks.Emit(tx, &config.Webhooks, conf.WebhookEventUserCreated, user, nil); terr != nil {
+			return terr
+		}
+
+		resource, terr = a.loadSCIMUserResource(tx, user)
+		return terr
+	})
+	if err != nil {
+		return apierrors.NewInternalServerError("Database error creating new user").WithInternalError(err)
+	}
+
+	w.Header().Set("Location", resource.Meta.Location)
+	return sendSCIM(w, http.StatusCreated, resource)
+}
+
+func (a *API) scimUserGet(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	db := a.db.WithContext(ctx)
+	user := getUser(ctx)
+
+	resource, err := a.loadSCIMUserResource(db, user)
+	if err != nil {
+		return err
+	}
+
+	return sendSCIM(w, http.StatusOK, resource)
+}
+
+func (a *API) scimUserReplace(w http.ResponseWriter, r *http.Request) error {
+	params := &SCIMUserParams{}
+	if err := retrieveRequestParams(r, params); err != nil {
+		return err
+	}
+
+	return a.scimUpdateUser(w, r, params)
+}
+
+func (a *API) scimUserPatch(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	db := a.db.WithContext(ctx)
+	user := getUser(ctx)
+
+	patch := &SCIMPatchParams{}
+	if err := retrieveRequestParams(r, patch); err != nil {
+		return err
+	}
+
+	current, err := a.loadSCIMUserResource(db, user)
+	if err != nil {
+		return err
+	}
+
+	// The patch is applied to the current SCIM representation of the user,
+	// which is then stored in the same way as a full replacement.
+	b, err := json.Marshal(current)
+	if err != nil {
+		return apierrors.NewInternalServerError("Error encoding user").WithInternalError(err)
+	}
+
+	resource := make(map[string]interface{})
+	if err := json.Unmarshal(b, &resource); err != nil {
+		return apierrors.NewInternalServerError("Error decoding user").WithInternalError(err)
+	}
+
+	if err := applySCIMPatch(resource, patch.Operations); err != nil {
+		return err
+	}
+
+	b, err = json.Marshal(resource)
+	if err != nil {
+		return apierrors.NewInternalServerError("Error encoding user").WithInternalError(err)
+	}
+
+	params := &SCIMUserParams{}
+	if err := json.Unmarshal(b, params); err != nil {
+		return newSCIMError(http.StatusBadRequest, scimTypeInvalidValue, "Invalid attribute value: %v", err)
+	}
+
+	return a.scimUpdateUser(w, r, params)
+}
+
+// scimUpdateUser replaces the SCIM attributes of the user loaded in the
+// request context with params.
+func (a *API) scimUpdateUser(w http.ResponseWriter, r *http.Request, params *SCIMUserParams) error {
+	ctx := r.Context()
+	db := a.db.WithContext(ctx)
+	config := a.config
+	user := getUser(ctx)
+	adminUser := getAdminUser(ctx)
+
+	if params.UserName == "" {
+		return newSCIMError(http.StatusBadRequest, scimTypeInvalidValue, "userName is required")
+	}
+
+	email, err := a.validateEmail(params.email())
+	if err != nil {
+		return err
+	}
+
+	emailChanged := !strings.EqualFold(email, user.GetEmail())
+	if emailChanged {
+		if duplicate, err := models.IsDuplicatedEmail(db, email, user.Aud, user, config.Experimental.ProvidersWithOwnLinkingDomain); err != nil {
+			return apierrors.NewInternalServerError("Database error checking email").WithInternalError(err)
+		} else if duplicate != nil {
+			return newSCIMError(http.StatusConflict, scimTypeUniqueness, "A user with this userName or email already exists")
+		}
+	}
+
+	phone := primaryValue(params.PhoneNumbers)
+	if phone != "" {
+		phone, err = validatePhone(phone)
+		if err != nil {
+			return err
+		}
+	}
+	phoneChanged := phone != "" && phone != user.GetPhone()
+	if phoneChanged {
+		if exists, err := models.IsDuplicatedPhone(db, phone, user.Aud); err != nil {
+			return apierrors.NewInternalServerError("Database error checking phone").WithInternalError(err)
+		} else if exists {
+			return newSCIMError(http.StatusConflict, scimTypeUniqueness, "A user with this phone number already exists")
+		}
+	}
+
+	if params.Password != "" {
+		if err := a.checkPasswordStrength(ctx, params.Password, email); err != nil {
+			return err
+		}
+
+		if err := user.SetPassword(ctx, params.Password, config.Security.DBEncryption.Encrypt, config.Security.DBEncryption.EncryptionKeyID, config.Security.DBEncryption.EncryptionKey); err != nil {
+			return err
+		}
+	}
+
+	var resource *SCIMUser
+	err = db.Transaction(func(tx *storage.Connection) error {
+		if emailChanged {
+			if identity, terr := models.FindIdentityByIdAndProvider(tx, user.ID.String(), "email"); terr != nil && !models.IsNotFoundError(terr) {
+				return terr
+			} else if identity == nil {
+				if _, terr := a.createNewIdentity(tx, user, "email", structs.Map(provider.Claims{
+					Subject:       user.ID.String(),
+					Email:         email,
+					EmailVerified: true,
+				})); terr != nil {
+					return terr
+				}
+			} else if terr := identity.UpdateIdentityData(tx, map[string]interface{}{
+				"email":          email,
+				"email_verified": true,
+			}); terr != nil {
+				return terr
+			}
+
+			if terr := user.SetEmail(tx, email); terr != nil {
+				return terr
+			}
+		}
+
+		if phoneChanged {
+			if identity, terr := models.FindIdentityByIdAndProvider(tx, user.ID.String(), "phone"); terr != nil && !models.IsNotFoundError(terr) {
+				return terr
+			} else if identity == nil {
+				if _, terr := a.createNewIdentity(tx, user, "phone", structs.Map(provider.Claims{
+					Subject: user.ID.String(),
+					Phone:   phone,
+				})); terr != nil {
+					return terr
+				}
+			} else if terr := identity.UpdateIdentityData(tx, map[string]interface{}{
+				"phone": phone,
+			}); terr != nil {
+				return terr
+			}
+
+			if terr := user.SetPhone(tx, phone); terr != nil {
+				return terr
+			}
+		}
+
+		if params.Password != "" {
+			if terr := user.UpdatePassword(tx, nil); terr != nil {
+				return terr
+			}
+		}
+
+		if terr := user.UpdateUserMetaData(tx, params.userMetaData()); terr != nil {
+			return terr
+		}
+
+		if terr := user.UpdateAppMetaData(tx, params.appMetaData(email)); terr != nil {
+			return terr
+		}
+
+		if params.Active != nil {
+			if !*params.Active && !user.IsBanned() {
+				if terr := user.Ban(tx, scimDeactivatedBanDuration); terr != nil {
+					return terr
+				}
+				if terr := models.Logout(tx, user.ID); terr != nil {
+					return terr
+				}
+			} else if *params.Active && user.IsBanned() {
+				if terr := user.Ban(tx, 0); terr != nil {
+					return terr
+				}
+			}
+		}
+
+		if terr := models.NewAuditLogEntry(config.AuditLog, r, tx, adminUser, models.UserModifiedAction, "", map[string]interface{}{
+			"user_id":    user.ID,
+			"user_email": user.Email,
+			"user_phone": user.Phone,
+		}); terr != nil {
+			return terr
+		}
+
+		var terr error
+		resource, terr = a.loadSCIMUserResource(tx, user)
+		return terr
+	})
+	if err != nil {
+		return apierrors.NewInternalServerError("Error updating user").WithInternalError(err)
+	}
+
+	return sendSCIM(w, http.StatusOK, resource)
+}
+
+func (a *API) scimUserDelete(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	db := a.db.WithContext(ctx)
+	config := a.config
+	user := getUser(ctx)
+	adminUser := getAdminUser(ctx)
+
+	err := db.Transaction(func(tx *storage.Connection) error {
+		if terr := models.NewAuditLogEntry(config.AuditLog, r, tx, adminUser, models.UserDeletedAction, "", map[string]interface{}{
+			"user_id":    user.ID,
+			"user_email": user.Email,
+			"user_phone": user.Phone,
+		}); terr != nil {
+			return terr
+		}
+
+		return tx.Destroy(user)
+	})
+	if err != nil {
+		return apierrors.NewInternalServerError("Database error deleting user").WithInternalError(err)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+func (a *API) scimGroupsList(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	db := a.db.WithContext(ctx)
+
+	offset, limit, err := scimListParams(r)
+	if err != nil {
+		return err
+	}
+
+	displayName := ""
+	if f := r.URL.Query().Get("filter"); f != "" {
+		attribute, value, err := parseSCIMFilter(f)
+		if err != nil {
+			return err
+		}
+
+		if !strings.EqualFold(attribute, "displayName") {
+			return newSCIMError(http.StatusBadRequest, scimTypeInvalidFilter, "Filtering groups by %q is not supported", attribute)
+		}
+		displayName = value
+	}
+
+	groups, total, err := models.FindSCIMGroups(db, displayName, offset, limit)
+	if err != nil {
+		return apierrors.NewInternalServerError("Database error finding SCIM groups").WithInternalError(err)
+	}
+
+	resources := make([]interface{}, 0, len(groups))
+	for _, group := range groups {
+		resource, err := a.loadSCIMGroupResource(db, group)
+		if err != nil {
+			return err
+		}
+		resources = append(resources, resource)
+	}
+
+	return sendSCIM(w, http.StatusOK, SCIMListResponse{
+		Schemas:      []string{scimSchemaListResponse},
+		TotalResults: total,
+		StartIndex:   offset + 1,
+		ItemsPerPage: len(resources),
+		Resources:    resources,
+	})
+}
+
+// scimMemberIDs validates the members of a group, which must all be existing
+// users.
+func scimMemberIDs(tx *storage.Connection, members []SCIMMultiValued) ([]uuid.UUID, error) {
+	ids := make([]uuid.UUID, 0, len(members))
+	for _, member := range members {
+		id, err := uuid.FromString(member.Value)
+		if err != nil {
+			return nil, newSCIMError(http.StatusBadRequest, scimTypeInvalidValue, "Group member %q is not a valid user ID", member.Value)
+		}
+
+		if _, err := models.FindUserByID(tx, id); err != nil {
+			if models.IsNotFoundError(err) {
+				return nil, newSCIMError(http.StatusBadRequest, scimTypeInvalidValue, "Group member %q does not exist", member.Value)
+			}
+			return nil, apierrors.NewInternalServerError("Database error finding user").WithInternalError(err)
+		}
+
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// checkSCIMGroupDisplayName makes sure no group other than the given one uses
+// displayName.
+func checkSCIMGroupDisplayName(tx *storage.Connection, displayName string, group *models.SCIMGroup) error {
+	if displayName == "" {
+		return newSCIMError(http.StatusBadRequest, scimTypeInvalidValue, "displayName is required")
+	}
+
+	existing, _, err := models.FindSCIMGroups(tx, displayName, 0, 1)
+	if err != nil {
+		return apierrors.NewInternalServerError("Database error finding SCIM groups").WithInternalError(err)
+	}
+
+	if len(existing) > 0 && (group == nil || existing[0].ID != group.ID) {
+		return newSCIMError(http.StatusConflict, scimTypeUniqueness, "A group with this displayName already exists")
+	}
+
+	return nil
+}
+
+func (a *API) scimGroupsCreate(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	db := a.db.WithContext(ctx)
+
+	params := &SCIMGroupParams{}
+	if err := retrieveRequestParams(r, params); err != nil {
+		return err
+	}
+
+	var resource *SCIMGroup
+	err := db.Transaction(func(tx *storage.Connection) error {
+		if terr := checkSCIMGroupDisplayName(tx, params.DisplayName, nil); terr != nil {
+			return terr
+		}
+
+		memberIDs, terr := scimMemberIDs(tx, params.Members)
+		if terr != nil {
+			return terr
+		}
+
+		var externalID *string
+		if params.ExternalID != "" {
+			externalID = &params.ExternalID
+		}
+
+		group := models.NewSCIMGroup(params.DisplayName, externalID)
+		if terr := tx.Create(group); terr != nil {
+			return apierrors.NewInternalServerError("Database error creating SCIM group").WithInternalError(terr)
+		}
+
+		if terr := group.AddMembers(tx, memberIDs); terr != nil {
+			return apierrors.NewInternalServerError("Database error adding SCIM group members").WithInternalError(terr)
+		}
+
+		resource, terr = a.loadSCIMGroupResource(tx, group)
+		return terr
+	})
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Location", resource.Meta.Location)
+	return sendSCIM(w, http.StatusCreated, resource)
+}
+
+func (a *API) scimGroupGet(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	db := a.db.WithContext(ctx)
+	group := getSCIMGroup(ctx)
+
+	resource, err := a.loadSCIMGroupResource(db, group)
+	if err != nil {
+		return err
+	}
+
+	return sendSCIM(w, http.StatusOK, resource)
+}
+
+func (a *API) scimGroupReplace(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	db := a.db.WithContext(ctx)
+	group := getSCIMGroup(ctx)
+
+	params := &SCIMGroupParams{}
+	if err := retrieveRequestParams(r, params); err != nil {
+		return err
+	}
+
+	var resource *SCIMGroup
+	err := db.Transaction(func(tx *storage.Connection) error {
+		if terr := checkSCIMGroupDisplayName(tx, params.DisplayName, group); terr != nil {
+			return terr
+		}
+
+		memberIDs, terr := scimMemberIDs(tx, params.Members)
+		if terr != nil {
+			return terr
+		}
+
+		group.DisplayName = params.DisplayName
+		group.ExternalID = nil
+		if params.ExternalID != "" {
+			group.ExternalID = &params.ExternalID
+		}
+
+		if terr := tx.Update(group); terr != nil {
+			return apierrors.NewInternalServerError("Database error updating SCIM group").WithInternalError(terr)
+		}
+
+		if terr := group.SetMembers(tx, memberIDs); terr != nil {
+			return apierrors.NewInternalServerError("Database error updating SCIM group members").WithInternalError(terr)
+		}
+
+		resource, terr = a.loadSCIMGroupResource(tx, group)
+		return terr
+	})
+	if err != nil {
+		return err
+	}
+
+	return sendSCIM(w, http.StatusOK, resource)
+}
+
+func (a *API) scimGroupPatch(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	db := a.db.WithContext(ctx)
+	group := getSCIMGroup(ctx)
+
+	patch := &SCIMPatchParams{}
+	if err := retrieveRequestParams(r, patch); err != nil {
+		return err
+	}
+
+	var resource *SCIMGroup
+	err := db.Transaction(func(tx *storage.Connection) error {
+		for _, operation := range patch.Operations {
+			if terr := applySCIMGroupOperation(tx, group, operation); terr != nil {
+				return terr
+			}
+		}
+
+		if terr := checkSCIMGroupDisplayName(tx, group.DisplayName, group); terr != nil {
+			return terr
+		}
+
+		if terr := tx.Update(group); terr != nil {
+			return apierrors.NewInternalServerError("Database error updating SCIM group").WithInternalError(terr)
+		}
+
+		var terr error
+		resource, terr = a.loadSCIMGroupResource(tx, group)
+		return terr
+	})
+	if err != nil {
+		return err
+	}
+
+	return sendSCIM(w, http.StatusOK, resource)
+}
+
+var scimMemberPathRegexp = regexp.MustCompile(`^(?i:members)\[\s*(?i:value)\s+(?i:eq)\s+"([^"]*)"\s*\]$`)
+
+// applySCIMGroupOperation applies a single PATCH operation to a group.
+// Membership changes are written immediately while attribute changes are
+// left for the caller to save.
+func applySCIMGroupOperation(tx *storage.Connection, group *models.SCIMGroup, operation SCIMPatchOperation) error {
+	op := strings.ToLower(operation.Op)
+	if op != "add" && op != "replace" && op != "remove" {
+		return newSCIMError(http.StatusBadRequest, scimTypeInvalidSyntax, "Unsupported PATCH operation %q", operation.Op)
+	}
+
+	if matches := scimMemberPathRegexp.FindStringSubmatch(operation.Path); matches != nil {
+		if op != "remove" {
+			return newSCIMError(http.StatusBadRequest, scimTypeInvalidPath, "Filtered member paths are only supported for remove operations")
+		}
+		id, err := uuid.FromString(matches[1])
+		if err != nil {
+			return newSCIMError(http.StatusBadRequest, scimTypeInvalidValue, "Group member %q is not a valid user ID", matches[1])
+		}
+		return group.RemoveMembers(tx, []uuid.UUID{id})
+	}
+
+	switch strings.ToLower(operation.Path) {
+	case "":
+		if op == "remove" {
+			return newSCIMError(http.StatusBadRequest, scimTypeNoTarget, "PATCH remove operations require a path")
+		}
+
+		var params SCIMGroupParams
+		if err := json.Unmarshal(operation.Value, &params); err != nil {
+			return newSCIMError(http.StatusBadRequest, scimTypeInvalidValue, "PATCH operations without a path require an object value")
+		}
+
+		if params.DisplayName != "" {
+			group.DisplayName = params.DisplayName
+		}
+		if params.ExternalID != "" {
+			group.ExternalID = &params.ExternalID
+		}
+		if params.Members != nil {
+			return applySCIMGroupMembers(tx, group, op, params.Members)
+		}
+		return nil
+
+	case "displayname":
+		if op == "remove" {
+			return newSCIMError(http.StatusBadRequest, scimTypeInvalidValue, "displayName is required")
+		}
+		if err := json.Unmarshal(operation.Value, &group.DisplayName); err != nil {
+			return newSCIMError(http.StatusBadRequest, scimTypeInvalidValue, "displayName must be a string")
+		}
+		return nil
+
+	case "externalid":
+		if op == "remove" {
+			group.ExternalID = nil
+			return nil
+		}
+		var externalID string
+		if err := json.Unmarshal(operation.Value, &externalID); err != nil {
+			return newSCIMError(http.StatusBadRequest, scimTypeInvalidValue, "externalId must be a string")
+		}
+		group.ExternalID = &externalID
+		return nil
+
+	case "members":
+		var members []SCIMMultiValued
+		if len(operation.Value) > 0 {
+			if err := json.Unmarshal(operation.Value, &members); err != nil {
+				return newSCIMError(http.StatusBadRequest, scimTypeInvalidValue, "members must be a list of users")
+			}
+		}
+		if op == "remove" && len(members) == 0 {
+			return group.SetMembers(tx, nil)
+		}
+		return applySCIMGroupMembers(tx, group, op, members)
+	}
+
+	return newSCIMError(http.StatusBadRequest, scimTypeInvalidPath, "Unsupported path %q", operation.Path)
+}
+
+func applySCIMGroupMembers(tx *storage.Connection, group *models.SCIMGroup, op string, members []SCIMMultiValued) error {
+	if op == "remove" {
+		ids := make([]uuid.UUID, 0, len(members))
+		for _, member := range members {
+			id, err := uuid.FromString(member.Value)
+			if err != nil {
+				return newSCIMError(http.StatusBadRequest, scimTypeInvalidValue, "Group member %q is not a valid user ID", member.Value)
+			}
+			ids = append(ids, id)
+		}
+		return group.RemoveMembers(tx, ids)
+	}
+
+	ids, err := scimMemberIDs(tx, members)
+	if err != nil {
+		return err
+	}
+
+	if op == "replace" {
+		return group.SetMembers(tx, ids)
+	}
+	return group.AddMembers(tx, ids)
+}
+
+func (a *API) scimGroupDelete(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	db := a.db.WithContext(ctx)
+	group := getSCIMGroup(ctx)
+
+	if err := db.Destroy(group); err != nil {
+		return apierrors.NewInternalServerError("Database error deleting SCIM group").WithInternalError(err)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
@@ -0,0 +1,153 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/supabase/auth/internal/api/apierrors"
+)
+
+func TestParseSCIMFilter(t *testing.T) {
+	cases := []struct {
+		filter    string
+		attribute string
+		value     string
+		err       bool
+	}{
+		{filter: `userName eq "jane@example.com"`, attribute: "userName", value: "jane@example.com"},
+		{filter: ` emails.value EQ "jane@example.com" `, attribute: "emails.value", value: "jane@example.com"},
+		{filter: `externalId eq "a \"quoted\" id"`, attribute: "externalId", value: `a "quoted" id`},
+		{filter: `userName sw "jane"`, err: true},
+		{filter: `userName eq "a" and externalId eq "b"`, err: true},
+		{filter: `userName eq jane`, err: true},
+	}
+
+	for _, c := range cases {
+		attribute, value, err := parseSCIMFilter(c.filter)
+		if c.err {
+			require.Error(t, err, c.filter)
+			require.Equal(t, scimTypeInvalidFilter, err.(*SCIMError).ScimType)
+			continue
+		}
+
+		require.NoError(t, err, c.filter)
+		require.Equal(t, c.attribute, attribute)
+		require.Equal(t, c.value, value)
+	}
+}
+
+func TestSCIMListParams(t *testing.T) {
+	cases := []struct {
+		query  string
+		offset int
+		limit  int
+		err    bool
+	}{
+		{query: "", offset: 0, limit: scimMaxResults},
+		{query: "startIndex=11&count=10", offset: 10, limit: 10},
+		{query: "startIndex=-5&count=1000", offset: 0, limit: scimMaxResults},
+		{query: "count=0", offset: 0, limit: 0},
+		{query: "startIndex=abc", err: true},
+	}
+
+	for _, c := range cases {
+		req := httptest.NewRequest(http.MethodGet, "/scim/v2/Users?"+c.query, nil)
+		offset, limit, err := scimListParams(req)
+		if c.err {
+			require.Error(t, err, c.query)
+			continue
+		}
+
+		require.NoError(t, err, c.query)
+		require.Equal(t, c.offset, offset, c.query)
+		require.Equal(t, c.limit, limit, c.query)
+	}
+}
+
+func TestApplySCIMPatch(t *testing.T) {
+	resource := map[string]interface{}{
+		"userName":   "jane@example.com",
+		"externalId": "00u1",
+		"active":     true,
+		"name": map[string]interface{}{
+			"givenName":  "Jane",
+			"familyName": "Doe",
+		},
+	}
+
+	operations := []SCIMPatchOperation{
+		{Op: "Replace", Path: "name.givenName", Value: json.RawMessage(`"Janet"`)},
+		{Op: "replace", Path: "active", Value: json.RawMessage(`"False"`)},
+		{Op: "remove", Path: "externalId"},
+		{Op: "add", Path: `phoneNumbers[type eq "work"].value`, Value: json.RawMessage(`"+15555550100"`)},
+		{Op: "replace", Value: json.RawMessage(`{"USERNAME": "janet@example.com", "name.formatted": "Janet Doe"}`)},
+	}
+
+	require.NoError(t, applySCIMPatch(resource, operations))
+
+	b, err := json.Marshal(resource)
+	require.NoError(t, err)
+
+	var params SCIMUserParams
+	require.NoError(t, json.Unmarshal(b, &params))
+
+	require.Equal(t, "janet@example.com", params.UserName)
+	require.Equal(t, "", params.ExternalID)
+	require.NotNil(t, params.Active)
+	require.False(t, *params.Active)
+	require.Equal(t, &SCIMName{GivenName: "Janet", FamilyName: "Doe", Formatted: "Janet Doe"}, params.Name)
+	require.Equal(t, "+15555550100", primaryValue(params.PhoneNumbers))
+
+	require.Error(t, applySCIMPatch(resource, []SCIMPatchOperation{{Op: "move", Path: "userName"}}))
+	require.Error(t, applySCIMPatch(resource, []SCIMPatchOperation{{Op: "remove"}}))
+	require.Error(t, applySCIMPatch(resource, []SCIMPatchOperation{{Op: "replace", Value: json.RawMessage(`"value"`)}}))
+}
+
+func TestSCIMUserParamsMapping(t *testing.T) {
+	params := &SCIMUserParams{
+		UserName:   "jdoe",
+		ExternalID: "00u1",
+		Name:       &SCIMName{GivenName: "Jane", FamilyName: "Doe"},
+		Emails: []SCIMMultiValued{
+			{Value: "jane.personal@example.com"},
+			{Value: "jane@example.com", Primary: true},
+		},
+	}
+
+	require.Equal(t, "jane@example.com", params.email())
+	require.Equal(t, map[string]interface{}{
+		"given_name":  "Jane",
+		"family_name": "Doe",
+		"full_name":   "Jane Doe",
+	}, params.userMetaData())
+	require.Equal(t, map[string]interface{}{
+		scimExternalIDKey: "00u1",
+		scimUserNameKey:   "jdoe",
+	}, params.appMetaData(params.email()))
+
+	params = &SCIMUserParams{UserName: "jane@example.com"}
+	require.Equal(t, "jane@example.com", params.email())
+	require.Equal(t, map[string]interface{}{
+		scimExternalIDKey: nil,
+		scimUserNameKey:   nil,
+	}, params.appMetaData(params.email()))
+}
+
+func TestToSCIMError(t *testing.T) {
+	err := toSCIMError(apierrors.NewUnprocessableEntityError(apierrors.ErrorCodeEmailExists, "exists")).(*SCIMError)
+	require.Equal(t, http.StatusConflict, err.HTTPStatus)
+	require.Equal(t, "409", err.Status)
+	require.Equal(t, scimTypeUniqueness, err.ScimType)
+
+	err = toSCIMError(apierrors.NewBadRequestError(apierrors.ErrorCodeValidationFailed, "invalid")).(*SCIMError)
+	require.Equal(t, http.StatusBadRequest, err.HTTPStatus)
+	require.Equal(t, scimTypeInvalidValue, err.ScimType)
+	require.Equal(t, "invalid", err.Detail)
+
+	err = toSCIMError(apierrors.NewInternalServerError("oops")).(*SCIMError)
+	require.Equal(t, http.StatusInternalServerError, err.HTTPStatus)
+	require.Equal(t, []string{scimSchemaError}, err.Schemas)
+}
@@ -0,0 +1,99 @@
+package api
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/supabase/auth/internal/api/apierrors"
+	"github.com/supabase/auth/internal/conf"
+	"github.com/supabase/auth/internal/crypto"
+)
+
+// csrfHeaderName is the header the CSRF token of a cookie session is
+// returned in, and has to be sent back in to refresh the session.
+const csrfHeaderName = "X-CSRF-Token"
+
+// csrfCookieSuffix is appended to the name of the refresh token cookie for
+// the name of the cookie holding the CSRF token.
+const csrfCookieSuffix = "-csrf"
+
+// useSessionCookie reports whether the refresh token of the request is kept
+// in a cookie.
+func (a *API) useSessionCookie(r *http.Request) bool {
+	return a.config.Sessions.Cookie.Enabled && r.Header.Get(useCookieHeader) != ""
+}
+
+// newSessionCookie returns a cookie of a cookie session. Only the refresh
+// token cookie is HttpOnly, the CSRF token cookie is read by the scripts of
+// the application so that they can send it back after a page reload.
+func newSessionCookie(config *conf.SessionCookieConfiguration, name, value string, maxAge int, httpOnly bool) *http.Cookie {
+	sameSite := http.SameSiteLaxMode
+	switch strings.ToLower(config.SameSite) {
+	case "strict":
+		sameSite = http.SameSiteStrictMode
+	case "none":
+		sameSite = http.SameSiteNoneMode
+	}
+
+	return &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Domain:   config.Domain,
+		Path:     config.Path,
+		MaxAge:   maxAge,
+		HttpOnly: httpOnly,
+		Secure:   true,
+		SameSite: sameSite,
+	}
+}
+
+// sendTokenResponse sends the token response. When the request uses a
+// cookie session, the refresh token is moved from the response to a cookie
+// and a new CSRF token is returned in the X-CSRF-Token header and in a
+// cookie scripts can read.
+func (a *API) sendTokenResponse(w http.ResponseWriter, r *http.Request, token *AccessTokenResponse) error {
+	if a.useSessionCookie(r) && token.RefreshToken != "" {
+		config := &a.config.Sessions.Cookie
+		maxAge := int(config.MaxAge.Seconds())
+		csrfToken := crypto.SecureAlphanumeric(32)
+
+		http.SetCookie(w, newSessionCookie(config, config.Name, token.RefreshToken, maxAge, true))
+		http.SetCookie(w, newSessionCookie(config, config.Name+csrfCookieSuffix, csrfToken, maxAge, false))
+		w.Header().Set(csrfHeaderName, csrfToken)
+
+		response := *token
+		response.RefreshToken = ""
+		token = &response
+	}
+
+	return sendJSON(w, http.StatusOK, token)
+}
+
+// sessionCookieRefreshToken returns the refresh token in the cookie of the
+// request, or an empty string when there is none. The CSRF token returned
+// with the cookie has to be sent in the X-CSRF-Token header, as other sites
+// can make browsers send the cookies but cannot read them.
+func (a *API) sessionCookieRefreshToken(r *http.Request) (string, error) {
+	config := &a.config.Sessions.Cookie
+
+	refreshTokenCookie, err := r.Cookie(config.Name)
+	if err != nil {
+		return "", nil
+	}
+
+	csrfToken := r.Header.Get(csrfHeaderName)
+	csrfCookie, err := r.Cookie(config.Name + csrfCookieSuffix)
+	if err != nil || csrfToken == "" || subtle.ConstantTimeCompare([]byte(csrfToken), []byte(csrfCookie.Value)) != 1 {
+		return "", apierrors.NewForbiddenError(apierrors.ErrorCodeBadCSRFToken, "CSRF token is missing or invalid")
+	}
+
+	return refreshTokenCookie.Value, nil
+}
+
+// clearSessionCookies removes the cookies of a cookie session.
+func (a *API) clearSessionCookies(w http.ResponseWriter) {
+	config := &a.config.Sessions.Cookie
+	http.SetCookie(w, newSessionCookie(config, config.Name, "", -1, true))
+	http.SetCookie(w, newSessionCookie(config, config.Name+csrfCookieSuffix, "", -1, false))
+}
@@ -0,0 +1,182 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/gofrs/uuid"
+	"github.com/supabase/auth/internal/api/apierrors"
+	"github.com/supabase/auth/internal/models"
+	"github.com/supabase/auth/internal/storage"
+)
+
+// SessionResponse describes one of the user's active sessions.
+type SessionResponse struct {
+	ID          uuid.UUID  `json:"id"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	RefreshedAt time.Time  `json:"refreshed_at"`
+	NotAfter    *time.Time `json:"not_after,omitempty"`
+	UserAgent   string     `json:"user_agent,omitempty"`
+	IP          string     `json:"ip,omitempty"`
+	Country     string     `json:"country,omitempty"`
+	City        string     `json:"city,omitempty"`
+	RiskScore   *int       `json:"risk_score,omitempty"`
+	AAL         string     `json:"aal"`
+	Current     bool       `json:"current"`
+
+	// Impersonator is the admin impersonating the user in the session.
+	Impersonator *models.SessionActor `json:"impersonator,omitempty"`
+}
+
+func newSessionResponse(session *models.Session, current *models.Session) *SessionResponse {
+	resp := &SessionResponse{
+		ID:          session.ID,
+		CreatedAt:   session.CreatedAt,
+		UpdatedAt:   session.UpdatedAt,
+		RefreshedAt: session.LastRefreshedAt(nil),
+		NotAfter:    session.NotAfter,
+		AAL:         session.GetAAL(),
+		RiskScore:   session.RiskScore,
+		Current:     current != nil && current.ID == session.ID,
+
+		Impersonator: session.Actor(),
+	}
+
+	if session.UserAgent != nil {
+		resp.UserAgent = *session.UserAgent
+	}
+
+	if session.IP != nil {
+		resp.IP = *session.IP
+	}
+
+	if session.Country != nil {
+		resp.Country = *session.Country
+	}
+
+	if session.City != nil {
+		resp.City = *session.City
+	}
+
+	return resp
+}
+
+// findActiveSessions returns the sessions of the user that are still valid,
+// marking the current one.
+func (a *API) findActiveSessions(db *storage.Connection, user *models.User, current *models.Session) ([]*SessionResponse, error) {
+	config := a.config
+
+	sessions, err := models.FindAllSessionsForUser(db, user.ID, false)
+	if err != nil {
+		return nil, apierrors.NewInternalServerError("Database error finding sessions").WithInternalError(err)
+	}
+
+	validityConfig := models.NewSessionValidityConfig(&config.Sessions, user.Role)
+
+	now := time.Now()
+	highestAAL := user.HighestPossibleAAL()
+
+	resp := []*SessionResponse{}
+	for _, session := range sessions {
+		if session.CheckValidity(validityConfig, now, nil, highestAAL) != models.SessionValid {
+			continue
+		}
+
+		resp = append(resp, newSessionResponse(session, current))
+	}
+
+	return resp, nil
+}
+
+// ListSessions returns the active sessions of the authenticated user.
+func (a *API) ListSessions(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	db := a.db.WithContext(ctx)
+
+	resp, err := a.findActiveSessions(db, getUser(ctx), getSession(ctx))
+	if err != nil {
+		return err
+	}
+
+	return sendJSON(w, http.StatusOK, map[string]interface{}{
+		"sessions": resp,
+	})
+}
+
+// RevokeSession signs out one of the authenticated user's sessions.
+func (a *API) RevokeSession(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	db := a.db.WithContext(ctx)
+	config := a.config
+
+	user := getUser(ctx)
+
+	sessionID, err := uuid.FromString(chi.URLParam(r, "session_id"))
+	if err != nil {
+		return apierrors.NewNotFoundError(apierrors.ErrorCodeValidationFailed, "session_id must be an UUID")
+	}
+
+	session, err := models.FindSessionByID(db, sessionID, false)
+	if err != nil {
+		if models.IsNotFoundError(err) {
+			return apierrors.NewNotFoundError(apierrors.ErrorCodeSessionNotFound, "Session not found")
+		}
+		return apierrors.NewInternalServerError("Database error finding session").WithInternalError(err)
+	}
+
+	// sessions of other users are reported as missing so that session IDs
+	// cannot be probed
+	if session.UserID != user.ID {
+		return apierrors.NewNotFoundError(apierrors.ErrorCodeSessionNotFound, "Session not found")
+	}
+
+	err = db.Transaction(func(tx *storage.Connection) error {
+		if terr := models.NewAuditLogEntry(config.AuditLog, r, tx, user, models.SessionRevokedAction, "", map[string]interface{}{
+			"session_id": session.ID,
+		}); terr != nil {
+			return terr
+		}
+
+		return models.LogoutSession(tx, session.ID)
+	})
+	if err != nil {
+		return apierrors.NewInternalServerError("Error revoking session").WithInternalError(err)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+
+	return nil
+}
+
+// RevokeOtherSessions signs out all of the authenticated user's sessions
+// except the one used to make the request.
+func (a *API) RevokeOtherSessions(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	db := a.db.WithContext(ctx)
+	config := a.config
+
+	user := getUser(ctx)
+	current := getSession(ctx)
+	if current == nil {
+		return apierrors.NewBadRequestError(apierrors.ErrorCodeSessionNotFound, "Revoking other sessions requires a session")
+	}
+
+	err := db.Transaction(func(tx *storage.Connection) error {
+		if terr := models.NewAuditLogEntry(config.AuditLog, r, tx, user, models.SessionRevokedAction, "", map[string]interface{}{
+			"scope": LogoutOthers,
+		}); terr != nil {
+			return terr
+		}
+
+		return models.LogoutAllExceptMe(tx, current.ID, user.ID)
+	})
+	if err != nil {
+		return apierrors.NewInternalServerError("Error revoking sessions").WithInternalError(err)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+
+	return nil
+}
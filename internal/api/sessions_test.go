@@ -0,0 +1,126 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"github.com/supabase/auth/internal/conf"
+	"github.com/supabase/auth/internal/models"
+)
+
+type SessionsTestSuite struct {
+	suite.Suite
+	API    *API
+	Config *conf.GlobalConfiguration
+
+	user     *models.User
+	sessions []*models.Session
+	token    string
+}
+
+func TestSessions(t *testing.T) {
+	api, config, err := setupAPIForTest()
+	require.NoError(t, err)
+
+	ts := &SessionsTestSuite{
+		API:    api,
+		Config: config,
+	}
+	defer api.db.Close()
+
+	suite.Run(t, ts)
+}
+
+func (ts *SessionsTestSuite) SetupTest() {
+	models.TruncateAll(ts.API.db)
+
+	u, err := models.NewUser("", "test@example.com", "password", ts.Config.JWT.Aud, nil)
+	require.NoError(ts.T(), err, "Error creating test user model")
+	require.NoError(ts.T(), ts.API.db.Create(u), "Error saving new test user")
+	ts.user = u
+
+	ts.sessions = nil
+	for i := 0; i < 3; i++ {
+		s, err := models.NewSession(u.ID, nil)
+		require.NoError(ts.T(), err)
+		require.NoError(ts.T(), ts.API.db.Create(s))
+		ts.sessions = append(ts.sessions, s)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/token?grant_type=password", nil)
+	ts.token, _, err = ts.API.generateAccessToken(req, ts.API.db, u, &ts.sessions[0].ID, models.PasswordGrant)
+	require.NoError(ts.T(), err)
+}
+
+func (ts *SessionsTestSuite) do(method, path string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, "http://localhost"+path, nil)
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", ts.token))
+	w := httptest.NewRecorder()
+
+	ts.API.handler.ServeHTTP(w, req)
+
+	return w
+}
+
+func (ts *SessionsTestSuite) TestListSessions() {
+	w := ts.do(http.MethodGet, "/user/sessions")
+	require.Equal(ts.T(), http.StatusOK, w.Code)
+
+	var data struct {
+		Sessions []SessionResponse `json:"sessions"`
+	}
+	require.NoError(ts.T(), json.NewDecoder(w.Body).Decode(&data))
+	require.Len(ts.T(), data.Sessions, 3)
+
+	for _, session := range data.Sessions {
+		require.Equal(ts.T(), session.ID == ts.sessions[0].ID, session.Current)
+		require.Equal(ts.T(), models.AAL1.String(), session.AAL)
+	}
+}
+
+func (ts *SessionsTestSuite) TestRevokeSession() {
+	w := ts.do(http.MethodDelete, "/user/sessions/"+ts.sessions[1].ID.String())
+	require.Equal(ts.T(), http.StatusNoContent, w.Code)
+
+	_, err := models.FindSessionByID(ts.API.db, ts.sessions[1].ID, false)
+	require.True(ts.T(), models.IsNotFoundError(err))
+
+	sessions, err := models.FindAllSessionsForUser(ts.API.db, ts.user.ID, false)
+	require.NoError(ts.T(), err)
+	require.Len(ts.T(), sessions, 2)
+
+	// already revoked
+	w = ts.do(http.MethodDelete, "/user/sessions/"+ts.sessions[1].ID.String())
+	require.Equal(ts.T(), http.StatusNotFound, w.Code)
+}
+
+func (ts *SessionsTestSuite) TestRevokeSessionOfOtherUser() {
+	other, err := models.NewUser("", "other@example.com", "password", ts.Config.JWT.Aud, nil)
+	require.NoError(ts.T(), err)
+	require.NoError(ts.T(), ts.API.db.Create(other))
+
+	s, err := models.NewSession(other.ID, nil)
+	require.NoError(ts.T(), err)
+	require.NoError(ts.T(), ts.API.db.Create(s))
+
+	w := ts.do(http.MethodDelete, "/user/sessions/"+s.ID.String())
+	require.Equal(ts.T(), http.StatusNotFound, w.Code)
+
+	_, err = models.FindSessionByID(ts.API.db, s.ID, false)
+	require.NoError(ts.T(), err)
+}
+
+func (ts *SessionsTestSuite) TestRevokeOtherSessions() {
+	w := ts.do(http.MethodDelete, "/user/sessions")
+	require.Equal(ts.T(), http.StatusNoContent, w.Code)
+
+	sessions, err := models.FindAllSessionsForUser(ts.API.db, ts.user.ID, false)
+	require.NoError(ts.T(), err)
+	require.Len(ts.T(), sessions, 1)
+	require.Equal(ts.T(), ts.sessions[0].ID, sessions[0].ID)
+}
@@ -1,12 +1,17 @@
 package api
 
-import "net/http"
+import (
+	"net/http"
+
+	"github.com/supabase/auth/internal/conf"
+)
 
 type ProviderSettings struct {
 	AnonymousUsers bool `json:"anonymous_users"`
 	Apple          bool `json:"apple"`
 	Azure          bool `json:"azure"`
 	Bitbucket      bool `json:"bitbucket"`
+	Custom         bool `json:"custom"`
 	Discord        bool `json:"discord"`
 	Facebook       bool `json:"facebook"`
 	Snapchat       bool `json:"snapchat"`
@@ -23,15 +28,24 @@ type ProviderSettings struct {
 	Linkedin       bool `json:"linkedin"`
 	LinkedinOIDC   bool `json:"linkedin_oidc"`
 	Notion         bool `json:"notion"`
+	QQ             bool `json:"qq"`
 	Spotify        bool `json:"spotify"`
 	Slack          bool `json:"slack"`
 	SlackOIDC      bool `json:"slack_oidc"`
+	Steam          bool `json:"steam"`
+	WeChat         bool `json:"wechat"`
+	WeChatOA       bool `json:"wechat_official_account"`
 	WorkOS         bool `json:"workos"`
 	Twitch         bool `json:"twitch"`
 	Twitter        bool `json:"twitter"`
+	X              bool `json:"x"`
 	Email          bool `json:"email"`
 	Phone          bool `json:"phone"`
 	Zoom           bool `json:"zoom"`
+
+	// Connections maps the slugs of the external connections to whether
+	// they are enabled.
+	Connections map[string]bool `json:"connections,omitempty"`
 }
 
 type Settings struct {
@@ -52,6 +66,7 @@ func (a *API) Settings(w http.ResponseWriter, r *http.Request) error {
 			Apple:          config.External.Apple.Enabled,
 			Azure:          config.External.Azure.Enabled,
 			Bitbucket:      config.External.Bitbucket.Enabled,
+			Custom:         config.External.Custom.Enabled,
 			Discord:        config.External.Discord.Enabled,
 			Facebook:       config.External.Facebook.Enabled,
 			Snapchat:       config.External.Snapchat.Enabled,
@@ -68,15 +83,21 @@ func (a *API) Settings(w http.ResponseWriter, r *http.Request) error {
 			Linkedin:       config.External.Linkedin.Enabled,
 			LinkedinOIDC:   config.External.LinkedinOIDC.Enabled,
 			Notion:         config.External.Notion.Enabled,
+			QQ:             config.External.QQ.Enabled,
 			Spotify:        config.External.Spotify.Enabled,
 			Slack:          config.External.Slack.Enabled,
 			SlackOIDC:      config.External.SlackOIDC.Enabled,
+			Steam:          config.External.Steam.Enabled,
 			Twitch:         config.External.Twitch.Enabled,
 			Twitter:        config.External.Twitter.Enabled,
+			X:              config.External.X.Enabled,
+			WeChat:         config.External.WeChat.Enabled,
+			WeChatOA:       config.External.WeChatOfficialAccount.Enabled,
 			WorkOS:         config.External.WorkOS.Enabled,
 			Email:          config.External.Email.Enabled,
 			Phone:          config.External.Phone.Enabled,
 			Zoom:           config.External.Zoom.Enabled,
+			Connections:    connectionSettings(config.External.Connections),
 		},
 		DisableSignup:     config.DisableSignup,
 		MailerAutoconfirm: config.Mailer.Autoconfirm,
@@ -85,3 +106,15 @@ func (a *API) Settings(w http.ResponseWriter, r *http.Request) error {
 		SAMLEnabled:       config.SAML.Enabled,
 	})
 }
+
+func connectionSettings(connections conf.ExternalConnections) map[string]bool {
+	if len(connections) == 0 {
+		return nil
+	}
+
+	settings := make(map[string]bool, len(connections))
+	for _, conn := range connections {
+		settings[conn.Slug] = conn.Enabled
+	}
+	return settings
+}
@@ -30,13 +30,16 @@ func TestSettings_DefaultProviders(t *testing.T) {
 	require.True(t, p.Email)
 	require.True(t, p.Azure)
 	require.True(t, p.Bitbucket)
+	require.True(t, p.Custom)
 	require.True(t, p.Discord)
 	require.True(t, p.Facebook)
 	require.True(t, p.Snapchat)
 	require.True(t, p.Notion)
+	require.True(t, p.QQ)
 	require.True(t, p.Spotify)
 	require.True(t, p.Slack)
 	require.True(t, p.SlackOIDC)
+	require.True(t, p.Steam)
 	require.True(t, p.Google)
 	require.True(t, p.Kakao)
 	require.True(t, p.Keycloak)
@@ -45,7 +48,10 @@ func TestSettings_DefaultProviders(t *testing.T) {
 	require.True(t, p.GitHub)
 	require.True(t, p.GitLab)
 	require.True(t, p.Twitch)
+	require.True(t, p.WeChat)
+	require.True(t, p.WeChatOA)
 	require.True(t, p.WorkOS)
+	require.True(t, p.X)
 	require.True(t, p.Zoom)
 	require.True(t, p.GenericOIDC1)
 	require.True(t, p.GenericOIDC2)
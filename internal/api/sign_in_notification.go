@@ -0,0 +1,210 @@
+package api
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/gofrs/uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/supabase/auth/internal/api/apierrors"
+	mail "github.com/supabase/auth/internal/mailer"
+	"github.com/supabase/auth/internal/models"
+	"github.com/supabase/auth/internal/storage"
+)
+
+// sessionReportExpiry is how long the link of a new sign-in notification
+// can sign out the session it was sent for.
+const sessionReportExpiry = 7 * 24 * time.Hour
+
+// sessionReportedMessage is the message users are redirected with after
+// signing out a session from a new sign-in notification.
+const sessionReportedMessage = "The session has been signed out. Please change your password if you did not sign in."
+
+// sessionReportPage asks users to confirm signing out the session of a new
+// sign-in notification. Opening the link does not sign out the session, as
+// email scanners open the links of emails. The form is posted to the URL of
+// the page, which holds the signature of the link.
+const sessionReportPage = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>Sign out session</title>
+</head>
+<body>
+<h2>Sign out the new session?</h2>
+<p>If you did not sign in to your account from the new location or device, sign out the session and change your password immediately.</p>
+<form method="post">
+<button type="submit">Sign out session</button>
+</form>
+</body>
+</html>
+`
+
+// notifyNewSignIn records the location and device a new session was signed
+// in from, and notifies the user when they have not signed in from it
+// before. It is called by the token service when sessions are issued. The
+// locations are recorded for risk scoring as well.
+func (a *API) notifyNewSignIn(r *http.Request, tx *storage.Connection, user *models.User, session *models.Session) error {
+	config := a.config
+
+	if !config.Mailer.Notifications.NewSignInEnabled && !config.Risk.Enabled {
+		return nil
+	}
+
+	isNew, err := models.RecordSignInLocation(tx, session)
+	if err != nil {
+		return apierrors.NewInternalServerError("Database error recording sign-in location").WithInternalError(err)
+	}
+	if !isNew || !config.Mailer.Notifications.NewSignInEnabled || user.GetEmail() == "" {
+		return nil
+	}
+
+	signIn := &mail.SignIn{
+		RevokeURL: a.sessionReportURL(r.Context(), session),
+	}
+	if session.IP != nil {
+		signIn.IP = *session.IP
+	}
+	if session.UserAgent != nil {
+		signIn.UserAgent = *session.UserAgent
+	}
+	if session.Country != nil {
+		signIn.Country = *session.Country
+	}
+	if session.City != nil {
+		signIn.City = *session.City
+	}
+
+	if err := a.sendNewSignInNotification(r, tx, user, signIn); err != nil {
+		// Log the error but don't fail the sign-in
+		logrus.WithError(err).Warn("Unable to send new sign-in notification email")
+	}
+
+	return nil
+}
+
+// sessionReportURL returns the link of a new sign-in notification, which
+// signs out the session. It is signed with the link secret of the
+// notifications.
+func (a *API) sessionReportURL(ctx context.Context, session *models.Session) string {
+	expires := strconv.FormatInt(time.Now().Add(sessionReportExpiry).Unix(), 10)
+
+	u := getExternalHost(ctx).JoinPath("user", "sessions", session.ID.String(), "report")
+	u.RawQuery = url.Values{
+		"expires":   {expires},
+		"signature": {a.sessionReportSignature(session.ID, expires)},
+	}.Encode()
+
+	return u.String()
+}
+
+func (a *API) sessionReportSignature(id uuid.UUID, expires string) string {
+	mac := hmac.New(sha256.New, []byte(a.config.Mailer.Notifications.NewSignInLinkSecret))
+	mac.Write([]byte("session-report:" + id.String() + ":" + expires))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// verifySessionReportLink returns the ID of the session of a new sign-in
+// notification link, when the link is signed and has not expired.
+func (a *API) verifySessionReportLink(r *http.Request) (uuid.UUID, error) {
+	notFound := apierrors.NewNotFoundError(apierrors.ErrorCodeSessionNotFound, "Session not found or link expired")
+
+	if a.config.Mailer.Notifications.NewSignInLinkSecret == "" {
+		return uuid.Nil, notFound
+	}
+
+	sessionID, err := uuid.FromString(chi.URLParam(r, "session_id"))
+	if err != nil {
+		return uuid.Nil, notFound
+	}
+
+	query := r.URL.Query()
+	expires := query.Get("expires")
+	expiresAt, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil {
+		return uuid.Nil, notFound
+	}
+	if !hmac.Equal([]byte(query.Get("signature")), []byte(a.sessionReportSignature(sessionID, expires))) {
+		return uuid.Nil, notFound
+	}
+	if time.Now().Unix() >= expiresAt {
+		return uuid.Nil, notFound
+	}
+
+	return sessionID, nil
+}
+
+// ReportSessionConfirm shows the page of the link of a new sign-in
+// notification, which asks the user to confirm signing out the session.
+func (a *API) ReportSessionConfirm(w http.ResponseWriter, r *http.Request) error {
+	if _, err := a.verifySessionReportLink(r); err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	// the URL of the page holds the signature of the link
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	w.WriteHeader(http.StatusOK)
+	_, err := w.Write([]byte(sessionReportPage))
+	return err
+}
+
+// ReportSession signs out the session of a new sign-in notification the
+// user did not recognize once they confirmed it, and redirects to the site
+// URL. It is authenticated by the signature of the link rather than an
+// access token, as the link is opened from the email it was sent with.
+func (a *API) ReportSession(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	db := a.db.WithContext(ctx)
+	config := a.config
+
+	sessionID, err := a.verifySessionReportLink(r)
+	if err != nil {
+		return err
+	}
+
+	session, err := models.FindSessionByID(db, sessionID, false)
+	if err != nil && !models.IsNotFoundError(err) {
+		return apierrors.NewInternalServerError("Database error finding session").WithInternalError(err)
+	}
+
+	// a session that is already signed out is reported as signed out, as
+	// the link may be opened more than once
+	if session != nil {
+		user, err := models.FindUserByID(db, session.UserID)
+		if err != nil {
+			return apierrors.NewInternalServerError("Database error finding user").WithInternalError(err)
+		}
+
+		err = db.Transaction(func(tx *storage.Connection) error {
+			if terr := models.NewAuditLogEntry(config.AuditLog, r, tx, user, models.SessionRevokedAction, "", map[string]interface{}{
+				"session_id": session.ID,
+				"reported":   true,
+			}); terr != nil {
+				return terr
+			}
+
+			return models.LogoutSession(tx, session.ID)
+		})
+		if err != nil {
+			return apierrors.NewInternalServerError("Error revoking session").WithInternalError(err)
+		}
+	}
+
+	rurl, err := a.prepRedirectURL(sessionReportedMessage, config.SiteURL, models.ImplicitFlow)
+	if err != nil {
+		return apierrors.NewInternalServerError("Error building redirect URL").WithInternalError(err)
+	}
+
+	http.Redirect(w, r, rurl, http.StatusSeeOther)
+	return nil
+}
@@ -0,0 +1,181 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"github.com/supabase/auth/internal/conf"
+	"github.com/supabase/auth/internal/geoip"
+	"github.com/supabase/auth/internal/mailer/mockclient"
+	"github.com/supabase/auth/internal/models"
+)
+
+type SignInNotificationTestSuite struct {
+	suite.Suite
+	API    *API
+	Config *conf.GlobalConfiguration
+	Mailer *mockclient.MockMailer
+
+	user *models.User
+}
+
+func TestSignInNotification(t *testing.T) {
+	mockMailer := &mockclient.MockMailer{}
+	api, config, err := setupAPIForTest(WithMailer(mockMailer))
+	require.NoError(t, err)
+
+	ts := &SignInNotificationTestSuite{
+		API:    api,
+		Config: config,
+		Mailer: mockMailer,
+	}
+	defer api.db.Close()
+
+	suite.Run(t, ts)
+}
+
+func (ts *SignInNotificationTestSuite) SetupTest() {
+	models.TruncateAll(ts.API.db)
+	ts.Mailer.Reset()
+	ts.Config.Mailer.Notifications.NewSignInEnabled = true
+	ts.Config.Mailer.Notifications.NewSignInLinkSecret = "new-sign-in-link-secret-of-32-chars"
+	ts.API.tokenService.SetLocator(&geoip.Header{
+		CountryHeader: "CF-IPCountry",
+		CityHeader:    "CF-IPCity",
+	})
+
+	u, err := models.NewUser("", "test@example.com", "password", ts.Config.JWT.Aud, nil)
+	require.NoError(ts.T(), err)
+	now := time.Now()
+	u.EmailConfirmedAt = &now
+	require.NoError(ts.T(), ts.API.db.Create(u))
+	ts.user = u
+}
+
+func (ts *SignInNotificationTestSuite) TearDownTest() {
+	ts.Config.Mailer.Notifications.NewSignInEnabled = false
+	ts.Config.Mailer.Notifications.NewSignInLinkSecret = ""
+	ts.API.tokenService.SetLocator(nil)
+}
+
+func (ts *SignInNotificationTestSuite) signIn(userAgent, country, city string) *AccessTokenResponse {
+	var buffer bytes.Buffer
+	require.NoError(ts.T(), json.NewEncoder(&buffer).Encode(map[string]interface{}{
+		"email":    "test@example.com",
+		"password": "password",
+	}))
+	req := httptest.NewRequest(http.MethodPost, "http://localhost/token?grant_type=password", &buffer)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("CF-IPCountry", country)
+	req.Header.Set("CF-IPCity", city)
+	w := httptest.NewRecorder()
+	ts.API.handler.ServeHTTP(w, req)
+	require.Equal(ts.T(), http.StatusOK, w.Code, w.Body.String())
+
+	var token AccessTokenResponse
+	require.NoError(ts.T(), json.NewDecoder(w.Body).Decode(&token))
+	return &token
+}
+
+func (ts *SignInNotificationTestSuite) report(method, link string) *httptest.ResponseRecorder {
+	u, err := url.Parse(link)
+	require.NoError(ts.T(), err)
+
+	req := httptest.NewRequest(method, "http://localhost"+u.RequestURI(), nil)
+	w := httptest.NewRecorder()
+	ts.API.handler.ServeHTTP(w, req)
+	return w
+}
+
+func (ts *SignInNotificationTestSuite) TestNewSignIn() {
+	// the first sign-in of a user is not new
+	ts.signIn("Firefox", "DE", "Berlin")
+	require.Empty(ts.T(), ts.Mailer.NewSignInMailCalls)
+
+	ts.signIn("Firefox", "DE", "Berlin")
+	require.Empty(ts.T(), ts.Mailer.NewSignInMailCalls)
+
+	ts.signIn("Firefox", "FR", "Paris")
+	require.Len(ts.T(), ts.Mailer.NewSignInMailCalls, 1)
+
+	signIn := ts.Mailer.NewSignInMailCalls[0].SignIn
+	require.Equal(ts.T(), "FR", signIn.Country)
+	require.Equal(ts.T(), "Paris", signIn.City)
+	require.Equal(ts.T(), "Firefox", signIn.UserAgent)
+	require.NotEmpty(ts.T(), signIn.RevokeURL)
+
+	// a new device is new as well
+	ts.signIn("Safari", "DE", "Berlin")
+	require.Len(ts.T(), ts.Mailer.NewSignInMailCalls, 2)
+
+	sessions, err := models.FindAllSessionsForUser(ts.API.db, ts.user.ID, false)
+	require.NoError(ts.T(), err)
+	require.Len(ts.T(), sessions, 4)
+	for _, session := range sessions {
+		require.NotNil(ts.T(), session.Country)
+	}
+}
+
+func (ts *SignInNotificationTestSuite) TestNewSignInDisabled() {
+	ts.Config.Mailer.Notifications.NewSignInEnabled = false
+
+	ts.signIn("Firefox", "DE", "Berlin")
+	ts.signIn("Safari", "FR", "Paris")
+	require.Empty(ts.T(), ts.Mailer.NewSignInMailCalls)
+}
+
+func (ts *SignInNotificationTestSuite) TestReportSession() {
+	ts.signIn("Firefox", "DE", "Berlin")
+	ts.signIn("Safari", "FR", "Paris")
+	require.Len(ts.T(), ts.Mailer.NewSignInMailCalls, 1)
+	link := ts.Mailer.NewSignInMailCalls[0].SignIn.RevokeURL
+
+	u, err := url.Parse(link)
+	require.NoError(ts.T(), err)
+
+	// links with a tampered signature or expiry are rejected
+	tampered := *u
+	q := tampered.Query()
+	q.Set("expires", "9999999999")
+	tampered.RawQuery = q.Encode()
+	w := ts.report(http.MethodGet, tampered.String())
+	require.Equal(ts.T(), http.StatusNotFound, w.Code)
+	w = ts.report(http.MethodPost, tampered.String())
+	require.Equal(ts.T(), http.StatusNotFound, w.Code)
+
+	// opening the link only asks to confirm signing out the session, as
+	// email scanners open links too
+	w = ts.report(http.MethodGet, link)
+	require.Equal(ts.T(), http.StatusOK, w.Code, w.Body.String())
+	require.Contains(ts.T(), w.Body.String(), `<form method="post">`)
+
+	sessions, err := models.FindAllSessionsForUser(ts.API.db, ts.user.ID, false)
+	require.NoError(ts.T(), err)
+	require.Len(ts.T(), sessions, 2)
+
+	w = ts.report(http.MethodPost, link)
+	require.Equal(ts.T(), http.StatusSeeOther, w.Code, w.Body.String())
+	require.Contains(ts.T(), w.Header().Get("Location"), ts.Config.SiteURL)
+
+	sessions, err = models.FindAllSessionsForUser(ts.API.db, ts.user.ID, false)
+	require.NoError(ts.T(), err)
+	require.Len(ts.T(), sessions, 1)
+	require.Equal(ts.T(), "Firefox", *sessions[0].UserAgent)
+
+	// the link can be opened again
+	w = ts.report(http.MethodPost, link)
+	require.Equal(ts.T(), http.StatusSeeOther, w.Code)
+
+	// changing the link secret invalidates the links
+	ts.Config.Mailer.Notifications.NewSignInLinkSecret = "another-new-sign-in-link-secret-of-32-chars"
+	w = ts.report(http.MethodGet, link)
+	require.Equal(ts.T(), http.StatusNotFound, w.Code)
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/supabase/auth/internal/api/apierrors"
 	"github.com/supabase/auth/internal/api/provider"
 	"github.com/supabase/auth/internal/api/sms_provider"
+	"github.com/supabase/auth/internal/mailer/templatemailer"
 	"github.com/supabase/auth/internal/metering"
 	"github.com/supabase/auth/internal/models"
 	"github.com/supabase/auth/internal/storage"
@@ -36,7 +37,7 @@ func (a *API) validateSignupParams(ctx context.Context, p *SignupParams) error {
 		return apierrors.NewBadRequestError(apierrors.ErrorCodeValidationFailed, "Signup requires a valid password")
 	}
 
-	if err := a.checkPasswordStrength(ctx, p.Password); err != nil {
+	if err := a.checkPasswordStrength(ctx, p.Password, p.Email); err != nil {
 		return err
 	}
 	if p.Email != "" && p.Phone != "" {
@@ -123,6 +124,14 @@ func (a *API) Signup(w http.ResponseWriter, r *http.Request) error {
 
 	params.ConfigureDefaults()
 
+	// Store the locale of the request so that mail sent outside of it, such
+	// as a recovery email requested from another browser, uses it too.
+	if _, ok := params.Data["locale"]; !ok && config.Mailer.LocalizationEnabled {
+		if locale := templatemailer.RequestLocale(r); locale != "" {
+			params.Data["locale"] = locale
+		}
+	}
+
 	if err := a.validateSignupParams(ctx, params); err != nil {
 		return err
 	}
@@ -176,6 +185,7 @@ func (a *API) Signup(w http.ResponseWriter, r *http.Request) error {
 	}
 
 	var signupUser *models.User
+	var pwnedPassword bool
 	if user == nil {
 		// always call this outside of a database transaction as this method
 		// can be computationally hard and block due to password hashing
@@ -186,6 +196,9 @@ func (a *API) Signup(w http.ResponseWriter, r *http.Request) error {
 		if err := a.triggerBeforeUserCreated(r, db, signupUser); err != nil {
 			return err
 		}
+		if pwnedPassword, err = a.shouldAuditPwnedPassword(ctx, params.Password); err != nil {
+			return err
+		}
 	}
 
 	err = db.Transaction(func(tx *storage.Connection) error {
@@ -200,6 +213,13 @@ func (a *API) Signup(w http.ResponseWriter, r *http.Request) error {
 			if terr != nil {
 				return terr
 			}
+			if pwnedPassword {
+				if terr = models.NewAuditLogEntry(config.AuditLog, r, tx, user, models.PasswordPwnedAction, "", map[string]interface{}{
+					"provider": params.Provider,
+				}); terr != nil {
+					return terr
+				}
+			}
 		}
 		identity, terr := models.FindIdentityByIdAndProvider(tx, user.ID.String(), params.Provider)
 		if terr != nil {
@@ -329,7 +349,7 @@ func (a *API) Signup(w http.ResponseWriter, r *http.Request) error {
 				"immediate_login_after_signup": true,
 			},
 		})
-		return sendJSON(w, http.StatusOK, token)
+		return a.sendTokenResponse(w, r, token)
 	}
 	if user.HasBeenInvited() {
 		// Remove sensitive fields
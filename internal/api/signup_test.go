@@ -74,6 +74,41 @@ func (ts *SignupTestSuite) TestSignup() {
 	assert.Equal(ts.T(), []interface{}{"email"}, data.AppMetaData["providers"])
 }
 
+func (ts *SignupTestSuite) TestSignupStoresLocale() {
+	ts.Config.Mailer.LocalizationEnabled = true
+	defer func() {
+		ts.Config.Mailer.LocalizationEnabled = false
+	}()
+
+	signup := func(email string, data map[string]interface{}) models.User {
+		var buffer bytes.Buffer
+		require.NoError(ts.T(), json.NewEncoder(&buffer).Encode(map[string]interface{}{
+			"email":    email,
+			"password": "test123",
+			"data":     data,
+		}))
+
+		req := httptest.NewRequest(http.MethodPost, "/signup", &buffer)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Accept-Language", "fr-ca,fr;q=0.9,en;q=0.5")
+
+		w := httptest.NewRecorder()
+		ts.API.handler.ServeHTTP(w, req)
+		require.Equal(ts.T(), http.StatusOK, w.Code, w.Body.String())
+
+		var user models.User
+		require.NoError(ts.T(), json.NewDecoder(w.Body).Decode(&user))
+		return user
+	}
+
+	user := signup("locale@example.com", nil)
+	assert.Equal(ts.T(), "fr-CA", user.UserMetaData["locale"])
+
+	// a locale given by the user is kept
+	user = signup("locale2@example.com", map[string]interface{}{"locale": "de"})
+	assert.Equal(ts.T(), "de", user.UserMetaData["locale"])
+}
+
 // TestSignupTwice checks to make sure the same email cannot be registered twice
 func (ts *SignupTestSuite) TestSignupTwice() {
 	// Request body
@@ -0,0 +1,47 @@
+package sms_provider
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+)
+
+type namedSmsProvider struct {
+	Name     string
+	Provider SmsProvider
+}
+
+// FailoverProvider sends messages with the first provider, retrying it up to
+// MaxRetries times, before falling back to the next provider in order. This
+// keeps phone auth working when a single carrier gateway is down.
+type FailoverProvider struct {
+	Providers  []namedSmsProvider
+	MaxRetries int
+}
+
+func (f *FailoverProvider) SendMessage(phone, message, channel, otp string) (string, error) {
+	var errs []error
+
+	for _, p := range f.Providers {
+		for attempt := 0; attempt <= f.MaxRetries; attempt += 1 {
+			messageID, err := p.Provider.SendMessage(phone, message, channel, otp)
+			if err == nil {
+				return messageID, nil
+			}
+
+			logrus.WithError(err).WithFields(logrus.Fields{
+				"provider": p.Name,
+				"attempt":  attempt + 1,
+			}).Warn("sms provider failed to send message")
+
+			errs = append(errs, fmt.Errorf("%s: %w", p.Name, err))
+		}
+	}
+
+	return "", errors.Join(errs...)
+}
+
+func (f *FailoverProvider) VerifyOTP(phone, code string) error {
+	return fmt.Errorf("VerifyOTP is not supported with fallback sms providers")
+}
@@ -3,6 +3,8 @@ package sms_provider
 import (
 	"fmt"
 	"log"
+	"net"
+	"net/url"
 	"os"
 	"time"
 
@@ -38,22 +40,88 @@ func GetSmsProvider(config conf.GlobalConfiguration) (SmsProvider, error) {
 		return MockProvider, nil
 	}
 
-	switch name := config.Sms.Provider; name {
+	if len(config.Sms.FallbackProviders) == 0 && config.Sms.MaxRetries <= 0 {
+		return newSmsProvider(config.Sms.Provider, config.Sms)
+	}
+
+	if config.Sms.IsTwilioVerifyProvider() {
+		return nil, fmt.Errorf("sms Provider twilio_verify does not support fallback providers or retries")
+	}
+
+	names := append([]string{config.Sms.Provider}, config.Sms.FallbackProviders...)
+	providers := make([]namedSmsProvider, 0, len(names))
+	for _, name := range names {
+		if name == "twilio_verify" {
+			return nil, fmt.Errorf("sms Provider twilio_verify cannot be used as a fallback provider")
+		}
+
+		provider, err := newSmsProvider(name, config.Sms)
+		if err != nil {
+			return nil, err
+		}
+
+		providers = append(providers, namedSmsProvider{
+			Name:     name,
+			Provider: provider,
+		})
+	}
+
+	return &FailoverProvider{
+		Providers:  providers,
+		MaxRetries: config.Sms.MaxRetries,
+	}, nil
+}
+
+func newSmsProvider(name string, config conf.SmsProviderConfiguration) (SmsProvider, error) {
+	switch name {
 	case "twilio":
-		return NewTwilioProvider(config.Sms.Twilio)
+		return NewTwilioProvider(config.Twilio)
 	case "messagebird":
-		return NewMessagebirdProvider(config.Sms.Messagebird)
+		return NewMessagebirdProvider(config.Messagebird)
 	case "textlocal":
-		return NewTextlocalProvider(config.Sms.Textlocal)
+		return NewTextlocalProvider(config.Textlocal)
 	case "vonage":
-		return NewVonageProvider(config.Sms.Vonage)
+		return NewVonageProvider(config.Vonage)
 	case "twilio_verify":
-		return NewTwilioVerifyProvider(config.Sms.TwilioVerify)
+		return NewTwilioVerifyProvider(config.TwilioVerify)
+	case "sns":
+		return NewSNSProvider(config.SNS)
 	default:
 		return nil, fmt.Errorf("sms Provider %s could not be found", name)
 	}
 }
 
+// APIHost returns the host and port of the API of the provider, for checking
+// that it can be reached.
+func APIHost(name string, config conf.SmsProviderConfiguration) (string, error) {
+	var base string
+	switch name {
+	case "twilio":
+		base = defaultTwilioApiBase
+	case "messagebird":
+		base = defaultMessagebirdApiBase
+	case "textlocal":
+		base = defaultTextLocalApiBase
+	case "vonage":
+		base = defaultVonageApiBase
+	case "twilio_verify":
+		base = verifyServiceApiBase
+	case "sns":
+		if config.SNS.Region == "" {
+			return "", fmt.Errorf("sms Provider sns has no region")
+		}
+		base = "https://sns." + config.SNS.Region + ".amazonaws.com/"
+	default:
+		return "", fmt.Errorf("sms Provider %s could not be found", name)
+	}
+
+	u, err := url.Parse(base)
+	if err != nil {
+		return "", err
+	}
+	return net.JoinHostPort(u.Hostname(), "443"), nil
+}
+
 func IsValidMessageChannel(channel string, config *conf.GlobalConfiguration) bool {
 	if config.Hook.SendSMS.Enabled {
 		// channel doesn't matter if SMS hook is enabled
@@ -2,10 +2,12 @@ package sms_provider
 
 import (
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
@@ -56,6 +58,12 @@ func TestSmsProvider(t *testing.T) {
 					ApiKey: "test_api_key",
 					Sender: "test_sender",
 				},
+				SNS: conf.SNSProviderConfiguration{
+					AccessKeyID:     "test_access_key_id",
+					SecretAccessKey: "test_secret_access_key",
+					Region:          "us-east-1",
+					SMSType:         "Transactional",
+				},
 			},
 		},
 	}
@@ -285,3 +293,109 @@ func (ts *SmsProviderTestSuite) TestTwilioVerifySendSms() {
 		})
 	}
 }
+
+func (ts *SmsProviderTestSuite) TestSNSSendSms() {
+	defer gock.Off()
+	provider, err := NewSNSProvider(ts.Config.Sms.SNS)
+	require.NoError(ts.T(), err)
+
+	snsProvider, ok := provider.(*SNSProvider)
+	require.Equal(ts.T(), true, ok)
+	snsProvider.signer.Now = func() time.Time {
+		return time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	}
+
+	phone := "123456789"
+	message := "This is the sms code: 123456"
+
+	body := url.Values{
+		"Action":      {"Publish"},
+		"Version":     {"2010-03-31"},
+		"PhoneNumber": {"+" + phone},
+		"Message":     {message},
+
+		"MessageAttributes.entry.1.Name":              {"AWS.SNS.SMS.SMSType"},
+		"MessageAttributes.entry.1.Value.DataType":    {"String"},
+		"MessageAttributes.entry.1.Value.StringValue": {"Transactional"},
+	}
+
+	gock.New(snsProvider.APIPath).Post("").
+		MatchHeader("X-Amz-Date", "20240102T030405Z").
+		MatchHeader("Authorization", "^AWS4-HMAC-SHA256 Credential=test_access_key_id/20240102/us-east-1/sns/aws4_request, SignedHeaders=content-type;host;x-amz-date, Signature=[0-9a-f]{64}$").
+		BodyString(body.Encode()).
+		Reply(200).BodyString(`<PublishResponse><PublishResult><MessageId>message-id</MessageId></PublishResult></PublishResponse>`)
+
+	messageID, err := snsProvider.SendSms(phone, message)
+	require.NoError(ts.T(), err)
+	require.Equal(ts.T(), "message-id", messageID)
+
+	gock.New(snsProvider.APIPath).Post("").
+		Reply(400).BodyString(`<ErrorResponse><Error><Code>InvalidParameter</Code><Message>Invalid phone number</Message></Error></ErrorResponse>`)
+
+	_, err = snsProvider.SendSms(phone, message)
+	require.EqualError(ts.T(), err, "aws sns error: Invalid phone number (code: InvalidParameter)")
+}
+
+type failingSmsProvider struct {
+	calls int
+	err   error
+}
+
+func (p *failingSmsProvider) SendMessage(phone, message, channel, otp string) (string, error) {
+	p.calls += 1
+	if p.err != nil {
+		return "", p.err
+	}
+	return "message-id", nil
+}
+
+func (p *failingSmsProvider) VerifyOTP(phone, code string) error {
+	return nil
+}
+
+func (ts *SmsProviderTestSuite) TestFailoverProvider() {
+	primary := &failingSmsProvider{err: errors.New("gateway down")}
+	fallback := &failingSmsProvider{}
+
+	provider := &FailoverProvider{
+		Providers: []namedSmsProvider{
+			{Name: "primary", Provider: primary},
+			{Name: "fallback", Provider: fallback},
+		},
+		MaxRetries: 2,
+	}
+
+	messageID, err := provider.SendMessage("123456789", "message", SMSProvider, "123456")
+	require.NoError(ts.T(), err)
+	require.Equal(ts.T(), "message-id", messageID)
+	require.Equal(ts.T(), 3, primary.calls)
+	require.Equal(ts.T(), 1, fallback.calls)
+
+	fallback.err = errors.New("also down")
+	_, err = provider.SendMessage("123456789", "message", SMSProvider, "123456")
+	require.ErrorContains(ts.T(), err, "primary: gateway down")
+	require.ErrorContains(ts.T(), err, "fallback: also down")
+}
+
+func (ts *SmsProviderTestSuite) TestGetSmsProviderWithFallbacks() {
+	config := *ts.Config
+	config.Sms.Provider = "vonage"
+	config.Sms.FallbackProviders = []string{"sns"}
+
+	provider, err := GetSmsProvider(config)
+	require.NoError(ts.T(), err)
+
+	failover, ok := provider.(*FailoverProvider)
+	require.True(ts.T(), ok)
+	require.Len(ts.T(), failover.Providers, 2)
+	require.Equal(ts.T(), "sns", failover.Providers[1].Name)
+
+	config.Sms.FallbackProviders = []string{"twilio_verify"}
+	_, err = GetSmsProvider(config)
+	require.Error(ts.T(), err)
+
+	config.Sms.Provider = "twilio_verify"
+	config.Sms.FallbackProviders = []string{"twilio"}
+	_, err = GetSmsProvider(config)
+	require.Error(ts.T(), err)
+}
@@ -0,0 +1,124 @@
+package sms_provider
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/supabase/auth/internal/conf"
+	"github.com/supabase/auth/internal/utilities"
+)
+
+const (
+	snsAPIVersion = "2010-03-31"
+	snsService    = "sns"
+)
+
+type SNSProvider struct {
+	Config  *conf.SNSProviderConfiguration
+	APIPath string
+
+	signer utilities.Signer
+}
+
+type SNSPublishResponse struct {
+	MessageID string `xml:"PublishResult>MessageId"`
+}
+
+type SNSErrorResponse struct {
+	Code    string `xml:"Error>Code"`
+	Message string `xml:"Error>Message"`
+}
+
+// Creates a SmsProvider with the AWS SNS Config
+func NewSNSProvider(config conf.SNSProviderConfiguration) (SmsProvider, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	apiPath := "https://sns." + config.Region + ".amazonaws.com/"
+	return &SNSProvider{
+		Config:  &config,
+		APIPath: apiPath,
+	}, nil
+}
+
+func (t *SNSProvider) SendMessage(phone, message, channel, otp string) (string, error) {
+	switch channel {
+	case SMSProvider:
+		return t.SendSms(phone, message)
+	default:
+		return "", fmt.Errorf("channel type %q is not supported for AWS SNS", channel)
+	}
+}
+
+// Send an SMS containing the OTP with the AWS SNS Publish API
+func (t *SNSProvider) SendSms(phone, message string) (string, error) {
+	body := url.Values{
+		"Action":      {"Publish"},
+		"Version":     {snsAPIVersion},
+		"PhoneNumber": {"+" + phone},
+		"Message":     {message},
+	}
+
+	attributes := [][2]string{}
+	if t.Config.SMSType != "" {
+		attributes = append(attributes, [2]string{"AWS.SNS.SMS.SMSType", t.Config.SMSType})
+	}
+	if t.Config.SenderID != "" {
+		attributes = append(attributes, [2]string{"AWS.SNS.SMS.SenderID", t.Config.SenderID})
+	}
+	for i, attribute := range attributes {
+		prefix := fmt.Sprintf("MessageAttributes.entry.%d.", i+1)
+		body.Set(prefix+"Name", attribute[0])
+		body.Set(prefix+"Value.DataType", "String")
+		body.Set(prefix+"Value.StringValue", attribute[1])
+	}
+
+	payload := body.Encode()
+
+	client := &http.Client{Timeout: defaultTimeout}
+	r, err := http.NewRequest("POST", t.APIPath, strings.NewReader(payload))
+	if err != nil {
+		return "", err
+	}
+
+	r.Header.Add("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+	t.sign(r, payload)
+
+	res, err := client.Do(r)
+	if err != nil {
+		return "", err
+	}
+	defer utilities.SafeClose(res.Body)
+
+	if res.StatusCode != http.StatusOK {
+		resp := &SNSErrorResponse{}
+		if err := xml.NewDecoder(res.Body).Decode(resp); err != nil {
+			return "", fmt.Errorf("aws sns error: unexpected response status %d", res.StatusCode)
+		}
+		return "", fmt.Errorf("aws sns error: %v (code: %v)", resp.Message, resp.Code)
+	}
+
+	resp := &SNSPublishResponse{}
+	if err := xml.NewDecoder(res.Body).Decode(resp); err != nil {
+		return "", err
+	}
+
+	return resp.MessageID, nil
+}
+
+// sign adds an AWS Signature Version 4 Authorization header to the request.
+func (t *SNSProvider) sign(r *http.Request, payload string) {
+	t.signer.SignAWS(r, payload, snsService, t.Config.Region, utilities.AWSCredentials{
+		AccessKeyID:     t.Config.AccessKeyID,
+		SecretAccessKey: t.Config.SecretAccessKey,
+		SessionToken:    t.Config.SessionToken,
+	})
+}
+
+func (t *SNSProvider) VerifyOTP(phone, code string) error {
+	return fmt.Errorf("VerifyOTP is not supported for AWS SNS")
+}
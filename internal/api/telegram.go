@@ -0,0 +1,147 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/supabase/auth/internal/api/apierrors"
+	"github.com/supabase/auth/internal/api/provider"
+	"github.com/supabase/auth/internal/metering"
+	"github.com/supabase/auth/internal/models"
+	"github.com/supabase/auth/internal/storage"
+)
+
+// TelegramGrantParams are the auth data the Telegram Login Widget passed to
+// the app, as is.
+type TelegramGrantParams struct {
+	provider.TelegramAuthData
+
+	LinkIdentity bool `json:"link_identity"`
+}
+
+// TelegramGrant signs in or links the Telegram user whose auth data was
+// signed with the bot token.
+func (a *API) TelegramGrant(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	config := a.config
+	db := a.db.WithContext(ctx)
+
+	if !config.External.Telegram.Enabled {
+		return apierrors.NewUnprocessableEntityError(apierrors.ErrorCodeTelegramProviderDisabled, "Telegram provider is disabled")
+	}
+
+	params := &TelegramGrantParams{}
+	if err := retrieveRequestParams(r, params); err != nil {
+		return err
+	}
+
+	if params.ID == 0 {
+		return apierrors.NewBadRequestError(apierrors.ErrorCodeValidationFailed, "id needs to be non-empty")
+	}
+	if params.AuthDate == 0 {
+		return apierrors.NewBadRequestError(apierrors.ErrorCodeValidationFailed, "auth_date needs to be non-empty")
+	}
+	if params.Hash == "" {
+		return apierrors.NewBadRequestError(apierrors.ErrorCodeValidationFailed, "hash needs to be non-empty")
+	}
+
+	if !params.Verify(config.External.Telegram.BotToken) {
+		return apierrors.NewOAuthError("invalid_grant", "Hash does not match Telegram auth data")
+	}
+
+	now := a.Now()
+	authTime := params.AuthTime()
+
+	if now.After(authTime.Add(config.External.Telegram.MaximumValidityDuration)) {
+		return apierrors.NewOAuthError("invalid_grant", "Telegram auth data was issued too long ago")
+	}
+
+	if now.Before(authTime.Add(-config.External.Telegram.MaximumValidityDuration)) {
+		return apierrors.NewOAuthError("invalid_grant", "Telegram auth data was issued too far in the future")
+	}
+
+	if params.LinkIdentity {
+		if r.Header.Get("Authorization") == "" {
+			return apierrors.NewOAuthError("invalid request", "Linking requires a valid user access token in Authorization")
+		}
+
+		requireAuthCtx, err := a.requireAuthentication(w, r)
+		if err != nil {
+			return err
+		}
+
+		targetUser := getUser(requireAuthCtx)
+		if targetUser == nil {
+			return apierrors.NewOAuthError("invalid request", "Linking requires a valid user authentication")
+		}
+
+		// set it so linkIdentityToUser works below
+		ctx = withTargetUser(ctx, targetUser)
+	}
+
+	const providerType = "telegram"
+	userData := params.UserData()
+
+	var createdUser bool
+	var token *AccessTokenResponse
+	var grantParams models.GrantParams
+	grantParams.FillGrantParams(r)
+
+	if !params.LinkIdentity {
+		if err := a.triggerBeforeUserCreatedExternal(r, db, userData, providerType); err != nil {
+			return err
+		}
+	}
+
+	var user *models.User
+	err := db.Transaction(func(tx *storage.Connection) error {
+		var terr error
+		var decision models.AccountLinkingDecision
+		if params.LinkIdentity {
+			user, terr = a.linkIdentityToUser(r, ctx, tx, userData, providerType)
+		} else {
+			decision, user, terr = a.createAccountFromExternalIdentity(tx, r, userData, providerType, true)
+		}
+		if terr != nil {
+			return terr
+		}
+		createdUser = decision == models.CreateAccount
+
+		if terr := models.NewAuditLogEntry(config.AuditLog, r, tx, user, models.LoginAction, "", map[string]interface{}{
+			"provider":    providerType,
+			"telegram_id": strconv.FormatInt(params.ID, 10),
+			"username":    params.Username,
+		}); terr != nil {
+			return terr
+		}
+
+		token, terr = a.issueRefreshToken(r, w.Header(), tx, user, models.Telegram, grantParams)
+		if terr != nil {
+			return terr
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		switch err.(type) {
+		case *storage.CommitWithError:
+			return err
+		case *HTTPError:
+			return err
+		default:
+			return apierrors.NewOAuthError("server_error", "Internal Server Error").WithInternalError(err)
+		}
+	}
+	if createdUser {
+		if err := a.triggerAfterUserCreated(r, db, user); err != nil {
+			return err
+		}
+	}
+
+	metering.RecordLogin(metering.LoginTypeTelegram, token.User.ID, &metering.LoginData{
+		Provider: providerType,
+	})
+
+	return a.sendTokenResponse(w, r, token)
+}
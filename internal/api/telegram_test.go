@@ -0,0 +1,153 @@
+package api
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"github.com/supabase/auth/internal/api/apierrors"
+	"github.com/supabase/auth/internal/conf"
+	"github.com/supabase/auth/internal/models"
+)
+
+type TelegramTestSuite struct {
+	suite.Suite
+	API    *API
+	Config *conf.GlobalConfiguration
+}
+
+func TestTelegram(t *testing.T) {
+	api, config, err := setupAPIForTest()
+	require.NoError(t, err)
+
+	ts := &TelegramTestSuite{
+		API:    api,
+		Config: config,
+	}
+	defer api.db.Close()
+
+	suite.Run(t, ts)
+}
+
+func (ts *TelegramTestSuite) SetupTest() {
+	models.TruncateAll(ts.API.db)
+}
+
+// signTelegramAuthData signs the auth data like the Telegram Login Widget.
+func (ts *TelegramTestSuite) signTelegramAuthData(id int64, username string, authDate time.Time) map[string]interface{} {
+	dataCheckString := fmt.Sprintf("auth_date=%d\nfirst_name=Jane\nid=%d\nphoto_url=https://t.me/i/userpic/320/%s.jpg\nusername=%s", authDate.Unix(), id, username, username)
+
+	secretKey := sha256.Sum256([]byte(ts.Config.External.Telegram.BotToken))
+	mac := hmac.New(sha256.New, secretKey[:])
+	mac.Write([]byte(dataCheckString))
+
+	return map[string]interface{}{
+		"id":         id,
+		"first_name": "Jane",
+		"username":   username,
+		"photo_url":  fmt.Sprintf("https://t.me/i/userpic/320/%s.jpg", username),
+		"auth_date":  authDate.Unix(),
+		"hash":       hex.EncodeToString(mac.Sum(nil)),
+	}
+}
+
+func (ts *TelegramTestSuite) requestTelegramGrant(body map[string]interface{}) *httptest.ResponseRecorder {
+	var buffer bytes.Buffer
+	require.NoError(ts.T(), json.NewEncoder(&buffer).Encode(body))
+
+	req := httptest.NewRequest(http.MethodPost, "http://localhost/token?grant_type=telegram", &buffer)
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	ts.API.handler.ServeHTTP(w, req)
+	return w
+}
+
+func (ts *TelegramTestSuite) TestDisabled() {
+	defer func() {
+		ts.Config.External.Telegram.Enabled = true
+	}()
+	ts.Config.External.Telegram.Enabled = false
+
+	w := ts.requestTelegramGrant(ts.signTelegramAuthData(123456789, "janedoe", time.Now()))
+
+	var result struct {
+		ErrorCode string `json:"error_code"`
+		Message   string `json:"msg"`
+	}
+	require.NoError(ts.T(), json.NewDecoder(w.Body).Decode(&result))
+	assert.Equal(ts.T(), http.StatusUnprocessableEntity, w.Code)
+	assert.Equal(ts.T(), apierrors.ErrorCodeTelegramProviderDisabled, result.ErrorCode)
+	assert.Equal(ts.T(), "Telegram provider is disabled", result.Message)
+}
+
+func (ts *TelegramTestSuite) TestHappyPath() {
+	body := ts.signTelegramAuthData(123456789, "janedoe", time.Now())
+
+	w := ts.requestTelegramGrant(body)
+	require.Equal(ts.T(), http.StatusOK, w.Code)
+
+	var result AccessTokenResponse
+	require.NoError(ts.T(), json.NewDecoder(w.Body).Decode(&result))
+	require.NotEmpty(ts.T(), result.Token)
+	require.NotEmpty(ts.T(), result.RefreshToken)
+
+	user, err := models.FindUserByID(ts.API.db, result.User.ID)
+	require.NoError(ts.T(), err)
+	assert.Empty(ts.T(), user.GetEmail())
+	assert.Equal(ts.T(), "janedoe", user.UserMetaData["user_name"])
+	assert.Equal(ts.T(), "https://t.me/i/userpic/320/janedoe.jpg", user.UserMetaData["avatar_url"])
+
+	identity, err := models.FindIdentityByIdAndProvider(ts.API.db, "123456789", "telegram")
+	require.NoError(ts.T(), err)
+	assert.Equal(ts.T(), user.ID, identity.UserID)
+
+	// signing in again signs in the same user
+	w = ts.requestTelegramGrant(ts.signTelegramAuthData(123456789, "janedoe", time.Now()))
+	require.Equal(ts.T(), http.StatusOK, w.Code)
+
+	var secondResult AccessTokenResponse
+	require.NoError(ts.T(), json.NewDecoder(w.Body).Decode(&secondResult))
+	assert.Equal(ts.T(), user.ID, secondResult.User.ID)
+}
+
+func (ts *TelegramTestSuite) TestInvalidHash() {
+	body := ts.signTelegramAuthData(123456789, "janedoe", time.Now())
+	body["username"] = "someoneelse"
+
+	w := ts.requestTelegramGrant(body)
+	assert.Equal(ts.T(), http.StatusBadRequest, w.Code)
+
+	var result struct {
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	require.NoError(ts.T(), json.NewDecoder(w.Body).Decode(&result))
+	assert.Equal(ts.T(), "invalid_grant", result.Error)
+	assert.Equal(ts.T(), "Hash does not match Telegram auth data", result.ErrorDescription)
+}
+
+func (ts *TelegramTestSuite) TestExpired() {
+	authDate := time.Now().Add(-ts.Config.External.Telegram.MaximumValidityDuration - time.Minute)
+
+	w := ts.requestTelegramGrant(ts.signTelegramAuthData(123456789, "janedoe", authDate))
+	assert.Equal(ts.T(), http.StatusBadRequest, w.Code)
+
+	var result struct {
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	require.NoError(ts.T(), json.NewDecoder(w.Body).Decode(&result))
+	assert.Equal(ts.T(), "invalid_grant", result.Error)
+	assert.Equal(ts.T(), "Telegram auth data was issued too long ago", result.ErrorDescription)
+}
@@ -0,0 +1,281 @@
+package api
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/supabase/auth/internal/api/apierrors"
+	"github.com/supabase/auth/internal/cache"
+	"github.com/supabase/auth/internal/conf"
+	"github.com/supabase/auth/internal/models"
+	"github.com/supabase/auth/internal/storage"
+	"golang.org/x/sync/singleflight"
+)
+
+// NewTenantAPIFunc creates the API serving a tenant.
+type NewTenantAPIFunc func(tenant *models.Tenant, config *conf.GlobalConfiguration, db *storage.Connection) *API
+
+// tenantMissCacheSize bounds the number of hostnames and tenant IDs that
+// select no tenant which are remembered, so that requests with arbitrary
+// Host headers can't grow the memory of the router.
+const tenantMissCacheSize = 10000
+
+// TenantRouter serves each request with the API of the tenant selected by
+// the tenancy header or, without the header, by the request's hostname.
+// Requests that select no tenant are served by the deployment's own API.
+type TenantRouter struct {
+	newAPI NewTenantAPIFunc
+
+	// dial opens the database of a tenant, it is only overridden in tests.
+	dial func(config *conf.GlobalConfiguration) (*storage.Connection, error)
+
+	// lookups resolves a hostname or tenant ID once for concurrent requests.
+	lookups singleflight.Group
+
+	mu      sync.Mutex
+	base    *API                    // Guarded by mu.
+	tenants map[string]*tenantRoute // Guarded by mu.
+	misses  *cache.MemoryStore      // Guarded by mu.
+	conns   map[string]*tenantConn  // Guarded by mu.
+}
+
+// tenantConn is the connection to the database at a URL, and the tenant
+// whose users it holds.
+type tenantConn struct {
+	tenantID uuid.UUID
+	db       *storage.Connection
+}
+
+type tenantRoute struct {
+	tenant    *models.Tenant
+	api       *API
+	expiresAt time.Time
+}
+
+// NewTenantRouter returns a TenantRouter for the deployment's API.
+func NewTenantRouter(base *API, newAPI NewTenantAPIFunc) *TenantRouter {
+	return &TenantRouter{
+		newAPI:  newAPI,
+		dial:    storage.Dial,
+		base:    base,
+		tenants: make(map[string]*tenantRoute),
+		misses:  cache.NewMemoryStore(tenantMissCacheSize),
+		conns:   make(map[string]*tenantConn),
+	}
+}
+
+// Reload replaces the deployment's API after a configuration change, the
+// tenants' APIs are recreated on their next request. Database connections
+// are kept.
+func (tr *TenantRouter) Reload(base *API) {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+
+	tr.base = base
+	tr.tenants = make(map[string]*tenantRoute)
+	tr.misses = cache.NewMemoryStore(tenantMissCacheSize)
+}
+
+// ServeHTTP implements http.Handler.
+func (tr *TenantRouter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	api, err := tr.route(r)
+	if err != nil {
+		HandleResponseError(err, w, r)
+		return
+	}
+
+	api.ServeHTTP(w, r)
+}
+
+func (tr *TenantRouter) route(r *http.Request) (*API, error) {
+	tr.mu.Lock()
+	base, misses := tr.base, tr.misses
+	tr.mu.Unlock()
+
+	config := base.config
+	// the lookup is shared by concurrent requests, so it must not be
+	// canceled with the request that started it
+	ctx := context.WithoutCancel(r.Context())
+	db := base.db.WithContext(ctx)
+
+	var key string
+	var find func() (*models.Tenant, error)
+
+	byID := false
+	if header := r.Header.Get(config.Tenancy.Header); header != "" {
+		tenantID, err := uuid.FromString(header)
+		if err != nil {
+			return nil, apierrors.NewNotFoundError(apierrors.ErrorCodeTenantNotFound, "Tenant not found")
+		}
+
+		byID = true
+		key = "id:" + tenantID.String()
+		find = func() (*models.Tenant, error) {
+			return models.FindTenantByID(db, tenantID)
+		}
+	} else {
+		hostname := r.Host
+		if host, _, err := net.SplitHostPort(hostname); err == nil {
+			hostname = host
+		}
+		hostname = strings.ToLower(hostname)
+
+		key = "host:" + hostname
+		find = func() (*models.Tenant, error) {
+			return models.FindTenantByHostname(db, hostname)
+		}
+	}
+
+	notFound := func() (*API, error) {
+		if byID {
+			return nil, apierrors.NewNotFoundError(apierrors.ErrorCodeTenantNotFound, "Tenant not found")
+		}
+		// the hostname is served by the deployment itself
+		return base, nil
+	}
+
+	now := time.Now()
+
+	tr.mu.Lock()
+	route := tr.tenants[key]
+	tr.mu.Unlock()
+	if route != nil && now.Before(route.expiresAt) {
+		return route.api, nil
+	}
+	if _, miss, _ := misses.Get(ctx, key); miss {
+		return notFound()
+	}
+
+	v, err, _ := tr.lookups.Do(key, func() (interface{}, error) {
+		tenant, err := find()
+		if err != nil {
+			if !models.IsNotFoundError(err) {
+				return nil, apierrors.NewInternalServerError("Database error finding tenant").WithInternalError(err)
+			}
+
+			tr.mu.Lock()
+			if tr.tenants[key] == route {
+				delete(tr.tenants, key)
+			}
+			tr.mu.Unlock()
+
+			return nil, misses.Set(ctx, key, nil, config.Tenancy.CacheTTL)
+		}
+
+		next := &tenantRoute{
+			tenant:    tenant,
+			expiresAt: time.Now().Add(config.Tenancy.CacheTTL),
+		}
+		if route != nil && sameTenant(route.tenant, tenant) {
+			next.api = route.api
+		} else {
+			next.api, err = tr.tenantAPI(base, tenant)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		tr.mu.Lock()
+		// routes resolved with an API replaced by Reload are not kept
+		if tr.base == base {
+			tr.tenants[key] = next
+		}
+		tr.mu.Unlock()
+
+		return next.api, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if v == nil {
+		return notFound()
+	}
+	return v.(*API), nil
+}
+
+// tenantAPI creates the API of a tenant, opening the tenant's database when
+// it is not open yet. A database opened for another tenant is only reused
+// once that tenant was deleted or moved to another database.
+func (tr *TenantRouter) tenantAPI(base *API, tenant *models.Tenant) (*API, error) {
+	config, err := tenant.Configuration(base.config)
+	if err != nil {
+		return nil, apierrors.NewInternalServerError("Tenant configuration is not valid").WithInternalError(err)
+	}
+
+	// tenants sharing the deployment's database would share its users
+	if config.DB.URL == base.config.DB.URL {
+		return nil, apierrors.NewInternalServerError("Tenant has no database of its own")
+	}
+
+	tr.mu.Lock()
+	conn := tr.conns[config.DB.URL]
+	tr.mu.Unlock()
+
+	if conn == nil {
+		db, err := tr.dial(config)
+		if err != nil {
+			return nil, apierrors.NewInternalServerError("Error opening tenant database").WithInternalError(err)
+		}
+
+		tr.mu.Lock()
+		if existing := tr.conns[config.DB.URL]; existing != nil {
+			// another request opened the same database meanwhile
+			tr.mu.Unlock()
+			db.Close()
+			conn = existing
+		} else {
+			conn = &tenantConn{tenantID: tenant.ID, db: db}
+			tr.conns[config.DB.URL] = conn
+			tr.mu.Unlock()
+		}
+	}
+
+	tr.mu.Lock()
+	owner := conn.tenantID
+	tr.mu.Unlock()
+
+	if owner != tenant.ID {
+		// tenants sharing a database would share its users
+		other, err := models.FindTenantByID(base.db, owner)
+		if err != nil && !models.IsNotFoundError(err) {
+			return nil, apierrors.NewInternalServerError("Database error finding tenant").WithInternalError(err)
+		}
+		if other != nil && other.Config.DatabaseURL == config.DB.URL {
+			return nil, apierrors.NewInternalServerError("Tenant database is used by another tenant")
+		}
+
+		tr.mu.Lock()
+		conn.tenantID = tenant.ID
+		tr.mu.Unlock()
+	}
+
+	return tr.newAPI(tenant, config, conn.db), nil
+}
+
+// sameTenant reports whether both are the same version of a tenant.
+func sameTenant(a, b *models.Tenant) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.ID == b.ID && a.UpdatedAt.Equal(b.UpdatedAt)
+}
+
+// Close closes the database connections opened for tenants.
+func (tr *TenantRouter) Close() error {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+
+	var err error
+	for url, conn := range tr.conns {
+		if cerr := conn.db.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+		delete(tr.conns, url)
+	}
+	return err
+}
@@ -0,0 +1,253 @@
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/gofrs/uuid"
+	"github.com/supabase/auth/internal/api/apierrors"
+	"github.com/supabase/auth/internal/models"
+	"github.com/supabase/auth/internal/observability"
+	"github.com/supabase/auth/internal/storage"
+)
+
+// TenantParams are the parameters for creating or updating a tenant.
+type TenantParams struct {
+	Name     string               `json:"name"`
+	Hostname *string              `json:"hostname"`
+	Config   *models.TenantConfig `json:"config"`
+}
+
+// requireOperatorCredentials checks the request is authenticated with the
+// operator token, which manages the tenants of the deployment.
+func (a *API) requireOperatorCredentials(w http.ResponseWriter, r *http.Request) (context.Context, error) {
+	token, err := a.extractBearerToken(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if subtle.ConstantTimeCompare([]byte(a.config.OperatorToken), []byte(token)) != 1 {
+		return nil, apierrors.NewHTTPError(http.StatusUnauthorized, apierrors.ErrorCodeNoAuthorization, "Invalid operator token")
+	}
+
+	return r.Context(), nil
+}
+
+func (a *API) loadTenant(w http.ResponseWriter, r *http.Request) (context.Context, error) {
+	ctx := r.Context()
+	db := a.db.WithContext(ctx)
+
+	tenantID, err := uuid.FromString(chi.URLParam(r, "tenant_id"))
+	if err != nil {
+		return nil, apierrors.NewNotFoundError(apierrors.ErrorCodeTenantNotFound, "Tenant not found")
+	}
+
+	tenant, err := models.FindTenantByID(db, tenantID)
+	if err != nil {
+		if models.IsNotFoundError(err) {
+			return nil, apierrors.NewNotFoundError(apierrors.ErrorCodeTenantNotFound, "Tenant not found")
+		}
+		return nil, apierrors.NewInternalServerError("Database error finding tenant").WithInternalError(err)
+	}
+
+	observability.LogEntrySetField(r, "tenant_id", tenant.ID.String())
+	return withTenant(ctx, tenant), nil
+}
+
+// validate normalizes the hostname and checks that the tenant's
+// configuration has a database of its own and can be applied on top of the
+// deployment's configuration.
+func (p *TenantParams) validate(a *API, forUpdate bool) error {
+	p.Name = strings.TrimSpace(p.Name)
+	if !forUpdate && p.Name == "" {
+		return apierrors.NewBadRequestError(apierrors.ErrorCodeValidationFailed, "Tenant name is required")
+	}
+
+	if len(p.Name) > 255 {
+		return apierrors.NewBadRequestError(apierrors.ErrorCodeValidationFailed, "Tenant name must be at most 255 characters long")
+	}
+
+	if p.Hostname != nil {
+		hostname := strings.ToLower(strings.TrimSpace(*p.Hostname))
+		if strings.ContainsAny(hostname, "/:@ ") {
+			return apierrors.NewBadRequestError(apierrors.ErrorCodeValidationFailed, "Tenant hostname must be a hostname without scheme or port")
+		}
+		p.Hostname = &hostname
+	}
+
+	if !forUpdate && p.Config == nil {
+		return apierrors.NewBadRequestError(apierrors.ErrorCodeValidationFailed, "Tenant configuration with a database_url is required")
+	}
+
+	if p.Config != nil {
+		// tenants are isolated by their database, sharing the deployment's
+		// database would share its users
+		if p.Config.DatabaseURL == "" {
+			return apierrors.NewBadRequestError(apierrors.ErrorCodeValidationFailed, "Tenant database_url is required")
+		}
+		if p.Config.DatabaseURL == a.config.DB.URL {
+			return apierrors.NewBadRequestError(apierrors.ErrorCodeValidationFailed, "Tenant database_url must not be the deployment's database")
+		}
+
+		// tenants sharing the deployment's signing keys could sign in each
+		// other's users
+		if p.Config.JWT == nil {
+			return apierrors.NewBadRequestError(apierrors.ErrorCodeValidationFailed, "Tenant jwt configuration with a secret or keys is required")
+		}
+
+		tenant := &models.Tenant{Config: *p.Config}
+		if _, err := tenant.Configuration(a.config); err != nil {
+			return apierrors.NewBadRequestError(apierrors.ErrorCodeValidationFailed, "Tenant configuration is not valid: %s", err.Error())
+		}
+	}
+
+	return nil
+}
+
+// checkTenantHostname returns an error if the hostname is already used by
+// another tenant.
+func checkTenantHostname(db *storage.Connection, hostname string, tenantID uuid.UUID) error {
+	existing, err := models.FindTenantByHostname(db, hostname)
+	if err != nil && !models.IsNotFoundError(err) {
+		return apierrors.NewInternalServerError("Database error finding tenant").WithInternalError(err)
+	}
+
+	if existing != nil && existing.ID != tenantID {
+		return apierrors.NewUnprocessableEntityError(apierrors.ErrorCodeConflict, "Hostname %q is already used by tenant %s", hostname, existing.ID.String())
+	}
+
+	return nil
+}
+
+// checkTenantDatabaseURL returns an error if the database is already used by
+// another tenant, as tenants sharing a database would share its users.
+func checkTenantDatabaseURL(db *storage.Connection, databaseURL string, tenantID uuid.UUID) error {
+	existing, err := models.FindTenantByDatabaseURL(db, databaseURL)
+	if err != nil && !models.IsNotFoundError(err) {
+		return apierrors.NewInternalServerError("Database error finding tenant").WithInternalError(err)
+	}
+
+	if existing != nil && existing.ID != tenantID {
+		return apierrors.NewUnprocessableEntityError(apierrors.ErrorCodeConflict, "Database URL is already used by tenant %s", existing.ID.String())
+	}
+
+	return nil
+}
+
+// adminTenantsList lists all tenants of the deployment.
+func (a *API) adminTenantsList(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	db := a.db.WithContext(ctx)
+
+	tenants, err := models.FindAllTenants(db)
+	if err != nil {
+		return apierrors.NewInternalServerError("Database error finding tenants").WithInternalError(err)
+	}
+
+	return sendJSON(w, http.StatusOK, map[string]interface{}{
+		"items": tenants,
+	})
+}
+
+// adminTenantsCreate creates a new tenant.
+func (a *API) adminTenantsCreate(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	db := a.db.WithContext(ctx)
+
+	params := &TenantParams{}
+	if err := retrieveRequestParams(r, params); err != nil {
+		return err
+	}
+
+	if err := params.validate(a, false /* <- forUpdate */); err != nil {
+		return err
+	}
+
+	var hostname *string
+	if params.Hostname != nil && *params.Hostname != "" {
+		if err := checkTenantHostname(db, *params.Hostname, uuid.Nil); err != nil {
+			return err
+		}
+		hostname = params.Hostname
+	}
+
+	if err := checkTenantDatabaseURL(db, params.Config.DatabaseURL, uuid.Nil); err != nil {
+		return err
+	}
+	config := *params.Config
+
+	tenant := models.NewTenant(params.Name, hostname, config)
+	if err := db.Create(tenant); err != nil {
+		return apierrors.NewInternalServerError("Database error creating tenant").WithInternalError(err)
+	}
+
+	return sendJSON(w, http.StatusCreated, tenant)
+}
+
+// adminTenantsGet returns a tenant.
+func (a *API) adminTenantsGet(w http.ResponseWriter, r *http.Request) error {
+	return sendJSON(w, http.StatusOK, getTenant(r.Context()))
+}
+
+// adminTenantsUpdate updates the name, hostname or configuration of a
+// tenant. An empty hostname removes it, a configuration replaces the
+// existing one.
+func (a *API) adminTenantsUpdate(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	db := a.db.WithContext(ctx)
+	tenant := getTenant(ctx)
+
+	params := &TenantParams{}
+	if err := retrieveRequestParams(r, params); err != nil {
+		return err
+	}
+
+	if err := params.validate(a, true /* <- forUpdate */); err != nil {
+		return err
+	}
+
+	if params.Name != "" {
+		tenant.Name = params.Name
+	}
+
+	if params.Hostname != nil {
+		if *params.Hostname == "" {
+			tenant.Hostname = nil
+		} else {
+			if err := checkTenantHostname(db, *params.Hostname, tenant.ID); err != nil {
+				return err
+			}
+			tenant.Hostname = params.Hostname
+		}
+	}
+
+	if params.Config != nil {
+		if err := checkTenantDatabaseURL(db, params.Config.DatabaseURL, tenant.ID); err != nil {
+			return err
+		}
+		tenant.Config = *params.Config
+	}
+
+	if err := db.Update(tenant); err != nil {
+		return apierrors.NewInternalServerError("Database error updating tenant").WithInternalError(err)
+	}
+
+	return sendJSON(w, http.StatusOK, tenant)
+}
+
+// adminTenantsDelete deletes a tenant. The tenant's database is left
+// untouched.
+func (a *API) adminTenantsDelete(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	db := a.db.WithContext(ctx)
+	tenant := getTenant(ctx)
+
+	if err := db.Destroy(tenant); err != nil {
+		return apierrors.NewInternalServerError("Database error deleting tenant").WithInternalError(err)
+	}
+
+	return sendJSON(w, http.StatusOK, tenant)
+}
@@ -3,10 +3,12 @@ package api
 import (
 	"context"
 	"net/http"
+	"time"
 
 	"github.com/gofrs/uuid"
 
 	"github.com/supabase/auth/internal/api/apierrors"
+	"github.com/supabase/auth/internal/conf"
 	"github.com/supabase/auth/internal/crypto"
 	"github.com/supabase/auth/internal/hooks/v0hooks"
 	"github.com/supabase/auth/internal/metering"
@@ -14,6 +16,8 @@ import (
 	"github.com/supabase/auth/internal/observability"
 	"github.com/supabase/auth/internal/storage"
 	"github.com/supabase/auth/internal/tokens"
+	"github.com/supabase/auth/internal/utilities"
+	"github.com/supabase/auth/internal/webhooks"
 )
 
 // Aliases for backward compatibility
@@ -56,6 +60,10 @@ func (a *API) Token(w http.ResponseWriter, r *http.Request) error {
 	case "web3":
 		handler = a.Web3Grant
 		limiter = a.limiterOpts.Web3
+	case "telegram":
+		handler = a.TelegramGrant
+	case "token-exchange", tokenExchangeGrantType:
+		handler = a.TokenExchangeGrant
 	default:
 		return apierrors.NewBadRequestError(apierrors.ErrorCodeInvalidCredentials, "unsupported_grant_type")
 	}
@@ -108,12 +116,20 @@ func (a *API) ResourceOwnerPasswordGrant(ctx context.Context, w http.ResponseWri
 
 	if err != nil {
 		if models.IsNotFoundError(err) {
+			metering.RecordLoginFailure(metering.LoginTypePassword, provider)
+			if err := a.emitLoginFailed(db, nil, params, provider, "user_not_found"); err != nil {
+				return err
+			}
 			return apierrors.NewBadRequestError(apierrors.ErrorCodeInvalidCredentials, InvalidLoginMessage)
 		}
 		return apierrors.NewInternalServerError("Database error querying schema").WithInternalError(err)
 	}
 
 	if !user.HasPassword() {
+		metering.RecordLoginFailure(metering.LoginTypePassword, provider)
+		if err := a.emitLoginFailed(db, user, params, provider, "no_password"); err != nil {
+			return err
+		}
 		return apierrors.NewBadRequestError(apierrors.ErrorCodeInvalidCredentials, InvalidLoginMessage)
 	}
 
@@ -121,14 +137,40 @@ func (a *API) ResourceOwnerPasswordGrant(ctx context.Context, w http.ResponseWri
 		return apierrors.NewBadRequestError(apierrors.ErrorCodeUserBanned, "User is banned")
 	}
 
+	// the password verification attempt hook can lock users without the
+	// built-in lockout policy. Locked users get the same error as unknown
+	// users, without checking the password, so that the lockout neither
+	// tells which accounts exist nor lets passwords be guessed meanwhile.
+	if (config.Security.Lockout.Enabled || config.Hook.PasswordVerificationAttempt.Enabled) && user.IsLocked() {
+		metering.RecordLoginFailure(metering.LoginTypePassword, provider)
+		if err := a.emitLoginFailed(db, user, params, provider, "user_locked"); err != nil {
+			return err
+		}
+		return apierrors.NewBadRequestError(apierrors.ErrorCodeInvalidCredentials, InvalidLoginMessage)
+	}
+
+	// captchas of the token endpoint are verified by the middleware already
+	if user.SignInCaptchaRequired && config.Security.Captcha.Enabled && !config.Security.Captcha.IsEnabledFor(conf.CaptchaEndpointToken) {
+		if err := a.checkCaptcha(r); err != nil {
+			return err
+		}
+	}
+
 	isValidPassword, shouldReEncrypt, err := user.Authenticate(ctx, db, params.Password, config.Security.DBEncryption.DecryptionKeys, config.Security.DBEncryption.Encrypt, config.Security.DBEncryption.EncryptionKeyID)
 	if err != nil {
 		return err
 	}
 
+	if !isValidPassword {
+		metering.RecordLoginFailure(metering.LoginTypePassword, provider)
+		if err := a.emitLoginFailed(db, user, params, provider, "invalid_password"); err != nil {
+			return err
+		}
+	}
+
 	var weakPasswordError *WeakPasswordError
 	if isValidPassword {
-		if err := a.checkPasswordStrength(ctx, params.Password); err != nil {
+		if err := a.checkPasswordStrength(ctx, params.Password, user.GetEmail()); err != nil {
 			if wpe, ok := err.(*WeakPasswordError); ok {
 				weakPasswordError = wpe
 			} else {
@@ -151,16 +193,24 @@ func (a *API) ResourceOwnerPasswordGrant(ctx context.Context, w http.ResponseWri
 		}
 	}
 
+	output := v0hooks.PasswordVerificationAttemptOutput{}
 	if config.Hook.PasswordVerificationAttempt.Enabled {
 		input := v0hooks.PasswordVerificationAttemptInput{
-			UserID: user.ID,
-			Valid:  isValidPassword,
+			UserID:         user.ID,
+			Valid:          isValidPassword,
+			FailedAttempts: user.FailedSignInAttempts,
+			IPAddress:      utilities.GetIPAddress(r),
 		}
-		output := v0hooks.PasswordVerificationAttemptOutput{}
 		if err := a.hooksMgr.InvokeHook(nil, r, &input, &output); err != nil {
 			return err
 		}
+	}
+
+	if err := a.applyPasswordLockout(r, db, user, isValidPassword, &output); err != nil {
+		return err
+	}
 
+	if config.Hook.PasswordVerificationAttempt.Enabled {
 		if output.Decision == v0hooks.HookRejection {
 			if output.Message == "" {
 				output.Message = v0hooks.DefaultPasswordHookRejectionMessage
@@ -177,6 +227,10 @@ func (a *API) ResourceOwnerPasswordGrant(ctx context.Context, w http.ResponseWri
 		return apierrors.NewBadRequestError(apierrors.ErrorCodeInvalidCredentials, InvalidLoginMessage)
 	}
 
+	if user.PasswordResetRequired {
+		return apierrors.NewBadRequestError(apierrors.ErrorCodePasswordResetRequired, "Password has expired and must be reset")
+	}
+
 	if params.Email != "" && !user.IsConfirmed() {
 		return apierrors.NewBadRequestError(apierrors.ErrorCodeEmailNotConfirmed, "Email not confirmed")
 	} else if params.Phone != "" && !user.IsPhoneConfirmed() {
@@ -191,6 +245,11 @@ func (a *API) ResourceOwnerPasswordGrant(ctx context.Context, w http.ResponseWri
 		}); terr != nil {
 			return terr
 		}
+		if user.FailedSignInAttempts > 0 || user.LockedUntil != nil || user.SignInCaptchaRequired {
+			if terr = user.ResetFailedSignIns(tx); terr != nil {
+				return terr
+			}
+		}
 		token, terr = a.tokenService.IssueRefreshToken(r, w.Header(), tx, user, models.PasswordGrant, grantParams)
 		if terr != nil {
 			return terr
@@ -207,7 +266,79 @@ func (a *API) ResourceOwnerPasswordGrant(ctx context.Context, w http.ResponseWri
 	metering.RecordLogin(metering.LoginTypePassword, user.ID, &metering.LoginData{
 		Provider: provider,
 	})
-	return sendJSON(w, http.StatusOK, token)
+	return a.sendTokenResponse(w, r, token)
+}
+
+// applyPasswordLockout records the outcome of a password sign-in attempt
+// with the lockout returned by the password verification attempt hook, or
+// with the built-in lockout policy when the hook returned none.
+func (a *API) applyPasswordLockout(r *http.Request, db *storage.Connection, user *models.User, isValidPassword bool, output *v0hooks.PasswordVerificationAttemptOutput) error {
+	config := a.config.Security.Lockout
+
+	switch output.Lockout {
+	case v0hooks.PasswordLockoutAllow:
+		if user.FailedSignInAttempts == 0 && user.LockedUntil == nil && !user.SignInCaptchaRequired {
+			return nil
+		}
+		if err := user.ResetFailedSignIns(db); err != nil {
+			return apierrors.NewInternalServerError("Database error unlocking user").WithInternalError(err)
+		}
+		return nil
+
+	case v0hooks.PasswordLockoutBlock:
+		lockedUntil := time.Now().Add(config.Duration)
+		if output.LockedUntil != nil {
+			lockedUntil = *output.LockedUntil
+		}
+
+		return db.Transaction(func(tx *storage.Connection) error {
+			if terr := user.LockUntil(tx, lockedUntil); terr != nil {
+				return apierrors.NewInternalServerError("Database error locking user").WithInternalError(terr)
+			}
+
+			return models.NewAuditLogEntry(a.config.AuditLog, r, tx, user, models.UserLockedAction, "", map[string]interface{}{
+				"failed_attempts": user.FailedSignInAttempts,
+				"locked_until":    user.LockedUntil,
+				"hook":            true,
+			})
+		})
+
+	case v0hooks.PasswordLockoutCaptcha:
+		if user.SignInCaptchaRequired {
+			return nil
+		}
+		if err := user.RequireSignInCaptcha(db); err != nil {
+			return apierrors.NewInternalServerError("Database error requiring captcha").WithInternalError(err)
+		}
+		return nil
+	}
+
+	if !isValidPassword && config.Enabled {
+		return a.recordFailedSignIn(r, db, user)
+	}
+	return nil
+}
+
+// recordFailedSignIn counts a failed password sign-in attempt of the user and
+// records an audit log entry when it causes the user to be locked.
+func (a *API) recordFailedSignIn(r *http.Request, db *storage.Connection, user *models.User) error {
+	config := a.config.Security.Lockout
+
+	return db.Transaction(func(tx *storage.Connection) error {
+		locked, terr := user.RecordFailedSignIn(tx, config.MaxAttempts, config.Duration, config.MaxDuration)
+		if terr != nil {
+			return apierrors.NewInternalServerError("Database error recording failed sign-in attempt").WithInternalError(terr)
+		}
+
+		if !locked {
+			return nil
+		}
+
+		return models.NewAuditLogEntry(a.config.AuditLog, r, tx, user, models.UserLockedAction, "", map[string]interface{}{
+			"failed_attempts": user.FailedSignInAttempts,
+			"locked_until":    user.LockedUntil,
+		})
+	})
 }
 
 func (a *API) PKCE(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
@@ -266,11 +397,15 @@ func (a *API) PKCE(ctx context.Context, w http.ResponseWriter, r *http.Request)
 			// error type is already handled in issueRefreshToken
 			return terr
 		}
-		token.ProviderAccessToken = flowState.ProviderAccessToken
+		providerAccessToken, providerRefreshToken, terr := flowState.GetProviderTokens(config.Security.DBEncryption)
+		if terr != nil {
+			return apierrors.NewInternalServerError("Error decrypting provider tokens").WithInternalError(terr)
+		}
+		token.ProviderAccessToken = providerAccessToken
 		// Because not all providers give out a refresh token
 		// See corresponding OAuth2 spec: <https://www.rfc-editor.org/rfc/rfc6749.html#section-5.1>
-		if flowState.ProviderRefreshToken != "" {
-			token.ProviderRefreshToken = flowState.ProviderRefreshToken
+		if providerRefreshToken != "" {
+			token.ProviderRefreshToken = providerRefreshToken
 		}
 		if terr = tx.Destroy(flowState); terr != nil {
 			return terr
@@ -284,7 +419,7 @@ func (a *API) PKCE(ctx context.Context, w http.ResponseWriter, r *http.Request)
 	metering.RecordLogin(metering.LoginTypePKCE, user.ID, &metering.LoginData{
 		Provider: flowState.ProviderType,
 	})
-	return sendJSON(w, http.StatusOK, token)
+	return a.sendTokenResponse(w, r, token)
 }
 
 func (a *API) generateAccessToken(r *http.Request, tx *storage.Connection, user *models.User, sessionId *uuid.UUID, authenticationMethod models.AuthenticationMethod) (string, int64, error) {
@@ -295,6 +430,25 @@ func (a *API) generateAccessToken(r *http.Request, tx *storage.Connection, user
 	})
 }
 
+// emitLoginFailed queues the login.failed webhook event of a password
+// sign-in, the user is nil when no user has the email or phone.
+func (a *API) emitLoginFailed(db *storage.Connection, user *models.User, params *PasswordGrantParams, provider, reason string) error {
+	data := map[string]interface{}{
+		"provider": provider,
+		"reason":   reason,
+	}
+	if params.Email != "" {
+		data["email"] = params.Email
+	} else {
+		data["phone"] = params.Phone
+	}
+
+	if err := webhooks.Emit(db, &a.config.Webhooks, conf.WebhookEventLoginFailed, user, data); err != nil {
+		return apierrors.NewInternalServerError("Error queueing webhook event").WithInternalError(err)
+	}
+	return nil
+}
+
 func (a *API) issueRefreshToken(r *http.Request, headers http.Header, conn *storage.Connection, user *models.User, authenticationMethod models.AuthenticationMethod, grantParams models.GrantParams) (*tokens.AccessTokenResponse, error) {
 	return a.tokenService.IssueRefreshToken(r, headers, conn, user, authenticationMethod, grantParams)
 }
@@ -394,6 +548,8 @@ func (a *API) updateMFASessionAndClaims(r *http.Request, tx *storage.Connection,
 	if err != nil {
 		return nil, err
 	}
+
+	metering.RecordTokenIssued(authenticationMethod.String())
 	return &tokens.AccessTokenResponse{
 		Token:        tokenString,
 		TokenType:    "bearer",
@@ -0,0 +1,151 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/supabase/auth/internal/api/apierrors"
+)
+
+// Token types and the grant type of RFC 8693 token exchange.
+const (
+	tokenExchangeGrantType = "urn:ietf:params:oauth:grant-type:token-exchange"
+	accessTokenType        = "urn:ietf:params:oauth:token-type:access_token"
+	jwtTokenType           = "urn:ietf:params:oauth:token-type:jwt"
+)
+
+// TokenExchangeGrantParams are the parameters the TokenExchangeGrant method
+// accepts.
+type TokenExchangeGrantParams struct {
+	SubjectToken       string `json:"subject_token"`
+	SubjectTokenType   string `json:"subject_token_type"`
+	Audience           string `json:"audience"`
+	Scope              string `json:"scope"`
+	RequestedTokenType string `json:"requested_token_type"`
+}
+
+// TokenExchangeResponse is the response of the token exchange grant.
+type TokenExchangeResponse struct {
+	Token           string `json:"access_token"`
+	IssuedTokenType string `json:"issued_token_type"`
+	TokenType       string `json:"token_type"`
+	ExpiresIn       int    `json:"expires_in"`
+	ExpiresAt       int64  `json:"expires_at"`
+	Scope           string `json:"scope,omitempty"`
+}
+
+// TokenExchangeGrant exchanges the access token of a session for a short
+// lived token of a downstream service, restricted to its audience and to a
+// subset of its scopes, and of the scopes of the access token when it has
+// any.
+func (a *API) TokenExchangeGrant(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	config := a.config
+
+	if !config.TokenExchange.Enabled {
+		return apierrors.NewBadRequestError(apierrors.ErrorCodeTokenExchangeDisabled, "Token exchange is disabled")
+	}
+
+	params := &TokenExchangeGrantParams{}
+	if err := retrieveRequestParams(r, params); err != nil {
+		return err
+	}
+
+	if params.SubjectToken == "" {
+		return apierrors.NewBadRequestError(apierrors.ErrorCodeValidationFailed, "subject_token is required")
+	}
+	if params.SubjectTokenType != "" && params.SubjectTokenType != accessTokenType && params.SubjectTokenType != jwtTokenType {
+		return apierrors.NewBadRequestError(apierrors.ErrorCodeValidationFailed, "subject_token_type must be %q", accessTokenType)
+	}
+	if params.RequestedTokenType != "" && params.RequestedTokenType != accessTokenType && params.RequestedTokenType != jwtTokenType {
+		return apierrors.NewBadRequestError(apierrors.ErrorCodeValidationFailed, "requested_token_type must be %q", accessTokenType)
+	}
+
+	audience := config.TokenExchange.Audiences.Find(params.Audience)
+	if audience == nil {
+		return apierrors.NewBadRequestError(apierrors.ErrorCodeInvalidTarget, "Tokens cannot be exchanged for audience %q", params.Audience)
+	}
+
+	scopes := audience.Scopes
+	if params.Scope != "" {
+		scopes = strings.Fields(params.Scope)
+		for _, scope := range scopes {
+			if !slices.Contains(audience.Scopes, scope) {
+				return apierrors.NewBadRequestError(apierrors.ErrorCodeInvalidTarget, "Scope %q is not allowed for audience %q", scope, audience.Audience)
+			}
+		}
+	}
+
+	ctx, err := a.parseJWTClaims(params.SubjectToken, r.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	ctx, err = a.maybeLoadUserOrSession(ctx)
+	if err != nil {
+		return err
+	}
+
+	// tokens of OAuth clients can only be exchanged for the scopes they
+	// were granted
+	claims := getClaims(ctx)
+	if claims.Scope != "" {
+		granted := strings.Fields(claims.Scope)
+		if params.Scope == "" {
+			scopes = slices.DeleteFunc(slices.Clone(scopes), func(scope string) bool {
+				return !slices.Contains(granted, scope)
+			})
+		}
+		for _, scope := range scopes {
+			if !slices.Contains(granted, scope) {
+				return apierrors.NewBadRequestError(apierrors.ErrorCodeInvalidTarget, "Scope %q was not granted to the subject token", scope)
+			}
+		}
+		if len(scopes) == 0 {
+			return apierrors.NewBadRequestError(apierrors.ErrorCodeInvalidTarget, "No scope of audience %q was granted to the subject token", audience.Audience)
+		}
+	}
+
+	user := getUser(ctx)
+	if getSession(ctx) == nil {
+		return apierrors.NewForbiddenError(apierrors.ErrorCodeSessionNotFound, "Only access tokens of sessions can be exchanged")
+	}
+	if user.IsBanned() {
+		return apierrors.NewBadRequestError(apierrors.ErrorCodeUserBanned, "User is banned")
+	}
+
+	token, expiresAt, err := a.tokenService.GenerateExchangedToken(user, claims, audience, scopes)
+	if err != nil {
+		return apierrors.NewInternalServerError("Error generating token").WithInternalError(err)
+	}
+
+	return sendJSON(w, http.StatusOK, &TokenExchangeResponse{
+		Token:           token,
+		IssuedTokenType: accessTokenType,
+		TokenType:       "bearer",
+		ExpiresIn:       audience.Exp,
+		ExpiresAt:       expiresAt,
+		Scope:           strings.Join(scopes, " "),
+	})
+}
+
+// TokenExchangeJwks returns the public keys of the tokens exchanged for the
+// audience, for the downstream service to verify them with.
+func (a *API) TokenExchangeJwks(w http.ResponseWriter, r *http.Request) error {
+	config := a.config
+
+	if !config.TokenExchange.Enabled {
+		return apierrors.NewBadRequestError(apierrors.ErrorCodeTokenExchangeDisabled, "Token exchange is disabled")
+	}
+
+	audience := config.TokenExchange.Audiences.Find(r.URL.Query().Get("audience"))
+	if audience == nil {
+		return apierrors.NewNotFoundError(apierrors.ErrorCodeInvalidTarget, "Tokens cannot be exchanged for audience %q", r.URL.Query().Get("audience"))
+	}
+
+	w.Header().Set("Cache-Control", "public, max-age=600")
+	return sendJSON(w, http.StatusOK, JwksResponse{
+		Keys: []jwk.Key{audience.PublicKey},
+	})
+}
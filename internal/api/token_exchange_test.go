@@ -0,0 +1,169 @@
+package api
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+
+	jwt "github.com/golang-jwt/jwt/v5"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/stretchr/testify/require"
+	"github.com/supabase/auth/internal/api/apierrors"
+	"github.com/supabase/auth/internal/conf"
+	"github.com/supabase/auth/internal/tokens"
+)
+
+func (ts *TokenTestSuite) TestTokenExchange() {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(ts.T(), err)
+	key, err := jwk.FromRaw(privateKey)
+	require.NoError(ts.T(), err)
+	require.NoError(ts.T(), key.Set(jwk.KeyIDKey, "billing"))
+	require.NoError(ts.T(), key.Set(jwk.AlgorithmKey, "ES256"))
+	rawKey, err := json.Marshal(key)
+	require.NoError(ts.T(), err)
+
+	var audiences conf.TokenExchangeAudiences
+	require.NoError(ts.T(), audiences.Decode(fmt.Sprintf(`[{"audience": "https://billing.example.com", "scopes": ["invoices:read", "invoices:write"], "exp": 60, "key": %s}]`, rawKey)))
+	require.NoError(ts.T(), audiences.Validate())
+
+	ts.Config.TokenExchange = conf.TokenExchangeConfiguration{
+		Enabled:   true,
+		Audiences: audiences,
+	}
+	defer func() {
+		ts.Config.TokenExchange = conf.TokenExchangeConfiguration{}
+	}()
+
+	var buffer bytes.Buffer
+	require.NoError(ts.T(), json.NewEncoder(&buffer).Encode(map[string]interface{}{
+		"email":    "test@example.com",
+		"password": "password",
+	}))
+	req := httptest.NewRequest(http.MethodPost, "http://localhost/token?grant_type=password", &buffer)
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	ts.API.handler.ServeHTTP(w, req)
+	require.Equal(ts.T(), http.StatusOK, w.Code, w.Body.String())
+
+	var session AccessTokenResponse
+	require.NoError(ts.T(), json.NewDecoder(w.Body).Decode(&session))
+
+	exchange := func(params TokenExchangeGrantParams) *httptest.ResponseRecorder {
+		var buffer bytes.Buffer
+		require.NoError(ts.T(), json.NewEncoder(&buffer).Encode(params))
+		req := httptest.NewRequest(http.MethodPost, "http://localhost/token?grant_type=token-exchange", &buffer)
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		ts.API.handler.ServeHTTP(w, req)
+		return w
+	}
+
+	cases := []struct {
+		desc   string
+		params TokenExchangeGrantParams
+		code   int
+		error  apierrors.ErrorCode
+	}{
+		{
+			desc:   "unknown audience",
+			params: TokenExchangeGrantParams{SubjectToken: session.Token, Audience: "https://other.example.com"},
+			code:   http.StatusBadRequest,
+			error:  apierrors.ErrorCodeInvalidTarget,
+		},
+		{
+			desc:   "scope not allowed for the audience",
+			params: TokenExchangeGrantParams{SubjectToken: session.Token, Audience: "https://billing.example.com", Scope: "invoices:read admin"},
+			code:   http.StatusBadRequest,
+			error:  apierrors.ErrorCodeInvalidTarget,
+		},
+		{
+			desc:   "invalid subject token",
+			params: TokenExchangeGrantParams{SubjectToken: "invalid", Audience: "https://billing.example.com"},
+			code:   http.StatusForbidden,
+			error:  apierrors.ErrorCodeBadJWT,
+		},
+	}
+	for _, c := range cases {
+		ts.Run(c.desc, func() {
+			w := exchange(c.params)
+			require.Equal(ts.T(), c.code, w.Code, w.Body.String())
+
+			data := make(map[string]interface{})
+			require.NoError(ts.T(), json.NewDecoder(w.Body).Decode(&data))
+			require.Equal(ts.T(), string(c.error), data["error_code"])
+		})
+	}
+
+	w = exchange(TokenExchangeGrantParams{
+		SubjectToken:     session.Token,
+		SubjectTokenType: accessTokenType,
+		Audience:         "https://billing.example.com",
+		Scope:            "invoices:read",
+	})
+	require.Equal(ts.T(), http.StatusOK, w.Code, w.Body.String())
+
+	var res TokenExchangeResponse
+	require.NoError(ts.T(), json.NewDecoder(w.Body).Decode(&res))
+	require.Equal(ts.T(), accessTokenType, res.IssuedTokenType)
+	require.Equal(ts.T(), 60, res.ExpiresIn)
+	require.Equal(ts.T(), "invoices:read", res.Scope)
+
+	// the downstream service verifies the token with the public key of
+	// its audience
+	req = httptest.NewRequest(http.MethodGet, "http://localhost/token-exchange/jwks?audience=https://billing.example.com", nil)
+	w = httptest.NewRecorder()
+	ts.API.handler.ServeHTTP(w, req)
+	require.Equal(ts.T(), http.StatusOK, w.Code, w.Body.String())
+
+	keys, err := jwk.Parse(w.Body.Bytes())
+	require.NoError(ts.T(), err)
+	require.Equal(ts.T(), 1, keys.Len())
+	publicKey, _ := keys.Key(0)
+	var rawPublicKey ecdsa.PublicKey
+	require.NoError(ts.T(), publicKey.Raw(&rawPublicKey))
+
+	claims := &jwt.MapClaims{}
+	_, err = jwt.ParseWithClaims(res.Token, claims, func(token *jwt.Token) (interface{}, error) {
+		require.Equal(ts.T(), "billing", token.Header["kid"])
+		return &rawPublicKey, nil
+	}, jwt.WithAudience("https://billing.example.com"))
+	require.NoError(ts.T(), err)
+	require.Equal(ts.T(), ts.User.ID.String(), (*claims)["sub"])
+	require.Equal(ts.T(), "invoices:read", (*claims)["scope"])
+
+	// the session and how it was authenticated are those of the subject
+	// token
+	subject := &AccessTokenClaims{}
+	_, _, err = jwt.NewParser().ParseUnverified(session.Token, subject)
+	require.NoError(ts.T(), err)
+	require.Equal(ts.T(), subject.SessionId, (*claims)["session_id"])
+	require.Equal(ts.T(), subject.AuthenticatorAssuranceLevel, (*claims)["aal"])
+	require.Len(ts.T(), (*claims)["amr"], 1)
+
+	// tokens with scopes are only exchanged for the scopes they were
+	// granted
+	subject.Scope = "openid invoices:read"
+	scopedToken, err := tokens.SignJWT(&ts.Config.JWT, subject)
+	require.NoError(ts.T(), err)
+
+	w = exchange(TokenExchangeGrantParams{SubjectToken: scopedToken, Audience: "https://billing.example.com", Scope: "invoices:write"})
+	require.Equal(ts.T(), http.StatusBadRequest, w.Code, w.Body.String())
+
+	w = exchange(TokenExchangeGrantParams{SubjectToken: scopedToken, Audience: "https://billing.example.com"})
+	require.Equal(ts.T(), http.StatusOK, w.Code, w.Body.String())
+	require.NoError(ts.T(), json.NewDecoder(w.Body).Decode(&res))
+	require.Equal(ts.T(), "invoices:read", res.Scope)
+
+	// Auth does not accept the exchanged token
+	req = httptest.NewRequest(http.MethodGet, "http://localhost/user", nil)
+	req.Header.Set("Authorization", "Bearer "+res.Token)
+	w = httptest.NewRecorder()
+	ts.API.handler.ServeHTTP(w, req)
+	require.Equal(ts.T(), http.StatusForbidden, w.Code)
+}
@@ -27,6 +27,10 @@ type IdTokenGrantParams struct {
 	ClientID     string `json:"client_id"`
 	Issuer       string `json:"issuer"`
 	LinkIdentity bool   `json:"link_identity"`
+
+	// User is the one-time user payload of Sign in with Apple, which holds
+	// the user's name on the first sign-in only.
+	User *provider.AppleUser `json:"user"`
 }
 
 func (p *IdTokenGrantParams) getProvider(ctx context.Context, config *conf.GlobalConfiguration, r *http.Request) (*oidc.Provider, bool, string, []string, bool, error) {
@@ -87,7 +91,7 @@ func (p *IdTokenGrantParams) getProvider(ctx context.Context, config *conf.Globa
 		}
 
 		issuer = detectedIssuer
-		cfg = &config.External.Azure
+		cfg = &config.External.Azure.OAuthProviderConfiguration
 		providerType = "azure"
 		acceptableClientIDs = append(acceptableClientIDs, config.External.Azure.ClientID...)
 
@@ -99,10 +103,10 @@ func (p *IdTokenGrantParams) getProvider(ctx context.Context, config *conf.Globa
 		issuer = provider.IssuerFacebook
 		acceptableClientIDs = append(acceptableClientIDs, config.External.Facebook.ClientID...)
 
-	case p.Provider == "keycloak" || (config.External.Keycloak.Enabled && config.External.Keycloak.URL != "" && p.Issuer == config.External.Keycloak.URL):
-		cfg = &config.External.Keycloak
+	case p.Provider == "keycloak" || (config.External.Keycloak.Enabled && config.External.Keycloak.URL != "" && p.Issuer == config.External.Keycloak.RealmURL()):
+		cfg = &config.External.Keycloak.OAuthProviderConfiguration
 		providerType = "keycloak"
-		issuer = config.External.Keycloak.URL
+		issuer = config.External.Keycloak.RealmURL()
 		acceptableClientIDs = append(acceptableClientIDs, config.External.Keycloak.ClientID...)
 
 	case p.Provider == "kakao" || p.Issuer == provider.IssuerKakao:
@@ -222,6 +226,12 @@ func (a *API) IdTokenGrant(ctx context.Context, w http.ResponseWriter, r *http.R
 		return apierrors.NewOAuthError("invalid request", "Bad ID token").WithInternalError(err)
 	}
 
+	if providerType == "azure" {
+		if err := provider.ValidateAzureTenant(userData, config.External.Azure.AllowedTenants); err != nil {
+			return apierrors.NewForbiddenError(apierrors.ErrorCodeProviderTenantNotAllowed, "Azure tenant is not allowed").WithInternalError(err)
+		}
+	}
+
 	userData.Metadata.EmailVerified = false
 	for _, email := range userData.Emails {
 		if email.Primary {
@@ -238,6 +248,14 @@ func (a *API) IdTokenGrant(ctx context.Context, w http.ResponseWriter, r *http.R
 		return apierrors.NewOAuthError("invalid request", "Missing sub claim in id_token")
 	}
 
+	if params.User != nil {
+		if providerType != "apple" {
+			return apierrors.NewOAuthError("invalid request", "user is only supported with the apple provider")
+		}
+
+		params.User.Apply(userData)
+	}
+
 	correctAudience := false
 	for _, clientID := range acceptableClientIDs {
 		if clientID == "" {
@@ -332,5 +350,5 @@ func (a *API) IdTokenGrant(ctx context.Context, w http.ResponseWriter, r *http.R
 		Provider: providerType,
 	})
 
-	return sendJSON(w, http.StatusOK, token)
+	return a.sendTokenResponse(w, r, token)
 }
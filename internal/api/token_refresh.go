@@ -45,6 +45,14 @@ func (a *API) RefreshTokenGrant(ctx context.Context, w http.ResponseWriter, r *h
 		return err
 	}
 
+	if params.RefreshToken == "" && a.useSessionCookie(r) {
+		refreshToken, err := a.sessionCookieRefreshToken(r)
+		if err != nil {
+			return err
+		}
+		params.RefreshToken = refreshToken
+	}
+
 	if err := params.Validate(); err != nil {
 		return err
 	}
@@ -57,5 +65,5 @@ func (a *API) RefreshTokenGrant(ctx context.Context, w http.ResponseWriter, r *h
 		return err
 	}
 
-	return sendJSON(w, http.StatusOK, tokenResponse)
+	return a.sendTokenResponse(w, r, tokenResponse)
 }
@@ -224,6 +224,86 @@ func (ts *TokenTestSuite) TestSingleSessionPerUserNoTags() {
 	assert.Equal(ts.T(), "Invalid Refresh Token: Session Expired (Revoked by Newer Login)", firstResult.Message)
 }
 
+func (ts *TokenTestSuite) TestRoleSessionTimebox() {
+	ts.API.config.Sessions.RoleTimebox = map[string]time.Duration{"admin": 12 * time.Hour}
+	ts.API.overrideTime = func() time.Time {
+		return time.Now().Add(13 * time.Hour)
+	}
+
+	defer func() {
+		ts.API.overrideTime = nil
+		ts.API.config.Sessions.RoleTimebox = nil
+	}()
+
+	refresh := func(token string) *httptest.ResponseRecorder {
+		var buffer bytes.Buffer
+		require.NoError(ts.T(), json.NewEncoder(&buffer).Encode(map[string]interface{}{
+			"refresh_token": token,
+		}))
+
+		req := httptest.NewRequest(http.MethodPost, "http://localhost/token?grant_type=refresh_token", &buffer)
+		req.Header.Set("Content-Type", "application/json")
+
+		w := httptest.NewRecorder()
+		ts.API.handler.ServeHTTP(w, req)
+		return w
+	}
+
+	// the sessions of users without the role are not timeboxed
+	w := refresh(ts.RefreshToken.Token)
+	require.Equal(ts.T(), http.StatusOK, w.Code, w.Body.String())
+
+	require.NoError(ts.T(), ts.User.SetRole(ts.API.db, "admin"))
+	token, err := models.GrantAuthenticatedUser(ts.API.db, ts.User, models.GrantParams{})
+	require.NoError(ts.T(), err)
+
+	w = refresh(token.Token)
+	require.Equal(ts.T(), http.StatusBadRequest, w.Code)
+
+	var result struct {
+		ErrorCode string `json:"error_code"`
+	}
+	require.NoError(ts.T(), json.NewDecoder(w.Body).Decode(&result))
+	require.Equal(ts.T(), apierrors.ErrorCodeSessionExpired, result.ErrorCode)
+}
+
+func (ts *TokenTestSuite) TestSingleSessionPerUserRevokedOnSignIn() {
+	ts.API.config.Sessions.SinglePerUserRoles = []string{"admin"}
+	defer func() {
+		ts.API.config.Sessions.SinglePerUserRoles = nil
+	}()
+
+	signIn := func() {
+		var buffer bytes.Buffer
+		require.NoError(ts.T(), json.NewEncoder(&buffer).Encode(map[string]interface{}{
+			"email":    "test@example.com",
+			"password": "password",
+		}))
+
+		req := httptest.NewRequest(http.MethodPost, "http://localhost/token?grant_type=password", &buffer)
+		req.Header.Set("Content-Type", "application/json")
+
+		w := httptest.NewRecorder()
+		ts.API.handler.ServeHTTP(w, req)
+		require.Equal(ts.T(), http.StatusOK, w.Code, w.Body.String())
+	}
+
+	// users without the role can have more than one session
+	signIn()
+	sessions, err := models.FindAllSessionsForUser(ts.API.db, ts.User.ID, false)
+	require.NoError(ts.T(), err)
+	require.Len(ts.T(), sessions, 2)
+
+	require.NoError(ts.T(), ts.User.SetRole(ts.API.db, "admin"))
+	signIn()
+	sessions, err = models.FindAllSessionsForUser(ts.API.db, ts.User.ID, false)
+	require.NoError(ts.T(), err)
+	require.Len(ts.T(), sessions, 1)
+
+	_, err = models.FindSessionByID(ts.API.db, *ts.RefreshToken.SessionId, false)
+	require.True(ts.T(), models.IsNotFoundError(err))
+}
+
 func (ts *TokenTestSuite) TestRateLimitTokenRefresh() {
 	var buffer bytes.Buffer
 	req := httptest.NewRequest(http.MethodPost, "http://localhost/token?grant_type=refresh_token", &buffer)
@@ -315,6 +395,76 @@ func (ts *TokenTestSuite) TestTokenRefreshTokenGrantSuccess() {
 	assert.Equal(ts.T(), http.StatusOK, w.Code)
 }
 
+func (ts *TokenTestSuite) TestTokenSessionCookie() {
+	ts.Config.Sessions.Cookie = conf.SessionCookieConfiguration{
+		Enabled:  true,
+		Name:     "sb-refresh-token",
+		Path:     "/",
+		SameSite: "strict",
+		MaxAge:   time.Hour,
+	}
+	defer func() {
+		ts.Config.Sessions.Cookie = conf.SessionCookieConfiguration{}
+	}()
+
+	token := func(grantType string, body map[string]interface{}, cookies []*http.Cookie, csrfToken string) *httptest.ResponseRecorder {
+		var buffer bytes.Buffer
+		require.NoError(ts.T(), json.NewEncoder(&buffer).Encode(body))
+		req := httptest.NewRequest(http.MethodPost, "http://localhost/token?grant_type="+grantType, &buffer)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(useCookieHeader, "true")
+		if csrfToken != "" {
+			req.Header.Set(csrfHeaderName, csrfToken)
+		}
+		for _, cookie := range cookies {
+			req.AddCookie(cookie)
+		}
+		w := httptest.NewRecorder()
+		ts.API.handler.ServeHTTP(w, req)
+		return w
+	}
+
+	w := token("password", map[string]interface{}{
+		"email":    "test@example.com",
+		"password": "password",
+	}, nil, "")
+	require.Equal(ts.T(), http.StatusOK, w.Code, w.Body.String())
+
+	var data AccessTokenResponse
+	require.NoError(ts.T(), json.NewDecoder(w.Body).Decode(&data))
+	require.NotEmpty(ts.T(), data.Token)
+	require.Empty(ts.T(), data.RefreshToken, "the refresh token is only in the cookie")
+
+	cookies := w.Result().Cookies()
+	require.Len(ts.T(), cookies, 2)
+	for _, cookie := range cookies {
+		require.True(ts.T(), cookie.Secure)
+		require.Equal(ts.T(), http.SameSiteStrictMode, cookie.SameSite)
+		require.Equal(ts.T(), 3600, cookie.MaxAge)
+	}
+	require.Equal(ts.T(), "sb-refresh-token", cookies[0].Name)
+	require.True(ts.T(), cookies[0].HttpOnly)
+	// the CSRF token can be read by the application after a page reload
+	require.Equal(ts.T(), "sb-refresh-token-csrf", cookies[1].Name)
+	require.False(ts.T(), cookies[1].HttpOnly)
+	csrfToken := w.Header().Get(csrfHeaderName)
+	require.Equal(ts.T(), cookies[1].Value, csrfToken)
+
+	// the cookie is not enough without the CSRF token
+	w = token("refresh_token", map[string]interface{}{}, cookies, "")
+	require.Equal(ts.T(), http.StatusForbidden, w.Code)
+	w = token("refresh_token", map[string]interface{}{}, cookies, "wrong")
+	require.Equal(ts.T(), http.StatusForbidden, w.Code)
+
+	w = token("refresh_token", map[string]interface{}{}, cookies, csrfToken)
+	require.Equal(ts.T(), http.StatusOK, w.Code, w.Body.String())
+
+	refreshed := w.Result().Cookies()
+	require.Len(ts.T(), refreshed, 2)
+	require.NotEqual(ts.T(), cookies[0].Value, refreshed[0].Value)
+	require.NotEqual(ts.T(), csrfToken, w.Header().Get(csrfHeaderName))
+}
+
 func (ts *TokenTestSuite) TestTokenPasswordGrantFailure() {
 	u := ts.createBannedUser()
 
@@ -332,6 +482,69 @@ func (ts *TokenTestSuite) TestTokenPasswordGrantFailure() {
 	assert.Equal(ts.T(), http.StatusBadRequest, w.Code)
 }
 
+func (ts *TokenTestSuite) TestTokenPasswordGrantLockout() {
+	ts.Config.Security.Lockout = conf.LockoutConfiguration{
+		Enabled:     true,
+		MaxAttempts: 2,
+		Duration:    time.Minute,
+		MaxDuration: time.Hour,
+	}
+	defer func() {
+		ts.Config.Security.Lockout = conf.LockoutConfiguration{}
+	}()
+
+	signIn := func(password string) *httptest.ResponseRecorder {
+		var buffer bytes.Buffer
+		require.NoError(ts.T(), json.NewEncoder(&buffer).Encode(map[string]interface{}{
+			"email":    "test@example.com",
+			"password": password,
+		}))
+
+		req := httptest.NewRequest(http.MethodPost, "http://localhost/token?grant_type=password", &buffer)
+		req.Header.Set("Content-Type", "application/json")
+
+		w := httptest.NewRecorder()
+		ts.API.handler.ServeHTTP(w, req)
+		return w
+	}
+
+	// a successful sign-in resets the failed attempts
+	require.Equal(ts.T(), http.StatusBadRequest, signIn("wrong").Code)
+	require.Equal(ts.T(), http.StatusOK, signIn("password").Code)
+
+	u, err := models.FindUserByID(ts.API.db, ts.User.ID)
+	require.NoError(ts.T(), err)
+	require.Equal(ts.T(), 0, u.FailedSignInAttempts)
+
+	require.Equal(ts.T(), http.StatusBadRequest, signIn("wrong").Code)
+	require.Equal(ts.T(), http.StatusBadRequest, signIn("wrong").Code)
+
+	u, err = models.FindUserByID(ts.API.db, ts.User.ID)
+	require.NoError(ts.T(), err)
+	require.Equal(ts.T(), 2, u.FailedSignInAttempts)
+	require.True(ts.T(), u.IsLocked())
+	require.WithinDuration(ts.T(), time.Now().Add(time.Minute), *u.LockedUntil, 5*time.Second)
+
+	// the correct password is rejected while locked, with the error of
+	// unknown users
+	w := signIn("password")
+	require.Equal(ts.T(), http.StatusBadRequest, w.Code)
+
+	var data map[string]interface{}
+	require.NoError(ts.T(), json.NewDecoder(w.Body).Decode(&data))
+	require.Equal(ts.T(), string(apierrors.ErrorCodeInvalidCredentials), data["error_code"])
+	require.Equal(ts.T(), InvalidLoginMessage, data["msg"])
+
+	// further failed attempts double the lockout duration
+	require.NoError(ts.T(), u.ResetFailedSignIns(ts.API.db))
+	for i := 0; i < 3; i++ {
+		_, err := u.RecordFailedSignIn(ts.API.db, 2, time.Minute, time.Hour)
+		require.NoError(ts.T(), err)
+	}
+	require.Equal(ts.T(), 3, u.FailedSignInAttempts)
+	require.WithinDuration(ts.T(), time.Now().Add(2*time.Minute), *u.LockedUntil, 5*time.Second)
+}
+
 func (ts *TokenTestSuite) TestTokenPKCEGrantFailure() {
 	authCode := "1234563"
 	codeVerifier := "4a9505b9-0857-42bb-ab3c-098b4d28ddc2"
@@ -477,6 +690,11 @@ func (ts *TokenTestSuite) TestRefreshTokenReuseRevocation() {
 	require.Equal(ts.T(), apierrors.ErrorCodeRefreshTokenAlreadyUsed, response.ErrorCode)
 	require.Equal(ts.T(), "Invalid Refresh Token: Already Used", response.Message)
 
+	// ensure that the reuse was recorded in the audit log
+	logs, err := models.FindAuditLogEntries(ts.API.db, []string{"action"}, string(models.TokenReuseDetectedAction), nil, nil)
+	require.NoError(ts.T(), err)
+	require.Len(ts.T(), logs, 1)
+
 	// ensure that the refresh tokens are marked as revoked in the database
 	for _, refreshToken := range refreshTokens {
 		_, anyToken, _, err := models.FindUserWithRefreshToken(ts.API.db, ts.Config.Security.DBEncryption, refreshToken, false)
@@ -694,6 +912,81 @@ func (ts *TokenTestSuite) TestPasswordVerificationHook() {
 
 }
 
+func (ts *TokenTestSuite) TestPasswordVerificationHookLockout() {
+	ts.Config.Hook.PasswordVerificationAttempt.Enabled = true
+	ts.Config.Hook.PasswordVerificationAttempt.URI = "pg-functions://postgres/auth/password_verification_hook_lockout"
+	require.NoError(ts.T(), ts.Config.Hook.PasswordVerificationAttempt.PopulateExtensibilityPoint())
+	defer func() {
+		ts.Config.Hook.PasswordVerificationAttempt.Enabled = false
+		require.NoError(ts.T(), ts.API.db.RawQuery("drop function if exists password_verification_hook_lockout").Exec())
+	}()
+
+	setLockout := func(lockout string) {
+		require.NoError(ts.T(), ts.API.db.RawQuery(fmt.Sprintf(`
+            create or replace function password_verification_hook_lockout(input jsonb)
+            returns jsonb as $$
+            begin
+                if (input->>'valid')::boolean then
+                    return jsonb_build_object('decision', 'continue');
+                end if;
+                return jsonb_build_object(
+                    'decision', 'continue',
+                    'lockout', '%s',
+                    'locked_until', to_jsonb(now() + interval '1 hour')
+                );
+            end; $$ language plpgsql;`, lockout)).Exec())
+	}
+
+	signIn := func(password string) *httptest.ResponseRecorder {
+		var buffer bytes.Buffer
+		require.NoError(ts.T(), json.NewEncoder(&buffer).Encode(map[string]interface{}{
+			"email":    "test@example.com",
+			"password": password,
+		}))
+
+		req := httptest.NewRequest(http.MethodPost, "http://localhost/token?grant_type=password", &buffer)
+		req.Header.Set("Content-Type", "application/json")
+
+		w := httptest.NewRecorder()
+		ts.API.handler.ServeHTTP(w, req)
+		return w
+	}
+
+	// the hook requires a captcha, which a successful sign-in clears
+	setLockout("captcha")
+	require.Equal(ts.T(), http.StatusBadRequest, signIn("wrong").Code)
+
+	u, err := models.FindUserByID(ts.API.db, ts.User.ID)
+	require.NoError(ts.T(), err)
+	require.True(ts.T(), u.SignInCaptchaRequired)
+	require.False(ts.T(), u.IsLocked())
+
+	require.Equal(ts.T(), http.StatusOK, signIn("password").Code)
+
+	u, err = models.FindUserByID(ts.API.db, ts.User.ID)
+	require.NoError(ts.T(), err)
+	require.False(ts.T(), u.SignInCaptchaRequired)
+
+	// the hook locks the user without the built-in lockout policy
+	setLockout("block")
+	require.Equal(ts.T(), http.StatusBadRequest, signIn("wrong").Code)
+
+	u, err = models.FindUserByID(ts.API.db, ts.User.ID)
+	require.NoError(ts.T(), err)
+	require.True(ts.T(), u.IsLocked())
+	require.WithinDuration(ts.T(), time.Now().Add(time.Hour), *u.LockedUntil, 5*time.Second)
+
+	w := signIn("password")
+	require.Equal(ts.T(), http.StatusBadRequest, w.Code)
+
+	var data map[string]interface{}
+	require.NoError(ts.T(), json.NewDecoder(w.Body).Decode(&data))
+	require.Equal(ts.T(), string(apierrors.ErrorCodeInvalidCredentials), data["error_code"])
+	require.Equal(ts.T(), InvalidLoginMessage, data["msg"])
+
+	require.NoError(ts.T(), u.ResetFailedSignIns(ts.API.db))
+}
+
 func (ts *TokenTestSuite) TestCustomAccessToken() {
 	type customAccessTokenTestcase struct {
 		desc            string
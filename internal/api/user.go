@@ -9,9 +9,11 @@ import (
 	"github.com/sirupsen/logrus"
 	"github.com/supabase/auth/internal/api/apierrors"
 	"github.com/supabase/auth/internal/api/sms_provider"
+	"github.com/supabase/auth/internal/conf"
 	"github.com/supabase/auth/internal/mailer"
 	"github.com/supabase/auth/internal/models"
 	"github.com/supabase/auth/internal/storage"
+	"github.com/supabase/auth/internal/webhooks"
 )
 
 // UserUpdateParams parameters for updating a user
@@ -27,7 +29,14 @@ type UserUpdateParams struct {
 	CodeChallengeMethod string                 `json:"code_challenge_method"`
 }
 
-func (a *API) validateUserUpdateParams(ctx context.Context, p *UserUpdateParams) error {
+// UserDeleteParams are the credentials users confirm deleting their own
+// account with, either their password or a reauthentication nonce.
+type UserDeleteParams struct {
+	Password string `json:"password"`
+	Nonce    string `json:"nonce"`
+}
+
+func (a *API) validateUserUpdateParams(ctx context.Context, user *models.User, p *UserUpdateParams) error {
 	config := a.config
 
 	var err error
@@ -51,7 +60,7 @@ func (a *API) validateUserUpdateParams(ctx context.Context, p *UserUpdateParams)
 	}
 
 	if p.Password != nil {
-		if err := a.checkPasswordStrength(ctx, *p.Password); err != nil {
+		if err := a.checkPasswordStrength(ctx, *p.Password, user.GetEmail(), p.Email); err != nil {
 			return err
 		}
 	}
@@ -92,7 +101,7 @@ func (a *API) UserUpdate(w http.ResponseWriter, r *http.Request) error {
 	user := getUser(ctx)
 	session := getSession(ctx)
 
-	if err := a.validateUserUpdateParams(ctx, params); err != nil {
+	if err := a.validateUserUpdateParams(ctx, user, params); err != nil {
 		return err
 	}
 
@@ -146,20 +155,27 @@ func (a *API) UserUpdate(w http.ResponseWriter, r *http.Request) error {
 		}
 	}
 
-	if params.Password != nil {
-		if config.Security.UpdatePasswordRequireReauthentication {
-			now := time.Now()
-			// we require reauthentication if the user hasn't signed in recently in the current session
-			if session == nil || now.After(session.CreatedAt.Add(24*time.Hour)) {
-				if len(params.Nonce) == 0 {
-					return apierrors.NewBadRequestError(apierrors.ErrorCodeReauthenticationNeeded, "Password update requires reauthentication")
-				}
-				if err := a.verifyReauthentication(params.Nonce, db, config, user); err != nil {
-					return err
-				}
+	// we require reauthentication if the user hasn't signed in recently in the current session
+	if session == nil || time.Now().After(session.CreatedAt.Add(config.Security.ReauthenticationMaxSessionAge)) {
+		var reauthenticationErr error
+		switch {
+		case params.Password != nil && config.Security.UpdatePasswordRequireReauthentication:
+			reauthenticationErr = apierrors.NewBadRequestError(apierrors.ErrorCodeReauthenticationNeeded, "Password update requires reauthentication")
+		case params.Email != "" && params.Email != user.GetEmail() && config.Security.UpdateEmailRequireReauthentication:
+			reauthenticationErr = apierrors.NewBadRequestError(apierrors.ErrorCodeReauthenticationNeeded, "Email update requires reauthentication")
+		}
+		if reauthenticationErr != nil {
+			if len(params.Nonce) == 0 {
+				return reauthenticationErr
+			}
+			if err := a.verifyReauthentication(params.Nonce, db, config, user); err != nil {
+				return err
 			}
 		}
+	}
 
+	var pwnedPassword bool
+	if params.Password != nil {
 		password := *params.Password
 		if password != "" {
 			isSamePassword := false
@@ -181,6 +197,11 @@ func (a *API) UserUpdate(w http.ResponseWriter, r *http.Request) error {
 		if err := user.SetPassword(ctx, password, config.Security.DBEncryption.Encrypt, config.Security.DBEncryption.EncryptionKeyID, config.Security.DBEncryption.EncryptionKey); err != nil {
 			return err
 		}
+
+		var err error
+		if pwnedPassword, err = a.shouldAuditPwnedPassword(ctx, password); err != nil {
+			return err
+		}
 	}
 
 	err := db.Transaction(func(tx *storage.Connection) error {
@@ -199,6 +220,16 @@ func (a *API) UserUpdate(w http.ResponseWriter, r *http.Request) error {
 				return terr
 			}
 
+			if terr := webhooks.Emit(tx, &config.Webhooks, conf.WebhookEventPasswordChanged, user, nil); terr != nil {
+				return apierrors.NewInternalServerError("Error queueing webhook event").WithInternalError(terr)
+			}
+
+			if pwnedPassword {
+				if terr := models.NewAuditLogEntry(config.AuditLog, r, tx, user, models.PasswordPwnedAction, "", nil); terr != nil {
+					return terr
+				}
+			}
+
 			// send a Password Changed email notification to the user to inform them that their password has been changed
 			if config.Mailer.Notifications.PasswordChangedEnabled && user.GetEmail() != "" {
 				if err := a.sendPasswordChangedNotification(r, tx, user); err != nil {
@@ -275,3 +306,51 @@ func (a *API) UserUpdate(w http.ResponseWriter, r *http.Request) error {
 
 	return sendJSON(w, http.StatusOK, user)
 }
+
+// UserDelete deletes the account of the current user once they confirmed it
+// with their password or a reauthentication nonce. The account is soft
+// deleted and kept until its grace period ends when purging is enabled.
+func (a *API) UserDelete(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	db := a.db.WithContext(ctx)
+	config := a.config
+
+	params := &UserDeleteParams{}
+	if err := retrieveRequestParams(r, params); err != nil {
+		return err
+	}
+
+	user := getUser(ctx)
+	session := getSession(ctx)
+
+	if user.HasMFAEnabled() && !session.IsAAL2() {
+		return apierrors.NewHTTPError(http.StatusUnauthorized, apierrors.ErrorCodeInsufficientAAL, "AAL2 session is required to delete your account when MFA is enabled.")
+	}
+
+	switch {
+	case user.IsAnonymous:
+		// anonymous users have no credentials to confirm the deletion with
+	case params.Password != "":
+		authenticated, _, err := user.Authenticate(ctx, db, params.Password, config.Security.DBEncryption.DecryptionKeys, false, "")
+		if err != nil {
+			return apierrors.NewInternalServerError("Error verifying password").WithInternalError(err)
+		}
+		if !authenticated {
+			return apierrors.NewBadRequestError(apierrors.ErrorCodeInvalidCredentials, "Invalid password")
+		}
+	case params.Nonce != "":
+		if err := db.Transaction(func(tx *storage.Connection) error {
+			return a.verifyReauthentication(params.Nonce, tx, config, user)
+		}); err != nil {
+			return err
+		}
+	default:
+		return apierrors.NewBadRequestError(apierrors.ErrorCodeReauthenticationNeeded, "Deleting your account requires reauthentication")
+	}
+
+	if err := a.deleteUser(r, user, user, config.UserDeletion.PurgeEnabled, false); err != nil {
+		return err
+	}
+
+	return sendJSON(w, http.StatusOK, map[string]interface{}{})
+}
@@ -0,0 +1,248 @@
+package api
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/gofrs/uuid"
+	"github.com/supabase/auth/internal/api/apierrors"
+	"github.com/supabase/auth/internal/api/apitask"
+	"github.com/supabase/auth/internal/models"
+	"github.com/supabase/auth/internal/storage"
+)
+
+// userExportFileName is the name of the JSON document in zipped exports.
+const userExportFileName = "user-export.json"
+
+// UserExportArchive is the document holding the data of a user export. The
+// user holds the profile with the identities and MFA factors of the user.
+type UserExportArchive struct {
+	ExportedAt  time.Time               `json:"exported_at"`
+	User        *models.User            `json:"user"`
+	Sessions    []*SessionResponse      `json:"sessions"`
+	AuditEvents []*models.AuditLogEntry `json:"audit_events"`
+}
+
+// UserExport requests an export of the data of the current user. The
+// export is generated in the background and its download link is sent to
+// the user by email.
+func (a *API) UserExport(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	db := a.db.WithContext(ctx)
+	config := a.config
+	user := getUser(ctx)
+
+	if user.GetEmail() == "" {
+		return apierrors.NewUnprocessableEntityError(apierrors.ErrorCodeValidationFailed, "Exporting your data requires an email address to send the download link to")
+	}
+
+	format := models.UserExportJSON
+	switch value := models.UserExportFormat(r.URL.Query().Get("format")); value {
+	case "":
+	case models.UserExportJSON, models.UserExportZip:
+		format = value
+	default:
+		return apierrors.NewBadRequestError(apierrors.ErrorCodeValidationFailed, "format must be json or zip")
+	}
+
+	latest, err := models.FindLatestUserExport(db, user.ID)
+	if err != nil && !models.IsNotFoundError(err) {
+		return apierrors.NewInternalServerError("Database error finding user export").WithInternalError(err)
+	}
+	if latest != nil && latest.CreatedAt.Add(config.UserExport.Frequency).After(time.Now()) {
+		return apierrors.NewTooManyRequestsError(apierrors.ErrorCodeOverRequestRateLimit, "%s", generateFrequencyLimitErrorMessage(&latest.CreatedAt, config.UserExport.Frequency))
+	}
+
+	export := models.NewUserExport(user.ID, format)
+	err = db.Transaction(func(tx *storage.Connection) error {
+		if terr := models.DeleteUserExports(tx, user.ID); terr != nil {
+			return apierrors.NewInternalServerError("Database error deleting user exports").WithInternalError(terr)
+		}
+
+		if terr := tx.Create(export); terr != nil {
+			return apierrors.NewInternalServerError("Database error creating user export").WithInternalError(terr)
+		}
+
+		if terr := models.NewAuditLogEntry(config.AuditLog, r, tx, user, models.UserDataExportRequestedAction, "", map[string]interface{}{
+			"export_id": export.ID,
+			"format":    export.Format,
+		}); terr != nil {
+			return apierrors.NewInternalServerError("Error recording audit log entry").WithInternalError(terr)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	task := apitask.Func("user_export", func(ctx context.Context) error {
+		return a.generateUserExport(r.WithContext(ctx), user, export)
+	})
+	if err := apitask.Run(ctx, task); err != nil {
+		return err
+	}
+
+	return sendJSON(w, http.StatusAccepted, export)
+}
+
+// generateUserExport stores the archive of the export and sends its
+// download link to the user.
+func (a *API) generateUserExport(r *http.Request, user *models.User, export *models.UserExport) error {
+	ctx := r.Context()
+	db := a.db.WithContext(ctx)
+	config := a.config
+
+	data, err := a.buildUserExport(db, user.ID, export.Format)
+	if err != nil {
+		if ferr := export.Fail(db); ferr != nil {
+			return ferr
+		}
+		return err
+	}
+
+	if err := export.Complete(db, data, time.Now().Add(config.UserExport.Expiry)); err != nil {
+		return err
+	}
+
+	return a.sendDataExportNotification(r, db, user, a.userExportDownloadURL(ctx, export))
+}
+
+// buildUserExport returns the archive of the data of the user in the
+// format.
+func (a *API) buildUserExport(db *storage.Connection, userID uuid.UUID, format models.UserExportFormat) ([]byte, error) {
+	user, err := models.FindUserByID(db, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	sessions, err := models.FindAllSessionsForUser(db, user.ID, false)
+	if err != nil {
+		return nil, err
+	}
+
+	auditEvents, err := models.FindUserAuditLogEntries(db, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	archive := &UserExportArchive{
+		ExportedAt:  time.Now(),
+		User:        user,
+		Sessions:    make([]*SessionResponse, 0, len(sessions)),
+		AuditEvents: auditEvents,
+	}
+	for _, session := range sessions {
+		archive.Sessions = append(archive.Sessions, newSessionResponse(session, nil))
+	}
+
+	data, err := json.MarshalIndent(archive, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	if format != models.UserExportZip {
+		return data, nil
+	}
+
+	buf := new(bytes.Buffer)
+	zw := zip.NewWriter(buf)
+	f, err := zw.CreateHeader(&zip.FileHeader{
+		Name:     userExportFileName,
+		Method:   zip.Deflate,
+		Modified: archive.ExportedAt.In(time.UTC),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := f.Write(data); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// userExportDownloadURL returns the download link of the export, which is
+// signed with the JWT secret and expires with the export.
+func (a *API) userExportDownloadURL(ctx context.Context, export *models.UserExport) string {
+	expires := strconv.FormatInt(export.ExpiresAt.Unix(), 10)
+
+	u := getExternalHost(ctx).JoinPath("user", "export", export.ID.String(), "download")
+	u.RawQuery = url.Values{
+		"expires":   {expires},
+		"signature": {a.userExportSignature(export.ID, expires)},
+	}.Encode()
+
+	return u.String()
+}
+
+func (a *API) userExportSignature(id uuid.UUID, expires string) string {
+	mac := hmac.New(sha256.New, []byte(a.config.UserExport.LinkSecret))
+	mac.Write([]byte(id.String() + ":" + expires))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// UserExportDownload responds with the archive of an export. It is
+// authenticated by the signature of the download link rather than an
+// access token, as the link is opened from the email it was sent with.
+func (a *API) UserExportDownload(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	db := a.db.WithContext(ctx)
+	notFound := apierrors.NewNotFoundError(apierrors.ErrorCodeUserExportNotFound, "Export not found or expired")
+
+	id, err := uuid.FromString(chi.URLParam(r, "export_id"))
+	if err != nil {
+		return notFound
+	}
+
+	query := r.URL.Query()
+	expires := query.Get("expires")
+	expiresAt, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil {
+		return notFound
+	}
+	if !hmac.Equal([]byte(query.Get("signature")), []byte(a.userExportSignature(id, expires))) {
+		return notFound
+	}
+
+	now := time.Now()
+	if now.Unix() >= expiresAt {
+		return notFound
+	}
+
+	export, err := models.FindUserExportByID(db, id)
+	if err != nil {
+		if models.IsNotFoundError(err) {
+			return notFound
+		}
+		return apierrors.NewInternalServerError("Database error finding user export").WithInternalError(err)
+	}
+	if export.Status != models.UserExportReady || export.IsExpired(now) {
+		return notFound
+	}
+
+	contentType := "application/json"
+	if export.Format == models.UserExportZip {
+		contentType = "application/zip"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"user-export-%s.%s\"", export.ID, export.Format))
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+
+	_, err = w.Write(export.Data)
+	return err
+}
@@ -0,0 +1,151 @@
+package api
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"github.com/supabase/auth/internal/conf"
+	"github.com/supabase/auth/internal/mailer/mockclient"
+	"github.com/supabase/auth/internal/models"
+)
+
+type UserExportTestSuite struct {
+	suite.Suite
+	API    *API
+	Config *conf.GlobalConfiguration
+	Mailer *mockclient.MockMailer
+
+	user  *models.User
+	token string
+}
+
+func TestUserExport(t *testing.T) {
+	mockMailer := &mockclient.MockMailer{}
+	api, config, err := setupAPIForTest(WithMailer(mockMailer))
+	require.NoError(t, err)
+
+	ts := &UserExportTestSuite{
+		API:    api,
+		Config: config,
+		Mailer: mockMailer,
+	}
+	defer api.db.Close()
+
+	suite.Run(t, ts)
+}
+
+func (ts *UserExportTestSuite) SetupTest() {
+	models.TruncateAll(ts.API.db)
+	ts.Mailer.Reset()
+	ts.Config.UserExport.Enabled = true
+	ts.Config.UserExport.Expiry = time.Hour
+	ts.Config.UserExport.Frequency = time.Hour
+	ts.Config.UserExport.LinkSecret = "user-export-link-secret-of-32-chars"
+
+	u, err := models.NewUser("", "test@example.com", "password", ts.Config.JWT.Aud, nil)
+	require.NoError(ts.T(), err)
+	require.NoError(ts.T(), ts.API.db.Create(u))
+	ts.user = u
+
+	session, err := models.NewSession(u.ID, nil)
+	require.NoError(ts.T(), err)
+	require.NoError(ts.T(), ts.API.db.Create(session))
+
+	req := httptest.NewRequest(http.MethodPost, "/token?grant_type=password", nil)
+	token, _, err := ts.API.generateAccessToken(req, ts.API.db, u, &session.ID, models.PasswordGrant)
+	require.NoError(ts.T(), err)
+	ts.token = token
+}
+
+func (ts *UserExportTestSuite) requestExport(query string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "http://localhost/user/export"+query, nil)
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", ts.token))
+	w := httptest.NewRecorder()
+	ts.API.handler.ServeHTTP(w, req)
+	return w
+}
+
+func (ts *UserExportTestSuite) download(link string) *httptest.ResponseRecorder {
+	u, err := url.Parse(link)
+	require.NoError(ts.T(), err)
+
+	req := httptest.NewRequest(http.MethodGet, "http://localhost"+u.RequestURI(), nil)
+	w := httptest.NewRecorder()
+	ts.API.handler.ServeHTTP(w, req)
+	return w
+}
+
+func (ts *UserExportTestSuite) TestExport() {
+	w := ts.requestExport("")
+	require.Equal(ts.T(), http.StatusAccepted, w.Code, w.Body.String())
+
+	var export models.UserExport
+	require.NoError(ts.T(), json.NewDecoder(w.Body).Decode(&export))
+	require.Equal(ts.T(), models.UserExportJSON, export.Format)
+
+	// exports can only be requested every frequency
+	w = ts.requestExport("")
+	require.Equal(ts.T(), http.StatusTooManyRequests, w.Code)
+
+	require.Len(ts.T(), ts.Mailer.DataExportMailCalls, 1)
+	link := ts.Mailer.DataExportMailCalls[0].DownloadURL
+	require.Contains(ts.T(), link, "/user/export/"+export.ID.String()+"/download")
+
+	w = ts.download(link)
+	require.Equal(ts.T(), http.StatusOK, w.Code, w.Body.String())
+	require.Equal(ts.T(), "application/json", w.Header().Get("Content-Type"))
+
+	var archive UserExportArchive
+	require.NoError(ts.T(), json.NewDecoder(w.Body).Decode(&archive))
+	require.Equal(ts.T(), ts.user.ID, archive.User.ID)
+	require.Len(ts.T(), archive.Sessions, 1)
+	require.NotEmpty(ts.T(), archive.AuditEvents)
+	require.Equal(ts.T(), string(models.UserDataExportRequestedAction), archive.AuditEvents[0].Payload["action"])
+
+	// tampered links are rejected
+	w = ts.download(link + "x")
+	require.Equal(ts.T(), http.StatusNotFound, w.Code)
+}
+
+func (ts *UserExportTestSuite) TestExportZip() {
+	w := ts.requestExport("?format=zip")
+	require.Equal(ts.T(), http.StatusAccepted, w.Code, w.Body.String())
+
+	require.Len(ts.T(), ts.Mailer.DataExportMailCalls, 1)
+	w = ts.download(ts.Mailer.DataExportMailCalls[0].DownloadURL)
+	require.Equal(ts.T(), http.StatusOK, w.Code, w.Body.String())
+	require.Equal(ts.T(), "application/zip", w.Header().Get("Content-Type"))
+
+	body := w.Body.Bytes()
+	zr, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+	require.NoError(ts.T(), err)
+	require.Len(ts.T(), zr.File, 1)
+	require.Equal(ts.T(), userExportFileName, zr.File[0].Name)
+
+	f, err := zr.File[0].Open()
+	require.NoError(ts.T(), err)
+	defer f.Close()
+	data, err := io.ReadAll(f)
+	require.NoError(ts.T(), err)
+
+	var archive UserExportArchive
+	require.NoError(ts.T(), json.Unmarshal(data, &archive))
+	require.Equal(ts.T(), ts.user.ID, archive.User.ID)
+}
+
+func (ts *UserExportTestSuite) TestExportDisabled() {
+	ts.Config.UserExport.Enabled = false
+
+	w := ts.requestExport("")
+	require.Equal(ts.T(), http.StatusNotFound, w.Code)
+}
@@ -13,6 +13,7 @@ import (
 	"github.com/gofrs/uuid"
 	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
+	"github.com/supabase/auth/internal/api/apierrors"
 	"github.com/supabase/auth/internal/conf"
 	"github.com/supabase/auth/internal/crypto"
 	"github.com/supabase/auth/internal/mailer"
@@ -485,6 +486,54 @@ func (ts *UserTestSuite) TestUserUpdatePasswordReauthentication() {
 	require.Nil(ts.T(), u.ReauthenticationSentAt)
 }
 
+func (ts *UserTestSuite) TestUserUpdateEmailReauthentication() {
+	ts.Config.Security.UpdateEmailRequireReauthentication = true
+	ts.Config.Security.ReauthenticationMaxSessionAge = 0
+	defer func() {
+		ts.Config.Security.UpdateEmailRequireReauthentication = false
+		ts.Config.Security.ReauthenticationMaxSessionAge = 24 * time.Hour
+	}()
+
+	u, err := models.FindUserByEmailAndAudience(ts.API.db, "test@example.com", ts.Config.JWT.Aud)
+	require.NoError(ts.T(), err)
+	now := time.Now()
+	u.EmailConfirmedAt = &now
+	u.ReauthenticationToken = crypto.GenerateTokenHash(u.GetEmail(), "123456")
+	u.ReauthenticationSentAt = &now
+	require.NoError(ts.T(), ts.API.db.Update(u))
+
+	token := ts.generateAccessTokenAndSession(u)
+
+	update := func(body map[string]interface{}) *httptest.ResponseRecorder {
+		var buffer bytes.Buffer
+		require.NoError(ts.T(), json.NewEncoder(&buffer).Encode(body))
+		req := httptest.NewRequest(http.MethodPut, "http://localhost/user", &buffer)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+		w := httptest.NewRecorder()
+		ts.API.handler.ServeHTTP(w, req)
+		return w
+	}
+
+	w := update(map[string]interface{}{"email": "new@example.com"})
+	require.Equal(ts.T(), http.StatusBadRequest, w.Code)
+	data := make(map[string]interface{})
+	require.NoError(ts.T(), json.NewDecoder(w.Body).Decode(&data))
+	require.Equal(ts.T(), string(apierrors.ErrorCodeReauthenticationNeeded), data["error_code"])
+
+	// updating the user metadata does not require reauthentication
+	w = update(map[string]interface{}{"data": map[string]interface{}{"name": "test"}})
+	require.Equal(ts.T(), http.StatusOK, w.Code, w.Body.String())
+
+	w = update(map[string]interface{}{"email": "new@example.com", "nonce": "123456"})
+	require.Equal(ts.T(), http.StatusOK, w.Code, w.Body.String())
+
+	u, err = models.FindUserByID(ts.API.db, u.ID)
+	require.NoError(ts.T(), err)
+	require.Empty(ts.T(), u.ReauthenticationToken)
+	require.Equal(ts.T(), "new@example.com", u.EmailChange)
+}
+
 func (ts *UserTestSuite) TestUserUpdatePasswordLogoutOtherSessions() {
 	ts.Config.Security.UpdatePasswordRequireReauthentication = false
 	u, err := models.FindUserByEmailAndAudience(ts.API.db, "test@example.com", ts.Config.JWT.Aud)
@@ -630,3 +679,108 @@ func (ts *UserTestSuite) TestUserUpdatePasswordSendsNotificationEmail() {
 		})
 	}
 }
+
+func (ts *UserTestSuite) TestUserDelete() {
+	u, err := models.FindUserByEmailAndAudience(ts.API.db, "test@example.com", ts.Config.JWT.Aud)
+	require.NoError(ts.T(), err)
+	token := ts.generateAccessTokenAndSession(u)
+
+	request := func(body map[string]interface{}) *httptest.ResponseRecorder {
+		var buffer bytes.Buffer
+		require.NoError(ts.T(), json.NewEncoder(&buffer).Encode(body))
+		req := httptest.NewRequest(http.MethodDelete, "http://localhost/user", &buffer)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+		w := httptest.NewRecorder()
+		ts.API.handler.ServeHTTP(w, req)
+		return w
+	}
+
+	w := request(map[string]interface{}{"password": "password"})
+	require.Equal(ts.T(), http.StatusNotFound, w.Code)
+
+	ts.Config.UserDeletion.SelfServiceEnabled = true
+	ts.Config.UserDeletion.PurgeEnabled = true
+	defer func() {
+		ts.Config.UserDeletion.SelfServiceEnabled = false
+		ts.Config.UserDeletion.PurgeEnabled = false
+	}()
+
+	w = request(map[string]interface{}{})
+	require.Equal(ts.T(), http.StatusBadRequest, w.Code)
+	w = request(map[string]interface{}{"password": "incorrect"})
+	require.Equal(ts.T(), http.StatusBadRequest, w.Code)
+
+	w = request(map[string]interface{}{"password": "password"})
+	require.Equal(ts.T(), http.StatusOK, w.Code, w.Body.String())
+
+	// the account is kept until its grace period ends
+	deletedUser, err := models.FindUserByID(ts.API.db, u.ID)
+	require.NoError(ts.T(), err)
+	require.NotNil(ts.T(), deletedUser.DeletedAt)
+	require.NotNil(ts.T(), deletedUser.PurgeAfter)
+	require.False(ts.T(), deletedUser.IsAnonymized)
+}
+
+func (ts *UserTestSuite) TestUserDeleteWithNonce() {
+	ts.Config.UserDeletion.SelfServiceEnabled = true
+	defer func() {
+		ts.Config.UserDeletion.SelfServiceEnabled = false
+	}()
+
+	u, err := models.FindUserByEmailAndAudience(ts.API.db, "test@example.com", ts.Config.JWT.Aud)
+	require.NoError(ts.T(), err)
+	token := ts.generateAccessTokenAndSession(u)
+
+	now := time.Now()
+	u.ReauthenticationToken = crypto.GenerateTokenHash(u.GetEmail(), "123456")
+	u.ReauthenticationSentAt = &now
+	require.NoError(ts.T(), ts.API.db.Update(u))
+
+	var buffer bytes.Buffer
+	require.NoError(ts.T(), json.NewEncoder(&buffer).Encode(map[string]interface{}{"nonce": "123456"}))
+	req := httptest.NewRequest(http.MethodDelete, "http://localhost/user", &buffer)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	w := httptest.NewRecorder()
+	ts.API.handler.ServeHTTP(w, req)
+	require.Equal(ts.T(), http.StatusOK, w.Code, w.Body.String())
+
+	// without purging the account is deleted right away
+	_, err = models.FindUserByID(ts.API.db, u.ID)
+	require.True(ts.T(), models.IsNotFoundError(err))
+}
+
+func (ts *UserTestSuite) TestUserDeleteRequiresAAL2() {
+	ts.Config.UserDeletion.SelfServiceEnabled = true
+	defer func() {
+		ts.Config.UserDeletion.SelfServiceEnabled = false
+	}()
+
+	u, err := models.FindUserByEmailAndAudience(ts.API.db, "test@example.com", ts.Config.JWT.Aud)
+	require.NoError(ts.T(), err)
+
+	f := models.NewFactor(u, "test_factor", models.TOTP, models.FactorStateVerified)
+	require.NoError(ts.T(), f.SetSecret("secretkey", ts.Config.Security.DBEncryption.Encrypt, ts.Config.Security.DBEncryption.EncryptionKeyID, ts.Config.Security.DBEncryption.EncryptionKey))
+	require.NoError(ts.T(), ts.API.db.Create(f))
+
+	// the session has not been verified with the factor
+	token := ts.generateAccessTokenAndSession(u)
+
+	var buffer bytes.Buffer
+	require.NoError(ts.T(), json.NewEncoder(&buffer).Encode(map[string]interface{}{"password": "password"}))
+	req := httptest.NewRequest(http.MethodDelete, "http://localhost/user", &buffer)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	w := httptest.NewRecorder()
+	ts.API.handler.ServeHTTP(w, req)
+	require.Equal(ts.T(), http.StatusUnauthorized, w.Code)
+
+	data := map[string]interface{}{}
+	require.NoError(ts.T(), json.NewDecoder(w.Body).Decode(&data))
+	require.Equal(ts.T(), apierrors.ErrorCodeInsufficientAAL, data["error_code"])
+
+	deletedUser, err := models.FindUserByID(ts.API.db, u.ID)
+	require.NoError(ts.T(), err)
+	require.Nil(ts.T(), deletedUser.DeletedAt)
+}
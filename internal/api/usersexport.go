@@ -0,0 +1,153 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/supabase/auth/internal/api/apierrors"
+	"github.com/supabase/auth/internal/models"
+	"github.com/supabase/auth/internal/observability"
+)
+
+// userExportPageSize is the number of users read from the database at a
+// time while streaming an export.
+const userExportPageSize = 1000
+
+// parseUserExportFilter parses the created_after, created_before, provider
+// and confirmed query parameters of an export.
+func parseUserExportFilter(query url.Values) (*models.UserExportFilter, error) {
+	filter := &models.UserExportFilter{
+		Provider: query.Get("provider"),
+	}
+
+	for name, dst := range map[string]**time.Time{
+		"created_after":  &filter.CreatedAfter,
+		"created_before": &filter.CreatedBefore,
+	} {
+		if value := query.Get(name); value != "" {
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				return nil, apierrors.NewBadRequestError(apierrors.ErrorCodeValidationFailed, "%s must be an RFC 3339 timestamp", name)
+			}
+			*dst = &t
+		}
+	}
+
+	if value := query.Get("confirmed"); value != "" {
+		confirmed, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, apierrors.NewBadRequestError(apierrors.ErrorCodeValidationFailed, "confirmed must be true or false")
+		}
+		filter.Confirmed = &confirmed
+	}
+
+	return filter, nil
+}
+
+// adminUsersExport streams the users of the audience matching the filter as
+// NDJSON, one user with its identities per line, in the order they were
+// created. An interrupted export is resumed by passing the ID of the last
+// user received as the after query parameter.
+func (a *API) adminUsersExport(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	db := a.db.WithContext(ctx)
+	aud := a.requestAud(ctx, r)
+	query := r.URL.Query()
+
+	filter, err := parseUserExportFilter(query)
+	if err != nil {
+		return err
+	}
+
+	var cursor *models.UserCursor
+	if after := query.Get("after"); after != "" {
+		afterID, err := uuid.FromString(after)
+		if err != nil {
+			return apierrors.NewBadRequestError(apierrors.ErrorCodeValidationFailed, "after must be an UUID")
+		}
+
+		user, err := models.FindUserByID(db, afterID)
+		if err != nil {
+			if models.IsNotFoundError(err) {
+				return apierrors.NewBadRequestError(apierrors.ErrorCodeUserNotFound, "User in after not found")
+			}
+			return apierrors.NewInternalServerError("Database error finding user").WithInternalError(err)
+		}
+		cursor = &models.UserCursor{CreatedAt: user.CreatedAt, ID: user.ID}
+	}
+
+	users, err := a.findUsersForExport(r, aud, filter, cursor)
+	if err != nil {
+		return apierrors.NewInternalServerError("Database error finding users").WithInternalError(err)
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+
+	flusher, _ := w.(http.Flusher)
+	encoder := json.NewEncoder(w)
+
+	for len(users) > 0 {
+		for _, user := range users {
+			if err := encoder.Encode(user); err != nil {
+				// the client went away
+				return nil
+			}
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+
+		if len(users) < userExportPageSize {
+			break
+		}
+
+		last := users[len(users)-1]
+		cursor = &models.UserCursor{CreatedAt: last.CreatedAt, ID: last.ID}
+
+		users, err = a.findUsersForExport(r, aud, filter, cursor)
+		if err != nil {
+			// The status was sent already, so the connection is aborted for
+			// the client to notice the export is incomplete.
+			observability.GetLogEntry(r).Entry.WithError(err).Error("Database error finding users to export")
+			panic(http.ErrAbortHandler)
+		}
+	}
+
+	return nil
+}
+
+// findUsersForExport finds the next page of an export with the users'
+// identities.
+func (a *API) findUsersForExport(r *http.Request, aud string, filter *models.UserExportFilter, cursor *models.UserCursor) ([]*models.User, error) {
+	db := a.db.WithContext(r.Context())
+
+	users, err := models.FindUsersForExport(db, aud, filter, cursor, userExportPageSize)
+	if err != nil {
+		return nil, err
+	}
+
+	userIDs := make([]uuid.UUID, len(users))
+	usersByID := make(map[uuid.UUID]*models.User, len(users))
+	for i, user := range users {
+		userIDs[i] = user.ID
+		usersByID[user.ID] = user
+		user.Identities = []models.Identity{}
+	}
+
+	identities, err := models.FindIdentitiesByUserIDs(db, userIDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, identity := range identities {
+		if user := usersByID[identity.UserID]; user != nil {
+			user.Identities = append(user.Identities, *identity)
+		}
+	}
+
+	return users, nil
+}
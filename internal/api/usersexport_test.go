@@ -0,0 +1,147 @@
+package api
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	jwt "github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"github.com/supabase/auth/internal/conf"
+	"github.com/supabase/auth/internal/models"
+)
+
+type UsersExportTestSuite struct {
+	suite.Suite
+	API    *API
+	Config *conf.GlobalConfiguration
+
+	token string
+	users []*models.User
+}
+
+func TestUsersExport(t *testing.T) {
+	api, config, err := setupAPIForTest()
+	require.NoError(t, err)
+
+	ts := &UsersExportTestSuite{
+		API:    api,
+		Config: config,
+	}
+	defer api.db.Close()
+
+	suite.Run(t, ts)
+}
+
+func (ts *UsersExportTestSuite) SetupTest() {
+	models.TruncateAll(ts.API.db)
+
+	claims := &AccessTokenClaims{
+		Role: "supabase_admin",
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(ts.Config.JWT.Secret))
+	require.NoError(ts.T(), err, "Error generating admin jwt")
+	ts.token = token
+
+	// users are created a day apart, the first two are confirmed and the
+	// second signed up with GitHub
+	ts.users = nil
+	createdAt := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 4; i++ {
+		u, err := models.NewUser("", fmt.Sprintf("test%d@example.com", i), "test", ts.Config.JWT.Aud, nil)
+		require.NoError(ts.T(), err)
+		u.CreatedAt = createdAt.Add(time.Duration(i) * 24 * time.Hour)
+		if i < 2 {
+			now := time.Now()
+			u.EmailConfirmedAt = &now
+		}
+		require.NoError(ts.T(), ts.API.db.Create(u))
+
+		provider := "email"
+		if i == 1 {
+			provider = "github"
+		}
+		identity, err := models.NewIdentity(u, provider, map[string]interface{}{
+			"sub":   u.ID.String(),
+			"email": u.GetEmail(),
+		})
+		require.NoError(ts.T(), err)
+		require.NoError(ts.T(), ts.API.db.Create(identity))
+
+		ts.users = append(ts.users, u)
+	}
+}
+
+func (ts *UsersExportTestSuite) export(query string) (int, []*models.User) {
+	req := httptest.NewRequest(http.MethodGet, "/admin/users/export"+query, nil)
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", ts.token))
+
+	w := httptest.NewRecorder()
+	ts.API.handler.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		return w.Code, nil
+	}
+	require.Equal(ts.T(), "application/x-ndjson", w.Header().Get("Content-Type"))
+
+	var users []*models.User
+	scanner := bufio.NewScanner(w.Body)
+	for scanner.Scan() {
+		var user models.User
+		require.NoError(ts.T(), json.Unmarshal(scanner.Bytes(), &user))
+		users = append(users, &user)
+	}
+	require.NoError(ts.T(), scanner.Err())
+
+	return w.Code, users
+}
+
+func (ts *UsersExportTestSuite) requireUsers(expected []*models.User, actual []*models.User) {
+	require.Len(ts.T(), actual, len(expected))
+	for i := range expected {
+		require.Equal(ts.T(), expected[i].ID, actual[i].ID)
+	}
+}
+
+func (ts *UsersExportTestSuite) TestExport() {
+	code, users := ts.export("")
+	require.Equal(ts.T(), http.StatusOK, code)
+	ts.requireUsers(ts.users, users)
+
+	require.Len(ts.T(), users[1].Identities, 1)
+	require.Equal(ts.T(), "github", users[1].Identities[0].Provider)
+
+	// resumes after a user
+	_, users = ts.export("?after=" + ts.users[1].ID.String())
+	ts.requireUsers(ts.users[2:], users)
+}
+
+func (ts *UsersExportTestSuite) TestExportFilters() {
+	_, users := ts.export("?created_after=2026-01-02T00:00:00Z&created_before=2026-01-04T00:00:00Z")
+	ts.requireUsers(ts.users[1:3], users)
+
+	_, users = ts.export("?provider=github")
+	ts.requireUsers(ts.users[1:2], users)
+
+	_, users = ts.export("?confirmed=true")
+	ts.requireUsers(ts.users[:2], users)
+
+	_, users = ts.export("?confirmed=false&created_after=2026-01-04T00:00:00Z")
+	ts.requireUsers(ts.users[3:], users)
+}
+
+func (ts *UsersExportTestSuite) TestExportInvalidParameters() {
+	for _, query := range []string{
+		"?created_after=yesterday",
+		"?confirmed=maybe",
+		"?after=not-a-uuid",
+		"?after=00000000-0000-0000-0000-000000000001",
+	} {
+		code, _ := ts.export(query)
+		require.Equal(ts.T(), http.StatusBadRequest, code, query)
+	}
+}
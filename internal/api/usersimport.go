@@ -0,0 +1,492 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/fatih/structs"
+	"github.com/go-chi/chi/v5"
+	"github.com/gofrs/uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/supabase/auth/internal/api/apierrors"
+	"github.com/supabase/auth/internal/api/provider"
+	"github.com/supabase/auth/internal/conf"
+	"github.com/supabase/auth/internal/models"
+	"github.com/supabase/auth/internal/observability"
+	"github.com/supabase/auth/internal/storage"
+	"github.com/supabase/auth/internal/utilities"
+	"github.com/supabase/auth/internal/webhooks"
+)
+
+const (
+	userImportFormatNDJSON = "ndjson"
+	userImportFormatCSV    = "csv"
+
+	// userImportMaxSize is the largest upload accepted by the import
+	// endpoint, larger migrations need to be split over several imports.
+	userImportMaxSize = 256 << 20
+
+	// userImportBatchSize is the number of records processed between
+	// progress updates.
+	userImportBatchSize = 500
+)
+
+// UserImportRecord is a user of a bulk import.
+type UserImportRecord struct {
+	Id           string                 `json:"id"`
+	Aud          string                 `json:"aud"`
+	Role         string                 `json:"role"`
+	Email        string                 `json:"email"`
+	Phone        string                 `json:"phone"`
+	PasswordHash string                 `json:"password_hash"`
+	EmailConfirm bool                   `json:"email_confirm"`
+	PhoneConfirm bool                   `json:"phone_confirm"`
+	UserMetaData map[string]interface{} `json:"user_metadata"`
+	AppMetaData  map[string]interface{} `json:"app_metadata"`
+	Identities   []UserImportIdentity   `json:"identities"`
+
+	// line is where the record starts in the upload.
+	line int
+}
+
+// UserImportIdentity is an identity of an imported user with an external
+// provider.
+type UserImportIdentity struct {
+	Provider     string                 `json:"provider"`
+	ProviderID   string                 `json:"provider_id"`
+	IdentityData map[string]interface{} `json:"identity_data"`
+}
+
+// parseUserImport parses the records of an upload in the format.
+func parseUserImport(format string, body io.Reader) ([]*UserImportRecord, error) {
+	switch format {
+	case userImportFormatNDJSON:
+		return parseUserImportNDJSON(body)
+	case userImportFormatCSV:
+		return parseUserImportCSV(body)
+	}
+	return nil, fmt.Errorf("unsupported format %q", format)
+}
+
+func parseUserImportNDJSON(body io.Reader) ([]*UserImportRecord, error) {
+	var records []*UserImportRecord
+
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	line := 0
+	for scanner.Scan() {
+		line++
+
+		data := bytes.TrimSpace(scanner.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+
+		record := UserImportRecord{line: line}
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&record); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		records = append(records, &record)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("line %d: %w", line+1, err)
+	}
+
+	return records, nil
+}
+
+func parseUserImportCSV(body io.Reader) ([]*UserImportRecord, error) {
+	reader := csv.NewReader(body)
+	reader.ReuseRecord = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	columns := append([]string(nil), header...)
+
+	var records []*UserImportRecord
+	for {
+		fields, err := reader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		line, _ := reader.FieldPos(0)
+
+		record := &UserImportRecord{line: line}
+		for i, value := range fields {
+			if value == "" {
+				continue
+			}
+
+			if err := record.setCSVField(strings.TrimSpace(columns[i]), value); err != nil {
+				return nil, fmt.Errorf("line %d: %w", line, err)
+			}
+		}
+		records = append(records, record)
+	}
+
+	return records, nil
+}
+
+func (r *UserImportRecord) setCSVField(column, value string) error {
+	var err error
+
+	switch column {
+	case "id":
+		r.Id = value
+	case "aud":
+		r.Aud = value
+	case "role":
+		r.Role = value
+	case "email":
+		r.Email = value
+	case "phone":
+		r.Phone = value
+	case "password_hash":
+		r.PasswordHash = value
+	case "email_confirm":
+		r.EmailConfirm, err = strconv.ParseBool(value)
+	case "phone_confirm":
+		r.PhoneConfirm, err = strconv.ParseBool(value)
+	case "user_metadata":
+		err = json.Unmarshal([]byte(value), &r.UserMetaData)
+	case "app_metadata":
+		err = json.Unmarshal([]byte(value), &r.AppMetaData)
+	case "identities":
+		err = json.Unmarshal([]byte(value), &r.Identities)
+	default:
+		return fmt.Errorf("unknown column %q", column)
+	}
+
+	if err != nil {
+		return fmt.Errorf("column %q: %w", column, err)
+	}
+	return nil
+}
+
+// adminUsersImport starts importing the users of an NDJSON or CSV upload,
+// the users are created in the background.
+func (a *API) adminUsersImport(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	db := a.db.WithContext(ctx)
+
+	var format string
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case "application/x-ndjson", "application/jsonl":
+		format = userImportFormatNDJSON
+	case "text/csv":
+		format = userImportFormatCSV
+	default:
+		return apierrors.NewBadRequestError(apierrors.ErrorCodeValidationFailed, "Content-Type must be application/x-ndjson or text/csv")
+	}
+
+	records, err := parseUserImport(format, http.MaxBytesReader(w, r.Body, userImportMaxSize))
+	if err != nil {
+		return apierrors.NewBadRequestError(apierrors.ErrorCodeValidationFailed, "Could not parse users: %v", err)
+	}
+	if len(records) == 0 {
+		return apierrors.NewBadRequestError(apierrors.ErrorCodeValidationFailed, "No users to import")
+	}
+
+	userImport := models.NewUserImport(format, len(records))
+	if err := db.Create(userImport); err != nil {
+		return apierrors.NewInternalServerError("Database error creating user import").WithInternalError(err)
+	}
+
+	// The import outlives the request, so it runs with a detached request
+	// that keeps the request ID and headers for audit logging.
+	bgCtx := utilities.WithRequestID(context.Background(), utilities.GetRequestID(ctx))
+	bgReq := r.Clone(bgCtx)
+	bgReq.Body = http.NoBody
+
+	aud := a.requestAud(ctx, r)
+	adminUser := getAdminUser(ctx)
+	log := observability.GetLogEntry(r).Entry.WithField("user_import_id", userImport.ID)
+
+	if err := sendJSON(w, http.StatusAccepted, userImport); err != nil {
+		return err
+	}
+
+	go a.runUserImport(bgReq, adminUser, aud, userImport, records, log)
+
+	return nil
+}
+
+// adminUsersImportGet returns the progress of an import.
+func (a *API) adminUsersImportGet(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	db := a.db.WithContext(ctx)
+
+	importID, err := uuid.FromString(chi.URLParam(r, "import_id"))
+	if err != nil {
+		return apierrors.NewNotFoundError(apierrors.ErrorCodeValidationFailed, "import_id must be an UUID")
+	}
+
+	userImport, err := models.FindUserImportByID(db, importID)
+	if err != nil {
+		if models.IsNotFoundError(err) {
+			return apierrors.NewNotFoundError(apierrors.ErrorCodeUserImportNotFound, "User import not found")
+		}
+		return apierrors.NewInternalServerError("Database error finding user import").WithInternalError(err)
+	}
+
+	return sendJSON(w, http.StatusOK, userImport)
+}
+
+// runUserImport creates the users of an import in batches, saving the
+// progress after each batch.
+func (a *API) runUserImport(r *http.Request, adminUser *models.User, aud string, userImport *models.UserImport, records []*UserImportRecord, log logrus.FieldLogger) {
+	db := a.db.WithContext(r.Context())
+
+	defer func() {
+		if rec := recover(); rec != nil {
+			log.WithField("panic", rec).Error("user import failed")
+
+			userImport.Finish(models.UserImportFailed)
+			if err := userImport.UpdateProgress(db); err != nil {
+				log.WithError(err).Error("failed to update user import progress")
+			}
+		}
+	}()
+
+	userImport.Status = models.UserImportRunning
+	if err := userImport.UpdateProgress(db); err != nil {
+		log.WithError(err).Error("failed to start user import")
+	}
+
+	for start := 0; start < len(records); start += userImportBatchSize {
+		end := min(start+userImportBatchSize, len(records))
+
+		for i, record := range records[start:end] {
+			if err := a.importUser(r, db, adminUser, aud, record); err != nil {
+				message := err.Error()
+				if httpErr, ok := err.(*HTTPError); ok {
+					message = httpErr.Message
+					if httpErr.HTTPStatus >= http.StatusInternalServerError {
+						log.WithError(httpErr.Cause()).WithField("line", record.line).Error("failed to import user")
+					}
+				}
+
+				userImport.RecordError(models.UserImportError{
+					Line:  record.line,
+					Email: record.Email,
+					Phone: record.Phone,
+					Error: message,
+				})
+			} else {
+				userImport.Imported++
+			}
+			userImport.Processed++
+
+			// records are released as they are processed
+			records[start+i] = nil
+		}
+
+		if end == len(records) {
+			userImport.Finish(models.UserImportCompleted)
+		}
+
+		if err := userImport.UpdateProgress(db); err != nil {
+			log.WithError(err).Error("failed to update user import progress")
+		}
+	}
+
+	log.WithField("imported", userImport.Imported).WithField("failed", userImport.Failed).Info("user import finished")
+}
+
+// importUser creates a user of an import the way adminUserCreate does, with
+// the record's password hash and identities.
+func (a *API) importUser(r *http.Request, db *storage.Connection, adminUser *models.User, aud string, record *UserImportRecord) error {
+	config := a.config
+
+	if record.Aud != "" {
+		aud = record.Aud
+	}
+
+	if record.Email == "" && record.Phone == "" {
+		return apierrors.NewBadRequestError(apierrors.ErrorCodeValidationFailed, "Cannot create a user without either an email or phone")
+	}
+
+	var err error
+	var providers []string
+	if record.Email != "" {
+		record.Email, err = a.validateEmail(record.Email)
+		if err != nil {
+			return err
+		}
+		if user, err := models.IsDuplicatedEmail(db, record.Email, aud, nil, config.Experimental.ProvidersWithOwnLinkingDomain); err != nil {
+			return apierrors.NewInternalServerError("Database error checking email").WithInternalError(err)
+		} else if user != nil {
+			return apierrors.NewUnprocessableEntityError(apierrors.ErrorCodeEmailExists, DuplicateEmailMsg)
+		}
+		providers = append(providers, "email")
+	}
+
+	if record.Phone != "" {
+		record.Phone, err = validatePhone(record.Phone)
+		if err != nil {
+			return err
+		}
+		if exists, err := models.IsDuplicatedPhone(db, record.Phone, aud); err != nil {
+			return apierrors.NewInternalServerError("Database error checking phone").WithInternalError(err)
+		} else if exists {
+			return apierrors.NewUnprocessableEntityError(apierrors.ErrorCodePhoneExists, "Phone number already registered by another user")
+		}
+		providers = append(providers, "phone")
+	}
+
+	for _, identity := range record.Identities {
+		if identity.Provider == "" || identity.ProviderID == "" {
+			return apierrors.NewBadRequestError(apierrors.ErrorCodeValidationFailed, "Identities require a provider and a provider_id")
+		}
+		if identity.Provider == "email" || identity.Provider == "phone" {
+			return apierrors.NewBadRequestError(apierrors.ErrorCodeValidationFailed, "Identities of the %s provider are created from the user's %s", identity.Provider, identity.Provider)
+		}
+		if _, err := models.FindIdentityByIdAndProvider(db, identity.ProviderID, identity.Provider); err == nil {
+			return apierrors.NewUnprocessableEntityError(apierrors.ErrorCodeIdentityAlreadyExists, "Identity is already linked to another user")
+		} else if !models.IsNotFoundError(err) {
+			return apierrors.NewInternalServerError("Database error checking identity").WithInternalError(err)
+		}
+		if !slices.Contains(providers, identity.Provider) {
+			providers = append(providers, identity.Provider)
+		}
+	}
+
+	var user *models.User
+	if record.PasswordHash != "" {
+		user, err = models.NewUserWithPasswordHash(record.Phone, record.Email, record.PasswordHash, aud, record.UserMetaData)
+		if err != nil {
+			return apierrors.NewBadRequestError(apierrors.ErrorCodeValidationFailed, "Invalid password hash: %v", err)
+		}
+	} else {
+		user, err = models.NewUser(record.Phone, record.Email, "", aud, record.UserMetaData)
+		if err != nil {
+			return apierrors.NewInternalServerError("Error creating user").WithInternalError(err)
+		}
+	}
+
+	if record.Id != "" {
+		customId, err := uuid.FromString(record.Id)
+		if err != nil {
+			return apierrors.NewBadRequestError(apierrors.ErrorCodeValidationFailed, "ID must conform to the uuid v4 format")
+		}
+		if customId == uuid.Nil {
+			return apierrors.NewBadRequestError(apierrors.ErrorCodeValidationFailed, "ID cannot be a nil uuid")
+		}
+		user.ID = customId
+	}
+
+	user.AppMetaData = map[string]interface{}{
+		// TODO: Deprecate "provider" field
+		// default to the first provider in the providers slice
+		"provider":  providers[0],
+		"providers": providers,
+	}
+
+	err = db.Transaction(func(tx *storage.Connection) error {
+		if terr := tx.Create(user); terr != nil {
+			return terr
+		}
+
+		if user.GetEmail() != "" {
+			if _, terr := a.createNewIdentity(tx, user, "email", structs.Map(provider.Claims{
+				Subject: user.ID.String(),
+				Email:   user.GetEmail(),
+			})); terr != nil {
+				return terr
+			}
+		}
+
+		if user.GetPhone() != "" {
+			if _, terr := a.createNewIdentity(tx, user, "phone", structs.Map(provider.Claims{
+				Subject: user.ID.String(),
+				Phone:   user.GetPhone(),
+			})); terr != nil {
+				return terr
+			}
+		}
+
+		for _, identity := range record.Identities {
+			identityData := make(map[string]interface{}, len(identity.IdentityData)+1)
+			for k, v := range identity.IdentityData {
+				identityData[k] = v
+			}
+			identityData["sub"] = identity.ProviderID
+
+			if _, terr := a.createNewIdentity(tx, user, identity.Provider, identityData); terr != nil {
+				return terr
+			}
+		}
+
+		if terr := models.NewAuditLogEntry(config.AuditLog, r, tx, adminUser, models.UserSignedUpAction, "", map[string]interface{}{
+			"user_id":    user.ID,
+			"user_email": user.Email,
+			"user_phone": user.Phone,
+			"provider":   providers[0],
+			"imported":   true,
+		}); terr != nil {
+			return terr
+		}
+
+		role := config.JWT.DefaultGroupName
+		if record.Role != "" {
+			role = record.Role
+		}
+		if terr := user.SetRole(tx, role); terr != nil {
+			return terr
+		}
+
+		if record.AppMetaData != nil {
+			if terr := user.UpdateAppMetaData(tx, record.AppMetaData); terr != nil {
+				return terr
+			}
+		}
+
+		if record.EmailConfirm {
+			if terr := user.Confirm(tx); terr != nil {
+				return terr
+			}
+		}
+
+		if record.PhoneConfirm {
+			if terr := user.ConfirmPhone(tx); terr != nil {
+				return terr
+			}
+		}
+
+		if terr := webhooks.Emit(tx, &config.Webhooks, conf.WebhookEventUserCreated, user, nil); terr != nil {
+			return terr
+		}
+
+		return nil
+	})
+	if err != nil {
+		if httpErr, ok := err.(*HTTPError); ok {
+			return httpErr
+		}
+		return apierrors.NewInternalServerError("Database error creating new user").WithInternalError(err)
+	}
+
+	return nil
+}
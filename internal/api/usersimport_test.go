@@ -0,0 +1,221 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	jwt "github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"github.com/supabase/auth/internal/conf"
+	"github.com/supabase/auth/internal/models"
+)
+
+func TestParseUserImport(t *testing.T) {
+	ndjson := `{"email":"a@example.com","password_hash":"$2y$10$srNl09aPtc2qr.0Vl.NtjekJRt/NxRxYQm3qd3OvfcKsJgVnr6.Ve","email_confirm":true}
+
+{"phone":"12345678","identities":[{"provider":"github","provider_id":"42"}]}
+`
+	records, err := parseUserImport(userImportFormatNDJSON, strings.NewReader(ndjson))
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	require.Equal(t, "a@example.com", records[0].Email)
+	require.True(t, records[0].EmailConfirm)
+	require.Equal(t, 1, records[0].line)
+	require.Equal(t, "github", records[1].Identities[0].Provider)
+	require.Equal(t, 3, records[1].line)
+
+	_, err = parseUserImport(userImportFormatNDJSON, strings.NewReader(`{"email":"a@example.com","password":"test"}`))
+	require.EqualError(t, err, `line 1: json: unknown field "password"`)
+
+	csv := `email,phone,email_confirm,user_metadata,identities
+a@example.com,,true,"{""name"":""A""}",
+,12345678,,,"[{""provider"":""github"",""provider_id"":""42""}]"
+`
+	records, err = parseUserImport(userImportFormatCSV, strings.NewReader(csv))
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	require.Equal(t, "a@example.com", records[0].Email)
+	require.True(t, records[0].EmailConfirm)
+	require.Equal(t, "A", records[0].UserMetaData["name"])
+	require.Equal(t, 2, records[0].line)
+	require.Equal(t, "12345678", records[1].Phone)
+	require.Equal(t, "42", records[1].Identities[0].ProviderID)
+	require.Equal(t, 3, records[1].line)
+
+	_, err = parseUserImport(userImportFormatCSV, strings.NewReader("email,password\na@example.com,test\n"))
+	require.EqualError(t, err, `line 2: unknown column "password"`)
+
+	_, err = parseUserImport(userImportFormatCSV, strings.NewReader("email,email_confirm\na@example.com,maybe\n"))
+	require.Error(t, err)
+}
+
+type UsersImportTestSuite struct {
+	suite.Suite
+	API    *API
+	Config *conf.GlobalConfiguration
+
+	token string
+}
+
+func TestUsersImport(t *testing.T) {
+	api, config, err := setupAPIForTest()
+	require.NoError(t, err)
+
+	ts := &UsersImportTestSuite{
+		API:    api,
+		Config: config,
+	}
+	defer api.db.Close()
+
+	suite.Run(t, ts)
+}
+
+func (ts *UsersImportTestSuite) SetupTest() {
+	models.TruncateAll(ts.API.db)
+
+	claims := &AccessTokenClaims{
+		Role: "supabase_admin",
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(ts.Config.JWT.Secret))
+	require.NoError(ts.T(), err, "Error generating admin jwt")
+	ts.token = token
+}
+
+func (ts *UsersImportTestSuite) startImport(contentType, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/admin/users/import", strings.NewReader(body))
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", ts.token))
+	req.Header.Set("Content-Type", contentType)
+
+	w := httptest.NewRecorder()
+	ts.API.handler.ServeHTTP(w, req)
+	return w
+}
+
+func (ts *UsersImportTestSuite) waitForImport(id string) *models.UserImport {
+	var userImport models.UserImport
+
+	require.Eventually(ts.T(), func() bool {
+		req := httptest.NewRequest(http.MethodGet, "/admin/users/import/"+id, nil)
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", ts.token))
+
+		w := httptest.NewRecorder()
+		ts.API.handler.ServeHTTP(w, req)
+		if w.Code != http.StatusOK || json.NewDecoder(w.Body).Decode(&userImport) != nil {
+			return false
+		}
+
+		return userImport.Status == models.UserImportCompleted
+	}, 10*time.Second, 50*time.Millisecond)
+
+	return &userImport
+}
+
+func (ts *UsersImportTestSuite) TestImport() {
+	existing, err := models.NewUser("", "existing@example.com", "test", ts.Config.JWT.Aud, nil)
+	require.NoError(ts.T(), err)
+	require.NoError(ts.T(), ts.API.db.Create(existing))
+
+	// every password hash is of "test"
+	records := []string{
+		`{"email":"bcrypt@example.com","password_hash":"$2y$10$srNl09aPtc2qr.0Vl.NtjekJRt/NxRxYQm3qd3OvfcKsJgVnr6.Ve","email_confirm":true,"user_metadata":{"name":"B"},"app_metadata":{"plan":"pro"},"identities":[{"provider":"github","provider_id":"42","identity_data":{"user_name":"b"}}]}`,
+		`{"email":"scrypt@example.com","password_hash":"$scrypt$ln=4,r=8,p=1$c2FsdHNhbHRzYWx0c2FsdA$Xm5Jhj+IG4YfthdtDVddQUEAe9RXODln+i0vrX/P38c","email_confirm":true}`,
+		`{"phone":"12345678","password_hash":"$pbkdf2-sha256$i=1000,l=32$c2FsdHNhbHRzYWx0c2FsdA$4+KBqsOVLqj6E9Td14CGM3lT9IqBoXdgdB7aKUjWvVE","phone_confirm":true}`,
+		`{"email":"existing@example.com"}`,
+		`{"email":"invalid@example.com","password_hash":"$pbkdf2-md5$i=1000$c2FsdA$c2FsdA"}`,
+		`{"email":"identity@example.com","identities":[{"provider":"github","provider_id":"42"}]}`,
+	}
+
+	w := ts.startImport("application/x-ndjson", strings.Join(records, "\n"))
+	require.Equal(ts.T(), http.StatusAccepted, w.Code, w.Body.String())
+
+	var started models.UserImport
+	require.NoError(ts.T(), json.NewDecoder(w.Body).Decode(&started))
+	require.Equal(ts.T(), models.UserImportPending, started.Status)
+	require.Equal(ts.T(), len(records), started.Total)
+
+	userImport := ts.waitForImport(started.ID.String())
+	require.Equal(ts.T(), len(records), userImport.Processed)
+	require.Equal(ts.T(), 3, userImport.Imported)
+	require.Equal(ts.T(), 3, userImport.Failed)
+	require.NotNil(ts.T(), userImport.FinishedAt)
+
+	require.Len(ts.T(), userImport.Errors, 3)
+	require.Equal(ts.T(), 4, userImport.Errors[0].Line)
+	require.Equal(ts.T(), DuplicateEmailMsg, userImport.Errors[0].Error)
+	require.Equal(ts.T(), 5, userImport.Errors[1].Line)
+	require.Equal(ts.T(), 6, userImport.Errors[2].Line)
+
+	user, err := models.FindUserByEmailAndAudience(ts.API.db, "bcrypt@example.com", ts.Config.JWT.Aud)
+	require.NoError(ts.T(), err)
+	require.True(ts.T(), user.IsConfirmed())
+	require.Equal(ts.T(), "B", user.UserMetaData["name"])
+	require.Equal(ts.T(), "pro", user.AppMetaData["plan"])
+	require.Equal(ts.T(), []interface{}{"email", "github"}, user.AppMetaData["providers"])
+
+	identity, err := models.FindIdentityByIdAndProvider(ts.API.db, "42", "github")
+	require.NoError(ts.T(), err)
+	require.Equal(ts.T(), user.ID, identity.UserID)
+
+	// imported hashes are migrated to bcrypt on sign in
+	user, err = models.FindUserByEmailAndAudience(ts.API.db, "scrypt@example.com", ts.Config.JWT.Aud)
+	require.NoError(ts.T(), err)
+
+	isAuthenticated, _, err := user.Authenticate(context.Background(), ts.API.db, "test", nil, false, "")
+	require.NoError(ts.T(), err)
+	require.True(ts.T(), isAuthenticated)
+
+	user, err = models.FindUserByEmailAndAudience(ts.API.db, "scrypt@example.com", ts.Config.JWT.Aud)
+	require.NoError(ts.T(), err)
+	require.True(ts.T(), strings.HasPrefix(*user.EncryptedPassword, "$2a$"))
+
+	user, err = models.FindUserByPhoneAndAudience(ts.API.db, "12345678", ts.Config.JWT.Aud)
+	require.NoError(ts.T(), err)
+	require.True(ts.T(), user.IsPhoneConfirmed())
+
+	isAuthenticated, _, err = user.Authenticate(context.Background(), ts.API.db, "test", nil, false, "")
+	require.NoError(ts.T(), err)
+	require.True(ts.T(), isAuthenticated)
+}
+
+func (ts *UsersImportTestSuite) TestImportCSV() {
+	body := `email,password_hash,email_confirm
+csv@example.com,$2y$10$srNl09aPtc2qr.0Vl.NtjekJRt/NxRxYQm3qd3OvfcKsJgVnr6.Ve,true
+`
+	w := ts.startImport("text/csv; charset=utf-8", body)
+	require.Equal(ts.T(), http.StatusAccepted, w.Code, w.Body.String())
+
+	var started models.UserImport
+	require.NoError(ts.T(), json.NewDecoder(w.Body).Decode(&started))
+	require.Equal(ts.T(), userImportFormatCSV, started.Format)
+
+	userImport := ts.waitForImport(started.ID.String())
+	require.Equal(ts.T(), 1, userImport.Imported)
+
+	user, err := models.FindUserByEmailAndAudience(ts.API.db, "csv@example.com", ts.Config.JWT.Aud)
+	require.NoError(ts.T(), err)
+	require.True(ts.T(), user.IsConfirmed())
+}
+
+func (ts *UsersImportTestSuite) TestImportRejected() {
+	w := ts.startImport("application/json", `{"email":"a@example.com"}`)
+	require.Equal(ts.T(), http.StatusBadRequest, w.Code)
+
+	w = ts.startImport("application/x-ndjson", "")
+	require.Equal(ts.T(), http.StatusBadRequest, w.Code)
+
+	w = ts.startImport("application/x-ndjson", "not json")
+	require.Equal(ts.T(), http.StatusBadRequest, w.Code)
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/users/import/00000000-0000-0000-0000-000000000001", nil)
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", ts.token))
+	w = httptest.NewRecorder()
+	ts.API.handler.ServeHTTP(w, req)
+	require.Equal(ts.T(), http.StatusNotFound, w.Code)
+}
@@ -15,6 +15,7 @@ import (
 	"github.com/supabase/auth/internal/api/apierrors"
 	"github.com/supabase/auth/internal/api/provider"
 	"github.com/supabase/auth/internal/api/sms_provider"
+	"github.com/supabase/auth/internal/conf"
 	"github.com/supabase/auth/internal/crypto"
 	mail "github.com/supabase/auth/internal/mailer"
 	"github.com/supabase/auth/internal/metering"
@@ -22,6 +23,7 @@ import (
 	"github.com/supabase/auth/internal/observability"
 	"github.com/supabase/auth/internal/storage"
 	"github.com/supabase/auth/internal/utilities"
+	"github.com/supabase/auth/internal/webhooks"
 )
 
 const (
@@ -96,6 +98,9 @@ func (a *API) Verify(w http.ResponseWriter, r *http.Request) error {
 	params := &VerifyParams{}
 	switch r.Method {
 	case http.MethodGet:
+		if a.config.Mailer.OTPOnly {
+			return apierrors.NewForbiddenError(apierrors.ErrorCodeEmailLinkDisabled, "Email links are disabled, enter the code sent to your email instead")
+		}
 		params.Token = r.FormValue("token")
 		params.Type = r.FormValue("type")
 		params.RedirectTo = utilities.GetReferrer(r, a.config)
@@ -289,6 +294,10 @@ func (a *API) verifyPost(w http.ResponseWriter, r *http.Request, params *VerifyP
 		return nil
 	})
 	if err != nil {
+		var httpErr *apierrors.HTTPError
+		if errors.As(err, &httpErr) && httpErr.ErrorCode == apierrors.ErrorCodeOTPExpired {
+			metering.RecordLoginFailure(metering.LoginTypeOTP, verifyProvider(params))
+		}
 		return err
 	}
 	if isSingleConfirmationResponse {
@@ -298,16 +307,11 @@ func (a *API) verifyPost(w http.ResponseWriter, r *http.Request, params *VerifyP
 		})
 	}
 
-	// Record login for analytics - determine provider based on verification type
-	provider := metering.ProviderEmail // default
-	if params.Type == smsVerification || params.Type == phoneChangeVerification {
-		provider = metering.ProviderPhone
-	}
 	metering.RecordLogin(metering.LoginTypeOTP, user.ID, &metering.LoginData{
-		Provider: provider,
+		Provider: verifyProvider(params),
 	})
 
-	return sendJSON(w, http.StatusOK, token)
+	return a.sendTokenResponse(w, r, token)
 }
 
 func (a *API) signupVerify(r *http.Request, ctx context.Context, conn *storage.Connection, user *models.User) (*models.User, error) {
@@ -443,6 +447,11 @@ func (a *API) smsVerify(r *http.Request, conn *storage.Connection, user *models.
 			if terr := user.ConfirmPhoneChange(tx); terr != nil {
 				return apierrors.NewInternalServerError("Error confirming user").WithInternalError(terr)
 			}
+			if terr := webhooks.Emit(tx, &config.Webhooks, conf.WebhookEventPhoneChanged, user, map[string]interface{}{
+				"old_phone": oldPhone,
+			}); terr != nil {
+				return apierrors.NewInternalServerError("Error queueing webhook event").WithInternalError(terr)
+			}
 		}
 
 		if user.IsAnonymous {
@@ -640,25 +649,44 @@ func (a *API) emailChangeVerify(r *http.Request, conn *storage.Connection, param
 	return user, nil
 }
 
+// verifyProvider returns the provider of the verification type for login
+// analytics.
+func verifyProvider(params *VerifyParams) string {
+	if params.Type == smsVerification || params.Type == phoneChangeVerification {
+		return metering.ProviderPhone
+	}
+	return metering.ProviderEmail
+}
+
 func (a *API) verifyTokenHash(conn *storage.Connection, params *VerifyParams) (*models.User, error) {
 	config := a.config
 
-	var user *models.User
-	var err error
+	var findUser func(tx *storage.Connection, token string) (*models.User, error)
 	switch params.Type {
 	case mail.EmailOTPVerification:
 		// need to find user by confirmation token or recovery token with the token hash
-		user, err = models.FindUserByConfirmationOrRecoveryToken(conn, params.TokenHash)
+		findUser = models.FindUserByConfirmationOrRecoveryToken
 	case mail.SignupVerification, mail.InviteVerification:
-		user, err = models.FindUserByConfirmationToken(conn, params.TokenHash)
+		findUser = models.FindUserByConfirmationToken
 	case mail.RecoveryVerification, mail.MagicLinkVerification:
-		user, err = models.FindUserByRecoveryToken(conn, params.TokenHash)
+		findUser = models.FindUserByRecoveryToken
 	case mail.EmailChangeVerification:
-		user, err = models.FindUserByEmailChangeToken(conn, params.TokenHash)
+		findUser = models.FindUserByEmailChangeToken
 	default:
 		return nil, apierrors.NewBadRequestError(apierrors.ErrorCodeValidationFailed, "Invalid email verification type")
 	}
 
+	user, err := findUser(conn, params.TokenHash)
+	if err == nil {
+		// The user is locked until the verification ends, so that
+		// concurrent verifications of the token wait for each other. The
+		// token is found again once the user is locked, as the first
+		// verification consumed it.
+		_, err = models.FindUserByIDForUpdate(conn, user.ID)
+		if err == nil {
+			user, err = findUser(conn, params.TokenHash)
+		}
+	}
 	if err != nil {
 		if models.IsNotFoundError(err) {
 			return nil, apierrors.NewForbiddenError(apierrors.ErrorCodeOTPExpired, "Email link is invalid or has expired").WithInternalError(err)
@@ -680,8 +708,10 @@ func (a *API) verifyTokenHash(conn *storage.Connection, params *VerifyParams) (*
 			params.Type = "magiclink"
 		}
 		isExpired = isOtpExpired(sentAt, config.Mailer.OtpExp)
-	case mail.SignupVerification, mail.InviteVerification:
+	case mail.SignupVerification:
 		isExpired = isOtpExpired(user.ConfirmationSentAt, config.Mailer.OtpExp)
+	case mail.InviteVerification:
+		isExpired = isOtpExpired(user.ConfirmationSentAt, config.Mailer.InviteExp)
 	case mail.RecoveryVerification, mail.MagicLinkVerification:
 		isExpired = isOtpExpired(user.RecoverySentAt, config.Mailer.OtpExp)
 	case mail.EmailChangeVerification:
@@ -715,6 +745,12 @@ func (a *API) verifyUserAndToken(conn *storage.Connection, params *VerifyParams,
 	default:
 		user, err = models.FindUserByEmailAndAudience(conn, params.Email, aud)
 	}
+	if err == nil {
+		// The user is locked until the verification ends, so that
+		// concurrent verifications of the token wait for each other and
+		// check it against the user as the first verification left it.
+		user, err = models.FindUserByIDForUpdate(conn, user.ID)
+	}
 
 	if err != nil {
 		if models.IsNotFoundError(err) {
@@ -742,8 +778,10 @@ func (a *API) verifyUserAndToken(conn *storage.Connection, params *VerifyParams,
 		} else {
 			isValid = false
 		}
-	case mail.SignupVerification, mail.InviteVerification:
+	case mail.SignupVerification:
 		isValid = isOtpValid(tokenHash, user.ConfirmationToken, user.ConfirmationSentAt, config.Mailer.OtpExp)
+	case mail.InviteVerification:
+		isValid = isOtpValid(tokenHash, user.ConfirmationToken, user.ConfirmationSentAt, config.Mailer.InviteExp)
 	case mail.RecoveryVerification, mail.MagicLinkVerification:
 		isValid = isOtpValid(tokenHash, user.RecoveryToken, user.RecoverySentAt, config.Mailer.OtpExp)
 	case mail.EmailChangeVerification:
@@ -766,7 +804,7 @@ func (a *API) verifyUserAndToken(conn *storage.Connection, params *VerifyParams,
 		}
 
 		if !config.Hook.SendSMS.Enabled && config.Sms.IsTwilioVerifyProvider() {
-			if err := smsProvider.(*sms_provider.TwilioVerifyProvider).VerifyOTP(phone, params.Token); err != nil {
+			if err := verifySMSOTP(conn.Context(), config, smsProvider, phone, params.Token); err != nil {
 				return nil, apierrors.NewForbiddenError(apierrors.ErrorCodeOTPExpired, "Token has expired or is invalid").WithInternalError(err)
 			}
 			return user, nil
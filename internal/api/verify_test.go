@@ -9,6 +9,7 @@ import (
 	"net/http/httptest"
 	"net/url"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -325,6 +326,32 @@ func (ts *VerifyTestSuite) TestExpiredConfirmationToken() {
 	assert.Equal(ts.T(), "access_denied", f.Get("error"))
 }
 
+func (ts *VerifyTestSuite) TestVerifyLinkDisabledWithOTPOnly() {
+	ts.Config.Mailer.OTPOnly = true
+	defer func() {
+		ts.Config.Mailer.OTPOnly = false
+	}()
+
+	u, err := models.FindUserByEmailAndAudience(ts.API.db, "test@example.com", ts.Config.JWT.Aud)
+	require.NoError(ts.T(), err)
+	u.ConfirmationToken = "asdf3"
+	sentTime := time.Now()
+	u.ConfirmationSentAt = &sentTime
+	require.NoError(ts.T(), ts.API.db.Update(u))
+	require.NoError(ts.T(), models.CreateOneTimeToken(ts.API.db, u.ID, u.GetEmail(), u.ConfirmationToken, models.ConfirmationToken))
+
+	reqURL := fmt.Sprintf("http://localhost/verify?type=%s&token=%s", mail.SignupVerification, u.ConfirmationToken)
+	req := httptest.NewRequest(http.MethodGet, reqURL, nil)
+	w := httptest.NewRecorder()
+
+	ts.API.handler.ServeHTTP(w, req)
+	assert.Equal(ts.T(), http.StatusForbidden, w.Code)
+
+	// the token must not have been consumed
+	_, err = models.FindOneTimeToken(ts.API.db, u.ConfirmationToken, models.ConfirmationToken)
+	require.NoError(ts.T(), err)
+}
+
 func (ts *VerifyTestSuite) TestInvalidOtp() {
 	u, err := models.FindUserByPhoneAndAudience(ts.API.db, "12345678", ts.Config.JWT.Aud)
 	require.NoError(ts.T(), err)
@@ -1582,3 +1609,78 @@ func (ts *VerifyTestSuite) TestVerifyPhoneChangeSendsNotificationEmailDisabled()
 	// Assert that phone change notification email was not sent
 	require.Len(ts.T(), mockMailer.PhoneChangedMailCalls, 0, "Expected 0 phone change notification email(s) to be sent")
 }
+
+func (ts *VerifyTestSuite) TestConcurrentVerification() {
+	u, err := models.FindUserByEmailAndAudience(ts.API.db, "test@example.com", ts.Config.JWT.Aud)
+	require.NoError(ts.T(), err)
+	tokenHash := crypto.GenerateTokenHash(u.GetEmail(), "123456")
+
+	cases := []struct {
+		desc string
+		body map[string]interface{}
+	}{
+		{
+			desc: "Token hash",
+			body: map[string]interface{}{
+				"type":       mail.MagicLinkVerification,
+				"token_hash": tokenHash,
+			},
+		},
+		{
+			desc: "Email OTP",
+			body: map[string]interface{}{
+				"type":  mail.MagicLinkVerification,
+				"token": "123456",
+				"email": u.GetEmail(),
+			},
+		},
+	}
+
+	for _, c := range cases {
+		ts.Run(c.desc, func() {
+			require.NoError(ts.T(), models.Logout(ts.API.db, u.ID))
+			sentTime := time.Now()
+			u.RecoveryToken = tokenHash
+			u.RecoverySentAt = &sentTime
+			require.NoError(ts.T(), ts.API.db.Update(u))
+			require.NoError(ts.T(), models.CreateOneTimeToken(ts.API.db, u.ID, u.GetEmail(), u.RecoveryToken, models.RecoveryToken))
+
+			// the requests race to consume the token, as if they were
+			// sent to different replicas
+			const requests = 5
+			codes := make([]int, requests)
+			var wg sync.WaitGroup
+			for i := 0; i < requests; i++ {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+
+					var buffer bytes.Buffer
+					if err := json.NewEncoder(&buffer).Encode(c.body); err != nil {
+						return
+					}
+					req := httptest.NewRequest(http.MethodPost, "http://localhost/verify", &buffer)
+					req.Header.Set("Content-Type", "application/json")
+					w := httptest.NewRecorder()
+					ts.API.handler.ServeHTTP(w, req)
+					codes[i] = w.Code
+				}(i)
+			}
+			wg.Wait()
+
+			succeeded := 0
+			for _, code := range codes {
+				if code == http.StatusOK {
+					succeeded++
+				} else {
+					require.Equal(ts.T(), http.StatusForbidden, code)
+				}
+			}
+			require.Equal(ts.T(), 1, succeeded)
+
+			sessions, err := models.FindAllSessionsForUser(ts.API.db, u.ID, false)
+			require.NoError(ts.T(), err)
+			require.Len(ts.T(), sessions, 1)
+		})
+	}
+}
@@ -1,10 +1,13 @@
 package api
 
 import (
+	"bytes"
 	"context"
 	"encoding/base64"
 	"net/http"
+	"net/url"
 	"strings"
+	"time"
 
 	"github.com/supabase/auth/internal/api/apierrors"
 	"github.com/supabase/auth/internal/api/provider"
@@ -22,6 +25,158 @@ type Web3GrantParams struct {
 	Chain     string `json:"chain,omitempty"`
 }
 
+// Web3NonceParams ask for the message the wallet with the address signs to
+// sign in to the app at the URI. Without a chain, only a nonce is issued.
+type Web3NonceParams struct {
+	Chain     string `json:"chain,omitempty"`
+	Address   string `json:"address,omitempty"`
+	URI       string `json:"uri,omitempty"`
+	Statement string `json:"statement,omitempty"`
+	Network   string `json:"network,omitempty"`
+}
+
+// Web3NonceResponse is the issued nonce, and the message with the nonce to
+// be signed when asked for.
+type Web3NonceResponse struct {
+	Nonce     string    `json:"nonce"`
+	ExpiresAt time.Time `json:"expires_at"`
+	Message   string    `json:"message,omitempty"`
+}
+
+// Web3Nonce issues a nonce to be signed into a Sign in with Ethereum or
+// Solana message, which the web3 grant accepts once. Given a chain, it also
+// returns the message for the wallet to sign.
+func (a *API) Web3Nonce(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	config := a.config
+	db := a.db.WithContext(ctx)
+
+	if !config.External.Web3Solana.Enabled && !config.External.Web3Ethereum.Enabled {
+		return apierrors.NewUnprocessableEntityError(apierrors.ErrorCodeWeb3ProviderDisabled, "Web3 provider is disabled")
+	}
+
+	params := &Web3NonceParams{}
+	body, err := utilities.GetBodyBytes(r)
+	if err != nil {
+		return apierrors.NewInternalServerError("Could not read body into byte slice").WithInternalError(err)
+	}
+	if len(bytes.TrimSpace(body)) > 0 {
+		if err := retrieveRequestParams(r, params); err != nil {
+			return err
+		}
+	}
+
+	var uri *url.URL
+	switch params.Chain {
+	case "":
+		// only a nonce
+	case "solana", "ethereum":
+		if params.Chain == "solana" && !config.External.Web3Solana.Enabled {
+			return apierrors.NewUnprocessableEntityError(apierrors.ErrorCodeWeb3ProviderDisabled, "Solana Web3 provider is disabled")
+		}
+		if params.Chain == "ethereum" && !config.External.Web3Ethereum.Enabled {
+			return apierrors.NewUnprocessableEntityError(apierrors.ErrorCodeWeb3ProviderDisabled, "Ethereum Web3 provider is disabled")
+		}
+
+		uri, err = url.ParseRequestURI(params.URI)
+		if err != nil || uri.Host == "" {
+			return apierrors.NewBadRequestError(apierrors.ErrorCodeValidationFailed, "uri must be an absolute URL")
+		}
+		if uri.Scheme != "https" && uri.Hostname() != "localhost" {
+			return apierrors.NewBadRequestError(apierrors.ErrorCodeValidationFailed, "uri must use HTTPS")
+		}
+		if !utilities.IsRedirectURLValid(config, uri.String()) {
+			return apierrors.NewBadRequestError(apierrors.ErrorCodeValidationFailed, "uri is not allowed on this server")
+		}
+		if strings.ContainsAny(params.Statement, "\r\n") {
+			return apierrors.NewBadRequestError(apierrors.ErrorCodeValidationFailed, "statement must be a single line")
+		}
+	default:
+		return apierrors.NewBadRequestError(apierrors.ErrorCodeWeb3UnsupportedChain, "Unsupported chain")
+	}
+
+	nonce, err := models.NewWeb3Nonce(db)
+	if err != nil {
+		return apierrors.NewInternalServerError("Database error creating nonce").WithInternalError(err)
+	}
+
+	response := &Web3NonceResponse{
+		Nonce:     nonce.Nonce,
+		ExpiresAt: nonce.ExpiresAt,
+	}
+
+	issuedAt := a.Now().UTC().Truncate(time.Second)
+	switch params.Chain {
+	case "solana":
+		msg := &siws.SIWSMessage{
+			Domain:         uri.Host,
+			Address:        params.Address,
+			Statement:      params.Statement,
+			URI:            uri,
+			Version:        "1",
+			ChainID:        params.Network,
+			Nonce:          nonce.Nonce,
+			IssuedAt:       issuedAt,
+			ExpirationTime: minTime(nonce.ExpiresAt, issuedAt.Add(config.External.Web3Solana.MaximumValidityDuration)).Truncate(time.Second),
+		}
+		response.Message = msg.Format()
+		if _, err := siws.ParseMessage(response.Message); err != nil {
+			return apierrors.NewBadRequestError(apierrors.ErrorCodeValidationFailed, "%s", err.Error())
+		}
+	case "ethereum":
+		chainID := params.Network
+		if chainID == "" {
+			chainID = "1"
+		}
+		expirationTime := minTime(nonce.ExpiresAt, issuedAt.Add(config.External.Web3Ethereum.MaximumValidityDuration)).Truncate(time.Second)
+		msg := &siwe.SIWEMessage{
+			Domain:         uri.Host,
+			Address:        params.Address,
+			URI:            *uri,
+			Version:        "1",
+			ChainID:        chainID,
+			Nonce:          nonce.Nonce,
+			IssuedAt:       issuedAt,
+			ExpirationTime: &expirationTime,
+		}
+		if params.Statement != "" {
+			msg.Statement = &params.Statement
+		}
+		response.Message = msg.Format()
+		if _, err := siwe.ParseMessage(response.Message); err != nil {
+			return apierrors.NewBadRequestError(apierrors.ErrorCodeValidationFailed, "%s", err.Error())
+		}
+	}
+
+	return sendJSON(w, http.StatusOK, response)
+}
+
+func minTime(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
+
+// consumeWeb3Nonce accepts the nonce of a signed message once, if it was
+// issued by Web3Nonce and hasn't expired.
+func (a *API) consumeWeb3Nonce(ctx context.Context, nonce string, chain string) error {
+	db := a.db.WithContext(ctx)
+
+	if nonce == "" {
+		return apierrors.NewOAuthError("invalid_grant", "Signed "+chain+" message does not have a nonce")
+	}
+
+	if err := models.ConsumeWeb3Nonce(db, nonce, a.Now()); err != nil {
+		if models.IsNotFoundError(err) {
+			return apierrors.NewOAuthError("invalid_grant", "Signed "+chain+" message is using a nonce which was not issued by this server, was already used or has expired")
+		}
+		return apierrors.NewInternalServerError("Database error consuming nonce").WithInternalError(err)
+	}
+
+	return nil
+}
+
 func (a *API) Web3Grant(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
 	config := a.config
 
@@ -114,6 +269,12 @@ func (a *API) web3GrantSolana(ctx context.Context, w http.ResponseWriter, r *htt
 		return apierrors.NewOAuthError("invalid_grant", "Solana message was issued too far in the future")
 	}
 
+	if config.External.Web3Solana.NonceRequired {
+		if err := a.consumeWeb3Nonce(ctx, parsedMessage.Nonce, "Solana"); err != nil {
+			return err
+		}
+	}
+
 	const providerType = "web3"
 	providerId := strings.Join([]string{
 		providerType,
@@ -200,7 +361,7 @@ func (a *API) web3GrantSolana(ctx context.Context, w http.ResponseWriter, r *htt
 		},
 	})
 
-	return sendJSON(w, http.StatusOK, token)
+	return a.sendTokenResponse(w, r, token)
 }
 
 func (a *API) web3GrantEthereum(ctx context.Context, w http.ResponseWriter, r *http.Request, params *Web3GrantParams) error {
@@ -260,6 +421,12 @@ func (a *API) web3GrantEthereum(ctx context.Context, w http.ResponseWriter, r *h
 		return apierrors.NewOAuthError("invalid_grant", "Ethereum message was issued too far in the future")
 	}
 
+	if config.External.Web3Ethereum.NonceRequired {
+		if err := a.consumeWeb3Nonce(ctx, parsedMessage.Nonce, "Ethereum"); err != nil {
+			return err
+		}
+	}
+
 	const providerType = "web3"
 	providerId := strings.Join([]string{
 		providerType,
@@ -335,5 +502,5 @@ func (a *API) web3GrantEthereum(ctx context.Context, w http.ResponseWriter, r *h
 			return err
 		}
 	}
-	return sendJSON(w, http.StatusOK, token)
+	return a.sendTokenResponse(w, r, token)
 }
@@ -2,6 +2,9 @@ package api
 
 import (
 	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -10,6 +13,10 @@ import (
 	"testing"
 	"time"
 
+	"github.com/btcsuite/btcutil/base58"
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/gobwas/glob"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -901,3 +908,230 @@ func (ts *Web3TestSuite) TestValidationRules_BasicValidation() {
 	}
 
 }
+
+func (ts *Web3TestSuite) requestWeb3Nonce() string {
+	req := httptest.NewRequest(http.MethodPost, "http://localhost/web3/nonce", nil)
+	w := httptest.NewRecorder()
+	ts.API.handler.ServeHTTP(w, req)
+	require.Equal(ts.T(), http.StatusOK, w.Code)
+
+	var result struct {
+		Nonce     string    `json:"nonce"`
+		ExpiresAt time.Time `json:"expires_at"`
+	}
+	require.NoError(ts.T(), json.NewDecoder(w.Body).Decode(&result))
+	require.Len(ts.T(), result.Nonce, 32)
+	require.True(ts.T(), result.ExpiresAt.After(time.Now()))
+
+	return result.Nonce
+}
+
+// signEthereumMessage signs a Sign in with Ethereum message with the nonce
+// with a new key, like a wallet.
+func (ts *Web3TestSuite) signEthereumMessage(nonce string) (string, string) {
+	key, err := crypto.GenerateKey()
+	require.NoError(ts.T(), err)
+	address := crypto.PubkeyToAddress(key.PublicKey).Hex()
+
+	message := "supabase.com wants you to sign in with your Ethereum account:\n" + address + "\n\nStatement\n\nURI: https://supabase.com\nVersion: 1\nChain ID: 1\n"
+	if nonce != "" {
+		message += "Nonce: " + nonce + "\n"
+	}
+	message += "Issued At: " + time.Now().UTC().Format(time.RFC3339)
+
+	signature, err := crypto.Sign(accounts.TextHash([]byte(message)), key)
+	require.NoError(ts.T(), err)
+	signature[64] += 27
+
+	return message, hexutil.Encode(signature)
+}
+
+func (ts *Web3TestSuite) requestEthereumGrant(message, signature string) *httptest.ResponseRecorder {
+	var buffer bytes.Buffer
+	require.NoError(ts.T(), json.NewEncoder(&buffer).Encode(map[string]interface{}{
+		"chain":     ChainEthereum,
+		"message":   message,
+		"signature": signature,
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "http://localhost/token?grant_type=web3", &buffer)
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	ts.API.handler.ServeHTTP(w, req)
+	return w
+}
+
+func (ts *Web3TestSuite) TestNonceRequired() {
+	defer func() {
+		ts.Config.External.Web3Ethereum.NonceRequired = false
+	}()
+	ts.Config.External.Web3Ethereum.Enabled = true
+	ts.Config.External.Web3Ethereum.NonceRequired = true
+
+	message, signature := ts.signEthereumMessage(ts.requestWeb3Nonce())
+
+	w := ts.requestEthereumGrant(message, signature)
+	require.Equal(ts.T(), http.StatusOK, w.Code)
+
+	var result struct {
+		AccessToken  string `json:"access_token"`
+		RefreshToken string `json:"refresh_token"`
+	}
+	require.NoError(ts.T(), json.NewDecoder(w.Body).Decode(&result))
+	assert.NotEmpty(ts.T(), result.AccessToken)
+	assert.NotEmpty(ts.T(), result.RefreshToken)
+
+	// a nonce is accepted only once
+	w = ts.requestEthereumGrant(message, signature)
+	assert.Equal(ts.T(), http.StatusBadRequest, w.Code)
+
+	var errorResult struct {
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	require.NoError(ts.T(), json.NewDecoder(w.Body).Decode(&errorResult))
+	assert.Equal(ts.T(), "invalid_grant", errorResult.Error)
+	assert.Equal(ts.T(), "Signed Ethereum message is using a nonce which was not issued by this server, was already used or has expired", errorResult.ErrorDescription)
+
+	// nonces not issued by the server are rejected
+	message, signature = ts.signEthereumMessage("abcdefgh12345678")
+	w = ts.requestEthereumGrant(message, signature)
+	assert.Equal(ts.T(), http.StatusBadRequest, w.Code)
+
+	message, signature = ts.signEthereumMessage("")
+	w = ts.requestEthereumGrant(message, signature)
+	assert.Equal(ts.T(), http.StatusBadRequest, w.Code)
+	require.NoError(ts.T(), json.NewDecoder(w.Body).Decode(&errorResult))
+	assert.Equal(ts.T(), "Signed Ethereum message does not have a nonce", errorResult.ErrorDescription)
+}
+
+func (ts *Web3TestSuite) TestNonceNotRequired() {
+	ts.Config.External.Web3Ethereum.Enabled = true
+
+	// messages with nonces chosen by the app are accepted when nonces
+	// issued by the server aren't required
+	message, signature := ts.signEthereumMessage("abcdefgh12345678")
+
+	w := ts.requestEthereumGrant(message, signature)
+	assert.Equal(ts.T(), http.StatusOK, w.Code)
+}
+
+func (ts *Web3TestSuite) requestWeb3NonceMessage(params map[string]interface{}) *httptest.ResponseRecorder {
+	var buffer bytes.Buffer
+	require.NoError(ts.T(), json.NewEncoder(&buffer).Encode(params))
+
+	req := httptest.NewRequest(http.MethodPost, "http://localhost/web3/nonce", &buffer)
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	ts.API.handler.ServeHTTP(w, req)
+	return w
+}
+
+func (ts *Web3TestSuite) TestNonceMessage_Solana() {
+	defer func() {
+		ts.Config.External.Web3Solana.NonceRequired = false
+	}()
+	ts.Config.External.Web3Solana.Enabled = true
+	ts.Config.External.Web3Solana.NonceRequired = true
+
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(ts.T(), err)
+	address := base58.Encode(publicKey)
+
+	w := ts.requestWeb3NonceMessage(map[string]interface{}{
+		"chain":     ChainSolana,
+		"address":   address,
+		"uri":       "https://supabase.com",
+		"statement": "Sign in to Supabase",
+		"network":   "solana:mainnet",
+	})
+	require.Equal(ts.T(), http.StatusOK, w.Code)
+
+	var nonceResult Web3NonceResponse
+	require.NoError(ts.T(), json.NewDecoder(w.Body).Decode(&nonceResult))
+	require.Len(ts.T(), nonceResult.Nonce, 32)
+	require.True(ts.T(), strings.HasPrefix(nonceResult.Message, "supabase.com wants you to sign in with your Solana account:\n"+address+"\n\nSign in to Supabase\n\nURI: https://supabase.com\nVersion: 1\nChain ID: solana:mainnet\nNonce: "+nonceResult.Nonce+"\n"))
+
+	signature := base64.StdEncoding.EncodeToString(ed25519.Sign(privateKey, []byte(nonceResult.Message)))
+
+	grant := func() *httptest.ResponseRecorder {
+		var buffer bytes.Buffer
+		require.NoError(ts.T(), json.NewEncoder(&buffer).Encode(map[string]interface{}{
+			"chain":     ChainSolana,
+			"message":   nonceResult.Message,
+			"signature": signature,
+		}))
+
+		req := httptest.NewRequest(http.MethodPost, "http://localhost/token?grant_type=web3", &buffer)
+		req.Header.Set("Content-Type", "application/json")
+
+		w := httptest.NewRecorder()
+		ts.API.handler.ServeHTTP(w, req)
+		return w
+	}
+
+	w = grant()
+	require.Equal(ts.T(), http.StatusOK, w.Code)
+
+	var result struct {
+		AccessToken string `json:"access_token"`
+		User        struct {
+			Identities []struct {
+				Provider   string `json:"provider"`
+				ProviderID string `json:"id"`
+			} `json:"identities"`
+		} `json:"user"`
+	}
+	require.NoError(ts.T(), json.NewDecoder(w.Body).Decode(&result))
+	assert.NotEmpty(ts.T(), result.AccessToken)
+	require.Len(ts.T(), result.User.Identities, 1)
+	assert.Equal(ts.T(), "web3", result.User.Identities[0].Provider)
+	assert.Equal(ts.T(), "web3:solana:"+address, result.User.Identities[0].ProviderID)
+
+	// the message can't be replayed
+	w = grant()
+	assert.Equal(ts.T(), http.StatusBadRequest, w.Code)
+}
+
+func (ts *Web3TestSuite) TestNonceMessage_Validation() {
+	ts.Config.External.Web3Solana.Enabled = true
+
+	examples := []struct {
+		params    map[string]interface{}
+		errorCode string
+	}{
+		{
+			params:    map[string]interface{}{"chain": "blockchain"},
+			errorCode: apierrors.ErrorCodeWeb3UnsupportedChain,
+		},
+		{
+			params:    map[string]interface{}{"chain": ChainSolana, "address": "2EZEiBdw47VHT6SpZSW9VnuSvBe7DxuYHBTxj19gxvv8", "uri": "https://supabase.com.evil.com"},
+			errorCode: apierrors.ErrorCodeValidationFailed,
+		},
+		{
+			params:    map[string]interface{}{"chain": ChainSolana, "address": "2EZEiBdw47VHT6SpZSW9VnuSvBe7DxuYHBTxj19gxvv8", "uri": "http://supabase.com"},
+			errorCode: apierrors.ErrorCodeValidationFailed,
+		},
+		{
+			params:    map[string]interface{}{"chain": ChainSolana, "address": "not-an-address", "uri": "https://supabase.com"},
+			errorCode: apierrors.ErrorCodeValidationFailed,
+		},
+		{
+			params:    map[string]interface{}{"chain": ChainSolana, "address": "2EZEiBdw47VHT6SpZSW9VnuSvBe7DxuYHBTxj19gxvv8", "uri": "https://supabase.com", "statement": "Sign in\nNonce: mine"},
+			errorCode: apierrors.ErrorCodeValidationFailed,
+		},
+	}
+
+	for _, example := range examples {
+		w := ts.requestWeb3NonceMessage(example.params)
+		assert.Equal(ts.T(), http.StatusBadRequest, w.Code)
+
+		var result struct {
+			ErrorCode string `json:"error_code"`
+		}
+		require.NoError(ts.T(), json.NewDecoder(w.Body).Decode(&result))
+		assert.Equal(ts.T(), example.errorCode, result.ErrorCode)
+	}
+}
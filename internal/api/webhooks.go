@@ -0,0 +1,126 @@
+package api
+
+import (
+	"net/http"
+	"slices"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/gofrs/uuid"
+	"github.com/supabase/auth/internal/api/apierrors"
+	"github.com/supabase/auth/internal/conf"
+	"github.com/supabase/auth/internal/models"
+)
+
+// AdminListWebhookDeliveriesResponse is the response of the admin webhook
+// delivery log endpoint.
+type AdminListWebhookDeliveriesResponse struct {
+	Deliveries []*models.WebhookDelivery `json:"deliveries"`
+}
+
+// WebhookDeliveryRedriveParams are the parameters of a webhook delivery
+// re-drive.
+type WebhookDeliveryRedriveParams struct {
+	IDs []uuid.UUID `json:"ids"`
+}
+
+// WebhookDeliveryRedriveResponse is the response of a webhook delivery
+// re-drive.
+type WebhookDeliveryRedriveResponse struct {
+	Redriven int `json:"redriven"`
+}
+
+// adminWebhookDeliveries lists the deliveries of the webhook delivery log,
+// newest first, filtered by the status, endpoint, event_type and user_id
+// query parameters.
+func (a *API) adminWebhookDeliveries(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	db := a.db.WithContext(ctx)
+	query := r.URL.Query()
+
+	pageParams, err := paginate(r)
+	if err != nil {
+		return apierrors.NewBadRequestError(apierrors.ErrorCodeValidationFailed, "Bad Pagination Parameters: %v", err).WithInternalError(err)
+	}
+
+	filter := models.WebhookDeliveryFilter{
+		Endpoint: query.Get("endpoint"),
+	}
+
+	switch value := models.WebhookDeliveryStatus(query.Get("status")); value {
+	case "":
+	case models.WebhookDeliveryPending, models.WebhookDeliveryDelivered, models.WebhookDeliveryDead:
+		filter.Status = value
+	default:
+		return apierrors.NewBadRequestError(apierrors.ErrorCodeValidationFailed, "status must be pending, delivered or dead")
+	}
+
+	if value := query.Get("event_type"); value != "" {
+		if !slices.Contains(conf.WebhookEvents, value) {
+			return apierrors.NewBadRequestError(apierrors.ErrorCodeValidationFailed, "event_type %q is not a webhook event", value)
+		}
+		filter.EventType = value
+	}
+
+	if value := query.Get("user_id"); value != "" {
+		userID, err := uuid.FromString(value)
+		if err != nil {
+			return apierrors.NewBadRequestError(apierrors.ErrorCodeValidationFailed, "user_id must be an UUID")
+		}
+		filter.UserID = &userID
+	}
+
+	deliveries, err := models.FindWebhookDeliveries(db, filter, pageParams)
+	if err != nil {
+		return apierrors.NewInternalServerError("Database error finding webhook deliveries").WithInternalError(err)
+	}
+	addPaginationHeaders(w, r, pageParams)
+
+	return sendJSON(w, http.StatusOK, AdminListWebhookDeliveriesResponse{
+		Deliveries: deliveries,
+	})
+}
+
+// adminWebhookDelivery responds with a delivery of the webhook delivery
+// log.
+func (a *API) adminWebhookDelivery(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	db := a.db.WithContext(ctx)
+
+	id, err := uuid.FromString(chi.URLParam(r, "delivery_id"))
+	if err != nil {
+		return apierrors.NewNotFoundError(apierrors.ErrorCodeValidationFailed, "delivery_id must be an UUID")
+	}
+
+	delivery, err := models.FindWebhookDeliveryByID(db, id)
+	if err != nil {
+		if models.IsNotFoundError(err) {
+			return apierrors.NewNotFoundError(apierrors.ErrorCodeWebhookDeliveryNotFound, "Webhook delivery not found")
+		}
+		return apierrors.NewInternalServerError("Database error finding webhook delivery").WithInternalError(err)
+	}
+
+	return sendJSON(w, http.StatusOK, delivery)
+}
+
+// adminWebhookDeliveriesRedrive queues dead-lettered webhook deliveries for
+// sending again, either the deliveries with the given IDs or all of them.
+func (a *API) adminWebhookDeliveriesRedrive(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	db := a.db.WithContext(ctx)
+
+	params := &WebhookDeliveryRedriveParams{}
+	if r.ContentLength != 0 {
+		if err := retrieveRequestParams(r, params); err != nil {
+			return err
+		}
+	}
+
+	redriven, err := models.RedriveWebhookDeliveries(db, params.IDs)
+	if err != nil {
+		return apierrors.NewInternalServerError("Database error re-driving webhook deliveries").WithInternalError(err)
+	}
+
+	return sendJSON(w, http.StatusOK, WebhookDeliveryRedriveResponse{
+		Redriven: redriven,
+	})
+}
@@ -0,0 +1,240 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	jwt "github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"github.com/supabase/auth/internal/conf"
+	"github.com/supabase/auth/internal/crypto"
+	"github.com/supabase/auth/internal/models"
+	"github.com/supabase/auth/internal/webhooks"
+)
+
+type WebhooksTestSuite struct {
+	suite.Suite
+	API    *API
+	Config *conf.GlobalConfiguration
+
+	token string
+}
+
+func TestWebhooks(t *testing.T) {
+	api, config, err := setupAPIForTest()
+	require.NoError(t, err)
+
+	ts := &WebhooksTestSuite{
+		API:    api,
+		Config: config,
+	}
+	defer api.db.Close()
+
+	suite.Run(t, ts)
+}
+
+func (ts *WebhooksTestSuite) SetupTest() {
+	models.TruncateAll(ts.API.db)
+
+	ts.Config.Webhooks = conf.WebhooksConfiguration{
+		Enabled: true,
+		Endpoints: conf.WebhookEndpoints{
+			{Name: "crm", URL: "https://crm.example.com", Secrets: conf.HTTPHookSecrets{"v1,whsec_aWxpa2V0b2VhdGx1bmNoYXRkb3JzZXRnb2JsaW5z"}, Events: []string{conf.WebhookEventUserCreated}},
+			{Name: "siem", URL: "https://siem.example.com", Secrets: conf.HTTPHookSecrets{"v1,whsec_aWxpa2V0b2VhdGx1bmNoYXRkb3JzZXRnb2JsaW5z"}},
+		},
+		Timeout:          time.Second,
+		MaxAttempts:      2,
+		RetryInterval:    time.Minute,
+		MaxRetryInterval: time.Minute,
+		PollInterval:     time.Second,
+		BatchSize:        10,
+		Retention:        time.Hour,
+	}
+
+	claims := &AccessTokenClaims{
+		Role: "supabase_admin",
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(ts.Config.JWT.Secret))
+	require.NoError(ts.T(), err, "Error generating admin jwt")
+	ts.token = token
+}
+
+func (ts *WebhooksTestSuite) TearDownTest() {
+	ts.Config.Webhooks = conf.WebhooksConfiguration{}
+}
+
+func (ts *WebhooksTestSuite) request(method, path string, body io.Reader) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, body)
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", ts.token))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	ts.API.handler.ServeHTTP(w, req)
+	return w
+}
+
+func (ts *WebhooksTestSuite) listDeliveries(query string) []*models.WebhookDelivery {
+	w := ts.request(http.MethodGet, "/admin/webhooks/deliveries"+query, nil)
+	require.Equal(ts.T(), http.StatusOK, w.Code, w.Body.String())
+
+	var res AdminListWebhookDeliveriesResponse
+	require.NoError(ts.T(), json.NewDecoder(w.Body).Decode(&res))
+	return res.Deliveries
+}
+
+func (ts *WebhooksTestSuite) signIn(email, password string) *httptest.ResponseRecorder {
+	var body bytes.Buffer
+	require.NoError(ts.T(), json.NewEncoder(&body).Encode(map[string]interface{}{
+		"email":    email,
+		"password": password,
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/token?grant_type=password", &body)
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	ts.API.handler.ServeHTTP(w, req)
+	return w
+}
+
+func (ts *WebhooksTestSuite) TestUserCreated() {
+	var body bytes.Buffer
+	require.NoError(ts.T(), json.NewEncoder(&body).Encode(map[string]interface{}{
+		"email":    "test@example.com",
+		"password": "test123",
+	}))
+
+	w := ts.request(http.MethodPost, "/admin/users", &body)
+	require.Equal(ts.T(), http.StatusOK, w.Code, w.Body.String())
+
+	var user models.User
+	require.NoError(ts.T(), json.NewDecoder(w.Body).Decode(&user))
+
+	// both endpoints are subscribed to the event
+	deliveries := ts.listDeliveries("?event_type=user.created")
+	require.Len(ts.T(), deliveries, 2)
+	require.Equal(ts.T(), deliveries[0].EventID, deliveries[1].EventID)
+	require.Equal(ts.T(), user.ID, *deliveries[0].UserID)
+	require.Equal(ts.T(), models.WebhookDeliveryPending, deliveries[0].Status)
+
+	var event webhooks.Event
+	require.NoError(ts.T(), json.Unmarshal(deliveries[0].Payload, &event))
+	require.Equal(ts.T(), deliveries[0].EventID, event.ID)
+	require.Equal(ts.T(), conf.WebhookEventUserCreated, event.Type)
+	require.Equal(ts.T(), "test@example.com", event.Data["user"].(map[string]interface{})["email"])
+
+	w = ts.request(http.MethodGet, "/admin/webhooks/deliveries/"+deliveries[0].ID.String(), nil)
+	require.Equal(ts.T(), http.StatusOK, w.Code, w.Body.String())
+
+	w = ts.request(http.MethodGet, "/admin/webhooks/deliveries/"+uuid.Must(uuid.NewV4()).String(), nil)
+	require.Equal(ts.T(), http.StatusNotFound, w.Code)
+}
+
+func (ts *WebhooksTestSuite) TestLogin() {
+	u, err := models.NewUser("", "test@example.com", "password", ts.Config.JWT.Aud, nil)
+	require.NoError(ts.T(), err)
+	require.NoError(ts.T(), ts.API.db.Create(u))
+	require.NoError(ts.T(), u.Confirm(ts.API.db))
+
+	w := ts.signIn("test@example.com", "password")
+	require.Equal(ts.T(), http.StatusOK, w.Code, w.Body.String())
+
+	w = ts.signIn("test@example.com", "wrong")
+	require.Equal(ts.T(), http.StatusBadRequest, w.Code)
+
+	w = ts.signIn("unknown@example.com", "password")
+	require.Equal(ts.T(), http.StatusBadRequest, w.Code)
+
+	// only the siem endpoint is subscribed to the login events
+	deliveries := ts.listDeliveries("?event_type=login.success")
+	require.Len(ts.T(), deliveries, 1)
+	require.Equal(ts.T(), "siem", deliveries[0].Endpoint)
+	require.Equal(ts.T(), u.ID, *deliveries[0].UserID)
+
+	deliveries = ts.listDeliveries("?event_type=login.failed")
+	require.Len(ts.T(), deliveries, 2)
+	require.Nil(ts.T(), deliveries[0].UserID, "no user has the email")
+	require.Equal(ts.T(), u.ID, *deliveries[1].UserID)
+
+	var event webhooks.Event
+	require.NoError(ts.T(), json.Unmarshal(deliveries[1].Payload, &event))
+	require.Equal(ts.T(), "invalid_password", event.Data["reason"])
+
+	require.Len(ts.T(), ts.listDeliveries("?user_id="+u.ID.String()), 2)
+	require.Empty(ts.T(), ts.listDeliveries("?endpoint=crm"))
+
+	w = ts.request(http.MethodGet, "/admin/webhooks/deliveries?event_type=user.updated", nil)
+	require.Equal(ts.T(), http.StatusBadRequest, w.Code)
+}
+
+func (ts *WebhooksTestSuite) TestPhoneChanged() {
+	u, err := models.NewUser("123456789", "test@example.com", "password", ts.Config.JWT.Aud, nil)
+	require.NoError(ts.T(), err)
+	require.NoError(ts.T(), ts.API.db.Create(u))
+
+	u.PhoneChange = "1234567890"
+	u.PhoneChangeToken = crypto.GenerateTokenHash(u.PhoneChange, "123456")
+	now := time.Now()
+	u.PhoneChangeSentAt = &now
+	require.NoError(ts.T(), ts.API.db.Update(u))
+	require.NoError(ts.T(), models.CreateOneTimeToken(ts.API.db, u.ID, u.PhoneChange, u.PhoneChangeToken, models.PhoneChangeToken))
+
+	var body bytes.Buffer
+	require.NoError(ts.T(), json.NewEncoder(&body).Encode(map[string]interface{}{
+		"type":  phoneChangeVerification,
+		"token": "123456",
+		"phone": u.PhoneChange,
+	}))
+	w := ts.request(http.MethodPost, "/verify", &body)
+	require.Equal(ts.T(), http.StatusOK, w.Code, w.Body.String())
+
+	deliveries := ts.listDeliveries("?event_type=phone.changed")
+	require.Len(ts.T(), deliveries, 1)
+	require.Equal(ts.T(), u.ID, *deliveries[0].UserID)
+
+	var event webhooks.Event
+	require.NoError(ts.T(), json.Unmarshal(deliveries[0].Payload, &event))
+	require.Equal(ts.T(), "123456789", event.Data["old_phone"])
+	require.Equal(ts.T(), "1234567890", event.Data["user"].(map[string]interface{})["phone"])
+}
+
+func (ts *WebhooksTestSuite) TestRedrive() {
+	create := func() *models.WebhookDelivery {
+		delivery := models.NewWebhookDelivery("siem", uuid.Must(uuid.NewV4()), conf.WebhookEventUserDeleted, nil, []byte(`{}`))
+		require.NoError(ts.T(), ts.API.db.Create(delivery))
+		require.NoError(ts.T(), delivery.Failed(ts.API.db, errors.New("connection refused"), nil, nil))
+		return delivery
+	}
+	first := create()
+	create()
+
+	var body bytes.Buffer
+	require.NoError(ts.T(), json.NewEncoder(&body).Encode(WebhookDeliveryRedriveParams{
+		IDs: []uuid.UUID{first.ID},
+	}))
+
+	w := ts.request(http.MethodPost, "/admin/webhooks/deliveries/redrive", &body)
+	require.Equal(ts.T(), http.StatusOK, w.Code, w.Body.String())
+
+	var res WebhookDeliveryRedriveResponse
+	require.NoError(ts.T(), json.NewDecoder(w.Body).Decode(&res))
+	require.Equal(ts.T(), 1, res.Redriven)
+
+	deliveries := ts.listDeliveries("?status=pending")
+	require.Len(ts.T(), deliveries, 1)
+	require.Equal(ts.T(), first.ID, deliveries[0].ID)
+	require.Equal(ts.T(), 0, deliveries[0].Attempts)
+
+	// without IDs every dead delivery is re-driven
+	w = ts.request(http.MethodPost, "/admin/webhooks/deliveries/redrive", nil)
+	require.Equal(ts.T(), http.StatusOK, w.Code, w.Body.String())
+	require.Empty(ts.T(), ts.listDeliveries("?status=dead"))
+}
@@ -0,0 +1,176 @@
+// Package auditretention prunes the audit log entries older than the
+// configured retention, archiving them to a bucket first when an archive is
+// configured.
+package auditretention
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/supabase/auth/internal/conf"
+	"github.com/supabase/auth/internal/models"
+	"github.com/supabase/auth/internal/storage"
+	"github.com/supabase/auth/internal/utilities"
+)
+
+const (
+	s3Service = "s3"
+
+	// gcsEndpoint is the XML API of Google Cloud Storage, which accepts
+	// requests signed with HMAC keys as S3 does.
+	gcsEndpoint = "https://storage.googleapis.com"
+	gcsRegion   = "auto"
+
+	archiveTimeout = 30 * time.Second
+)
+
+// Prune deletes up to config.BatchSize of the oldest entries that are older
+// than config.MaxAge, archiving them first when an archive is configured.
+// The entries are deleted only once they are archived. It returns the number
+// of entries it pruned.
+func Prune(
+	ctx context.Context,
+	db *storage.Connection,
+	config *conf.AuditLogRetentionConfiguration,
+	now time.Time,
+	le *logrus.Entry,
+) (int, error) {
+	var pruned int
+
+	err := db.WithContext(ctx).Transaction(func(tx *storage.Connection) error {
+		logs, err := models.FindExpiredAuditLogEntries(tx, now.Add(-config.MaxAge), config.BatchSize)
+		if err != nil {
+			return err
+		}
+		if len(logs) == 0 {
+			return nil
+		}
+
+		if config.Archive.Provider != "" {
+			key, err := archive(ctx, &config.Archive, logs)
+			if err != nil {
+				return err
+			}
+			le.WithFields(logrus.Fields{
+				"archive_key": key,
+				"entries":     len(logs),
+			}).Info("auditretention: archived audit log entries")
+		}
+
+		if err := models.DeleteAuditLogEntries(tx, logs); err != nil {
+			return err
+		}
+
+		pruned = len(logs)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return pruned, nil
+}
+
+// archive uploads the entries to the bucket as gzipped NDJSON, one entry per
+// line. The key is derived from the first entry, so that a batch which is
+// archived again after its deletion failed overwrites the same object.
+func archive(ctx context.Context, config *conf.AuditLogArchiveConfiguration, logs []*models.AuditLogEntry) (string, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	encoder := json.NewEncoder(zw)
+	for _, l := range logs {
+		if err := encoder.Encode(l); err != nil {
+			return "", fmt.Errorf("auditretention: error encoding audit log entry: %w", err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return "", fmt.Errorf("auditretention: error compressing audit log entries: %w", err)
+	}
+
+	first := logs[0]
+	key := path.Join(
+		config.Prefix,
+		first.CreatedAt.UTC().Format("2006/01/02"),
+		first.CreatedAt.UTC().Format("20060102T150405Z")+"-"+first.ID.String()+".ndjson.gz",
+	)
+
+	if err := putObject(ctx, config, key, buf.Bytes(), time.Now()); err != nil {
+		return "", err
+	}
+
+	return key, nil
+}
+
+// objectURL returns the path-style URL of the key in the bucket and the
+// region requests to it are signed for.
+func objectURL(config *conf.AuditLogArchiveConfiguration, key string) (string, string) {
+	endpoint, region := config.Endpoint, config.Region
+	switch config.Provider {
+	case "gcs":
+		if endpoint == "" {
+			endpoint = gcsEndpoint
+		}
+		if region == "" {
+			region = gcsRegion
+		}
+
+	default:
+		if endpoint == "" {
+			endpoint = "https://s3." + region + ".amazonaws.com"
+		}
+	}
+
+	escaped := make([]string, 0, strings.Count(key, "/")+1)
+	for _, segment := range strings.Split(key, "/") {
+		escaped = append(escaped, url.PathEscape(segment))
+	}
+
+	return strings.TrimSuffix(endpoint, "/") + "/" + url.PathEscape(config.Bucket) + "/" + strings.Join(escaped, "/"), region
+}
+
+func putObject(ctx context.Context, config *conf.AuditLogArchiveConfiguration, key string, body []byte, now time.Time) error {
+	ctx, cancel := context.WithTimeout(ctx, archiveTimeout)
+	defer cancel()
+
+	objectURL, region := objectURL(config, key)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, objectURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("auditretention: error creating archive request: %w", err)
+	}
+
+	sum := sha256.Sum256(body)
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	req.Header.Set("Content-Encoding", "gzip")
+	req.Header.Set("X-Amz-Content-Sha256", hex.EncodeToString(sum[:]))
+	utilities.SignAWSRequest(req, string(body), s3Service, region, utilities.AWSCredentials{
+		AccessKeyID:     config.AccessKeyID,
+		SecretAccessKey: config.SecretAccessKey,
+		SessionToken:    config.SessionToken,
+	}, now)
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("auditretention: error archiving audit log entries: %w", err)
+	}
+	defer utilities.SafeClose(res.Body)
+
+	if res.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+		return fmt.Errorf("auditretention: archiving audit log entries failed with status %d: %s", res.StatusCode, strings.TrimSpace(string(msg)))
+	}
+
+	return nil
+}
@@ -0,0 +1,127 @@
+package auditretention
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/require"
+	"github.com/supabase/auth/internal/conf"
+	"github.com/supabase/auth/internal/models"
+)
+
+func TestObjectURL(t *testing.T) {
+	cases := []struct {
+		config conf.AuditLogArchiveConfiguration
+		url    string
+		region string
+	}{
+		{
+			config: conf.AuditLogArchiveConfiguration{Provider: "s3", Bucket: "audit", Region: "eu-west-1"},
+			url:    "https://s3.eu-west-1.amazonaws.com/audit/2026/01/02/log file.ndjson.gz",
+			region: "eu-west-1",
+		},
+		{
+			config: conf.AuditLogArchiveConfiguration{Provider: "s3", Bucket: "audit", Region: "us-east-1", Endpoint: "http://minio:9000/"},
+			url:    "http://minio:9000/audit/2026/01/02/log file.ndjson.gz",
+			region: "us-east-1",
+		},
+		{
+			config: conf.AuditLogArchiveConfiguration{Provider: "gcs", Bucket: "audit"},
+			url:    "https://storage.googleapis.com/audit/2026/01/02/log file.ndjson.gz",
+			region: "auto",
+		},
+	}
+
+	for _, tc := range cases {
+		u, region := objectURL(&tc.config, "2026/01/02/log file.ndjson.gz")
+		require.Equal(t, strings.ReplaceAll(tc.url, " ", "%20"), u)
+		require.Equal(t, tc.region, region)
+	}
+}
+
+func TestArchive(t *testing.T) {
+	var (
+		gotPath string
+		gotReq  *http.Request
+		gotLogs []models.AuditLogEntry
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		gotReq = r
+
+		zr, err := gzip.NewReader(r.Body)
+		require.NoError(t, err)
+		decoder := json.NewDecoder(zr)
+		for {
+			var l models.AuditLogEntry
+			err := decoder.Decode(&l)
+			if err == io.EOF {
+				break
+			}
+			require.NoError(t, err)
+			gotLogs = append(gotLogs, l)
+		}
+	}))
+	defer server.Close()
+
+	config := &conf.AuditLogArchiveConfiguration{
+		Provider:        "s3",
+		Bucket:          "audit",
+		Prefix:          "auth",
+		Region:          "us-east-1",
+		Endpoint:        server.URL,
+		AccessKeyID:     "AKIDEXAMPLE",
+		SecretAccessKey: "secret",
+	}
+
+	createdAt := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	logs := []*models.AuditLogEntry{
+		{ID: uuid.Must(uuid.NewV4()), CreatedAt: createdAt, Payload: models.JSONMap{"action": "login"}},
+		{ID: uuid.Must(uuid.NewV4()), CreatedAt: createdAt.Add(time.Second), Payload: models.JSONMap{"action": "logout"}},
+	}
+
+	key, err := archive(context.Background(), config, logs)
+	require.NoError(t, err)
+	require.Equal(t, "auth/2026/01/02/20260102T030405Z-"+logs[0].ID.String()+".ndjson.gz", key)
+	require.Equal(t, "/audit/"+key, gotPath)
+
+	require.Equal(t, http.MethodPut, gotReq.Method)
+	require.Equal(t, "gzip", gotReq.Header.Get("Content-Encoding"))
+	require.NotEmpty(t, gotReq.Header.Get("X-Amz-Content-Sha256"))
+	require.Contains(t, gotReq.Header.Get("Authorization"), "Credential=AKIDEXAMPLE/")
+	require.Contains(t, gotReq.Header.Get("Authorization"), "/us-east-1/s3/aws4_request, SignedHeaders=content-type;host;x-amz-content-sha256;x-amz-date,")
+
+	require.Len(t, gotLogs, 2)
+	require.Equal(t, logs[0].ID, gotLogs[0].ID)
+	require.Equal(t, "logout", gotLogs[1].Payload["action"])
+}
+
+func TestArchiveFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte("<Error><Code>AccessDenied</Code></Error>"))
+	}))
+	defer server.Close()
+
+	config := &conf.AuditLogArchiveConfiguration{
+		Provider:        "gcs",
+		Bucket:          "audit",
+		Endpoint:        server.URL,
+		AccessKeyID:     "GOOG1EXAMPLE",
+		SecretAccessKey: "secret",
+	}
+
+	_, err := archive(context.Background(), config, []*models.AuditLogEntry{
+		{ID: uuid.Must(uuid.NewV4()), CreatedAt: time.Now()},
+	})
+	require.ErrorContains(t, err, "archiving audit log entries failed with status 403: <Error><Code>AccessDenied</Code></Error>")
+}
@@ -0,0 +1,232 @@
+// Package auditsink ships the audit log entries to external systems, such
+// as a SIEM, as they are recorded. Every enabled sink has a buffer that
+// entries are added to without waiting for the sink, and a goroutine that
+// sends them in batches with retries. When a sink falls behind and its
+// buffer is full, recording an entry waits for a bounded time before the
+// entry is dropped for that sink, so a slow sink slows requests down but
+// never stops them.
+package auditsink
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/supabase/auth/internal/conf"
+)
+
+// sendTimeout bounds a single attempt to send a batch to a sink.
+const sendTimeout = 30 * time.Second
+
+// Event is an audit log entry as it is shipped to the sinks.
+type Event struct {
+	ID        uuid.UUID              `json:"id"`
+	CreatedAt time.Time              `json:"created_at"`
+	IPAddress string                 `json:"ip_address"`
+	Payload   map[string]interface{} `json:"payload"`
+}
+
+// Sink sends batches of events to an external system.
+type Sink interface {
+	// Send sends the events, in order. A batch that failed to be sent is
+	// sent again, so sinks deliver events at least once.
+	Send(ctx context.Context, events []*Event) error
+
+	// Close releases the resources of the sink once no more events are
+	// sent to it.
+	Close() error
+}
+
+var (
+	mu          sync.RWMutex
+	dispatchers []*dispatcher
+)
+
+// Configure starts shipping events to the sinks enabled in the
+// configuration, replacing the sinks of a previous configuration. The
+// events buffered for the previous sinks are still sent.
+func Configure(config *conf.AuditLogSinksConfiguration, le *logrus.Entry) error {
+	sinks := map[string]Sink{}
+	if config.Syslog.Enabled {
+		sinks["syslog"] = NewSyslogSink(&config.Syslog)
+	}
+	if config.Kafka.Enabled {
+		sinks["kafka"] = NewKafkaSink(&config.Kafka)
+	}
+	if config.Webhook.Enabled {
+		sink, err := NewWebhookSink(&config.Webhook)
+		if err != nil {
+			return err
+		}
+		sinks["webhook"] = sink
+	}
+
+	next := make([]*dispatcher, 0, len(sinks))
+	for name, sink := range sinks {
+		next = append(next, newDispatcher(sink, config, le.WithField("audit_sink", name)))
+	}
+
+	mu.Lock()
+	previous := dispatchers
+	dispatchers = next
+	mu.Unlock()
+
+	for _, d := range previous {
+		go d.close(context.Background())
+	}
+
+	return nil
+}
+
+// Emit adds the event to the buffer of every sink.
+func Emit(e *Event) {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	for _, d := range dispatchers {
+		d.emit(e)
+	}
+}
+
+// Close stops shipping events, sending the buffered events until the
+// context is done.
+func Close(ctx context.Context) error {
+	mu.Lock()
+	previous := dispatchers
+	dispatchers = nil
+	mu.Unlock()
+
+	var errs []error
+	for _, d := range previous {
+		errs = append(errs, d.close(ctx))
+	}
+	return errors.Join(errs...)
+}
+
+// dispatcher buffers the events of a sink and sends them in batches.
+type dispatcher struct {
+	sink   Sink
+	config conf.AuditLogSinksConfiguration
+	le     *logrus.Entry
+
+	events chan *Event
+	done   chan struct{}
+
+	// dropped counts the events dropped since the last batch was sent
+	dropped atomic.Int64
+}
+
+func newDispatcher(sink Sink, config *conf.AuditLogSinksConfiguration, le *logrus.Entry) *dispatcher {
+	d := &dispatcher{
+		sink:   sink,
+		config: *config,
+		le:     le,
+		events: make(chan *Event, config.BufferSize),
+		done:   make(chan struct{}),
+	}
+	go d.run()
+	return d
+}
+
+// emit adds the event to the buffer, waiting up to the block timeout for
+// room in it. It must not be called after close.
+func (d *dispatcher) emit(e *Event) {
+	select {
+	case d.events <- e:
+		return
+	default:
+	}
+
+	if d.config.BlockTimeout > 0 {
+		timer := time.NewTimer(d.config.BlockTimeout)
+		defer timer.Stop()
+
+		select {
+		case d.events <- e:
+			return
+		case <-timer.C:
+		}
+	}
+
+	d.dropped.Add(1)
+}
+
+// close stops accepting events and waits until the buffered events are sent
+// or the context is done.
+func (d *dispatcher) close(ctx context.Context) error {
+	close(d.events)
+
+	select {
+	case <-d.done:
+		return d.sink.Close()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (d *dispatcher) run() {
+	defer close(d.done)
+
+	ticker := time.NewTicker(d.config.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]*Event, 0, d.config.BatchSize)
+	for {
+		select {
+		case e, ok := <-d.events:
+			if !ok {
+				d.flush(batch)
+				return
+			}
+
+			batch = append(batch, e)
+			if len(batch) < d.config.BatchSize {
+				continue
+			}
+
+		case <-ticker.C:
+		}
+
+		d.flush(batch)
+		batch = make([]*Event, 0, d.config.BatchSize)
+	}
+}
+
+// flush sends the batch, retrying with an exponential backoff until it was
+// attempted max attempts times.
+func (d *dispatcher) flush(batch []*Event) {
+	if dropped := d.dropped.Swap(0); dropped > 0 {
+		d.le.WithField("dropped", dropped).Warn("auditsink: dropped audit log entries, the sink's buffer is full")
+	}
+
+	if len(batch) == 0 {
+		return
+	}
+
+	interval := d.config.RetryInterval
+	for attempt := 1; ; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), sendTimeout)
+		err := d.sink.Send(ctx, batch)
+		cancel()
+		if err == nil {
+			return
+		}
+
+		le := d.le.WithError(err).WithFields(logrus.Fields{
+			"attempt": attempt,
+			"entries": len(batch),
+		})
+		if attempt >= d.config.MaxAttempts {
+			le.Error("auditsink: dropped audit log entries that failed to be sent")
+			return
+		}
+
+		le.Warn("auditsink: failed to send audit log entries, retrying")
+		time.Sleep(interval)
+		interval *= 2
+	}
+}
@@ -0,0 +1,180 @@
+package auditsink
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+	"github.com/supabase/auth/internal/conf"
+)
+
+type testSink struct {
+	mu      sync.Mutex
+	batches [][]*Event
+	fails   int
+	block   chan struct{}
+	closed  bool
+}
+
+func (s *testSink) Send(ctx context.Context, events []*Event) error {
+	if s.block != nil {
+		<-s.block
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.fails > 0 {
+		s.fails--
+		return errors.New("unavailable")
+	}
+	s.batches = append(s.batches, events)
+	return nil
+}
+
+func (s *testSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	return nil
+}
+
+func (s *testSink) sent() [][]*Event {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.batches
+}
+
+func testConfig() *conf.AuditLogSinksConfiguration {
+	return &conf.AuditLogSinksConfiguration{
+		BufferSize:    10,
+		BatchSize:     3,
+		FlushInterval: time.Hour,
+		MaxAttempts:   3,
+		RetryInterval: time.Millisecond,
+	}
+}
+
+func newEvent() *Event {
+	return &Event{
+		ID:        uuid.Must(uuid.NewV4()),
+		CreatedAt: time.Now(),
+		Payload:   map[string]interface{}{"action": "login"},
+	}
+}
+
+func TestDispatcherBatches(t *testing.T) {
+	sink := &testSink{fails: 2}
+	d := newDispatcher(sink, testConfig(), logrus.NewEntry(logrus.New()))
+
+	var events []*Event
+	for i := 0; i < 7; i++ {
+		e := newEvent()
+		events = append(events, e)
+		d.emit(e)
+	}
+
+	require.NoError(t, d.close(context.Background()))
+	require.True(t, sink.closed)
+
+	// full batches are sent as they fill up, the rest when the dispatcher
+	// is closed, and the failed batch is retried
+	batches := sink.sent()
+	require.Len(t, batches, 3)
+	require.Equal(t, events[0:3], batches[0])
+	require.Equal(t, events[3:6], batches[1])
+	require.Equal(t, events[6:], batches[2])
+}
+
+func TestDispatcherFlushInterval(t *testing.T) {
+	config := testConfig()
+	config.FlushInterval = 10 * time.Millisecond
+
+	sink := &testSink{}
+	d := newDispatcher(sink, config, logrus.NewEntry(logrus.New()))
+	defer d.close(context.Background())
+
+	d.emit(newEvent())
+	require.Eventually(t, func() bool {
+		return len(sink.sent()) == 1
+	}, time.Second, 5*time.Millisecond)
+}
+
+func TestDispatcherDropsWhenFull(t *testing.T) {
+	config := testConfig()
+	config.BufferSize = 3
+	config.BlockTimeout = time.Millisecond
+
+	sink := &testSink{block: make(chan struct{})}
+	d := newDispatcher(sink, config, logrus.NewEntry(logrus.New()))
+
+	// the first batch is being sent, the next fills the buffer
+	for i := 0; i < 6; i++ {
+		d.emit(newEvent())
+	}
+	require.Eventually(t, func() bool {
+		return len(d.events) == 3
+	}, time.Second, time.Millisecond)
+
+	start := time.Now()
+	d.emit(newEvent())
+	require.GreaterOrEqual(t, time.Since(start), config.BlockTimeout)
+	require.Equal(t, int64(1), d.dropped.Load())
+
+	close(sink.block)
+	require.NoError(t, d.close(context.Background()))
+
+	sent := 0
+	for _, batch := range sink.sent() {
+		sent += len(batch)
+	}
+	require.Equal(t, 6, sent)
+	require.Equal(t, int64(0), d.dropped.Load())
+}
+
+func TestDispatcherGivesUp(t *testing.T) {
+	sink := &testSink{fails: 3}
+	d := newDispatcher(sink, testConfig(), logrus.NewEntry(logrus.New()))
+
+	d.emit(newEvent())
+	d.emit(newEvent())
+	d.emit(newEvent())
+	d.emit(newEvent())
+	require.NoError(t, d.close(context.Background()))
+
+	// the first batch failed max attempts times and was dropped
+	batches := sink.sent()
+	require.Len(t, batches, 1)
+	require.Len(t, batches[0], 1)
+}
+
+func TestEmit(t *testing.T) {
+	config := testConfig()
+	config.Webhook = conf.AuditLogWebhookConfiguration{
+		Enabled: true,
+		URL:     "http://localhost:1",
+		Secrets: conf.HTTPHookSecrets{"v1,whsec_aWxpa2V0b2VhdGx1bmNoYXRkb3JzZXRnb2JsaW5z"},
+		Timeout: time.Second,
+	}
+	require.NoError(t, Configure(config, logrus.NewEntry(logrus.New())))
+	require.Len(t, dispatchers, 1)
+
+	sink := &testSink{}
+	dispatchers[0].sink = sink
+
+	Emit(newEvent())
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	require.NoError(t, Close(ctx))
+	require.Empty(t, dispatchers)
+	require.Len(t, sink.sent(), 1)
+
+	// events are not shipped once closed
+	Emit(newEvent())
+}
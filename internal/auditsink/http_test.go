@@ -0,0 +1,111 @@
+package auditsink
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	standardwebhooks "github.com/standard-webhooks/standard-webhooks/libraries/go"
+	"github.com/stretchr/testify/require"
+	"github.com/supabase/auth/internal/conf"
+)
+
+func TestKafkaSink(t *testing.T) {
+	var records []kafkaRecord
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/topics/auth.audit", r.URL.Path)
+		require.Equal(t, kafkaContentType, r.Header.Get("Content-Type"))
+
+		username, password, ok := r.BasicAuth()
+		require.True(t, ok)
+		require.Equal(t, "auth", username)
+		require.Equal(t, "secret", password)
+
+		var body struct {
+			Records []kafkaRecord `json:"records"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		records = body.Records
+
+		w.Header().Set("Content-Type", "application/vnd.kafka.v2+json")
+		_, _ = w.Write([]byte(`{"offsets":[{"partition":0,"offset":1,"error_code":null,"error":null},{"partition":1,"offset":7,"error_code":null,"error":null}]}`))
+	}))
+	defer server.Close()
+
+	sink := NewKafkaSink(&conf.AuditLogKafkaConfiguration{
+		Enabled:  true,
+		URL:      server.URL + "/",
+		Topic:    "auth.audit",
+		Username: "auth",
+		Password: "secret",
+	})
+	defer sink.Close()
+
+	actorID := uuid.Must(uuid.NewV4())
+	events := []*Event{
+		{ID: uuid.Must(uuid.NewV4()), Payload: map[string]interface{}{"actor_id": actorID, "action": "login"}},
+		{ID: uuid.Must(uuid.NewV4()), Payload: map[string]interface{}{"action": "logout"}},
+	}
+	require.NoError(t, sink.Send(context.Background(), events))
+
+	require.Len(t, records, 2)
+	require.Equal(t, actorID.String(), records[0].Key)
+	require.Equal(t, events[0].ID, records[0].Value.ID)
+	require.Equal(t, "", records[1].Key)
+}
+
+func TestKafkaSinkRecordError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"offsets":[{"partition":null,"offset":null,"error_code":50003,"error":"Kafka error: leader not available"}]}`))
+	}))
+	defer server.Close()
+
+	sink := NewKafkaSink(&conf.AuditLogKafkaConfiguration{Enabled: true, URL: server.URL, Topic: "audit"})
+	err := sink.Send(context.Background(), []*Event{{ID: uuid.Must(uuid.NewV4())}})
+	require.ErrorContains(t, err, "producing to kafka failed with error code 50003: Kafka error: leader not available")
+}
+
+func TestWebhookSink(t *testing.T) {
+	const secret = "v1,whsec_aWxpa2V0b2VhdGx1bmNoYXRkb3JzZXRnb2JsaW5z"
+	now := time.Now()
+
+	status := http.StatusNoContent
+	var payload webhookPayload
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+
+		wh, err := standardwebhooks.NewWebhook(secret[len("v1,"):])
+		require.NoError(t, err)
+		require.NoError(t, wh.Verify(body, r.Header))
+		require.Equal(t, strconv.FormatInt(now.Unix(), 10), r.Header.Get("webhook-timestamp"))
+
+		require.NoError(t, json.Unmarshal(body, &payload))
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	sink, err := NewWebhookSink(&conf.AuditLogWebhookConfiguration{
+		Enabled: true,
+		URL:     server.URL,
+		Secrets: conf.HTTPHookSecrets{secret},
+		Timeout: time.Second,
+	})
+	require.NoError(t, err)
+	sink.signer.Now = func() time.Time { return now }
+
+	events := []*Event{{ID: uuid.Must(uuid.NewV4()), Payload: map[string]interface{}{"action": "login"}}}
+	require.NoError(t, sink.Send(context.Background(), events))
+	require.Equal(t, "audit_log", payload.Type)
+	require.Len(t, payload.Entries, 1)
+	require.Equal(t, events[0].ID, payload.Entries[0].ID)
+
+	status = http.StatusBadGateway
+	require.ErrorContains(t, sink.Send(context.Background(), events), "webhook failed with status 502")
+}
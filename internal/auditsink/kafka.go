@@ -0,0 +1,102 @@
+package auditsink
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/supabase/auth/internal/conf"
+	"github.com/supabase/auth/internal/utilities"
+)
+
+const kafkaContentType = "application/vnd.kafka.json.v2+json"
+
+// KafkaSink produces events to a Kafka topic through the v2 API of a Kafka
+// REST Proxy, which the Confluent REST Proxy and the Redpanda HTTP Proxy
+// implement. Events are keyed by their actor, so the events of a user are
+// kept in order.
+type KafkaSink struct {
+	config *conf.AuditLogKafkaConfiguration
+	client *http.Client
+}
+
+// NewKafkaSink returns a KafkaSink based on the given configuration.
+func NewKafkaSink(config *conf.AuditLogKafkaConfiguration) *KafkaSink {
+	return &KafkaSink{
+		config: config,
+		client: &http.Client{},
+	}
+}
+
+type kafkaRecord struct {
+	Key   string `json:"key,omitempty"`
+	Value *Event `json:"value"`
+}
+
+type kafkaProduceResponse struct {
+	Offsets []struct {
+		ErrorCode *int   `json:"error_code"`
+		Error     string `json:"error"`
+	} `json:"offsets"`
+}
+
+// Send implements Sink by producing a record per event in a single request.
+func (s *KafkaSink) Send(ctx context.Context, events []*Event) error {
+	records := make([]kafkaRecord, len(events))
+	for i, e := range events {
+		records[i] = kafkaRecord{
+			Key:   payloadString(e.Payload, "actor_id"),
+			Value: e,
+		}
+	}
+
+	body, err := json.Marshal(map[string]interface{}{"records": records})
+	if err != nil {
+		return fmt.Errorf("auditsink: error encoding kafka records: %w", err)
+	}
+
+	endpoint := strings.TrimSuffix(s.config.URL, "/") + "/topics/" + url.PathEscape(s.config.Topic)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("auditsink: error creating kafka request: %w", err)
+	}
+	req.Header.Set("Content-Type", kafkaContentType)
+	req.Header.Set("Accept", "application/vnd.kafka.v2+json, application/json")
+	if s.config.Username != "" {
+		req.SetBasicAuth(s.config.Username, s.config.Password)
+	}
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("auditsink: error producing to kafka: %w", err)
+	}
+	defer utilities.SafeClose(res.Body)
+
+	data, _ := io.ReadAll(io.LimitReader(res.Body, 1<<20))
+	if res.StatusCode/100 != 2 {
+		return fmt.Errorf("auditsink: producing to kafka failed with status %d: %s", res.StatusCode, strings.TrimSpace(string(data)))
+	}
+
+	var produced kafkaProduceResponse
+	if err := json.Unmarshal(data, &produced); err != nil {
+		return fmt.Errorf("auditsink: error decoding kafka response: %w", err)
+	}
+	for _, offset := range produced.Offsets {
+		if offset.ErrorCode != nil {
+			return fmt.Errorf("auditsink: producing to kafka failed with error code %d: %s", *offset.ErrorCode, offset.Error)
+		}
+	}
+
+	return nil
+}
+
+// Close implements Sink.
+func (s *KafkaSink) Close() error {
+	s.client.CloseIdleConnections()
+	return nil
+}
@@ -0,0 +1,211 @@
+package auditsink
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/supabase/auth/internal/conf"
+	"github.com/supabase/auth/internal/utilities"
+)
+
+const (
+	syslogSeverityWarning = 4
+	syslogSeverityInfo    = 6
+)
+
+// securityActions are the actions that indicate an attack or a locked out
+// user, they are sent with a higher severity.
+var securityActions = map[string]bool{
+	"token_reuse_detected": true,
+	"password_pwned":       true,
+	"user_locked":          true,
+}
+
+// SyslogSink sends events to a syslog server as RFC 5424 messages with a CEF
+// payload, one message per event.
+type SyslogSink struct {
+	config   *conf.AuditLogSyslogConfiguration
+	hostname string
+
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+// NewSyslogSink returns a SyslogSink based on the given configuration. The
+// connection is opened when the first events are sent.
+func NewSyslogSink(config *conf.AuditLogSyslogConfiguration) *SyslogSink {
+	hostname := config.Hostname
+	if hostname == "" {
+		hostname, _ = os.Hostname()
+	}
+	if hostname == "" {
+		hostname = "-"
+	}
+
+	return &SyslogSink{
+		config:   config,
+		hostname: hostname,
+	}
+}
+
+// Send implements Sink by writing a message per event to the connection. The
+// connection is opened again for the next batch after a write failed.
+func (s *SyslogSink) Send(ctx context.Context, events []*Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.conn == nil {
+		conn, err := s.dial(ctx)
+		if err != nil {
+			return fmt.Errorf("auditsink: error connecting to syslog: %w", err)
+		}
+		s.conn = conn
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = s.conn.SetWriteDeadline(deadline)
+	}
+
+	for _, e := range events {
+		msg := s.message(e)
+		if s.config.Network != "udp" {
+			// octet counting framing of RFC 6587
+			msg = strconv.Itoa(len(msg)) + " " + msg
+		}
+
+		if _, err := s.conn.Write([]byte(msg)); err != nil {
+			_ = s.conn.Close()
+			s.conn = nil
+			return fmt.Errorf("auditsink: error writing to syslog: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Close implements Sink by closing the connection.
+func (s *SyslogSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
+
+func (s *SyslogSink) dial(ctx context.Context) (net.Conn, error) {
+	if s.config.Network == "tls" {
+		dialer := &tls.Dialer{Config: &tls.Config{MinVersion: tls.VersionTLS12}}
+		return dialer.DialContext(ctx, "tcp", s.config.Address)
+	}
+
+	var dialer net.Dialer
+	return dialer.DialContext(ctx, s.config.Network, s.config.Address)
+}
+
+// message formats the event as an RFC 5424 message.
+func (s *SyslogSink) message(e *Event) string {
+	severity := syslogSeverityInfo
+	if securityActions[payloadString(e.Payload, "action")] {
+		severity = syslogSeverityWarning
+	}
+
+	return fmt.Sprintf("<%d>1 %s %s %s - audit - %s\n",
+		s.config.Facility*8+severity,
+		e.CreatedAt.UTC().Format(time.RFC3339Nano),
+		s.hostname,
+		s.config.AppName,
+		FormatCEF(e),
+	)
+}
+
+// FormatCEF formats the event in the ArcSight Common Event Format.
+func FormatCEF(e *Event) string {
+	action := payloadString(e.Payload, "action")
+
+	severity := 3
+	if securityActions[action] {
+		severity = 7
+	}
+
+	version := utilities.Version
+	if version == "" {
+		version = "unknown"
+	}
+
+	extensions := [][2]string{
+		{"rt", strconv.FormatInt(e.CreatedAt.UnixMilli(), 10)},
+		{"externalId", e.ID.String()},
+		{"act", action},
+		{"cat", payloadString(e.Payload, "log_type")},
+		{"src", e.IPAddress},
+		{"suid", payloadString(e.Payload, "actor_id")},
+		{"suser", payloadString(e.Payload, "actor_username")},
+	}
+
+	if traits, ok := e.Payload["traits"].(map[string]interface{}); ok {
+		extensions = append(extensions,
+			[2]string{"duid", payloadString(traits, "user_id")},
+			[2]string{"duser", payloadString(traits, "user_email")},
+		)
+		if data, err := json.Marshal(traits); err == nil {
+			extensions = append(extensions,
+				[2]string{"cs1Label", "traits"},
+				[2]string{"cs1", string(data)},
+			)
+		}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "CEF:0|Supabase|Auth|%s|%s|%s|%d|",
+		cefHeaderEscaper.Replace(version),
+		cefHeaderEscaper.Replace(action),
+		cefHeaderEscaper.Replace(action),
+		severity,
+	)
+
+	first := true
+	for _, ext := range extensions {
+		if ext[1] == "" {
+			continue
+		}
+		if !first {
+			b.WriteByte(' ')
+		}
+		first = false
+		b.WriteString(ext[0])
+		b.WriteByte('=')
+		b.WriteString(cefExtensionEscaper.Replace(ext[1]))
+	}
+
+	return b.String()
+}
+
+var (
+	cefHeaderEscaper    = strings.NewReplacer(`\`, `\\`, `|`, `\|`, "\n", " ", "\r", " ")
+	cefExtensionEscaper = strings.NewReplacer(`\`, `\\`, `=`, `\=`, "\n", `\n`, "\r", `\r`)
+)
+
+// payloadString returns the value of the key as a string, or "" when it is
+// not set.
+func payloadString(payload map[string]interface{}, key string) string {
+	value, ok := payload[key]
+	if !ok || value == nil {
+		return ""
+	}
+	if s, ok := value.(string); ok {
+		return s
+	}
+	return fmt.Sprint(value)
+}
@@ -0,0 +1,101 @@
+package auditsink
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/require"
+	"github.com/supabase/auth/internal/conf"
+)
+
+func TestFormatCEF(t *testing.T) {
+	id := uuid.Must(uuid.FromString("6f2d4a55-8b8c-4a43-9d8e-0b6f8f1c2e7a"))
+	userID := uuid.Must(uuid.FromString("3b1c0c4e-1d1f-4c55-9f1c-5e0e7f3a6b2d"))
+
+	e := &Event{
+		ID:        id,
+		CreatedAt: time.Date(2026, 3, 25, 12, 0, 0, 0, time.UTC),
+		IPAddress: "203.0.113.7",
+		Payload: map[string]interface{}{
+			"actor_id":       "8e1d4b6a-5f0e-4b8e-8b43-1c2d3e4f5a6b",
+			"actor_username": "admin=root@example.com",
+			"action":         "user_deleted",
+			"log_type":       "team",
+			"traits": map[string]interface{}{
+				"user_id":    userID,
+				"user_email": "user@example.com",
+			},
+		},
+	}
+
+	require.Equal(t,
+		`CEF:0|Supabase|Auth|unknown|user_deleted|user_deleted|3|rt=1774440000000 externalId=6f2d4a55-8b8c-4a43-9d8e-0b6f8f1c2e7a act=user_deleted cat=team src=203.0.113.7 suid=8e1d4b6a-5f0e-4b8e-8b43-1c2d3e4f5a6b suser=admin\=root@example.com duid=3b1c0c4e-1d1f-4c55-9f1c-5e0e7f3a6b2d duser=user@example.com cs1Label=traits cs1={"user_email":"user@example.com","user_id":"3b1c0c4e-1d1f-4c55-9f1c-5e0e7f3a6b2d"}`,
+		FormatCEF(e))
+
+	e.Payload = map[string]interface{}{"action": "token_reuse_detected|x"}
+	e.IPAddress = ""
+	require.Equal(t,
+		`CEF:0|Supabase|Auth|unknown|token_reuse_detected\|x|token_reuse_detected\|x|3|rt=1774440000000 externalId=6f2d4a55-8b8c-4a43-9d8e-0b6f8f1c2e7a act=token_reuse_detected|x`,
+		FormatCEF(e))
+}
+
+func TestSyslogSinkTCP(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+
+	received := make(chan string, 2)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		r := bufio.NewReader(conn)
+		for {
+			length, err := r.ReadString(' ')
+			if err != nil {
+				return
+			}
+			n, _ := strconv.Atoi(strings.TrimSpace(length))
+			msg := make([]byte, n)
+			if _, err := io.ReadFull(r, msg); err != nil {
+				return
+			}
+			received <- string(msg)
+		}
+	}()
+
+	sink := NewSyslogSink(&conf.AuditLogSyslogConfiguration{
+		Enabled:  true,
+		Network:  "tcp",
+		Address:  l.Addr().String(),
+		Facility: 10,
+		Hostname: "auth-1",
+		AppName:  "gotrue",
+	})
+	defer sink.Close()
+
+	createdAt := time.Date(2026, 3, 25, 12, 0, 0, 0, time.UTC)
+	require.NoError(t, sink.Send(context.Background(), []*Event{
+		{ID: uuid.Must(uuid.NewV4()), CreatedAt: createdAt, Payload: map[string]interface{}{"action": "login"}},
+		{ID: uuid.Must(uuid.NewV4()), CreatedAt: createdAt, Payload: map[string]interface{}{"action": "token_reuse_detected"}},
+	}))
+
+	msg := <-received
+	require.True(t, strings.HasPrefix(msg, "<86>1 2026-03-25T12:00:00Z auth-1 gotrue - audit - CEF:0|Supabase|Auth|"), msg)
+	require.True(t, strings.HasSuffix(msg, "act=login\n"), msg)
+
+	// security relevant actions are warnings
+	msg = <-received
+	require.True(t, strings.HasPrefix(msg, "<84>1 "), msg)
+	require.Contains(t, msg, "|token_reuse_detected|token_reuse_detected|7|")
+}
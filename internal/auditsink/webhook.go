@@ -0,0 +1,94 @@
+package auditsink
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gofrs/uuid"
+	standardwebhooks "github.com/standard-webhooks/standard-webhooks/libraries/go"
+	"github.com/supabase/auth/internal/conf"
+	"github.com/supabase/auth/internal/utilities"
+)
+
+// WebhookSink posts batches of events to a URL as JSON, signed as Standard
+// Webhooks so the receiver can verify they were sent by Auth.
+type WebhookSink struct {
+	config   *conf.AuditLogWebhookConfiguration
+	client   *http.Client
+	webhooks []*standardwebhooks.Webhook
+	signer   utilities.Signer
+}
+
+// NewWebhookSink returns a WebhookSink based on the given configuration.
+func NewWebhookSink(config *conf.AuditLogWebhookConfiguration) (*WebhookSink, error) {
+	s := &WebhookSink{
+		config: config,
+		client: &http.Client{Timeout: config.Timeout},
+	}
+
+	for _, secret := range config.Secrets {
+		wh, err := standardwebhooks.NewWebhook(strings.TrimPrefix(secret, "v1,"))
+		if err != nil {
+			return nil, fmt.Errorf("auditsink: invalid webhook secret: %w", err)
+		}
+		s.webhooks = append(s.webhooks, wh)
+	}
+
+	return s, nil
+}
+
+type webhookPayload struct {
+	Type    string   `json:"type"`
+	Entries []*Event `json:"entries"`
+}
+
+// Send implements Sink by posting the events in a single request.
+func (s *WebhookSink) Send(ctx context.Context, events []*Event) error {
+	body, err := json.Marshal(&webhookPayload{
+		Type:    "audit_log",
+		Entries: events,
+	})
+	if err != nil {
+		return fmt.Errorf("auditsink: error encoding webhook payload: %w", err)
+	}
+
+	// The ID of the message is the ID of its first event, so a batch that
+	// is sent again can be recognized by the receiver.
+	msgID := events[0].ID
+	if msgID == uuid.Nil {
+		msgID = uuid.Must(uuid.NewV4())
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.config.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("auditsink: error creating webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if err := s.signer.SignWebhook(req, msgID.String(), body, s.webhooks); err != nil {
+		return fmt.Errorf("auditsink: error signing webhook payload: %w", err)
+	}
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("auditsink: error sending webhook: %w", err)
+	}
+	defer utilities.SafeClose(res.Body)
+
+	if res.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+		return fmt.Errorf("auditsink: webhook failed with status %d: %s", res.StatusCode, strings.TrimSpace(string(msg)))
+	}
+
+	return nil
+}
+
+// Close implements Sink.
+func (s *WebhookSink) Close() error {
+	s.client.CloseIdleConnections()
+	return nil
+}
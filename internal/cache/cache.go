@@ -0,0 +1,172 @@
+// Package cache keeps copies of the rows read on hot paths, such as the user
+// and session of every authenticated request, in the memory of the process or
+// in Redis. Values are gob encoded, so that the fields not serialized to
+// JSON are kept. Errors of the store are logged and treated as misses, so an
+// unavailable store only sends the reads back to the database.
+package cache
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/supabase/auth/internal/conf"
+	"github.com/supabase/auth/internal/observability"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+var (
+	hitsCounter   = observability.ObtainMetricCounter("gotrue_cache_hits", "Number of cache lookups which found an entry by namespace")
+	missesCounter = observability.ObtainMetricCounter("gotrue_cache_misses", "Number of cache lookups which found no entry by namespace")
+)
+
+func init() {
+	// the types of the values of the JSON columns, such as user metadata
+	gob.Register(map[string]interface{}{})
+	gob.Register([]interface{}{})
+}
+
+// Store keeps values for arbitrary keys until they expire.
+//
+// Implementations of Store must be safe for concurrent use.
+type Store interface {
+	// Get returns the value of the key and true, or false if the key has
+	// no value or it expired.
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+
+	// Set sets the value of the key, which expires after ttl.
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+
+	// Delete removes the values of the keys.
+	Delete(ctx context.Context, keys ...string) error
+}
+
+// NewStore returns a new Store based on the given config.
+//
+// When the type is conf.CacheRedis it returns a RedisStore, otherwise
+// NewStore returns a MemoryStore.
+func NewStore(c *conf.CacheConfiguration) (Store, error) {
+	switch c.Type {
+	case conf.CacheRedis:
+		return NewRedisStore(c.RedisURL, c.RedisKeyPrefix)
+	default:
+		return NewMemoryStore(c.MaxEntries), nil
+	}
+}
+
+var (
+	mu     sync.RWMutex
+	config conf.CacheConfiguration // Guarded by mu.
+	store  Store                   // Guarded by mu.
+	le     = logrus.WithField("component", "cache")
+)
+
+// Configure starts caching in the store of the configuration, or stops
+// caching when it is disabled. The store of a previous configuration is kept
+// when the configuration did not change.
+func Configure(c *conf.CacheConfiguration) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if !c.Enabled {
+		config, store = *c, nil
+		return nil
+	}
+	if store != nil && config == *c {
+		return nil
+	}
+
+	s, err := NewStore(c)
+	if err != nil {
+		return err
+	}
+	config, store = *c, s
+	return nil
+}
+
+// Enabled returns true when a store is configured.
+func Enabled() bool {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	return store != nil
+}
+
+func current() (Store, time.Duration) {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	return store, config.TTL
+}
+
+func key(namespace, id string) string {
+	return namespace + ":" + id
+}
+
+// Get decodes the cached value of the id in the namespace into v and returns
+// true, or returns false when caching is disabled or there is no value.
+func Get(ctx context.Context, namespace, id string, v interface{}) bool {
+	s, _ := current()
+	if s == nil {
+		return false
+	}
+
+	attrs := metric.WithAttributes(attribute.String("namespace", namespace))
+
+	value, ok, err := s.Get(ctx, key(namespace, id))
+	if err == nil && ok {
+		err = gob.NewDecoder(bytes.NewReader(value)).Decode(v)
+	}
+	if err != nil {
+		le.WithError(err).WithField("namespace", namespace).Warn("cache: unable to get value")
+		ok = false
+	}
+
+	if ok {
+		hitsCounter.Add(ctx, 1, attrs)
+	} else {
+		missesCounter.Add(ctx, 1, attrs)
+	}
+	return ok
+}
+
+// Set caches v as the value of the id in the namespace when caching is
+// enabled.
+func Set(ctx context.Context, namespace, id string, v interface{}) {
+	s, ttl := current()
+	if s == nil {
+		return
+	}
+
+	var buf bytes.Buffer
+	err := gob.NewEncoder(&buf).Encode(v)
+	if err == nil {
+		err = s.Set(ctx, key(namespace, id), buf.Bytes(), ttl)
+	}
+	if err != nil {
+		le.WithError(err).WithField("namespace", namespace).Warn("cache: unable to set value")
+	}
+}
+
+// Delete removes the cached values of the ids in the namespace when caching
+// is enabled. Replicas may use a value whose removal failed until it
+// expires.
+func Delete(ctx context.Context, namespace string, ids ...string) {
+	s, _ := current()
+	if s == nil || len(ids) == 0 {
+		return
+	}
+
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = key(namespace, id)
+	}
+
+	if err := s.Delete(ctx, keys...); err != nil {
+		le.WithError(err).WithField("namespace", namespace).Error("cache: unable to delete values, they are used until they expire")
+	}
+}
@@ -0,0 +1,116 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/require"
+	"github.com/supabase/auth/internal/conf"
+)
+
+func TestMemoryStore(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+
+	s := NewMemoryStore(2)
+	s.now = func() time.Time { return now }
+
+	require.NoError(t, s.Set(ctx, "a", []byte("1"), time.Minute))
+	require.NoError(t, s.Set(ctx, "b", []byte("2"), time.Minute))
+
+	value, ok, err := s.Get(ctx, "a")
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, []byte("1"), value)
+
+	// b is the least recently used value
+	require.NoError(t, s.Set(ctx, "c", []byte("3"), time.Minute))
+	require.Equal(t, 2, s.Len())
+
+	_, ok, err = s.Get(ctx, "b")
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	require.NoError(t, s.Delete(ctx, "a", "unknown"))
+	_, ok, err = s.Get(ctx, "a")
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	now = now.Add(time.Minute)
+	_, ok, err = s.Get(ctx, "c")
+	require.NoError(t, err)
+	require.False(t, ok)
+	require.Equal(t, 0, s.Len())
+}
+
+func TestRedisStore(t *testing.T) {
+	mr := miniredis.RunT(t)
+
+	s, err := NewRedisStore("redis://"+mr.Addr(), "gotrue:cache:")
+	require.NoError(t, err)
+
+	ctx := context.Background()
+
+	_, ok, err := s.Get(ctx, "user:1")
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	require.NoError(t, s.Set(ctx, "user:1", []byte("\x00value\r\n"), 30*time.Second))
+	require.Equal(t, 30*time.Second, mr.TTL("gotrue:cache:user:1"))
+
+	value, ok, err := s.Get(ctx, "user:1")
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, []byte("\x00value\r\n"), value)
+
+	require.NoError(t, s.Set(ctx, "user:2", []byte("2"), time.Minute))
+	require.NoError(t, s.Delete(ctx, "user:1", "user:2", "user:3"))
+	require.Empty(t, mr.Keys())
+
+	require.NoError(t, s.Delete(ctx))
+}
+
+func TestConfigure(t *testing.T) {
+	ctx := context.Background()
+	defer Configure(&conf.CacheConfiguration{})
+
+	type value struct {
+		ID       string
+		Metadata map[string]interface{}
+		Secret   string `json:"-"`
+	}
+
+	require.NoError(t, Configure(&conf.CacheConfiguration{}))
+	require.False(t, Enabled())
+	Set(ctx, "test", "1", &value{ID: "1"})
+	require.False(t, Get(ctx, "test", "1", &value{}))
+
+	config := &conf.CacheConfiguration{
+		Enabled:    true,
+		Type:       conf.CacheMemory,
+		MaxEntries: 10,
+		TTL:        time.Minute,
+	}
+	require.NoError(t, Configure(config))
+	require.True(t, Enabled())
+
+	expected := &value{
+		ID:       "1",
+		Metadata: map[string]interface{}{"roles": []interface{}{"admin"}, "age": 42.0},
+		Secret:   "secret",
+	}
+	Set(ctx, "test", "1", expected)
+
+	var actual value
+	require.True(t, Get(ctx, "test", "1", &actual))
+	require.Equal(t, expected, &actual)
+
+	// the store is kept when the configuration did not change
+	require.NoError(t, Configure(config))
+	require.True(t, Get(ctx, "test", "1", &actual))
+
+	Delete(ctx, "test", "1")
+	require.False(t, Get(ctx, "test", "1", &actual))
+}
@@ -0,0 +1,110 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+type memoryEntry struct {
+	key       string
+	value     []byte
+	expiresAt time.Time
+}
+
+// MemoryStore is a Store that keeps up to a maximum number of values in the
+// memory of the current process, removing the least recently used values
+// first.
+type MemoryStore struct {
+	maxEntries int
+	now        func() time.Time
+
+	mu sync.Mutex
+
+	// Guarded by mu.
+	entries map[string]*list.Element
+	lru     *list.List // Most recently used first.
+}
+
+// NewMemoryStore returns an empty MemoryStore keeping up to maxEntries
+// values.
+func NewMemoryStore(maxEntries int) *MemoryStore {
+	return &MemoryStore{
+		maxEntries: maxEntries,
+		now:        time.Now,
+		entries:    make(map[string]*list.Element),
+		lru:        list.New(),
+	}
+}
+
+// Get implements Store.
+func (s *MemoryStore) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	el, ok := s.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+
+	e := el.Value.(*memoryEntry)
+	if !s.now().Before(e.expiresAt) {
+		s.remove(el)
+		return nil, false, nil
+	}
+
+	s.lru.MoveToFront(el)
+	return e.value, true, nil
+}
+
+// Set implements Store.
+func (s *MemoryStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	e := &memoryEntry{
+		key:       key,
+		value:     append([]byte(nil), value...),
+		expiresAt: s.now().Add(ttl),
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if el, ok := s.entries[key]; ok {
+		el.Value = e
+		s.lru.MoveToFront(el)
+		return nil
+	}
+
+	s.entries[key] = s.lru.PushFront(e)
+	for s.lru.Len() > s.maxEntries {
+		s.remove(s.lru.Back())
+	}
+	return nil
+}
+
+// Delete implements Store.
+func (s *MemoryStore) Delete(ctx context.Context, keys ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, key := range keys {
+		if el, ok := s.entries[key]; ok {
+			s.remove(el)
+		}
+	}
+	return nil
+}
+
+// Len returns the number of values kept, including expired values which
+// were not removed yet.
+func (s *MemoryStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.lru.Len()
+}
+
+func (s *MemoryStore) remove(el *list.Element) {
+	s.lru.Remove(el)
+	delete(s.entries, el.Value.(*memoryEntry).key)
+}
@@ -0,0 +1,63 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/supabase/auth/internal/redisclient"
+)
+
+// RedisStore is a Store that keeps the values in Redis, so that they are
+// shared by every replica using the same Redis server.
+type RedisStore struct {
+	Prefix string
+
+	client *redis.Client
+}
+
+// NewRedisStore returns a RedisStore for a URL accepted by redisclient.New.
+func NewRedisStore(rawURL, prefix string) (*RedisStore, error) {
+	client, err := redisclient.New(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	return &RedisStore{Prefix: prefix, client: client}, nil
+}
+
+// Get implements Store.
+func (s *RedisStore) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	value, err := s.client.Get(ctx, s.Prefix+key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("redis: %w", err)
+	}
+	return value, true, nil
+}
+
+// Set implements Store.
+func (s *RedisStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	if err := s.client.Set(ctx, s.Prefix+key, value, max(time.Millisecond, ttl)).Err(); err != nil {
+		return fmt.Errorf("redis: %w", err)
+	}
+	return nil
+}
+
+// Delete implements Store.
+func (s *RedisStore) Delete(ctx context.Context, keys ...string) error {
+	// the keys are deleted one by one, as keys in different slots of a Redis
+	// cluster can't be deleted by the same command
+	pipe := s.client.Pipeline()
+	for _, key := range keys {
+		pipe.Del(ctx, s.Prefix+key)
+	}
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("redis: %w", err)
+	}
+	return nil
+}
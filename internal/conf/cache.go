@@ -0,0 +1,52 @@
+package conf
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	CacheMemory = "memory"
+	CacheRedis  = "redis"
+)
+
+// CacheConfiguration configures caching the users, sessions and JWKS read
+// on every authenticated request. Entries are invalidated when the rows
+// change and expire after TTL, which bounds how long a replica may use a
+// stale entry. The in-memory cache is not shared between replicas, use the
+// redis cache when more than one replica serves requests.
+type CacheConfiguration struct {
+	Enabled bool `json:"enabled"`
+
+	Type           string `json:"type" default:"memory"`
+	RedisURL       string `json:"-" split_words:"true"`
+	RedisKeyPrefix string `json:"redis_key_prefix" split_words:"true" default:"gotrue:cache:"`
+
+	MaxEntries int           `json:"max_entries" split_words:"true" default:"10000"`
+	TTL        time.Duration `json:"ttl" default:"30s"`
+}
+
+func (c *CacheConfiguration) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+
+	if c.TTL <= 0 {
+		return errors.New("conf: cache TTL must be positive")
+	}
+
+	switch c.Type {
+	case CacheMemory:
+		if c.MaxEntries < 1 {
+			return errors.New("conf: cache max entries must be positive")
+		}
+		return nil
+
+	case CacheRedis:
+		return validateRedisURL("cache", c.RedisURL)
+
+	default:
+		return fmt.Errorf("conf: cache type %q is not supported, use memory or redis", c.Type)
+	}
+}
@@ -0,0 +1,42 @@
+package conf
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCacheConfigurationValidate(t *testing.T) {
+	valid := CacheConfiguration{
+		Enabled:    true,
+		Type:       CacheMemory,
+		MaxEntries: 10000,
+		TTL:        30 * time.Second,
+	}
+	require.NoError(t, valid.Validate())
+
+	require.NoError(t, (&CacheConfiguration{}).Validate())
+
+	c := valid
+	c.TTL = 0
+	require.Error(t, c.Validate())
+
+	c = valid
+	c.MaxEntries = 0
+	require.Error(t, c.Validate())
+
+	c = valid
+	c.Type = "memcached"
+	require.EqualError(t, c.Validate(), `conf: cache type "memcached" is not supported, use memory or redis`)
+
+	c = valid
+	c.Type = CacheRedis
+	require.EqualError(t, c.Validate(), "conf: cache redis URL is required when the redis store is used")
+
+	c.RedisURL = "http://localhost:6379"
+	require.Error(t, c.Validate())
+
+	c.RedisURL = "redis://localhost:6379/0"
+	require.NoError(t, c.Validate())
+}
@@ -9,13 +9,16 @@ import (
 	"net/url"
 	"os"
 	"path/filepath"
+	"reflect"
 	"regexp"
+	"slices"
 	"strings"
 	"text/template"
 	"time"
 
 	"github.com/gobwas/glob"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/jmespath/go-jmespath"
 	"github.com/joho/godotenv"
 	"github.com/kelseyhightower/envconfig"
 	"github.com/lestrrat-go/jwx/v2/jwk"
@@ -73,6 +76,113 @@ type OAuthProviderConfiguration struct {
 	// SkipNonceCheck bypasses nonce verification during OIDC token validation.
 	// Note: Nonce verification helps prevent replay attacks; only disable when necessary.
 	SkipNonceCheck bool `json:"skip_nonce_check" split_words:"true"`
+
+	// AllowedEmailDomains are the email domains users may sign up with
+	// through the provider, any domain when empty. DeniedEmailDomains are
+	// the domains users may not sign up with. A *. prefix matches the
+	// subdomains of a domain. Users that already have an account can sign
+	// in regardless.
+	AllowedEmailDomains []string `json:"allowed_email_domains,omitempty" split_words:"true"`
+	DeniedEmailDomains  []string `json:"denied_email_domains,omitempty" split_words:"true"`
+}
+
+// AzureProviderConfiguration holds the config of the Azure (Microsoft Entra
+// ID) provider.
+type AzureProviderConfiguration struct {
+	OAuthProviderConfiguration
+
+	// AllowedTenants is a list of tenant IDs whose users may sign in,
+	// checked against the tid claim of the ID token. Any tenant is
+	// allowed when empty.
+	AllowedTenants []string `json:"allowed_tenants" split_words:"true"`
+
+	// GroupsInAppMetadata stores the group memberships in the groups claim
+	// of the ID token in the azure_groups key of the user's app_metadata.
+	GroupsInAppMetadata bool `json:"groups_in_app_metadata" split_words:"true"`
+}
+
+// KeycloakProviderConfiguration holds the config of the Keycloak provider.
+type KeycloakProviderConfiguration struct {
+	OAuthProviderConfiguration
+
+	// Realm is the realm users sign in to when URL is the base URL of the
+	// Keycloak server. Without it URL is the URL of the realm.
+	Realm string `json:"realm"`
+
+	// RolesInAppMetadata stores the roles of the user in the roles key of
+	// the user's app_metadata, read from the access token after verifying
+	// it against the keys of the realm.
+	RolesInAppMetadata bool `json:"roles_in_app_metadata" split_words:"true"`
+
+	// RolesExpression is a JMESPath expression evaluated against the claims
+	// of the access token that returns the roles of the user. It defaults
+	// to the realm roles and the roles of the client.
+	RolesExpression string `json:"roles_expression" split_words:"true"`
+}
+
+func (c *KeycloakProviderConfiguration) Validate() error {
+	if c.RolesExpression == "" {
+		return nil
+	}
+
+	if _, err := jmespath.Compile(c.RolesExpression); err != nil {
+		return fmt.Errorf("conf: invalid keycloak roles expression: %w", err)
+	}
+
+	return nil
+}
+
+// RealmURL returns the URL of the realm, which is the issuer of its tokens.
+func (c KeycloakProviderConfiguration) RealmURL() string {
+	realmURL := strings.TrimSuffix(c.URL, "/")
+	if c.Realm != "" {
+		realmURL += "/realms/" + url.PathEscape(c.Realm)
+	}
+	return realmURL
+}
+
+// CustomProviderConfiguration holds the config of the custom provider, which
+// delegates the authorization URL, the code exchange and the user data to
+// the operator's endpoint at URL.
+type CustomProviderConfiguration struct {
+	OAuthProviderConfiguration
+
+	// Secrets are the v1,whsec_ secrets the requests to the endpoint are
+	// signed with as Standard Webhooks, separated by |.
+	Secrets HTTPHookSecrets `json:"secrets" envconfig:"secrets"`
+}
+
+func (c *CustomProviderConfiguration) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+
+	u, err := url.ParseRequestURI(c.URL)
+	if err != nil || u.Host == "" {
+		return errors.New("conf: custom provider url must be a valid HTTP URL")
+	}
+	switch u.Scheme {
+	case "https":
+	case "http":
+		hostname := u.Hostname()
+		if hostname != "localhost" && hostname != "127.0.0.1" && hostname != "::1" && hostname != "host.docker.internal" {
+			return errors.New("conf: custom provider url must use https except for localhost")
+		}
+	default:
+		return errors.New("conf: custom provider url must be a valid HTTP URL")
+	}
+
+	if len(c.Secrets) == 0 {
+		return errors.New("conf: missing custom provider secret")
+	}
+
+	for _, secret := range c.Secrets {
+		if !symmetricSecretFormat.MatchString(secret) {
+			return errors.New("conf: custom provider secrets must be symmetric v1,whsec_ secrets")
+		}
+	}
+
+	return nil
 }
 
 // GenericOAuthProviderConfiguration holds all config related to generic OAuth providers.
@@ -85,6 +195,39 @@ type GenericOAuthProviderConfiguration struct {
 	TokenURL        string            `json:"token_url" split_words:"true"`
 	ProfileURL      string            `json:"profile_url" split_words:"true"`
 	UserDataMapping map[string]string `json:"user_data_mapping" split_words:"true"`
+
+	// UserDataExpressions is a JSON object of claim names (as used in
+	// UserDataMapping) to JMESPath expressions evaluated against the
+	// provider's user data. Expressions take precedence over UserDataMapping.
+	UserDataExpressions string `json:"user_data_expressions" split_words:"true"`
+
+	userDataExpressions map[string]string `json:"-"`
+}
+
+func (c *GenericOAuthProviderConfiguration) Validate() error {
+	if c.UserDataExpressions == "" {
+		c.userDataExpressions = nil
+		return nil
+	}
+
+	expressions := make(map[string]string)
+	if err := json.Unmarshal([]byte(c.UserDataExpressions), &expressions); err != nil {
+		return fmt.Errorf("conf: user data expressions not a map[string]string format: %w", err)
+	}
+
+	for field, expression := range expressions {
+		if _, err := jmespath.Compile(expression); err != nil {
+			return fmt.Errorf("conf: invalid user data expression for %q: %w", field, err)
+		}
+	}
+
+	c.userDataExpressions = expressions
+	return nil
+}
+
+// GetUserDataExpressions returns the parsed UserDataExpressions.
+func (c *GenericOAuthProviderConfiguration) GetUserDataExpressions() map[string]string {
+	return c.userDataExpressions
 }
 
 // OAuthServerConfiguration holds OAuth server configuration
@@ -95,6 +238,107 @@ type OAuthServerConfiguration struct {
 	AuthorizationTTL         time.Duration `json:"authorization_ttl" split_words:"true" default:"10m"`
 	// Placeholder for now, for (near) future extensibility
 	DefaultScope string `json:"default_scope" split_words:"true" default:"email"`
+
+	// ClientCredentialsRole is the role claim of the service tokens issued
+	// with the client_credentials grant, which carry no user.
+	ClientCredentialsRole string `json:"client_credentials_role" split_words:"true" default:"service"`
+}
+
+// SCIMConfiguration holds the configuration of the SCIM 2.0 provisioning API.
+type SCIMConfiguration struct {
+	Enabled bool `json:"enabled" default:"false"`
+
+	// Tokens are the bearer tokens SCIM clients authenticate with. Multiple
+	// tokens can be configured to allow rotating them without downtime.
+	Tokens []string `json:"-"`
+}
+
+// OrganizationsConfiguration configures organizations, which group users
+// with a role in each organization they are a member of. The roles are
+// added to the access tokens as the orgs claim.
+type OrganizationsConfiguration struct {
+	Enabled bool `json:"enabled" default:"false"`
+
+	// SelfServiceCreate lets users create organizations, which they become
+	// the owner of. Otherwise organizations are created with the admin API.
+	SelfServiceCreate bool `json:"self_service_create" split_words:"true" default:"true"`
+}
+
+// ImpersonationConfiguration configures the impersonation of users by
+// admins, who get an access token of a short-lived session of the user that
+// identifies the admin as the act claim.
+type ImpersonationConfiguration struct {
+	Enabled bool `json:"enabled" default:"false"`
+
+	// Duration is how long an impersonated session lasts. Its access token
+	// expires with it and can't be refreshed.
+	Duration time.Duration `json:"duration" default:"15m"`
+}
+
+func (c *ImpersonationConfiguration) Validate() error {
+	if c.Enabled && c.Duration <= 0 {
+		return errors.New("conf: GOTRUE_IMPERSONATION_DURATION must be positive")
+	}
+	return nil
+}
+
+// RBACConfiguration configures role based access control. Roles granting
+// permissions are managed and assigned to users with the admin API, and are
+// added to the access tokens as the roles and permissions claims.
+type RBACConfiguration struct {
+	Enabled bool `json:"enabled" default:"false"`
+}
+
+func (c *SCIMConfiguration) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+
+	if len(c.Tokens) == 0 {
+		return errors.New("conf: SCIM is enabled but no SCIM bearer tokens are configured")
+	}
+
+	for _, token := range c.Tokens {
+		if len(token) < 32 {
+			return errors.New("conf: SCIM bearer tokens must be at least 32 characters long")
+		}
+	}
+
+	return nil
+}
+
+// GRPCConfiguration holds the configuration of the gRPC admin service.
+type GRPCConfiguration struct {
+	Enabled bool   `json:"enabled" default:"false"`
+	Host    string `json:"host"`
+	Port    string `json:"port" default:"50051"`
+
+	// TLSCertFile and TLSKeyFile are the server's certificate and key. The
+	// service is served without TLS when they are not set, which should
+	// only be done behind a proxy terminating TLS.
+	TLSCertFile string `json:"tls_cert_file" split_words:"true"`
+	TLSKeyFile  string `json:"tls_key_file" split_words:"true"`
+
+	// TLSClientCAFile is the bundle of CA certificates client certificates
+	// are verified with. When set clients must present a certificate signed
+	// by one of them (mTLS).
+	TLSClientCAFile string `json:"tls_client_ca_file" split_words:"true"`
+}
+
+func (c *GRPCConfiguration) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+
+	if (c.TLSCertFile == "") != (c.TLSKeyFile == "") {
+		return errors.New("conf: both gRPC TLS certificate and key files must be set")
+	}
+
+	if c.TLSClientCAFile != "" && c.TLSCertFile == "" {
+		return errors.New("conf: gRPC client certificate verification requires a TLS certificate and key")
+	}
+
+	return nil
 }
 
 type AnonymousProviderConfiguration struct {
@@ -131,14 +375,48 @@ type DBConfiguration struct {
 	ConnMaxLifetime   time.Duration `json:"conn_max_lifetime,omitempty" split_words:"true"`
 	ConnMaxIdleTime   time.Duration `json:"conn_max_idle_time,omitempty" split_words:"true"`
 	HealthCheckPeriod time.Duration `json:"health_check_period" split_words:"true"`
-	MigrationsPath    string        `json:"migrations_path" split_words:"true" default:"./migrations"`
-	CleanupEnabled    bool          `json:"cleanup_enabled" split_words:"true" default:"false"`
+
+	// StatementCacheMode is "prepare" to cache prepared statements, or
+	// "describe" to only cache their descriptions. StatementCacheCapacity
+	// is the number of cached statements per connection, where 0 keeps
+	// the default of the driver and a negative number disables the cache.
+	StatementCacheMode     string `json:"statement_cache_mode" split_words:"true"`
+	StatementCacheCapacity int    `json:"statement_cache_capacity" split_words:"true"`
+
+	// PgbouncerMode uses no prepared statements, for pgbouncer and other
+	// poolers in transaction pooling mode.
+	PgbouncerMode bool `json:"pgbouncer_mode" split_words:"true"`
+
+	MigrationsPath string `json:"migrations_path" split_words:"true" default:"./migrations"`
+	CleanupEnabled bool   `json:"cleanup_enabled" split_words:"true" default:"false"`
+
+	// ReplicaURL is the connection string of a read-only replica, which
+	// serves lookups of users and sessions while its lag is within
+	// ReplicaMaxLag.
+	ReplicaURL           string        `json:"-" split_words:"true"`
+	ReplicaMaxLag        time.Duration `json:"replica_max_lag" split_words:"true" default:"5s"`
+	ReplicaCheckInterval time.Duration `json:"replica_check_interval" split_words:"true" default:"1s"`
 
 	Advisor DBAdvisorConfiguration `json:"advisor"`
 }
 
 func (c *DBConfiguration) Validate() error {
 	c.ConnPercentage = min(max(c.ConnPercentage, 0), 100)
+
+	switch c.StatementCacheMode {
+	case "", "prepare", "describe":
+	default:
+		return fmt.Errorf("conf: db statement cache mode must be prepare or describe, not %q", c.StatementCacheMode)
+	}
+
+	if c.ReplicaURL != "" {
+		if c.ReplicaMaxLag < 0 {
+			return errors.New("conf: db replica max lag must not be negative")
+		}
+		if c.ReplicaCheckInterval <= 0 {
+			return errors.New("conf: db replica check interval must be positive")
+		}
+	}
 	return nil
 }
 
@@ -154,6 +432,50 @@ type JWTConfiguration struct {
 	KeyID            string         `json:"key_id" split_words:"true"`
 	Keys             JwtKeysDecoder `json:"keys"`
 	ValidMethods     []string       `json:"-"`
+
+	// DenylistEnabled adds a jti to the access tokens, so that they can be
+	// revoked before they expire through the token revocation endpoint.
+	DenylistEnabled bool `json:"denylist_enabled" split_words:"true"`
+
+	// ExcludeClaims lists the claims left out of access tokens, while
+	// AppMetadataClaim and UserMetadataClaim rename the claims holding the
+	// metadata of the user.
+	ExcludeClaims     []string `json:"exclude_claims" split_words:"true"`
+	AppMetadataClaim  string   `json:"app_metadata_claim" split_words:"true"`
+	UserMetadataClaim string   `json:"user_metadata_claim" split_words:"true"`
+
+	// ClientAudiences and ClientIssuers set the audience added to the
+	// access tokens of OAuth clients and their issuer, by client ID.
+	ClientAudiences map[string]string `json:"client_audiences" split_words:"true"`
+	ClientIssuers   map[string]string `json:"client_issuers" split_words:"true"`
+}
+
+// ExcludableClaims are the claims of access tokens that can be left out.
+// The others are needed to verify the tokens and by Auth itself.
+var ExcludableClaims = []string{"email", "phone", "app_metadata", "user_metadata", "amr", "is_anonymous"}
+
+// reservedClaims are the claims of access tokens the metadata claims
+// cannot be renamed to.
+var reservedClaims = []string{"iss", "sub", "aud", "exp", "nbf", "iat", "jti", "email", "phone", "app_metadata", "user_metadata", "role", "aal", "amr", "session_id", "is_anonymous", "client_id", "scope", "orgs", "roles", "permissions", "act"}
+
+func (c *JWTConfiguration) Validate() error {
+	for _, claim := range c.ExcludeClaims {
+		if !slices.Contains(ExcludableClaims, claim) {
+			return fmt.Errorf("conf: JWT claim %q cannot be excluded, only %s", claim, strings.Join(ExcludableClaims, ", "))
+		}
+	}
+
+	for _, claim := range []string{c.AppMetadataClaim, c.UserMetadataClaim} {
+		if slices.Contains(reservedClaims, claim) {
+			return fmt.Errorf("conf: JWT metadata claim cannot be renamed to the reserved claim %q", claim)
+		}
+	}
+
+	if c.AppMetadataClaim != "" && c.AppMetadataClaim == c.UserMetadataClaim {
+		return errors.New("conf: JWT app and user metadata claims must be different")
+	}
+
+	return nil
 }
 
 type MFAFactorTypeConfiguration struct {
@@ -166,6 +488,45 @@ type TOTPFactorTypeConfiguration struct {
 	VerifyEnabled bool `json:"verify_enabled" split_words:"true" default:"true"`
 }
 
+// WebAuthnFactorTypeConfiguration holds the relying party settings used for
+// WebAuthn registration and assertion ceremonies. When RPID is set the
+// server-side values take precedence and client supplied values must match.
+type WebAuthnFactorTypeConfiguration struct {
+	MFAFactorTypeConfiguration
+	RPID                  string   `json:"rp_id" envconfig:"RP_ID"`
+	RPDisplayName         string   `json:"rp_display_name" envconfig:"RP_DISPLAY_NAME"`
+	RPOrigins             []string `json:"rp_origins" envconfig:"RP_ORIGINS"`
+	AttestationPreference string   `json:"attestation_preference" split_words:"true" default:"none"`
+	UserVerification      string   `json:"user_verification" split_words:"true" default:"preferred"`
+}
+
+func (c *WebAuthnFactorTypeConfiguration) Validate() error {
+	switch c.AttestationPreference {
+	case "", "none", "indirect", "direct", "enterprise":
+	default:
+		return fmt.Errorf("conf: unsupported WebAuthn attestation preference %q", c.AttestationPreference)
+	}
+
+	switch c.UserVerification {
+	case "", "required", "preferred", "discouraged":
+	default:
+		return fmt.Errorf("conf: unsupported WebAuthn user verification requirement %q", c.UserVerification)
+	}
+
+	if c.RPID != "" && len(c.RPOrigins) == 0 {
+		return errors.New("conf: WebAuthn RP origins must be set when RP ID is configured")
+	}
+
+	for _, origin := range c.RPOrigins {
+		u, err := url.Parse(origin)
+		if err != nil || u.Host == "" {
+			return fmt.Errorf("conf: invalid WebAuthn RP origin %q", origin)
+		}
+	}
+
+	return nil
+}
+
 type PhoneFactorTypeConfiguration struct {
 	// Default to false in order to ensure Phone MFA is opt-in
 	MFAFactorTypeConfiguration
@@ -173,18 +534,40 @@ type PhoneFactorTypeConfiguration struct {
 	SMSTemplate  *template.Template `json:"-"`
 	MaxFrequency time.Duration      `json:"max_frequency" split_words:"true"`
 	Template     string             `json:"template"`
+
+	// MaxChallengesPerHour limits the OTPs sent to a phone factor in an
+	// hour, apart from the limits of phone sign-in OTPs. 0 disables it.
+	MaxChallengesPerHour int `json:"max_challenges_per_hour" split_words:"true" default:"10"`
 }
 
 // MFAConfiguration holds all the MFA related Configuration
 type MFAConfiguration struct {
-	ChallengeExpiryDuration     float64                      `json:"challenge_expiry_duration" default:"300" split_words:"true"`
-	FactorExpiryDuration        time.Duration                `json:"factor_expiry_duration" default:"300s" split_words:"true"`
-	RateLimitChallengeAndVerify float64                      `split_words:"true" default:"15"`
-	MaxEnrolledFactors          float64                      `split_words:"true" default:"10"`
-	MaxVerifiedFactors          int                          `split_words:"true" default:"10"`
-	Phone                       PhoneFactorTypeConfiguration `split_words:"true"`
-	TOTP                        TOTPFactorTypeConfiguration  `split_words:"true"`
-	WebAuthn                    MFAFactorTypeConfiguration   `split_words:"true"`
+	ChallengeExpiryDuration     float64                         `json:"challenge_expiry_duration" default:"300" split_words:"true"`
+	FactorExpiryDuration        time.Duration                   `json:"factor_expiry_duration" default:"300s" split_words:"true"`
+	RateLimitChallengeAndVerify float64                         `split_words:"true" default:"15"`
+	MaxEnrolledFactors          float64                         `split_words:"true" default:"10"`
+	MaxVerifiedFactors          int                             `split_words:"true" default:"10"`
+	Phone                       PhoneFactorTypeConfiguration    `split_words:"true"`
+	TOTP                        TOTPFactorTypeConfiguration     `split_words:"true"`
+	WebAuthn                    WebAuthnFactorTypeConfiguration `split_words:"true"`
+	RecoveryCodes               RecoveryCodesConfiguration      `json:"recovery_codes" split_words:"true"`
+	TrustedDevices              TrustedDevicesConfiguration     `json:"trusted_devices" split_words:"true"`
+}
+
+// RecoveryCodesConfiguration holds the settings of the one-time recovery
+// codes users verify MFA with when they lose their authenticators. Count
+// codes are issued when a user enrolls their first factor.
+type RecoveryCodesConfiguration struct {
+	Enabled bool `json:"enabled" default:"false"`
+	Count   int  `json:"count" default:"10"`
+}
+
+// TrustedDevicesConfiguration holds the settings of the devices users can
+// choose to remember after verifying MFA, which skip MFA challenges for
+// Duration.
+type TrustedDevicesConfiguration struct {
+	Enabled  bool          `json:"enabled" default:"false"`
+	Duration time.Duration `json:"duration" default:"720h"`
 }
 
 type APIConfiguration struct {
@@ -194,6 +577,13 @@ type APIConfiguration struct {
 	RequestIDHeader    string        `envconfig:"REQUEST_ID_HEADER"`
 	ExternalURL        string        `json:"external_url" envconfig:"API_EXTERNAL_URL" required:"true"`
 	MaxRequestDuration time.Duration `json:"max_request_duration" split_words:"true" default:"10s"`
+
+	// ShutdownDelay is how long the server keeps accepting requests after
+	// it was signaled to stop, while the readiness endpoint reports it is
+	// shutting down, so load balancers stop routing requests to it first.
+	// In-flight requests are then drained for up to ShutdownTimeout.
+	ShutdownDelay   time.Duration `json:"shutdown_delay" split_words:"true"`
+	ShutdownTimeout time.Duration `json:"shutdown_timeout" split_words:"true" default:"1m"`
 }
 
 func (a *APIConfiguration) Validate() error {
@@ -202,6 +592,26 @@ func (a *APIConfiguration) Validate() error {
 		return err
 	}
 
+	if a.ShutdownDelay < 0 || a.ShutdownTimeout < 0 {
+		return errors.New("conf: API shutdown delay and timeout must not be negative")
+	}
+
+	return nil
+}
+
+// HealthConfiguration configures the dependency checks of the readiness
+// endpoint. The checks of the SMTP server and the SMS provider only dial
+// them, and are off by default as Auth can serve most requests without them.
+type HealthConfiguration struct {
+	Timeout   time.Duration `json:"timeout" default:"2s"`
+	CheckSMTP bool          `json:"check_smtp" split_words:"true"`
+	CheckSMS  bool          `json:"check_sms" split_words:"true"`
+}
+
+func (c *HealthConfiguration) Validate() error {
+	if c.Timeout < 0 {
+		return errors.New("conf: health timeout must not be negative")
+	}
 	return nil
 }
 
@@ -212,6 +622,49 @@ type SessionsConfiguration struct {
 
 	SinglePerUser bool     `json:"single_per_user" split_words:"true"`
 	Tags          []string `json:"tags,omitempty"`
+
+	// RoleTimebox, RoleInactivityTimeout and SinglePerUserRoles set the
+	// policies of the sessions of the users with a role, overriding the
+	// ones above.
+	RoleTimebox           map[string]time.Duration `json:"role_timebox,omitempty" split_words:"true"`
+	RoleInactivityTimeout map[string]time.Duration `json:"role_inactivity_timeout,omitempty" split_words:"true"`
+	SinglePerUserRoles    []string                 `json:"single_per_user_roles,omitempty" split_words:"true"`
+
+	Cookie SessionCookieConfiguration `json:"cookie"`
+}
+
+// SessionCookieConfiguration configures keeping the refresh tokens of
+// browsers in HttpOnly cookies instead of the token responses. Browsers opt
+// in with the X-Use-Cookie header.
+type SessionCookieConfiguration struct {
+	Enabled  bool          `json:"enabled"`
+	Name     string        `json:"name" default:"sb-refresh-token"`
+	Domain   string        `json:"domain"`
+	Path     string        `json:"path" default:"/"`
+	SameSite string        `json:"same_site" split_words:"true" default:"lax"`
+	MaxAge   time.Duration `json:"max_age" split_words:"true" default:"720h"`
+}
+
+func (c *SessionCookieConfiguration) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+
+	if c.Name == "" {
+		return errors.New("conf: session cookie name must not be empty")
+	}
+
+	switch strings.ToLower(c.SameSite) {
+	case "lax", "strict", "none":
+	default:
+		return fmt.Errorf("conf: session cookie same site must be lax, strict or none, was %q", c.SameSite)
+	}
+
+	if c.MaxAge <= 0 {
+		return errors.New("conf: session cookie max age must be positive")
+	}
+
+	return nil
 }
 
 func (c *SessionsConfiguration) Validate() error {
@@ -223,11 +676,46 @@ func (c *SessionsConfiguration) Validate() error {
 		return fmt.Errorf("conf: session inactivity timeout duration must be positive when set, was %v", (*c.InactivityTimeout).String())
 	}
 
+	for role, timebox := range c.RoleTimebox {
+		if timebox <= time.Duration(0) {
+			return fmt.Errorf("conf: session timebox duration of role %q must be positive, was %v", role, timebox.String())
+		}
+	}
+
+	for role, timeout := range c.RoleInactivityTimeout {
+		if timeout <= time.Duration(0) {
+			return fmt.Errorf("conf: session inactivity timeout duration of role %q must be positive, was %v", role, timeout.String())
+		}
+	}
+
 	if c.AllowLowAAL != nil && *c.AllowLowAAL <= time.Duration(0) {
 		return fmt.Errorf("conf: session allow low AAL duration must be positive when set, was %v", (*c.AllowLowAAL).String())
 	}
 
-	return nil
+	return c.Cookie.Validate()
+}
+
+// TimeboxFor returns the timebox of the sessions of users with the role.
+func (c *SessionsConfiguration) TimeboxFor(role string) *time.Duration {
+	if timebox, ok := c.RoleTimebox[role]; ok {
+		return &timebox
+	}
+	return c.Timebox
+}
+
+// InactivityTimeoutFor returns the inactivity timeout of the sessions of
+// users with the role.
+func (c *SessionsConfiguration) InactivityTimeoutFor(role string) *time.Duration {
+	if timeout, ok := c.RoleInactivityTimeout[role]; ok {
+		return &timeout
+	}
+	return c.InactivityTimeout
+}
+
+// IsSinglePerUser reports whether users with the role can only have one
+// session per tag.
+func (c *SessionsConfiguration) IsSinglePerUser(role string) bool {
+	return c.SinglePerUser || slices.Contains(c.SinglePerUserRoles, role)
 }
 
 type PasswordRequiredCharacters []string
@@ -235,58 +723,421 @@ type PasswordRequiredCharacters []string
 func (v *PasswordRequiredCharacters) Decode(value string) error {
 	parts := strings.Split(value, ":")
 
-	for i := 0; i < len(parts)-1; i += 1 {
-		part := parts[i]
+	for i := 0; i < len(parts)-1; i += 1 {
+		part := parts[i]
+
+		if part == "" {
+			continue
+		}
+
+		// part ended in escape character, so it should be joined with the next one
+		if part[len(part)-1] == '\\' {
+			parts[i] = part[0:len(part)-1] + ":" + parts[i+1]
+			parts[i+1] = ""
+			continue
+		}
+	}
+
+	for _, part := range parts {
+		if part != "" {
+			*v = append(*v, part)
+		}
+	}
+
+	return nil
+}
+
+// HIBPBloomConfiguration configures a bloom cache for pwned passwords. Use
+// this tool to gauge the Items and FalsePositives values:
+// https://hur.st/bloomfilter
+type HIBPBloomConfiguration struct {
+	Enabled        bool    `json:"enabled"`
+	Items          uint    `json:"items" default:"100000"`
+	FalsePositives float64 `json:"false_positives" split_words:"true" default:"0.0000099"`
+}
+
+type HIBPConfiguration struct {
+	Enabled    bool `json:"enabled"`
+	FailClosed bool `json:"fail_closed" split_words:"true"`
+
+	// AuditOnly records breached passwords in the audit log instead of
+	// rejecting them.
+	AuditOnly bool `json:"audit_only" split_words:"true"`
+
+	Timeout time.Duration `json:"timeout" default:"5s"`
+
+	UserAgent string `json:"user_agent" split_words:"true" default:"https://github.com/supabase/gotrue"`
+
+	Bloom HIBPBloomConfiguration `json:"bloom"`
+}
+
+const (
+	PasswordHashAlgorithmBcrypt   = "bcrypt"
+	PasswordHashAlgorithmArgon2id = "argon2id"
+)
+
+// PasswordHashingConfiguration selects the algorithm new password hashes
+// are generated with. The Argon2id defaults follow the OWASP
+// recommendation of 19 MiB of memory, 2 iterations and 1 degree of
+// parallelism.
+type PasswordHashingConfiguration struct {
+	Algorithm string `json:"algorithm" default:"bcrypt"`
+
+	// Argon2Memory is in KiB.
+	Argon2Memory      uint32 `json:"argon2_memory" split_words:"true" default:"19456"`
+	Argon2Iterations  uint32 `json:"argon2_iterations" split_words:"true" default:"2"`
+	Argon2Parallelism uint8  `json:"argon2_parallelism" split_words:"true" default:"1"`
+}
+
+func (c *PasswordHashingConfiguration) Validate() error {
+	switch c.Algorithm {
+	case "", PasswordHashAlgorithmBcrypt:
+		return nil
+
+	case PasswordHashAlgorithmArgon2id:
+		if c.Argon2Iterations < 1 {
+			return errors.New("conf: password hashing argon2 iterations must be at least 1")
+		}
+		if c.Argon2Parallelism < 1 {
+			return errors.New("conf: password hashing argon2 parallelism must be at least 1")
+		}
+		if c.Argon2Memory < 8*uint32(c.Argon2Parallelism) {
+			return errors.New("conf: password hashing argon2 memory must be at least 8 KiB per degree of parallelism")
+		}
+		return nil
+	}
+
+	return fmt.Errorf("conf: password hashing algorithm %q is not supported, use %q or %q", c.Algorithm, PasswordHashAlgorithmBcrypt, PasswordHashAlgorithmArgon2id)
+}
+
+type PasswordConfiguration struct {
+	MinLength int `json:"min_length" split_words:"true"`
+
+	RequiredCharacters PasswordRequiredCharacters `json:"required_characters" split_words:"true"`
+
+	// MinScore is the minimum estimated strength, on the 0 (too guessable)
+	// to 4 (very unguessable) zxcvbn scale, a password must reach. Zero
+	// disables the check.
+	MinScore int `json:"min_score" split_words:"true"`
+
+	// RejectEmail rejects passwords which contain the user's email address
+	// or its local part.
+	RejectEmail bool `json:"reject_email" split_words:"true"`
+
+	HIBP HIBPConfiguration `json:"hibp"`
+
+	Hashing PasswordHashingConfiguration `json:"hashing"`
+}
+
+func (c *PasswordConfiguration) Validate() error {
+	if c.MinScore < 0 || c.MinScore > 4 {
+		return errors.New("conf: password min score must be between 0 and 4")
+	}
+
+	return c.Hashing.Validate()
+}
+
+type AuditLogConfiguration struct {
+	DisablePostgres bool `split_words:"true" default:"false"`
+
+	Retention AuditLogRetentionConfiguration `json:"retention"`
+	Sinks     AuditLogSinksConfiguration     `json:"sinks"`
+}
+
+func (c *AuditLogConfiguration) Validate() error {
+	if err := c.Retention.Validate(); err != nil {
+		return err
+	}
+	return c.Sinks.Validate()
+}
+
+// AuditLogSinksConfiguration configures shipping the audit log entries to
+// external systems as they are recorded. Each enabled sink buffers up to
+// BufferSize entries and sends them in batches of up to BatchSize, at least
+// every FlushInterval. Failed batches are retried MaxAttempts times, starting
+// after RetryInterval. When a sink's buffer is full, recording an entry
+// waits up to BlockTimeout for room before the entry is dropped for that
+// sink.
+type AuditLogSinksConfiguration struct {
+	BufferSize    int           `json:"buffer_size" split_words:"true" default:"10000"`
+	BatchSize     int           `json:"batch_size" split_words:"true" default:"100"`
+	FlushInterval time.Duration `json:"flush_interval" split_words:"true" default:"1s"`
+	BlockTimeout  time.Duration `json:"block_timeout" split_words:"true" default:"100ms"`
+	MaxAttempts   int           `json:"max_attempts" split_words:"true" default:"5"`
+	RetryInterval time.Duration `json:"retry_interval" split_words:"true" default:"1s"`
+
+	Syslog  AuditLogSyslogConfiguration  `json:"syslog"`
+	Kafka   AuditLogKafkaConfiguration   `json:"kafka"`
+	Webhook AuditLogWebhookConfiguration `json:"webhook"`
+}
+
+func (c *AuditLogSinksConfiguration) Validate() error {
+	if !c.Syslog.Enabled && !c.Kafka.Enabled && !c.Webhook.Enabled {
+		return nil
+	}
+
+	if c.BufferSize < 1 || c.BatchSize < 1 || c.BatchSize > c.BufferSize {
+		return errors.New("conf: audit log sinks batch size must be at least 1 and at most the buffer size")
+	}
+
+	if c.FlushInterval <= 0 {
+		return errors.New("conf: audit log sinks flush interval must be positive")
+	}
+
+	if c.MaxAttempts < 1 || c.RetryInterval <= 0 {
+		return errors.New("conf: audit log sinks max attempts must be at least 1 and retry interval positive")
+	}
+
+	validatables := []interface {
+		Validate() error
+	}{
+		&c.Syslog,
+		&c.Kafka,
+		&c.Webhook,
+	}
+	for _, validatable := range validatables {
+		if err := validatable.Validate(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// AuditLogSyslogConfiguration configures sending the audit log entries to a
+// syslog server as RFC 5424 messages with a CEF payload. TCP messages are
+// framed with octet counting.
+type AuditLogSyslogConfiguration struct {
+	Enabled  bool   `json:"enabled"`
+	Network  string `json:"network" default:"udp"`
+	Address  string `json:"address"`
+	Facility int    `json:"facility" default:"10"`
+	Hostname string `json:"hostname"`
+	AppName  string `json:"app_name" split_words:"true" default:"gotrue"`
+}
+
+func (c *AuditLogSyslogConfiguration) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+
+	switch c.Network {
+	case "udp", "tcp", "tls":
+	default:
+		return fmt.Errorf("conf: audit log syslog network %q is not supported", c.Network)
+	}
+
+	if c.Address == "" {
+		return errors.New("conf: missing audit log syslog address")
+	}
+
+	if c.Facility < 0 || c.Facility > 23 {
+		return errors.New("conf: audit log syslog facility must be between 0 and 23")
+	}
+
+	return nil
+}
+
+// AuditLogKafkaConfiguration configures producing the audit log entries to
+// a Kafka topic through a Kafka REST Proxy (v2 API), keyed by the actor.
+type AuditLogKafkaConfiguration struct {
+	Enabled  bool   `json:"enabled"`
+	URL      string `json:"url"`
+	Topic    string `json:"topic"`
+	Username string `json:"username"`
+	Password string `json:"-"`
+}
+
+func (c *AuditLogKafkaConfiguration) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+
+	if _, err := url.ParseRequestURI(c.URL); err != nil {
+		return errors.New("conf: audit log kafka url must be a valid URL")
+	}
+
+	if c.Topic == "" {
+		return errors.New("conf: missing audit log kafka topic")
+	}
+
+	return nil
+}
+
+// AuditLogWebhookConfiguration configures posting batches of audit log
+// entries to a URL, signed as Standard Webhooks with the secrets.
+type AuditLogWebhookConfiguration struct {
+	Enabled bool            `json:"enabled"`
+	URL     string          `json:"url"`
+	Secrets HTTPHookSecrets `json:"secrets" envconfig:"secrets"`
+	Timeout time.Duration   `json:"timeout" default:"5s"`
+}
+
+func (c *AuditLogWebhookConfiguration) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+
+	u, err := url.ParseRequestURI(c.URL)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") {
+		return errors.New("conf: audit log webhook url must be a valid HTTP URL")
+	}
+
+	if len(c.Secrets) == 0 {
+		return errors.New("conf: missing audit log webhook secret")
+	}
+
+	for _, secret := range c.Secrets {
+		if !symmetricSecretFormat.MatchString(secret) {
+			return errors.New("conf: audit log webhook secrets must be symmetric v1,whsec_ secrets")
+		}
+	}
+
+	return nil
+}
+
+// UserDeletionConfiguration configures purging soft deleted users, which
+// are permanently deleted with their identities, sessions and MFA factors
+// GracePeriod after they were soft deleted. Due users are purged every
+// PurgeInterval in batches of BatchSize users. SelfServiceEnabled lets users
+// delete their own account.
+type UserDeletionConfiguration struct {
+	PurgeEnabled       bool `json:"purge_enabled" split_words:"true"`
+	SelfServiceEnabled bool `json:"self_service_enabled" split_words:"true"`
+
+	GracePeriod   time.Duration `json:"grace_period" split_words:"true" default:"720h"`
+	PurgeInterval time.Duration `json:"purge_interval" split_words:"true" default:"1h"`
+	BatchSize     int           `json:"batch_size" split_words:"true" default:"100"`
+}
+
+func (c *UserDeletionConfiguration) Validate() error {
+	if !c.PurgeEnabled {
+		return nil
+	}
+
+	if c.GracePeriod < 0 {
+		return errors.New("conf: user deletion grace period must not be negative")
+	}
+
+	if c.PurgeInterval <= 0 {
+		return errors.New("conf: user deletion purge interval must be positive")
+	}
+
+	if c.BatchSize < 1 {
+		return errors.New("conf: user deletion batch size must be at least 1")
+	}
+
+	return nil
+}
+
+// UserExportConfiguration configures the exports of their data users can
+// request, which are downloaded with a link sent by email that expires
+// after Expiry. Users can request an export every Frequency.
+type UserExportConfiguration struct {
+	Enabled bool `json:"enabled"`
+
+	Expiry    time.Duration `json:"expiry" default:"24h"`
+	Frequency time.Duration `json:"frequency" default:"1h"`
+
+	// LinkSecret signs the download links of exports.
+	LinkSecret string `json:"link_secret" split_words:"true"`
+}
+
+func (c *UserExportConfiguration) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+
+	if c.Expiry <= 0 {
+		return errors.New("conf: user export expiry must be positive")
+	}
+
+	if c.Frequency < 0 {
+		return errors.New("conf: user export frequency must not be negative")
+	}
+
+	if len(c.LinkSecret) < 32 {
+		return errors.New("conf: user export link secret must be at least 32 characters long")
+	}
+
+	return nil
+}
+
+// AuditLogRetentionConfiguration configures pruning the audit log entries
+// older than MaxAge, every Interval in batches of BatchSize entries. The
+// entries are archived before they are pruned when an archive is
+// configured.
+type AuditLogRetentionConfiguration struct {
+	Enabled bool `json:"enabled"`
+
+	MaxAge    time.Duration `json:"max_age" split_words:"true" default:"2160h"`
+	Interval  time.Duration `json:"interval" default:"1h"`
+	BatchSize int           `json:"batch_size" split_words:"true" default:"1000"`
+
+	Archive AuditLogArchiveConfiguration `json:"archive"`
+}
+
+func (c *AuditLogRetentionConfiguration) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
 
-		if part == "" {
-			continue
-		}
+	if c.MaxAge <= 0 {
+		return errors.New("conf: audit log retention max age must be positive")
+	}
 
-		// part ended in escape character, so it should be joined with the next one
-		if part[len(part)-1] == '\\' {
-			parts[i] = part[0:len(part)-1] + ":" + parts[i+1]
-			parts[i+1] = ""
-			continue
-		}
+	if c.Interval <= 0 {
+		return errors.New("conf: audit log retention interval must be positive")
 	}
 
-	for _, part := range parts {
-		if part != "" {
-			*v = append(*v, part)
-		}
+	if c.BatchSize < 1 {
+		return errors.New("conf: audit log retention batch size must be at least 1")
 	}
 
-	return nil
+	return c.Archive.Validate()
 }
 
-// HIBPBloomConfiguration configures a bloom cache for pwned passwords. Use
-// this tool to gauge the Items and FalsePositives values:
-// https://hur.st/bloomfilter
-type HIBPBloomConfiguration struct {
-	Enabled        bool    `json:"enabled"`
-	Items          uint    `json:"items" default:"100000"`
-	FalsePositives float64 `json:"false_positives" split_words:"true" default:"0.0000099"`
+// AuditLogArchiveConfiguration configures the bucket pruned audit log
+// entries are archived to. S3 and Google Cloud Storage, with HMAC keys, are
+// supported. Endpoint overrides the default endpoint of the provider, for
+// S3 compatible storage.
+type AuditLogArchiveConfiguration struct {
+	Provider string `json:"provider"`
+	Bucket   string `json:"bucket"`
+	Prefix   string `json:"prefix"`
+	Region   string `json:"region"`
+	Endpoint string `json:"endpoint"`
+
+	AccessKeyID     string `json:"access_key_id" split_words:"true"`
+	SecretAccessKey string `json:"-" split_words:"true"`
+	SessionToken    string `json:"-" split_words:"true"`
 }
 
-type HIBPConfiguration struct {
-	Enabled    bool `json:"enabled"`
-	FailClosed bool `json:"fail_closed" split_words:"true"`
+func (c *AuditLogArchiveConfiguration) Validate() error {
+	switch c.Provider {
+	case "":
+		return nil
 
-	UserAgent string `json:"user_agent" split_words:"true" default:"https://github.com/supabase/gotrue"`
+	case "s3":
+		if c.Region == "" && c.Endpoint == "" {
+			return errors.New("conf: audit log archive to s3 requires a region or an endpoint")
+		}
 
-	Bloom HIBPBloomConfiguration `json:"bloom"`
-}
+	case "gcs":
 
-type PasswordConfiguration struct {
-	MinLength int `json:"min_length" split_words:"true"`
+	default:
+		return fmt.Errorf("conf: audit log archive provider %q is not supported", c.Provider)
+	}
 
-	RequiredCharacters PasswordRequiredCharacters `json:"required_characters" split_words:"true"`
+	if c.Bucket == "" {
+		return errors.New("conf: missing audit log archive bucket")
+	}
 
-	HIBP HIBPConfiguration `json:"hibp"`
-}
+	if c.AccessKeyID == "" || c.SecretAccessKey == "" {
+		return errors.New("conf: missing audit log archive access key")
+	}
 
-type AuditLogConfiguration struct {
-	DisablePostgres bool `split_words:"true" default:"false"`
+	return nil
 }
 
 type ExperimentalConfiguration struct {
@@ -333,14 +1184,29 @@ type GlobalConfiguration struct {
 	API           APIConfiguration
 	DB            DBConfiguration
 	External      ProviderConfiguration
-	OAuthServer   OAuthServerConfiguration `envconfig:"OAUTH_SERVER"`
-	Logging       LoggingConfig            `envconfig:"LOG"`
-	Profiler      ProfilerConfig           `envconfig:"PROFILER"`
-	OperatorToken string                   `split_words:"true" required:"false"`
+	OAuthServer   OAuthServerConfiguration   `envconfig:"OAUTH_SERVER"`
+	SCIM          SCIMConfiguration          `envconfig:"SCIM"`
+	GRPC          GRPCConfiguration          `envconfig:"GRPC"`
+	Tenancy       TenancyConfiguration       `json:"tenancy"`
+	Organizations OrganizationsConfiguration `json:"organizations"`
+	RBAC          RBACConfiguration          `json:"rbac" envconfig:"RBAC"`
+	Impersonation ImpersonationConfiguration `json:"impersonation"`
+	Logging       LoggingConfig              `envconfig:"LOG"`
+	Profiler      ProfilerConfig             `envconfig:"PROFILER"`
+	OperatorToken string                     `split_words:"true" required:"false"`
 	Tracing       TracingConfig
 	Metrics       MetricsConfig
 	SMTP          SMTPConfiguration
-	AuditLog      AuditLogConfiguration `split_words:"true"`
+	AuditLog      AuditLogConfiguration      `split_words:"true"`
+	UserDeletion  UserDeletionConfiguration  `json:"user_deletion" split_words:"true"`
+	UserExport    UserExportConfiguration    `json:"user_export" split_words:"true"`
+	Webhooks      WebhooksConfiguration      `json:"webhooks"`
+	TokenExchange TokenExchangeConfiguration `json:"token_exchange" split_words:"true"`
+	GeoIP         GeoIPConfiguration         `json:"geoip" envconfig:"GEOIP"`
+	Risk          RiskConfiguration          `json:"risk"`
+	IPAccess      IPAccessConfiguration      `json:"ip_access" envconfig:"IP_ACCESS"`
+	Reaper        ReaperConfiguration        `json:"reaper"`
+	Cache         CacheConfiguration         `json:"cache"`
 
 	RateLimitHeader                     string  `split_words:"true"`
 	RateLimitEmailSent                  Rate    `split_words:"true" default:"30"`
@@ -353,6 +1219,8 @@ type GlobalConfiguration struct {
 	RateLimitWeb3                       float64 `split_words:"true" default:"30"`
 	RateLimitOAuthDynamicClientRegister float64 `split_words:"true" default:"10"`
 
+	RateLimitStore RateLimitStoreConfiguration `json:"rate_limit_store" split_words:"true"`
+
 	SiteURL         string   `json:"site_url" split_words:"true" required:"true"`
 	URIAllowList    []string `json:"uri_allow_list" split_words:"true"`
 	URIAllowListMap map[string]glob.Glob
@@ -368,6 +1236,7 @@ type GlobalConfiguration struct {
 	SAML            SAMLConfiguration        `json:"saml"`
 	CORS            CORSConfiguration        `json:"cors"`
 	IndexWorker     IndexWorkerConfiguration `json:"index_worker" split_words:"true"`
+	Health          HealthConfiguration      `json:"health"`
 
 	Experimental ExperimentalConfiguration `json:"experimental"`
 	Reloading    ReloadingConfiguration    `json:"reloading"`
@@ -375,6 +1244,7 @@ type GlobalConfiguration struct {
 
 type CORSConfiguration struct {
 	AllowedHeaders []string `json:"allowed_headers" split_words:"true"`
+	AllowedOrigins []string `json:"allowed_origins" split_words:"true"`
 }
 
 func (c *CORSConfiguration) AllAllowedHeaders(defaults []string) []string {
@@ -414,6 +1284,14 @@ type EmailContentConfiguration struct {
 	IdentityUnlinkedNotification    string `json:"identity_unlinked_notification" split_words:"true"`
 	MFAFactorEnrolledNotification   string `json:"mfa_factor_enrolled_notification" split_words:"true"`
 	MFAFactorUnenrolledNotification string `json:"mfa_factor_unenrolled_notification" split_words:"true"`
+
+	// DataExportNotification is sent with the download link of the exports
+	// users request.
+	DataExportNotification string `json:"data_export_notification" split_words:"true"`
+
+	// NewSignInNotification is sent when a user signs in from a location or
+	// device they have not signed in from before.
+	NewSignInNotification string `json:"new_sign_in_notification" split_words:"true"`
 }
 
 // NotificationsConfiguration holds the configuration for notification email states to indicate whether they are enabled or disabled.
@@ -425,13 +1303,19 @@ type NotificationsConfiguration struct {
 	IdentityUnlinkedEnabled    bool `json:"identity_unlinked_enabled" split_words:"true" default:"false"`
 	MFAFactorEnrolledEnabled   bool `json:"mfa_factor_enrolled_enabled" split_words:"true" default:"false"`
 	MFAFactorUnenrolledEnabled bool `json:"mfa_factor_unenrolled_enabled" split_words:"true" default:"false"`
+	NewSignInEnabled           bool `json:"new_sign_in_enabled" split_words:"true" default:"false"`
+
+	// NewSignInLinkSecret signs the links of new sign-in notifications,
+	// which sign out the new session.
+	NewSignInLinkSecret string `json:"new_sign_in_link_secret" split_words:"true"`
 }
 
 type ProviderConfiguration struct {
 	AnonymousUsers          AnonymousProviderConfiguration    `json:"anonymous_users" split_words:"true"`
 	Apple                   OAuthProviderConfiguration        `json:"apple"`
-	Azure                   OAuthProviderConfiguration        `json:"azure"`
+	Azure                   AzureProviderConfiguration        `json:"azure"`
 	Bitbucket               OAuthProviderConfiguration        `json:"bitbucket"`
+	Custom                  CustomProviderConfiguration       `json:"custom"`
 	Discord                 OAuthProviderConfiguration        `json:"discord"`
 	Facebook                OAuthProviderConfiguration        `json:"facebook"`
 	Snapchat                OAuthProviderConfiguration        `json:"snapchat"`
@@ -445,15 +1329,19 @@ type ProviderConfiguration struct {
 	Google                  OAuthProviderConfiguration        `json:"google"`
 	Kakao                   OAuthProviderConfiguration        `json:"kakao"`
 	Notion                  OAuthProviderConfiguration        `json:"notion"`
-	Keycloak                OAuthProviderConfiguration        `json:"keycloak"`
+	QQ                      OAuthProviderConfiguration        `json:"qq" envconfig:"QQ"`
+	Keycloak                KeycloakProviderConfiguration     `json:"keycloak"`
 	Linkedin                OAuthProviderConfiguration        `json:"linkedin"`
 	LinkedinOIDC            OAuthProviderConfiguration        `json:"linkedin_oidc" envconfig:"LINKEDIN_OIDC"`
 	Spotify                 OAuthProviderConfiguration        `json:"spotify"`
 	Slack                   OAuthProviderConfiguration        `json:"slack"`
 	SlackOIDC               OAuthProviderConfiguration        `json:"slack_oidc" envconfig:"SLACK_OIDC"`
+	Steam                   OAuthProviderConfiguration        `json:"steam"`
 	Twitter                 OAuthProviderConfiguration        `json:"twitter"`
 	Twitch                  OAuthProviderConfiguration        `json:"twitch"`
 	VercelMarketplace       OAuthProviderConfiguration        `json:"vercel_marketplace" split_words:"true"`
+	WeChat                  OAuthProviderConfiguration        `json:"wechat" envconfig:"WECHAT"`
+	WeChatOfficialAccount   OAuthProviderConfiguration        `json:"wechat_official_account" envconfig:"WECHAT_OFFICIAL_ACCOUNT"`
 	WorkOS                  OAuthProviderConfiguration        `json:"workos"`
 	Email                   EmailProviderConfiguration        `json:"email"`
 	Phone                   PhoneProviderConfiguration        `json:"phone"`
@@ -463,19 +1351,115 @@ type ProviderConfiguration struct {
 	RedirectURL             string                            `json:"redirect_url"`
 	AllowedIdTokenIssuers   []string                          `json:"allowed_id_token_issuers" split_words:"true"`
 	FlowStateExpiryDuration time.Duration                     `json:"flow_state_expiry_duration" split_words:"true"`
+	ProviderTokens          ProviderTokensConfiguration       `json:"provider_tokens" split_words:"true"`
 
 	Web3Solana   SolanaConfiguration   `json:"web3_solana" split_words:"true"`
 	Web3Ethereum EthereumConfiguration `json:"web3_ethereum" split_words:"true"`
+
+	Telegram TelegramConfiguration `json:"telegram"`
+
+	Connections ExternalConnections `json:"connections"`
+}
+
+// OAuthProvider returns the OAuth configuration of the provider or the
+// external connection with the name.
+func (p *ProviderConfiguration) OAuthProvider(name string) (OAuthProviderConfiguration, bool) {
+	if conn := p.Connections.Find(name); conn != nil {
+		return *conn.OAuthProviderConfiguration, true
+	}
+
+	v := reflect.ValueOf(p).Elem()
+	t := v.Type()
+
+	oauthType := reflect.TypeOf(OAuthProviderConfiguration{})
+
+	for i := 0; i < t.NumField(); i++ {
+		if strings.Split(t.Field(i).Tag.Get("json"), ",")[0] != name {
+			continue
+		}
+
+		field := v.Field(i)
+		if field.Type() == oauthType {
+			return field.Interface().(OAuthProviderConfiguration), true
+		}
+		if field.Kind() != reflect.Struct {
+			return OAuthProviderConfiguration{}, false
+		}
+
+		// providers with their own settings embed the OAuth configuration
+		embedded := field.FieldByName("OAuthProviderConfiguration")
+		if embedded.Kind() == reflect.Ptr {
+			if embedded.IsNil() {
+				return OAuthProviderConfiguration{}, false
+			}
+			embedded = embedded.Elem()
+		}
+		if embedded.IsValid() && embedded.Type() == oauthType {
+			return embedded.Interface().(OAuthProviderConfiguration), true
+		}
+		return OAuthProviderConfiguration{}, false
+	}
+
+	return OAuthProviderConfiguration{}, false
+}
+
+// ProviderTokensConfiguration configures the storage of the access and
+// refresh tokens of the OAuth providers users sign in with, so that
+// applications can call the provider APIs on behalf of the users. Stored
+// tokens expiring within RefreshBefore are refreshed every RefreshInterval
+// in batches of BatchSize tokens.
+type ProviderTokensConfiguration struct {
+	Enabled bool `json:"enabled"`
+
+	RefreshInterval time.Duration `json:"refresh_interval" split_words:"true" default:"1m"`
+	RefreshBefore   time.Duration `json:"refresh_before" split_words:"true" default:"5m"`
+	BatchSize       int           `json:"batch_size" split_words:"true" default:"100"`
+}
+
+func (c *ProviderTokensConfiguration) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+
+	if c.RefreshInterval <= 0 {
+		return errors.New("conf: provider tokens refresh interval must be positive")
+	}
+
+	if c.RefreshBefore < 0 {
+		return errors.New("conf: provider tokens refresh before must not be negative")
+	}
+
+	if c.BatchSize < 1 {
+		return errors.New("conf: provider tokens batch size must be at least 1")
+	}
+
+	return nil
 }
 
 type SolanaConfiguration struct {
 	Enabled                 bool          `json:"enabled,omitempty" split_words:"true"`
 	MaximumValidityDuration time.Duration `json:"maximum_validity_duration,omitempty" default:"10m" split_words:"true"`
+
+	// NonceRequired only accepts messages signed with a nonce issued by
+	// POST /web3/nonce, each once.
+	NonceRequired bool `json:"nonce_required,omitempty" split_words:"true"`
+}
+
+// TelegramConfiguration configures sign in with the Telegram Login Widget,
+// whose auth data is signed with the token of the bot users authorize.
+type TelegramConfiguration struct {
+	Enabled                 bool          `json:"enabled,omitempty"`
+	BotToken                string        `json:"bot_token,omitempty" split_words:"true"`
+	MaximumValidityDuration time.Duration `json:"maximum_validity_duration,omitempty" default:"24h" split_words:"true"`
 }
 
 type EthereumConfiguration struct {
 	Enabled                 bool          `json:"enabled,omitempty" split_words:"true"`
 	MaximumValidityDuration time.Duration `json:"maximum_validity_duration,omitempty" default:"10m" split_words:"true"`
+
+	// NonceRequired only accepts messages signed with a nonce issued by
+	// POST /web3/nonce, each once.
+	NonceRequired bool `json:"nonce_required,omitempty" split_words:"true"`
 }
 
 type SMTPConfiguration struct {
@@ -536,8 +1520,33 @@ type MailerConfiguration struct {
 	OtpExp    uint `json:"otp_exp" split_words:"true"`
 	OtpLength int  `json:"otp_length" split_words:"true"`
 
+	// InviteExp is the number of seconds an invite link or OTP is valid
+	// for, which defaults to OtpExp.
+	InviteExp uint `json:"invite_exp" split_words:"true"`
+
+	// OTPOnly leaves verification links out of emails and disables them,
+	// so that users verify with the emailed code. Mail scanners that follow
+	// links would otherwise consume the code before the user can enter it.
+	OTPOnly bool `json:"otp_only" split_words:"true"`
+
 	ExternalHosts []string `json:"external_hosts" split_words:"true"`
 
+	// LocalizationEnabled sends mail with the email template variant of the
+	// user's locale, stored as the locale in the user's metadata or taken
+	// from the Accept-Language header of the request. Signups store the
+	// locale of the request when the user has none.
+	LocalizationEnabled bool `json:"localization_enabled" split_words:"true" default:"false"`
+
+	Queue MailQueueConfiguration `json:"queue"`
+
+	// Transport delivers mail, over SMTP or with the HTTP API of an email
+	// service. The sender is GOTRUE_SMTP_ADMIN_EMAIL with every transport.
+	Transport string                         `json:"transport"`
+	SES       SESTransportConfiguration      `json:"ses"`
+	SendGrid  SendGridTransportConfiguration `json:"sendgrid"`
+	Mailgun   MailgunTransportConfiguration  `json:"mailgun"`
+	Postmark  PostmarkTransportConfiguration `json:"postmark"`
+
 	// EXPERIMENTAL: All config below here may be removed in a future release.
 	EmailBackgroundSending        bool   `json:"email_background_sending" split_words:"true" default:"false"`
 	EmailValidationExtended       bool   `json:"email_validation_extended" split_words:"true" default:"false"`
@@ -598,7 +1607,32 @@ func (c *MailerConfiguration) Validate() error {
 
 	c.blockedMXRecords = blockedMXRecords
 
-	return nil
+	if c.Notifications.NewSignInEnabled && len(c.Notifications.NewSignInLinkSecret) < 32 {
+		return errors.New("conf: new sign-in notification link secret must be at least 32 characters long")
+	}
+
+	if err := c.validateTransport(); err != nil {
+		return err
+	}
+
+	return c.Queue.Validate()
+}
+
+func (c *MailerConfiguration) validateTransport() error {
+	switch c.Transport {
+	case "", MailerTransportSMTP:
+		return nil
+	case MailerTransportSES:
+		return c.SES.Validate()
+	case MailerTransportSendGrid:
+		return c.SendGrid.Validate()
+	case MailerTransportMailgun:
+		return c.Mailgun.Validate()
+	case MailerTransportPostmark:
+		return c.Postmark.Validate()
+	default:
+		return fmt.Errorf("conf: mailer transport %q is not supported", c.Transport)
+	}
 }
 
 func (c *MailerConfiguration) GetEmailValidationServiceHeaders() map[string][]string {
@@ -609,6 +1643,150 @@ func (c *MailerConfiguration) GetEmailValidationBlockedMXRecords() map[string]bo
 	return c.blockedMXRecords
 }
 
+// Mail transports, see MailerConfiguration.Transport.
+const (
+	MailerTransportSMTP     = "smtp"
+	MailerTransportSES      = "ses"
+	MailerTransportSendGrid = "sendgrid"
+	MailerTransportMailgun  = "mailgun"
+	MailerTransportPostmark = "postmark"
+)
+
+// SESTransportConfiguration configures sending mail with the Amazon SES v2
+// API.
+type SESTransportConfiguration struct {
+	AccessKeyID      string `json:"access_key_id" split_words:"true"`
+	SecretAccessKey  string `json:"-" split_words:"true"`
+	SessionToken     string `json:"-" split_words:"true"`
+	Region           string `json:"region"`
+	ConfigurationSet string `json:"configuration_set" split_words:"true"`
+}
+
+func (c *SESTransportConfiguration) Validate() error {
+	if c.AccessKeyID == "" {
+		return errors.New("conf: missing AWS SES access key ID")
+	}
+	if c.SecretAccessKey == "" {
+		return errors.New("conf: missing AWS SES secret access key")
+	}
+	if c.Region == "" {
+		return errors.New("conf: missing AWS SES region")
+	}
+	return nil
+}
+
+// SendGridTransportConfiguration configures sending mail with the SendGrid
+// v3 API.
+type SendGridTransportConfiguration struct {
+	APIKey string `json:"-" split_words:"true"`
+}
+
+func (c *SendGridTransportConfiguration) Validate() error {
+	if c.APIKey == "" {
+		return errors.New("conf: missing SendGrid API key")
+	}
+	return nil
+}
+
+// MailgunTransportConfiguration configures sending mail with the Mailgun
+// API of the domain, in the US or EU region.
+type MailgunTransportConfiguration struct {
+	APIKey string `json:"-" split_words:"true"`
+	Domain string `json:"domain"`
+	Region string `json:"region" default:"us"`
+}
+
+func (c *MailgunTransportConfiguration) Validate() error {
+	if c.APIKey == "" {
+		return errors.New("conf: missing Mailgun API key")
+	}
+	if c.Domain == "" {
+		return errors.New("conf: missing Mailgun domain")
+	}
+	if c.Region != "" && c.Region != "us" && c.Region != "eu" {
+		return fmt.Errorf("conf: unsupported Mailgun region %q, use \"us\" or \"eu\"", c.Region)
+	}
+	return nil
+}
+
+// PostmarkTransportConfiguration configures sending mail with the Postmark
+// API to the message stream.
+type PostmarkTransportConfiguration struct {
+	ServerToken   string `json:"-" split_words:"true"`
+	MessageStream string `json:"message_stream" split_words:"true" default:"outbound"`
+}
+
+func (c *PostmarkTransportConfiguration) Validate() error {
+	if c.ServerToken == "" {
+		return errors.New("conf: missing Postmark server token")
+	}
+	return nil
+}
+
+// MailQueueConfiguration configures sending mail from a queue in the
+// database instead of during the request. Failed sends are retried with an
+// exponential backoff starting at RetryInterval, until MaxAttempts attempts
+// failed and the mail is dead-lettered.
+type MailQueueConfiguration struct {
+	Enabled bool `json:"enabled"`
+
+	MaxAttempts      int           `json:"max_attempts" split_words:"true" default:"5"`
+	RetryInterval    time.Duration `json:"retry_interval" split_words:"true" default:"30s"`
+	MaxRetryInterval time.Duration `json:"max_retry_interval" split_words:"true" default:"1h"`
+
+	// PollInterval is how often the worker looks for mail to send, and
+	// BatchSize how many mails it sends at a time.
+	PollInterval time.Duration `json:"poll_interval" split_words:"true" default:"1s"`
+	BatchSize    int           `json:"batch_size" split_words:"true" default:"10"`
+}
+
+func (c *MailQueueConfiguration) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+
+	if c.MaxAttempts < 1 {
+		return errors.New("conf: mailer queue max attempts must be at least 1")
+	}
+
+	if c.RetryInterval <= 0 || c.MaxRetryInterval < c.RetryInterval {
+		return errors.New("conf: mailer queue retry interval must be positive and at most the max retry interval")
+	}
+
+	if c.PollInterval <= 0 {
+		return errors.New("conf: mailer queue poll interval must be positive")
+	}
+
+	if c.BatchSize < 1 {
+		return errors.New("conf: mailer queue batch size must be at least 1")
+	}
+
+	return nil
+}
+
+// RetryAt returns when to retry a mail that failed to be sent attempts
+// times, or nil when it should not be retried.
+func (c *MailQueueConfiguration) RetryAt(attempts int, now time.Time) *time.Time {
+	return backoff(attempts, c.MaxAttempts, c.RetryInterval, c.MaxRetryInterval, now)
+}
+
+// backoff returns when to retry after attempts failed attempts, waiting
+// interval after the first and doubling the wait for every further attempt
+// up to maxInterval. It returns nil once maxAttempts attempts failed.
+func backoff(attempts, maxAttempts int, interval, maxInterval time.Duration, now time.Time) *time.Time {
+	if attempts >= maxAttempts {
+		return nil
+	}
+
+	for i := 1; i < attempts && interval < maxInterval; i++ {
+		interval *= 2
+	}
+	interval = min(interval, maxInterval)
+
+	retryAt := now.Add(interval)
+	return &retryAt
+}
+
 type PhoneProviderConfiguration struct {
 	Enabled bool `json:"enabled" default:"false"`
 }
@@ -624,11 +1802,33 @@ type SmsProviderConfiguration struct {
 	TestOTPValidUntil Time               `json:"test_otp_valid_until" split_words:"true"`
 	SMSTemplate       *template.Template `json:"-"`
 
+	// WhatsappTemplate is used instead of Template for messages delivered
+	// over the whatsapp channel. It defaults to Template.
+	WhatsappTemplate        string             `json:"whatsapp_template" split_words:"true"`
+	WhatsappMessageTemplate *template.Template `json:"-"`
+
+	// FallbackProviders are tried in order when sending a message with
+	// Provider fails.
+	FallbackProviders []string `json:"fallback_providers" split_words:"true"`
+	// MaxRetries is the number of times sending a message is retried with
+	// each provider before moving on to the next one.
+	MaxRetries int `json:"max_retries" split_words:"true"`
+
 	Twilio       TwilioProviderConfiguration       `json:"twilio"`
 	TwilioVerify TwilioVerifyProviderConfiguration `json:"twilio_verify" split_words:"true"`
 	Messagebird  MessagebirdProviderConfiguration  `json:"messagebird"`
 	Textlocal    TextlocalProviderConfiguration    `json:"textlocal"`
 	Vonage       VonageProviderConfiguration       `json:"vonage"`
+	SNS          SNSProviderConfiguration          `json:"sns"`
+}
+
+// GetTemplate returns the message template for the delivery channel.
+func (c *SmsProviderConfiguration) GetTemplate(channel string) *template.Template {
+	if channel == "whatsapp" && c.WhatsappMessageTemplate != nil {
+		return c.WhatsappMessageTemplate
+	}
+
+	return c.SMSTemplate
 }
 
 func (c *SmsProviderConfiguration) GetTestOTP(phone string, now time.Time) (string, bool) {
@@ -669,10 +1869,48 @@ type VonageProviderConfiguration struct {
 	From      string `json:"from" split_words:"true"`
 }
 
+type SNSProviderConfiguration struct {
+	AccessKeyID     string `json:"access_key_id" split_words:"true"`
+	SecretAccessKey string `json:"-" split_words:"true"`
+	SessionToken    string `json:"-" split_words:"true"`
+	Region          string `json:"region"`
+	SenderID        string `json:"sender_id" split_words:"true"`
+	SMSType         string `json:"sms_type" split_words:"true" default:"Transactional"`
+}
+
+// Endpoints that can be protected with a captcha.
+const (
+	CaptchaEndpointSignup    = "signup"
+	CaptchaEndpointRecover   = "recover"
+	CaptchaEndpointResend    = "resend"
+	CaptchaEndpointMagicLink = "magiclink"
+	CaptchaEndpointOTP       = "otp"
+	CaptchaEndpointToken     = "token"
+	CaptchaEndpointSSO       = "sso"
+)
+
+var captchaEndpoints = []string{
+	CaptchaEndpointSignup,
+	CaptchaEndpointRecover,
+	CaptchaEndpointResend,
+	CaptchaEndpointMagicLink,
+	CaptchaEndpointOTP,
+	CaptchaEndpointToken,
+	CaptchaEndpointSSO,
+}
+
 type CaptchaConfiguration struct {
 	Enabled  bool   `json:"enabled" default:"false"`
 	Provider string `json:"provider" default:"hcaptcha"`
 	Secret   string `json:"provider_secret"`
+
+	// MinScore is the lowest reCAPTCHA v3 score, from 0.0 to 1.0, that is
+	// accepted.
+	MinScore float64 `json:"min_score" split_words:"true" default:"0.5"`
+
+	// Endpoints limits captcha protection to the listed endpoints. All
+	// endpoints are protected when empty.
+	Endpoints []string `json:"endpoints"`
 }
 
 func (c *CaptchaConfiguration) Validate() error {
@@ -680,7 +1918,7 @@ func (c *CaptchaConfiguration) Validate() error {
 		return nil
 	}
 
-	if c.Provider != "hcaptcha" && c.Provider != "turnstile" {
+	if c.Provider != "hcaptcha" && c.Provider != "turnstile" && c.Provider != "recaptcha" {
 		return fmt.Errorf("unsupported captcha provider: %s", c.Provider)
 	}
 
@@ -690,9 +1928,28 @@ func (c *CaptchaConfiguration) Validate() error {
 		return errors.New("captcha provider secret is empty")
 	}
 
+	if c.MinScore < 0 || c.MinScore > 1 {
+		return errors.New("captcha min score must be between 0 and 1")
+	}
+
+	for _, endpoint := range c.Endpoints {
+		if !slices.Contains(captchaEndpoints, endpoint) {
+			return fmt.Errorf("unsupported captcha endpoint: %s", endpoint)
+		}
+	}
+
 	return nil
 }
 
+// IsEnabledFor reports whether captcha protection applies to the endpoint.
+func (c *CaptchaConfiguration) IsEnabledFor(endpoint string) bool {
+	if !c.Enabled {
+		return false
+	}
+
+	return len(c.Endpoints) == 0 || slices.Contains(c.Endpoints, endpoint)
+}
+
 // DatabaseEncryptionConfiguration configures Auth to encrypt certain columns.
 // Once Encrypt is set to true, data will start getting encrypted with the
 // provided encryption key. Setting it to false just stops encryption from
@@ -748,10 +2005,44 @@ type SecurityConfiguration struct {
 	RefreshTokenReuseInterval             int                  `json:"refresh_token_reuse_interval" split_words:"true"`
 	RefreshTokenAllowReuse                bool                 `json:"refresh_token_allow_reuse" split_words:"true"`
 	UpdatePasswordRequireReauthentication bool                 `json:"update_password_require_reauthentication" split_words:"true"`
+	UpdateEmailRequireReauthentication    bool                 `json:"update_email_require_reauthentication" split_words:"true"`
+	ReauthenticationMaxSessionAge         time.Duration        `json:"reauthentication_max_session_age" split_words:"true" default:"24h"`
 	ManualLinkingEnabled                  bool                 `json:"manual_linking_enabled" split_words:"true" default:"false"`
 	SbForwardedForEnabled                 bool                 `json:"sb_forwarded_for_enabled" split_words:"true" default:"false"`
 
 	DBEncryption DatabaseEncryptionConfiguration `json:"database_encryption" split_words:"true"`
+	Lockout      LockoutConfiguration            `json:"lockout"`
+}
+
+// LockoutConfiguration configures the temporary lockout of users after
+// repeated failed password sign-in attempts.
+type LockoutConfiguration struct {
+	Enabled bool `json:"enabled" default:"false"`
+
+	// MaxAttempts is the number of consecutive failed attempts after which
+	// the user is locked.
+	MaxAttempts int `json:"max_attempts" split_words:"true" default:"5"`
+
+	// Duration is how long the user is locked after reaching MaxAttempts.
+	// It doubles with every further failed attempt, up to MaxDuration.
+	Duration    time.Duration `json:"duration" default:"5m"`
+	MaxDuration time.Duration `json:"max_duration" split_words:"true" default:"24h"`
+}
+
+func (c *LockoutConfiguration) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+
+	if c.MaxAttempts < 1 {
+		return errors.New("conf: lockout max attempts must be at least 1")
+	}
+
+	if c.Duration <= 0 || c.MaxDuration < c.Duration {
+		return errors.New("conf: lockout duration must be positive and not exceed the lockout max duration")
+	}
+
+	return nil
 }
 
 func (c *SecurityConfiguration) Validate() error {
@@ -759,6 +2050,14 @@ func (c *SecurityConfiguration) Validate() error {
 		return err
 	}
 
+	if err := c.Lockout.Validate(); err != nil {
+		return err
+	}
+
+	if c.ReauthenticationMaxSessionAge < 0 {
+		return errors.New("conf: reauthentication max session age must not be negative")
+	}
+
 	if err := c.DBEncryption.Validate(); err != nil {
 		return err
 	}
@@ -789,7 +2088,11 @@ type HookConfiguration struct {
 	SendSMS                     ExtensibilityPointConfiguration `json:"send_sms" split_words:"true"`
 
 	BeforeUserCreated ExtensibilityPointConfiguration `json:"before_user_created" split_words:"true"`
+	BeforeSignIn      ExtensibilityPointConfiguration `json:"before_sign_in" split_words:"true"`
 	AfterUserCreated  ExtensibilityPointConfiguration `json:"after_user_created" split_words:"true"`
+	AfterUserDeleted  ExtensibilityPointConfiguration `json:"after_user_deleted" split_words:"true"`
+
+	OAuthConsent ExtensibilityPointConfiguration `json:"oauth_consent" envconfig:"OAUTH_CONSENT"`
 }
 
 type HTTPHookSecrets []string
@@ -822,7 +2125,10 @@ func (h *HookConfiguration) Validate() error {
 		h.SendSMS,
 		h.SendEmail,
 		h.BeforeUserCreated,
+		h.BeforeSignIn,
 		h.AfterUserCreated,
+		h.AfterUserDeleted,
+		h.OAuthConsent,
 	}
 	for _, point := range points {
 		if err := point.ValidateExtensibilityPoint(); err != nil {
@@ -1001,6 +2307,10 @@ func loadGlobal(config *GlobalConfiguration) error {
 		return err
 	}
 
+	if err := resolveSecrets(config); err != nil {
+		return err
+	}
+
 	if err := config.ApplyDefaults(); err != nil {
 		return err
 	}
@@ -1047,12 +2357,30 @@ func populateGlobal(config *GlobalConfiguration) error {
 		}
 	}
 
+	if config.Hook.BeforeSignIn.Enabled {
+		if err := config.Hook.BeforeSignIn.PopulateExtensibilityPoint(); err != nil {
+			return err
+		}
+	}
+
 	if config.Hook.AfterUserCreated.Enabled {
 		if err := config.Hook.AfterUserCreated.PopulateExtensibilityPoint(); err != nil {
 			return err
 		}
 	}
 
+	if config.Hook.AfterUserDeleted.Enabled {
+		if err := config.Hook.AfterUserDeleted.PopulateExtensibilityPoint(); err != nil {
+			return err
+		}
+	}
+
+	if config.Hook.OAuthConsent.Enabled {
+		if err := config.Hook.OAuthConsent.PopulateExtensibilityPoint(); err != nil {
+			return err
+		}
+	}
+
 	if config.SAML.Enabled {
 		if err := config.SAML.PopulateFields(config.API.ExternalURL); err != nil {
 			return err
@@ -1066,6 +2394,15 @@ func populateGlobal(config *GlobalConfiguration) error {
 		if SMSTemplate == "" {
 			SMSTemplate = "Your code is {{ .Code }}"
 		}
+		config.Sms.WhatsappMessageTemplate = nil
+		if config.Sms.WhatsappTemplate != "" {
+			whatsappTemplate, err := template.New("").Parse(config.Sms.WhatsappTemplate)
+			if err != nil {
+				return err
+			}
+			config.Sms.WhatsappMessageTemplate = whatsappTemplate
+		}
+
 		template, err := template.New("").Parse(SMSTemplate)
 		if err != nil {
 			return err
@@ -1102,6 +2439,14 @@ func (config *GlobalConfiguration) ApplyDefaults() error {
 		config.JWT.Exp = 3600
 	}
 
+	if config.Health.Timeout == 0 {
+		config.Health.Timeout = 2 * time.Second
+	}
+
+	if config.API.ShutdownTimeout == 0 {
+		config.API.ShutdownTimeout = time.Minute
+	}
+
 	if len(config.JWT.Keys) == 0 {
 		// transform the secret into a JWK for consistency
 		if err := config.applyDefaultsJWT([]byte(config.JWT.Secret)); err != nil {
@@ -1142,6 +2487,10 @@ func (config *GlobalConfiguration) ApplyDefaults() error {
 		config.Mailer.OtpExp = 86400 // 1 day
 	}
 
+	if config.Mailer.InviteExp == 0 {
+		config.Mailer.InviteExp = config.Mailer.OtpExp
+	}
+
 	if config.Mailer.OtpLength == 0 || config.Mailer.OtpLength < 6 || config.Mailer.OtpLength > 10 {
 		// 6-digit otp by default
 		config.Mailer.OtpLength = 6
@@ -1193,6 +2542,10 @@ func (config *GlobalConfiguration) ApplyDefaults() error {
 		config.Password.MinLength = defaultMinPasswordLength
 	}
 
+	if config.Password.Hashing.Algorithm == "" {
+		config.Password.Hashing.Algorithm = PasswordHashAlgorithmBcrypt
+	}
+
 	if config.MFA.ChallengeExpiryDuration < defaultChallengeExpiryDuration {
 		config.MFA.ChallengeExpiryDuration = defaultChallengeExpiryDuration
 	}
@@ -1210,6 +2563,14 @@ func (config *GlobalConfiguration) ApplyDefaults() error {
 		config.MFA.Phone.OtpLength = 6
 	}
 
+	if config.MFA.RecoveryCodes.Count < 1 || config.MFA.RecoveryCodes.Count > 50 {
+		config.MFA.RecoveryCodes.Count = 10
+	}
+
+	if config.MFA.TrustedDevices.Duration <= 0 {
+		config.MFA.TrustedDevices.Duration = 30 * 24 * time.Hour
+	}
+
 	if config.External.FlowStateExpiryDuration < defaultFlowStateExpiryDuration {
 		config.External.FlowStateExpiryDuration = defaultFlowStateExpiryDuration
 	}
@@ -1277,7 +2638,32 @@ func (c *GlobalConfiguration) Validate() error {
 		&c.Security,
 		&c.Sessions,
 		&c.Hook,
+		&c.MFA.WebAuthn,
+		&c.External.GenericOIDC1,
+		&c.External.GenericOIDC2,
+		&c.External.GenericOIDC3,
+		&c.External.Keycloak,
+		&c.External.Custom,
+		&c.External.Connections,
+		&c.External.ProviderTokens,
+		&c.JWT,
 		&c.JWT.Keys,
+		&c.SCIM,
+		&c.GRPC,
+		&c.Tenancy,
+		&c.Impersonation,
+		&c.Password,
+		&c.RateLimitStore,
+		&c.AuditLog,
+		&c.UserDeletion,
+		&c.UserExport,
+		&c.Webhooks,
+		&c.TokenExchange,
+		&c.GeoIP,
+		&c.Risk,
+		&c.Reaper,
+		&c.Cache,
+		&c.Health,
 	}
 
 	for _, validatable := range validatables {
@@ -1286,6 +2672,22 @@ func (c *GlobalConfiguration) Validate() error {
 		}
 	}
 
+	if c.Tenancy.Enabled && len(c.OperatorToken) < 32 {
+		return errors.New("conf: tenancy requires an operator token of at least 32 characters")
+	}
+
+	if c.External.ProviderTokens.Enabled && !c.Security.DBEncryption.Encrypt {
+		return errors.New("conf: provider token storage requires database encryption to be enabled")
+	}
+
+	if c.TokenExchange.Enabled {
+		for _, audience := range c.TokenExchange.Audiences {
+			if _, ok := c.JWT.Keys[audience.SigningKey.KeyID()]; ok {
+				return fmt.Errorf("conf: token exchange audience %q must not use one of the JWT keys", audience.Audience)
+			}
+		}
+	}
+
 	return nil
 }
 
@@ -1364,6 +2766,22 @@ func (t *VonageProviderConfiguration) Validate() error {
 	return nil
 }
 
+func (t *SNSProviderConfiguration) Validate() error {
+	if t.AccessKeyID == "" {
+		return errors.New("missing AWS SNS access key ID")
+	}
+	if t.SecretAccessKey == "" {
+		return errors.New("missing AWS SNS secret access key")
+	}
+	if t.Region == "" {
+		return errors.New("missing AWS SNS region")
+	}
+	if t.SMSType != "" && t.SMSType != "Transactional" && t.SMSType != "Promotional" {
+		return fmt.Errorf("unsupported AWS SNS SMS type %q", t.SMSType)
+	}
+	return nil
+}
+
 func (t *SmsProviderConfiguration) IsTwilioVerifyProvider() bool {
 	return t.Provider == "twilio_verify"
 }
@@ -59,6 +59,21 @@ func TestGlobal(t *testing.T) {
 		assert.Equal(t, time.Hour, gc.RateLimitEmailSent.OverTime)
 	}
 
+	{
+		os.Setenv("GOTRUE_RATE_LIMIT_STORE_RULES", "signup:ip=30/1h,token:email=10/5m")
+
+		gc, err := LoadGlobal("")
+		require.NoError(t, err)
+		assert.Equal(t, RateLimitStoreMemory, gc.RateLimitStore.Type)
+		assert.Equal(t, []RateLimitRule{
+			{Endpoint: "signup", Key: RateLimitKeyIP, Rate: Rate{Events: 30, OverTime: time.Hour, typ: BurstRateType}},
+			{Endpoint: "token", Key: RateLimitKeyEmail, Rate: Rate{Events: 10, OverTime: 5 * time.Minute, typ: BurstRateType}},
+		}, gc.RateLimitStore.Rules)
+		assert.Len(t, gc.RateLimitStore.RulesFor("token"), 1)
+
+		os.Unsetenv("GOTRUE_RATE_LIMIT_STORE_RULES")
+	}
+
 	{
 		gc, err := LoadGlobal("")
 		require.NoError(t, err)
@@ -246,6 +261,30 @@ func TestGlobal(t *testing.T) {
 		require.Error(t, err)
 	}
 
+	{
+		cfg := new(GlobalConfiguration)
+		cfg.Sms.Provider = "twilio"
+		cfg.Sms.WhatsappTemplate = "{{{{{{{{{}}}}}}}}}"
+
+		err := populateGlobal(cfg)
+		require.Error(t, err)
+	}
+
+	{
+		cfg := new(GlobalConfiguration)
+		cfg.Sms.Provider = "twilio"
+		cfg.Sms.WhatsappTemplate = "Your WhatsApp code is {{ .Code }}"
+
+		err := populateGlobal(cfg)
+		require.NoError(t, err)
+		require.Equal(t, cfg.Sms.SMSTemplate, cfg.Sms.GetTemplate("sms"))
+		require.Equal(t, cfg.Sms.WhatsappMessageTemplate, cfg.Sms.GetTemplate("whatsapp"))
+
+		cfg.Sms.WhatsappTemplate = ""
+		require.NoError(t, populateGlobal(cfg))
+		require.Equal(t, cfg.Sms.SMSTemplate, cfg.Sms.GetTemplate("whatsapp"))
+	}
+
 	{
 		cfg := new(GlobalConfiguration)
 		cfg.MFA.Phone.EnrollEnabled = true
@@ -542,6 +581,31 @@ func TestValidate(t *testing.T) {
 			err: `parse "invalid": invalid URI for request`,
 		},
 
+		{
+			val: &MailerConfiguration{Notifications: NotificationsConfiguration{
+				NewSignInEnabled:    true,
+				NewSignInLinkSecret: "new-sign-in-link-secret-of-32-chars",
+			}},
+		},
+		{
+			val: &MailerConfiguration{Notifications: NotificationsConfiguration{
+				NewSignInEnabled: true,
+			}},
+			err: `conf: new sign-in notification link secret must be at least 32 characters long`,
+		},
+
+		{
+			val: &UserExportConfiguration{
+				Enabled:    true,
+				Expiry:     time.Hour,
+				LinkSecret: "user-export-link-secret-of-32-chars",
+			},
+		},
+		{
+			val: &UserExportConfiguration{Enabled: true, Expiry: time.Hour},
+			err: `conf: user export link secret must be at least 32 characters long`,
+		},
+
 		{
 			val: &SessionsConfiguration{Timebox: nil},
 		},
@@ -574,6 +638,29 @@ func TestValidate(t *testing.T) {
 		{
 			val: &SessionsConfiguration{Timebox: toPtr(time.Duration(1))},
 		},
+		{
+			val: &SessionsConfiguration{RoleTimebox: map[string]time.Duration{"admin": 0}},
+			err: `conf: session timebox duration of role "admin" must be positive, was 0s`,
+		},
+		{
+			val: &SessionsConfiguration{RoleInactivityTimeout: map[string]time.Duration{"admin": -time.Minute}},
+			err: `conf: session inactivity timeout duration of role "admin" must be positive, was -1m0s`,
+		},
+		{
+			val: &SessionsConfiguration{
+				Timebox:            toPtr(24 * time.Hour),
+				RoleTimebox:        map[string]time.Duration{"admin": 12 * time.Hour},
+				SinglePerUserRoles: []string{"admin"},
+			},
+			check: func(t *testing.T, v any) {
+				c := v.(*SessionsConfiguration)
+				require.Equal(t, 12*time.Hour, *c.TimeboxFor("admin"))
+				require.Equal(t, 24*time.Hour, *c.TimeboxFor("authenticated"))
+				require.Nil(t, c.InactivityTimeoutFor("admin"))
+				require.True(t, c.IsSinglePerUser("admin"))
+				require.False(t, c.IsSinglePerUser("authenticated"))
+			},
+		},
 
 		{
 			val: &SMTPConfiguration{},
@@ -613,6 +700,187 @@ func TestValidate(t *testing.T) {
 				require.True(t, got["foo.com"])
 			},
 		},
+		{
+			val: &MailerConfiguration{Queue: MailQueueConfiguration{Enabled: true}},
+			err: `conf: mailer queue max attempts must be at least 1`,
+		},
+
+		{
+			val: &MailerConfiguration{Transport: "smtp"},
+		},
+		{
+			val: &MailerConfiguration{Transport: "sparkpost"},
+			err: `conf: mailer transport "sparkpost" is not supported`,
+		},
+		{
+			val: &MailerConfiguration{Transport: "ses", SES: SESTransportConfiguration{AccessKeyID: "id", SecretAccessKey: "secret"}},
+			err: `conf: missing AWS SES region`,
+		},
+		{
+			val: &MailerConfiguration{Transport: "ses", SES: SESTransportConfiguration{AccessKeyID: "id", SecretAccessKey: "secret", Region: "eu-west-1"}},
+		},
+		{
+			val: &MailerConfiguration{Transport: "sendgrid"},
+			err: `conf: missing SendGrid API key`,
+		},
+		{
+			val: &MailerConfiguration{Transport: "mailgun", Mailgun: MailgunTransportConfiguration{APIKey: "key"}},
+			err: `conf: missing Mailgun domain`,
+		},
+		{
+			val: &MailerConfiguration{Transport: "mailgun", Mailgun: MailgunTransportConfiguration{APIKey: "key", Domain: "mg.example.com", Region: "ap"}},
+			err: `conf: unsupported Mailgun region "ap"`,
+		},
+		{
+			val: &MailerConfiguration{Transport: "postmark", Postmark: PostmarkTransportConfiguration{ServerToken: "token"}},
+		},
+
+		{
+			val: &MailQueueConfiguration{},
+		},
+		{
+			val: &MailQueueConfiguration{
+				Enabled:          true,
+				MaxAttempts:      4,
+				RetryInterval:    time.Minute,
+				MaxRetryInterval: 3 * time.Minute,
+				PollInterval:     time.Second,
+				BatchSize:        10,
+			},
+			check: func(t *testing.T, v any) {
+				c := v.(*MailQueueConfiguration)
+				now := time.Now()
+				require.Equal(t, now.Add(time.Minute), *c.RetryAt(1, now))
+				require.Equal(t, now.Add(2*time.Minute), *c.RetryAt(2, now))
+				require.Equal(t, now.Add(3*time.Minute), *c.RetryAt(3, now))
+				require.Nil(t, c.RetryAt(4, now))
+			},
+		},
+		{
+			val: &MailQueueConfiguration{
+				Enabled:          true,
+				MaxAttempts:      5,
+				RetryInterval:    time.Hour,
+				MaxRetryInterval: time.Minute,
+				PollInterval:     time.Second,
+				BatchSize:        10,
+			},
+			err: `conf: mailer queue retry interval must be positive and at most the max retry interval`,
+		},
+		{
+			val: &MailQueueConfiguration{
+				Enabled:          true,
+				MaxAttempts:      5,
+				RetryInterval:    time.Minute,
+				MaxRetryInterval: time.Hour,
+				BatchSize:        10,
+			},
+			err: `conf: mailer queue poll interval must be positive`,
+		},
+		{
+			val: &MailQueueConfiguration{
+				Enabled:          true,
+				MaxAttempts:      5,
+				RetryInterval:    time.Minute,
+				MaxRetryInterval: time.Hour,
+				PollInterval:     time.Second,
+			},
+			err: `conf: mailer queue batch size must be at least 1`,
+		},
+
+		{
+			val: &TracingConfig{SampleRatio: 0.25},
+		},
+		{
+			val: &TracingConfig{SampleRatio: 2},
+			err: `conf: tracing sample ratio must be between 0 and 1`,
+		},
+		{
+			val: &MetricsConfig{Exporter: Prometheus, EndpointEnabled: true, EndpointUsername: "metrics", EndpointPassword: "secret"},
+		},
+		{
+			val: &MetricsConfig{Exporter: OpenTelemetryMetrics, EndpointEnabled: true},
+			err: `conf: the metrics endpoint is only available with the prometheus exporter`,
+		},
+		{
+			val: &MetricsConfig{Exporter: Prometheus, EndpointEnabled: true, EndpointUsername: "metrics"},
+			err: `conf: both metrics endpoint username and password must be set`,
+		},
+		{
+			val: &AuditLogConfiguration{},
+		},
+		{
+			val: &AuditLogConfiguration{Retention: AuditLogRetentionConfiguration{Enabled: true, Interval: time.Hour, BatchSize: 100}},
+			err: `conf: audit log retention max age must be positive`,
+		},
+		{
+			val: &AuditLogRetentionConfiguration{Enabled: true, MaxAge: time.Hour, Interval: time.Hour},
+			err: `conf: audit log retention batch size must be at least 1`,
+		},
+		{
+			val: &AuditLogRetentionConfiguration{
+				Enabled:   true,
+				MaxAge:    90 * 24 * time.Hour,
+				Interval:  time.Hour,
+				BatchSize: 1000,
+				Archive:   AuditLogArchiveConfiguration{Provider: "azure"},
+			},
+			err: `conf: audit log archive provider "azure" is not supported`,
+		},
+		{
+			val: &AuditLogArchiveConfiguration{Provider: "s3", Bucket: "audit", AccessKeyID: "id", SecretAccessKey: "secret"},
+			err: `conf: audit log archive to s3 requires a region or an endpoint`,
+		},
+		{
+			val: &AuditLogArchiveConfiguration{Provider: "s3", Region: "eu-west-1", AccessKeyID: "id", SecretAccessKey: "secret"},
+			err: `conf: missing audit log archive bucket`,
+		},
+		{
+			val: &AuditLogArchiveConfiguration{Provider: "gcs", Bucket: "audit"},
+			err: `conf: missing audit log archive access key`,
+		},
+		{
+			val: &AuditLogArchiveConfiguration{Provider: "gcs", Bucket: "audit", AccessKeyID: "id", SecretAccessKey: "secret"},
+		},
+		{
+			val: &ProviderTokensConfiguration{},
+		},
+		{
+			val: &ProviderTokensConfiguration{Enabled: true, RefreshInterval: time.Minute},
+			err: `conf: provider tokens batch size must be at least 1`,
+		},
+		{
+			val: &ProviderTokensConfiguration{Enabled: true, RefreshInterval: time.Minute, RefreshBefore: 5 * time.Minute, BatchSize: 100},
+		},
+		{
+			val: &AuditLogSinksConfiguration{},
+		},
+		{
+			val: &AuditLogSinksConfiguration{
+				BufferSize: 10, BatchSize: 100, FlushInterval: time.Second, MaxAttempts: 5, RetryInterval: time.Second,
+				Syslog: AuditLogSyslogConfiguration{Enabled: true, Network: "udp", Address: "localhost:514"},
+			},
+			err: `conf: audit log sinks batch size must be at least 1 and at most the buffer size`,
+		},
+		{
+			val: &AuditLogSyslogConfiguration{Enabled: true, Network: "unix", Address: "/dev/log"},
+			err: `conf: audit log syslog network "unix" is not supported`,
+		},
+		{
+			val: &AuditLogSyslogConfiguration{Enabled: true, Network: "tls", Address: "siem.example.com:6514", Facility: 24},
+			err: `conf: audit log syslog facility must be between 0 and 23`,
+		},
+		{
+			val: &AuditLogKafkaConfiguration{Enabled: true, URL: "http://kafka-rest:8082"},
+			err: `conf: missing audit log kafka topic`,
+		},
+		{
+			val: &AuditLogWebhookConfiguration{Enabled: true, URL: "https://siem.example.com/auth", Secrets: HTTPHookSecrets{"v1a,whpk_key"}},
+			err: `conf: audit log webhook secrets must be symmetric v1,whsec_ secrets`,
+		},
+		{
+			val: &AuditLogWebhookConfiguration{Enabled: true, URL: "https://siem.example.com/auth", Secrets: HTTPHookSecrets{"v1,whsec_aWxpa2V0b2VhdGx1bmNoYXRkb3JzZXRnb2JsaW5z"}},
+		},
 
 		{
 			val: &CaptchaConfiguration{Enabled: false},
@@ -636,6 +904,38 @@ func TestValidate(t *testing.T) {
 				Secret:   "abc",
 			},
 		},
+		{
+			val: &CaptchaConfiguration{
+				Enabled:   true,
+				Provider:  "recaptcha",
+				Secret:    "abc",
+				MinScore:  0.7,
+				Endpoints: []string{"signup", "recover"},
+			},
+			check: func(t *testing.T, v any) {
+				c := v.(*CaptchaConfiguration)
+				require.True(t, c.IsEnabledFor(CaptchaEndpointSignup))
+				require.False(t, c.IsEnabledFor(CaptchaEndpointToken))
+			},
+		},
+		{
+			val: &CaptchaConfiguration{
+				Enabled:  true,
+				Provider: "recaptcha",
+				Secret:   "abc",
+				MinScore: 1.5,
+			},
+			err: "captcha min score must be between 0 and 1",
+		},
+		{
+			val: &CaptchaConfiguration{
+				Enabled:   true,
+				Provider:  "turnstile",
+				Secret:    "abc",
+				Endpoints: []string{"verify"},
+			},
+			err: "unsupported captcha endpoint: verify",
+		},
 
 		{
 			val: &DatabaseEncryptionConfiguration{Encrypt: false},
@@ -714,6 +1014,211 @@ func TestValidate(t *testing.T) {
 			},
 		},
 
+		{
+			val: &GenericOAuthProviderConfiguration{},
+		},
+		{
+			val: &GenericOAuthProviderConfiguration{
+				UserDataExpressions: `{"Name": "join(' ', [given_name, family_name])", "Email": "emails[0].value"}`,
+			},
+			check: func(t *testing.T, v any) {
+				got := (v.(*GenericOAuthProviderConfiguration)).GetUserDataExpressions()
+				require.Equal(t, "emails[0].value", got["Email"])
+			},
+		},
+		{
+			val: &GenericOAuthProviderConfiguration{UserDataExpressions: "invalid"},
+			err: `conf: user data expressions not a map[string]string format:`,
+		},
+		{
+			val: &GenericOAuthProviderConfiguration{UserDataExpressions: `{"Name": "join(' ', ["}`},
+			err: `conf: invalid user data expression for "Name":`,
+		},
+
+		{
+			val: &KeycloakProviderConfiguration{},
+		},
+		{
+			val: &KeycloakProviderConfiguration{RolesExpression: "realm_access.roles"},
+		},
+		{
+			val: &KeycloakProviderConfiguration{RolesExpression: "realm_access.["},
+			err: `conf: invalid keycloak roles expression:`,
+		},
+
+		{
+			val: &WebAuthnFactorTypeConfiguration{},
+		},
+		{
+			val: &WebAuthnFactorTypeConfiguration{
+				RPID:                  "example.com",
+				RPOrigins:             []string{"https://example.com"},
+				AttestationPreference: "direct",
+				UserVerification:      "required",
+			},
+		},
+		{
+			val: &WebAuthnFactorTypeConfiguration{AttestationPreference: "always"},
+			err: `conf: unsupported WebAuthn attestation preference "always"`,
+		},
+		{
+			val: &WebAuthnFactorTypeConfiguration{UserVerification: "sometimes"},
+			err: `conf: unsupported WebAuthn user verification requirement "sometimes"`,
+		},
+		{
+			val: &WebAuthnFactorTypeConfiguration{RPID: "example.com"},
+			err: `conf: WebAuthn RP origins must be set when RP ID is configured`,
+		},
+		{
+			val: &WebAuthnFactorTypeConfiguration{
+				RPID:      "example.com",
+				RPOrigins: []string{"example.com"},
+			},
+			err: `conf: invalid WebAuthn RP origin "example.com"`,
+		},
+
+		{
+			val: &SCIMConfiguration{},
+		},
+		{
+			val: &SCIMConfiguration{
+				Enabled: true,
+				Tokens:  []string{strings.Repeat("a", 32)},
+			},
+		},
+		{
+			val: &SCIMConfiguration{Enabled: true},
+			err: `conf: SCIM is enabled but no SCIM bearer tokens are configured`,
+		},
+		{
+			val: &SCIMConfiguration{
+				Enabled: true,
+				Tokens:  []string{"short"},
+			},
+			err: `conf: SCIM bearer tokens must be at least 32 characters long`,
+		},
+
+		{
+			val: &GRPCConfiguration{Enabled: true},
+		},
+		{
+			val: &GRPCConfiguration{Enabled: true, TLSCertFile: "server.crt", TLSKeyFile: "server.key", TLSClientCAFile: "ca.crt"},
+		},
+		{
+			val: &GRPCConfiguration{Enabled: true, TLSCertFile: "server.crt"},
+			err: `conf: both gRPC TLS certificate and key files must be set`,
+		},
+		{
+			val: &GRPCConfiguration{Enabled: true, TLSClientCAFile: "ca.crt"},
+			err: `conf: gRPC client certificate verification requires a TLS certificate and key`,
+		},
+
+		{
+			val: &TenancyConfiguration{},
+		},
+		{
+			val: &TenancyConfiguration{Enabled: true, Header: "X-Tenant-ID", CacheTTL: time.Minute},
+		},
+		{
+			val: &TenancyConfiguration{Enabled: true, CacheTTL: time.Minute},
+			err: `conf: tenancy header must not be empty`,
+		},
+		{
+			val: &TenancyConfiguration{Enabled: true, Header: "X-Tenant-ID"},
+			err: `conf: tenancy cache TTL must be a positive duration`,
+		},
+
+		{
+			val: &LockoutConfiguration{Enabled: true, MaxAttempts: 5, Duration: time.Minute, MaxDuration: time.Hour},
+		},
+		{
+			val: &LockoutConfiguration{Enabled: true, MaxAttempts: 0, Duration: time.Minute, MaxDuration: time.Hour},
+			err: `conf: lockout max attempts must be at least 1`,
+		},
+		{
+			val: &LockoutConfiguration{Enabled: true, MaxAttempts: 5, Duration: time.Hour, MaxDuration: time.Minute},
+			err: `conf: lockout duration must be positive and not exceed the lockout max duration`,
+		},
+		{
+			val: &JWTConfiguration{ExcludeClaims: []string{"email", "phone"}, AppMetadataClaim: "https://example.com/app_metadata"},
+		},
+		{
+			val: &JWTConfiguration{ExcludeClaims: []string{"session_id"}},
+			err: `conf: JWT claim "session_id" cannot be excluded, only email, phone, app_metadata, user_metadata, amr, is_anonymous`,
+		},
+		{
+			val: &JWTConfiguration{UserMetadataClaim: "app_metadata"},
+			err: `conf: JWT metadata claim cannot be renamed to the reserved claim "app_metadata"`,
+		},
+		{
+			val: &JWTConfiguration{AppMetadataClaim: "metadata", UserMetadataClaim: "metadata"},
+			err: `conf: JWT app and user metadata claims must be different`,
+		},
+		{
+			val: &SessionCookieConfiguration{Enabled: true, Name: "sb-refresh-token", SameSite: "None", MaxAge: time.Hour},
+		},
+		{
+			val: &SessionCookieConfiguration{Enabled: true, Name: "sb-refresh-token", SameSite: "always", MaxAge: time.Hour},
+			err: `conf: session cookie same site must be lax, strict or none, was "always"`,
+		},
+		{
+			val: &SessionCookieConfiguration{Enabled: true, Name: "sb-refresh-token", SameSite: "lax"},
+			err: `conf: session cookie max age must be positive`,
+		},
+		{
+			val: &SecurityConfiguration{ReauthenticationMaxSessionAge: -time.Hour},
+			err: `conf: reauthentication max session age must not be negative`,
+		},
+
+		{
+			val: &RateLimitStoreConfiguration{Type: RateLimitStoreMemory},
+		},
+		{
+			val: &RateLimitStoreConfiguration{Type: RateLimitStoreRedis, RedisURL: "rediss://:secret@redis.example.com:6380/1"},
+		},
+		{
+			val: &RateLimitStoreConfiguration{Type: RateLimitStoreRedis},
+			err: `conf: rate limit store redis URL is required when the redis store is used`,
+		},
+		{
+			val: &RateLimitStoreConfiguration{Type: RateLimitStoreRedis, RedisURL: "http://redis.example.com"},
+			err: `conf: rate limit store redis URL must be in the form`,
+		},
+		{
+			val: &RateLimitStoreConfiguration{Type: "memcached"},
+			err: `conf: rate limit store type "memcached" is not supported`,
+		},
+
+		{
+			val: &PasswordConfiguration{MinScore: 3},
+		},
+		{
+			val: &PasswordConfiguration{MinScore: 5},
+			err: `conf: password min score must be between 0 and 4`,
+		},
+		{
+			val: &PasswordConfiguration{MinScore: -1},
+			err: `conf: password min score must be between 0 and 4`,
+		},
+		{
+			val: &PasswordHashingConfiguration{Algorithm: "bcrypt"},
+		},
+		{
+			val: &PasswordHashingConfiguration{Algorithm: "argon2id", Argon2Memory: 19456, Argon2Iterations: 2, Argon2Parallelism: 1},
+		},
+		{
+			val: &PasswordHashingConfiguration{Algorithm: "scrypt"},
+			err: `conf: password hashing algorithm "scrypt" is not supported`,
+		},
+		{
+			val: &PasswordHashingConfiguration{Algorithm: "argon2id", Argon2Memory: 19456, Argon2Parallelism: 1},
+			err: `conf: password hashing argon2 iterations must be at least 1`,
+		},
+		{
+			val: &PasswordHashingConfiguration{Algorithm: "argon2id", Argon2Memory: 8, Argon2Iterations: 2, Argon2Parallelism: 2},
+			err: `conf: password hashing argon2 memory must be at least 8 KiB per degree of parallelism`,
+		},
+
 		{
 			val: &SecurityConfiguration{
 				Captcha: CaptchaConfiguration{
@@ -0,0 +1,125 @@
+package conf
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// GenericOIDCConnectionType is the type of the connections to generic OpenID
+// Connect providers, configured like GOTRUE_EXTERNAL_GENERIC_OIDC_1.
+const GenericOIDCConnectionType = "generic_oidc"
+
+var connectionSlugPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// ExternalConnectionConfiguration configures a named connection to an OAuth
+// provider, so several providers of the same type can be enabled at once.
+// The connection is addressed by its slug instead of the type, which is
+// also the provider of the identities signed in with it. The generic OIDC
+// settings only apply to connections of the generic_oidc type.
+type ExternalConnectionConfiguration struct {
+	Slug string `json:"slug"`
+	Type string `json:"type"`
+
+	GenericOAuthProviderConfiguration
+}
+
+// ExternalConnections holds the connections of GOTRUE_EXTERNAL_CONNECTIONS,
+// which is a JSON array of connections.
+type ExternalConnections []ExternalConnectionConfiguration
+
+func (c *ExternalConnections) Decode(value string) error {
+	if value == "" {
+		return nil
+	}
+
+	var connections []ExternalConnectionConfiguration
+	if err := json.Unmarshal([]byte(value), &connections); err != nil {
+		return fmt.Errorf("conf: external connections not a JSON array of connections: %w", err)
+	}
+
+	for i := range connections {
+		if connections[i].OAuthProviderConfiguration == nil {
+			connections[i].OAuthProviderConfiguration = &OAuthProviderConfiguration{}
+		}
+	}
+
+	*c = connections
+	return nil
+}
+
+// Find returns the connection with the slug, or nil.
+func (c ExternalConnections) Find(slug string) *ExternalConnectionConfiguration {
+	for i := range c {
+		if c[i].Slug == slug {
+			return &c[i]
+		}
+	}
+	return nil
+}
+
+func (c ExternalConnections) Validate() error {
+	slugs := make(map[string]bool, len(c))
+
+	for i := range c {
+		conn := &c[i]
+
+		if !connectionSlugPattern.MatchString(conn.Slug) {
+			return fmt.Errorf("conf: external connection slug %q must be lowercase letters, digits, - and _", conn.Slug)
+		}
+		if slugs[conn.Slug] {
+			return fmt.Errorf("conf: duplicate external connection slug %q", conn.Slug)
+		}
+		slugs[conn.Slug] = true
+
+		if conn.Slug == GenericOIDCConnectionType || isProviderName(conn.Slug) {
+			return fmt.Errorf("conf: external connection slug %q is the name of a provider", conn.Slug)
+		}
+
+		if conn.OAuthProviderConfiguration == nil {
+			conn.OAuthProviderConfiguration = &OAuthProviderConfiguration{}
+		}
+
+		switch conn.Type {
+		case GenericOIDCConnectionType:
+			if err := conn.GenericOAuthProviderConfiguration.Validate(); err != nil {
+				return err
+			}
+		case "twitter", "steam":
+			// the callbacks of OAuth 1.0a and OpenID 2.0 are tied to the type
+			return fmt.Errorf("conf: external connection %q can not be of type %q", conn.Slug, conn.Type)
+		default:
+			if _, err := conn.ProviderConfiguration(); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// ProviderConfiguration returns a provider configuration with only the
+// connection's type configured, as the connection.
+func (c *ExternalConnectionConfiguration) ProviderConfiguration() (ProviderConfiguration, error) {
+	var providers ProviderConfiguration
+	if err := providers.override(map[string]OAuthProviderConfiguration{
+		c.Type: *c.OAuthProviderConfiguration,
+	}); err != nil {
+		return providers, fmt.Errorf("conf: external connection %q has unsupported type %q", c.Slug, c.Type)
+	}
+	return providers, nil
+}
+
+// isProviderName reports whether name is the JSON field name of one of the
+// providers.
+func isProviderName(name string) bool {
+	t := reflect.TypeOf(ProviderConfiguration{})
+	for i := 0; i < t.NumField(); i++ {
+		if strings.Split(t.Field(i).Tag.Get("json"), ",")[0] == name {
+			return true
+		}
+	}
+	return false
+}
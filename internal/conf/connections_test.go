@@ -0,0 +1,85 @@
+package conf
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestExternalConnections(t *testing.T) {
+	var connections ExternalConnections
+	require.NoError(t, connections.Decode(`[
+		{"slug": "gitlab-internal", "type": "gitlab", "enabled": true, "client_id": ["internal"], "secret": "secret", "url": "https://gitlab.example.com"},
+		{"slug": "acme", "type": "generic_oidc", "enabled": true, "client_id": ["acme"], "secret": "secret", "issuer": "https://idp.acme.com"}
+	]`))
+	require.Len(t, connections, 2)
+	require.NoError(t, connections.Validate())
+
+	conn := connections.Find("gitlab-internal")
+	require.NotNil(t, conn)
+	providers, err := conn.ProviderConfiguration()
+	require.NoError(t, err)
+	require.Equal(t, "https://gitlab.example.com", providers.Gitlab.URL)
+	require.Equal(t, []string{"internal"}, providers.Gitlab.ClientID)
+
+	conn = connections.Find("acme")
+	require.NotNil(t, conn)
+	require.Equal(t, "https://idp.acme.com", conn.Issuer)
+	require.Equal(t, []string{"acme"}, conn.ClientID)
+
+	require.Nil(t, connections.Find("gitlab"))
+
+	require.Error(t, connections.Decode(`{"slug": "acme"}`))
+
+	for _, value := range []string{
+		`[{"slug": "acme", "type": "gitlab"}, {"slug": "acme", "type": "github"}]`,
+		`[{"slug": "gitlab", "type": "gitlab"}]`,
+		`[{"slug": "generic_oidc_1", "type": "generic_oidc"}]`,
+		`[{"slug": "Acme", "type": "gitlab"}]`,
+		`[{"slug": "acme", "type": "unknown"}]`,
+		`[{"slug": "acme", "type": "twitter"}]`,
+		`[{"slug": "acme", "type": "keycloak"}]`,
+	} {
+		var invalid ExternalConnections
+		require.NoError(t, invalid.Decode(value))
+		require.Error(t, invalid.Validate(), value)
+	}
+}
+
+func TestOAuthProvider(t *testing.T) {
+	var providers ProviderConfiguration
+	providers.Google = OAuthProviderConfiguration{Enabled: true, AllowedEmailDomains: []string{"ourcompany.com"}}
+	providers.Azure.Enabled = true
+	providers.GenericOIDC1.OAuthProviderConfiguration = &OAuthProviderConfiguration{Enabled: true}
+	providers.Connections = ExternalConnections{{
+		Slug: "acme",
+		Type: GenericOIDCConnectionType,
+		GenericOAuthProviderConfiguration: GenericOAuthProviderConfiguration{
+			OAuthProviderConfiguration: &OAuthProviderConfiguration{Enabled: true, URL: "https://idp.acme.com"},
+		},
+	}}
+
+	google, ok := providers.OAuthProvider("google")
+	require.True(t, ok)
+	require.Equal(t, []string{"ourcompany.com"}, google.AllowedEmailDomains)
+
+	azure, ok := providers.OAuthProvider("azure")
+	require.True(t, ok)
+	require.True(t, azure.Enabled)
+
+	generic, ok := providers.OAuthProvider("generic_oidc_1")
+	require.True(t, ok)
+	require.True(t, generic.Enabled)
+
+	_, ok = providers.OAuthProvider("generic_oidc_2")
+	require.False(t, ok)
+
+	acme, ok := providers.OAuthProvider("acme")
+	require.True(t, ok)
+	require.Equal(t, "https://idp.acme.com", acme.URL)
+
+	for _, name := range []string{"unknown", "email", "telegram", "ios_bundle_id"} {
+		_, ok = providers.OAuthProvider(name)
+		require.False(t, ok, name)
+	}
+}
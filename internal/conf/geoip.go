@@ -0,0 +1,63 @@
+package conf
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// GeoIP providers.
+const (
+	GeoIPProviderMaxMind = "maxmind"
+	GeoIPProviderHeader  = "header"
+)
+
+// GeoIPConfiguration configures looking up the country and city of the IP
+// address sessions are created from. The maxmind provider queries the
+// MaxMind GeoIP2 or GeoLite2 City web service, the header provider reads the
+// location from headers set by a CDN or proxy in front of Auth.
+type GeoIPConfiguration struct {
+	Provider string `json:"provider"`
+
+	MaxMind GeoIPMaxMindConfiguration `json:"maxmind" envconfig:"MAXMIND"`
+
+	CountryHeader string `json:"country_header" split_words:"true" default:"CF-IPCountry"`
+	CityHeader    string `json:"city_header" split_words:"true" default:"CF-IPCity"`
+
+	Timeout time.Duration `json:"timeout" default:"2s"`
+}
+
+// GeoIPMaxMindConfiguration holds the credentials of the MaxMind web
+// service. The URL defaults to the free GeoLite2 City service, use
+// https://geoip.maxmind.com/geoip/v2.1/city for GeoIP2 City.
+type GeoIPMaxMindConfiguration struct {
+	AccountID  string `json:"account_id" split_words:"true"`
+	LicenseKey string `json:"-" split_words:"true"`
+	URL        string `json:"url" default:"https://geolite.info/geoip/v2.1/city"`
+}
+
+func (c *GeoIPConfiguration) Validate() error {
+	switch c.Provider {
+	case "":
+		return nil
+
+	case GeoIPProviderMaxMind:
+		if c.MaxMind.AccountID == "" || c.MaxMind.LicenseKey == "" {
+			return errors.New("conf: the maxmind geoip provider requires an account ID and a license key")
+		}
+
+	case GeoIPProviderHeader:
+		if c.CountryHeader == "" {
+			return errors.New("conf: the header geoip provider requires a country header")
+		}
+
+	default:
+		return fmt.Errorf("conf: geoip provider %q is not supported, use %q or %q", c.Provider, GeoIPProviderMaxMind, GeoIPProviderHeader)
+	}
+
+	if c.Timeout < 0 {
+		return errors.New("conf: geoip timeout must not be negative")
+	}
+
+	return nil
+}
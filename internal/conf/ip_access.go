@@ -0,0 +1,84 @@
+package conf
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// IPAccessConfiguration restricts the IP addresses requests are accepted
+// from. Denied networks take precedence over allowed ones, and when allowed
+// networks are set only requests from them are accepted. The admin rules
+// apply to the /admin endpoints in addition to the rules of all endpoints.
+type IPAccessConfiguration struct {
+	Allow      IPNetworks `json:"allow"`
+	Deny       IPNetworks `json:"deny"`
+	AdminAllow IPNetworks `json:"admin_allow" split_words:"true"`
+	AdminDeny  IPNetworks `json:"admin_deny" split_words:"true"`
+
+	// TrustedProxies are the networks of the proxies in front of the
+	// server, whose X-Forwarded-For entries are used to find the address
+	// of the client.
+	TrustedProxies IPNetworks `json:"trusted_proxies" split_words:"true"`
+}
+
+// Enabled reports whether any access rule is set.
+func (c *IPAccessConfiguration) Enabled() bool {
+	return len(c.Allow) > 0 || len(c.Deny) > 0 || len(c.AdminAllow) > 0 || len(c.AdminDeny) > 0
+}
+
+// IPNetworks holds a comma separated list of IP addresses and CIDRs.
+type IPNetworks []*net.IPNet
+
+func (n *IPNetworks) Decode(value string) error {
+	var networks IPNetworks
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		network, err := parseIPNetwork(entry)
+		if err != nil {
+			return fmt.Errorf("conf: %q is not a valid IP address or network", entry)
+		}
+		networks = append(networks, network)
+	}
+
+	*n = networks
+	return nil
+}
+
+// Contains reports whether the IP address is in one of the networks.
+func (n IPNetworks) Contains(ip string) bool {
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return false
+	}
+
+	for _, network := range n {
+		if network.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseIPNetwork parses a CIDR, or an IP address as the network of only
+// that address.
+func parseIPNetwork(entry string) (*net.IPNet, error) {
+	if !strings.Contains(entry, "/") {
+		ip := net.ParseIP(entry)
+		if ip == nil {
+			return nil, fmt.Errorf("invalid IP address %q", entry)
+		}
+		if ip.To4() != nil {
+			entry += "/32"
+		} else {
+			entry += "/128"
+		}
+	}
+
+	_, network, err := net.ParseCIDR(entry)
+	return network, err
+}
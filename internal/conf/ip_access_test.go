@@ -0,0 +1,36 @@
+package conf
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestIPNetworks(t *testing.T) {
+	var networks IPNetworks
+	require.NoError(t, networks.Decode("10.0.0.0/8, 192.0.2.1,2001:db8::/32,"))
+	require.Len(t, networks, 3)
+
+	require.True(t, networks.Contains("10.1.2.3"))
+	require.True(t, networks.Contains("192.0.2.1"))
+	require.False(t, networks.Contains("192.0.2.2"))
+	require.True(t, networks.Contains("2001:db8::1"))
+	require.False(t, networks.Contains("invalid"))
+
+	require.ErrorContains(t, networks.Decode("10.0.0.0/8,10.0.0.0/33"), "10.0.0.0/33")
+	require.Error(t, networks.Decode("not an address"))
+
+	require.NoError(t, networks.Decode(""))
+	require.Empty(t, networks)
+}
+
+func TestIPAccessConfigurationEnabled(t *testing.T) {
+	var c IPAccessConfiguration
+	require.False(t, c.Enabled())
+
+	require.NoError(t, c.TrustedProxies.Decode("10.0.0.0/8"))
+	require.False(t, c.Enabled())
+
+	require.NoError(t, c.AdminAllow.Decode("192.0.2.0/24"))
+	require.True(t, c.Enabled())
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"slices"
+	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/lestrrat-go/jwx/v2/jwk"
@@ -14,6 +15,44 @@ type JwtKeysDecoder map[string]JwkInfo
 type JwkInfo struct {
 	PublicKey  jwk.Key `json:"public_key"`
 	PrivateKey jwk.Key `json:"private_key"`
+
+	// ExpiresAt ends the grace period of a key that was rotated out. Tokens
+	// signed with the key are accepted until then, after which the key is
+	// no longer used for verification nor published. It is read from the
+	// non-standard "exp" member (seconds since the epoch) of the JWK.
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// IsExpired reports whether the key's grace period has ended at now.
+func (k JwkInfo) IsExpired(now time.Time) bool {
+	return k.ExpiresAt != nil && !now.Before(*k.ExpiresAt)
+}
+
+const jwkExpiryKey = "exp"
+
+// jwkExpiry reads the optional "exp" member of a JWK.
+func jwkExpiry(key jwk.Key) (*time.Time, error) {
+	v, ok := key.Get(jwkExpiryKey)
+	if !ok {
+		return nil, nil
+	}
+
+	var seconds int64
+	switch exp := v.(type) {
+	case float64:
+		seconds = int64(exp)
+	case json.Number:
+		n, err := exp.Int64()
+		if err != nil {
+			return nil, fmt.Errorf("invalid JWK %q member: %w", jwkExpiryKey, err)
+		}
+		seconds = n
+	default:
+		return nil, fmt.Errorf("invalid JWK %q member: must be a number", jwkExpiryKey)
+	}
+
+	expiresAt := time.Unix(seconds, 0)
+	return &expiresAt, nil
 }
 
 // Decode implements the Decoder interface
@@ -68,9 +107,22 @@ func (j *JwtKeysDecoder) decodePublicKey(
 		return err
 	}
 
+	expiresAt, err := jwkExpiry(privJwk)
+	if err != nil {
+		return err
+	}
+
+	if expiresAt != nil {
+		// the expiry is specific to this server and not published
+		if err := pubJwk.Remove(jwkExpiryKey); err != nil {
+			return err
+		}
+	}
+
 	config[pubJwk.KeyID()] = JwkInfo{
 		PublicKey:  pubJwk,
 		PrivateKey: privJwk,
+		ExpiresAt:  expiresAt,
 	}
 	return nil
 }
@@ -99,6 +151,9 @@ func (j *JwtKeysDecoder) Validate() error {
 		}
 
 		if slices.Contains(key.PrivateKey.KeyOps(), jwk.KeyOpSign) {
+			if key.ExpiresAt != nil {
+				return fmt.Errorf("signing key %q must not have an expiry", key.PrivateKey.KeyID())
+			}
 			signingKeys = append(signingKeys, key.PrivateKey)
 		}
 	}
@@ -155,6 +210,9 @@ func GetSigningAlg(k jwk.Key) jwt.SigningMethod {
 
 func FindPublicKeyByKid(kid string, config *JWTConfiguration) (any, error) {
 	if k, ok := config.Keys[kid]; ok {
+		if k.IsExpired(time.Now()) {
+			return nil, fmt.Errorf("JWT key %q has expired", kid)
+		}
 		key, err := GetSigningKey(k.PublicKey)
 		if err != nil {
 			return nil, err
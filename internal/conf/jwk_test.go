@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/lestrrat-go/jwx/v2/jwa"
@@ -336,6 +337,52 @@ func TestDecode(t *testing.T) {
 	}
 }
 
+func TestJwtKeysExpiry(t *testing.T) {
+	m := helpToMap(t, testJwtKey)
+
+	expiresAt := time.Now().Add(time.Hour).Unix()
+	m["2"]["exp"] = expiresAt
+	m["4"]["exp"] = time.Now().Add(-time.Hour).Unix()
+
+	keys := []map[string]interface{}{m["1"], m["2"], m["3"], m["4"]}
+	data, err := json.Marshal(keys)
+	require.NoError(t, err)
+
+	var decoder JwtKeysDecoder
+	require.NoError(t, decoder.Decode(string(data)))
+	require.NoError(t, decoder.Validate())
+
+	require.Nil(t, decoder["1"].ExpiresAt)
+	require.NotNil(t, decoder["2"].ExpiresAt)
+	require.Equal(t, expiresAt, decoder["2"].ExpiresAt.Unix())
+
+	// the expiry is not published with the public key
+	_, ok := decoder["2"].PublicKey.Get("exp")
+	require.False(t, ok)
+
+	config := &JWTConfiguration{Keys: decoder}
+
+	key, err := FindPublicKeyByKid("2", config)
+	require.NoError(t, err)
+	require.NotNil(t, key)
+
+	_, err = FindPublicKeyByKid("4", config)
+	require.EqualError(t, err, `JWT key "4" has expired`)
+
+	// signing keys can't be rotated out
+	m["3"]["exp"] = expiresAt
+	data, err = json.Marshal([]map[string]interface{}{m["3"]})
+	require.NoError(t, err)
+	require.NoError(t, decoder.Decode(string(data)))
+	require.EqualError(t, decoder.Validate(), `signing key "3" must not have an expiry`)
+
+	// the expiry must be numeric
+	m["3"]["exp"] = "tomorrow"
+	data, err = json.Marshal([]map[string]interface{}{m["3"]})
+	require.NoError(t, err)
+	require.Error(t, decoder.Decode(string(data)))
+}
+
 func TestJWTConfiguration(t *testing.T) {
 	// array of JWKs containing 4 keys
 	gotrueJwtKeys := testJwtKey
@@ -1,5 +1,10 @@
 package conf
 
+import (
+	"errors"
+	"time"
+)
+
 type MetricsExporter = string
 
 const (
@@ -19,8 +24,32 @@ type MetricsConfig struct {
 
 	PrometheusListenHost string `default:"0.0.0.0" envconfig:"OTEL_EXPORTER_PROMETHEUS_HOST"`
 	PrometheusListenPort string `default:"9100" envconfig:"OTEL_EXPORTER_PROMETHEUS_PORT"`
+
+	// EndpointEnabled serves the Prometheus metrics on the /metrics
+	// endpoint of the API, in addition to the Prometheus listener. Only
+	// available when exporter is prometheus.
+	EndpointEnabled bool `split_words:"true"`
+
+	// EndpointUsername and EndpointPassword protect the /metrics endpoint
+	// with basic authentication when both are set.
+	EndpointUsername string `split_words:"true"`
+	EndpointPassword string `split_words:"true"`
+
+	// ActiveSessionsInterval is how long the count of active sessions is
+	// cached for, so that collecting metrics does not query the database
+	// every time.
+	ActiveSessionsInterval time.Duration `default:"1m" split_words:"true"`
 }
 
-func (mc MetricsConfig) Validate() error {
+func (mc *MetricsConfig) Validate() error {
+	if mc.EndpointEnabled && mc.Exporter != Prometheus {
+		return errors.New("conf: the metrics endpoint is only available with the prometheus exporter")
+	}
+	if (mc.EndpointUsername == "") != (mc.EndpointPassword == "") {
+		return errors.New("conf: both metrics endpoint username and password must be set")
+	}
+	if mc.ActiveSessionsInterval < 0 {
+		return errors.New("conf: metrics active sessions interval must not be negative")
+	}
 	return nil
 }
@@ -2,6 +2,7 @@ package conf
 
 import (
 	"fmt"
+	"net/url"
 	"strconv"
 	"strings"
 	"time"
@@ -63,3 +64,116 @@ func (r *Rate) String() string {
 	}
 	return fmt.Sprintf("%d/%s", uint64(r.Events), r.OverTime.String())
 }
+
+const (
+	RateLimitStoreMemory = "memory"
+	RateLimitStoreRedis  = "redis"
+)
+
+const (
+	RateLimitKeyIP    = "ip"
+	RateLimitKeyEmail = "email"
+	RateLimitKeyUser  = "user"
+)
+
+// RateLimitRule is a sliding window limit applied to a single endpoint,
+// keyed by the client IP, the email in the request body or the ID of the
+// authenticated user.
+type RateLimitRule struct {
+	Endpoint string `json:"endpoint"`
+	Key      string `json:"key"`
+	Rate     Rate   `json:"rate"`
+}
+
+// Decode parses a rule in the form endpoint:key=events/window, for example
+// signup:ip=30/1h or token:email=10/5m.
+func (r *RateLimitRule) Decode(value string) error {
+	endpoint, limit, ok := strings.Cut(strings.TrimSpace(value), ":")
+	if !ok {
+		return fmt.Errorf("rate limit rule: value %q does not match endpoint:key=events/window", value)
+	}
+
+	key, rate, ok := strings.Cut(limit, "=")
+	if !ok {
+		return fmt.Errorf("rate limit rule: value %q does not match endpoint:key=events/window", value)
+	}
+
+	endpoint = strings.Trim(strings.TrimSpace(endpoint), "/")
+	if endpoint == "" {
+		return fmt.Errorf("rate limit rule: endpoint of rule %q is empty", value)
+	}
+
+	switch key = strings.TrimSpace(key); key {
+	case RateLimitKeyIP, RateLimitKeyEmail, RateLimitKeyUser:
+	default:
+		return fmt.Errorf("rate limit rule: key %q of rule %q must be one of ip, email or user", key, value)
+	}
+
+	var parsed Rate
+	if err := parsed.Decode(strings.TrimSpace(rate)); err != nil {
+		return err
+	}
+	if parsed.GetRateType() != BurstRateType || parsed.OverTime <= 0 {
+		return fmt.Errorf("rate limit rule: limit of rule %q must be in the form events/window", value)
+	}
+
+	r.Endpoint = endpoint
+	r.Key = key
+	r.Rate = parsed
+	return nil
+}
+
+// RateLimitStoreConfiguration configures where rate limit counters are kept
+// and which sliding window limits are enforced. The in-memory store only
+// counts requests seen by the current process, use the redis store to share
+// the counters between replicas.
+type RateLimitStoreConfiguration struct {
+	Type           string `json:"type" default:"memory"`
+	RedisURL       string `json:"-" split_words:"true"`
+	RedisKeyPrefix string `json:"redis_key_prefix" split_words:"true" default:"gotrue:ratelimit:"`
+
+	Rules []RateLimitRule `json:"rules"`
+}
+
+func (c *RateLimitStoreConfiguration) Validate() error {
+	switch c.Type {
+	case RateLimitStoreMemory:
+		return nil
+
+	case RateLimitStoreRedis:
+		return validateRedisURL("rate limit store", c.RedisURL)
+
+	default:
+		return fmt.Errorf("conf: rate limit store type %q is not supported, use memory or redis", c.Type)
+	}
+}
+
+// validateRedisURL checks that the Redis URL of the named feature is set and
+// in the form accepted by the Redis client.
+func validateRedisURL(name, rawURL string) error {
+	if rawURL == "" {
+		return fmt.Errorf("conf: %s redis URL is required when the redis store is used", name)
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("conf: %s redis URL is invalid: %w", name, err)
+	}
+
+	if (u.Scheme != "redis" && u.Scheme != "rediss") || u.Host == "" {
+		return fmt.Errorf("conf: %s redis URL must be in the form redis://host:port/db or rediss://host:port/db", name)
+	}
+
+	return nil
+}
+
+// RulesFor returns the rules that apply to the endpoint.
+func (c *RateLimitStoreConfiguration) RulesFor(endpoint string) []RateLimitRule {
+	var rules []RateLimitRule
+	for _, rule := range c.Rules {
+		if rule.Endpoint == endpoint {
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
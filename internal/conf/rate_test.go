@@ -67,3 +67,36 @@ func TestRateDecode(t *testing.T) {
 	require.Equal(t, "0.000000", (&Rate{}).String())
 	require.Equal(t, "100/1h0m0s", (&Rate{Events: 100, OverTime: time.Hour}).String())
 }
+
+func TestRateLimitRuleDecode(t *testing.T) {
+	cases := []struct {
+		str string
+		exp RateLimitRule
+		err string
+	}{
+		{str: "signup:ip=30/1h",
+			exp: RateLimitRule{Endpoint: "signup", Key: RateLimitKeyIP, Rate: Rate{Events: 30, OverTime: time.Hour, typ: BurstRateType}}},
+		{str: " /token/:email=5/10m ",
+			exp: RateLimitRule{Endpoint: "token", Key: RateLimitKeyEmail, Rate: Rate{Events: 5, OverTime: 10 * time.Minute, typ: BurstRateType}}},
+		{str: "user:user=100/5m",
+			exp: RateLimitRule{Endpoint: "user", Key: RateLimitKeyUser, Rate: Rate{Events: 100, OverTime: 5 * time.Minute, typ: BurstRateType}}},
+		{str: "signup", err: `does not match endpoint:key=events/window`},
+		{str: "signup:ip", err: `does not match endpoint:key=events/window`},
+		{str: ":ip=30/1h", err: `endpoint of rule ":ip=30/1h" is empty`},
+		{str: "signup:phone=30/1h", err: `key "phone" of rule "signup:phone=30/1h" must be one of`},
+		{str: "signup:ip=30", err: `must be in the form events/window`},
+		{str: "signup:ip=30/0s", err: `must be in the form events/window`},
+		{str: "signup:ip=a/1h", err: `rate: events part of rate value`},
+	}
+	for _, tc := range cases {
+		var r RateLimitRule
+		err := r.Decode(tc.str)
+		if tc.err != "" {
+			require.Error(t, err, tc.str)
+			require.Contains(t, err.Error(), tc.err)
+			continue
+		}
+		require.NoError(t, err, tc.str)
+		require.Equal(t, tc.exp, r)
+	}
+}
@@ -0,0 +1,51 @@
+package conf
+
+import (
+	"errors"
+	"time"
+)
+
+// ReaperConfiguration configures pruning expired rows every Interval, in
+// batches of BatchSize rows. Rows are pruned once they have been expired,
+// revoked or consumed for longer than their retention, and a retention of 0
+// keeps the rows of its kind.
+type ReaperConfiguration struct {
+	Enabled bool `json:"enabled"`
+
+	Interval  time.Duration `json:"interval" default:"1h"`
+	BatchSize int           `json:"batch_size" split_words:"true" default:"1000"`
+
+	RefreshTokenRetention    time.Duration `json:"refresh_token_retention" split_words:"true" default:"24h"`
+	OneTimeTokenRetention    time.Duration `json:"one_time_token_retention" split_words:"true" default:"24h"`
+	UnconfirmedUserRetention time.Duration `json:"unconfirmed_user_retention" split_words:"true"`
+	SessionRetention         time.Duration `json:"session_retention" split_words:"true" default:"72h"`
+	FlowStateRetention       time.Duration `json:"flow_state_retention" split_words:"true" default:"24h"`
+}
+
+func (c *ReaperConfiguration) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+
+	if c.Interval <= 0 {
+		return errors.New("conf: reaper interval must be positive")
+	}
+	if c.BatchSize < 1 {
+		return errors.New("conf: reaper batch size must be positive")
+	}
+
+	retentions := []time.Duration{
+		c.RefreshTokenRetention,
+		c.OneTimeTokenRetention,
+		c.UnconfirmedUserRetention,
+		c.SessionRetention,
+		c.FlowStateRetention,
+	}
+	for _, retention := range retentions {
+		if retention < 0 {
+			return errors.New("conf: reaper retentions must not be negative")
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,32 @@
+package conf
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReaperConfigurationValidate(t *testing.T) {
+	valid := ReaperConfiguration{
+		Enabled:               true,
+		Interval:              time.Hour,
+		BatchSize:             1000,
+		RefreshTokenRetention: 24 * time.Hour,
+	}
+	require.NoError(t, valid.Validate())
+
+	require.NoError(t, (&ReaperConfiguration{}).Validate())
+
+	c := valid
+	c.Interval = 0
+	require.Error(t, c.Validate())
+
+	c = valid
+	c.BatchSize = 0
+	require.Error(t, c.Validate())
+
+	c = valid
+	c.SessionRetention = -time.Hour
+	require.Error(t, c.Validate())
+}
@@ -0,0 +1,114 @@
+package conf
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// RiskConfiguration configures scoring the risk of sign-ins from signals
+// such as impossible travel or sign-ins from Tor. The score is the sum of
+// the scores of the signals of a sign-in, up to 100. The action of the
+// highest threshold the score reaches is taken, a threshold of 0 disables
+// its action.
+type RiskConfiguration struct {
+	Enabled bool `json:"enabled"`
+
+	ImpossibleTravelScore  int           `json:"impossible_travel_score" split_words:"true" default:"60"`
+	ImpossibleTravelWindow time.Duration `json:"impossible_travel_window" split_words:"true" default:"2h"`
+	NewDeviceScore         int           `json:"new_device_score" split_words:"true" default:"20"`
+	TorScore               int           `json:"tor_score" split_words:"true" default:"50"`
+	TorIPList              IPListFile    `json:"tor_ip_list" split_words:"true"`
+	DataCenterScore        int           `json:"data_center_score" split_words:"true" default:"30"`
+	DataCenterIPList       IPListFile    `json:"data_center_ip_list" split_words:"true"`
+	VelocityScore          int           `json:"velocity_score" split_words:"true" default:"40"`
+	VelocityMaxSignIns     int           `json:"velocity_max_sign_ins" split_words:"true" default:"10"`
+	VelocityWindow         time.Duration `json:"velocity_window" split_words:"true" default:"1h"`
+
+	RequireMFAScore               int `json:"require_mfa_score" split_words:"true" default:"40"`
+	RequireEmailVerificationScore int `json:"require_email_verification_score" split_words:"true" default:"60"`
+	BlockScore                    int `json:"block_score" split_words:"true" default:"90"`
+}
+
+func (c *RiskConfiguration) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+
+	scores := map[string]int{
+		"impossible travel score":          c.ImpossibleTravelScore,
+		"new device score":                 c.NewDeviceScore,
+		"tor score":                        c.TorScore,
+		"data center score":                c.DataCenterScore,
+		"velocity score":                   c.VelocityScore,
+		"require mfa score":                c.RequireMFAScore,
+		"require email verification score": c.RequireEmailVerificationScore,
+		"block score":                      c.BlockScore,
+	}
+	for name, score := range scores {
+		if score < 0 || score > 100 {
+			return fmt.Errorf("conf: risk %s must be between 0 and 100", name)
+		}
+	}
+
+	if c.RequireMFAScore == 0 && c.RequireEmailVerificationScore == 0 && c.BlockScore == 0 {
+		return errors.New("conf: risk scoring requires the threshold of at least one action")
+	}
+	if c.VelocityMaxSignIns < 1 {
+		return errors.New("conf: risk velocity max sign ins must be positive")
+	}
+	if c.ImpossibleTravelWindow <= 0 || c.VelocityWindow <= 0 {
+		return errors.New("conf: risk windows must be positive")
+	}
+
+	return nil
+}
+
+// IPListFile holds the networks listed in a file, one IP address or CIDR
+// per line. Empty lines and lines starting with # are ignored.
+type IPListFile struct {
+	Path     string     `json:"path"`
+	Networks IPNetworks `json:"-"`
+}
+
+func (l *IPListFile) Decode(value string) error {
+	if value == "" {
+		return nil
+	}
+
+	f, err := os.Open(value)
+	if err != nil {
+		return fmt.Errorf("conf: unable to open IP list: %w", err)
+	}
+	defer f.Close()
+
+	var networks IPNetworks
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		entry := strings.TrimSpace(scanner.Text())
+		if entry == "" || strings.HasPrefix(entry, "#") {
+			continue
+		}
+
+		network, err := parseIPNetwork(entry)
+		if err != nil {
+			return fmt.Errorf("conf: IP list %q has an invalid network on line %d", value, line)
+		}
+		networks = append(networks, network)
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("conf: unable to read IP list: %w", err)
+	}
+
+	l.Path = value
+	l.Networks = networks
+	return nil
+}
+
+// Contains reports whether the IP address is in one of the networks.
+func (l *IPListFile) Contains(ip string) bool {
+	return l.Networks.Contains(ip)
+}
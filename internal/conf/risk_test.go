@@ -0,0 +1,53 @@
+package conf
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestIPListFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tor.txt")
+	require.NoError(t, os.WriteFile(path, []byte("# Tor exit nodes\n185.220.101.1\n\n185.220.102.0/24\n2001:db8::1\n"), 0600))
+
+	var list IPListFile
+	require.NoError(t, list.Decode(path))
+	require.Equal(t, path, list.Path)
+	require.Len(t, list.Networks, 3)
+
+	require.True(t, list.Contains("185.220.101.1"))
+	require.False(t, list.Contains("185.220.101.2"))
+	require.True(t, list.Contains("185.220.102.200"))
+	require.True(t, list.Contains("2001:db8::1"))
+	require.False(t, list.Contains("invalid"))
+
+	require.NoError(t, os.WriteFile(path, []byte("185.220.101.1\nnot an address\n"), 0600))
+	require.ErrorContains(t, list.Decode(path), "line 2")
+
+	require.Error(t, list.Decode(filepath.Join(t.TempDir(), "missing.txt")))
+}
+
+func TestRiskConfigurationValidate(t *testing.T) {
+	valid := RiskConfiguration{
+		Enabled:                true,
+		ImpossibleTravelScore:  60,
+		ImpossibleTravelWindow: 2 * time.Hour,
+		VelocityMaxSignIns:     10,
+		VelocityWindow:         time.Hour,
+		BlockScore:             90,
+	}
+	require.NoError(t, valid.Validate())
+
+	noActions := valid
+	noActions.BlockScore = 0
+	require.ErrorContains(t, noActions.Validate(), "at least one action")
+
+	outOfRange := valid
+	outOfRange.TorScore = 101
+	require.ErrorContains(t, outOfRange.Validate(), "tor score")
+
+	require.NoError(t, (&RiskConfiguration{}).Validate())
+}
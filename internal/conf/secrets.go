@@ -0,0 +1,217 @@
+package conf
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Schemes of the secret references, such as
+// vault:secret/data/gotrue#smtp_pass, which are resolved when the
+// configuration is loaded.
+const (
+	SecretSchemeVault = "vault"
+	SecretSchemeAWS   = "aws-secretsmanager"
+	SecretSchemeGCP   = "gcp-secretmanager"
+)
+
+var secretSchemes = []string{
+	SecretSchemeVault,
+	SecretSchemeAWS,
+	SecretSchemeGCP,
+}
+
+// secretsTimeout bounds resolving all the secret references of a
+// configuration.
+const secretsTimeout = 30 * time.Second
+
+// SecretProvider fetches secrets from a secret manager.
+type SecretProvider interface {
+	// FetchSecret returns the secret at the path. Secrets holding several
+	// values return them as a JSON object, whose values are referenced by
+	// their key.
+	FetchSecret(ctx context.Context, path string) (string, error)
+}
+
+var (
+	secretProvidersMu sync.RWMutex
+	secretProviders   = make(map[string]SecretProvider)
+)
+
+// RegisterSecretProvider resolves the references with the scheme with the
+// provider.
+func RegisterSecretProvider(scheme string, p SecretProvider) {
+	secretProvidersMu.Lock()
+	defer secretProvidersMu.Unlock()
+
+	secretProviders[scheme] = p
+}
+
+// parseSecretRef splits a secret reference into its scheme, path and key.
+// It returns false when the value is not a reference.
+func parseSecretRef(value string) (scheme, path, key string, ok bool) {
+	scheme, ref, found := strings.Cut(value, ":")
+	if !found {
+		return "", "", "", false
+	}
+	for _, s := range secretSchemes {
+		if s == scheme {
+			path, key, _ = strings.Cut(ref, "#")
+			return scheme, path, key, path != ""
+		}
+	}
+	return "", "", "", false
+}
+
+// secretResolver resolves secret references, fetching every secret once.
+type secretResolver struct {
+	ctx     context.Context
+	secrets map[string]string
+}
+
+func (r *secretResolver) resolve(value string) (string, bool, error) {
+	scheme, path, key, ok := parseSecretRef(value)
+	if !ok {
+		return value, false, nil
+	}
+
+	secretProvidersMu.RLock()
+	p, found := secretProviders[scheme]
+	secretProvidersMu.RUnlock()
+	if !found {
+		return "", true, fmt.Errorf("no secret provider is registered for %q", scheme)
+	}
+
+	id := scheme + ":" + path
+	secret, fetched := r.secrets[id]
+	if !fetched {
+		var err error
+		secret, err = p.FetchSecret(r.ctx, path)
+		if err != nil {
+			return "", true, fmt.Errorf("fetching secret %s: %w", id, err)
+		}
+		r.secrets[id] = secret
+	}
+
+	if key == "" {
+		return secret, true, nil
+	}
+
+	var values map[string]any
+	if err := json.Unmarshal([]byte(secret), &values); err != nil {
+		return "", true, fmt.Errorf("secret %s is not a JSON object with the key %q", id, key)
+	}
+	v, found := values[key]
+	if !found {
+		return "", true, fmt.Errorf("secret %s has no key %q", id, key)
+	}
+	if s, isString := v.(string); isString {
+		return s, true, nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", true, err
+	}
+	return string(b), true, nil
+}
+
+// resolveSecrets replaces the secret references in the string settings of
+// the configuration with the secrets they reference. Settings decoded into
+// other types, such as durations or JWKs, can not be references.
+func resolveSecrets(config *GlobalConfiguration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), secretsTimeout)
+	defer cancel()
+
+	r := &secretResolver{
+		ctx:     ctx,
+		secrets: make(map[string]string),
+	}
+	return r.walk(reflect.ValueOf(config).Elem(), "")
+}
+
+var confPkgPath = reflect.TypeOf(GlobalConfiguration{}).PkgPath()
+
+// walk resolves the references in the exported string settings of v, and
+// of the structs of this package v holds.
+func (r *secretResolver) walk(v reflect.Value, name string) error {
+	switch v.Kind() {
+	case reflect.Pointer:
+		if !v.IsNil() && v.Elem().Kind() == reflect.Struct && v.Elem().Type().PkgPath() == confPkgPath {
+			return r.walk(v.Elem(), name)
+		}
+
+	case reflect.Struct:
+		if v.Type().PkgPath() != confPkgPath {
+			return nil
+		}
+		t := v.Type()
+		for i := range t.NumField() {
+			f := t.Field(i)
+			if !f.IsExported() {
+				continue
+			}
+			if err := r.walk(v.Field(i), joinSettingName(name, f.Name)); err != nil {
+				return err
+			}
+		}
+
+	case reflect.String:
+		return r.set(v, name)
+
+	case reflect.Slice, reflect.Array:
+		if v.Type().Elem().Kind() == reflect.String {
+			for i := range v.Len() {
+				if err := r.set(v.Index(i), fmt.Sprintf("%s[%d]", name, i)); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		for i := range v.Len() {
+			if err := r.walk(v.Index(i), fmt.Sprintf("%s[%d]", name, i)); err != nil {
+				return err
+			}
+		}
+
+	case reflect.Map:
+		if v.Type().Elem().Kind() != reflect.String {
+			return nil
+		}
+		iter := v.MapRange()
+		for iter.Next() {
+			value, resolved, err := r.resolve(iter.Value().String())
+			if err != nil {
+				return fmt.Errorf("conf: %s[%v]: %w", name, iter.Key(), err)
+			}
+			if resolved {
+				v.SetMapIndex(iter.Key(), reflect.ValueOf(value).Convert(v.Type().Elem()))
+			}
+		}
+	}
+	return nil
+}
+
+func (r *secretResolver) set(v reflect.Value, name string) error {
+	if !v.CanSet() {
+		return nil
+	}
+	value, resolved, err := r.resolve(v.String())
+	if err != nil {
+		return fmt.Errorf("conf: %s: %w", name, err)
+	}
+	if resolved {
+		v.SetString(value)
+	}
+	return nil
+}
+
+func joinSettingName(parent, name string) string {
+	if parent == "" {
+		return name
+	}
+	return parent + "." + name
+}
@@ -0,0 +1,89 @@
+package conf
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type fakeSecretProvider struct {
+	secrets map[string]string
+	fetches int
+}
+
+func (p *fakeSecretProvider) FetchSecret(ctx context.Context, path string) (string, error) {
+	p.fetches++
+	secret, ok := p.secrets[path]
+	if !ok {
+		return "", errors.New("secret not found")
+	}
+	return secret, nil
+}
+
+func TestResolveSecrets(t *testing.T) {
+	p := &fakeSecretProvider{
+		secrets: map[string]string{
+			"secret/data/gotrue": `{"smtp_pass": "smtp-password", "jwt_secret": "jwt-secret", "port": 587}`,
+			"plain":              "plain-secret",
+		},
+	}
+	RegisterSecretProvider(SecretSchemeVault, p)
+	defer delete(secretProviders, SecretSchemeVault)
+
+	config := &GlobalConfiguration{}
+	config.SMTP.Pass = "vault:secret/data/gotrue#smtp_pass"
+	config.JWT.Secret = "vault:secret/data/gotrue#jwt_secret"
+	config.External.Google.Secret = "vault:plain"
+	config.SMTP.Host = "smtp.example.com"
+	config.DB.URL = "postgres://localhost:5432/postgres"
+	config.Hook.SendEmail.HTTPHookSecrets = []string{"vault:plain"}
+	config.External.Apple.Enabled = true
+
+	require.NoError(t, resolveSecrets(config))
+	require.Equal(t, "smtp-password", config.SMTP.Pass)
+	require.Equal(t, "jwt-secret", config.JWT.Secret)
+	require.Equal(t, "plain-secret", config.External.Google.Secret)
+	require.Equal(t, "smtp.example.com", config.SMTP.Host)
+	require.Equal(t, "postgres://localhost:5432/postgres", config.DB.URL)
+	require.Equal(t, HTTPHookSecrets{"plain-secret"}, config.Hook.SendEmail.HTTPHookSecrets)
+
+	// every secret is fetched once
+	require.Equal(t, 2, p.fetches)
+
+	cases := []struct {
+		desc  string
+		value string
+		err   string
+	}{
+		{
+			desc:  "Missing key",
+			value: "vault:secret/data/gotrue#missing",
+			err:   `conf: SMTP.Pass: secret vault:secret/data/gotrue has no key "missing"`,
+		},
+		{
+			desc:  "Missing secret",
+			value: "vault:missing#smtp_pass",
+			err:   "conf: SMTP.Pass: fetching secret vault:missing: secret not found",
+		},
+		{
+			desc:  "Not a JSON object",
+			value: "vault:plain#smtp_pass",
+			err:   `conf: SMTP.Pass: secret vault:plain is not a JSON object with the key "smtp_pass"`,
+		},
+		{
+			desc:  "Provider not registered",
+			value: "gcp-secretmanager:projects/p/secrets/s/versions/latest",
+			err:   `conf: SMTP.Pass: no secret provider is registered for "gcp-secretmanager"`,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.desc, func(t *testing.T) {
+			config := &GlobalConfiguration{}
+			config.SMTP.Pass = c.value
+			require.EqualError(t, resolveSecrets(config), c.err)
+		})
+	}
+}
@@ -0,0 +1,208 @@
+package conf
+
+import (
+	"crypto"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// TenancyConfiguration holds the configuration for serving multiple tenants
+// from a single deployment.
+type TenancyConfiguration struct {
+	Enabled bool `json:"enabled" default:"false"`
+
+	// Header is the request header selecting the tenant by its ID, it takes
+	// precedence over the tenant's hostname.
+	Header string `json:"header" default:"X-Tenant-ID"`
+
+	// CacheTTL is how long a resolved tenant is served before it is looked
+	// up again, so that updates to the tenant are picked up.
+	CacheTTL time.Duration `json:"cache_ttl" split_words:"true" default:"1m"`
+}
+
+func (c *TenancyConfiguration) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+
+	if c.Header == "" {
+		return errors.New("conf: tenancy header must not be empty")
+	}
+
+	if c.CacheTTL <= 0 {
+		return errors.New("conf: tenancy cache TTL must be a positive duration")
+	}
+
+	return nil
+}
+
+// TenantJWTConfiguration holds the JWT settings of a tenant. Keys is a JSON
+// array of JWKs in the same format as GOTRUE_JWT_KEYS.
+type TenantJWTConfiguration struct {
+	Secret string `json:"secret,omitempty"`
+	Issuer string `json:"issuer,omitempty"`
+	KeyID  string `json:"key_id,omitempty"`
+	Keys   string `json:"keys,omitempty"`
+}
+
+// TenantConfiguration holds the settings a tenant overrides on top of the
+// configuration of the deployment. Empty values keep the deployment's setting,
+// sections that are set replace the deployment's section as a whole.
+type TenantConfiguration struct {
+	SiteURL      string   `json:"site_url,omitempty"`
+	URIAllowList []string `json:"uri_allow_list,omitempty"`
+
+	// ExternalURL is the URL the tenant's API is reachable at.
+	ExternalURL string `json:"external_url,omitempty"`
+
+	// DatabaseURL points to the database, or the database user with its own
+	// search_path, that holds the tenant's users. It is required, tenants are
+	// only isolated by their database.
+	DatabaseURL string `json:"database_url,omitempty"`
+
+	// JWT is required, tenants sign their tokens with a secret or keys of
+	// their own and never with the deployment's.
+	JWT  *TenantJWTConfiguration `json:"jwt,omitempty"`
+	SMTP *SMTPConfiguration      `json:"smtp,omitempty"`
+
+	// External maps provider names as used in the settings, such as
+	// "google" or "github", to their OAuth credentials.
+	External map[string]OAuthProviderConfiguration `json:"external,omitempty"`
+}
+
+// ForTenant returns a copy of the configuration with the tenant's settings
+// applied. The receiver is not modified.
+func (c *GlobalConfiguration) ForTenant(t *TenantConfiguration) (*GlobalConfiguration, error) {
+	config := *c
+
+	// tenants can not manage other tenants
+	config.Tenancy.Enabled = false
+
+	if t.SiteURL != "" {
+		config.SiteURL = t.SiteURL
+	}
+
+	if t.URIAllowList != nil {
+		config.URIAllowList = t.URIAllowList
+	}
+
+	if t.ExternalURL != "" {
+		config.API.ExternalURL = t.ExternalURL
+	}
+
+	if t.DatabaseURL != "" {
+		config.DB.URL = t.DatabaseURL
+
+		// the worker only delivers the webhook events queued in the
+		// deployment's database
+		config.Webhooks.Enabled = false
+	}
+
+	// tenants sharing the deployment's signing keys could sign in each
+	// other's users, so they never fall back to them
+	if t.JWT == nil {
+		return nil, errors.New("conf: tenant JWT configuration is required")
+	}
+
+	config.JWT.Secret = t.JWT.Secret
+	config.JWT.Issuer = t.JWT.Issuer
+	config.JWT.KeyID = t.JWT.KeyID
+	config.JWT.Keys = nil
+	config.JWT.ValidMethods = nil
+
+	if t.JWT.Keys != "" {
+		if err := config.JWT.Keys.Decode(t.JWT.Keys); err != nil {
+			return nil, fmt.Errorf("conf: tenant JWT keys are not valid: %w", err)
+		}
+	} else if t.JWT.Secret == "" {
+		return nil, errors.New("conf: tenant JWT configuration requires a secret or keys")
+	}
+
+	if t.JWT.Secret != "" && t.JWT.Secret == c.JWT.Secret {
+		return nil, errors.New("conf: tenant JWT secret must not be the deployment's secret")
+	}
+
+	if t.SMTP != nil {
+		config.SMTP = *t.SMTP
+		if config.SMTP.MaxFrequency == 0 {
+			config.SMTP.MaxFrequency = c.SMTP.MaxFrequency
+		}
+	}
+
+	if len(t.External) > 0 {
+		if err := config.External.override(t.External); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := config.ApplyDefaults(); err != nil {
+		return nil, err
+	}
+
+	if err := c.checkTenantKeys(config.JWT.Keys); err != nil {
+		return nil, err
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &config, nil
+}
+
+// checkTenantKeys returns an error if one of the tenant's keys, including the
+// key derived from its secret, is also a key of the deployment.
+func (c *GlobalConfiguration) checkTenantKeys(keys JwtKeysDecoder) error {
+	deployment := make(map[string]bool, len(c.JWT.Keys))
+	for _, key := range c.JWT.Keys {
+		thumbprint, err := key.PublicKey.Thumbprint(crypto.SHA256)
+		if err != nil {
+			return err
+		}
+		deployment[string(thumbprint)] = true
+	}
+
+	for _, key := range keys {
+		thumbprint, err := key.PublicKey.Thumbprint(crypto.SHA256)
+		if err != nil {
+			return err
+		}
+		if deployment[string(thumbprint)] {
+			return errors.New("conf: tenant JWT keys must not be the deployment's keys")
+		}
+	}
+
+	return nil
+}
+
+// override replaces the OAuth providers named by their JSON field name.
+func (p *ProviderConfiguration) override(providers map[string]OAuthProviderConfiguration) error {
+	v := reflect.ValueOf(p).Elem()
+	t := v.Type()
+
+	oauthType := reflect.TypeOf(OAuthProviderConfiguration{})
+
+	for name, provider := range providers {
+		found := false
+
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if field.Type != oauthType || strings.Split(field.Tag.Get("json"), ",")[0] != name {
+				continue
+			}
+
+			v.Field(i).Set(reflect.ValueOf(provider))
+			found = true
+			break
+		}
+
+		if !found {
+			return fmt.Errorf("conf: tenant OAuth provider %q is not supported", name)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,83 @@
+package conf
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestForTenant(t *testing.T) {
+	base := &GlobalConfiguration{
+		SiteURL: "https://example.com",
+		API:     APIConfiguration{ExternalURL: "https://auth.example.com"},
+		DB:      DBConfiguration{URL: "postgres://localhost/auth"},
+		JWT:     JWTConfiguration{Secret: "deployment-secret"},
+		SMTP:    SMTPConfiguration{Host: "smtp.example.com", Port: 587},
+		Tenancy: TenancyConfiguration{Enabled: true},
+
+		RateLimitStore: RateLimitStoreConfiguration{Type: RateLimitStoreMemory},
+	}
+	base.External.Google = OAuthProviderConfiguration{Enabled: true, ClientID: []string{"deployment"}}
+	require.NoError(t, base.ApplyDefaults())
+
+	config, err := base.ForTenant(&TenantConfiguration{JWT: &TenantJWTConfiguration{Secret: "tenant-secret"}})
+	require.NoError(t, err)
+	require.Equal(t, base.SiteURL, config.SiteURL)
+	require.Equal(t, base.DB.URL, config.DB.URL)
+	require.False(t, config.Tenancy.Enabled)
+
+	// tenants never sign with the deployment's keys
+	_, err = base.ForTenant(&TenantConfiguration{})
+	require.EqualError(t, err, "conf: tenant JWT configuration is required")
+
+	_, err = base.ForTenant(&TenantConfiguration{JWT: &TenantJWTConfiguration{Secret: base.JWT.Secret}})
+	require.EqualError(t, err, "conf: tenant JWT secret must not be the deployment's secret")
+
+	keys, err := json.Marshal([]interface{}{base.JWT.Keys[base.JWT.KeyID].PrivateKey})
+	require.NoError(t, err)
+	_, err = base.ForTenant(&TenantConfiguration{JWT: &TenantJWTConfiguration{Keys: string(keys)}})
+	require.EqualError(t, err, "conf: tenant JWT keys must not be the deployment's keys")
+
+	config, err = base.ForTenant(&TenantConfiguration{
+		SiteURL:      "https://tenant.example.com",
+		URIAllowList: []string{"https://tenant.example.com/**"},
+		ExternalURL:  "https://auth.tenant.example.com",
+		DatabaseURL:  "postgres://localhost/tenant",
+		JWT:          &TenantJWTConfiguration{Secret: "tenant-secret"},
+		SMTP:         &SMTPConfiguration{Host: "smtp.tenant.example.com", Port: 465},
+		External: map[string]OAuthProviderConfiguration{
+			"github": {Enabled: true, ClientID: []string{"tenant"}, Secret: "secret"},
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, "https://tenant.example.com", config.SiteURL)
+	require.Contains(t, config.URIAllowListMap, "https://tenant.example.com/**")
+	require.Equal(t, "https://auth.tenant.example.com", config.API.ExternalURL)
+	require.Equal(t, "postgres://localhost/tenant", config.DB.URL)
+	require.Equal(t, "tenant-secret", config.JWT.Secret)
+	require.Len(t, config.JWT.Keys, 1)
+	require.Equal(t, "smtp.tenant.example.com", config.SMTP.Host)
+	require.Equal(t, base.SMTP.MaxFrequency, config.SMTP.MaxFrequency)
+	require.Equal(t, []string{"tenant"}, config.External.Github.ClientID)
+	require.Equal(t, []string{"deployment"}, config.External.Google.ClientID)
+
+	// the deployment's configuration is untouched
+	require.Equal(t, "https://example.com", base.SiteURL)
+	require.Equal(t, "deployment-secret", base.JWT.Secret)
+	require.Equal(t, "smtp.example.com", base.SMTP.Host)
+	require.False(t, base.External.Github.Enabled)
+	require.NotContains(t, base.URIAllowListMap, "https://tenant.example.com/**")
+
+	_, err = base.ForTenant(&TenantConfiguration{
+		JWT:      &TenantJWTConfiguration{Secret: "tenant-secret"},
+		External: map[string]OAuthProviderConfiguration{"unknown": {}},
+	})
+	require.EqualError(t, err, `conf: tenant OAuth provider "unknown" is not supported`)
+
+	_, err = base.ForTenant(&TenantConfiguration{JWT: &TenantJWTConfiguration{}})
+	require.EqualError(t, err, "conf: tenant JWT configuration requires a secret or keys")
+
+	_, err = base.ForTenant(&TenantConfiguration{JWT: &TenantJWTConfiguration{Keys: "not json"}})
+	require.Error(t, err)
+}
@@ -0,0 +1,141 @@
+package conf
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+)
+
+// defaultTokenExchangeExp is how long exchanged tokens are valid for when
+// their audience does not set it, in seconds.
+const defaultTokenExchangeExp = 300
+
+// TokenExchangeConfiguration configures exchanging access tokens for tokens
+// of downstream services, with the token exchange grant of RFC 8693.
+type TokenExchangeConfiguration struct {
+	Enabled   bool                   `json:"enabled"`
+	Audiences TokenExchangeAudiences `json:"audiences"`
+}
+
+func (c *TokenExchangeConfiguration) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+
+	if len(c.Audiences) == 0 {
+		return errors.New("conf: token exchange requires at least one audience")
+	}
+
+	return c.Audiences.Validate()
+}
+
+// TokenExchangeAudienceConfiguration configures a downstream service access
+// tokens can be exchanged for. Its tokens carry at most the scopes, expire
+// after Exp seconds and are signed with the private JWK Key. The key must
+// not be one of the JWT keys, so that Auth does not accept the tokens.
+type TokenExchangeAudienceConfiguration struct {
+	Audience string          `json:"audience"`
+	Scopes   []string        `json:"scopes,omitempty"`
+	Exp      int             `json:"exp,omitempty"`
+	Key      json.RawMessage `json:"key"`
+
+	SigningKey jwk.Key `json:"-"`
+	PublicKey  jwk.Key `json:"-"`
+}
+
+// TokenExchangeAudiences holds the audiences of
+// GOTRUE_TOKEN_EXCHANGE_AUDIENCES, which is a JSON array of audiences.
+type TokenExchangeAudiences []TokenExchangeAudienceConfiguration
+
+func (a *TokenExchangeAudiences) Decode(value string) error {
+	if value == "" {
+		return nil
+	}
+
+	var audiences []TokenExchangeAudienceConfiguration
+	if err := json.Unmarshal([]byte(value), &audiences); err != nil {
+		return fmt.Errorf("conf: token exchange audiences not a JSON array of audiences: %w", err)
+	}
+
+	for i := range audiences {
+		audience := &audiences[i]
+
+		if audience.Exp == 0 {
+			audience.Exp = defaultTokenExchangeExp
+		}
+
+		if len(audience.Key) == 0 {
+			continue
+		}
+
+		key, err := jwk.ParseKey(audience.Key)
+		if err != nil {
+			return fmt.Errorf("conf: token exchange audience %q has an invalid key: %w", audience.Audience, err)
+		}
+		audience.SigningKey = key
+
+		if key.KeyType() != jwa.OctetSeq {
+			publicKey, err := jwk.PublicKeyOf(key)
+			if err != nil {
+				return fmt.Errorf("conf: token exchange audience %q has an invalid key: %w", audience.Audience, err)
+			}
+			if err := publicKey.Set(jwk.KeyUsageKey, "sig"); err != nil {
+				return err
+			}
+			audience.PublicKey = publicKey
+		}
+	}
+
+	*a = audiences
+	return nil
+}
+
+// Find returns the configuration of the audience, or nil.
+func (a TokenExchangeAudiences) Find(audience string) *TokenExchangeAudienceConfiguration {
+	for i := range a {
+		if a[i].Audience == audience {
+			return &a[i]
+		}
+	}
+	return nil
+}
+
+func (a TokenExchangeAudiences) Validate() error {
+	audiences := make(map[string]bool, len(a))
+
+	for i := range a {
+		audience := &a[i]
+
+		if audience.Audience == "" {
+			return errors.New("conf: token exchange audience must not be empty")
+		}
+		if audiences[audience.Audience] {
+			return fmt.Errorf("conf: duplicate token exchange audience %q", audience.Audience)
+		}
+		audiences[audience.Audience] = true
+
+		if audience.Exp < 0 {
+			return fmt.Errorf("conf: token exchange audience %q must have a positive exp", audience.Audience)
+		}
+
+		if audience.SigningKey == nil {
+			return fmt.Errorf("conf: token exchange audience %q requires a key", audience.Audience)
+		}
+		// downstream services verify the tokens without being able to
+		// sign them
+		if audience.PublicKey == nil {
+			return fmt.Errorf("conf: token exchange audience %q requires an asymmetric key", audience.Audience)
+		}
+		if audience.SigningKey.Algorithm().String() == "" || audience.SigningKey.KeyID() == "" {
+			return fmt.Errorf("conf: token exchange audience %q requires a key with an alg and a kid", audience.Audience)
+		}
+		if err := audience.SigningKey.Validate(); err != nil {
+			return fmt.Errorf("conf: token exchange audience %q has an invalid key: %w", audience.Audience, err)
+		}
+	}
+
+	return nil
+}
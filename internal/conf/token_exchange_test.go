@@ -0,0 +1,64 @@
+package conf
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"testing"
+
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokenExchangeAudiences(t *testing.T) {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	key, err := jwk.FromRaw(privateKey)
+	require.NoError(t, err)
+	require.NoError(t, key.Set(jwk.KeyIDKey, "billing"))
+	require.NoError(t, key.Set(jwk.AlgorithmKey, "ES256"))
+	rawKey, err := json.Marshal(key)
+	require.NoError(t, err)
+
+	var audiences TokenExchangeAudiences
+	require.NoError(t, audiences.Decode(`[
+		{"audience": "https://billing.example.com", "scopes": ["invoices:read"], "key": `+string(rawKey)+`},
+		{"audience": "https://reports.example.com", "exp": 60, "key": `+string(rawKey)+`}
+	]`))
+	require.NoError(t, audiences.Validate())
+
+	billing := audiences.Find("https://billing.example.com")
+	require.NotNil(t, billing)
+	require.Equal(t, defaultTokenExchangeExp, billing.Exp)
+	require.NotNil(t, billing.PublicKey)
+	_, hasPrivateKey := billing.PublicKey.Get("d")
+	require.False(t, hasPrivateKey)
+	require.Equal(t, 60, audiences.Find("https://reports.example.com").Exp)
+	require.Nil(t, audiences.Find("https://unknown.example.com"))
+
+	cases := []struct {
+		value string
+		err   string
+	}{
+		{
+			value: `[{"audience": "https://billing.example.com"}]`,
+			err:   `conf: token exchange audience "https://billing.example.com" requires a key`,
+		},
+		{
+			value: `[{"audience": "https://billing.example.com", "key": {"kty": "oct", "k": "c2VjcmV0", "kid": "billing", "alg": "HS256"}}]`,
+			err:   `conf: token exchange audience "https://billing.example.com" requires an asymmetric key`,
+		},
+		{
+			value: `[{"audience": "https://billing.example.com", "key": ` + string(rawKey) + `}, {"audience": "https://billing.example.com", "key": ` + string(rawKey) + `}]`,
+			err:   `conf: duplicate token exchange audience "https://billing.example.com"`,
+		},
+	}
+	for _, c := range cases {
+		var audiences TokenExchangeAudiences
+		require.NoError(t, audiences.Decode(c.value))
+		require.EqualError(t, audiences.Validate(), c.err)
+	}
+
+	require.Error(t, audiences.Decode(`{}`))
+}
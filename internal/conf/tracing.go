@@ -1,5 +1,7 @@
 package conf
 
+import "errors"
+
 type TracingExporter = string
 
 const (
@@ -26,8 +28,16 @@ type TracingConfig struct {
 
 	// Tags are the tags to associate with OpenTracing.
 	Tags map[string]string
+
+	// SampleRatio is the ratio of the traces started by Auth that are
+	// sampled. Traces started by a caller are sampled when the caller
+	// sampled them.
+	SampleRatio float64 `default:"1" split_words:"true"`
 }
 
 func (tc *TracingConfig) Validate() error {
+	if tc.SampleRatio < 0 || tc.SampleRatio > 1 {
+		return errors.New("conf: tracing sample ratio must be between 0 and 1")
+	}
 	return nil
 }
@@ -0,0 +1,250 @@
+package conf
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Webhook events are the lifecycle events of users sent to the webhook
+// endpoints.
+const (
+	WebhookEventUserCreated     = "user.created"
+	WebhookEventUserDeleted     = "user.deleted"
+	WebhookEventLoginSuccess    = "login.success"
+	WebhookEventLoginFailed     = "login.failed"
+	WebhookEventPasswordChanged = "password.changed"
+	WebhookEventPhoneChanged    = "phone.changed"
+	WebhookEventMFAEnrolled     = "mfa.enrolled"
+	WebhookEventTokenRefreshed  = "token.refreshed"
+)
+
+// Webhook endpoint types are the ways events are sent to an endpoint: posted
+// to a URL, or published to a Kafka topic, a NATS subject or a Postgres
+// NOTIFY channel.
+const (
+	WebhookEndpointTypeHTTP     = "http"
+	WebhookEndpointTypeKafka    = "kafka"
+	WebhookEndpointTypeNATS     = "nats"
+	WebhookEndpointTypePostgres = "postgres"
+)
+
+var postgresChannelPattern = regexp.MustCompile(`^[a-z_][a-z0-9_]{0,62}$`)
+
+// WebhookEvents lists every webhook event.
+var WebhookEvents = []string{
+	WebhookEventUserCreated,
+	WebhookEventUserDeleted,
+	WebhookEventLoginSuccess,
+	WebhookEventLoginFailed,
+	WebhookEventPasswordChanged,
+	WebhookEventPhoneChanged,
+	WebhookEventMFAEnrolled,
+	WebhookEventTokenRefreshed,
+}
+
+// WebhooksConfiguration configures sending the lifecycle events of users to
+// webhook endpoints. Events are queued in the database for every endpoint
+// subscribed to them, and posted signed as Standard Webhooks by a background
+// worker. Failed deliveries are retried with an exponential backoff starting
+// at RetryInterval, until MaxAttempts attempts failed and the delivery is
+// dead-lettered. Delivered events are kept in the delivery log for
+// Retention.
+type WebhooksConfiguration struct {
+	Enabled   bool             `json:"enabled"`
+	Endpoints WebhookEndpoints `json:"endpoints"`
+	Timeout   time.Duration    `json:"timeout" default:"5s"`
+
+	MaxAttempts      int           `json:"max_attempts" split_words:"true" default:"8"`
+	RetryInterval    time.Duration `json:"retry_interval" split_words:"true" default:"30s"`
+	MaxRetryInterval time.Duration `json:"max_retry_interval" split_words:"true" default:"6h"`
+
+	// PollInterval is how often the worker looks for events to deliver, and
+	// BatchSize how many events it delivers at a time.
+	PollInterval time.Duration `json:"poll_interval" split_words:"true" default:"1s"`
+	BatchSize    int           `json:"batch_size" split_words:"true" default:"10"`
+
+	Retention time.Duration `json:"retention" default:"168h"`
+}
+
+func (c *WebhooksConfiguration) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+
+	if len(c.Endpoints) == 0 {
+		return errors.New("conf: webhooks require at least one endpoint")
+	}
+
+	if c.Timeout <= 0 {
+		return errors.New("conf: webhooks timeout must be positive")
+	}
+
+	if c.MaxAttempts < 1 {
+		return errors.New("conf: webhooks max attempts must be at least 1")
+	}
+
+	if c.RetryInterval <= 0 || c.MaxRetryInterval < c.RetryInterval {
+		return errors.New("conf: webhooks retry interval must be positive and at most the max retry interval")
+	}
+
+	if c.PollInterval <= 0 {
+		return errors.New("conf: webhooks poll interval must be positive")
+	}
+
+	if c.BatchSize < 1 {
+		return errors.New("conf: webhooks batch size must be at least 1")
+	}
+
+	if c.Retention <= 0 {
+		return errors.New("conf: webhooks retention must be positive")
+	}
+
+	return c.Endpoints.Validate()
+}
+
+// RetryAt returns when to retry a delivery that failed attempts times, or
+// nil when it should not be retried.
+func (c *WebhooksConfiguration) RetryAt(attempts int, now time.Time) *time.Time {
+	return backoff(attempts, c.MaxAttempts, c.RetryInterval, c.MaxRetryInterval, now)
+}
+
+// WebhookEndpointConfiguration configures a named webhook endpoint, which
+// receives the events it is subscribed to, or every event when Events is
+// empty. HTTP endpoints are posted to at the URL, signed with each of the
+// secrets. The other types publish to the topic: Kafka endpoints through
+// the REST Proxy at the URL, NATS endpoints to the server at the URL and
+// Postgres endpoints with pg_notify in the deployment's database. Kafka and
+// NATS endpoints authenticate with the username and password, or NATS with
+// the password alone as a token.
+type WebhookEndpointConfiguration struct {
+	Name     string          `json:"name"`
+	Type     string          `json:"type,omitempty"`
+	URL      string          `json:"url,omitempty"`
+	Secrets  HTTPHookSecrets `json:"secrets,omitempty"`
+	Topic    string          `json:"topic,omitempty"`
+	Username string          `json:"username,omitempty"`
+	Password string          `json:"password,omitempty"`
+	Events   []string        `json:"events,omitempty"`
+}
+
+// EndpointType returns the type of the endpoint, HTTP by default.
+func (c *WebhookEndpointConfiguration) EndpointType() string {
+	if c.Type == "" {
+		return WebhookEndpointTypeHTTP
+	}
+	return c.Type
+}
+
+// Subscribes reports whether the endpoint receives the event.
+func (c *WebhookEndpointConfiguration) Subscribes(event string) bool {
+	return len(c.Events) == 0 || slices.Contains(c.Events, event)
+}
+
+// WebhookEndpoints holds the endpoints of GOTRUE_WEBHOOKS_ENDPOINTS, which
+// is a JSON array of endpoints.
+type WebhookEndpoints []WebhookEndpointConfiguration
+
+func (e *WebhookEndpoints) Decode(value string) error {
+	if value == "" {
+		return nil
+	}
+
+	var endpoints []WebhookEndpointConfiguration
+	if err := json.Unmarshal([]byte(value), &endpoints); err != nil {
+		return fmt.Errorf("conf: webhook endpoints not a JSON array of endpoints: %w", err)
+	}
+
+	*e = endpoints
+	return nil
+}
+
+// Find returns the endpoint with the name, or nil.
+func (e WebhookEndpoints) Find(name string) *WebhookEndpointConfiguration {
+	for i := range e {
+		if e[i].Name == name {
+			return &e[i]
+		}
+	}
+	return nil
+}
+
+func (e WebhookEndpoints) Validate() error {
+	names := make(map[string]bool, len(e))
+
+	for i := range e {
+		endpoint := &e[i]
+
+		if !connectionSlugPattern.MatchString(endpoint.Name) {
+			return fmt.Errorf("conf: webhook endpoint name %q must be lowercase letters, digits, - and _", endpoint.Name)
+		}
+		if names[endpoint.Name] {
+			return fmt.Errorf("conf: duplicate webhook endpoint name %q", endpoint.Name)
+		}
+		names[endpoint.Name] = true
+
+		if err := endpoint.validateType(); err != nil {
+			return err
+		}
+
+		for _, event := range endpoint.Events {
+			if !slices.Contains(WebhookEvents, event) {
+				return fmt.Errorf("conf: webhook endpoint %q is subscribed to unknown event %q", endpoint.Name, event)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (c *WebhookEndpointConfiguration) validateType() error {
+	switch c.EndpointType() {
+	case WebhookEndpointTypeHTTP:
+		u, err := url.ParseRequestURI(c.URL)
+		if err != nil || (u.Scheme != "https" && u.Scheme != "http") {
+			return fmt.Errorf("conf: webhook endpoint %q url must be a valid HTTP URL", c.Name)
+		}
+
+		if len(c.Secrets) == 0 {
+			return fmt.Errorf("conf: missing secret of webhook endpoint %q", c.Name)
+		}
+		for _, secret := range c.Secrets {
+			if !symmetricSecretFormat.MatchString(secret) {
+				return fmt.Errorf("conf: webhook endpoint %q secrets must be symmetric v1,whsec_ secrets", c.Name)
+			}
+		}
+
+	case WebhookEndpointTypeKafka:
+		u, err := url.ParseRequestURI(c.URL)
+		if err != nil || (u.Scheme != "https" && u.Scheme != "http") {
+			return fmt.Errorf("conf: webhook endpoint %q url must be the HTTP URL of a Kafka REST Proxy", c.Name)
+		}
+		if c.Topic == "" {
+			return fmt.Errorf("conf: missing topic of webhook endpoint %q", c.Name)
+		}
+
+	case WebhookEndpointTypeNATS:
+		u, err := url.Parse(c.URL)
+		if err != nil || (u.Scheme != "nats" && u.Scheme != "tls") || u.Host == "" {
+			return fmt.Errorf("conf: webhook endpoint %q url must be a nats:// or tls:// URL", c.Name)
+		}
+		if c.Topic == "" || strings.ContainsAny(c.Topic, " \t\r\n*>") {
+			return fmt.Errorf("conf: webhook endpoint %q topic must be a NATS subject without wildcards", c.Name)
+		}
+
+	case WebhookEndpointTypePostgres:
+		if !postgresChannelPattern.MatchString(c.Topic) {
+			return fmt.Errorf("conf: webhook endpoint %q topic must be a lowercase Postgres channel name", c.Name)
+		}
+
+	default:
+		return fmt.Errorf("conf: webhook endpoint %q has unsupported type %q", c.Name, c.Type)
+	}
+
+	return nil
+}
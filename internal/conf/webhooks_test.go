@@ -0,0 +1,87 @@
+package conf
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhooksConfiguration(t *testing.T) {
+	const secret = "v1,whsec_aWxpa2V0b2VhdGx1bmNoYXRkb3JzZXRnb2JsaW5z"
+
+	var endpoints WebhookEndpoints
+	require.NoError(t, endpoints.Decode(`[
+		{"name": "crm", "url": "https://crm.example.com/hooks", "secrets": ["`+secret+`"], "events": ["user.created", "user.deleted"]},
+		{"name": "siem", "url": "https://siem.example.com", "secrets": ["`+secret+`"]}
+	]`))
+	require.Len(t, endpoints, 2)
+	require.NoError(t, endpoints.Validate())
+
+	crm := endpoints.Find("crm")
+	require.NotNil(t, crm)
+	require.True(t, crm.Subscribes(WebhookEventUserCreated))
+	require.False(t, crm.Subscribes(WebhookEventLoginFailed))
+
+	siem := endpoints.Find("siem")
+	require.NotNil(t, siem)
+	for _, event := range WebhookEvents {
+		require.True(t, siem.Subscribes(event))
+	}
+
+	require.Nil(t, endpoints.Find("unknown"))
+	require.Equal(t, WebhookEndpointTypeHTTP, crm.EndpointType())
+
+	var publishers WebhookEndpoints
+	require.NoError(t, publishers.Decode(`[
+		{"name": "kafka", "type": "kafka", "url": "http://kafka-rest:8082", "topic": "auth.events", "username": "auth", "password": "secret"},
+		{"name": "nats", "type": "nats", "url": "tls://nats:4222", "topic": "auth.events"},
+		{"name": "postgres", "type": "postgres", "topic": "auth_events"}
+	]`))
+	require.NoError(t, publishers.Validate())
+	require.Equal(t, WebhookEndpointTypeNATS, publishers.Find("nats").EndpointType())
+
+	require.Error(t, endpoints.Decode(`{"name": "crm"}`))
+
+	for _, value := range []string{
+		`[{"name": "crm", "url": "https://crm.example.com", "secrets": ["` + secret + `"]}, {"name": "crm", "url": "https://crm.example.com", "secrets": ["` + secret + `"]}]`,
+		`[{"name": "CRM", "url": "https://crm.example.com", "secrets": ["` + secret + `"]}]`,
+		`[{"name": "crm", "url": "crm.example.com", "secrets": ["` + secret + `"]}]`,
+		`[{"name": "crm", "url": "https://crm.example.com"}]`,
+		`[{"name": "crm", "url": "https://crm.example.com", "secrets": ["secret"]}]`,
+		`[{"name": "crm", "url": "https://crm.example.com", "secrets": ["` + secret + `"], "events": ["user.updated"]}]`,
+		`[{"name": "events", "type": "sqs", "url": "https://sqs.example.com"}]`,
+		`[{"name": "events", "type": "kafka", "url": "kafka:9092", "topic": "auth.events"}]`,
+		`[{"name": "events", "type": "kafka", "url": "http://kafka-rest:8082"}]`,
+		`[{"name": "events", "type": "nats", "url": "http://nats:4222", "topic": "auth.events"}]`,
+		`[{"name": "events", "type": "nats", "url": "nats://nats:4222", "topic": "auth.>"}]`,
+		`[{"name": "events", "type": "postgres", "topic": "Auth Events"}]`,
+	} {
+		var invalid WebhookEndpoints
+		require.NoError(t, invalid.Decode(value))
+		require.Error(t, invalid.Validate(), value)
+	}
+
+	config := &WebhooksConfiguration{
+		Enabled:          true,
+		Endpoints:        endpoints,
+		Timeout:          time.Second,
+		MaxAttempts:      3,
+		RetryInterval:    time.Minute,
+		MaxRetryInterval: 3 * time.Minute,
+		PollInterval:     time.Second,
+		BatchSize:        10,
+		Retention:        time.Hour,
+	}
+	require.NoError(t, config.Validate())
+
+	now := time.Now()
+	require.Equal(t, now.Add(time.Minute), *config.RetryAt(1, now))
+	require.Equal(t, now.Add(2*time.Minute), *config.RetryAt(2, now))
+	require.Nil(t, config.RetryAt(3, now))
+
+	config.Endpoints = nil
+	require.Error(t, config.Validate())
+
+	require.NoError(t, (&WebhooksConfiguration{}).Validate())
+}
@@ -4,20 +4,28 @@ import (
 	"context"
 	"crypto/aes"
 	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha1" // #nosec G505 -- PBKDF2-SHA1 hashes are only verified for imported users
+	"crypto/sha256"
+	"crypto/sha512"
 	"crypto/subtle"
 	"encoding/base64"
 	"errors"
 	"fmt"
+	"hash"
 	"regexp"
 	"strconv"
 	"strings"
+	"sync/atomic"
 
+	"github.com/supabase/auth/internal/conf"
 	"github.com/supabase/auth/internal/observability"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/metric"
 
 	"golang.org/x/crypto/argon2"
 	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/crypto/pbkdf2"
 	"golang.org/x/crypto/scrypt"
 )
 
@@ -35,6 +43,8 @@ const (
 
 	Argon2Prefix         = "$argon2"
 	FirebaseScryptPrefix = "$fbscrypt"
+	ScryptPrefix         = "$scrypt$"
+	PBKDF2Prefix         = "$pbkdf2"
 	FirebaseScryptKeyLen = 32 // Firebase uses AES-256 which requires 32 byte keys: https://pkg.go.dev/golang.org/x/crypto/scrypt#Key
 )
 
@@ -55,11 +65,29 @@ var (
 
 var ErrArgon2MismatchedHashAndPassword = errors.New("crypto: argon2 hash and password mismatch")
 var ErrScryptMismatchedHashAndPassword = errors.New("crypto: fbscrypt hash and password mismatch")
+var ErrStdScryptMismatchedHashAndPassword = errors.New("crypto: scrypt hash and password mismatch")
+var ErrPBKDF2MismatchedHashAndPassword = errors.New("crypto: pbkdf2 hash and password mismatch")
 
 // argon2HashRegexp https://github.com/P-H-C/phc-string-format/blob/master/phc-sf-spec.md#argon2-encoding
 var argon2HashRegexp = regexp.MustCompile("^[$](?P<alg>argon2(d|i|id))[$]v=(?P<v>(16|19))[$]m=(?P<m>[0-9]+),t=(?P<t>[0-9]+),p=(?P<p>[0-9]+)(,keyid=(?P<keyid>[^,$]+))?(,data=(?P<data>[^$]+))?[$](?P<salt>[^$]*)[$](?P<hash>.*)$")
 var fbscryptHashRegexp = regexp.MustCompile(`^\$fbscrypt\$v=(?P<v>[0-9]+),n=(?P<n>[0-9]+),r=(?P<r>[0-9]+),p=(?P<p>[0-9]+)(?:,ss=(?P<ss>[^,]+))?(?:,sk=(?P<sk>[^$]+))?\$(?P<salt>[^$]+)\$(?P<hash>.+)$`)
 
+// scryptHashRegexp https://github.com/P-H-C/phc-string-format/blob/master/phc-sf-spec.md
+// as produced by passlib, ln is the base 2 logarithm of the N parameter
+var scryptHashRegexp = regexp.MustCompile(`^\$scrypt\$ln=(?P<ln>[0-9]+),r=(?P<r>[0-9]+),p=(?P<p>[0-9]+)\$(?P<salt>[^$]+)\$(?P<hash>[^$]+)$`)
+
+// The costs of imported scrypt and PBKDF2 hashes are capped, so that a hash
+// can't exhaust the memory or CPU of the server when a password is verified.
+const (
+	scryptMaxLn         = 20      // N of 2^20
+	scryptMaxRP         = 64      // r·p
+	scryptMaxMemory     = 1 << 30 // 128·r·N bytes
+	pbkdf2MaxIterations = 5_000_000
+)
+
+// pbkdf2HashRegexp https://github.com/simonepri/phc-pbkdf2
+var pbkdf2HashRegexp = regexp.MustCompile(`^\$pbkdf2-(?P<alg>sha1|sha256|sha512)\$i=(?P<i>[0-9]+)(?:,l=(?P<l>[0-9]+))?\$(?P<salt>[^$]+)\$(?P<hash>[^$]+)$`)
+
 type Argon2HashInput struct {
 	alg     string
 	v       string
@@ -83,6 +111,129 @@ type FirebaseScryptHashInput struct {
 	rawHash       []byte
 }
 
+type ScryptHashInput struct {
+	memory  uint64
+	rounds  uint64
+	threads uint64
+	salt    []byte
+	rawHash []byte
+}
+
+type PBKDF2HashInput struct {
+	alg        string
+	iterations uint64
+	salt       []byte
+	rawHash    []byte
+}
+
+// decodePHCBase64 decodes the base64 of PHC strings, which omits padding.
+// The "." used instead of "+" by passlib is accepted as well.
+func decodePHCBase64(value string) ([]byte, error) {
+	return base64.RawStdEncoding.DecodeString(strings.TrimRight(strings.ReplaceAll(value, ".", "+"), "="))
+}
+
+func ParseScryptHash(hash string) (*ScryptHashInput, error) {
+	submatch := scryptHashRegexp.FindStringSubmatchIndex(hash)
+	if submatch == nil {
+		return nil, errors.New("crypto: incorrect scrypt hash format")
+	}
+
+	ln := string(scryptHashRegexp.ExpandString(nil, "$ln", hash, submatch))
+	r := string(scryptHashRegexp.ExpandString(nil, "$r", hash, submatch))
+	p := string(scryptHashRegexp.ExpandString(nil, "$p", hash, submatch))
+	saltB64 := string(scryptHashRegexp.ExpandString(nil, "$salt", hash, submatch))
+	hashB64 := string(scryptHashRegexp.ExpandString(nil, "$hash", hash, submatch))
+
+	memoryPower, err := strconv.ParseUint(ln, 10, 8)
+	if err != nil || memoryPower == 0 || memoryPower > scryptMaxLn {
+		return nil, fmt.Errorf("crypto: scrypt hash has invalid ln parameter %q", ln)
+	}
+
+	rounds, err := strconv.ParseUint(r, 10, 32)
+	if err != nil || rounds == 0 {
+		return nil, fmt.Errorf("crypto: scrypt hash has invalid r parameter %q", r)
+	}
+
+	threads, err := strconv.ParseUint(p, 10, 8)
+	if err != nil || threads == 0 {
+		return nil, fmt.Errorf("crypto: scrypt hash has invalid p parameter %q", p)
+	}
+
+	if rounds*threads > scryptMaxRP || 128*rounds<<memoryPower > scryptMaxMemory {
+		return nil, fmt.Errorf("crypto: scrypt hash parameters ln=%s,r=%s,p=%s exceed the supported cost", ln, r, p)
+	}
+
+	rawHash, err := decodePHCBase64(hashB64)
+	if err != nil {
+		return nil, fmt.Errorf("crypto: scrypt hash has invalid base64 in the hash section %w", err)
+	}
+	if len(rawHash) == 0 {
+		return nil, errors.New("crypto: scrypt hash is empty")
+	}
+
+	salt, err := decodePHCBase64(saltB64)
+	if err != nil {
+		return nil, fmt.Errorf("crypto: scrypt hash has invalid base64 in the salt section %w", err)
+	}
+
+	input := &ScryptHashInput{
+		memory:  uint64(1) << memoryPower,
+		rounds:  rounds,
+		threads: threads,
+		salt:    salt,
+		rawHash: rawHash,
+	}
+
+	return input, nil
+}
+
+func ParsePBKDF2Hash(hash string) (*PBKDF2HashInput, error) {
+	submatch := pbkdf2HashRegexp.FindStringSubmatchIndex(hash)
+	if submatch == nil {
+		return nil, errors.New("crypto: incorrect pbkdf2 hash format")
+	}
+
+	alg := string(pbkdf2HashRegexp.ExpandString(nil, "$alg", hash, submatch))
+	i := string(pbkdf2HashRegexp.ExpandString(nil, "$i", hash, submatch))
+	l := string(pbkdf2HashRegexp.ExpandString(nil, "$l", hash, submatch))
+	saltB64 := string(pbkdf2HashRegexp.ExpandString(nil, "$salt", hash, submatch))
+	hashB64 := string(pbkdf2HashRegexp.ExpandString(nil, "$hash", hash, submatch))
+
+	iterations, err := strconv.ParseUint(i, 10, 32)
+	if err != nil || iterations == 0 || iterations > pbkdf2MaxIterations {
+		return nil, fmt.Errorf("crypto: pbkdf2 hash has invalid i parameter %q", i)
+	}
+
+	rawHash, err := decodePHCBase64(hashB64)
+	if err != nil {
+		return nil, fmt.Errorf("crypto: pbkdf2 hash has invalid base64 in the hash section %w", err)
+	}
+	if len(rawHash) == 0 {
+		return nil, errors.New("crypto: pbkdf2 hash is empty")
+	}
+
+	if l != "" {
+		length, err := strconv.Atoi(l)
+		if err != nil || length != len(rawHash) {
+			return nil, fmt.Errorf("crypto: pbkdf2 hash has invalid l parameter %q", l)
+		}
+	}
+
+	salt, err := decodePHCBase64(saltB64)
+	if err != nil {
+		return nil, fmt.Errorf("crypto: pbkdf2 hash has invalid base64 in the salt section %w", err)
+	}
+
+	input := &PBKDF2HashInput{
+		alg:        alg,
+		iterations: iterations,
+		salt:       salt,
+		rawHash:    rawHash,
+	}
+
+	return input, nil
+}
+
 // See: https://github.com/firebase/scrypt for implementation
 func ParseFirebaseScryptHash(hash string) (*FirebaseScryptHashInput, error) {
 	submatch := fbscryptHashRegexp.FindStringSubmatchIndex(hash)
@@ -310,6 +461,109 @@ func compareHashAndPasswordFirebaseScrypt(ctx context.Context, hash, password st
 	return nil
 }
 
+func compareHashAndPasswordScrypt(ctx context.Context, hash, password string) error {
+	input, err := ParseScryptHash(hash)
+	if err != nil {
+		return err
+	}
+
+	attributes := []attribute.KeyValue{
+		attribute.String("alg", "scrypt"),
+		attribute.Int64("n", int64(input.memory)),
+		attribute.Int64("r", int64(input.rounds)),
+		attribute.Int("p", int(input.threads)),
+		attribute.Int("len", len(input.rawHash)),
+	} // #nosec G115
+
+	var match bool
+	compareHashAndPasswordSubmittedCounter.Add(ctx, 1, metric.WithAttributes(attributes...))
+	defer func() {
+		attributes = append(attributes, attribute.Bool("match", match))
+		compareHashAndPasswordCompletedCounter.Add(ctx, 1, metric.WithAttributes(attributes...))
+	}()
+
+	derivedKey, err := scrypt.Key([]byte(password), input.salt, int(input.memory), int(input.rounds), int(input.threads), len(input.rawHash)) // #nosec G115
+	if err != nil {
+		return err
+	}
+
+	match = subtle.ConstantTimeCompare(derivedKey, input.rawHash) == 1
+	if !match {
+		return ErrStdScryptMismatchedHashAndPassword
+	}
+
+	return nil
+}
+
+func compareHashAndPasswordPBKDF2(ctx context.Context, encodedHash, password string) error {
+	input, err := ParsePBKDF2Hash(encodedHash)
+	if err != nil {
+		return err
+	}
+
+	attributes := []attribute.KeyValue{
+		attribute.String("alg", "pbkdf2-"+input.alg),
+		attribute.Int64("i", int64(input.iterations)),
+		attribute.Int("len", len(input.rawHash)),
+	} // #nosec G115
+
+	var match bool
+	compareHashAndPasswordSubmittedCounter.Add(ctx, 1, metric.WithAttributes(attributes...))
+	defer func() {
+		attributes = append(attributes, attribute.Bool("match", match))
+		compareHashAndPasswordCompletedCounter.Add(ctx, 1, metric.WithAttributes(attributes...))
+	}()
+
+	var h func() hash.Hash
+	switch input.alg {
+	case "sha1":
+		h = sha1.New
+	case "sha256":
+		h = sha256.New
+	case "sha512":
+		h = sha512.New
+	}
+
+	derivedKey := pbkdf2.Key([]byte(password), input.salt, int(input.iterations), len(input.rawHash), h) // #nosec G115
+
+	match = subtle.ConstantTimeCompare(derivedKey, input.rawHash) == 1
+	if !match {
+		return ErrPBKDF2MismatchedHashAndPassword
+	}
+
+	return nil
+}
+
+// IsBcryptHash reports whether the hash is not one of the other supported
+// formats, which are all assumed to be bcrypt hashes.
+func IsBcryptHash(hash string) bool {
+	return !strings.HasPrefix(hash, Argon2Prefix) &&
+		!strings.HasPrefix(hash, FirebaseScryptPrefix) &&
+		!strings.HasPrefix(hash, ScryptPrefix) &&
+		!strings.HasPrefix(hash, PBKDF2Prefix)
+}
+
+// ValidatePasswordHash checks that the hash is in one of the supported
+// formats: bcrypt, argon2, Firebase scrypt, scrypt or PBKDF2.
+func ValidatePasswordHash(hash string) error {
+	var err error
+
+	switch {
+	case strings.HasPrefix(hash, Argon2Prefix):
+		_, err = ParseArgon2Hash(hash)
+	case strings.HasPrefix(hash, FirebaseScryptPrefix):
+		_, err = ParseFirebaseScryptHash(hash)
+	case strings.HasPrefix(hash, ScryptPrefix):
+		_, err = ParseScryptHash(hash)
+	case strings.HasPrefix(hash, PBKDF2Prefix):
+		_, err = ParsePBKDF2Hash(hash)
+	default:
+		_, err = bcrypt.Cost([]byte(hash))
+	}
+
+	return err
+}
+
 func firebaseScrypt(password, salt, signerKey, saltSeparator []byte, memCost, rounds, p uint64) []byte {
 	ck := must(scrypt.Key(password, append(salt, saltSeparator...), int(memCost), int(rounds), int(p), FirebaseScryptKeyLen)) // #nosec G115
 	block := must(aes.NewCipher(ck))
@@ -326,11 +580,26 @@ func firebaseScrypt(password, salt, signerKey, saltSeparator []byte, memCost, ro
 // CompareHashAndPassword compares the hash and
 // password, returns nil if equal otherwise an error. Context can be used to
 // cancel the hashing if the algorithm supports it.
-func CompareHashAndPassword(ctx context.Context, hash, password string) error {
+func CompareHashAndPassword(ctx context.Context, hash, password string) (err error) {
+	// A mismatch is not an error of the operation, so it is an attribute.
+	ctx, span := observability.StartSpan(ctx, "password.compare")
+	defer func() {
+		span.SetAttributes(attribute.Bool("gotrue.password.match", err == nil))
+		span.End()
+	}()
+
+	return compareHashAndPassword(ctx, hash, password)
+}
+
+func compareHashAndPassword(ctx context.Context, hash, password string) error {
 	if strings.HasPrefix(hash, Argon2Prefix) {
 		return compareHashAndPasswordArgon2(ctx, hash, password)
 	} else if strings.HasPrefix(hash, FirebaseScryptPrefix) {
 		return compareHashAndPasswordFirebaseScrypt(ctx, hash, password)
+	} else if strings.HasPrefix(hash, ScryptPrefix) {
+		return compareHashAndPasswordScrypt(ctx, hash, password)
+	} else if strings.HasPrefix(hash, PBKDF2Prefix) {
+		return compareHashAndPasswordPBKDF2(ctx, hash, password)
 	}
 
 	// assume bcrypt
@@ -358,10 +627,90 @@ func CompareHashAndPassword(ctx context.Context, hash, password string) error {
 	return err
 }
 
-// GenerateFromPassword generates a password hash from a
-// password, using PasswordHashCost. Context can be used to cancel the hashing
-// if the algorithm supports it.
-func GenerateFromPassword(ctx context.Context, password string) (string, error) {
+var passwordHashing atomic.Pointer[conf.PasswordHashingConfiguration]
+
+// ConfigurePasswordHashing sets the algorithm and parameters
+// GenerateFromPassword hashes passwords with, bcrypt is used until it is
+// called.
+func ConfigurePasswordHashing(config *conf.PasswordHashingConfiguration) {
+	c := *config
+	passwordHashing.Store(&c)
+}
+
+func passwordHashAlgorithm() string {
+	if c := passwordHashing.Load(); c != nil && c.Algorithm != "" {
+		return c.Algorithm
+	}
+	return conf.PasswordHashAlgorithmBcrypt
+}
+
+// argon2idParameters returns the configured memory, iterations and
+// parallelism of Argon2id hashes.
+func argon2idParameters() (uint32, uint32, uint8) {
+	if PasswordHashCost == QuickHashCost {
+		return 64, 1, 1
+	}
+
+	c := passwordHashing.Load()
+	if c == nil {
+		return 19456, 2, 1
+	}
+	return c.Argon2Memory, c.Argon2Iterations, c.Argon2Parallelism
+}
+
+// CurrentPasswordHash returns the algorithm GenerateFromPassword hashes
+// passwords with, and the prefix of the hashes it generates.
+func CurrentPasswordHash() (algorithm string, prefix string) {
+	algorithm = passwordHashAlgorithm()
+	if algorithm == conf.PasswordHashAlgorithmArgon2id {
+		memory, iterations, parallelism := argon2idParameters()
+		return algorithm, fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$", argon2.Version, memory, iterations, parallelism)
+	}
+
+	return algorithm, "$2"
+}
+
+// NeedsRehash reports whether a verified password's hash should be replaced
+// with one generated by GenerateFromPassword: when it is an imported scrypt
+// or PBKDF2 hash, or Argon2id is configured and the hash is not an Argon2id
+// hash with the configured parameters. The cost of bcrypt hashes is not
+// considered.
+func NeedsRehash(hash string) bool {
+	if strings.HasPrefix(hash, ScryptPrefix) || strings.HasPrefix(hash, PBKDF2Prefix) {
+		return true
+	}
+
+	if passwordHashAlgorithm() != conf.PasswordHashAlgorithmArgon2id {
+		return false
+	}
+
+	input, err := ParseArgon2Hash(hash)
+	if err != nil {
+		return true
+	}
+
+	memory, iterations, parallelism := argon2idParameters()
+	return input.alg != "argon2id" ||
+		input.memory != uint64(memory) ||
+		input.time != uint64(iterations) ||
+		input.threads != uint64(parallelism)
+}
+
+// GenerateFromPassword generates a password hash from a password with the
+// configured algorithm, using PasswordHashCost. Context can be used to
+// cancel the hashing if the algorithm supports it.
+func GenerateFromPassword(ctx context.Context, password string) (hash string, err error) {
+	ctx, span := observability.StartSpan(ctx, "password.hash")
+	defer func() { observability.EndSpan(span, err) }()
+
+	return generateFromPassword(ctx, password)
+}
+
+func generateFromPassword(ctx context.Context, password string) (string, error) {
+	if passwordHashAlgorithm() == conf.PasswordHashAlgorithmArgon2id {
+		return generateFromPasswordArgon2id(ctx, password)
+	}
+
 	hashCost := bcrypt.DefaultCost
 
 	switch PasswordHashCost {
@@ -382,6 +731,32 @@ func GenerateFromPassword(ctx context.Context, password string) (string, error)
 	return string(hash), nil
 }
 
+func generateFromPasswordArgon2id(ctx context.Context, password string) (string, error) {
+	memory, iterations, parallelism := argon2idParameters()
+
+	attributes := []attribute.KeyValue{
+		attribute.String("alg", "argon2id"),
+		attribute.Int64("m", int64(memory)),
+		attribute.Int64("t", int64(iterations)),
+		attribute.Int("p", int(parallelism)),
+	}
+
+	generateFromPasswordSubmittedCounter.Add(ctx, 1, metric.WithAttributes(attributes...))
+	defer generateFromPasswordCompletedCounter.Add(ctx, 1, metric.WithAttributes(attributes...))
+
+	salt := make([]byte, 16)
+	must(rand.Read(salt))
+
+	key := argon2.IDKey([]byte(password), salt, iterations, memory, parallelism, 32)
+
+	return fmt.Sprintf(
+		"$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, memory, iterations, parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
 func GeneratePassword(requiredChars []string, length int) string {
 	passwordBuilder := strings.Builder{}
 	passwordBuilder.Grow(length)
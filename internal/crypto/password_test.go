@@ -6,6 +6,8 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/supabase/auth/internal/conf"
 )
 
 func TestArgon2(t *testing.T) {
@@ -176,3 +178,125 @@ func TestBcrypt(t *testing.T) {
 		assert.Error(t, CompareHashAndPassword(context.Background(), example, "test"))
 	}
 }
+
+func TestScrypt(t *testing.T) {
+	// all of these hash the `test` string
+
+	examples := []string{
+		"$scrypt$ln=4,r=8,p=1$c2FsdHNhbHRzYWx0c2FsdA$Xm5Jhj+IG4YfthdtDVddQUEAe9RXODln+i0vrX/P38c",
+		// passlib's adapted base64
+		"$scrypt$ln=4,r=8,p=1$c2FsdHNhbHRzYWx0c2FsdA$Xm5Jhj.IG4YfthdtDVddQUEAe9RXODln.i0vrX/P38c",
+	}
+
+	for _, example := range examples {
+		assert.NoError(t, ValidatePasswordHash(example))
+		assert.NoError(t, CompareHashAndPassword(context.Background(), example, "test"))
+		assert.Error(t, CompareHashAndPassword(context.Background(), example, "test1"))
+	}
+
+	negativeExamples := []string{
+		// ln out of range
+		"$scrypt$ln=0,r=8,p=1$c2FsdHNhbHRzYWx0c2FsdA$Xm5Jhj+IG4YfthdtDVddQUEAe9RXODln+i0vrX/P38c",
+		"$scrypt$ln=33,r=8,p=1$c2FsdHNhbHRzYWx0c2FsdA$Xm5Jhj+IG4YfthdtDVddQUEAe9RXODln+i0vrX/P38c",
+		"$scrypt$ln=21,r=8,p=1$c2FsdHNhbHRzYWx0c2FsdA$Xm5Jhj+IG4YfthdtDVddQUEAe9RXODln+i0vrX/P38c",
+		// r·p or memory above the cap
+		"$scrypt$ln=4,r=4294967295,p=1$c2FsdHNhbHRzYWx0c2FsdA$Xm5Jhj+IG4YfthdtDVddQUEAe9RXODln+i0vrX/P38c",
+		"$scrypt$ln=4,r=8,p=9$c2FsdHNhbHRzYWx0c2FsdA$Xm5Jhj+IG4YfthdtDVddQUEAe9RXODln+i0vrX/P38c",
+		"$scrypt$ln=20,r=16,p=1$c2FsdHNhbHRzYWx0c2FsdA$Xm5Jhj+IG4YfthdtDVddQUEAe9RXODln+i0vrX/P38c",
+		// p larger than 8 bits
+		"$scrypt$ln=4,r=8,p=256$c2FsdHNhbHRzYWx0c2FsdA$Xm5Jhj+IG4YfthdtDVddQUEAe9RXODln+i0vrX/P38c",
+		// salt not Base64
+		"$scrypt$ln=4,r=8,p=1$!!!$Xm5Jhj+IG4YfthdtDVddQUEAe9RXODln+i0vrX/P38c",
+		// hash missing
+		"$scrypt$ln=4,r=8,p=1$c2FsdHNhbHRzYWx0c2FsdA$",
+	}
+
+	for _, example := range negativeExamples {
+		assert.Error(t, ValidatePasswordHash(example))
+		assert.Error(t, CompareHashAndPassword(context.Background(), example, "test"))
+	}
+}
+
+func TestPBKDF2(t *testing.T) {
+	// all of these hash the `test` string
+
+	examples := []string{
+		"$pbkdf2-sha1$i=1000,l=32$c2FsdHNhbHRzYWx0c2FsdA$9RFFGO2qiTl+r3U617Ka74PcsImet0IIBfqaeKeqOT0",
+		"$pbkdf2-sha256$i=1000,l=32$c2FsdHNhbHRzYWx0c2FsdA$4+KBqsOVLqj6E9Td14CGM3lT9IqBoXdgdB7aKUjWvVE",
+		"$pbkdf2-sha256$i=1000$c2FsdHNhbHRzYWx0c2FsdA$4+KBqsOVLqj6E9Td14CGM3lT9IqBoXdgdB7aKUjWvVE",
+		"$pbkdf2-sha512$i=1000,l=64$c2FsdHNhbHRzYWx0c2FsdA$dyidlA4OGvsctKcO0KZFOlGtA27staAA8mDrz6VzN3SelD3nnmE2WsnMdcGQvfaA2JIllqdSoSWAJNMZXPY7FQ",
+	}
+
+	for _, example := range examples {
+		assert.NoError(t, ValidatePasswordHash(example))
+		assert.NoError(t, CompareHashAndPassword(context.Background(), example, "test"))
+		assert.Error(t, CompareHashAndPassword(context.Background(), example, "test1"))
+	}
+
+	negativeExamples := []string{
+		// unsupported digest
+		"$pbkdf2-md5$i=1000$c2FsdHNhbHRzYWx0c2FsdA$4+KBqsOVLqj6E9Td14CGM3lT9IqBoXdgdB7aKUjWvVE",
+		// zero iterations
+		"$pbkdf2-sha256$i=0$c2FsdHNhbHRzYWx0c2FsdA$4+KBqsOVLqj6E9Td14CGM3lT9IqBoXdgdB7aKUjWvVE",
+		// iterations above the cap
+		"$pbkdf2-sha256$i=4294967295$c2FsdHNhbHRzYWx0c2FsdA$4+KBqsOVLqj6E9Td14CGM3lT9IqBoXdgdB7aKUjWvVE",
+		// length does not match the hash
+		"$pbkdf2-sha256$i=1000,l=16$c2FsdHNhbHRzYWx0c2FsdA$4+KBqsOVLqj6E9Td14CGM3lT9IqBoXdgdB7aKUjWvVE",
+		// hash not Base64
+		"$pbkdf2-sha256$i=1000$c2FsdHNhbHRzYWx0c2FsdA$!!!",
+	}
+
+	for _, example := range negativeExamples {
+		assert.Error(t, ValidatePasswordHash(example))
+		assert.Error(t, CompareHashAndPassword(context.Background(), example, "test"))
+	}
+}
+
+func TestGenerateFromPasswordArgon2id(t *testing.T) {
+	ctx := context.Background()
+
+	ConfigurePasswordHashing(&conf.PasswordHashingConfiguration{
+		Algorithm:         conf.PasswordHashAlgorithmArgon2id,
+		Argon2Memory:      64,
+		Argon2Iterations:  1,
+		Argon2Parallelism: 1,
+	})
+	t.Cleanup(func() {
+		ConfigurePasswordHashing(&conf.PasswordHashingConfiguration{Algorithm: conf.PasswordHashAlgorithmBcrypt})
+	})
+
+	hash, err := GenerateFromPassword(ctx, "test")
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(hash, "$argon2id$v=19$m=64,t=1,p=1$"))
+
+	algorithm, prefix := CurrentPasswordHash()
+	require.Equal(t, conf.PasswordHashAlgorithmArgon2id, algorithm)
+	require.True(t, strings.HasPrefix(hash, prefix))
+	require.NoError(t, CompareHashAndPassword(ctx, hash, "test"))
+	require.Error(t, CompareHashAndPassword(ctx, hash, "test1"))
+
+	other, err := GenerateFromPassword(ctx, "test")
+	require.NoError(t, err)
+	require.NotEqual(t, hash, other, "hashes are salted")
+
+	// hashes are upgraded to Argon2id with the configured parameters
+	require.False(t, NeedsRehash(hash))
+	require.True(t, NeedsRehash("$2y$10$srNl09aPtc2qr.0Vl.NtjekJRt/NxRxYQm3qd3OvfcKsJgVnr6.Ve"))
+	require.True(t, NeedsRehash("$argon2i$v=19$m=16,t=2,p=1$bGJRWThNOHJJTVBSdHl2dQ$NfEnUOuUpb7F2fQkgFUG4g"))
+	require.True(t, NeedsRehash("$argon2id$v=19$m=32,t=3,p=2$SFVpOWJ0eXhjRzVkdGN1RQ$RXnb8rh7LaDcn07xsssqqulZYXOM/EUCEFMVcAcyYVk"))
+
+	// bcrypt hashes are kept when bcrypt is configured
+	ConfigurePasswordHashing(&conf.PasswordHashingConfiguration{Algorithm: conf.PasswordHashAlgorithmBcrypt})
+
+	hash, err = GenerateFromPassword(ctx, "test")
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(hash, "$2a$"))
+	require.False(t, NeedsRehash(hash))
+
+	algorithm, prefix = CurrentPasswordHash()
+	require.Equal(t, conf.PasswordHashAlgorithmBcrypt, algorithm)
+	require.True(t, strings.HasPrefix(hash, prefix))
+	require.False(t, NeedsRehash("$argon2id$v=19$m=32,t=3,p=2$SFVpOWJ0eXhjRzVkdGN1RQ$RXnb8rh7LaDcn07xsssqqulZYXOM/EUCEFMVcAcyYVk"))
+	require.True(t, NeedsRehash("$scrypt$ln=4,r=8,p=1$c2FsdHNhbHRzYWx0c2FsdA$Xm5Jhj+IG4YfthdtDVddQUEAe9RXODln+i0vrX/P38c"))
+	require.True(t, NeedsRehash("$pbkdf2-sha256$i=1000$c2FsdHNhbHRzYWx0c2FsdA$4+KBqsOVLqj6E9Td14CGM3lT9IqBoXdgdB7aKUjWvVE"))
+}
@@ -0,0 +1,147 @@
+// Package geoip looks up the country and city of IP addresses, which are
+// recorded on sessions and shown in new sign-in notifications.
+package geoip
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/supabase/auth/internal/conf"
+)
+
+// Location is where an IP address is located. Country is the ISO 3166-1
+// alpha-2 code of the country and City its English name, either can be
+// empty when unknown.
+type Location struct {
+	Country string `json:"country,omitempty"`
+	City    string `json:"city,omitempty"`
+}
+
+// Locator looks up the location of the IP address of a request. It returns
+// nil when the location is unknown.
+type Locator interface {
+	Lookup(r *http.Request, ip string) (*Location, error)
+}
+
+// New returns the locator of the configured provider, or nil when no
+// provider is configured.
+func New(config *conf.GeoIPConfiguration) Locator {
+	switch config.Provider {
+	case conf.GeoIPProviderMaxMind:
+		return &MaxMind{
+			AccountID:  config.MaxMind.AccountID,
+			LicenseKey: config.MaxMind.LicenseKey,
+			URL:        config.MaxMind.URL,
+			HTTPClient: &http.Client{Timeout: config.Timeout},
+		}
+	case conf.GeoIPProviderHeader:
+		return &Header{
+			CountryHeader: config.CountryHeader,
+			CityHeader:    config.CityHeader,
+		}
+	default:
+		return nil
+	}
+}
+
+// MaxMind looks up locations with the MaxMind GeoIP2 or GeoLite2 City web
+// service.
+type MaxMind struct {
+	AccountID  string
+	LicenseKey string
+	URL        string
+	HTTPClient *http.Client
+}
+
+type maxMindResponse struct {
+	City struct {
+		Names map[string]string `json:"names"`
+	} `json:"city"`
+	Country struct {
+		ISOCode string `json:"iso_code"`
+	} `json:"country"`
+}
+
+func (m *MaxMind) Lookup(r *http.Request, ip string) (*Location, error) {
+	if !isPublicIP(ip) {
+		return nil, nil
+	}
+
+	req, err := http.NewRequestWithContext(r.Context(), http.MethodGet, strings.TrimSuffix(m.URL, "/")+"/"+url.PathEscape(ip), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.SetBasicAuth(m.AccountID, m.LicenseKey)
+	req.Header.Set("Accept", "application/json")
+
+	client := m.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(res.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+
+	// addresses missing from the database are not an error
+	if res.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("geoip: maxmind request failed with status %d: %s", res.StatusCode, body)
+	}
+
+	var data maxMindResponse
+	if err := json.Unmarshal(body, &data); err != nil {
+		return nil, fmt.Errorf("geoip: invalid maxmind response: %w", err)
+	}
+
+	if data.Country.ISOCode == "" {
+		return nil, nil
+	}
+
+	return &Location{
+		Country: data.Country.ISOCode,
+		City:    data.City.Names["en"],
+	}, nil
+}
+
+// Header reads locations from headers set by a CDN or proxy, such as the
+// CF-IPCountry and CF-IPCity headers of Cloudflare. Only use it when all
+// requests go through the proxy, as clients can set the headers themselves.
+type Header struct {
+	CountryHeader string
+	CityHeader    string
+}
+
+func (h *Header) Lookup(r *http.Request, ip string) (*Location, error) {
+	country := strings.ToUpper(strings.TrimSpace(r.Header.Get(h.CountryHeader)))
+
+	// Cloudflare uses XX for unknown countries and T1 for Tor
+	if country == "" || country == "XX" || country == "T1" {
+		return nil, nil
+	}
+
+	location := &Location{Country: country}
+	if h.CityHeader != "" {
+		location.City = strings.TrimSpace(r.Header.Get(h.CityHeader))
+	}
+	return location, nil
+}
+
+func isPublicIP(ip string) bool {
+	addr := net.ParseIP(ip)
+	return addr != nil && addr.IsGlobalUnicast() && !addr.IsPrivate()
+}
@@ -0,0 +1,80 @@
+package geoip
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/supabase/auth/internal/conf"
+)
+
+func TestMaxMind(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		username, password, ok := r.BasicAuth()
+		require.True(t, ok)
+		require.Equal(t, "42", username)
+		require.Equal(t, "license", password)
+
+		switch r.URL.Path {
+		case "/city/81.2.69.142":
+			w.Write([]byte(`{"city": {"names": {"en": "London", "de": "London"}}, "country": {"iso_code": "GB"}}`))
+		case "/city/1.1.1.1":
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"code": "IP_ADDRESS_NOT_FOUND"}`))
+		default:
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"code": "AUTHORIZATION_INVALID"}`))
+		}
+	}))
+	defer srv.Close()
+
+	locator := New(&conf.GeoIPConfiguration{
+		Provider: conf.GeoIPProviderMaxMind,
+		MaxMind: conf.GeoIPMaxMindConfiguration{
+			AccountID:  "42",
+			LicenseKey: "license",
+			URL:        srv.URL + "/city/",
+		},
+	})
+	req := httptest.NewRequest(http.MethodPost, "/token", nil)
+
+	location, err := locator.Lookup(req, "81.2.69.142")
+	require.NoError(t, err)
+	require.Equal(t, &Location{Country: "GB", City: "London"}, location)
+
+	location, err = locator.Lookup(req, "1.1.1.1")
+	require.NoError(t, err)
+	require.Nil(t, location)
+
+	// private addresses are not looked up
+	location, err = locator.Lookup(req, "10.0.0.1")
+	require.NoError(t, err)
+	require.Nil(t, location)
+
+	_, err = locator.Lookup(req, "8.8.8.8")
+	require.ErrorContains(t, err, "status 401")
+}
+
+func TestHeader(t *testing.T) {
+	locator := New(&conf.GeoIPConfiguration{
+		Provider:      conf.GeoIPProviderHeader,
+		CountryHeader: "CF-IPCountry",
+		CityHeader:    "CF-IPCity",
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/token", nil)
+	req.Header.Set("CF-IPCountry", "de")
+	req.Header.Set("CF-IPCity", "Berlin")
+
+	location, err := locator.Lookup(req, "")
+	require.NoError(t, err)
+	require.Equal(t, &Location{Country: "DE", City: "Berlin"}, location)
+
+	req.Header.Set("CF-IPCountry", "XX")
+	location, err = locator.Lookup(req, "")
+	require.NoError(t, err)
+	require.Nil(t, location)
+
+	require.Nil(t, New(&conf.GeoIPConfiguration{}))
+}
@@ -18,6 +18,7 @@ import (
 	"github.com/supabase/auth/internal/api/apierrors"
 	"github.com/supabase/auth/internal/conf"
 	"github.com/supabase/auth/internal/observability"
+	"github.com/supabase/auth/internal/utilities"
 
 	standardwebhooks "github.com/standard-webhooks/standard-webhooks/libraries/go"
 )
@@ -152,6 +153,9 @@ func (o *Dispatcher) runHTTPHook(
 		req.Header.Set("webhook-id", msgID.String())
 		req.Header.Set("webhook-timestamp", fmt.Sprintf("%d", currentTime.Unix()))
 		req.Header.Set("webhook-signature", strings.Join(signatureList, ", "))
+		if requestID := utilities.GetRequestID(ctx); requestID != "" {
+			req.Header.Set(observability.RequestIDHeaderName, requestID)
+		}
 		// By default, Go Client sets encoding to gzip, which does not carry a content length header.
 		req.Header.Set("Accept-Encoding", "identity")
 
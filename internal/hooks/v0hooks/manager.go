@@ -16,6 +16,7 @@ import (
 	"github.com/supabase/auth/internal/hooks/hookspgfunc"
 	"github.com/supabase/auth/internal/observability"
 	"github.com/supabase/auth/internal/storage"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 type Manager struct {
@@ -60,8 +61,14 @@ func configByName(
 		return &cfg.PasswordVerificationAttempt, true
 	case BeforeUserCreated:
 		return &cfg.BeforeUserCreated, true
+	case BeforeSignIn:
+		return &cfg.BeforeSignIn, true
 	case AfterUserCreated:
 		return &cfg.AfterUserCreated, true
+	case AfterUserDeleted:
+		return &cfg.AfterUserDeleted, true
+	case OAuthConsent:
+		return &cfg.OAuthConsent, true
 	default:
 		return nil, false
 	}
@@ -138,6 +145,14 @@ func (o *Manager) invokeHook(
 		return o.dispatch(
 			r.Context(), &o.config.Hook.BeforeUserCreated, conn, input, output)
 
+	case *BeforeSignInInput:
+		if _, ok := output.(*BeforeSignInOutput); !ok {
+			return apierrors.NewInternalServerError(
+				"output should be *hooks.BeforeSignInOutput")
+		}
+		return o.dispatch(
+			r.Context(), &o.config.Hook.BeforeSignIn, conn, input, output)
+
 	case *AfterUserCreatedInput:
 		_, ok := output.(*AfterUserCreatedOutput)
 		if !ok {
@@ -146,6 +161,22 @@ func (o *Manager) invokeHook(
 		}
 		return o.dispatch(
 			r.Context(), &o.config.Hook.AfterUserCreated, conn, input, output)
+
+	case *AfterUserDeletedInput:
+		if _, ok := output.(*AfterUserDeletedOutput); !ok {
+			return apierrors.NewInternalServerError(
+				"output should be *hooks.AfterUserDeletedOutput")
+		}
+		return o.dispatch(
+			r.Context(), &o.config.Hook.AfterUserDeleted, conn, input, output)
+
+	case *OAuthConsentInput:
+		if _, ok := output.(*OAuthConsentOutput); !ok {
+			return apierrors.NewInternalServerError(
+				"output should be *hooks.OAuthConsentOutput")
+		}
+		return o.dispatch(
+			r.Context(), &o.config.Hook.OAuthConsent, conn, input, output)
 	}
 }
 
@@ -159,6 +190,9 @@ func (o *Manager) dispatch(
 	hookStart := time.Now()
 
 	var err error
+	ctx, span := observability.StartSpan(ctx, "hook.run", attribute.String("gotrue.hook.uri", hookConfig.URI))
+	defer func() { observability.EndSpan(span, err) }()
+
 	switch {
 	case strings.HasPrefix(hookConfig.URI, "http:") ||
 		strings.HasPrefix(hookConfig.URI, "https:"):
@@ -288,6 +288,55 @@ func TestHooks(t *testing.T) {
 				end; $$ language plpgsql;`,
 		},
 
+		{
+			desc: "pass - before_user_created metadata",
+			setup: func() {
+				globalCfg.Hook.BeforeUserCreated =
+					conf.ExtensibilityPointConfiguration{
+						URI: `pg-functions://postgres/auth/` +
+							`v0hooks_test_before_user_created_metadata`,
+						HookName: `"auth"."v0hooks_test_before_user_created_metadata"`,
+					}
+			},
+			req: NewBeforeUserCreatedInput(httpReq, &models.User{}),
+			res: &BeforeUserCreatedOutput{},
+			exp: &BeforeUserCreatedOutput{
+				MetaDataChanges: MetaDataChanges{
+					UserMetaData: M{"plan": "free"},
+					AppMetaData:  M{"tenant": "acme"},
+				},
+			},
+			sql: `
+				create or replace function
+					v0hooks_test_before_user_created_metadata(input jsonb)
+				returns json as $$
+				begin
+					return '{"user_metadata": {"plan": "free"}, "app_metadata": {"tenant": "acme"}}'::jsonb;
+				end; $$ language plpgsql;`,
+		},
+
+		{
+			desc: "pass - before_sign_in reject with message",
+			setup: func() {
+				globalCfg.Hook.BeforeSignIn =
+					conf.ExtensibilityPointConfiguration{
+						URI: `pg-functions://postgres/auth/` +
+							`v0hooks_test_before_sign_in_reject_msg`,
+						HookName: `"auth"."v0hooks_test_before_sign_in_reject_msg"`,
+					}
+			},
+			req: NewBeforeSignInInput(httpReq, &models.User{}, "password"),
+			res: &BeforeSignInOutput{},
+			exp: &BeforeSignInOutput{Decision: "reject", Message: "test case"},
+			sql: `
+				create or replace function
+					v0hooks_test_before_sign_in_reject_msg(input jsonb)
+				returns json as $$
+				begin
+					return '{"decision": "reject", "message": "test case"}'::jsonb;
+				end; $$ language plpgsql;`,
+		},
+
 		{
 			desc: "pass - after_user_created",
 			setup: func() {
@@ -310,6 +359,28 @@ func TestHooks(t *testing.T) {
 				end; $$ language plpgsql;`,
 		},
 
+		{
+			desc: "pass - oauth_consent",
+			setup: func() {
+				globalCfg.Hook.OAuthConsent =
+					conf.ExtensibilityPointConfiguration{
+						URI: `pg-functions://postgres/auth/` +
+							`v0hooks_test_oauth_consent`,
+						HookName: `"auth"."v0hooks_test_oauth_consent"`,
+					}
+			},
+			req: &OAuthConsentInput{Client: OAuthConsentClient{ID: "client"}},
+			res: &OAuthConsentOutput{},
+			exp: &OAuthConsentOutput{Decision: OAuthConsentApprove},
+			sql: `
+				create or replace function
+					v0hooks_test_oauth_consent(input jsonb)
+				returns json as $$
+				begin
+					return '{"decision": "approve"}'::jsonb;
+				end; $$ language plpgsql;`,
+		},
+
 		// fail
 		{
 			desc: "fail - customize_access_token - error propagation",
@@ -414,12 +485,24 @@ func TestHooks(t *testing.T) {
 			res:    M{},
 			errStr: "500: output should be *hooks.BeforeUserCreatedOutput",
 		},
+		{
+			desc:   "fail - before_sign_in - invalid output type",
+			req:    &BeforeSignInInput{},
+			res:    M{},
+			errStr: "500: output should be *hooks.BeforeSignInOutput",
+		},
 		{
 			desc:   "fail - after_user_created - invalid output type",
 			req:    &AfterUserCreatedInput{},
 			res:    M{},
 			errStr: "500: output should be *hooks.AfterUserCreatedOutput",
 		},
+		{
+			desc:   "fail - oauth_consent - invalid output type",
+			req:    &OAuthConsentInput{},
+			res:    M{},
+			errStr: "500: output should be *hooks.OAuthConsentOutput",
+		},
 
 		// fail - invalid query
 		{
@@ -540,9 +623,15 @@ func TestConfig(t *testing.T) {
 			BeforeUserCreated: conf.ExtensibilityPointConfiguration{
 				URI: "http:localhost/" + string(BeforeUserCreated),
 			},
+			BeforeSignIn: conf.ExtensibilityPointConfiguration{
+				URI: "http:localhost/" + string(BeforeSignIn),
+			},
 			AfterUserCreated: conf.ExtensibilityPointConfiguration{
 				URI: "http:localhost/" + string(AfterUserCreated),
 			},
+			OAuthConsent: conf.ExtensibilityPointConfiguration{
+				URI: "http:localhost/" + string(OAuthConsent),
+			},
 		},
 	}
 	cfg := &globalCfg.Hook
@@ -569,8 +658,12 @@ func TestConfig(t *testing.T) {
 			name: PasswordVerification, exp: &cfg.PasswordVerificationAttempt},
 		{cfg: cfg, ok: true,
 			name: BeforeUserCreated, exp: &cfg.BeforeUserCreated},
+		{cfg: cfg, ok: true,
+			name: BeforeSignIn, exp: &cfg.BeforeSignIn},
 		{cfg: cfg, ok: true,
 			name: AfterUserCreated, exp: &cfg.AfterUserCreated},
+		{cfg: cfg, ok: true,
+			name: OAuthConsent, exp: &cfg.OAuthConsent},
 	}
 	for _, test := range tests {
 		t.Run(string(test.name), func(t *testing.T) {
@@ -589,3 +682,36 @@ func TestConfig(t *testing.T) {
 		})
 	}
 }
+
+func TestMetaDataChanges(t *testing.T) {
+	identityData := M{"name": "Jane", "nickname": "jd"}
+	user := &models.User{
+		UserMetaData: identityData,
+		AppMetaData:  M{"provider": "email", "providers": []string{"email"}},
+	}
+
+	changes := &MetaDataChanges{
+		UserMetaData: M{"plan": "free", "nickname": nil},
+		AppMetaData:  M{"tenant": "acme", "provider": "github"},
+	}
+	require.Equal(t, M{"tenant": "acme"}, changes.AppMetaDataChanges())
+
+	changes.Apply(user)
+	require.Equal(t, models.JSONMap{"name": "Jane", "plan": "free"}, user.UserMetaData)
+	require.Equal(t, "email", user.AppMetaData["provider"])
+	require.Equal(t, "acme", user.AppMetaData["tenant"])
+
+	// the identity data shared with the user is not changed
+	require.Equal(t, M{"name": "Jane", "nickname": "jd"}, identityData)
+
+	require.Nil(t, (&MetaDataChanges{}).AppMetaDataChanges())
+}
+
+func TestSendSMSOutputErr(t *testing.T) {
+	require.NoError(t, (&SendSMSOutput{}).Err())
+	require.NoError(t, (&SendSMSOutput{Status: SendSMSStatusQueued, MessageID: "1"}).Err())
+	require.EqualError(t, (&SendSMSOutput{Status: SendSMSStatusFailed}).Err(),
+		"send SMS hook failed to deliver the message")
+	require.EqualError(t, (&SendSMSOutput{Status: SendSMSStatusFailed, Message: "carrier unreachable"}).Err(),
+		"send SMS hook failed to deliver the message: carrier unreachable")
+}
@@ -2,7 +2,11 @@ package v0hooks
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
 	"net/http"
+	"slices"
 	"time"
 
 	"github.com/gofrs/uuid"
@@ -22,18 +26,87 @@ const (
 	MFAVerification      Name = "mfa-verification"
 	PasswordVerification Name = "password-verification"
 	BeforeUserCreated    Name = "before-user-created"
+	BeforeSignIn         Name = "before-sign-in"
 	AfterUserCreated     Name = "after-user-created"
+	AfterUserDeleted     Name = "after-user-deleted"
+	OAuthConsent         Name = "oauth-consent"
 )
 
 const (
 	HookRejection = "reject"
 )
 
+// HookRequireAdditionalVerification is a decision of the MFA verification
+// attempt hook refusing the factor without logging the user out, so the
+// client can verify another factor instead.
+const HookRequireAdditionalVerification = "require_additional_verification"
+
+// Decisions of the OAuth consent hook. Any other decision shows the consent
+// screen to the user.
+const (
+	OAuthConsentApprove = "approve"
+	OAuthConsentDeny    = "deny"
+)
+
 const (
 	DefaultMFAHookRejectionMessage      = "Further MFA verification attempts will be rejected."
+	DefaultMFAHookAdditionalMessage     = "Verify another factor to continue."
 	DefaultPasswordHookRejectionMessage = "Further password verification attempts will be rejected."
+	DefaultSignupHookRejectionMessage   = "Signup was rejected."
+	DefaultSignInHookRejectionMessage   = "Sign in was rejected."
 )
 
+// protectedAppMetaData are the keys of the app metadata maintained by Auth,
+// which hooks can not change.
+var protectedAppMetaData = []string{"provider", "providers"}
+
+// MetaDataChanges are the changes a hook makes to the metadata of a user.
+// Keys set to null are removed.
+type MetaDataChanges struct {
+	UserMetaData map[string]interface{} `json:"user_metadata,omitempty"`
+	AppMetaData  map[string]interface{} `json:"app_metadata,omitempty"`
+}
+
+// AppMetaDataChanges returns the changes to the app metadata, without the
+// keys maintained by Auth.
+func (c *MetaDataChanges) AppMetaDataChanges() map[string]interface{} {
+	if len(c.AppMetaData) == 0 {
+		return nil
+	}
+
+	changes := make(map[string]interface{}, len(c.AppMetaData))
+	for key, value := range c.AppMetaData {
+		if !slices.Contains(protectedAppMetaData, key) {
+			changes[key] = value
+		}
+	}
+	return changes
+}
+
+// Apply makes the changes to the metadata of a user that is not saved yet.
+// The metadata is copied, as it is often shared with the identity data.
+func (c *MetaDataChanges) Apply(user *models.User) {
+	user.UserMetaData = applyMetaData(user.UserMetaData, c.UserMetaData)
+	user.AppMetaData = applyMetaData(user.AppMetaData, c.AppMetaDataChanges())
+}
+
+func applyMetaData(metadata, changes map[string]interface{}) map[string]interface{} {
+	if len(changes) == 0 {
+		return metadata
+	}
+
+	updated := make(map[string]interface{}, len(metadata)+len(changes))
+	maps.Copy(updated, metadata)
+	for key, value := range changes {
+		if value != nil {
+			updated[key] = value
+		} else {
+			delete(updated, key)
+		}
+	}
+	return updated
+}
+
 type Metadata struct {
 	UUID uuid.UUID `json:"uuid"`
 	Time time.Time `json:"time"`
@@ -69,9 +142,41 @@ func NewBeforeUserCreatedInput(
 	}
 }
 
+// BeforeUserCreatedOutput rejects the user with the reject decision, and
+// otherwise may change the metadata of the user before it is created.
 type BeforeUserCreatedOutput struct {
 	Decision string `json:"decision"`
 	Message  string `json:"message"`
+	MetaDataChanges
+}
+
+// BeforeSignInInput is sent before a session is issued to a user, with
+// any sign in method.
+type BeforeSignInInput struct {
+	Metadata             *Metadata    `json:"metadata"`
+	User                 *models.User `json:"user"`
+	AuthenticationMethod string       `json:"authentication_method"`
+}
+
+func NewBeforeSignInInput(
+	r *http.Request,
+	user *models.User,
+	authenticationMethod string,
+) *BeforeSignInInput {
+	return &BeforeSignInInput{
+		Metadata:             NewMetadata(r, BeforeSignIn),
+		User:                 user,
+		AuthenticationMethod: authenticationMethod,
+	}
+}
+
+// BeforeSignInOutput rejects the sign in with the reject decision, and
+// otherwise may change the metadata of the user before the session is
+// issued.
+type BeforeSignInOutput struct {
+	Decision string `json:"decision"`
+	Message  string `json:"message"`
+	MetaDataChanges
 }
 
 type AfterUserCreatedInput struct {
@@ -91,11 +196,39 @@ func NewAfterUserCreatedInput(
 
 type AfterUserCreatedOutput struct{}
 
+// AfterUserDeletedInput is sent after a user was deleted, so that downstream
+// systems can clean up. SoftDeleted users are kept until their purge_after
+// time, and are purged without invoking the hook again.
+type AfterUserDeletedInput struct {
+	Metadata    *Metadata    `json:"metadata"`
+	User        *models.User `json:"user"`
+	SoftDeleted bool         `json:"soft_deleted"`
+}
+
+func NewAfterUserDeletedInput(
+	r *http.Request,
+	user *models.User,
+	softDeleted bool,
+) *AfterUserDeletedInput {
+	return &AfterUserDeletedInput{
+		Metadata:    NewMetadata(r, AfterUserDeleted),
+		User:        user,
+		SoftDeleted: softDeleted,
+	}
+}
+
+type AfterUserDeletedOutput struct{}
+
 // TODO(joel): Move this to phone package
 type SMS struct {
 	OTP     string `json:"otp,omitempty"`
 	SMSType string `json:"sms_type,omitempty"`
 	Phone   string `json:"phone,omitempty"`
+	Channel string `json:"channel,omitempty"`
+
+	// Message is the OTP message rendered from the SMS template of the
+	// channel, for hooks sending it as is.
+	Message string `json:"message,omitempty"`
 }
 
 // AccessTokenClaims is a struct thats used for JWT claims
@@ -112,6 +245,10 @@ type AccessTokenClaims struct {
 	IsAnonymous                   bool                   `json:"is_anonymous"`
 	ClientID                      string                 `json:"client_id,omitempty"`
 	Scope                         string                 `json:"scope,omitempty"`
+	Organizations                 map[string]string      `json:"orgs,omitempty"`
+	Roles                         []string               `json:"roles,omitempty"`
+	Permissions                   []string               `json:"permissions,omitempty"`
+	Actor                         *models.SessionActor   `json:"act,omitempty"`
 }
 
 type MFAVerificationAttemptInput struct {
@@ -119,6 +256,7 @@ type MFAVerificationAttemptInput struct {
 	FactorID   uuid.UUID `json:"factor_id"`
 	FactorType string    `json:"factor_type"`
 	Valid      bool      `json:"valid"`
+	IPAddress  string    `json:"ip_address,omitempty"`
 }
 
 type MFAVerificationAttemptOutput struct {
@@ -126,21 +264,66 @@ type MFAVerificationAttemptOutput struct {
 	Message  string `json:"message"`
 }
 
+// Lockouts the password verification attempt hook can return to override
+// the built-in lockout policy for the next attempts of the user.
+const (
+	// PasswordLockoutAllow clears the failed attempts and any lockout.
+	PasswordLockoutAllow = "allow"
+
+	// PasswordLockoutBlock locks the user until locked_until, or for the
+	// configured lockout duration.
+	PasswordLockoutBlock = "block"
+
+	// PasswordLockoutCaptcha requires a captcha until a sign-in succeeds.
+	PasswordLockoutCaptcha = "captcha"
+)
+
 type PasswordVerificationAttemptInput struct {
-	UserID uuid.UUID `json:"user_id"`
-	Valid  bool      `json:"valid"`
+	UserID         uuid.UUID `json:"user_id"`
+	Valid          bool      `json:"valid"`
+	FailedAttempts int       `json:"failed_attempts"`
+	IPAddress      string    `json:"ip_address,omitempty"`
 }
 
 type PasswordVerificationAttemptOutput struct {
-	Decision         string `json:"decision"`
-	Message          string `json:"message"`
-	ShouldLogoutUser bool   `json:"should_logout_user"`
+	Decision         string     `json:"decision"`
+	Message          string     `json:"message"`
+	ShouldLogoutUser bool       `json:"should_logout_user"`
+	Lockout          string     `json:"lockout,omitempty"`
+	LockedUntil      *time.Time `json:"locked_until,omitempty"`
+}
+
+// OAuthConsentInput is sent to the OAuth consent hook before the consent
+// screen of the OAuth server is shown to the user.
+type OAuthConsentInput struct {
+	Metadata    *Metadata          `json:"metadata"`
+	User        *models.User       `json:"user"`
+	Client      OAuthConsentClient `json:"client"`
+	Scopes      []string           `json:"scopes"`
+	RedirectURI string             `json:"redirect_uri"`
+}
+
+type OAuthConsentClient struct {
+	ID   string `json:"id"`
+	Name string `json:"name,omitempty"`
+	URI  string `json:"uri,omitempty"`
+}
+
+type OAuthConsentOutput struct {
+	Decision string `json:"decision"`
+	Message  string `json:"message"`
 }
 
 type CustomAccessTokenInput struct {
 	UserID               uuid.UUID          `json:"user_id"`
 	Claims               *AccessTokenClaims `json:"claims"`
 	AuthenticationMethod string             `json:"authentication_method"`
+
+	// User and Session give the hook the full context the token is being
+	// minted for, so claims such as tenant IDs can be derived without an
+	// extra lookup.
+	User    *models.User    `json:"user,omitempty"`
+	Session *models.Session `json:"session,omitempty"`
 }
 
 type CustomAccessTokenOutput struct {
@@ -182,7 +365,31 @@ type SendSMSInput struct {
 	SMS  SMS          `json:"sms,omitempty"`
 }
 
+// Delivery statuses returned by the send SMS hook.
+const (
+	SendSMSStatusSent   = "sent"
+	SendSMSStatusQueued = "queued"
+	SendSMSStatusFailed = "failed"
+)
+
+// SendSMSOutput optionally reports the delivery of the message, with the
+// ID given to it by the gateway. A failed status fails the request like an
+// error of an SMS provider, with the message as the reason.
 type SendSMSOutput struct {
+	MessageID string `json:"message_id,omitempty"`
+	Status    string `json:"status,omitempty"`
+	Message   string `json:"message,omitempty"`
+}
+
+// Err returns an error when the hook failed to deliver the message.
+func (o *SendSMSOutput) Err() error {
+	if o.Status != SendSMSStatusFailed {
+		return nil
+	}
+	if o.Message == "" {
+		return errors.New("send SMS hook failed to deliver the message")
+	}
+	return fmt.Errorf("send SMS hook failed to deliver the message: %s", o.Message)
 }
 
 type SendEmailInput struct {
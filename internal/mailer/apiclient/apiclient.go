@@ -0,0 +1,111 @@
+// Package apiclient provides implementations of mailer.Client that send mail
+// with the HTTP APIs of email services instead of SMTP: Amazon SES, SendGrid,
+// Mailgun and Postmark.
+package apiclient
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/mail"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/supabase/auth/internal/conf"
+	"github.com/supabase/auth/internal/utilities"
+)
+
+const (
+	defaultTimeout = 10 * time.Second
+
+	// maxErrorSize is the size of an error response read to report the
+	// error.
+	maxErrorSize = 64 * 1024
+)
+
+// client holds what is common to sending mail with every API.
+type client struct {
+	// service names the email service in errors and logs
+	service string
+
+	from    string
+	address *mail.Address
+
+	httpClient  *http.Client
+	logger      logrus.FieldLogger
+	mailLogging bool
+}
+
+func newClient(service string, globalConfig *conf.GlobalConfiguration) client {
+	return client{
+		service: service,
+		from:    globalConfig.SMTP.FromAddress(),
+		address: &mail.Address{
+			Name:    globalConfig.SMTP.SenderName,
+			Address: globalConfig.SMTP.AdminEmail,
+		},
+		httpClient:  &http.Client{Timeout: defaultTimeout},
+		logger:      logrus.StandardLogger(),
+		mailLogging: globalConfig.SMTP.LoggingEnabled,
+	}
+}
+
+// send sends the request to the API and checks its response, the error of
+// a failed request is taken from the response with errorMessage.
+func (c *client) send(
+	ctx context.Context,
+	req *http.Request,
+	typ string,
+	to string,
+	errorMessage func(body []byte) string,
+) error {
+	res, err := c.httpClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("%s error: %w", c.service, err)
+	}
+	defer utilities.SafeClose(res.Body)
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(res.Body, maxErrorSize))
+		if message := errorMessage(body); message != "" {
+			return fmt.Errorf("%s error: %s (status %d)", c.service, message, res.StatusCode)
+		}
+		return fmt.Errorf("%s error: unexpected response status %d", c.service, res.StatusCode)
+	}
+
+	if c.mailLogging {
+		c.logger.WithFields(logrus.Fields{
+			"event":          "mail.send",
+			"mail_type":      typ,
+			"mail_from":      c.from,
+			"mail_to":        to,
+			"mail_transport": c.service,
+		}).Info("mail.send")
+	}
+
+	return nil
+}
+
+// header is a single mail header of APIs taking headers as a list.
+type header struct {
+	Name  string `json:"Name"`
+	Value string `json:"Value"`
+}
+
+// headerList returns the headers sorted by name, headers with several
+// values are joined with a comma.
+func headerList(headers map[string][]string) []header {
+	list := make([]header, 0, len(headers))
+	for name, values := range headers {
+		if len(values) > 0 {
+			list = append(list, header{Name: name, Value: strings.Join(values, ", ")})
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Name < list[j].Name
+	})
+	return list
+}
@@ -0,0 +1,75 @@
+package apiclient
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/supabase/auth/internal/conf"
+)
+
+// MailgunClient sends mail with the messages API of a Mailgun domain.
+type MailgunClient struct {
+	client
+
+	Config  *conf.MailgunTransportConfiguration
+	APIPath string
+}
+
+// NewMailgun returns a MailgunClient based on the given configuration.
+func NewMailgun(globalConfig *conf.GlobalConfiguration) *MailgunClient {
+	config := globalConfig.Mailer.Mailgun
+
+	host := "api.mailgun.net"
+	if config.Region == "eu" {
+		host = "api.eu.mailgun.net"
+	}
+
+	return &MailgunClient{
+		client:  newClient("mailgun", globalConfig),
+		Config:  &config,
+		APIPath: "https://" + host + "/v3/" + url.PathEscape(config.Domain) + "/messages",
+	}
+}
+
+// Mail implements mailer.Client interface by sending the mail with Mailgun.
+func (c *MailgunClient) Mail(
+	ctx context.Context,
+	to string,
+	subject string,
+	body string,
+	headers map[string][]string,
+	typ string,
+) error {
+	form := url.Values{
+		"from":    {c.from},
+		"to":      {to},
+		"subject": {subject},
+		"html":    {body},
+	}
+	for name, values := range headers {
+		if len(values) > 0 {
+			form.Set("h:"+name, strings.Join(values, ", "))
+		}
+	}
+	if typ != "" {
+		form.Set("o:tag", typ)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, c.APIPath, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth("api", c.Config.APIKey)
+
+	return c.send(ctx, req, typ, to, func(body []byte) string {
+		var res struct {
+			Message string `json:"message"`
+		}
+		_ = json.Unmarshal(body, &res)
+		return res.Message
+	})
+}
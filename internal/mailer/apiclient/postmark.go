@@ -0,0 +1,78 @@
+package apiclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/supabase/auth/internal/conf"
+)
+
+// PostmarkClient sends mail with the Postmark email API.
+type PostmarkClient struct {
+	client
+
+	Config  *conf.PostmarkTransportConfiguration
+	APIPath string
+}
+
+// NewPostmark returns a PostmarkClient based on the given configuration.
+func NewPostmark(globalConfig *conf.GlobalConfiguration) *PostmarkClient {
+	config := globalConfig.Mailer.Postmark
+	return &PostmarkClient{
+		client:  newClient("postmark", globalConfig),
+		Config:  &config,
+		APIPath: "https://api.postmarkapp.com/email",
+	}
+}
+
+type postmarkEmailRequest struct {
+	From          string   `json:"From"`
+	To            string   `json:"To"`
+	Subject       string   `json:"Subject"`
+	HTMLBody      string   `json:"HtmlBody"`
+	Headers       []header `json:"Headers,omitempty"`
+	Tag           string   `json:"Tag,omitempty"`
+	MessageStream string   `json:"MessageStream,omitempty"`
+}
+
+// Mail implements mailer.Client interface by sending the mail with
+// Postmark.
+func (c *PostmarkClient) Mail(
+	ctx context.Context,
+	to string,
+	subject string,
+	body string,
+	headers map[string][]string,
+	typ string,
+) error {
+	payload, err := json.Marshal(&postmarkEmailRequest{
+		From:          c.from,
+		To:            to,
+		Subject:       subject,
+		HTMLBody:      body,
+		Headers:       headerList(headers),
+		Tag:           typ,
+		MessageStream: c.Config.MessageStream,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, c.APIPath, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("X-Postmark-Server-Token", c.Config.ServerToken)
+
+	return c.send(ctx, req, typ, to, func(body []byte) string {
+		var res struct {
+			Message string `json:"Message"`
+		}
+		_ = json.Unmarshal(body, &res)
+		return res.Message
+	})
+}
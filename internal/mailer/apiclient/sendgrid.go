@@ -0,0 +1,110 @@
+package apiclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/supabase/auth/internal/conf"
+)
+
+// SendGridClient sends mail with the SendGrid v3 Mail Send API.
+type SendGridClient struct {
+	client
+
+	Config  *conf.SendGridTransportConfiguration
+	APIPath string
+}
+
+// NewSendGrid returns a SendGridClient based on the given configuration.
+func NewSendGrid(globalConfig *conf.GlobalConfiguration) *SendGridClient {
+	config := globalConfig.Mailer.SendGrid
+	return &SendGridClient{
+		client:  newClient("sendgrid", globalConfig),
+		Config:  &config,
+		APIPath: "https://api.sendgrid.com/v3/mail/send",
+	}
+}
+
+type sendGridAddress struct {
+	Email string `json:"email"`
+	Name  string `json:"name,omitempty"`
+}
+
+type sendGridContent struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+type sendGridMailRequest struct {
+	Personalizations []struct {
+		To []sendGridAddress `json:"to"`
+	} `json:"personalizations"`
+	From       sendGridAddress   `json:"from"`
+	Subject    string            `json:"subject"`
+	Content    []sendGridContent `json:"content"`
+	Headers    map[string]string `json:"headers,omitempty"`
+	Categories []string          `json:"categories,omitempty"`
+}
+
+// Mail implements mailer.Client interface by sending the mail with
+// SendGrid.
+func (c *SendGridClient) Mail(
+	ctx context.Context,
+	to string,
+	subject string,
+	body string,
+	headers map[string][]string,
+	typ string,
+) error {
+	msg := &sendGridMailRequest{
+		From:    sendGridAddress{Email: c.address.Address, Name: c.address.Name},
+		Subject: subject,
+		Content: []sendGridContent{{Type: "text/html", Value: body}},
+	}
+	msg.Personalizations = make([]struct {
+		To []sendGridAddress `json:"to"`
+	}, 1)
+	msg.Personalizations[0].To = []sendGridAddress{{Email: to}}
+
+	if len(headers) > 0 {
+		msg.Headers = make(map[string]string, len(headers))
+		for name, values := range headers {
+			if len(values) > 0 {
+				msg.Headers[name] = strings.Join(values, ", ")
+			}
+		}
+	}
+	if typ != "" {
+		msg.Categories = []string{typ}
+	}
+
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, c.APIPath, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+c.Config.APIKey)
+
+	return c.send(ctx, req, typ, to, func(body []byte) string {
+		var res struct {
+			Errors []struct {
+				Message string `json:"message"`
+			} `json:"errors"`
+		}
+		_ = json.Unmarshal(body, &res)
+
+		messages := make([]string, len(res.Errors))
+		for i, e := range res.Errors {
+			messages[i] = e.Message
+		}
+		return strings.Join(messages, ", ")
+	})
+}
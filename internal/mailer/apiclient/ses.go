@@ -0,0 +1,107 @@
+package apiclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/supabase/auth/internal/conf"
+	"github.com/supabase/auth/internal/utilities"
+)
+
+const sesService = "ses"
+
+// SESClient sends mail with the SendEmail action of the Amazon SES v2 API.
+type SESClient struct {
+	client
+
+	Config  *conf.SESTransportConfiguration
+	APIPath string
+
+	signer utilities.Signer
+}
+
+// NewSES returns a SESClient based on the given configuration.
+func NewSES(globalConfig *conf.GlobalConfiguration) *SESClient {
+	config := globalConfig.Mailer.SES
+	return &SESClient{
+		client:  newClient("aws ses", globalConfig),
+		Config:  &config,
+		APIPath: "https://email." + config.Region + ".amazonaws.com/v2/email/outbound-emails",
+	}
+}
+
+type sesContent struct {
+	Data    string `json:"Data"`
+	Charset string `json:"Charset"`
+}
+
+type sesSendEmailRequest struct {
+	FromEmailAddress string `json:"FromEmailAddress"`
+	Destination      struct {
+		ToAddresses []string `json:"ToAddresses"`
+	} `json:"Destination"`
+	Content struct {
+		Simple struct {
+			Subject sesContent `json:"Subject"`
+			Body    struct {
+				HTML sesContent `json:"Html"`
+			} `json:"Body"`
+			Headers []header `json:"Headers,omitempty"`
+		} `json:"Simple"`
+	} `json:"Content"`
+	EmailTags            []sesEmailTag `json:"EmailTags,omitempty"`
+	ConfigurationSetName string        `json:"ConfigurationSetName,omitempty"`
+}
+
+type sesEmailTag struct {
+	Name  string `json:"Name"`
+	Value string `json:"Value"`
+}
+
+// Mail implements mailer.Client interface by sending the mail with SES.
+func (c *SESClient) Mail(
+	ctx context.Context,
+	to string,
+	subject string,
+	body string,
+	headers map[string][]string,
+	typ string,
+) error {
+	msg := &sesSendEmailRequest{
+		FromEmailAddress:     c.from,
+		ConfigurationSetName: c.Config.ConfigurationSet,
+	}
+	msg.Destination.ToAddresses = []string{to}
+	msg.Content.Simple.Subject = sesContent{Data: subject, Charset: "UTF-8"}
+	msg.Content.Simple.Body.HTML = sesContent{Data: body, Charset: "UTF-8"}
+	msg.Content.Simple.Headers = headerList(headers)
+	if typ != "" {
+		msg.EmailTags = []sesEmailTag{{Name: "type", Value: typ}}
+	}
+
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, c.APIPath, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	c.signer.SignAWS(req, string(payload), sesService, c.Config.Region, utilities.AWSCredentials{
+		AccessKeyID:     c.Config.AccessKeyID,
+		SecretAccessKey: c.Config.SecretAccessKey,
+		SessionToken:    c.Config.SessionToken,
+	})
+
+	return c.send(ctx, req, typ, to, func(body []byte) string {
+		var res struct {
+			Message string `json:"message"`
+		}
+		_ = json.Unmarshal(body, &res)
+		return res.Message
+	})
+}
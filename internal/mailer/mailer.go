@@ -27,6 +27,9 @@ const (
 	IdentityUnlinkedNotification    = "identity_unlinked_notification"
 	MFAFactorEnrolledNotification   = "mfa_factor_enrolled_notification"
 	MFAFactorUnenrolledNotification = "mfa_factor_unenrolled_notification"
+
+	DataExportNotification = "data_export_notification"
+	NewSignInNotification  = "new_sign_in_notification"
 )
 
 // Mailer defines the interface a mailer must implement.
@@ -47,6 +50,19 @@ type Mailer interface {
 	IdentityUnlinkedNotificationMail(r *http.Request, user *models.User, provider string) error
 	MFAFactorEnrolledNotificationMail(r *http.Request, user *models.User, factorType string) error
 	MFAFactorUnenrolledNotificationMail(r *http.Request, user *models.User, factorType string) error
+
+	DataExportNotificationMail(r *http.Request, user *models.User, downloadURL string) error
+	NewSignInNotificationMail(r *http.Request, user *models.User, signIn *SignIn) error
+}
+
+// SignIn describes a sign-in from a new location or device. RevokeURL signs
+// out the session of the sign-in, for users who don't recognize it.
+type SignIn struct {
+	IP        string `json:"ip"`
+	UserAgent string `json:"user_agent"`
+	Country   string `json:"country"`
+	City      string `json:"city"`
+	RevokeURL string `json:"revoke_url"`
 }
 
 // TODO(cstockton): Mail(...) -> Mail(Email{...}) ?
@@ -62,15 +78,23 @@ type Client interface {
 }
 
 type EmailData struct {
-	Token           string `json:"token"`
-	TokenHash       string `json:"token_hash"`
-	RedirectTo      string `json:"redirect_to"`
-	EmailActionType string `json:"email_action_type"`
-	SiteURL         string `json:"site_url"`
-	TokenNew        string `json:"token_new"`
-	TokenHashNew    string `json:"token_hash_new"`
-	OldEmail        string `json:"old_email"`
-	OldPhone        string `json:"old_phone"`
-	Provider        string `json:"provider"`
-	FactorType      string `json:"factor_type"`
+	Token           string  `json:"token"`
+	TokenHash       string  `json:"token_hash"`
+	RedirectTo      string  `json:"redirect_to"`
+	EmailActionType string  `json:"email_action_type"`
+	SiteURL         string  `json:"site_url"`
+	TokenNew        string  `json:"token_new"`
+	TokenHashNew    string  `json:"token_hash_new"`
+	OldEmail        string  `json:"old_email"`
+	OldPhone        string  `json:"old_phone"`
+	Provider        string  `json:"provider"`
+	FactorType      string  `json:"factor_type"`
+	DownloadURL     string  `json:"download_url"`
+	SignIn          *SignIn `json:"sign_in,omitempty"`
+
+	// ConfirmationURL is the link the built-in email would contain, for
+	// the new address of an email change. ConfirmationURLCurrent is the
+	// link for the current address of a secure email change.
+	ConfirmationURL        string `json:"confirmation_url,omitempty"`
+	ConfirmationURLCurrent string `json:"confirmation_url_current,omitempty"`
 }
@@ -4,6 +4,7 @@ import (
 	"net/http"
 	"net/url"
 
+	"github.com/supabase/auth/internal/mailer"
 	"github.com/supabase/auth/internal/models"
 )
 
@@ -24,6 +25,9 @@ type MockMailer struct {
 	IdentityUnlinkedMailCalls    []IdentityUnlinkedMailCall
 	MFAFactorEnrolledMailCalls   []MFAFactorEnrolledMailCall
 	MFAFactorUnenrolledMailCalls []MFAFactorUnenrolledMailCall
+
+	DataExportMailCalls []DataExportMailCall
+	NewSignInMailCalls  []NewSignInMailCall
 }
 
 type InviteMailCall struct {
@@ -110,6 +114,16 @@ type MFAFactorUnenrolledMailCall struct {
 	FactorType string
 }
 
+type DataExportMailCall struct {
+	User        *models.User
+	DownloadURL string
+}
+
+type NewSignInMailCall struct {
+	User   *models.User
+	SignIn *mailer.SignIn
+}
+
 func (m *MockMailer) InviteMail(r *http.Request, user *models.User, otp, referrerURL string, externalURL *url.URL) error {
 	m.InviteMailCalls = append(m.InviteMailCalls, InviteMailCall{
 		User:        user,
@@ -237,6 +251,22 @@ func (m *MockMailer) MFAFactorUnenrolledNotificationMail(r *http.Request, user *
 	return nil
 }
 
+func (m *MockMailer) DataExportNotificationMail(r *http.Request, user *models.User, downloadURL string) error {
+	m.DataExportMailCalls = append(m.DataExportMailCalls, DataExportMailCall{
+		User:        user,
+		DownloadURL: downloadURL,
+	})
+	return nil
+}
+
+func (m *MockMailer) NewSignInNotificationMail(r *http.Request, user *models.User, signIn *mailer.SignIn) error {
+	m.NewSignInMailCalls = append(m.NewSignInMailCalls, NewSignInMailCall{
+		User:   user,
+		SignIn: signIn,
+	})
+	return nil
+}
+
 func (m *MockMailer) Reset() {
 	m.InviteMailCalls = nil
 	m.ConfirmationMailCalls = nil
@@ -253,4 +283,6 @@ func (m *MockMailer) Reset() {
 	m.IdentityUnlinkedMailCalls = nil
 	m.MFAFactorEnrolledMailCalls = nil
 	m.MFAFactorUnenrolledMailCalls = nil
+	m.DataExportMailCalls = nil
+	m.NewSignInMailCalls = nil
 }
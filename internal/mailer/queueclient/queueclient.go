@@ -0,0 +1,96 @@
+// Package queueclient provides an implementation of mailer.Client that adds
+// mail to a queue in the database, for a background worker to send it with
+// retries. Failures to send mail then no longer fail the requests sending it.
+package queueclient
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/supabase/auth/internal/conf"
+	"github.com/supabase/auth/internal/mailer"
+	"github.com/supabase/auth/internal/models"
+	"github.com/supabase/auth/internal/storage"
+)
+
+// lease is how long a mail being sent is hidden from other workers. It is
+// longer than sending a mail may take, a mail whose worker stopped while
+// sending it is retried once it passed.
+const lease = 5 * time.Minute
+
+// New returns a Client that adds mail to the queue in db.
+func New(db *storage.Connection) mailer.Client {
+	return &queueMailClient{db: db}
+}
+
+type queueMailClient struct {
+	db *storage.Connection
+}
+
+// Mail implements mailer.MailClient interface by adding the mail to the
+// queue.
+func (o *queueMailClient) Mail(
+	ctx context.Context,
+	to string,
+	subject string,
+	body string,
+	headers map[string][]string,
+	typ string,
+) error {
+	job := models.NewMailJob(to, subject, body, headers, typ)
+	if err := o.db.WithContext(ctx).Create(job); err != nil {
+		return fmt.Errorf("queueclient: error queueing mail: %w", err)
+	}
+	return nil
+}
+
+// Process sends up to config.BatchSize queued mails that are due with mc.
+// Mail that fails to be sent is retried later, or dead-lettered once it
+// failed config.MaxAttempts times. It returns the number of mails it
+// attempted to send.
+func Process(
+	ctx context.Context,
+	db *storage.Connection,
+	mc mailer.Client,
+	config *conf.MailQueueConfiguration,
+	le *logrus.Entry,
+) (int, error) {
+	db = db.WithContext(ctx)
+
+	jobs, err := models.ClaimMailJobs(db, config.BatchSize, lease)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, job := range jobs {
+		jle := le.WithFields(logrus.Fields{
+			"mail_job_id": job.ID,
+			"mail_type":   job.Type,
+			"attempts":    job.Attempts,
+		})
+
+		sendErr := mc.Mail(ctx, job.To, job.Subject, job.Body, job.Headers, job.Type)
+		if sendErr == nil {
+			if err := job.Sent(db); err != nil {
+				// the mail is sent again once the lease passed
+				jle.WithError(err).Error("queueclient: error removing sent mail from the queue")
+			}
+			continue
+		}
+
+		retryAt := config.RetryAt(job.Attempts, time.Now())
+		if retryAt != nil {
+			jle.WithError(sendErr).WithField("retry_at", *retryAt).Warn("queueclient: error sending mail, it will be retried")
+		} else {
+			jle.WithError(sendErr).Error("queueclient: error sending mail, it was dead-lettered")
+		}
+
+		if err := job.Failed(db, sendErr, retryAt); err != nil {
+			jle.WithError(err).Error("queueclient: error recording failure to send mail")
+		}
+	}
+
+	return len(jobs), nil
+}
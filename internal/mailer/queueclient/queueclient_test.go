@@ -0,0 +1,114 @@
+package queueclient
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+	"github.com/supabase/auth/internal/conf"
+	"github.com/supabase/auth/internal/models"
+	"github.com/supabase/auth/internal/storage/test"
+)
+
+type mail struct {
+	to      string
+	subject string
+	body    string
+	headers map[string][]string
+	typ     string
+}
+
+type testClient struct {
+	err  error
+	sent []mail
+}
+
+func (o *testClient) Mail(
+	ctx context.Context,
+	to string,
+	subject string,
+	body string,
+	headers map[string][]string,
+	typ string,
+) error {
+	if o.err != nil {
+		return o.err
+	}
+	o.sent = append(o.sent, mail{to, subject, body, headers, typ})
+	return nil
+}
+
+func TestQueue(t *testing.T) {
+	globalConfig, err := conf.LoadGlobal("../../../hack/test.env")
+	require.NoError(t, err)
+	conn, err := test.SetupDBConnection(globalConfig)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	require.NoError(t, models.TruncateAll(conn))
+
+	ctx := context.Background()
+	le := logrus.NewEntry(logrus.StandardLogger())
+	config := &conf.MailQueueConfiguration{
+		Enabled:          true,
+		MaxAttempts:      2,
+		RetryInterval:    time.Hour,
+		MaxRetryInterval: time.Hour,
+		PollInterval:     time.Second,
+		BatchSize:        10,
+	}
+
+	headers := map[string][]string{"X-Test": {"1"}}
+	require.NoError(t, New(conn).Mail(ctx, "test@example.com", "Subject", "Body", headers, "signup"))
+
+	// the first failure is retried later
+	failing := &testClient{err: errors.New("connection refused")}
+	n, err := Process(ctx, conn, failing, config, le)
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+
+	jobs, err := models.FindMailJobs(conn, models.MailJobPending, nil)
+	require.NoError(t, err)
+	require.Len(t, jobs, 1)
+	require.Equal(t, 1, jobs[0].Attempts)
+	require.Equal(t, "connection refused", *jobs[0].LastError)
+	require.True(t, jobs[0].RunAt.After(time.Now().Add(59*time.Minute)))
+
+	n, err = Process(ctx, conn, failing, config, le)
+	require.NoError(t, err)
+	require.Equal(t, 0, n, "mail is not retried before it is due")
+
+	// the second failure dead-letters the mail
+	require.NoError(t, conn.RawQuery("update mail_jobs set run_at = ?", time.Now().Add(-time.Second)).Exec())
+
+	n, err = Process(ctx, conn, failing, config, le)
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+
+	jobs, err = models.FindMailJobs(conn, models.MailJobDead, nil)
+	require.NoError(t, err)
+	require.Len(t, jobs, 1)
+	require.Equal(t, 2, jobs[0].Attempts)
+
+	n, err = Process(ctx, conn, failing, config, le)
+	require.NoError(t, err)
+	require.Equal(t, 0, n, "dead mail is not retried")
+
+	// re-driven mail is sent and removed from the queue
+	redriven, err := models.RedriveMailJobs(conn, nil)
+	require.NoError(t, err)
+	require.Equal(t, 1, redriven)
+
+	working := &testClient{}
+	n, err = Process(ctx, conn, working, config, le)
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+	require.Equal(t, []mail{{"test@example.com", "Subject", "Body", headers, "signup"}}, working.sent)
+
+	jobs, err = models.FindMailJobs(conn, models.MailJobPending, nil)
+	require.NoError(t, err)
+	require.Empty(t, jobs)
+}
@@ -0,0 +1,96 @@
+package templatemailer
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/supabase/auth/internal/models"
+	"golang.org/x/text/language"
+)
+
+var anyLocale = language.Make("mul")
+
+// NormalizeLocale returns the canonical form of a BCP 47 language tag, such
+// as fr-CA. Underscores are accepted as separators, as in fr_CA.
+func NormalizeLocale(locale string) (string, error) {
+	tag, err := language.Parse(strings.ReplaceAll(strings.TrimSpace(locale), "_", "-"))
+	if err != nil {
+		return "", err
+	}
+	return tag.String(), nil
+}
+
+// RequestLocale returns the most preferred locale of the Accept-Language
+// header of the request, or "" when there is none.
+func RequestLocale(r *http.Request) string {
+	if locales := acceptedLocales(r); len(locales) > 0 {
+		return locales[0]
+	}
+	return ""
+}
+
+// acceptedLocales returns the locales of the Accept-Language header of the
+// request, most preferred first.
+func acceptedLocales(r *http.Request) []string {
+	header := r.Header.Get("Accept-Language")
+	if header == "" {
+		return nil
+	}
+
+	tags, _, err := language.ParseAcceptLanguage(header)
+	if err != nil {
+		return nil
+	}
+
+	var locales []string
+	for _, tag := range tags {
+		// The wildcard * is parsed as mul, it is served by the default
+		// locale.
+		if tag == language.Und || tag == anyLocale {
+			continue
+		}
+		locales = append(locales, tag.String())
+	}
+	return locales
+}
+
+// localeFallbacks returns the locales followed by their fallbacks, which
+// drop the last subtag, and ending with the default locale "". For fr-CA
+// this is fr-CA, fr and "".
+func localeFallbacks(locales []string) []string {
+	var chain []string
+	for _, locale := range locales {
+		for locale != "" {
+			if !slices.Contains(chain, locale) {
+				chain = append(chain, locale)
+			}
+
+			i := strings.LastIndex(locale, "-")
+			if i < 0 {
+				break
+			}
+			locale = locale[:i]
+		}
+	}
+	return append(chain, "")
+}
+
+// locales returns the locales of the templates to send mail to the user
+// with, most preferred first. They are the user's locale and the locales
+// accepted by the request, with their fallbacks.
+func (m *Mailer) locales(r *http.Request, user *models.User) []string {
+	if !m.cfg.Mailer.LocalizationEnabled {
+		return []string{""}
+	}
+
+	var preferred []string
+	if value, ok := user.UserMetaData["locale"].(string); ok {
+		if locale, err := NormalizeLocale(value); err == nil {
+			preferred = append(preferred, locale)
+		}
+	}
+	preferred = append(preferred, acceptedLocales(r)...)
+
+	return localeFallbacks(preferred)
+}
@@ -0,0 +1,60 @@
+package templatemailer
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/supabase/auth/internal/conf"
+	"github.com/supabase/auth/internal/models"
+)
+
+func TestNormalizeLocale(t *testing.T) {
+	cases := map[string]string{
+		"fr":         "fr",
+		"fr-ca":      "fr-CA",
+		"fr_CA":      "fr-CA",
+		"zh-hant-tw": "zh-Hant-TW",
+		" PT-br ":    "pt-BR",
+	}
+	for from, exp := range cases {
+		locale, err := NormalizeLocale(from)
+		require.NoError(t, err, from)
+		require.Equal(t, exp, locale, from)
+	}
+
+	for _, from := range []string{"", "not a locale", "fr-"} {
+		_, err := NormalizeLocale(from)
+		require.Error(t, err, from)
+	}
+}
+
+func TestLocaleFallbacks(t *testing.T) {
+	require.Equal(t, []string{""}, localeFallbacks(nil))
+	require.Equal(t, []string{"fr-CA", "fr", ""}, localeFallbacks([]string{"fr-CA"}))
+	require.Equal(t,
+		[]string{"zh-Hant-TW", "zh-Hant", "zh", "fr", "en-GB", "en", ""},
+		localeFallbacks([]string{"zh-Hant-TW", "fr", "zh", "en-GB"}))
+}
+
+func TestMailerLocales(t *testing.T) {
+	cfg := &conf.GlobalConfiguration{}
+	cfg.Mailer.LocalizationEnabled = true
+	m := New(cfg, nil, NewCache())
+
+	req := httptest.NewRequest("POST", "/recover", nil)
+	req.Header.Set("Accept-Language", "de-AT;q=0.8, fr-CA, *;q=0.1")
+
+	user := &models.User{}
+	require.Equal(t, []string{"fr-CA", "fr", "de-AT", "de", ""}, m.locales(req, user))
+
+	// the user's locale takes precedence over the request's
+	user.UserMetaData = map[string]interface{}{"locale": "pt_BR"}
+	require.Equal(t, []string{"pt-BR", "pt", "fr-CA", "fr", "de-AT", "de", ""}, m.locales(req, user))
+
+	require.Equal(t, "fr-CA", RequestLocale(req))
+	require.Equal(t, "", RequestLocale(httptest.NewRequest("POST", "/signup", nil)))
+
+	cfg.Mailer.LocalizationEnabled = false
+	require.Equal(t, []string{""}, m.locales(req, user))
+}
@@ -0,0 +1,397 @@
+package templatemailer
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// CompileMJML compiles an MJML document to an HTML template. It supports the
+// subset of MJML used for transactional mail: mj-head with mj-title,
+// mj-preview and mj-style, and mj-body with mj-section, mj-column, mj-text,
+// mj-button, mj-image, mj-divider, mj-spacer and mj-raw. Template actions
+// such as {{ .ConfirmationURL }} may be used in attributes and content, text
+// between elements is kept so that actions can wrap whole elements.
+func CompileMJML(src string) (string, error) {
+	c := &mjmlCompiler{
+		src: src,
+		dec: xml.NewDecoder(strings.NewReader(src)),
+	}
+	c.dec.Strict = false
+	c.dec.AutoClose = xml.HTMLAutoClose
+	c.dec.Entity = xml.HTMLEntity
+
+	if err := c.compile(); err != nil {
+		return "", fmt.Errorf("mjml: %w", err)
+	}
+	return c.out.String(), nil
+}
+
+type mjmlCompiler struct {
+	src string
+	dec *xml.Decoder
+	out strings.Builder
+
+	title   string
+	preview string
+	style   string
+}
+
+// mjmlElement is an element with its attributes and, for the elements with
+// HTML content, the source between its start and end tags.
+type mjmlElement struct {
+	name     string
+	attrs    map[string]string
+	children []mjmlNode
+	inner    string
+}
+
+// mjmlNode is either an element or text between elements.
+type mjmlNode struct {
+	elem *mjmlElement
+	text string
+}
+
+func (e *mjmlElement) attr(name, def string) string {
+	if v, ok := e.attrs[name]; ok && v != "" {
+		return v
+	}
+	return def
+}
+
+// mjmlContentElements hold HTML content instead of MJML elements.
+var mjmlContentElements = map[string]bool{
+	"mj-text":    true,
+	"mj-button":  true,
+	"mj-raw":     true,
+	"mj-title":   true,
+	"mj-preview": true,
+	"mj-style":   true,
+}
+
+func (c *mjmlCompiler) compile() error {
+	root, err := c.parseDocument()
+	if err != nil {
+		return err
+	}
+	if root.name != "mjml" {
+		return fmt.Errorf("document must start with <mjml>, found <%s>", root.name)
+	}
+
+	var body *mjmlElement
+	for _, child := range root.children {
+		switch {
+		case child.elem == nil:
+			continue
+		case child.elem.name == "mj-head":
+			if err := c.head(child.elem); err != nil {
+				return err
+			}
+		case child.elem.name == "mj-body":
+			body = child.elem
+		default:
+			return unsupportedElement(child.elem, "mjml")
+		}
+	}
+	if body == nil {
+		return errors.New("document has no <mj-body>")
+	}
+
+	width := body.attr("width", "600px")
+	background := body.attr("background-color", "#ffffff")
+
+	c.out.WriteString("<!doctype html>\n<html>\n<head>\n")
+	c.out.WriteString(`<meta charset="utf-8">` + "\n")
+	c.out.WriteString(`<meta name="viewport" content="width=device-width, initial-scale=1">` + "\n")
+	if c.title != "" {
+		c.out.WriteString("<title>" + c.title + "</title>\n")
+	}
+	if c.style != "" {
+		c.out.WriteString("<style>" + c.style + "</style>\n")
+	}
+	c.out.WriteString("</head>\n")
+	c.out.WriteString("<body" + attrs("style", "margin:0;padding:0;background-color:"+background) + ">\n")
+	if c.preview != "" {
+		c.out.WriteString(`<div style="display:none;max-height:0;overflow:hidden">` + c.preview + "</div>\n")
+	}
+	c.out.WriteString("<div" + attrs("style", "margin:0 auto;max-width:"+width) + ">\n")
+
+	for _, child := range body.children {
+		if child.elem == nil {
+			c.out.WriteString(child.text)
+			continue
+		}
+		if child.elem.name != "mj-section" {
+			return unsupportedElement(child.elem, "mj-body")
+		}
+		if err := c.section(child.elem); err != nil {
+			return err
+		}
+	}
+
+	c.out.WriteString("</div>\n</body>\n</html>\n")
+	return nil
+}
+
+func (c *mjmlCompiler) head(head *mjmlElement) error {
+	for _, child := range head.children {
+		if child.elem == nil {
+			continue
+		}
+		switch child.elem.name {
+		case "mj-title":
+			c.title = strings.TrimSpace(child.elem.inner)
+		case "mj-preview":
+			c.preview = strings.TrimSpace(child.elem.inner)
+		case "mj-style":
+			c.style += child.elem.inner
+		default:
+			return unsupportedElement(child.elem, "mj-head")
+		}
+	}
+	return nil
+}
+
+func (c *mjmlCompiler) section(section *mjmlElement) error {
+	style := "padding:" + section.attr("padding", "20px 0") +
+		";text-align:" + section.attr("text-align", "center")
+	if bg := section.attr("background-color", ""); bg != "" {
+		style += ";background-color:" + bg
+	}
+
+	var columns []*mjmlElement
+	for _, child := range section.children {
+		if child.elem == nil {
+			continue
+		}
+		if child.elem.name != "mj-column" {
+			return unsupportedElement(child.elem, "mj-section")
+		}
+		columns = append(columns, child.elem)
+	}
+
+	c.out.WriteString(`<table role="presentation" width="100%" cellpadding="0" cellspacing="0" border="0"><tr>`)
+	c.out.WriteString("<td" + attrs("style", style) + ">\n")
+	c.out.WriteString(`<table role="presentation" width="100%" cellpadding="0" cellspacing="0" border="0"><tr>` + "\n")
+
+	for _, child := range section.children {
+		if child.elem == nil {
+			c.out.WriteString(child.text)
+			continue
+		}
+		if err := c.column(child.elem, len(columns)); err != nil {
+			return err
+		}
+	}
+
+	c.out.WriteString("</tr></table>\n</td></tr></table>\n")
+	return nil
+}
+
+func (c *mjmlCompiler) column(column *mjmlElement, count int) error {
+	width := column.attr("width", fmt.Sprintf("%d%%", 100/count))
+
+	style := "vertical-align:" + column.attr("vertical-align", "top")
+	if padding := column.attr("padding", ""); padding != "" {
+		style += ";padding:" + padding
+	}
+	if bg := column.attr("background-color", ""); bg != "" {
+		style += ";background-color:" + bg
+	}
+
+	c.out.WriteString("<td" + attrs("width", width, "style", style) + ">\n")
+	c.out.WriteString(`<table role="presentation" width="100%" cellpadding="0" cellspacing="0" border="0">` + "\n")
+
+	for _, child := range column.children {
+		if child.elem == nil {
+			c.out.WriteString(child.text)
+			continue
+		}
+		if err := c.component(child.elem); err != nil {
+			return err
+		}
+	}
+
+	c.out.WriteString("</table>\n</td>\n")
+	return nil
+}
+
+func (c *mjmlCompiler) component(e *mjmlElement) error {
+	align := e.attr("align", "left")
+	padding := e.attr("padding", "10px 25px")
+
+	var content string
+	switch e.name {
+	case "mj-text":
+		style := "font-family:" + e.attr("font-family", "Ubuntu, Helvetica, Arial, sans-serif") +
+			";font-size:" + e.attr("font-size", "13px") +
+			";line-height:" + e.attr("line-height", "1.5") +
+			";color:" + e.attr("color", "#000000") +
+			";text-align:" + align
+		content = "<div" + attrs("style", style) + ">" + e.inner + "</div>"
+
+	case "mj-button":
+		align = e.attr("align", "center")
+		style := "display:inline-block" +
+			";background-color:" + e.attr("background-color", "#414141") +
+			";color:" + e.attr("color", "#ffffff") +
+			";font-family:" + e.attr("font-family", "Ubuntu, Helvetica, Arial, sans-serif") +
+			";font-size:" + e.attr("font-size", "13px") +
+			";padding:" + e.attr("inner-padding", "10px 25px") +
+			";border-radius:" + e.attr("border-radius", "3px") +
+			";text-decoration:none"
+		content = "<a" + attrs("href", e.attr("href", "#"), "style", style) + ">" + e.inner + "</a>"
+
+	case "mj-image":
+		align = e.attr("align", "center")
+		src, ok := e.attrs["src"]
+		if !ok {
+			return errors.New("<mj-image> requires a src attribute")
+		}
+		img := "<img" + attrs(
+			"src", src,
+			"alt", e.attr("alt", ""),
+			"width", strings.TrimSuffix(e.attr("width", "100%"), "px"),
+			"style", "border:0;display:block;outline:none;max-width:100%;height:auto",
+		) + ">"
+		if href := e.attr("href", ""); href != "" {
+			img = "<a" + attrs("href", href) + ">" + img + "</a>"
+		}
+		content = img
+
+	case "mj-divider":
+		style := "border-top:" + e.attr("border-style", "solid") +
+			" " + e.attr("border-width", "4px") +
+			" " + e.attr("border-color", "#000000") +
+			";margin:0 auto;width:100%"
+		content = "<p" + attrs("style", style) + "></p>"
+
+	case "mj-spacer":
+		height := e.attr("height", "20px")
+		padding = "0"
+		content = "<div" + attrs("style", "height:"+height+";line-height:"+height) + ">&#8202;</div>"
+
+	case "mj-raw":
+		c.out.WriteString(e.inner + "\n")
+		return nil
+
+	default:
+		return unsupportedElement(e, "mj-column")
+	}
+
+	c.out.WriteString("<tr><td" + attrs("style", "padding:"+padding+";text-align:"+align) + ">")
+	c.out.WriteString(content)
+	c.out.WriteString("</td></tr>\n")
+	return nil
+}
+
+func unsupportedElement(e *mjmlElement, parent string) error {
+	return fmt.Errorf("<%s> is not supported in <%s>", e.name, parent)
+}
+
+// attrs formats HTML attributes from name and value pairs. Values are not
+// escaped so that template actions in them are kept as written, they are
+// quoted with single quotes when they hold a double quote.
+func attrs(pairs ...string) string {
+	var b strings.Builder
+	for i := 0; i+1 < len(pairs); i += 2 {
+		quote := `"`
+		if strings.Contains(pairs[i+1], `"`) {
+			quote = `'`
+		}
+		b.WriteString(" " + pairs[i] + "=" + quote + pairs[i+1] + quote)
+	}
+	return b.String()
+}
+
+// parseDocument parses the source into its root element.
+func (c *mjmlCompiler) parseDocument() (*mjmlElement, error) {
+	for {
+		tok, err := c.dec.Token()
+		if err == io.EOF {
+			return nil, errors.New("document is empty")
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if start, ok := tok.(xml.StartElement); ok {
+			return c.parseElement(start)
+		}
+	}
+}
+
+func (c *mjmlCompiler) parseElement(start xml.StartElement) (*mjmlElement, error) {
+	e := &mjmlElement{
+		name:  start.Name.Local,
+		attrs: make(map[string]string, len(start.Attr)),
+	}
+	for _, attr := range start.Attr {
+		e.attrs[attr.Name.Local] = attr.Value
+	}
+
+	if mjmlContentElements[e.name] {
+		inner, err := c.parseInner(e.name)
+		if err != nil {
+			return nil, err
+		}
+		e.inner = inner
+		return e, nil
+	}
+
+	for {
+		tok, err := c.dec.Token()
+		if err == io.EOF {
+			return nil, fmt.Errorf("<%s> is not closed", e.name)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			child, err := c.parseElement(tok)
+			if err != nil {
+				return nil, err
+			}
+			e.children = append(e.children, mjmlNode{elem: child})
+		case xml.EndElement:
+			return e, nil
+		case xml.CharData:
+			if text := strings.TrimSpace(string(tok)); text != "" {
+				e.children = append(e.children, mjmlNode{text: text + "\n"})
+			}
+		}
+	}
+}
+
+// parseInner returns the source between the start tag of a content element,
+// which was just read, and its end tag.
+func (c *mjmlCompiler) parseInner(name string) (string, error) {
+	from := c.dec.InputOffset()
+	depth := 0
+
+	for {
+		to := c.dec.InputOffset()
+
+		tok, err := c.dec.Token()
+		if err == io.EOF {
+			return "", fmt.Errorf("<%s> is not closed", name)
+		}
+		if err != nil {
+			return "", err
+		}
+
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			depth++
+		case xml.EndElement:
+			if depth == 0 && tok.Name.Local == name {
+				return strings.TrimSpace(c.src[from:to]), nil
+			}
+			depth--
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package templatemailer
+
+import (
+	"bytes"
+	"html/template"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompileMJML(t *testing.T) {
+	src := `<mjml>
+  <mj-head>
+    <mj-title>Confirm Your Email</mj-title>
+    <mj-preview>Confirm your email address</mj-preview>
+  </mj-head>
+  <mj-body background-color="#f4f4f4" width="500px">
+    <mj-section background-color="#ffffff">
+      <mj-column>
+        <mj-image src="https://example.com/logo.png" alt="Logo" width="100px" />
+        <mj-text font-size="16px">Hi {{ .Email }},<br>please confirm your email.</mj-text>
+        {{ if .ConfirmationURL }}
+        <mj-button href="{{ .ConfirmationURL }}" background-color="#3ecf8e">Confirm</mj-button>
+        {{ end }}
+        <mj-divider border-width="1px" />
+        <mj-spacer height="10px" />
+        <mj-raw><p class="code">{{ .Token }}</p></mj-raw>
+      </mj-column>
+    </mj-section>
+  </mj-body>
+</mjml>`
+
+	out, err := CompileMJML(src)
+	require.NoError(t, err)
+
+	require.Contains(t, out, "<title>Confirm Your Email</title>")
+	require.Contains(t, out, "Confirm your email address</div>")
+	require.Contains(t, out, "max-width:500px")
+	require.Contains(t, out, "background-color:#ffffff")
+	require.Contains(t, out, `<img src="https://example.com/logo.png" alt="Logo" width="100"`)
+	require.Contains(t, out, "font-size:16px")
+	require.Contains(t, out, ">Hi {{ .Email }},<br>please confirm your email.</div>")
+	require.Contains(t, out, "{{ if .ConfirmationURL }}\n<tr>")
+	require.Contains(t, out, `<a href="{{ .ConfirmationURL }}" style="display:inline-block;background-color:#3ecf8e`)
+	require.Contains(t, out, "border-top:solid 1px #000000")
+	require.Contains(t, out, "height:10px")
+	require.Contains(t, out, `<p class="code">{{ .Token }}</p>`)
+
+	// the compiled template can be executed
+	temp, err := template.New("").Parse(out)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, temp.Execute(&buf, map[string]any{
+		"Email":           "user@example.com",
+		"ConfirmationURL": "https://example.com/verify?token=abc",
+		"Token":           "123456",
+	}))
+	require.Contains(t, buf.String(), `href="https://example.com/verify?token=abc"`)
+	require.Contains(t, buf.String(), "123456")
+}
+
+func TestCompileMJMLQuotedAttributes(t *testing.T) {
+	out, err := CompileMJML(`<mjml><mj-body><mj-section><mj-column>
+<mj-button href='{{ index .Data "url" }}'>Open</mj-button>
+</mj-column></mj-section></mj-body></mjml>`)
+	require.NoError(t, err)
+	require.Contains(t, out, `<a href='{{ index .Data "url" }}'`)
+}
+
+func TestCompileMJMLErrors(t *testing.T) {
+	cases := []struct {
+		src string
+		err string
+	}{
+		{
+			src: ``,
+			err: "document is empty",
+		},
+		{
+			src: `<html><body></body></html>`,
+			err: "document must start with <mjml>",
+		},
+		{
+			src: `<mjml><mj-head></mj-head></mjml>`,
+			err: "document has no <mj-body>",
+		},
+		{
+			src: `<mjml><mj-body><mj-column></mj-column></mj-body></mjml>`,
+			err: "<mj-column> is not supported in <mj-body>",
+		},
+		{
+			src: `<mjml><mj-body><mj-section><mj-column><mj-carousel /></mj-column></mj-section></mj-body></mjml>`,
+			err: "<mj-carousel> is not supported in <mj-column>",
+		},
+		{
+			src: `<mjml><mj-body><mj-section><mj-column><mj-image /></mj-column></mj-section></mj-body></mjml>`,
+			err: "<mj-image> requires a src attribute",
+		},
+		{
+			src: `<mjml><mj-body><mj-section><mj-column><mj-text>Hello`,
+			err: "unexpected EOF",
+		},
+	}
+
+	for _, tc := range cases {
+		_, err := CompileMJML(tc.src)
+		require.ErrorContains(t, err, tc.err, tc.src)
+	}
+}
@@ -11,14 +11,19 @@ import (
 	"sync"
 	"time"
 
+	"github.com/gofrs/uuid"
 	"github.com/sirupsen/logrus"
 	"github.com/supabase/auth/internal/conf"
 	"github.com/supabase/auth/internal/mailer"
+	"github.com/supabase/auth/internal/mailer/apiclient"
 	"github.com/supabase/auth/internal/mailer/mailmeclient"
 	"github.com/supabase/auth/internal/mailer/noopclient"
 	"github.com/supabase/auth/internal/mailer/taskclient"
 	"github.com/supabase/auth/internal/mailer/validateclient"
+	"github.com/supabase/auth/internal/models"
 	"github.com/supabase/auth/internal/observability"
+	"github.com/supabase/auth/internal/storage"
+	"go.opentelemetry.io/otel/attribute"
 	"golang.org/x/sync/singleflight"
 )
 
@@ -34,17 +39,16 @@ type Mailer struct {
 	cfg *conf.GlobalConfiguration
 	mc  mailer.Client
 	tc  *Cache
+
+	// db holds the templates managed with the admin API, they are used
+	// instead of the configured templates when db is set.
+	db       *storage.Connection
+	tenantID *uuid.UUID
 }
 
 // FromConfig returns a new mailer configured using the global configuration.
 func FromConfig(globalConfig *conf.GlobalConfiguration, tc *Cache) *Mailer {
-	var mc mailer.Client
-	if globalConfig.SMTP.Host == "" {
-		logrus.Infof("Noop mail client being used for %v", globalConfig.SiteURL)
-		mc = noopclient.New()
-	} else {
-		mc = mailmeclient.New(globalConfig)
-	}
+	mc := NewClient(globalConfig)
 
 	// Wrap client with validation first
 	mc = validateclient.New(globalConfig, mc)
@@ -56,6 +60,37 @@ func FromConfig(globalConfig *conf.GlobalConfiguration, tc *Cache) *Mailer {
 	return New(globalConfig, mc, tc)
 }
 
+// FromQueue returns a new mailer configured using the global configuration
+// that adds mail to the queue with qc instead of sending it.
+func FromQueue(globalConfig *conf.GlobalConfiguration, qc mailer.Client, tc *Cache) *Mailer {
+	// Mail is validated before it is queued so that invalid addresses are
+	// still reported to the caller.
+	mc := validateclient.New(globalConfig, qc)
+
+	return New(globalConfig, mc, tc)
+}
+
+// NewClient returns the mailer.Client sending mail with the configured
+// transport. With SMTP it is a noop client when no SMTP host is configured.
+func NewClient(globalConfig *conf.GlobalConfiguration) mailer.Client {
+	switch globalConfig.Mailer.Transport {
+	case conf.MailerTransportSES:
+		return apiclient.NewSES(globalConfig)
+	case conf.MailerTransportSendGrid:
+		return apiclient.NewSendGrid(globalConfig)
+	case conf.MailerTransportMailgun:
+		return apiclient.NewMailgun(globalConfig)
+	case conf.MailerTransportPostmark:
+		return apiclient.NewPostmark(globalConfig)
+	}
+
+	if globalConfig.SMTP.Host == "" {
+		logrus.Infof("Noop mail client being used for %v", globalConfig.SiteURL)
+		return noopclient.New()
+	}
+	return mailmeclient.New(globalConfig)
+}
+
 // New will return a *TemplateMailer backed by the given mailer.Client.
 func New(globalConfig *conf.GlobalConfiguration, mc mailer.Client, tc *Cache) *Mailer {
 	return &Mailer{
@@ -65,11 +100,23 @@ func New(globalConfig *conf.GlobalConfiguration, mc mailer.Client, tc *Cache) *M
 	}
 }
 
+// WithDatabase returns a copy of the mailer that sends mail with the active
+// templates stored in db, falling back to the configured templates for the
+// types without one. A tenant's mailer prefers the tenant's templates over
+// the deployment's, tenantID is nil for the deployment's mailer.
+func (m *Mailer) WithDatabase(db *storage.Connection, tenantID *uuid.UUID) *Mailer {
+	cpy := *m
+	cpy.db = db
+	cpy.tenantID = tenantID
+	return &cpy
+}
+
 func (m *Mailer) mail(
 	ctx context.Context,
 	cfg *conf.GlobalConfiguration,
 	tpl string,
 	to string,
+	locales []string,
 	data map[string]any,
 ) error {
 	if _, ok := lookupEmailContentConfig(&cfg.Mailer.Subjects, tpl); !ok {
@@ -84,9 +131,17 @@ func (m *Mailer) mail(
 	}
 	headers := m.Headers(cfg, typ)
 
-	ent, err := m.tc.get(ctx, cfg, tpl)
-	if err != nil {
-		return err
+	if _, ok := data["ConfirmationURL"]; ok && cfg.Mailer.OTPOnly {
+		data["ConfirmationURL"] = ""
+	}
+
+	ent := m.loadDatabaseEntry(ctx, tpl, locales)
+	if ent == nil {
+		var err error
+		ent, err = m.tc.get(ctx, cfg, tpl)
+		if err != nil {
+			return err
+		}
 	}
 
 	var buf bytes.Buffer
@@ -94,7 +149,9 @@ func (m *Mailer) mail(
 	if err != nil {
 		return err
 	}
-	return m.mc.Mail(
+
+	ctx, span := observability.StartSpan(ctx, "mailer.send", attribute.String("gotrue.mail.type", typ))
+	err = m.mc.Mail(
 		ctx,
 		to,
 		subject,
@@ -102,6 +159,8 @@ func (m *Mailer) mail(
 		headers,
 		typ,
 	)
+	observability.EndSpan(span, err)
+	return err
 }
 
 type tplCacheEntry struct {
@@ -402,6 +461,42 @@ func (o *Cache) loadEntry(
 	return ent, nil
 }
 
+// loadDatabaseEntry returns an entry for the active template of typ stored
+// in the database, in the first of the locales that has one. It returns nil
+// when there is no such template or it can not be loaded, so that the
+// configured template is used instead.
+func (m *Mailer) loadDatabaseEntry(
+	ctx context.Context,
+	typ string,
+	locales []string,
+) *tplCacheEntry {
+	if m.db == nil {
+		return nil
+	}
+
+	t, err := models.FindActiveEmailTemplate(m.db.WithContext(ctx), m.tenantID, typ, locales)
+	if err != nil {
+		if !models.IsNotFoundError(err) {
+			_ = wrapError(ctx, typ, "template_database_error", err)
+		}
+		return nil
+	}
+
+	subjectTemp, err := template.New("Subject").Parse(t.Subject)
+	if err != nil {
+		_ = wrapError(ctx, typ, "template_subject_parse_error", err)
+		return nil
+	}
+
+	bodyTemp, err := template.New(typ).Parse(t.Body)
+	if err != nil {
+		_ = wrapError(ctx, typ, "template_body_parse_error", err)
+		return nil
+	}
+
+	return newTplCacheEntry(t.UpdatedAt, typ, subjectTemp, bodyTemp)
+}
+
 // loadEntryDefault will never fail due to the checkDefaults() in init().
 func (o *Cache) loadEntryDefault(
 	typ string,
@@ -549,6 +644,11 @@ func lookupEmailContentConfig(
 		return cfg.MFAFactorEnrolledNotification, true
 	case MFAFactorUnenrolledNotificationTemplate:
 		return cfg.MFAFactorUnenrolledNotification, true
+
+	case DataExportNotificationTemplate:
+		return cfg.DataExportNotification, true
+	case NewSignInNotificationTemplate:
+		return cfg.NewSignInNotification, true
 	}
 }
 
@@ -567,15 +667,22 @@ func getEmailContentConfig(
 func checkDefaults() error {
 	seen := make(map[string]bool)
 	data := map[string]any{
+		"City":            "City",
 		"ConfirmationURL": "ConfirmationURL",
+		"Country":         "Country",
 		"Data":            "Data",
+		"DownloadURL":     "DownloadURL",
 		"Email":           "Email",
+		"ExpiresIn":       "ExpiresIn",
+		"IP":              "IP",
 		"NewEmail":        "NewEmail",
 		"RedirectTo":      "RedirectTo",
+		"RevokeURL":       "RevokeURL",
 		"SendingTo":       "SendingTo",
 		"SiteURL":         "SiteURL",
 		"Token":           "Token",
 		"TokenHash":       "TokenHash",
+		"UserAgent":       "UserAgent",
 	}
 
 	buf := new(bytes.Buffer)
@@ -8,6 +8,7 @@ import (
 	"strings"
 
 	"github.com/supabase/auth/internal/conf"
+	"github.com/supabase/auth/internal/mailer"
 	"github.com/supabase/auth/internal/models"
 )
 
@@ -27,38 +28,61 @@ const (
 	IdentityUnlinkedNotificationTemplate    = "identity_unlinked_notification"
 	MFAFactorEnrolledNotificationTemplate   = "mfa_factor_enrolled_notification"
 	MFAFactorUnenrolledNotificationTemplate = "mfa_factor_unenrolled_notification"
+
+	DataExportNotificationTemplate = "data_export_notification"
+	NewSignInNotificationTemplate  = "new_sign_in_notification"
 )
 
 const defaultInviteMail = `<h2>You have been invited</h2>
 
+{{ if .ConfirmationURL -}}
 <p>You have been invited to create a user on {{ .SiteURL }}. Follow this link to accept the invite:</p>
 <p><a href="{{ .ConfirmationURL }}">Accept the invite</a></p>
-<p>Alternatively, enter the code: {{ .Token }}</p>`
+<p>Alternatively, enter the code: {{ .Token }}</p>
+{{- else -}}
+<p>You have been invited to create a user on {{ .SiteURL }}. Enter the code to accept the invite: {{ .Token }}</p>
+{{- end }}`
 
 const defaultConfirmationMail = `<h2>Confirm Your Email</h2>
 
+{{ if .ConfirmationURL -}}
 <p>Follow this link to confirm your email:</p>
 <p><a href="{{ .ConfirmationURL }}">Confirm your email address</a></p>
 <p>Alternatively, enter the code: {{ .Token }}</p>
+{{- else -}}
+<p>Enter the code to confirm your email: {{ .Token }}</p>
+{{- end }}
 `
 
 const defaultRecoveryMail = `<h2>Reset password</h2>
 
+{{ if .ConfirmationURL -}}
 <p>Follow this link to reset the password for your user:</p>
 <p><a href="{{ .ConfirmationURL }}">Reset password</a></p>
-<p>Alternatively, enter the code: {{ .Token }}</p>`
+<p>Alternatively, enter the code: {{ .Token }}</p>
+{{- else -}}
+<p>Enter the code to reset the password for your user: {{ .Token }}</p>
+{{- end }}`
 
 const defaultMagicLinkMail = `<h2>Magic Link</h2>
 
+{{ if .ConfirmationURL -}}
 <p>Follow this link to login:</p>
 <p><a href="{{ .ConfirmationURL }}">Log In</a></p>
-<p>Alternatively, enter the code: {{ .Token }}</p>`
+<p>Alternatively, enter the code: {{ .Token }}</p>
+{{- else -}}
+<p>Enter the code to login: {{ .Token }}</p>
+{{- end }}`
 
 const defaultEmailChangeMail = `<h2>Confirm email address change</h2>
 
+{{ if .ConfirmationURL -}}
 <p>Follow this link to confirm the update of your email address from {{ .Email }} to {{ .NewEmail }}:</p>
 <p><a href="{{ .ConfirmationURL }}">Change email address</a></p>
-<p>Alternatively, enter the code: {{ .Token }}</p>`
+<p>Alternatively, enter the code: {{ .Token }}</p>
+{{- else -}}
+<p>Enter the code to confirm the update of your email address from {{ .Email }} to {{ .NewEmail }}: {{ .Token }}</p>
+{{- end }}`
 
 const defaultReauthenticateMail = `<h2>Confirm reauthentication</h2>
 
@@ -108,6 +132,25 @@ const defaultMFAFactorUnenrolledNotificationMail = `<h2>An MFA factor has been u
 <p>If you did not make this change, please contact support immediately.</p>
 `
 
+const defaultDataExportNotificationMail = `<h2>Your data export is ready</h2>
+
+<p>The export of the data of your account {{ .Email }} is ready.</p>
+<p><a href="{{ .DownloadURL }}">Download your data</a></p>
+<p>The link expires in {{ .ExpiresIn }}. If you did not request this export, please contact support immediately.</p>
+`
+
+const defaultNewSignInNotificationMail = `<h2>New sign-in to your account</h2>
+
+<p>Your account {{ .Email }} was signed in to from a new location or device.</p>
+<ul>
+  <li>Location: {{ if .City }}{{ .City }}, {{ end }}{{ if .Country }}{{ .Country }}{{ else }}Unknown{{ end }}</li>
+  <li>Device: {{ if .UserAgent }}{{ .UserAgent }}{{ else }}Unknown{{ end }}</li>
+  <li>IP address: {{ .IP }}</li>
+</ul>
+<p>If this was you, you can ignore this email. Otherwise, sign out the new session and change your password immediately.</p>
+<p><a href="{{ .RevokeURL }}">This wasn't me</a></p>
+`
+
 var (
 	templateTypes = []string{
 		InviteTemplate,
@@ -125,6 +168,9 @@ var (
 		IdentityUnlinkedNotificationTemplate,
 		MFAFactorEnrolledNotificationTemplate,
 		MFAFactorUnenrolledNotificationTemplate,
+
+		DataExportNotificationTemplate,
+		NewSignInNotificationTemplate,
 	}
 	defaultTemplateSubjects = &conf.EmailContentConfiguration{
 		Invite:           "You have been invited",
@@ -142,6 +188,9 @@ var (
 		IdentityUnlinkedNotification:    "An identity has been unlinked",
 		MFAFactorEnrolledNotification:   "A new MFA factor has been enrolled",
 		MFAFactorUnenrolledNotification: "An MFA factor has been unenrolled",
+
+		DataExportNotification: "Your data export is ready",
+		NewSignInNotification:  "New sign-in to your account",
 	}
 	defaultTemplateBodies = &conf.EmailContentConfiguration{
 		Invite:           defaultInviteMail,
@@ -159,6 +208,9 @@ var (
 		IdentityUnlinkedNotification:    defaultIdentityUnlinkedNotificationMail,
 		MFAFactorEnrolledNotification:   defaultMFAFactorEnrolledNotificationMail,
 		MFAFactorUnenrolledNotification: defaultMFAFactorUnenrolledNotificationMail,
+
+		DataExportNotification: defaultDataExportNotificationMail,
+		NewSignInNotification:  defaultNewSignInNotificationMail,
 	}
 )
 
@@ -218,7 +270,7 @@ func (m *Mailer) InviteMail(r *http.Request, user *models.User, otp, referrerURL
 		"Data":            user.UserMetaData,
 		"RedirectTo":      referrerURL,
 	}
-	return m.mail(r.Context(), m.cfg, InviteTemplate, user.GetEmail(), data)
+	return m.mail(r.Context(), m.cfg, InviteTemplate, user.GetEmail(), m.locales(r, user), data)
 }
 
 // ConfirmationMail sends a signup confirmation mail to a new user
@@ -241,7 +293,7 @@ func (m *Mailer) ConfirmationMail(r *http.Request, user *models.User, otp, refer
 		"Data":            user.UserMetaData,
 		"RedirectTo":      referrerURL,
 	}
-	return m.mail(r.Context(), m.cfg, ConfirmationTemplate, user.GetEmail(), data)
+	return m.mail(r.Context(), m.cfg, ConfirmationTemplate, user.GetEmail(), m.locales(r, user), data)
 }
 
 // ReauthenticateMail sends a reauthentication mail to an authenticated user
@@ -252,7 +304,7 @@ func (m *Mailer) ReauthenticateMail(r *http.Request, user *models.User, otp stri
 		"Token":   otp,
 		"Data":    user.UserMetaData,
 	}
-	return m.mail(r.Context(), m.cfg, ReauthenticationTemplate, user.GetEmail(), data)
+	return m.mail(r.Context(), m.cfg, ReauthenticationTemplate, user.GetEmail(), m.locales(r, user), data)
 }
 
 // EmailChangeMail sends an email change confirmation mail to a user
@@ -283,6 +335,7 @@ func (m *Mailer) EmailChangeMail(r *http.Request, user *models.User, otpNew, otp
 	ctx, cancel := context.WithCancel(r.Context())
 	defer cancel()
 
+	locales := m.locales(r, user)
 	errors := make(chan error, len(emails))
 	for _, email := range emails {
 		path, err := getPath(
@@ -313,6 +366,7 @@ func (m *Mailer) EmailChangeMail(r *http.Request, user *models.User, otpNew, otp
 				m.cfg,
 				EmailChangeTemplate,
 				address,
+				locales,
 				data,
 			)
 		}(email.Address, email.Otp, email.TokenHash)
@@ -346,7 +400,7 @@ func (m *Mailer) RecoveryMail(r *http.Request, user *models.User, otp, referrerU
 		"Data":            user.UserMetaData,
 		"RedirectTo":      referrerURL,
 	}
-	return m.mail(r.Context(), m.cfg, RecoveryTemplate, user.GetEmail(), data)
+	return m.mail(r.Context(), m.cfg, RecoveryTemplate, user.GetEmail(), m.locales(r, user), data)
 }
 
 // MagicLinkMail sends a login link mail
@@ -369,7 +423,7 @@ func (m *Mailer) MagicLinkMail(r *http.Request, user *models.User, otp, referrer
 		"Data":            user.UserMetaData,
 		"RedirectTo":      referrerURL,
 	}
-	return m.mail(r.Context(), m.cfg, MagicLinkTemplate, user.GetEmail(), data)
+	return m.mail(r.Context(), m.cfg, MagicLinkTemplate, user.GetEmail(), m.locales(r, user), data)
 }
 
 // GetEmailActionLink returns a magiclink, recovery or invite link based on the actionType passed.
@@ -428,7 +482,7 @@ func (m *Mailer) PasswordChangedNotificationMail(r *http.Request, user *models.U
 		"Email": user.Email,
 		"Data":  user.UserMetaData,
 	}
-	return m.mail(r.Context(), m.cfg, PasswordChangedNotificationTemplate, user.GetEmail(), data)
+	return m.mail(r.Context(), m.cfg, PasswordChangedNotificationTemplate, user.GetEmail(), m.locales(r, user), data)
 }
 
 func (m *Mailer) EmailChangedNotificationMail(r *http.Request, user *models.User, oldEmail string) error {
@@ -437,7 +491,7 @@ func (m *Mailer) EmailChangedNotificationMail(r *http.Request, user *models.User
 		"OldEmail": oldEmail,        // the old email address that was on the account before the change
 		"Data":     user.UserMetaData,
 	}
-	return m.mail(r.Context(), m.cfg, EmailChangedNotificationTemplate, oldEmail, data)
+	return m.mail(r.Context(), m.cfg, EmailChangedNotificationTemplate, oldEmail, m.locales(r, user), data)
 }
 
 func (m *Mailer) PhoneChangedNotificationMail(r *http.Request, user *models.User, oldPhone string) error {
@@ -447,7 +501,7 @@ func (m *Mailer) PhoneChangedNotificationMail(r *http.Request, user *models.User
 		"OldPhone": oldPhone,        // the old phone number that was on the account before the change
 		"Data":     user.UserMetaData,
 	}
-	return m.mail(r.Context(), m.cfg, PhoneChangedNotificationTemplate, user.GetEmail(), data)
+	return m.mail(r.Context(), m.cfg, PhoneChangedNotificationTemplate, user.GetEmail(), m.locales(r, user), data)
 }
 
 func (m *Mailer) IdentityLinkedNotificationMail(r *http.Request, user *models.User, provider string) error {
@@ -456,7 +510,7 @@ func (m *Mailer) IdentityLinkedNotificationMail(r *http.Request, user *models.Us
 		"Provider": provider, // the provider of the newly linked identity
 		"Data":     user.UserMetaData,
 	}
-	return m.mail(r.Context(), m.cfg, IdentityLinkedNotificationTemplate, user.GetEmail(), data)
+	return m.mail(r.Context(), m.cfg, IdentityLinkedNotificationTemplate, user.GetEmail(), m.locales(r, user), data)
 }
 
 func (m *Mailer) IdentityUnlinkedNotificationMail(r *http.Request, user *models.User, provider string) error {
@@ -465,7 +519,7 @@ func (m *Mailer) IdentityUnlinkedNotificationMail(r *http.Request, user *models.
 		"Provider": provider, // the provider of the unlinked identity
 		"Data":     user.UserMetaData,
 	}
-	return m.mail(r.Context(), m.cfg, IdentityUnlinkedNotificationTemplate, user.GetEmail(), data)
+	return m.mail(r.Context(), m.cfg, IdentityUnlinkedNotificationTemplate, user.GetEmail(), m.locales(r, user), data)
 }
 
 func (m *Mailer) MFAFactorEnrolledNotificationMail(r *http.Request, user *models.User, factorType string) error {
@@ -474,7 +528,7 @@ func (m *Mailer) MFAFactorEnrolledNotificationMail(r *http.Request, user *models
 		"FactorType": factorType,
 		"Data":       user.UserMetaData,
 	}
-	return m.mail(r.Context(), m.cfg, MFAFactorEnrolledNotificationTemplate, user.GetEmail(), data)
+	return m.mail(r.Context(), m.cfg, MFAFactorEnrolledNotificationTemplate, user.GetEmail(), m.locales(r, user), data)
 }
 
 func (m *Mailer) MFAFactorUnenrolledNotificationMail(r *http.Request, user *models.User, factorType string) error {
@@ -483,7 +537,30 @@ func (m *Mailer) MFAFactorUnenrolledNotificationMail(r *http.Request, user *mode
 		"FactorType": factorType,
 		"Data":       user.UserMetaData,
 	}
-	return m.mail(r.Context(), m.cfg, MFAFactorUnenrolledNotificationTemplate, user.GetEmail(), data)
+	return m.mail(r.Context(), m.cfg, MFAFactorUnenrolledNotificationTemplate, user.GetEmail(), m.locales(r, user), data)
+}
+
+func (m *Mailer) DataExportNotificationMail(r *http.Request, user *models.User, downloadURL string) error {
+	data := map[string]any{
+		"Email":       user.GetEmail(),
+		"DownloadURL": downloadURL,
+		"ExpiresIn":   m.cfg.UserExport.Expiry.String(),
+		"Data":        user.UserMetaData,
+	}
+	return m.mail(r.Context(), m.cfg, DataExportNotificationTemplate, user.GetEmail(), m.locales(r, user), data)
+}
+
+func (m *Mailer) NewSignInNotificationMail(r *http.Request, user *models.User, signIn *mailer.SignIn) error {
+	data := map[string]any{
+		"Email":     user.GetEmail(),
+		"IP":        signIn.IP,
+		"UserAgent": signIn.UserAgent,
+		"Country":   signIn.Country,
+		"City":      signIn.City,
+		"RevokeURL": signIn.RevokeURL,
+		"Data":      user.UserMetaData,
+	}
+	return m.mail(r.Context(), m.cfg, NewSignInNotificationTemplate, user.GetEmail(), m.locales(r, user), data)
 }
 
 type emailParams struct {
@@ -1,10 +1,14 @@
 package templatemailer
 
 import (
+	"context"
+	"net/http/httptest"
+	"net/url"
 	"testing"
 
 	"github.com/stretchr/testify/require"
 	"github.com/supabase/auth/internal/conf"
+	"github.com/supabase/auth/internal/models"
 )
 
 func TestTemplateHeaders(t *testing.T) {
@@ -62,3 +66,48 @@ func TestTemplateHeaders(t *testing.T) {
 		require.Equal(t, hdrs, tc.exp)
 	}
 }
+
+type recordingClient struct {
+	body string
+}
+
+func (c *recordingClient) Mail(
+	ctx context.Context,
+	to string,
+	subject string,
+	body string,
+	headers map[string][]string,
+	typ string,
+) error {
+	c.body = body
+	return nil
+}
+
+func TestMagicLinkMailOTPOnly(t *testing.T) {
+	cfg := &conf.GlobalConfiguration{
+		SiteURL: "https://example.com",
+	}
+	cfg.Mailer.URLPaths.Recovery = "/verify"
+
+	externalURL, err := url.Parse("https://auth.example.com")
+	require.NoError(t, err)
+
+	user := &models.User{
+		Email:         "test@example.com",
+		RecoveryToken: "token-hash",
+	}
+	req := httptest.NewRequest("POST", "/otp", nil)
+
+	mc := &recordingClient{}
+	m := New(cfg, mc, NewCache())
+
+	require.NoError(t, m.MagicLinkMail(req, user, "123456", "", externalURL))
+	require.Contains(t, mc.body, `href="https://auth.example.com/verify?token=token-hash&amp;type=magiclink`)
+	require.Contains(t, mc.body, "Alternatively, enter the code: 123456")
+
+	cfg.Mailer.OTPOnly = true
+
+	require.NoError(t, m.MagicLinkMail(req, user, "123456", "", externalURL))
+	require.NotContains(t, mc.body, "href=")
+	require.Contains(t, mc.body, "Enter the code to login: 123456")
+}
@@ -0,0 +1,173 @@
+package templatemailer
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"slices"
+	"strings"
+	"text/template/parse"
+)
+
+var (
+	// linkVariables are available to the templates of mail with a link.
+	linkVariables = []string{
+		"SiteURL", "ConfirmationURL", "Email", "Token", "TokenHash", "Data", "RedirectTo",
+	}
+
+	// templateVariables maps each template type to the variables the mailer
+	// executes its templates with.
+	templateVariables = map[string][]string{
+		InviteTemplate:           linkVariables,
+		ConfirmationTemplate:     linkVariables,
+		RecoveryTemplate:         linkVariables,
+		MagicLinkTemplate:        linkVariables,
+		EmailChangeTemplate:      append(slices.Clone(linkVariables), "NewEmail", "SendingTo"),
+		ReauthenticationTemplate: {"SiteURL", "Email", "Token", "Data"},
+
+		// Account Changes Notifications
+		PasswordChangedNotificationTemplate:     {"Email", "Data"},
+		EmailChangedNotificationTemplate:        {"Email", "OldEmail", "Data"},
+		PhoneChangedNotificationTemplate:        {"Email", "Phone", "OldPhone", "Data"},
+		IdentityLinkedNotificationTemplate:      {"Email", "Provider", "Data"},
+		IdentityUnlinkedNotificationTemplate:    {"Email", "Provider", "Data"},
+		MFAFactorEnrolledNotificationTemplate:   {"Email", "FactorType", "Data"},
+		MFAFactorUnenrolledNotificationTemplate: {"Email", "FactorType", "Data"},
+
+		DataExportNotificationTemplate: {"Email", "DownloadURL", "ExpiresIn", "Data"},
+		NewSignInNotificationTemplate:  {"Email", "IP", "UserAgent", "Country", "City", "RevokeURL", "Data"},
+	}
+
+	// requiredVariables maps template types to the variables the body must
+	// use at least one of, so that the recipient can complete the flow.
+	requiredVariables = map[string][]string{
+		InviteTemplate:                 {"ConfirmationURL", "Token", "TokenHash"},
+		ConfirmationTemplate:           {"ConfirmationURL", "Token", "TokenHash"},
+		RecoveryTemplate:               {"ConfirmationURL", "Token", "TokenHash"},
+		MagicLinkTemplate:              {"ConfirmationURL", "Token", "TokenHash"},
+		EmailChangeTemplate:            {"ConfirmationURL", "Token", "TokenHash"},
+		ReauthenticationTemplate:       {"Token"},
+		DataExportNotificationTemplate: {"DownloadURL"},
+		NewSignInNotificationTemplate:  {"RevokeURL"},
+	}
+)
+
+// IsTemplateType reports whether typ is the type of a mail template.
+func IsTemplateType(typ string) bool {
+	return slices.Contains(templateTypes, typ)
+}
+
+// ValidateTemplate checks that the subject and body of a template of typ
+// parse, only use the variables available to typ and can be executed.
+func ValidateTemplate(typ, subject, body string) error {
+	vars, ok := templateVariables[typ]
+	if !ok {
+		return fmt.Errorf("unknown template type %q", typ)
+	}
+
+	data := map[string]any{}
+	for _, name := range vars {
+		data[name] = name
+	}
+	data["Data"] = map[string]any{}
+
+	check := func(name, text string) (map[string]bool, error) {
+		temp, err := template.New(name).Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("%s does not parse: %w", name, err)
+		}
+
+		used := make(map[string]bool)
+		for _, t := range temp.Templates() {
+			if t.Tree != nil {
+				templateFields(t.Tree.Root, true, used)
+			}
+		}
+
+		for field := range used {
+			if !slices.Contains(vars, field) {
+				return nil, fmt.Errorf(
+					"%s uses unknown variable .%s, the variables available to %s templates are .%s",
+					name, field, typ, strings.Join(vars, ", ."))
+			}
+		}
+
+		if err := temp.Execute(io.Discard, data); err != nil {
+			return nil, fmt.Errorf("%s does not execute: %w", name, err)
+		}
+		return used, nil
+	}
+
+	if strings.TrimSpace(subject) == "" {
+		return fmt.Errorf("subject must not be empty")
+	}
+	if _, err := check("subject", subject); err != nil {
+		return err
+	}
+
+	used, err := check("body", body)
+	if err != nil {
+		return err
+	}
+
+	if required := requiredVariables[typ]; len(required) > 0 {
+		if !slices.ContainsFunc(required, func(name string) bool { return used[name] }) {
+			return fmt.Errorf(
+				"body must use at least one of .%s", strings.Join(required, ", ."))
+		}
+	}
+	return nil
+}
+
+// templateFields records in used the top-level fields of the template data
+// that node refers to. Fields are only recorded where dot is the template
+// data, that is outside of the body of range and with actions, or when they
+// are accessed through $.
+func templateFields(node parse.Node, root bool, used map[string]bool) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			templateFields(child, root, used)
+		}
+	case *parse.ActionNode:
+		templateFields(n.Pipe, root, used)
+	case *parse.IfNode:
+		templateFields(n.Pipe, root, used)
+		templateFields(n.List, root, used)
+		templateFields(n.ElseList, root, used)
+	case *parse.RangeNode:
+		templateFields(n.Pipe, root, used)
+		templateFields(n.List, false, used)
+		templateFields(n.ElseList, root, used)
+	case *parse.WithNode:
+		templateFields(n.Pipe, root, used)
+		templateFields(n.List, false, used)
+		templateFields(n.ElseList, root, used)
+	case *parse.TemplateNode:
+		templateFields(n.Pipe, root, used)
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		for _, cmd := range n.Cmds {
+			templateFields(cmd, root, used)
+		}
+	case *parse.CommandNode:
+		for _, arg := range n.Args {
+			templateFields(arg, root, used)
+		}
+	case *parse.ChainNode:
+		templateFields(n.Node, root, used)
+	case *parse.FieldNode:
+		if root {
+			used[n.Ident[0]] = true
+		}
+	case *parse.VariableNode:
+		if len(n.Ident) > 1 && n.Ident[0] == "$" {
+			used[n.Ident[1]] = true
+		}
+	}
+}
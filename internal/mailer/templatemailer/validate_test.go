@@ -0,0 +1,103 @@
+package templatemailer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateTemplate(t *testing.T) {
+	cases := []struct {
+		typ     string
+		subject string
+		body    string
+		err     string
+	}{
+		{
+			typ:     ConfirmationTemplate,
+			subject: "Confirm your email on {{ .SiteURL }}",
+			body:    `<a href="{{ .ConfirmationURL }}">Confirm</a>`,
+		},
+		{
+			typ:     EmailChangeTemplate,
+			subject: "Confirm the change to {{ .NewEmail }}",
+			body:    `{{ if eq .SendingTo .NewEmail }}{{ .Token }}{{ end }}`,
+		},
+		{
+			// fields in range and with bodies are not template data
+			typ:     MagicLinkTemplate,
+			subject: "Log in",
+			body:    `{{ with .Data }}Hi {{ .name }}, {{ $.Token }}{{ end }}{{ range $k, $v := .Data }}{{ .x }}{{ end }}`,
+		},
+		{
+			typ:     PasswordChangedNotificationTemplate,
+			subject: "Password changed",
+			body:    `<p>The password of {{ .Email }} changed.</p>`,
+		},
+		{
+			typ:     RecoveryTemplate,
+			subject: "Reset",
+			body:    `{{ with .Data }}{{ $.Password }}{{ end }}{{ .Token }}`,
+			err:     "body uses unknown variable .Password",
+		},
+		{
+			typ:     RecoveryTemplate,
+			subject: "Reset {{ .NewEmail }}",
+			body:    `{{ .Token }}`,
+			err:     "subject uses unknown variable .NewEmail",
+		},
+		{
+			typ:     ReauthenticationTemplate,
+			subject: "Reauthenticate",
+			body:    `<a href="{{ .ConfirmationURL }}">Confirm</a>`,
+			err:     "body uses unknown variable .ConfirmationURL",
+		},
+		{
+			typ:     InviteTemplate,
+			subject: "You are invited",
+			body:    `<p>Welcome {{ .Email }}</p>`,
+			err:     "body must use at least one of .ConfirmationURL, .Token, .TokenHash",
+		},
+		{
+			typ:     InviteTemplate,
+			subject: " ",
+			body:    `{{ .Token }}`,
+			err:     "subject must not be empty",
+		},
+		{
+			typ:     InviteTemplate,
+			subject: "Invite",
+			body:    `{{ if .Token }}`,
+			err:     "body does not parse",
+		},
+		{
+			typ:     InviteTemplate,
+			subject: "Invite",
+			body:    `{{ .Token.Value }}`,
+			err:     "body does not execute",
+		},
+		{
+			typ:     "welcome",
+			subject: "Welcome",
+			body:    `{{ .Token }}`,
+			err:     `unknown template type "welcome"`,
+		},
+	}
+
+	for _, tc := range cases {
+		err := ValidateTemplate(tc.typ, tc.subject, tc.body)
+		if tc.err == "" {
+			require.NoError(t, err, tc.body)
+			continue
+		}
+		require.ErrorContains(t, err, tc.err, tc.body)
+	}
+}
+
+func TestValidateDefaultTemplates(t *testing.T) {
+	for _, typ := range templateTypes {
+		subject := getEmailContentConfig(defaultTemplateSubjects, typ, "")
+		body := getEmailContentConfig(defaultTemplateBodies, typ, "")
+		require.NoError(t, ValidateTemplate(typ, subject, body), typ)
+	}
+}
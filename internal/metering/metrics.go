@@ -0,0 +1,59 @@
+package metering
+
+import (
+	"context"
+
+	"github.com/supabase/auth/internal/observability"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+var (
+	signInCounter      = observability.ObtainMetricCounter("gotrue_sign_ins", "Number of sign-in attempts by login method, provider and result")
+	otpSendCounter     = observability.ObtainMetricCounter("gotrue_otp_sends", "Number of OTPs sent by channel and type")
+	tokenIssuedCounter = observability.ObtainMetricCounter("gotrue_tokens_issued", "Number of access and refresh tokens issued by authentication method")
+)
+
+// Sign-in results of the gotrue_sign_ins counter.
+const (
+	SignInSuccess = "success"
+	SignInFailure = "failure"
+)
+
+// OTP channels of the gotrue_otp_sends counter. SMS providers report their
+// own channels, such as whatsapp.
+const (
+	OTPChannelEmail = "email"
+	OTPChannelSMS   = "sms"
+)
+
+func countSignIn(loginType LoginType, provider, result string) {
+	signInCounter.Add(context.Background(), 1, metric.WithAttributes(
+		attribute.String("login_method", string(loginType)),
+		attribute.String("provider", provider),
+		attribute.String("result", result),
+	))
+}
+
+// RecordLoginFailure counts a sign-in attempt that failed because the
+// credentials were invalid.
+func RecordLoginFailure(loginType LoginType, provider string) {
+	countSignIn(loginType, provider, SignInFailure)
+}
+
+// RecordOTPSent counts an OTP sent to the user over the channel, otpType is
+// the kind of OTP such as signup, recovery or sms.
+func RecordOTPSent(channel, otpType string) {
+	otpSendCounter.Add(context.Background(), 1, metric.WithAttributes(
+		attribute.String("channel", channel),
+		attribute.String("type", otpType),
+	))
+}
+
+// RecordTokenIssued counts an access and refresh token pair issued for the
+// authentication method, such as password or token_refresh.
+func RecordTokenIssued(authenticationMethod string) {
+	tokenIssuedCounter.Add(context.Background(), 1, metric.WithAttributes(
+		attribute.String("authentication_method", authenticationMethod),
+	))
+}
@@ -14,6 +14,7 @@ const (
 	LoginTypeSSO       LoginType = "sso"
 	LoginTypeOAuth     LoginType = "oauth"
 	LoginTypeWeb3      LoginType = "web3"
+	LoginTypeTelegram  LoginType = "telegram"
 	LoginTypeImplicit  LoginType = "implicit"
 	LoginTypeOIDC      LoginType = "oidc"
 	LoginTypeOTP       LoginType = "otp"
@@ -30,9 +31,10 @@ const (
 	ProviderPhone = "phone"
 
 	// MFA providers
-	ProviderMFATOTP     = "totp"
-	ProviderMFAPhone    = "phone"
-	ProviderMFAWebAuthn = "webauthn"
+	ProviderMFATOTP         = "totp"
+	ProviderMFAPhone        = "phone"
+	ProviderMFAWebAuthn     = "webauthn"
+	ProviderMFARecoveryCode = "recovery_code"
 
 	// SSO providers
 	ProviderSAML = "saml"
@@ -106,5 +108,11 @@ func RecordLogin(loginType LoginType, userID uuid.UUID, data *LoginData) {
 		}
 	}
 
+	provider := ""
+	if data != nil {
+		provider = data.Provider
+	}
+	countSignIn(loginType, provider, SignInSuccess)
+
 	logger.WithFields(fields).Info("Login")
 }
@@ -2,15 +2,19 @@ package models
 
 import (
 	"bytes"
+	"database/sql"
 	"fmt"
 	"net/http"
+	"strings"
 	"time"
 
 	"maps"
 
+	"github.com/gobuffalo/pop/v6"
 	"github.com/gofrs/uuid"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
+	"github.com/supabase/auth/internal/auditsink"
 	"github.com/supabase/auth/internal/conf"
 	"github.com/supabase/auth/internal/observability"
 	"github.com/supabase/auth/internal/storage"
@@ -23,8 +27,15 @@ type auditLogType string
 const (
 	LoginAction                     AuditAction = "login"
 	LogoutAction                    AuditAction = "logout"
+	SessionRevokedAction            AuditAction = "session_revoked"
+	UserLockedAction                AuditAction = "user_locked"
+	UserUnlockedAction              AuditAction = "user_unlocked"
+	UserBannedAction                AuditAction = "user_banned"
+	UserUnbannedAction              AuditAction = "user_unbanned"
+	UserPasswordResetRequiredAction AuditAction = "user_password_reset_required"
 	InviteAcceptedAction            AuditAction = "invite_accepted"
 	UserSignedUpAction              AuditAction = "user_signedup"
+	UserSignupDeniedAction          AuditAction = "user_signup_denied"
 	UserInvitedAction               AuditAction = "user_invited"
 	UserDeletedAction               AuditAction = "user_deleted"
 	UserModifiedAction              AuditAction = "user_modified"
@@ -33,8 +44,10 @@ const (
 	UserConfirmationRequestedAction AuditAction = "user_confirmation_requested"
 	UserRepeatedSignUpAction        AuditAction = "user_repeated_signup"
 	UserUpdatePasswordAction        AuditAction = "user_updated_password"
+	PasswordPwnedAction             AuditAction = "password_pwned"
 	TokenRevokedAction              AuditAction = "token_revoked"
 	TokenRefreshedAction            AuditAction = "token_refreshed"
+	TokenReuseDetectedAction        AuditAction = "token_reuse_detected"
 	GenerateRecoveryCodesAction     AuditAction = "generate_recovery_codes"
 	EnrollFactorAction              AuditAction = "factor_in_progress"
 	UnenrollFactorAction            AuditAction = "factor_unenrolled"
@@ -43,8 +56,27 @@ const (
 	DeleteFactorAction              AuditAction = "factor_deleted"
 	DeleteRecoveryCodesAction       AuditAction = "recovery_codes_deleted"
 	UpdateFactorAction              AuditAction = "factor_updated"
+	UnverifyFactorAction            AuditAction = "factor_unverified"
 	MFACodeLoginAction              AuditAction = "mfa_code_login"
+	TrustedDeviceAddedAction        AuditAction = "trusted_device_added"
+	TrustedDeviceRevokedAction      AuditAction = "trusted_device_revoked"
 	IdentityUnlinkAction            AuditAction = "identity_unlinked"
+	OrganizationCreatedAction       AuditAction = "organization_created"
+	OrganizationUpdatedAction       AuditAction = "organization_updated"
+	OrganizationDeletedAction       AuditAction = "organization_deleted"
+	OrganizationMemberAddedAction   AuditAction = "organization_member_added"
+	OrganizationMemberUpdatedAction AuditAction = "organization_member_updated"
+	OrganizationMemberRemovedAction AuditAction = "organization_member_removed"
+	RoleCreatedAction               AuditAction = "role_created"
+	RoleUpdatedAction               AuditAction = "role_updated"
+	RoleDeletedAction               AuditAction = "role_deleted"
+	UserRoleAssignedAction          AuditAction = "user_role_assigned"
+	UserRoleRemovedAction           AuditAction = "user_role_removed"
+	UserImpersonatedAction          AuditAction = "user_impersonated"
+	UserPurgedAction                AuditAction = "user_purged"
+	UserDeletionCancelledAction     AuditAction = "user_deletion_cancelled"
+	UserDataExportRequestedAction   AuditAction = "user_data_export_requested"
+	IPAccessDeniedAction            AuditAction = "ip_access_denied"
 
 	account       auditLogType = "account"
 	team          auditLogType = "team"
@@ -52,22 +84,38 @@ const (
 	user          auditLogType = "user"
 	factor        auditLogType = "factor"
 	recoveryCodes auditLogType = "recovery_codes"
+	organization  auditLogType = "organization"
+	role          auditLogType = "role"
+	access        auditLogType = "access"
 )
 
 var ActionLogTypeMap = map[AuditAction]auditLogType{
 	LoginAction:                     account,
 	LogoutAction:                    account,
+	SessionRevokedAction:            account,
+	UserLockedAction:                account,
+	UserUnlockedAction:              team,
+	UserBannedAction:                team,
+	UserUnbannedAction:              team,
+	UserPasswordResetRequiredAction: team,
 	InviteAcceptedAction:            account,
 	UserSignedUpAction:              team,
+	UserSignupDeniedAction:          team,
 	UserInvitedAction:               team,
+	UserImpersonatedAction:          team,
+	UserPurgedAction:                team,
+	UserDeletionCancelledAction:     team,
 	UserDeletedAction:               team,
 	TokenRevokedAction:              token,
 	TokenRefreshedAction:            token,
+	TokenReuseDetectedAction:        token,
 	UserModifiedAction:              user,
 	UserRecoveryRequestedAction:     user,
 	UserConfirmationRequestedAction: user,
 	UserRepeatedSignUpAction:        user,
 	UserUpdatePasswordAction:        user,
+	PasswordPwnedAction:             user,
+	UserDataExportRequestedAction:   user,
 	GenerateRecoveryCodesAction:     user,
 	EnrollFactorAction:              factor,
 	UnenrollFactorAction:            factor,
@@ -75,8 +123,23 @@ var ActionLogTypeMap = map[AuditAction]auditLogType{
 	VerifyFactorAction:              factor,
 	DeleteFactorAction:              factor,
 	UpdateFactorAction:              factor,
+	UnverifyFactorAction:            factor,
 	MFACodeLoginAction:              factor,
+	TrustedDeviceAddedAction:        factor,
+	TrustedDeviceRevokedAction:      factor,
 	DeleteRecoveryCodesAction:       recoveryCodes,
+	OrganizationCreatedAction:       organization,
+	OrganizationUpdatedAction:       organization,
+	OrganizationDeletedAction:       organization,
+	OrganizationMemberAddedAction:   organization,
+	OrganizationMemberUpdatedAction: organization,
+	OrganizationMemberRemovedAction: organization,
+	RoleCreatedAction:               role,
+	RoleUpdatedAction:               role,
+	RoleDeletedAction:               role,
+	UserRoleAssignedAction:          role,
+	UserRoleRemovedAction:           role,
+	IPAccessDeniedAction:            access,
 }
 
 // AuditLogEntry is the database model for audit log entries.
@@ -119,6 +182,12 @@ func NewAuditLogEntry(config conf.AuditLogConfiguration, r *http.Request, tx *st
 		payload["traits"] = traits
 	}
 
+	// The ID of the request is stored with the entry to correlate it with
+	// the logs, webhooks and error responses of the request.
+	if requestID := utilities.GetRequestID(r.Context()); requestID != "" {
+		payload["request_id"] = requestID
+	}
+
 	observability.LogEntrySetFields(r, logrus.Fields{
 		"auth_event": logrus.Fields(payload),
 	})
@@ -138,15 +207,14 @@ func NewAuditLogEntry(config conf.AuditLogConfiguration, r *http.Request, tx *st
 	// as previously missing events (like token_refreshed) will now appear in logs.
 	// Eventually, we should remove the observability.LogEntrySetFields() call above
 	// once new logging is proven stable.
+	createdAt := time.Now().UTC()
+
 	auditLogPayload := make(map[string]interface{})
 	maps.Copy(auditLogPayload, payload)
 	auditLogPayload["audit_log_id"] = id
 	auditLogPayload["ip_address"] = ipAddress
-	auditLogPayload["created_at"] = time.Now().UTC()
+	auditLogPayload["created_at"] = createdAt
 
-	if requestID := utilities.GetRequestID(r.Context()); requestID != "" {
-		auditLogPayload["request_id"] = requestID
-	}
 	if userAgent := r.Header.Get("User-Agent"); userAgent != "" {
 		auditLogPayload["user_agent"] = userAgent
 	}
@@ -154,6 +222,15 @@ func NewAuditLogEntry(config conf.AuditLogConfiguration, r *http.Request, tx *st
 		"auth_audit_event": auditLogPayload,
 	}).Info("audit_event")
 
+	// As the log above, the entry is shipped to the sinks even when the
+	// transaction it is recorded in is rolled back.
+	auditsink.Emit(&auditsink.Event{
+		ID:        id,
+		CreatedAt: createdAt,
+		IPAddress: ipAddress,
+		Payload:   payload,
+	})
+
 	if config.DisablePostgres {
 		return nil
 	}
@@ -171,8 +248,26 @@ func NewAuditLogEntry(config conf.AuditLogConfiguration, r *http.Request, tx *st
 	return nil
 }
 
-func FindAuditLogEntries(tx *storage.Connection, filterColumns []string, filterValue string, pageParams *Pagination) ([]*AuditLogEntry, error) {
-	q := tx.Q().Order("created_at desc").Where("instance_id = ?", uuid.Nil)
+// AuditLogFilter selects the audit log entries by exact values of their
+// payload and by the time they were created.
+type AuditLogFilter struct {
+	ActorID      *uuid.UUID
+	Action       string
+	TargetUserID *uuid.UUID
+	IPAddress    string
+	From         *time.Time
+	To           *time.Time
+}
+
+// AuditLogCursor is the position of an entry in the order of the audit log,
+// newest first.
+type AuditLogCursor struct {
+	CreatedAt time.Time
+	ID        uuid.UUID
+}
+
+func auditLogQuery(tx *storage.Connection, filterColumns []string, filterValue string, filter *AuditLogFilter) *pop.Query {
+	q := tx.Q().Where("instance_id = ?", uuid.Nil)
 
 	if len(filterColumns) > 0 && filterValue != "" {
 		lf := "%" + filterValue + "%"
@@ -193,6 +288,33 @@ func FindAuditLogEntries(tx *storage.Connection, filterColumns []string, filterV
 		q = q.Where(builder.String(), values...)
 	}
 
+	if filter != nil {
+		if filter.ActorID != nil {
+			q = q.Where("payload->>'actor_id' = ?", filter.ActorID.String())
+		}
+		if filter.Action != "" {
+			q = q.Where("payload->>'action' = ?", filter.Action)
+		}
+		if filter.TargetUserID != nil {
+			q = q.Where("payload->'traits'->>'user_id' = ?", filter.TargetUserID.String())
+		}
+		if filter.IPAddress != "" {
+			q = q.Where("ip_address = ?", filter.IPAddress)
+		}
+		if filter.From != nil {
+			q = q.Where("created_at >= ?", *filter.From)
+		}
+		if filter.To != nil {
+			q = q.Where("created_at < ?", *filter.To)
+		}
+	}
+
+	return q
+}
+
+func FindAuditLogEntries(tx *storage.Connection, filterColumns []string, filterValue string, filter *AuditLogFilter, pageParams *Pagination) ([]*AuditLogEntry, error) {
+	q := auditLogQuery(tx, filterColumns, filterValue, filter).Order("created_at desc")
+
 	logs := []*AuditLogEntry{}
 	var err error
 	if pageParams != nil {
@@ -204,3 +326,76 @@ func FindAuditLogEntries(tx *storage.Connection, filterColumns []string, filterV
 
 	return logs, err
 }
+
+// FindAuditLogEntriesAfter returns up to limit entries that match the
+// filter, newest first. Only entries older than the cursor are returned, so
+// pages are found with an index scan instead of an offset.
+func FindAuditLogEntriesAfter(tx *storage.Connection, filterColumns []string, filterValue string, filter *AuditLogFilter, after *AuditLogCursor, limit int) ([]*AuditLogEntry, error) {
+	q := auditLogQuery(tx, filterColumns, filterValue, filter)
+
+	if after != nil {
+		q = q.Where("(created_at, id) < (?, ?)", after.CreatedAt, after.ID)
+	}
+
+	logs := []*AuditLogEntry{}
+	if err := q.Order("created_at desc, id desc").Limit(limit).All(&logs); err != nil {
+		return nil, errors.Wrap(err, "error finding audit log entries")
+	}
+
+	return logs, nil
+}
+
+// FindAuditLogEntryByID finds an audit log entry by its ID.
+func FindAuditLogEntryByID(tx *storage.Connection, id uuid.UUID) (*AuditLogEntry, error) {
+	var l AuditLogEntry
+	if err := tx.Q().Where("instance_id = ? and id = ?", uuid.Nil, id).First(&l); err != nil {
+		if errors.Cause(err) == sql.ErrNoRows {
+			return nil, AuditLogEntryNotFoundError{}
+		}
+		return nil, errors.Wrap(err, "error finding audit log entry")
+	}
+	return &l, nil
+}
+
+// FindUserAuditLogEntries returns the entries of the actions the user took
+// or that were taken on the user, oldest first.
+func FindUserAuditLogEntries(tx *storage.Connection, userID uuid.UUID) ([]*AuditLogEntry, error) {
+	logs := []*AuditLogEntry{}
+	if err := tx.Q().Where("instance_id = ? and (payload->>'actor_id' = ? or payload->'traits'->>'user_id' = ?)", uuid.Nil, userID.String(), userID.String()).Order("created_at asc, id asc").All(&logs); err != nil {
+		return nil, errors.Wrap(err, "error finding user audit log entries")
+	}
+	return logs, nil
+}
+
+// FindExpiredAuditLogEntries returns up to limit of the oldest entries
+// created before the time. The entries are locked for update, skipping the
+// entries another transaction has locked, so that they are pruned once when
+// several instances prune the audit log at the same time. It must be called
+// in a transaction.
+func FindExpiredAuditLogEntries(tx *storage.Connection, before time.Time, limit int) ([]*AuditLogEntry, error) {
+	logs := []*AuditLogEntry{}
+	query := fmt.Sprintf("select * from %q where created_at < ? order by created_at asc, id asc limit ? for update skip locked", AuditLogEntry{}.TableName())
+	if err := tx.RawQuery(query, before, limit).All(&logs); err != nil {
+		return nil, errors.Wrap(err, "error finding expired audit log entries")
+	}
+	return logs, nil
+}
+
+// DeleteAuditLogEntries deletes the entries. It must be called in the
+// transaction the entries were found in.
+func DeleteAuditLogEntries(tx *storage.Connection, logs []*AuditLogEntry) error {
+	if len(logs) == 0 {
+		return nil
+	}
+
+	ids := make([]interface{}, len(logs))
+	for i, l := range logs {
+		ids[i] = l.ID
+	}
+
+	query := fmt.Sprintf("delete from %q where id in (?%s)", AuditLogEntry{}.TableName(), strings.Repeat(", ?", len(ids)-1))
+	if err := tx.RawQuery(query, ids...).Exec(); err != nil {
+		return errors.Wrap(err, "error deleting audit log entries")
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package models
+
+import (
+	"context"
+	"strings"
+
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gofrs/uuid"
+	"github.com/supabase/auth/internal/cache"
+	"github.com/supabase/auth/internal/storage"
+)
+
+const (
+	userCacheNamespace    = "user"
+	sessionCacheNamespace = "session"
+)
+
+// FindUserByIDCached finds a user matching the provided ID, reading it from
+// the cache when caching is enabled. Users found in a transaction are always
+// read from the database, as the transaction may update them.
+func FindUserByIDCached(tx *storage.Connection, id uuid.UUID) (*User, error) {
+	if !cache.Enabled() || tx.TX != nil {
+		return FindUserByID(tx, id)
+	}
+
+	ctx := tx.Context()
+
+	user := &User{}
+	if cache.Get(ctx, userCacheNamespace, id.String(), user) {
+		// gob does not distinguish empty maps and slices from nil ones
+		if user.AppMetaData == nil {
+			user.AppMetaData = JSONMap{}
+		}
+		if user.UserMetaData == nil {
+			user.UserMetaData = JSONMap{}
+		}
+		if user.Factors == nil {
+			user.Factors = []Factor{}
+		}
+		if user.Identities == nil {
+			user.Identities = []Identity{}
+		}
+		return user, nil
+	}
+
+	user, err := FindUserByID(tx, id)
+	if err != nil {
+		return nil, err
+	}
+	cache.Set(ctx, userCacheNamespace, id.String(), user)
+	return user, nil
+}
+
+// FindSessionByIDCached finds a session matching the provided ID, reading it
+// from the cache when caching is enabled. Sessions found in a transaction are
+// always read from the database, as the transaction may update them.
+func FindSessionByIDCached(tx *storage.Connection, id uuid.UUID) (*Session, error) {
+	if !cache.Enabled() || tx.TX != nil {
+		return FindSessionByID(tx, id, false)
+	}
+
+	ctx := tx.Context()
+
+	session := &Session{}
+	if cache.Get(ctx, sessionCacheNamespace, id.String(), session) {
+		if session.AMRClaims == nil {
+			session.AMRClaims = []AMRClaim{}
+		}
+		return session, nil
+	}
+
+	session, err := FindSessionByID(tx, id, false)
+	if err != nil {
+		return nil, err
+	}
+	cache.Set(ctx, sessionCacheNamespace, id.String(), session)
+	return session, nil
+}
+
+// invalidateCache removes the cached rows of the ids in the namespace once
+// the transaction of conn is committed. Removing them earlier would let
+// concurrent requests cache the rows as they were before the transaction.
+func invalidateCache(conn *pop.Connection, namespace string, ids ...uuid.UUID) {
+	if !cache.Enabled() || len(ids) == 0 {
+		return
+	}
+
+	keys := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if id != uuid.Nil {
+			keys = append(keys, id.String())
+		}
+	}
+
+	storage.AfterCommit(conn, func() {
+		cache.Delete(context.Background(), namespace, keys...)
+	})
+}
+
+// invalidateUserCache removes the cached users, which include their
+// factors and identities.
+func invalidateUserCache(conn *pop.Connection, userIDs ...uuid.UUID) {
+	invalidateCache(conn, userCacheNamespace, userIDs...)
+}
+
+// invalidateSessionCache removes the cached sessions, which include their
+// AMR claims.
+func invalidateSessionCache(conn *pop.Connection, sessionIDs ...uuid.UUID) {
+	invalidateCache(conn, sessionCacheNamespace, sessionIDs...)
+}
+
+// deletedRow holds the column returned by a delete invalidating the cache.
+type deletedRow struct {
+	ID uuid.UUID `db:"id"`
+}
+
+// execInvalidatingCache runs a delete query and invalidates the cached rows
+// of the namespace whose ids are in the column of the deleted rows. The
+// query must not have a returning clause. It returns the number of deleted
+// rows.
+func execInvalidatingCache(tx *storage.Connection, namespace, column, query string, args ...interface{}) (int, error) {
+	if !cache.Enabled() {
+		return tx.RawQuery(query, args...).ExecWithCount()
+	}
+
+	var rows []deletedRow
+	query = strings.TrimSuffix(strings.TrimSpace(query), ";") + " returning " + column + " as id"
+	if err := tx.RawQuery(query, args...).All(&rows); err != nil {
+		return 0, err
+	}
+
+	ids := make([]uuid.UUID, 0, len(rows))
+	for _, row := range rows {
+		ids = append(ids, row.ID)
+	}
+	invalidateCache(tx.Connection, namespace, ids...)
+	return len(rows), nil
+}
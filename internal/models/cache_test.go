@@ -0,0 +1,177 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"github.com/supabase/auth/internal/cache"
+	"github.com/supabase/auth/internal/conf"
+	"github.com/supabase/auth/internal/storage"
+	"github.com/supabase/auth/internal/storage/test"
+)
+
+type CacheTestSuite struct {
+	suite.Suite
+	db *storage.Connection
+}
+
+func (ts *CacheTestSuite) SetupTest() {
+	TruncateAll(ts.db)
+
+	require.NoError(ts.T(), cache.Configure(&conf.CacheConfiguration{
+		Enabled:    true,
+		Type:       conf.CacheMemory,
+		MaxEntries: 100,
+		TTL:        time.Minute,
+	}))
+}
+
+func (ts *CacheTestSuite) TearDownTest() {
+	require.NoError(ts.T(), cache.Configure(&conf.CacheConfiguration{}))
+}
+
+func TestCache(t *testing.T) {
+	globalConfig, err := conf.LoadGlobal(modelsTestConfig)
+	require.NoError(t, err)
+
+	conn, err := test.SetupDBConnection(globalConfig)
+	require.NoError(t, err)
+
+	ts := &CacheTestSuite{
+		db: conn,
+	}
+	defer ts.db.Close()
+
+	suite.Run(t, ts)
+}
+
+func (ts *CacheTestSuite) TestFindUserByIDCached() {
+	u, err := NewUser("", "cached@example.com", "secret", "authenticated", nil)
+	require.NoError(ts.T(), err)
+	require.NoError(ts.T(), ts.db.Create(u))
+
+	cached, err := FindUserByIDCached(ts.db, u.ID)
+	require.NoError(ts.T(), err)
+	require.Equal(ts.T(), u.Email, cached.Email)
+
+	// the fields which are not serialized to JSON are cached
+	cached, err = FindUserByIDCached(ts.db, u.ID)
+	require.NoError(ts.T(), err)
+	require.Equal(ts.T(), u.EncryptedPassword, cached.EncryptedPassword)
+	require.NotNil(ts.T(), cached.Identities)
+	require.NotNil(ts.T(), cached.UserMetaData)
+
+	// rows changed in a transaction are invalidated once it is committed
+	require.NoError(ts.T(), ts.db.Transaction(func(tx *storage.Connection) error {
+		if err := u.UpdateUserMetaData(tx, map[string]interface{}{"name": "Cached"}); err != nil {
+			return err
+		}
+
+		cached, err := FindUserByIDCached(ts.db, u.ID)
+		require.NoError(ts.T(), err)
+		require.Empty(ts.T(), cached.UserMetaData)
+		return nil
+	}))
+
+	cached, err = FindUserByIDCached(ts.db, u.ID)
+	require.NoError(ts.T(), err)
+	require.Equal(ts.T(), "Cached", cached.UserMetaData["name"])
+
+	identity, err := NewIdentity(u, "email", map[string]interface{}{"sub": u.ID.String()})
+	require.NoError(ts.T(), err)
+	require.NoError(ts.T(), ts.db.Create(identity))
+
+	cached, err = FindUserByIDCached(ts.db, u.ID)
+	require.NoError(ts.T(), err)
+	require.Len(ts.T(), cached.Identities, 1)
+
+	require.NoError(ts.T(), ts.db.Destroy(u))
+	_, err = FindUserByIDCached(ts.db, u.ID)
+	require.True(ts.T(), IsNotFoundError(err))
+}
+
+func (ts *CacheTestSuite) TestFindSessionByIDCached() {
+	u, err := NewUser("", "cached@example.com", "secret", "authenticated", nil)
+	require.NoError(ts.T(), err)
+	require.NoError(ts.T(), ts.db.Create(u))
+
+	var sessions []*Session
+	for i := 0; i < 3; i++ {
+		s, err := NewSession(u.ID, nil)
+		require.NoError(ts.T(), err)
+		require.NoError(ts.T(), ts.db.Create(s))
+		sessions = append(sessions, s)
+
+		_, err = FindSessionByIDCached(ts.db, s.ID)
+		require.NoError(ts.T(), err)
+	}
+
+	require.NoError(ts.T(), AddClaimToSession(ts.db, sessions[0].ID, PasswordGrant))
+	cached, err := FindSessionByIDCached(ts.db, sessions[0].ID)
+	require.NoError(ts.T(), err)
+	require.Len(ts.T(), cached.AMRClaims, 1)
+
+	require.NoError(ts.T(), LogoutSession(ts.db, sessions[0].ID))
+	_, err = FindSessionByIDCached(ts.db, sessions[0].ID)
+	require.True(ts.T(), IsNotFoundError(err))
+
+	require.NoError(ts.T(), Logout(ts.db, u.ID))
+	for _, s := range sessions[1:] {
+		_, err = FindSessionByIDCached(ts.db, s.ID)
+		require.True(ts.T(), IsNotFoundError(err))
+	}
+}
+
+func (ts *CacheTestSuite) TestDeletesInvalidateCache() {
+	now := time.Now()
+	before := now.Add(time.Hour)
+
+	u, err := NewUser("", "cached@example.com", "secret", "authenticated", nil)
+	require.NoError(ts.T(), err)
+	require.NoError(ts.T(), ts.db.Create(u))
+
+	token, err := GrantAuthenticatedUser(ts.db, u, GrantParams{SessionNotAfter: &now})
+	require.NoError(ts.T(), err)
+
+	_, err = FindSessionByIDCached(ts.db, *token.SessionId)
+	require.NoError(ts.T(), err)
+	_, err = FindUserByIDCached(ts.db, u.ID)
+	require.NoError(ts.T(), err)
+
+	n, err := DeleteSessionsEndedBefore(ts.db, &conf.SessionsConfiguration{}, before, 100)
+	require.NoError(ts.T(), err)
+	require.Equal(ts.T(), 1, n)
+	_, err = FindSessionByIDCached(ts.db, *token.SessionId)
+	require.True(ts.T(), IsNotFoundError(err))
+
+	n, err = DeleteUnconfirmedUsers(ts.db, before, 100)
+	require.NoError(ts.T(), err)
+	require.Equal(ts.T(), 1, n)
+	_, err = FindUserByIDCached(ts.db, u.ID)
+	require.True(ts.T(), IsNotFoundError(err))
+
+	// the anonymous users deleted by the cleanup are invalidated too
+	anonymous, err := NewUser("", "", "", "authenticated", nil)
+	require.NoError(ts.T(), err)
+	anonymous.IsAnonymous = true
+	anonymous.CreatedAt = now.Add(-31 * 24 * time.Hour)
+	require.NoError(ts.T(), ts.db.Create(anonymous))
+	require.NoError(ts.T(), ts.db.RawQuery("update auth.users set created_at = ? where id = ?", anonymous.CreatedAt, anonymous.ID).Exec())
+
+	_, err = FindUserByIDCached(ts.db, anonymous.ID)
+	require.NoError(ts.T(), err)
+
+	cleanup := &Cleanup{}
+	cleanup.cleanupStatements = append(cleanup.cleanupStatements, cleanupStatement{
+		query:     "delete from auth.users where id in (select id from auth.users where created_at < now() - interval '30 days' and is_anonymous is true limit 100 for update skip locked);",
+		namespace: userCacheNamespace,
+		column:    "id",
+	})
+	n, err = cleanup.Clean(ts.db)
+	require.NoError(ts.T(), err)
+	require.Equal(ts.T(), 1, n)
+	_, err = FindUserByIDCached(ts.db, anonymous.ID)
+	require.True(ts.T(), IsNotFoundError(err))
+}
@@ -87,6 +87,16 @@ func (c *Challenge) Verify(tx *storage.Connection) error {
 	return tx.UpdateOnly(c, "verified_at")
 }
 
+// CountChallengesSince returns the number of challenges of the factor
+// created since the given time.
+func CountChallengesSince(tx *storage.Connection, factorID uuid.UUID, since time.Time) (int, error) {
+	count, err := tx.Q().Where("factor_id = ? and created_at >= ?", factorID, since).Count(&Challenge{})
+	if err != nil {
+		return 0, fmt.Errorf("error counting challenges: %w", err)
+	}
+	return count, nil
+}
+
 func (c *Challenge) HasExpired(expiryDuration float64) bool {
 	return time.Now().After(c.GetExpiryTime(expiryDuration))
 }
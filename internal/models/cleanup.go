@@ -20,8 +20,17 @@ type Cleaner interface {
 	Clean(*storage.Connection) (int, error)
 }
 
+// cleanupStatement is a statement run by the cleanup. Statements deleting
+// cached rows set the namespace of the cache and the column holding the ids
+// of the rows to invalidate.
+type cleanupStatement struct {
+	query     string
+	namespace string
+	column    string
+}
+
 type Cleanup struct {
-	cleanupStatements []string
+	cleanupStatements []cleanupStatement
 
 	// cleanupNext holds an atomically incrementing value that determines which of
 	// the cleanupStatements will be run next.
@@ -41,6 +50,8 @@ func NewCleanup(config *conf.GlobalConfiguration) *Cleanup {
 	tableMFAChallenges := Challenge{}.TableName()
 	tableMFAFactors := Factor{}.TableName()
 	tableOAuthClientStates := OAuthClientState{}.TableName()
+	tableMFATrustedDevices := TrustedDevice{}.TableName()
+	tableWeb3Nonces := Web3Nonce{}.TableName()
 
 	c := &Cleanup{}
 
@@ -49,41 +60,62 @@ func NewCleanup(config *conf.GlobalConfiguration) *Cleanup {
 	// transaction are deleted. These deletes are thus very quick and
 	// efficient, as they don't wait on other transactions.
 	c.cleanupStatements = append(c.cleanupStatements,
-		fmt.Sprintf("delete from %q where id in (select id from %q where revoked is true and updated_at < now() - interval '24 hours' limit 100 for update skip locked);", tableRefreshTokens, tableRefreshTokens),
-		fmt.Sprintf("update %q set revoked = true, updated_at = now() where id in (select %q.id from %q join %q on %q.session_id = %q.id where %q.not_after < now() - interval '24 hours' and %q.revoked is false limit 100 for update skip locked);", tableRefreshTokens, tableRefreshTokens, tableRefreshTokens, tableSessions, tableRefreshTokens, tableSessions, tableSessions, tableRefreshTokens),
+		cleanupStatement{query: fmt.Sprintf("delete from %q where id in (select id from %q where revoked is true and updated_at < now() - interval '24 hours' limit 100 for update skip locked);", tableRefreshTokens, tableRefreshTokens)},
+		cleanupStatement{query: fmt.Sprintf("update %q set revoked = true, updated_at = now() where id in (select %q.id from %q join %q on %q.session_id = %q.id where %q.not_after < now() - interval '24 hours' and %q.revoked is false limit 100 for update skip locked);", tableRefreshTokens, tableRefreshTokens, tableRefreshTokens, tableSessions, tableRefreshTokens, tableSessions, tableSessions, tableRefreshTokens)},
 		// sessions are deleted after 72 hours to allow refresh tokens
 		// to be deleted piecemeal; 10 at once so that cascades don't
 		// overwork the database
-		fmt.Sprintf("delete from %q where id in (select id from %q where not_after < now() - interval '72 hours' limit 10 for update skip locked);", tableSessions, tableSessions),
-		fmt.Sprintf("delete from %q where id in (select id from %q where created_at < now() - interval '24 hours' limit 100 for update skip locked);", tableRelayStates, tableRelayStates),
-		fmt.Sprintf("delete from %q where id in (select id from %q where created_at < now() - interval '24 hours' limit 100 for update skip locked);", tableFlowStates, tableFlowStates),
-		fmt.Sprintf("delete from %q where id in (select id from %q where created_at < now() - interval '24 hours' limit 100 for update skip locked);", tableOAuthClientStates, tableOAuthClientStates),
-		fmt.Sprintf("delete from %q where id in (select id from %q where created_at < now() - interval '24 hours' limit 100 for update skip locked);", tableMFAChallenges, tableMFAChallenges),
-		fmt.Sprintf("delete from %q where id in (select id from %q where created_at < now() - interval '24 hours' and status = 'unverified' limit 100 for update skip locked);", tableMFAFactors, tableMFAFactors),
+		cleanupStatement{query: fmt.Sprintf("delete from %q where id in (select id from %q where not_after < now() - interval '72 hours' limit 10 for update skip locked);", tableSessions, tableSessions), namespace: sessionCacheNamespace, column: "id"},
+		cleanupStatement{query: fmt.Sprintf("delete from %q where id in (select id from %q where created_at < now() - interval '24 hours' limit 100 for update skip locked);", tableRelayStates, tableRelayStates)},
+		cleanupStatement{query: fmt.Sprintf("delete from %q where id in (select id from %q where created_at < now() - interval '24 hours' limit 100 for update skip locked);", tableFlowStates, tableFlowStates)},
+		cleanupStatement{query: fmt.Sprintf("delete from %q where id in (select id from %q where created_at < now() - interval '24 hours' limit 100 for update skip locked);", tableOAuthClientStates, tableOAuthClientStates)},
+		cleanupStatement{query: fmt.Sprintf("delete from %q where id in (select id from %q where created_at < now() - interval '24 hours' limit 100 for update skip locked);", tableMFAChallenges, tableMFAChallenges)},
+		cleanupStatement{query: fmt.Sprintf("delete from %q where id in (select id from %q where created_at < now() - interval '24 hours' and status = 'unverified' limit 100 for update skip locked);", tableMFAFactors, tableMFAFactors), namespace: userCacheNamespace, column: "user_id"},
+		cleanupStatement{query: fmt.Sprintf("delete from %q where id in (select id from %q where expires_at < now() limit 100 for update skip locked);", tableMFATrustedDevices, tableMFATrustedDevices)},
+		cleanupStatement{query: fmt.Sprintf("delete from %q where id in (select id from %q where expires_at < now() limit 100 for update skip locked);", tableWeb3Nonces, tableWeb3Nonces)},
 	)
 
 	if config.External.AnonymousUsers.Enabled {
 		// delete anonymous users older than 30 days
 		c.cleanupStatements = append(c.cleanupStatements,
-			fmt.Sprintf("delete from %q where id in (select id from %q where created_at < now() - interval '30 days' and is_anonymous is true limit 100 for update skip locked);", tableUsers, tableUsers),
+			cleanupStatement{query: fmt.Sprintf("delete from %q where id in (select id from %q where created_at < now() - interval '30 days' and is_anonymous is true limit 100 for update skip locked);", tableUsers, tableUsers), namespace: userCacheNamespace, column: "id"},
+		)
+	}
+
+	if config.JWT.DenylistEnabled {
+		tableRevokedAccessTokens := RevokedAccessToken{}.TableName()
+
+		// revoked access tokens are kept on the denylist until they expire
+		c.cleanupStatements = append(c.cleanupStatements,
+			cleanupStatement{query: fmt.Sprintf("delete from %q where jti in (select jti from %q where expires_at < now() limit 100 for update skip locked);", tableRevokedAccessTokens, tableRevokedAccessTokens)},
 		)
 	}
 
 	if config.Sessions.Timebox != nil {
-		timeboxSeconds := int((*config.Sessions.Timebox).Seconds())
+		// sessions of roles with a longer timebox must not be deleted
+		// before they end
+		timebox := *config.Sessions.Timebox
+		for _, roleTimebox := range config.Sessions.RoleTimebox {
+			timebox = max(timebox, roleTimebox)
+		}
+		timeboxSeconds := int(timebox.Seconds())
 
-		c.cleanupStatements = append(c.cleanupStatements, fmt.Sprintf("delete from %q where id in (select id from %q where created_at + interval '%d seconds' < now() - interval '24 hours' limit 100 for update skip locked);", tableSessions, tableSessions, timeboxSeconds))
+		c.cleanupStatements = append(c.cleanupStatements, cleanupStatement{query: fmt.Sprintf("delete from %q where id in (select id from %q where created_at + interval '%d seconds' < now() - interval '24 hours' limit 100 for update skip locked);", tableSessions, tableSessions, timeboxSeconds), namespace: sessionCacheNamespace, column: "id"})
 	}
 
 	if config.Sessions.InactivityTimeout != nil {
-		inactivitySeconds := int((*config.Sessions.InactivityTimeout).Seconds())
+		inactivityTimeout := *config.Sessions.InactivityTimeout
+		for _, roleTimeout := range config.Sessions.RoleInactivityTimeout {
+			inactivityTimeout = max(inactivityTimeout, roleTimeout)
+		}
+		inactivitySeconds := int(inactivityTimeout.Seconds())
 
 		// delete sessions with a refreshed_at column
-		c.cleanupStatements = append(c.cleanupStatements, fmt.Sprintf("delete from %q where id in (select id from %q where refreshed_at is not null and refreshed_at + interval '%d seconds' < now() - interval '24 hours' limit 100 for update skip locked);", tableSessions, tableSessions, inactivitySeconds))
+		c.cleanupStatements = append(c.cleanupStatements, cleanupStatement{query: fmt.Sprintf("delete from %q where id in (select id from %q where refreshed_at is not null and refreshed_at + interval '%d seconds' < now() - interval '24 hours' limit 100 for update skip locked);", tableSessions, tableSessions, inactivitySeconds), namespace: sessionCacheNamespace, column: "id"})
 
 		// delete sessions without a refreshed_at column by looking for
 		// unrevoked refresh_tokens
-		c.cleanupStatements = append(c.cleanupStatements, fmt.Sprintf("delete from %q where id in (select %q.id as id from %q, %q where %q.session_id = %q.id and %q.refreshed_at is null and %q.revoked is false and %q.updated_at + interval '%d seconds' < now() - interval '24 hours' limit 100 for update skip locked)", tableSessions, tableSessions, tableSessions, tableRefreshTokens, tableRefreshTokens, tableSessions, tableSessions, tableRefreshTokens, tableRefreshTokens, inactivitySeconds))
+		c.cleanupStatements = append(c.cleanupStatements, cleanupStatement{query: fmt.Sprintf("delete from %q where id in (select %q.id as id from %q, %q where %q.session_id = %q.id and %q.refreshed_at is null and %q.revoked is false and %q.updated_at + interval '%d seconds' < now() - interval '24 hours' limit 100 for update skip locked)", tableSessions, tableSessions, tableSessions, tableRefreshTokens, tableRefreshTokens, tableSessions, tableSessions, tableRefreshTokens, tableRefreshTokens, inactivitySeconds), namespace: sessionCacheNamespace, column: "id"})
 	}
 
 	meter := otel.Meter("gotrue")
@@ -121,7 +153,13 @@ func (c *Cleanup) Clean(db *storage.Connection) (int, error) {
 		nextIndex := atomic.AddUint32(&c.cleanupNext, 1) % uint32(len(c.cleanupStatements)) // #nosec G115
 		statement := c.cleanupStatements[nextIndex]
 
-		count, terr := tx.RawQuery(statement).ExecWithCount()
+		var count int
+		var terr error
+		if statement.namespace != "" {
+			count, terr = execInvalidatingCache(tx, statement.namespace, statement.column, statement.query)
+		} else {
+			count, terr = tx.RawQuery(statement.query).ExecWithCount()
+		}
 		if terr != nil {
 			return terr
 		}
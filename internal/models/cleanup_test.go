@@ -21,6 +21,7 @@ func TestCleanup(t *testing.T) {
 	globalConfig.Sessions.Timebox = &timebox
 	globalConfig.Sessions.InactivityTimeout = &inactivityTimeout
 	globalConfig.External.AnonymousUsers.Enabled = true
+	globalConfig.JWT.DenylistEnabled = true
 
 	cleanup := NewCleanup(globalConfig)
 
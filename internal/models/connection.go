@@ -50,6 +50,22 @@ func TruncateAll(conn *storage.Connection) error {
 			(&pop.Model{Value: FlowState{}}).TableName(),
 			(&pop.Model{Value: OneTimeToken{}}).TableName(),
 			(&pop.Model{Value: OAuthServerClient{}}).TableName(),
+			(&pop.Model{Value: SCIMGroup{}}).TableName(),
+			(&pop.Model{Value: Tenant{}}).TableName(),
+			(&pop.Model{Value: UserImport{}}).TableName(),
+			(&pop.Model{Value: MailJob{}}).TableName(),
+			(&pop.Model{Value: EmailTemplate{}}).TableName(),
+			(&pop.Model{Value: RevokedAccessToken{}}).TableName(),
+			(&pop.Model{Value: ProviderToken{}}).TableName(),
+			(&pop.Model{Value: RecoveryCode{}}).TableName(),
+			(&pop.Model{Value: TrustedDevice{}}).TableName(),
+			(&pop.Model{Value: Web3Nonce{}}).TableName(),
+			(&pop.Model{Value: ProviderDomainRule{}}).TableName(),
+			(&pop.Model{Value: Organization{}}).TableName(),
+			(&pop.Model{Value: Role{}}).TableName(),
+			(&pop.Model{Value: UserExport{}}).TableName(),
+			(&pop.Model{Value: WebhookDelivery{}}).TableName(),
+			(&pop.Model{Value: SignInLocation{}}).TableName(),
 		}
 
 		for _, tableName := range tables {
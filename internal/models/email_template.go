@@ -0,0 +1,220 @@
+package models
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+	"github.com/supabase/auth/internal/storage"
+)
+
+type EmailTemplateFormat string
+
+const (
+	// EmailTemplateHTML templates are HTML templates as sent.
+	EmailTemplateHTML EmailTemplateFormat = "html"
+
+	// EmailTemplateMJML templates are compiled to HTML when they are saved.
+	EmailTemplateMJML EmailTemplateFormat = "mjml"
+)
+
+// EmailTemplate is a version of an email template managed with the admin
+// API. The active version of a type is used instead of the configured
+// template. Templates with a tenant override the deployment's template for
+// that tenant. Each locale of a type has its own versions, the empty locale
+// is the default.
+type EmailTemplate struct {
+	ID       uuid.UUID           `json:"id" db:"id"`
+	TenantID *uuid.UUID          `json:"tenant_id,omitempty" db:"tenant_id"`
+	Type     string              `json:"type" db:"type"`
+	Locale   string              `json:"locale,omitempty" db:"locale"`
+	Version  int                 `json:"version" db:"version"`
+	Format   EmailTemplateFormat `json:"format" db:"format"`
+	Subject  string              `json:"subject" db:"subject"`
+	Source   string              `json:"source" db:"source"`
+	Body     string              `json:"body" db:"body"`
+	Active   bool                `json:"active" db:"active"`
+
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+func (EmailTemplate) TableName() string {
+	tableName := "email_templates"
+	return tableName
+}
+
+// NewEmailTemplate initializes a template, its version is assigned when it
+// is created with CreateEmailTemplateVersion.
+func NewEmailTemplate(tenantID *uuid.UUID, typ, locale string, format EmailTemplateFormat, subject, source, body string) *EmailTemplate {
+	return &EmailTemplate{
+		ID:       uuid.Must(uuid.NewV4()),
+		TenantID: tenantID,
+		Type:     typ,
+		Locale:   locale,
+		Format:   format,
+		Subject:  subject,
+		Source:   source,
+		Body:     body,
+	}
+}
+
+// BeforeSave is invoked before the email template is saved to the database.
+func (t *EmailTemplate) BeforeSave(tx *pop.Connection) error {
+	t.UpdatedAt = time.Now()
+	return nil
+}
+
+// emailTemplateScope returns the condition matching the templates of the
+// tenant, or of the deployment when tenantID is nil.
+func emailTemplateScope(tenantID *uuid.UUID) (string, []interface{}) {
+	if tenantID == nil {
+		return "tenant_id is null", nil
+	}
+	return "tenant_id = ?", []interface{}{*tenantID}
+}
+
+// CreateEmailTemplateVersion saves the template as the next version of its
+// type and locale and makes it the active version. It must be called in a
+// transaction.
+func CreateEmailTemplateVersion(tx *storage.Connection, t *EmailTemplate) error {
+	t.Version = 1
+
+	latest, err := FindEmailTemplateVersions(tx, t.TenantID, t.Type, t.Locale)
+	if err != nil {
+		return err
+	}
+	if len(latest) > 0 {
+		t.Version = latest[0].Version + 1
+	}
+
+	if err := deactivateEmailTemplates(tx, t.TenantID, t.Type, t.Locale); err != nil {
+		return err
+	}
+
+	t.Active = true
+
+	if err := tx.Create(t); err != nil {
+		return errors.Wrap(err, "error creating email template")
+	}
+
+	return nil
+}
+
+// Activate makes the template the active version of its type and locale. It
+// must be called in a transaction.
+func (t *EmailTemplate) Activate(tx *storage.Connection) error {
+	if err := deactivateEmailTemplates(tx, t.TenantID, t.Type, t.Locale); err != nil {
+		return err
+	}
+
+	t.Active = true
+	return tx.UpdateOnly(t, "active", "updated_at")
+}
+
+// DeactivateEmailTemplates deactivates the active version of a type and
+// locale, so that the template of the next locale in the fallback chain or
+// the configured template is used again. It reports whether a version was
+// active.
+func DeactivateEmailTemplates(tx *storage.Connection, tenantID *uuid.UUID, typ, locale string) (bool, error) {
+	scope, args := emailTemplateScope(tenantID)
+
+	query := fmt.Sprintf("update %q set active = false, updated_at = ? where %s and type = ? and locale = ? and active", EmailTemplate{}.TableName(), scope)
+	args = append([]interface{}{time.Now()}, args...)
+
+	count, err := tx.RawQuery(query, append(args, typ, locale)...).ExecWithCount()
+	if err != nil {
+		return false, errors.Wrap(err, "error deactivating email templates")
+	}
+
+	return count > 0, nil
+}
+
+func deactivateEmailTemplates(tx *storage.Connection, tenantID *uuid.UUID, typ, locale string) error {
+	_, err := DeactivateEmailTemplates(tx, tenantID, typ, locale)
+	return err
+}
+
+// FindActiveEmailTemplate finds the template used to send mail of a type in
+// the first of the locales that has one. The tenant's templates take
+// precedence over the deployment's templates in any locale.
+func FindActiveEmailTemplate(tx *storage.Connection, tenantID *uuid.UUID, typ string, locales []string) (*EmailTemplate, error) {
+	if len(locales) == 0 {
+		return nil, EmailTemplateNotFoundError{}
+	}
+
+	templates := []*EmailTemplate{}
+
+	args := []interface{}{typ}
+	for _, locale := range locales {
+		args = append(args, locale)
+	}
+	q := tx.Q().Where("type = ? and active and locale in (?"+strings.Repeat(", ?", len(locales)-1)+")", args...)
+	if tenantID != nil {
+		q = q.Where("(tenant_id = ? or tenant_id is null)", *tenantID)
+	} else {
+		q = q.Where("tenant_id is null")
+	}
+
+	if err := q.All(&templates); err != nil {
+		return nil, errors.Wrap(err, "error finding email template")
+	}
+
+	for _, tenant := range []bool{true, false} {
+		for _, locale := range locales {
+			for _, t := range templates {
+				if (t.TenantID != nil) == tenant && t.Locale == locale {
+					return t, nil
+				}
+			}
+		}
+	}
+
+	return nil, EmailTemplateNotFoundError{}
+}
+
+// FindActiveEmailTemplates finds the active versions of the tenant's
+// templates, or of the deployment's when tenantID is nil.
+func FindActiveEmailTemplates(tx *storage.Connection, tenantID *uuid.UUID) ([]*EmailTemplate, error) {
+	templates := []*EmailTemplate{}
+	scope, args := emailTemplateScope(tenantID)
+
+	if err := tx.Q().Where(scope+" and active", args...).Order("type asc, locale asc").All(&templates); err != nil {
+		return nil, errors.Wrap(err, "error finding email templates")
+	}
+
+	return templates, nil
+}
+
+// FindEmailTemplateVersions finds every version of a type and locale, latest
+// first.
+func FindEmailTemplateVersions(tx *storage.Connection, tenantID *uuid.UUID, typ, locale string) ([]*EmailTemplate, error) {
+	templates := []*EmailTemplate{}
+	scope, args := emailTemplateScope(tenantID)
+
+	if err := tx.Q().Where(scope+" and type = ? and locale = ?", append(args, typ, locale)...).Order("version desc").All(&templates); err != nil {
+		return nil, errors.Wrap(err, "error finding email template versions")
+	}
+
+	return templates, nil
+}
+
+// FindEmailTemplateVersion finds a version of a type and locale.
+func FindEmailTemplateVersion(tx *storage.Connection, tenantID *uuid.UUID, typ, locale string, version int) (*EmailTemplate, error) {
+	var t EmailTemplate
+	scope, args := emailTemplateScope(tenantID)
+
+	if err := tx.Q().Where(scope+" and type = ? and locale = ? and version = ?", append(args, typ, locale, version)...).First(&t); err != nil {
+		if errors.Cause(err) == sql.ErrNoRows {
+			return nil, EmailTemplateNotFoundError{}
+		}
+
+		return nil, errors.Wrap(err, "error finding email template")
+	}
+
+	return &t, nil
+}
@@ -0,0 +1,90 @@
+package models
+
+import (
+	"fmt"
+
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+	"github.com/supabase/auth/internal/conf"
+	"github.com/supabase/auth/internal/crypto"
+	"github.com/supabase/auth/internal/storage"
+)
+
+// EncryptedColumn is a column holding values encrypted with the database
+// encryption keys. The values are bound to the id of their row.
+type EncryptedColumn struct {
+	Table  string
+	Column string
+}
+
+// EncryptedColumns returns the columns encrypted when database encryption is
+// enabled.
+func EncryptedColumns() []EncryptedColumn {
+	return []EncryptedColumn{
+		{Table: User{}.TableName(), Column: "encrypted_password"},
+		{Table: Factor{}.TableName(), Column: "secret"},
+		{Table: Challenge{}.TableName(), Column: "otp_code"},
+		{Table: Session{}.TableName(), Column: "refresh_token_hmac_key"},
+		{Table: FlowState{}.TableName(), Column: "provider_access_token"},
+		{Table: FlowState{}.TableName(), Column: "provider_refresh_token"},
+		{Table: ProviderToken{}.TableName(), Column: "access_token"},
+		{Table: ProviderToken{}.TableName(), Column: "refresh_token"},
+	}
+}
+
+type encryptedValue struct {
+	ID    uuid.UUID `db:"id"`
+	Value string    `db:"value"`
+}
+
+// ReEncryptColumn encrypts the values of the column encrypted with another
+// key than the encryption key again with the encryption key, in batches of
+// batchSize rows. It returns the number of values encrypted again. Values
+// written concurrently are left as they are, so it can run while the
+// decryption keys still hold the previous keys and requests are served.
+func ReEncryptColumn(tx *storage.Connection, column EncryptedColumn, dbEncryption conf.DatabaseEncryptionConfiguration, batchSize int) (int, error) {
+	if !dbEncryption.Encrypt {
+		return 0, errors.New("database encryption is not enabled")
+	}
+
+	selectQuery := fmt.Sprintf("select id, %q as value from %q where id > ? and %q like '{%%' order by id limit ?", column.Column, column.Table, column.Column)
+	updateQuery := fmt.Sprintf("update %q set %q = ? where id = ? and %q = ?", column.Table, column.Column, column.Column)
+
+	var reEncrypted int
+	var lastID uuid.UUID
+	for {
+		values := []encryptedValue{}
+		if err := tx.RawQuery(selectQuery, lastID, batchSize).All(&values); err != nil {
+			return reEncrypted, errors.Wrapf(err, "error finding encrypted values of %s.%s", column.Table, column.Column)
+		}
+
+		for _, v := range values {
+			lastID = v.ID
+
+			es := crypto.ParseEncryptedString(v.Value)
+			if es == nil || !es.ShouldReEncrypt(dbEncryption.EncryptionKeyID) {
+				continue
+			}
+
+			data, err := es.Decrypt(v.ID.String(), dbEncryption.DecryptionKeys)
+			if err != nil {
+				return reEncrypted, errors.Wrapf(err, "error decrypting %s.%s of %s", column.Table, column.Column, v.ID)
+			}
+
+			nes, err := crypto.NewEncryptedString(v.ID.String(), data, dbEncryption.EncryptionKeyID, dbEncryption.EncryptionKey)
+			if err != nil {
+				return reEncrypted, err
+			}
+
+			n, err := tx.RawQuery(updateQuery, nes.String(), v.ID, v.Value).ExecWithCount()
+			if err != nil {
+				return reEncrypted, errors.Wrapf(err, "error updating %s.%s of %s", column.Table, column.Column, v.ID)
+			}
+			reEncrypted += n
+		}
+
+		if len(values) < batchSize {
+			return reEncrypted, nil
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package models
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/supabase/auth/internal/conf"
+	"github.com/supabase/auth/internal/crypto"
+	"github.com/supabase/auth/internal/storage"
+	"github.com/supabase/auth/internal/storage/test"
+)
+
+type EncryptionTestSuite struct {
+	suite.Suite
+	db *storage.Connection
+}
+
+func TestEncryption(t *testing.T) {
+	globalConfig, err := conf.LoadGlobal(modelsTestConfig)
+	require.NoError(t, err)
+	conn, err := test.SetupDBConnection(globalConfig)
+	require.NoError(t, err)
+	ts := &EncryptionTestSuite{
+		db: conn,
+	}
+	defer ts.db.Close()
+	suite.Run(t, ts)
+}
+
+func (ts *EncryptionTestSuite) SetupTest() {
+	TruncateAll(ts.db)
+}
+
+func (ts *EncryptionTestSuite) TestReEncryptColumn() {
+	oldKey := base64.RawURLEncoding.EncodeToString([]byte("0123456789abcdef0123456789abcdef"))
+	newKey := base64.RawURLEncoding.EncodeToString([]byte("fedcba9876543210fedcba9876543210"))
+
+	user, err := NewUser("", "test@example.com", "secret", "test", nil)
+	require.NoError(ts.T(), err)
+	require.NoError(ts.T(), ts.db.Create(user))
+
+	factors := make([]*Factor, 3)
+	for i := range factors {
+		factors[i] = NewTOTPFactor(user, string(rune('a'+i)))
+		require.NoError(ts.T(), factors[i].SetSecret("topsecret", true, "old", oldKey))
+		require.NoError(ts.T(), ts.db.Create(factors[i]))
+	}
+
+	dbEncryption := conf.DatabaseEncryptionConfiguration{
+		Encrypt:         true,
+		EncryptionKeyID: "new",
+		EncryptionKey:   newKey,
+		DecryptionKeys: map[string]string{
+			"old": oldKey,
+			"new": newKey,
+		},
+	}
+
+	column := EncryptedColumn{Table: Factor{}.TableName(), Column: "secret"}
+	n, err := ReEncryptColumn(ts.db, column, dbEncryption, 2)
+	require.NoError(ts.T(), err)
+	require.Equal(ts.T(), 3, n)
+
+	for _, f := range factors {
+		factor, err := FindFactorByFactorID(ts.db, f.ID)
+		require.NoError(ts.T(), err)
+
+		es := crypto.ParseEncryptedString(factor.Secret)
+		require.NotNil(ts.T(), es)
+		require.Equal(ts.T(), "new", es.KeyID)
+
+		secret, shouldReEncrypt, err := factor.GetSecret(map[string]string{"new": newKey}, true, "new")
+		require.NoError(ts.T(), err)
+		require.False(ts.T(), shouldReEncrypt)
+		require.Equal(ts.T(), "topsecret", secret)
+	}
+
+	// values already encrypted with the encryption key are left as they are
+	n, err = ReEncryptColumn(ts.db, column, dbEncryption, 2)
+	require.NoError(ts.T(), err)
+	require.Equal(ts.T(), 0, n)
+
+	// the previous key is needed to decrypt the values
+	require.NoError(ts.T(), factors[0].SetSecret("topsecret", true, "old", oldKey))
+	require.NoError(ts.T(), ts.db.UpdateOnly(factors[0], "secret"))
+	delete(dbEncryption.DecryptionKeys, "old")
+	_, err = ReEncryptColumn(ts.db, column, dbEncryption, 2)
+	require.Error(ts.T(), err)
+}
+
+func (ts *EncryptionTestSuite) TestFlowStateProviderTokens() {
+	key := base64.RawURLEncoding.EncodeToString([]byte("0123456789abcdef0123456789abcdef"))
+	dbEncryption := conf.DatabaseEncryptionConfiguration{
+		Encrypt:         true,
+		EncryptionKeyID: "key",
+		EncryptionKey:   key,
+		DecryptionKeys:  map[string]string{"key": key},
+	}
+
+	flowState := &FlowState{}
+	require.NoError(ts.T(), flowState.SetProviderTokens("access-token", "", dbEncryption))
+	require.NotNil(ts.T(), crypto.ParseEncryptedString(flowState.ProviderAccessToken))
+	require.Empty(ts.T(), flowState.ProviderRefreshToken)
+
+	accessToken, refreshToken, err := flowState.GetProviderTokens(dbEncryption)
+	require.NoError(ts.T(), err)
+	require.Equal(ts.T(), "access-token", accessToken)
+	require.Empty(ts.T(), refreshToken)
+
+	// tokens stored before encryption was enabled are still readable
+	require.NoError(ts.T(), flowState.SetProviderTokens("access-token", "refresh-token", conf.DatabaseEncryptionConfiguration{}))
+	accessToken, refreshToken, err = flowState.GetProviderTokens(dbEncryption)
+	require.NoError(ts.T(), err)
+	require.Equal(ts.T(), "access-token", accessToken)
+	require.Equal(ts.T(), "refresh-token", refreshToken)
+}
@@ -33,6 +33,36 @@ func IsNotFoundError(err error) bool {
 		return true
 	case OAuthClientStateNotFoundError, *OAuthClientStateNotFoundError:
 		return true
+	case SCIMGroupNotFoundError, *SCIMGroupNotFoundError:
+		return true
+	case TenantNotFoundError, *TenantNotFoundError:
+		return true
+	case UserImportNotFoundError, *UserImportNotFoundError:
+		return true
+	case EmailTemplateNotFoundError, *EmailTemplateNotFoundError:
+		return true
+	case AuditLogEntryNotFoundError, *AuditLogEntryNotFoundError:
+		return true
+	case ProviderTokenNotFoundError, *ProviderTokenNotFoundError:
+		return true
+	case RecoveryCodeNotFoundError, *RecoveryCodeNotFoundError:
+		return true
+	case TrustedDeviceNotFoundError, *TrustedDeviceNotFoundError:
+		return true
+	case Web3NonceNotFoundError, *Web3NonceNotFoundError:
+		return true
+	case ProviderDomainRuleNotFoundError, *ProviderDomainRuleNotFoundError:
+		return true
+	case OrganizationNotFoundError, *OrganizationNotFoundError:
+		return true
+	case OrganizationMemberNotFoundError, *OrganizationMemberNotFoundError:
+		return true
+	case RoleNotFoundError, *RoleNotFoundError:
+		return true
+	case UserExportNotFoundError, *UserExportNotFoundError:
+		return true
+	case WebhookDeliveryNotFoundError, *WebhookDeliveryNotFoundError:
+		return true
 	}
 	return false
 }
@@ -135,3 +165,110 @@ type OAuthClientStateNotFoundError struct{}
 func (e OAuthClientStateNotFoundError) Error() string {
 	return "OAuth state not found"
 }
+
+// SCIMGroupNotFoundError represents an error when a SCIM group can't be found.
+type SCIMGroupNotFoundError struct{}
+
+func (e SCIMGroupNotFoundError) Error() string {
+	return "SCIM group not found"
+}
+
+// TenantNotFoundError represents an error when a tenant can't be found.
+type TenantNotFoundError struct{}
+
+func (e TenantNotFoundError) Error() string {
+	return "Tenant not found"
+}
+
+// UserImportNotFoundError represents an error when a user import can't be found.
+type UserImportNotFoundError struct{}
+
+func (e UserImportNotFoundError) Error() string {
+	return "User import not found"
+}
+
+// EmailTemplateNotFoundError represents an error when an email template can't be found.
+type EmailTemplateNotFoundError struct{}
+
+func (e EmailTemplateNotFoundError) Error() string {
+	return "Email template not found"
+}
+
+// AuditLogEntryNotFoundError represents an error when an audit log entry can't be found.
+type AuditLogEntryNotFoundError struct{}
+
+func (e AuditLogEntryNotFoundError) Error() string {
+	return "Audit log entry not found"
+}
+
+// ProviderTokenNotFoundError represents an error when a provider token can't be found.
+type ProviderTokenNotFoundError struct{}
+
+func (e ProviderTokenNotFoundError) Error() string {
+	return "Provider token not found"
+}
+
+// RecoveryCodeNotFoundError represents an error when an unused recovery code can't be found.
+type RecoveryCodeNotFoundError struct{}
+
+func (e RecoveryCodeNotFoundError) Error() string {
+	return "Recovery code not found"
+}
+
+// TrustedDeviceNotFoundError represents an error when a trusted device can't be found.
+type TrustedDeviceNotFoundError struct{}
+
+func (e TrustedDeviceNotFoundError) Error() string {
+	return "Trusted device not found"
+}
+
+// Web3NonceNotFoundError represents an error when a web3 nonce can't be found.
+type Web3NonceNotFoundError struct{}
+
+func (e Web3NonceNotFoundError) Error() string {
+	return "Web3 nonce not found"
+}
+
+// ProviderDomainRuleNotFoundError represents an error when the email domain rule of a provider can't be found.
+type ProviderDomainRuleNotFoundError struct{}
+
+func (e ProviderDomainRuleNotFoundError) Error() string {
+	return "Provider domain rule not found"
+}
+
+// OrganizationNotFoundError represents an error when an organization can't be found.
+type OrganizationNotFoundError struct{}
+
+func (e OrganizationNotFoundError) Error() string {
+	return "Organization not found"
+}
+
+// OrganizationMemberNotFoundError represents an error when a user is not a member of an organization.
+type OrganizationMemberNotFoundError struct{}
+
+func (e OrganizationMemberNotFoundError) Error() string {
+	return "Organization member not found"
+}
+
+// RoleNotFoundError represents an error when a role can't be found.
+type RoleNotFoundError struct{}
+
+func (e RoleNotFoundError) Error() string {
+	return "Role not found"
+}
+
+// UserExportNotFoundError represents an error when a user export can't be
+// found.
+type UserExportNotFoundError struct{}
+
+func (e UserExportNotFoundError) Error() string {
+	return "User export not found"
+}
+
+// WebhookDeliveryNotFoundError represents an error when a webhook delivery
+// can't be found.
+type WebhookDeliveryNotFoundError struct{}
+
+func (e WebhookDeliveryNotFoundError) Error() string {
+	return "Webhook delivery not found"
+}
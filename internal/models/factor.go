@@ -56,6 +56,10 @@ const (
 	Anonymous
 	Web3
 	OAuthProviderAuthorizationCode
+	MFARecoveryCode
+	MFATrustedDevice
+	Telegram
+	Impersonation
 )
 
 func (authMethod AuthenticationMethod) String() string {
@@ -92,6 +96,14 @@ func (authMethod AuthenticationMethod) String() string {
 		return "web3"
 	case OAuthProviderAuthorizationCode:
 		return "oauth_provider/authorization_code"
+	case MFARecoveryCode:
+		return "mfa/recovery_code"
+	case MFATrustedDevice:
+		return "mfa/trusted_device"
+	case Telegram:
+		return "telegram"
+	case Impersonation:
+		return "impersonation"
 	}
 	return ""
 }
@@ -131,6 +143,14 @@ func ParseAuthenticationMethod(authMethod string) (AuthenticationMethod, error)
 		return Web3, nil
 	case "oauth_provider/authorization_code":
 		return OAuthProviderAuthorizationCode, nil
+	case "mfa/recovery_code":
+		return MFARecoveryCode, nil
+	case "mfa/trusted_device":
+		return MFATrustedDevice, nil
+	case "telegram":
+		return Telegram, nil
+	case "impersonation":
+		return Impersonation, nil
 
 	}
 	return 0, fmt.Errorf("unsupported authentication method %q", authMethod)
@@ -227,6 +247,16 @@ func (Factor) TableName() string {
 	return tableName
 }
 
+func (f *Factor) AfterSave(tx *pop.Connection) error {
+	invalidateUserCache(tx, f.UserID)
+	return nil
+}
+
+func (f *Factor) AfterDestroy(tx *pop.Connection) error {
+	invalidateUserCache(tx, f.UserID)
+	return nil
+}
+
 func NewFactor(user *User, friendlyName string, factorType string, state FactorState) *Factor {
 	id := uuid.Must(uuid.NewV4())
 
@@ -330,6 +360,7 @@ func DeleteUnverifiedFactors(tx *storage.Connection, user *User, factorType stri
 	if err := tx.RawQuery("DELETE FROM "+(&pop.Model{Value: Factor{}}).TableName()+" WHERE user_id = ? and status = ? and factor_type = ?", user.ID, FactorStateUnverified.String(), factorType).Exec(); err != nil {
 		return err
 	}
+	invalidateUserCache(tx.Connection, user.ID)
 
 	return nil
 }
@@ -424,6 +455,7 @@ func DeleteFactorsByUserId(tx *storage.Connection, userId uuid.UUID) error {
 	if err := tx.RawQuery("DELETE FROM "+(&pop.Model{Value: Factor{}}).TableName()+" WHERE user_id = ?", userId).Exec(); err != nil {
 		return err
 	}
+	invalidateUserCache(tx.Connection, userId)
 	return nil
 }
 
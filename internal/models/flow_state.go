@@ -7,6 +7,8 @@ import (
 	"time"
 
 	"github.com/pkg/errors"
+	"github.com/supabase/auth/internal/conf"
+	"github.com/supabase/auth/internal/crypto"
 	"github.com/supabase/auth/internal/security"
 	"github.com/supabase/auth/internal/storage"
 
@@ -194,6 +196,61 @@ func (f *FlowState) IsExpired(expiryDuration time.Duration) bool {
 	return time.Now().After(f.CreatedAt.Add(expiryDuration))
 }
 
+// SetProviderTokens sets the tokens issued by the OAuth provider, encrypted
+// when database encryption is enabled.
+func (f *FlowState) SetProviderTokens(accessToken, refreshToken string, dbEncryption conf.DatabaseEncryptionConfiguration) error {
+	f.ProviderAccessToken = accessToken
+	f.ProviderRefreshToken = refreshToken
+	if !dbEncryption.Encrypt {
+		return nil
+	}
+
+	es, err := crypto.NewEncryptedString(f.ID.String(), []byte(accessToken), dbEncryption.EncryptionKeyID, dbEncryption.EncryptionKey)
+	if err != nil {
+		return err
+	}
+	f.ProviderAccessToken = es.String()
+
+	// not all providers issue a refresh token
+	if refreshToken != "" {
+		es, err := crypto.NewEncryptedString(f.ID.String(), []byte(refreshToken), dbEncryption.EncryptionKeyID, dbEncryption.EncryptionKey)
+		if err != nil {
+			return err
+		}
+		f.ProviderRefreshToken = es.String()
+	}
+
+	return nil
+}
+
+// GetProviderTokens returns the tokens issued by the OAuth provider,
+// decrypting them if needed.
+func (f *FlowState) GetProviderTokens(dbEncryption conf.DatabaseEncryptionConfiguration) (string, string, error) {
+	accessToken, err := f.decryptProviderToken(f.ProviderAccessToken, dbEncryption)
+	if err != nil {
+		return "", "", err
+	}
+
+	refreshToken, err := f.decryptProviderToken(f.ProviderRefreshToken, dbEncryption)
+	if err != nil {
+		return "", "", err
+	}
+
+	return accessToken, refreshToken, nil
+}
+
+func (f *FlowState) decryptProviderToken(value string, dbEncryption conf.DatabaseEncryptionConfiguration) (string, error) {
+	if es := crypto.ParseEncryptedString(value); es != nil {
+		bytes, err := es.Decrypt(f.ID.String(), dbEncryption.DecryptionKeys)
+		if err != nil {
+			return "", err
+		}
+		return string(bytes), nil
+	}
+
+	return value, nil
+}
+
 func (f *FlowState) RecordAuthCodeIssuedAtTime(tx *storage.Connection) error {
 	issueTime := time.Now()
 	f.AuthCodeIssuedAt = &issueTime
@@ -70,6 +70,16 @@ func (i *Identity) BeforeUpdate(tx *pop.Connection) error {
 	return nil
 }
 
+func (i *Identity) AfterSave(tx *pop.Connection) error {
+	invalidateUserCache(tx, i.UserID)
+	return nil
+}
+
+func (i *Identity) AfterDestroy(tx *pop.Connection) error {
+	invalidateUserCache(tx, i.UserID)
+	return nil
+}
+
 func (i *Identity) IsForSSOProvider() bool {
 	return strings.HasPrefix(i.Provider, "sso:")
 }
@@ -98,6 +108,27 @@ func FindIdentitiesByUserID(tx *storage.Connection, userID uuid.UUID) ([]*Identi
 	return identities, nil
 }
 
+// FindIdentitiesByUserIDs returns all identities associated to the user IDs.
+func FindIdentitiesByUserIDs(tx *storage.Connection, userIDs []uuid.UUID) ([]*Identity, error) {
+	identities := []*Identity{}
+	if len(userIDs) == 0 {
+		return identities, nil
+	}
+
+	args := make([]interface{}, len(userIDs))
+	for i, id := range userIDs {
+		args[i] = id
+	}
+
+	if err := tx.Q().Where("user_id in (?)", args...).All(&identities); err != nil {
+		if errors.Cause(err) == sql.ErrNoRows {
+			return identities, nil
+		}
+		return nil, errors.Wrap(err, "error finding identities")
+	}
+	return identities, nil
+}
+
 // FindProvidersByUser returns all providers associated to a user
 func FindProvidersByUser(tx *storage.Connection, user *User) ([]string, error) {
 	identities := []Identity{}
@@ -134,6 +165,7 @@ func (i *Identity) UpdateIdentityData(tx *storage.Connection, updates map[string
 		}
 	}
 	// pop doesn't support updates on tables with composite primary keys so we use a raw query here.
+	defer invalidateUserCache(tx.Connection, i.UserID)
 	return tx.RawQuery(
 		"update "+(&pop.Model{Value: Identity{}}).TableName()+" set identity_data = ? where id = ?",
 		i.IdentityData,
@@ -0,0 +1,180 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+	"github.com/supabase/auth/internal/storage"
+)
+
+type MailJobStatus string
+
+const (
+	// MailJobPending jobs are sent once their run_at time has passed.
+	MailJobPending MailJobStatus = "pending"
+
+	// MailJobDead jobs failed to be sent too many times, they are kept until
+	// they are re-driven through the admin API.
+	MailJobDead MailJobStatus = "dead"
+)
+
+type MailJobHeaders map[string][]string
+
+func (h *MailJobHeaders) Scan(src interface{}) error {
+	b, ok := src.([]byte)
+	if !ok {
+		return errors.New("scan source was not []byte")
+	}
+	return json.Unmarshal(b, h)
+}
+
+func (h MailJobHeaders) Value() (driver.Value, error) {
+	if h == nil {
+		h = MailJobHeaders{}
+	}
+	b, err := json.Marshal(h)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+// MailJob is a mail queued for sending. Sent mail is deleted from the queue,
+// the body is not exposed as it may hold one time passwords.
+type MailJob struct {
+	ID        uuid.UUID      `json:"id" db:"id"`
+	Status    MailJobStatus  `json:"status" db:"status"`
+	To        string         `json:"to" db:"recipient"`
+	Subject   string         `json:"subject" db:"subject"`
+	Body      string         `json:"-" db:"body"`
+	Headers   MailJobHeaders `json:"-" db:"headers"`
+	Type      string         `json:"type" db:"type"`
+	Attempts  int            `json:"attempts" db:"attempts"`
+	LastError *string        `json:"last_error,omitempty" db:"last_error"`
+
+	RunAt     time.Time `json:"run_at" db:"run_at"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+func (MailJob) TableName() string {
+	tableName := "mail_jobs"
+	return tableName
+}
+
+// NewMailJob initializes a pending mail job to be sent right away.
+func NewMailJob(to, subject, body string, headers map[string][]string, typ string) *MailJob {
+	return &MailJob{
+		ID:      uuid.Must(uuid.NewV4()),
+		Status:  MailJobPending,
+		To:      to,
+		Subject: subject,
+		Body:    body,
+		Headers: headers,
+		Type:    typ,
+		RunAt:   time.Now(),
+	}
+}
+
+// BeforeSave is invoked before the mail job is saved to the database.
+func (j *MailJob) BeforeSave(tx *pop.Connection) error {
+	j.UpdatedAt = time.Now()
+	return nil
+}
+
+// ClaimMailJobs claims up to limit pending jobs that are due and counts an
+// attempt for each. Claimed jobs are not due again until lease has passed,
+// so that a job is retried when the worker sending it stops before
+// recording the outcome. Concurrent workers claim different jobs.
+func ClaimMailJobs(tx *storage.Connection, limit int, lease time.Duration) ([]*MailJob, error) {
+	jobs := []*MailJob{}
+	now := time.Now()
+
+	query := fmt.Sprintf(`update %[1]q
+set attempts = attempts + 1, run_at = ?, updated_at = ?
+where id in (
+	select id from %[1]q
+	where status = ? and run_at <= ?
+	order by run_at
+	limit ?
+	for update skip locked
+)
+returning *`, MailJob{}.TableName())
+
+	if err := tx.RawQuery(query, now.Add(lease), now, MailJobPending, now, limit).All(&jobs); err != nil {
+		return nil, errors.Wrap(err, "error claiming mail jobs")
+	}
+
+	return jobs, nil
+}
+
+// Sent removes the job from the queue.
+func (j *MailJob) Sent(tx *storage.Connection) error {
+	return tx.Destroy(j)
+}
+
+// Failed records the error of the last attempt. The job is retried at
+// retryAt, or dead-lettered when retryAt is nil.
+func (j *MailJob) Failed(tx *storage.Connection, sendErr error, retryAt *time.Time) error {
+	lastError := sendErr.Error()
+	j.LastError = &lastError
+
+	if retryAt != nil {
+		j.RunAt = *retryAt
+	} else {
+		j.Status = MailJobDead
+	}
+
+	return tx.UpdateOnly(j, "status", "last_error", "run_at", "updated_at")
+}
+
+// FindMailJobs finds the jobs with the status, oldest first.
+func FindMailJobs(tx *storage.Connection, status MailJobStatus, pageParams *Pagination) ([]*MailJob, error) {
+	jobs := []*MailJob{}
+	q := tx.Q().Where("status = ?", status).Order("created_at asc, id asc")
+
+	var err error
+	if pageParams != nil {
+		err = q.Paginate(int(pageParams.Page), int(pageParams.PerPage)).All(&jobs) // #nosec G115
+		pageParams.Count = uint64(q.Paginator.TotalEntriesSize)                    // #nosec G115
+	} else {
+		err = q.All(&jobs)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "error finding mail jobs")
+	}
+
+	return jobs, nil
+}
+
+// RedriveMailJobs makes dead jobs pending again with their attempts reset,
+// to be sent right away. Every dead job is re-driven when ids is empty. It
+// returns the number of jobs that were re-driven.
+func RedriveMailJobs(tx *storage.Connection, ids []uuid.UUID) (int, error) {
+	now := time.Now()
+
+	query := fmt.Sprintf(`update %q
+set status = ?, attempts = 0, run_at = ?, updated_at = ?
+where status = ?`, MailJob{}.TableName())
+	args := []interface{}{MailJobPending, now, now, MailJobDead}
+
+	if len(ids) > 0 {
+		query += " and id in (?" + strings.Repeat(", ?", len(ids)-1) + ")"
+		for _, id := range ids {
+			args = append(args, id)
+		}
+	}
+
+	count, err := tx.RawQuery(query, args...).ExecWithCount()
+	if err != nil {
+		return 0, errors.Wrap(err, "error re-driving mail jobs")
+	}
+
+	return count, nil
+}
@@ -27,6 +27,10 @@ const (
 	TokenEndpointAuthMethodClientSecretPost  = "client_secret_post"
 )
 
+// OAuthServerGrantTypeClientCredentials is the grant type machine-to-machine
+// clients obtain service tokens with, which carry no user.
+const OAuthServerGrantTypeClientCredentials = "client_credentials"
+
 // OAuthServerClient represents an OAuth client application registered with this OAuth server
 type OAuthServerClient struct {
 	ID                      uuid.UUID `json:"client_id" db:"id"`
@@ -35,14 +39,16 @@ type OAuthServerClient struct {
 	ClientType              string    `json:"client_type" db:"client_type"`
 	TokenEndpointAuthMethod string    `json:"token_endpoint_auth_method" db:"token_endpoint_auth_method"`
 
-	RedirectURIs string     `json:"-" db:"redirect_uris"`
-	GrantTypes   string     `json:"grant_types" db:"grant_types"`
-	ClientName   *string    `json:"client_name,omitempty" db:"client_name"`
-	ClientURI    *string    `json:"client_uri,omitempty" db:"client_uri"`
-	LogoURI      *string    `json:"logo_uri,omitempty" db:"logo_uri"`
-	CreatedAt    time.Time  `json:"created_at" db:"created_at"`
-	UpdatedAt    time.Time  `json:"updated_at" db:"updated_at"`
-	DeletedAt    *time.Time `json:"deleted_at,omitempty" db:"deleted_at"`
+	RedirectURIs     string     `json:"-" db:"redirect_uris"`
+	GrantTypes       string     `json:"grant_types" db:"grant_types"`
+	AllowedScopes    string     `json:"-" db:"allowed_scopes"`
+	AllowedAudiences string     `json:"-" db:"allowed_audiences"`
+	ClientName       *string    `json:"client_name,omitempty" db:"client_name"`
+	ClientURI        *string    `json:"client_uri,omitempty" db:"client_uri"`
+	LogoURI          *string    `json:"logo_uri,omitempty" db:"logo_uri"`
+	CreatedAt        time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at" db:"updated_at"`
+	DeletedAt        *time.Time `json:"deleted_at,omitempty" db:"deleted_at"`
 }
 
 // TableName returns the table name for the OAuthServerClient model
@@ -70,10 +76,16 @@ func (c *OAuthServerClient) Validate() error {
 		return fmt.Errorf("client_type must be '%s' or '%s'", OAuthServerClientTypePublic, OAuthServerClientTypeConfidential)
 	}
 
-	if c.RedirectURIs == "" {
+	// Clients only using the client_credentials grant never redirect users
+	if c.RedirectURIs == "" && !c.IsClientCredentialsOnly() {
 		return fmt.Errorf("at least one redirect_uri is required")
 	}
 
+	// Service tokens are only issued to clients that can authenticate
+	if c.IsGrantTypeAllowed(OAuthServerGrantTypeClientCredentials) && c.ClientType != OAuthServerClientTypeConfidential {
+		return fmt.Errorf("the %s grant type is only allowed for confidential clients", OAuthServerGrantTypeClientCredentials)
+	}
+
 	// Confidential clients must have a client secret
 	if c.ClientType == OAuthServerClientTypeConfidential && c.ClientSecretHash == "" {
 		return fmt.Errorf("client_secret is required for confidential clients")
@@ -141,6 +153,41 @@ func (c *OAuthServerClient) SetGrantTypes(types []string) {
 	c.GrantTypes = strings.Join(types, ",")
 }
 
+// GetAllowedScopes returns the scopes the client may request for service
+// tokens as a slice
+func (c *OAuthServerClient) GetAllowedScopes() []string {
+	if c.AllowedScopes == "" {
+		return []string{}
+	}
+	return strings.Split(c.AllowedScopes, ",")
+}
+
+// SetAllowedScopes sets the allowed scopes from a slice
+func (c *OAuthServerClient) SetAllowedScopes(scopes []string) {
+	c.AllowedScopes = strings.Join(scopes, ",")
+}
+
+// GetAllowedAudiences returns the audiences the client may request service
+// tokens for as a slice
+func (c *OAuthServerClient) GetAllowedAudiences() []string {
+	if c.AllowedAudiences == "" {
+		return []string{}
+	}
+	return strings.Split(c.AllowedAudiences, ",")
+}
+
+// SetAllowedAudiences sets the allowed audiences from a slice
+func (c *OAuthServerClient) SetAllowedAudiences(audiences []string) {
+	c.AllowedAudiences = strings.Join(audiences, ",")
+}
+
+// IsClientCredentialsOnly returns true if the client only uses the
+// client_credentials grant type
+func (c *OAuthServerClient) IsClientCredentialsOnly() bool {
+	grantTypes := c.GetGrantTypes()
+	return len(grantTypes) == 1 && strings.TrimSpace(grantTypes[0]) == OAuthServerGrantTypeClientCredentials
+}
+
 // IsPublic returns true if the client is a public client
 func (c *OAuthServerClient) IsPublic() bool {
 	return c.ClientType == OAuthServerClientTypePublic
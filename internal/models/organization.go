@@ -0,0 +1,270 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+	"github.com/supabase/auth/internal/storage"
+)
+
+// OrganizationRole is the role of a member of an organization.
+type OrganizationRole string
+
+const (
+	// OrganizationRoleOwner members manage the organization and all of its
+	// members. An organization always has at least one owner.
+	OrganizationRoleOwner OrganizationRole = "owner"
+
+	// OrganizationRoleAdmin members manage the members that are not owners.
+	OrganizationRoleAdmin OrganizationRole = "admin"
+
+	// OrganizationRoleMember members belong to the organization without
+	// managing it.
+	OrganizationRoleMember OrganizationRole = "member"
+)
+
+// IsValid reports whether the role is one of the organization roles.
+func (r OrganizationRole) IsValid() bool {
+	switch r {
+	case OrganizationRoleOwner, OrganizationRoleAdmin, OrganizationRoleMember:
+		return true
+	}
+	return false
+}
+
+// CanManageMembers reports whether members with the role may add, update and
+// remove members.
+func (r OrganizationRole) CanManageMembers() bool {
+	return r == OrganizationRoleOwner || r == OrganizationRoleAdmin
+}
+
+// Organization groups users, who are members with a role in each
+// organization they belong to.
+type Organization struct {
+	ID        uuid.UUID `json:"id" db:"id"`
+	Name      string    `json:"name" db:"name"`
+	Slug      string    `json:"slug" db:"slug"`
+	Metadata  JSONMap   `json:"metadata" db:"metadata"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+func (Organization) TableName() string {
+	tableName := "organizations"
+	return tableName
+}
+
+// OrganizationMember links a user to an organization with a role.
+type OrganizationMember struct {
+	OrganizationID uuid.UUID        `json:"organization_id" db:"organization_id"`
+	UserID         uuid.UUID        `json:"user_id" db:"user_id"`
+	Role           OrganizationRole `json:"role" db:"role"`
+	CreatedAt      time.Time        `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time        `json:"updated_at" db:"updated_at"`
+}
+
+func (OrganizationMember) TableName() string {
+	tableName := "organization_members"
+	return tableName
+}
+
+// UserOrganization is an organization a user is a member of, with the
+// user's role in it.
+type UserOrganization struct {
+	Organization
+	Role OrganizationRole `json:"role" db:"role"`
+}
+
+// NewOrganization initializes a new organization.
+func NewOrganization(name, slug string, metadata map[string]interface{}) *Organization {
+	if metadata == nil {
+		metadata = map[string]interface{}{}
+	}
+
+	return &Organization{
+		ID:       uuid.Must(uuid.NewV4()),
+		Name:     name,
+		Slug:     slug,
+		Metadata: metadata,
+	}
+}
+
+// BeforeSave is invoked before the organization is saved to the database.
+func (o *Organization) BeforeSave(tx *pop.Connection) error {
+	o.UpdatedAt = time.Now()
+	return nil
+}
+
+// FindOrganizationByID finds an organization by its ID.
+func FindOrganizationByID(tx *storage.Connection, id uuid.UUID) (*Organization, error) {
+	return findOrganization(tx, "id = ?", id)
+}
+
+// FindOrganizationBySlug finds an organization by its slug.
+func FindOrganizationBySlug(tx *storage.Connection, slug string) (*Organization, error) {
+	return findOrganization(tx, "slug = ?", slug)
+}
+
+func findOrganization(tx *storage.Connection, query string, args ...interface{}) (*Organization, error) {
+	var organization Organization
+
+	if err := tx.Q().Where(query, args...).First(&organization); err != nil {
+		if errors.Cause(err) == sql.ErrNoRows {
+			return nil, OrganizationNotFoundError{}
+		}
+
+		return nil, errors.Wrap(err, "error finding organization")
+	}
+
+	return &organization, nil
+}
+
+// FindOrganizations lists the organizations ordered by creation time, newest
+// first, optionally only those whose name or slug contains the filter.
+func FindOrganizations(tx *storage.Connection, pageParams *Pagination, filter string) ([]*Organization, error) {
+	organizations := []*Organization{}
+	q := tx.Q()
+
+	if filter != "" {
+		lf := "%" + filter + "%"
+		q = q.Where("(name ILIKE ? OR slug ILIKE ?)", lf, lf)
+	}
+	q = q.Order("created_at desc")
+
+	var err error
+	if pageParams != nil {
+		err = q.Paginate(int(pageParams.Page), int(pageParams.PerPage)).All(&organizations) // #nosec G115
+		pageParams.Count = uint64(q.Paginator.TotalEntriesSize)                             // #nosec G115
+	} else {
+		err = q.All(&organizations)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "error finding organizations")
+	}
+
+	return organizations, nil
+}
+
+// FindUserOrganizations returns the organizations the user is a member of,
+// with the user's role in each of them.
+func FindUserOrganizations(tx *storage.Connection, userID uuid.UUID) ([]*UserOrganization, error) {
+	organizations := []*UserOrganization{}
+
+	if err := tx.RawQuery(
+		"SELECT o.*, m.role FROM "+(&pop.Model{Value: Organization{}}).TableName()+" o JOIN "+
+			(&pop.Model{Value: OrganizationMember{}}).TableName()+" m ON m.organization_id = o.id WHERE m.user_id = ? ORDER BY o.created_at ASC",
+		userID,
+	).All(&organizations); err != nil {
+		return nil, errors.Wrap(err, "error finding organizations by user ID")
+	}
+
+	return organizations, nil
+}
+
+// FindUserOrganizationRoles returns the roles of the user in the
+// organizations the user is a member of, by organization ID.
+func FindUserOrganizationRoles(tx *storage.Connection, userID uuid.UUID) (map[string]string, error) {
+	var members []OrganizationMember
+
+	if err := tx.Q().Where("user_id = ?", userID).All(&members); err != nil {
+		return nil, errors.Wrap(err, "error finding organization memberships")
+	}
+
+	roles := make(map[string]string, len(members))
+	for _, member := range members {
+		roles[member.OrganizationID.String()] = string(member.Role)
+	}
+
+	return roles, nil
+}
+
+// Members returns the members of the organization, oldest first.
+func (o *Organization) Members(tx *storage.Connection) ([]*OrganizationMember, error) {
+	members := []*OrganizationMember{}
+
+	if err := tx.Q().Where("organization_id = ?", o.ID).Order("created_at asc").All(&members); err != nil {
+		return nil, errors.Wrap(err, "error finding organization members")
+	}
+
+	return members, nil
+}
+
+// FindMember finds the membership of the user in the organization.
+func (o *Organization) FindMember(tx *storage.Connection, userID uuid.UUID) (*OrganizationMember, error) {
+	var member OrganizationMember
+
+	if err := tx.Q().Where("organization_id = ? and user_id = ?", o.ID, userID).First(&member); err != nil {
+		if errors.Cause(err) == sql.ErrNoRows {
+			return nil, OrganizationMemberNotFoundError{}
+		}
+
+		return nil, errors.Wrap(err, "error finding organization member")
+	}
+
+	return &member, nil
+}
+
+// CountOwners returns the number of owners of the organization.
+func (o *Organization) CountOwners(tx *storage.Connection) (int, error) {
+	count, err := tx.Q().Where("organization_id = ? and role = ?", o.ID, OrganizationRoleOwner).Count(&OrganizationMember{})
+	if err != nil {
+		return 0, errors.Wrap(err, "error counting organization owners")
+	}
+
+	return count, nil
+}
+
+// AddMember adds the user to the organization with the role.
+func (o *Organization) AddMember(tx *storage.Connection, userID uuid.UUID, role OrganizationRole) (*OrganizationMember, error) {
+	now := time.Now()
+	member := &OrganizationMember{
+		OrganizationID: o.ID,
+		UserID:         userID,
+		Role:           role,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+
+	if err := tx.RawQuery(
+		"INSERT INTO "+(&pop.Model{Value: OrganizationMember{}}).TableName()+
+			" (organization_id, user_id, role, created_at, updated_at) VALUES (?, ?, ?, ?, ?)",
+		member.OrganizationID, member.UserID, member.Role, member.CreatedAt, member.UpdatedAt,
+	).Exec(); err != nil {
+		return nil, errors.Wrap(err, "error adding organization member")
+	}
+
+	return member, nil
+}
+
+// UpdateRole changes the role of the member.
+func (m *OrganizationMember) UpdateRole(tx *storage.Connection, role OrganizationRole) error {
+	now := time.Now()
+
+	if err := tx.RawQuery(
+		"UPDATE "+(&pop.Model{Value: OrganizationMember{}}).TableName()+
+			" SET role = ?, updated_at = ? WHERE organization_id = ? AND user_id = ?",
+		role, now, m.OrganizationID, m.UserID,
+	).Exec(); err != nil {
+		return errors.Wrap(err, "error updating organization member")
+	}
+
+	m.Role = role
+	m.UpdatedAt = now
+	return nil
+}
+
+// Delete removes the member from the organization.
+func (m *OrganizationMember) Delete(tx *storage.Connection) error {
+	if err := tx.RawQuery(
+		"DELETE FROM "+(&pop.Model{Value: OrganizationMember{}}).TableName()+
+			" WHERE organization_id = ? AND user_id = ?",
+		m.OrganizationID, m.UserID,
+	).Exec(); err != nil {
+		return errors.Wrap(err, "error removing organization member")
+	}
+
+	return nil
+}
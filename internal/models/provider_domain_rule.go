@@ -0,0 +1,198 @@
+package models
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+	"github.com/supabase/auth/internal/storage"
+)
+
+// EmailDomains is a list of email domains, stored comma separated. A *.
+// prefix matches the subdomains of a domain.
+type EmailDomains []string
+
+// Value implements driver.Valuer.
+func (d EmailDomains) Value() (driver.Value, error) {
+	return strings.Join(d, ","), nil
+}
+
+// Scan implements sql.Scanner.
+func (d *EmailDomains) Scan(src interface{}) error {
+	var value string
+	switch v := src.(type) {
+	case string:
+		value = v
+	case []byte:
+		value = string(v)
+	case nil:
+	default:
+		return fmt.Errorf("models: can't scan %T into email domains", src)
+	}
+
+	domains := EmailDomains{}
+	for _, domain := range strings.Split(value, ",") {
+		if domain != "" {
+			domains = append(domains, domain)
+		}
+	}
+	*d = domains
+	return nil
+}
+
+// Match reports whether the domain is one of the domains, or a subdomain of
+// a *. domain.
+func (d EmailDomains) Match(domain string) bool {
+	domain = strings.ToLower(domain)
+
+	for _, entry := range d {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		if parent, ok := strings.CutPrefix(entry, "*."); ok {
+			if strings.HasSuffix(domain, "."+parent) {
+				return true
+			}
+		} else if domain == entry {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ProviderDomainRule restricts the email domains users may sign up with
+// through a provider. A rule managed with the admin API is used instead of
+// the domains configured for the provider. Rules with a tenant apply to the
+// tenant's signups only.
+type ProviderDomainRule struct {
+	ID             uuid.UUID    `json:"-" db:"id"`
+	TenantID       *uuid.UUID   `json:"tenant_id,omitempty" db:"tenant_id"`
+	Provider       string       `json:"provider" db:"provider"`
+	AllowedDomains EmailDomains `json:"allowed_domains" db:"allowed_domains"`
+	DeniedDomains  EmailDomains `json:"denied_domains" db:"denied_domains"`
+
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+func (ProviderDomainRule) TableName() string {
+	tableName := "provider_domain_rules"
+	return tableName
+}
+
+// NewProviderDomainRule initializes the rule of a provider.
+func NewProviderDomainRule(tenantID *uuid.UUID, provider string, allowed, denied []string) *ProviderDomainRule {
+	return &ProviderDomainRule{
+		ID:             uuid.Must(uuid.NewV4()),
+		TenantID:       tenantID,
+		Provider:       provider,
+		AllowedDomains: append(EmailDomains{}, allowed...),
+		DeniedDomains:  append(EmailDomains{}, denied...),
+	}
+}
+
+// BeforeSave is invoked before the rule is saved to the database.
+func (r *ProviderDomainRule) BeforeSave(tx *pop.Connection) error {
+	r.UpdatedAt = time.Now()
+	return nil
+}
+
+// Allows reports whether a user with the email may sign up. Denied domains
+// take precedence over allowed domains, and users without an email may
+// only sign up when no domains are allowed explicitly.
+func (r *ProviderDomainRule) Allows(email string) bool {
+	domain := ""
+	if at := strings.LastIndex(email, "@"); at >= 0 {
+		domain = email[at+1:]
+	}
+
+	if domain != "" && r.DeniedDomains.Match(domain) {
+		return false
+	}
+
+	if len(r.AllowedDomains) == 0 {
+		return true
+	}
+
+	return domain != "" && r.AllowedDomains.Match(domain)
+}
+
+// providerDomainRuleScope returns the condition matching the rules of the
+// tenant, or of the deployment when tenantID is nil.
+func providerDomainRuleScope(tenantID *uuid.UUID) (string, []interface{}) {
+	if tenantID == nil {
+		return "tenant_id is null", nil
+	}
+	return "tenant_id = ?", []interface{}{*tenantID}
+}
+
+// FindProviderDomainRules finds the rules of the tenant, or of the
+// deployment when tenantID is nil.
+func FindProviderDomainRules(tx *storage.Connection, tenantID *uuid.UUID) ([]*ProviderDomainRule, error) {
+	rules := []*ProviderDomainRule{}
+	scope, args := providerDomainRuleScope(tenantID)
+
+	if err := tx.Q().Where(scope, args...).Order("provider asc").All(&rules); err != nil {
+		return nil, errors.Wrap(err, "error finding provider domain rules")
+	}
+
+	return rules, nil
+}
+
+// FindProviderDomainRule finds the rule of a provider.
+func FindProviderDomainRule(tx *storage.Connection, tenantID *uuid.UUID, provider string) (*ProviderDomainRule, error) {
+	var rule ProviderDomainRule
+	scope, args := providerDomainRuleScope(tenantID)
+
+	if err := tx.Q().Where(scope+" and provider = ?", append(args, provider)...).First(&rule); err != nil {
+		if errors.Cause(err) == sql.ErrNoRows {
+			return nil, ProviderDomainRuleNotFoundError{}
+		}
+
+		return nil, errors.Wrap(err, "error finding provider domain rule")
+	}
+
+	return &rule, nil
+}
+
+// SaveProviderDomainRule creates the rule, or replaces the domains of the
+// provider's existing rule. It must be called in a transaction.
+func SaveProviderDomainRule(tx *storage.Connection, rule *ProviderDomainRule) error {
+	existing, err := FindProviderDomainRule(tx, rule.TenantID, rule.Provider)
+	if err != nil && !IsNotFoundError(err) {
+		return err
+	}
+
+	if existing == nil {
+		if err := tx.Create(rule); err != nil {
+			return errors.Wrap(err, "error creating provider domain rule")
+		}
+		return nil
+	}
+
+	rule.ID = existing.ID
+	rule.CreatedAt = existing.CreatedAt
+	if err := tx.UpdateOnly(rule, "allowed_domains", "denied_domains", "updated_at"); err != nil {
+		return errors.Wrap(err, "error updating provider domain rule")
+	}
+
+	return nil
+}
+
+// DeleteProviderDomainRule deletes the rule of a provider, so that the
+// configured domains are used again. It reports whether a rule was deleted.
+func DeleteProviderDomainRule(tx *storage.Connection, tenantID *uuid.UUID, provider string) (bool, error) {
+	scope, args := providerDomainRuleScope(tenantID)
+
+	query := fmt.Sprintf("delete from %q where %s and provider = ?", ProviderDomainRule{}.TableName(), scope)
+	count, err := tx.RawQuery(query, append(args, provider)...).ExecWithCount()
+	if err != nil {
+		return false, errors.Wrap(err, "error deleting provider domain rule")
+	}
+
+	return count > 0, nil
+}
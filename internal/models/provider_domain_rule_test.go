@@ -0,0 +1,49 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestProviderDomainRuleAllows(t *testing.T) {
+	cases := []struct {
+		allowed []string
+		denied  []string
+		email   string
+		allows  bool
+	}{
+		{email: "user@example.com", allows: true},
+		{email: "", allows: true},
+		{allowed: []string{"ourcompany.com"}, email: "user@ourcompany.com", allows: true},
+		{allowed: []string{"ourcompany.com"}, email: "user@OurCompany.COM", allows: true},
+		{allowed: []string{"ourcompany.com"}, email: "user@example.com", allows: false},
+		{allowed: []string{"ourcompany.com"}, email: "user@eu.ourcompany.com", allows: false},
+		{allowed: []string{"ourcompany.com"}, email: "", allows: false},
+		{allowed: []string{"*.ourcompany.com"}, email: "user@eu.ourcompany.com", allows: true},
+		{allowed: []string{"*.ourcompany.com"}, email: "user@ourcompany.com", allows: false},
+		{allowed: []string{"*.ourcompany.com"}, email: "user@notourcompany.com", allows: false},
+		{denied: []string{"spam.example"}, email: "user@spam.example", allows: false},
+		{denied: []string{"spam.example"}, email: "user@example.com", allows: true},
+		{denied: []string{"spam.example"}, email: "", allows: true},
+		{allowed: []string{"*.ourcompany.com"}, denied: []string{"contractors.ourcompany.com"}, email: "user@contractors.ourcompany.com", allows: false},
+	}
+
+	for _, c := range cases {
+		rule := NewProviderDomainRule(nil, "google", c.allowed, c.denied)
+		require.Equal(t, c.allows, rule.Allows(c.email), "%v %v %q", c.allowed, c.denied, c.email)
+	}
+}
+
+func TestEmailDomainsScan(t *testing.T) {
+	var domains EmailDomains
+	require.NoError(t, domains.Scan("ourcompany.com,*.ourcompany.com"))
+	require.Equal(t, EmailDomains{"ourcompany.com", "*.ourcompany.com"}, domains)
+
+	value, err := domains.Value()
+	require.NoError(t, err)
+	require.Equal(t, "ourcompany.com,*.ourcompany.com", value)
+
+	require.NoError(t, domains.Scan(""))
+	require.Equal(t, EmailDomains{}, domains)
+}
@@ -0,0 +1,197 @@
+package models
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+	"github.com/supabase/auth/internal/conf"
+	"github.com/supabase/auth/internal/crypto"
+	"github.com/supabase/auth/internal/storage"
+)
+
+// ProviderToken holds the access and refresh tokens the OAuth provider of
+// an identity issued when the user signed in, so that applications can call
+// the provider APIs on behalf of the user. The tokens are encrypted.
+type ProviderToken struct {
+	ID           uuid.UUID  `json:"-" db:"id"`
+	UserID       uuid.UUID  `json:"user_id" db:"user_id"`
+	IdentityID   uuid.UUID  `json:"identity_id" db:"identity_id"`
+	Provider     string     `json:"provider" db:"provider"`
+	AccessToken  string     `json:"-" db:"access_token"`
+	RefreshToken *string    `json:"-" db:"refresh_token"`
+	ExpiresAt    *time.Time `json:"expires_at,omitempty" db:"expires_at"`
+	Scopes       *string    `json:"scopes,omitempty" db:"scopes"`
+
+	// RefreshError is the error of the last refresh of the token. Tokens
+	// that failed to be refreshed are not refreshed again until the user
+	// signs in with the provider again.
+	RefreshError *string `json:"-" db:"refresh_error"`
+
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+func (ProviderToken) TableName() string {
+	tableName := "provider_tokens"
+	return tableName
+}
+
+// SetTokens encrypts the tokens issued by the provider. The refresh token
+// is kept when refreshToken is empty, as not all providers rotate it.
+func (t *ProviderToken) SetTokens(accessToken, refreshToken string, expiresAt *time.Time, dbEncryption conf.DatabaseEncryptionConfiguration) error {
+	es, err := crypto.NewEncryptedString(t.ID.String(), []byte(accessToken), dbEncryption.EncryptionKeyID, dbEncryption.EncryptionKey)
+	if err != nil {
+		return err
+	}
+	t.AccessToken = es.String()
+
+	if refreshToken != "" {
+		es, err := crypto.NewEncryptedString(t.ID.String(), []byte(refreshToken), dbEncryption.EncryptionKeyID, dbEncryption.EncryptionKey)
+		if err != nil {
+			return err
+		}
+		encrypted := es.String()
+		t.RefreshToken = &encrypted
+	}
+
+	t.ExpiresAt = expiresAt
+	t.RefreshError = nil
+	return nil
+}
+
+// GetAccessToken returns the decrypted access token.
+func (t *ProviderToken) GetAccessToken(dbEncryption conf.DatabaseEncryptionConfiguration) (string, error) {
+	return t.decrypt(t.AccessToken, dbEncryption)
+}
+
+// GetRefreshToken returns the decrypted refresh token, which is empty when
+// the provider did not issue one.
+func (t *ProviderToken) GetRefreshToken(dbEncryption conf.DatabaseEncryptionConfiguration) (string, error) {
+	if t.RefreshToken == nil {
+		return "", nil
+	}
+	return t.decrypt(*t.RefreshToken, dbEncryption)
+}
+
+func (t *ProviderToken) decrypt(value string, dbEncryption conf.DatabaseEncryptionConfiguration) (string, error) {
+	es := crypto.ParseEncryptedString(value)
+	if es == nil {
+		return "", errors.New("provider token is not encrypted")
+	}
+
+	bytes, err := es.Decrypt(t.ID.String(), dbEncryption.DecryptionKeys)
+	if err != nil {
+		return "", err
+	}
+	return string(bytes), nil
+}
+
+// IsExpired reports whether the access token expires within leeway of now.
+func (t *ProviderToken) IsExpired(now time.Time, leeway time.Duration) bool {
+	return t.ExpiresAt != nil && !t.ExpiresAt.After(now.Add(leeway))
+}
+
+// SaveProviderToken stores the tokens the provider of the identity issued,
+// replacing the tokens stored for it before.
+func SaveProviderToken(tx *storage.Connection, identity *Identity, accessToken, refreshToken string, expiresAt *time.Time, scopes string, dbEncryption conf.DatabaseEncryptionConfiguration) (*ProviderToken, error) {
+	token, err := FindProviderTokenByIdentityID(tx, identity.ID)
+	if err != nil && !IsNotFoundError(err) {
+		return nil, err
+	}
+
+	isNew := token == nil
+	if isNew {
+		token = &ProviderToken{
+			ID:         uuid.Must(uuid.NewV4()),
+			UserID:     identity.UserID,
+			IdentityID: identity.ID,
+			Provider:   identity.Provider,
+		}
+	}
+
+	// the refresh token of an earlier sign-in is not kept, the provider may
+	// have revoked it
+	token.RefreshToken = nil
+	if err := token.SetTokens(accessToken, refreshToken, expiresAt, dbEncryption); err != nil {
+		return nil, errors.Wrap(err, "error encrypting provider token")
+	}
+
+	token.Scopes = nil
+	if scopes != "" {
+		token.Scopes = &scopes
+	}
+
+	if isNew {
+		err = tx.Create(token)
+	} else {
+		err = tx.Update(token)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "error saving provider token")
+	}
+
+	return token, nil
+}
+
+// UpdateTokens saves the refreshed tokens.
+func (t *ProviderToken) UpdateTokens(tx *storage.Connection, accessToken, refreshToken string, expiresAt *time.Time, dbEncryption conf.DatabaseEncryptionConfiguration) error {
+	if err := t.SetTokens(accessToken, refreshToken, expiresAt, dbEncryption); err != nil {
+		return errors.Wrap(err, "error encrypting provider token")
+	}
+	return tx.UpdateOnly(t, "access_token", "refresh_token", "expires_at", "refresh_error", "updated_at")
+}
+
+// UpdateRefreshError records that the token could not be refreshed.
+func (t *ProviderToken) UpdateRefreshError(tx *storage.Connection, refreshErr error) error {
+	msg := refreshErr.Error()
+	t.RefreshError = &msg
+	return tx.UpdateOnly(t, "refresh_error", "updated_at")
+}
+
+// FindProviderTokenByIdentityID finds the token of the identity.
+func FindProviderTokenByIdentityID(tx *storage.Connection, identityID uuid.UUID) (*ProviderToken, error) {
+	token := &ProviderToken{}
+	if err := tx.Q().Where("identity_id = ?", identityID).First(token); err != nil {
+		if errors.Cause(err) == sql.ErrNoRows {
+			return nil, ProviderTokenNotFoundError{}
+		}
+		return nil, errors.Wrap(err, "error finding provider token")
+	}
+	return token, nil
+}
+
+// FindProviderTokenByUserAndProvider finds the token of the provider the
+// user last signed in with. When forUpdate is set the token is locked until
+// the transaction ends.
+func FindProviderTokenByUserAndProvider(tx *storage.Connection, userID uuid.UUID, provider string, forUpdate bool) (*ProviderToken, error) {
+	token := &ProviderToken{}
+
+	query := fmt.Sprintf("select * from %q where user_id = ? and provider = ? order by updated_at desc limit 1", token.TableName())
+	if forUpdate {
+		query += " for update"
+	}
+
+	if err := tx.RawQuery(query, userID, provider).First(token); err != nil {
+		if errors.Cause(err) == sql.ErrNoRows {
+			return nil, ProviderTokenNotFoundError{}
+		}
+		return nil, errors.Wrap(err, "error finding provider token")
+	}
+	return token, nil
+}
+
+// FindProviderTokensToRefresh locks up to limit of the tokens expiring
+// before expiresBefore and last updated before updatedBefore that can be
+// refreshed, skipping the tokens locked by other transactions.
+func FindProviderTokensToRefresh(tx *storage.Connection, expiresBefore, updatedBefore time.Time, limit int) ([]*ProviderToken, error) {
+	tokens := []*ProviderToken{}
+
+	query := fmt.Sprintf("select * from %q where expires_at < ? and updated_at < ? and refresh_token is not null and refresh_error is null order by expires_at limit ? for update skip locked", ProviderToken{}.TableName())
+	if err := tx.RawQuery(query, expiresBefore, updatedBefore, limit).All(&tokens); err != nil {
+		return nil, errors.Wrap(err, "error finding provider tokens to refresh")
+	}
+	return tokens, nil
+}
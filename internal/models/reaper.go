@@ -0,0 +1,109 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/supabase/auth/internal/conf"
+	"github.com/supabase/auth/internal/storage"
+)
+
+// deleteBatch deletes up to limit rows of the table matching the condition.
+// Rows locked by other transactions are skipped, so that pruning never
+// waits on requests using them.
+func deleteBatch(tx *storage.Connection, table, condition string, args ...interface{}) (int, error) {
+	return tx.RawQuery(deleteBatchQuery(table, condition), args...).ExecWithCount()
+}
+
+// deleteCachedBatch is deleteBatch for tables whose rows are cached in the
+// namespace, which invalidates the deleted rows.
+func deleteCachedBatch(tx *storage.Connection, namespace, table, condition string, args ...interface{}) (int, error) {
+	return execInvalidatingCache(tx, namespace, "id", deleteBatchQuery(table, condition), args...)
+}
+
+func deleteBatchQuery(table, condition string) string {
+	return fmt.Sprintf("delete from %q where id in (select id from %q where %s limit ? for update skip locked)", table, table, condition)
+}
+
+// DeleteRevokedRefreshTokens deletes up to limit refresh tokens revoked
+// before the time.
+func DeleteRevokedRefreshTokens(tx *storage.Connection, before time.Time, limit int) (int, error) {
+	count, err := deleteBatch(tx, RefreshToken{}.TableName(), "revoked is true and updated_at < ?", before, limit)
+	if err != nil {
+		return 0, errors.Wrap(err, "error deleting revoked refresh tokens")
+	}
+	return count, nil
+}
+
+// DeleteOneTimeTokensCreatedBefore deletes up to limit one-time tokens
+// created before the time, which are expired if they were not used.
+func DeleteOneTimeTokensCreatedBefore(tx *storage.Connection, before time.Time, limit int) (int, error) {
+	count, err := deleteBatch(tx, OneTimeToken{}.TableName(), "created_at < ?", before, limit)
+	if err != nil {
+		return 0, errors.Wrap(err, "error deleting one-time tokens")
+	}
+	return count, nil
+}
+
+// DeleteUnconfirmedUsers deletes up to limit users who signed up before
+// the time and never confirmed their email or phone. Anonymous, SSO, soft
+// deleted and invited users are kept, and so are users who signed in, such
+// as with a provider which does not confirm their email.
+func DeleteUnconfirmedUsers(tx *storage.Connection, before time.Time, limit int) (int, error) {
+	count, err := deleteCachedBatch(tx, userCacheNamespace, User{}.TableName(), "email_confirmed_at is null and phone_confirmed_at is null and last_sign_in_at is null and invited_at is null and is_anonymous is false and is_sso_user is false and deleted_at is null and created_at < ?", before, limit)
+	if err != nil {
+		return 0, errors.Wrap(err, "error deleting unconfirmed users")
+	}
+	return count, nil
+}
+
+// DeleteSessionsEndedBefore deletes up to limit sessions which ended before
+// the time, with their refresh tokens. Besides their not_after, sessions end
+// once they outlive the timebox or are inactive for longer than the
+// inactivity timeout of the configuration. The longest timeouts of the roles
+// are used, so that no session is deleted before it ended.
+func DeleteSessionsEndedBefore(tx *storage.Connection, config *conf.SessionsConfiguration, before time.Time, limit int) (int, error) {
+	tableSessions := Session{}.TableName()
+
+	condition := "not_after < ?"
+	args := []interface{}{before}
+
+	if config.Timebox != nil && *config.Timebox > 0 {
+		timebox := *config.Timebox
+		for _, roleTimebox := range config.RoleTimebox {
+			timebox = max(timebox, roleTimebox)
+		}
+
+		condition += " or created_at < ?"
+		args = append(args, before.Add(-timebox))
+	}
+
+	if config.InactivityTimeout != nil && *config.InactivityTimeout > 0 {
+		inactivityTimeout := *config.InactivityTimeout
+		for _, roleTimeout := range config.RoleInactivityTimeout {
+			inactivityTimeout = max(inactivityTimeout, roleTimeout)
+		}
+
+		// sessions without a refreshed_at column were last active when
+		// their latest refresh token was issued or used
+		condition += fmt.Sprintf(" or coalesce(refreshed_at, (select max(updated_at) from %q where session_id = %q.id), created_at) < ?", RefreshToken{}.TableName(), tableSessions)
+		args = append(args, before.Add(-inactivityTimeout))
+	}
+
+	count, err := deleteCachedBatch(tx, sessionCacheNamespace, tableSessions, condition, append(args, limit)...)
+	if err != nil {
+		return 0, errors.Wrap(err, "error deleting ended sessions")
+	}
+	return count, nil
+}
+
+// DeleteFlowStatesCreatedBefore deletes up to limit flow states created
+// before the time.
+func DeleteFlowStatesCreatedBefore(tx *storage.Connection, before time.Time, limit int) (int, error) {
+	count, err := deleteBatch(tx, FlowState{}.TableName(), "created_at < ?", before, limit)
+	if err != nil {
+		return 0, errors.Wrap(err, "error deleting flow states")
+	}
+	return count, nil
+}
@@ -0,0 +1,137 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/supabase/auth/internal/conf"
+	"github.com/supabase/auth/internal/storage/test"
+)
+
+func TestReaperDeletes(t *testing.T) {
+	globalConfig, err := conf.LoadGlobal(modelsTestConfig)
+	require.NoError(t, err)
+	conn, err := test.SetupDBConnection(globalConfig)
+	require.NoError(t, err)
+	defer conn.Close()
+	TruncateAll(conn)
+
+	now := time.Now()
+	before := now.Add(time.Hour)
+
+	confirmed, err := NewUser("", "confirmed@example.com", "secret", globalConfig.JWT.Aud, nil)
+	require.NoError(t, err)
+	confirmed.EmailConfirmedAt = &now
+	require.NoError(t, conn.Create(confirmed))
+
+	unconfirmed, err := NewUser("", "unconfirmed@example.com", "secret", globalConfig.JWT.Aud, nil)
+	require.NoError(t, err)
+	require.NoError(t, conn.Create(unconfirmed))
+	require.NoError(t, CreateOneTimeToken(conn, unconfirmed.ID, unconfirmed.GetEmail(), "hash", ConfirmationToken))
+
+	// a revoked refresh token of a session which has not ended
+	token, err := GrantAuthenticatedUser(conn, confirmed, GrantParams{})
+	require.NoError(t, err)
+	require.NoError(t, RevokeTokenFamily(conn, token))
+	_, err = GrantAuthenticatedUser(conn, confirmed, GrantParams{})
+	require.NoError(t, err)
+
+	// an ended session
+	ended, err := GrantAuthenticatedUser(conn, confirmed, GrantParams{SessionNotAfter: &now})
+	require.NoError(t, err)
+
+	n, err := DeleteRevokedRefreshTokens(conn, before, 100)
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+
+	n, err = DeleteOneTimeTokensCreatedBefore(conn, before, 100)
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+
+	n, err = DeleteSessionsEndedBefore(conn, &globalConfig.Sessions, before, 100)
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+	_, err = FindSessionByID(conn, *ended.SessionId, false)
+	require.True(t, IsNotFoundError(err))
+
+	// unconfirmed users who signed in or were invited are kept
+	signedIn, err := NewUser("", "signed-in@example.com", "secret", globalConfig.JWT.Aud, nil)
+	require.NoError(t, err)
+	signedIn.LastSignInAt = &now
+	require.NoError(t, conn.Create(signedIn))
+
+	invited, err := NewUser("", "invited@example.com", "", globalConfig.JWT.Aud, nil)
+	require.NoError(t, err)
+	invited.InvitedAt = &now
+	require.NoError(t, conn.Create(invited))
+
+	n, err = DeleteUnconfirmedUsers(conn, before, 100)
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+	_, err = FindUserByID(conn, unconfirmed.ID)
+	require.True(t, IsNotFoundError(err))
+	_, err = FindUserByID(conn, confirmed.ID)
+	require.NoError(t, err)
+
+	// rows are kept until their retention has passed
+	n, err = DeleteFlowStatesCreatedBefore(conn, now.Add(-time.Hour), 100)
+	require.NoError(t, err)
+	require.Zero(t, n)
+}
+
+func TestReaperDeletesTimedOutSessions(t *testing.T) {
+	globalConfig, err := conf.LoadGlobal(modelsTestConfig)
+	require.NoError(t, err)
+	conn, err := test.SetupDBConnection(globalConfig)
+	require.NoError(t, err)
+	defer conn.Close()
+	TruncateAll(conn)
+
+	user, err := NewUser("", "sessions@example.com", "secret", globalConfig.JWT.Aud, nil)
+	require.NoError(t, err)
+	require.NoError(t, conn.Create(user))
+
+	now := time.Now()
+	newSession := func(createdAt time.Time, refreshedAt *time.Time) *Session {
+		session, err := NewSession(user.ID, nil)
+		require.NoError(t, err)
+		session.CreatedAt = createdAt
+		session.RefreshedAt = refreshedAt
+		require.NoError(t, conn.Create(session))
+		require.NoError(t, conn.RawQuery("update sessions set created_at = ? where id = ?", createdAt, session.ID).Exec())
+		return session
+	}
+
+	refreshed := now.Add(-time.Hour)
+	inactive := now.Add(-3 * time.Hour)
+	active := newSession(now.Add(-2*time.Hour), &refreshed)
+	timedOut := newSession(now.Add(-10*time.Hour), &refreshed)
+	abandoned := newSession(now.Add(-4*time.Hour), &inactive)
+
+	timebox := 8 * time.Hour
+	inactivityTimeout := time.Hour
+	config := &conf.SessionsConfiguration{
+		Timebox:           &timebox,
+		InactivityTimeout: &inactivityTimeout,
+		// the longest timeout of the roles applies
+		RoleInactivityTimeout: map[string]time.Duration{"admin": 2 * time.Hour},
+	}
+
+	// without timeouts the sessions have not ended
+	n, err := DeleteSessionsEndedBefore(conn, &conf.SessionsConfiguration{}, now, 100)
+	require.NoError(t, err)
+	require.Zero(t, n)
+
+	n, err = DeleteSessionsEndedBefore(conn, config, now, 100)
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+
+	_, err = FindSessionByID(conn, active.ID, false)
+	require.NoError(t, err)
+	for _, session := range []*Session{timedOut, abandoned} {
+		_, err = FindSessionByID(conn, session.ID, false)
+		require.True(t, IsNotFoundError(err))
+	}
+}
@@ -0,0 +1,114 @@
+package models
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+	"github.com/supabase/auth/internal/crypto"
+	"github.com/supabase/auth/internal/storage"
+)
+
+// recoveryCodeLength is the number of characters of a recovery code,
+// without the dash separating its halves.
+const recoveryCodeLength = 10
+
+// RecoveryCode is a one-time code a user verifies MFA with when the
+// authenticators of their factors are lost. Only the hash of the code is
+// stored.
+type RecoveryCode struct {
+	ID        uuid.UUID  `json:"id" db:"id"`
+	UserID    uuid.UUID  `json:"user_id" db:"user_id"`
+	CodeHash  string     `json:"-" db:"code_hash"`
+	UsedAt    *time.Time `json:"used_at,omitempty" db:"used_at"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+}
+
+func (RecoveryCode) TableName() string {
+	tableName := "mfa_recovery_codes"
+	return tableName
+}
+
+// hashRecoveryCode hashes the code ignoring case, spaces and dashes, so that
+// codes typed in by users match however they were copied.
+func hashRecoveryCode(code string) string {
+	normalized := strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(normalized)))
+}
+
+// GenerateRecoveryCodes replaces the recovery codes of the user with count
+// new codes, which are returned in plain text. They can't be retrieved
+// again.
+func GenerateRecoveryCodes(tx *storage.Connection, user *User, count int) ([]string, error) {
+	if err := DeleteRecoveryCodes(tx, user.ID); err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, 0, count)
+	for len(codes) < count {
+		code := crypto.SecureAlphanumeric(recoveryCodeLength)
+		recoveryCode := &RecoveryCode{
+			ID:       uuid.Must(uuid.NewV4()),
+			UserID:   user.ID,
+			CodeHash: hashRecoveryCode(code),
+		}
+		if err := tx.Create(recoveryCode); err != nil {
+			return nil, errors.Wrap(err, "error creating recovery code")
+		}
+		codes = append(codes, code[:recoveryCodeLength/2]+"-"+code[recoveryCodeLength/2:])
+	}
+	return codes, nil
+}
+
+// DeleteRecoveryCodes deletes the recovery codes of the user.
+func DeleteRecoveryCodes(tx *storage.Connection, userID uuid.UUID) error {
+	if err := tx.RawQuery(fmt.Sprintf("delete from %q where user_id = ?", RecoveryCode{}.TableName()), userID).Exec(); err != nil {
+		return errors.Wrap(err, "error deleting recovery codes")
+	}
+	return nil
+}
+
+type recoveryCodeCounts struct {
+	Total     int `db:"total"`
+	Remaining int `db:"remaining"`
+}
+
+// CountRecoveryCodes returns the number of recovery codes of the user and
+// how many of them are unused.
+func CountRecoveryCodes(tx *storage.Connection, userID uuid.UUID) (total int, remaining int, err error) {
+	counts := recoveryCodeCounts{}
+	query := fmt.Sprintf("select count(*) as total, count(*) filter (where used_at is null) as remaining from %q where user_id = ?", RecoveryCode{}.TableName())
+	if err := tx.RawQuery(query, userID).First(&counts); err != nil {
+		return 0, 0, errors.Wrap(err, "error counting recovery codes")
+	}
+	return counts.Total, counts.Remaining, nil
+}
+
+// IsUnusedRecoveryCode reports whether the code is an unused recovery code
+// of the user, without using it.
+func IsUnusedRecoveryCode(tx *storage.Connection, userID uuid.UUID, code string) (bool, error) {
+	query := fmt.Sprintf("select exists (select 1 from %q where user_id = ? and code_hash = ? and used_at is null)", RecoveryCode{}.TableName())
+	var exists bool
+	if err := tx.RawQuery(query, userID, hashRecoveryCode(code)).First(&exists); err != nil {
+		return false, errors.Wrap(err, "error finding recovery code")
+	}
+	return exists, nil
+}
+
+// UseRecoveryCode marks the unused recovery code of the user as used. It
+// returns RecoveryCodeNotFoundError when the code is wrong or was used
+// before.
+func UseRecoveryCode(tx *storage.Connection, userID uuid.UUID, code string) error {
+	query := fmt.Sprintf("update %q set used_at = now() where user_id = ? and code_hash = ? and used_at is null", RecoveryCode{}.TableName())
+	n, err := tx.RawQuery(query, userID, hashRecoveryCode(code)).ExecWithCount()
+	if err != nil {
+		return errors.Wrap(err, "error using recovery code")
+	}
+	if n == 0 {
+		return RecoveryCodeNotFoundError{}
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package models
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/supabase/auth/internal/conf"
+	"github.com/supabase/auth/internal/storage"
+	"github.com/supabase/auth/internal/storage/test"
+)
+
+type RecoveryCodeTestSuite struct {
+	suite.Suite
+	db *storage.Connection
+}
+
+func TestRecoveryCode(t *testing.T) {
+	globalConfig, err := conf.LoadGlobal(modelsTestConfig)
+	require.NoError(t, err)
+	conn, err := test.SetupDBConnection(globalConfig)
+	require.NoError(t, err)
+	ts := &RecoveryCodeTestSuite{
+		db: conn,
+	}
+	defer ts.db.Close()
+	suite.Run(t, ts)
+}
+
+func (ts *RecoveryCodeTestSuite) SetupTest() {
+	TruncateAll(ts.db)
+}
+
+func (ts *RecoveryCodeTestSuite) TestUseRecoveryCode() {
+	user, err := NewUser("", "test@example.com", "secret", "test", nil)
+	require.NoError(ts.T(), err)
+	require.NoError(ts.T(), ts.db.Create(user))
+
+	codes, err := GenerateRecoveryCodes(ts.db, user, 2)
+	require.NoError(ts.T(), err)
+	require.Len(ts.T(), codes, 2)
+
+	// codes are matched ignoring case and separators
+	require.NoError(ts.T(), UseRecoveryCode(ts.db, user.ID, strings.ToUpper(strings.ReplaceAll(codes[0], "-", " "))))
+
+	err = UseRecoveryCode(ts.db, user.ID, codes[0])
+	require.ErrorIs(ts.T(), err, RecoveryCodeNotFoundError{})
+
+	total, remaining, err := CountRecoveryCodes(ts.db, user.ID)
+	require.NoError(ts.T(), err)
+	require.Equal(ts.T(), 2, total)
+	require.Equal(ts.T(), 1, remaining)
+
+	// generating codes again replaces the previous ones
+	_, err = GenerateRecoveryCodes(ts.db, user, 3)
+	require.NoError(ts.T(), err)
+
+	err = UseRecoveryCode(ts.db, user.ID, codes[1])
+	require.ErrorIs(ts.T(), err, RecoveryCodeNotFoundError{})
+
+	total, remaining, err = CountRecoveryCodes(ts.db, user.ID)
+	require.NoError(ts.T(), err)
+	require.Equal(ts.T(), 3, total)
+	require.Equal(ts.T(), 3, remaining)
+}
@@ -51,6 +51,15 @@ type GrantParams struct {
 
 	UserAgent string
 	IP        string
+
+	// Country and City are where the IP address is located, when known.
+	Country string
+	City    string
+
+	// RiskScore is the risk score of the sign-in, and MFARequired whether
+	// the session has to reach AAL2 before it can be refreshed.
+	RiskScore   *int
+	MFARequired bool
 }
 
 func (g *GrantParams) FillGrantParams(r *http.Request) {
@@ -135,6 +144,20 @@ func (s *Session) ApplyGrantParams(params *GrantParams) {
 		s.IP = &params.IP
 	}
 
+	if params.Country != "" {
+		s.Country = &params.Country
+	}
+
+	if params.City != "" {
+		s.City = &params.City
+	}
+
+	if params.RiskScore != nil {
+		s.RiskScore = params.RiskScore
+	}
+
+	s.MFARequired = params.MFARequired
+
 	if params.SessionTag != nil && *params.SessionTag != "" {
 		s.Tag = params.SessionTag
 	}
@@ -0,0 +1,43 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+	"github.com/supabase/auth/internal/storage"
+)
+
+// RevokedAccessToken is the jti of an access token revoked before it
+// expired. It is kept on the denylist until the access token expires.
+type RevokedAccessToken struct {
+	JTI       uuid.UUID `json:"jti" db:"jti"`
+	ExpiresAt time.Time `json:"expires_at" db:"expires_at"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+func (RevokedAccessToken) TableName() string {
+	tableName := "revoked_access_tokens"
+	return tableName
+}
+
+// RevokeAccessToken adds the jti of an access token expiring at expiresAt
+// to the denylist. Revoking a token twice is not an error.
+func RevokeAccessToken(tx *storage.Connection, jti uuid.UUID, expiresAt time.Time) error {
+	query := fmt.Sprintf("insert into %q (jti, expires_at, created_at) values (?, ?, ?) on conflict (jti) do nothing", RevokedAccessToken{}.TableName())
+	if err := tx.RawQuery(query, jti, expiresAt, time.Now()).Exec(); err != nil {
+		return errors.Wrap(err, "error revoking access token")
+	}
+	return nil
+}
+
+// IsAccessTokenRevoked reports whether the access token with the jti is on
+// the denylist.
+func IsAccessTokenRevoked(tx *storage.Connection, jti uuid.UUID) (bool, error) {
+	exists, err := tx.Q().Where("jti = ?", jti).Exists(&RevokedAccessToken{})
+	if err != nil {
+		return false, errors.Wrap(err, "error finding revoked access token")
+	}
+	return exists, nil
+}
@@ -0,0 +1,206 @@
+package models
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+	"github.com/supabase/auth/internal/storage"
+)
+
+// Permissions is a list of permissions, stored as a JSON array.
+type Permissions []string
+
+// Value implements driver.Valuer.
+func (p Permissions) Value() (driver.Value, error) {
+	if p == nil {
+		p = Permissions{}
+	}
+	data, err := json.Marshal([]string(p))
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// Scan implements sql.Scanner.
+func (p *Permissions) Scan(src interface{}) error {
+	var source []byte
+	switch v := src.(type) {
+	case string:
+		source = []byte(v)
+	case []byte:
+		source = v
+	case nil:
+		*p = Permissions{}
+		return nil
+	default:
+		return fmt.Errorf("models: can't scan %T into permissions", src)
+	}
+
+	permissions := Permissions{}
+	if err := json.Unmarshal(source, &permissions); err != nil {
+		return err
+	}
+	*p = permissions
+	return nil
+}
+
+// Role grants permissions to the users it is assigned to. Roles are managed
+// with the admin API, and are unrelated to the Postgres role of the user.
+type Role struct {
+	ID          uuid.UUID   `json:"id" db:"id"`
+	Name        string      `json:"name" db:"name"`
+	Description string      `json:"description" db:"description"`
+	Permissions Permissions `json:"permissions" db:"permissions"`
+	CreatedAt   time.Time   `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time   `json:"updated_at" db:"updated_at"`
+}
+
+func (Role) TableName() string {
+	tableName := "roles"
+	return tableName
+}
+
+// UserRole assigns a role to a user.
+type UserRole struct {
+	UserID    uuid.UUID `json:"user_id" db:"user_id"`
+	RoleID    uuid.UUID `json:"role_id" db:"role_id"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+func (UserRole) TableName() string {
+	tableName := "user_roles"
+	return tableName
+}
+
+// NewRole initializes a new role.
+func NewRole(name, description string, permissions []string) *Role {
+	if permissions == nil {
+		permissions = []string{}
+	}
+
+	return &Role{
+		ID:          uuid.Must(uuid.NewV4()),
+		Name:        name,
+		Description: description,
+		Permissions: permissions,
+	}
+}
+
+// BeforeSave is invoked before the role is saved to the database.
+func (r *Role) BeforeSave(tx *pop.Connection) error {
+	r.UpdatedAt = time.Now()
+	return nil
+}
+
+// FindRoleByID finds a role by its ID.
+func FindRoleByID(tx *storage.Connection, id uuid.UUID) (*Role, error) {
+	return findRole(tx, "id = ?", id)
+}
+
+// FindRoleByName finds a role by its name.
+func FindRoleByName(tx *storage.Connection, name string) (*Role, error) {
+	return findRole(tx, "name = ?", name)
+}
+
+func findRole(tx *storage.Connection, query string, args ...interface{}) (*Role, error) {
+	var role Role
+
+	if err := tx.Q().Where(query, args...).First(&role); err != nil {
+		if errors.Cause(err) == sql.ErrNoRows {
+			return nil, RoleNotFoundError{}
+		}
+
+		return nil, errors.Wrap(err, "error finding role")
+	}
+
+	return &role, nil
+}
+
+// FindRoles lists the roles ordered by name.
+func FindRoles(tx *storage.Connection) ([]*Role, error) {
+	roles := []*Role{}
+
+	if err := tx.Q().Order("name asc").All(&roles); err != nil {
+		return nil, errors.Wrap(err, "error finding roles")
+	}
+
+	return roles, nil
+}
+
+// FindUserRoles returns the roles assigned to the user, ordered by name.
+func FindUserRoles(tx *storage.Connection, userID uuid.UUID) ([]*Role, error) {
+	roles := []*Role{}
+
+	if err := tx.RawQuery(
+		"SELECT r.* FROM "+(&pop.Model{Value: Role{}}).TableName()+" r JOIN "+
+			(&pop.Model{Value: UserRole{}}).TableName()+" ur ON ur.role_id = r.id WHERE ur.user_id = ? ORDER BY r.name ASC",
+		userID,
+	).All(&roles); err != nil {
+		return nil, errors.Wrap(err, "error finding roles by user ID")
+	}
+
+	return roles, nil
+}
+
+// RoleNames returns the names of the roles.
+func RoleNames(roles []*Role) []string {
+	names := make([]string, 0, len(roles))
+	for _, role := range roles {
+		names = append(names, role.Name)
+	}
+	return names
+}
+
+// RolePermissions returns the permissions granted by any of the roles,
+// sorted and without duplicates.
+func RolePermissions(roles []*Role) []string {
+	seen := make(map[string]bool)
+	permissions := []string{}
+	for _, role := range roles {
+		for _, permission := range role.Permissions {
+			if !seen[permission] {
+				seen[permission] = true
+				permissions = append(permissions, permission)
+			}
+		}
+	}
+	sort.Strings(permissions)
+	return permissions
+}
+
+// AssignRole assigns the role to the user. Assigning a role the user already
+// has is a no-op.
+func AssignRole(tx *storage.Connection, userID, roleID uuid.UUID) error {
+	if err := tx.RawQuery(
+		"INSERT INTO "+(&pop.Model{Value: UserRole{}}).TableName()+
+			" (user_id, role_id, created_at) VALUES (?, ?, ?) ON CONFLICT DO NOTHING",
+		userID, roleID, time.Now(),
+	).Exec(); err != nil {
+		return errors.Wrap(err, "error assigning role")
+	}
+
+	return nil
+}
+
+// UnassignRole removes the role from the user, reporting whether the user
+// had the role.
+func UnassignRole(tx *storage.Connection, userID, roleID uuid.UUID) (bool, error) {
+	count, err := tx.RawQuery(
+		"DELETE FROM "+(&pop.Model{Value: UserRole{}}).TableName()+
+			" WHERE user_id = ? AND role_id = ?",
+		userID, roleID,
+	).ExecWithCount()
+	if err != nil {
+		return false, errors.Wrap(err, "error removing role")
+	}
+
+	return count > 0, nil
+}
@@ -0,0 +1,222 @@
+package models
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+	"github.com/supabase/auth/internal/storage"
+)
+
+// SCIMGroup is a group provisioned by an identity provider through the SCIM
+// 2.0 API.
+type SCIMGroup struct {
+	ID          uuid.UUID `json:"id" db:"id"`
+	DisplayName string    `json:"display_name" db:"display_name"`
+	ExternalID  *string   `json:"external_id,omitempty" db:"external_id"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+}
+
+func (SCIMGroup) TableName() string {
+	tableName := "scim_groups"
+	return tableName
+}
+
+// SCIMGroupMember links a user to a SCIM group.
+type SCIMGroupMember struct {
+	GroupID   uuid.UUID `db:"group_id"`
+	UserID    uuid.UUID `db:"user_id"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+func (SCIMGroupMember) TableName() string {
+	tableName := "scim_group_members"
+	return tableName
+}
+
+// NewSCIMGroup initializes a new SCIM group.
+func NewSCIMGroup(displayName string, externalID *string) *SCIMGroup {
+	return &SCIMGroup{
+		ID:          uuid.Must(uuid.NewV4()),
+		DisplayName: displayName,
+		ExternalID:  externalID,
+	}
+}
+
+// BeforeSave is invoked before the group is saved to the database.
+func (g *SCIMGroup) BeforeSave(tx *pop.Connection) error {
+	g.UpdatedAt = time.Now()
+	return nil
+}
+
+// FindSCIMGroupByID finds a SCIM group by its ID.
+func FindSCIMGroupByID(tx *storage.Connection, id uuid.UUID) (*SCIMGroup, error) {
+	var group SCIMGroup
+
+	if err := tx.Q().Where("id = ?", id).First(&group); err != nil {
+		if errors.Cause(err) == sql.ErrNoRows {
+			return nil, SCIMGroupNotFoundError{}
+		}
+
+		return nil, errors.Wrap(err, "error finding SCIM group by ID")
+	}
+
+	return &group, nil
+}
+
+// FindSCIMGroups lists SCIM groups ordered by creation time, optionally only
+// those with the given display name, skipping the first offset groups and
+// returning at most limit groups. The total number of matching groups is
+// returned alongside.
+func FindSCIMGroups(tx *storage.Connection, displayName string, offset, limit int) ([]*SCIMGroup, int, error) {
+	groups := []*SCIMGroup{}
+
+	where := "true"
+	var args []interface{}
+
+	if displayName != "" {
+		where = "display_name = ?"
+		args = append(args, displayName)
+	}
+
+	total, err := tx.Q().Where(where, args...).Count(&SCIMGroup{})
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "error counting SCIM groups")
+	}
+
+	if limit > 0 {
+		if err := tx.RawQuery(
+			"SELECT * FROM "+(&pop.Model{Value: SCIMGroup{}}).TableName()+" WHERE "+where+" ORDER BY created_at ASC LIMIT ? OFFSET ?",
+			append(args, limit, offset)...,
+		).All(&groups); err != nil {
+			return nil, 0, errors.Wrap(err, "error finding SCIM groups")
+		}
+	}
+
+	return groups, total, nil
+}
+
+// FindSCIMGroupsByUserID returns the SCIM groups the user is a member of.
+func FindSCIMGroupsByUserID(tx *storage.Connection, userID uuid.UUID) ([]*SCIMGroup, error) {
+	groups := []*SCIMGroup{}
+
+	if err := tx.RawQuery(
+		"SELECT g.* FROM "+(&pop.Model{Value: SCIMGroup{}}).TableName()+" g JOIN "+
+			(&pop.Model{Value: SCIMGroupMember{}}).TableName()+" m ON m.group_id = g.id WHERE m.user_id = ? ORDER BY g.created_at ASC",
+		userID,
+	).All(&groups); err != nil {
+		return nil, errors.Wrap(err, "error finding SCIM groups by user ID")
+	}
+
+	return groups, nil
+}
+
+// SCIMUserFilter restricts the users returned by FindSCIMUsers. Empty fields
+// are ignored.
+type SCIMUserFilter struct {
+	UserName   string
+	Email      string
+	ExternalID string
+}
+
+// FindSCIMUsers lists the users in the audience ordered by creation time,
+// skipping the first offset users and returning at most limit users. The
+// total number of users matching the filter is returned alongside.
+func FindSCIMUsers(tx *storage.Connection, aud string, filter SCIMUserFilter, offset, limit int) ([]*User, int, error) {
+	users := []*User{}
+
+	where := "instance_id = ? and aud = ? and deleted_at is null"
+	args := []interface{}{uuid.Nil, aud}
+
+	if filter.UserName != "" {
+		where += " and (LOWER(email) = ? or raw_app_meta_data->>'scim_user_name' = ?)"
+		args = append(args, strings.ToLower(filter.UserName), filter.UserName)
+	}
+
+	if filter.Email != "" {
+		where += " and LOWER(email) = ?"
+		args = append(args, strings.ToLower(filter.Email))
+	}
+
+	if filter.ExternalID != "" {
+		where += " and raw_app_meta_data->>'scim_external_id' = ?"
+		args = append(args, filter.ExternalID)
+	}
+
+	total, err := tx.Q().Where(where, args...).Count(&User{})
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "error counting SCIM users")
+	}
+
+	if limit > 0 {
+		if err := tx.RawQuery(
+			"SELECT * FROM "+(&pop.Model{Value: User{}}).TableName()+" WHERE "+where+" ORDER BY created_at ASC LIMIT ? OFFSET ?",
+			append(args, limit, offset)...,
+		).All(&users); err != nil {
+			return nil, 0, errors.Wrap(err, "error finding SCIM users")
+		}
+	}
+
+	return users, total, nil
+}
+
+// MemberIDs returns the IDs of the users that are members of the group.
+func (g *SCIMGroup) MemberIDs(tx *storage.Connection) ([]uuid.UUID, error) {
+	var members []SCIMGroupMember
+
+	if err := tx.Q().Where("group_id = ?", g.ID).Order("created_at asc").All(&members); err != nil {
+		return nil, errors.Wrap(err, "error finding SCIM group members")
+	}
+
+	ids := make([]uuid.UUID, 0, len(members))
+	for _, member := range members {
+		ids = append(ids, member.UserID)
+	}
+
+	return ids, nil
+}
+
+// AddMembers adds the users to the group, ignoring existing memberships.
+func (g *SCIMGroup) AddMembers(tx *storage.Connection, userIDs []uuid.UUID) error {
+	for _, userID := range userIDs {
+		if err := tx.RawQuery(
+			"INSERT INTO "+(&pop.Model{Value: SCIMGroupMember{}}).TableName()+
+				" (group_id, user_id, created_at) VALUES (?, ?, ?) ON CONFLICT DO NOTHING",
+			g.ID, userID, time.Now(),
+		).Exec(); err != nil {
+			return errors.Wrap(err, "error adding SCIM group member")
+		}
+	}
+
+	return nil
+}
+
+// RemoveMembers removes the users from the group.
+func (g *SCIMGroup) RemoveMembers(tx *storage.Connection, userIDs []uuid.UUID) error {
+	for _, userID := range userIDs {
+		if err := tx.RawQuery(
+			"DELETE FROM "+(&pop.Model{Value: SCIMGroupMember{}}).TableName()+" WHERE group_id = ? AND user_id = ?",
+			g.ID, userID,
+		).Exec(); err != nil {
+			return errors.Wrap(err, "error removing SCIM group member")
+		}
+	}
+
+	return nil
+}
+
+// SetMembers replaces the group's members with the given users.
+func (g *SCIMGroup) SetMembers(tx *storage.Connection, userIDs []uuid.UUID) error {
+	if err := tx.RawQuery(
+		"DELETE FROM "+(&pop.Model{Value: SCIMGroupMember{}}).TableName()+" WHERE group_id = ?",
+		g.ID,
+	).Exec(); err != nil {
+		return errors.Wrap(err, "error clearing SCIM group members")
+	}
+
+	return g.AddMembers(tx, userIDs)
+}
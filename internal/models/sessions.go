@@ -12,6 +12,7 @@ import (
 	"github.com/gobuffalo/pop/v6"
 	"github.com/gofrs/uuid"
 	"github.com/pkg/errors"
+	"github.com/supabase/auth/internal/cache"
 	"github.com/supabase/auth/internal/conf"
 	"github.com/supabase/auth/internal/crypto"
 	"github.com/supabase/auth/internal/storage"
@@ -76,7 +77,7 @@ type AMREntry struct {
 }
 
 type Session struct {
-	ID     uuid.UUID `json:"-" db:"id"`
+	ID     uuid.UUID `json:"id" db:"id"`
 	UserID uuid.UUID `json:"user_id" db:"user_id"`
 
 	// NotAfter is overriden by timeboxed sessions.
@@ -91,6 +92,14 @@ type Session struct {
 	RefreshedAt *time.Time `json:"refreshed_at,omitempty" db:"refreshed_at"`
 	UserAgent   *string    `json:"user_agent,omitempty" db:"user_agent"`
 	IP          *string    `json:"ip,omitempty" db:"ip"`
+	Country     *string    `json:"country,omitempty" db:"country"`
+	City        *string    `json:"city,omitempty" db:"city"`
+
+	// RiskScore is the risk score of the sign-in that created the session.
+	// Sessions of risky sign-ins that require MFA can't be refreshed until
+	// they reach AAL2.
+	RiskScore   *int `json:"risk_score,omitempty" db:"risk_score"`
+	MFARequired bool `json:"mfa_required,omitempty" db:"mfa_required"`
 
 	Tag           *string    `json:"tag" db:"tag"`
 	OAuthClientID *uuid.UUID `json:"oauth_client_id" db:"oauth_client_id"`
@@ -98,6 +107,18 @@ type Session struct {
 
 	RefreshTokenHmacKey *string `json:"-" db:"refresh_token_hmac_key"`
 	RefreshTokenCounter *int64  `json:"-" db:"refresh_token_counter"`
+
+	// ImpersonatorID and ImpersonatorRole identify the admin who created
+	// the session to impersonate the user.
+	ImpersonatorID   *uuid.UUID `json:"impersonator_id,omitempty" db:"impersonator_id"`
+	ImpersonatorRole *string    `json:"impersonator_role,omitempty" db:"impersonator_role"`
+}
+
+// SessionActor is the act claim of the access tokens of an impersonated
+// session, which identifies the admin acting as the user (RFC 8693).
+type SessionActor struct {
+	Subject string `json:"sub,omitempty"`
+	Role    string `json:"role"`
 }
 
 func (Session) TableName() string {
@@ -105,6 +126,30 @@ func (Session) TableName() string {
 	return tableName
 }
 
+func (s *Session) AfterSave(tx *pop.Connection) error {
+	invalidateSessionCache(tx, s.ID)
+	return nil
+}
+
+func (s *Session) AfterDestroy(tx *pop.Connection) error {
+	invalidateSessionCache(tx, s.ID)
+	return nil
+}
+
+// Actor returns the admin impersonating the user, or nil when the session
+// is not impersonated.
+func (s *Session) Actor() *SessionActor {
+	if s.ImpersonatorRole == nil {
+		return nil
+	}
+
+	actor := &SessionActor{Role: *s.ImpersonatorRole}
+	if s.ImpersonatorID != nil {
+		actor.Subject = s.ImpersonatorID.String()
+	}
+	return actor
+}
+
 func (s *Session) GetRefreshTokenHmacKey(dbEncryption conf.DatabaseEncryptionConfiguration) ([]byte, bool, error) {
 	if s.RefreshTokenHmacKey == nil {
 		return nil, false, nil
@@ -197,6 +242,16 @@ type SessionValidityConfig struct {
 	AllowLowAAL       *time.Duration
 }
 
+// NewSessionValidityConfig returns the validity config of the sessions of
+// users with the role.
+func NewSessionValidityConfig(config *conf.SessionsConfiguration, role string) SessionValidityConfig {
+	return SessionValidityConfig{
+		Timebox:           config.TimeboxFor(role),
+		InactivityTimeout: config.InactivityTimeoutFor(role),
+		AllowLowAAL:       config.AllowLowAAL,
+	}
+}
+
 func (s *Session) CheckValidity(config SessionValidityConfig, now time.Time, refreshTokenTime *time.Time, userHighestPossibleAAL AuthenticatorAssuranceLevel) SessionValidityReason {
 	if s.NotAfter != nil && now.After(*s.NotAfter) {
 		return SessionPastNotAfter
@@ -214,6 +269,10 @@ func (s *Session) CheckValidity(config SessionValidityConfig, now time.Time, ref
 		return SessionLowAAL
 	}
 
+	if s.MFARequired && CompareAAL(ParseAAL(s.AAL), AAL2) < 0 {
+		return SessionLowAAL
+	}
+
 	return SessionValid
 }
 
@@ -283,6 +342,16 @@ func FindSessionByID(tx *storage.Connection, id uuid.UUID, forUpdate bool) (*Ses
 	return session, nil
 }
 
+// CountActiveSessions counts the sessions that have not ended. Sessions
+// ended by the inactivity timeout are counted until they are cleaned up.
+func CountActiveSessions(tx *storage.Connection, now time.Time) (int, error) {
+	count, err := tx.Q().Where("not_after is null or not_after > ?", now).Count(&Session{})
+	if err != nil {
+		return 0, errors.Wrap(err, "error counting active sessions")
+	}
+	return count, nil
+}
+
 func FindSessionByUserID(tx *storage.Connection, userId uuid.UUID) (*Session, error) {
 	session := &Session{}
 	if err := tx.Eager().Q().Where("user_id = ?", userId).Order("created_at asc").First(session); err != nil {
@@ -331,32 +400,93 @@ func FindAllSessionsForUser(tx *storage.Connection, userId uuid.UUID, forUpdate
 	return sessions, nil
 }
 
+// CountSessionsSince returns the number of sessions of the user created
+// since the given time.
+func CountSessionsSince(tx *storage.Connection, userID uuid.UUID, since time.Time) (int, error) {
+	count, err := tx.Q().Where("user_id = ? and created_at >= ?", userID, since).Count(&Session{})
+	if err != nil {
+		return 0, errors.Wrap(err, "error counting sessions")
+	}
+	return count, nil
+}
+
 func updateFactorAssociatedSessions(tx *storage.Connection, userID, factorID uuid.UUID, aal string) error {
-	return tx.RawQuery("UPDATE "+(&pop.Model{Value: Session{}}).TableName()+" set aal = ?, factor_id = ? WHERE user_id = ? AND factor_id = ?", aal, nil, userID, factorID).Exec()
+	return modifySessions(tx, "UPDATE "+(&pop.Model{Value: Session{}}).TableName()+" set aal = ?, factor_id = ? WHERE user_id = ? AND factor_id = ?", aal, nil, userID, factorID)
+}
+
+// modifySessions runs a query updating or deleting sessions, which must not
+// have a RETURNING clause, and invalidates the cached sessions it affected.
+func modifySessions(tx *storage.Connection, query string, args ...interface{}) error {
+	if !cache.Enabled() {
+		return tx.RawQuery(query, args...).Exec()
+	}
+
+	var sessions []Session
+	if err := tx.RawQuery(query+" RETURNING id", args...).All(&sessions); err != nil {
+		return err
+	}
+
+	ids := make([]uuid.UUID, len(sessions))
+	for i := range sessions {
+		ids[i] = sessions[i].ID
+	}
+	invalidateSessionCache(tx.Connection, ids...)
+	return nil
 }
 
 func InvalidateSessionsWithAALLessThan(tx *storage.Connection, userID uuid.UUID, level string) error {
-	return tx.RawQuery("DELETE FROM "+(&pop.Model{Value: Session{}}).TableName()+" WHERE user_id = ? AND aal < ?", userID, level).Exec()
+	return modifySessions(tx, "DELETE FROM "+(&pop.Model{Value: Session{}}).TableName()+" WHERE user_id = ? AND aal < ?", userID, level)
 }
 
 // Logout deletes all sessions for a user.
 func Logout(tx *storage.Connection, userId uuid.UUID) error {
-	return tx.RawQuery("DELETE FROM "+(&pop.Model{Value: Session{}}).TableName()+" WHERE user_id = ?", userId).Exec()
+	return modifySessions(tx, "DELETE FROM "+(&pop.Model{Value: Session{}}).TableName()+" WHERE user_id = ?", userId)
 }
 
 // LogoutSession deletes the current session for a user
+// LogoutOtherSessionsWithSameTag signs out the other sessions of the user of
+// the session that have the same tag as it.
+func LogoutOtherSessionsWithSameTag(tx *storage.Connection, sessionId uuid.UUID, tags []string) error {
+	session, err := FindSessionByID(tx, sessionId, false)
+	if err != nil {
+		return err
+	}
+
+	sessions, err := FindAllSessionsForUser(tx, session.UserID, false)
+	if err != nil {
+		return err
+	}
+
+	tag := session.DetermineTag(tags)
+	for _, s := range sessions {
+		if s.ID == session.ID || s.DetermineTag(tags) != tag {
+			continue
+		}
+
+		if err := LogoutSession(tx, s.ID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func LogoutSession(tx *storage.Connection, sessionId uuid.UUID) error {
-	return tx.RawQuery("DELETE FROM "+(&pop.Model{Value: Session{}}).TableName()+" WHERE id = ?", sessionId).Exec()
+	if err := tx.RawQuery("DELETE FROM "+(&pop.Model{Value: Session{}}).TableName()+" WHERE id = ?", sessionId).Exec(); err != nil {
+		return err
+	}
+	invalidateSessionCache(tx.Connection, sessionId)
+	return nil
 }
 
 // LogoutAllExceptMe deletes all sessions for a user except the current one
 func LogoutAllExceptMe(tx *storage.Connection, sessionId uuid.UUID, userID uuid.UUID) error {
-	return tx.RawQuery("DELETE FROM "+(&pop.Model{Value: Session{}}).TableName()+" WHERE id != ? AND user_id = ?", sessionId, userID).Exec()
+	return modifySessions(tx, "DELETE FROM "+(&pop.Model{Value: Session{}}).TableName()+" WHERE id != ? AND user_id = ?", sessionId, userID)
 }
 
 // RevokeOAuthSessions deletes all sessions associated with a specific OAuth client for a user
 func RevokeOAuthSessions(tx *storage.Connection, userID uuid.UUID, oauthClientID uuid.UUID) error {
-	return tx.RawQuery("DELETE FROM "+(&pop.Model{Value: Session{}}).TableName()+" WHERE user_id = ? AND oauth_client_id = ?", userID, oauthClientID).Exec()
+	return modifySessions(tx, "DELETE FROM "+(&pop.Model{Value: Session{}}).TableName()+" WHERE user_id = ? AND oauth_client_id = ?", userID, oauthClientID)
 }
 
 func (s *Session) UpdateAALAndAssociatedFactor(tx *storage.Connection, aal AuthenticatorAssuranceLevel, factorID *uuid.UUID) error {
@@ -56,6 +56,24 @@ func (ts *SessionsTestSuite) TestFindBySessionIDWithForUpdate() {
 	require.Equal(ts.T(), session.ID, found.ID)
 }
 
+func (ts *SessionsTestSuite) TestCountActiveSessions() {
+	u, err := FindUserByEmailAndAudience(ts.db, "test@example.com", ts.Config.JWT.Aud)
+	require.NoError(ts.T(), err)
+
+	now := time.Now()
+	later, earlier := now.Add(time.Hour), now.Add(-time.Hour)
+	for _, notAfter := range []*time.Time{nil, &later, &earlier} {
+		session, err := NewSession(u.ID, nil)
+		require.NoError(ts.T(), err)
+		session.NotAfter = notAfter
+		require.NoError(ts.T(), ts.db.Create(session))
+	}
+
+	count, err := CountActiveSessions(ts.db, now)
+	require.NoError(ts.T(), err)
+	require.Equal(ts.T(), 2, count)
+}
+
 func (ts *SessionsTestSuite) AddClaimAndReloadSession(session *Session, claim AuthenticationMethod) *Session {
 	err := AddClaimToSession(ts.db, session.ID, claim)
 	require.NoError(ts.T(), err)
@@ -0,0 +1,79 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+	"github.com/supabase/auth/internal/storage"
+)
+
+// SignInLocation is a combination of location and device a user signed in
+// from, which is used to notify users of sign-ins from new ones. Country
+// and City are empty when the location is unknown.
+type SignInLocation struct {
+	ID         uuid.UUID `json:"id" db:"id"`
+	UserID     uuid.UUID `json:"user_id" db:"user_id"`
+	Country    string    `json:"country" db:"country"`
+	City       string    `json:"city" db:"city"`
+	UserAgent  string    `json:"user_agent" db:"user_agent"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at" db:"last_seen_at"`
+}
+
+func (SignInLocation) TableName() string {
+	tableName := "sign_in_locations"
+	return tableName
+}
+
+// FindSignInLocationsByUserID finds the locations and devices the user
+// signed in from, most recently seen first.
+func FindSignInLocationsByUserID(tx *storage.Connection, userID uuid.UUID) ([]*SignInLocation, error) {
+	locations := []*SignInLocation{}
+	if err := tx.Q().Where("user_id = ?", userID).Order("last_seen_at desc").All(&locations); err != nil {
+		return nil, errors.Wrap(err, "error finding sign-in locations")
+	}
+	return locations, nil
+}
+
+// RecordSignInLocation records the location and device the session was
+// signed in from. It reports whether the user signed in from other
+// locations or devices before but never from this one, so that the first
+// sign-in of a user is not considered new.
+func RecordSignInLocation(tx *storage.Connection, session *Session) (bool, error) {
+	location := &SignInLocation{UserID: session.UserID}
+	if session.Country != nil {
+		location.Country = *session.Country
+	}
+	if session.City != nil {
+		location.City = *session.City
+	}
+	if session.UserAgent != nil {
+		location.UserAgent = *session.UserAgent
+	}
+
+	existing := &SignInLocation{}
+	err := tx.Q().Where("user_id = ? and country = ? and city = ? and user_agent = ?", location.UserID, location.Country, location.City, location.UserAgent).First(existing)
+	if err == nil {
+		existing.LastSeenAt = time.Now()
+		if err := tx.UpdateOnly(existing, "last_seen_at"); err != nil {
+			return false, errors.Wrap(err, "error updating sign-in location")
+		}
+		return false, nil
+	} else if errors.Cause(err) != sql.ErrNoRows {
+		return false, errors.Wrap(err, "error finding sign-in location")
+	}
+
+	seen, err := tx.Q().Where("user_id = ?", location.UserID).Exists(&SignInLocation{})
+	if err != nil {
+		return false, errors.Wrap(err, "error finding sign-in locations")
+	}
+
+	location.ID = uuid.Must(uuid.NewV4())
+	location.LastSeenAt = time.Now()
+	if err := tx.Create(location); err != nil {
+		return false, errors.Wrap(err, "error creating sign-in location")
+	}
+	return seen, nil
+}
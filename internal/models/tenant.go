@@ -0,0 +1,114 @@
+package models
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"time"
+
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+	"github.com/supabase/auth/internal/conf"
+	"github.com/supabase/auth/internal/storage"
+)
+
+// TenantConfig holds the settings a tenant overrides, it is stored as JSON.
+type TenantConfig conf.TenantConfiguration
+
+func (c *TenantConfig) Scan(src interface{}) error {
+	b, ok := src.([]byte)
+	if !ok {
+		return errors.New("scan source was not []byte")
+	}
+	return json.Unmarshal(b, c)
+}
+
+func (c TenantConfig) Value() (driver.Value, error) {
+	b, err := json.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+// Tenant is a project served by a multi-tenant deployment, with its own
+// configuration and users.
+type Tenant struct {
+	ID       uuid.UUID    `json:"id" db:"id"`
+	Name     string       `json:"name" db:"name"`
+	Hostname *string      `json:"hostname,omitempty" db:"hostname"`
+	Config   TenantConfig `json:"config" db:"config"`
+
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+func (Tenant) TableName() string {
+	tableName := "tenants"
+	return tableName
+}
+
+// NewTenant initializes a new tenant.
+func NewTenant(name string, hostname *string, config TenantConfig) *Tenant {
+	return &Tenant{
+		ID:       uuid.Must(uuid.NewV4()),
+		Name:     name,
+		Hostname: hostname,
+		Config:   config,
+	}
+}
+
+// BeforeSave is invoked before the tenant is saved to the database.
+func (t *Tenant) BeforeSave(tx *pop.Connection) error {
+	t.UpdatedAt = time.Now()
+	return nil
+}
+
+// Configuration returns the deployment's configuration with the tenant's
+// settings applied.
+func (t *Tenant) Configuration(config *conf.GlobalConfiguration) (*conf.GlobalConfiguration, error) {
+	tc := conf.TenantConfiguration(t.Config)
+	return config.ForTenant(&tc)
+}
+
+// FindTenantByID finds a tenant by its ID.
+func FindTenantByID(tx *storage.Connection, id uuid.UUID) (*Tenant, error) {
+	return findTenant(tx, "id = ?", id)
+}
+
+// FindTenantByHostname finds the tenant served at a hostname.
+func FindTenantByHostname(tx *storage.Connection, hostname string) (*Tenant, error) {
+	return findTenant(tx, "hostname = lower(?)", hostname)
+}
+
+// FindTenantByDatabaseURL finds the tenant whose users are kept in the
+// database at a URL.
+func FindTenantByDatabaseURL(tx *storage.Connection, databaseURL string) (*Tenant, error) {
+	return findTenant(tx, "config->>'database_url' = ?", databaseURL)
+}
+
+func findTenant(tx *storage.Connection, query string, args ...interface{}) (*Tenant, error) {
+	var tenant Tenant
+
+	if err := tx.Q().Where(query, args...).First(&tenant); err != nil {
+		if errors.Cause(err) == sql.ErrNoRows {
+			return nil, TenantNotFoundError{}
+		}
+
+		return nil, errors.Wrap(err, "error finding tenant")
+	}
+
+	return &tenant, nil
+}
+
+// FindAllTenants lists all tenants ordered by creation time.
+func FindAllTenants(tx *storage.Connection) ([]*Tenant, error) {
+	tenants := []*Tenant{}
+
+	if err := tx.Q().Order("created_at asc").All(&tenants); err != nil {
+		return nil, errors.Wrap(err, "error finding tenants")
+	}
+
+	return tenants, nil
+}
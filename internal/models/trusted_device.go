@@ -0,0 +1,122 @@
+package models
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+	"github.com/supabase/auth/internal/crypto"
+	"github.com/supabase/auth/internal/storage"
+)
+
+// trustedDeviceTokenLength is the number of characters of a device token.
+const trustedDeviceTokenLength = 40
+
+// TrustedDevice is a device a user chose to remember after verifying MFA.
+// Presenting its token elevates sessions on the device to AAL2 without a
+// challenge until it expires. Only the hash of the token is stored. The
+// device is no longer trusted once the factor it was remembered with is
+// unenrolled.
+type TrustedDevice struct {
+	ID         uuid.UUID  `json:"id" db:"id"`
+	UserID     uuid.UUID  `json:"user_id" db:"user_id"`
+	FactorID   uuid.UUID  `json:"factor_id" db:"factor_id"`
+	TokenHash  string     `json:"-" db:"token_hash"`
+	UserAgent  *string    `json:"user_agent,omitempty" db:"user_agent"`
+	IP         *string    `json:"ip,omitempty" db:"ip"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty" db:"last_used_at"`
+	ExpiresAt  time.Time  `json:"expires_at" db:"expires_at"`
+}
+
+func (TrustedDevice) TableName() string {
+	tableName := "mfa_trusted_devices"
+	return tableName
+}
+
+func hashTrustedDeviceToken(token string) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(token)))
+}
+
+// NewTrustedDevice creates a device of the user trusted with the factor,
+// expiring after duration. It returns the device token, which can't be
+// retrieved again.
+func NewTrustedDevice(tx *storage.Connection, userID, factorID uuid.UUID, userAgent, ip string, duration time.Duration) (*TrustedDevice, string, error) {
+	token := crypto.SecureAlphanumeric(trustedDeviceTokenLength)
+
+	device := &TrustedDevice{
+		ID:        uuid.Must(uuid.NewV4()),
+		UserID:    userID,
+		FactorID:  factorID,
+		TokenHash: hashTrustedDeviceToken(token),
+		ExpiresAt: time.Now().Add(duration),
+	}
+	if userAgent != "" {
+		device.UserAgent = &userAgent
+	}
+	if ip != "" {
+		device.IP = &ip
+	}
+
+	if err := tx.Create(device); err != nil {
+		return nil, "", errors.Wrap(err, "error creating trusted device")
+	}
+	return device, token, nil
+}
+
+// IsExpired reports whether the device is no longer trusted at now.
+func (d *TrustedDevice) IsExpired(now time.Time) bool {
+	return !d.ExpiresAt.After(now)
+}
+
+// UpdateLastUsedAt records that the device token was used.
+func (d *TrustedDevice) UpdateLastUsedAt(tx *storage.Connection) error {
+	now := time.Now()
+	d.LastUsedAt = &now
+	return tx.UpdateOnly(d, "last_used_at")
+}
+
+// FindTrustedDeviceByToken finds the device of the user with the token.
+func FindTrustedDeviceByToken(tx *storage.Connection, userID uuid.UUID, token string) (*TrustedDevice, error) {
+	device := &TrustedDevice{}
+	if err := tx.Q().Where("user_id = ? and token_hash = ?", userID, hashTrustedDeviceToken(token)).First(device); err != nil {
+		if errors.Cause(err) == sql.ErrNoRows {
+			return nil, TrustedDeviceNotFoundError{}
+		}
+		return nil, errors.Wrap(err, "error finding trusted device")
+	}
+	return device, nil
+}
+
+// FindTrustedDeviceByID finds the device of the user with the id.
+func FindTrustedDeviceByID(tx *storage.Connection, userID, id uuid.UUID) (*TrustedDevice, error) {
+	device := &TrustedDevice{}
+	if err := tx.Q().Where("user_id = ? and id = ?", userID, id).First(device); err != nil {
+		if errors.Cause(err) == sql.ErrNoRows {
+			return nil, TrustedDeviceNotFoundError{}
+		}
+		return nil, errors.Wrap(err, "error finding trusted device")
+	}
+	return device, nil
+}
+
+// FindTrustedDevicesByUserID finds the devices the user trusts, most
+// recently created first.
+func FindTrustedDevicesByUserID(tx *storage.Connection, userID uuid.UUID) ([]*TrustedDevice, error) {
+	devices := []*TrustedDevice{}
+	if err := tx.Q().Where("user_id = ? and expires_at > now()", userID).Order("created_at desc").All(&devices); err != nil {
+		return nil, errors.Wrap(err, "error finding trusted devices")
+	}
+	return devices, nil
+}
+
+// DeleteTrustedDevices revokes all the devices the user trusts.
+func DeleteTrustedDevices(tx *storage.Connection, userID uuid.UUID) error {
+	if err := tx.RawQuery(fmt.Sprintf("delete from %q where user_id = ?", TrustedDevice{}.TableName()), userID).Exec(); err != nil {
+		return errors.Wrap(err, "error deleting trusted devices")
+	}
+	return nil
+}
@@ -5,7 +5,9 @@ import (
 	"crypto/sha256"
 	"database/sql"
 	"encoding/base64"
+	"encoding/json"
 	"fmt"
+	"slices"
 	"strings"
 	"time"
 
@@ -71,26 +73,28 @@ type User struct {
 	DeletedAt   *time.Time `json:"deleted_at,omitempty" db:"deleted_at"`
 	IsAnonymous bool       `json:"is_anonymous" db:"is_anonymous"`
 
+	// PurgeAfter is when the soft deleted user is permanently deleted, and
+	// IsAnonymized whether its personal data has been erased already.
+	PurgeAfter   *time.Time `json:"purge_after,omitempty" db:"purge_after"`
+	IsAnonymized bool       `json:"is_anonymized,omitempty" db:"is_anonymized"`
+
+	FailedSignInAttempts int        `json:"-" db:"failed_sign_in_attempts"`
+	LockedUntil          *time.Time `json:"locked_until,omitempty" db:"locked_until"`
+
+	// SignInCaptchaRequired is set by the password verification attempt hook
+	// to require a captcha for password sign-ins until one succeeds.
+	SignInCaptchaRequired bool `json:"-" db:"sign_in_captcha_required"`
+
+	// PasswordResetRequired is set by admins to expire the password of the
+	// user, who can't sign in with it until it is changed.
+	PasswordResetRequired bool `json:"password_reset_required,omitempty" db:"password_reset_required"`
+
 	DONTUSEINSTANCEID uuid.UUID `json:"-" db:"instance_id"`
 }
 
 func NewUserWithPasswordHash(phone, email, passwordHash, aud string, userData map[string]interface{}) (*User, error) {
-	if strings.HasPrefix(passwordHash, crypto.Argon2Prefix) {
-		_, err := crypto.ParseArgon2Hash(passwordHash)
-		if err != nil {
-			return nil, err
-		}
-	} else if strings.HasPrefix(passwordHash, crypto.FirebaseScryptPrefix) {
-		_, err := crypto.ParseFirebaseScryptHash(passwordHash)
-		if err != nil {
-			return nil, err
-		}
-	} else {
-		// verify that the hash is a bcrypt hash
-		_, err := bcrypt.Cost([]byte(passwordHash))
-		if err != nil {
-			return nil, err
-		}
+	if err := crypto.ValidatePasswordHash(passwordHash); err != nil {
+		return nil, err
 	}
 	id := uuid.Must(uuid.NewV4())
 	user := &User{
@@ -184,6 +188,18 @@ func (u *User) BeforeSave(tx *pop.Connection) error {
 	return nil
 }
 
+// AfterSave is invoked after the user is saved to the database
+func (u *User) AfterSave(tx *pop.Connection) error {
+	invalidateUserCache(tx, u.ID)
+	return nil
+}
+
+// AfterDestroy is invoked after the user is deleted from the database
+func (u *User) AfterDestroy(tx *pop.Connection) error {
+	invalidateUserCache(tx, u.ID)
+	return nil
+}
+
 // IsConfirmed checks if a user has already been
 // registered and confirmed.
 func (u *User) IsConfirmed() bool {
@@ -365,8 +381,9 @@ func (u *User) UpdatePassword(tx *storage.Connection, sessionID *uuid.UUID) erro
 	u.PhoneChangeSentAt = nil
 	u.ReauthenticationToken = ""
 	u.ReauthenticationSentAt = nil
+	u.PasswordResetRequired = false
 
-	if err := tx.UpdateOnly(u, "encrypted_password", "confirmation_token", "confirmation_sent_at", "recovery_token", "recovery_sent_at", "email_change_token_current", "email_change_token_new", "email_change_sent_at", "phone_change_token", "phone_change_sent_at", "reauthentication_token", "reauthentication_sent_at"); err != nil {
+	if err := tx.UpdateOnly(u, "encrypted_password", "confirmation_token", "confirmation_sent_at", "recovery_token", "recovery_sent_at", "email_change_token_current", "email_change_token_new", "email_change_sent_at", "phone_change_token", "phone_change_sent_at", "reauthentication_token", "reauthentication_sent_at", "password_reset_required"); err != nil {
 		return err
 	}
 
@@ -407,7 +424,18 @@ func (u *User) Authenticate(ctx context.Context, tx *storage.Connection, passwor
 
 	compareErr := crypto.CompareHashAndPassword(ctx, hash, password)
 
-	if !strings.HasPrefix(hash, crypto.Argon2Prefix) && !strings.HasPrefix(hash, crypto.FirebaseScryptPrefix) {
+	if compareErr == nil && crypto.NeedsRehash(hash) {
+		// hashes are upgraded to the configured algorithm on sign in, the
+		// caller encrypts the new hash if needed
+		if err := u.SetPassword(ctx, password, false, "", ""); err != nil {
+			return true, false, err
+		}
+		if err := tx.UpdateOnly(u, "encrypted_password"); err != nil {
+			return true, false, err
+		}
+
+		return true, encrypt, nil
+	} else if crypto.IsBcryptHash(hash) {
 		// check if cost exceeds default cost or is too low
 		cost, err := bcrypt.Cost([]byte(hash))
 		if err != nil {
@@ -630,6 +658,24 @@ func FindUserByID(tx *storage.Connection, id uuid.UUID) (*User, error) {
 	return findUser(tx, "instance_id = ? and id = ?", uuid.Nil, id)
 }
 
+// FindUserByIDForUpdate finds a user matching the provided ID and locks it
+// until the transaction ends. Transactions locking a user that is locked
+// wait for the other transaction to end, and find the user as it committed
+// it.
+func FindUserByIDForUpdate(tx *storage.Connection, id uuid.UUID) (*User, error) {
+	// pop does not provide us with a way to execute FOR UPDATE queries
+	locked := &User{}
+	if err := tx.RawQuery(fmt.Sprintf("SELECT * FROM %q WHERE instance_id = ? AND id = ? LIMIT 1 FOR UPDATE;", locked.TableName()), uuid.Nil, id).First(locked); err != nil {
+		if errors.Cause(err) == sql.ErrNoRows {
+			return nil, UserNotFoundError{}
+		}
+		return nil, errors.Wrap(err, "error finding user for update")
+	}
+
+	// once the row is locked, the user is loaded again with its relations
+	return FindUserByID(tx, id)
+}
+
 // FindUserWithRefreshToken finds a user from the provided refresh token. If
 // forUpdate is set to true, then the SELECT statement used by the query has
 // the form SELECT ... FOR UPDATE SKIP LOCKED. This means that a FOR UPDATE
@@ -747,16 +793,142 @@ func findUserWithLegacyRefreshToken(tx *storage.Connection, token string, forUpd
 	return user, refreshToken, session, nil
 }
 
-// FindUsersInAudience finds users with the matching audience.
-func FindUsersInAudience(tx *storage.Connection, aud string, pageParams *Pagination, sortParams *SortParams, filter string) ([]*User, error) {
-	users := []*User{}
+// UserFilter selects the users of an admin user search.
+type UserFilter struct {
+	// Query matches part of the email or full name of the users, as the
+	// filter of the user list always did.
+	Query string
+
+	// Email and Phone match part of the email, case insensitively, and of
+	// the phone number.
+	Email string
+	Phone string
+
+	Provider  string
+	Confirmed *bool
+	Banned    *bool
+
+	CreatedAfter     *time.Time
+	CreatedBefore    *time.Time
+	LastSignInAfter  *time.Time
+	LastSignInBefore *time.Time
+
+	// UserMetadata and AppMetadata select the users whose metadata contain
+	// them, as with the @> operator of jsonb.
+	UserMetadata map[string]interface{}
+	AppMetadata  map[string]interface{}
+}
+
+// userSortExpressions are the expressions users are sorted by for the
+// fields of the sort parameters with keyset pagination. Nullable columns are
+// coalesced so that they can be compared with the cursor.
+var userSortExpressions = map[string]string{
+	CreatedAt:         "coalesce(created_at, '-infinity')",
+	"updated_at":      "coalesce(updated_at, '-infinity')",
+	"last_sign_in_at": "coalesce(last_sign_in_at, '-infinity')",
+	"email":           "coalesce(email, '')",
+	"phone":           "coalesce(phone, '')",
+}
+
+// UserSortFields are the fields users can be sorted by.
+func UserSortFields() map[string]bool {
+	fields := make(map[string]bool, len(userSortExpressions))
+	for name := range userSortExpressions {
+		fields[name] = true
+	}
+	return fields
+}
+
+func userSortExpression(name string) string {
+	if expr, ok := userSortExpressions[name]; ok {
+		return expr
+	}
+	return name
+}
+
+// userHasProvider is the condition on users of having an identity with the
+// provider given as its argument.
+func userHasProvider() string {
+	identitiesTable := (&pop.Model{Value: Identity{}}).TableName()
+	return fmt.Sprintf("exists (select 1 from %q where %q.user_id = %q.id and %q.provider = ?)", identitiesTable, identitiesTable, User{}.TableName(), identitiesTable)
+}
+
+func userSearchQuery(tx *storage.Connection, aud string, filter *UserFilter) (*pop.Query, error) {
 	q := tx.Q().Where("instance_id = ? and aud = ?", uuid.Nil, aud)
+	if filter == nil {
+		return q, nil
+	}
 
-	if filter != "" {
-		lf := "%" + filter + "%"
+	if filter.Query != "" {
+		lf := "%" + filter.Query + "%"
 		// we must specify the collation in order to get case insensitive search for the JSON column
 		q = q.Where("(email LIKE ? OR raw_user_meta_data->>'full_name' ILIKE ?)", lf, lf)
 	}
+	if filter.Email != "" {
+		q = q.Where("email ILIKE ?", "%"+filter.Email+"%")
+	}
+	if filter.Phone != "" {
+		q = q.Where("phone LIKE ?", "%"+strings.TrimPrefix(filter.Phone, "+")+"%")
+	}
+	if filter.Provider != "" {
+		q = q.Where(userHasProvider(), filter.Provider)
+	}
+	if filter.Confirmed != nil {
+		if *filter.Confirmed {
+			q = q.Where("(email_confirmed_at is not null or phone_confirmed_at is not null)")
+		} else {
+			q = q.Where("email_confirmed_at is null and phone_confirmed_at is null")
+		}
+	}
+	if filter.Banned != nil {
+		if *filter.Banned {
+			q = q.Where("banned_until > now()")
+		} else {
+			q = q.Where("(banned_until is null or banned_until <= now())")
+		}
+	}
+	if filter.CreatedAfter != nil {
+		q = q.Where("created_at >= ?", *filter.CreatedAfter)
+	}
+	if filter.CreatedBefore != nil {
+		q = q.Where("created_at < ?", *filter.CreatedBefore)
+	}
+	if filter.LastSignInAfter != nil {
+		q = q.Where("last_sign_in_at >= ?", *filter.LastSignInAfter)
+	}
+	if filter.LastSignInBefore != nil {
+		q = q.Where("last_sign_in_at < ?", *filter.LastSignInBefore)
+	}
+	for column, metadata := range map[string]map[string]interface{}{
+		"raw_user_meta_data": filter.UserMetadata,
+		"raw_app_meta_data":  filter.AppMetadata,
+	} {
+		if len(metadata) == 0 {
+			continue
+		}
+		b, err := json.Marshal(metadata)
+		if err != nil {
+			return nil, errors.Wrap(err, "error encoding metadata filter")
+		}
+		q = q.Where(column+" @> ?::jsonb", string(b))
+	}
+
+	return q, nil
+}
+
+// FindUsersInAudience finds users with the matching audience.
+func FindUsersInAudience(tx *storage.Connection, aud string, pageParams *Pagination, sortParams *SortParams, filter string) ([]*User, error) {
+	return SearchUsersInAudience(tx, aud, &UserFilter{Query: filter}, pageParams, sortParams)
+}
+
+// SearchUsersInAudience finds the users in the audience that match the
+// filter, in the order of the sort parameters.
+func SearchUsersInAudience(tx *storage.Connection, aud string, filter *UserFilter, pageParams *Pagination, sortParams *SortParams) ([]*User, error) {
+	users := []*User{}
+	q, err := userSearchQuery(tx, aud, filter)
+	if err != nil {
+		return nil, err
+	}
 
 	if sortParams != nil && len(sortParams.Fields) > 0 {
 		for _, field := range sortParams.Fields {
@@ -764,6 +936,81 @@ func FindUsersInAudience(tx *storage.Connection, aud string, pageParams *Paginat
 		}
 	}
 
+	if pageParams != nil {
+		err = q.Paginate(int(pageParams.Page), int(pageParams.PerPage)).All(&users) // #nosec G115
+		pageParams.Count = uint64(q.Paginator.TotalEntriesSize)                     // #nosec G115
+	} else {
+		err = q.All(&users)
+	}
+
+	return users, err
+}
+
+// SearchUsersInAudienceAfter returns up to limit users in the audience that
+// match the filter, in the order of the sort parameters and then of their
+// IDs. Only users after the user with the ID after are returned, so pages
+// are found without an offset.
+func SearchUsersInAudienceAfter(tx *storage.Connection, aud string, filter *UserFilter, sortParams *SortParams, after *uuid.UUID, limit int) ([]*User, error) {
+	users := []*User{}
+	q, err := userSearchQuery(tx, aud, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	var fields []SortField
+	if sortParams != nil {
+		fields = sortParams.Fields
+	}
+	idDir := Ascending
+	if len(fields) > 0 {
+		idDir = fields[len(fields)-1].Dir
+	}
+
+	if after != nil {
+		// Users after the cursor have a greater value, or a smaller one when
+		// sorted descending, for the first field they differ in. The values
+		// of the cursor are those of the user it is the ID of.
+		var clauses []string
+		var args []interface{}
+		var equal []string
+		var equalArgs []interface{}
+		for _, field := range append(fields, SortField{Name: "id", Dir: idDir}) {
+			expr := userSortExpression(field.Name)
+			value := fmt.Sprintf("(select %s from %q where id = ?)", expr, User{}.TableName())
+			op := ">"
+			if field.Dir == Descending {
+				op = "<"
+			}
+
+			clauses = append(clauses, "("+strings.Join(append(slices.Clone(equal), expr+" "+op+" "+value), " and ")+")")
+			args = append(args, equalArgs...)
+			args = append(args, *after)
+
+			equal = append(equal, expr+" = "+value)
+			equalArgs = append(equalArgs, *after)
+		}
+		q = q.Where("("+strings.Join(clauses, " or ")+")", args...)
+	}
+
+	for _, field := range fields {
+		q = q.Order(userSortExpression(field.Name) + " " + string(field.Dir))
+	}
+	q = q.Order("id " + string(idDir))
+
+	if err := q.Limit(limit).All(&users); err != nil {
+		return nil, errors.Wrap(err, "error finding users")
+	}
+
+	return users, nil
+}
+
+// FindPendingInvitesInAudience finds the users in the audience who were
+// invited and have not confirmed their email yet, most recently invited
+// first.
+func FindPendingInvitesInAudience(tx *storage.Connection, aud string, pageParams *Pagination) ([]*User, error) {
+	users := []*User{}
+	q := tx.Q().Where("instance_id = ? and aud = ? and invited_at is not null and email_confirmed_at is null and deleted_at is null", uuid.Nil, aud).Order("invited_at desc")
+
 	var err error
 	if pageParams != nil {
 		err = q.Paginate(int(pageParams.Page), int(pageParams.PerPage)).All(&users) // #nosec G115
@@ -775,6 +1022,113 @@ func FindUsersInAudience(tx *storage.Connection, aud string, pageParams *Paginat
 	return users, err
 }
 
+// UserExportFilter selects the users of an export.
+type UserExportFilter struct {
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+	Provider      string
+	Confirmed     *bool
+}
+
+// UserCursor is the position of a user in the order of an export.
+type UserCursor struct {
+	CreatedAt time.Time
+	ID        uuid.UUID
+}
+
+// FindUsersForExport returns up to limit users in the audience that match
+// the filter, ordered by creation time and ID. Only users after the cursor
+// are returned, so pages are found with an index scan instead of an offset.
+func FindUsersForExport(tx *storage.Connection, aud string, filter *UserExportFilter, after *UserCursor, limit int) ([]*User, error) {
+	users := []*User{}
+	q := tx.Q().Where("instance_id = ? and aud = ?", uuid.Nil, aud)
+
+	if after != nil {
+		q = q.Where("(created_at, id) > (?, ?)", after.CreatedAt, after.ID)
+	}
+
+	if filter != nil {
+		if filter.CreatedAfter != nil {
+			q = q.Where("created_at >= ?", *filter.CreatedAfter)
+		}
+		if filter.CreatedBefore != nil {
+			q = q.Where("created_at < ?", *filter.CreatedBefore)
+		}
+		if filter.Provider != "" {
+			q = q.Where(userHasProvider(), filter.Provider)
+		}
+		if filter.Confirmed != nil {
+			if *filter.Confirmed {
+				q = q.Where("(email_confirmed_at is not null or phone_confirmed_at is not null)")
+			} else {
+				q = q.Where("email_confirmed_at is null and phone_confirmed_at is null")
+			}
+		}
+	}
+
+	if err := q.Order("created_at asc, id asc").Limit(limit).All(&users); err != nil {
+		return nil, errors.Wrap(err, "error finding users")
+	}
+
+	return users, nil
+}
+
+// PasswordHashCounts is the number of users with a password, by the
+// algorithm of their password hash.
+type PasswordHashCounts struct {
+	// Algorithms are counted from the hash's prefix, hashes encrypted in
+	// the database are counted as "encrypted".
+	Algorithms map[string]int
+
+	// Current is the number of hashes starting with the current prefix.
+	Current int
+}
+
+type passwordHashCount struct {
+	Algorithm string `db:"algorithm"`
+	Current   bool   `db:"current"`
+	Count     int    `db:"count"`
+}
+
+// CountPasswordHashes counts the password hashes of all users by algorithm,
+// and those starting with currentPrefix.
+func CountPasswordHashes(tx *storage.Connection, currentPrefix string) (*PasswordHashCounts, error) {
+	var rows []*passwordHashCount
+
+	query := fmt.Sprintf(`select
+	case
+		when encrypted_password like '{%%' then 'encrypted'
+		when encrypted_password like '$argon2id$%%' then 'argon2id'
+		when encrypted_password like '$argon2i$%%' then 'argon2i'
+		when encrypted_password like '$fbscrypt$%%' then 'fbscrypt'
+		when encrypted_password like '$scrypt$%%' then 'scrypt'
+		when encrypted_password like '$pbkdf2-%%' then 'pbkdf2'
+		when encrypted_password like '$2%%' then 'bcrypt'
+		else 'unknown'
+	end as algorithm,
+	starts_with(encrypted_password, ?) as current,
+	count(*) as count
+from %q
+where encrypted_password is not null and encrypted_password <> ''
+group by 1, 2`, User{}.TableName())
+
+	if err := tx.RawQuery(query, currentPrefix).All(&rows); err != nil {
+		return nil, errors.Wrap(err, "error counting password hashes")
+	}
+
+	counts := &PasswordHashCounts{
+		Algorithms: make(map[string]int),
+	}
+	for _, row := range rows {
+		counts.Algorithms[row.Algorithm] += row.Count
+		if row.Current {
+			counts.Current += row.Count
+		}
+	}
+
+	return counts, nil
+}
+
 // IsDuplicatedEmail returns whether a user exists with a matching email and
 // audience importantly in the *default* identity linking domain (meaning SSO
 // accounts and similar are not considered).
@@ -849,6 +1203,13 @@ func (u *User) Ban(tx *storage.Connection, duration time.Duration) error {
 	return tx.UpdateOnly(u, "banned_until")
 }
 
+// RequirePasswordReset expires the password of the user, until it is changed
+// with UpdatePassword.
+func (u *User) RequirePasswordReset(tx *storage.Connection) error {
+	u.PasswordResetRequired = true
+	return tx.UpdateOnly(u, "password_reset_required")
+}
+
 // IsBanned checks if a user is banned or not
 func (u *User) IsBanned() bool {
 	if u.BannedUntil == nil {
@@ -857,6 +1218,74 @@ func (u *User) IsBanned() bool {
 	return time.Now().Before(*u.BannedUntil)
 }
 
+// IsLocked checks if the user is temporarily locked out after too many
+// failed sign-in attempts.
+func (u *User) IsLocked() bool {
+	if u.LockedUntil == nil {
+		return false
+	}
+	return time.Now().Before(*u.LockedUntil)
+}
+
+// RecordFailedSignIn increments the failed sign-in attempts of the user and
+// locks the user once maxAttempts is reached. Every further failed attempt
+// doubles the lockout duration, up to maxDuration. It returns true when the
+// user has been locked.
+func (u *User) RecordFailedSignIn(tx *storage.Connection, maxAttempts int, duration, maxDuration time.Duration) (bool, error) {
+	// increment in the database so concurrent attempts are all counted
+	if err := tx.RawQuery(
+		fmt.Sprintf("update %q set failed_sign_in_attempts = failed_sign_in_attempts + 1 where id = ?", u.TableName()),
+		u.ID,
+	).Exec(); err != nil {
+		return false, errors.Wrap(err, "error recording failed sign-in attempt")
+	}
+	invalidateUserCache(tx.Connection, u.ID)
+
+	if err := tx.Reload(u); err != nil {
+		return false, errors.Wrap(err, "error reloading user")
+	}
+
+	if u.FailedSignInAttempts < maxAttempts {
+		return false, nil
+	}
+
+	lockout := maxDuration
+	if exponent := u.FailedSignInAttempts - maxAttempts; exponent < 32 {
+		lockout = min(duration*time.Duration(1<<exponent), maxDuration)
+		if lockout <= 0 {
+			// overflow
+			lockout = maxDuration
+		}
+	}
+
+	t := time.Now().Add(lockout)
+	u.LockedUntil = &t
+	return true, tx.UpdateOnly(u, "locked_until")
+}
+
+// LockUntil locks the user until t, regardless of its failed sign-in
+// attempts.
+func (u *User) LockUntil(tx *storage.Connection, t time.Time) error {
+	u.LockedUntil = &t
+	return tx.UpdateOnly(u, "locked_until")
+}
+
+// RequireSignInCaptcha requires a captcha for the password sign-ins of the
+// user until one succeeds.
+func (u *User) RequireSignInCaptcha(tx *storage.Connection) error {
+	u.SignInCaptchaRequired = true
+	return tx.UpdateOnly(u, "sign_in_captcha_required")
+}
+
+// ResetFailedSignIns clears the failed sign-in attempts, any lockout and the
+// captcha requirement of the user.
+func (u *User) ResetFailedSignIns(tx *storage.Connection) error {
+	u.FailedSignInAttempts = 0
+	u.LockedUntil = nil
+	u.SignInCaptchaRequired = false
+	return tx.UpdateOnly(u, "failed_sign_in_attempts", "locked_until", "sign_in_captcha_required")
+}
+
 func (u *User) HasMFAEnabled() bool {
 	for _, factor := range u.Factors {
 		if factor.IsVerified() {
@@ -921,6 +1350,7 @@ func (u *User) SoftDeleteUser(tx *storage.Connection) error {
 	// set deleted_at time
 	now := time.Now()
 	u.DeletedAt = &now
+	u.IsAnonymized = true
 
 	if err := tx.UpdateOnly(
 		u,
@@ -935,6 +1365,7 @@ func (u *User) SoftDeleteUser(tx *storage.Connection) error {
 		"email_change_token_new",
 		"phone_change_token",
 		"deleted_at",
+		"is_anonymized",
 	); err != nil {
 		return err
 	}
@@ -970,6 +1401,42 @@ func (u *User) SoftDeleteUser(tx *storage.Connection) error {
 	return nil
 }
 
+// ScheduleDeletion soft deletes the user without erasing its data, when it
+// is not soft deleted already, and sets when the user is purged. A nil
+// purgeAfter keeps the soft deleted user.
+func (u *User) ScheduleDeletion(tx *storage.Connection, purgeAfter *time.Time) error {
+	if u.DeletedAt == nil {
+		now := time.Now()
+		u.DeletedAt = &now
+	}
+	u.PurgeAfter = purgeAfter
+
+	return tx.UpdateOnly(u, "deleted_at", "purge_after")
+}
+
+// CancelDeletion cancels the purge of the soft deleted user. A user whose
+// data has not been erased is restored, anonymized users stay soft deleted.
+func (u *User) CancelDeletion(tx *storage.Connection) error {
+	u.PurgeAfter = nil
+	if !u.IsAnonymized {
+		u.DeletedAt = nil
+	}
+
+	return tx.UpdateOnly(u, "deleted_at", "purge_after")
+}
+
+// FindUsersToPurge locks and returns up to limit soft deleted users whose
+// purge is due. It must be called in the transaction the users are purged
+// in.
+func FindUsersToPurge(tx *storage.Connection, now time.Time, limit int) ([]*User, error) {
+	users := []*User{}
+	query := fmt.Sprintf("select * from %q where deleted_at is not null and purge_after <= ? order by purge_after asc, id asc limit ? for update skip locked", User{}.TableName())
+	if err := tx.RawQuery(query, now, limit).All(&users); err != nil {
+		return nil, errors.Wrap(err, "error finding users to purge")
+	}
+	return users, nil
+}
+
 // SoftDeleteUserIdentities performs a soft deletion on all identities associated to a user
 func (u *User) SoftDeleteUserIdentities(tx *storage.Connection) error {
 	identities, err := FindIdentitiesByUserID(tx, u.ID)
@@ -998,6 +1465,7 @@ func (u *User) SoftDeleteUserIdentities(tx *storage.Connection) error {
 			return err
 		}
 	}
+	invalidateUserCache(tx.Connection, u.ID)
 	return nil
 }
 
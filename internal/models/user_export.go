@@ -0,0 +1,125 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+	"github.com/supabase/auth/internal/storage"
+)
+
+type UserExportStatus string
+
+const (
+	// UserExportPending exports are being generated.
+	UserExportPending UserExportStatus = "pending"
+
+	// UserExportReady exports can be downloaded until they expire.
+	UserExportReady UserExportStatus = "ready"
+
+	// UserExportFailed exports could not be generated.
+	UserExportFailed UserExportStatus = "failed"
+)
+
+type UserExportFormat string
+
+const (
+	UserExportJSON UserExportFormat = "json"
+	UserExportZip  UserExportFormat = "zip"
+)
+
+// UserExport is an export of the data of a user, the archive is only
+// exposed through its download link.
+type UserExport struct {
+	ID     uuid.UUID        `json:"id" db:"id"`
+	UserID uuid.UUID        `json:"user_id" db:"user_id"`
+	Status UserExportStatus `json:"status" db:"status"`
+	Format UserExportFormat `json:"format" db:"format"`
+	Data   []byte           `json:"-" db:"data"`
+
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at" db:"updated_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty" db:"completed_at"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty" db:"expires_at"`
+}
+
+func (UserExport) TableName() string {
+	tableName := "user_exports"
+	return tableName
+}
+
+// NewUserExport initializes a pending export of the data of the user.
+func NewUserExport(userID uuid.UUID, format UserExportFormat) *UserExport {
+	return &UserExport{
+		ID:     uuid.Must(uuid.NewV4()),
+		UserID: userID,
+		Status: UserExportPending,
+		Format: format,
+	}
+}
+
+// BeforeSave is invoked before the export is saved to the database.
+func (e *UserExport) BeforeSave(tx *pop.Connection) error {
+	e.UpdatedAt = time.Now()
+	return nil
+}
+
+// IsExpired reports whether the download link of the export has expired.
+func (e *UserExport) IsExpired(now time.Time) bool {
+	return e.ExpiresAt == nil || !now.Before(*e.ExpiresAt)
+}
+
+// Complete stores the archive of the export, which can be downloaded until
+// expiresAt.
+func (e *UserExport) Complete(tx *storage.Connection, data []byte, expiresAt time.Time) error {
+	now := time.Now()
+	e.Status = UserExportReady
+	e.Data = data
+	e.CompletedAt = &now
+	e.ExpiresAt = &expiresAt
+
+	return tx.UpdateOnly(e, "status", "data", "completed_at", "expires_at", "updated_at")
+}
+
+// Fail marks the export as failed.
+func (e *UserExport) Fail(tx *storage.Connection) error {
+	now := time.Now()
+	e.Status = UserExportFailed
+	e.CompletedAt = &now
+
+	return tx.UpdateOnly(e, "status", "completed_at", "updated_at")
+}
+
+// FindUserExportByID finds an export by its ID.
+func FindUserExportByID(tx *storage.Connection, id uuid.UUID) (*UserExport, error) {
+	export := &UserExport{}
+	if err := tx.Find(export, id); err != nil {
+		if errors.Cause(err) == sql.ErrNoRows {
+			return nil, UserExportNotFoundError{}
+		}
+		return nil, errors.Wrap(err, "error finding user export")
+	}
+
+	return export, nil
+}
+
+// FindLatestUserExport finds the export the user requested last.
+func FindLatestUserExport(tx *storage.Connection, userID uuid.UUID) (*UserExport, error) {
+	export := &UserExport{}
+	if err := tx.Q().Where("user_id = ?", userID).Order("created_at desc").First(export); err != nil {
+		if errors.Cause(err) == sql.ErrNoRows {
+			return nil, UserExportNotFoundError{}
+		}
+		return nil, errors.Wrap(err, "error finding user export")
+	}
+
+	return export, nil
+}
+
+// DeleteUserExports deletes the exports of the user, so that only the export
+// requested last is kept.
+func DeleteUserExports(tx *storage.Connection, userID uuid.UUID) error {
+	return tx.RawQuery("delete from "+UserExport{}.TableName()+" where user_id = ?", userID).Exec()
+}
@@ -0,0 +1,129 @@
+package models
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"time"
+
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+	"github.com/supabase/auth/internal/storage"
+)
+
+// MaxUserImportErrors is the number of failed records whose errors are kept
+// on a user import.
+const MaxUserImportErrors = 100
+
+type UserImportStatus string
+
+const (
+	UserImportPending   UserImportStatus = "pending"
+	UserImportRunning   UserImportStatus = "running"
+	UserImportCompleted UserImportStatus = "completed"
+	UserImportFailed    UserImportStatus = "failed"
+)
+
+// UserImportError describes why a record of an import was not imported.
+type UserImportError struct {
+	Line  int    `json:"line"`
+	Email string `json:"email,omitempty"`
+	Phone string `json:"phone,omitempty"`
+	Error string `json:"error"`
+}
+
+type UserImportErrors []UserImportError
+
+func (e *UserImportErrors) Scan(src interface{}) error {
+	b, ok := src.([]byte)
+	if !ok {
+		return errors.New("scan source was not []byte")
+	}
+	return json.Unmarshal(b, e)
+}
+
+func (e UserImportErrors) Value() (driver.Value, error) {
+	if e == nil {
+		e = UserImportErrors{}
+	}
+	b, err := json.Marshal(e)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+// UserImport tracks the progress of a bulk user import.
+type UserImport struct {
+	ID        uuid.UUID        `json:"id" db:"id"`
+	Status    UserImportStatus `json:"status" db:"status"`
+	Format    string           `json:"format" db:"format"`
+	Total     int              `json:"total" db:"total"`
+	Processed int              `json:"processed" db:"processed"`
+	Imported  int              `json:"imported" db:"imported"`
+	Failed    int              `json:"failed" db:"failed"`
+	Errors    UserImportErrors `json:"errors" db:"errors"`
+
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at" db:"updated_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty" db:"finished_at"`
+}
+
+func (UserImport) TableName() string {
+	tableName := "user_imports"
+	return tableName
+}
+
+// NewUserImport initializes a pending import of total records.
+func NewUserImport(format string, total int) *UserImport {
+	return &UserImport{
+		ID:     uuid.Must(uuid.NewV4()),
+		Status: UserImportPending,
+		Format: format,
+		Total:  total,
+		Errors: UserImportErrors{},
+	}
+}
+
+// BeforeSave is invoked before the user import is saved to the database.
+func (i *UserImport) BeforeSave(tx *pop.Connection) error {
+	i.UpdatedAt = time.Now()
+	return nil
+}
+
+// RecordError counts a failed record, its error is kept while fewer than
+// MaxUserImportErrors errors were recorded.
+func (i *UserImport) RecordError(importErr UserImportError) {
+	i.Failed++
+	if len(i.Errors) < MaxUserImportErrors {
+		i.Errors = append(i.Errors, importErr)
+	}
+}
+
+// Finish marks the import as done with the status.
+func (i *UserImport) Finish(status UserImportStatus) {
+	now := time.Now()
+	i.Status = status
+	i.FinishedAt = &now
+}
+
+// UpdateProgress saves the status and counters of the import.
+func (i *UserImport) UpdateProgress(tx *storage.Connection) error {
+	return tx.UpdateOnly(i, "status", "processed", "imported", "failed", "errors", "finished_at", "updated_at")
+}
+
+// FindUserImportByID finds a user import by its ID.
+func FindUserImportByID(tx *storage.Connection, id uuid.UUID) (*UserImport, error) {
+	var userImport UserImport
+
+	if err := tx.Q().Where("id = ?", id).First(&userImport); err != nil {
+		if errors.Cause(err) == sql.ErrNoRows {
+			return nil, UserImportNotFoundError{}
+		}
+
+		return nil, errors.Wrap(err, "error finding user import")
+	}
+
+	return &userImport, nil
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"testing"
 
+	"github.com/gofrs/uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
@@ -139,6 +140,22 @@ func (ts *UserTestSuite) TestFindUserByID() {
 	require.Equal(ts.T(), u.ID, n.ID)
 }
 
+func (ts *UserTestSuite) TestFindUserByIDForUpdate() {
+	u := ts.createUser()
+
+	err := ts.db.Transaction(func(tx *storage.Connection) error {
+		n, terr := FindUserByIDForUpdate(tx, u.ID)
+		require.NoError(ts.T(), terr)
+		require.Equal(ts.T(), u.ID, n.ID)
+		require.Len(ts.T(), n.Identities, 1)
+		return nil
+	})
+	require.NoError(ts.T(), err)
+
+	_, err = FindUserByIDForUpdate(ts.db, uuid.Must(uuid.NewV4()))
+	require.True(ts.T(), IsNotFoundError(err))
+}
+
 func (ts *UserTestSuite) TestFindUserByRecoveryToken() {
 	u := ts.createUser()
 	tokenHash := "test_recovery_token"
@@ -393,6 +410,14 @@ func (ts *UserTestSuite) TestNewUserWithPasswordHashSuccess() {
 			desc: "Valid Firebase scrypt hash",
 			hash: "$fbscrypt$v=1,n=14,r=8,p=1,ss=Bw==,sk=ou9tdYTGyYm8kuR6Dt0Bp0kDuAYoXrK16mbZO4yGwAn3oLspjnN0/c41v8xZnO1n14J3MjKj1b2g6AUCAlFwMw==$C0sHCg9ek77hsg==$ZGlmZmVyZW50aGFzaA==",
 		},
+		{
+			desc: "Valid scrypt hash",
+			hash: "$scrypt$ln=4,r=8,p=1$c2FsdHNhbHRzYWx0c2FsdA$Xm5Jhj+IG4YfthdtDVddQUEAe9RXODln+i0vrX/P38c",
+		},
+		{
+			desc: "Valid PBKDF2 hash",
+			hash: "$pbkdf2-sha256$i=1000,l=32$c2FsdHNhbHRzYWx0c2FsdA$4+KBqsOVLqj6E9Td14CGM3lT9IqBoXdgdB7aKUjWvVE",
+		},
 	}
 
 	for _, c := range cases {
@@ -421,6 +446,10 @@ func (ts *UserTestSuite) TestNewUserWithPasswordHashFailure() {
 			desc: "Invalid scrypt hash",
 			hash: "$fbscrypt$invalid",
 		},
+		{
+			desc: "Invalid PBKDF2 hash",
+			hash: "$pbkdf2-md5$i=1000$c2FsdA$c2FsdA",
+		},
 	}
 
 	for _, c := range cases {
@@ -449,6 +478,16 @@ func (ts *UserTestSuite) TestAuthenticate() {
 			hash:             "$2y$10$va66S4MxFrH6G6L7BzYl0.QgcYgvSr/F92gc.3botlz7bG4p/g/1i",
 			expectedHashCost: bcrypt.DefaultCost,
 		},
+		{
+			desc:             "Imported scrypt hash is migrated",
+			hash:             "$scrypt$ln=4,r=8,p=1$c2FsdHNhbHRzYWx0c2FsdA$Xm5Jhj+IG4YfthdtDVddQUEAe9RXODln+i0vrX/P38c",
+			expectedHashCost: bcrypt.MinCost,
+		},
+		{
+			desc:             "Imported PBKDF2 hash is migrated",
+			hash:             "$pbkdf2-sha512$i=1000,l=64$c2FsdHNhbHRzYWx0c2FsdA$dyidlA4OGvsctKcO0KZFOlGtA27staAA8mDrz6VzN3SelD3nnmE2WsnMdcGQvfaA2JIllqdSoSWAJNMZXPY7FQ",
+			expectedHashCost: bcrypt.MinCost,
+		},
 	}
 
 	for _, c := range cases {
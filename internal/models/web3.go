@@ -1,4 +1,65 @@
 package models
 
+import (
+	"database/sql"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+	"github.com/supabase/auth/internal/crypto"
+	"github.com/supabase/auth/internal/storage"
+)
+
 const Web3Provider = "web3"
 const Web3Grant = "web3"
+
+// web3NonceLength is the number of characters of a nonce, well above the 8
+// alphanumeric characters EIP-4361 requires.
+const web3NonceLength = 32
+
+// Web3NonceTimeout is how long a nonce can be signed into a message.
+const Web3NonceTimeout = 10 * time.Minute
+
+// Web3Nonce is a nonce issued to be signed into a Sign in with Ethereum or
+// Solana message, which is accepted once until it expires.
+type Web3Nonce struct {
+	ID        uuid.UUID `json:"-" db:"id"`
+	Nonce     string    `json:"nonce" db:"nonce"`
+	CreatedAt time.Time `json:"-" db:"created_at"`
+	ExpiresAt time.Time `json:"expires_at" db:"expires_at"`
+}
+
+func (Web3Nonce) TableName() string {
+	return "web3_nonces"
+}
+
+// NewWeb3Nonce issues a nonce expiring after Web3NonceTimeout.
+func NewWeb3Nonce(tx *storage.Connection) (*Web3Nonce, error) {
+	nonce := &Web3Nonce{
+		ID:        uuid.Must(uuid.NewV4()),
+		Nonce:     crypto.SecureAlphanumeric(web3NonceLength),
+		ExpiresAt: time.Now().Add(Web3NonceTimeout),
+	}
+
+	if err := tx.Create(nonce); err != nil {
+		return nil, errors.Wrap(err, "error creating web3 nonce")
+	}
+	return nonce, nil
+}
+
+// ConsumeWeb3Nonce deletes the nonce so it can't be used again, returning
+// Web3NonceNotFoundError when it wasn't issued, was already used or expired
+// before now.
+func ConsumeWeb3Nonce(tx *storage.Connection, nonce string, now time.Time) error {
+	obj := &Web3Nonce{}
+	if err := tx.RawQuery("DELETE FROM "+obj.TableName()+" WHERE nonce = ? RETURNING *", nonce).First(obj); err != nil {
+		if errors.Cause(err) == sql.ErrNoRows {
+			return Web3NonceNotFoundError{}
+		}
+		return errors.Wrap(err, "error deleting web3 nonce")
+	}
+	if !obj.ExpiresAt.After(now) {
+		return Web3NonceNotFoundError{}
+	}
+	return nil
+}
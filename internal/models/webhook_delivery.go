@@ -0,0 +1,258 @@
+package models
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+	"github.com/supabase/auth/internal/storage"
+)
+
+type WebhookDeliveryStatus string
+
+const (
+	// WebhookDeliveryPending deliveries are sent once their run_at time has
+	// passed.
+	WebhookDeliveryPending WebhookDeliveryStatus = "pending"
+
+	// WebhookDeliveryDelivered deliveries were accepted by the endpoint,
+	// they are kept in the delivery log until the retention passed.
+	WebhookDeliveryDelivered WebhookDeliveryStatus = "delivered"
+
+	// WebhookDeliveryDead deliveries failed too many times, they are kept
+	// until they are re-driven through the admin API.
+	WebhookDeliveryDead WebhookDeliveryStatus = "dead"
+)
+
+// WebhookPayload is the JSON body of a webhook delivery, it is sent as is.
+type WebhookPayload []byte
+
+func (p *WebhookPayload) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case []byte:
+		*p = append(WebhookPayload(nil), v...)
+	case string:
+		*p = WebhookPayload(v)
+	default:
+		return errors.New("scan source was not []byte")
+	}
+	return nil
+}
+
+func (p WebhookPayload) Value() (driver.Value, error) {
+	return string(p), nil
+}
+
+func (p WebhookPayload) MarshalJSON() ([]byte, error) {
+	if len(p) == 0 {
+		return []byte("null"), nil
+	}
+	return p, nil
+}
+
+// WebhookDelivery is the delivery of an event to a webhook endpoint. An
+// event sent to several endpoints has a delivery for each, with the same
+// event ID.
+type WebhookDelivery struct {
+	ID             uuid.UUID             `json:"id" db:"id"`
+	Status         WebhookDeliveryStatus `json:"status" db:"status"`
+	Endpoint       string                `json:"endpoint" db:"endpoint"`
+	EventID        uuid.UUID             `json:"event_id" db:"event_id"`
+	EventType      string                `json:"event_type" db:"event_type"`
+	UserID         *uuid.UUID            `json:"user_id,omitempty" db:"user_id"`
+	Payload        WebhookPayload        `json:"payload" db:"payload"`
+	Attempts       int                   `json:"attempts" db:"attempts"`
+	LastError      *string               `json:"last_error,omitempty" db:"last_error"`
+	LastStatusCode *int                  `json:"last_status_code,omitempty" db:"last_status_code"`
+
+	RunAt       time.Time  `json:"run_at" db:"run_at"`
+	DeliveredAt *time.Time `json:"delivered_at,omitempty" db:"delivered_at"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+func (WebhookDelivery) TableName() string {
+	tableName := "webhook_deliveries"
+	return tableName
+}
+
+// NewWebhookDelivery initializes a pending delivery of the event to the
+// endpoint, to be sent right away.
+func NewWebhookDelivery(endpoint string, eventID uuid.UUID, eventType string, userID *uuid.UUID, payload []byte) *WebhookDelivery {
+	return &WebhookDelivery{
+		ID:        uuid.Must(uuid.NewV4()),
+		Status:    WebhookDeliveryPending,
+		Endpoint:  endpoint,
+		EventID:   eventID,
+		EventType: eventType,
+		UserID:    userID,
+		Payload:   payload,
+		RunAt:     time.Now(),
+	}
+}
+
+// BeforeSave is invoked before the delivery is saved to the database.
+func (d *WebhookDelivery) BeforeSave(tx *pop.Connection) error {
+	d.UpdatedAt = time.Now()
+	return nil
+}
+
+// ClaimWebhookDeliveries claims up to limit pending deliveries that are due
+// and counts an attempt for each. Claimed deliveries are not due again until
+// lease has passed, so that a delivery is retried when the worker sending it
+// stops before recording the outcome. Concurrent workers claim different
+// deliveries.
+func ClaimWebhookDeliveries(tx *storage.Connection, limit int, lease time.Duration) ([]*WebhookDelivery, error) {
+	deliveries := []*WebhookDelivery{}
+	now := time.Now()
+
+	query := fmt.Sprintf(`update %[1]q
+set attempts = attempts + 1, run_at = ?, updated_at = ?
+where id in (
+	select id from %[1]q
+	where status = ? and run_at <= ?
+	order by run_at
+	limit ?
+	for update skip locked
+)
+returning *`, WebhookDelivery{}.TableName())
+
+	if err := tx.RawQuery(query, now.Add(lease), now, WebhookDeliveryPending, now, limit).All(&deliveries); err != nil {
+		return nil, errors.Wrap(err, "error claiming webhook deliveries")
+	}
+
+	return deliveries, nil
+}
+
+// Delivered records that the endpoint accepted the delivery.
+func (d *WebhookDelivery) Delivered(tx *storage.Connection, statusCode int) error {
+	now := time.Now()
+	d.Status = WebhookDeliveryDelivered
+	d.DeliveredAt = &now
+	d.LastStatusCode = &statusCode
+	d.LastError = nil
+
+	return tx.UpdateOnly(d, "status", "delivered_at", "last_status_code", "last_error", "updated_at")
+}
+
+// Failed records the error of the last attempt and the status code the
+// endpoint responded with, if any. The delivery is retried at retryAt, or
+// dead-lettered when retryAt is nil.
+func (d *WebhookDelivery) Failed(tx *storage.Connection, sendErr error, statusCode *int, retryAt *time.Time) error {
+	lastError := sendErr.Error()
+	d.LastError = &lastError
+	d.LastStatusCode = statusCode
+
+	if retryAt != nil {
+		d.RunAt = *retryAt
+	} else {
+		d.Status = WebhookDeliveryDead
+	}
+
+	return tx.UpdateOnly(d, "status", "last_error", "last_status_code", "run_at", "updated_at")
+}
+
+// WebhookDeliveryFilter selects deliveries from the delivery log, empty
+// fields match every delivery.
+type WebhookDeliveryFilter struct {
+	Status    WebhookDeliveryStatus
+	Endpoint  string
+	EventType string
+	UserID    *uuid.UUID
+}
+
+// FindWebhookDeliveries finds the deliveries matching the filter, newest
+// first.
+func FindWebhookDeliveries(tx *storage.Connection, filter WebhookDeliveryFilter, pageParams *Pagination) ([]*WebhookDelivery, error) {
+	deliveries := []*WebhookDelivery{}
+	q := tx.Q()
+
+	if filter.Status != "" {
+		q = q.Where("status = ?", filter.Status)
+	}
+	if filter.Endpoint != "" {
+		q = q.Where("endpoint = ?", filter.Endpoint)
+	}
+	if filter.EventType != "" {
+		q = q.Where("event_type = ?", filter.EventType)
+	}
+	if filter.UserID != nil {
+		q = q.Where("user_id = ?", *filter.UserID)
+	}
+	q = q.Order("created_at desc, id desc")
+
+	var err error
+	if pageParams != nil {
+		err = q.Paginate(int(pageParams.Page), int(pageParams.PerPage)).All(&deliveries) // #nosec G115
+		pageParams.Count = uint64(q.Paginator.TotalEntriesSize)                          // #nosec G115
+	} else {
+		err = q.All(&deliveries)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "error finding webhook deliveries")
+	}
+
+	return deliveries, nil
+}
+
+// FindWebhookDeliveryByID finds a delivery by its ID.
+func FindWebhookDeliveryByID(tx *storage.Connection, id uuid.UUID) (*WebhookDelivery, error) {
+	delivery := &WebhookDelivery{}
+	if err := tx.Find(delivery, id); err != nil {
+		if errors.Cause(err) == sql.ErrNoRows {
+			return nil, WebhookDeliveryNotFoundError{}
+		}
+		return nil, errors.Wrap(err, "error finding webhook delivery")
+	}
+	return delivery, nil
+}
+
+// RedriveWebhookDeliveries makes dead deliveries pending again with their
+// attempts reset, to be sent right away. Every dead delivery is re-driven
+// when ids is empty. It returns the number of deliveries that were
+// re-driven.
+func RedriveWebhookDeliveries(tx *storage.Connection, ids []uuid.UUID) (int, error) {
+	now := time.Now()
+
+	query := fmt.Sprintf(`update %q
+set status = ?, attempts = 0, run_at = ?, updated_at = ?
+where status = ?`, WebhookDelivery{}.TableName())
+	args := []interface{}{WebhookDeliveryPending, now, now, WebhookDeliveryDead}
+
+	if len(ids) > 0 {
+		query += " and id in (?" + strings.Repeat(", ?", len(ids)-1) + ")"
+		for _, id := range ids {
+			args = append(args, id)
+		}
+	}
+
+	count, err := tx.RawQuery(query, args...).ExecWithCount()
+	if err != nil {
+		return 0, errors.Wrap(err, "error re-driving webhook deliveries")
+	}
+
+	return count, nil
+}
+
+// DeleteDeliveredWebhookDeliveries deletes up to limit deliveries that were
+// delivered before the time. It returns the number of deliveries deleted.
+func DeleteDeliveredWebhookDeliveries(tx *storage.Connection, before time.Time, limit int) (int, error) {
+	query := fmt.Sprintf(`delete from %[1]q
+where id in (
+	select id from %[1]q
+	where status = ? and delivered_at < ?
+	limit ?
+)`, WebhookDelivery{}.TableName())
+
+	count, err := tx.RawQuery(query, WebhookDeliveryDelivered, before, limit).ExecWithCount()
+	if err != nil {
+		return 0, errors.Wrap(err, "error deleting delivered webhook deliveries")
+	}
+
+	return count, nil
+}
@@ -200,3 +200,49 @@ func ConfigureMetrics(ctx context.Context, mc *conf.MetricsConfig) error {
 
 	return err
 }
+
+// PrometheusHandler returns an HTTP handler serving the metrics collected by
+// the Prometheus exporter.
+func PrometheusHandler() http.Handler {
+	return promhttp.Handler()
+}
+
+// ObtainCachedGauge registers a gauge observing the value returned by fn. The
+// value is cached for the interval, so that fn is called at most once per
+// interval no matter how often metrics are collected. When fn fails the
+// previous value is observed.
+func ObtainCachedGauge(name, desc string, interval time.Duration, fn func(ctx context.Context) (int64, error)) error {
+	var (
+		mu         sync.Mutex
+		value      int64
+		observed   bool
+		observedAt time.Time
+	)
+
+	_, err := Meter("gotrue").Int64ObservableGauge(
+		name,
+		metric.WithDescription(desc),
+		metric.WithInt64Callback(func(ctx context.Context, obsrv metric.Int64Observer) error {
+			mu.Lock()
+			defer mu.Unlock()
+
+			if observedAt.IsZero() || time.Since(observedAt) >= interval {
+				v, err := fn(ctx)
+				if err != nil {
+					logrus.WithError(err).Errorf("unable to observe gotrue.%s gauge metric", name)
+				} else {
+					value = v
+					observed = true
+				}
+				// wait for the interval before retrying after an error too
+				observedAt = time.Now()
+			}
+
+			if observed {
+				obsrv.Observe(value)
+			}
+			return nil
+		}),
+	)
+	return err
+}
@@ -0,0 +1,123 @@
+package observability
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+func withManualReader(t *testing.T) *sdkmetric.ManualReader {
+	reader := sdkmetric.NewManualReader()
+	previous := otel.GetMeterProvider()
+	otel.SetMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)))
+	t.Cleanup(func() { otel.SetMeterProvider(previous) })
+	return reader
+}
+
+func findMetric(t *testing.T, reader *sdkmetric.ManualReader, name string) metricdata.Aggregation {
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(context.Background(), &rm))
+
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if m.Name == name {
+				return m.Data
+			}
+		}
+	}
+
+	t.Fatalf("metric %s was not collected", name)
+	return nil
+}
+
+func TestRequestDurationHistogram(t *testing.T) {
+	reader := withManualReader(t)
+
+	r := chi.NewRouter()
+	r.Use(RequestTracing())
+	r.Get("/admin/users/{user_id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	for i := 0; i < 2; i++ {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/admin/users/123", nil))
+	}
+
+	histogram, ok := findMetric(t, reader, "http_request_duration").(metricdata.Histogram[float64])
+	require.True(t, ok)
+	require.Len(t, histogram.DataPoints, 1)
+
+	dp := histogram.DataPoints[0]
+	require.Equal(t, uint64(2), dp.Count)
+	require.Equal(t, requestDurationBuckets, dp.Bounds)
+
+	route, _ := dp.Attributes.Value("http.route")
+	require.Equal(t, "/admin/users/{user_id}", route.AsString())
+	method, _ := dp.Attributes.Value("method")
+	require.Equal(t, http.MethodGet, method.AsString())
+	code, _ := dp.Attributes.Value(attribute.Key("code"))
+	require.Equal(t, int64(http.StatusNotFound), code.AsInt64())
+}
+
+func TestObtainCachedGauge(t *testing.T) {
+	reader := withManualReader(t)
+
+	calls := 0
+	require.NoError(t, ObtainCachedGauge("gotrue_test_cached", "Test gauge", time.Hour, func(context.Context) (int64, error) {
+		calls++
+		return int64(2 + calls), nil
+	}))
+
+	gaugeValue := func() int64 {
+		gauge, ok := findMetric(t, reader, "gotrue_test_cached").(metricdata.Gauge[int64])
+		require.True(t, ok)
+		require.Len(t, gauge.DataPoints, 1)
+		return gauge.DataPoints[0].Value
+	}
+
+	// the value is cached for the interval
+	require.Equal(t, int64(3), gaugeValue())
+	require.Equal(t, int64(3), gaugeValue())
+	require.Equal(t, 1, calls)
+}
+
+func TestObtainCachedGaugeError(t *testing.T) {
+	reader := withManualReader(t)
+
+	fail := true
+	require.NoError(t, ObtainCachedGauge("gotrue_test_cached_error", "Test gauge", 0, func(context.Context) (int64, error) {
+		if fail {
+			return 0, errors.New("unavailable")
+		}
+		return 7, nil
+	}))
+
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(context.Background(), &rm))
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			require.NotEqual(t, "gotrue_test_cached_error", m.Name, "nothing is observed before the first value")
+		}
+	}
+
+	fail = false
+	gauge, ok := findMetric(t, reader, "gotrue_test_cached_error").(metricdata.Gauge[int64])
+	require.True(t, ok)
+	require.Equal(t, int64(7), gauge.DataPoints[0].Value)
+
+	// the previous value is observed when fn fails
+	fail = true
+	gauge, ok = findMetric(t, reader, "gotrue_test_cached_error").(metricdata.Gauge[int64])
+	require.True(t, ok)
+	require.Equal(t, int64(7), gauge.DataPoints[0].Value)
+}
@@ -13,13 +13,30 @@ import (
 	"github.com/supabase/auth/internal/utilities"
 )
 
+// RequestIDHeaderName is the header the ID of a request is returned in, and
+// sent to the auth hooks called while handling it.
+const RequestIDHeaderName = "X-Request-Id"
+
+// maxRequestIDLength bounds the length of the request IDs taken from the
+// incoming requests, as they are logged and stored with audit entries.
+const maxRequestIDLength = 128
+
+// AddRequestID assigns an ID to every request, taken from the header named
+// by API.RequestIDHeader when it is set, or generated. The ID is added to
+// the context of the request, to its log lines, audit log entries, webhook
+// events and error responses, and returned in the X-Request-Id header.
 func AddRequestID(globalConfig *conf.GlobalConfiguration) func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		fn := func(w http.ResponseWriter, r *http.Request) {
-			id := uuid.Must(uuid.NewV4()).String()
+			var id string
 			if globalConfig.API.RequestIDHeader != "" {
 				id = r.Header.Get(globalConfig.API.RequestIDHeader)
 			}
+			if !isValidRequestID(id) {
+				id = uuid.Must(uuid.NewV4()).String()
+			}
+			w.Header().Set(RequestIDHeaderName, id)
+
 			ctx := r.Context()
 			ctx = utilities.WithRequestID(ctx, id)
 			next.ServeHTTP(w, r.WithContext(ctx))
@@ -28,6 +45,20 @@ func AddRequestID(globalConfig *conf.GlobalConfiguration) func(next http.Handler
 	}
 }
 
+// isValidRequestID reports whether id can be used as the ID of a request. It
+// must be at most maxRequestIDLength printable ASCII characters.
+func isValidRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
 func NewStructuredLogger(logger *logrus.Logger, config *conf.GlobalConfiguration) func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
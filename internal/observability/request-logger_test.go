@@ -6,12 +6,15 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/gofrs/uuid"
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/require"
 	"github.com/supabase/auth/internal/conf"
+	"github.com/supabase/auth/internal/utilities"
 )
 
 const apiTestConfig = "../../hack/test.env"
@@ -50,6 +53,44 @@ func TestLogger(t *testing.T) {
 	require.NotNil(t, logs["time"])
 }
 
+func TestAddRequestID(t *testing.T) {
+	config := &conf.GlobalConfiguration{}
+	config.API.RequestIDHeader = "X-Request-ID"
+
+	var requestID string
+	handler := AddRequestID(config)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID = utilities.GetRequestID(r.Context())
+	}))
+
+	cases := []struct {
+		desc     string
+		header   string
+		expected string
+	}{
+		{desc: "Incoming ID", header: "test-request-id", expected: "test-request-id"},
+		{desc: "Missing ID", header: ""},
+		{desc: "Invalid ID", header: "test request id"},
+		{desc: "Long ID", header: strings.Repeat("a", maxRequestIDLength+1)},
+	}
+	for _, c := range cases {
+		t.Run(c.desc, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "http://example.com/path", nil)
+			if c.header != "" {
+				req.Header.Set("X-Request-ID", c.header)
+			}
+			handler.ServeHTTP(w, req)
+
+			if c.expected != "" {
+				require.Equal(t, c.expected, requestID)
+			} else {
+				require.NotEqual(t, uuid.Nil, uuid.FromStringOrNil(requestID))
+			}
+			require.Equal(t, requestID, w.Header().Get(RequestIDHeaderName))
+		})
+	}
+}
+
 func TestExcludeHealthFromLogs(t *testing.T) {
 	var logBuffer bytes.Buffer
 	config, err := conf.LoadGlobal(apiTestConfig)
@@ -2,6 +2,7 @@ package observability
 
 import (
 	"net/http"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/sirupsen/logrus"
@@ -112,6 +113,50 @@ func countStatusCodesSafely(w *interceptingResponseWriter, r *http.Request, coun
 	)
 }
 
+// requestDurationBuckets are the bounds in seconds of the request duration
+// histogram, from fast token refreshes to slow password hashing and
+// outbound calls.
+var requestDurationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// recordRequestDurationSafely records the duration of the request per route,
+// method and status code. If it is not able to identify the route via
+// chi.RouteContext(ctx).RoutePattern() it records with a noroute attribute.
+func recordRequestDurationSafely(w *interceptingResponseWriter, r *http.Request, histogram metric.Float64Histogram, start time.Time) {
+	if histogram == nil {
+		return
+	}
+
+	duration := time.Since(start).Seconds()
+
+	defer func() {
+		if rec := recover(); rec != nil {
+			logrus.WithField("error", rec).Error("unable to record request duration safely, metrics may be off")
+			histogram.Record(
+				r.Context(),
+				duration,
+				metric.WithAttributes(
+					attribute.Bool("noroute", true),
+					attribute.String("method", r.Method),
+					attribute.Int("code", w.statusCode)),
+			)
+		}
+	}()
+
+	ctx := r.Context()
+
+	routeContext := chi.RouteContext(ctx)
+	routePattern := semconv.HTTPRouteKey.String(routeContext.RoutePattern())
+
+	histogram.Record(
+		ctx,
+		duration,
+		metric.WithAttributes(
+			routePattern,
+			attribute.String("method", r.Method),
+			attribute.Int("code", w.statusCode)),
+	)
+}
+
 // RequestTracing returns an HTTP handler that traces all HTTP requests coming
 // in. Supports Chi routers, so this should be one of the first middlewares on
 // the router.
@@ -125,15 +170,27 @@ func RequestTracing() func(http.Handler) http.Handler {
 		logrus.WithError(err).Error("unable to get gotrue.http_status_codes counter metric")
 	}
 
+	requestDuration, err := meter.Float64Histogram(
+		"http_request_duration",
+		metric.WithDescription("Duration of HTTP requests per route"),
+		metric.WithUnit("s"),
+		metric.WithExplicitBucketBoundaries(requestDurationBuckets...),
+	)
+	if err != nil {
+		logrus.WithError(err).Error("unable to get gotrue.http_request_duration histogram metric")
+	}
+
 	return func(next http.Handler) http.Handler {
 		fn := func(w http.ResponseWriter, r *http.Request) {
 			writer := interceptingResponseWriter{
 				writer: w,
 			}
+			start := time.Now()
 
 			defer traceChiRoutesSafely(r)
 			defer traceChiRouteURLParamsSafely(r)
 			defer countStatusCodesSafely(&writer, r, statusCodes)
+			defer recordRequestDurationSafely(&writer, r, requestDuration, start)
 
 			originalUserAgent := r.Header.Get("X-Gotrue-Original-User-Agent")
 			if originalUserAgent != "" {
@@ -3,6 +3,7 @@ package observability
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"sync"
 	"time"
 
@@ -10,8 +11,10 @@ import (
 	"github.com/supabase/auth/internal/conf"
 	"github.com/supabase/auth/internal/utilities"
 
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
 	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
 	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
 	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
@@ -25,6 +28,22 @@ func Tracer(name string, opts ...trace.TracerOption) trace.Tracer {
 	return otel.Tracer(name, opts...)
 }
 
+// StartSpan starts a span of the gotrue tracer as a child of the span in the
+// context, for an operation that may take a while, such as a call to another
+// service.
+func StartSpan(ctx context.Context, name string, attributes ...attribute.KeyValue) (context.Context, trace.Span) {
+	return Tracer("gotrue").Start(ctx, name, trace.WithAttributes(attributes...))
+}
+
+// EndSpan ends the span, recording the error when the operation failed.
+func EndSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
 func openTelemetryResource() *sdkresource.Resource {
 	environmentResource := sdkresource.Environment()
 	gotrueResource := sdkresource.NewSchemaless(attribute.String("gotrue.version", utilities.Version))
@@ -65,10 +84,17 @@ func enableOpenTelemetryTracing(ctx context.Context, tc *conf.TracingConfig) err
 	traceProvider := sdktrace.NewTracerProvider(
 		sdktrace.WithBatcher(traceExporter),
 		sdktrace.WithResource(openTelemetryResource()),
+		// Requests that are part of a sampled trace are always sampled.
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(tc.SampleRatio))),
 	)
 
 	otel.SetTracerProvider(traceProvider)
 
+	// Trace the outbound HTTP calls, such as to OAuth providers, SMS
+	// providers, mail APIs and hooks, and propagate the trace context to
+	// them. Their clients all use the default transport.
+	http.DefaultTransport = otelhttp.NewTransport(http.DefaultTransport)
+
 	// Register the W3C trace context and baggage propagators so data is
 	// propagated across services/processes
 	otel.SetTextMapPropagator(
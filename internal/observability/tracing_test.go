@@ -0,0 +1,44 @@
+package observability
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestStartSpan(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	defer otel.SetTracerProvider(previous)
+
+	ctx, parent := StartSpan(context.Background(), "parent")
+
+	_, span := StartSpan(ctx, "sms.send", attribute.String("gotrue.sms.provider", "twilio"))
+	EndSpan(span, errors.New("unavailable"))
+
+	_, span = StartSpan(ctx, "mailer.send")
+	EndSpan(span, nil)
+
+	EndSpan(parent, nil)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 3)
+
+	require.Equal(t, "sms.send", spans[0].Name())
+	require.Equal(t, parent.SpanContext().SpanID(), spans[0].Parent().SpanID())
+	require.Contains(t, spans[0].Attributes(), attribute.String("gotrue.sms.provider", "twilio"))
+	require.Equal(t, codes.Error, spans[0].Status().Code)
+	require.Equal(t, "unavailable", spans[0].Status().Description)
+	require.Len(t, spans[0].Events(), 1)
+
+	require.Equal(t, "mailer.send", spans[1].Name())
+	require.Equal(t, codes.Unset, spans[1].Status().Code)
+}
@@ -0,0 +1,113 @@
+// Package providertokens refreshes the stored access tokens of the OAuth
+// providers users signed in with, so that applications can keep calling the
+// provider APIs on behalf of the users.
+package providertokens
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/supabase/auth/internal/api/provider"
+	"github.com/supabase/auth/internal/conf"
+	"github.com/supabase/auth/internal/models"
+	"github.com/supabase/auth/internal/storage"
+	"golang.org/x/oauth2"
+)
+
+// ErrRefreshFailed is returned when the provider refused to refresh the
+// token. The user has to sign in with the provider again.
+var ErrRefreshFailed = errors.New("providertokens: the provider refused to refresh the token")
+
+// Refresh exchanges the refresh token of the token for new tokens with the
+// provider and saves them. The token should be locked by the transaction.
+// When the provider refuses the refresh token the error is recorded on the
+// token and ErrRefreshFailed is returned.
+func Refresh(ctx context.Context, tx *storage.Connection, config *conf.GlobalConfiguration, token *models.ProviderToken) error {
+	if token.RefreshToken == nil || token.RefreshError != nil {
+		return ErrRefreshFailed
+	}
+
+	refreshToken, err := token.GetRefreshToken(config.Security.DBEncryption)
+	if err != nil {
+		return fmt.Errorf("providertokens: error decrypting refresh token: %w", err)
+	}
+
+	p, _, err := provider.New(ctx, config, token.Provider, "")
+	if err != nil {
+		return fmt.Errorf("providertokens: error creating provider %q: %w", token.Provider, err)
+	}
+
+	refreshed, err := provider.RefreshOAuthToken(ctx, p, refreshToken)
+	if err != nil {
+		if !isRefused(err) {
+			return fmt.Errorf("providertokens: error refreshing token: %w", err)
+		}
+
+		if terr := token.UpdateRefreshError(tx, err); terr != nil {
+			return terr
+		}
+		return ErrRefreshFailed
+	}
+
+	var expiresAt *time.Time
+	if !refreshed.Expiry.IsZero() {
+		expiresAt = &refreshed.Expiry
+	}
+
+	return token.UpdateTokens(tx, refreshed.AccessToken, refreshed.RefreshToken, expiresAt, config.Security.DBEncryption)
+}
+
+// RefreshExpiring refreshes up to config.External.ProviderTokens.BatchSize
+// of the tokens expiring within RefreshBefore of now, except for the ones
+// updated since then. It returns the number of tokens it refreshed.
+func RefreshExpiring(
+	ctx context.Context,
+	db *storage.Connection,
+	config *conf.GlobalConfiguration,
+	now time.Time,
+	le *logrus.Entry,
+) (int, error) {
+	tokensConfig := &config.External.ProviderTokens
+	var refreshed int
+
+	err := db.WithContext(ctx).Transaction(func(tx *storage.Connection) error {
+		tokens, err := models.FindProviderTokensToRefresh(tx, now.Add(tokensConfig.RefreshBefore), now, tokensConfig.BatchSize)
+		if err != nil {
+			return err
+		}
+
+		for _, token := range tokens {
+			if err := Refresh(ctx, tx, config, token); err != nil {
+				le.WithError(err).WithFields(logrus.Fields{
+					"provider":    token.Provider,
+					"identity_id": token.IdentityID,
+				}).Warn("providertokens: failed to refresh provider token")
+				continue
+			}
+			refreshed++
+		}
+
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return refreshed, nil
+}
+
+// isRefused reports whether the provider responded to the refresh with a
+// client error, which is not worth retrying.
+func isRefused(err error) bool {
+	var retrieveErr *oauth2.RetrieveError
+	if !errors.As(err, &retrieveErr) || retrieveErr.Response == nil {
+		return false
+	}
+
+	code := retrieveErr.Response.StatusCode
+	return code >= http.StatusBadRequest && code < http.StatusInternalServerError && code != http.StatusTooManyRequests
+}
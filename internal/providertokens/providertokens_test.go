@@ -0,0 +1,36 @@
+package providertokens
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/supabase/auth/internal/conf"
+	"github.com/supabase/auth/internal/models"
+	"golang.org/x/oauth2"
+)
+
+func TestIsRefused(t *testing.T) {
+	cases := []struct {
+		err     error
+		refused bool
+	}{
+		{err: errors.New("connection refused"), refused: false},
+		{err: &oauth2.RetrieveError{Response: &http.Response{StatusCode: http.StatusBadRequest}}, refused: true},
+		{err: fmt.Errorf("wrapped: %w", &oauth2.RetrieveError{Response: &http.Response{StatusCode: http.StatusUnauthorized}}), refused: true},
+		{err: &oauth2.RetrieveError{Response: &http.Response{StatusCode: http.StatusTooManyRequests}}, refused: false},
+		{err: &oauth2.RetrieveError{Response: &http.Response{StatusCode: http.StatusBadGateway}}, refused: false},
+	}
+
+	for _, tc := range cases {
+		require.Equal(t, tc.refused, isRefused(tc.err), tc.err.Error())
+	}
+}
+
+func TestRefreshWithoutRefreshToken(t *testing.T) {
+	err := Refresh(context.Background(), nil, &conf.GlobalConfiguration{}, &models.ProviderToken{Provider: "github"})
+	require.ErrorIs(t, err, ErrRefreshFailed)
+}
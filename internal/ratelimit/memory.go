@@ -0,0 +1,83 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/supabase/auth/internal/conf"
+)
+
+// memorySweepInterval is how often expired counters are removed.
+const memorySweepInterval = time.Minute
+
+type memoryCounter struct {
+	window time.Duration
+	index  int64 // Index of the current fixed window.
+	prev   int64 // Events in the previous fixed window.
+	curr   int64 // Events in the current fixed window.
+}
+
+// MemoryStore is a Store that keeps the counters in the memory of the current
+// process.
+type MemoryStore struct {
+	mu sync.Mutex
+
+	// Guarded by mu.
+	counters  map[string]*memoryCounter
+	lastSweep time.Time
+}
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		counters: make(map[string]*memoryCounter),
+	}
+}
+
+// AllowAt implements Store.
+func (s *MemoryStore) AllowAt(ctx context.Context, key string, r conf.Rate, at time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sweep(at)
+
+	index, weight := slidingWindow(at, r.OverTime)
+
+	c, ok := s.counters[key]
+	if !ok || c.window != r.OverTime {
+		c = &memoryCounter{window: r.OverTime, index: index}
+		s.counters[key] = c
+	}
+
+	switch index - c.index {
+	case 0:
+	case 1:
+		c.prev, c.curr = c.curr, 0
+		c.index = index
+	default:
+		c.prev, c.curr = 0, 0
+		c.index = index
+	}
+
+	if slidingCount(c.prev, c.curr, weight) >= r.Events {
+		return false, nil
+	}
+
+	c.curr++
+	return true, nil
+}
+
+// sweep removes the counters that can no longer affect a sliding window.
+func (s *MemoryStore) sweep(at time.Time) {
+	if at.Sub(s.lastSweep) < memorySweepInterval {
+		return
+	}
+	s.lastSweep = at
+
+	for key, c := range s.counters {
+		if index, _ := slidingWindow(at, c.window); index-c.index > 1 {
+			delete(s.counters, key)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/supabase/auth/internal/conf"
+	"github.com/supabase/auth/internal/redisclient"
+)
+
+// redisSlidingWindowScript increments the counter of the current fixed window
+// only when the estimated number of events in the sliding window is below the
+// limit. KEYS[1] is the counter of the current window and KEYS[2] the counter
+// of the previous window.
+const redisSlidingWindowScript = `
+local curr = tonumber(redis.call('GET', KEYS[1]) or '0')
+local prev = tonumber(redis.call('GET', KEYS[2]) or '0')
+if prev * tonumber(ARGV[2]) + curr >= tonumber(ARGV[1]) then
+	return 0
+end
+redis.call('INCR', KEYS[1])
+redis.call('PEXPIRE', KEYS[1], ARGV[3])
+return 1
+`
+
+var redisSlidingWindow = redis.NewScript(redisSlidingWindowScript)
+
+// RedisStore is a Store that keeps the counters in Redis, so that they are
+// shared by every replica using the same Redis server.
+type RedisStore struct {
+	Prefix string
+
+	client *redis.Client
+}
+
+// NewRedisStore returns a RedisStore for a URL accepted by redisclient.New.
+func NewRedisStore(rawURL, prefix string) (*RedisStore, error) {
+	client, err := redisclient.New(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	return &RedisStore{Prefix: prefix, client: client}, nil
+}
+
+// AllowAt implements Store.
+func (s *RedisStore) AllowAt(ctx context.Context, key string, r conf.Rate, at time.Time) (bool, error) {
+	index, weight := slidingWindow(at, r.OverTime)
+
+	// the counters of both windows share a hash tag, so that they are kept in
+	// the same slot of a Redis cluster as required by the script
+	key = s.Prefix + "{" + key + ":" + strconv.FormatInt(int64(r.OverTime/time.Millisecond), 10) + "}"
+	allowed, err := redisSlidingWindow.Run(ctx, s.client,
+		[]string{
+			key + ":" + strconv.FormatInt(index, 10),
+			key + ":" + strconv.FormatInt(index-1, 10),
+		},
+		strconv.FormatFloat(r.Events, 'f', -1, 64),
+		strconv.FormatFloat(weight, 'f', 6, 64),
+		// the counter is needed while the window is current or previous
+		strconv.FormatInt(int64(2*r.OverTime/time.Millisecond), 10),
+	).Int64()
+	if err != nil {
+		return false, fmt.Errorf("redis: %w", err)
+	}
+
+	return allowed == 1, nil
+}
@@ -0,0 +1,48 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+
+	"github.com/supabase/auth/internal/conf"
+)
+
+// Store keeps rate limit counters for arbitrary keys.
+//
+// Implementations of Store must be safe for concurrent use.
+type Store interface {
+
+	// AllowAt records an event for key at the given time and returns true if
+	// the event is within the limit of r.Events per r.OverTime, using a
+	// sliding window. Denied events are not counted.
+	AllowAt(ctx context.Context, key string, r conf.Rate, at time.Time) (bool, error)
+}
+
+// NewStore returns a new Store based on the given config.
+//
+// When the type is conf.RateLimitStoreRedis it returns a RedisStore, otherwise
+// NewStore returns a MemoryStore.
+func NewStore(c conf.RateLimitStoreConfiguration) (Store, error) {
+	switch c.Type {
+	case conf.RateLimitStoreRedis:
+		return NewRedisStore(c.RedisURL, c.RedisKeyPrefix)
+	default:
+		return NewMemoryStore(), nil
+	}
+}
+
+// slidingWindow returns the index of the fixed window containing at and the
+// weight of the previous window, which is the fraction of the previous window
+// that still overlaps with the sliding window ending at at.
+func slidingWindow(at time.Time, window time.Duration) (int64, float64) {
+	nanos := at.UnixNano()
+	index := nanos / int64(window)
+	elapsed := float64(nanos%int64(window)) / float64(window)
+	return index, 1 - elapsed
+}
+
+// slidingCount estimates the number of events in the sliding window from the
+// counts of the current and previous fixed windows.
+func slidingCount(prev, curr int64, weight float64) float64 {
+	return float64(prev)*weight + float64(curr)
+}
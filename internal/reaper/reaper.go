@@ -0,0 +1,121 @@
+// Package reaper prunes the rows which expired, were revoked or were
+// consumed longer than their retention ago, such as revoked refresh tokens
+// and ended sessions.
+package reaper
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/supabase/auth/internal/conf"
+	"github.com/supabase/auth/internal/models"
+	"github.com/supabase/auth/internal/observability"
+	"github.com/supabase/auth/internal/storage"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+var removedRowsCounter = observability.ObtainMetricCounter("gotrue_reaper_removed_rows", "Number of expired rows removed by the reaper by kind")
+
+// Kind is a kind of rows the reaper prunes.
+type Kind string
+
+const (
+	KindRefreshTokens    Kind = "refresh_tokens"
+	KindOneTimeTokens    Kind = "one_time_tokens"
+	KindUnconfirmedUsers Kind = "unconfirmed_users"
+	KindSessions         Kind = "sessions"
+	KindFlowStates       Kind = "flow_states"
+)
+
+type pruner struct {
+	kind      Kind
+	retention time.Duration
+	delete    func(tx *storage.Connection, before time.Time, limit int) (int, error)
+}
+
+func pruners(config *conf.GlobalConfiguration) []pruner {
+	deleteSessions := func(tx *storage.Connection, before time.Time, limit int) (int, error) {
+		return models.DeleteSessionsEndedBefore(tx, &config.Sessions, before, limit)
+	}
+
+	return []pruner{
+		{KindRefreshTokens, config.Reaper.RefreshTokenRetention, models.DeleteRevokedRefreshTokens},
+		{KindOneTimeTokens, oneTimeTokenRetention(config), models.DeleteOneTimeTokensCreatedBefore},
+		{KindUnconfirmedUsers, config.Reaper.UnconfirmedUserRetention, models.DeleteUnconfirmedUsers},
+		{KindSessions, config.Reaper.SessionRetention, deleteSessions},
+		{KindFlowStates, config.Reaper.FlowStateRetention, models.DeleteFlowStatesCreatedBefore},
+	}
+}
+
+// oneTimeTokenRetention extends the retention of one-time tokens to the
+// longest time a link or code is valid for, as they are pruned by their
+// creation time and must not be pruned while they can still be used.
+func oneTimeTokenRetention(config *conf.GlobalConfiguration) time.Duration {
+	retention := config.Reaper.OneTimeTokenRetention
+	if retention <= 0 {
+		return retention
+	}
+
+	return max(
+		retention,
+		time.Duration(config.Mailer.OtpExp)*time.Second,
+		time.Duration(config.Mailer.InviteExp)*time.Second,
+		time.Duration(config.Sms.OtpExp)*time.Second,
+	)
+}
+
+// Prune deletes the rows of every kind with a retention which expired more
+// than their retention before now, in batches of config.Reaper.BatchSize rows
+// until none is left or ctx is done. It returns the number of rows it deleted
+// by kind, including those of the kinds pruned before an error. Sessions end
+// with the timebox and inactivity timeout of the sessions configuration.
+func Prune(
+	ctx context.Context,
+	db *storage.Connection,
+	config *conf.GlobalConfiguration,
+	now time.Time,
+	le *logrus.Entry,
+) (map[Kind]int, error) {
+	removed := make(map[Kind]int)
+	batchSize := config.Reaper.BatchSize
+
+	for _, p := range pruners(config) {
+		if p.retention <= 0 {
+			continue
+		}
+
+		before := now.Add(-p.retention)
+		for ctx.Err() == nil {
+			var n int
+			err := db.WithContext(ctx).Transaction(func(tx *storage.Connection) error {
+				var terr error
+				n, terr = p.delete(tx, before, batchSize)
+				return terr
+			})
+			if err != nil {
+				return removed, err
+			}
+
+			if n > 0 {
+				removed[p.kind] += n
+				removedRowsCounter.Add(ctx, int64(n), metric.WithAttributes(
+					attribute.String("kind", string(p.kind)),
+				))
+			}
+			if n < batchSize {
+				break
+			}
+		}
+
+		if removed[p.kind] > 0 {
+			le.WithFields(logrus.Fields{
+				"kind":    p.kind,
+				"removed": removed[p.kind],
+			}).Info("reaper: removed expired rows")
+		}
+	}
+
+	return removed, ctx.Err()
+}
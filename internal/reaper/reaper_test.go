@@ -0,0 +1,39 @@
+package reaper
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/supabase/auth/internal/conf"
+)
+
+func TestOneTimeTokenRetention(t *testing.T) {
+	config := &conf.GlobalConfiguration{}
+	config.Reaper.OneTimeTokenRetention = 24 * time.Hour
+	config.Mailer.OtpExp = 3600
+	config.Mailer.InviteExp = 3600
+	config.Sms.OtpExp = 60
+	require.Equal(t, 24*time.Hour, oneTimeTokenRetention(config))
+
+	// invites and OTPs valid for longer than the retention are kept until
+	// they expire
+	config.Mailer.InviteExp = 7 * 24 * 3600
+	require.Equal(t, 7*24*time.Hour, oneTimeTokenRetention(config))
+
+	config.Mailer.OtpExp = 8 * 24 * 3600
+	require.Equal(t, 8*24*time.Hour, oneTimeTokenRetention(config))
+
+	config.Sms.OtpExp = 9 * 24 * 3600
+	require.Equal(t, 9*24*time.Hour, oneTimeTokenRetention(config))
+
+	// a retention of 0 keeps the tokens
+	config.Reaper.OneTimeTokenRetention = 0
+	require.Equal(t, time.Duration(0), oneTimeTokenRetention(config))
+
+	for _, p := range pruners(config) {
+		if p.kind == KindOneTimeTokens {
+			require.Equal(t, time.Duration(0), p.retention)
+		}
+	}
+}
@@ -0,0 +1,33 @@
+// Package redisclient creates the Redis clients shared by the stores kept in
+// Redis, such as the rate limit counters and the cache.
+package redisclient
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	defaultTimeout = 2 * time.Second
+	maxIdleConns   = 16
+)
+
+// New returns a Redis client for a URL in the form
+// redis://[user:password@]host:port[/db], use the rediss scheme to connect
+// with TLS. Connections are established lazily.
+func New(rawURL string) (*redis.Client, error) {
+	opts, err := redis.ParseURL(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("redis: invalid URL: %w", err)
+	}
+
+	opts.DialTimeout = defaultTimeout
+	opts.ReadTimeout = defaultTimeout
+	opts.WriteTimeout = defaultTimeout
+	opts.MaxIdleConns = maxIdleConns
+	opts.DisableIdentity = true
+
+	return redis.NewClient(opts), nil
+}
@@ -0,0 +1,21 @@
+package redisclient
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	mr := miniredis.RunT(t)
+
+	client, err := New("redis://" + mr.Addr() + "/0")
+	require.NoError(t, err)
+	defer client.Close()
+	require.NoError(t, client.Ping(context.Background()).Err())
+
+	_, err = New("http://" + mr.Addr())
+	require.ErrorContains(t, err, "redis: invalid URL")
+}
@@ -8,6 +8,7 @@ import (
 	"io/fs"
 	"maps"
 	"os"
+	"path/filepath"
 	"strings"
 	"sync"
 	"time"
@@ -165,6 +166,11 @@ func (o *poller) scanFile(
 func (o *poller) scanEntries(ps *pollerState, ents []fs.DirEntry) {
 	for _, ent := range ents {
 		fi, err := ent.Info()
+		if err == nil && fi.Mode()&fs.ModeSymlink != 0 {
+			// Follow symlinks, such as the files of Kubernetes secret and
+			// config map volumes, which change when their target does.
+			fi, err = os.Stat(filepath.Join(o.dir, ent.Name()))
+		}
 		if err != nil {
 			continue
 		}
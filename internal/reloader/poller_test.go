@@ -91,6 +91,40 @@ func TestPoller(t *testing.T) {
 		}
 	})
 
+	t.Run("Symlinks", func(t *testing.T) {
+		dir, cleanup := helpTestDir(t)
+		defer cleanup()
+
+		// lay out the dir like a kubernetes secret volume, where the files
+		// link into a data dir which is swapped on updates
+		writeData := func(name, port string) {
+			require.NoError(t, os.MkdirAll(path.Join(dir, name), 0750))
+			helpWriteEnvFile(t, path.Join(dir, name), "conf.env", map[string]string{
+				"GOTRUE_SMTP_PORT": port,
+			})
+			tmp := path.Join(dir, "..data_tmp")
+			require.NoError(t, os.Symlink(name, tmp))
+			require.NoError(t, os.Rename(tmp, path.Join(dir, "..data")))
+		}
+		writeData("..v1", "1000")
+		require.NoError(t, os.Symlink("..data/conf.env", path.Join(dir, "conf.env")))
+
+		// the first poll takes the initial snapshot
+		pr := newPoller(dir)
+		changes, err := pr.poll(ctx)
+		require.NoError(t, err)
+		require.False(t, changes)
+
+		changes, err = pr.poll(ctx)
+		require.NoError(t, err)
+		require.False(t, changes)
+
+		writeData("..v2", "10000")
+		changes, err = pr.poll(ctx)
+		require.NoError(t, err)
+		require.True(t, changes)
+	})
+
 	t.Run("InvalidDir", func(t *testing.T) {
 		dir, cleanup := helpTestDir(t)
 		defer cleanup()
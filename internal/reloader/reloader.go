@@ -6,6 +6,7 @@ import (
 	"errors"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"strings"
 	"sync"
 	"syscall"
@@ -21,6 +22,11 @@ const (
 
 	// tickerInterval is the maximum latency between configuration reloads.
 	tickerInterval = time.Second
+
+	// atomicDataDir is the symlink Kubernetes swaps to update the files of
+	// secret and config map volumes at once. The .env files are symlinks
+	// into it, so no event names them when they are updated.
+	atomicDataDir = "..data"
 )
 
 type ConfigFunc func(*conf.GlobalConfiguration)
@@ -259,7 +265,7 @@ func (rl *Reloader) watchNotify(
 			}
 
 			// We only read files ending in .env
-			if !strings.HasSuffix(evt.Name, ".env") {
+			if !strings.HasSuffix(evt.Name, ".env") && filepath.Base(evt.Name) != atomicDataDir {
 				continue
 			}
 
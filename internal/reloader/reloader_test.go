@@ -594,6 +594,22 @@ func TestWatchNotify(t *testing.T) {
 			require.Equal(t, cfg.SMTP.Port, 2221)
 		}
 
+		// test the swap of the data dir of a kubernetes volume triggers a reload
+		{
+			helpWriteEnvFile(t, dir, "06_example.env", map[string]string{
+				"GOTRUE_SMTP_PORT": "2223",
+			})
+			wr.eventCh <- fsnotify.Event{
+				Name: filepath.Join(dir, atomicDataDir),
+				Op:   fsnotify.Create,
+			}
+
+			cfg, err := ctxSelect(egCtx, rr.configCh)
+			require.NoError(t, err)
+			require.NotNil(t, cfg)
+			require.Equal(t, cfg.SMTP.Port, 2223)
+		}
+
 		// test cases ran, end context to unblock Wait()
 		egCancel()
 
@@ -0,0 +1,111 @@
+// Package risk scores the risk of sign-ins from the signals they show and
+// decides the action to take on them.
+package risk
+
+import (
+	"github.com/supabase/auth/internal/conf"
+)
+
+// Signal is a sign of a suspicious sign-in.
+type Signal string
+
+const (
+	// SignalImpossibleTravel is a sign-in from another country than the
+	// previous sign-in of the user, within the impossible travel window.
+	SignalImpossibleTravel Signal = "impossible_travel"
+
+	// SignalNewDevice is a sign-in from a user agent the user has not
+	// signed in with before.
+	SignalNewDevice Signal = "new_device"
+
+	// SignalTor is a sign-in from an address of the Tor IP list.
+	SignalTor Signal = "tor"
+
+	// SignalDataCenter is a sign-in from an address of the data center IP
+	// list.
+	SignalDataCenter Signal = "data_center"
+
+	// SignalVelocity is a sign-in of a user who signed in too many times
+	// within the velocity window.
+	SignalVelocity Signal = "velocity"
+)
+
+// Action is what is done with a sign-in.
+type Action string
+
+const (
+	ActionAllow                    Action = "allow"
+	ActionRequireMFA               Action = "require_mfa"
+	ActionRequireEmailVerification Action = "require_email_verification"
+	ActionBlock                    Action = "block"
+)
+
+// maxScore is the highest risk score.
+const maxScore = 100
+
+// Assessment is the outcome of scoring a sign-in.
+type Assessment struct {
+	Score   int      `json:"score"`
+	Signals []Signal `json:"signals"`
+	Action  Action   `json:"action"`
+}
+
+// Assess scores the signals of a sign-in and decides its action, which is
+// the action of the highest threshold the score reaches.
+func Assess(config *conf.RiskConfiguration, signals []Signal) *Assessment {
+	score := 0
+	for _, signal := range signals {
+		score += signalScore(config, signal)
+	}
+	if score > maxScore {
+		score = maxScore
+	}
+
+	return &Assessment{
+		Score:   score,
+		Signals: signals,
+		Action:  actionFor(config, score),
+	}
+}
+
+func signalScore(config *conf.RiskConfiguration, signal Signal) int {
+	switch signal {
+	case SignalImpossibleTravel:
+		return config.ImpossibleTravelScore
+	case SignalNewDevice:
+		return config.NewDeviceScore
+	case SignalTor:
+		return config.TorScore
+	case SignalDataCenter:
+		return config.DataCenterScore
+	case SignalVelocity:
+		return config.VelocityScore
+	default:
+		return 0
+	}
+}
+
+func actionFor(config *conf.RiskConfiguration, score int) Action {
+	action := ActionAllow
+	threshold := 0
+
+	for _, candidate := range []struct {
+		action    Action
+		threshold int
+	}{
+		{ActionRequireMFA, config.RequireMFAScore},
+		{ActionRequireEmailVerification, config.RequireEmailVerificationScore},
+		{ActionBlock, config.BlockScore},
+	} {
+		if candidate.threshold == 0 || score < candidate.threshold {
+			continue
+		}
+		// ties go to the stricter action
+		if candidate.threshold >= threshold {
+			action = candidate.action
+			threshold = candidate.threshold
+		}
+	}
+
+	return action
+}
@@ -0,0 +1,49 @@
+package risk
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/supabase/auth/internal/conf"
+)
+
+func TestAssess(t *testing.T) {
+	config := &conf.RiskConfiguration{
+		ImpossibleTravelScore:         60,
+		NewDeviceScore:                20,
+		TorScore:                      50,
+		DataCenterScore:               30,
+		VelocityScore:                 40,
+		RequireMFAScore:               40,
+		RequireEmailVerificationScore: 60,
+		BlockScore:                    90,
+	}
+
+	cases := []struct {
+		signals []Signal
+		score   int
+		action  Action
+	}{
+		{nil, 0, ActionAllow},
+		{[]Signal{SignalNewDevice}, 20, ActionAllow},
+		{[]Signal{SignalNewDevice, SignalDataCenter}, 50, ActionRequireMFA},
+		{[]Signal{SignalImpossibleTravel}, 60, ActionRequireEmailVerification},
+		{[]Signal{SignalTor, SignalVelocity}, 90, ActionBlock},
+		{[]Signal{SignalImpossibleTravel, SignalTor, SignalVelocity}, 100, ActionBlock},
+	}
+	for _, c := range cases {
+		assessment := Assess(config, c.signals)
+		require.Equal(t, c.score, assessment.Score, c.signals)
+		require.Equal(t, c.action, assessment.Action, c.signals)
+	}
+
+	// disabled actions are skipped
+	config.RequireEmailVerificationScore = 0
+	require.Equal(t, ActionRequireMFA, Assess(config, []Signal{SignalImpossibleTravel}).Action)
+
+	// the order of the actions follows their thresholds
+	config.RequireMFAScore = 80
+	config.RequireEmailVerificationScore = 40
+	require.Equal(t, ActionRequireEmailVerification, Assess(config, []Signal{SignalImpossibleTravel}).Action)
+	require.Equal(t, ActionRequireMFA, Assess(config, []Signal{SignalImpossibleTravel, SignalNewDevice}).Action)
+}
@@ -0,0 +1,106 @@
+package secrets
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/supabase/auth/internal/utilities"
+)
+
+const awsSecretsManagerService = "secretsmanager"
+
+// AWSSecretsManager reads secrets from AWS Secrets Manager. The path of a
+// reference is the name or ARN of the secret. The region defaults to the one
+// of the ARN, AWS_REGION or AWS_DEFAULT_REGION, and the credentials to
+// AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN, or the
+// role of the web identity, ECS task or EC2 instance.
+type AWSSecretsManager struct {
+	Region      string
+	Credentials utilities.AWSCredentials
+
+	// Endpoint replaces the endpoint of the region, and STSEndpoint,
+	// ContainerEndpoint and MetadataEndpoint the endpoints the credentials
+	// of roles are read from.
+	Endpoint          string
+	STSEndpoint       string
+	ContainerEndpoint string
+	MetadataEndpoint  string
+	Client            *http.Client
+
+	now func() time.Time
+
+	mu        sync.Mutex
+	temporary *awsTemporaryCredentials // Guarded by mu.
+}
+
+func (a *AWSSecretsManager) timeNow() time.Time {
+	if a.now != nil {
+		return a.now()
+	}
+	return time.Now()
+}
+
+// FetchSecret returns the current version of the secret.
+func (a *AWSSecretsManager) FetchSecret(ctx context.Context, path string) (string, error) {
+	region := a.Region
+	if region == "" && strings.HasPrefix(path, "arn:") {
+		if parts := strings.Split(path, ":"); len(parts) > 3 {
+			region = parts[3]
+		}
+	}
+	region = cmp.Or(region, os.Getenv("AWS_REGION"), os.Getenv("AWS_DEFAULT_REGION"))
+	if region == "" {
+		return "", errors.New("aws-secretsmanager: AWS_REGION is not set")
+	}
+
+	credentials, err := a.credentials(ctx, region)
+	if err != nil {
+		return "", err
+	}
+
+	payload, err := json.Marshal(map[string]string{"SecretId": path})
+	if err != nil {
+		return "", err
+	}
+
+	endpoint := cmp.Or(a.Endpoint, "https://secretsmanager."+region+".amazonaws.com/")
+	req, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "secretsmanager.GetSecretValue")
+
+	utilities.SignAWSRequest(req, string(payload), awsSecretsManagerService, region, credentials, a.timeNow())
+
+	body, err := do(ctx, a.Client, req)
+	if err != nil {
+		return "", err
+	}
+
+	var res struct {
+		SecretString *string `json:"SecretString"`
+		SecretBinary string  `json:"SecretBinary"`
+	}
+	if err := json.Unmarshal(body, &res); err != nil {
+		return "", err
+	}
+	if res.SecretString != nil {
+		return *res.SecretString, nil
+	}
+
+	b, err := base64.StdEncoding.DecodeString(res.SecretBinary)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
@@ -0,0 +1,250 @@
+package secrets
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/supabase/auth/internal/utilities"
+)
+
+const (
+	awsMetadataEndpoint  = "http://169.254.169.254"
+	awsContainerEndpoint = "http://169.254.170.2"
+
+	// awsCredentialsExpiryWindow is how long before they expire temporary
+	// credentials are replaced.
+	awsCredentialsExpiryWindow = 5 * time.Minute
+)
+
+// awsTemporaryCredentials are credentials of a role, which expire.
+type awsTemporaryCredentials struct {
+	credentials utilities.AWSCredentials
+	expiration  time.Time
+}
+
+// awsCredentialsResponse is the response of the ECS container credentials
+// endpoint and the EC2 instance metadata service.
+type awsCredentialsResponse struct {
+	AccessKeyID     string    `json:"AccessKeyId"`
+	SecretAccessKey string    `json:"SecretAccessKey"`
+	Token           string    `json:"Token"`
+	Expiration      time.Time `json:"Expiration"`
+}
+
+func (r *awsCredentialsResponse) temporaryCredentials() (*awsTemporaryCredentials, error) {
+	if r.AccessKeyID == "" || r.SecretAccessKey == "" {
+		return nil, errors.New("the response has no credentials")
+	}
+	return &awsTemporaryCredentials{
+		credentials: utilities.AWSCredentials{
+			AccessKeyID:     r.AccessKeyID,
+			SecretAccessKey: r.SecretAccessKey,
+			SessionToken:    r.Token,
+		},
+		expiration: r.Expiration,
+	}, nil
+}
+
+// credentials returns the credentials of the first source providing them,
+// like the official clients: the Credentials field, AWS_ACCESS_KEY_ID and
+// AWS_SECRET_ACCESS_KEY, a web identity token such as the ones of IAM roles
+// for service accounts on EKS, the ECS container credentials endpoint and
+// the EC2 instance profile. Temporary credentials are reused until shortly
+// before they expire.
+func (a *AWSSecretsManager) credentials(ctx context.Context, region string) (utilities.AWSCredentials, error) {
+	if a.Credentials.AccessKeyID != "" {
+		return a.Credentials, nil
+	}
+
+	if accessKeyID := os.Getenv("AWS_ACCESS_KEY_ID"); accessKeyID != "" {
+		credentials := utilities.AWSCredentials{
+			AccessKeyID:     accessKeyID,
+			SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+			SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+		}
+		if credentials.SecretAccessKey == "" {
+			return utilities.AWSCredentials{}, errors.New("aws-secretsmanager: AWS_SECRET_ACCESS_KEY is not set")
+		}
+		return credentials, nil
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	now := a.timeNow()
+	if a.temporary != nil && now.Add(awsCredentialsExpiryWindow).Before(a.temporary.expiration) {
+		return a.temporary.credentials, nil
+	}
+
+	var temporary *awsTemporaryCredentials
+	var err error
+	switch {
+	case os.Getenv("AWS_WEB_IDENTITY_TOKEN_FILE") != "":
+		temporary, err = a.assumeRoleWithWebIdentity(ctx, region)
+		if err != nil {
+			err = errors.New("aws-secretsmanager: unable to assume the role of the web identity: " + err.Error())
+		}
+	case os.Getenv("AWS_CONTAINER_CREDENTIALS_RELATIVE_URI") != "" || os.Getenv("AWS_CONTAINER_CREDENTIALS_FULL_URI") != "":
+		temporary, err = a.containerCredentials(ctx)
+		if err != nil {
+			err = errors.New("aws-secretsmanager: unable to get the credentials of the container: " + err.Error())
+		}
+	default:
+		temporary, err = a.instanceProfileCredentials(ctx)
+		if err != nil {
+			err = errors.New("aws-secretsmanager: AWS_ACCESS_KEY_ID is not set and the instance metadata service is not available: " + err.Error())
+		}
+	}
+	if err != nil {
+		return utilities.AWSCredentials{}, err
+	}
+
+	a.temporary = temporary
+	return temporary.credentials, nil
+}
+
+// assumeRoleWithWebIdentity exchanges the token in AWS_WEB_IDENTITY_TOKEN_FILE
+// for the credentials of the role AWS_ROLE_ARN with STS.
+func (a *AWSSecretsManager) assumeRoleWithWebIdentity(ctx context.Context, region string) (*awsTemporaryCredentials, error) {
+	roleARN := os.Getenv("AWS_ROLE_ARN")
+	if roleARN == "" {
+		return nil, errors.New("AWS_ROLE_ARN is not set")
+	}
+
+	token, err := os.ReadFile(os.Getenv("AWS_WEB_IDENTITY_TOKEN_FILE"))
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{
+		"Action":           {"AssumeRoleWithWebIdentity"},
+		"Version":          {"2011-06-15"},
+		"RoleArn":          {roleARN},
+		"RoleSessionName":  {cmp.Or(os.Getenv("AWS_ROLE_SESSION_NAME"), "gotrue-"+strconv.FormatInt(a.timeNow().Unix(), 10))},
+		"WebIdentityToken": {strings.TrimSpace(string(token))},
+	}
+
+	endpoint := cmp.Or(a.STSEndpoint, "https://sts."+region+".amazonaws.com/")
+	req, err := http.NewRequest(http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	body, err := do(ctx, a.Client, req)
+	if err != nil {
+		return nil, err
+	}
+
+	var res struct {
+		Credentials struct {
+			AccessKeyID     string    `xml:"AccessKeyId"`
+			SecretAccessKey string    `xml:"SecretAccessKey"`
+			SessionToken    string    `xml:"SessionToken"`
+			Expiration      time.Time `xml:"Expiration"`
+		} `xml:"AssumeRoleWithWebIdentityResult>Credentials"`
+	}
+	if err := xml.Unmarshal(body, &res); err != nil {
+		return nil, err
+	}
+
+	return (&awsCredentialsResponse{
+		AccessKeyID:     res.Credentials.AccessKeyID,
+		SecretAccessKey: res.Credentials.SecretAccessKey,
+		Token:           res.Credentials.SessionToken,
+		Expiration:      res.Credentials.Expiration,
+	}).temporaryCredentials()
+}
+
+// containerCredentials reads the credentials of the task role from the ECS
+// container credentials endpoint.
+func (a *AWSSecretsManager) containerCredentials(ctx context.Context) (*awsTemporaryCredentials, error) {
+	endpoint := os.Getenv("AWS_CONTAINER_CREDENTIALS_FULL_URI")
+	if uri := os.Getenv("AWS_CONTAINER_CREDENTIALS_RELATIVE_URI"); uri != "" {
+		endpoint = cmp.Or(a.ContainerEndpoint, awsContainerEndpoint) + uri
+	}
+
+	req, err := http.NewRequest(http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	authorization := os.Getenv("AWS_CONTAINER_AUTHORIZATION_TOKEN")
+	if file := os.Getenv("AWS_CONTAINER_AUTHORIZATION_TOKEN_FILE"); file != "" {
+		b, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		authorization = strings.TrimSpace(string(b))
+	}
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+
+	body, err := do(ctx, a.Client, req)
+	if err != nil {
+		return nil, err
+	}
+
+	var res awsCredentialsResponse
+	if err := json.Unmarshal(body, &res); err != nil {
+		return nil, err
+	}
+	return res.temporaryCredentials()
+}
+
+// instanceProfileCredentials reads the credentials of the role of the EC2
+// instance profile from the instance metadata service, using a session
+// token as required by IMDSv2.
+func (a *AWSSecretsManager) instanceProfileCredentials(ctx context.Context) (*awsTemporaryCredentials, error) {
+	endpoint := cmp.Or(a.MetadataEndpoint, awsMetadataEndpoint)
+
+	req, err := http.NewRequest(http.MethodPut, endpoint+"/latest/api/token", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Aws-Ec2-Metadata-Token-Ttl-Seconds", "300")
+
+	token, err := do(ctx, a.Client, req)
+	if err != nil {
+		return nil, err
+	}
+
+	get := func(path string) ([]byte, error) {
+		req, err := http.NewRequest(http.MethodGet, endpoint+path, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("X-Aws-Ec2-Metadata-Token", string(token))
+		return do(ctx, a.Client, req)
+	}
+
+	roles, err := get("/latest/meta-data/iam/security-credentials/")
+	if err != nil {
+		return nil, err
+	}
+	role, _, _ := strings.Cut(strings.TrimSpace(string(roles)), "\n")
+	if role == "" {
+		return nil, errors.New("the instance has no instance profile")
+	}
+
+	body, err := get("/latest/meta-data/iam/security-credentials/" + role)
+	if err != nil {
+		return nil, err
+	}
+
+	var res awsCredentialsResponse
+	if err := json.Unmarshal(body, &res); err != nil {
+		return nil, err
+	}
+	return res.temporaryCredentials()
+}
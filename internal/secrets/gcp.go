@@ -0,0 +1,95 @@
+package secrets
+
+import (
+	"cmp"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"strings"
+)
+
+const (
+	gcpSecretManagerEndpoint = "https://secretmanager.googleapis.com"
+	gcpMetadataTokenURL      = "http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/token"
+)
+
+// GCPSecretManager reads secrets from GCP Secret Manager. The path of a
+// reference is the name of the secret or of one of its versions, such as
+// projects/my-project/secrets/gotrue, which reads the latest version. The
+// access token defaults to GOOGLE_OAUTH_ACCESS_TOKEN, or the token of the
+// service account from the metadata server.
+type GCPSecretManager struct {
+	Token string
+
+	// Endpoint and MetadataTokenURL replace the URLs of Google's APIs.
+	Endpoint         string
+	MetadataTokenURL string
+	Client           *http.Client
+}
+
+// FetchSecret returns the payload of the secret version.
+func (g *GCPSecretManager) FetchSecret(ctx context.Context, path string) (string, error) {
+	name := strings.Trim(path, "/")
+	if !strings.Contains(name, "/versions/") {
+		name += "/versions/latest"
+	}
+
+	token, err := g.token(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequest(http.MethodGet, cmp.Or(g.Endpoint, gcpSecretManagerEndpoint)+"/v1/"+name+":access", nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	body, err := do(ctx, g.Client, req)
+	if err != nil {
+		return "", err
+	}
+
+	var res struct {
+		Payload struct {
+			Data string `json:"data"`
+		} `json:"payload"`
+	}
+	if err := json.Unmarshal(body, &res); err != nil {
+		return "", err
+	}
+
+	b, err := base64.StdEncoding.DecodeString(res.Payload.Data)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+func (g *GCPSecretManager) token(ctx context.Context) (string, error) {
+	if token := cmp.Or(g.Token, os.Getenv("GOOGLE_OAUTH_ACCESS_TOKEN")); token != "" {
+		return token, nil
+	}
+
+	req, err := http.NewRequest(http.MethodGet, cmp.Or(g.MetadataTokenURL, gcpMetadataTokenURL), nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+
+	body, err := do(ctx, g.Client, req)
+	if err != nil {
+		return "", errors.New("gcp-secretmanager: GOOGLE_OAUTH_ACCESS_TOKEN is not set and the metadata server is not available: " + err.Error())
+	}
+
+	var res struct {
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.Unmarshal(body, &res); err != nil {
+		return "", err
+	}
+	return res.AccessToken, nil
+}
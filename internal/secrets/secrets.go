@@ -0,0 +1,50 @@
+// Package secrets fetches the secrets referenced in the configuration from
+// HashiCorp Vault, AWS Secrets Manager and GCP Secret Manager. Importing it
+// registers the providers with the conf package, which resolves references
+// such as vault:secret/data/gotrue#smtp_pass when loading the configuration.
+//
+// The providers are configured with the environment variables of the
+// official clients, read when a secret is fetched.
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/supabase/auth/internal/conf"
+	"github.com/supabase/auth/internal/utilities"
+)
+
+// fetchTimeout bounds fetching a single secret.
+const fetchTimeout = 10 * time.Second
+
+func init() {
+	conf.RegisterSecretProvider(conf.SecretSchemeVault, &Vault{})
+	conf.RegisterSecretProvider(conf.SecretSchemeAWS, &AWSSecretsManager{})
+	conf.RegisterSecretProvider(conf.SecretSchemeGCP, &GCPSecretManager{})
+}
+
+func do(ctx context.Context, client *http.Client, req *http.Request) ([]byte, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, fetchTimeout)
+	defer cancel()
+
+	res, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer utilities.SafeClose(res.Body)
+
+	if res.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+		return nil, fmt.Errorf("request failed with status %d: %s", res.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	return io.ReadAll(io.LimitReader(res.Body, 1<<20))
+}
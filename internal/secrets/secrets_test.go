@@ -0,0 +1,296 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/supabase/auth/internal/utilities"
+)
+
+func TestVault(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "test-token", r.Header.Get("X-Vault-Token"))
+		require.Equal(t, "team", r.Header.Get("X-Vault-Namespace"))
+
+		switch r.URL.Path {
+		case "/v1/secret/data/gotrue":
+			w.Write([]byte(`{"data": {"data": {"smtp_pass": "password"}, "metadata": {"version": 3}}}`))
+		case "/v1/kv/gotrue":
+			w.Write([]byte(`{"data": {"smtp_pass": "password"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"errors": []}`))
+		}
+	}))
+	defer srv.Close()
+
+	v := &Vault{Addr: srv.URL, Token: "test-token", Namespace: "team"}
+
+	secret, err := v.FetchSecret(context.Background(), "secret/data/gotrue")
+	require.NoError(t, err)
+	require.JSONEq(t, `{"smtp_pass": "password"}`, secret)
+
+	secret, err = v.FetchSecret(context.Background(), "kv/gotrue")
+	require.NoError(t, err)
+	require.JSONEq(t, `{"smtp_pass": "password"}`, secret)
+
+	_, err = v.FetchSecret(context.Background(), "secret/data/missing")
+	require.ErrorContains(t, err, "request failed with status 404")
+}
+
+func TestVaultLogin(t *testing.T) {
+	t.Setenv("VAULT_TOKEN", "")
+	t.Setenv("VAULT_NAMESPACE", "")
+	t.Setenv("VAULT_AUTH_MOUNT", "")
+
+	var logins, renewals int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		if r.Method == http.MethodPost {
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		}
+
+		switch r.URL.Path {
+		case "/v1/auth/approle/login":
+			require.Equal(t, map[string]string{"role_id": "role", "secret_id": "secret"}, body)
+			logins++
+			fmt.Fprintf(w, `{"auth": {"client_token": "approle-%d", "lease_duration": 3600, "renewable": true}}`, logins)
+		case "/v1/auth/k8s/login":
+			require.Equal(t, map[string]string{"role": "gotrue", "jwt": "service-account-token"}, body)
+			w.Write([]byte(`{"auth": {"client_token": "kubernetes", "lease_duration": 3600, "renewable": false}}`))
+		case "/v1/auth/token/renew-self":
+			require.Equal(t, "approle-1", r.Header.Get("X-Vault-Token"))
+			renewals++
+			w.Write([]byte(`{"auth": {"client_token": "approle-1", "lease_duration": 600, "renewable": true}}`))
+		case "/v1/kv/gotrue":
+			fmt.Fprintf(w, `{"data": {"token": %q}}`, r.Header.Get("X-Vault-Token"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	v := &Vault{Addr: srv.URL, now: func() time.Time { return now }}
+
+	_, err := v.FetchSecret(context.Background(), "kv/gotrue")
+	require.ErrorContains(t, err, "VAULT_ROLE_ID")
+
+	t.Setenv("VAULT_ROLE_ID", "role")
+	t.Setenv("VAULT_SECRET_ID", "secret")
+
+	// the token of the login is reused
+	for i := 0; i < 2; i++ {
+		secret, err := v.FetchSecret(context.Background(), "kv/gotrue")
+		require.NoError(t, err)
+		require.JSONEq(t, `{"token": "approle-1"}`, secret)
+	}
+	require.Equal(t, 1, logins)
+
+	// and renewed once half of its lease passed
+	now = now.Add(31 * time.Minute)
+	secret, err := v.FetchSecret(context.Background(), "kv/gotrue")
+	require.NoError(t, err)
+	require.JSONEq(t, `{"token": "approle-1"}`, secret)
+	require.Equal(t, 1, logins)
+	require.Equal(t, 1, renewals)
+
+	// expired tokens are replaced by a new login
+	now = now.Add(10 * time.Minute)
+	secret, err = v.FetchSecret(context.Background(), "kv/gotrue")
+	require.NoError(t, err)
+	require.JSONEq(t, `{"token": "approle-2"}`, secret)
+	require.Equal(t, 2, logins)
+
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(tokenFile, []byte("service-account-token\n"), 0600))
+	t.Setenv("VAULT_ROLE_ID", "")
+	t.Setenv("VAULT_KUBERNETES_ROLE", "gotrue")
+	t.Setenv("VAULT_AUTH_MOUNT", "k8s")
+
+	v = &Vault{Addr: srv.URL, KubernetesTokenFile: tokenFile}
+	secret, err = v.FetchSecret(context.Background(), "kv/gotrue")
+	require.NoError(t, err)
+	require.JSONEq(t, `{"token": "kubernetes"}`, secret)
+}
+
+func TestAWSSecretsManager(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodPost, r.Method)
+		require.Equal(t, "secretsmanager.GetSecretValue", r.Header.Get("X-Amz-Target"))
+		require.True(t, strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKID/20260101/eu-west-1/secretsmanager/aws4_request"))
+
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		var req struct {
+			SecretId string
+		}
+		require.NoError(t, json.Unmarshal(body, &req))
+
+		switch req.SecretId {
+		case "arn:aws:secretsmanager:eu-west-1:123456789012:secret:gotrue":
+			w.Write([]byte(`{"SecretString": "{\"smtp_pass\": \"password\"}"}`))
+		case "binary":
+			w.Write([]byte(`{"SecretBinary": "cGFzc3dvcmQ="}`))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"__type": "ResourceNotFoundException"}`))
+		}
+	}))
+	defer srv.Close()
+
+	a := &AWSSecretsManager{
+		Credentials: utilities.AWSCredentials{AccessKeyID: "AKID", SecretAccessKey: "secret"},
+		Endpoint:    srv.URL,
+		now:         func() time.Time { return time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC) },
+	}
+
+	// the region is taken from the ARN
+	secret, err := a.FetchSecret(context.Background(), "arn:aws:secretsmanager:eu-west-1:123456789012:secret:gotrue")
+	require.NoError(t, err)
+	require.JSONEq(t, `{"smtp_pass": "password"}`, secret)
+
+	a.Region = "eu-west-1"
+	secret, err = a.FetchSecret(context.Background(), "binary")
+	require.NoError(t, err)
+	require.Equal(t, "password", secret)
+
+	_, err = a.FetchSecret(context.Background(), "missing")
+	require.ErrorContains(t, err, "ResourceNotFoundException")
+}
+
+func TestAWSCredentials(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "")
+	t.Setenv("AWS_WEB_IDENTITY_TOKEN_FILE", "")
+	t.Setenv("AWS_CONTAINER_CREDENTIALS_RELATIVE_URI", "")
+	t.Setenv("AWS_CONTAINER_CREDENTIALS_FULL_URI", "")
+	t.Setenv("AWS_CONTAINER_AUTHORIZATION_TOKEN", "")
+	t.Setenv("AWS_CONTAINER_AUTHORIZATION_TOKEN_FILE", "")
+
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	expiration := now.Add(time.Hour).Format(time.RFC3339)
+
+	var requests int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		switch r.URL.Path {
+		case "/sts":
+			require.NoError(t, r.ParseForm())
+			require.Equal(t, "AssumeRoleWithWebIdentity", r.PostForm.Get("Action"))
+			require.Equal(t, "arn:aws:iam::123456789012:role/gotrue", r.PostForm.Get("RoleArn"))
+			require.Equal(t, "web-identity-token", r.PostForm.Get("WebIdentityToken"))
+			fmt.Fprintf(w, `<AssumeRoleWithWebIdentityResponse xmlns="https://sts.amazonaws.com/doc/2011-06-15/">
+  <AssumeRoleWithWebIdentityResult>
+    <Credentials>
+      <AccessKeyId>IRSA</AccessKeyId>
+      <SecretAccessKey>secret</SecretAccessKey>
+      <SessionToken>session</SessionToken>
+      <Expiration>%s</Expiration>
+    </Credentials>
+  </AssumeRoleWithWebIdentityResult>
+</AssumeRoleWithWebIdentityResponse>`, expiration)
+		case "/v2/credentials/task":
+			require.Equal(t, "container-token", r.Header.Get("Authorization"))
+			fmt.Fprintf(w, `{"AccessKeyId": "ECS", "SecretAccessKey": "secret", "Token": "session", "Expiration": %q}`, expiration)
+		case "/latest/api/token":
+			require.Equal(t, http.MethodPut, r.Method)
+			w.Write([]byte("imds-token"))
+		case "/latest/meta-data/iam/security-credentials/":
+			require.Equal(t, "imds-token", r.Header.Get("X-Aws-Ec2-Metadata-Token"))
+			w.Write([]byte("gotrue-role"))
+		case "/latest/meta-data/iam/security-credentials/gotrue-role":
+			require.Equal(t, "imds-token", r.Header.Get("X-Aws-Ec2-Metadata-Token"))
+			fmt.Fprintf(w, `{"Code": "Success", "AccessKeyId": "EC2", "SecretAccessKey": "secret", "Token": "session", "Expiration": %q}`, expiration)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	newManager := func() *AWSSecretsManager {
+		return &AWSSecretsManager{
+			STSEndpoint:       srv.URL + "/sts",
+			ContainerEndpoint: srv.URL,
+			MetadataEndpoint:  srv.URL,
+			now:               func() time.Time { return now },
+		}
+	}
+
+	// the instance profile is the last source
+	a := newManager()
+	credentials, err := a.credentials(context.Background(), "eu-west-1")
+	require.NoError(t, err)
+	require.Equal(t, utilities.AWSCredentials{AccessKeyID: "EC2", SecretAccessKey: "secret", SessionToken: "session"}, credentials)
+	require.Equal(t, 3, requests)
+
+	// temporary credentials are reused until shortly before they expire
+	_, err = a.credentials(context.Background(), "eu-west-1")
+	require.NoError(t, err)
+	require.Equal(t, 3, requests)
+
+	now = now.Add(56 * time.Minute)
+	_, err = a.credentials(context.Background(), "eu-west-1")
+	require.NoError(t, err)
+	require.Equal(t, 6, requests)
+	now = now.Add(-56 * time.Minute)
+
+	t.Setenv("AWS_CONTAINER_CREDENTIALS_RELATIVE_URI", "/v2/credentials/task")
+	t.Setenv("AWS_CONTAINER_AUTHORIZATION_TOKEN", "container-token")
+	credentials, err = newManager().credentials(context.Background(), "eu-west-1")
+	require.NoError(t, err)
+	require.Equal(t, "ECS", credentials.AccessKeyID)
+
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(tokenFile, []byte("web-identity-token"), 0600))
+	t.Setenv("AWS_WEB_IDENTITY_TOKEN_FILE", tokenFile)
+	t.Setenv("AWS_ROLE_ARN", "arn:aws:iam::123456789012:role/gotrue")
+	credentials, err = newManager().credentials(context.Background(), "eu-west-1")
+	require.NoError(t, err)
+	require.Equal(t, utilities.AWSCredentials{AccessKeyID: "IRSA", SecretAccessKey: "secret", SessionToken: "session"}, credentials)
+
+	// static credentials come first
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKID")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "static")
+	credentials, err = newManager().credentials(context.Background(), "eu-west-1")
+	require.NoError(t, err)
+	require.Equal(t, "AKID", credentials.AccessKeyID)
+}
+
+func TestGCPSecretManager(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/token":
+			require.Equal(t, "Google", r.Header.Get("Metadata-Flavor"))
+			w.Write([]byte(`{"access_token": "metadata-token", "expires_in": 3599, "token_type": "Bearer"}`))
+		case "/v1/projects/p/secrets/gotrue/versions/latest:access", "/v1/projects/p/secrets/gotrue/versions/2:access":
+			require.Equal(t, "Bearer metadata-token", r.Header.Get("Authorization"))
+			w.Write([]byte(`{"name": "projects/p/secrets/gotrue/versions/2", "payload": {"data": "cGFzc3dvcmQ="}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	g := &GCPSecretManager{
+		Endpoint:         srv.URL,
+		MetadataTokenURL: srv.URL + "/token",
+	}
+
+	secret, err := g.FetchSecret(context.Background(), "projects/p/secrets/gotrue")
+	require.NoError(t, err)
+	require.Equal(t, "password", secret)
+
+	secret, err = g.FetchSecret(context.Background(), "projects/p/secrets/gotrue/versions/2")
+	require.NoError(t, err)
+	require.Equal(t, "password", secret)
+}
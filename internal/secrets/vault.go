@@ -0,0 +1,233 @@
+package secrets
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const vaultKubernetesTokenFile = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+
+// Vault reads secrets from the KV secrets engine of HashiCorp Vault. The
+// path of a reference is the API path of the secret, such as
+// secret/data/gotrue for a secret of version 2 of the engine mounted at
+// secret. The fields default to VAULT_ADDR, VAULT_TOKEN and VAULT_NAMESPACE.
+//
+// Without a token, Vault logs in with the AppRole auth method when
+// VAULT_ROLE_ID is set, or the Kubernetes auth method when
+// VAULT_KUBERNETES_ROLE is set, at the mount VAULT_AUTH_MOUNT which defaults
+// to the name of the method. The token of the login is renewed once half of
+// its lease has passed, and replaced by a new login when it can't be.
+type Vault struct {
+	Addr      string
+	Token     string
+	Namespace string
+	Client    *http.Client
+
+	// KubernetesTokenFile replaces the path of the token of the service
+	// account of the pod.
+	KubernetesTokenFile string
+
+	now func() time.Time
+
+	mu    sync.Mutex
+	login *vaultLogin // Guarded by mu.
+}
+
+// vaultLogin is a token Vault issued by logging in.
+type vaultLogin struct {
+	token     string
+	renewable bool
+	renewAt   time.Time
+	expiresAt time.Time
+}
+
+// vaultAuthResponse is the response of a login or renewal.
+type vaultAuthResponse struct {
+	Auth *struct {
+		ClientToken   string `json:"client_token"`
+		LeaseDuration int64  `json:"lease_duration"`
+		Renewable     bool   `json:"renewable"`
+	} `json:"auth"`
+}
+
+// FetchSecret returns the data of the secret as a JSON object.
+func (v *Vault) FetchSecret(ctx context.Context, path string) (string, error) {
+	addr := cmp.Or(v.Addr, os.Getenv("VAULT_ADDR"))
+	if addr == "" {
+		return "", errors.New("vault: VAULT_ADDR is not set")
+	}
+
+	token, err := v.token(ctx, addr)
+	if err != nil {
+		return "", err
+	}
+
+	body, err := v.do(ctx, addr, http.MethodGet, path, token, nil)
+	if err != nil {
+		return "", err
+	}
+
+	var res struct {
+		Data map[string]json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(body, &res); err != nil {
+		return "", err
+	}
+
+	// Version 2 of the engine nests the data of the secret next to its
+	// metadata.
+	data, hasData := res.Data["data"]
+	_, hasMetadata := res.Data["metadata"]
+	if hasData && hasMetadata {
+		return string(data), nil
+	}
+
+	b, err := json.Marshal(res.Data)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+func (v *Vault) do(ctx context.Context, addr, method, path, token string, payload interface{}) ([]byte, error) {
+	var body io.Reader
+	if payload != nil {
+		b, err := json.Marshal(payload)
+		if err != nil {
+			return nil, err
+		}
+		body = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequest(method, strings.TrimSuffix(addr, "/")+"/v1/"+strings.TrimPrefix(path, "/"), body)
+	if err != nil {
+		return nil, err
+	}
+	if token != "" {
+		req.Header.Set("X-Vault-Token", token)
+	}
+	if ns := cmp.Or(v.Namespace, os.Getenv("VAULT_NAMESPACE")); ns != "" {
+		req.Header.Set("X-Vault-Namespace", ns)
+	}
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	return do(ctx, v.Client, req)
+}
+
+// token returns the token secrets are read with, which is either the
+// configured token or the token of a login.
+func (v *Vault) token(ctx context.Context, addr string) (string, error) {
+	if token := cmp.Or(v.Token, os.Getenv("VAULT_TOKEN")); token != "" {
+		return token, nil
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	now := time.Now()
+	if v.now != nil {
+		now = v.now()
+	}
+
+	if v.login != nil && now.Before(v.login.expiresAt) {
+		if now.Before(v.login.renewAt) {
+			return v.login.token, nil
+		}
+		if v.login.renewable {
+			// tokens are not renewed past their maximum TTL, their lease
+			// shortens until they are replaced by a new login
+			body, err := v.do(ctx, addr, http.MethodPost, "auth/token/renew-self", v.login.token, map[string]interface{}{})
+			if err == nil {
+				if login, err := newVaultLogin(body, now); err == nil {
+					v.login = login
+					return login.token, nil
+				}
+			}
+		}
+	}
+
+	login, err := v.authenticate(ctx, addr, now)
+	if err != nil {
+		return "", err
+	}
+	v.login = login
+	return login.token, nil
+}
+
+// authenticate logs in with the AppRole or Kubernetes auth method.
+func (v *Vault) authenticate(ctx context.Context, addr string, now time.Time) (*vaultLogin, error) {
+	var method string
+	var payload map[string]string
+	switch {
+	case os.Getenv("VAULT_ROLE_ID") != "":
+		method = "approle"
+		payload = map[string]string{
+			"role_id":   os.Getenv("VAULT_ROLE_ID"),
+			"secret_id": os.Getenv("VAULT_SECRET_ID"),
+		}
+
+	case os.Getenv("VAULT_KUBERNETES_ROLE") != "":
+		jwt, err := os.ReadFile(cmp.Or(v.KubernetesTokenFile, vaultKubernetesTokenFile))
+		if err != nil {
+			return nil, errors.New("vault: unable to read the token of the service account: " + err.Error())
+		}
+		method = "kubernetes"
+		payload = map[string]string{
+			"role": os.Getenv("VAULT_KUBERNETES_ROLE"),
+			"jwt":  strings.TrimSpace(string(jwt)),
+		}
+
+	default:
+		return nil, errors.New("vault: VAULT_TOKEN, VAULT_ROLE_ID and VAULT_KUBERNETES_ROLE are not set")
+	}
+
+	mount := strings.Trim(cmp.Or(os.Getenv("VAULT_AUTH_MOUNT"), method), "/")
+	body, err := v.do(ctx, addr, http.MethodPost, "auth/"+mount+"/login", "", payload)
+	if err != nil {
+		return nil, errors.New("vault: unable to log in with the " + method + " auth method: " + err.Error())
+	}
+
+	login, err := newVaultLogin(body, now)
+	if err != nil {
+		return nil, errors.New("vault: unable to log in with the " + method + " auth method: " + err.Error())
+	}
+	return login, nil
+}
+
+func newVaultLogin(body []byte, now time.Time) (*vaultLogin, error) {
+	var res vaultAuthResponse
+	if err := json.Unmarshal(body, &res); err != nil {
+		return nil, err
+	}
+	if res.Auth == nil || res.Auth.ClientToken == "" {
+		return nil, errors.New("the response has no token")
+	}
+
+	login := &vaultLogin{
+		token:     res.Auth.ClientToken,
+		renewable: res.Auth.Renewable,
+	}
+
+	// tokens without a lease, such as root tokens, do not expire
+	if res.Auth.LeaseDuration <= 0 {
+		login.renewAt = now.AddDate(100, 0, 0)
+		login.expiresAt = login.renewAt
+		return login, nil
+	}
+
+	lease := time.Duration(res.Auth.LeaseDuration) * time.Second
+	login.renewAt = now.Add(lease / 2)
+	login.expiresAt = now.Add(lease - min(lease/10, time.Minute))
+	return login, nil
+}
@@ -28,6 +28,10 @@ type VerificationResponse struct {
 	Success    bool     `json:"success"`
 	ErrorCodes []string `json:"error-codes"`
 	Hostname   string   `json:"hostname"`
+
+	// Score and Action are only returned by reCAPTCHA v3
+	Score  float64 `json:"score"`
+	Action string  `json:"action"`
 }
 
 var Client *http.Client
@@ -46,21 +50,76 @@ func init() {
 	Client = &http.Client{Timeout: defaultTimeout}
 }
 
-func VerifyRequest(requestBody *GotrueRequest, clientIP, secretKey, captchaProvider string) (VerificationResponse, error) {
-	captchaResponse := strings.TrimSpace(requestBody.Security.Token)
+// CaptchaProvider verifies captcha tokens solved by clients.
+type CaptchaProvider interface {
+	Verify(token, clientIP string) (VerificationResponse, error)
+}
 
-	if captchaResponse == "" {
-		return VerificationResponse{}, errors.New("no captcha response (captcha_token) found in request")
-	}
+// siteverifyProvider verifies tokens with the siteverify API shared by
+// hCaptcha and Cloudflare Turnstile.
+type siteverifyProvider struct {
+	url    string
+	secret string
+}
 
-	captchaURL, err := GetCaptchaURL(captchaProvider)
+func (p *siteverifyProvider) Verify(token, clientIP string) (VerificationResponse, error) {
+	return verifyCaptchaCode(token, p.secret, clientIP, p.url)
+}
+
+// recaptchaProvider verifies reCAPTCHA v3 tokens, which are scored from 0.0
+// (likely a bot) to 1.0 (likely a human) instead of passing or failing.
+type recaptchaProvider struct {
+	secret   string
+	minScore float64
+}
+
+func (p *recaptchaProvider) Verify(token, clientIP string) (VerificationResponse, error) {
+	res, err := verifyCaptchaCode(token, p.secret, clientIP, recaptchaURL)
 	if err != nil {
-		return VerificationResponse{}, err
+		return res, err
 	}
 
-	return verifyCaptchaCode(captchaResponse, secretKey, clientIP, captchaURL)
+	if res.Success && res.Score < p.minScore {
+		res.Success = false
+		res.ErrorCodes = append(res.ErrorCodes, "score-threshold-not-met")
+	}
+
+	return res, nil
+}
+
+const recaptchaURL = "https://www.google.com/recaptcha/api/siteverify"
+
+// NewCaptchaProvider returns the CaptchaProvider with the given name. The
+// minScore is only used by reCAPTCHA v3.
+func NewCaptchaProvider(captchaProvider, secretKey string, minScore float64) (CaptchaProvider, error) {
+	switch captchaProvider {
+	case "recaptcha":
+		return &recaptchaProvider{
+			secret:   secretKey,
+			minScore: minScore,
+		}, nil
+	default:
+		captchaURL, err := GetCaptchaURL(captchaProvider)
+		if err != nil {
+			return nil, err
+		}
+
+		return &siteverifyProvider{
+			url:    captchaURL,
+			secret: secretKey,
+		}, nil
+	}
 }
 
+func VerifyRequest(requestBody *GotrueRequest, clientIP string, provider CaptchaProvider) (VerificationResponse, error) {
+	captchaResponse := strings.TrimSpace(requestBody.Security.Token)
+
+	if captchaResponse == "" {
+		return VerificationResponse{}, errors.New("no captcha response (captcha_token) found in request")
+	}
+
+	return provider.Verify(captchaResponse, clientIP)
+}
 func verifyCaptchaCode(token, secretKey, clientIP, captchaURL string) (VerificationResponse, error) {
 	data := url.Values{}
 	data.Set("secret", secretKey)
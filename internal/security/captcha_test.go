@@ -0,0 +1,58 @@
+package security
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/h2non/gock.v1"
+)
+
+func TestRecaptchaProvider(t *testing.T) {
+	defer gock.Off()
+	gock.InterceptClient(Client)
+	defer gock.RestoreClient(Client)
+
+	provider, err := NewCaptchaProvider("recaptcha", "secret", 0.5)
+	require.NoError(t, err)
+
+	cases := []struct {
+		score      float64
+		success    bool
+		errorCodes []string
+	}{
+		{score: 0.9, success: true},
+		{score: 0.5, success: true},
+		{score: 0.1, success: false, errorCodes: []string{"score-threshold-not-met"}},
+	}
+
+	for _, c := range cases {
+		gock.New(recaptchaURL).Post("").
+			MatchType("url").
+			BodyString("remoteip=127.0.0.1&response=token&secret=secret").
+			Reply(200).
+			JSON(map[string]any{
+				"success": true,
+				"score":   c.score,
+				"action":  "signup",
+			})
+
+		res, err := VerifyRequest(&GotrueRequest{Security: GotrueSecurity{Token: " token "}}, "127.0.0.1", provider)
+		require.NoError(t, err)
+		require.Equal(t, c.success, res.Success, "score %v", c.score)
+		require.Equal(t, c.errorCodes, res.ErrorCodes, "score %v", c.score)
+		require.Equal(t, "signup", res.Action)
+	}
+
+	_, err = VerifyRequest(&GotrueRequest{}, "127.0.0.1", provider)
+	require.Error(t, err)
+}
+
+func TestNewCaptchaProvider(t *testing.T) {
+	for _, name := range []string{"hcaptcha", "turnstile", "recaptcha"} {
+		_, err := NewCaptchaProvider(name, "secret", 0.5)
+		require.NoError(t, err, name)
+	}
+
+	_, err := NewCaptchaProvider("unknown", "secret", 0.5)
+	require.Error(t, err)
+}
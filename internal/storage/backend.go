@@ -0,0 +1,97 @@
+package storage
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Names of the built-in backends.
+const (
+	BackendPostgres  = "postgres"
+	BackendCockroach = "cockroach"
+)
+
+// Backend is a database engine Auth can store its data in. A backend only
+// selects how pop connects to the engine: the models and migrations are not
+// abstracted over backends and are written against the SQL of Postgres, so
+// backends are limited to Postgres and engines compatible with it.
+type Backend interface {
+	// Name is the name of the backend, as set in GOTRUE_DB_DRIVER.
+	Name() string
+
+	// Dialect is the dialect of pop used with the backend.
+	Dialect() string
+
+	// Driver is the database/sql driver used with the backend, or empty
+	// for the default driver of the dialect.
+	Driver() string
+
+	// MaxConnsQuery is the query returning the maximum number of
+	// connections of the server, or empty when the backend has none.
+	MaxConnsQuery() string
+}
+
+var (
+	backendsMu sync.RWMutex
+	backends   = make(map[string]Backend)
+	aliases    = make(map[string]string)
+)
+
+// RegisterBackend makes a backend available under its name and the given
+// aliases, such as the schemes of its connection URLs.
+func RegisterBackend(b Backend, alias ...string) {
+	backendsMu.Lock()
+	defer backendsMu.Unlock()
+
+	backends[b.Name()] = b
+	for _, a := range alias {
+		aliases[a] = b.Name()
+	}
+}
+
+// LookupBackend returns the backend registered under the given name or
+// alias.
+func LookupBackend(name string) (Backend, error) {
+	backendsMu.RLock()
+	defer backendsMu.RUnlock()
+
+	name = strings.ToLower(name)
+	if a, ok := aliases[name]; ok {
+		name = a
+	}
+
+	b, ok := backends[name]
+	if !ok {
+		names := make([]string, 0, len(backends))
+		for n := range backends {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("storage: unknown database driver %q, must be one of %s", name, strings.Join(names, ", "))
+	}
+	return b, nil
+}
+
+type postgresBackend struct{}
+
+func (postgresBackend) Name() string          { return BackendPostgres }
+func (postgresBackend) Dialect() string       { return "postgres" }
+func (postgresBackend) Driver() string        { return "pgx" }
+func (postgresBackend) MaxConnsQuery() string { return "SHOW max_connections;" }
+
+// cockroachBackend runs Auth on CockroachDB in its Postgres compatibility
+// mode. CockroachDB has no max_connections setting, so percentage based
+// connection limits are not available.
+type cockroachBackend struct{}
+
+func (cockroachBackend) Name() string          { return BackendCockroach }
+func (cockroachBackend) Dialect() string       { return "cockroach" }
+func (cockroachBackend) Driver() string        { return "pgx" }
+func (cockroachBackend) MaxConnsQuery() string { return "" }
+
+func init() {
+	RegisterBackend(postgresBackend{}, "postgresql", "pgx")
+	RegisterBackend(cockroachBackend{}, "cockroachdb", "crdb")
+}
@@ -0,0 +1,52 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/gobuffalo/pop/v6"
+	"github.com/stretchr/testify/require"
+	"github.com/supabase/auth/internal/conf"
+)
+
+func TestLookupBackend(t *testing.T) {
+	cases := []struct {
+		name string
+		exp  string
+	}{
+		{name: "postgres", exp: BackendPostgres},
+		{name: "postgresql", exp: BackendPostgres},
+		{name: "PGX", exp: BackendPostgres},
+		{name: "cockroach", exp: BackendCockroach},
+		{name: "cockroachdb", exp: BackendCockroach},
+	}
+	for _, tc := range cases {
+		b, err := LookupBackend(tc.name)
+		require.NoError(t, err, tc.name)
+		require.Equal(t, tc.exp, b.Name())
+	}
+
+	_, err := LookupBackend("sqlite3")
+	require.ErrorContains(t, err, `unknown database driver "sqlite3"`)
+
+	b, err := LookupBackend(BackendCockroach)
+	require.NoError(t, err)
+	require.Empty(t, b.MaxConnsQuery())
+}
+
+func TestApplyDBDriverUnknown(t *testing.T) {
+	config := &conf.GlobalConfiguration{}
+	config.DB.URL = "mysql://root@localhost/auth"
+
+	// unknown drivers are used as the dialect with a warning, rather than
+	// failing to start
+	cd := &pop.ConnectionDetails{}
+	require.NoError(t, applyDBDriver(config, cd))
+	require.Equal(t, "mysql", config.DB.Driver)
+	require.Equal(t, "mysql", cd.Dialect)
+	require.Empty(t, cd.Driver)
+
+	config.DB.Driver = "postgresql"
+	require.NoError(t, applyDBDriver(config, cd))
+	require.Equal(t, "postgres", cd.Dialect)
+	require.Equal(t, "pgx", cd.Driver)
+}
@@ -0,0 +1,21 @@
+package storage_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/supabase/auth/internal/conf"
+	"github.com/supabase/auth/internal/storage"
+	"github.com/supabase/auth/internal/storage/storagetest"
+)
+
+func TestConformance(t *testing.T) {
+	config, err := conf.LoadGlobal("../../hack/test.env")
+	require.NoError(t, err)
+
+	conn, err := storage.Dial(config)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	storagetest.Run(t, conn)
+}
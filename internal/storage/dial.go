@@ -6,6 +6,9 @@ import (
 	"fmt"
 	"net/url"
 	"reflect"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/XSAM/otelsql"
@@ -21,7 +24,9 @@ import (
 // a storage connection
 type Connection struct {
 	*pop.Connection
-	sqldb *sql.DB
+	sqldb   *sql.DB
+	backend Backend
+	replica *replica
 }
 
 // Dial will connect to that storage engine
@@ -38,6 +43,9 @@ func DialContext(
 		return nil, err
 	}
 
+	// drivers without a backend are used with the queries of Postgres
+	backend, _ := LookupBackend(config.DB.Driver)
+
 	db, err := pop.NewConnection(cd)
 	if err != nil {
 		return nil, errors.Wrap(err, "opening database connection")
@@ -54,10 +62,27 @@ func DialContext(
 	conn := &Connection{
 		Connection: db,
 		sqldb:      sqldb,
+		backend:    backend,
+	}
+
+	if config.DB.ReplicaURL != "" {
+		conn.replica, err = dialReplica(ctx, config)
+		if err != nil {
+			_ = db.Close()
+			return nil, err
+		}
 	}
 	return conn, nil
 }
 
+// Backend returns the backend of the connection.
+func (c *Connection) Backend() Backend {
+	if c.backend == nil {
+		return postgresBackend{}
+	}
+	return c.backend
+}
+
 // // GetSqlDB returns the underlying *sql.DB and true or nil if no db could be obtained.
 // func (c *Connection) GetSqlDB() (*sql.DB, bool) { return c.sqldb, c.sqldb != nil }
 
@@ -83,6 +108,13 @@ func newConnectionDetails(
 	if err := applyDBDriver(config, cd); err != nil {
 		return nil, err
 	}
+	if cd.Dialect != "mysql" {
+		u, err := ConnectionURL(&config.DB, cd.URL)
+		if err != nil {
+			return nil, err
+		}
+		cd.URL = u
+	}
 	if config.DB.HealthCheckPeriod != time.Duration(0) {
 		cd.Options["pool_health_check_period"] = config.DB.HealthCheckPeriod.String()
 	}
@@ -107,13 +139,21 @@ func applyDBDriver(
 		config.DB.Driver = u.Scheme
 	}
 
-	driver := ""
-	if config.DB.Driver != "postgres" {
-		logrus.Warn("DEPRECATION NOTICE: only PostgreSQL is supported by Supabase's GoTrue, will be removed soon")
-	} else {
-		// pop v5 uses pgx as the default PostgreSQL driver
-		driver = "pgx"
+	backend, err := LookupBackend(config.DB.Driver)
+	if err != nil {
+		// drivers without a backend are still used as the dialect of pop,
+		// as they were before backends were registered
+		logrus.WithError(err).Warn("DEPRECATION NOTICE: only PostgreSQL compatible backends are supported by Supabase's GoTrue, will be removed soon")
+		cd.Dialect = config.DB.Driver
+		cd.Driver = ""
+		return nil
+	}
+	if backend.Name() != BackendPostgres {
+		logrus.Warnf("storage: the %s backend runs in the compatibility mode of Postgres", backend.Name())
 	}
+	cd.Dialect = backend.Dialect()
+
+	driver := backend.Driver()
 
 	if driver != "" && (config.Tracing.Enabled || config.Metrics.Enabled) {
 		instrumentedDriver, err := otelsql.Register(driver)
@@ -283,11 +323,16 @@ func (c *Connection) applyPercentageLimits(
 
 // showMaxConns retrieves the max_connections from the db.
 func (c *Connection) showMaxConns(ctx context.Context) (int, error) {
+	query := c.Backend().MaxConnsQuery()
+	if query == "" {
+		return 0, nil
+	}
+
 	db := c.WithContext(ctx)
 
 	var maxConns int
 	err := db.Transaction(func(tx *Connection) error {
-		return tx.RawQuery("SHOW max_connections;").First(&maxConns)
+		return tx.RawQuery(query).First(&maxConns)
 	})
 	if err != nil {
 		return 0, err
@@ -375,10 +420,15 @@ func NewCommitWithError(err error) *CommitWithError {
 func (c *Connection) Transaction(fn func(*Connection) error) error {
 	if c.TX == nil {
 		var returnErr error
+		markWrite(c.Context())
+		var hooks []func()
 		if terr := c.Connection.Transaction(func(tx *pop.Connection) error {
 			conn := c.Copy()
 			conn.Connection = tx
 
+			afterCommitHooks.Store(tx.TX, &hooks)
+			defer afterCommitHooks.Delete(tx.TX)
+
 			err := fn(conn)
 			switch err.(type) {
 			case *CommitWithError:
@@ -396,11 +446,32 @@ func (c *Connection) Transaction(fn func(*Connection) error) error {
 				return terr
 			}
 		}
+		for _, hook := range hooks {
+			hook()
+		}
 		return returnErr
 	}
 	return fn(c)
 }
 
+// afterCommitHooks holds the functions to call once each transaction started
+// by Transaction is committed, by *pop.Tx.
+var afterCommitHooks sync.Map
+
+// AfterCommit calls fn once the transaction of conn is committed, and never
+// if it is rolled back. When conn is not in a transaction started by
+// Transaction, fn is called immediately. It takes a *pop.Connection so that
+// it can be used from pop callbacks.
+func AfterCommit(conn *pop.Connection, fn func()) {
+	if conn.TX != nil {
+		if hooks, ok := afterCommitHooks.Load(conn.TX); ok {
+			*hooks.(*[]func()) = append(*hooks.(*[]func()), fn)
+			return
+		}
+	}
+	fn()
+}
+
 // WithContext returns a new connection with an updated context. This is
 // typically used for tracing as the context contains trace span information.
 func (c *Connection) WithContext(ctx context.Context) *Connection {
@@ -435,3 +506,51 @@ func getExcludedColumns(model interface{}, includeColumns ...string) ([]string,
 	}
 	return xcols, nil
 }
+
+// ConnectionURL returns the connection string with the statement cache
+// options of the configuration, which the pgx driver reads from it.
+func ConnectionURL(dbCfg *conf.DBConfiguration, connString string) (string, error) {
+	params := make(map[string]string)
+	if dbCfg.StatementCacheMode != "" {
+		params["statement_cache_mode"] = dbCfg.StatementCacheMode
+	}
+	if dbCfg.StatementCacheCapacity != 0 {
+		params["statement_cache_capacity"] = fmt.Sprint(max(dbCfg.StatementCacheCapacity, 0))
+	}
+	if dbCfg.PgbouncerMode {
+		// transaction pooling runs the statements of a connection on
+		// different server connections, which do not share prepared
+		// statements, so none are created
+		params["prefer_simple_protocol"] = "true"
+		params["statement_cache_capacity"] = "0"
+		delete(params, "statement_cache_mode")
+	}
+	if len(params) == 0 {
+		return connString, nil
+	}
+
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	if !strings.HasPrefix(connString, "postgres://") && !strings.HasPrefix(connString, "postgresql://") {
+		// keyword/value connection strings
+		for _, k := range keys {
+			connString += fmt.Sprintf(" %s=%s", k, params[k])
+		}
+		return connString, nil
+	}
+
+	u, err := url.Parse(connString)
+	if err != nil {
+		return "", errors.Wrap(err, "parsing db connection url")
+	}
+	q := u.Query()
+	for _, k := range keys {
+		q.Set(k, params[k])
+	}
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
@@ -65,6 +65,41 @@ func TestTransaction(t *testing.T) {
 	require.Empty(t, data)
 }
 
+func TestAfterCommit(t *testing.T) {
+	apiTestConfig := "../../hack/test.env"
+	config, err := conf.LoadGlobal(apiTestConfig)
+	require.NoError(t, err)
+	conn, err := Dial(config)
+	require.NoError(t, err)
+
+	var calls []string
+	AfterCommit(conn.Connection, func() { calls = append(calls, "immediate") })
+	require.Equal(t, []string{"immediate"}, calls)
+
+	err = conn.Transaction(func(tx *Connection) error {
+		AfterCommit(tx.Connection, func() { calls = append(calls, "committed") })
+
+		// nested transactions use the transaction of the outer one
+		require.NoError(t, tx.Transaction(func(nested *Connection) error {
+			AfterCommit(nested.Connection, func() { calls = append(calls, "nested") })
+			return nil
+		}))
+
+		require.Equal(t, []string{"immediate"}, calls)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"immediate", "committed", "nested"}, calls)
+
+	rollback := errors.New("rollback")
+	err = conn.Transaction(func(tx *Connection) error {
+		AfterCommit(tx.Connection, func() { calls = append(calls, "rolled back") })
+		return rollback
+	})
+	require.ErrorIs(t, err, rollback)
+	require.Equal(t, []string{"immediate", "committed", "nested"}, calls)
+}
+
 func TestPopConnToStd(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
 	defer cancel()
@@ -501,3 +536,49 @@ func mustConfig(t *testing.T) *conf.GlobalConfiguration {
 	config.DB = *dbCfg
 	return config
 }
+
+func TestConnectionURL(t *testing.T) {
+	cases := []struct {
+		desc  string
+		dbCfg conf.DBConfiguration
+		url   string
+		exp   string
+	}{
+		{
+			desc: "Unchanged",
+			url:  "postgres://auth@localhost:5432/postgres",
+			exp:  "postgres://auth@localhost:5432/postgres",
+		},
+		{
+			desc:  "StatementCache",
+			dbCfg: conf.DBConfiguration{StatementCacheMode: "describe", StatementCacheCapacity: 64},
+			url:   "postgres://auth@localhost:5432/postgres?sslmode=disable",
+			exp:   "postgres://auth@localhost:5432/postgres?sslmode=disable&statement_cache_capacity=64&statement_cache_mode=describe",
+		},
+		{
+			desc:  "StatementCacheDisabled",
+			dbCfg: conf.DBConfiguration{StatementCacheCapacity: -1},
+			url:   "postgres://auth@localhost:5432/postgres",
+			exp:   "postgres://auth@localhost:5432/postgres?statement_cache_capacity=0",
+		},
+		{
+			desc:  "Pgbouncer",
+			dbCfg: conf.DBConfiguration{PgbouncerMode: true, StatementCacheMode: "prepare"},
+			url:   "postgresql://auth@localhost:6432/postgres",
+			exp:   "postgresql://auth@localhost:6432/postgres?prefer_simple_protocol=true&statement_cache_capacity=0",
+		},
+		{
+			desc:  "KeywordValue",
+			dbCfg: conf.DBConfiguration{PgbouncerMode: true},
+			url:   "host=localhost user=auth",
+			exp:   "host=localhost user=auth prefer_simple_protocol=true statement_cache_capacity=0",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			u, err := ConnectionURL(&tc.dbCfg, tc.url)
+			require.NoError(t, err)
+			require.Equal(t, tc.exp, u)
+		})
+	}
+}
@@ -0,0 +1,172 @@
+package storage
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gobuffalo/pop/v6"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/supabase/auth/internal/conf"
+)
+
+// replicaLagQuery returns the seconds the replica lags behind the primary.
+// A replica which replayed all it received while streaming from the primary
+// is not lagging, even when the primary has not written for a while. Once
+// streaming stalls, the time since the last replayed transaction is the lag,
+// as the replica can not know what it missed. The status of the WAL receiver
+// is only visible to roles with the privileges of pg_read_all_stats, for
+// other roles a running WAL receiver is taken as streaming.
+const replicaLagQuery = `select case
+	when pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn()
+		and exists (select 1 from pg_stat_wal_receiver where coalesce(status, 'streaming') = 'streaming') then 0
+	else coalesce(extract(epoch from now() - pg_last_xact_replay_timestamp()), 0)
+end`
+
+// replica is a read-only connection to a replica of the primary, which is
+// used while its lag is within the configured maximum.
+type replica struct {
+	conn    *pop.Connection
+	healthy atomic.Bool
+	stop    chan struct{}
+	once    sync.Once
+}
+
+func dialReplica(ctx context.Context, config *conf.GlobalConfiguration) (*replica, error) {
+	cd, err := newConnectionDetails(config)
+	if err != nil {
+		return nil, err
+	}
+	cd.URL, err = ConnectionURL(&config.DB, config.DB.ReplicaURL)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := pop.NewConnection(cd)
+	if err != nil {
+		return nil, errors.Wrap(err, "opening replica connection")
+	}
+	if err := db.Open(); err != nil {
+		return nil, errors.Wrap(err, "checking replica connection")
+	}
+
+	r := &replica{
+		conn: db,
+		stop: make(chan struct{}),
+	}
+	r.check(ctx, config.DB.ReplicaMaxLag)
+	go r.monitor(config.DB.ReplicaMaxLag, config.DB.ReplicaCheckInterval)
+	return r, nil
+}
+
+// check marks the replica healthy when its lag is within maxLag.
+func (r *replica) check(ctx context.Context, maxLag time.Duration) {
+	var lag float64
+	err := r.conn.WithContext(ctx).RawQuery(replicaLagQuery).First(&lag)
+	if err != nil {
+		logrus.WithError(err).Warn("storage: unable to determine the lag of the replica")
+	}
+
+	healthy := err == nil && time.Duration(lag*float64(time.Second)) <= maxLag
+	if r.healthy.Swap(healthy) != healthy && !healthy {
+		logrus.WithField("lag_seconds", lag).Warn("storage: reading from the primary as the replica lags behind")
+	}
+}
+
+func (r *replica) monitor(maxLag, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.stop:
+			return
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), interval)
+			r.check(ctx, maxLag)
+			cancel()
+		}
+	}
+}
+
+func (r *replica) close() error {
+	r.once.Do(func() { close(r.stop) })
+	return r.conn.Close()
+}
+
+type replicaReadsKey struct{}
+
+// replicaReads tracks whether a context wrote to the primary, after which
+// it reads its own writes from the primary.
+type replicaReads struct {
+	wrote atomic.Bool
+}
+
+// WithReplicaReads allows Reader to use the replica for the context, until
+// it writes to the primary.
+func WithReplicaReads(ctx context.Context) context.Context {
+	return context.WithValue(ctx, replicaReadsKey{}, &replicaReads{})
+}
+
+func markWrite(ctx context.Context) {
+	if ctx == nil {
+		return
+	}
+	if rr, ok := ctx.Value(replicaReadsKey{}).(*replicaReads); ok {
+		rr.wrote.Store(true)
+	}
+}
+
+// Reader returns a connection for queries which can read slightly stale
+// data, such as lookups of users and sessions. It is the replica when one is
+// configured, its lag is within the maximum and the context of the
+// connection allows replica reads and has not written yet. Otherwise it is
+// the connection itself.
+func (c *Connection) Reader() *Connection {
+	if c.replica == nil || c.TX != nil || !c.replica.healthy.Load() {
+		return c
+	}
+
+	ctx := c.Context()
+	rr, ok := ctx.Value(replicaReadsKey{}).(*replicaReads)
+	if !ok || rr.wrote.Load() {
+		return c
+	}
+
+	cpy := c.Copy()
+	cpy.Connection = c.replica.conn.WithContext(ctx)
+	return cpy
+}
+
+// Create marks the context of the connection as having written, before
+// creating the model.
+func (c *Connection) Create(model interface{}, excludeColumns ...string) error {
+	markWrite(c.Context())
+	return c.Connection.Create(model, excludeColumns...)
+}
+
+// Update marks the context of the connection as having written, before
+// updating the model.
+func (c *Connection) Update(model interface{}, excludeColumns ...string) error {
+	markWrite(c.Context())
+	return c.Connection.Update(model, excludeColumns...)
+}
+
+// Destroy marks the context of the connection as having written, before
+// destroying the model.
+func (c *Connection) Destroy(model interface{}) error {
+	markWrite(c.Context())
+	return c.Connection.Destroy(model)
+}
+
+// Close closes the connection and the replica.
+func (c *Connection) Close() error {
+	if c.replica != nil {
+		if err := c.replica.close(); err != nil {
+			logrus.WithError(err).Warn("storage: error closing replica connection")
+		}
+	}
+	return c.Connection.Close()
+}
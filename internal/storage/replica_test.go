@@ -0,0 +1,44 @@
+package storage
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/gobuffalo/pop/v6"
+	"github.com/stretchr/testify/require"
+)
+
+// requireConn requires db to be a copy of the connection with the given ID,
+// as pop appends a suffix to the IDs of copies.
+func requireConn(t *testing.T, id string, db *Connection) {
+	require.True(t, strings.HasPrefix(db.ID, id), "expected %s, got %s", id, db.ID)
+}
+
+func TestReader(t *testing.T) {
+	r := &replica{conn: &pop.Connection{ID: "replica"}}
+	r.healthy.Store(true)
+	primary := &Connection{
+		Connection: &pop.Connection{ID: "primary"},
+		replica:    r,
+	}
+
+	// replica reads are not allowed for the context
+	requireConn(t, "primary", primary.WithContext(context.Background()).Reader())
+
+	ctx := WithReplicaReads(context.Background())
+	db := primary.WithContext(ctx)
+	requireConn(t, "replica", db.Reader())
+
+	// the replica is not used while it lags behind
+	r.healthy.Store(false)
+	requireConn(t, "primary", db.Reader())
+	r.healthy.Store(true)
+
+	// the context reads its own writes after writing
+	markWrite(ctx)
+	requireConn(t, "primary", db.Reader())
+
+	// without a replica the connection itself is used
+	requireConn(t, "primary", (&Connection{Connection: &pop.Connection{ID: "primary"}}).WithContext(WithReplicaReads(context.Background())).Reader())
+}
@@ -0,0 +1,147 @@
+// Package storagetest is a conformance test suite for the backends of the
+// storage package. Run it against a connection of a backend to check the
+// behavior of connections the models rely on, such as transactions, partial
+// updates and atomic increments. It does not test the models themselves,
+// which only run on Postgres and engines compatible with it.
+package storagetest
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/require"
+	"github.com/supabase/auth/internal/storage"
+)
+
+const tableName = "storage_conformance"
+
+type record struct {
+	ID        uuid.UUID `db:"id"`
+	Name      string    `db:"name"`
+	Counter   int       `db:"counter"`
+	CreatedAt time.Time `db:"created_at"`
+	UpdatedAt time.Time `db:"updated_at"`
+}
+
+func (record) TableName() string {
+	return tableName
+}
+
+// Run runs the conformance test suite against conn. It creates and drops a
+// table of its own.
+func Run(t *testing.T, conn *storage.Connection) {
+	require.NoError(t, conn.RawQuery("drop table if exists "+tableName).Exec())
+	require.NoError(t, conn.RawQuery(`create table `+tableName+` (
+		id varchar(36) primary key,
+		name varchar(255) not null,
+		counter integer not null,
+		created_at timestamp not null,
+		updated_at timestamp not null
+	)`).Exec())
+	t.Cleanup(func() {
+		_ = conn.RawQuery("drop table if exists " + tableName).Exec()
+	})
+
+	t.Run("Backend", func(t *testing.T) {
+		b := conn.Backend()
+		if query := b.MaxConnsQuery(); query != "" {
+			var maxConns int
+			require.NoError(t, conn.RawQuery(query).First(&maxConns))
+			require.Greater(t, maxConns, 0)
+		}
+	})
+
+	t.Run("CreateFind", func(t *testing.T) {
+		r := &record{ID: uuid.Must(uuid.NewV4()), Name: "create"}
+		require.NoError(t, conn.Create(r))
+
+		found := &record{}
+		require.NoError(t, conn.Find(found, r.ID))
+		require.Equal(t, r.Name, found.Name)
+		require.False(t, found.CreatedAt.IsZero())
+	})
+
+	t.Run("TransactionCommit", func(t *testing.T) {
+		r := &record{ID: uuid.Must(uuid.NewV4()), Name: "commit"}
+		require.NoError(t, conn.Transaction(func(tx *storage.Connection) error {
+			return tx.Create(r)
+		}))
+		require.NoError(t, conn.Find(&record{}, r.ID))
+	})
+
+	t.Run("TransactionRollback", func(t *testing.T) {
+		errRollback := errors.New("rollback")
+		r := &record{ID: uuid.Must(uuid.NewV4()), Name: "rollback"}
+		err := conn.Transaction(func(tx *storage.Connection) error {
+			if err := tx.Create(r); err != nil {
+				return err
+			}
+			return errRollback
+		})
+		require.ErrorIs(t, err, errRollback)
+		require.Error(t, conn.Find(&record{}, r.ID))
+	})
+
+	t.Run("TransactionCommitWithError", func(t *testing.T) {
+		r := &record{ID: uuid.Must(uuid.NewV4()), Name: "commit_with_error"}
+		err := conn.Transaction(func(tx *storage.Connection) error {
+			if err := tx.Create(r); err != nil {
+				return err
+			}
+			return storage.NewCommitWithError(errors.New("committed"))
+		})
+		require.ErrorContains(t, err, "committed")
+		require.NoError(t, conn.Find(&record{}, r.ID))
+	})
+
+	t.Run("NestedTransaction", func(t *testing.T) {
+		r := &record{ID: uuid.Must(uuid.NewV4()), Name: "nested"}
+		err := conn.Transaction(func(tx *storage.Connection) error {
+			if err := tx.Transaction(func(inner *storage.Connection) error {
+				require.Same(t, tx.TX, inner.TX)
+				return inner.Create(r)
+			}); err != nil {
+				return err
+			}
+			return errors.New("rollback")
+		})
+		require.Error(t, err)
+		require.Error(t, conn.Find(&record{}, r.ID))
+	})
+
+	t.Run("UpdateOnly", func(t *testing.T) {
+		r := &record{ID: uuid.Must(uuid.NewV4()), Name: "update_only"}
+		require.NoError(t, conn.Create(r))
+
+		r.Name = "changed"
+		r.Counter = 1
+		require.NoError(t, conn.UpdateOnly(r, "counter"))
+
+		found := &record{}
+		require.NoError(t, conn.Find(found, r.ID))
+		require.Equal(t, "update_only", found.Name)
+		require.Equal(t, 1, found.Counter)
+	})
+
+	t.Run("ConcurrentIncrement", func(t *testing.T) {
+		r := &record{ID: uuid.Must(uuid.NewV4()), Name: "increment"}
+		require.NoError(t, conn.Create(r))
+
+		// counters are incremented in the database, as failed sign-ins are
+		const n = 10
+		errs := make(chan error, n)
+		for i := 0; i < n; i++ {
+			go func() {
+				errs <- conn.RawQuery("update "+tableName+" set counter = counter + 1 where id = ?", r.ID).Exec()
+			}()
+		}
+		for i := 0; i < n; i++ {
+			require.NoError(t, <-errs)
+		}
+
+		require.NoError(t, conn.Reload(r))
+		require.Equal(t, n, r.Counter)
+	})
+}
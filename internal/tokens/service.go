@@ -13,16 +13,20 @@ import (
 
 	"github.com/gofrs/uuid"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/lestrrat-go/jwx/v2/jwk"
 	"github.com/xeipuuv/gojsonschema"
 
 	"github.com/supabase/auth/internal/api/apierrors"
 	"github.com/supabase/auth/internal/conf"
 	"github.com/supabase/auth/internal/crypto"
+	"github.com/supabase/auth/internal/geoip"
 	"github.com/supabase/auth/internal/hooks/v0hooks"
 	"github.com/supabase/auth/internal/metering"
 	"github.com/supabase/auth/internal/models"
+	"github.com/supabase/auth/internal/observability"
 	"github.com/supabase/auth/internal/storage"
 	"github.com/supabase/auth/internal/utilities"
+	"github.com/supabase/auth/internal/webhooks"
 )
 
 const retryLoopDuration = 5.0
@@ -82,6 +86,10 @@ type AccessTokenClaims struct {
 	IsAnonymous                   bool                   `json:"is_anonymous"`
 	ClientID                      string                 `json:"client_id,omitempty"`
 	Scope                         string                 `json:"scope,omitempty"`
+	Organizations                 map[string]string      `json:"orgs,omitempty"`
+	Roles                         []string               `json:"roles,omitempty"`
+	Permissions                   []string               `json:"permissions,omitempty"`
+	Actor                         *models.SessionActor   `json:"act,omitempty"`
 }
 
 // IDTokenClaims represents OpenID Connect ID Token claims
@@ -111,7 +119,13 @@ type AccessTokenResponse struct {
 	ProviderAccessToken  string       `json:"provider_token,omitempty"`
 	ProviderRefreshToken string       `json:"provider_refresh_token,omitempty"`
 	WeakPassword         interface{}  `json:"weak_password,omitempty"`
-	IDToken              string       `json:"id_token,omitempty"` // OIDC ID Token
+	IDToken              string       `json:"id_token,omitempty"`     // OIDC ID Token
+	DeviceToken          string       `json:"device_token,omitempty"` // MFA trusted device token
+	Scope                string       `json:"scope,omitempty"`        // OAuth scopes of the access token
+
+	// RecoveryCodes are issued when the first factor of the user is verified
+	// and are only ever returned here.
+	RecoveryCodes []string `json:"recovery_codes,omitempty"`
 }
 
 // GenerateAccessTokenParams contains parameters for generating access tokens
@@ -120,6 +134,7 @@ type GenerateAccessTokenParams struct {
 	SessionID            *uuid.UUID
 	AuthenticationMethod models.AuthenticationMethod
 	ClientID             *uuid.UUID // OAuth2 server client ID if applicable
+	Scopes               []string   // Narrows the scopes of the session for this token, if set
 }
 
 // GenerateIDTokenParams contains parameters for generating OIDC ID tokens
@@ -135,6 +150,7 @@ type GenerateIDTokenParams struct {
 type RefreshTokenGrantParams struct {
 	RefreshToken string
 	ClientID     *uuid.UUID // OAuth2 server client ID if applicable
+	Scopes       []string   // Subset of the session's scopes requested for the access token, if any
 }
 
 // AsRedirectURL encodes the AccessTokenResponse as a redirect URL that
@@ -161,8 +177,20 @@ type Service struct {
 	config      *conf.GlobalConfiguration
 	hookManager HookManager
 	now         func() time.Time
+	locator     geoip.Locator
+	signInFunc  SignInFunc
+	riskFunc    RiskFunc
 }
 
+// SignInFunc is called in the transaction issuing a new session, after the
+// session is created.
+type SignInFunc func(r *http.Request, tx *storage.Connection, user *models.User, session *models.Session) error
+
+// RiskFunc is called before a new session is issued, to assess the risk of
+// the sign-in. It may reject the sign-in, or change the grant params of the
+// session.
+type RiskFunc func(r *http.Request, conn *storage.Connection, user *models.User, authenticationMethod models.AuthenticationMethod, grantParams *models.GrantParams) error
+
 // NewService creates a new token service
 func NewService(config *conf.GlobalConfiguration, hookManager HookManager) *Service {
 	if hookManager == nil {
@@ -183,6 +211,22 @@ func (s *Service) SetTimeFunc(timeFunc func() time.Time) {
 	}
 }
 
+// SetLocator sets the locator used to record where new sessions are signed
+// in from.
+func (s *Service) SetLocator(locator geoip.Locator) {
+	s.locator = locator
+}
+
+// SetSignInFunc sets the function called when a new session is issued.
+func (s *Service) SetSignInFunc(signInFunc SignInFunc) {
+	s.signInFunc = signInFunc
+}
+
+// SetRiskFunc sets the function assessing the risk of new sessions.
+func (s *Service) SetRiskFunc(riskFunc RiskFunc) {
+	s.riskFunc = riskFunc
+}
+
 // RefreshTokenGrant implements the refresh_token grant type flow
 func (s *Service) RefreshTokenGrant(ctx context.Context, db *storage.Connection, r *http.Request, responseHeaders http.Header, params RefreshTokenGrantParams) (*AccessTokenResponse, error) {
 	db = db.WithContext(ctx)
@@ -217,6 +261,10 @@ func (s *Service) RefreshTokenGrant(ctx context.Context, db *storage.Connection,
 			return nil, apierrors.NewBadRequestError(apierrors.ErrorCodeUserBanned, "Invalid Refresh Token: User Banned")
 		}
 
+		if user.DeletedAt != nil {
+			return nil, apierrors.NewBadRequestError(apierrors.ErrorCodeUserDeleted, "Invalid Refresh Token: User Deleted")
+		}
+
 		if session == nil {
 			if token, ok := anyToken.(*models.RefreshToken); ok {
 				// a refresh token won't have a session if it's created prior to the sessions table introduced
@@ -232,11 +280,7 @@ func (s *Service) RefreshTokenGrant(ctx context.Context, db *storage.Connection,
 		// OAuth client validation will be done inside the transaction
 		var sessionClientID *uuid.UUID
 
-		sessionValidityConfig := models.SessionValidityConfig{
-			Timebox:           config.Sessions.Timebox,
-			InactivityTimeout: config.Sessions.InactivityTimeout,
-			AllowLowAAL:       config.Sessions.AllowLowAAL,
-		}
+		sessionValidityConfig := models.NewSessionValidityConfig(&config.Sessions, user.Role)
 
 		var refreshTokenTime *time.Time
 		if token, ok := anyToken.(*models.RefreshToken); ok {
@@ -259,6 +303,12 @@ func (s *Service) RefreshTokenGrant(ctx context.Context, db *storage.Connection,
 			return nil, apierrors.NewBadRequestError(apierrors.ErrorCodeSessionExpired, "Invalid Refresh Token: Session Expired")
 		}
 
+		// Access tokens can be narrowed to fewer scopes than were granted,
+		// but never widened (RFC 6749 section 6)
+		if len(params.Scopes) > 0 && !models.HasAllScopes(session.GetScopeList(), params.Scopes) {
+			return nil, apierrors.NewOAuthError("invalid_scope", "Requested scope exceeds the scope granted to the session")
+		}
+
 		// Basic checks above passed, now we need to serialize access
 		// to the session in a transaction so that there's no
 		// concurrent modification. In the event that the refresh
@@ -306,7 +356,7 @@ func (s *Service) RefreshTokenGrant(ctx context.Context, db *storage.Connection,
 				sessionClientID = nil
 			}
 
-			if config.Sessions.SinglePerUser {
+			if config.Sessions.IsSinglePerUser(user.Role) {
 				sessions, terr := models.FindAllSessionsForUser(tx, user.ID, true /* forUpdate */)
 				if models.IsNotFoundError(terr) {
 					// because forUpdate was set, and the
@@ -397,6 +447,14 @@ func (s *Service) RefreshTokenGrant(ctx context.Context, db *storage.Connection,
 								}
 							}
 
+							if terr := models.NewAuditLogEntry(config.AuditLog, r, tx, user, models.TokenReuseDetectedAction, "", map[string]interface{}{
+								"session_id":       session.ID,
+								"refresh_token_id": token.ID,
+								"revoked":          config.Security.RefreshTokenRotationEnabled,
+							}); terr != nil {
+								return terr
+							}
+
 							return storage.NewCommitWithError(apierrors.NewBadRequestError(apierrors.ErrorCodeRefreshTokenAlreadyUsed, "Invalid Refresh Token: Already Used").WithInternalMessage("Possible abuse attempt: %v", token.ID))
 						}
 					}
@@ -515,6 +573,14 @@ func (s *Service) RefreshTokenGrant(ctx context.Context, db *storage.Connection,
 							return apierrors.NewInternalServerError("destroying session after detected refresh token reuse failed").WithInternalError(terr)
 						}
 
+						if terr := models.NewAuditLogEntry(config.AuditLog, r, tx, user, models.TokenReuseDetectedAction, "", map[string]interface{}{
+							"session_id":         session.ID,
+							"counter_difference": counterDifference,
+							"revoked":            true,
+						}); terr != nil {
+							return terr
+						}
+
 						responseHeaders.Set("sb-auth-refresh-token-rotated", "true")
 
 						return storage.NewCommitWithError(apierrors.NewBadRequestError(apierrors.ErrorCodeRefreshTokenAlreadyUsed, "Invalid Refresh Token: Already Used").WithInternalMessage("Refresh token behind current counter by %v, session %v is terminated due to refresh token reuse", counterDifference, session.ID.String()))
@@ -524,6 +590,14 @@ func (s *Service) RefreshTokenGrant(ctx context.Context, db *storage.Connection,
 						// enabled. So only fail this
 						// request.
 
+						if terr := models.NewAuditLogEntry(config.AuditLog, r, tx, user, models.TokenReuseDetectedAction, "", map[string]interface{}{
+							"session_id":         session.ID,
+							"counter_difference": counterDifference,
+							"revoked":            false,
+						}); terr != nil {
+							return terr
+						}
+
 						return storage.NewCommitWithError(apierrors.NewBadRequestError(apierrors.ErrorCodeRefreshTokenAlreadyUsed, "Invalid Refresh Token: Already Used").WithInternalMessage("Refresh token behind current counter by %v, session %v is not terminated but error is returned", counterDifference, session.ID.String()))
 					}
 				}
@@ -544,6 +618,7 @@ func (s *Service) RefreshTokenGrant(ctx context.Context, db *storage.Connection,
 				SessionID:            &session.ID,
 				AuthenticationMethod: models.TokenRefresh,
 				ClientID:             sessionClientID,
+				Scopes:               params.Scopes,
 			})
 			if terr != nil {
 				httpErr, ok := terr.(*apierrors.HTTPError)
@@ -574,6 +649,12 @@ func (s *Service) RefreshTokenGrant(ctx context.Context, db *storage.Connection,
 				return apierrors.NewInternalServerError("failed to update session information").WithInternalError(terr)
 			}
 
+			if terr := webhooks.Emit(tx, &config.Webhooks, conf.WebhookEventTokenRefreshed, user, map[string]interface{}{
+				"session_id": session.ID,
+			}); terr != nil {
+				return apierrors.NewInternalServerError("Error queueing webhook event").WithInternalError(terr)
+			}
+
 			newTokenResponse = &AccessTokenResponse{
 				Token:        tokenString,
 				TokenType:    "bearer",
@@ -582,6 +663,11 @@ func (s *Service) RefreshTokenGrant(ctx context.Context, db *storage.Connection,
 				RefreshToken: issuedToken,
 				User:         user,
 			}
+			if len(params.Scopes) > 0 {
+				newTokenResponse.Scope = strings.Join(params.Scopes, " ")
+			} else if session.Scopes != nil {
+				newTokenResponse.Scope = *session.Scopes
+			}
 
 			return nil
 		})
@@ -597,6 +683,7 @@ func (s *Service) RefreshTokenGrant(ctx context.Context, db *storage.Connection,
 			}
 		}
 		metering.RecordLogin(metering.LoginTypeToken, user.ID, nil)
+		metering.RecordTokenIssued(models.TokenRefresh.String())
 		return newTokenResponse, nil
 	}
 
@@ -621,6 +708,11 @@ func (s *Service) GenerateAccessToken(r *http.Request, tx *storage.Connection, p
 
 	issuedAt := s.now().UTC()
 	expiresAt := issuedAt.Add(time.Second * time.Duration(config.JWT.Exp))
+	actor := session.Actor()
+	if actor != nil && session.NotAfter != nil && session.NotAfter.Before(expiresAt) {
+		// the access tokens of impersonated sessions don't outlive them
+		expiresAt = *session.NotAfter
+	}
 	var clientID string
 	if params.ClientID != nil && *params.ClientID != uuid.Nil {
 		clientID = params.ClientID.String()
@@ -631,6 +723,9 @@ func (s *Service) GenerateAccessToken(r *http.Request, tx *storage.Connection, p
 	if session.Scopes != nil {
 		scopes = *session.Scopes
 	}
+	if len(params.Scopes) > 0 {
+		scopes = strings.Join(params.Scopes, " ")
+	}
 
 	claims := &v0hooks.AccessTokenClaims{
 		RegisteredClaims: jwt.RegisteredClaims{
@@ -651,6 +746,45 @@ func (s *Service) GenerateAccessToken(r *http.Request, tx *storage.Connection, p
 		IsAnonymous:                   params.User.IsAnonymous,
 		ClientID:                      clientID,
 		Scope:                         scopes,
+		Actor:                         actor,
+	}
+	if config.Organizations.Enabled {
+		// the roles of the user by organization ID, for row level
+		// security policies of organization data
+		organizations, err := models.FindUserOrganizationRoles(tx, params.User.ID)
+		if err != nil {
+			return "", 0, err
+		}
+		if len(organizations) > 0 {
+			claims.Organizations = organizations
+		}
+	}
+	if config.RBAC.Enabled {
+		// the roles of the user and the permissions they grant, which the
+		// custom access token hook receives and may change
+		roles, err := models.FindUserRoles(tx, params.User.ID)
+		if err != nil {
+			return "", 0, err
+		}
+		if len(roles) > 0 {
+			claims.Roles = models.RoleNames(roles)
+			claims.Permissions = models.RolePermissions(roles)
+		}
+	}
+	if config.JWT.DenylistEnabled {
+		// the jti identifies the access token on the denylist when it is
+		// revoked
+		claims.ID = uuid.Must(uuid.NewV4()).String()
+	}
+	if clientID != "" {
+		// the user's audience stays first, it selects the audience of the
+		// requests made with the token
+		if aud, ok := config.JWT.ClientAudiences[clientID]; ok {
+			claims.Audience = append(claims.Audience, aud)
+		}
+		if iss, ok := config.JWT.ClientIssuers[clientID]; ok {
+			claims.Issuer = iss
+		}
 	}
 
 	var gotrueClaims jwt.Claims = claims
@@ -659,6 +793,8 @@ func (s *Service) GenerateAccessToken(r *http.Request, tx *storage.Connection, p
 			UserID:               params.User.ID,
 			Claims:               claims,
 			AuthenticationMethod: params.AuthenticationMethod.String(),
+			User:                 params.User,
+			Session:              session,
 		}
 
 		output := &v0hooks.CustomAccessTokenOutput{}
@@ -673,6 +809,11 @@ func (s *Service) GenerateAccessToken(r *http.Request, tx *storage.Connection, p
 		gotrueClaims = jwt.MapClaims(output.Claims)
 	}
 
+	gotrueClaims, err := shapeClaims(&config.JWT, gotrueClaims)
+	if err != nil {
+		return "", 0, err
+	}
+
 	signed, err := SignJWT(&config.JWT, gotrueClaims)
 	if err != nil {
 		return "", 0, err
@@ -680,6 +821,120 @@ func (s *Service) GenerateAccessToken(r *http.Request, tx *storage.Connection, p
 	return signed, expiresAt.Unix(), nil
 }
 
+// ExchangedTokenClaims are the claims of the tokens issued for downstream
+// services by the token exchange grant. The session and how it was
+// authenticated are those of the subject token.
+type ExchangedTokenClaims struct {
+	jwt.RegisteredClaims
+	Scope                         string   `json:"scope,omitempty"`
+	AuthenticatorAssuranceLevel   string   `json:"aal,omitempty"`
+	AuthenticationMethodReference AMRClaim `json:"amr,omitempty"`
+	SessionId                     string   `json:"session_id,omitempty"`
+}
+
+// GenerateExchangedToken issues a token of the user of the subject token for
+// the downstream service of the audience, with the scopes. It is signed with
+// the key of the audience, and cannot be used with Auth itself.
+func (s *Service) GenerateExchangedToken(user *models.User, subject *AccessTokenClaims, audience *conf.TokenExchangeAudienceConfiguration, scopes []string) (string, int64, error) {
+	issuedAt := s.now().UTC()
+	expiresAt := issuedAt.Add(time.Second * time.Duration(audience.Exp))
+
+	claims := &ExchangedTokenClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.Must(uuid.NewV4()).String(),
+			Subject:   user.ID.String(),
+			Audience:  jwt.ClaimStrings{audience.Audience},
+			IssuedAt:  jwt.NewNumericDate(issuedAt),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			Issuer:    s.config.JWT.Issuer,
+		},
+		Scope:                         strings.Join(scopes, " "),
+		AuthenticatorAssuranceLevel:   subject.AuthenticatorAssuranceLevel,
+		AuthenticationMethodReference: subject.AuthenticationMethodReference,
+		SessionId:                     subject.SessionId,
+	}
+
+	signed, err := signJWTWithKey(audience.SigningKey, claims)
+	if err != nil {
+		return "", 0, err
+	}
+	return signed, expiresAt.Unix(), nil
+}
+
+// ServiceTokenClaims are the claims of the service tokens issued to OAuth
+// clients with the client_credentials grant. The subject is the client.
+type ServiceTokenClaims struct {
+	jwt.RegisteredClaims
+	Role     string `json:"role"`
+	ClientID string `json:"client_id"`
+	Scope    string `json:"scope,omitempty"`
+}
+
+// GenerateServiceToken issues a token of the OAuth client itself, for the
+// audience and with the scopes. It carries no user and cannot be refreshed.
+func (s *Service) GenerateServiceToken(client *models.OAuthServerClient, audience string, scopes []string) (string, int64, error) {
+	config := s.config
+	clientID := client.ID.String()
+
+	issuedAt := s.now().UTC()
+	expiresAt := issuedAt.Add(time.Second * time.Duration(config.JWT.Exp))
+
+	claims := &ServiceTokenClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.Must(uuid.NewV4()).String(),
+			Subject:   clientID,
+			Audience:  jwt.ClaimStrings{audience},
+			IssuedAt:  jwt.NewNumericDate(issuedAt),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			Issuer:    config.JWT.Issuer,
+		},
+		Role:     config.OAuthServer.ClientCredentialsRole,
+		ClientID: clientID,
+		Scope:    strings.Join(scopes, " "),
+	}
+	if iss, ok := config.JWT.ClientIssuers[clientID]; ok {
+		claims.Issuer = iss
+	}
+
+	signed, err := SignJWT(&config.JWT, claims)
+	if err != nil {
+		return "", 0, err
+	}
+	return signed, expiresAt.Unix(), nil
+}
+
+// shapeClaims leaves the excluded claims out of the access token claims and
+// renames the metadata claims.
+func shapeClaims(config *conf.JWTConfiguration, claims jwt.Claims) (jwt.Claims, error) {
+	if len(config.ExcludeClaims) == 0 && config.AppMetadataClaim == "" && config.UserMetadataClaim == "" {
+		return claims, nil
+	}
+
+	b, err := json.Marshal(claims)
+	if err != nil {
+		return nil, err
+	}
+	shaped := jwt.MapClaims{}
+	if err := json.Unmarshal(b, &shaped); err != nil {
+		return nil, err
+	}
+
+	for _, claim := range config.ExcludeClaims {
+		delete(shaped, claim)
+	}
+	for claim, name := range map[string]string{
+		"app_metadata":  config.AppMetadataClaim,
+		"user_metadata": config.UserMetadataClaim,
+	} {
+		if value, ok := shaped[claim]; ok && name != "" {
+			delete(shaped, claim)
+			shaped[name] = value
+		}
+	}
+
+	return shaped, nil
+}
+
 // GenerateIDToken generates an OpenID Connect ID Token
 // IDToken is generated only when the signing key is an asymmetric one.
 // HS256 is not supported for ID token signing.
@@ -794,6 +1049,12 @@ func (s *Service) GenerateIDToken(params GenerateIDTokenParams) (string, error)
 func (s *Service) IssueRefreshToken(r *http.Request, responseHeaders http.Header, conn *storage.Connection, user *models.User, authenticationMethod models.AuthenticationMethod, grantParams models.GrantParams) (*AccessTokenResponse, error) {
 	config := s.config
 
+	if user.DeletedAt != nil {
+		// soft deleted users keep their data until they are purged, but
+		// can't sign in
+		return nil, apierrors.NewForbiddenError(apierrors.ErrorCodeUserDeleted, "User has been deleted")
+	}
+
 	now := s.now()
 	user.LastSignInAt = &now
 
@@ -805,9 +1066,37 @@ func (s *Service) IssueRefreshToken(r *http.Request, responseHeaders http.Header
 
 	responseHeaders.Set("sb-auth-user-id", user.ID.String())
 
+	if s.locator != nil && grantParams.Country == "" {
+		ip := grantParams.IP
+		if ip == "" {
+			ip = utilities.GetIPAddress(r)
+		}
+
+		// sign-ins don't fail when the location is unavailable
+		location, err := s.locator.Lookup(r, ip)
+		if err != nil {
+			observability.GetLogEntry(r).Entry.WithError(err).Warn("error looking up sign-in location")
+		} else if location != nil {
+			grantParams.Country = location.Country
+			grantParams.City = location.City
+		}
+	}
+
+	if s.riskFunc != nil {
+		if err := s.riskFunc(r, conn, user, authenticationMethod, &grantParams); err != nil {
+			return nil, err
+		}
+	}
+
 	err := conn.Transaction(func(tx *storage.Connection) error {
 		var terr error
 
+		if config.Hook.BeforeSignIn.Enabled {
+			if terr := s.beforeSignIn(r, tx, user, authenticationMethod); terr != nil {
+				return terr
+			}
+		}
+
 		if config.Security.RefreshTokenAlgorithmVersion == 2 {
 			session, terr := models.NewSession(user.ID, grantParams.FactorID)
 			if terr != nil {
@@ -858,6 +1147,30 @@ func (s *Service) IssueRefreshToken(r *http.Request, responseHeaders http.Header
 			return terr
 		}
 
+		if s.signInFunc != nil {
+			session, terr := models.FindSessionByID(tx, sessionID, false)
+			if terr != nil {
+				return apierrors.NewInternalServerError("Database error loading session").WithInternalError(terr)
+			}
+			if terr := s.signInFunc(r, tx, user, session); terr != nil {
+				return terr
+			}
+		}
+
+		if terr := webhooks.Emit(tx, &config.Webhooks, conf.WebhookEventLoginSuccess, user, map[string]interface{}{
+			"session_id":            sessionID,
+			"authentication_method": authenticationMethod.String(),
+		}); terr != nil {
+			return apierrors.NewInternalServerError("Error queueing webhook event").WithInternalError(terr)
+		}
+
+		if config.Sessions.IsSinglePerUser(user.Role) {
+			// the new session replaces the user's other sessions
+			if terr := models.LogoutOtherSessionsWithSameTag(tx, sessionID, config.Sessions.Tags); terr != nil {
+				return apierrors.NewInternalServerError("Database error signing out other sessions").WithInternalError(terr)
+			}
+		}
+
 		tokenString, expiresAt, terr = s.GenerateAccessToken(r, tx, GenerateAccessTokenParams{
 			User:                 user,
 			SessionID:            &sessionID,
@@ -878,6 +1191,8 @@ func (s *Service) IssueRefreshToken(r *http.Request, responseHeaders http.Header
 		return nil, err
 	}
 
+	metering.RecordTokenIssued(authenticationMethod.String())
+
 	return &AccessTokenResponse{
 		Token:        tokenString,
 		TokenType:    "bearer",
@@ -888,12 +1203,46 @@ func (s *Service) IssueRefreshToken(r *http.Request, responseHeaders http.Header
 	}, nil
 }
 
+// beforeSignIn invokes the before sign in hook, which may reject the sign in
+// or change the metadata of the user before the session is issued.
+func (s *Service) beforeSignIn(r *http.Request, tx *storage.Connection, user *models.User, authenticationMethod models.AuthenticationMethod) error {
+	input := v0hooks.NewBeforeSignInInput(r, user, authenticationMethod.String())
+	output := &v0hooks.BeforeSignInOutput{}
+	if err := s.hookManager.InvokeHook(tx, r, input, output); err != nil {
+		return err
+	}
+
+	if output.Decision == v0hooks.HookRejection {
+		if output.Message == "" {
+			output.Message = v0hooks.DefaultSignInHookRejectionMessage
+		}
+		return apierrors.NewForbiddenError(apierrors.ErrorCodeSignInRejected, "%s", output.Message)
+	}
+
+	if len(output.UserMetaData) > 0 {
+		if err := user.UpdateUserMetaData(tx, output.UserMetaData); err != nil {
+			return apierrors.NewInternalServerError("Database error updating user metadata").WithInternalError(err)
+		}
+	}
+	if changes := output.AppMetaDataChanges(); len(changes) > 0 {
+		if err := user.UpdateAppMetaData(tx, changes); err != nil {
+			return apierrors.NewInternalServerError("Database error updating user metadata").WithInternalError(err)
+		}
+	}
+
+	return nil
+}
+
 // SignJWT signs a JWT token with the configured signing key
 func SignJWT(config *conf.JWTConfiguration, claims jwt.Claims) (string, error) {
 	signingJwk, err := conf.GetSigningJwk(config)
 	if err != nil {
 		return "", err
 	}
+	return signJWTWithKey(signingJwk, claims)
+}
+
+func signJWTWithKey(signingJwk jwk.Key, claims jwt.Claims) (string, error) {
 	signingMethod := conf.GetSigningAlg(signingJwk)
 	token := jwt.NewWithClaims(signingMethod, claims)
 	if token.Header == nil {
@@ -151,6 +151,47 @@ func (ts *RefreshTokenV2Suite) TestNormalUse() {
 	}
 }
 
+func (ts *RefreshTokenV2Suite) TestNarrowedScopes() {
+	config := ts.config()
+	srv := NewService(config, &panicHookManager{})
+
+	req, err := http.NewRequest("POST", "https://example.com/", nil)
+	require.NoError(ts.T(), err)
+
+	scopes := "openid email profile"
+	at, err := srv.IssueRefreshToken(req, make(http.Header), ts.Conn, ts.User, models.OAuthProviderAuthorizationCode, models.GrantParams{
+		Scopes: &scopes,
+	})
+	require.NoError(ts.T(), err)
+
+	// scopes that were not granted cannot be requested
+	_, err = srv.RefreshTokenGrant(context.Background(), ts.Conn, req, make(http.Header), RefreshTokenGrantParams{
+		RefreshToken: at.RefreshToken,
+		Scopes:       []string{"email", "phone"},
+	})
+	require.Error(ts.T(), err)
+	require.Contains(ts.T(), err.Error(), "invalid_scope")
+
+	nrt, err := srv.RefreshTokenGrant(context.Background(), ts.Conn, req, make(http.Header), RefreshTokenGrantParams{
+		RefreshToken: at.RefreshToken,
+		Scopes:       []string{"email"},
+	})
+	require.NoError(ts.T(), err)
+	require.Equal(ts.T(), "email", nrt.Scope)
+
+	claims := &AccessTokenClaims{}
+	_, _, err = jwt.NewParser().ParseUnverified(nrt.Token, claims)
+	require.NoError(ts.T(), err)
+	require.Equal(ts.T(), "email", claims.Scope)
+
+	// the session keeps all the granted scopes
+	nrt, err = srv.RefreshTokenGrant(context.Background(), ts.Conn, req, make(http.Header), RefreshTokenGrantParams{
+		RefreshToken: nrt.RefreshToken,
+	})
+	require.NoError(ts.T(), err)
+	require.Equal(ts.T(), scopes, nrt.Scope)
+}
+
 func (ts *RefreshTokenV2Suite) TestMaliciousReuse() {
 	config := ts.config()
 	require.Equal(ts.T(), 2, config.Security.RefreshTokenAlgorithmVersion)
@@ -1126,3 +1167,41 @@ func TestAsRedirectURL(t *testing.T) {
 	require.Contains(t, fragment, "sb", "Fragment should contain Supabase Auth identifier 'sb'")
 	require.Equal(t, "", fragment.Get("sb"), "Supabase Auth identifier should have empty value")
 }
+
+func TestShapeClaims(t *testing.T) {
+	claims := &AccessTokenClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   "user",
+			ExpiresAt: jwt.NewNumericDate(time.Unix(1700000000, 0)),
+		},
+		Email:        "test@example.com",
+		Phone:        "123456789",
+		AppMetaData:  map[string]interface{}{"provider": "email"},
+		UserMetaData: map[string]interface{}{"name": "test"},
+		Role:         "authenticated",
+	}
+
+	shaped, err := shapeClaims(&conf.JWTConfiguration{}, claims)
+	require.NoError(t, err)
+	require.Same(t, claims, shaped)
+
+	shaped, err = shapeClaims(&conf.JWTConfiguration{
+		ExcludeClaims:     []string{"email", "phone"},
+		AppMetadataClaim:  "https://example.com/app",
+		UserMetadataClaim: "https://example.com/user",
+	}, claims)
+	require.NoError(t, err)
+
+	mapClaims := shaped.(jwt.MapClaims)
+	require.NotContains(t, mapClaims, "email")
+	require.NotContains(t, mapClaims, "phone")
+	require.NotContains(t, mapClaims, "app_metadata")
+	require.NotContains(t, mapClaims, "user_metadata")
+	require.Equal(t, map[string]interface{}{"provider": "email"}, mapClaims["https://example.com/app"])
+	require.Equal(t, map[string]interface{}{"name": "test"}, mapClaims["https://example.com/user"])
+	require.Equal(t, "authenticated", mapClaims["role"])
+
+	exp, err := shaped.GetExpirationTime()
+	require.NoError(t, err)
+	require.Equal(t, int64(1700000000), exp.Unix())
+}
@@ -0,0 +1,52 @@
+// Package userpurge permanently deletes the soft deleted users whose grace
+// period has ended.
+package userpurge
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/supabase/auth/internal/conf"
+	"github.com/supabase/auth/internal/models"
+	"github.com/supabase/auth/internal/storage"
+)
+
+// Purge deletes up to config.BatchSize of the soft deleted users whose
+// purge is due at now, with their identities, sessions and MFA factors. It
+// returns the number of users it purged.
+func Purge(
+	ctx context.Context,
+	db *storage.Connection,
+	config *conf.UserDeletionConfiguration,
+	now time.Time,
+	le *logrus.Entry,
+) (int, error) {
+	var purged int
+
+	err := db.WithContext(ctx).Transaction(func(tx *storage.Connection) error {
+		users, err := models.FindUsersToPurge(tx, now, config.BatchSize)
+		if err != nil {
+			return err
+		}
+
+		for _, user := range users {
+			if err := tx.Destroy(user); err != nil {
+				return err
+			}
+			le.WithFields(logrus.Fields{
+				"user_id":     user.ID,
+				"deleted_at":  user.DeletedAt,
+				"purge_after": user.PurgeAfter,
+			}).Info("userpurge: purged soft deleted user")
+		}
+
+		purged = len(users)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return purged, nil
+}
@@ -0,0 +1,94 @@
+package utilities
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// AWSCredentials are the credentials requests to AWS APIs are signed with.
+type AWSCredentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+}
+
+// SignAWSRequest adds an AWS Signature Version 4 Authorization header to a
+// request with the payload to the service in the region. The request must
+// have a Content-Type header.
+func SignAWSRequest(r *http.Request, payload, service, region string, credentials AWSCredentials, now time.Time) {
+	now = now.UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+
+	r.Header.Set("X-Amz-Date", amzDate)
+	if credentials.SessionToken != "" {
+		r.Header.Set("X-Amz-Security-Token", credentials.SessionToken)
+	}
+
+	signedHeaders := "content-type;host"
+	canonicalHeaders := "content-type:" + r.Header.Get("Content-Type") + "\n" +
+		"host:" + r.URL.Host + "\n"
+	// S3 requires the payload hash in a header, which is signed when set.
+	if contentSHA256 := r.Header.Get("X-Amz-Content-Sha256"); contentSHA256 != "" {
+		signedHeaders += ";x-amz-content-sha256"
+		canonicalHeaders += "x-amz-content-sha256:" + contentSHA256 + "\n"
+	}
+	signedHeaders += ";x-amz-date"
+	canonicalHeaders += "x-amz-date:" + amzDate + "\n"
+	if credentials.SessionToken != "" {
+		signedHeaders += ";x-amz-security-token"
+		canonicalHeaders += "x-amz-security-token:" + credentials.SessionToken + "\n"
+	}
+
+	path := r.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+
+	canonicalRequest := strings.Join([]string{
+		r.Method,
+		path,
+		r.URL.RawQuery,
+		canonicalHeaders,
+		signedHeaders,
+		awsSHA256Hex(payload),
+	}, "\n")
+
+	scope := date + "/" + region + "/" + service + "/aws4_request"
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		awsSHA256Hex(canonicalRequest),
+	}, "\n")
+
+	key := awsHMAC([]byte("AWS4"+credentials.SecretAccessKey), date)
+	key = awsHMAC(key, region)
+	key = awsHMAC(key, service)
+	key = awsHMAC(key, "aws4_request")
+	signature := hex.EncodeToString(awsHMAC(key, stringToSign))
+
+	r.Header.Set("Authorization", fmt.Sprintf(
+		"AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		credentials.AccessKeyID,
+		scope,
+		signedHeaders,
+		signature,
+	))
+}
+
+func awsSHA256Hex(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:])
+}
+
+func awsHMAC(key []byte, value string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(value))
+	return mac.Sum(nil)
+}
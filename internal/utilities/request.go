@@ -53,6 +53,40 @@ func GetIPAddress(r *http.Request) string {
 	return getIPAddressWithXFF(r)
 }
 
+// GetClientIPAddress returns the IP address of the client of the HTTP
+// request, trusting Sb-Forwarded-For only from a trusted proxy and the
+// X-Forwarded-For entries only as far as they were added by the trusted
+// proxies. It must be called with the remote address of the connection,
+// before it is rewritten from X-Forwarded-For.
+func GetClientIPAddress(r *http.Request, trustedProxies conf.IPNetworks) string {
+	addr, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		addr = r.RemoteAddr
+	}
+
+	if !trustedProxies.Contains(addr) {
+		return addr
+	}
+
+	if sbffAddr, ok := sbff.GetIPAddress(r); ok {
+		return sbffAddr
+	}
+
+	// each proxy appends the address it received the request from, so the
+	// entries are walked from the right until one was not added by a
+	// trusted proxy
+	ips := strings.Split(r.Header.Get("X-Forwarded-For"), ",")
+	for i := len(ips) - 1; i >= 0 && trustedProxies.Contains(addr); i-- {
+		parsed := net.ParseIP(strings.TrimSpace(ips[i]))
+		if parsed == nil {
+			break
+		}
+		addr = parsed.String()
+	}
+
+	return addr
+}
+
 // GetBodyBytes reads the whole request body properly into a byte array.
 func GetBodyBytes(req *http.Request) ([]byte, error) {
 	if req.Body == nil || req.Body == http.NoBody {
@@ -148,6 +148,91 @@ func TestGetIPAddressWithXFF(t *tst.T) {
 	}
 }
 
+func TestGetClientIPAddress(t *tst.T) {
+	var trustedProxies conf.IPNetworks
+	require.NoError(t, trustedProxies.Decode("10.0.0.0/8"))
+
+	testCases := []struct {
+		name       string
+		remoteAddr string
+		xff        string
+		sbff       string
+		expAddr    string
+	}{
+		{
+			name:       "UntrustedRemote",
+			remoteAddr: "192.0.2.1:8080",
+			xff:        "198.51.100.1",
+			expAddr:    "192.0.2.1",
+		},
+		{
+			name:       "SpoofedSBFF",
+			remoteAddr: "192.0.2.1:8080",
+			sbff:       "198.51.100.1",
+			expAddr:    "192.0.2.1",
+		},
+		{
+			name:       "TrustedSBFF",
+			remoteAddr: "10.0.0.1:8080",
+			xff:        "203.0.113.1",
+			sbff:       "198.51.100.1",
+			expAddr:    "198.51.100.1",
+		},
+		{
+			name:       "TrustedRemote",
+			remoteAddr: "10.0.0.1:8080",
+			xff:        "198.51.100.1",
+			expAddr:    "198.51.100.1",
+		},
+		{
+			name:       "SpoofedEntry",
+			remoteAddr: "10.0.0.1:8080",
+			xff:        "203.0.113.1, 198.51.100.1",
+			expAddr:    "198.51.100.1",
+		},
+		{
+			name:       "TrustedProxyChain",
+			remoteAddr: "10.0.0.1:8080",
+			xff:        "203.0.113.1, 198.51.100.1, 10.0.0.2",
+			expAddr:    "198.51.100.1",
+		},
+		{
+			name:       "InvalidEntry",
+			remoteAddr: "10.0.0.1:8080",
+			xff:        "198.51.100.1, invalid",
+			expAddr:    "10.0.0.1",
+		},
+		{
+			name:       "MissingXFF",
+			remoteAddr: "10.0.0.1:8080",
+			expAddr:    "10.0.0.1",
+		},
+	}
+
+	config := conf.SecurityConfiguration{
+		SbForwardedForEnabled: true,
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *tst.T) {
+			var handler http.HandlerFunc = func(rw http.ResponseWriter, r *http.Request) {
+				require.Equal(t, tc.expAddr, GetClientIPAddress(r, trustedProxies))
+			}
+
+			r := httptest.NewRequest(http.MethodGet, "http://localhost/", nil)
+			r.RemoteAddr = tc.remoteAddr
+			if tc.xff != "" {
+				r.Header.Set("X-Forwarded-For", tc.xff)
+			}
+			if tc.sbff != "" {
+				r.Header.Set(sbff.HeaderName, tc.sbff)
+			}
+
+			sbff.Middleware(&config, func(r *http.Request, err error) {})(handler).ServeHTTP(nil, r)
+		})
+	}
+}
+
 func TestGetReferrer(t *tst.T) {
 	config := conf.GlobalConfiguration{
 		SiteURL:      "https://example.com",
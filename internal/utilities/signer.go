@@ -0,0 +1,52 @@
+package utilities
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	standardwebhooks "github.com/standard-webhooks/standard-webhooks/libraries/go"
+)
+
+// Signer signs outgoing requests, with AWS Signature Version 4 or as
+// Standard Webhooks, at the time returned by Now. The zero value signs at
+// the current time.
+type Signer struct {
+	// Now replaces the time requests are signed at, in tests.
+	Now func() time.Time
+}
+
+func (s *Signer) now() time.Time {
+	if s.Now != nil {
+		return s.Now()
+	}
+	return time.Now()
+}
+
+// SignAWS adds an AWS Signature Version 4 Authorization header to a request
+// with the payload to the service in the region, as SignAWSRequest does.
+func (s *Signer) SignAWS(r *http.Request, payload, service, region string, credentials AWSCredentials) {
+	SignAWSRequest(r, payload, service, region, credentials, s.now())
+}
+
+// SignWebhook signs a request with the payload as the Standard Webhooks
+// message msgID, with a signature of each of the webhooks so that receivers
+// can rotate their secrets.
+func (s *Signer) SignWebhook(r *http.Request, msgID string, payload []byte, webhooks []*standardwebhooks.Webhook) error {
+	now := s.now()
+
+	signatures := make([]string, 0, len(webhooks))
+	for _, wh := range webhooks {
+		signature, err := wh.Sign(msgID, now, payload)
+		if err != nil {
+			return err
+		}
+		signatures = append(signatures, signature)
+	}
+
+	r.Header.Set("webhook-id", msgID)
+	r.Header.Set("webhook-timestamp", fmt.Sprintf("%d", now.Unix()))
+	r.Header.Set("webhook-signature", strings.Join(signatures, ", "))
+	return nil
+}
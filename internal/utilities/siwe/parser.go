@@ -129,7 +129,7 @@ func ParseMessage(raw string) (*SIWEMessage, error) {
 			msg.ChainID = value
 
 		case "Nonce":
-			// this is supposed to be REQUIRED >8 chr alphanum but we'll leave it for now for gotrue's nonce impl
+			// this is supposed to be REQUIRED >8 chr alphanum, but it's only checked by the web3 grant when nonces issued by gotrue are required
 			msg.Nonce = value
 
 		case "Issued At":
@@ -237,3 +237,42 @@ func (m *SIWEMessage) VerifySignature(signatureHex string) bool {
 
 	return strings.EqualFold(recoveredAddr.Hex(), m.Address)
 }
+
+// Format returns the text of the message to be signed, laid out as
+// ParseMessage expects. Raw is ignored.
+func (m *SIWEMessage) Format() string {
+	var b strings.Builder
+
+	b.WriteString(m.Domain + headerSuffix + "\n")
+	b.WriteString(m.Address + "\n\n")
+	if m.Statement != nil && *m.Statement != "" {
+		b.WriteString(*m.Statement + "\n\n")
+	}
+
+	b.WriteString("URI: " + m.URI.String() + "\n")
+	b.WriteString("Version: " + m.Version + "\n")
+	if m.ChainID != "" {
+		b.WriteString("Chain ID: " + m.ChainID + "\n")
+	}
+	if m.Nonce != "" {
+		b.WriteString("Nonce: " + m.Nonce + "\n")
+	}
+	b.WriteString("Issued At: " + m.IssuedAt.UTC().Format(time.RFC3339) + "\n")
+	if m.ExpirationTime != nil {
+		b.WriteString("Expiration Time: " + m.ExpirationTime.UTC().Format(time.RFC3339) + "\n")
+	}
+	if m.NotBefore != nil {
+		b.WriteString("Not Before: " + m.NotBefore.UTC().Format(time.RFC3339) + "\n")
+	}
+	if m.RequestID != nil {
+		b.WriteString("Request ID: " + *m.RequestID + "\n")
+	}
+	if len(m.Resources) > 0 {
+		b.WriteString("Resources:\n")
+		for _, resource := range m.Resources {
+			b.WriteString("- " + resource.String() + "\n")
+		}
+	}
+
+	return strings.TrimSuffix(b.String(), "\n")
+}
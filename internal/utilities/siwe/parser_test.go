@@ -2,7 +2,9 @@ package siwe
 
 import (
 	"fmt"
+	"net/url"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 )
@@ -134,3 +136,36 @@ func TestParseMessage(t *testing.T) {
 		})
 	}
 }
+
+func TestFormatMessage(t *testing.T) {
+	uri, err := url.ParseRequestURI("https://example.com/login")
+	require.NoError(t, err)
+
+	statement := "Sign in to Example"
+	expirationTime := time.Date(2025, 3, 29, 0, 10, 0, 0, time.UTC)
+	msg := &SIWEMessage{
+		Domain:         "example.com",
+		Address:        "0xa1E993d09257291470e86778399D79A0864F327E",
+		Statement:      &statement,
+		URI:            *uri,
+		Version:        "1",
+		ChainID:        "1",
+		Nonce:          "abcdefgh12345678",
+		IssuedAt:       time.Date(2025, 3, 29, 0, 0, 0, 0, time.UTC),
+		ExpirationTime: &expirationTime,
+	}
+
+	formatted := msg.Format()
+	require.Equal(t, "example.com wants you to sign in with your Ethereum account:\n0xa1E993d09257291470e86778399D79A0864F327E\n\nSign in to Example\n\nURI: https://example.com/login\nVersion: 1\nChain ID: 1\nNonce: abcdefgh12345678\nIssued At: 2025-03-29T00:00:00Z\nExpiration Time: 2025-03-29T00:10:00Z", formatted)
+
+	parsed, err := ParseMessage(formatted)
+	require.NoError(t, err)
+	msg.Raw = formatted
+	require.Equal(t, msg, parsed)
+
+	msg.Statement = nil
+	parsed, err = ParseMessage(msg.Format())
+	require.NoError(t, err)
+	require.Nil(t, parsed.Statement)
+	require.Equal(t, "abcdefgh12345678", parsed.Nonce)
+}
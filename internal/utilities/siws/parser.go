@@ -237,3 +237,44 @@ func (m *SIWSMessage) VerifySignature(signature []byte) bool {
 
 	return ed25519.Verify(pubKey, buffer.Bytes(), signature)
 }
+
+// Format returns the text of the message to be signed, laid out as
+// ParseMessage expects. Raw is ignored.
+func (m *SIWSMessage) Format() string {
+	var b strings.Builder
+
+	b.WriteString(m.Domain + headerSuffix + "\n")
+	b.WriteString(m.Address + "\n\n")
+	if m.Statement != "" {
+		b.WriteString(m.Statement + "\n\n")
+	}
+
+	if m.URI != nil {
+		b.WriteString("URI: " + m.URI.String() + "\n")
+	}
+	b.WriteString("Version: " + m.Version + "\n")
+	if m.ChainID != "" {
+		b.WriteString("Chain ID: " + m.ChainID + "\n")
+	}
+	if m.Nonce != "" {
+		b.WriteString("Nonce: " + m.Nonce + "\n")
+	}
+	b.WriteString("Issued At: " + m.IssuedAt.UTC().Format(time.RFC3339) + "\n")
+	if !m.ExpirationTime.IsZero() {
+		b.WriteString("Expiration Time: " + m.ExpirationTime.UTC().Format(time.RFC3339) + "\n")
+	}
+	if !m.NotBefore.IsZero() {
+		b.WriteString("Not Before: " + m.NotBefore.UTC().Format(time.RFC3339) + "\n")
+	}
+	if m.RequestID != "" {
+		b.WriteString("Request ID: " + m.RequestID + "\n")
+	}
+	if len(m.Resources) > 0 {
+		b.WriteString("Resources:\n")
+		for _, resource := range m.Resources {
+			b.WriteString("- " + resource.String() + "\n")
+		}
+	}
+
+	return strings.TrimSuffix(b.String(), "\n")
+}
@@ -2,7 +2,9 @@ package siws
 
 import (
 	"fmt"
+	"net/url"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 )
@@ -124,3 +126,38 @@ func TestParseMessage(t *testing.T) {
 		})
 	}
 }
+
+func TestFormatMessage(t *testing.T) {
+	uri, err := url.ParseRequestURI("https://example.com/login")
+	require.NoError(t, err)
+	resource, err := url.ParseRequestURI("https://example.com/terms")
+	require.NoError(t, err)
+
+	msg := &SIWSMessage{
+		Domain:         "example.com",
+		Address:        "4Cw1koUQtqybLFem7uqhzMBznMPGARbFS4cjaYbM9RnR",
+		Statement:      "Sign in to Example",
+		URI:            uri,
+		Version:        "1",
+		ChainID:        "solana:mainnet",
+		Nonce:          "abcdefgh12345678",
+		IssuedAt:       time.Date(2025, 3, 29, 0, 0, 0, 0, time.UTC),
+		ExpirationTime: time.Date(2025, 3, 29, 0, 10, 0, 0, time.UTC),
+		Resources:      []*url.URL{resource},
+	}
+
+	formatted := msg.Format()
+	require.Equal(t, "example.com wants you to sign in with your Solana account:\n4Cw1koUQtqybLFem7uqhzMBznMPGARbFS4cjaYbM9RnR\n\nSign in to Example\n\nURI: https://example.com/login\nVersion: 1\nChain ID: solana:mainnet\nNonce: abcdefgh12345678\nIssued At: 2025-03-29T00:00:00Z\nExpiration Time: 2025-03-29T00:10:00Z\nResources:\n- https://example.com/terms", formatted)
+
+	parsed, err := ParseMessage(formatted)
+	require.NoError(t, err)
+	msg.Raw = formatted
+	require.Equal(t, msg, parsed)
+
+	msg.Statement = ""
+	msg.Resources = nil
+	parsed, err = ParseMessage(msg.Format())
+	require.NoError(t, err)
+	require.Equal(t, "", parsed.Statement)
+	require.Equal(t, "abcdefgh12345678", parsed.Nonce)
+}
@@ -0,0 +1,72 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	standardwebhooks "github.com/standard-webhooks/standard-webhooks/libraries/go"
+	"github.com/supabase/auth/internal/conf"
+	"github.com/supabase/auth/internal/models"
+	"github.com/supabase/auth/internal/utilities"
+)
+
+// HTTPPublisher posts deliveries to HTTP endpoints, signed as Standard
+// Webhooks so the receiver can verify they were sent by Auth.
+type HTTPPublisher struct {
+	client *http.Client
+	signer utilities.Signer
+}
+
+// NewHTTPPublisher returns an HTTPPublisher based on the given
+// configuration.
+func NewHTTPPublisher(config *conf.WebhooksConfiguration) *HTTPPublisher {
+	return &HTTPPublisher{
+		client: &http.Client{Timeout: config.Timeout},
+	}
+}
+
+// Publish implements Publisher by posting the delivery to the endpoint,
+// signed with each of its secrets. The ID of the delivery is the ID of the
+// message, so a delivery that is retried can be recognized by the receiver.
+// It returns the status code the endpoint responded with, or 0 when it did
+// not respond.
+func (p *HTTPPublisher) Publish(
+	ctx context.Context,
+	endpoint *conf.WebhookEndpointConfiguration,
+	delivery *models.WebhookDelivery,
+) (int, error) {
+	webhooks := make([]*standardwebhooks.Webhook, 0, len(endpoint.Secrets))
+	for _, secret := range endpoint.Secrets {
+		wh, err := standardwebhooks.NewWebhook(strings.TrimPrefix(secret, "v1,"))
+		if err != nil {
+			return 0, fmt.Errorf("webhooks: invalid secret of endpoint %q: %w", endpoint.Name, err)
+		}
+		webhooks = append(webhooks, wh)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, fmt.Errorf("webhooks: error creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if err := p.signer.SignWebhook(req, delivery.ID.String(), delivery.Payload, webhooks); err != nil {
+		return 0, fmt.Errorf("webhooks: error signing payload: %w", err)
+	}
+
+	res, err := p.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("webhooks: error sending event: %w", err)
+	}
+	defer utilities.SafeClose(res.Body)
+
+	if res.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+		return res.StatusCode, fmt.Errorf("webhooks: endpoint failed with status %d: %s", res.StatusCode, strings.TrimSpace(string(msg)))
+	}
+
+	return res.StatusCode, nil
+}
@@ -0,0 +1,100 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/supabase/auth/internal/conf"
+	"github.com/supabase/auth/internal/models"
+	"github.com/supabase/auth/internal/utilities"
+)
+
+const kafkaContentType = "application/vnd.kafka.json.v2+json"
+
+// KafkaPublisher produces deliveries to the topic of Kafka endpoints
+// through the v2 API of a Kafka REST Proxy, which the Confluent REST Proxy
+// and the Redpanda HTTP Proxy implement. Events are keyed by their user, so
+// the events of a user are kept in order.
+type KafkaPublisher struct {
+	client *http.Client
+}
+
+// NewKafkaPublisher returns a KafkaPublisher based on the given
+// configuration.
+func NewKafkaPublisher(config *conf.WebhooksConfiguration) *KafkaPublisher {
+	return &KafkaPublisher{
+		client: &http.Client{Timeout: config.Timeout},
+	}
+}
+
+type kafkaRecord struct {
+	Key   string          `json:"key,omitempty"`
+	Value json.RawMessage `json:"value"`
+}
+
+type kafkaProduceResponse struct {
+	Offsets []struct {
+		ErrorCode *int   `json:"error_code"`
+		Error     string `json:"error"`
+	} `json:"offsets"`
+}
+
+// Publish implements Publisher by producing the event as a record of the
+// topic of the endpoint.
+func (p *KafkaPublisher) Publish(
+	ctx context.Context,
+	endpoint *conf.WebhookEndpointConfiguration,
+	delivery *models.WebhookDelivery,
+) (int, error) {
+	record := kafkaRecord{
+		Value: json.RawMessage(delivery.Payload),
+	}
+	if delivery.UserID != nil {
+		record.Key = delivery.UserID.String()
+	}
+
+	body, err := json.Marshal(map[string]interface{}{"records": []kafkaRecord{record}})
+	if err != nil {
+		return 0, fmt.Errorf("webhooks: error encoding kafka record: %w", err)
+	}
+
+	u := strings.TrimSuffix(endpoint.URL, "/") + "/topics/" + url.PathEscape(endpoint.Topic)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("webhooks: error creating kafka request: %w", err)
+	}
+	req.Header.Set("Content-Type", kafkaContentType)
+	req.Header.Set("Accept", "application/vnd.kafka.v2+json, application/json")
+	if endpoint.Username != "" {
+		req.SetBasicAuth(endpoint.Username, endpoint.Password)
+	}
+
+	res, err := p.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("webhooks: error producing to kafka: %w", err)
+	}
+	defer utilities.SafeClose(res.Body)
+
+	data, _ := io.ReadAll(io.LimitReader(res.Body, 1<<20))
+	if res.StatusCode/100 != 2 {
+		return res.StatusCode, fmt.Errorf("webhooks: producing to kafka failed with status %d: %s", res.StatusCode, strings.TrimSpace(string(data)))
+	}
+
+	var produced kafkaProduceResponse
+	if err := json.Unmarshal(data, &produced); err != nil {
+		return res.StatusCode, fmt.Errorf("webhooks: error decoding kafka response: %w", err)
+	}
+	for _, offset := range produced.Offsets {
+		if offset.ErrorCode != nil {
+			return res.StatusCode, fmt.Errorf("webhooks: producing to kafka failed with error code %d: %s", *offset.ErrorCode, offset.Error)
+		}
+	}
+
+	return res.StatusCode, nil
+}
@@ -0,0 +1,72 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/require"
+	"github.com/supabase/auth/internal/conf"
+	"github.com/supabase/auth/internal/models"
+)
+
+func TestKafkaPublisherPublish(t *testing.T) {
+	var received struct {
+		Records []struct {
+			Key   string `json:"key"`
+			Value Event  `json:"value"`
+		} `json:"records"`
+	}
+	response := `{"offsets": [{"partition": 0, "offset": 1}]}`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/topics/auth.events", r.URL.Path)
+		require.Equal(t, kafkaContentType, r.Header.Get("Content-Type"))
+
+		username, password, ok := r.BasicAuth()
+		require.True(t, ok)
+		require.Equal(t, "auth", username)
+		require.Equal(t, "secret", password)
+
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+		w.Header().Set("Content-Type", "application/vnd.kafka.v2+json")
+		_, _ = w.Write([]byte(response))
+	}))
+	defer server.Close()
+
+	endpoint := &conf.WebhookEndpointConfiguration{
+		Name:     "events",
+		Type:     conf.WebhookEndpointTypeKafka,
+		URL:      server.URL + "/",
+		Topic:    "auth.events",
+		Username: "auth",
+		Password: "secret",
+	}
+
+	eventID := uuid.Must(uuid.NewV4())
+	userID := uuid.Must(uuid.NewV4())
+	payload, err := json.Marshal(&Event{
+		ID:        eventID,
+		Type:      conf.WebhookEventLoginSuccess,
+		CreatedAt: time.Now(),
+		Data:      map[string]interface{}{},
+	})
+	require.NoError(t, err)
+	delivery := models.NewWebhookDelivery(endpoint.Name, eventID, conf.WebhookEventLoginSuccess, &userID, payload)
+
+	p := NewKafkaPublisher(&conf.WebhooksConfiguration{Timeout: time.Second})
+
+	statusCode, err := p.Publish(context.Background(), endpoint, delivery)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, statusCode)
+	require.Len(t, received.Records, 1)
+	require.Equal(t, userID.String(), received.Records[0].Key)
+	require.Equal(t, eventID, received.Records[0].Value.ID)
+
+	response = `{"offsets": [{"partition": null, "offset": null, "error_code": 40403, "error": "Topic not found"}]}`
+	_, err = p.Publish(context.Background(), endpoint, delivery)
+	require.ErrorContains(t, err, "error code 40403")
+}
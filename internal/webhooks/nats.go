@@ -0,0 +1,171 @@
+package webhooks
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/supabase/auth/internal/conf"
+	"github.com/supabase/auth/internal/models"
+	"github.com/supabase/auth/internal/utilities"
+)
+
+// NATSPublisher publishes deliveries to the subject of NATS endpoints. It
+// speaks the client protocol of NATS directly, with a connection per
+// delivery, and waits for the server to process the message before
+// returning. When the server supports headers the ID of the delivery is
+// sent as Nats-Msg-Id, so JetStream drops the duplicates of a retried
+// delivery.
+type NATSPublisher struct {
+	timeout time.Duration
+}
+
+// NewNATSPublisher returns a NATSPublisher based on the given
+// configuration.
+func NewNATSPublisher(config *conf.WebhooksConfiguration) *NATSPublisher {
+	return &NATSPublisher{
+		timeout: config.Timeout,
+	}
+}
+
+type natsInfo struct {
+	TLSRequired bool `json:"tls_required"`
+	Headers     bool `json:"headers"`
+}
+
+type natsConnect struct {
+	Verbose   bool   `json:"verbose"`
+	Pedantic  bool   `json:"pedantic"`
+	Name      string `json:"name"`
+	Lang      string `json:"lang"`
+	Version   string `json:"version"`
+	Protocol  int    `json:"protocol"`
+	Headers   bool   `json:"headers"`
+	User      string `json:"user,omitempty"`
+	Pass      string `json:"pass,omitempty"`
+	AuthToken string `json:"auth_token,omitempty"`
+}
+
+// Publish implements Publisher by publishing the event to the subject of
+// the endpoint.
+func (p *NATSPublisher) Publish(
+	ctx context.Context,
+	endpoint *conf.WebhookEndpointConfiguration,
+	delivery *models.WebhookDelivery,
+) (int, error) {
+	u, err := url.Parse(endpoint.URL)
+	if err != nil {
+		return 0, fmt.Errorf("webhooks: invalid nats url of endpoint %q: %w", endpoint.Name, err)
+	}
+
+	host := u.Host
+	if u.Port() == "" {
+		host = net.JoinHostPort(u.Hostname(), "4222")
+	}
+
+	dialer := &net.Dialer{Timeout: p.timeout}
+	rawConn, err := dialer.DialContext(ctx, "tcp", host)
+	if err != nil {
+		return 0, fmt.Errorf("webhooks: error connecting to nats: %w", err)
+	}
+	defer utilities.SafeClose(rawConn)
+
+	deadline := time.Now().Add(p.timeout)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+	if err := rawConn.SetDeadline(deadline); err != nil {
+		return 0, fmt.Errorf("webhooks: error setting nats deadline: %w", err)
+	}
+
+	var conn net.Conn = rawConn
+	r := bufio.NewReader(conn)
+
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return 0, fmt.Errorf("webhooks: error reading nats info: %w", err)
+	}
+	if !strings.HasPrefix(line, "INFO ") {
+		return 0, fmt.Errorf("webhooks: unexpected nats greeting: %s", strings.TrimSpace(line))
+	}
+
+	var info natsInfo
+	if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "INFO ")), &info); err != nil {
+		return 0, fmt.Errorf("webhooks: error decoding nats info: %w", err)
+	}
+
+	if u.Scheme == "tls" || info.TLSRequired {
+		tlsConn := tls.Client(rawConn, &tls.Config{
+			ServerName: u.Hostname(),
+			MinVersion: tls.VersionTLS12,
+		})
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			return 0, fmt.Errorf("webhooks: error negotiating tls with nats: %w", err)
+		}
+		conn = tlsConn
+		r = bufio.NewReader(conn)
+	}
+
+	connect := &natsConnect{
+		Name:     "auth",
+		Lang:     "go",
+		Version:  "1.0.0",
+		Protocol: 1,
+		Headers:  info.Headers,
+	}
+	if endpoint.Username != "" {
+		connect.User = endpoint.Username
+		connect.Pass = endpoint.Password
+	} else {
+		connect.AuthToken = endpoint.Password
+	}
+
+	connectJSON, err := json.Marshal(connect)
+	if err != nil {
+		return 0, fmt.Errorf("webhooks: error encoding nats connect: %w", err)
+	}
+
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "CONNECT %s\r\n", connectJSON)
+	if info.Headers {
+		header := "NATS/1.0\r\nNats-Msg-Id: " + delivery.ID.String() + "\r\n\r\n"
+		fmt.Fprintf(&msg, "HPUB %s %d %d\r\n%s", endpoint.Topic, len(header), len(header)+len(delivery.Payload), header)
+	} else {
+		fmt.Fprintf(&msg, "PUB %s %d\r\n", endpoint.Topic, len(delivery.Payload))
+	}
+	msg.Write(delivery.Payload)
+	msg.WriteString("\r\nPING\r\n")
+
+	if _, err := conn.Write([]byte(msg.String())); err != nil {
+		return 0, fmt.Errorf("webhooks: error publishing to nats: %w", err)
+	}
+
+	// the server processes the commands in order, so once it responds to
+	// the PING the message was published or an error was sent before
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return 0, fmt.Errorf("webhooks: error reading nats response: %w", err)
+		}
+		line = strings.TrimSpace(line)
+
+		switch {
+		case line == "PONG":
+			return 0, nil
+
+		case line == "PING":
+			if _, err := conn.Write([]byte("PONG\r\n")); err != nil {
+				return 0, fmt.Errorf("webhooks: error responding to nats: %w", err)
+			}
+
+		case strings.HasPrefix(line, "-ERR"):
+			return 0, fmt.Errorf("webhooks: publishing to nats failed: %s", strings.TrimSpace(strings.TrimPrefix(line, "-ERR")))
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package webhooks
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/require"
+	"github.com/supabase/auth/internal/conf"
+	"github.com/supabase/auth/internal/models"
+)
+
+// natsMessage is a message received by fakeNATSServer.
+type natsMessage struct {
+	connect natsConnect
+	subject string
+	header  string
+	payload string
+}
+
+// fakeNATSServer accepts a connection at a time and speaks enough of the
+// protocol of NATS to receive a message. It responds to the PING with reply.
+func fakeNATSServer(t *testing.T, headers bool, reply string) (string, <-chan natsMessage) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = l.Close() })
+
+	messages := make(chan natsMessage, 1)
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+
+			func() {
+				defer conn.Close()
+				fmt.Fprintf(conn, "INFO {\"server_id\":\"test\",\"headers\":%t}\r\n", headers)
+
+				var msg natsMessage
+				r := bufio.NewReader(conn)
+				for {
+					line, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					fields := strings.Fields(line)
+
+					switch fields[0] {
+					case "CONNECT":
+						_ = json.Unmarshal([]byte(strings.TrimPrefix(line, "CONNECT ")), &msg.connect)
+
+					case "PUB", "HPUB":
+						msg.subject = fields[1]
+						headerLen := 0
+						if fields[0] == "HPUB" {
+							headerLen, _ = strconv.Atoi(fields[2])
+						}
+						total, _ := strconv.Atoi(fields[len(fields)-1])
+						data := make([]byte, total+2)
+						if _, err := io.ReadFull(r, data); err != nil {
+							return
+						}
+						msg.header = string(data[:headerLen])
+						msg.payload = string(data[headerLen:total])
+
+					case "PING":
+						_, _ = conn.Write([]byte(reply))
+						messages <- msg
+						return
+					}
+				}
+			}()
+		}
+	}()
+
+	return "nats://" + l.Addr().String(), messages
+}
+
+func TestNATSPublisherPublish(t *testing.T) {
+	eventID := uuid.Must(uuid.NewV4())
+	payload, err := json.Marshal(&Event{
+		ID:        eventID,
+		Type:      conf.WebhookEventUserDeleted,
+		CreatedAt: time.Now(),
+		Data:      map[string]interface{}{"soft_deleted": false},
+	})
+	require.NoError(t, err)
+
+	p := NewNATSPublisher(&conf.WebhooksConfiguration{Timeout: time.Second})
+
+	t.Run("Headers", func(t *testing.T) {
+		url, messages := fakeNATSServer(t, true, "+OK\r\nPONG\r\n")
+		endpoint := &conf.WebhookEndpointConfiguration{
+			Name:     "events",
+			Type:     conf.WebhookEndpointTypeNATS,
+			URL:      url,
+			Topic:    "auth.events",
+			Password: "token",
+		}
+		delivery := models.NewWebhookDelivery(endpoint.Name, eventID, conf.WebhookEventUserDeleted, nil, payload)
+
+		_, err := p.Publish(context.Background(), endpoint, delivery)
+		require.NoError(t, err)
+
+		msg := <-messages
+		require.Equal(t, "token", msg.connect.AuthToken)
+		require.Empty(t, msg.connect.User)
+		require.Equal(t, "auth.events", msg.subject)
+		require.Contains(t, msg.header, "Nats-Msg-Id: "+delivery.ID.String()+"\r\n")
+		require.Equal(t, string(payload), msg.payload)
+	})
+
+	t.Run("NoHeaders", func(t *testing.T) {
+		url, messages := fakeNATSServer(t, false, "PONG\r\n")
+		endpoint := &conf.WebhookEndpointConfiguration{
+			Name:     "events",
+			Type:     conf.WebhookEndpointTypeNATS,
+			URL:      url,
+			Topic:    "auth.events",
+			Username: "auth",
+			Password: "secret",
+		}
+		delivery := models.NewWebhookDelivery(endpoint.Name, eventID, conf.WebhookEventUserDeleted, nil, payload)
+
+		_, err := p.Publish(context.Background(), endpoint, delivery)
+		require.NoError(t, err)
+
+		msg := <-messages
+		require.Equal(t, "auth", msg.connect.User)
+		require.Equal(t, "secret", msg.connect.Pass)
+		require.Empty(t, msg.header)
+		require.Equal(t, string(payload), msg.payload)
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		url, _ := fakeNATSServer(t, false, "-ERR 'Permissions Violation for Publish to auth.events'\r\n")
+		endpoint := &conf.WebhookEndpointConfiguration{
+			Name:  "events",
+			Type:  conf.WebhookEndpointTypeNATS,
+			URL:   url,
+			Topic: "auth.events",
+		}
+		delivery := models.NewWebhookDelivery(endpoint.Name, eventID, conf.WebhookEventUserDeleted, nil, payload)
+
+		_, err := p.Publish(context.Background(), endpoint, delivery)
+		require.ErrorContains(t, err, "Permissions Violation")
+	})
+}
@@ -0,0 +1,66 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/supabase/auth/internal/conf"
+	"github.com/supabase/auth/internal/models"
+	"github.com/supabase/auth/internal/storage"
+)
+
+// maxNotifyPayload is the largest payload sent with NOTIFY, which Postgres
+// limits to less than 8000 bytes.
+const maxNotifyPayload = 7999
+
+// PostgresPublisher notifies the channel of Postgres endpoints with
+// pg_notify, so consumers of the database can LISTEN to events.
+type PostgresPublisher struct {
+	db *storage.Connection
+}
+
+// NewPostgresPublisher returns a PostgresPublisher notifying through db.
+func NewPostgresPublisher(db *storage.Connection) *PostgresPublisher {
+	return &PostgresPublisher{
+		db: db,
+	}
+}
+
+// notifyReference is the payload of the notification of an event too
+// large for NOTIFY. The consumer reads the event from the delivery log.
+type notifyReference struct {
+	ID         string `json:"id"`
+	Type       string `json:"type"`
+	DeliveryID string `json:"delivery_id"`
+	Truncated  bool   `json:"truncated"`
+}
+
+// Publish implements Publisher by notifying the channel of the endpoint
+// with the event, or a reference to the delivery when the event is too
+// large.
+func (p *PostgresPublisher) Publish(
+	ctx context.Context,
+	endpoint *conf.WebhookEndpointConfiguration,
+	delivery *models.WebhookDelivery,
+) (int, error) {
+	payload := []byte(delivery.Payload)
+	if len(payload) > maxNotifyPayload {
+		var err error
+		payload, err = json.Marshal(&notifyReference{
+			ID:         delivery.EventID.String(),
+			Type:       delivery.EventType,
+			DeliveryID: delivery.ID.String(),
+			Truncated:  true,
+		})
+		if err != nil {
+			return 0, fmt.Errorf("webhooks: error encoding notification: %w", err)
+		}
+	}
+
+	if err := p.db.WithContext(ctx).RawQuery("select pg_notify(?, ?)", endpoint.Topic, string(payload)).Exec(); err != nil {
+		return 0, fmt.Errorf("webhooks: error notifying postgres channel: %w", err)
+	}
+
+	return 0, nil
+}
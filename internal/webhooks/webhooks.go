@@ -0,0 +1,195 @@
+// Package webhooks sends the lifecycle events of users to the webhook
+// endpoints. An event is queued as a delivery for every endpoint subscribed
+// to it, in the transaction of the change it describes, and sent by a
+// background worker with retries. Endpoints are HTTP callbacks, Kafka
+// topics, NATS subjects or Postgres NOTIFY channels.
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/supabase/auth/internal/conf"
+	"github.com/supabase/auth/internal/models"
+	"github.com/supabase/auth/internal/storage"
+	"github.com/supabase/auth/internal/utilities"
+)
+
+// lease is how long a delivery being sent is hidden from other workers. It
+// is longer than sending a delivery may take, a delivery whose worker
+// stopped while sending it is retried once it passed.
+const lease = 5 * time.Minute
+
+// PruneBatchSize is how many delivered deliveries Prune deletes at a time.
+const PruneBatchSize = 1000
+
+// Event is the body of a webhook request, and the message published to
+// the other endpoint types.
+type Event struct {
+	ID        uuid.UUID              `json:"id"`
+	Type      string                 `json:"type"`
+	CreatedAt time.Time              `json:"created_at"`
+	RequestID string                 `json:"request_id,omitempty"`
+	Data      map[string]interface{} `json:"data"`
+}
+
+// Emit queues the event for the endpoints subscribed to it. The user, when
+// not nil, is added to the data of the event as user, and the ID of the
+// request in the context of tx as its request_id. It does nothing when
+// webhooks are disabled.
+func Emit(
+	tx *storage.Connection,
+	config *conf.WebhooksConfiguration,
+	eventType string,
+	user *models.User,
+	data map[string]interface{},
+) error {
+	if !config.Enabled {
+		return nil
+	}
+
+	var endpoints []string
+	for i := range config.Endpoints {
+		if config.Endpoints[i].Subscribes(eventType) {
+			endpoints = append(endpoints, config.Endpoints[i].Name)
+		}
+	}
+	if len(endpoints) == 0 {
+		return nil
+	}
+
+	event := &Event{
+		ID:        uuid.Must(uuid.NewV4()),
+		Type:      eventType,
+		CreatedAt: time.Now().UTC(),
+		RequestID: utilities.GetRequestID(tx.Context()),
+		Data:      make(map[string]interface{}, len(data)+1),
+	}
+	maps.Copy(event.Data, data)
+
+	var userID *uuid.UUID
+	if user != nil {
+		event.Data["user"] = user
+		userID = &user.ID
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("webhooks: error encoding event: %w", err)
+	}
+
+	for _, endpoint := range endpoints {
+		delivery := models.NewWebhookDelivery(endpoint, event.ID, eventType, userID, payload)
+		if err := tx.Create(delivery); err != nil {
+			return fmt.Errorf("webhooks: error queueing event: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Publisher sends deliveries to endpoints of a type.
+type Publisher interface {
+	// Publish sends the delivery to the endpoint. It returns the status
+	// code the endpoint responded with when it is an HTTP endpoint, or 0.
+	Publish(ctx context.Context, endpoint *conf.WebhookEndpointConfiguration, delivery *models.WebhookDelivery) (int, error)
+}
+
+// Publishers maps the endpoint types to their publishers.
+type Publishers map[string]Publisher
+
+// NewPublishers returns the publishers of every endpoint type based on the
+// given configuration. Postgres endpoints are notified through db.
+func NewPublishers(config *conf.WebhooksConfiguration, db *storage.Connection) Publishers {
+	return Publishers{
+		conf.WebhookEndpointTypeHTTP:     NewHTTPPublisher(config),
+		conf.WebhookEndpointTypeKafka:    NewKafkaPublisher(config),
+		conf.WebhookEndpointTypeNATS:     NewNATSPublisher(config),
+		conf.WebhookEndpointTypePostgres: NewPostgresPublisher(db),
+	}
+}
+
+// Process sends up to config.BatchSize deliveries that are due with the
+// publisher of the type of their endpoint.
+// Deliveries that fail are retried later, or dead-lettered once they failed
+// config.MaxAttempts times or their endpoint is no longer configured. It
+// returns the number of deliveries it attempted to send.
+func Process(
+	ctx context.Context,
+	db *storage.Connection,
+	publishers Publishers,
+	config *conf.WebhooksConfiguration,
+	le *logrus.Entry,
+) (int, error) {
+	db = db.WithContext(ctx)
+
+	deliveries, err := models.ClaimWebhookDeliveries(db, config.BatchSize, lease)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, delivery := range deliveries {
+		dle := le.WithFields(logrus.Fields{
+			"webhook_delivery_id": delivery.ID,
+			"webhook_endpoint":    delivery.Endpoint,
+			"webhook_event_type":  delivery.EventType,
+			"attempts":            delivery.Attempts,
+		})
+
+		var statusCode int
+		var sendErr error
+		retryAt := config.RetryAt(delivery.Attempts, time.Now())
+
+		endpoint := config.Endpoints.Find(delivery.Endpoint)
+		if endpoint == nil {
+			sendErr = fmt.Errorf("webhooks: endpoint %q is not configured", delivery.Endpoint)
+			retryAt = nil
+		} else if publisher, ok := publishers[endpoint.EndpointType()]; !ok {
+			sendErr = fmt.Errorf("webhooks: endpoint %q has unsupported type %q", delivery.Endpoint, endpoint.Type)
+			retryAt = nil
+		} else {
+			statusCode, sendErr = publisher.Publish(ctx, endpoint, delivery)
+		}
+
+		if sendErr == nil {
+			if err := delivery.Delivered(db, statusCode); err != nil {
+				// the event is sent again once the lease passed
+				dle.WithError(err).Error("webhooks: error recording delivered event")
+			}
+			continue
+		}
+
+		if retryAt != nil {
+			dle.WithError(sendErr).WithField("retry_at", *retryAt).Warn("webhooks: error sending event, it will be retried")
+		} else {
+			dle.WithError(sendErr).Error("webhooks: error sending event, it was dead-lettered")
+		}
+
+		var lastStatusCode *int
+		if statusCode != 0 {
+			lastStatusCode = &statusCode
+		}
+		if err := delivery.Failed(db, sendErr, lastStatusCode, retryAt); err != nil {
+			dle.WithError(err).Error("webhooks: error recording failure to send event")
+		}
+	}
+
+	return len(deliveries), nil
+}
+
+// Prune deletes up to PruneBatchSize deliveries that were delivered longer
+// than config.Retention before now. It returns the number of deliveries it
+// deleted.
+func Prune(
+	ctx context.Context,
+	db *storage.Connection,
+	config *conf.WebhooksConfiguration,
+	now time.Time,
+) (int, error) {
+	return models.DeleteDeliveredWebhookDeliveries(db.WithContext(ctx), now.Add(-config.Retention), PruneBatchSize)
+}
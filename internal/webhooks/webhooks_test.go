@@ -0,0 +1,91 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	standardwebhooks "github.com/standard-webhooks/standard-webhooks/libraries/go"
+	"github.com/stretchr/testify/require"
+	"github.com/supabase/auth/internal/conf"
+	"github.com/supabase/auth/internal/models"
+)
+
+func TestHTTPPublisherPublish(t *testing.T) {
+	const secret = "v1,whsec_aWxpa2V0b2VhdGx1bmNoYXRkb3JzZXRnb2JsaW5z"
+	now := time.Now()
+
+	status := http.StatusNoContent
+	var event Event
+	var msgID string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+
+		wh, err := standardwebhooks.NewWebhook(secret[len("v1,"):])
+		require.NoError(t, err)
+		require.NoError(t, wh.Verify(body, r.Header))
+		require.Equal(t, strconv.FormatInt(now.Unix(), 10), r.Header.Get("webhook-timestamp"))
+		msgID = r.Header.Get("webhook-id")
+
+		require.NoError(t, json.Unmarshal(body, &event))
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	config := &conf.WebhooksConfiguration{Timeout: time.Second}
+	endpoint := &conf.WebhookEndpointConfiguration{
+		Name:    "crm",
+		URL:     server.URL,
+		Secrets: conf.HTTPHookSecrets{secret},
+	}
+
+	eventID := uuid.Must(uuid.NewV4())
+	payload, err := json.Marshal(&Event{
+		ID:        eventID,
+		Type:      conf.WebhookEventUserCreated,
+		CreatedAt: now,
+		Data:      map[string]interface{}{"user": map[string]interface{}{"email": "test@example.com"}},
+	})
+	require.NoError(t, err)
+	delivery := models.NewWebhookDelivery(endpoint.Name, eventID, conf.WebhookEventUserCreated, nil, payload)
+
+	p := NewHTTPPublisher(config)
+	p.signer.Now = func() time.Time { return now }
+
+	statusCode, err := p.Publish(context.Background(), endpoint, delivery)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusNoContent, statusCode)
+	require.Equal(t, delivery.ID.String(), msgID)
+	require.Equal(t, eventID, event.ID)
+	require.Equal(t, conf.WebhookEventUserCreated, event.Type)
+
+	status = http.StatusBadGateway
+	statusCode, err = p.Publish(context.Background(), endpoint, delivery)
+	require.ErrorContains(t, err, "endpoint failed with status 502")
+	require.Equal(t, http.StatusBadGateway, statusCode)
+
+	server.Close()
+	statusCode, err = p.Publish(context.Background(), endpoint, delivery)
+	require.Error(t, err)
+	require.Equal(t, 0, statusCode)
+}
+
+func TestEmitDisabled(t *testing.T) {
+	// nothing is queued when webhooks are disabled, so no connection is
+	// needed
+	config := &conf.WebhooksConfiguration{
+		Endpoints: conf.WebhookEndpoints{{Name: "crm"}},
+	}
+	require.NoError(t, Emit(nil, config, conf.WebhookEventUserCreated, nil, nil))
+
+	config.Enabled = true
+	config.Endpoints[0].Events = []string{conf.WebhookEventLoginFailed}
+	require.NoError(t, Emit(nil, config, conf.WebhookEventUserCreated, nil, nil))
+}
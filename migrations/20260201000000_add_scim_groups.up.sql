@@ -0,0 +1,23 @@
+-- Groups provisioned through the SCIM 2.0 API
+create table if not exists {{ index .Options "Namespace" }}.scim_groups (
+    id uuid not null,
+    display_name text not null,
+    external_id text null,
+    created_at timestamptz not null default now(),
+    updated_at timestamptz not null default now(),
+    constraint scim_groups_pkey primary key (id),
+    constraint scim_groups_display_name_key unique (display_name),
+    constraint scim_groups_display_name_length check (char_length(display_name) <= 1024)
+);
+
+create table if not exists {{ index .Options "Namespace" }}.scim_group_members (
+    group_id uuid not null,
+    user_id uuid not null,
+    created_at timestamptz not null default now(),
+    constraint scim_group_members_pkey primary key (group_id, user_id),
+    constraint scim_group_members_group_id_fkey foreign key (group_id) references {{ index .Options "Namespace" }}.scim_groups(id) on delete cascade,
+    constraint scim_group_members_user_id_fkey foreign key (user_id) references {{ index .Options "Namespace" }}.users(id) on delete cascade
+);
+
+create index if not exists scim_group_members_user_id_idx
+    on {{ index .Options "Namespace" }}.scim_group_members (user_id);
@@ -0,0 +1,4 @@
+-- Tracks failed password sign-in attempts for account lockout
+alter table {{ index .Options "Namespace" }}.users
+    add column if not exists failed_sign_in_attempts integer not null default 0,
+    add column if not exists locked_until timestamptz null;
@@ -0,0 +1,15 @@
+-- Tenants served by a multi-tenant deployment
+create table if not exists {{ index .Options "Namespace" }}.tenants (
+    id uuid not null,
+    name text not null,
+    hostname text null,
+    config jsonb not null default '{}'::jsonb,
+    created_at timestamptz not null default now(),
+    updated_at timestamptz not null default now(),
+    constraint tenants_pkey primary key (id),
+    constraint tenants_name_length check (char_length(name) <= 255),
+    constraint tenants_hostname_lowercase check (hostname = lower(hostname))
+);
+
+create unique index if not exists tenants_hostname_idx
+    on {{ index .Options "Namespace" }}.tenants (hostname);
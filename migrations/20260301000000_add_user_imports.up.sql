@@ -0,0 +1,23 @@
+-- Bulk user imports started through the admin API
+do $$ begin
+    create type {{ index .Options "Namespace" }}.user_import_status as enum('pending', 'running', 'completed', 'failed');
+exception
+    when duplicate_object then null;
+end $$;
+
+create table if not exists {{ index .Options "Namespace" }}.user_imports (
+    id uuid not null,
+    status {{ index .Options "Namespace" }}.user_import_status not null default 'pending',
+    format text not null,
+    total integer not null default 0,
+    processed integer not null default 0,
+    imported integer not null default 0,
+    failed integer not null default 0,
+    errors jsonb not null default '[]'::jsonb,
+    created_at timestamptz not null default now(),
+    updated_at timestamptz not null default now(),
+    finished_at timestamptz null,
+    constraint user_imports_pkey primary key (id)
+);
+
+comment on table {{ index .Options "Namespace" }}.user_imports is 'auth: stores the progress of bulk user imports';
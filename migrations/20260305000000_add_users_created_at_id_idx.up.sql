@@ -0,0 +1,2 @@
+-- Index the order of user exports, which are paginated by (created_at, id)
+create index if not exists users_created_at_id_idx on {{ index .Options "Namespace" }}.users using btree (created_at, id);
@@ -0,0 +1,28 @@
+-- Mail queued for sending by the background worker
+do $$ begin
+    create type {{ index .Options "Namespace" }}.mail_job_status as enum('pending', 'dead');
+exception
+    when duplicate_object then null;
+end $$;
+
+create table if not exists {{ index .Options "Namespace" }}.mail_jobs (
+    id uuid not null,
+    status {{ index .Options "Namespace" }}.mail_job_status not null default 'pending',
+    recipient text not null,
+    subject text not null,
+    body text not null,
+    headers jsonb not null default '{}'::jsonb,
+    type text not null,
+    attempts integer not null default 0,
+    last_error text null,
+    run_at timestamptz not null default now(),
+    created_at timestamptz not null default now(),
+    updated_at timestamptz not null default now(),
+    constraint mail_jobs_pkey primary key (id)
+);
+
+create index if not exists mail_jobs_pending_run_at_idx
+    on {{ index .Options "Namespace" }}.mail_jobs (run_at)
+    where status = 'pending';
+
+comment on table {{ index .Options "Namespace" }}.mail_jobs is 'auth: stores mail waiting to be sent or that failed to be sent';
@@ -0,0 +1,33 @@
+-- Email templates managed with the admin API, with every version kept
+do $$ begin
+    create type {{ index .Options "Namespace" }}.email_template_format as enum('html', 'mjml');
+exception
+    when duplicate_object then null;
+end $$;
+
+create table if not exists {{ index .Options "Namespace" }}.email_templates (
+    id uuid not null,
+    tenant_id uuid null,
+    type text not null,
+    version integer not null,
+    format {{ index .Options "Namespace" }}.email_template_format not null default 'html',
+    subject text not null,
+    source text not null,
+    body text not null,
+    active boolean not null default false,
+    created_at timestamptz not null default now(),
+    updated_at timestamptz not null default now(),
+    constraint email_templates_pkey primary key (id),
+    constraint email_templates_version_positive check (version > 0)
+);
+
+create unique index if not exists email_templates_tenant_type_version_idx
+    on {{ index .Options "Namespace" }}.email_templates (coalesce(tenant_id, '00000000-0000-0000-0000-000000000000'::uuid), type, version);
+
+create unique index if not exists email_templates_tenant_type_active_idx
+    on {{ index .Options "Namespace" }}.email_templates (coalesce(tenant_id, '00000000-0000-0000-0000-000000000000'::uuid), type)
+    where active;
+
+comment on table {{ index .Options "Namespace" }}.email_templates is 'auth: stores the versions of email templates managed with the admin API';
+comment on column {{ index .Options "Namespace" }}.email_templates.tenant_id is 'auth: tenant overriding the template, null for the deployment''s template';
+comment on column {{ index .Options "Namespace" }}.email_templates.body is 'auth: HTML template the mail is rendered with, compiled from the source when the format is mjml';
@@ -0,0 +1,15 @@
+-- Email templates have a variant per locale, '' is the default locale
+alter table {{ index .Options "Namespace" }}.email_templates
+    add column if not exists locale text not null default '';
+
+drop index if exists {{ index .Options "Namespace" }}.email_templates_tenant_type_version_idx;
+drop index if exists {{ index .Options "Namespace" }}.email_templates_tenant_type_active_idx;
+
+create unique index if not exists email_templates_tenant_type_locale_version_idx
+    on {{ index .Options "Namespace" }}.email_templates (coalesce(tenant_id, '00000000-0000-0000-0000-000000000000'::uuid), type, locale, version);
+
+create unique index if not exists email_templates_tenant_type_locale_active_idx
+    on {{ index .Options "Namespace" }}.email_templates (coalesce(tenant_id, '00000000-0000-0000-0000-000000000000'::uuid), type, locale)
+    where active;
+
+comment on column {{ index .Options "Namespace" }}.email_templates.locale is 'auth: BCP 47 language tag of the template, empty for the default template';
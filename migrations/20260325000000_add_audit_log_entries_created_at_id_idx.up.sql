@@ -0,0 +1,3 @@
+-- Index the order of the audit log, which is paginated by (created_at, id)
+-- and pruned oldest first
+create index if not exists audit_log_entries_created_at_id_idx on {{ index .Options "Namespace" }}.audit_log_entries using btree (created_at, id);
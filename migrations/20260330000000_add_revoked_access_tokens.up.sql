@@ -0,0 +1,12 @@
+-- Access tokens revoked through the token revocation endpoint
+create table if not exists {{ index .Options "Namespace" }}.revoked_access_tokens (
+    jti uuid not null,
+    expires_at timestamptz not null,
+    created_at timestamptz not null default now(),
+    constraint revoked_access_tokens_pkey primary key (jti)
+);
+
+create index if not exists revoked_access_tokens_expires_at_idx
+    on {{ index .Options "Namespace" }}.revoked_access_tokens (expires_at);
+
+comment on table {{ index .Options "Namespace" }}.revoked_access_tokens is 'auth: stores the jti of revoked access tokens until they expire';
@@ -0,0 +1,27 @@
+-- Access and refresh tokens of the OAuth providers users sign in with
+create table if not exists {{ index .Options "Namespace" }}.provider_tokens (
+    id uuid not null,
+    user_id uuid not null,
+    identity_id uuid not null,
+    provider text not null,
+    access_token text not null,
+    refresh_token text null,
+    expires_at timestamptz null,
+    scopes text null,
+    refresh_error text null,
+    created_at timestamptz not null default now(),
+    updated_at timestamptz not null default now(),
+    constraint provider_tokens_pkey primary key (id),
+    constraint provider_tokens_identity_id_key unique (identity_id),
+    constraint provider_tokens_user_id_fkey foreign key (user_id) references {{ index .Options "Namespace" }}.users(id) on delete cascade,
+    constraint provider_tokens_identity_id_fkey foreign key (identity_id) references {{ index .Options "Namespace" }}.identities(id) on delete cascade
+);
+
+create index if not exists provider_tokens_user_id_provider_idx
+    on {{ index .Options "Namespace" }}.provider_tokens (user_id, provider);
+
+create index if not exists provider_tokens_refresh_idx
+    on {{ index .Options "Namespace" }}.provider_tokens (expires_at)
+    where refresh_token is not null and refresh_error is null;
+
+comment on table {{ index .Options "Namespace" }}.provider_tokens is 'auth: stores the encrypted access and refresh tokens of the OAuth providers users sign in with';
@@ -0,0 +1,13 @@
+-- One-time recovery codes users verify MFA with when they lose their authenticators
+create table if not exists {{ index .Options "Namespace" }}.mfa_recovery_codes (
+    id uuid not null,
+    user_id uuid not null,
+    code_hash text not null,
+    used_at timestamptz null,
+    created_at timestamptz not null default now(),
+    constraint mfa_recovery_codes_pkey primary key (id),
+    constraint mfa_recovery_codes_user_id_code_hash_key unique (user_id, code_hash),
+    constraint mfa_recovery_codes_user_id_fkey foreign key (user_id) references {{ index .Options "Namespace" }}.users(id) on delete cascade
+);
+
+comment on table {{ index .Options "Namespace" }}.mfa_recovery_codes is 'auth: stores the hashed one-time MFA recovery codes of users';
@@ -0,0 +1,5 @@
+-- Index used to count the recent challenges of a factor when rate limiting them
+
+create index if not exists
+  mfa_challenges_factor_id_created_at_idx
+  on {{ index .Options "Namespace" }}.mfa_challenges (factor_id, created_at desc);
@@ -0,0 +1,24 @@
+-- Devices users chose to remember after verifying MFA, which skip MFA challenges until they expire
+create table if not exists {{ index .Options "Namespace" }}.mfa_trusted_devices (
+    id uuid not null,
+    user_id uuid not null,
+    factor_id uuid not null,
+    token_hash text not null,
+    user_agent text null,
+    ip inet null,
+    created_at timestamptz not null default now(),
+    last_used_at timestamptz null,
+    expires_at timestamptz not null,
+    constraint mfa_trusted_devices_pkey primary key (id),
+    constraint mfa_trusted_devices_token_hash_key unique (token_hash),
+    constraint mfa_trusted_devices_user_id_fkey foreign key (user_id) references {{ index .Options "Namespace" }}.users(id) on delete cascade,
+    constraint mfa_trusted_devices_factor_id_fkey foreign key (factor_id) references {{ index .Options "Namespace" }}.mfa_factors(id) on delete cascade
+);
+
+create index if not exists mfa_trusted_devices_user_id_idx
+    on {{ index .Options "Namespace" }}.mfa_trusted_devices (user_id);
+
+create index if not exists mfa_trusted_devices_expires_at_idx
+    on {{ index .Options "Namespace" }}.mfa_trusted_devices (expires_at);
+
+comment on table {{ index .Options "Namespace" }}.mfa_trusted_devices is 'auth: stores the hashed tokens of the devices users chose to remember after verifying MFA';
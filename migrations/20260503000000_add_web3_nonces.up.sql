@@ -0,0 +1,14 @@
+-- Nonces issued for Sign in with Ethereum and Solana messages, each accepted once until it expires
+create table if not exists {{ index .Options "Namespace" }}.web3_nonces (
+    id uuid not null,
+    nonce text not null,
+    created_at timestamptz not null default now(),
+    expires_at timestamptz not null,
+    constraint web3_nonces_pkey primary key (id),
+    constraint web3_nonces_nonce_key unique (nonce)
+);
+
+create index if not exists web3_nonces_expires_at_idx
+    on {{ index .Options "Namespace" }}.web3_nonces (expires_at);
+
+comment on table {{ index .Options "Namespace" }}.web3_nonces is 'auth: stores the nonces issued for signed Web3 sign in messages';
@@ -0,0 +1,18 @@
+-- Email domains users may or may not sign up with through a provider, managed with the admin API
+create table if not exists {{ index .Options "Namespace" }}.provider_domain_rules (
+    id uuid not null,
+    tenant_id uuid null,
+    provider text not null,
+    allowed_domains text not null default '',
+    denied_domains text not null default '',
+    created_at timestamptz not null default now(),
+    updated_at timestamptz not null default now(),
+    constraint provider_domain_rules_pkey primary key (id)
+);
+
+create unique index if not exists provider_domain_rules_tenant_provider_idx
+    on {{ index .Options "Namespace" }}.provider_domain_rules (coalesce(tenant_id, '00000000-0000-0000-0000-000000000000'::uuid), provider);
+
+comment on table {{ index .Options "Namespace" }}.provider_domain_rules is 'auth: stores the email domain rules of providers managed with the admin API, used instead of the configured domains';
+comment on column {{ index .Options "Namespace" }}.provider_domain_rules.tenant_id is 'auth: tenant the rule applies to, null for the deployment''s rule';
+comment on column {{ index .Options "Namespace" }}.provider_domain_rules.allowed_domains is 'auth: comma separated email domains users may sign up with, any domain when empty';
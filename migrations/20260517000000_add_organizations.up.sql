@@ -0,0 +1,30 @@
+-- Organizations group users, who are members with a role in each organization they belong to
+create table if not exists {{ index .Options "Namespace" }}.organizations (
+    id uuid not null,
+    name text not null,
+    slug text not null,
+    metadata jsonb not null default '{}'::jsonb,
+    created_at timestamptz not null default now(),
+    updated_at timestamptz not null default now(),
+    constraint organizations_pkey primary key (id),
+    constraint organizations_slug_key unique (slug),
+    constraint organizations_name_length check (char_length(name) <= 255)
+);
+
+create table if not exists {{ index .Options "Namespace" }}.organization_members (
+    organization_id uuid not null,
+    user_id uuid not null,
+    role text not null,
+    created_at timestamptz not null default now(),
+    updated_at timestamptz not null default now(),
+    constraint organization_members_pkey primary key (organization_id, user_id),
+    constraint organization_members_organization_id_fkey foreign key (organization_id) references {{ index .Options "Namespace" }}.organizations(id) on delete cascade,
+    constraint organization_members_user_id_fkey foreign key (user_id) references {{ index .Options "Namespace" }}.users(id) on delete cascade
+);
+
+create index if not exists organization_members_user_id_idx
+    on {{ index .Options "Namespace" }}.organization_members (user_id);
+
+comment on table {{ index .Options "Namespace" }}.organizations is 'auth: stores the organizations users are members of';
+comment on table {{ index .Options "Namespace" }}.organization_members is 'auth: stores the members of organizations, with their role in the organization';
+comment on column {{ index .Options "Namespace" }}.organization_members.role is 'auth: owner, admin or member';
@@ -0,0 +1,27 @@
+-- Roles grant permissions to the users they are assigned to, for role based access control
+create table if not exists {{ index .Options "Namespace" }}.roles (
+    id uuid not null,
+    name text not null,
+    description text not null default '',
+    permissions jsonb not null default '[]'::jsonb,
+    created_at timestamptz not null default now(),
+    updated_at timestamptz not null default now(),
+    constraint roles_pkey primary key (id),
+    constraint roles_name_key unique (name)
+);
+
+create table if not exists {{ index .Options "Namespace" }}.user_roles (
+    user_id uuid not null,
+    role_id uuid not null,
+    created_at timestamptz not null default now(),
+    constraint user_roles_pkey primary key (user_id, role_id),
+    constraint user_roles_user_id_fkey foreign key (user_id) references {{ index .Options "Namespace" }}.users(id) on delete cascade,
+    constraint user_roles_role_id_fkey foreign key (role_id) references {{ index .Options "Namespace" }}.roles(id) on delete cascade
+);
+
+create index if not exists user_roles_role_id_idx
+    on {{ index .Options "Namespace" }}.user_roles (role_id);
+
+comment on table {{ index .Options "Namespace" }}.roles is 'auth: stores the roles managed with the admin API and the permissions they grant';
+comment on table {{ index .Options "Namespace" }}.user_roles is 'auth: stores the roles assigned to users';
+comment on column {{ index .Options "Namespace" }}.roles.permissions is 'auth: JSON array of the permissions granted to the users with the role';
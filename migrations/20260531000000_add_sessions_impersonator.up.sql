@@ -0,0 +1,7 @@
+-- Sessions admins create to impersonate users record the admin
+alter table {{ index .Options "Namespace" }}.sessions
+    add column if not exists impersonator_id uuid null,
+    add column if not exists impersonator_role text null;
+
+comment on column {{ index .Options "Namespace" }}.sessions.impersonator_id is 'auth: user ID of the admin impersonating the user, when the admin token has one';
+comment on column {{ index .Options "Namespace" }}.sessions.impersonator_role is 'auth: role of the admin impersonating the user, set on impersonated sessions only';
@@ -0,0 +1,14 @@
+-- Soft deleted users are purged after a grace period, and may keep their data until then
+alter table {{ index .Options "Namespace" }}.users
+    add column if not exists purge_after timestamptz null,
+    add column if not exists is_anonymized boolean not null default false;
+
+-- users soft deleted before were always anonymized
+update {{ index .Options "Namespace" }}.users set is_anonymized = true where deleted_at is not null;
+
+create index if not exists users_purge_after_idx
+    on {{ index .Options "Namespace" }}.users (purge_after)
+    where purge_after is not null;
+
+comment on column {{ index .Options "Namespace" }}.users.purge_after is 'auth: time after which the soft deleted user is permanently deleted';
+comment on column {{ index .Options "Namespace" }}.users.is_anonymized is 'auth: whether the personal data of the soft deleted user has been erased';
@@ -0,0 +1,20 @@
+-- User exports hold the archives of their data users request for data portability
+create table if not exists {{ index .Options "Namespace" }}.user_exports (
+    id uuid not null,
+    user_id uuid not null,
+    status text not null default 'pending',
+    format text not null default 'json',
+    data bytea null,
+    created_at timestamptz not null default now(),
+    updated_at timestamptz not null default now(),
+    completed_at timestamptz null,
+    expires_at timestamptz null,
+    constraint user_exports_pkey primary key (id),
+    constraint user_exports_user_id_fkey foreign key (user_id) references {{ index .Options "Namespace" }}.users(id) on delete cascade
+);
+
+create index if not exists user_exports_user_id_idx
+    on {{ index .Options "Namespace" }}.user_exports (user_id);
+
+comment on table {{ index .Options "Namespace" }}.user_exports is 'auth: stores the exports of their data users requested, until their download link expires';
+comment on column {{ index .Options "Namespace" }}.user_exports.data is 'auth: the archive of the export, a JSON document or a zip file holding it';
@@ -0,0 +1,34 @@
+-- Deliveries of user lifecycle events to the webhook endpoints
+do $$ begin
+    create type {{ index .Options "Namespace" }}.webhook_delivery_status as enum('pending', 'delivered', 'dead');
+exception
+    when duplicate_object then null;
+end $$;
+
+create table if not exists {{ index .Options "Namespace" }}.webhook_deliveries (
+    id uuid not null,
+    status {{ index .Options "Namespace" }}.webhook_delivery_status not null default 'pending',
+    endpoint text not null,
+    event_id uuid not null,
+    event_type text not null,
+    user_id uuid null,
+    payload jsonb not null,
+    attempts integer not null default 0,
+    last_error text null,
+    last_status_code integer null,
+    run_at timestamptz not null default now(),
+    delivered_at timestamptz null,
+    created_at timestamptz not null default now(),
+    updated_at timestamptz not null default now(),
+    constraint webhook_deliveries_pkey primary key (id)
+);
+
+create index if not exists webhook_deliveries_pending_run_at_idx
+    on {{ index .Options "Namespace" }}.webhook_deliveries (run_at)
+    where status = 'pending';
+
+create index if not exists webhook_deliveries_created_at_idx
+    on {{ index .Options "Namespace" }}.webhook_deliveries (created_at);
+
+comment on table {{ index .Options "Namespace" }}.webhook_deliveries is 'auth: stores the user lifecycle events sent or to be sent to each webhook endpoint';
+comment on column {{ index .Options "Namespace" }}.webhook_deliveries.user_id is 'auth: the user of the event, not a foreign key as the events of deleted users are still delivered';
@@ -0,0 +1,5 @@
+-- The password verification attempt hook can require a captcha for the next password sign-ins of a user
+alter table {{ index .Options "Namespace" }}.users
+    add column if not exists sign_in_captcha_required boolean not null default false;
+
+comment on column {{ index .Options "Namespace" }}.users.sign_in_captcha_required is 'auth: whether password sign-ins of the user require a captcha until one succeeds';
@@ -0,0 +1,5 @@
+-- Admins can expire the password of a user, who then has to reset it before signing in with a password again
+alter table {{ index .Options "Namespace" }}.users
+    add column if not exists password_reset_required boolean not null default false;
+
+comment on column {{ index .Options "Namespace" }}.users.password_reset_required is 'auth: whether the password of the user has expired and must be reset before password sign-ins';
@@ -0,0 +1,3 @@
+alter table {{ index .Options "Namespace" }}.oauth_clients
+    drop column if exists allowed_scopes,
+    drop column if exists allowed_audiences;
@@ -0,0 +1,7 @@
+-- Machine-to-machine clients obtain service tokens with the client_credentials grant, restricted to their allowed scopes and audiences
+alter table {{ index .Options "Namespace" }}.oauth_clients
+    add column if not exists allowed_scopes text not null default '',
+    add column if not exists allowed_audiences text not null default '';
+
+comment on column {{ index .Options "Namespace" }}.oauth_clients.allowed_scopes is 'auth: comma separated scopes the client may request with the client_credentials grant';
+comment on column {{ index .Options "Namespace" }}.oauth_clients.allowed_audiences is 'auth: comma separated audiences the client may request with the client_credentials grant';
@@ -0,0 +1,22 @@
+-- Sessions record where they were signed in from, and users are notified of sign-ins from locations and devices they have not signed in from before
+alter table {{ index .Options "Namespace" }}.sessions
+    add column if not exists country text null,
+    add column if not exists city text null;
+
+comment on column {{ index .Options "Namespace" }}.sessions.country is 'auth: ISO 3166-1 alpha-2 code of the country the session was signed in from';
+comment on column {{ index .Options "Namespace" }}.sessions.city is 'auth: city the session was signed in from';
+
+create table if not exists {{ index .Options "Namespace" }}.sign_in_locations (
+    id uuid not null,
+    user_id uuid not null,
+    country text not null default '',
+    city text not null default '',
+    user_agent text not null default '',
+    created_at timestamptz not null default now(),
+    last_seen_at timestamptz not null default now(),
+    constraint sign_in_locations_pkey primary key (id),
+    constraint sign_in_locations_user_id_country_city_user_agent_key unique (user_id, country, city, user_agent),
+    constraint sign_in_locations_user_id_fkey foreign key (user_id) references {{ index .Options "Namespace" }}.users(id) on delete cascade
+);
+
+comment on table {{ index .Options "Namespace" }}.sign_in_locations is 'auth: stores the locations and devices users signed in from, to notify them of sign-ins from new ones';
@@ -0,0 +1,7 @@
+-- Sign-ins are scored by their risk, and risky sessions can be required to verify MFA before they can be refreshed
+alter table {{ index .Options "Namespace" }}.sessions
+    add column if not exists risk_score smallint null,
+    add column if not exists mfa_required boolean not null default false;
+
+comment on column {{ index .Options "Namespace" }}.sessions.risk_score is 'auth: risk score between 0 and 100 of the sign-in that created the session';
+comment on column {{ index .Options "Namespace" }}.sessions.mfa_required is 'auth: the session can not be refreshed until it reaches AAL2, as its sign-in was risky';